@@ -9,13 +9,23 @@ import (
 
 // User represents a user entity
 type User struct {
-	ID        string    `json:"id" dynamodb:"id,hash"`
-	TenantID  string    `json:"tenant_id" dynamodb:"tenant_id"`
-	Email     string    `json:"email" validate:"required,email"`
-	Name      string    `json:"name" validate:"required,min=1,max=100"`
-	Active    bool      `json:"active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string     `json:"id" dynamodb:"id,hash"`
+	TenantID  string     `json:"tenant_id" dynamodb:"tenant_id"`
+	Email     string     `json:"email" validate:"required,email"`
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" dynamodb:"deleted_at"`
+	DeletedBy string     `json:"deleted_by,omitempty" dynamodb:"deleted_by"`
+}
+
+// SoftDeletedAt implements dynamorm.SoftDeletable
+func (u User) SoftDeletedAt() time.Time {
+	if u.DeletedAt == nil {
+		return time.Time{}
+	}
+	return *u.DeletedAt
 }
 
 // CreateUserRequest represents the request to create a user
@@ -70,6 +80,7 @@ func main() {
 	app.GET("/users", ListUsers)
 	app.PUT("/users/:id", UpdateUser)
 	app.DELETE("/users/:id", DeleteUser)
+	app.POST("/users/:id/restore", RestoreUser)
 
 	// Health check
 	app.GET("/health", HealthCheck)
@@ -247,7 +258,8 @@ func UpdateUser(ctx *lift.Context) error {
 	})
 }
 
-// DeleteUser deletes a user
+// DeleteUser soft deletes a user, who can be restored until the purge
+// job removes them
 func DeleteUser(ctx *lift.Context) error {
 	userID := ctx.Param("id")
 	if userID == "" {
@@ -271,17 +283,18 @@ func DeleteUser(ctx *lift.Context) error {
 		return lift.NotFound("User not found")
 	}
 
-	// Delete user
-	if err := db.Delete(ctx, userID); err != nil {
+	// Soft delete user
+	if err := db.SoftDelete(ctx, &User{}, userID, ctx.UserID()); err != nil {
 		return lift.NewLiftError("INTERNAL_ERROR", "Failed to delete user", 500).WithCause(err)
 	}
 
 	// Log the deletion
 	if ctx.Logger != nil {
 		ctx.Logger.Info("User deleted", map[string]any{
-			"user_id":   user.ID,
-			"tenant_id": user.TenantID,
-			"email":     user.Email,
+			"user_id":    user.ID,
+			"tenant_id":  user.TenantID,
+			"email":      user.Email,
+			"deleted_by": ctx.UserID(),
 		})
 	}
 
@@ -290,6 +303,56 @@ func DeleteUser(ctx *lift.Context) error {
 	})
 }
 
+// RestoreUser restores a soft-deleted user
+func RestoreUser(ctx *lift.Context) error {
+	userID := ctx.Param("id")
+	if userID == "" {
+		return lift.NewLiftError("BAD_REQUEST", "User ID is required", 400)
+	}
+
+	// Get tenant-scoped database
+	db, err := dynamorm.TenantDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Retrieve user, including deleted users
+	var user User
+	if err := db.GetWithDeleted(ctx, userID, &user); err != nil {
+		return lift.NotFound("User not found")
+	}
+
+	// Verify tenant access
+	if user.TenantID != ctx.TenantID() {
+		return lift.NotFound("User not found")
+	}
+
+	if !dynamorm.IsSoftDeleted(user) {
+		return lift.NewLiftError("CONFLICT", "User is not deleted", 409)
+	}
+
+	// Restore user
+	if err := db.Restore(ctx, &User{}, userID); err != nil {
+		return lift.NewLiftError("INTERNAL_ERROR", "Failed to restore user", 500).WithCause(err)
+	}
+	user.DeletedAt = nil
+	user.DeletedBy = ""
+
+	// Log the restore
+	if ctx.Logger != nil {
+		ctx.Logger.Info("User restored", map[string]any{
+			"user_id":     user.ID,
+			"tenant_id":   user.TenantID,
+			"restored_by": ctx.UserID(),
+		})
+	}
+
+	return ctx.JSON(&UserResponse{
+		User:    &user,
+		Message: "User restored successfully",
+	})
+}
+
 // HealthCheck provides a health check endpoint
 func HealthCheck(ctx *lift.Context) error {
 	return ctx.JSON(map[string]any{
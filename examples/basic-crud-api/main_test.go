@@ -303,6 +303,7 @@ func setupRoutes(app *lift.App) {
 	app.GET("/users", ListUsers)
 	app.PUT("/users/:id", UpdateUser)
 	app.DELETE("/users/:id", DeleteUser)
+	app.POST("/users/:id/restore", RestoreUser)
 	app.GET("/health", HealthCheck)
 }
 
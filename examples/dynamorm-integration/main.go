@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/pay-theory/lift/pkg/dynamorm"
@@ -10,13 +13,23 @@ import (
 
 // User represents a user model for DynamORM
 type User struct {
-	ID        string    `dynamorm:"pk" json:"id"`
-	TenantID  string    `dynamorm:"gsi1pk" json:"tenant_id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Active    bool      `json:"active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string     `dynamorm:"pk" json:"id"`
+	TenantID  string     `dynamorm:"gsi1pk" json:"tenant_id"`
+	Email     string     `json:"email"`
+	Name      string     `json:"name"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `dynamorm:"attr:deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy string     `dynamorm:"attr:deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// SoftDeletedAt implements dynamorm.SoftDeletable
+func (u User) SoftDeletedAt() time.Time {
+	if u.DeletedAt == nil {
+		return time.Time{}
+	}
+	return *u.DeletedAt
 }
 
 // CreateUserRequest represents the request to create a user
@@ -35,6 +48,21 @@ func main() {
 	// Create a new Lift application
 	app := lift.New()
 
+	// Writes can be switched off during failovers and migrations by
+	// setting READ_ONLY to "all" or a comma-separated list of tenant IDs
+	readOnly := dynamorm.NewReadOnlySwitch(dynamorm.ReadOnlyConfig{
+		Source: func(ctx context.Context) (dynamorm.ReadOnlyState, error) {
+			setting := os.Getenv("READ_ONLY")
+			if setting == "all" {
+				return dynamorm.ReadOnlyState{All: true, Reason: "maintenance"}, nil
+			}
+			if setting == "" {
+				return dynamorm.ReadOnlyState{}, nil
+			}
+			return dynamorm.ReadOnlyState{Tenants: strings.Split(setting, ","), Reason: "data migration"}, nil
+		},
+	})
+
 	// Configure DynamORM middleware
 	app.Use(dynamorm.WithDynamORM(&dynamorm.DynamORMConfig{
 		TableName:       "lift_users",
@@ -43,6 +71,7 @@ func main() {
 		TenantIsolation: true,                    // Enable tenant isolation
 		AutoTransaction: true,                    // Enable automatic transactions
 		ConsistentRead:  false,                   // Use eventually consistent reads
+		ReadOnly:        readOnly,                // Reject writes while read-only
 	}))
 
 	// Health check endpoint
@@ -177,8 +206,8 @@ func main() {
 			return lift.NotFound("User not found")
 		}
 
-		// Delete user
-		if err := db.Delete(ctx.Context, userID); err != nil {
+		// Soft delete user so the deletion can be undone
+		if err := db.SoftDelete(ctx.Context, &User{}, userID, ctx.UserID()); err != nil {
 			return lift.NewLiftError("INTERNAL_ERROR", "Failed to delete user", 500).WithCause(err)
 		}
 
@@ -187,6 +216,62 @@ func main() {
 		})
 	})
 
+	// Restore user endpoint
+	app.POST("/users/:id/restore", func(ctx *lift.Context) error {
+		userID := ctx.Param("id")
+
+		// Get DynamORM instance from context
+		db, err := dynamorm.TenantDB(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Deleted users are only visible through GetWithDeleted
+		var user User
+		if err := db.GetWithDeleted(ctx.Context, userID, &user); err != nil {
+			return lift.NotFound("User not found").WithCause(err)
+		}
+
+		if user.TenantID != ctx.TenantID() {
+			return lift.NotFound("User not found")
+		}
+
+		if !dynamorm.IsSoftDeleted(user) {
+			return lift.NewLiftError("CONFLICT", "User is not deleted", 409)
+		}
+
+		if err := db.Restore(ctx.Context, &User{}, userID); err != nil {
+			return lift.NewLiftError("INTERNAL_ERROR", "Failed to restore user", 500).WithCause(err)
+		}
+
+		return ctx.JSON(map[string]string{
+			"message": "User restored successfully",
+		})
+	})
+
+	// List deleted users for tenant
+	app.GET("/users/deleted", func(ctx *lift.Context) error {
+		db, err := dynamorm.TenantDB(ctx)
+		if err != nil {
+			return err
+		}
+
+		result, err := db.Query(ctx.Context, &dynamorm.Query{
+			PartitionKey: ctx.TenantID(),
+			IndexName:    "GSI1",
+			Limit:        50,
+			OnlyDeleted:  true,
+		})
+		if err != nil {
+			return lift.NewLiftError("INTERNAL_ERROR", "Failed to list deleted users", 500).WithCause(err)
+		}
+
+		return ctx.JSON(map[string]any{
+			"users": result.Items,
+			"count": result.Count,
+		})
+	})
+
 	// List users for tenant
 	app.GET("/users", func(ctx *lift.Context) error {
 		// Get DynamORM instance from context
@@ -225,12 +310,15 @@ func main() {
 	fmt.Println("  GET    /users/:id")
 	fmt.Println("  PUT    /users/:id")
 	fmt.Println("  DELETE /users/:id")
+	fmt.Println("  POST   /users/:id/restore")
 	fmt.Println("  GET    /users")
+	fmt.Println("  GET    /users/deleted")
 	fmt.Println("")
 	fmt.Println("Features demonstrated:")
 	fmt.Println("  ✅ DynamORM integration")
 	fmt.Println("  ✅ Tenant isolation")
 	fmt.Println("  ✅ Automatic transactions")
 	fmt.Println("  ✅ CRUD operations")
+	fmt.Println("  ✅ Soft delete and restore")
 	fmt.Println("  ✅ Error handling")
 }
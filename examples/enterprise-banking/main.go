@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/id"
+	"github.com/pay-theory/lift/pkg/middleware"
 )
 
 // Banking domain models
@@ -119,7 +121,7 @@ type mockFraudDetectionService struct{}
 
 func (m *mockAccountService) CreateAccount(ctx context.Context, req CreateAccountRequest) (*Account, error) {
 	return &Account{
-		ID:            generateID(),
+		ID:            id.New("acct"),
 		CustomerID:    req.CustomerID,
 		AccountNumber: generateAccountNumber(),
 		AccountType:   req.AccountType,
@@ -155,7 +157,7 @@ func (m *mockAccountService) UpdateBalance(ctx context.Context, accountID string
 
 func (m *mockTransactionService) CreateTransaction(ctx context.Context, req CreateTransactionRequest) (*Transaction, error) {
 	return &Transaction{
-		ID:            generateID(),
+		ID:            id.New("txn"),
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
@@ -215,7 +217,7 @@ func (m *mockTransactionService) GetAccountTransactions(ctx context.Context, acc
 
 func (m *mockPaymentService) ProcessPayment(ctx context.Context, req ProcessPaymentRequest) (*Payment, error) {
 	return &Payment{
-		ID:              generateID(),
+		ID:              id.New("pay"),
 		PayerAccountID:  req.PayerAccountID,
 		PayeeAccountID:  req.PayeeAccountID,
 		Amount:          req.Amount,
@@ -244,7 +246,7 @@ func (m *mockPaymentService) GetPayment(ctx context.Context, id string) (*Paymen
 
 func (m *mockPaymentService) RefundPayment(ctx context.Context, req RefundPaymentRequest) (*Payment, error) {
 	return &Payment{
-		ID:             generateID(),
+		ID:             id.New("ref"),
 		PayerAccountID: "system",
 		PayeeAccountID: "acc_123",
 		Amount:         req.Amount,
@@ -302,10 +304,6 @@ func (m *mockFraudDetectionService) CheckRisk(ctx context.Context, accountID str
 }
 
 // Utility functions
-func generateID() string {
-	return fmt.Sprintf("id_%d", time.Now().UnixNano())
-}
-
 func generateAccountNumber() string {
 	return fmt.Sprintf("ACC-%d", time.Now().UnixNano()%1000000)
 }
@@ -573,81 +571,6 @@ func healthCheck(ctx *lift.Context) error {
 	return ctx.OK(health)
 }
 
-// Recovery middleware function
-func Recovery() lift.Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			defer func() {
-				if r := recover(); r != nil {
-					if ctx.Logger != nil {
-						ctx.Logger.Error("Handler panicked", map[string]any{
-							"panic": r,
-						})
-					}
-					ctx.SystemError("Internal server error", fmt.Errorf("panic: %v", r))
-				}
-			}()
-			return next.Handle(ctx)
-		})
-	}
-}
-
-// CORS configuration
-type CORSConfig struct {
-	AllowOrigins []string
-	AllowMethods []string
-	AllowHeaders []string
-}
-
-// CORS middleware function
-func CORS(config CORSConfig) lift.Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			origin := ctx.Header("Origin")
-
-			// Check if origin is allowed
-			allowed := false
-			for _, allowedOrigin := range config.AllowOrigins {
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					allowed = true
-					break
-				}
-			}
-
-			if allowed {
-				ctx.Response.Header("Access-Control-Allow-Origin", origin)
-				if len(config.AllowMethods) > 0 {
-					methods := ""
-					for i, method := range config.AllowMethods {
-						if i > 0 {
-							methods += ", "
-						}
-						methods += method
-					}
-					ctx.Response.Header("Access-Control-Allow-Methods", methods)
-				}
-				if len(config.AllowHeaders) > 0 {
-					headers := ""
-					for i, header := range config.AllowHeaders {
-						if i > 0 {
-							headers += ", "
-						}
-						headers += header
-					}
-					ctx.Response.Header("Access-Control-Allow-Headers", headers)
-				}
-			}
-
-			if ctx.Request.Method == "OPTIONS" {
-				ctx.Response.StatusCode = 204
-				return nil
-			}
-
-			return next.Handle(ctx)
-		})
-	}
-}
-
 // Logger middleware function
 func Logger() lift.Middleware {
 	return func(next lift.Handler) lift.Handler {
@@ -703,46 +626,14 @@ func RateLimit(config RateLimitConfig) lift.Middleware {
 	}
 }
 
-// Group represents a route group
-type Group struct {
-	prefix string
-	app    *lift.App
-}
-
-// GET adds a GET route to the group
-func (g *Group) GET(path string, handler func(*lift.Context) error) {
-	g.app.GET(g.prefix+path, handler)
-}
-
-// POST adds a POST route to the group
-func (g *Group) POST(path string, handler func(*lift.Context) error) {
-	g.app.POST(g.prefix+path, handler)
-}
-
-// Group creates a new route group
-func (g *Group) Group(prefix string) *Group {
-	return &Group{
-		prefix: g.prefix + prefix,
-		app:    g.app,
-	}
-}
-
-// NewGroup creates a new route group
-func NewGroup(app *lift.App, prefix string) *Group {
-	return &Group{
-		prefix: prefix,
-		app:    app,
-	}
-}
-
 func main() {
 	// Create Lift application
 	app := lift.New()
 
 	// Enterprise middleware stack
 	app.Use(Logger())
-	app.Use(Recovery())
-	app.Use(CORS(CORSConfig{
+	app.Use(middleware.Recovery(middleware.RecoveryOptions{}))
+	app.Use(middleware.CORS(middleware.CORSConfig{
 		AllowOrigins: []string{"https://banking.example.com"},
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE"},
 		AllowHeaders: []string{"Authorization", "Content-Type"},
@@ -755,15 +646,13 @@ func main() {
 		Logging: true,
 	}))
 
-	// Security middleware (would include JWT, rate limiting, etc.)
-	app.Use(RateLimit(RateLimitConfig{
+	// API versioning, with security middleware (would include JWT, rate
+	// limiting, etc.) scoped to the API routes
+	api := app.Group("/api/v1", RateLimit(RateLimitConfig{
 		Limit: 100,
 		Burst: 20,
 	}))
 
-	// API versioning
-	api := NewGroup(app, "/api/v1")
-
 	// Health check endpoint
 	api.GET("/health", healthCheck)
 
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/pay-theory/lift/pkg/auth"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// Customer authentication. The demo keeps credentials, tokens and the
+// revocation list in memory; production deployments use
+// auth.NewDynamoDBTokenStore, middleware.NewDynamoDBAuthFailureStore and
+// security.NewDynamoDBRevocationList, and a CredentialStore over the
+// customer table.
+var (
+	customerCredentials = auth.NewMemoryCredentialStore()
+	customerRevocations = mustRevocations()
+	customerAuth        = mustCustomerAuth()
+)
+
+func mustRevocations() *security.Revocations {
+	revocations, err := security.NewRevocations(security.RevocationConfig{
+		List:             security.NewMemoryRevocationList(),
+		MaxTokenLifetime: auth.DefaultRefreshTokenTTL,
+	})
+	if err != nil {
+		log.Fatalf("failed to create revocation list: %v", err)
+	}
+	return revocations
+}
+
+func mustCustomerAuth() *auth.Service {
+	service, err := auth.NewService(auth.Config{
+		Credentials: customerCredentials,
+		Revocations: customerRevocations,
+		SigningKey:  []byte(customerTokenSecret()),
+		Issuer:      "enterprise-ecommerce",
+		OnError: func(err error) {
+			log.Printf("ECOMMERCE AUTH: %v", err)
+		},
+	})
+	if err != nil {
+		log.Fatalf("failed to create customer auth: %v", err)
+	}
+	return service
+}
+
+// customerTokenSecret signs customer access tokens
+func customerTokenSecret() string {
+	if secret := os.Getenv("CUSTOMER_TOKEN_SECRET"); secret != "" {
+		return secret
+	}
+	return "development-only-customer-token-secret"
+}
+
+// sendPasswordReset would email the reset link; the demo only logs it
+func sendPasswordReset(ctx *lift.Context, reset *auth.PasswordReset) error {
+	log.Printf("ECOMMERCE AUDIT: Password reset requested - Tenant: %s, Customer: %s, Expires: %s",
+		reset.TenantID, reset.UserID, reset.ExpiresAt)
+	return nil
+}
+
+// authError maps customer authentication failures to responses
+func authError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrTooManyAttempts):
+		return lift.NewLiftError("TOO_MANY_ATTEMPTS", "Too many failed login attempts, please retry later", 429)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return lift.Unauthorized("Invalid credentials")
+	default:
+		return lift.NewLiftError("INTERNAL_ERROR", "Failed to authenticate customer", 500).WithCause(err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/pay-theory/lift/pkg/address"
 	"github.com/pay-theory/lift/pkg/lift"
 )
 
@@ -12,9 +13,11 @@ import (
 var (
 	tenantService   = &mockTenantService{}
 	productService  = &mockProductService{}
-	customerService = &mockCustomerService{}
-	orderService    = &mockOrderService{}
-	cartService     = &mockCartService{}
+	customerService = &mockCustomerService{credentials: customerCredentials, auth: customerAuth}
+	orderService    = &mockOrderService{pricing: pricingEngine}
+	cartService     = &mockCartService{pricing: pricingEngine}
+
+	pricingEngine = NewPricingEngine(DefaultPricingConfig())
 )
 
 // Tenant handlers
@@ -242,6 +245,11 @@ func createCustomer(ctx *lift.Context) error {
 	if err := ctx.ParseRequest(&req); err != nil {
 		return ctx.BadRequest("Invalid request", err)
 	}
+	for i := range req.Addresses {
+		if err := normalizeAddress(&req.Addresses[i]); err != nil {
+			return ctx.BadRequest("Invalid address", err)
+		}
+	}
 
 	customer, err := customerService.CreateCustomer(ctx.Request.Context(), tenantID, req)
 	if err != nil {
@@ -325,20 +333,17 @@ func authenticateCustomer(ctx *lift.Context) error {
 		return ctx.BadRequest("Invalid request", err)
 	}
 
-	customer, err := customerService.AuthenticateCustomer(ctx.Request.Context(), tenantID, req.Email, req.Password)
+	customer, session, err := customerService.AuthenticateCustomer(ctx.Request.Context(), tenantID, req.Email, req.Password)
 	if err != nil {
-		return ctx.Unauthorized("Invalid credentials", err)
+		return authError(err)
 	}
 
-	// In production, generate JWT token
-	token := "jwt_token_here"
-
 	log.Printf("ECOMMERCE AUDIT: Customer authenticated - Tenant: %s, Customer: %s, Email: %s",
 		tenantID, customer.ID, customer.Email)
 
 	return ctx.OK(map[string]any{
 		"customer":         customer,
-		"token":            token,
+		"session":          session,
 		"authenticated_at": time.Now(),
 	})
 }
@@ -354,6 +359,10 @@ func createOrder(ctx *lift.Context) error {
 	if err := ctx.ParseRequest(&req); err != nil {
 		return ctx.BadRequest("Invalid request", err)
 	}
+	if err := normalizeAddress(&req.Shipping.Address); err != nil {
+		return ctx.BadRequest("Invalid shipping address", err)
+	}
+	req.IdempotencyKey = ctx.Header("Idempotency-Key")
 
 	order, err := orderService.CreateOrder(ctx.Request.Context(), tenantID, req)
 	if err != nil {
@@ -610,3 +619,15 @@ func checkout(ctx *lift.Context) error {
 
 	return ctx.Created(order)
 }
+
+// normalizeAddress normalizes an address in place so orders are taxed and
+// shipped on canonical state and country codes, and rejects addresses
+// with a missing or malformed part
+func normalizeAddress(a *Address) error {
+	normalized := address.Normalize(a.Postal())
+	if err := address.Validate(normalized); err != nil {
+		return err
+	}
+	a.SetPostal(normalized)
+	return nil
+}
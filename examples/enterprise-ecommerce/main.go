@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/pay-theory/lift/pkg/address"
+	"github.com/pay-theory/lift/pkg/auth"
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/metering"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/pay-theory/lift/pkg/sequence"
 )
 
 // Core e-commerce domain models with multi-tenant architecture
@@ -204,6 +211,25 @@ type Address struct {
 	IsDefault  bool   `json:"isDefault"`
 }
 
+// Postal returns the address in the form used by the address package
+func (a Address) Postal() address.Address {
+	return address.Address{
+		Line1:      a.Address1,
+		Line2:      a.Address2,
+		City:       a.City,
+		Region:     a.State,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
+
+// SetPostal copies a normalized address back into the model
+func (a *Address) SetPostal(postal address.Address) {
+	a.Address1, a.Address2 = postal.Line1, postal.Line2
+	a.City, a.State = postal.City, postal.Region
+	a.PostalCode, a.Country = postal.PostalCode, postal.Country
+}
+
 // PaymentMethod represents customer payment methods
 type PaymentMethod struct {
 	ID          string    `json:"id"`
@@ -265,6 +291,8 @@ type OrderTotals struct {
 	Discount Money   `json:"discount"`
 	Total    Money   `json:"total"`
 	TaxRate  float64 `json:"taxRate"`
+	// Adjustments itemize the discounts, shipping and tax
+	Adjustments []PriceAdjustment `json:"adjustments,omitempty"`
 }
 
 // PaymentInfo represents payment information
@@ -313,6 +341,20 @@ type ShoppingCart struct {
 	CreatedAt  time.Time  `json:"createdAt"`
 	UpdatedAt  time.Time  `json:"updatedAt"`
 	ExpiresAt  time.Time  `json:"expiresAt"`
+	// TTL is ExpiresAt in Unix seconds, the carts table's TTL attribute.
+	// DynamoDB removes the cart once it passes, and the removal reaches
+	// cartExpired through the table's stream.
+	TTL int64 `json:"ttl"`
+}
+
+// cartLifetime is how long a cart lives after its last change
+const cartLifetime = 24 * time.Hour
+
+// Touch records a change to the cart and pushes back its expiry
+func (c *ShoppingCart) Touch(now time.Time) {
+	c.UpdatedAt = now
+	c.ExpiresAt = now.Add(cartLifetime)
+	c.TTL = c.ExpiresAt.Unix()
 }
 
 // CartItem represents items in shopping cart
@@ -329,10 +371,13 @@ type CartItem struct {
 // CartTotals represents cart totals
 type CartTotals struct {
 	Subtotal  Money `json:"subtotal"`
+	Discount  Money `json:"discount"`
 	Tax       Money `json:"tax"`
 	Shipping  Money `json:"shipping"`
 	Total     Money `json:"total"`
 	ItemCount int   `json:"itemCount"`
+	// Adjustments itemize the discounts, shipping and tax
+	Adjustments []PriceAdjustment `json:"adjustments,omitempty"`
 }
 
 // Request/Response models
@@ -362,6 +407,8 @@ type CreateCustomerRequest struct {
 	Profile     CustomerProfile     `json:"profile" validate:"required"`
 	Addresses   []Address           `json:"addresses"`
 	Preferences CustomerPreferences `json:"preferences"`
+	// Password, if set, lets the customer sign in
+	Password string `json:"password,omitempty"`
 }
 
 type CreateOrderRequest struct {
@@ -370,6 +417,11 @@ type CreateOrderRequest struct {
 	Shipping   ShippingInfo `json:"shipping" validate:"required"`
 	Payment    PaymentInfo  `json:"payment" validate:"required"`
 	Notes      string       `json:"notes"`
+	// CouponCodes are applied by the pricing engine
+	CouponCodes []string `json:"couponCodes"`
+	// IdempotencyKey comes from the Idempotency-Key header so a retried
+	// request gets the order number it was first given
+	IdempotencyKey string `json:"-"`
 }
 
 type AddToCartRequest struct {
@@ -407,7 +459,7 @@ type CustomerService interface {
 	GetCustomer(ctx context.Context, tenantID, id string) (*Customer, error)
 	UpdateCustomer(ctx context.Context, tenantID, id string, customer *Customer) error
 	ListCustomers(ctx context.Context, tenantID string, limit, offset int) ([]Customer, error)
-	AuthenticateCustomer(ctx context.Context, tenantID, email, password string) (*Customer, error)
+	AuthenticateCustomer(ctx context.Context, tenantID, email, password string) (*Customer, *auth.Session, error)
 }
 
 type OrderService interface {
@@ -455,63 +507,23 @@ type OrderFilters struct {
 }
 
 // Utility functions
-func generateID() string {
-	return fmt.Sprintf("id_%d", time.Now().UnixNano())
-}
-
 func generateSKU() string {
 	return fmt.Sprintf("SKU-%d", time.Now().UnixNano()%1000000)
 }
 
-func generateOrderNumber() string {
-	return fmt.Sprintf("ORD-%d", time.Now().UnixNano()%10000000)
-}
-
-func calculateCartTotals(items []CartItem) CartTotals {
-	var subtotal float64
-	itemCount := 0
+// orderNumbers numbers each tenant's orders from 1 every year. In
+// production it would use sequence.NewDynamoDBStore.
+var orderNumbers, _ = sequence.New(sequence.Config{
+	Name:   "order",
+	Format: "ORD-{year}-{seq:6}",
+})
 
-	for _, item := range items {
-		subtotal += item.Total.Amount
-		itemCount += item.Quantity
-	}
-
-	tax := subtotal * 0.08 // 8% tax rate
-	shipping := 0.0
-	if subtotal < 50 {
-		shipping = 9.99 // Free shipping over $50
-	}
-
-	return CartTotals{
-		Subtotal:  Money{Amount: subtotal, Currency: "USD"},
-		Tax:       Money{Amount: tax, Currency: "USD"},
-		Shipping:  Money{Amount: shipping, Currency: "USD"},
-		Total:     Money{Amount: subtotal + tax + shipping, Currency: "USD"},
-		ItemCount: itemCount,
-	}
-}
-
-func calculateOrderTotals(items []OrderItem) OrderTotals {
-	var subtotal float64
-
-	for _, item := range items {
-		subtotal += item.Total.Amount
-	}
-
-	tax := subtotal * 0.08 // 8% tax rate
-	shipping := 0.0
-	if subtotal < 50 {
-		shipping = 9.99 // Free shipping over $50
-	}
-
-	return OrderTotals{
-		Subtotal: Money{Amount: subtotal, Currency: "USD"},
-		Tax:      Money{Amount: tax, Currency: "USD"},
-		Shipping: Money{Amount: shipping, Currency: "USD"},
-		Discount: Money{Amount: 0, Currency: "USD"},
-		Total:    Money{Amount: subtotal + tax + shipping, Currency: "USD"},
-		TaxRate:  0.08,
+func generateOrderNumber(ctx context.Context, tenantID, idempotencyKey string) (string, error) {
+	number, err := orderNumbers.Next(ctx, tenantID, idempotencyKey)
+	if err != nil {
+		return "", err
 	}
+	return number.Formatted, nil
 }
 
 // Tenant isolation middleware
@@ -520,6 +532,11 @@ func tenantIsolationMiddleware() lift.Middleware {
 		return lift.HandlerFunc(func(ctx *lift.Context) error {
 			// Extract tenant ID from subdomain or header
 			tenantID := ctx.Request.GetHeader("X-Tenant-ID")
+			if tenantID == "" {
+				// Event sources such as the carts stream set the tenant
+				// before middleware runs
+				tenantID = ctx.TenantID()
+			}
 			if tenantID == "" {
 				// Try to extract from subdomain
 				if host := ctx.Request.GetHeader("Host"); host != "" {
@@ -559,8 +576,8 @@ func main() {
 	// Enterprise e-commerce middleware stack
 	// Note: Using basic middleware for now - full middleware integration pending
 	// app.Use(middleware.Logger())
-	// app.Use(middleware.Recover())
-	// app.Use(middleware.CORS([]string{"*"}))
+	// app.Use(middleware.Recovery(middleware.RecoveryOptions{}))
+	// app.Use(middleware.CORS(middleware.CORSConfig{AllowOrigins: []string{"*"}}))
 
 	// Enhanced observability for e-commerce
 	// app.Use(middleware.EnhancedObservabilityMiddleware(middleware.EnhancedObservabilityConfig{
@@ -578,9 +595,22 @@ func main() {
 	// Tenant isolation middleware
 	app.Use(tenantIsolationMiddleware())
 
+	// Plan entitlements for feature-gated routes
+	app.Use(middleware.Entitlements(middleware.EntitlementConfig{
+		Resolve:    tenantEntitlements,
+		Plans:      subscriptionPlans,
+		UpgradeURL: "https://demo.example.com/billing",
+	}))
+
 	// Setup all API routes
 	setupAPIRoutes(app)
 
+	// Deliver recorded usage to billing on a schedule
+	app.EventBridge("billing-flush", usageEmitter.FlushHandler())
+
+	// Announce carts that expired with items still in them
+	app.Stream("carts", lift.SimpleHandler(cartExpired), lift.StreamTTLExpiries(), lift.StreamTenant(cartTenant))
+
 	log.Println("Starting Enterprise E-commerce Platform on port 8080...")
 	log.Println("Multi-Tenant E-commerce Features:")
 	log.Println("  ✓ Multi-tenant architecture with data isolation")
@@ -589,6 +619,7 @@ func main() {
 	log.Println("  ✓ Customer management and authentication")
 	log.Println("  ✓ Shopping cart and checkout workflows")
 	log.Println("  ✓ Inventory management with real-time updates")
+	log.Println("  ✓ Abandoned cart events from cart expiry")
 	log.Println("")
 	log.Println("Available endpoints:")
 	log.Println("  GET  /api/v1/health")
@@ -604,6 +635,10 @@ func main() {
 	log.Println("  GET  /api/v1/customers")
 	log.Println("  GET  /api/v1/customers/:id")
 	log.Println("  POST /api/v1/customers/auth")
+	log.Println("  POST /api/v1/customers/auth/refresh")
+	log.Println("  POST /api/v1/customers/auth/logout")
+	log.Println("  POST /api/v1/customers/password/forgot")
+	log.Println("  POST /api/v1/customers/password/reset")
 	log.Println("  GET  /api/v1/customers/:id/orders")
 	log.Println("  POST /api/v1/orders")
 	log.Println("  GET  /api/v1/orders")
@@ -618,6 +653,146 @@ func main() {
 	app.Start()
 }
 
+// subscriptionPlans lists the plans from cheapest to most expensive
+var subscriptionPlans = []middleware.Plan{
+	{Name: "starter", Features: []string{"productReviews", "wishList"}},
+	{Name: "professional", Features: []string{"productReviews", "wishList", "advancedSearch", "recommendations", "analytics"}},
+	{Name: "enterprise", Features: []string{"productReviews", "wishList", "advancedSearch", "recommendations", "analytics", "multiCurrency"}},
+}
+
+// tenantEntitlements reads a tenant's plan and feature flags for the
+// Entitlements middleware
+func tenantEntitlements(ctx context.Context, tenantID string) (*middleware.TenantEntitlements, error) {
+	tenant, err := tenantService.GetTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flags grant features beyond the plan; features the plan lacks and no
+	// flag grants are answered with an upgrade hint
+	flags := tenant.Configuration.Features
+	features := make(map[string]bool)
+	for name, enabled := range map[string]bool{
+		"advancedSearch":  flags.AdvancedSearch,
+		"productReviews":  flags.ProductReviews,
+		"wishList":        flags.WishList,
+		"recommendations": flags.Recommendations,
+		"multiCurrency":   flags.MultiCurrency,
+		"analytics":       flags.Analytics,
+	} {
+		if enabled {
+			features[name] = true
+		}
+	}
+
+	return &middleware.TenantEntitlements{
+		Plan:     tenant.Subscription.Plan,
+		Active:   tenant.IsActive && tenant.Subscription.Status == "active",
+		Features: features,
+	}, nil
+}
+
+// usageEmitter records billable usage, such as orders placed, against the
+// tenant's subscription. In production the outbox would be a
+// metering.NewDynamoDBOutbox and the publisher a metering.NewServicePublisher
+// for the billing service.
+var usageEmitter, _ = metering.NewEmitter(metering.EmitterConfig{
+	Source:        "ecommerce.orders",
+	Subscriptions: tenantSubscription,
+	Publisher: metering.PublisherFunc(func(ctx context.Context, event *metering.BillingEvent) error {
+		log.Printf("BILLING: %s %s %.0f %s (plan %s, period %s)",
+			event.TenantID, event.ID, event.Quantity, event.Metric, event.Plan, event.PeriodStart.Format("2006-01-02"))
+		return nil
+	}),
+})
+
+// tenantSubscription reads the subscription a tenant's usage is billed
+// against
+func tenantSubscription(ctx context.Context, tenantID string) (*metering.Subscription, error) {
+	tenant, err := tenantService.GetTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &metering.Subscription{
+		Plan:         tenant.Subscription.Plan,
+		Status:       tenant.Subscription.Status,
+		BillingCycle: tenant.Subscription.BillingCycle,
+		StartDate:    tenant.Subscription.StartDate,
+	}, nil
+}
+
+// CartAbandonedEvent announces a cart that expired with items in it, for
+// marketing automations such as reminder emails
+type CartAbandonedEvent struct {
+	EventID        string     `json:"eventId"`
+	TenantID       string     `json:"tenantId"`
+	CartID         string     `json:"cartId"`
+	CustomerID     string     `json:"customerId"`
+	Items          []CartItem `json:"items"`
+	Totals         CartTotals `json:"totals"`
+	LastActivityAt time.Time  `json:"lastActivityAt"`
+	ExpiredAt      time.Time  `json:"expiredAt"`
+}
+
+// cartEventSource is the event bus source of cart events
+const cartEventSource = "ecommerce.carts"
+
+// publishCartEvent puts an event on the event bus. In production this
+// would call EventBridge PutEvents with cartEventSource as the source and
+// detailType as the detail type; subscribers deduplicate on the event ID,
+// as stream records can be delivered more than once.
+var publishCartEvent = func(ctx context.Context, detailType string, detail any) error {
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	log.Printf("EVENT: %s %s %s", cartEventSource, detailType, data)
+	return nil
+}
+
+// cartTenant reads the tenant of an expired cart from its last image
+func cartTenant(record *adapters.DynamoDBStreamRecord) string {
+	tenantID, _ := record.OldImage["tenantId"].(string)
+	return tenantID
+}
+
+// cartExpired publishes a "Cart Abandoned" event when DynamoDB removes an
+// expired cart that still holds items
+func cartExpired(ctx *lift.Context, record adapters.DynamoDBStreamRecord) (struct{}, error) {
+	var cart ShoppingCart
+	data, err := json.Marshal(record.OldImage)
+	if err == nil {
+		err = json.Unmarshal(data, &cart)
+	}
+	if err != nil {
+		// Retrying won't fix a malformed image, so skip it rather than
+		// block the shard
+		ctx.Logger.Warn("Skipping unreadable expired cart", map[string]any{
+			"event_id": record.EventID,
+			"error":    err.Error(),
+		})
+		return struct{}{}, nil
+	}
+	if len(cart.Items) == 0 {
+		return struct{}{}, nil
+	}
+
+	event := &CartAbandonedEvent{
+		EventID:        record.EventID,
+		TenantID:       cart.TenantID,
+		CartID:         cart.ID,
+		CustomerID:     cart.CustomerID,
+		Items:          cart.Items,
+		Totals:         cart.Totals,
+		LastActivityAt: cart.UpdatedAt,
+		ExpiredAt:      cart.ExpiresAt,
+	}
+	if err := publishCartEvent(ctx.Context, "Cart Abandoned", event); err != nil {
+		return struct{}{}, lift.NewLiftError("EVENT_PUBLISH_FAILED", "Failed to publish cart abandoned event", 500).WithCause(err)
+	}
+	return struct{}{}, nil
+}
+
 // setupAPIRoutes configures all the API routes for the e-commerce platform
 func setupAPIRoutes(app *lift.App) {
 	// Health check endpoint
@@ -631,7 +806,7 @@ func setupAPIRoutes(app *lift.App) {
 	// Product management endpoints (tenant-scoped)
 	app.POST("/api/v1/products", createProductHandler)
 	app.GET("/api/v1/products", listProductsHandler)
-	app.GET("/api/v1/products/search", searchProductsHandler)
+	app.GET("/api/v1/products/search", middleware.RequireFeature("advancedSearch")(lift.HandlerFunc(searchProductsHandler)))
 	app.GET("/api/v1/products/:id", getProductHandler)
 	app.PUT("/api/v1/products/:id/inventory", updateInventoryHandler)
 
@@ -640,6 +815,10 @@ func setupAPIRoutes(app *lift.App) {
 	app.GET("/api/v1/customers", listCustomersHandler)
 	app.GET("/api/v1/customers/:id", getCustomerHandler)
 	app.POST("/api/v1/customers/auth", authenticateCustomerHandler)
+	app.POST("/api/v1/customers/auth/refresh", customerAuth.RefreshHandler())
+	app.POST("/api/v1/customers/auth/logout", customerAuth.LogoutHandler())
+	app.POST("/api/v1/customers/password/forgot", customerAuth.ForgotPasswordHandler(sendPasswordReset))
+	app.POST("/api/v1/customers/password/reset", customerAuth.ResetPasswordHandler())
 	app.GET("/api/v1/customers/:id/orders", getCustomerOrdersHandler)
 
 	// Order management endpoints (tenant-scoped)
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// TaxRequest describes the amount a TaxProvider calculates tax on
+type TaxRequest struct {
+	TenantID string
+	Currency string
+	// Amount is the taxable amount: the subtotal after discounts
+	Amount float64
+	ShipTo *Address
+}
+
+// TaxQuote is the tax a TaxProvider charges
+type TaxQuote struct {
+	Rate   float64
+	Amount float64
+	// Jurisdiction names the taxing authority, e.g. "US-CA"
+	Jurisdiction string
+}
+
+// TaxProvider calculates sales tax. A production deployment would call a
+// tax service (Avalara, TaxJar, Stripe Tax) here.
+type TaxProvider interface {
+	Tax(ctx context.Context, req TaxRequest) (TaxQuote, error)
+}
+
+// FlatRateTax charges the same rate everywhere
+type FlatRateTax struct {
+	Rate float64
+}
+
+// Tax implements TaxProvider
+func (f FlatRateTax) Tax(ctx context.Context, req TaxRequest) (TaxQuote, error) {
+	return TaxQuote{Rate: f.Rate, Amount: req.Amount * f.Rate}, nil
+}
+
+// RegionalTax charges a rate per country or "country-state" region, e.g.
+// "US-CA", falling back to Default
+type RegionalTax struct {
+	Rates   map[string]float64
+	Default float64
+}
+
+// Tax implements TaxProvider
+func (r RegionalTax) Tax(ctx context.Context, req TaxRequest) (TaxQuote, error) {
+	if req.ShipTo != nil {
+		country := strings.ToUpper(req.ShipTo.Country)
+		for _, region := range []string{country + "-" + strings.ToUpper(req.ShipTo.State), country} {
+			if rate, ok := r.Rates[region]; ok {
+				return TaxQuote{Rate: rate, Amount: req.Amount * rate, Jurisdiction: region}, nil
+			}
+		}
+	}
+	return TaxQuote{Rate: r.Default, Amount: req.Amount * r.Default}, nil
+}
+
+// PriceLine is one line item to price
+type PriceLine struct {
+	ItemID    string
+	ProductID string
+	Quantity  int
+	UnitPrice Money
+}
+
+// PricingRequest is a cart or order to price
+type PricingRequest struct {
+	TenantID    string
+	Currency    string
+	Lines       []PriceLine
+	CouponCodes []string
+	ShipTo      *Address
+}
+
+// DiscountRule decides which discounts apply to a request. Each returned
+// adjustment's amount is taken off the subtotal.
+type DiscountRule interface {
+	Discount(ctx context.Context, req PricingRequest, subtotal float64) ([]PriceAdjustment, error)
+}
+
+// Coupon is a discount a customer redeems with a code
+type Coupon struct {
+	Code        string
+	Description string
+	// PercentOff takes a percentage off the subtotal, e.g. 10 for 10%
+	PercentOff float64
+	// AmountOff takes a fixed amount off the subtotal
+	AmountOff float64
+	// MinSubtotal is the subtotal the order must reach for the coupon to apply
+	MinSubtotal float64
+	ExpiresAt   time.Time
+}
+
+// Coupons is a DiscountRule for the coupons a request names. Unknown,
+// expired or ineligible codes fail the request so the customer knows why
+// their code didn't apply.
+type Coupons map[string]Coupon
+
+// Discount implements DiscountRule
+func (c Coupons) Discount(ctx context.Context, req PricingRequest, subtotal float64) ([]PriceAdjustment, error) {
+	var discounts []PriceAdjustment
+	for _, code := range req.CouponCodes {
+		coupon, ok := c[strings.ToUpper(code)]
+		if !ok {
+			return nil, lift.NewLiftError("INVALID_COUPON", fmt.Sprintf("Coupon %s is not valid", code), 400)
+		}
+		if !coupon.ExpiresAt.IsZero() && time.Now().After(coupon.ExpiresAt) {
+			return nil, lift.NewLiftError("COUPON_EXPIRED", fmt.Sprintf("Coupon %s has expired", code), 400)
+		}
+		if subtotal < coupon.MinSubtotal {
+			return nil, lift.NewLiftError("COUPON_NOT_ELIGIBLE",
+				fmt.Sprintf("Coupon %s requires a subtotal of at least %.2f", code, coupon.MinSubtotal), 400)
+		}
+
+		discounts = append(discounts, PriceAdjustment{
+			Type:        AdjustmentDiscount,
+			Code:        coupon.Code,
+			Description: coupon.Description,
+			Amount:      Money{Amount: subtotal*coupon.PercentOff/100 + coupon.AmountOff},
+		})
+	}
+	return discounts, nil
+}
+
+// VolumeDiscount takes PercentOff off orders of at least MinQuantity items
+type VolumeDiscount struct {
+	MinQuantity int
+	PercentOff  float64
+}
+
+// Discount implements DiscountRule
+func (v VolumeDiscount) Discount(ctx context.Context, req PricingRequest, subtotal float64) ([]PriceAdjustment, error) {
+	quantity := 0
+	for _, line := range req.Lines {
+		quantity += line.Quantity
+	}
+	if quantity < v.MinQuantity {
+		return nil, nil
+	}
+	return []PriceAdjustment{{
+		Type:        AdjustmentDiscount,
+		Code:        "VOLUME",
+		Description: fmt.Sprintf("%g%% off %d or more items", v.PercentOff, v.MinQuantity),
+		Amount:      Money{Amount: subtotal * v.PercentOff / 100},
+	}}, nil
+}
+
+// Adjustment types in a price breakdown
+const (
+	AdjustmentDiscount = "discount"
+	AdjustmentShipping = "shipping"
+	AdjustmentTax      = "tax"
+)
+
+// PriceAdjustment is one itemized change to the subtotal
+type PriceAdjustment struct {
+	Type        string `json:"type"`
+	Code        string `json:"code,omitempty"`
+	Description string `json:"description,omitempty"`
+	Amount      Money  `json:"amount"`
+}
+
+// ShippingRates charges a flat rate below a free shipping threshold
+type ShippingRates struct {
+	FlatRate float64
+	// FreeOverSubtotal is the discounted subtotal from which shipping is free
+	FreeOverSubtotal float64
+}
+
+// PricingConfig configures a PricingEngine
+type PricingConfig struct {
+	// Currency is used when a request doesn't name one (default: USD)
+	Currency string
+	Tax      TaxProvider
+	// Discounts apply in order, each to the subtotal
+	Discounts []DiscountRule
+	Shipping  ShippingRates
+}
+
+// DefaultPricingConfig returns the storefront's standard pricing: 8% tax
+// and $9.99 shipping on orders under $50
+func DefaultPricingConfig() PricingConfig {
+	return PricingConfig{
+		Currency: "USD",
+		Tax:      FlatRateTax{Rate: 0.08},
+		Discounts: []DiscountRule{
+			Coupons{
+				"WELCOME10": {Code: "WELCOME10", Description: "10% off your first order", PercentOff: 10},
+				"SAVE20":    {Code: "SAVE20", Description: "$20 off orders over $100", AmountOff: 20, MinSubtotal: 100},
+			},
+		},
+		Shipping: ShippingRates{FlatRate: 9.99, FreeOverSubtotal: 50},
+	}
+}
+
+// PricingEngine turns line items into itemized totals: subtotal, then
+// discounts, then shipping, then tax on the discounted subtotal. Every
+// amount is rounded to the currency's minor unit as it is calculated, so
+// the breakdown always adds up to the total.
+type PricingEngine struct {
+	config PricingConfig
+}
+
+// NewPricingEngine creates a pricing engine
+func NewPricingEngine(config PricingConfig) *PricingEngine {
+	if config.Currency == "" {
+		config.Currency = "USD"
+	}
+	if config.Tax == nil {
+		config.Tax = FlatRateTax{}
+	}
+	return &PricingEngine{config: config}
+}
+
+// PriceBreakdown is the itemized result of pricing a request
+type PriceBreakdown struct {
+	Subtotal    Money             `json:"subtotal"`
+	Discount    Money             `json:"discount"`
+	Shipping    Money             `json:"shipping"`
+	Tax         Money             `json:"tax"`
+	Total       Money             `json:"total"`
+	TaxRate     float64           `json:"taxRate"`
+	ItemCount   int               `json:"itemCount"`
+	Adjustments []PriceAdjustment `json:"adjustments,omitempty"`
+}
+
+// Price calculates the totals for a request
+func (e *PricingEngine) Price(ctx context.Context, req PricingRequest) (*PriceBreakdown, error) {
+	currency := req.Currency
+	if currency == "" {
+		currency = e.config.Currency
+	}
+	money := func(amount float64) Money {
+		return Money{Amount: roundMoney(amount, currency), Currency: currency}
+	}
+
+	breakdown := &PriceBreakdown{}
+	var subtotal float64
+	for _, line := range req.Lines {
+		if line.UnitPrice.Currency != "" && !strings.EqualFold(line.UnitPrice.Currency, currency) {
+			return nil, lift.NewLiftError("CURRENCY_MISMATCH",
+				fmt.Sprintf("Item %s is priced in %s, not %s", line.ProductID, line.UnitPrice.Currency, currency), 400)
+		}
+		subtotal += roundMoney(line.UnitPrice.Amount*float64(line.Quantity), currency)
+		breakdown.ItemCount += line.Quantity
+	}
+	breakdown.Subtotal = money(subtotal)
+
+	// Discounts never take the subtotal below zero
+	var discount float64
+	for _, rule := range e.config.Discounts {
+		adjustments, err := rule.Discount(ctx, req, breakdown.Subtotal.Amount)
+		if err != nil {
+			return nil, err
+		}
+		for _, adjustment := range adjustments {
+			amount := math.Min(roundMoney(adjustment.Amount.Amount, currency), breakdown.Subtotal.Amount-discount)
+			if amount <= 0 {
+				continue
+			}
+			discount += amount
+			adjustment.Type = AdjustmentDiscount
+			adjustment.Amount = money(-amount)
+			breakdown.Adjustments = append(breakdown.Adjustments, adjustment)
+		}
+	}
+	breakdown.Discount = money(discount)
+	discounted := breakdown.Subtotal.Amount - breakdown.Discount.Amount
+
+	var shipping float64
+	if rates := e.config.Shipping; rates.FlatRate > 0 && discounted < rates.FreeOverSubtotal && breakdown.ItemCount > 0 {
+		shipping = rates.FlatRate
+		breakdown.Adjustments = append(breakdown.Adjustments, PriceAdjustment{
+			Type:        AdjustmentShipping,
+			Description: "Standard shipping",
+			Amount:      money(shipping),
+		})
+	}
+	breakdown.Shipping = money(shipping)
+
+	tax, err := e.config.Tax.Tax(ctx, TaxRequest{
+		TenantID: req.TenantID,
+		Currency: currency,
+		Amount:   discounted,
+		ShipTo:   req.ShipTo,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate tax: %w", err)
+	}
+	breakdown.Tax = money(tax.Amount)
+	breakdown.TaxRate = tax.Rate
+	if breakdown.Tax.Amount > 0 {
+		breakdown.Adjustments = append(breakdown.Adjustments, PriceAdjustment{
+			Type:        AdjustmentTax,
+			Code:        tax.Jurisdiction,
+			Description: fmt.Sprintf("Sales tax (%g%%)", tax.Rate*100),
+			Amount:      breakdown.Tax,
+		})
+	}
+
+	breakdown.Total = money(discounted + breakdown.Shipping.Amount + breakdown.Tax.Amount)
+	return breakdown, nil
+}
+
+// PriceCart prices a cart's items
+func (e *PricingEngine) PriceCart(ctx context.Context, tenantID string, items []CartItem, couponCodes []string) (CartTotals, error) {
+	lines := make([]PriceLine, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, PriceLine{ItemID: item.ID, ProductID: item.ProductID, Quantity: item.Quantity, UnitPrice: item.Price})
+	}
+
+	breakdown, err := e.Price(ctx, PricingRequest{TenantID: tenantID, Currency: linesCurrency(lines), Lines: lines, CouponCodes: couponCodes})
+	if err != nil {
+		return CartTotals{}, err
+	}
+	return CartTotals{
+		Subtotal:    breakdown.Subtotal,
+		Discount:    breakdown.Discount,
+		Tax:         breakdown.Tax,
+		Shipping:    breakdown.Shipping,
+		Total:       breakdown.Total,
+		ItemCount:   breakdown.ItemCount,
+		Adjustments: breakdown.Adjustments,
+	}, nil
+}
+
+// PriceOrder prices an order's items, shipped to shipTo
+func (e *PricingEngine) PriceOrder(ctx context.Context, tenantID string, items []OrderItem, couponCodes []string, shipTo *Address) (OrderTotals, error) {
+	lines := make([]PriceLine, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, PriceLine{ItemID: item.ID, ProductID: item.ProductID, Quantity: item.Quantity, UnitPrice: item.Price})
+	}
+
+	breakdown, err := e.Price(ctx, PricingRequest{TenantID: tenantID, Currency: linesCurrency(lines), Lines: lines, CouponCodes: couponCodes, ShipTo: shipTo})
+	if err != nil {
+		return OrderTotals{}, err
+	}
+	return OrderTotals{
+		Subtotal:    breakdown.Subtotal,
+		Tax:         breakdown.Tax,
+		Shipping:    breakdown.Shipping,
+		Discount:    breakdown.Discount,
+		Total:       breakdown.Total,
+		TaxRate:     breakdown.TaxRate,
+		Adjustments: breakdown.Adjustments,
+	}, nil
+}
+
+// linesCurrency returns the currency the lines are priced in, or "" to
+// use the engine's default
+func linesCurrency(lines []PriceLine) string {
+	for _, line := range lines {
+		if line.UnitPrice.Currency != "" {
+			return line.UnitPrice.Currency
+		}
+	}
+	return ""
+}
+
+// currencyDecimals lists ISO 4217 currencies whose minor unit isn't cents
+var currencyDecimals = map[string]int{
+	"JPY": 0, "KRW": 0, "VND": 0, "CLP": 0, "ISK": 0,
+	"BHD": 3, "JOD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
+
+// roundMoney rounds an amount half away from zero to the currency's minor unit
+func roundMoney(amount float64, currency string) float64 {
+	decimals, ok := currencyDecimals[strings.ToUpper(currency)]
+	if !ok {
+		decimals = 2
+	}
+	scale := math.Pow(10, float64(decimals))
+	// Nudge by a fraction of a minor unit so binary representation errors,
+	// e.g. 1.005 stored as 1.00499..., still round up
+	return math.Round(amount*scale+math.Copysign(1e-7, amount)) / scale
+}
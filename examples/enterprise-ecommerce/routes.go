@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
+
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/metering"
 )
 
 // setupRoutes configures all the API routes for the e-commerce platform
@@ -32,6 +35,10 @@ func setupRoutes(app *lift.App) {
 	customers.GET("", listCustomersHandler)
 	customers.GET("/:id", getCustomerHandler)
 	customers.POST("/auth", authenticateCustomerHandler)
+	customers.POST("/auth/refresh", customerAuth.RefreshHandler())
+	customers.POST("/auth/logout", customerAuth.LogoutHandler())
+	customers.POST("/password/forgot", customerAuth.ForgotPasswordHandler(sendPasswordReset))
+	customers.POST("/password/reset", customerAuth.ResetPasswordHandler())
 	customers.GET("/:id/orders", getCustomerOrdersHandler)
 
 	// Order management endpoints (tenant-scoped)
@@ -272,14 +279,14 @@ func authenticateCustomerHandler(ctx *lift.Context) error {
 		return lift.NewLiftError("BAD_REQUEST", "Invalid request", 400)
 	}
 
-	customer, err := customerService.AuthenticateCustomer(ctx.Context, tenantID, req.Email, req.Password)
+	customer, session, err := customerService.AuthenticateCustomer(ctx.Context, tenantID, req.Email, req.Password)
 	if err != nil {
-		return lift.Unauthorized("Invalid credentials")
+		return authError(err)
 	}
 
 	return ctx.JSON(map[string]any{
 		"customer": customer,
-		"token":    "jwt_token_here",
+		"session":  session,
 	})
 }
 
@@ -293,12 +300,30 @@ func createOrderHandler(ctx *lift.Context) error {
 	if err := ctx.ParseRequest(&req); err != nil {
 		return lift.NewLiftError("BAD_REQUEST", "Invalid request", 400)
 	}
+	req.IdempotencyKey = ctx.Header("Idempotency-Key")
 
 	order, err := orderService.CreateOrder(ctx.Context, tenantID, req)
 	if err != nil {
+		// Pricing rejects invalid coupons and currencies with client errors
+		var liftErr *lift.LiftError
+		if errors.As(err, &liftErr) {
+			return liftErr
+		}
 		return lift.NewLiftError("INTERNAL_ERROR", "Failed to create order", 500)
 	}
 
+	// Orders are billed per order placed; the order number keeps a retried
+	// request from being billed twice
+	if _, err := usageEmitter.Record(ctx.Context, metering.UsageRecord{
+		ID:       "order#" + order.OrderNumber,
+		TenantID: tenantID,
+		Metric:   "orders",
+		Quantity: 1,
+		Unit:     "count",
+	}); err != nil {
+		return lift.NewLiftError("INTERNAL_ERROR", "Failed to record order usage", 500).WithCause(err)
+	}
+
 	return ctx.Status(201).JSON(order)
 }
 
@@ -478,6 +503,10 @@ func checkoutHandler(ctx *lift.Context) error {
 
 	order, err := cartService.ConvertCartToOrder(ctx.Context, tenantID, cartID, req)
 	if err != nil {
+		var liftErr *lift.LiftError
+		if errors.As(err, &liftErr) {
+			return liftErr
+		}
 		return lift.NewLiftError("INTERNAL_ERROR", "Failed to checkout", 500)
 	}
 
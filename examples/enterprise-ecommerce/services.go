@@ -4,6 +4,9 @@ import (
 	"context"
 	"strings"
 	"time"
+
+	"github.com/pay-theory/lift/pkg/auth"
+	"github.com/pay-theory/lift/pkg/lift/id"
 )
 
 // Mock service implementations for the e-commerce platform
@@ -13,7 +16,7 @@ type mockTenantService struct{}
 
 func (m *mockTenantService) CreateTenant(ctx context.Context, req CreateTenantRequest) (*Tenant, error) {
 	tenant := &Tenant{
-		ID:     generateID(),
+		ID:     id.New("ten"),
 		Name:   req.Name,
 		Domain: req.Domain,
 		Configuration: TenantConfig{
@@ -149,7 +152,7 @@ type mockProductService struct{}
 
 func (m *mockProductService) CreateProduct(ctx context.Context, tenantID string, req CreateProductRequest) (*Product, error) {
 	product := &Product{
-		ID:          generateID(),
+		ID:          id.New("prod"),
 		TenantID:    tenantID,
 		SKU:         req.SKU,
 		Name:        req.Name,
@@ -310,11 +313,14 @@ func (m *mockProductService) UpdateInventory(ctx context.Context, tenantID, prod
 }
 
 // Mock Customer Service
-type mockCustomerService struct{}
+type mockCustomerService struct {
+	credentials *auth.MemoryCredentialStore
+	auth        *auth.Service
+}
 
 func (m *mockCustomerService) CreateCustomer(ctx context.Context, tenantID string, req CreateCustomerRequest) (*Customer, error) {
 	customer := &Customer{
-		ID:             generateID(),
+		ID:             id.New("cust"),
 		TenantID:       tenantID,
 		Email:          req.Email,
 		Profile:        req.Profile,
@@ -329,6 +335,20 @@ func (m *mockCustomerService) CreateCustomer(ctx context.Context, tenantID strin
 		Tags:           []string{"new_customer"},
 	}
 
+	if req.Password != "" {
+		hash, err := m.auth.HashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		m.credentials.Put(auth.Credential{
+			TenantID:     tenantID,
+			UserID:       customer.ID,
+			Email:        customer.Email,
+			PasswordHash: hash,
+			Roles:        []string{"customer"},
+		})
+	}
+
 	return customer, nil
 }
 
@@ -417,22 +437,41 @@ func (m *mockCustomerService) ListCustomers(ctx context.Context, tenantID string
 	return customers, nil
 }
 
-func (m *mockCustomerService) AuthenticateCustomer(ctx context.Context, tenantID, email, password string) (*Customer, error) {
-	// Simulate authentication
-	return m.GetCustomer(ctx, tenantID, "customer_auth")
+func (m *mockCustomerService) AuthenticateCustomer(ctx context.Context, tenantID, email, password string) (*Customer, *auth.Session, error) {
+	session, err := m.auth.Login(ctx, tenantID, email, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	customer, err := m.GetCustomer(ctx, tenantID, session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	customer.Email = strings.ToLower(email)
+	customer.LastLoginAt = time.Now()
+	return customer, session, nil
 }
 
 // Mock Order Service
-type mockOrderService struct{}
+type mockOrderService struct {
+	pricing *PricingEngine
+}
 
 func (m *mockOrderService) CreateOrder(ctx context.Context, tenantID string, req CreateOrderRequest) (*Order, error) {
-	totals := calculateOrderTotals(req.Items)
+	totals, err := m.pricing.PriceOrder(ctx, tenantID, req.Items, req.CouponCodes, &req.Shipping.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	orderNumber, err := generateOrderNumber(ctx, tenantID, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
 
 	order := &Order{
-		ID:          generateID(),
+		ID:          id.New("ord"),
 		TenantID:    tenantID,
 		CustomerID:  req.CustomerID,
-		OrderNumber: generateOrderNumber(),
+		OrderNumber: orderNumber,
 		Items:       req.Items,
 		Totals:      totals,
 		Payment:     req.Payment,
@@ -545,7 +584,9 @@ func (m *mockOrderService) RefundOrder(ctx context.Context, tenantID, id string,
 }
 
 // Mock Cart Service
-type mockCartService struct{}
+type mockCartService struct {
+	pricing *PricingEngine
+}
 
 func (m *mockCartService) GetCart(ctx context.Context, tenantID, customerID string) (*ShoppingCart, error) {
 	items := []CartItem{
@@ -559,18 +600,21 @@ func (m *mockCartService) GetCart(ctx context.Context, tenantID, customerID stri
 		},
 	}
 
-	totals := calculateCartTotals(items)
+	totals, err := m.pricing.PriceCart(ctx, tenantID, items, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	return &ShoppingCart{
-		ID:         generateID(),
+	cart := &ShoppingCart{
+		ID:         id.New("cart"),
 		TenantID:   tenantID,
 		CustomerID: customerID,
 		Items:      items,
 		Totals:     totals,
 		CreatedAt:  time.Now().Add(-30 * time.Minute),
-		UpdatedAt:  time.Now().Add(-5 * time.Minute),
-		ExpiresAt:  time.Now().Add(24 * time.Hour),
-	}, nil
+	}
+	cart.Touch(time.Now().Add(-5 * time.Minute))
+	return cart, nil
 }
 
 func (m *mockCartService) AddToCart(ctx context.Context, tenantID, customerID string, req AddToCartRequest) (*ShoppingCart, error) {
@@ -593,6 +637,6 @@ func (m *mockCartService) ClearCart(ctx context.Context, tenantID, cartID string
 }
 
 func (m *mockCartService) ConvertCartToOrder(ctx context.Context, tenantID, cartID string, orderReq CreateOrderRequest) (*Order, error) {
-	orderService := &mockOrderService{}
+	orderService := &mockOrderService{pricing: m.pricing}
 	return orderService.CreateOrder(ctx, tenantID, orderReq)
 }
@@ -12,86 +12,14 @@ import (
 	"log"
 	"time"
 
+	"github.com/pay-theory/lift/pkg/address"
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/id"
+	"github.com/pay-theory/lift/pkg/middleware"
 )
 
 // Add missing middleware functions
 
-// Recovery middleware function
-func Recovery() lift.Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			defer func() {
-				if r := recover(); r != nil {
-					if ctx.Logger != nil {
-						ctx.Logger.Error("Handler panicked", map[string]any{
-							"panic": r,
-						})
-					}
-					ctx.SystemError("Internal server error", fmt.Errorf("panic: %v", r))
-				}
-			}()
-			return next.Handle(ctx)
-		})
-	}
-}
-
-// CORS configuration
-type CORSConfig struct {
-	AllowOrigins []string
-	AllowMethods []string
-	AllowHeaders []string
-}
-
-// CORS middleware function
-func CORS(config CORSConfig) lift.Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			origin := ctx.Header("Origin")
-
-			// Check if origin is allowed
-			allowed := false
-			for _, allowedOrigin := range config.AllowOrigins {
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					allowed = true
-					break
-				}
-			}
-
-			if allowed {
-				ctx.Response.Header("Access-Control-Allow-Origin", origin)
-				if len(config.AllowMethods) > 0 {
-					methods := ""
-					for i, method := range config.AllowMethods {
-						if i > 0 {
-							methods += ", "
-						}
-						methods += method
-					}
-					ctx.Response.Header("Access-Control-Allow-Methods", methods)
-				}
-				if len(config.AllowHeaders) > 0 {
-					headers := ""
-					for i, header := range config.AllowHeaders {
-						if i > 0 {
-							headers += ", "
-						}
-						headers += header
-					}
-					ctx.Response.Header("Access-Control-Allow-Headers", headers)
-				}
-			}
-
-			if ctx.Request.Method == "OPTIONS" {
-				ctx.Response.StatusCode = 204
-				return nil
-			}
-
-			return next.Handle(ctx)
-		})
-	}
-}
-
 // Logger middleware function
 func Logger() lift.Middleware {
 	return func(next lift.Handler) lift.Handler {
@@ -184,6 +112,25 @@ type Address struct {
 	Country string `json:"country"`
 }
 
+// Postal returns the address in the form used by the address package
+func (a Address) Postal() address.Address {
+	return address.Address{
+		Line1:      a.Street1,
+		Line2:      a.Street2,
+		City:       a.City,
+		Region:     a.State,
+		PostalCode: a.ZipCode,
+		Country:    a.Country,
+	}
+}
+
+// SetPostal copies a normalized address back into the model
+func (a *Address) SetPostal(postal address.Address) {
+	a.Street1, a.Street2 = postal.Line1, postal.Line2
+	a.City, a.State = postal.City, postal.Region
+	a.ZipCode, a.Country = postal.PostalCode, postal.Country
+}
+
 // PrivacySettings controls patient privacy preferences
 type PrivacySettings struct {
 	AllowResearch       bool     `json:"allowResearch"`
@@ -423,7 +370,7 @@ func (e *mockEncryptionService) GenerateKey() ([]byte, error) {
 // Mock service implementations
 func (m *mockPatientService) CreatePatient(ctx context.Context, req CreatePatientRequest) (*Patient, error) {
 	patient := &Patient{
-		ID:              generateID(),
+		ID:              id.New("pat"),
 		MRN:             generateMRN(),
 		Demographics:    req.Demographics,
 		PrivacySettings: req.PrivacySettings,
@@ -532,7 +479,7 @@ func (m *mockMedicalRecordService) CreateRecord(ctx context.Context, req CreateM
 	}
 
 	record := &MedicalRecord{
-		ID:            generateID(),
+		ID:            id.New("rec"),
 		PatientID:     req.PatientID,
 		ProviderID:    providerID,
 		RecordType:    req.RecordType,
@@ -623,7 +570,7 @@ func (m *mockMedicalRecordService) DeleteRecord(ctx context.Context, id string,
 
 func (m *mockProviderService) CreateProvider(ctx context.Context, req CreateProviderRequest) (*Provider, error) {
 	provider := &Provider{
-		ID:            generateID(),
+		ID:            id.New("prov"),
 		NPI:           req.NPI,
 		FirstName:     req.FirstName,
 		LastName:      req.LastName,
@@ -765,10 +712,6 @@ func (m *mockComplianceService) DetectBreach(ctx context.Context, accessPattern
 }
 
 // Utility functions
-func generateID() string {
-	return fmt.Sprintf("id_%d", time.Now().UnixNano())
-}
-
 func generateMRN() string {
 	return fmt.Sprintf("MRN-%d", time.Now().UnixNano()%1000000)
 }
@@ -780,6 +723,13 @@ func createPatient(ctx *lift.Context) error {
 		return lift.NewLiftError("BAD_REQUEST", "Invalid request", 400)
 	}
 
+	// Normalize the address so patient matching compares like with like
+	normalized := address.Normalize(req.Demographics.Address.Postal())
+	if err := address.Validate(normalized); err != nil {
+		return ctx.BadRequest("Invalid address", err)
+	}
+	req.Demographics.Address.SetPostal(normalized)
+
 	// HIPAA compliance validation
 	complianceService := &mockComplianceService{}
 	if err := complianceService.ValidateHIPAACompliance(ctx.Request.Context(), "create_patient", req); err != nil {
@@ -1152,8 +1102,8 @@ func main() {
 
 	// Enterprise healthcare middleware stack
 	app.Use(Logger())
-	app.Use(Recovery())
-	app.Use(CORS(CORSConfig{
+	app.Use(middleware.Recovery(middleware.RecoveryOptions{}))
+	app.Use(middleware.CORS(middleware.CORSConfig{
 		AllowOrigins: []string{"https://healthcare.example.com"},
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE"},
 		AllowHeaders: []string{"Authorization", "Content-Type", "X-Provider-ID"},
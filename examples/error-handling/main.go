@@ -13,14 +13,19 @@ func main() {
 	// Register routes with error handling
 	if err := app.GET("/health", func(ctx *lift.Context) error {
 		return ctx.JSON(map[string]string{"status": "healthy"})
-	}); err != nil {
+	}).Err(); err != nil {
 		log.Fatalf("Failed to register GET /health: %v", err)
 	}
 
-	// Register a route with an invalid handler to demonstrate error handling
-	if err := app.POST("/invalid", "this is not a valid handler"); err != nil {
+	// Register a route with an invalid handler to demonstrate error handling.
+	// A separate app is used because Start fails for an app with bad routes.
+	demo := lift.New()
+	if err := demo.POST("/invalid", "this is not a valid handler").Err(); err != nil {
 		log.Printf("Expected error: %v", err)
 	}
+	if err := demo.Start(); err != nil {
+		log.Printf("Expected start error: %v", err)
+	}
 
 	// Register event handlers with error handling
 	if err := app.SQS("my-queue", func(ctx *lift.Context) error {
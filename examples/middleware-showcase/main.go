@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/middleware"
 	"github.com/pay-theory/lift/pkg/security"
 )
 
@@ -49,41 +51,30 @@ func LoggingMiddleware() lift.Middleware {
 	}
 }
 
-// AuthenticationMiddleware demonstrates JWT token validation
+// AuthenticationMiddleware validates JWTs against the keys published by
+// the Cognito user pool or Auth0 tenant at JWKS_URL. The token's subject
+// and tenant become ctx.UserID() and ctx.TenantID().
 func AuthenticationMiddleware() lift.Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			authHeader := ctx.Header("Authorization")
-			
-			if authHeader == "" {
-				return ctx.Status(401).JSON(map[string]string{
-					"error": "Missing authorization header",
-				})
-			}
-			
-			// Extract Bearer token
-			if !strings.HasPrefix(authHeader, "Bearer ") {
-				return ctx.Status(401).JSON(map[string]string{
-					"error": "Invalid authorization format",
-				})
-			}
-			
-			token := strings.TrimPrefix(authHeader, "Bearer ")
-			
-			// Simulate token validation
-			if token != "valid-token-123" {
-				return ctx.Status(401).JSON(map[string]string{
-					"error": "Invalid token",
-				})
-			}
-			
-			// Set user context
-			ctx.Set("user_id", "user123")
-			ctx.Set("user_email", "user@example.com")
-			
-			return next.Handle(ctx)
-		})
+	jwksURL := os.Getenv("JWKS_URL")
+	if jwksURL == "" {
+		jwksURL = "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_example/.well-known/jwks.json"
 	}
+
+	return middleware.JWT(security.JWTConfig{
+		JWKSURL:       jwksURL,
+		Issuer:        os.Getenv("JWT_ISSUER"),
+		TenantIDClaim: "custom:tenant_id",
+		ClaimValidators: []func(claims map[string]any) error{
+			// Cognito issues ID and access tokens from the same keys;
+			// only access tokens authorize API calls
+			func(claims map[string]any) error {
+				if use, ok := claims["token_use"]; ok && use != "access" {
+					return fmt.Errorf("token_use %v is not access", use)
+				}
+				return nil
+			},
+		},
+	})
 }
 
 // RateLimitingMiddleware demonstrates simple rate limiting
@@ -179,31 +170,6 @@ func ValidationMiddleware() lift.Middleware {
 	}
 }
 
-// RecoveryMiddleware demonstrates panic recovery
-func RecoveryMiddleware() lift.Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			defer func() {
-				if r := recover(); r != nil {
-					if ctx.Logger != nil {
-						ctx.Logger.Error("Panic recovered", map[string]any{
-							"panic": r,
-							"path": ctx.Request.Path,
-							"method": ctx.Request.Method,
-						})
-					}
-					
-					ctx.Status(500).JSON(map[string]string{
-						"error": "Internal server error",
-					})
-				}
-			}()
-			
-			return next.Handle(ctx)
-		})
-	}
-}
-
 // TimeoutMiddleware demonstrates request timeout handling
 func TimeoutMiddleware(timeout time.Duration) lift.Middleware {
 	return func(next lift.Handler) lift.Handler {
@@ -247,20 +213,6 @@ func CustomHeaderMiddleware() lift.Middleware {
 	}
 }
 
-// ConditionalMiddleware demonstrates conditional middleware application
-func ConditionalMiddleware(condition func(*lift.Context) bool, middleware lift.Middleware) lift.Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			if condition(ctx) {
-				// Apply middleware
-				return middleware(next).Handle(ctx)
-			}
-			// Skip middleware
-			return next.Handle(ctx)
-		})
-	}
-}
-
 // generateRequestID creates a simple request ID
 func generateRequestID() string {
 	return fmt.Sprintf("req_%d", time.Now().UnixNano())
@@ -281,15 +233,14 @@ func main() {
 	app := lift.New()
 	
 	// Global middleware (applied to all routes)
-	app.Use(RecoveryMiddleware())           // Always recover from panics
+	app.Use(middleware.Recovery(middleware.RecoveryOptions{})) // Always recover from panics
 	app.Use(LoggingMiddleware())            // Log all requests
 	app.Use(CORSMiddleware())               // Enable CORS
 	app.Use(CustomHeaderMiddleware())       // Add custom headers
 	app.Use(TimeoutMiddleware(30 * time.Second)) // 30 second timeout
 	
 	// Public routes (no authentication required)
-	public := app.Group("/public")
-	// Note: RouteGroup doesn't support Use() method directly - apply middleware at app level
+	public := app.Group("/public", RateLimitingMiddleware(60))
 	
 	public.GET("/health", func(ctx *lift.Context) error {
 		return ctx.JSON(map[string]string{
@@ -313,16 +264,13 @@ func main() {
 	})
 	
 	// API routes with authentication and validation
-	api := app.Group("/api")
-	// Note: RouteGroup doesn't support Use() method directly - apply middleware at app level
+	api := app.Group("/api", AuthenticationMiddleware())
+	api.Use(ValidationMiddleware())
 	
 	api.GET("/profile", func(ctx *lift.Context) error {
-		userID := ctx.Get("user_id").(string)
-		userEmail := ctx.Get("user_email").(string)
-		
 		return ctx.JSON(map[string]any{
-			"user_id": userID,
-			"email": userEmail,
+			"user_id": ctx.UserID(),
+			"tenant_id": ctx.TenantID(),
 			"profile": map[string]any{
 				"name": "John Doe",
 				"role": "user",
@@ -352,20 +300,18 @@ func main() {
 		return ctx.JSON(map[string]any{
 			"message": "Protected resource accessed",
 			"api_version": apiVersion,
-			"user_id": ctx.Get("user_id"),
+			"user_id": ctx.UserID(),
 		})
 	})
 	
 	// Admin routes with conditional middleware
-	admin := app.Group("/admin")
-	// Note: RouteGroup doesn't support Use() method directly - apply middleware at app level
-	// Admin routes would need authentication applied at the app level
+	admin := api.Group("/admin")
 	
 	admin.GET("/stats", func(ctx *lift.Context) error {
 		return ctx.JSON(map[string]any{
 			"message": "Admin stats",
 			"timestamp": time.Now().Format(time.RFC3339),
-			"user_id": ctx.Get("user_id"),
+			"user_id": ctx.UserID(),
 		})
 	})
 	
@@ -389,10 +335,10 @@ func main() {
 	fmt.Println("Try these endpoints:")
 	fmt.Println("  GET /public/health")
 	fmt.Println("  POST /public/feedback")
-	fmt.Println("  GET /api/profile (requires Authorization: Bearer valid-token-123)")
+	fmt.Println("  GET /api/profile (requires Authorization: Bearer <JWT from JWKS_URL issuer>)")
 	fmt.Println("  POST /api/data (requires auth + JSON)")
 	fmt.Println("  GET /api/protected (requires auth + X-API-Version header)")
-	fmt.Println("  GET /admin/stats (requires auth)")
+	fmt.Println("  GET /api/admin/stats (requires auth)")
 	fmt.Println("  GET /panic (demonstrates recovery)")
 	fmt.Println("  GET /slow (demonstrates timeout)")
 }
\ No newline at end of file
@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
@@ -109,6 +108,12 @@ type UpdateTaskRequest struct {
 	DueDate     *time.Time `json:"due_date,omitempty"`
 }
 
+// PageQuery is the pagination query string accepted by list endpoints
+type PageQuery struct {
+	Page    int `query:"page" default:"1" validate:"min=1"`
+	PerPage int `query:"per_page" default:"10" validate:"min=1,max=100"`
+}
+
 // PaginatedResponse represents a paginated response
 type PaginatedResponse struct {
 	Data       any `json:"data"`
@@ -459,15 +464,11 @@ func (h *UserHandlers) ListUsers(ctx *lift.Context) error {
 		return lift.NewLiftError("BAD_REQUEST", "Tenant ID is required", 400)
 	}
 
-	page, _ := strconv.Atoi(ctx.Query("page"))
-	if page < 1 {
-		page = 1
-	}
-
-	perPage, _ := strconv.Atoi(ctx.Query("per_page"))
-	if perPage < 1 || perPage > 100 {
-		perPage = 10
+	var query PageQuery
+	if err := ctx.BindQuery(&query); err != nil {
+		return err
 	}
+	page, perPage := query.Page, query.PerPage
 
 	users, total, err := h.service.GetUsersByTenant(ctx.Context, tenantID, page, perPage)
 	if err != nil {
@@ -550,15 +551,11 @@ func (h *ProjectHandlers) ListProjects(ctx *lift.Context) error {
 		return lift.NewLiftError("BAD_REQUEST", "Tenant ID is required", 400)
 	}
 
-	page, _ := strconv.Atoi(ctx.Query("page"))
-	if page < 1 {
-		page = 1
-	}
-
-	perPage, _ := strconv.Atoi(ctx.Query("per_page"))
-	if perPage < 1 || perPage > 100 {
-		perPage = 10
+	var query PageQuery
+	if err := ctx.BindQuery(&query); err != nil {
+		return err
 	}
+	page, perPage := query.Page, query.PerPage
 
 	projects, total, err := h.service.GetProjectsByTenant(ctx.Context, tenantID, page, perPage)
 	if err != nil {
@@ -677,15 +674,11 @@ func (h *TaskHandlers) ListTasks(ctx *lift.Context) error {
 		return lift.NewLiftError("BAD_REQUEST", "Project ID is required", 400)
 	}
 
-	page, _ := strconv.Atoi(ctx.Query("page"))
-	if page < 1 {
-		page = 1
-	}
-
-	perPage, _ := strconv.Atoi(ctx.Query("per_page"))
-	if perPage < 1 || perPage > 100 {
-		perPage = 10
+	var query PageQuery
+	if err := ctx.BindQuery(&query); err != nil {
+		return err
 	}
+	page, perPage := query.Page, query.PerPage
 
 	tasks, total, err := h.service.GetTasksByProject(ctx.Context, tenantID, projectID, page, perPage)
 	if err != nil {
@@ -244,6 +244,7 @@ type ProductionAPI struct {
 	userService      *UserService
 	healthManager    health.HealthManager
 	healthEndpoints  *health.HealthEndpoints
+	healthCache      *health.CachedHealth
 	healthMiddleware *health.HealthMiddleware
 	resourceManager  *resources.ResourceManager
 }
@@ -369,6 +370,11 @@ func NewProductionAPI() *ProductionAPI {
 	// 3. Setup Health Endpoints
 	endpointsConfig := health.DefaultHealthEndpointsConfig()
 	endpointsConfig.EnableDetailedErrors = true // Enable for demo
+
+	// Load balancer probes hit /health every few seconds; serve them from a
+	// cache refreshed in the background instead of running every check
+	healthCache := health.NewCachedHealth(healthManager, health.DefaultCachedHealthConfig())
+	endpointsConfig.Cache = healthCache
 	healthEndpoints := health.NewHealthEndpoints(healthManager, endpointsConfig)
 
 	// 4. Setup Health Middleware
@@ -382,6 +388,7 @@ func NewProductionAPI() *ProductionAPI {
 		userService:      userService,
 		healthManager:    healthManager,
 		healthEndpoints:  healthEndpoints,
+		healthCache:      healthCache,
 		healthMiddleware: healthMiddleware,
 		resourceManager:  resourceManager,
 	}
@@ -792,12 +799,16 @@ func main() {
 	fmt.Printf("Database Pool: %d active, %d idle, %d total connections\n",
 		poolStats.Active, poolStats.Idle, poolStats.Total)
 
+	// Keep the cached /health status fresh
+	api.healthCache.Start(ctx)
+	defer api.healthCache.Stop()
+
 	// Start server
 	port := ":8080"
 	fmt.Printf("\n🌐 Starting Production API on http://localhost%s\n", port)
 	fmt.Println("\nAPI Endpoints:")
 	fmt.Printf("  • http://localhost%s/           - API documentation\n", port)
-	fmt.Printf("  • http://localhost%s/health     - Health status\n", port)
+	fmt.Printf("  • http://localhost%s/health     - Health status (cached)\n", port)
 	fmt.Printf("  • http://localhost%s/api/users  - User management\n", port)
 	fmt.Printf("  • http://localhost%s/metrics    - Performance metrics\n", port)
 	fmt.Printf("  • http://localhost%s/status     - Service status\n", port)
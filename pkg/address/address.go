@@ -0,0 +1,196 @@
+// Package address normalizes postal addresses, validates postal codes by
+// country and verifies addresses with an external provider. Models with
+// their own address shape convert to Address at the edges:
+//
+//	normalized := address.Normalize(req.Shipping.Address.Postal())
+//	if err := address.Validate(normalized); err != nil {
+//		return ctx.BadRequest("Invalid shipping address", err)
+//	}
+package address
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pay-theory/lift/pkg/validation"
+)
+
+// Address is a postal address
+type Address struct {
+	Line1 string `json:"line1"`
+	Line2 string `json:"line2,omitempty"`
+	City  string `json:"city"`
+	// Region is the state, province or county
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code once normalized
+	Country string `json:"country"`
+}
+
+// Normalize returns the address with whitespace collapsed, the country as
+// an ISO 3166-1 alpha-2 code, US and Canadian regions as their postal
+// abbreviations and the postal code in its country's canonical format.
+// Values Normalize doesn't recognize are kept as given, so Validate can
+// report them.
+func Normalize(a Address) Address {
+	a.Line1 = collapse(a.Line1)
+	a.Line2 = collapse(a.Line2)
+	a.City = collapse(a.City)
+	a.Region = collapse(a.Region)
+	a.Country = NormalizeCountry(a.Country)
+	a.PostalCode = NormalizePostalCode(a.Country, a.PostalCode)
+
+	if regions, ok := regionCodes[a.Country]; ok {
+		if code, ok := regions[strings.ToUpper(a.Region)]; ok {
+			a.Region = code
+		}
+	}
+	return a
+}
+
+// NormalizeCountry returns the ISO 3166-1 alpha-2 code for a code or
+// common name, e.g. "USA" or "United Kingdom"
+func NormalizeCountry(country string) string {
+	country = strings.ToUpper(collapse(country))
+	if code, ok := countryAliases[country]; ok {
+		return code
+	}
+	return country
+}
+
+// Validate checks that the address has a street, city and country, that
+// US and Canadian addresses have a valid state or province, and that the
+// postal code is valid for the country. Normalize the address first.
+func Validate(a Address) error {
+	var errs validation.ValidationErrors
+	required := func(field, value string) {
+		if value == "" {
+			errs = append(errs, validation.ValidationError{Field: field, Message: "is required", Tag: "required"})
+		}
+	}
+	required("line1", a.Line1)
+	required("city", a.City)
+	required("country", a.Country)
+
+	if a.Country != "" && !isCountryCode(a.Country) {
+		errs = append(errs, validation.ValidationError{Field: "country", Message: "must be an ISO 3166-1 alpha-2 code", Tag: "country", Value: a.Country})
+	}
+
+	if regions, ok := regionCodes[a.Country]; ok {
+		required("region", a.Region)
+		if a.Region != "" && regions[a.Region] != a.Region {
+			errs = append(errs, validation.ValidationError{Field: "region", Message: "is not a valid region of " + a.Country, Tag: "region", Value: a.Region})
+		}
+	}
+
+	if format, ok := postalFormats[a.Country]; ok {
+		required("postal_code", a.PostalCode)
+		if a.PostalCode != "" && !format.pattern.MatchString(a.PostalCode) {
+			errs = append(errs, validation.ValidationError{Field: "postal_code", Message: postalCodeMessage(a.Country, format), Tag: "postal_code", Value: a.PostalCode})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// collapse trims a value and collapses runs of whitespace to one space
+func collapse(value string) string {
+	return strings.Join(strings.FieldsFunc(value, unicode.IsSpace), " ")
+}
+
+func isCountryCode(country string) bool {
+	if len(country) != 2 {
+		return false
+	}
+	for _, r := range country {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// countryAliases maps common names and alpha-3 codes to alpha-2 codes
+var countryAliases = map[string]string{
+	"USA":                      "US",
+	"U.S.":                     "US",
+	"U.S.A.":                   "US",
+	"UNITED STATES":            "US",
+	"UNITED STATES OF AMERICA": "US",
+	"CAN":                      "CA",
+	"CANADA":                   "CA",
+	"GBR":                      "GB",
+	"UK":                       "GB",
+	"UNITED KINGDOM":           "GB",
+	"GREAT BRITAIN":            "GB",
+	"ENGLAND":                  "GB",
+	"SCOTLAND":                 "GB",
+	"WALES":                    "GB",
+	"NORTHERN IRELAND":         "GB",
+	"IRL":                      "IE",
+	"IRELAND":                  "IE",
+	"DEU":                      "DE",
+	"GERMANY":                  "DE",
+	"DEUTSCHLAND":              "DE",
+	"FRA":                      "FR",
+	"FRANCE":                   "FR",
+	"ESP":                      "ES",
+	"SPAIN":                    "ES",
+	"ITA":                      "IT",
+	"ITALY":                    "IT",
+	"NLD":                      "NL",
+	"NETHERLANDS":              "NL",
+	"THE NETHERLANDS":          "NL",
+	"AUS":                      "AU",
+	"AUSTRALIA":                "AU",
+	"JPN":                      "JP",
+	"JAPAN":                    "JP",
+	"IND":                      "IN",
+	"INDIA":                    "IN",
+	"BRA":                      "BR",
+	"BRAZIL":                   "BR",
+	"MEX":                      "MX",
+	"MEXICO":                   "MX",
+}
+
+// regionCodes maps region names and codes to postal abbreviations for the
+// countries whose addresses require one
+var regionCodes = map[string]map[string]string{
+	"US": withCodes(map[string]string{
+		"ALABAMA": "AL", "ALASKA": "AK", "ARIZONA": "AZ", "ARKANSAS": "AR",
+		"CALIFORNIA": "CA", "COLORADO": "CO", "CONNECTICUT": "CT", "DELAWARE": "DE",
+		"DISTRICT OF COLUMBIA": "DC", "FLORIDA": "FL", "GEORGIA": "GA", "HAWAII": "HI",
+		"IDAHO": "ID", "ILLINOIS": "IL", "INDIANA": "IN", "IOWA": "IA",
+		"KANSAS": "KS", "KENTUCKY": "KY", "LOUISIANA": "LA", "MAINE": "ME",
+		"MARYLAND": "MD", "MASSACHUSETTS": "MA", "MICHIGAN": "MI", "MINNESOTA": "MN",
+		"MISSISSIPPI": "MS", "MISSOURI": "MO", "MONTANA": "MT", "NEBRASKA": "NE",
+		"NEVADA": "NV", "NEW HAMPSHIRE": "NH", "NEW JERSEY": "NJ", "NEW MEXICO": "NM",
+		"NEW YORK": "NY", "NORTH CAROLINA": "NC", "NORTH DAKOTA": "ND", "OHIO": "OH",
+		"OKLAHOMA": "OK", "OREGON": "OR", "PENNSYLVANIA": "PA", "RHODE ISLAND": "RI",
+		"SOUTH CAROLINA": "SC", "SOUTH DAKOTA": "SD", "TENNESSEE": "TN", "TEXAS": "TX",
+		"UTAH": "UT", "VERMONT": "VT", "VIRGINIA": "VA", "WASHINGTON": "WA",
+		"WEST VIRGINIA": "WV", "WISCONSIN": "WI", "WYOMING": "WY",
+		"AMERICAN SAMOA": "AS", "GUAM": "GU", "NORTHERN MARIANA ISLANDS": "MP",
+		"PUERTO RICO": "PR", "U.S. VIRGIN ISLANDS": "VI",
+		"ARMED FORCES AMERICAS": "AA", "ARMED FORCES EUROPE": "AE", "ARMED FORCES PACIFIC": "AP",
+	}),
+	"CA": withCodes(map[string]string{
+		"ALBERTA": "AB", "BRITISH COLUMBIA": "BC", "MANITOBA": "MB", "NEW BRUNSWICK": "NB",
+		"NEWFOUNDLAND AND LABRADOR": "NL", "NORTHWEST TERRITORIES": "NT", "NOVA SCOTIA": "NS",
+		"NUNAVUT": "NU", "ONTARIO": "ON", "PRINCE EDWARD ISLAND": "PE", "QUEBEC": "QC",
+		"QUÉBEC": "QC", "SASKATCHEWAN": "SK", "YUKON": "YT",
+	}),
+}
+
+// withCodes adds each abbreviation as a key for itself
+func withCodes(names map[string]string) map[string]string {
+	codes := make(map[string]string, len(names)*2)
+	for name, code := range names {
+		codes[name] = code
+		codes[code] = code
+	}
+	return codes
+}
@@ -0,0 +1,119 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	normalized := Normalize(Address{
+		Line1:      "  123   Main St ",
+		City:       "Springfield\t",
+		Region:     "illinois",
+		PostalCode: " 627011234",
+		Country:    "United States",
+	})
+	assert.Equal(t, Address{
+		Line1:      "123 Main St",
+		City:       "Springfield",
+		Region:     "IL",
+		PostalCode: "62701-1234",
+		Country:    "US",
+	}, normalized)
+	assert.NoError(t, Validate(normalized))
+}
+
+func TestNormalizePostalCode(t *testing.T) {
+	for _, tt := range []struct {
+		country, code, want string
+	}{
+		{"US", "62701", "62701"},
+		{"US", "62701 1234", "62701-1234"},
+		{"CA", "k1a0b1", "K1A 0B1"},
+		{"GB", "sw1a1aa", "SW1A 1AA"},
+		{"GB", "M1 1AE", "M1 1AE"},
+		{"IE", "d02x285", "D02 X285"},
+		{"NL", "1012ab", "1012 AB"},
+		{"JP", "1000001", "100-0001"},
+		{"BR", "01310100", "01310-100"},
+		{"US", "6270", "6270"},
+		{"ZZ", " ab-12 ", "AB-12"},
+	} {
+		t.Run(tt.country+" "+tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizePostalCode(tt.country, tt.code))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("reports each problem by field", func(t *testing.T) {
+		err := Validate(Normalize(Address{City: "Toronto", Region: "Atlantis", PostalCode: "12345", Country: "Canada"}))
+
+		var errs validation.ValidationErrors
+		require.ErrorAs(t, err, &errs)
+		fields := make([]string, len(errs))
+		for i, e := range errs {
+			fields[i] = e.Field
+		}
+		assert.Equal(t, []string{"line1", "region", "postal_code"}, fields)
+		assert.Contains(t, errs[2].Message, "K1A 0B1")
+	})
+
+	t.Run("accepts countries without known formats", func(t *testing.T) {
+		assert.NoError(t, Validate(Normalize(Address{Line1: "1 Rue", City: "Luxembourg", Country: "lu"})))
+	})
+
+	t.Run("rejects unknown countries", func(t *testing.T) {
+		assert.Error(t, Validate(Normalize(Address{Line1: "1 Main", City: "Nowhere", Country: "Atlantis"})))
+	})
+
+	assert.ErrorIs(t, ValidatePostalCode("US", "ABCDE"), ErrInvalidPostalCode)
+	assert.NoError(t, ValidatePostalCode("DE", "10115"))
+}
+
+func TestVerifier(t *testing.T) {
+	valid := Address{Line1: "1600 Pennsylvania Ave NW", City: "Washington", Region: "DC", PostalCode: "20500", Country: "US"}
+
+	t.Run("reports corrections", func(t *testing.T) {
+		verifier := NewVerifier(VerifierConfig{Provider: ProviderFunc(func(ctx context.Context, a Address) (*Verification, error) {
+			a.PostalCode = "20500-0003"
+			return &Verification{Status: StatusVerified, Address: a}, nil
+		})})
+
+		result, err := verifier.Verify(context.Background(), valid)
+		require.NoError(t, err)
+		assert.Equal(t, StatusCorrected, result.Status)
+		assert.Equal(t, "20500-0003", result.Address.PostalCode)
+	})
+
+	t.Run("skips the provider for invalid addresses", func(t *testing.T) {
+		verifier := NewVerifier(VerifierConfig{Provider: ProviderFunc(func(ctx context.Context, a Address) (*Verification, error) {
+			t.Fatal("provider should not be called")
+			return nil, nil
+		})})
+
+		result, err := verifier.Verify(context.Background(), Address{Country: "US"})
+		require.Error(t, err)
+		assert.Equal(t, StatusInvalid, result.Status)
+	})
+
+	t.Run("fails open when the provider is down", func(t *testing.T) {
+		var reported error
+		verifier := NewVerifier(VerifierConfig{
+			Provider: ProviderFunc(func(ctx context.Context, a Address) (*Verification, error) {
+				return nil, errors.New("503 from provider")
+			}),
+			OnError: func(err error) { reported = err },
+		})
+
+		result, err := verifier.Verify(context.Background(), valid)
+		require.NoError(t, err)
+		assert.Equal(t, StatusUnverified, result.Status)
+		assert.Error(t, reported)
+	})
+}
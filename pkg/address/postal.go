@@ -0,0 +1,92 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidPostalCode is returned by ValidatePostalCode
+var ErrInvalidPostalCode = errors.New("invalid postal code")
+
+// postalFormat describes a country's postal codes
+type postalFormat struct {
+	pattern *regexp.Regexp
+	example string
+	// format rewrites a compacted code (upper case, without spaces or
+	// dashes) in the country's canonical form
+	format func(compact string) string
+}
+
+var postalFormats = map[string]postalFormat{
+	"US": {regexp.MustCompile(`^\d{5}(-\d{4})?$`), "12345 or 12345-6789", splitAfter(5, "-")},
+	"CA": {regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] \d[ABCEGHJ-NPRSTV-Z]\d$`), "K1A 0B1", splitAfter(3, " ")},
+	"GB": {regexp.MustCompile(`^(GIR 0AA|[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2})$`), "SW1A 1AA", splitBeforeLast(3, " ")},
+	"IE": {regexp.MustCompile(`^[AC-FHKNPRTV-Y]\d[\dW] [\dAC-FHKNPRTV-Y]{4}$`), "D02 X285", splitAfter(3, " ")},
+	"NL": {regexp.MustCompile(`^[1-9]\d{3} [A-Z]{2}$`), "1012 AB", splitAfter(4, " ")},
+	"DE": {regexp.MustCompile(`^\d{5}$`), "10115", nil},
+	"FR": {regexp.MustCompile(`^\d{5}$`), "75008", nil},
+	"ES": {regexp.MustCompile(`^\d{5}$`), "28013", nil},
+	"IT": {regexp.MustCompile(`^\d{5}$`), "00184", nil},
+	"MX": {regexp.MustCompile(`^\d{5}$`), "06600", nil},
+	"AU": {regexp.MustCompile(`^\d{4}$`), "2000", nil},
+	"IN": {regexp.MustCompile(`^[1-9]\d{5}$`), "110001", nil},
+	"JP": {regexp.MustCompile(`^\d{3}-\d{4}$`), "100-0001", splitAfter(3, "-")},
+	"BR": {regexp.MustCompile(`^\d{5}-\d{3}$`), "01310-100", splitAfter(5, "-")},
+}
+
+// NormalizePostalCode returns code in the canonical format of country (an
+// alpha-2 code), e.g. "k1a0b1" becomes "K1A 0B1" for CA. Codes of
+// countries without a known format are trimmed and upper-cased.
+func NormalizePostalCode(country, code string) string {
+	code = strings.ToUpper(collapse(code))
+	format, ok := postalFormats[country]
+	if !ok || code == "" {
+		return code
+	}
+
+	compact := strings.NewReplacer(" ", "", "-", "").Replace(code)
+	if format.format != nil {
+		compact = format.format(compact)
+	}
+	if format.pattern.MatchString(compact) {
+		return compact
+	}
+	// Keep what was given so the error shows the caller's input
+	return code
+}
+
+// ValidatePostalCode checks code against the format of country (an alpha-2
+// code). Countries without a known format accept any code.
+func ValidatePostalCode(country, code string) error {
+	format, ok := postalFormats[country]
+	if !ok || format.pattern.MatchString(code) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidPostalCode, postalCodeMessage(country, format))
+}
+
+func postalCodeMessage(country string, format postalFormat) string {
+	return fmt.Sprintf("is not a valid %s postal code (e.g. %s)", country, format.example)
+}
+
+// splitAfter inserts sep after the first n characters of longer codes
+func splitAfter(n int, sep string) func(string) string {
+	return func(compact string) string {
+		if len(compact) <= n {
+			return compact
+		}
+		return compact[:n] + sep + compact[n:]
+	}
+}
+
+// splitBeforeLast inserts sep before the last n characters of longer codes
+func splitBeforeLast(n int, sep string) func(string) string {
+	return func(compact string) string {
+		if len(compact) <= n {
+			return compact
+		}
+		return compact[:len(compact)-n] + sep + compact[len(compact)-n:]
+	}
+}
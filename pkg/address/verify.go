@@ -0,0 +1,110 @@
+package address
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Status is the outcome of verifying an address
+type Status string
+
+const (
+	// StatusVerified means the provider confirmed the address as given
+	StatusVerified Status = "verified"
+	// StatusCorrected means the provider matched the address after
+	// correcting it; use the returned address
+	StatusCorrected Status = "corrected"
+	// StatusUnverified means no provider was available to check the address
+	StatusUnverified Status = "unverified"
+	// StatusInvalid means the address failed validation or the provider
+	// couldn't match it
+	StatusInvalid Status = "invalid"
+)
+
+// Verification is the result of verifying an address
+type Verification struct {
+	Status Status `json:"status"`
+	// Address is the normalized address, or the provider's standardized one
+	Address Address `json:"address"`
+	// Messages explain corrections and failures
+	Messages []string `json:"messages,omitempty"`
+}
+
+// Provider verifies addresses with an external service such as a postal
+// authority or commercial verification API. Verify receives a normalized,
+// valid address and returns an error only when the service can't be
+// reached; addresses it can't match are reported as StatusInvalid.
+type Provider interface {
+	Verify(ctx context.Context, address Address) (*Verification, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface
+type ProviderFunc func(ctx context.Context, address Address) (*Verification, error)
+
+// Verify calls f
+func (f ProviderFunc) Verify(ctx context.Context, address Address) (*Verification, error) {
+	return f(ctx, address)
+}
+
+// VerifierConfig configures a Verifier
+type VerifierConfig struct {
+	// Provider verifies valid addresses; nil reports them as StatusUnverified
+	Provider Provider
+	// Timeout bounds each provider call (default: 3s)
+	Timeout time.Duration
+	// OnError is called when the provider fails; the address is then
+	// reported as StatusUnverified so checkout isn't blocked by an outage
+	OnError func(err error)
+}
+
+// Verifier normalizes, validates and verifies addresses
+type Verifier struct {
+	config VerifierConfig
+}
+
+// NewVerifier creates a verifier
+func NewVerifier(config VerifierConfig) *Verifier {
+	if config.Timeout == 0 {
+		config.Timeout = 3 * time.Second
+	}
+	return &Verifier{config: config}
+}
+
+// Verify normalizes and validates the address, then checks it with the
+// provider. Addresses that fail validation are reported as StatusInvalid
+// along with the validation.ValidationErrors; the provider isn't called.
+func (v *Verifier) Verify(ctx context.Context, a Address) (*Verification, error) {
+	normalized := Normalize(a)
+	if err := Validate(normalized); err != nil {
+		return &Verification{Status: StatusInvalid, Address: normalized}, err
+	}
+	if v.config.Provider == nil {
+		return &Verification{Status: StatusUnverified, Address: normalized}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.config.Timeout)
+	defer cancel()
+
+	result, err := v.config.Provider.Verify(ctx, normalized)
+	if err != nil || result == nil {
+		if err == nil {
+			err = fmt.Errorf("provider returned no result")
+		}
+		if v.config.OnError != nil {
+			v.config.OnError(fmt.Errorf("address verification failed: %w", err))
+		}
+		return &Verification{Status: StatusUnverified, Address: normalized}, nil
+	}
+
+	verified := *result
+	if verified.Status == StatusInvalid {
+		verified.Address = normalized
+		return &verified, nil
+	}
+	verified.Address = Normalize(result.Address)
+	if verified.Status == StatusVerified && verified.Address != normalized {
+		verified.Status = StatusCorrected
+	}
+	return &verified, nil
+}
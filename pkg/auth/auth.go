@@ -0,0 +1,550 @@
+// Package auth authenticates customers with a password and keeps them
+// signed in with short-lived access tokens and rotating refresh tokens.
+// Passwords are hashed with Argon2id, repeated failed logins lock the
+// account out for a while, and logout, password changes and resets revoke
+// tokens through a security.Revocations list, so the access tokens it
+// issues are accepted by middleware.JWT with the same revocation list:
+//
+//	revocations, _ := security.NewRevocations(security.RevocationConfig{
+//		List:             security.NewDynamoDBRevocationList(client, "revocations"),
+//		MaxTokenLifetime: auth.DefaultRefreshTokenTTL,
+//	})
+//	service, _ := auth.NewService(auth.Config{
+//		Credentials: customers, // your CredentialStore
+//		Tokens:      auth.NewDynamoDBTokenStore(client, "auth-tokens"),
+//		Failures:    middleware.NewDynamoDBAuthFailureStore(client, "auth-failures"),
+//		Revocations: revocations,
+//		SigningKey:  []byte(secret),
+//		Issuer:      "https://shop.example.com",
+//	})
+//	app.POST("/auth/login", service.LoginHandler())
+//	app.Use(middleware.JWT(security.JWTConfig{SigningMethod: "HS256", SecretKey: secret, Revocation: revocations}))
+//
+// Customers can add a second factor, an authenticator app (TOTP) or a
+// security key or passkey (WebAuthn). Verifying it reissues the session's
+// tokens with the factor in their amr claim, which RequireMFA checks
+// against each tenant's MFAPolicy:
+//
+//	app.POST("/auth/mfa/totp/verify", service.VerifyTOTPHandler())
+//	admin := app.Group("/admin")
+//	admin.Use(service.RequireMFA())
+//
+// Support agents can act as a customer for a while with an impersonation
+// token, which names the agent in its act claim. Every request made with
+// it is audited with both identities by middleware.ImpersonationGuard:
+//
+//	app.POST("/support/impersonate", service.ImpersonateHandler()).Meta(lift.MetaRoles, "support")
+//	app.Use(middleware.ImpersonationGuard(impersonation))
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// DefaultRefreshTokenTTL is how long refresh tokens last by default.
+// Revocation entries must be kept at least this long, so set it as the
+// revocation list's MaxTokenLifetime.
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidCredentials is returned for an unknown email, a wrong
+	// password or a disabled account, without saying which
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrTooManyAttempts is returned while an account is locked out after
+	// repeated failed logins
+	ErrTooManyAttempts = errors.New("too many failed login attempts")
+	// ErrInvalidRefreshToken is returned for refresh tokens that are
+	// unknown, expired, revoked or already used
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrInvalidResetToken is returned for password reset tokens that are
+	// unknown, expired or already used
+	ErrInvalidResetToken = errors.New("invalid password reset token")
+	// ErrInvalidPassword is returned for new passwords that don't meet the
+	// length requirements
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrCredentialNotFound is returned by a CredentialStore for unknown
+	// customers
+	ErrCredentialNotFound = errors.New("credential not found")
+)
+
+// LockedError is returned while an account is locked out; it matches
+// ErrTooManyAttempts
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, locked until %s", ErrTooManyAttempts, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// Credential is a customer's login
+type Credential struct {
+	TenantID string `json:"tenant_id"`
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	// PasswordHash is an encoded Argon2id hash from HashPassword
+	PasswordHash string `json:"-"`
+	// Roles and Scopes are copied into access tokens
+	Roles  []string `json:"roles,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	// Disabled accounts can't log in or refresh
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// CredentialStore looks up and updates customers' credentials, usually
+// over the application's own customer table
+type CredentialStore interface {
+	// FindByEmail returns the credential for an email within a tenant, or
+	// ErrCredentialNotFound. Emails are passed lower-cased.
+	FindByEmail(ctx context.Context, tenantID, email string) (*Credential, error)
+	// Get returns a customer's credential, or ErrCredentialNotFound
+	Get(ctx context.Context, tenantID, userID string) (*Credential, error)
+	// SetPasswordHash replaces a customer's password hash
+	SetPasswordHash(ctx context.Context, tenantID, userID, hash string) error
+}
+
+// Config configures a Service
+type Config struct {
+	// Credentials looks up customers (required)
+	Credentials CredentialStore
+	// Tokens stores refresh and password reset tokens (default: in memory)
+	Tokens TokenStore
+	// Failures tracks failed logins (default: in memory)
+	Failures middleware.AuthFailureStore
+	// Revocations revokes tokens on logout, password change and reset
+	// (required). Pass the same list to middleware.JWT.
+	Revocations *security.Revocations
+
+	// SigningMethod signs access tokens: HS256, RS256 or ES256 (default: HS256)
+	SigningMethod string
+	// SigningKey is a []byte secret for HS256, or an *rsa.PrivateKey or
+	// *ecdsa.PrivateKey (required)
+	SigningKey any
+	// KeyID is set as the access tokens' kid header
+	KeyID    string
+	Issuer   string
+	Audience []string
+
+	// AccessTokenTTL is how long access tokens last (default: 15 minutes)
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is how long refresh tokens last (default: 30 days)
+	RefreshTokenTTL time.Duration
+	// ResetTokenTTL is how long password reset tokens last (default: 1 hour)
+	ResetTokenTTL time.Duration
+
+	// Password sets the Argon2id parameters for new hashes; older hashes
+	// are upgraded on the next successful login
+	Password PasswordParams
+	// MinPasswordLength is the shortest accepted password (default: 12)
+	MinPasswordLength int
+	// MaxPasswordLength bounds the work an attacker can cause per login
+	// (default: 128)
+	MaxPasswordLength int
+
+	// MaxFailedLogins is the number of failures within LockoutDuration
+	// that lock an account (default: 5)
+	MaxFailedLogins int
+	// LockoutDuration is how long an account stays locked (default: 15 minutes)
+	LockoutDuration time.Duration
+
+	// MFA stores customers' second factors (default: in memory)
+	MFA MFAStore
+	// MFAPolicies says which tenants' RequireMFA routes demand MFA
+	// (default: all of them)
+	MFAPolicies MFAPolicies
+	// TOTPIssuer names the service in authenticator apps (default: Issuer)
+	TOTPIssuer string
+	// WebAuthn configures the relying party for security keys and
+	// passkeys; WebAuthn is disabled without it
+	WebAuthn *webauthn.Config
+	// MFAChallengeTTL is how long WebAuthn challenges last (default: 5 minutes)
+	MFAChallengeTTL time.Duration
+
+	// Impersonation lets support agents act as customers with
+	// Impersonate; impersonation is disabled without it
+	Impersonation *security.Impersonation
+
+	// OnError is called with failures that don't fail the request, such
+	// as upgrading a password hash
+	OnError func(err error)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Service logs customers in and manages their tokens and passwords
+type Service struct {
+	config   Config
+	method   jwt.SigningMethod
+	webauthn *webauthn.WebAuthn
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+// NewService creates an authentication service
+func NewService(config Config) (*Service, error) {
+	if config.Credentials == nil {
+		return nil, fmt.Errorf("auth service requires a credential store")
+	}
+	if config.Revocations == nil {
+		return nil, fmt.Errorf("auth service requires a revocation list")
+	}
+	if config.SigningMethod == "" {
+		config.SigningMethod = "HS256"
+	}
+	method := jwt.GetSigningMethod(config.SigningMethod)
+	if method == nil || config.SigningMethod == "none" {
+		return nil, fmt.Errorf("unsupported signing method: %s", config.SigningMethod)
+	}
+	if config.SigningKey == nil {
+		return nil, fmt.Errorf("auth service requires a signing key")
+	}
+	if config.Tokens == nil {
+		config.Tokens = NewMemoryTokenStore()
+	}
+	if config.Failures == nil {
+		config.Failures = middleware.NewMemoryAuthFailureStore()
+	}
+	if config.AccessTokenTTL == 0 {
+		config.AccessTokenTTL = 15 * time.Minute
+	}
+	if config.RefreshTokenTTL == 0 {
+		config.RefreshTokenTTL = DefaultRefreshTokenTTL
+	}
+	if config.ResetTokenTTL == 0 {
+		config.ResetTokenTTL = time.Hour
+	}
+	config.Password = config.Password.withDefaults()
+	if config.MinPasswordLength == 0 {
+		config.MinPasswordLength = 12
+	}
+	if config.MaxPasswordLength == 0 {
+		config.MaxPasswordLength = 128
+	}
+	if config.MaxFailedLogins == 0 {
+		config.MaxFailedLogins = 5
+	}
+	if config.LockoutDuration == 0 {
+		config.LockoutDuration = 15 * time.Minute
+	}
+	if config.MFA == nil {
+		config.MFA = NewMemoryMFAStore()
+	}
+	if config.MFAPolicies == nil {
+		config.MFAPolicies = StaticMFAPolicies{"*": {Required: true}}
+	}
+	if config.MFAChallengeTTL == 0 {
+		config.MFAChallengeTTL = 5 * time.Minute
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	service := &Service{config: config, method: method}
+	if config.WebAuthn != nil {
+		relyingParty, err := webauthn.New(config.WebAuthn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WebAuthn config: %w", err)
+		}
+		service.webauthn = relyingParty
+	}
+	return service, nil
+}
+
+// Login checks a customer's email and password and starts a session.
+// Unknown emails take as long as wrong passwords, and every failure
+// counts towards the account's lockout.
+func (s *Service) Login(ctx context.Context, tenantID, email, password string) (*Session, error) {
+	email = normalizeEmail(email)
+	key := failureKey(tenantID, email)
+	now := s.config.Now()
+
+	record, err := s.config.Failures.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check login attempts: %w", err)
+	}
+	if record != nil && record.IsLocked(now) {
+		return nil, &LockedError{Until: record.LockedUntil}
+	}
+	if len(password) > s.config.MaxPasswordLength {
+		return nil, s.recordFailure(ctx, key, now, ErrInvalidCredentials)
+	}
+
+	credential, err := s.config.Credentials.FindByEmail(ctx, tenantID, email)
+	if err != nil && !errors.Is(err, ErrCredentialNotFound) {
+		return nil, fmt.Errorf("failed to find credential: %w", err)
+	}
+
+	hash := s.dummy()
+	if credential != nil {
+		hash = credential.PasswordHash
+	}
+	ok, err := VerifyPassword(password, hash)
+	if err != nil && credential != nil {
+		s.reportError(fmt.Errorf("stored password hash for %s is invalid: %w", credential.UserID, err))
+	}
+	if credential == nil || !ok || credential.Disabled {
+		return nil, s.recordFailure(ctx, key, now, ErrInvalidCredentials)
+	}
+
+	if err := s.config.Failures.Reset(ctx, key); err != nil {
+		s.reportError(fmt.Errorf("failed to reset login attempts: %w", err))
+	}
+	if NeedsRehash(credential.PasswordHash, s.config.Password) {
+		if upgraded, err := HashPassword(password, s.config.Password); err == nil {
+			if err := s.config.Credentials.SetPasswordHash(ctx, tenantID, credential.UserID, upgraded); err != nil {
+				s.reportError(fmt.Errorf("failed to upgrade password hash: %w", err))
+			}
+		}
+	}
+	return s.startSession(ctx, credential, newTokenID("ses"), passwordAuth(now), now)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token.
+// Each refresh token can be used once; presenting one again means it was
+// stolen, so the whole session is revoked.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*Session, error) {
+	record, err := s.refreshRecord(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	now := s.config.Now()
+
+	if err := s.config.Tokens.UseRefreshToken(ctx, record.ID, now); err != nil {
+		if errors.Is(err, ErrTokenReused) {
+			if err := s.config.Revocations.RevokeSession(ctx, record.SessionID); err != nil {
+				return nil, fmt.Errorf("failed to revoke session: %w", err)
+			}
+			return nil, fmt.Errorf("%w: token reused, session revoked", ErrInvalidRefreshToken)
+		}
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+
+	credential, err := s.config.Credentials.Get(ctx, record.TenantID, record.UserID)
+	if errors.Is(err, ErrCredentialNotFound) || (err == nil && credential.Disabled) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+	auth := sessionAuth{Time: record.AuthTime, AMR: record.AMR, ACR: record.ACR}
+	if len(auth.AMR) == 0 {
+		auth.AMR = []string{MethodPassword}
+	}
+	return s.startSession(ctx, credential, record.SessionID, auth, now)
+}
+
+// Logout revokes the session of a refresh token, including its access
+// tokens
+func (s *Service) Logout(ctx context.Context, refreshToken string) error {
+	record, err := s.refreshRecord(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	return s.config.Revocations.RevokeSession(ctx, record.SessionID)
+}
+
+// LogoutEverywhere revokes every session of a customer
+func (s *Service) LogoutEverywhere(ctx context.Context, tenantID, userID string) error {
+	return s.config.Revocations.RevokeUser(ctx, tenantID, userID)
+}
+
+// ChangePassword replaces a signed-in customer's password after checking
+// their current one, and revokes all their sessions
+func (s *Service) ChangePassword(ctx context.Context, tenantID, userID, current, password string) error {
+	credential, err := s.config.Credentials.Get(ctx, tenantID, userID)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return ErrInvalidCredentials
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get credential: %w", err)
+	}
+	if ok, _ := VerifyPassword(current, credential.PasswordHash); !ok {
+		return ErrInvalidCredentials
+	}
+	return s.SetPassword(ctx, tenantID, userID, password)
+}
+
+// SetPassword replaces a customer's password without checking the current
+// one, e.g. at sign-up or from an admin tool, and revokes all their
+// sessions
+func (s *Service) SetPassword(ctx context.Context, tenantID, userID, password string) error {
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.config.Credentials.SetPasswordHash(ctx, tenantID, userID, hash); err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+	return s.config.Revocations.RevokeUser(ctx, tenantID, userID)
+}
+
+// HashPassword checks a new password's length and hashes it with the
+// configured parameters, e.g. to store with a new customer
+func (s *Service) HashPassword(password string) (string, error) {
+	length := len([]rune(password))
+	if length < s.config.MinPasswordLength {
+		return "", fmt.Errorf("%w: must be at least %d characters", ErrInvalidPassword, s.config.MinPasswordLength)
+	}
+	if len(password) > s.config.MaxPasswordLength {
+		return "", fmt.Errorf("%w: must be at most %d bytes", ErrInvalidPassword, s.config.MaxPasswordLength)
+	}
+	return HashPassword(password, s.config.Password)
+}
+
+// recordFailure counts a failed login or MFA attempt, locking the account
+// once it reaches MaxFailedLogins, and returns failure
+func (s *Service) recordFailure(ctx context.Context, key string, now time.Time, failure error) error {
+	record, err := s.config.Failures.IncrementFailure(ctx, key, now.Add(s.config.LockoutDuration))
+	if err != nil {
+		s.reportError(fmt.Errorf("failed to record login failure: %w", err))
+		return failure
+	}
+	if record.Failures >= s.config.MaxFailedLogins {
+		until := now.Add(s.config.LockoutDuration)
+		if err := s.config.Failures.Lock(ctx, key, until); err != nil {
+			s.reportError(fmt.Errorf("failed to lock account: %w", err))
+		}
+	}
+	return failure
+}
+
+// refreshRecord returns the stored record of a valid refresh token
+func (s *Service) refreshRecord(ctx context.Context, token string) (*RefreshToken, error) {
+	tokenID, secret, ok := splitToken(token)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	record, err := s.config.Tokens.GetRefreshToken(ctx, tokenID)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if !secretMatches(secret, record.SecretHash) || !s.config.Now().Before(record.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	err = s.config.Revocations.Check(ctx, security.RevocationSubject{
+		SessionID: record.SessionID,
+		UserID:    record.UserID,
+		TenantID:  record.TenantID,
+		IssuedAt:  record.IssuedAt,
+	})
+	if errors.Is(err, security.ErrTokenRevoked) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// dummy returns a hash to verify against for unknown emails, so they
+// take as long as known ones
+func (s *Service) dummy() string {
+	s.dummyOnce.Do(func() {
+		s.dummyHash, _ = HashPassword(newSecret(), s.config.Password)
+	})
+	return s.dummyHash
+}
+
+func (s *Service) reportError(err error) {
+	if s.config.OnError != nil {
+		s.config.OnError(err)
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// failureKey identifies an account in the failure store without storing
+// its email
+func failureKey(tenantID, email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return "login:" + tenantID + ":" + hex.EncodeToString(sum[:])
+}
+
+// secretMatches compares a token secret with its stored hash
+func secretMatches(secret, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(hash)) == 1
+}
+
+// MemoryCredentialStore is an in-memory CredentialStore for tests and
+// local development
+type MemoryCredentialStore struct {
+	mu          sync.RWMutex
+	credentials map[string]Credential
+}
+
+// NewMemoryCredentialStore creates a store holding the given credentials
+func NewMemoryCredentialStore(credentials ...Credential) *MemoryCredentialStore {
+	store := &MemoryCredentialStore{credentials: make(map[string]Credential)}
+	for _, credential := range credentials {
+		store.Put(credential)
+	}
+	return store
+}
+
+// Put adds or replaces a credential
+func (m *MemoryCredentialStore) Put(credential Credential) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	credential.Email = normalizeEmail(credential.Email)
+	m.credentials[credential.TenantID+"#"+credential.UserID] = credential
+}
+
+// FindByEmail implements CredentialStore
+func (m *MemoryCredentialStore) FindByEmail(ctx context.Context, tenantID, email string) (*Credential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, credential := range m.credentials {
+		if credential.TenantID == tenantID && credential.Email == email {
+			return &credential, nil
+		}
+	}
+	return nil, ErrCredentialNotFound
+}
+
+// Get implements CredentialStore
+func (m *MemoryCredentialStore) Get(ctx context.Context, tenantID, userID string) (*Credential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	credential, ok := m.credentials[tenantID+"#"+userID]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	return &credential, nil
+}
+
+// SetPasswordHash implements CredentialStore
+func (m *MemoryCredentialStore) SetPasswordHash(ctx context.Context, tenantID, userID, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	credential, ok := m.credentials[tenantID+"#"+userID]
+	if !ok {
+		return ErrCredentialNotFound
+	}
+	credential.PasswordHash = hash
+	m.credentials[tenantID+"#"+userID] = credential
+	return nil
+}
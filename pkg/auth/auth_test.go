@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testParams keep hashing fast in tests
+var testParams = PasswordParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+const testSecret = "test-signing-secret-of-32-bytes!"
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestService(t *testing.T) (*Service, *MemoryCredentialStore, *security.Revocations, *testClock) {
+	t.Helper()
+	clock := &testClock{now: time.Now()}
+
+	hash, err := HashPassword("correct horse battery", testParams)
+	require.NoError(t, err)
+	credentials := NewMemoryCredentialStore(Credential{
+		TenantID:     "tenant-a",
+		UserID:       "cus_1",
+		Email:        "Ada@Example.com",
+		PasswordHash: hash,
+		Roles:        []string{"customer"},
+	})
+
+	revocations, err := security.NewRevocations(security.RevocationConfig{
+		List:             security.NewMemoryRevocationList(),
+		MaxTokenLifetime: DefaultRefreshTokenTTL,
+		Now:              clock.Now,
+	})
+	require.NoError(t, err)
+
+	service, err := NewService(Config{
+		Credentials: credentials,
+		Revocations: revocations,
+		SigningKey:  []byte(testSecret),
+		Issuer:      "https://shop.example.com",
+		Password:    testParams,
+		Now:         clock.Now,
+	})
+	require.NoError(t, err)
+	return service, credentials, revocations, clock
+}
+
+func TestPasswordHashing(t *testing.T) {
+	hash, err := HashPassword("correct horse battery", testParams)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	ok, err := VerifyPassword("correct horse battery", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = VerifyPassword("wrong password", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = VerifyPassword("anything", "$2a$10$bcrypt")
+	assert.ErrorIs(t, err, ErrInvalidHash)
+
+	assert.False(t, NeedsRehash(hash, testParams))
+	assert.True(t, NeedsRehash(hash, DefaultPasswordParams))
+}
+
+func TestLogin(t *testing.T) {
+	service, _, revocations, _ := newTestService(t)
+	ctx := context.Background()
+
+	session, err := service.Login(ctx, "tenant-a", " ada@example.com ", "correct horse battery")
+	require.NoError(t, err)
+	assert.Equal(t, "cus_1", session.UserID)
+	assert.Equal(t, "Bearer", session.TokenType)
+	assert.Equal(t, 900, session.ExpiresIn)
+
+	// The access token is accepted by the JWT middleware
+	validator, err := middleware.NewJWTValidator(security.JWTConfig{
+		SigningMethod: "HS256",
+		SecretKey:     testSecret,
+		Issuer:        "https://shop.example.com",
+		Revocation:    revocations,
+	})
+	require.NoError(t, err)
+	claims, err := validator.ValidateToken(session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "cus_1", claims.Subject)
+	assert.Equal(t, "tenant-a", claims.TenantID)
+	assert.Equal(t, session.SessionID, claims.SessionID)
+	assert.Equal(t, []string{"customer"}, claims.Roles)
+
+	_, err = service.Login(ctx, "tenant-b", "ada@example.com", "correct horse battery")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = service.Login(ctx, "tenant-a", "nobody@example.com", "correct horse battery")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLoginLockout(t *testing.T) {
+	service, _, _, clock := newTestService(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, err := service.Login(ctx, "tenant-a", "ada@example.com", "wrong password")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// Locked even with the right password
+	_, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	var locked *LockedError
+	require.ErrorAs(t, err, &locked)
+	assert.Equal(t, clock.now.Add(15*time.Minute), locked.Until)
+
+	clock.Advance(16 * time.Minute)
+	_, err = service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	assert.NoError(t, err)
+}
+
+func TestLoginUpgradesHash(t *testing.T) {
+	service, credentials, _, _ := newTestService(t)
+	service.config.Password = PasswordParams{Memory: 2048, Iterations: 1, Parallelism: 1}.withDefaults()
+
+	_, err := service.Login(context.Background(), "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+
+	credential, err := credentials.Get(context.Background(), "tenant-a", "cus_1")
+	require.NoError(t, err)
+	assert.Contains(t, credential.PasswordHash, "m=2048")
+}
+
+func TestRefresh(t *testing.T) {
+	service, _, revocations, clock := newTestService(t)
+	ctx := context.Background()
+
+	session, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+
+	clock.Advance(time.Minute)
+	refreshed, err := service.Refresh(ctx, session.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, session.SessionID, refreshed.SessionID)
+	assert.NotEqual(t, session.RefreshToken, refreshed.RefreshToken)
+
+	t.Run("rejects invalid tokens", func(t *testing.T) {
+		for _, token := range []string{"", "garbage", "rt_unknown.secret", strings.Split(refreshed.RefreshToken, ".")[0] + ".wrong"} {
+			_, err := service.Refresh(ctx, token)
+			assert.ErrorIs(t, err, ErrInvalidRefreshToken, token)
+		}
+	})
+
+	t.Run("reuse revokes the session", func(t *testing.T) {
+		clock.Advance(time.Minute)
+		_, err := service.Refresh(ctx, session.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+		_, err = service.Refresh(ctx, refreshed.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		err = revocations.Check(ctx, security.RevocationSubject{SessionID: session.SessionID, IssuedAt: clock.now.Add(-time.Second)})
+		assert.ErrorIs(t, err, security.ErrTokenRevoked)
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		session, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+		require.NoError(t, err)
+		clock.Advance(DefaultRefreshTokenTTL)
+		_, err = service.Refresh(ctx, session.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	service, _, _, clock := newTestService(t)
+	ctx := context.Background()
+
+	session, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+	other, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+
+	clock.Advance(time.Second)
+	require.NoError(t, service.Logout(ctx, session.RefreshToken))
+	_, err = service.Refresh(ctx, session.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Other sessions are unaffected until logging out everywhere
+	_, err = service.Refresh(ctx, other.RefreshToken)
+	require.NoError(t, err)
+
+	other, err = service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	require.NoError(t, service.LogoutEverywhere(ctx, "tenant-a", "cus_1"))
+	_, err = service.Refresh(ctx, other.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestPasswordReset(t *testing.T) {
+	service, _, _, clock := newTestService(t)
+	ctx := context.Background()
+
+	reset, err := service.RequestPasswordReset(ctx, "tenant-a", "nobody@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, reset)
+
+	session, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+
+	reset, err = service.RequestPasswordReset(ctx, "tenant-a", "ADA@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, reset)
+	assert.Equal(t, "ada@example.com", reset.Email)
+
+	// A rejected password doesn't use up the token
+	err = service.ResetPassword(ctx, reset.Token, "short")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	clock.Advance(time.Second)
+	require.NoError(t, service.ResetPassword(ctx, reset.Token, "a brand new passphrase"))
+	assert.ErrorIs(t, service.ResetPassword(ctx, reset.Token, "another new passphrase"), ErrInvalidResetToken)
+
+	// Existing sessions are revoked and only the new password works
+	_, err = service.Refresh(ctx, session.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	clock.Advance(time.Second)
+	_, err = service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = service.Login(ctx, "tenant-a", "ada@example.com", "a brand new passphrase")
+	assert.NoError(t, err)
+
+	t.Run("expires", func(t *testing.T) {
+		reset, err := service.RequestPasswordReset(ctx, "tenant-a", "ada@example.com")
+		require.NoError(t, err)
+		clock.Advance(2 * time.Hour)
+		assert.ErrorIs(t, service.ResetPassword(ctx, reset.Token, "yet another passphrase"), ErrInvalidResetToken)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	service, _, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	err := service.ChangePassword(ctx, "tenant-a", "cus_1", "wrong password", "a brand new passphrase")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	require.NoError(t, service.ChangePassword(ctx, "tenant-a", "cus_1", "correct horse battery", "a brand new passphrase"))
+
+	_, err = service.Login(ctx, "tenant-a", "ada@example.com", "a brand new passphrase")
+	assert.NoError(t, err)
+}
+
+func TestHandlers(t *testing.T) {
+	service, _, _, _ := newTestService(t)
+
+	call := func(handler lift.Handler, body string) (*lift.Context, error) {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  "POST",
+			Path:    "/auth",
+			Headers: map[string]string{"x-tenant-id": "tenant-a"},
+			Body:    []byte(body),
+		}))
+		return ctx, handler.Handle(ctx)
+	}
+
+	ctx, err := call(service.LoginHandler(), `{"email":"ada@example.com","password":"correct horse battery"}`)
+	require.NoError(t, err)
+	session := ctx.Response.Body.(*Session)
+
+	ctx, err = call(service.RefreshHandler(), `{"refresh_token":"`+session.RefreshToken+`"}`)
+	require.NoError(t, err)
+	session = ctx.Response.Body.(*Session)
+
+	ctx, err = call(service.LogoutHandler(), `{"refresh_token":"`+session.RefreshToken+`"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 204, ctx.Response.StatusCode)
+
+	var delivered *PasswordReset
+	forgot := service.ForgotPasswordHandler(func(ctx *lift.Context, reset *PasswordReset) error {
+		delivered = reset
+		return nil
+	})
+	ctx, err = call(forgot, `{"email":"nobody@example.com"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 202, ctx.Response.StatusCode)
+	assert.Nil(t, delivered)
+
+	_, err = call(forgot, `{"email":"ada@example.com"}`)
+	require.NoError(t, err)
+	require.NotNil(t, delivered)
+
+	_, err = call(service.ResetPasswordHandler(), `{"token":"`+delivered.Token+`","password":"short"}`)
+	var liftErr *lift.LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "INVALID_PASSWORD", liftErr.Code)
+
+	for i := 0; i < 5; i++ {
+		_, err = call(service.LoginHandler(), `{"email":"ada@example.com","password":"wrong password"}`)
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, 401, liftErr.StatusCode)
+	}
+	ctx, err = call(service.LoginHandler(), `{"email":"ada@example.com","password":"wrong password"}`)
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 429, liftErr.StatusCode)
+	assert.Equal(t, "901", ctx.Response.Headers["Retry-After"])
+}
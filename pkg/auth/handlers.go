@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// loginBody is the body accepted by LoginHandler
+type loginBody struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// refreshBody is the body accepted by RefreshHandler and LogoutHandler
+type refreshBody struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// forgotBody is the body accepted by ForgotPasswordHandler
+type forgotBody struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// resetBody is the body accepted by ResetPasswordHandler
+type resetBody struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// LoginHandler exchanges an email and password for a Session. The tenant
+// is ctx.TenantID() or, since customers aren't signed in yet, the
+// X-Tenant-ID header. Combine it with middleware.TarpitMiddleware and
+// middleware.TarpitByIP to also slow down guessing across accounts.
+func (s *Service) LoginHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var body loginBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		tenantID, err := tenantFor(ctx)
+		if err != nil {
+			return err
+		}
+
+		session, err := s.Login(ctx.Context, tenantID, body.Email, body.Password)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.OK(session)
+	})
+}
+
+// RefreshHandler exchanges a refresh token for a new Session
+func (s *Service) RefreshHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var body refreshBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		session, err := s.Refresh(ctx.Context, body.RefreshToken)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.OK(session)
+	})
+}
+
+// LogoutHandler revokes the session of a refresh token
+func (s *Service) LogoutHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var body refreshBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		if err := s.Logout(ctx.Context, body.RefreshToken); err != nil && !errors.Is(err, ErrInvalidRefreshToken) {
+			return s.authError(ctx, err)
+		}
+		ctx.Response.Status(204)
+		return nil
+	})
+}
+
+// ForgotPasswordHandler creates a reset token and passes it to deliver,
+// which sends it to the customer. It responds 202 whether or not the email
+// belongs to an account.
+func (s *Service) ForgotPasswordHandler(deliver func(ctx *lift.Context, reset *PasswordReset) error) lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var body forgotBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		tenantID, err := tenantFor(ctx)
+		if err != nil {
+			return err
+		}
+
+		reset, err := s.RequestPasswordReset(ctx.Context, tenantID, body.Email)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		if reset != nil {
+			if err := deliver(ctx, reset); err != nil {
+				return lift.NewLiftError("RESET_DELIVERY_FAILED", "Failed to send password reset", 500).WithCause(err)
+			}
+		}
+		return ctx.Status(202).JSON(map[string]any{"status": "accepted"})
+	})
+}
+
+// ResetPasswordHandler sets a new password with a reset token
+func (s *Service) ResetPasswordHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var body resetBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		if err := s.ResetPassword(ctx.Context, body.Token, body.Password); err != nil {
+			return s.authError(ctx, err)
+		}
+		ctx.Response.Status(204)
+		return nil
+	})
+}
+
+// impersonateBody is the body accepted by ImpersonateHandler
+type impersonateBody struct {
+	TenantID   string `json:"tenant_id" validate:"required"`
+	UserID     string `json:"user_id" validate:"required"`
+	ReasonCode string `json:"reason_code" validate:"required"`
+	Note       string `json:"note"`
+	Duration   string `json:"duration"`
+}
+
+// ImpersonateHandler issues the signed-in agent an ImpersonationSession for
+// a customer. The body names the customer, a reason code, and optionally a
+// note and a duration such as "30m":
+//
+//	{"tenant_id": "tenant-a", "user_id": "cus_1", "reason_code": "customer_request", "note": "ticket 4821"}
+//
+// Restrict who may impersonate with route metadata, e.g.
+// .Meta(lift.MetaRoles, "support"). Impersonation tokens can't be used to
+// impersonate someone else.
+func (s *Service) ImpersonateHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		if ctx.UserID() == "" {
+			return lift.Unauthorized("Authentication required")
+		}
+		if ctx.ActorID() != "" {
+			return lift.NewLiftError("IMPERSONATION_NOT_ALLOWED", "This action can't be taken while impersonating", 403)
+		}
+
+		var body impersonateBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		var duration time.Duration
+		if body.Duration != "" {
+			parsed, err := time.ParseDuration(body.Duration)
+			if err != nil {
+				return lift.NewLiftError("INVALID_DURATION", "Duration must be like \"30m\"", 400).WithCause(err)
+			}
+			duration = parsed
+		}
+
+		ip, _ := security.ExtractClientIP(ctx.Request.Headers, ctx.Request.RequestContext())
+		session, err := s.Impersonate(ctx.Context, security.ImpersonationRequest{
+			ActorID:       ctx.UserID(),
+			ActorTenantID: ctx.TenantID(),
+			TenantID:      body.TenantID,
+			UserID:        body.UserID,
+			ReasonCode:    body.ReasonCode,
+			Note:          body.Note,
+			Duration:      duration,
+			IPAddress:     ip,
+		})
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.Created(session)
+	})
+}
+
+// EndImpersonationHandler revokes the impersonation token it is called
+// with
+func (s *Service) EndImpersonationHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		principal, ok := ctx.Get("principal").(*security.Principal)
+		if !ok || principal == nil || !principal.IsImpersonated() {
+			return lift.NewLiftError("NOT_IMPERSONATING", "Request is not impersonated", 400)
+		}
+		if err := s.EndImpersonation(ctx.Context, principal.Actor.ImpersonationID); err != nil {
+			return s.authError(ctx, err)
+		}
+		ctx.Response.Status(204)
+		return nil
+	})
+}
+
+// tenantFor returns the tenant customers sign in to
+func tenantFor(ctx *lift.Context) (string, error) {
+	if tenantID := ctx.TenantID(); tenantID != "" {
+		return tenantID, nil
+	}
+	if tenantID := ctx.Request.GetHeader("X-Tenant-ID"); tenantID != "" {
+		return tenantID, nil
+	}
+	return "", lift.NewLiftError("TENANT_REQUIRED", "Tenant ID is required", 400)
+}
+
+// authError maps service errors to responses
+func (s *Service) authError(ctx *lift.Context, err error) error {
+	var locked *LockedError
+	switch {
+	case errors.As(err, &locked):
+		seconds := int(locked.Until.Sub(s.config.Now()).Seconds()) + 1
+		ctx.Response.Header("Retry-After", strconv.Itoa(seconds))
+		return lift.NewLiftError("TOO_MANY_ATTEMPTS", "Too many failed login attempts, please retry later", 429).
+			WithDetail("retry_after", seconds)
+	case errors.Is(err, ErrInvalidCredentials):
+		return lift.NewLiftError("INVALID_CREDENTIALS", "Invalid email or password", 401)
+	case errors.Is(err, ErrInvalidRefreshToken):
+		return lift.NewLiftError("INVALID_REFRESH_TOKEN", "Refresh token is invalid or expired", 401)
+	case errors.Is(err, ErrInvalidResetToken):
+		return lift.NewLiftError("INVALID_RESET_TOKEN", "Password reset token is invalid or expired", 400)
+	case errors.Is(err, ErrInvalidPassword):
+		return lift.NewLiftError("INVALID_PASSWORD", err.Error(), 400)
+	case errors.Is(err, ErrInvalidMFACode):
+		return lift.NewLiftError("INVALID_MFA_CODE", "Verification code is invalid", 401)
+	case errors.Is(err, ErrMFARequired):
+		return lift.NewLiftError("MFA_REQUIRED", "Verify with your existing factor first", 401)
+	case errors.Is(err, ErrMFANotEnrolled):
+		return lift.NewLiftError("MFA_NOT_ENROLLED", "Multi-factor authentication is not set up", 400)
+	case errors.Is(err, ErrMFAAlreadyEnrolled):
+		return lift.NewLiftError("MFA_ALREADY_ENROLLED", "Multi-factor authentication is already set up", 409)
+	case errors.Is(err, ErrInvalidMFAChallenge):
+		return lift.NewLiftError("INVALID_MFA_CHALLENGE", "Challenge is invalid or expired", 400)
+	case errors.Is(err, ErrInvalidWebAuthnResponse):
+		return lift.NewLiftError("INVALID_WEBAUTHN_RESPONSE", "Security key response could not be verified", 400)
+	case errors.Is(err, ErrWebAuthnNotConfigured):
+		return lift.NewLiftError("WEBAUTHN_UNAVAILABLE", "Security keys are not supported", 501)
+	case errors.Is(err, ErrImpersonationDisabled):
+		return lift.NewLiftError("IMPERSONATION_UNAVAILABLE", "Impersonation is not enabled", 501)
+	case errors.Is(err, security.ErrImpersonationReason), errors.Is(err, security.ErrImpersonationRequest):
+		return lift.NewLiftError("INVALID_IMPERSONATION_REQUEST", err.Error(), 400)
+	case errors.Is(err, ErrCredentialNotFound):
+		return lift.NewLiftError("USER_NOT_FOUND", "User not found", 404)
+	case errors.Is(err, security.ErrRevocationUnavailable):
+		return lift.NewLiftError("REVOCATION_UNAVAILABLE", "Unable to verify token status", 503).WithCause(err)
+	default:
+		return lift.NewLiftError("AUTH_FAILED", "Authentication failed", 500).WithCause(err)
+	}
+}
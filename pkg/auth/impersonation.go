@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// MethodImpersonation is the amr of impersonation tokens. They never
+// carry the customer's own factors, so step-up and MFA checks fail for
+// impersonating agents.
+const MethodImpersonation = "imp"
+
+// ErrImpersonationDisabled is returned by Impersonate when the service
+// has no Impersonation configured
+var ErrImpersonationDisabled = errors.New("impersonation is not enabled")
+
+// ImpersonationSession is an access token for an actor to act as a
+// customer. It can't be refreshed; once it expires the actor starts a new
+// impersonation with a new reason.
+type ImpersonationSession struct {
+	// AccessToken is a JWT accepted by middleware.JWT, with the actor in
+	// its act claim
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresIn   int       `json:"expires_in"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// Grant is the audited impersonation grant; its ID is the token's sid
+	Grant *security.ImpersonationGrant `json:"grant"`
+}
+
+// Impersonate starts an impersonation of a customer and issues a token for
+// it. The grant is recorded before the token is issued, and the token
+// expires with the grant.
+func (s *Service) Impersonate(ctx context.Context, req security.ImpersonationRequest) (*ImpersonationSession, error) {
+	if s.config.Impersonation == nil {
+		return nil, ErrImpersonationDisabled
+	}
+
+	credential, err := s.config.Credentials.Get(ctx, req.TenantID, req.UserID)
+	if err == nil && credential.Disabled {
+		err = ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+
+	grant, err := s.config.Impersonation.Start(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.config.Now()
+	token, err := s.signClaims(middleware.JWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID("at"),
+			Subject:   credential.UserID,
+			Issuer:    s.config.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(grant.ExpiresAt),
+		},
+		TenantID:  credential.TenantID,
+		Roles:     credential.Roles,
+		Scopes:    credential.Scopes,
+		SessionID: grant.ID,
+		AMR:       []string{MethodImpersonation},
+		AuthTime:  jwt.NewNumericDate(now),
+		Actor: &middleware.ActorClaims{
+			Subject:         grant.ActorID,
+			TenantID:        grant.ActorTenantID,
+			ImpersonationID: grant.ID,
+			ReasonCode:      grant.ReasonCode,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImpersonationSession{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(grant.ExpiresAt.Sub(now) / time.Second),
+		ExpiresAt:   grant.ExpiresAt,
+		Grant:       grant,
+	}, nil
+}
+
+// EndImpersonation revokes an impersonation's token before it expires
+func (s *Service) EndImpersonation(ctx context.Context, impersonationID string) error {
+	return s.config.Revocations.RevokeSession(ctx, impersonationID)
+}
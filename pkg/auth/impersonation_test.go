@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonation(t *testing.T) {
+	service, _, revocations, _ := newTestService(t)
+	storage := security.NewInMemoryAuditStorage()
+	impersonation, err := security.NewImpersonation(security.ImpersonationConfig{
+		Storage:     storage,
+		ReasonCodes: []string{"customer_request", "incident"},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = service.Impersonate(ctx, security.ImpersonationRequest{ActorID: "agent-7", TenantID: "tenant-a", UserID: "cus_1", ReasonCode: "customer_request"})
+	assert.ErrorIs(t, err, ErrImpersonationDisabled)
+
+	service.config.Impersonation = impersonation
+	_, err = service.Impersonate(ctx, security.ImpersonationRequest{ActorID: "agent-7", TenantID: "tenant-a", UserID: "cus_1", ReasonCode: "curious"})
+	assert.ErrorIs(t, err, security.ErrImpersonationReason)
+	_, err = service.Impersonate(ctx, security.ImpersonationRequest{ActorID: "agent-7", TenantID: "tenant-a", UserID: "cus_404", ReasonCode: "incident"})
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+
+	session, err := service.Impersonate(ctx, security.ImpersonationRequest{
+		ActorID:       "agent-7",
+		ActorTenantID: "support",
+		TenantID:      "tenant-a",
+		UserID:        "cus_1",
+		ReasonCode:    "customer_request",
+		Note:          "ticket 4821",
+		Duration:      10 * time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 600, session.ExpiresIn)
+
+	app := lift.New()
+	app.Use(middleware.JWT(security.JWTConfig{
+		SigningMethod: "HS256",
+		SecretKey:     testSecret,
+		Issuer:        "https://shop.example.com",
+		Revocation:    revocations,
+	}))
+	app.Use(middleware.ImpersonationGuard(impersonation))
+	app.GET("/orders", func(ctx *lift.Context) error {
+		return ctx.JSON(map[string]string{"user_id": ctx.UserID(), "actor_id": ctx.ActorID()})
+	})
+	app.PUT("/me/password", func(ctx *lift.Context) error { return ctx.Text("ok") }).
+		Meta(lift.MetaNoImpersonation, "true")
+	app.DELETE("/support/impersonation", service.EndImpersonationHandler())
+
+	request := func(method, path string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  method,
+			Path:    path,
+			Headers: map[string]string{"Authorization": "Bearer " + session.AccessToken},
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	orders := request("GET", "/orders")
+	require.Equal(t, 200, orders.Response.StatusCode)
+	assert.Equal(t, map[string]string{"user_id": "cus_1", "actor_id": "agent-7"}, orders.Response.Body)
+
+	assert.Equal(t, 403, request("PUT", "/me/password").Response.StatusCode)
+
+	accesses, err := storage.Query(ctx, security.AuditFilter{ActorID: "agent-7", EntryType: security.ImpersonationAccessEntry})
+	require.NoError(t, err)
+	require.Len(t, accesses, 1)
+	assert.Equal(t, "cus_1", accesses[0].UserID)
+	assert.Equal(t, "agent-7", accesses[0].Request.ActorID)
+	assert.Equal(t, "/orders", accesses[0].Request.Resource)
+	assert.Equal(t, session.Grant.ID, accesses[0].AuditID)
+
+	assert.Equal(t, 204, request("DELETE", "/support/impersonation").Response.StatusCode)
+	assert.Equal(t, 401, request("GET", "/orders").Response.StatusCode, "ended impersonations are revoked")
+}
+
+func TestImpersonateHandler(t *testing.T) {
+	service, _, _, _ := newTestService(t)
+	impersonation, err := security.NewImpersonation(security.ImpersonationConfig{
+		Storage:     security.NewInMemoryAuditStorage(),
+		ReasonCodes: []string{"customer_request"},
+	})
+	require.NoError(t, err)
+	service.config.Impersonation = impersonation
+
+	call := func(claims map[string]any, body string) (*lift.Context, error) {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method: "POST",
+			Path:   "/support/impersonate",
+			Body:   []byte(body),
+		}))
+		if claims != nil {
+			ctx.SetClaims(claims)
+		}
+		return ctx, service.ImpersonateHandler().Handle(ctx)
+	}
+	body := `{"tenant_id":"tenant-a","user_id":"cus_1","reason_code":"customer_request","duration":"5m"}`
+
+	_, err = call(nil, body)
+	var liftErr *lift.LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 401, liftErr.StatusCode)
+
+	ctx, err := call(map[string]any{"sub": "agent-7", "tenant_id": "support"}, body)
+	require.NoError(t, err)
+	assert.Equal(t, 201, ctx.Response.StatusCode)
+	session := ctx.Response.Body.(*ImpersonationSession)
+	assert.Equal(t, "agent-7", session.Grant.ActorID)
+	assert.Equal(t, "support", session.Grant.ActorTenantID)
+	assert.Equal(t, 300, session.ExpiresIn)
+
+	_, err = call(map[string]any{"sub": "cus_1", "tenant_id": "tenant-a", "act": map[string]any{"sub": "agent-7"}}, body)
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 403, liftErr.StatusCode, "impersonators can't impersonate")
+
+	_, err = call(map[string]any{"sub": "agent-7"}, `{"tenant_id":"tenant-a","user_id":"cus_1","reason_code":"bored"}`)
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 400, liftErr.StatusCode)
+}
@@ -0,0 +1,390 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// Authentication methods recorded in access tokens' amr claim (RFC 8176)
+const (
+	MethodPassword = "pwd"
+	MethodTOTP     = "otp"
+	MethodWebAuthn = "hwk"
+)
+
+// ACRMFA is the acr of access tokens issued after MFA, the value
+// middleware.StepUpGuard routes ask for
+const ACRMFA = "mfa"
+
+var (
+	// ErrMFARequired is returned when an action needs MFA the caller's
+	// session hasn't done
+	ErrMFARequired = errors.New("multi-factor authentication required")
+	// ErrMFANotEnrolled is returned when verifying a factor the customer
+	// hasn't enrolled
+	ErrMFANotEnrolled = errors.New("multi-factor authentication not enrolled")
+	// ErrMFAAlreadyEnrolled is returned when enrolling TOTP twice
+	ErrMFAAlreadyEnrolled = errors.New("multi-factor authentication already enrolled")
+	// ErrInvalidMFACode is returned for wrong or reused TOTP codes
+	ErrInvalidMFACode = errors.New("invalid verification code")
+	// ErrInvalidMFAChallenge is returned for WebAuthn challenges that are
+	// unknown, expired, used or someone else's
+	ErrInvalidMFAChallenge = errors.New("invalid MFA challenge")
+	// ErrInvalidWebAuthnResponse is returned when an authenticator's
+	// response fails verification
+	ErrInvalidWebAuthnResponse = errors.New("invalid WebAuthn response")
+	// ErrWebAuthnNotConfigured is returned by WebAuthn methods when
+	// Config.WebAuthn isn't set
+	ErrWebAuthnNotConfigured = errors.New("WebAuthn is not configured")
+	// ErrTOTPCodeReused is returned by MFAStore.UseTOTPStep for a time step
+	// that was already used
+	ErrTOTPCodeReused = errors.New("TOTP code already used")
+)
+
+// Caller is the signed-in customer making a request, as described by
+// their access token
+type Caller struct {
+	TenantID  string
+	UserID    string
+	SessionID string
+	// AMR and AuthTime say how and when they last authenticated
+	AMR      []string
+	AuthTime time.Time
+}
+
+// HasMFA reports whether the caller's session used a second factor
+func (c *Caller) HasMFA() bool {
+	return slices.Contains(c.AMR, MethodTOTP) || slices.Contains(c.AMR, MethodWebAuthn)
+}
+
+// CallerFromContext returns the caller authenticated by middleware.JWT or
+// middleware.JWTAuth, or a 401 error if there is none
+func CallerFromContext(ctx *lift.Context) (*Caller, error) {
+	auth := middleware.RequestAuthContext(ctx)
+	caller := &Caller{
+		TenantID: ctx.TenantID(),
+		UserID:   ctx.UserID(),
+		AMR:      auth.AMR,
+		AuthTime: auth.AuthTime,
+	}
+	if principal, ok := ctx.Get("principal").(*security.Principal); ok && principal != nil {
+		caller.TenantID = principal.TenantID
+		caller.UserID = principal.UserID
+		caller.SessionID = principal.SessionID
+	} else {
+		caller.SessionID, _ = ctx.GetClaim("sid").(string)
+	}
+	if caller.UserID == "" {
+		return nil, lift.Unauthorized("Authentication required")
+	}
+	return caller, nil
+}
+
+// MFAFactors are the second factors a customer has enrolled
+type MFAFactors struct {
+	TenantID string               `json:"tenant_id" dynamodbav:"tenant_id"`
+	UserID   string               `json:"user_id" dynamodbav:"user_id"`
+	TOTP     *TOTPFactor          `json:"totp,omitempty" dynamodbav:"totp,omitempty"`
+	WebAuthn []WebAuthnCredential `json:"webauthn,omitempty" dynamodbav:"webauthn,omitempty"`
+}
+
+// Methods returns the methods the customer can verify with
+func (f *MFAFactors) Methods() []string {
+	var methods []string
+	if f.TOTP != nil && f.TOTP.Confirmed {
+		methods = append(methods, MethodTOTP)
+	}
+	if len(f.WebAuthn) > 0 {
+		methods = append(methods, MethodWebAuthn)
+	}
+	return methods
+}
+
+// TOTPFactor is an authenticator app enrollment
+type TOTPFactor struct {
+	// Secret is the base32 key; stores should encrypt it at rest
+	Secret    string    `json:"-" dynamodbav:"secret"`
+	Confirmed bool      `json:"confirmed" dynamodbav:"confirmed"`
+	CreatedAt time.Time `json:"created_at" dynamodbav:"created_at"`
+	// LastStep is the time step of the last code used; older codes are
+	// rejected
+	LastStep int64 `json:"-" dynamodbav:"last_step"`
+}
+
+// WebAuthnCredential is a registered security key or passkey
+type WebAuthnCredential struct {
+	Name       string              `json:"name" dynamodbav:"name"`
+	CreatedAt  time.Time           `json:"created_at" dynamodbav:"created_at"`
+	LastUsedAt *time.Time          `json:"last_used_at,omitempty" dynamodbav:"last_used_at,omitempty"`
+	Credential webauthn.Credential `json:"credential" dynamodbav:"credential"`
+}
+
+// MFAChallenge is a pending WebAuthn ceremony
+type MFAChallenge struct {
+	ID       string `json:"id" dynamodbav:"id"`
+	TenantID string `json:"tenant_id" dynamodbav:"tenant_id"`
+	UserID   string `json:"user_id" dynamodbav:"user_id"`
+	// Kind is "registration" or "login"
+	Kind      string               `json:"kind" dynamodbav:"kind"`
+	Session   webauthn.SessionData `json:"session" dynamodbav:"session"`
+	ExpiresAt time.Time            `json:"expires_at" dynamodbav:"expires_at"`
+}
+
+// MFAStore persists customers' second factors and pending WebAuthn
+// challenges
+type MFAStore interface {
+	// GetFactors returns a customer's factors, empty if they have none
+	GetFactors(ctx context.Context, tenantID, userID string) (*MFAFactors, error)
+	// SaveFactors replaces a customer's factors
+	SaveFactors(ctx context.Context, factors *MFAFactors) error
+	// UseTOTPStep atomically records a TOTP time step as used, returning
+	// ErrTOTPCodeReused unless it is later than the last one
+	UseTOTPStep(ctx context.Context, tenantID, userID string, step int64) error
+	// SaveChallenge stores a pending challenge
+	SaveChallenge(ctx context.Context, challenge *MFAChallenge) error
+	// ConsumeChallenge atomically deletes and returns a challenge, or
+	// returns ErrTokenNotFound
+	ConsumeChallenge(ctx context.Context, id string) (*MFAChallenge, error)
+}
+
+// MFAPolicy is a tenant's MFA requirement for routes guarded by
+// RequireMFA
+type MFAPolicy struct {
+	// Required makes those routes demand MFA
+	Required bool `json:"required"`
+	// Methods are the accepted methods (default: TOTP and WebAuthn)
+	Methods []string `json:"methods,omitempty"`
+	// MaxAge is how long ago MFA may have been done; zero accepts MFA done
+	// at any point in the session
+	MaxAge time.Duration `json:"max_age,omitempty"`
+}
+
+// accepts reports whether one of the methods satisfies the policy
+func (p MFAPolicy) accepts(methods []string) bool {
+	accepted := p.Methods
+	if len(accepted) == 0 {
+		accepted = []string{MethodTOTP, MethodWebAuthn}
+	}
+	for _, method := range methods {
+		if slices.Contains(accepted, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// MFAPolicies looks up tenants' MFA policies
+type MFAPolicies interface {
+	MFAPolicy(ctx context.Context, tenantID string) (MFAPolicy, error)
+}
+
+// StaticMFAPolicies maps tenant IDs to policies. The "*" entry applies to
+// tenants without their own; without it they don't require MFA.
+type StaticMFAPolicies map[string]MFAPolicy
+
+// MFAPolicy implements MFAPolicies
+func (p StaticMFAPolicies) MFAPolicy(ctx context.Context, tenantID string) (MFAPolicy, error) {
+	if policy, ok := p[tenantID]; ok {
+		return policy, nil
+	}
+	return p["*"], nil
+}
+
+// RequireMFA guards routes, usually an admin group, with the tenant's MFA
+// policy. Register it after middleware.JWT:
+//
+//	admin := app.Group("/admin")
+//	admin.Use(service.RequireMFA())
+//
+// Callers whose session hasn't done an accepted second factor recently
+// enough get a 401 MFA_REQUIRED step-up challenge, or 403
+// MFA_ENROLLMENT_REQUIRED if they have no accepted factor to use.
+// Policy lookups that fail deny the request.
+func (s *Service) RequireMFA() lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			caller, err := CallerFromContext(ctx)
+			if err != nil {
+				return err
+			}
+			policy, err := s.config.MFAPolicies.MFAPolicy(ctx.Context, caller.TenantID)
+			if err != nil {
+				return lift.NewLiftError("MFA_POLICY_UNAVAILABLE", "Unable to check MFA policy", 503).WithCause(err)
+			}
+			if !policy.Required || s.satisfies(caller, policy) {
+				return next.Handle(ctx)
+			}
+
+			factors, err := s.config.MFA.GetFactors(ctx.Context, caller.TenantID, caller.UserID)
+			if err != nil {
+				return lift.NewLiftError("MFA_UNAVAILABLE", "Unable to check MFA enrollment", 503).WithCause(err)
+			}
+			if !policy.accepts(factors.Methods()) {
+				return lift.NewLiftError("MFA_ENROLLMENT_REQUIRED", "Multi-factor authentication must be set up", 403)
+			}
+
+			challenge := `Bearer error="insufficient_user_authentication", error_description="Multi-factor authentication is required", acr_values="mfa"`
+			if policy.MaxAge > 0 {
+				challenge += fmt.Sprintf(", max_age=%d", int(policy.MaxAge/time.Second))
+			}
+			ctx.Response.Header("WWW-Authenticate", challenge)
+			return lift.NewLiftError("MFA_REQUIRED", "Multi-factor authentication is required", 401).
+				WithDetail("methods", factors.Methods())
+		})
+	}
+}
+
+// satisfies reports whether the caller's session meets a policy
+func (s *Service) satisfies(caller *Caller, policy MFAPolicy) bool {
+	if !policy.accepts(caller.AMR) {
+		return false
+	}
+	return policy.MaxAge == 0 || s.config.Now().Sub(caller.AuthTime) <= policy.MaxAge
+}
+
+// mfaSubject returns the caller's credential and factors
+func (s *Service) mfaSubject(ctx context.Context, caller *Caller) (*Credential, *MFAFactors, error) {
+	credential, err := s.config.Credentials.Get(ctx, caller.TenantID, caller.UserID)
+	if errors.Is(err, ErrCredentialNotFound) || (err == nil && credential.Disabled) {
+		return nil, nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+	factors, err := s.config.MFA.GetFactors(ctx, caller.TenantID, caller.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get MFA factors: %w", err)
+	}
+	return credential, factors, nil
+}
+
+// requireCurrentMFA stops a stolen password from adding factors to an
+// account that already has one
+func (s *Service) requireCurrentMFA(caller *Caller, factors *MFAFactors) error {
+	if len(factors.Methods()) > 0 && !caller.HasMFA() {
+		return ErrMFARequired
+	}
+	return nil
+}
+
+// upgradeSession issues new tokens for the caller's session that record
+// the second factor. The MFA time becomes the session's auth_time.
+func (s *Service) upgradeSession(ctx context.Context, credential *Credential, caller *Caller, method string) (*Session, error) {
+	amr := slices.Clone(caller.AMR)
+	if len(amr) == 0 {
+		amr = []string{MethodPassword}
+	}
+	if !slices.Contains(amr, method) {
+		amr = append(amr, method)
+	}
+	sessionID := caller.SessionID
+	if sessionID == "" {
+		sessionID = newTokenID("ses")
+	}
+	now := s.config.Now()
+	return s.startSession(ctx, credential, sessionID, sessionAuth{Time: now, AMR: amr, ACR: ACRMFA}, now)
+}
+
+// mfaFailureKey identifies a customer's MFA attempts in the failure store
+func mfaFailureKey(caller *Caller) string {
+	return "mfa:" + caller.TenantID + ":" + caller.UserID
+}
+
+// webAuthnUserID is the opaque user handle given to authenticators
+func webAuthnUserID(tenantID, userID string) []byte {
+	sum := sha256.Sum256([]byte(tenantID + "#" + userID))
+	return sum[:]
+}
+
+// MemoryMFAStore is an in-memory MFAStore for tests and local development
+type MemoryMFAStore struct {
+	mu         sync.Mutex
+	factors    map[string]MFAFactors
+	challenges map[string]MFAChallenge
+}
+
+// NewMemoryMFAStore creates an empty MFA store
+func NewMemoryMFAStore() *MemoryMFAStore {
+	return &MemoryMFAStore{
+		factors:    make(map[string]MFAFactors),
+		challenges: make(map[string]MFAChallenge),
+	}
+}
+
+// GetFactors implements MFAStore
+func (m *MemoryMFAStore) GetFactors(ctx context.Context, tenantID, userID string) (*MFAFactors, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	factors, ok := m.factors[tenantID+"#"+userID]
+	if !ok {
+		return &MFAFactors{TenantID: tenantID, UserID: userID}, nil
+	}
+	if factors.TOTP != nil {
+		totp := *factors.TOTP
+		factors.TOTP = &totp
+	}
+	factors.WebAuthn = slices.Clone(factors.WebAuthn)
+	return &factors, nil
+}
+
+// SaveFactors implements MFAStore
+func (m *MemoryMFAStore) SaveFactors(ctx context.Context, factors *MFAFactors) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := *factors
+	if saved.TOTP != nil {
+		totp := *saved.TOTP
+		saved.TOTP = &totp
+	}
+	saved.WebAuthn = slices.Clone(saved.WebAuthn)
+	m.factors[factors.TenantID+"#"+factors.UserID] = saved
+	return nil
+}
+
+// UseTOTPStep implements MFAStore
+func (m *MemoryMFAStore) UseTOTPStep(ctx context.Context, tenantID, userID string, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	factors, ok := m.factors[tenantID+"#"+userID]
+	if !ok || factors.TOTP == nil {
+		return ErrMFANotEnrolled
+	}
+	if step <= factors.TOTP.LastStep {
+		return ErrTOTPCodeReused
+	}
+	totp := *factors.TOTP
+	totp.LastStep = step
+	factors.TOTP = &totp
+	m.factors[tenantID+"#"+userID] = factors
+	return nil
+}
+
+// SaveChallenge implements MFAStore
+func (m *MemoryMFAStore) SaveChallenge(ctx context.Context, challenge *MFAChallenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.challenges[challenge.ID] = *challenge
+	return nil
+}
+
+// ConsumeChallenge implements MFAStore
+func (m *MemoryMFAStore) ConsumeChallenge(ctx context.Context, id string) (*MFAChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	challenge, ok := m.challenges[id]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	delete(m.challenges, id)
+	return &challenge, nil
+}
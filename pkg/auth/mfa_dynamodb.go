@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBMFAStore implements MFAStore using a DynamoDB table with a
+// string partition key "pk", which can be the DynamoDBTokenStore's table.
+// Factors are stored as "mfa#<tenant>#<user>" and challenges as
+// "mfachallenge#<id>", which expire through the "ttl" attribute.
+type DynamoDBMFAStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBMFAStore creates a DynamoDB-backed MFA store
+func NewDynamoDBMFAStore(client *dynamodb.Client, tableName string) *DynamoDBMFAStore {
+	return &DynamoDBMFAStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// GetFactors implements MFAStore
+func (d *DynamoDBMFAStore) GetFactors(ctx context.Context, tenantID, userID string) (*MFAFactors, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.tableName),
+		Key:            tokenKey(factorsKey(tenantID, userID)),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return &MFAFactors{TenantID: tenantID, UserID: userID}, nil
+	}
+
+	var factors MFAFactors
+	if err := attributevalue.UnmarshalMap(result.Item, &factors); err != nil {
+		return nil, err
+	}
+	return &factors, nil
+}
+
+// SaveFactors implements MFAStore
+func (d *DynamoDBMFAStore) SaveFactors(ctx context.Context, factors *MFAFactors) error {
+	item, err := attributevalue.MarshalMap(factors)
+	if err != nil {
+		return err
+	}
+	item["pk"] = &types.AttributeValueMemberS{Value: factorsKey(factors.TenantID, factors.UserID)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	return err
+}
+
+// UseTOTPStep implements MFAStore with a conditional update, so a code
+// replayed concurrently can only be used once
+func (d *DynamoDBMFAStore) UseTOTPStep(ctx context.Context, tenantID, userID string, step int64) error {
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.tableName),
+		Key:                 tokenKey(factorsKey(tenantID, userID)),
+		UpdateExpression:    aws.String("SET totp.last_step = :step"),
+		ConditionExpression: aws.String("attribute_exists(totp) AND (attribute_not_exists(totp.last_step) OR totp.last_step < :step)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":step": &types.AttributeValueMemberN{Value: strconv.FormatInt(step, 10)},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrTOTPCodeReused
+	}
+	return err
+}
+
+// SaveChallenge implements MFAStore
+func (d *DynamoDBMFAStore) SaveChallenge(ctx context.Context, challenge *MFAChallenge) error {
+	item, err := attributevalue.MarshalMap(challenge)
+	if err != nil {
+		return err
+	}
+	item["pk"] = &types.AttributeValueMemberS{Value: "mfachallenge#" + challenge.ID}
+	item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(challenge.ExpiresAt.Unix(), 10)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	return err
+}
+
+// ConsumeChallenge implements MFAStore with a delete returning the old
+// item, so a challenge can only be answered once
+func (d *DynamoDBMFAStore) ConsumeChallenge(ctx context.Context, id string) (*MFAChallenge, error) {
+	result, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(d.tableName),
+		Key:          tokenKey("mfachallenge#" + id),
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Attributes == nil {
+		return nil, ErrTokenNotFound
+	}
+
+	var challenge MFAChallenge
+	if err := attributevalue.UnmarshalMap(result.Attributes, &challenge); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func factorsKey(tenantID, userID string) string {
+	return "mfa#" + tenantID + "#" + userID
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"encoding/json"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// totpBody is the body accepted by ConfirmTOTPHandler and VerifyTOTPHandler
+type totpBody struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// webAuthnBody is the body accepted by the WebAuthn finish handlers.
+// Credential is the browser's PublicKeyCredential as JSON.
+type webAuthnBody struct {
+	ChallengeID string          `json:"challenge_id" validate:"required"`
+	Name        string          `json:"name"`
+	Credential  json.RawMessage `json:"credential" validate:"required"`
+}
+
+// EnrollTOTPHandler creates a TOTP secret for the signed-in customer
+func (s *Service) EnrollTOTPHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		caller, err := CallerFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		enrollment, err := s.EnrollTOTP(ctx.Context, caller)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.Status(201).JSON(enrollment)
+	})
+}
+
+// ConfirmTOTPHandler completes TOTP enrollment with a code and returns a
+// Session that counts as MFA
+func (s *Service) ConfirmTOTPHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		caller, err := CallerFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		var body totpBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		session, err := s.ConfirmTOTP(ctx.Context, caller, body.Code)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.OK(session)
+	})
+}
+
+// VerifyTOTPHandler exchanges a TOTP code for a Session that counts as MFA
+func (s *Service) VerifyTOTPHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		caller, err := CallerFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		var body totpBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		session, err := s.VerifyTOTP(ctx.Context, caller, body.Code)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.OK(session)
+	})
+}
+
+// BeginWebAuthnRegistrationHandler returns the options for
+// navigator.credentials.create()
+func (s *Service) BeginWebAuthnRegistrationHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		caller, err := CallerFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		challenge, err := s.BeginWebAuthnRegistration(ctx.Context, caller)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.OK(challenge)
+	})
+}
+
+// FinishWebAuthnRegistrationHandler saves the new credential and returns
+// a Session that counts as MFA
+func (s *Service) FinishWebAuthnRegistrationHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		caller, err := CallerFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		var body webAuthnBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		session, err := s.FinishWebAuthnRegistration(ctx.Context, caller, body.ChallengeID, body.Name, body.Credential)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.OK(session)
+	})
+}
+
+// BeginWebAuthnLoginHandler returns the options for
+// navigator.credentials.get()
+func (s *Service) BeginWebAuthnLoginHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		caller, err := CallerFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		challenge, err := s.BeginWebAuthnLogin(ctx.Context, caller)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.OK(challenge)
+	})
+}
+
+// FinishWebAuthnLoginHandler exchanges an authenticator's assertion for a
+// Session that counts as MFA
+func (s *Service) FinishWebAuthnLoginHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		caller, err := CallerFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		var body webAuthnBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		session, err := s.FinishWebAuthnLogin(ctx.Context, caller, body.ChallengeID, body.Credential)
+		if err != nil {
+			return s.authError(ctx, err)
+		}
+		return ctx.OK(session)
+	})
+}
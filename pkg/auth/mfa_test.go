@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callerFor returns the caller an access token describes
+func callerFor(t *testing.T, revocations *security.Revocations, session *Session) *Caller {
+	t.Helper()
+	validator, err := middleware.NewJWTValidator(security.JWTConfig{
+		SigningMethod: "HS256",
+		SecretKey:     testSecret,
+		Issuer:        "https://shop.example.com",
+		Revocation:    revocations,
+	})
+	require.NoError(t, err)
+	claims, err := validator.ValidateToken(session.AccessToken)
+	require.NoError(t, err)
+
+	caller := &Caller{TenantID: claims.TenantID, UserID: claims.Subject, SessionID: claims.SessionID, AMR: claims.AMR}
+	if claims.AuthTime != nil {
+		caller.AuthTime = claims.AuthTime.Time
+	}
+	return caller
+}
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	code, err := TOTPCode(secret, time.Unix(59, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+	code, err = TOTPCode(secret, time.Unix(1111111109, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "081804", code)
+
+	assert.Equal(t,
+		"otpauth://totp/Shop:ada@example.com?algorithm=SHA1&digits=6&issuer=Shop&period=30&secret="+secret,
+		TOTPURI("Shop", "ada@example.com", secret))
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	service, _, revocations, clock := newTestService(t)
+	ctx := context.Background()
+
+	session, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+	caller := callerFor(t, revocations, session)
+	assert.Equal(t, []string{MethodPassword}, caller.AMR)
+
+	_, err = service.VerifyTOTP(ctx, caller, "123456")
+	assert.ErrorIs(t, err, ErrMFANotEnrolled)
+
+	enrollment, err := service.EnrollTOTP(ctx, caller)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "issuer=https%3A%2F%2Fshop.example.com")
+
+	code, err := TOTPCode(enrollment.Secret, clock.Now())
+	require.NoError(t, err)
+	upgraded, err := service.ConfirmTOTP(ctx, caller, code)
+	require.NoError(t, err)
+	assert.Equal(t, session.SessionID, upgraded.SessionID)
+
+	mfaCaller := callerFor(t, revocations, upgraded)
+	assert.Equal(t, []string{MethodPassword, MethodTOTP}, mfaCaller.AMR)
+	assert.True(t, mfaCaller.HasMFA())
+
+	// Refreshing keeps the session's MFA
+	refreshed, err := service.Refresh(ctx, upgraded.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{MethodPassword, MethodTOTP}, callerFor(t, revocations, refreshed).AMR)
+
+	_, err = service.EnrollTOTP(ctx, mfaCaller)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnrolled)
+
+	// A new password-only session can't add factors
+	_, err = service.BeginWebAuthnRegistration(ctx, caller)
+	assert.ErrorIs(t, err, ErrWebAuthnNotConfigured)
+
+	// The confirming code can't be replayed, but the next one works
+	_, err = service.VerifyTOTP(ctx, caller, code)
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+	clock.Advance(30 * time.Second)
+	code, err = TOTPCode(enrollment.Secret, clock.Now())
+	require.NoError(t, err)
+	_, err = service.VerifyTOTP(ctx, caller, code)
+	require.NoError(t, err)
+}
+
+func TestTOTPLockout(t *testing.T) {
+	service, _, revocations, clock := newTestService(t)
+	ctx := context.Background()
+
+	session, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+	caller := callerFor(t, revocations, session)
+	enrollment, err := service.EnrollTOTP(ctx, caller)
+	require.NoError(t, err)
+	code, err := TOTPCode(enrollment.Secret, clock.Now())
+	require.NoError(t, err)
+	_, err = service.ConfirmTOTP(ctx, caller, code)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err = service.VerifyTOTP(ctx, caller, "000000")
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+	}
+	clock.Advance(30 * time.Second)
+	code, err = TOTPCode(enrollment.Secret, clock.Now())
+	require.NoError(t, err)
+	_, err = service.VerifyTOTP(ctx, caller, code)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+}
+
+func TestRequireMFA(t *testing.T) {
+	service, _, _, clock := newTestService(t)
+	require.NoError(t, service.config.MFA.SaveFactors(context.Background(), &MFAFactors{
+		TenantID: "tenant-a",
+		UserID:   "cus_1",
+		TOTP:     &TOTPFactor{Secret: NewTOTPSecret(), Confirmed: true},
+	}))
+	service.config.MFAPolicies = StaticMFAPolicies{
+		"tenant-a": {Required: true, MaxAge: time.Hour},
+		"tenant-b": {Required: true, Methods: []string{MethodWebAuthn}},
+	}
+
+	run := func(claims map[string]any) (*lift.Context, error) {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Method: "GET", Path: "/admin"}))
+		if claims != nil {
+			ctx.SetClaims(claims)
+		}
+		return ctx, service.RequireMFA()(lift.HandlerFunc(func(ctx *lift.Context) error {
+			return ctx.OK("admin")
+		})).Handle(ctx)
+	}
+	authTime := float64(clock.Now().Unix())
+
+	_, err := run(nil)
+	var liftErr *lift.LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 401, liftErr.StatusCode)
+
+	ctx, err := run(map[string]any{"sub": "cus_1", "tenant_id": "tenant-a", "amr": []any{"pwd"}, "auth_time": authTime})
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "MFA_REQUIRED", liftErr.Code)
+	assert.Contains(t, ctx.Response.Headers["WWW-Authenticate"], `acr_values="mfa", max_age=3600`)
+
+	_, err = run(map[string]any{"sub": "cus_1", "tenant_id": "tenant-a", "amr": []any{"pwd", "otp"}, "auth_time": authTime})
+	require.NoError(t, err)
+
+	clock.Advance(2 * time.Hour)
+	_, err = run(map[string]any{"sub": "cus_1", "tenant_id": "tenant-a", "amr": []any{"pwd", "otp"}, "auth_time": authTime})
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "MFA_REQUIRED", liftErr.Code)
+
+	// TOTP doesn't satisfy a WebAuthn-only policy, and there's nothing to step up with
+	_, err = run(map[string]any{"sub": "cus_1", "tenant_id": "tenant-b", "amr": []any{"pwd", "otp"}})
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 403, liftErr.StatusCode)
+	assert.Equal(t, "MFA_ENROLLMENT_REQUIRED", liftErr.Code)
+
+	// Tenants without a policy don't require MFA
+	_, err = run(map[string]any{"sub": "cus_2", "tenant_id": "tenant-c", "amr": []any{"pwd"}})
+	require.NoError(t, err)
+}
+
+func TestWebAuthnChallenges(t *testing.T) {
+	service, _, revocations, _ := newTestService(t)
+	relyingParty, err := webauthn.New(&webauthn.Config{
+		RPID:          "shop.example.com",
+		RPDisplayName: "Shop",
+		RPOrigins:     []string{"https://shop.example.com"},
+	})
+	require.NoError(t, err)
+	service.webauthn = relyingParty
+	ctx := context.Background()
+
+	session, err := service.Login(ctx, "tenant-a", "ada@example.com", "correct horse battery")
+	require.NoError(t, err)
+	caller := callerFor(t, revocations, session)
+
+	_, err = service.BeginWebAuthnLogin(ctx, caller)
+	assert.ErrorIs(t, err, ErrMFANotEnrolled)
+
+	challenge, err := service.BeginWebAuthnRegistration(ctx, caller)
+	require.NoError(t, err)
+	assert.NotEmpty(t, challenge.ID)
+	assert.NotNil(t, challenge.Options)
+
+	// Someone else can't answer the challenge
+	other := *caller
+	other.UserID = "cus_2"
+	_, err = service.FinishWebAuthnRegistration(ctx, &other, challenge.ID, "", []byte(`{}`))
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+
+	challenge, err = service.BeginWebAuthnRegistration(ctx, caller)
+	require.NoError(t, err)
+	_, err = service.FinishWebAuthnRegistration(ctx, caller, challenge.ID, "", []byte(`{"id":"garbage"}`))
+	assert.ErrorIs(t, err, ErrInvalidWebAuthnResponse)
+
+	// Challenges can only be answered once
+	_, err = service.FinishWebAuthnRegistration(ctx, caller, challenge.ID, "", []byte(`{"id":"garbage"}`))
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+}
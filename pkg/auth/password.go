@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrInvalidHash is returned for password hashes that aren't in the
+// encoded Argon2id format
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// PasswordParams are the Argon2id cost parameters
+type PasswordParams struct {
+	// Memory is the memory cost in KiB (default: 64 MiB)
+	Memory uint32
+	// Iterations is the time cost (default: 3)
+	Iterations uint32
+	// Parallelism is the number of lanes (default: 2)
+	Parallelism uint8
+	// SaltLength is in bytes (default: 16)
+	SaltLength uint32
+	// KeyLength is in bytes (default: 32)
+	KeyLength uint32
+}
+
+// DefaultPasswordParams follow the OWASP recommendations for Argon2id and
+// hash in well under a second on a 1 GB Lambda
+var DefaultPasswordParams = PasswordParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func (p PasswordParams) withDefaults() PasswordParams {
+	if p.Memory == 0 {
+		p.Memory = DefaultPasswordParams.Memory
+	}
+	if p.Iterations == 0 {
+		p.Iterations = DefaultPasswordParams.Iterations
+	}
+	if p.Parallelism == 0 {
+		p.Parallelism = DefaultPasswordParams.Parallelism
+	}
+	if p.SaltLength == 0 {
+		p.SaltLength = DefaultPasswordParams.SaltLength
+	}
+	if p.KeyLength == 0 {
+		p.KeyLength = DefaultPasswordParams.KeyLength
+	}
+	return p
+}
+
+// HashPassword hashes a password with Argon2id and a random salt. The
+// result is self-describing, e.g.
+// "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>", so the parameters can be
+// raised later without invalidating stored hashes.
+func HashPassword(password string, params PasswordParams) (string, error) {
+	params = params.withDefaults()
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword reports whether password matches an encoded hash, in
+// constant time
+func VerifyPassword(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeHash(encoded)
+	if err != nil {
+		return false, err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, candidate) == 1, nil
+}
+
+// NeedsRehash reports whether an encoded hash used weaker parameters than
+// params, so it should be replaced after the next successful login
+func NeedsRehash(encoded string, params PasswordParams) bool {
+	params = params.withDefaults()
+	current, salt, key, err := decodeHash(encoded)
+	if err != nil {
+		return true
+	}
+	return current.Memory < params.Memory || current.Iterations < params.Iterations ||
+		current.Parallelism < params.Parallelism || uint32(len(salt)) < params.SaltLength ||
+		uint32(len(key)) < params.KeyLength
+}
+
+func decodeHash(encoded string) (PasswordParams, []byte, []byte, error) {
+	var params PasswordParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
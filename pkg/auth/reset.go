@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PasswordReset is a reset token to send to a customer, e.g. as a link in
+// an email
+type PasswordReset struct {
+	TenantID  string
+	UserID    string
+	Email     string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// RequestPasswordReset creates a single-use reset token for an email. It
+// returns nil without an error for unknown emails and disabled accounts,
+// so callers respond the same way whether or not the account exists.
+func (s *Service) RequestPasswordReset(ctx context.Context, tenantID, email string) (*PasswordReset, error) {
+	email = normalizeEmail(email)
+	credential, err := s.config.Credentials.FindByEmail(ctx, tenantID, email)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find credential: %w", err)
+	}
+	if credential.Disabled {
+		return nil, nil
+	}
+
+	now := s.config.Now()
+	secret := newSecret()
+	record := &ResetToken{
+		ID:         newTokenID("prt"),
+		TenantID:   credential.TenantID,
+		UserID:     credential.UserID,
+		SecretHash: hashSecret(secret),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(s.config.ResetTokenTTL),
+	}
+	if err := s.config.Tokens.SaveResetToken(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save reset token: %w", err)
+	}
+
+	return &PasswordReset{
+		TenantID:  credential.TenantID,
+		UserID:    credential.UserID,
+		Email:     credential.Email,
+		Token:     record.ID + "." + secret,
+		ExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// ResetPassword sets a new password with a reset token, revokes all the
+// customer's sessions and clears any login lockout. The token can't be
+// used again, even if the new password is rejected.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	tokenID, secret, ok := splitToken(token)
+	if !ok {
+		return ErrInvalidResetToken
+	}
+	// Check the password first so a rejected one doesn't use up the token
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	record, err := s.config.Tokens.ConsumeResetToken(ctx, tokenID)
+	if errors.Is(err, ErrTokenNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get reset token: %w", err)
+	}
+	if !secretMatches(secret, record.SecretHash) || !s.config.Now().Before(record.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	credential, err := s.config.Credentials.Get(ctx, record.TenantID, record.UserID)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get credential: %w", err)
+	}
+	if err := s.config.Credentials.SetPasswordHash(ctx, record.TenantID, record.UserID, hash); err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+	if err := s.config.Revocations.RevokeUser(ctx, record.TenantID, record.UserID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.config.Failures.Reset(ctx, failureKey(record.TenantID, normalizeEmail(credential.Email))); err != nil {
+		s.reportError(fmt.Errorf("failed to reset login attempts: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pay-theory/lift/pkg/lift/id"
+	"github.com/pay-theory/lift/pkg/middleware"
+)
+
+var (
+	// ErrTokenNotFound is returned by a TokenStore for unknown tokens
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrTokenReused is returned by UseRefreshToken for a token that was
+	// already used
+	ErrTokenReused = errors.New("refresh token already used")
+)
+
+// Session is a signed-in customer's tokens
+type Session struct {
+	TenantID  string `json:"tenant_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	// AccessToken is a JWT accepted by middleware.JWT
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn is the access token's lifetime in seconds
+	ExpiresIn int `json:"expires_in"`
+	// RefreshToken is exchanged for new tokens with Refresh, once
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// RefreshToken is a stored refresh token. Only a hash of its secret is
+// kept.
+type RefreshToken struct {
+	ID         string    `json:"id" dynamodbav:"id"`
+	TenantID   string    `json:"tenant_id" dynamodbav:"tenant_id"`
+	UserID     string    `json:"user_id" dynamodbav:"user_id"`
+	SessionID  string    `json:"session_id" dynamodbav:"session_id"`
+	SecretHash string    `json:"-" dynamodbav:"secret_hash"`
+	AuthTime   time.Time `json:"auth_time" dynamodbav:"auth_time"`
+	// AMR and ACR are carried into refreshed access tokens, so a session
+	// keeps its MFA
+	AMR       []string  `json:"amr,omitempty" dynamodbav:"amr,omitempty"`
+	ACR       string    `json:"acr,omitempty" dynamodbav:"acr,omitempty"`
+	IssuedAt  time.Time `json:"issued_at" dynamodbav:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at" dynamodbav:"expires_at"`
+	// UsedAt is set once the token is exchanged for new tokens
+	UsedAt *time.Time `json:"used_at,omitempty" dynamodbav:"used_at,omitempty"`
+}
+
+// sessionAuth describes how a session's customer authenticated
+type sessionAuth struct {
+	// Time is when they last actively authenticated
+	Time time.Time
+	AMR  []string
+	ACR  string
+}
+
+// passwordAuth is a session started with a password at t
+func passwordAuth(t time.Time) sessionAuth {
+	return sessionAuth{Time: t, AMR: []string{MethodPassword}}
+}
+
+// ResetToken is a stored password reset token. Only a hash of its secret
+// is kept.
+type ResetToken struct {
+	ID         string    `json:"id" dynamodbav:"id"`
+	TenantID   string    `json:"tenant_id" dynamodbav:"tenant_id"`
+	UserID     string    `json:"user_id" dynamodbav:"user_id"`
+	SecretHash string    `json:"-" dynamodbav:"secret_hash"`
+	CreatedAt  time.Time `json:"created_at" dynamodbav:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at" dynamodbav:"expires_at"`
+}
+
+// TokenStore persists refresh and password reset tokens. Expired tokens
+// can be dropped.
+type TokenStore interface {
+	// SaveRefreshToken stores a new refresh token
+	SaveRefreshToken(ctx context.Context, token *RefreshToken) error
+	// GetRefreshToken returns a refresh token, or ErrTokenNotFound
+	GetRefreshToken(ctx context.Context, id string) (*RefreshToken, error)
+	// UseRefreshToken atomically marks a refresh token used, returning
+	// ErrTokenReused if it already was
+	UseRefreshToken(ctx context.Context, id string, at time.Time) error
+	// SaveResetToken stores a new password reset token
+	SaveResetToken(ctx context.Context, token *ResetToken) error
+	// ConsumeResetToken atomically deletes and returns a password reset
+	// token, or returns ErrTokenNotFound
+	ConsumeResetToken(ctx context.Context, id string) (*ResetToken, error)
+}
+
+// startSession issues an access token and a new refresh token for a session
+func (s *Service) startSession(ctx context.Context, credential *Credential, sessionID string, auth sessionAuth, now time.Time) (*Session, error) {
+	accessToken, err := s.signAccessToken(credential, sessionID, auth, now)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := newSecret()
+	record := &RefreshToken{
+		ID:         newTokenID("rt"),
+		TenantID:   credential.TenantID,
+		UserID:     credential.UserID,
+		SessionID:  sessionID,
+		SecretHash: hashSecret(secret),
+		AuthTime:   auth.Time,
+		AMR:        auth.AMR,
+		ACR:        auth.ACR,
+		IssuedAt:   now,
+		ExpiresAt:  now.Add(s.config.RefreshTokenTTL),
+	}
+	if err := s.config.Tokens.SaveRefreshToken(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	return &Session{
+		TenantID:         credential.TenantID,
+		UserID:           credential.UserID,
+		SessionID:        sessionID,
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(s.config.AccessTokenTTL / time.Second),
+		RefreshToken:     record.ID + "." + secret,
+		RefreshExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// signAccessToken issues a JWT with the claims middleware.JWT reads
+func (s *Service) signAccessToken(credential *Credential, sessionID string, auth sessionAuth, now time.Time) (string, error) {
+	claims := middleware.JWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID("at"),
+			Subject:   credential.UserID,
+			Issuer:    s.config.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.AccessTokenTTL)),
+		},
+		TenantID:  credential.TenantID,
+		Roles:     credential.Roles,
+		Scopes:    credential.Scopes,
+		SessionID: sessionID,
+		ACR:       auth.ACR,
+		AMR:       auth.AMR,
+		AuthTime:  jwt.NewNumericDate(auth.Time),
+	}
+	return s.signClaims(claims)
+}
+
+// signClaims signs access token claims with the configured key
+func (s *Service) signClaims(claims middleware.JWTClaims) (string, error) {
+	if len(s.config.Audience) > 0 {
+		claims.Audience = s.config.Audience
+	}
+
+	token := jwt.NewWithClaims(s.method, claims)
+	if s.config.KeyID != "" {
+		token.Header["kid"] = s.config.KeyID
+	}
+	signed, err := token.SignedString(s.config.SigningKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return signed, nil
+}
+
+// newTokenID returns a new token record ID
+func newTokenID(prefix string) string {
+	return id.New(prefix)
+}
+
+// newSecret returns 256 random bits, URL-safe encoded
+func newSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashSecret returns the stored form of a token secret. Secrets are random,
+// so a fast hash is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitToken splits "<id>.<secret>"
+func splitToken(token string) (string, string, bool) {
+	tokenID, secret, ok := strings.Cut(strings.TrimSpace(token), ".")
+	return tokenID, secret, ok && tokenID != "" && secret != ""
+}
+
+// MemoryTokenStore is an in-memory TokenStore for tests and local
+// development
+type MemoryTokenStore struct {
+	mu      sync.Mutex
+	refresh map[string]RefreshToken
+	reset   map[string]ResetToken
+}
+
+// NewMemoryTokenStore creates an empty token store
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		refresh: make(map[string]RefreshToken),
+		reset:   make(map[string]ResetToken),
+	}
+}
+
+// SaveRefreshToken implements TokenStore
+func (m *MemoryTokenStore) SaveRefreshToken(ctx context.Context, token *RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refresh[token.ID] = *token
+	return nil
+}
+
+// GetRefreshToken implements TokenStore
+func (m *MemoryTokenStore) GetRefreshToken(ctx context.Context, id string) (*RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.refresh[id]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &token, nil
+}
+
+// UseRefreshToken implements TokenStore
+func (m *MemoryTokenStore) UseRefreshToken(ctx context.Context, id string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.refresh[id]
+	if !ok {
+		return ErrTokenNotFound
+	}
+	if token.UsedAt != nil {
+		return ErrTokenReused
+	}
+	token.UsedAt = &at
+	m.refresh[id] = token
+	return nil
+}
+
+// SaveResetToken implements TokenStore
+func (m *MemoryTokenStore) SaveResetToken(ctx context.Context, token *ResetToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset[token.ID] = *token
+	return nil
+}
+
+// ConsumeResetToken implements TokenStore
+func (m *MemoryTokenStore) ConsumeResetToken(ctx context.Context, id string) (*ResetToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.reset[id]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	delete(m.reset, id)
+	return &token, nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBTokenStore implements TokenStore using a DynamoDB table with a
+// string partition key "pk". Refresh tokens are stored as "refresh#<id>"
+// and reset tokens as "reset#<id>", and both expire through the "ttl"
+// attribute.
+type DynamoDBTokenStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBTokenStore creates a DynamoDB-backed token store
+func NewDynamoDBTokenStore(client *dynamodb.Client, tableName string) *DynamoDBTokenStore {
+	return &DynamoDBTokenStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SaveRefreshToken implements TokenStore
+func (d *DynamoDBTokenStore) SaveRefreshToken(ctx context.Context, token *RefreshToken) error {
+	return d.put(ctx, "refresh#"+token.ID, token, token.ExpiresAt)
+}
+
+// GetRefreshToken implements TokenStore
+func (d *DynamoDBTokenStore) GetRefreshToken(ctx context.Context, id string) (*RefreshToken, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       tokenKey("refresh#" + id),
+		// A token used a moment ago must be seen as used
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, ErrTokenNotFound
+	}
+
+	var token RefreshToken
+	if err := attributevalue.UnmarshalMap(result.Item, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UseRefreshToken implements TokenStore with a conditional update, so
+// concurrent refreshes with the same token can't both succeed
+func (d *DynamoDBTokenStore) UseRefreshToken(ctx context.Context, id string, at time.Time) error {
+	usedAt, err := attributevalue.Marshal(at)
+	if err != nil {
+		return err
+	}
+	_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.tableName),
+		Key:                 tokenKey("refresh#" + id),
+		UpdateExpression:    aws.String("SET used_at = :used_at"),
+		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_not_exists(used_at)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":used_at": usedAt,
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		if _, err := d.GetRefreshToken(ctx, id); errors.Is(err, ErrTokenNotFound) {
+			return ErrTokenNotFound
+		}
+		return ErrTokenReused
+	}
+	return err
+}
+
+// SaveResetToken implements TokenStore
+func (d *DynamoDBTokenStore) SaveResetToken(ctx context.Context, token *ResetToken) error {
+	return d.put(ctx, "reset#"+token.ID, token, token.ExpiresAt)
+}
+
+// ConsumeResetToken implements TokenStore with a delete returning the old
+// item, so a token can only be consumed once
+func (d *DynamoDBTokenStore) ConsumeResetToken(ctx context.Context, id string) (*ResetToken, error) {
+	result, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(d.tableName),
+		Key:          tokenKey("reset#" + id),
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Attributes == nil {
+		return nil, ErrTokenNotFound
+	}
+
+	var token ResetToken
+	if err := attributevalue.UnmarshalMap(result.Attributes, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// put stores a token under key until it expires
+func (d *DynamoDBTokenStore) put(ctx context.Context, key string, token any, expiresAt time.Time) error {
+	item, err := attributevalue.MarshalMap(token)
+	if err != nil {
+		return err
+	}
+	item["pk"] = &types.AttributeValueMemberS{Value: key}
+	item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	return err
+}
+
+func tokenKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: key},
+	}
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// WebAuthn challenge kinds
+const (
+	challengeRegistration = "registration"
+	challengeLogin        = "login"
+)
+
+// WebAuthnChallenge starts a WebAuthn ceremony. Options are passed to
+// navigator.credentials.create() or .get() in the browser, and the result
+// is sent back with the ID.
+type WebAuthnChallenge struct {
+	ID      string `json:"challenge_id"`
+	Options any    `json:"options"`
+}
+
+// webAuthnUser adapts a customer to webauthn.User
+type webAuthnUser struct {
+	credential *Credential
+	factors    *MFAFactors
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return webAuthnUserID(u.credential.TenantID, u.credential.UserID)
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.credential.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.credential.Email
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.factors.WebAuthn))
+	for i, registered := range u.factors.WebAuthn {
+		credentials[i] = registered.Credential
+	}
+	return credentials
+}
+
+// BeginWebAuthnRegistration starts registering a security key or passkey
+// for the caller. Customers who already have MFA must have used it in this
+// session.
+func (s *Service) BeginWebAuthnRegistration(ctx context.Context, caller *Caller) (*WebAuthnChallenge, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnNotConfigured
+	}
+	credential, factors, err := s.mfaSubject(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireCurrentMFA(caller, factors); err != nil {
+		return nil, err
+	}
+
+	user := &webAuthnUser{credential: credential, factors: factors}
+	var exclusions []protocol.CredentialDescriptor
+	for _, registered := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, registered.Descriptor())
+	}
+	options, session, err := s.webauthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin WebAuthn registration: %w", err)
+	}
+	return s.saveChallenge(ctx, caller, challengeRegistration, session, options)
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's response to a
+// registration challenge, saves the credential under name and returns a
+// new Session for the caller's session that counts as MFA
+func (s *Service) FinishWebAuthnRegistration(ctx context.Context, caller *Caller, challengeID, name string, response []byte) (*Session, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnNotConfigured
+	}
+	challenge, err := s.consumeChallenge(ctx, caller, challengeID, challengeRegistration)
+	if err != nil {
+		return nil, err
+	}
+	credential, factors, err := s.mfaSubject(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err)
+	}
+	created, err := s.webauthn.CreateCredential(&webAuthnUser{credential: credential, factors: factors}, challenge.Session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err)
+	}
+
+	if name == "" {
+		name = "Security key"
+	}
+	factors.WebAuthn = append(factors.WebAuthn, WebAuthnCredential{
+		Name:       name,
+		CreatedAt:  s.config.Now(),
+		Credential: *created,
+	})
+	if err := s.config.MFA.SaveFactors(ctx, factors); err != nil {
+		return nil, fmt.Errorf("failed to save WebAuthn credential: %w", err)
+	}
+	return s.upgradeSession(ctx, credential, caller, MethodWebAuthn)
+}
+
+// BeginWebAuthnLogin challenges the caller to use one of their registered
+// security keys or passkeys
+func (s *Service) BeginWebAuthnLogin(ctx context.Context, caller *Caller) (*WebAuthnChallenge, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnNotConfigured
+	}
+	credential, factors, err := s.mfaSubject(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	if len(factors.WebAuthn) == 0 {
+		return nil, ErrMFANotEnrolled
+	}
+
+	options, session, err := s.webauthn.BeginLogin(&webAuthnUser{credential: credential, factors: factors})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin WebAuthn login: %w", err)
+	}
+	return s.saveChallenge(ctx, caller, challengeLogin, session, options)
+}
+
+// FinishWebAuthnLogin verifies the authenticator's response to a login
+// challenge and returns a new Session for the caller's session that counts
+// as MFA. Failures count towards the account's lockout.
+func (s *Service) FinishWebAuthnLogin(ctx context.Context, caller *Caller, challengeID string, response []byte) (*Session, error) {
+	if s.webauthn == nil {
+		return nil, ErrWebAuthnNotConfigured
+	}
+	key := mfaFailureKey(caller)
+	now := s.config.Now()
+	record, err := s.config.Failures.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check MFA attempts: %w", err)
+	}
+	if record != nil && record.IsLocked(now) {
+		return nil, &LockedError{Until: record.LockedUntil}
+	}
+
+	challenge, err := s.consumeChallenge(ctx, caller, challengeID, challengeLogin)
+	if err != nil {
+		return nil, err
+	}
+	credential, factors, err := s.mfaSubject(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, s.recordFailure(ctx, key, now, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err))
+	}
+	used, err := s.webauthn.ValidateLogin(&webAuthnUser{credential: credential, factors: factors}, challenge.Session, parsed)
+	if err != nil {
+		return nil, s.recordFailure(ctx, key, now, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err))
+	}
+	if err := s.config.Failures.Reset(ctx, key); err != nil {
+		s.reportError(fmt.Errorf("failed to reset MFA attempts: %w", err))
+	}
+
+	// Keep the sign count so cloned authenticators are noticed next time
+	for i := range factors.WebAuthn {
+		if bytes.Equal(factors.WebAuthn[i].Credential.ID, used.ID) {
+			factors.WebAuthn[i].Credential.Authenticator = used.Authenticator
+			factors.WebAuthn[i].LastUsedAt = &now
+		}
+	}
+	if err := s.config.MFA.SaveFactors(ctx, factors); err != nil {
+		s.reportError(fmt.Errorf("failed to update WebAuthn credential: %w", err))
+	}
+	return s.upgradeSession(ctx, credential, caller, MethodWebAuthn)
+}
+
+// saveChallenge stores a WebAuthn session for the finishing request
+func (s *Service) saveChallenge(ctx context.Context, caller *Caller, kind string, session *webauthn.SessionData, options any) (*WebAuthnChallenge, error) {
+	challenge := &MFAChallenge{
+		ID:        newTokenID("mfa"),
+		TenantID:  caller.TenantID,
+		UserID:    caller.UserID,
+		Kind:      kind,
+		Session:   *session,
+		ExpiresAt: s.config.Now().Add(s.config.MFAChallengeTTL),
+	}
+	if err := s.config.MFA.SaveChallenge(ctx, challenge); err != nil {
+		return nil, fmt.Errorf("failed to save MFA challenge: %w", err)
+	}
+	return &WebAuthnChallenge{ID: challenge.ID, Options: options}, nil
+}
+
+// consumeChallenge returns a pending challenge, which can only be used
+// once, by the customer it was issued to
+func (s *Service) consumeChallenge(ctx context.Context, caller *Caller, id, kind string) (*MFAChallenge, error) {
+	challenge, err := s.config.MFA.ConsumeChallenge(ctx, id)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MFA challenge: %w", err)
+	}
+	if challenge.Kind != kind || challenge.TenantID != caller.TenantID || challenge.UserID != caller.UserID ||
+		!s.config.Now().Before(challenge.ExpiresAt) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	return challenge, nil
+}
@@ -39,6 +39,8 @@ func NewCLI(version string) *CLI {
 	cli.RegisterCommand(&LogsCommand{})
 	cli.RegisterCommand(&MetricsCommand{})
 	cli.RegisterCommand(&HealthCommand{})
+	cli.RegisterCommand(&FixturesCommand{})
+	cli.RegisterCommand(&RoutesCommand{})
 	cli.RegisterCommand(&VersionCommand{version: version})
 	cli.RegisterCommand(&HelpCommand{cli: cli})
 
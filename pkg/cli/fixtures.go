@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	liftesting "github.com/pay-theory/lift/pkg/testing"
+)
+
+// FixturesCommand converts captured logs and events into replayable test fixtures
+type FixturesCommand struct{}
+
+func (c *FixturesCommand) Name() string        { return "fixtures" }
+func (c *FixturesCommand) Description() string { return "Convert logs or events into test fixtures" }
+func (c *FixturesCommand) Usage() string {
+	return "lift fixtures <file> [--out=dir] [--event] [--name=NAME] [--redact=Header,...]"
+}
+
+func (c *FixturesCommand) Execute(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("input file is required\nUsage: %s", c.Usage())
+	}
+
+	input := args[0]
+	outDir := filepath.Join("testdata", "fixtures")
+	asEvent := false
+	name := ""
+	converter := &liftesting.FixtureConverter{Source: filepath.Base(input)}
+
+	for _, arg := range args[1:] {
+		switch {
+		case strings.HasPrefix(arg, "--out="):
+			outDir = strings.TrimPrefix(arg, "--out=")
+		case arg == "--event":
+			asEvent = true
+		case strings.HasPrefix(arg, "--name="):
+			name = strings.TrimPrefix(arg, "--name=")
+		case strings.HasPrefix(arg, "--redact="):
+			converter.RedactHeaders = strings.Split(strings.TrimPrefix(arg, "--redact="), ",")
+		}
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var fixtures []*liftesting.Fixture
+	if asEvent {
+		raw, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		fixture, err := converter.ConvertEvent(name, raw)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, fixture)
+	} else {
+		fixtures, err = converter.ConvertLogs(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(fixtures) == 0 {
+		return fmt.Errorf("no requests found in %s", input)
+	}
+	if err := liftesting.WriteFixtures(outDir, fixtures); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Wrote %d fixture(s) to %s\n", len(fixtures), outDir)
+	fmt.Printf("🔍 Review the fixtures for sensitive data before committing them\n")
+	return nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// RoutesCommand lists an application's routes and their metadata
+type RoutesCommand struct{}
+
+func (c *RoutesCommand) Name() string        { return "routes" }
+func (c *RoutesCommand) Description() string { return "List routes and their metadata" }
+func (c *RoutesCommand) Usage() string       { return "lift routes [package|routes.json] [--meta=key]" }
+
+// Execute lists routes from a JSON file written by app.WriteRoutes, or by
+// running the package with LIFT_PRINT_ROUTES set. The package's main must call
+// app.RunLocalTest() for the latter.
+func (c *RoutesCommand) Execute(ctx context.Context, args []string) error {
+	source := "."
+	var filter []string
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--meta="):
+			filter = strings.Split(strings.TrimPrefix(arg, "--meta="), ",")
+		case !strings.HasPrefix(arg, "--"):
+			source = arg
+		}
+	}
+
+	var raw []byte
+	var err error
+	if strings.HasSuffix(source, ".json") {
+		raw, err = os.ReadFile(source)
+	} else {
+		raw, err = runForRoutes(ctx, source)
+	}
+	if err != nil {
+		return err
+	}
+
+	var routes []lift.RouteInfo
+	if err := json.Unmarshal(raw, &routes); err != nil {
+		return fmt.Errorf("failed to parse routes: %w", err)
+	}
+
+	return printRoutes(os.Stdout, routes, filter)
+}
+
+// runForRoutes runs the package locally and captures the route table it prints
+func runForRoutes(ctx context.Context, pkg string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "go", "run", pkg)
+	cmd.Env = append(os.Environ(), "LIFT_PRINT_ROUTES=1")
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", pkg, err)
+	}
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s printed no routes; make sure main calls app.RunLocalTest()", pkg)
+	}
+	return out, nil
+}
+
+// printRoutes writes routes as a table. With filter set, only routes that have
+// at least one of the metadata keys are listed.
+func printRoutes(w io.Writer, routes []lift.RouteInfo, filter []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tMETADATA")
+
+	for _, route := range routes {
+		if len(filter) > 0 && !hasAnyMeta(route, filter) {
+			continue
+		}
+
+		keys := make([]string, 0, len(route.Metadata))
+		for key := range route.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, key+"="+route.Metadata[key])
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", route.Method, route.Path, strings.Join(pairs, " "))
+	}
+
+	return tw.Flush()
+}
+
+func hasAnyMeta(route lift.RouteInfo, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := route.Metadata[key]; ok {
+			return true
+		}
+	}
+	return false
+}
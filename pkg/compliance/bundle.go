@@ -0,0 +1,133 @@
+package compliance
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// exportBundle is the JSON form of an export
+type exportBundle struct {
+	RequestID   string                      `json:"request_id"`
+	Subject     Subject                     `json:"subject"`
+	GeneratedAt time.Time                   `json:"generated_at"`
+	Entities    map[string][]map[string]any `json:"entities"`
+}
+
+// bundle encodes exported records in the request's format, returning the
+// body, content type and file extension
+func bundle(request *SubjectRequest, records map[string][]map[string]any, now time.Time) ([]byte, string, string, error) {
+	if request.Format == FormatCSV {
+		body, err := csvBundle(request, records, now)
+		return body, "application/zip", ".zip", err
+	}
+
+	body, err := json.MarshalIndent(exportBundle{
+		RequestID:   request.ID,
+		Subject:     request.Subject,
+		GeneratedAt: now,
+		Entities:    records,
+	}, "", "  ")
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to encode export: %w", err)
+	}
+	return body, "application/json", ".json", nil
+}
+
+// csvBundle zips one CSV file per entity plus a manifest. Columns are the
+// union of the records' fields, sorted; nested values are written as JSON.
+func csvBundle(request *SubjectRequest, records map[string][]map[string]any, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	manifest, err := archive.Create("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(manifest).Encode(exportBundle{
+		RequestID:   request.ID,
+		Subject:     request.Subject,
+		GeneratedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		file, err := archive.Create(name + ".csv")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeCSV(file, records[name]); err != nil {
+			return nil, fmt.Errorf("failed to write %s.csv: %w", name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCSV writes rows under a header of every column they use
+func writeCSV(w io.Writer, rows []map[string]any) error {
+	seen := map[string]bool{}
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = csvValue(row[column])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue formats a field for a CSV cell
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	case bool, int, int32, int64, float32, float64:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
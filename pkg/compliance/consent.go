@@ -0,0 +1,460 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDocumentNotFound is returned when no consent document has been
+	// published for a purpose, or the requested version doesn't exist
+	ErrDocumentNotFound = errors.New("consent document not found")
+	// ErrDocumentExists is returned when publishing a version that already exists
+	ErrDocumentExists = errors.New("consent document version already published")
+	// ErrConsentNotFound is returned when withdrawing consent that was never given
+	ErrConsentNotFound = errors.New("consent not found")
+)
+
+// ConsentDocument is a published version of the terms a subject agrees to
+// for one purpose of processing, such as "marketing" or "research"
+type ConsentDocument struct {
+	Purpose string `json:"purpose"`
+	Version int    `json:"version"`
+	Title   string `json:"title"`
+	// URL points to the full text shown to the subject
+	URL string `json:"url,omitempty"`
+	// Material marks a change that invalidates consent given to earlier
+	// versions, so subjects must consent again
+	Material    bool      `json:"material"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// ConsentState is whether consent is currently given
+type ConsentState string
+
+const (
+	ConsentGranted   ConsentState = "granted"
+	ConsentWithdrawn ConsentState = "withdrawn"
+)
+
+// ConsentEvent is one grant or withdrawal in a consent record's history
+type ConsentEvent struct {
+	State   ConsentState `json:"state" dynamodbav:"state"`
+	Version int          `json:"version" dynamodbav:"version"`
+	// Source records how consent was collected or withdrawn, e.g.
+	// "signup-form" or "preference-center"
+	Source string    `json:"source,omitempty" dynamodbav:"source,omitempty"`
+	At     time.Time `json:"at" dynamodbav:"at"`
+}
+
+// ConsentRecord is a subject's consent for one purpose. History keeps every
+// grant and withdrawal as evidence of when consent was held.
+type ConsentRecord struct {
+	Subject   Subject        `json:"subject"`
+	Purpose   string         `json:"purpose"`
+	State     ConsentState   `json:"state"`
+	Version   int            `json:"version"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	History   []ConsentEvent `json:"history"`
+}
+
+// ConsentStatus reports whether a subject's consent for a purpose is valid
+type ConsentStatus struct {
+	Purpose string `json:"purpose"`
+	// Granted is true when consent is given for a version that is still valid
+	Granted bool `json:"granted"`
+	// Version is the document version the subject consented to, if any
+	Version        int `json:"version,omitempty"`
+	CurrentVersion int `json:"current_version"`
+	// Reconsent is true when the subject consented to a version that has
+	// since been superseded by a material change
+	Reconsent bool `json:"reconsent,omitempty"`
+}
+
+// ConsentStore persists consent documents and records
+type ConsentStore interface {
+	// SaveDocument stores a new document version, returning
+	// ErrDocumentExists if the version is already published
+	SaveDocument(ctx context.Context, doc ConsentDocument) error
+	// Documents returns the versions published for purpose, oldest first
+	Documents(ctx context.Context, purpose string) ([]ConsentDocument, error)
+	// GetConsent returns the subject's record for purpose, or nil if none
+	GetConsent(ctx context.Context, subject Subject, purpose string) (*ConsentRecord, error)
+	ListConsents(ctx context.Context, subject Subject) ([]ConsentRecord, error)
+	SaveConsent(ctx context.Context, record *ConsentRecord) error
+	DeleteConsents(ctx context.Context, subject Subject) (int, error)
+}
+
+// WithdrawalHandler stops processing that relied on a withdrawn consent,
+// e.g. unsubscribing from mailing lists. It must be idempotent, since a
+// failed withdrawal is retried by withdrawing again.
+type WithdrawalHandler func(ctx context.Context, record ConsentRecord) error
+
+// ConsentConfig configures a ConsentService
+type ConsentConfig struct {
+	// Store persists documents and records (default: in memory)
+	Store ConsentStore
+	// OnChange receives every grant and withdrawal, e.g. to forward it to
+	// the security audit log
+	OnChange func(ctx context.Context, record ConsentRecord, event ConsentEvent)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// ConsentService records consent against versioned documents and checks
+// whether it is held:
+//
+//	consents := compliance.NewConsentService(compliance.ConsentConfig{
+//		Store: compliance.NewDynamoDBConsentStore(client, "consents"),
+//	})
+//	consents.OnWithdraw("marketing", unsubscribeEverywhere)
+//
+//	requireMarketing := compliance.RequireConsent(consents, "marketing")
+//	app.POST("/campaigns/:id/enroll", requireMarketing(lift.HandlerFunc(enroll)))
+type ConsentService struct {
+	config ConsentConfig
+
+	mu          sync.RWMutex
+	withdrawals map[string][]WithdrawalHandler
+}
+
+// NewConsentService creates a consent service
+func NewConsentService(config ConsentConfig) *ConsentService {
+	if config.Store == nil {
+		config.Store = NewMemoryConsentStore()
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return &ConsentService{config: config, withdrawals: make(map[string][]WithdrawalHandler)}
+}
+
+// OnWithdraw registers a handler run when consent for purpose is withdrawn
+func (s *ConsentService) OnWithdraw(purpose string, handler WithdrawalHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.withdrawals[purpose] = append(s.withdrawals[purpose], handler)
+}
+
+// Publish stores a new document version. A zero Version publishes the next
+// version after the latest; the first version is always material.
+func (s *ConsentService) Publish(ctx context.Context, doc ConsentDocument) (*ConsentDocument, error) {
+	if doc.Purpose == "" {
+		return nil, fmt.Errorf("consent document must have a purpose")
+	}
+
+	docs, err := s.config.Store.Documents(ctx, doc.Purpose)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Version == 0 {
+		doc.Version = 1
+		if len(docs) > 0 {
+			doc.Version = docs[len(docs)-1].Version + 1
+		}
+	}
+	if len(docs) == 0 {
+		doc.Material = true
+	}
+	if doc.PublishedAt.IsZero() {
+		doc.PublishedAt = s.config.Now().UTC()
+	}
+
+	if err := s.config.Store.SaveDocument(ctx, doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Current returns the latest document for purpose
+func (s *ConsentService) Current(ctx context.Context, purpose string) (*ConsentDocument, error) {
+	docs, err := s.config.Store.Documents(ctx, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, purpose)
+	}
+	return &docs[len(docs)-1], nil
+}
+
+// Grant records the subject's consent to a document version; 0 means the
+// current version
+func (s *ConsentService) Grant(ctx context.Context, subject Subject, purpose string, version int, source string) (*ConsentRecord, error) {
+	docs, err := s.config.Store.Documents(ctx, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, purpose)
+	}
+	if version == 0 {
+		version = docs[len(docs)-1].Version
+	} else if !hasVersion(docs, version) {
+		return nil, fmt.Errorf("%w: %s version %d", ErrDocumentNotFound, purpose, version)
+	}
+
+	return s.change(ctx, subject, purpose, ConsentEvent{State: ConsentGranted, Version: version, Source: source})
+}
+
+// Withdraw records the withdrawal of the subject's consent for purpose and
+// runs the purpose's withdrawal handlers. The withdrawal is saved before the
+// handlers run; if any fail, the error is returned and calling Withdraw
+// again retries them.
+func (s *ConsentService) Withdraw(ctx context.Context, subject Subject, purpose string, source string) (*ConsentRecord, error) {
+	record, err := s.config.Store.GetConsent(ctx, subject, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: %s", ErrConsentNotFound, purpose)
+	}
+
+	if record.State != ConsentWithdrawn {
+		record, err = s.change(ctx, subject, purpose, ConsentEvent{State: ConsentWithdrawn, Version: record.Version, Source: source})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	handlers := s.withdrawals[purpose]
+	s.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, *record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return record, fmt.Errorf("consent withdrawn but handlers failed: %w", errors.Join(errs...))
+	}
+	return record, nil
+}
+
+// Status reports whether the subject holds valid consent for purpose
+func (s *ConsentService) Status(ctx context.Context, subject Subject, purpose string) (ConsentStatus, error) {
+	docs, err := s.config.Store.Documents(ctx, purpose)
+	if err != nil {
+		return ConsentStatus{}, err
+	}
+	record, err := s.config.Store.GetConsent(ctx, subject, purpose)
+	if err != nil {
+		return ConsentStatus{}, err
+	}
+	return consentStatus(purpose, docs, record), nil
+}
+
+// Statuses reports the subject's consent for every purpose they have a
+// record for
+func (s *ConsentService) Statuses(ctx context.Context, subject Subject) ([]ConsentStatus, error) {
+	records, err := s.config.Store.ListConsents(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ConsentStatus, 0, len(records))
+	for i := range records {
+		docs, err := s.config.Store.Documents(ctx, records[i].Purpose)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, consentStatus(records[i].Purpose, docs, &records[i]))
+	}
+	return statuses, nil
+}
+
+// Entity exposes consent records to the data subject request Toolkit, so
+// they are included in exports and deleted on erasure
+func (s *ConsentService) Entity() Entity {
+	return Entity{
+		Name: "consents",
+		Export: func(ctx context.Context, subject Subject) ([]map[string]any, error) {
+			records, err := s.config.Store.ListConsents(ctx, subject)
+			if err != nil {
+				return nil, err
+			}
+
+			rows := make([]map[string]any, 0, len(records))
+			for _, record := range records {
+				rows = append(rows, map[string]any{
+					"purpose":    record.Purpose,
+					"state":      record.State,
+					"version":    record.Version,
+					"updated_at": record.UpdatedAt,
+					"history":    record.History,
+				})
+			}
+			return rows, nil
+		},
+		Erase: func(ctx context.Context, subject Subject) (int, error) {
+			return s.config.Store.DeleteConsents(ctx, subject)
+		},
+	}
+}
+
+// change appends an event to the subject's record and saves it
+func (s *ConsentService) change(ctx context.Context, subject Subject, purpose string, event ConsentEvent) (*ConsentRecord, error) {
+	record, err := s.config.Store.GetConsent(ctx, subject, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		record = &ConsentRecord{Subject: subject, Purpose: purpose}
+	}
+
+	event.At = s.config.Now().UTC()
+	record.State = event.State
+	record.Version = event.Version
+	record.UpdatedAt = event.At
+	record.History = append(record.History, event)
+
+	if err := s.config.Store.SaveConsent(ctx, record); err != nil {
+		return nil, err
+	}
+	if s.config.OnChange != nil {
+		s.config.OnChange(ctx, *record, event)
+	}
+	return record, nil
+}
+
+// consentStatus evaluates a record against the published documents.
+// Consent is valid for the latest material version and anything after it.
+func consentStatus(purpose string, docs []ConsentDocument, record *ConsentRecord) ConsentStatus {
+	status := ConsentStatus{Purpose: purpose}
+	if len(docs) > 0 {
+		status.CurrentVersion = docs[len(docs)-1].Version
+	}
+	if record == nil || record.State != ConsentGranted {
+		return status
+	}
+
+	status.Version = record.Version
+	minimum := 0
+	for _, doc := range docs {
+		if doc.Material {
+			minimum = doc.Version
+		}
+	}
+	if record.Version >= minimum {
+		status.Granted = true
+	} else {
+		status.Reconsent = true
+	}
+	return status
+}
+
+func hasVersion(docs []ConsentDocument, version int) bool {
+	for _, doc := range docs {
+		if doc.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryConsentStore keeps consent in memory, for tests and local development
+type MemoryConsentStore struct {
+	mu        sync.RWMutex
+	documents map[string][]ConsentDocument
+	records   map[string]ConsentRecord
+}
+
+// NewMemoryConsentStore creates an in-memory consent store
+func NewMemoryConsentStore() *MemoryConsentStore {
+	return &MemoryConsentStore{
+		documents: make(map[string][]ConsentDocument),
+		records:   make(map[string]ConsentRecord),
+	}
+}
+
+// SaveDocument stores a document version
+func (m *MemoryConsentStore) SaveDocument(ctx context.Context, doc ConsentDocument) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs := m.documents[doc.Purpose]
+	if hasVersion(docs, doc.Version) {
+		return fmt.Errorf("%w: %s version %d", ErrDocumentExists, doc.Purpose, doc.Version)
+	}
+	docs = append(docs, doc)
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Version < docs[j].Version })
+	m.documents[doc.Purpose] = docs
+	return nil
+}
+
+// Documents returns the versions published for purpose, oldest first
+func (m *MemoryConsentStore) Documents(ctx context.Context, purpose string) ([]ConsentDocument, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]ConsentDocument(nil), m.documents[purpose]...), nil
+}
+
+// GetConsent returns a copy of the subject's record for purpose
+func (m *MemoryConsentStore) GetConsent(ctx context.Context, subject Subject, purpose string) (*ConsentRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	record, ok := m.records[consentKey(subject)+purpose]
+	if !ok {
+		return nil, nil
+	}
+	return copyConsent(&record), nil
+}
+
+// ListConsents returns the subject's records ordered by purpose
+func (m *MemoryConsentStore) ListConsents(ctx context.Context, subject Subject) ([]ConsentRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var records []ConsentRecord
+	for _, record := range m.records {
+		if record.Subject == subject {
+			records = append(records, *copyConsent(&record))
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Purpose < records[j].Purpose })
+	return records, nil
+}
+
+// SaveConsent stores a copy of the record
+func (m *MemoryConsentStore) SaveConsent(ctx context.Context, record *ConsentRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[consentKey(record.Subject)+record.Purpose] = *copyConsent(record)
+	return nil
+}
+
+// DeleteConsents removes all of the subject's records
+func (m *MemoryConsentStore) DeleteConsents(ctx context.Context, subject Subject) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := 0
+	for key, record := range m.records {
+		if record.Subject == subject {
+			delete(m.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// consentKey is the key prefix for a subject's records
+func consentKey(subject Subject) string {
+	return fmt.Sprintf("consent#%s#%s#", subject.TenantID, subject.ID)
+}
+
+// copyConsent copies a record so stored records can't be changed in place
+func copyConsent(record *ConsentRecord) *ConsentRecord {
+	copied := *record
+	copied.History = append([]ConsentEvent(nil), record.History...)
+	return &copied
+}
@@ -0,0 +1,236 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBConsentStore implements ConsentStore using a DynamoDB table with
+// a string partition key "pk" and sort key "sk". A subject's records share
+// a partition with one item per purpose, and each purpose's documents share
+// a partition with one item per version.
+type DynamoDBConsentStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBConsentStore creates a DynamoDB-backed consent store
+func NewDynamoDBConsentStore(client *dynamodb.Client, tableName string) *DynamoDBConsentStore {
+	return &DynamoDBConsentStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// dynamoDBConsentDocument is the DynamoDB item structure for a document version
+type dynamoDBConsentDocument struct {
+	PK          string    `dynamodbav:"pk"` // "consent-document#<purpose>"
+	SK          string    `dynamodbav:"sk"` // "version#<zero-padded version>"
+	Purpose     string    `dynamodbav:"purpose"`
+	Version     int       `dynamodbav:"version"`
+	Title       string    `dynamodbav:"title"`
+	URL         string    `dynamodbav:"url,omitempty"`
+	Material    bool      `dynamodbav:"material"`
+	PublishedAt time.Time `dynamodbav:"published_at"`
+}
+
+// dynamoDBConsentRecord is the DynamoDB item structure for a consent record
+type dynamoDBConsentRecord struct {
+	PK        string         `dynamodbav:"pk"` // "consent#<tenant>#<subject>#"
+	SK        string         `dynamodbav:"sk"` // purpose
+	SubjectID string         `dynamodbav:"subject_id"`
+	TenantID  string         `dynamodbav:"tenant_id,omitempty"`
+	Purpose   string         `dynamodbav:"purpose"`
+	State     ConsentState   `dynamodbav:"state"`
+	Version   int            `dynamodbav:"version"`
+	UpdatedAt time.Time      `dynamodbav:"updated_at"`
+	History   []ConsentEvent `dynamodbav:"history"`
+}
+
+// SaveDocument conditionally writes a new document version
+func (d *DynamoDBConsentStore) SaveDocument(ctx context.Context, doc ConsentDocument) error {
+	av, err := attributevalue.MarshalMap(dynamoDBConsentDocument{
+		PK:          documentKey(doc.Purpose),
+		SK:          fmt.Sprintf("version#%010d", doc.Version),
+		Purpose:     doc.Purpose,
+		Version:     doc.Version,
+		Title:       doc.Title,
+		URL:         doc.URL,
+		Material:    doc.Material,
+		PublishedAt: doc.PublishedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.tableName),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w: %s version %d", ErrDocumentExists, doc.Purpose, doc.Version)
+		}
+		return err
+	}
+	return nil
+}
+
+// Documents queries the purpose's document versions, oldest first
+func (d *DynamoDBConsentStore) Documents(ctx context.Context, purpose string) ([]ConsentDocument, error) {
+	var docs []ConsentDocument
+	err := d.query(ctx, documentKey(purpose), func(item map[string]types.AttributeValue) error {
+		var dbDoc dynamoDBConsentDocument
+		if err := attributevalue.UnmarshalMap(item, &dbDoc); err != nil {
+			return err
+		}
+		docs = append(docs, ConsentDocument{
+			Purpose:     dbDoc.Purpose,
+			Version:     dbDoc.Version,
+			Title:       dbDoc.Title,
+			URL:         dbDoc.URL,
+			Material:    dbDoc.Material,
+			PublishedAt: dbDoc.PublishedAt,
+		})
+		return nil
+	})
+	return docs, err
+}
+
+// GetConsent reads the subject's record for purpose
+func (d *DynamoDBConsentStore) GetConsent(ctx context.Context, subject Subject, purpose string) (*ConsentRecord, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: consentKey(subject)},
+			"sk": &types.AttributeValueMemberS{Value: purpose},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var dbRecord dynamoDBConsentRecord
+	if err := attributevalue.UnmarshalMap(result.Item, &dbRecord); err != nil {
+		return nil, err
+	}
+	return dbRecord.record(), nil
+}
+
+// ListConsents queries the subject's records, ordered by purpose
+func (d *DynamoDBConsentStore) ListConsents(ctx context.Context, subject Subject) ([]ConsentRecord, error) {
+	var records []ConsentRecord
+	err := d.query(ctx, consentKey(subject), func(item map[string]types.AttributeValue) error {
+		var dbRecord dynamoDBConsentRecord
+		if err := attributevalue.UnmarshalMap(item, &dbRecord); err != nil {
+			return err
+		}
+		records = append(records, *dbRecord.record())
+		return nil
+	})
+	return records, err
+}
+
+// SaveConsent writes the record
+func (d *DynamoDBConsentStore) SaveConsent(ctx context.Context, record *ConsentRecord) error {
+	av, err := attributevalue.MarshalMap(dynamoDBConsentRecord{
+		PK:        consentKey(record.Subject),
+		SK:        record.Purpose,
+		SubjectID: record.Subject.ID,
+		TenantID:  record.Subject.TenantID,
+		Purpose:   record.Purpose,
+		State:     record.State,
+		Version:   record.Version,
+		UpdatedAt: record.UpdatedAt,
+		History:   record.History,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      av,
+	})
+	return err
+}
+
+// DeleteConsents deletes every record in the subject's partition
+func (d *DynamoDBConsentStore) DeleteConsents(ctx context.Context, subject Subject) (int, error) {
+	records, err := d.ListConsents(ctx, subject)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, record := range records {
+		_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(d.tableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: consentKey(subject)},
+				"sk": &types.AttributeValueMemberS{Value: record.Purpose},
+			},
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// query reads every item in a partition in sort key order
+func (d *DynamoDBConsentStore) query(ctx context.Context, pk string, fn func(map[string]types.AttributeValue) error) error {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	for {
+		result, err := d.client.Query(ctx, input)
+		if err != nil {
+			return err
+		}
+		for _, item := range result.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (r dynamoDBConsentRecord) record() *ConsentRecord {
+	return &ConsentRecord{
+		Subject:   Subject{ID: r.SubjectID, TenantID: r.TenantID},
+		Purpose:   r.Purpose,
+		State:     r.State,
+		Version:   r.Version,
+		UpdatedAt: r.UpdatedAt,
+		History:   r.History,
+	}
+}
+
+// documentKey is the partition key for a purpose's documents
+func documentKey(purpose string) string {
+	return "consent-document#" + purpose
+}
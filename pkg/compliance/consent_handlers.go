@@ -0,0 +1,144 @@
+package compliance
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// RequireConsent rejects requests unless the authenticated user holds valid
+// consent for every purpose. Wrap the handlers of routes that need it:
+//
+//	requireResearch := compliance.RequireConsent(consents, "research")
+//	app.POST("/studies/:id/enroll", requireResearch(lift.HandlerFunc(enroll)))
+//
+// The subject is the authenticated user within the caller's tenant.
+// Missing or outdated consent is a 403 CONSENT_REQUIRED error whose details
+// name the purpose and current document version, so clients can prompt the
+// user to consent again.
+func RequireConsent(service *ConsentService, purposes ...string) lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			subject, err := contextSubject(ctx)
+			if err != nil {
+				return err
+			}
+
+			for _, purpose := range purposes {
+				status, err := service.Status(ctx.Context, subject, purpose)
+				if err != nil {
+					return lift.NewLiftError("CONSENT_CHECK_FAILED", "Failed to check consent", 500).WithCause(err)
+				}
+				if !status.Granted {
+					return lift.NewLiftError("CONSENT_REQUIRED", "Consent is required for "+purpose, 403).
+						WithDetail("purpose", purpose).
+						WithDetail("current_version", status.CurrentVersion).
+						WithDetail("reconsent", status.Reconsent)
+				}
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// consentBody is the body accepted by GrantHandler and WithdrawHandler
+type consentBody struct {
+	Version int    `json:"version"`
+	Source  string `json:"source"`
+}
+
+// StatusHandler lists the authenticated user's consents. The handlers act
+// on the caller's own consent, for a preference center:
+//
+//	me := app.Group("/me/consents")
+//	me.GET("", consents.StatusHandler())
+//	me.PUT("/:purpose", consents.GrantHandler())
+//	me.DELETE("/:purpose", consents.WithdrawHandler())
+func (s *ConsentService) StatusHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		subject, err := contextSubject(ctx)
+		if err != nil {
+			return err
+		}
+
+		statuses, err := s.Statuses(ctx.Context, subject)
+		if err != nil {
+			return consentError(err)
+		}
+		return ctx.OK(map[string]any{"consents": statuses})
+	})
+}
+
+// GrantHandler records consent for the purpose parameter. The body may
+// name the document version shown to the user; it defaults to the current
+// version.
+func (s *ConsentService) GrantHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		subject, err := contextSubject(ctx)
+		if err != nil {
+			return err
+		}
+		body, err := parseConsentBody(ctx)
+		if err != nil {
+			return err
+		}
+
+		record, err := s.Grant(ctx.Context, subject, ctx.Param("purpose"), body.Version, body.Source)
+		if err != nil {
+			return consentError(err)
+		}
+		return ctx.OK(record)
+	})
+}
+
+// WithdrawHandler withdraws consent for the purpose parameter
+func (s *ConsentService) WithdrawHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		subject, err := contextSubject(ctx)
+		if err != nil {
+			return err
+		}
+		body, err := parseConsentBody(ctx)
+		if err != nil {
+			return err
+		}
+
+		record, err := s.Withdraw(ctx.Context, subject, ctx.Param("purpose"), body.Source)
+		if err != nil {
+			return consentError(err)
+		}
+		return ctx.OK(record)
+	})
+}
+
+// contextSubject returns the authenticated user as a consent subject
+func contextSubject(ctx *lift.Context) (Subject, error) {
+	if ctx.UserID() == "" {
+		return Subject{}, lift.Unauthorized("Authentication required")
+	}
+	return Subject{ID: ctx.UserID(), TenantID: ctx.TenantID()}, nil
+}
+
+// parseConsentBody parses an optional consent body
+func parseConsentBody(ctx *lift.Context) (consentBody, error) {
+	var body consentBody
+	if len(ctx.Request.Body) > 0 {
+		if err := json.Unmarshal(ctx.Request.Body, &body); err != nil {
+			return body, lift.NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+		}
+	}
+	return body, nil
+}
+
+// consentError maps consent service errors to HTTP errors
+func consentError(err error) error {
+	switch {
+	case errors.Is(err, ErrDocumentNotFound):
+		return lift.NewLiftError("CONSENT_DOCUMENT_NOT_FOUND", err.Error(), 404)
+	case errors.Is(err, ErrConsentNotFound):
+		return lift.NewLiftError("CONSENT_NOT_FOUND", err.Error(), 404)
+	default:
+		return lift.NewLiftError("CONSENT_FAILED", "Failed to update consent", 500).WithCause(err)
+	}
+}
@@ -0,0 +1,199 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsentVersioning(t *testing.T) {
+	ctx := context.Background()
+	service := NewConsentService(ConsentConfig{})
+	subject := Subject{ID: "user-1", TenantID: "tenant-a"}
+
+	_, err := service.Grant(ctx, subject, "research", 0, "signup")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	v1, err := service.Publish(ctx, ConsentDocument{Purpose: "research", Title: "Research use"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+	assert.True(t, v1.Material)
+
+	_, err = service.Publish(ctx, ConsentDocument{Purpose: "research", Version: 1})
+	assert.ErrorIs(t, err, ErrDocumentExists)
+
+	record, err := service.Grant(ctx, subject, "research", 0, "signup")
+	require.NoError(t, err)
+	assert.Equal(t, ConsentGranted, record.State)
+	assert.Equal(t, 1, record.Version)
+
+	// A minor revision keeps existing consent valid
+	v2, err := service.Publish(ctx, ConsentDocument{Purpose: "research", Title: "Typo fixes"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+
+	status, err := service.Status(ctx, subject, "research")
+	require.NoError(t, err)
+	assert.Equal(t, ConsentStatus{Purpose: "research", Granted: true, Version: 1, CurrentVersion: 2}, status)
+
+	// A material revision requires consent again
+	_, err = service.Publish(ctx, ConsentDocument{Purpose: "research", Title: "New data sharing", Material: true})
+	require.NoError(t, err)
+
+	status, err = service.Status(ctx, subject, "research")
+	require.NoError(t, err)
+	assert.False(t, status.Granted)
+	assert.True(t, status.Reconsent)
+	assert.Equal(t, 3, status.CurrentVersion)
+
+	_, err = service.Grant(ctx, subject, "research", 9, "preference-center")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	record, err = service.Grant(ctx, subject, "research", 3, "preference-center")
+	require.NoError(t, err)
+	assert.Len(t, record.History, 2)
+
+	status, err = service.Status(ctx, subject, "research")
+	require.NoError(t, err)
+	assert.True(t, status.Granted)
+
+	// Consent is scoped to the tenant
+	status, err = service.Status(ctx, Subject{ID: "user-1", TenantID: "tenant-b"}, "research")
+	require.NoError(t, err)
+	assert.False(t, status.Granted)
+}
+
+func TestConsentWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	var changes []ConsentEvent
+	service := NewConsentService(ConsentConfig{
+		OnChange: func(ctx context.Context, record ConsentRecord, event ConsentEvent) {
+			changes = append(changes, event)
+		},
+	})
+	subject := Subject{ID: "user-1"}
+
+	failures := 1
+	var unsubscribed []string
+	service.OnWithdraw("marketing", func(ctx context.Context, record ConsentRecord) error {
+		if failures > 0 {
+			failures--
+			return errors.New("mailing list unavailable")
+		}
+		unsubscribed = append(unsubscribed, record.Subject.ID)
+		return nil
+	})
+
+	_, err := service.Withdraw(ctx, subject, "marketing", "")
+	assert.ErrorIs(t, err, ErrConsentNotFound)
+
+	_, err = service.Publish(ctx, ConsentDocument{Purpose: "marketing", Title: "Marketing emails"})
+	require.NoError(t, err)
+	_, err = service.Grant(ctx, subject, "marketing", 0, "signup")
+	require.NoError(t, err)
+
+	// The withdrawal is saved even though the handler fails
+	record, err := service.Withdraw(ctx, subject, "marketing", "unsubscribe-link")
+	require.Error(t, err)
+	assert.Equal(t, ConsentWithdrawn, record.State)
+
+	status, err := service.Status(ctx, subject, "marketing")
+	require.NoError(t, err)
+	assert.False(t, status.Granted)
+	assert.False(t, status.Reconsent)
+
+	// Withdrawing again retries the handlers without another history entry
+	record, err = service.Withdraw(ctx, subject, "marketing", "unsubscribe-link")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user-1"}, unsubscribed)
+	assert.Len(t, record.History, 2)
+	require.Len(t, changes, 2)
+	assert.Equal(t, ConsentWithdrawn, changes[1].State)
+	assert.Equal(t, "unsubscribe-link", changes[1].Source)
+}
+
+func TestConsentEntity(t *testing.T) {
+	ctx := context.Background()
+	service := NewConsentService(ConsentConfig{})
+	subject := Subject{ID: "user-1"}
+
+	_, err := service.Publish(ctx, ConsentDocument{Purpose: "marketing"})
+	require.NoError(t, err)
+	_, err = service.Grant(ctx, subject, "marketing", 0, "signup")
+	require.NoError(t, err)
+
+	entity := service.Entity()
+	rows, err := entity.Export(ctx, subject)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "marketing", rows[0]["purpose"])
+
+	erased, err := entity.Erase(ctx, subject)
+	require.NoError(t, err)
+	assert.Equal(t, 1, erased)
+
+	statuses, err := service.Statuses(ctx, subject)
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+}
+
+func TestRequireConsentAndHandlers(t *testing.T) {
+	service := NewConsentService(ConsentConfig{})
+	_, err := service.Publish(context.Background(), ConsentDocument{Purpose: "research"})
+	require.NoError(t, err)
+
+	call := func(handler lift.Handler, user, purpose, body string) (*lift.Context, error) {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method: "PUT",
+			Path:   "/me/consents/" + purpose,
+			Body:   []byte(body),
+		}))
+		ctx.SetUserID(user)
+		ctx.SetTenantID("tenant-a")
+		ctx.SetParam("purpose", purpose)
+		return ctx, handler.Handle(ctx)
+	}
+
+	enroll := RequireConsent(service, "research")(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return ctx.OK(map[string]string{"status": "enrolled"})
+	}))
+
+	var liftErr *lift.LiftError
+	_, err = call(enroll, "", "", "")
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 401, liftErr.StatusCode)
+
+	_, err = call(enroll, "user-1", "", "")
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 403, liftErr.StatusCode)
+	assert.Equal(t, "CONSENT_REQUIRED", liftErr.Code)
+	assert.Equal(t, "research", liftErr.Details["purpose"])
+
+	_, err = call(service.GrantHandler(), "user-1", "research", `{"source":"study-signup"}`)
+	require.NoError(t, err)
+
+	ctx, err := call(enroll, "user-1", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+
+	ctx, err = call(service.StatusHandler(), "user-1", "", "")
+	require.NoError(t, err)
+	statuses := ctx.Response.Body.(map[string]any)["consents"].([]ConsentStatus)
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Granted)
+
+	_, err = call(service.WithdrawHandler(), "user-1", "research", "")
+	require.NoError(t, err)
+	_, err = call(enroll, "user-1", "", "")
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 403, liftErr.StatusCode)
+
+	_, err = call(service.GrantHandler(), "user-1", "unknown", "")
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 404, liftErr.StatusCode)
+}
@@ -0,0 +1,151 @@
+package compliance
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// submitBody is the body accepted by SubmitHandler
+type submitBody struct {
+	Type      RequestType  `json:"type"`
+	SubjectID string       `json:"subject_id"`
+	Reason    string       `json:"reason"`
+	Format    ExportFormat `json:"format"`
+}
+
+// requestView is a request as returned by the handlers
+type requestView struct {
+	*SubjectRequest
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// SubmitHandler accepts new requests. The requester is the authenticated
+// user and the subject is scoped to the caller's tenant. The handlers must
+// only be reachable by privacy operators, e.g. with middleware.RouteAuth:
+//
+//	privacy := app.Group("/privacy/requests")
+//	privacy.POST("", toolkit.SubmitHandler()).Meta(lift.MetaAuth, "jwt").Meta(lift.MetaRoles, "privacy-operator")
+//	privacy.GET("/:id", toolkit.GetHandler()).Meta(lift.MetaAuth, "jwt").Meta(lift.MetaRoles, "privacy-operator")
+//	privacy.POST("/:id/approve", toolkit.ApproveHandler()).Meta(lift.MetaAuth, "jwt").Meta(lift.MetaRoles, "privacy-approver")
+//	privacy.POST("/:id/reject", toolkit.RejectHandler()).Meta(lift.MetaAuth, "jwt").Meta(lift.MetaRoles, "privacy-approver")
+func (t *Toolkit) SubmitHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var body submitBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+		if ctx.UserID() == "" {
+			return lift.Unauthorized("Authentication required")
+		}
+
+		request, err := t.Submit(ctx.Context, Submission{
+			Type:        body.Type,
+			Subject:     Subject{ID: body.SubjectID, TenantID: ctx.TenantID()},
+			RequestedBy: ctx.UserID(),
+			Reason:      body.Reason,
+			Format:      body.Format,
+		})
+		if err != nil {
+			return requestError(err)
+		}
+		return ctx.Status(202).JSON(t.view(ctx, request))
+	})
+}
+
+// GetHandler returns a request, its audit trail and, for completed
+// exports, a short-lived download URL
+func (t *Toolkit) GetHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		request, err := t.tenantRequest(ctx)
+		if err != nil {
+			return err
+		}
+		return ctx.OK(t.view(ctx, request))
+	})
+}
+
+// ApproveHandler records the authenticated user's approval
+func (t *Toolkit) ApproveHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		if _, err := t.tenantRequest(ctx); err != nil {
+			return err
+		}
+
+		request, err := t.Approve(ctx.Context, ctx.Param("id"), ctx.UserID())
+		if err != nil {
+			return requestError(err)
+		}
+		return ctx.OK(t.view(ctx, request))
+	})
+}
+
+// RejectHandler rejects a pending request; the body may include a reason
+func (t *Toolkit) RejectHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		if _, err := t.tenantRequest(ctx); err != nil {
+			return err
+		}
+
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		if len(ctx.Request.Body) > 0 {
+			if err := json.Unmarshal(ctx.Request.Body, &body); err != nil {
+				return lift.NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+			}
+		}
+
+		request, err := t.Reject(ctx.Context, ctx.Param("id"), ctx.UserID(), body.Reason)
+		if err != nil {
+			return requestError(err)
+		}
+		return ctx.OK(t.view(ctx, request))
+	})
+}
+
+// tenantRequest loads the request named by the id parameter, hiding
+// requests that belong to another tenant
+func (t *Toolkit) tenantRequest(ctx *lift.Context) (*SubjectRequest, error) {
+	request, err := t.Get(ctx.Context, ctx.Param("id"))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	if tenantID := ctx.TenantID(); tenantID != "" && request.Subject.TenantID != tenantID {
+		return nil, requestError(ErrRequestNotFound)
+	}
+	return request, nil
+}
+
+// view adds a download URL to completed exports
+func (t *Toolkit) view(ctx *lift.Context, request *SubjectRequest) requestView {
+	view := requestView{SubjectRequest: request}
+	if request.Type == RequestExport && request.Status == StatusCompleted {
+		if url, err := t.DownloadURL(ctx.Context, request); err == nil {
+			view.DownloadURL = url
+		} else if ctx.Logger != nil {
+			ctx.Logger.Warn("Failed to sign export download URL", map[string]any{
+				"request_id": request.ID,
+				"error":      err.Error(),
+			})
+		}
+	}
+	return view
+}
+
+// requestError maps toolkit errors to HTTP errors
+func requestError(err error) error {
+	switch {
+	case errors.Is(err, ErrRequestNotFound):
+		return lift.NewLiftError("REQUEST_NOT_FOUND", "Data subject request not found", 404)
+	case errors.Is(err, ErrInvalidTransition):
+		return lift.NewLiftError("INVALID_REQUEST_STATE", err.Error(), 409)
+	case errors.Is(err, ErrSelfApproval):
+		return lift.NewLiftError("SELF_APPROVAL", err.Error(), 403)
+	case errors.Is(err, ErrInvalidSubmission):
+		return lift.NewLiftError("INVALID_REQUEST", err.Error(), 400)
+	default:
+		return lift.NewLiftError("REQUEST_FAILED", "Failed to process data subject request", 500).WithCause(err)
+	}
+}
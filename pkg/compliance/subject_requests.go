@@ -0,0 +1,534 @@
+// Package compliance handles GDPR data subject requests: exporting everything
+// held about a person and erasing it on request. Services register an export
+// and an erasure function for each entity that stores personal data; the
+// toolkit runs them, bundles exports to S3, gates requests behind operator
+// approval and keeps an audit trail of every step.
+//
+// ConsentService records consent to versioned documents for each purpose of
+// processing, and RequireConsent guards routes that depend on it.
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pay-theory/lift/pkg/payload"
+)
+
+// RequestType is the kind of data subject request
+type RequestType string
+
+const (
+	// RequestExport collects the subject's data into a downloadable bundle
+	RequestExport RequestType = "export"
+	// RequestErasure deletes or anonymizes the subject's data
+	RequestErasure RequestType = "erasure"
+)
+
+// RequestStatus is the state of a data subject request
+type RequestStatus string
+
+const (
+	StatusPendingApproval RequestStatus = "pending_approval"
+	StatusApproved        RequestStatus = "approved"
+	StatusRejected        RequestStatus = "rejected"
+	StatusCompleted       RequestStatus = "completed"
+	StatusFailed          RequestStatus = "failed"
+)
+
+// ExportFormat is the format of an export bundle
+type ExportFormat string
+
+const (
+	// FormatJSON bundles all entities into one JSON document
+	FormatJSON ExportFormat = "json"
+	// FormatCSV bundles a CSV file per entity into a zip archive
+	FormatCSV ExportFormat = "csv"
+)
+
+var (
+	// ErrRequestNotFound is returned for an unknown request ID
+	ErrRequestNotFound = errors.New("data subject request not found")
+	// ErrInvalidTransition is returned when a request can't move to the requested state
+	ErrInvalidTransition = errors.New("data subject request can't be changed in its current state")
+	// ErrInvalidSubmission is returned when a submitted request is incomplete or malformed
+	ErrInvalidSubmission = errors.New("invalid data subject request")
+	// ErrSelfApproval is returned when the requester tries to approve their own request
+	ErrSelfApproval = errors.New("requests must be approved by someone other than the requester")
+)
+
+// Entity registers how one kind of stored data is exported and erased
+type Entity struct {
+	// Name identifies the entity in bundles and results (e.g. "customers")
+	Name string
+	// Export returns the subject's records; nil skips the entity in exports
+	Export func(ctx context.Context, subject Subject) ([]map[string]any, error)
+	// Erase deletes or anonymizes the subject's records and returns how many
+	// were affected. It must be idempotent so failed erasures can be retried.
+	// Nil means the entity holds nothing that must be erased.
+	Erase func(ctx context.Context, subject Subject) (int, error)
+}
+
+// Subject identifies the person a request is about
+type Subject struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// AuditEvent is one step in a request's audit trail
+type AuditEvent struct {
+	Action string    `json:"action"`
+	Actor  string    `json:"actor,omitempty"`
+	Entity string    `json:"entity,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// EntityResult reports what happened to one entity
+type EntityResult struct {
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SubjectRequest is a data subject request and its audit trail
+type SubjectRequest struct {
+	ID          string                  `json:"id"`
+	Type        RequestType             `json:"type"`
+	Subject     Subject                 `json:"subject"`
+	Format      ExportFormat            `json:"format,omitempty"`
+	Reason      string                  `json:"reason,omitempty"`
+	RequestedBy string                  `json:"requested_by"`
+	Status      RequestStatus           `json:"status"`
+	ApprovedBy  []string                `json:"approved_by,omitempty"`
+	Results     map[string]EntityResult `json:"results,omitempty"`
+	ExportKey   string                  `json:"export_key,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+	Audit       []AuditEvent            `json:"audit"`
+}
+
+// RequestStore persists data subject requests
+type RequestStore interface {
+	Save(ctx context.Context, request *SubjectRequest) error
+	// Get returns ErrRequestNotFound for an unknown ID
+	Get(ctx context.Context, id string) (*SubjectRequest, error)
+	List(ctx context.Context, status RequestStatus) ([]*SubjectRequest, error)
+}
+
+// Config configures a Toolkit
+type Config struct {
+	// Requests stores requests and their audit trails (default: in memory)
+	Requests RequestStore
+	// Exports receives export bundles; required for export requests
+	Exports payload.Store
+	// ExportPrefix prefixes export object keys (default: "subject-exports/")
+	ExportPrefix string
+	// DownloadExpiry is the lifetime of export download URLs (default: 15m)
+	DownloadExpiry time.Duration
+	// ExportApprovals and ErasureApprovals are the number of distinct
+	// operators who must approve a request before it runs (defaults: 0 and 1)
+	ExportApprovals  int
+	ErasureApprovals int
+	// Dispatch, if set, hands approved requests to a worker that calls
+	// Toolkit.Execute, instead of running them within the approving request
+	Dispatch func(ctx context.Context, requestID string) error
+	// OnAudit receives every audit event, e.g. to forward it to the
+	// security audit log
+	OnAudit func(ctx context.Context, request *SubjectRequest, event AuditEvent)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Toolkit runs data subject requests against the registered entities
+type Toolkit struct {
+	config Config
+
+	mu       sync.RWMutex
+	entities []Entity
+}
+
+// NewToolkit creates a toolkit
+func NewToolkit(config Config) *Toolkit {
+	if config.Requests == nil {
+		config.Requests = NewMemoryRequestStore()
+	}
+	if config.ExportPrefix == "" {
+		config.ExportPrefix = "subject-exports/"
+	}
+	if config.DownloadExpiry == 0 {
+		config.DownloadExpiry = payload.DefaultURLExpiry
+	}
+	if config.ErasureApprovals == 0 {
+		config.ErasureApprovals = 1
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return &Toolkit{config: config}
+}
+
+// Register adds entities. Erasure runs them in registration order, so
+// register dependent data (e.g. payment methods) before what it references.
+func (t *Toolkit) Register(entities ...Entity) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, entity := range entities {
+		if entity.Name == "" {
+			return fmt.Errorf("entity must have a name")
+		}
+		if entity.Export == nil && entity.Erase == nil {
+			return fmt.Errorf("entity %s needs an export or erase function", entity.Name)
+		}
+		for _, existing := range t.entities {
+			if existing.Name == entity.Name {
+				return fmt.Errorf("entity %s is registered twice", entity.Name)
+			}
+		}
+		t.entities = append(t.entities, entity)
+	}
+	return nil
+}
+
+// Entities returns the registered entity names
+func (t *Toolkit) Entities() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	names := make([]string, len(t.entities))
+	for i, entity := range t.entities {
+		names[i] = entity.Name
+	}
+	return names
+}
+
+// Submission describes a new request
+type Submission struct {
+	Type        RequestType
+	Subject     Subject
+	RequestedBy string
+	Reason      string
+	// Format applies to exports (default: FormatJSON)
+	Format ExportFormat
+}
+
+// Submit records a new request. Requests needing no approval run
+// immediately (or are dispatched).
+func (t *Toolkit) Submit(ctx context.Context, submission Submission) (*SubjectRequest, error) {
+	format := submission.Format
+	switch submission.Type {
+	case RequestExport:
+		if t.config.Exports == nil {
+			return nil, fmt.Errorf("%w: exports require an export store", ErrInvalidSubmission)
+		}
+		if format == "" {
+			format = FormatJSON
+		}
+		if format != FormatJSON && format != FormatCSV {
+			return nil, fmt.Errorf("%w: unknown export format %q", ErrInvalidSubmission, format)
+		}
+	case RequestErasure:
+		format = ""
+	default:
+		return nil, fmt.Errorf("%w: unknown request type %q", ErrInvalidSubmission, submission.Type)
+	}
+	if submission.Subject.ID == "" {
+		return nil, fmt.Errorf("%w: subject ID is required", ErrInvalidSubmission)
+	}
+	if submission.RequestedBy == "" {
+		return nil, fmt.Errorf("%w: requester is required", ErrInvalidSubmission)
+	}
+
+	now := t.config.Now().UTC()
+	request := &SubjectRequest{
+		ID:          uuid.NewString(),
+		Type:        submission.Type,
+		Subject:     submission.Subject,
+		Format:      format,
+		Reason:      submission.Reason,
+		RequestedBy: submission.RequestedBy,
+		Status:      StatusPendingApproval,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	t.audit(ctx, request, AuditEvent{Action: "submitted", Actor: request.RequestedBy, Detail: request.Reason})
+
+	if t.requiredApprovals(request) == 0 {
+		request.Status = StatusApproved
+	}
+	if err := t.config.Requests.Save(ctx, request); err != nil {
+		return nil, err
+	}
+
+	if request.Status == StatusApproved {
+		return t.start(ctx, request)
+	}
+	return request, nil
+}
+
+// Approve records an operator's approval; once enough distinct operators
+// have approved, the request runs (or is dispatched)
+func (t *Toolkit) Approve(ctx context.Context, id, approver string) (*SubjectRequest, error) {
+	request, err := t.config.Requests.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusPendingApproval {
+		return nil, ErrInvalidTransition
+	}
+	if approver == "" {
+		return nil, fmt.Errorf("%w: approver is required", ErrInvalidSubmission)
+	}
+	if approver == request.RequestedBy {
+		return nil, ErrSelfApproval
+	}
+	for _, existing := range request.ApprovedBy {
+		if existing == approver {
+			return request, nil
+		}
+	}
+
+	request.ApprovedBy = append(request.ApprovedBy, approver)
+	t.audit(ctx, request, AuditEvent{Action: "approved", Actor: approver})
+	if len(request.ApprovedBy) >= t.requiredApprovals(request) {
+		request.Status = StatusApproved
+	}
+	if err := t.config.Requests.Save(ctx, request); err != nil {
+		return nil, err
+	}
+
+	if request.Status == StatusApproved {
+		return t.start(ctx, request)
+	}
+	return request, nil
+}
+
+// Reject closes a pending request without running it
+func (t *Toolkit) Reject(ctx context.Context, id, operator, reason string) (*SubjectRequest, error) {
+	request, err := t.config.Requests.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusPendingApproval {
+		return nil, ErrInvalidTransition
+	}
+
+	request.Status = StatusRejected
+	t.audit(ctx, request, AuditEvent{Action: "rejected", Actor: operator, Detail: reason})
+	if err := t.config.Requests.Save(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Get returns a request
+func (t *Toolkit) Get(ctx context.Context, id string) (*SubjectRequest, error) {
+	return t.config.Requests.Get(ctx, id)
+}
+
+// DownloadURL returns a presigned URL for a completed export
+func (t *Toolkit) DownloadURL(ctx context.Context, request *SubjectRequest) (string, error) {
+	if request.Type != RequestExport || request.Status != StatusCompleted || request.ExportKey == "" {
+		return "", ErrInvalidTransition
+	}
+	return t.config.Exports.PresignGet(ctx, request.ExportKey, t.config.DownloadExpiry)
+}
+
+// Execute runs an approved request. Failed requests can be executed again;
+// erasure functions are idempotent and exports are rebuilt from scratch.
+func (t *Toolkit) Execute(ctx context.Context, id string) (*SubjectRequest, error) {
+	request, err := t.config.Requests.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusApproved && request.Status != StatusFailed {
+		return nil, ErrInvalidTransition
+	}
+
+	request.Results = make(map[string]EntityResult)
+	request.Error = ""
+	switch request.Type {
+	case RequestExport:
+		err = t.export(ctx, request)
+	case RequestErasure:
+		err = t.erase(ctx, request)
+	}
+
+	if err != nil {
+		request.Status = StatusFailed
+		request.Error = err.Error()
+		t.audit(ctx, request, AuditEvent{Action: "failed", Detail: err.Error()})
+	} else {
+		request.Status = StatusCompleted
+		t.audit(ctx, request, AuditEvent{Action: "completed"})
+	}
+
+	if saveErr := t.config.Requests.Save(ctx, request); saveErr != nil {
+		return nil, saveErr
+	}
+	return request, err
+}
+
+// start runs or dispatches an approved request
+func (t *Toolkit) start(ctx context.Context, request *SubjectRequest) (*SubjectRequest, error) {
+	if t.config.Dispatch != nil {
+		if err := t.config.Dispatch(ctx, request.ID); err != nil {
+			return nil, fmt.Errorf("failed to dispatch request %s: %w", request.ID, err)
+		}
+		return request, nil
+	}
+
+	executed, err := t.Execute(ctx, request.ID)
+	if executed == nil {
+		return nil, err
+	}
+	// A failed run is recorded on the request rather than returned
+	return executed, nil
+}
+
+// export collects every entity's records and uploads the bundle
+func (t *Toolkit) export(ctx context.Context, request *SubjectRequest) error {
+	records := make(map[string][]map[string]any)
+	for _, entity := range t.registered() {
+		if entity.Export == nil {
+			continue
+		}
+
+		rows, err := entity.Export(ctx, request.Subject)
+		if err != nil {
+			request.Results[entity.Name] = EntityResult{Error: err.Error()}
+			return fmt.Errorf("export of %s failed: %w", entity.Name, err)
+		}
+		records[entity.Name] = rows
+		request.Results[entity.Name] = EntityResult{Records: len(rows)}
+		t.audit(ctx, request, AuditEvent{Action: "exported", Entity: entity.Name, Detail: fmt.Sprintf("%d records", len(rows))})
+	}
+
+	body, contentType, extension, err := bundle(request, records, t.config.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%s%s", t.config.ExportPrefix, request.ID, extension)
+	if request.Subject.TenantID != "" {
+		key = fmt.Sprintf("%s%s/%s%s", t.config.ExportPrefix, request.Subject.TenantID, request.ID, extension)
+	}
+	if err := t.config.Exports.Put(ctx, key, body, contentType); err != nil {
+		return err
+	}
+	request.ExportKey = key
+	return nil
+}
+
+// erase runs every entity's erasure function in registration order
+func (t *Toolkit) erase(ctx context.Context, request *SubjectRequest) error {
+	for _, entity := range t.registered() {
+		if entity.Erase == nil {
+			continue
+		}
+
+		count, err := entity.Erase(ctx, request.Subject)
+		if err != nil {
+			request.Results[entity.Name] = EntityResult{Records: count, Error: err.Error()}
+			return fmt.Errorf("erasure of %s failed: %w", entity.Name, err)
+		}
+		request.Results[entity.Name] = EntityResult{Records: count}
+		t.audit(ctx, request, AuditEvent{Action: "erased", Entity: entity.Name, Detail: fmt.Sprintf("%d records", count)})
+	}
+	return nil
+}
+
+// requiredApprovals returns the number of approvals the request needs
+func (t *Toolkit) requiredApprovals(request *SubjectRequest) int {
+	if request.Type == RequestErasure {
+		return t.config.ErasureApprovals
+	}
+	return t.config.ExportApprovals
+}
+
+// audit appends an event to the request's trail and forwards it
+func (t *Toolkit) audit(ctx context.Context, request *SubjectRequest, event AuditEvent) {
+	event.At = t.config.Now().UTC()
+	request.Audit = append(request.Audit, event)
+	request.UpdatedAt = event.At
+	if t.config.OnAudit != nil {
+		t.config.OnAudit(ctx, request, event)
+	}
+}
+
+func (t *Toolkit) registered() []Entity {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]Entity(nil), t.entities...)
+}
+
+// MemoryRequestStore keeps requests in memory, for tests and local development
+type MemoryRequestStore struct {
+	mu       sync.RWMutex
+	requests map[string]SubjectRequest
+}
+
+// NewMemoryRequestStore creates an in-memory request store
+func NewMemoryRequestStore() *MemoryRequestStore {
+	return &MemoryRequestStore{requests: make(map[string]SubjectRequest)}
+}
+
+// Save stores a copy of the request
+func (m *MemoryRequestStore) Save(ctx context.Context, request *SubjectRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[request.ID] = *copyRequest(request)
+	return nil
+}
+
+// Get returns a copy of the request
+func (m *MemoryRequestStore) Get(ctx context.Context, id string) (*SubjectRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	request, ok := m.requests[id]
+	if !ok {
+		return nil, ErrRequestNotFound
+	}
+	return copyRequest(&request), nil
+}
+
+// List returns requests with the given status, or all requests if status
+// is empty, oldest first
+func (m *MemoryRequestStore) List(ctx context.Context, status RequestStatus) ([]*SubjectRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var requests []*SubjectRequest
+	for _, request := range m.requests {
+		if status == "" || request.Status == status {
+			requests = append(requests, copyRequest(&request))
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+	})
+	return requests, nil
+}
+
+// copyRequest copies a request so stored requests can't be changed in place
+func copyRequest(request *SubjectRequest) *SubjectRequest {
+	copied := *request
+	copied.ApprovedBy = append([]string(nil), request.ApprovedBy...)
+	copied.Audit = append([]AuditEvent(nil), request.Audit...)
+	if request.Results != nil {
+		copied.Results = make(map[string]EntityResult, len(request.Results))
+		for name, result := range request.Results {
+			copied.Results[name] = result
+		}
+	}
+	return &copied
+}
@@ -0,0 +1,253 @@
+package compliance
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryExports keeps export bundles in memory
+type memoryExports struct {
+	objects map[string]*payload.Object
+}
+
+func (s *memoryExports) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	s.objects[key] = &payload.Object{Body: body, ContentType: contentType, Size: int64(len(body))}
+	return nil
+}
+
+func (s *memoryExports) Get(ctx context.Context, key string, maxSize int64) (*payload.Object, error) {
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, payload.ErrNotFound
+	}
+	return obj, nil
+}
+
+func (s *memoryExports) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (string, error) {
+	return "https://exports.s3.amazonaws.com/" + key + "?X-Amz-Signature=put", nil
+}
+
+func (s *memoryExports) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "https://exports.s3.amazonaws.com/" + key + "?X-Amz-Signature=get", nil
+}
+
+func (s *memoryExports) Delete(ctx context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+// testCustomers is a fake entity holding records per subject
+type testCustomers struct {
+	records  map[string][]map[string]any
+	eraseErr error
+}
+
+func (c *testCustomers) entity(name string) Entity {
+	return Entity{
+		Name: name,
+		Export: func(ctx context.Context, subject Subject) ([]map[string]any, error) {
+			return c.records[subject.ID], nil
+		},
+		Erase: func(ctx context.Context, subject Subject) (int, error) {
+			if c.eraseErr != nil {
+				return 0, c.eraseErr
+			}
+			count := len(c.records[subject.ID])
+			delete(c.records, subject.ID)
+			return count, nil
+		},
+	}
+}
+
+func newTestToolkit(t *testing.T) (*Toolkit, *memoryExports, *testCustomers, *[]AuditEvent) {
+	exports := &memoryExports{objects: make(map[string]*payload.Object)}
+	customers := &testCustomers{records: map[string][]map[string]any{
+		"cus_1": {
+			{"id": "cus_1", "email": "ada@example.com", "tags": []string{"vip"}},
+		},
+	}}
+	var events []AuditEvent
+	toolkit := NewToolkit(Config{
+		Exports: exports,
+		OnAudit: func(ctx context.Context, request *SubjectRequest, event AuditEvent) {
+			events = append(events, event)
+		},
+	})
+	require.NoError(t, toolkit.Register(customers.entity("customers")))
+	return toolkit, exports, customers, &events
+}
+
+func TestExportRunsWithoutApproval(t *testing.T) {
+	toolkit, exports, _, events := newTestToolkit(t)
+	ctx := context.Background()
+
+	request, err := toolkit.Submit(ctx, Submission{
+		Type:        RequestExport,
+		Subject:     Subject{ID: "cus_1", TenantID: "tenant-a"},
+		RequestedBy: "operator-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, request.Status)
+	assert.Equal(t, EntityResult{Records: 1}, request.Results["customers"])
+	assert.Equal(t, "subject-exports/tenant-a/"+request.ID+".json", request.ExportKey)
+
+	var bundle exportBundle
+	require.NoError(t, json.Unmarshal(exports.objects[request.ExportKey].Body, &bundle))
+	assert.Equal(t, "ada@example.com", bundle.Entities["customers"][0]["email"])
+
+	url, err := toolkit.DownloadURL(ctx, request)
+	require.NoError(t, err)
+	assert.Contains(t, url, request.ExportKey)
+
+	var actions []string
+	for _, event := range *events {
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []string{"submitted", "exported", "completed"}, actions)
+}
+
+func TestCSVExportBundle(t *testing.T) {
+	toolkit, exports, _, _ := newTestToolkit(t)
+
+	request, err := toolkit.Submit(context.Background(), Submission{
+		Type:        RequestExport,
+		Subject:     Subject{ID: "cus_1"},
+		RequestedBy: "operator-1",
+		Format:      FormatCSV,
+	})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, request.Status)
+
+	body := exports.objects[request.ExportKey].Body
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		files[file.Name] = string(data)
+	}
+	assert.Contains(t, files, "manifest.json")
+	assert.Equal(t, "email,id,tags\nada@example.com,cus_1,\"[\"\"vip\"\"]\"\n", files["customers.csv"])
+}
+
+func TestErasureRequiresApproval(t *testing.T) {
+	toolkit, _, customers, _ := newTestToolkit(t)
+	ctx := context.Background()
+
+	request, err := toolkit.Submit(ctx, Submission{
+		Type:        RequestErasure,
+		Subject:     Subject{ID: "cus_1"},
+		RequestedBy: "operator-1",
+		Reason:      "customer emailed support",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusPendingApproval, request.Status)
+	assert.Contains(t, customers.records, "cus_1")
+
+	_, err = toolkit.Approve(ctx, request.ID, "operator-1")
+	assert.ErrorIs(t, err, ErrSelfApproval)
+
+	request, err = toolkit.Approve(ctx, request.ID, "operator-2")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, request.Status)
+	assert.Equal(t, []string{"operator-2"}, request.ApprovedBy)
+	assert.NotContains(t, customers.records, "cus_1")
+
+	_, err = toolkit.Reject(ctx, request.ID, "operator-2", "too late")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+}
+
+func TestFailedErasureCanBeRetried(t *testing.T) {
+	toolkit, _, customers, _ := newTestToolkit(t)
+	ctx := context.Background()
+	customers.eraseErr = errors.New("table unavailable")
+
+	request, err := toolkit.Submit(ctx, Submission{Type: RequestErasure, Subject: Subject{ID: "cus_1"}, RequestedBy: "operator-1"})
+	require.NoError(t, err)
+	request, err = toolkit.Approve(ctx, request.ID, "operator-2")
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, request.Status)
+	assert.Contains(t, request.Error, "table unavailable")
+
+	customers.eraseErr = nil
+	request, err = toolkit.Execute(ctx, request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, request.Status)
+	assert.Empty(t, request.Error)
+}
+
+func TestSubmitValidation(t *testing.T) {
+	toolkit, _, _, _ := newTestToolkit(t)
+	ctx := context.Background()
+
+	_, err := toolkit.Submit(ctx, Submission{Type: "rectify", Subject: Subject{ID: "cus_1"}, RequestedBy: "op"})
+	assert.ErrorIs(t, err, ErrInvalidSubmission)
+	_, err = toolkit.Submit(ctx, Submission{Type: RequestExport, RequestedBy: "op"})
+	assert.ErrorIs(t, err, ErrInvalidSubmission)
+	_, err = toolkit.Submit(ctx, Submission{Type: RequestExport, Subject: Subject{ID: "cus_1"}, RequestedBy: "op", Format: "xml"})
+	assert.ErrorIs(t, err, ErrInvalidSubmission)
+
+	assert.Error(t, toolkit.Register(Entity{Name: "customers", Erase: func(ctx context.Context, subject Subject) (int, error) { return 0, nil }}))
+	assert.Error(t, toolkit.Register(Entity{Name: "orders"}))
+}
+
+func TestHandlers(t *testing.T) {
+	toolkit, _, _, _ := newTestToolkit(t)
+
+	call := func(handler lift.Handler, user, tenant, id, body string) (*lift.Context, error) {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method: "POST",
+			Path:   "/privacy/requests",
+			Body:   []byte(body),
+		}))
+		ctx.SetUserID(user)
+		ctx.SetTenantID(tenant)
+		ctx.SetParam("id", id)
+		return ctx, handler.Handle(ctx)
+	}
+
+	ctx, err := call(toolkit.SubmitHandler(), "operator-1", "tenant-a", "", `{"type":"erasure","subject_id":"cus_1"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 202, ctx.Response.StatusCode)
+	view := ctx.Response.Body.(requestView)
+	assert.Equal(t, "tenant-a", view.Subject.TenantID)
+
+	// Requests from other tenants are hidden
+	_, err = call(toolkit.ApproveHandler(), "operator-2", "tenant-b", view.ID, "")
+	var liftErr *lift.LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 404, liftErr.StatusCode)
+
+	_, err = call(toolkit.ApproveHandler(), "operator-1", "tenant-a", view.ID, "")
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 403, liftErr.StatusCode)
+
+	ctx, err = call(toolkit.RejectHandler(), "operator-2", "tenant-a", view.ID, `{"reason":"duplicate"}`)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRejected, ctx.Response.Body.(requestView).Status)
+
+	ctx, err = call(toolkit.SubmitHandler(), "operator-1", "tenant-a", "", `{"type":"export","subject_id":"cus_1"}`)
+	require.NoError(t, err)
+	view = ctx.Response.Body.(requestView)
+	assert.Contains(t, view.DownloadURL, "X-Amz-Signature=get")
+
+	_, err = call(toolkit.SubmitHandler(), "operator-1", "tenant-a", "", `{"type":"export"}`)
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 400, liftErr.StatusCode)
+}
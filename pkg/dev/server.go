@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/seed"
 )
 
 // DevServerConfig configures the development server
@@ -53,6 +54,7 @@ type DevServer struct {
 	profiler  *ProfilerServer
 	dashboard *DevDashboard
 	watcher   *FileWatcher
+	seeder    *seed.Seeder
 
 	// Server state
 	server    *http.Server
@@ -122,6 +124,12 @@ func NewDevServer(app *lift.App, config *DevServerConfig) *DevServer {
 	return server
 }
 
+// WithSeeder seeds fixture data each time the server starts
+func (s *DevServer) WithSeeder(seeder *seed.Seeder) *DevServer {
+	s.seeder = seeder
+	return s
+}
+
 // Start starts the development server
 func (s *DevServer) Start(ctx context.Context) error {
 	s.mu.Lock()
@@ -135,6 +143,17 @@ func (s *DevServer) Start(ctx context.Context) error {
 	fmt.Printf("🚀 Starting Lift development server...\n")
 	fmt.Printf("📡 Server: http://localhost:%d\n", s.config.Port)
 
+	if s.seeder != nil {
+		result, err := s.seeder.Run(ctx)
+		if err != nil {
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
+			return fmt.Errorf("failed to seed fixtures: %w", err)
+		}
+		fmt.Printf("🌱 Seeded %d items into %v\n", result.Total(), result.Tables())
+	}
+
 	// Start profiler if enabled
 	if s.profiler != nil {
 		go func() {
@@ -0,0 +1,226 @@
+package dynamorm
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxBatchSize is DynamoDB's limit on items per BatchWriteItem request
+const maxBatchSize = 25
+
+// Retry backoff for batches that fail
+const (
+	defaultBatchRetryDelay = 100 * time.Millisecond
+	maxBatchRetryDelay     = 5 * time.Second
+)
+
+// ErrTenantMismatch is the cause of a batch item failure for an item that
+// belongs to a tenant other than the wrapper's
+var ErrTenantMismatch = errors.New("item belongs to another tenant")
+
+// BatchItemError is an item a batch write couldn't store
+type BatchItemError struct {
+	// Index is the item's position in the items passed to BatchPut
+	Index int
+	Item  any
+	Err   error
+}
+
+// BatchWriteError reports the items of a batch write that failed. Every
+// other item was written, so callers can retry just the failures.
+type BatchWriteError struct {
+	Written int
+	Failed  []BatchItemError
+}
+
+func (e *BatchWriteError) Error() string {
+	return fmt.Sprintf("batch write failed for %d of %d items: %v",
+		len(e.Failed), e.Written+len(e.Failed), e.Failed[0].Err)
+}
+
+// Unwrap returns each failed item's error, so errors.Is matches causes
+// such as ErrTenantMismatch or context.DeadlineExceeded
+func (e *BatchWriteError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for n, failed := range e.Failed {
+		errs[n] = failed.Err
+	}
+	return errs
+}
+
+// FailedItems returns the items that weren't written, in their original order
+func (e *BatchWriteError) FailedItems() []any {
+	items := make([]any, len(e.Failed))
+	for n, failed := range e.Failed {
+		items[n] = failed.Item
+	}
+	return items
+}
+
+// BatchPut upserts items of one model with DynamoDB batch writes. Items are
+// sent in chunks of the config's BatchSize (at most 25); DynamORM resubmits
+// unprocessed items, and a chunk that still fails is retried with
+// exponential backoff up to MaxRetries times.
+//
+// On a tenant-scoped wrapper with TenantIsolation enabled, items with an
+// empty TenantKey attribute are stamped with the tenant and items for
+// another tenant fail with ErrTenantMismatch without being written.
+//
+// When some items can't be written the others still are, and the error is
+// a *BatchWriteError listing each failed item.
+func (d *DynamORMWrapper) BatchPut(ctx context.Context, items []any) error {
+	if err := d.checkWritable(); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	result := &BatchWriteError{}
+	pending := make([]int, 0, len(items))
+	for n, item := range items {
+		if err := d.scopeToTenant(item); err != nil {
+			result.Failed = append(result.Failed, BatchItemError{Index: n, Item: item, Err: err})
+			continue
+		}
+		pending = append(pending, n)
+	}
+
+	size := d.batchSize()
+	for start := 0; start < len(pending); start += size {
+		chunk := pending[start:min(start+size, len(pending))]
+		batch := make([]any, len(chunk))
+		for n, index := range chunk {
+			batch[n] = items[index]
+		}
+
+		if err := d.writeBatch(ctx, batch); err != nil {
+			for _, index := range chunk {
+				result.Failed = append(result.Failed, BatchItemError{Index: index, Item: items[index], Err: err})
+			}
+			continue
+		}
+		result.Written += len(chunk)
+	}
+
+	if len(result.Failed) == 0 {
+		return nil
+	}
+	// Tenant failures are found before write failures; report in item order
+	slices.SortFunc(result.Failed, func(a, b BatchItemError) int {
+		return cmp.Compare(a.Index, b.Index)
+	})
+	return result
+}
+
+// writeBatch writes one chunk, retrying with backoff when it fails
+func (d *DynamORMWrapper) writeBatch(ctx context.Context, batch []any) error {
+	retries, delay := d.batchRetries()
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = d.db.WithContext(ctx).Model(batch[0]).BatchWrite(batch, nil); err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxBatchRetryDelay)
+	}
+}
+
+func (d *DynamORMWrapper) batchSize() int {
+	if d.config == nil || d.config.BatchSize <= 0 || d.config.BatchSize > maxBatchSize {
+		return maxBatchSize
+	}
+	return d.config.BatchSize
+}
+
+func (d *DynamORMWrapper) batchRetries() (int, time.Duration) {
+	if d.config == nil {
+		return 0, defaultBatchRetryDelay
+	}
+	delay := d.config.BatchRetryDelay
+	if delay <= 0 {
+		delay = defaultBatchRetryDelay
+	}
+	return max(d.config.MaxRetries, 0), delay
+}
+
+// scopeToTenant checks an item belongs to the wrapper's tenant, stamping
+// the tenant on items that don't name one. Items without a tenant
+// attribute aren't tenant-scoped and are left alone.
+func (d *DynamORMWrapper) scopeToTenant(item any) error {
+	if d.tenantID == "" || d.config == nil || !d.config.TenantIsolation {
+		return nil
+	}
+	key := d.config.TenantKey
+	if key == "" {
+		key = "tenant_id"
+	}
+
+	field, ok := attributeField(item, key)
+	if !ok || field.Kind() != reflect.String {
+		return nil
+	}
+	switch field.String() {
+	case d.tenantID:
+		return nil
+	case "":
+		if field.CanSet() {
+			field.SetString(d.tenantID)
+			return nil
+		}
+		return fmt.Errorf("%w: %s is not set", ErrTenantMismatch, key)
+	default:
+		return fmt.Errorf("%w: %s is %q", ErrTenantMismatch, key, field.String())
+	}
+}
+
+// attributeField finds the struct field stored as the named attribute,
+// by its dynamorm attr tag or, failing that, its json tag
+func attributeField(item any, name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		field := t.Field(n)
+		if !field.IsExported() {
+			continue
+		}
+		if attributeName(field) == name {
+			return v.Field(n), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func attributeName(field reflect.StructField) string {
+	for _, option := range strings.Split(field.Tag.Get("dynamorm"), ",") {
+		if attr, ok := strings.CutPrefix(option, "attr:"); ok {
+			return attr
+		}
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
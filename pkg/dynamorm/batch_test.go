@@ -0,0 +1,110 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/dynamorm/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatchTestWrapper(query *mocks.MockQuery, tenantID string) *DynamORMWrapper {
+	wrapper := newMockWrapper(query)
+	wrapper.config = DefaultConfig()
+	wrapper.config.BatchRetryDelay = time.Millisecond
+	wrapper.tenantID = tenantID
+	return wrapper
+}
+
+func testModels(count int) []any {
+	items := make([]any, count)
+	for n := range items {
+		items[n] = &TestModel{ID: string(rune('a' + n%26))}
+	}
+	return items
+}
+
+func TestBatchPutChunks(t *testing.T) {
+	query := new(mocks.MockQuery)
+	var sizes []int
+	query.On("BatchWrite", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sizes = append(sizes, len(args.Get(0).([]any)))
+	}).Return(nil)
+	wrapper := newBatchTestWrapper(query, "")
+
+	require.NoError(t, wrapper.BatchPut(context.Background(), testModels(60)))
+	assert.Equal(t, []int{25, 25, 10}, sizes)
+}
+
+func TestBatchPutRetriesWithBackoff(t *testing.T) {
+	throttled := errors.New("ProvisionedThroughputExceededException")
+	query := new(mocks.MockQuery)
+	query.On("BatchWrite", mock.Anything, mock.Anything).Return(throttled).Twice()
+	query.On("BatchWrite", mock.Anything, mock.Anything).Return(nil)
+	wrapper := newBatchTestWrapper(query, "")
+
+	require.NoError(t, wrapper.BatchPut(context.Background(), testModels(3)))
+	query.AssertNumberOfCalls(t, "BatchWrite", 3)
+}
+
+func TestBatchPutReportsFailedItems(t *testing.T) {
+	throttled := errors.New("ProvisionedThroughputExceededException")
+	query := new(mocks.MockQuery)
+	query.On("BatchWrite", mock.Anything, mock.Anything).Return(nil).Once()
+	query.On("BatchWrite", mock.Anything, mock.Anything).Return(throttled)
+	wrapper := newBatchTestWrapper(query, "")
+	wrapper.config.BatchSize = 2
+
+	items := testModels(3)
+	err := wrapper.BatchPut(context.Background(), items)
+
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, 2, batchErr.Written)
+	require.Len(t, batchErr.Failed, 1)
+	assert.Equal(t, 2, batchErr.Failed[0].Index)
+	assert.Equal(t, []any{items[2]}, batchErr.FailedItems())
+	assert.ErrorIs(t, err, throttled)
+	// One successful batch, then the failing one and its three retries
+	query.AssertNumberOfCalls(t, "BatchWrite", 5)
+}
+
+func TestBatchPutTenantIsolation(t *testing.T) {
+	query := new(mocks.MockQuery)
+	var written []any
+	query.On("BatchWrite", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		written = append(written, args.Get(0).([]any)...)
+	}).Return(nil)
+	wrapper := newBatchTestWrapper(query, "tenant-1")
+
+	unset := &TestModel{ID: "1"}
+	own := &TestModel{ID: "2", TenantID: "tenant-1"}
+	other := &TestModel{ID: "3", TenantID: "tenant-2"}
+	err := wrapper.BatchPut(context.Background(), []any{unset, other, own})
+
+	var batchErr *BatchWriteError
+	require.ErrorAs(t, err, &batchErr)
+	assert.ErrorIs(t, err, ErrTenantMismatch)
+	require.Len(t, batchErr.Failed, 1)
+	assert.Equal(t, 1, batchErr.Failed[0].Index)
+	assert.Equal(t, "tenant-1", unset.TenantID)
+	assert.Equal(t, []any{unset, own}, written)
+}
+
+func TestBatchPutStopsRetryingWhenCancelled(t *testing.T) {
+	query := new(mocks.MockQuery)
+	query.On("BatchWrite", mock.Anything, mock.Anything).Return(errors.New("throttled"))
+	wrapper := newBatchTestWrapper(query, "")
+	wrapper.config.BatchRetryDelay = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := wrapper.BatchPut(ctx, testModels(2))
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	query.AssertNumberOfCalls(t, "BatchWrite", 1)
+}
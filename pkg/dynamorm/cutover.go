@@ -0,0 +1,377 @@
+package dynamorm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// CutoverPhase is a stage of a blue/green table migration
+type CutoverPhase string
+
+const (
+	// CutoverOld reads and writes only the old table
+	CutoverOld CutoverPhase = "old"
+	// CutoverDualWrite writes both tables and reads the old one, while a
+	// backfill copies existing items to the new table
+	CutoverDualWrite CutoverPhase = "dual_write"
+	// CutoverShadowRead writes both tables, serves reads from the old table
+	// and compares them with the new table, recording differences
+	CutoverShadowRead CutoverPhase = "shadow_read"
+	// CutoverNewPrimary reads the new table and keeps writing the old one so
+	// the cutover can be rolled back
+	CutoverNewPrimary CutoverPhase = "new_primary"
+	// CutoverNew reads and writes only the new table
+	CutoverNew CutoverPhase = "new"
+)
+
+// valid reports whether p is a known phase
+func (p CutoverPhase) valid() bool {
+	switch p {
+	case CutoverOld, CutoverDualWrite, CutoverShadowRead, CutoverNewPrimary, CutoverNew:
+		return true
+	}
+	return false
+}
+
+// CutoverStore is one side of a cutover. Implementations map the logical key
+// to each table's key schema, so the old and new tables can be keyed
+// differently.
+type CutoverStore[T any] interface {
+	// Get returns the item, or nil if it doesn't exist
+	Get(ctx context.Context, key string) (*T, error)
+	Put(ctx context.Context, item *T) error
+	Delete(ctx context.Context, key string) error
+}
+
+// CutoverDiff describes a shadow read whose results differed
+type CutoverDiff[T any] struct {
+	Key string
+	// Fields lists the differing top-level fields; "*" means the item
+	// is missing from one table
+	Fields []string
+	Old    *T
+	New    *T
+}
+
+// CutoverConfig configures a TableCutover
+type CutoverConfig[T any] struct {
+	// Name identifies the cutover in metrics (e.g. "payments-v2")
+	Name string
+	Old  CutoverStore[T]
+	New  CutoverStore[T]
+	// Phase is the starting phase (default: CutoverOld)
+	Phase CutoverPhase
+	// PhaseSource, if set, is polled for the current phase so the cutover
+	// can be switched without a deploy (e.g. from an SSM parameter)
+	PhaseSource func(ctx context.Context) (CutoverPhase, error)
+	// RefreshInterval is how often PhaseSource is polled (default: 30s)
+	RefreshInterval time.Duration
+	// StrictMirror fails writes when the mirrored write fails; by default
+	// mirror failures are only reported, since the backfill repairs them
+	StrictMirror bool
+	// Compare returns the fields that differ between two items (default:
+	// top-level JSON fields)
+	Compare func(oldItem, newItem *T) []string
+	// Metrics receives cutover.reads, cutover.diffs and cutover.mirror_errors
+	// counters tagged with the cutover name
+	Metrics lift.MetricsCollector
+	// OnDiff is called for every shadow read mismatch
+	OnDiff func(ctx context.Context, diff CutoverDiff[T])
+	// OnError is called when a mirrored write, a shadow read or a phase
+	// refresh fails without failing the request
+	OnError func(err error)
+}
+
+// CutoverStats counts shadow read results and mirror failures
+type CutoverStats struct {
+	Reads        int64 `json:"reads"`
+	Matches      int64 `json:"matches"`
+	Diffs        int64 `json:"diffs"`
+	MirrorErrors int64 `json:"mirror_errors"`
+}
+
+type cutoverPhaseKey struct{ name string }
+
+// TableCutover routes reads and writes between an old and a new table
+// according to the current phase. It implements CutoverStore, so handlers
+// use it in place of the store being migrated:
+//
+//	payments, _ := dynamorm.NewTableCutover(dynamorm.CutoverConfig[Payment]{
+//		Name: "payments-v2",
+//		Old:  oldPayments,
+//		New:  newPayments,
+//		PhaseSource: func(ctx context.Context) (dynamorm.CutoverPhase, error) {
+//			return loadPhase(ctx, "/payments/cutover-phase")
+//		},
+//	})
+//	app.Use(payments.Middleware())
+//
+// A typical migration moves through dual_write (then backfill), shadow_read
+// until the diff rate is zero, new_primary, and finally new.
+type TableCutover[T any] struct {
+	config CutoverConfig[T]
+
+	mu       sync.RWMutex
+	phase    CutoverPhase
+	loadedAt time.Time
+	stats    CutoverStats
+}
+
+// NewTableCutover creates a cutover between two stores
+func NewTableCutover[T any](config CutoverConfig[T]) (*TableCutover[T], error) {
+	if config.Old == nil || config.New == nil {
+		return nil, fmt.Errorf("cutover %q requires old and new stores", config.Name)
+	}
+	if config.Phase == "" {
+		config.Phase = CutoverOld
+	}
+	if !config.Phase.valid() {
+		return nil, fmt.Errorf("unknown cutover phase %q", config.Phase)
+	}
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = 30 * time.Second
+	}
+	if config.Compare == nil {
+		config.Compare = diffFields[T]
+	}
+
+	return &TableCutover[T]{config: config, phase: config.Phase}, nil
+}
+
+// Phase returns the current phase
+func (c *TableCutover[T]) Phase() CutoverPhase {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.phase
+}
+
+// SetPhase switches the phase. It is overridden by the next PhaseSource refresh.
+func (c *TableCutover[T]) SetPhase(phase CutoverPhase) error {
+	if !phase.valid() {
+		return fmt.Errorf("unknown cutover phase %q", phase)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.phase = phase
+	return nil
+}
+
+// Stats returns shadow read and mirror counts since the cutover was created
+func (c *TableCutover[T]) Stats() CutoverStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats
+}
+
+// Refresh loads the phase from PhaseSource, keeping the current phase on error
+func (c *TableCutover[T]) Refresh(ctx context.Context) error {
+	if c.config.PhaseSource == nil {
+		return nil
+	}
+
+	phase, err := c.config.PhaseSource(ctx)
+	if err == nil && !phase.valid() {
+		err = fmt.Errorf("unknown cutover phase %q", phase)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Retry after the refresh interval rather than on every request
+	c.loadedAt = time.Now()
+	if err != nil {
+		return err
+	}
+	c.phase = phase
+	return nil
+}
+
+// Middleware refreshes the phase when it is stale and pins it for the
+// request, so a request that writes and then reads sees one phase
+func (c *TableCutover[T]) Middleware() lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			c.mu.RLock()
+			stale := c.config.PhaseSource != nil && time.Since(c.loadedAt) >= c.config.RefreshInterval
+			c.mu.RUnlock()
+
+			if stale {
+				if err := c.Refresh(ctx.Context); err != nil {
+					c.reportError(fmt.Errorf("cutover %s: failed to refresh phase: %w", c.config.Name, err))
+				}
+			}
+
+			ctx.Context = context.WithValue(ctx.Context, cutoverPhaseKey{c.config.Name}, c.Phase())
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// Get reads from the primary table for the phase, comparing with the new
+// table in shadow_read
+func (c *TableCutover[T]) Get(ctx context.Context, key string) (*T, error) {
+	phase := c.phaseFor(ctx)
+	if phase == CutoverNewPrimary || phase == CutoverNew {
+		return c.config.New.Get(ctx, key)
+	}
+
+	item, err := c.config.Old.Get(ctx, key)
+	if err != nil || phase != CutoverShadowRead {
+		return item, err
+	}
+
+	shadow, shadowErr := c.config.New.Get(ctx, key)
+	if shadowErr != nil {
+		c.reportError(fmt.Errorf("cutover %s: shadow read of %s failed: %w", c.config.Name, key, shadowErr))
+		return item, nil
+	}
+	c.compare(ctx, key, item, shadow)
+	return item, nil
+}
+
+// Put writes the primary table and mirrors the write when the phase has two tables
+func (c *TableCutover[T]) Put(ctx context.Context, item *T) error {
+	return c.write(ctx, func(store CutoverStore[T]) error {
+		return store.Put(ctx, item)
+	})
+}
+
+// Delete deletes from the primary table and mirrors the delete when the phase has two tables
+func (c *TableCutover[T]) Delete(ctx context.Context, key string) error {
+	return c.write(ctx, func(store CutoverStore[T]) error {
+		return store.Delete(ctx, key)
+	})
+}
+
+// write applies op to the primary store, then the mirror
+func (c *TableCutover[T]) write(ctx context.Context, op func(CutoverStore[T]) error) error {
+	var primary, mirror CutoverStore[T]
+	switch c.phaseFor(ctx) {
+	case CutoverOld:
+		primary = c.config.Old
+	case CutoverDualWrite, CutoverShadowRead:
+		primary, mirror = c.config.Old, c.config.New
+	case CutoverNewPrimary:
+		primary, mirror = c.config.New, c.config.Old
+	case CutoverNew:
+		primary = c.config.New
+	}
+
+	if err := op(primary); err != nil {
+		return err
+	}
+	if mirror == nil {
+		return nil
+	}
+
+	if err := op(mirror); err != nil {
+		c.mu.Lock()
+		c.stats.MirrorErrors++
+		c.mu.Unlock()
+		c.count("cutover.mirror_errors")
+
+		err = fmt.Errorf("cutover %s: mirrored write failed: %w", c.config.Name, err)
+		if c.config.StrictMirror {
+			return err
+		}
+		c.reportError(err)
+	}
+	return nil
+}
+
+// compare records the result of a shadow read
+func (c *TableCutover[T]) compare(ctx context.Context, key string, oldItem, newItem *T) {
+	var fields []string
+	switch {
+	case oldItem == nil && newItem == nil:
+	case oldItem == nil || newItem == nil:
+		fields = []string{"*"}
+	default:
+		fields = c.config.Compare(oldItem, newItem)
+	}
+
+	c.mu.Lock()
+	c.stats.Reads++
+	if len(fields) == 0 {
+		c.stats.Matches++
+	} else {
+		c.stats.Diffs++
+	}
+	c.mu.Unlock()
+
+	c.count("cutover.reads")
+	if len(fields) == 0 {
+		return
+	}
+	c.count("cutover.diffs")
+	if c.config.OnDiff != nil {
+		c.config.OnDiff(ctx, CutoverDiff[T]{Key: key, Fields: fields, Old: oldItem, New: newItem})
+	}
+}
+
+// phaseFor returns the phase pinned by Middleware, or the current phase
+func (c *TableCutover[T]) phaseFor(ctx context.Context) CutoverPhase {
+	if phase, ok := ctx.Value(cutoverPhaseKey{c.config.Name}).(CutoverPhase); ok {
+		return phase
+	}
+	return c.Phase()
+}
+
+func (c *TableCutover[T]) count(name string) {
+	if c.config.Metrics != nil {
+		c.config.Metrics.Counter(name, map[string]string{"cutover": c.config.Name}).Inc()
+	}
+}
+
+func (c *TableCutover[T]) reportError(err error) {
+	if c.config.OnError != nil {
+		c.config.OnError(err)
+	}
+}
+
+// diffFields compares items by their top-level JSON fields
+func diffFields[T any](oldItem, newItem *T) []string {
+	oldFields, oldErr := jsonFields(oldItem)
+	newFields, newErr := jsonFields(newItem)
+	if oldErr != nil || newErr != nil {
+		if reflect.DeepEqual(oldItem, newItem) {
+			return nil
+		}
+		return []string{"*"}
+	}
+
+	var fields []string
+	for name, value := range oldFields {
+		if !reflect.DeepEqual(value, newFields[name]) {
+			fields = append(fields, name)
+		}
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// jsonFields decodes an item's JSON form into its top-level fields
+func jsonFields(item any) (map[string]any, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,175 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cutoverItem struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+	Status string `json:"status"`
+}
+
+// memoryCutoverStore is a map-backed CutoverStore
+type memoryCutoverStore struct {
+	mu       sync.Mutex
+	items    map[string]cutoverItem
+	writeErr error
+}
+
+func newMemoryCutoverStore() *memoryCutoverStore {
+	return &memoryCutoverStore{items: make(map[string]cutoverItem)}
+}
+
+func (m *memoryCutoverStore) Get(ctx context.Context, key string) (*cutoverItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (m *memoryCutoverStore) Put(ctx context.Context, item *cutoverItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	m.items[item.ID] = *item
+	return nil
+}
+
+func (m *memoryCutoverStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	delete(m.items, key)
+	return nil
+}
+
+func newTestCutover(t *testing.T, config CutoverConfig[cutoverItem]) (*TableCutover[cutoverItem], *memoryCutoverStore, *memoryCutoverStore) {
+	oldStore, newStore := newMemoryCutoverStore(), newMemoryCutoverStore()
+	config.Name = "payments-v2"
+	config.Old, config.New = oldStore, newStore
+	cutover, err := NewTableCutover(config)
+	require.NoError(t, err)
+	return cutover, oldStore, newStore
+}
+
+func TestTableCutoverPhases(t *testing.T) {
+	cutover, oldStore, newStore := newTestCutover(t, CutoverConfig[cutoverItem]{})
+	ctx := context.Background()
+
+	require.NoError(t, cutover.Put(ctx, &cutoverItem{ID: "1", Amount: 100}))
+	assert.Len(t, oldStore.items, 1)
+	assert.Empty(t, newStore.items)
+
+	require.NoError(t, cutover.SetPhase(CutoverDualWrite))
+	require.NoError(t, cutover.Put(ctx, &cutoverItem{ID: "2", Amount: 200}))
+	assert.Len(t, oldStore.items, 2)
+	assert.Len(t, newStore.items, 1)
+
+	require.NoError(t, cutover.SetPhase(CutoverNewPrimary))
+	item, err := cutover.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Nil(t, item, "reads come from the new table")
+	require.NoError(t, cutover.Delete(ctx, "2"))
+	assert.Empty(t, newStore.items)
+	assert.Len(t, oldStore.items, 1, "deletes are mirrored to the old table")
+
+	require.NoError(t, cutover.SetPhase(CutoverNew))
+	require.NoError(t, cutover.Put(ctx, &cutoverItem{ID: "3"}))
+	assert.Len(t, newStore.items, 1)
+	assert.Len(t, oldStore.items, 1)
+
+	assert.Error(t, cutover.SetPhase("sideways"))
+}
+
+func TestTableCutoverShadowRead(t *testing.T) {
+	var diffs []CutoverDiff[cutoverItem]
+	cutover, oldStore, newStore := newTestCutover(t, CutoverConfig[cutoverItem]{
+		Phase: CutoverShadowRead,
+		OnDiff: func(ctx context.Context, diff CutoverDiff[cutoverItem]) {
+			diffs = append(diffs, diff)
+		},
+	})
+	ctx := context.Background()
+
+	require.NoError(t, cutover.Put(ctx, &cutoverItem{ID: "1", Amount: 100, Status: "settled"}))
+	oldStore.items["2"] = cutoverItem{ID: "2", Amount: 200}
+	newStore.items["3"] = cutoverItem{ID: "3", Amount: 300, Status: "pending"}
+	oldStore.items["3"] = cutoverItem{ID: "3", Amount: 301, Status: "settled"}
+
+	item, err := cutover.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, 100, item.Amount)
+
+	item, err = cutover.Get(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, 200, item.Amount, "reads are served from the old table")
+
+	item, err = cutover.Get(ctx, "3")
+	require.NoError(t, err)
+	assert.Equal(t, 301, item.Amount)
+
+	require.Len(t, diffs, 2)
+	assert.Equal(t, "2", diffs[0].Key)
+	assert.Equal(t, []string{"*"}, diffs[0].Fields)
+	assert.Equal(t, []string{"amount", "status"}, diffs[1].Fields)
+	assert.Equal(t, CutoverStats{Reads: 3, Matches: 1, Diffs: 2}, cutover.Stats())
+}
+
+func TestTableCutoverMirrorErrors(t *testing.T) {
+	var reported []error
+	cutover, oldStore, newStore := newTestCutover(t, CutoverConfig[cutoverItem]{
+		Phase:   CutoverDualWrite,
+		OnError: func(err error) { reported = append(reported, err) },
+	})
+	newStore.writeErr = errors.New("throttled")
+
+	require.NoError(t, cutover.Put(context.Background(), &cutoverItem{ID: "1"}))
+	assert.Len(t, oldStore.items, 1)
+	require.Len(t, reported, 1)
+	assert.Contains(t, reported[0].Error(), "throttled")
+	assert.Equal(t, int64(1), cutover.Stats().MirrorErrors)
+
+	cutover.config.StrictMirror = true
+	assert.Error(t, cutover.Put(context.Background(), &cutoverItem{ID: "2"}))
+
+	// A failed primary write is never mirrored
+	oldStore.writeErr = errors.New("unavailable")
+	newStore.writeErr = nil
+	assert.Error(t, cutover.Put(context.Background(), &cutoverItem{ID: "3"}))
+	assert.Empty(t, newStore.items)
+}
+
+func TestTableCutoverMiddlewarePinsPhase(t *testing.T) {
+	phase := CutoverDualWrite
+	cutover, oldStore, newStore := newTestCutover(t, CutoverConfig[cutoverItem]{
+		PhaseSource: func(ctx context.Context) (CutoverPhase, error) { return phase, nil },
+	})
+
+	handler := cutover.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error {
+		// Switching mid-request doesn't affect the pinned phase
+		require.NoError(t, cutover.SetPhase(CutoverNew))
+		return cutover.Put(ctx, &cutoverItem{ID: "1"})
+	}))
+
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Method: "POST", Path: "/payments"}))
+	require.NoError(t, handler.Handle(ctx))
+	assert.Len(t, oldStore.items, 1)
+	assert.Len(t, newStore.items, 1)
+	assert.Equal(t, CutoverNew, cutover.Phase())
+}
@@ -0,0 +1,265 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/dynamorm/pkg/core"
+	dynamormerrors "github.com/pay-theory/dynamorm/pkg/errors"
+	"github.com/pay-theory/dynamorm/pkg/session"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/health"
+)
+
+// DAXConfig configures a DAXRouter
+type DAXConfig struct {
+	// Endpoint is the DAX cluster endpoint, passed to Factory as the
+	// session endpoint (required)
+	Endpoint string
+	// Region defaults to the table's region
+	Region string
+	// Factory creates the DB that reads through the cluster, e.g. one
+	// built on the aws-dax-go-v2 client (required)
+	Factory DBFactory
+	// Timeout bounds each read through DAX before it falls back to
+	// DynamoDB (default: 200ms)
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive DAX failures after
+	// which reads go straight to DynamoDB (default: 3)
+	FailureThreshold int
+	// Cooldown is how long reads bypass DAX before it is tried again
+	// (default: 30s)
+	Cooldown time.Duration
+	// Probe, if set, is run by Check to test the cluster, e.g. by reading
+	// a known item
+	Probe func(ctx context.Context, db core.DB) error
+	// Metrics, if set, counts reads served by DAX (dynamorm.dax.hit), reads
+	// that fell back to DynamoDB (dynamorm.dax.fallback) and reads that
+	// bypassed an unhealthy cluster (dynamorm.dax.bypass)
+	Metrics lift.MetricsCollector
+	// OnError is called with DAX failures that were served from DynamoDB
+	OnError func(err error)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// DAXStats counts the reads routed by a DAXRouter
+type DAXStats struct {
+	Hits      int64 `json:"hits"`
+	Fallbacks int64 `json:"fallbacks"`
+	Bypassed  int64 `json:"bypassed"`
+	// HitRate is the share of eligible reads DAX served
+	HitRate float64 `json:"hit_rate"`
+	Healthy bool    `json:"healthy"`
+}
+
+// DAXRouter sends eventually consistent reads through a DAX cluster and
+// falls back to DynamoDB when the cluster fails or is slow. After
+// FailureThreshold consecutive failures, reads skip DAX for Cooldown
+// before it is tried again. Writes, strongly consistent reads and reads
+// made with a ConsistentRead context always go to DynamoDB.
+//
+// Set it as DynamORMConfig.DAX; one router is shared by every request, so
+// create it once per container:
+//
+//	dax, err := dynamorm.NewDAXRouter(dynamorm.DAXConfig{
+//		Endpoint: "dax://tenants.abc123.dax-clusters.us-east-1.amazonaws.com",
+//		Factory:  daxFactory,
+//		Metrics:  metrics,
+//	})
+//	config := dynamorm.DefaultConfig()
+//	config.DAX = dax
+//	app.Use(dynamorm.WithDynamORM(config))
+//
+// The router is a health.HealthChecker, reporting degraded while DAX is
+// bypassed since reads are still served.
+type DAXRouter struct {
+	config DAXConfig
+
+	mu          sync.Mutex
+	db          core.ExtendedDB
+	failures    int
+	bypassUntil time.Time
+	lastError   error
+	stats       DAXStats
+}
+
+// NewDAXRouter creates a DAX router. The cluster is connected on first use.
+func NewDAXRouter(config DAXConfig) (*DAXRouter, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("DAX router requires a cluster endpoint")
+	}
+	if config.Factory == nil {
+		return nil, fmt.Errorf("DAX router requires a DB factory")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 200 * time.Millisecond
+	}
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = 3
+	}
+	if config.Cooldown == 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &DAXRouter{config: config}, nil
+}
+
+type consistentReadKey struct{}
+
+// ConsistentRead marks reads made with the returned context as needing
+// the latest data, so they skip DAX, e.g. reading an item just written
+func ConsistentRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consistentReadKey{}, true)
+}
+
+// read runs fn against DAX if the cluster is available, falling back to
+// primary when it fails
+func (r *DAXRouter) read(ctx context.Context, region string, primary core.ExtendedDB, fn func(db core.DB) error) error {
+	db, ok := r.available(region)
+	if !ok {
+		r.count("dynamorm.dax.bypass", &r.stats.Bypassed)
+		return fn(primary.WithContext(ctx))
+	}
+
+	daxCtx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	err := fn(db.WithContext(daxCtx))
+	cancel()
+	// A missing item is an answer, not a cluster failure
+	if err == nil || errors.Is(err, dynamormerrors.ErrItemNotFound) {
+		r.succeeded()
+		return err
+	}
+	if ctx.Err() != nil {
+		// The caller gave up; DynamoDB wouldn't do better
+		return err
+	}
+
+	r.failed(err)
+	r.count("dynamorm.dax.fallback", &r.stats.Fallbacks)
+	if r.config.OnError != nil {
+		r.config.OnError(fmt.Errorf("DAX read failed, using DynamoDB: %w", err))
+	}
+	return fn(primary.WithContext(ctx))
+}
+
+// available returns the cluster's DB unless DAX is being bypassed
+func (r *DAXRouter) available(region string) (core.ExtendedDB, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.config.Now().Before(r.bypassUntil) {
+		return nil, false
+	}
+	if r.db == nil {
+		if r.config.Region != "" {
+			region = r.config.Region
+		}
+		db, err := r.config.Factory.CreateDB(session.Config{Region: region, Endpoint: r.config.Endpoint})
+		if err != nil {
+			r.failLocked(fmt.Errorf("failed to connect to DAX: %w", err))
+			return nil, false
+		}
+		r.db = db
+	}
+	return r.db, true
+}
+
+func (r *DAXRouter) succeeded() {
+	r.mu.Lock()
+	r.failures = 0
+	r.lastError = nil
+	r.mu.Unlock()
+	r.count("dynamorm.dax.hit", &r.stats.Hits)
+}
+
+func (r *DAXRouter) failed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failLocked(err)
+}
+
+func (r *DAXRouter) failLocked(err error) {
+	r.failures++
+	r.lastError = err
+	if r.failures >= r.config.FailureThreshold {
+		r.bypassUntil = r.config.Now().Add(r.config.Cooldown)
+		r.failures = 0
+	}
+}
+
+// count increments a stat and its counter
+func (r *DAXRouter) count(name string, stat *int64) {
+	r.mu.Lock()
+	*stat++
+	r.mu.Unlock()
+	if r.config.Metrics != nil {
+		r.config.Metrics.Counter(name, map[string]string{"endpoint": r.config.Endpoint}).Inc()
+	}
+}
+
+// Stats returns the reads routed so far
+func (r *DAXRouter) Stats() DAXStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	if total := stats.Hits + stats.Fallbacks + stats.Bypassed; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	stats.Healthy = !r.config.Now().Before(r.bypassUntil)
+	return stats
+}
+
+// Name implements health.HealthChecker
+func (r *DAXRouter) Name() string {
+	return "dynamorm-dax"
+}
+
+// Check implements health.HealthChecker. It runs Probe if set; a failed
+// probe or a bypassed cluster reports degraded.
+func (r *DAXRouter) Check(ctx context.Context) health.HealthStatus {
+	start := r.config.Now()
+	stats := r.Stats()
+	status := health.HealthStatus{
+		Status:    health.StatusHealthy,
+		Timestamp: start,
+		Message:   "DAX is serving reads",
+		Details: map[string]any{
+			"hits":      stats.Hits,
+			"fallbacks": stats.Fallbacks,
+			"bypassed":  stats.Bypassed,
+			"hit_rate":  stats.HitRate,
+		},
+	}
+
+	r.mu.Lock()
+	lastError, db := r.lastError, r.db
+	r.mu.Unlock()
+
+	var err error
+	if r.config.Probe != nil && db != nil {
+		probeCtx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+		err = r.config.Probe(probeCtx, db.WithContext(probeCtx))
+		cancel()
+	}
+	switch {
+	case err != nil:
+		status.Status = health.StatusDegraded
+		status.Message = "DAX probe failed, reads fall back to DynamoDB"
+		status.Error = err.Error()
+	case !stats.Healthy:
+		status.Status = health.StatusDegraded
+		status.Message = "DAX is bypassed, reads are served by DynamoDB"
+		if lastError != nil {
+			status.Error = lastError.Error()
+		}
+	}
+	status.Duration = r.config.Now().Sub(start)
+	return status
+}
@@ -0,0 +1,164 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/dynamorm/pkg/core"
+	dynamormerrors "github.com/pay-theory/dynamorm/pkg/errors"
+	"github.com/pay-theory/dynamorm/pkg/mocks"
+	"github.com/pay-theory/dynamorm/pkg/session"
+	liftmocks "github.com/pay-theory/lift/pkg/dynamorm/mocks"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type daxCountingMetrics struct {
+	lift.NoOpMetrics
+	counts map[string]int
+}
+
+func (m *daxCountingMetrics) Counter(name string, tags ...map[string]string) lift.Counter {
+	m.counts[name]++
+	return &lift.NoOpCounter{}
+}
+
+// newDAXTestDB returns a DB whose Get reads return err
+func newDAXTestDB(err error) (*liftmocks.MockExtendedDB, *mocks.MockQuery) {
+	query := new(mocks.MockQuery)
+	query.On("Where", "ID", "=", mock.Anything).Return(query)
+	query.On("First", mock.Anything).Return(err)
+	db := liftmocks.NewMockExtendedDB()
+	db.On("WithContext", mock.Anything).Return(db)
+	db.On("Model", mock.Anything).Return(query)
+	return db, query
+}
+
+func TestDAXRouter(t *testing.T) {
+	now := time.Now()
+	daxErr := errors.New("dax: no route to cluster")
+	daxDB, daxQuery := newDAXTestDB(nil)
+	primaryDB, primaryQuery := newDAXTestDB(nil)
+
+	var endpoint string
+	metrics := &daxCountingMetrics{counts: make(map[string]int)}
+	var reported []error
+	router, err := NewDAXRouter(DAXConfig{
+		Endpoint: "dax://tenants.abc123.dax-clusters.us-east-1.amazonaws.com",
+		Factory: &MockDBFactory{MockDB: daxDB, OnCreateDB: func(config session.Config) {
+			endpoint = config.Endpoint
+		}},
+		FailureThreshold: 2,
+		Metrics:          metrics,
+		OnError:          func(err error) { reported = append(reported, err) },
+		Now:              func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	wrapper := &DynamORMWrapper{db: primaryDB, config: DefaultConfig(), region: "us-east-1", dax: router}
+	get := func(ctx context.Context) error {
+		var item TestModel
+		return wrapper.Get(ctx, "tenant-1", &item)
+	}
+
+	t.Run("serves reads from DAX", func(t *testing.T) {
+		require.NoError(t, get(context.Background()))
+		assert.Equal(t, "dax://tenants.abc123.dax-clusters.us-east-1.amazonaws.com", endpoint)
+		daxQuery.AssertNumberOfCalls(t, "First", 1)
+		primaryQuery.AssertNotCalled(t, "First", mock.Anything)
+	})
+
+	t.Run("missing items don't fall back", func(t *testing.T) {
+		daxQuery.ExpectedCalls[1].ReturnArguments = mock.Arguments{dynamormerrors.ErrItemNotFound}
+		assert.ErrorIs(t, get(context.Background()), dynamormerrors.ErrItemNotFound)
+		primaryQuery.AssertNotCalled(t, "First", mock.Anything)
+	})
+
+	t.Run("consistent reads skip DAX", func(t *testing.T) {
+		require.NoError(t, get(ConsistentRead(context.Background())))
+		primaryQuery.AssertNumberOfCalls(t, "First", 1)
+	})
+
+	t.Run("falls back and bypasses a failing cluster", func(t *testing.T) {
+		daxQuery.ExpectedCalls[1].ReturnArguments = mock.Arguments{daxErr}
+		require.NoError(t, get(context.Background()))
+		require.NoError(t, get(context.Background()))
+		primaryQuery.AssertNumberOfCalls(t, "First", 3)
+		require.Len(t, reported, 2)
+		assert.ErrorIs(t, reported[0], daxErr)
+
+		// Bypassed: DAX isn't called at all
+		require.NoError(t, get(context.Background()))
+		daxQuery.AssertNumberOfCalls(t, "First", 4)
+		assert.Equal(t, health.StatusDegraded, router.Check(context.Background()).Status)
+
+		// Tried again after the cooldown
+		now = now.Add(time.Minute)
+		daxQuery.ExpectedCalls[1].ReturnArguments = mock.Arguments{nil}
+		require.NoError(t, get(context.Background()))
+		daxQuery.AssertNumberOfCalls(t, "First", 5)
+		assert.Equal(t, health.StatusHealthy, router.Check(context.Background()).Status)
+	})
+
+	stats := router.Stats()
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(2), stats.Fallbacks)
+	assert.Equal(t, int64(1), stats.Bypassed)
+	assert.InDelta(t, 0.5, stats.HitRate, 0.001)
+	assert.Equal(t, 3, metrics.counts["dynamorm.dax.hit"])
+	assert.Equal(t, 2, metrics.counts["dynamorm.dax.fallback"])
+	assert.Equal(t, 1, metrics.counts["dynamorm.dax.bypass"])
+}
+
+func TestDAXRouterQueryFallback(t *testing.T) {
+	daxQuery := new(mocks.MockQuery)
+	daxQuery.On("Filter", DeletedAtAttribute, mock.Anything, nil).Return(daxQuery)
+	daxQuery.On("All", mock.Anything).Run(func(args mock.Arguments) {
+		// A partial page is discarded when DAX fails
+		*args.Get(0).(*[]any) = append(*args.Get(0).(*[]any), "stale")
+	}).Return(errors.New("dax timeout"))
+	daxDB := liftmocks.NewMockExtendedDB()
+	daxDB.On("WithContext", mock.Anything).Return(daxDB)
+	daxDB.On("Model", mock.Anything).Return(daxQuery)
+
+	primaryQuery := new(mocks.MockQuery)
+	primaryQuery.On("Filter", DeletedAtAttribute, mock.Anything, nil).Return(primaryQuery)
+	primaryQuery.On("All", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*[]any) = append(*args.Get(0).(*[]any), "fresh")
+	}).Return(nil)
+
+	router, err := NewDAXRouter(DAXConfig{Endpoint: "dax://cluster", Factory: &MockDBFactory{MockDB: daxDB}})
+	require.NoError(t, err)
+	wrapper := newMockWrapper(primaryQuery)
+	wrapper.dax = router
+
+	result, err := wrapper.Query(context.Background(), &Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []any{"fresh"}, result.Items)
+}
+
+func TestDAXRouterConfig(t *testing.T) {
+	_, err := NewDAXRouter(DAXConfig{Factory: NewMockDBFactory()})
+	assert.Error(t, err)
+	_, err = NewDAXRouter(DAXConfig{Endpoint: "dax://cluster"})
+	assert.Error(t, err)
+
+	// A cluster that can't be reached is bypassed
+	router, err := NewDAXRouter(DAXConfig{
+		Endpoint:         "dax://cluster",
+		Factory:          &MockDBFactory{Error: errors.New("no such cluster")},
+		FailureThreshold: 1,
+		Probe:            func(ctx context.Context, db core.DB) error { return nil },
+	})
+	require.NoError(t, err)
+	primaryDB, _ := newDAXTestDB(nil)
+	wrapper := &DynamORMWrapper{db: primaryDB, dax: router}
+	var item TestModel
+	require.NoError(t, wrapper.Get(context.Background(), "tenant-1", &item))
+	assert.False(t, router.Stats().Healthy)
+}
@@ -0,0 +1,359 @@
+package dynamorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrScanRequired is returned for a query no key or index of its model can
+// serve. Routed queries never fall back to a table scan; use a
+// SegmentedScan when a scan is intended.
+var ErrScanRequired = errors.New("query requires a scan")
+
+// Models declare their keys and indexes with dynamorm tags, which Query
+// uses to route each query to the table key or index that serves it:
+//
+//	type Order struct {
+//		ID        string    `dynamorm:"pk"`
+//		Email     string    `dynamorm:"index:gsi-email"`
+//		Status    string    `dynamorm:"index:gsi-status-date,pk"`
+//		CreatedAt time.Time `dynamorm:"index:gsi-status-date,sk"`
+//	}
+//
+// A condition on Email uses gsi-email; one on Status, optionally with a
+// range on CreatedAt, uses gsi-status-date.
+
+// IndexKey is one key attribute of a table key or index
+type IndexKey struct {
+	// Field is the Go field name
+	Field string
+	// Attribute is the DynamoDB attribute name
+	Attribute string
+	// Type is the attribute's scalar type
+	Type types.ScalarAttributeType
+}
+
+// Index is a model's table key or one of its secondary indexes
+type Index struct {
+	// Name is the index name, or "" for the table key
+	Name string
+	// Local is set for local secondary indexes
+	Local        bool
+	PartitionKey IndexKey
+	SortKey      *IndexKey
+}
+
+// String describes the index, e.g. "gsi-status-date(Status, CreatedAt)"
+func (i Index) String() string {
+	name := i.Name
+	if name == "" {
+		name = "table"
+	}
+	if i.SortKey == nil {
+		return fmt.Sprintf("%s(%s)", name, i.PartitionKey.Field)
+	}
+	return fmt.Sprintf("%s(%s, %s)", name, i.PartitionKey.Field, i.SortKey.Field)
+}
+
+// ModelIndexes are the keys a model can be queried by
+type ModelIndexes struct {
+	// Model is the model's type name
+	Model string
+	// Table is the table's primary key
+	Table Index
+	// Secondary are the model's indexes, in field order
+	Secondary []Index
+}
+
+// KeyCondition is a condition on one of a model's fields
+type KeyCondition struct {
+	// Field is the Go field name or DynamoDB attribute name
+	Field string
+	// Operator is a DynamORM operator, e.g. "=", ">=", "BETWEEN" or "BEGINS_WITH"
+	Operator string
+	Value    any
+}
+
+var modelIndexes sync.Map // reflect.Type -> *ModelIndexes
+
+// IndexesOf returns the keys and indexes declared by a model's dynamorm
+// tags. The model is a struct, a pointer to one or a slice of either.
+func IndexesOf(model any) (*ModelIndexes, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dynamorm: %T is not a model", model)
+	}
+	if cached, ok := modelIndexes.Load(t); ok {
+		return cached.(*ModelIndexes), nil
+	}
+
+	indexes, err := parseIndexes(t)
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := modelIndexes.LoadOrStore(t, indexes)
+	return cached.(*ModelIndexes), nil
+}
+
+// parseIndexes reads a model's keys from its dynamorm tags, following the
+// tag rules of DynamORM's model registry
+func parseIndexes(t reflect.Type) (*ModelIndexes, error) {
+	m := &ModelIndexes{Model: t.Name()}
+	secondary := map[string]*Index{}
+	var order []string
+	var lsiKeys []string
+
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for n := 0; n < t.NumField(); n++ {
+			field := t.Field(n)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("dynamorm") == "" {
+				walk(field.Type)
+				continue
+			}
+			tag := field.Tag.Get("dynamorm")
+			if !field.IsExported() || tag == "" || tag == "-" {
+				continue
+			}
+
+			key := IndexKey{Field: field.Name, Attribute: field.Name, Type: scalarType(field.Type)}
+			parts := strings.Split(tag, ",")
+			for _, part := range parts {
+				if attr, ok := strings.CutPrefix(strings.TrimSpace(part), "attr:"); ok {
+					key.Attribute = attr
+				}
+			}
+
+			var roles []indexRole
+			for _, part := range parts {
+				part = strings.TrimSpace(part)
+				switch {
+				case strings.HasPrefix(part, "index:"):
+					roles = append(roles, indexRole{name: strings.TrimPrefix(part, "index:"), pk: true})
+				case strings.HasPrefix(part, "lsi:"):
+					roles = append(roles, indexRole{name: strings.TrimPrefix(part, "lsi:"), local: true})
+				case (part == "pk" || part == "sk") && len(roles) > 0:
+					// pk and sk after index: modify the index role
+					role := &roles[len(roles)-1]
+					if !role.modified {
+						role.pk = false
+						role.modified = true
+					}
+					role.pk = role.pk || part == "pk"
+					role.sk = role.sk || part == "sk"
+				case part == "pk":
+					m.Table.PartitionKey = key
+				case part == "sk":
+					k := key
+					m.Table.SortKey = &k
+				}
+			}
+
+			for _, role := range roles {
+				index, ok := secondary[role.name]
+				if !ok {
+					index = &Index{Name: role.name, Local: role.local}
+					secondary[role.name] = index
+					order = append(order, role.name)
+				}
+				k := key
+				switch {
+				case role.local || role.sk:
+					index.SortKey = &k
+					if role.local {
+						lsiKeys = append(lsiKeys, role.name)
+					}
+				default:
+					index.PartitionKey = k
+				}
+			}
+		}
+	}
+	walk(t)
+
+	if m.Table.PartitionKey.Field == "" {
+		return nil, fmt.Errorf("dynamorm: model %s has no pk field", m.Model)
+	}
+	for _, name := range lsiKeys {
+		// Local indexes share the table's partition key
+		secondary[name].PartitionKey = m.Table.PartitionKey
+	}
+	for _, name := range order {
+		index := secondary[name]
+		if index.PartitionKey.Field == "" {
+			return nil, fmt.Errorf("dynamorm: index %s of model %s has no partition key", name, m.Model)
+		}
+		m.Secondary = append(m.Secondary, *index)
+	}
+	return m, nil
+}
+
+type indexRole struct {
+	name     string
+	local    bool
+	pk, sk   bool
+	modified bool
+}
+
+// Route returns the table key or index that serves a query with the given
+// conditions: one with an equality condition on its partition key,
+// preferring one whose sort key is also constrained, then the table key,
+// then declaration order. Conditions on other fields are applied as filters.
+func (m *ModelIndexes) Route(conditions []KeyCondition) (Index, error) {
+	best, bestScore := Index{}, 0
+	for _, index := range m.all() {
+		if score := index.score(conditions); score > bestScore {
+			best, bestScore = index, score
+		}
+	}
+	if bestScore == 0 {
+		return Index{}, m.scanError(conditions)
+	}
+	return best, nil
+}
+
+// Using checks the named index serves a query with the given conditions
+func (m *ModelIndexes) Using(name string, conditions []KeyCondition) (Index, error) {
+	for _, index := range m.all() {
+		if index.Name != name {
+			continue
+		}
+		if index.score(conditions) == 0 {
+			return Index{}, fmt.Errorf("%w: %s needs an equality condition on %s", ErrScanRequired, index, index.PartitionKey.Field)
+		}
+		return index, nil
+	}
+	return Index{}, fmt.Errorf("dynamorm: model %s has no index %s", m.Model, name)
+}
+
+// GlobalSecondaryIndexes returns the definitions of the model's global
+// secondary indexes, projecting all attributes, e.g. to create tables for
+// tests and local development
+func (m *ModelIndexes) GlobalSecondaryIndexes() []types.GlobalSecondaryIndex {
+	var indexes []types.GlobalSecondaryIndex
+	for _, index := range m.Secondary {
+		if index.Local {
+			continue
+		}
+		indexes = append(indexes, types.GlobalSecondaryIndex{
+			IndexName:  aws.String(index.Name),
+			KeySchema:  index.keySchema(),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		})
+	}
+	return indexes
+}
+
+// AttributeDefinitions returns the definitions of every key attribute of
+// the table and its indexes
+func (m *ModelIndexes) AttributeDefinitions() []types.AttributeDefinition {
+	var definitions []types.AttributeDefinition
+	seen := map[string]bool{}
+	for _, index := range m.all() {
+		for _, key := range []*IndexKey{&index.PartitionKey, index.SortKey} {
+			if key == nil || seen[key.Attribute] {
+				continue
+			}
+			seen[key.Attribute] = true
+			definitions = append(definitions, types.AttributeDefinition{
+				AttributeName: aws.String(key.Attribute),
+				AttributeType: key.Type,
+			})
+		}
+	}
+	return definitions
+}
+
+// all returns the table key followed by the secondary indexes
+func (m *ModelIndexes) all() []Index {
+	return append([]Index{m.Table}, m.Secondary...)
+}
+
+func (m *ModelIndexes) scanError(conditions []KeyCondition) error {
+	fields := make([]string, len(conditions))
+	for n, condition := range conditions {
+		fields[n] = condition.Field
+	}
+	indexes := make([]string, 0, len(m.Secondary)+1)
+	for _, index := range m.all() {
+		indexes = append(indexes, index.String())
+	}
+	return fmt.Errorf("%w: no key of %s serves conditions on [%s]; available: %s",
+		ErrScanRequired, m.Model, strings.Join(fields, ", "), strings.Join(indexes, ", "))
+}
+
+// score rates how well the index serves the conditions: 0 if it can't,
+// higher when its sort key is constrained too
+func (i Index) score(conditions []KeyCondition) int {
+	score := 0
+	for _, condition := range conditions {
+		if i.PartitionKey.matches(condition.Field) && condition.Operator == "=" {
+			score = 2
+		}
+	}
+	if score == 0 || i.SortKey == nil {
+		return score
+	}
+	for _, condition := range conditions {
+		if i.SortKey.matches(condition.Field) && sortKeyOperator(condition.Operator) {
+			return score + 1
+		}
+	}
+	return score
+}
+
+func (i Index) keySchema() []types.KeySchemaElement {
+	schema := []types.KeySchemaElement{{
+		AttributeName: aws.String(i.PartitionKey.Attribute),
+		KeyType:       types.KeyTypeHash,
+	}}
+	if i.SortKey != nil {
+		schema = append(schema, types.KeySchemaElement{
+			AttributeName: aws.String(i.SortKey.Attribute),
+			KeyType:       types.KeyTypeRange,
+		})
+	}
+	return schema
+}
+
+func (k IndexKey) matches(field string) bool {
+	return field == k.Field || field == k.Attribute
+}
+
+// sortKeyOperator reports whether DynamoDB accepts the operator in a key
+// condition on a sort key
+func sortKeyOperator(operator string) bool {
+	switch strings.ToUpper(operator) {
+	case "=", "<", "<=", ">", ">=", "BETWEEN", "BEGINS_WITH":
+		return true
+	}
+	return false
+}
+
+// scalarType maps a Go type to the DynamoDB type it's stored as. Times are
+// stored as RFC 3339 strings.
+func scalarType(t reflect.Type) types.ScalarAttributeType {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return types.ScalarAttributeTypeN
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return types.ScalarAttributeTypeB
+		}
+	}
+	return types.ScalarAttributeTypeS
+}
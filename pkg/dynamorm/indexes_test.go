@@ -0,0 +1,154 @@
+package dynamorm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pay-theory/dynamorm/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type indexedOrder struct {
+	TenantID  string    `dynamorm:"pk,attr:tenant_id"`
+	ID        string    `dynamorm:"sk,attr:id"`
+	Email     string    `dynamorm:"index:gsi-email,attr:email"`
+	Status    string    `dynamorm:"index:gsi-status-date,pk,attr:status"`
+	CreatedAt time.Time `dynamorm:"index:gsi-status-date,sk,attr:created_at"`
+	Total     int64     `dynamorm:"lsi:lsi-total,attr:total"`
+	Note      string    `json:"note"`
+}
+
+func TestIndexesOf(t *testing.T) {
+	indexes, err := IndexesOf(&[]indexedOrder{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "indexedOrder", indexes.Model)
+	assert.Equal(t, "table(TenantID, ID)", indexes.Table.String())
+	require.Len(t, indexes.Secondary, 3)
+	assert.Equal(t, "gsi-email(Email)", indexes.Secondary[0].String())
+	assert.Equal(t, "gsi-status-date(Status, CreatedAt)", indexes.Secondary[1].String())
+	assert.Equal(t, "lsi-total(TenantID, Total)", indexes.Secondary[2].String())
+	assert.True(t, indexes.Secondary[2].Local)
+
+	_, err = IndexesOf(struct {
+		Email string `dynamorm:"index:gsi-email"`
+	}{})
+	assert.ErrorContains(t, err, "no pk field")
+}
+
+func TestIndexRouting(t *testing.T) {
+	indexes, err := IndexesOf(indexedOrder{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		conditions []KeyCondition
+		index      string
+	}{
+		{
+			name:       "table key",
+			conditions: []KeyCondition{{Field: "TenantID", Operator: "=", Value: "acme"}},
+			index:      "",
+		},
+		{
+			name:       "by email",
+			conditions: []KeyCondition{{Field: "email", Operator: "=", Value: "ada@example.com"}},
+			index:      "gsi-email",
+		},
+		{
+			name: "by status and date",
+			conditions: []KeyCondition{
+				{Field: "Status", Operator: "=", Value: "paid"},
+				{Field: "CreatedAt", Operator: ">=", Value: time.Now()},
+			},
+			index: "gsi-status-date",
+		},
+		{
+			name: "constrained sort key wins",
+			conditions: []KeyCondition{
+				{Field: "TenantID", Operator: "=", Value: "acme"},
+				{Field: "total", Operator: ">", Value: 100},
+			},
+			index: "lsi-total",
+		},
+		{
+			name: "other fields are filters",
+			conditions: []KeyCondition{
+				{Field: "Status", Operator: "=", Value: "paid"},
+				{Field: "Note", Operator: "CONTAINS", Value: "gift"},
+			},
+			index: "gsi-status-date",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := indexes.Route(tt.conditions)
+			require.NoError(t, err)
+			assert.Equal(t, tt.index, index.Name)
+		})
+	}
+
+	_, err = indexes.Route([]KeyCondition{{Field: "CreatedAt", Operator: ">=", Value: time.Now()}})
+	assert.ErrorIs(t, err, ErrScanRequired)
+	assert.ErrorContains(t, err, "no key of indexedOrder serves conditions on [CreatedAt]")
+	assert.ErrorContains(t, err, "gsi-status-date(Status, CreatedAt)")
+
+	_, err = indexes.Route([]KeyCondition{{Field: "Status", Operator: "BEGINS_WITH", Value: "pa"}})
+	assert.ErrorIs(t, err, ErrScanRequired, "partition keys need equality")
+
+	_, err = indexes.Using("gsi-email", []KeyCondition{{Field: "Status", Operator: "=", Value: "paid"}})
+	assert.ErrorIs(t, err, ErrScanRequired)
+	_, err = indexes.Using("gsi-missing", nil)
+	assert.ErrorContains(t, err, "has no index gsi-missing")
+}
+
+func TestIndexDefinitions(t *testing.T) {
+	indexes, err := IndexesOf(indexedOrder{})
+	require.NoError(t, err)
+
+	gsis := indexes.GlobalSecondaryIndexes()
+	require.Len(t, gsis, 2, "local indexes are defined with the table")
+	assert.Equal(t, "gsi-status-date", aws.ToString(gsis[1].IndexName))
+	assert.Equal(t, []types.KeySchemaElement{
+		{AttributeName: aws.String("status"), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String("created_at"), KeyType: types.KeyTypeRange},
+	}, gsis[1].KeySchema)
+
+	assert.Equal(t, []types.AttributeDefinition{
+		{AttributeName: aws.String("tenant_id"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("email"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("status"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("created_at"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("total"), AttributeType: types.ScalarAttributeTypeN},
+	}, indexes.AttributeDefinitions())
+}
+
+func TestQueryRoutesToIndex(t *testing.T) {
+	query := new(mocks.MockQuery)
+	query.On("Where", "Email", "=", "ada@example.com").Return(query)
+	query.On("Index", "gsi-email").Return(query)
+	query.On("Filter", DeletedAtAttribute, mock.Anything, nil).Return(query)
+	query.On("All", mock.Anything).Return(nil)
+	wrapper := newMockWrapper(query)
+
+	_, err := wrapper.Query(context.Background(), &Query{
+		Model: &indexedOrder{},
+		Where: []KeyCondition{{Field: "Email", Operator: "=", Value: "ada@example.com"}},
+	})
+	require.NoError(t, err)
+	query.AssertCalled(t, "Index", "gsi-email")
+
+	_, err = wrapper.Query(context.Background(), &Query{
+		Model: &indexedOrder{},
+		Where: []KeyCondition{{Field: "Note", Operator: "=", Value: "gift"}},
+	})
+	assert.ErrorIs(t, err, ErrScanRequired)
+	query.AssertNumberOfCalls(t, "All", 1)
+}
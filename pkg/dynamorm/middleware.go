@@ -31,6 +31,17 @@ type DynamORMConfig struct {
 	// Performance settings
 	ConsistentRead bool `json:"consistent_read"` // Use strongly consistent reads
 	BatchSize      int  `json:"batch_size"`      // Default batch size for operations
+
+	// BatchRetryDelay is the initial backoff before retrying a failed batch
+	// write; it doubles on each of up to MaxRetries retries (default: 100ms)
+	BatchRetryDelay time.Duration `json:"batch_retry_delay"`
+
+	// ReadOnly, if set, rejects writes while the app or tenant is read-only
+	ReadOnly *ReadOnlySwitch `json:"-"`
+
+	// DAX, if set, serves eventually consistent reads from a DAX cluster
+	// with fallback to DynamoDB. It's ignored when ConsistentRead is set.
+	DAX *DAXRouter `json:"-"`
 }
 
 // DefaultConfig returns a default DynamORM configuration
@@ -70,16 +81,30 @@ func WithDynamORM(config *DynamORMConfig, optionalFactory ...DBFactory) lift.Mid
 				return lift.SystemError("Failed to initialize DynamORM").WithCause(err)
 			}
 
+			// Require a tenant if isolation is enabled
+			tenantID := ctx.TenantID()
+			if config.TenantIsolation && tenantID == "" {
+				return lift.Unauthorized("Tenant ID required for data access")
+			}
+
+			// Reject write requests while read-only, and make the wrapper
+			// refuse writes made by read requests
+			if config.ReadOnly != nil {
+				if readOnly, reason := config.ReadOnly.check(ctx.Context, tenantID); readOnly {
+					ctx.Set("dynamorm_read_only", true)
+					ctx.Response.Header(HeaderReadOnly, "true")
+					if isWriteOperation(ctx.Request.Method) {
+						return config.ReadOnly.rejectWrite(ctx, reason)
+					}
+					db.readOnly, db.readOnlyReason = true, reason
+				}
+			}
+
 			// Store DynamORM instance in context
 			ctx.Set("dynamorm", db)
 
 			// Add tenant isolation if enabled
 			if config.TenantIsolation {
-				tenantID := ctx.TenantID()
-				if tenantID == "" {
-					return lift.Unauthorized("Tenant ID required for data access")
-				}
-
 				// Create tenant-scoped database instance
 				tenantDB := db.WithTenant(tenantID)
 				ctx.Set("dynamorm_tenant", tenantDB)
@@ -160,6 +185,18 @@ func isWriteOperation(method string) bool {
 	}
 }
 
+// Open connects to DynamORM outside of a request, e.g. in a queue worker
+// that writes on behalf of requests it was handed
+func Open(config *DynamORMConfig, optionalFactory ...DBFactory) (*DynamORMWrapper, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if len(optionalFactory) > 0 && optionalFactory[0] != nil {
+		return initDynamORMWithFactory(config, optionalFactory[0])
+	}
+	return initDynamORMWithFactory(config, &DefaultDBFactory{})
+}
+
 // initDynamORMWithFactory initializes a DynamORM connection using the provided factory
 func initDynamORMWithFactory(config *DynamORMConfig, factory DBFactory) (*DynamORMWrapper, error) {
 	// Create session config for DynamORM
@@ -183,6 +220,7 @@ func initDynamORMWithFactory(config *DynamORMConfig, factory DBFactory) (*DynamO
 		config:    config,
 		tableName: config.TableName,
 		region:    config.Region,
+		dax:       config.DAX,
 	}
 
 	return wrapper, nil
@@ -211,6 +249,7 @@ func initDynamORM(config *DynamORMConfig) (*DynamORMWrapper, error) {
 		config:    config,
 		tableName: config.TableName,
 		region:    config.Region,
+		dax:       config.DAX,
 	}
 
 	return wrapper, nil
@@ -223,6 +262,11 @@ type DynamORMWrapper struct {
 	tableName string
 	region    string
 	tenantID  string // Set when using tenant isolation
+	dax       *DAXRouter
+
+	// Set while the data layer is read-only
+	readOnly       bool
+	readOnlyReason string
 }
 
 // WithTenant creates a tenant-scoped wrapper
@@ -233,9 +277,21 @@ func (d *DynamORMWrapper) WithTenant(tenantID string) *DynamORMWrapper {
 		tableName: d.tableName,
 		region:    d.region,
 		tenantID:  tenantID,
+		dax:       d.dax,
+
+		readOnly:       d.readOnly,
+		readOnlyReason: d.readOnlyReason,
 	}
 }
 
+// checkWritable refuses writes while the data layer is read-only
+func (d *DynamORMWrapper) checkWritable() error {
+	if d.readOnly {
+		return readOnlyError(d.readOnlyReason)
+	}
+	return nil
+}
+
 // BeginTransaction starts a new transaction using DynamORM
 func (d *DynamORMWrapper) BeginTransaction() (*Transaction, error) {
 	// DynamORM handles transactions through the TransactionFunc method
@@ -248,26 +304,86 @@ func (d *DynamORMWrapper) BeginTransaction() (*Transaction, error) {
 	}, nil
 }
 
-// Get retrieves an item by primary key using DynamORM
+// Get retrieves an item by primary key using DynamORM. Soft-deleted items
+// are reported as ErrSoftDeleted; use GetWithDeleted to read them.
 func (d *DynamORMWrapper) Get(ctx context.Context, key any, result any) error {
 	// Use DynamORM's Model().Where().First() pattern
-	return d.db.WithContext(ctx).Model(result).
-		Where("ID", "=", key).
-		First(result)
+	err := d.read(ctx, func(db core.DB) error {
+		return db.Model(result).
+			Where("ID", "=", key).
+			First(result)
+	})
+	if err != nil {
+		return err
+	}
+	if IsSoftDeleted(result) {
+		return ErrSoftDeleted
+	}
+	return nil
 }
 
 // Put saves an item using DynamORM
 func (d *DynamORMWrapper) Put(ctx context.Context, item any) error {
+	if err := d.checkWritable(); err != nil {
+		return err
+	}
+
 	// Use DynamORM's Model().Create() pattern
 	return d.db.WithContext(ctx).Model(item).Create()
 }
 
-// Query performs a query operation using DynamORM
+// Query performs a query operation using DynamORM. Queries with a Model
+// and Where conditions are routed to the key or index that serves them, and
+// fail with ErrScanRequired before reaching DynamoDB if none does.
 func (d *DynamORMWrapper) Query(ctx context.Context, query *Query) (*QueryResult, error) {
+	indexName, err := routeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
 	var results []any
+	err = d.read(ctx, func(db core.DB) error {
+		results = nil
+		return d.buildQuery(db, query, indexName, &results).All(&results)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryResult{
+		Items:        results,
+		Count:        len(results),
+		ScannedCount: len(results),
+	}, nil
+}
 
-	// Build DynamORM query
-	q := d.db.WithContext(ctx).Model(&results)
+// routeQuery returns the index a query reads
+func routeQuery(query *Query) (string, error) {
+	if query.Model == nil || len(query.Where) == 0 {
+		return query.IndexName, nil
+	}
+	indexes, err := IndexesOf(query.Model)
+	if err != nil {
+		return "", err
+	}
+
+	var index Index
+	if query.IndexName != "" {
+		index, err = indexes.Using(query.IndexName, query.Where)
+	} else {
+		index, err = indexes.Route(query.Where)
+	}
+	return index.Name, err
+}
+
+// buildQuery builds a DynamORM query from a Query
+func (d *DynamORMWrapper) buildQuery(db core.DB, query *Query, indexName string, results *[]any) core.Query {
+	var q core.Query
+	if query.Model != nil {
+		q = db.Model(query.Model)
+	} else {
+		q = db.Model(results)
+	}
 
 	if query.PartitionKey != nil {
 		q = q.Where("PK", "=", query.PartitionKey)
@@ -277,8 +393,12 @@ func (d *DynamORMWrapper) Query(ctx context.Context, query *Query) (*QueryResult
 		q = q.Where("SK", "=", query.SortKey)
 	}
 
-	if query.IndexName != "" {
-		q = q.Index(query.IndexName)
+	for _, condition := range query.Where {
+		q = q.Where(condition.Field, condition.Operator, condition.Value)
+	}
+
+	if indexName != "" {
+		q = q.Index(indexName)
 	}
 
 	if query.Limit > 0 {
@@ -290,20 +410,30 @@ func (d *DynamORMWrapper) Query(ctx context.Context, query *Query) (*QueryResult
 		q = q.Where(field, "=", value)
 	}
 
-	err := q.All(&results)
-	if err != nil {
-		return nil, err
+	switch {
+	case query.OnlyDeleted:
+		q = q.Filter(DeletedAtAttribute, "attribute_exists", nil)
+	case !query.IncludeDeleted:
+		q = q.Filter(DeletedAtAttribute, "attribute_not_exists", nil)
 	}
+	return q
+}
 
-	return &QueryResult{
-		Items:        results,
-		Count:        len(results),
-		ScannedCount: len(results),
-	}, nil
+// read runs a read through DAX when configured and the read doesn't need
+// the latest data, otherwise against DynamoDB
+func (d *DynamORMWrapper) read(ctx context.Context, fn func(db core.DB) error) error {
+	if d.dax == nil || (d.config != nil && d.config.ConsistentRead) || ctx.Value(consistentReadKey{}) != nil {
+		return fn(d.db.WithContext(ctx))
+	}
+	return d.dax.read(ctx, d.region, d.db, fn)
 }
 
 // Delete removes an item using DynamORM
 func (d *DynamORMWrapper) Delete(ctx context.Context, key any) error {
+	if err := d.checkWritable(); err != nil {
+		return err
+	}
+
 	// Use DynamORM's Model().Where().Delete() pattern
 	return d.db.WithContext(ctx).Model(&struct{}{}).
 		Where("ID", "=", key).
@@ -362,6 +492,10 @@ func (t *Transaction) Commit() error {
 	if t.committed || t.rolledBack {
 		return lift.SystemError("Transaction already completed")
 	}
+	if err := t.wrapper.checkWritable(); err != nil {
+		t.rolledBack = true
+		return err
+	}
 
 	// Execute all operations using DynamORM's TransactionFunc
 	err := t.wrapper.db.Transaction(func(tx *core.Tx) error {
@@ -406,9 +540,18 @@ type Query struct {
 	PartitionKey any
 	SortKey      any
 	IndexName    string
-	Filters      map[string]any
-	Limit        int
-	Ascending    bool
+	// Model is the model queried. With Where, its dynamorm tags pick the
+	// key or index that serves the query; see IndexesOf.
+	Model any
+	// Where are conditions on the model's fields
+	Where     []KeyCondition
+	Filters   map[string]any
+	Limit     int
+	Ascending bool
+	// IncludeDeleted returns soft-deleted items alongside live ones
+	IncludeDeleted bool
+	// OnlyDeleted returns only soft-deleted items, e.g. for a recycle bin
+	OnlyDeleted bool
 }
 
 // QueryResult represents the result of a query operation
@@ -0,0 +1,100 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReadOnlyTestContext(method, tenantID string) *lift.Context {
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:      method,
+		Path:        "/orders",
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+		PathParams:  make(map[string]string),
+	}))
+	ctx.SetTenantID(tenantID)
+	return ctx
+}
+
+func TestReadOnlySwitch(t *testing.T) {
+	readOnly := NewReadOnlySwitch(ReadOnlyConfig{RetryAfter: 2 * time.Minute})
+	readOnly.Set(ReadOnlyState{Tenants: []string{"tenant-1"}, Reason: "migration in progress"})
+
+	config := DefaultConfig()
+	config.AutoTransaction = false
+	config.ReadOnly = readOnly
+	middleware := WithDynamORM(config, NewMockDBFactory())
+
+	t.Run("rejects writes for read-only tenants", func(t *testing.T) {
+		ctx := newReadOnlyTestContext("POST", "tenant-1")
+		err := middleware(lift.HandlerFunc(func(ctx *lift.Context) error {
+			t.Fatal("handler should not run")
+			return nil
+		})).Handle(ctx)
+
+		var liftErr *lift.LiftError
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, 503, liftErr.StatusCode)
+		assert.Contains(t, liftErr.Message, "migration in progress")
+		assert.ErrorIs(t, err, ErrReadOnly)
+		assert.Equal(t, "120", ctx.Response.Headers["Retry-After"])
+		assert.Equal(t, "true", ctx.Response.Headers[HeaderReadOnly])
+	})
+
+	t.Run("serves reads and refuses writes they make", func(t *testing.T) {
+		ctx := newReadOnlyTestContext("GET", "tenant-1")
+		err := middleware(lift.HandlerFunc(func(ctx *lift.Context) error {
+			assert.True(t, IsReadOnly(ctx))
+			db, err := TenantDB(ctx)
+			require.NoError(t, err)
+			assert.ErrorIs(t, db.BatchPut(ctx.Context, []any{&TestModel{ID: "1"}}), ErrReadOnly)
+			return db.Put(ctx.Context, &TestModel{ID: "1"})
+		})).Handle(ctx)
+
+		assert.ErrorIs(t, err, ErrReadOnly)
+		assert.Equal(t, "true", ctx.Response.Headers[HeaderReadOnly])
+	})
+
+	t.Run("leaves other tenants writable", func(t *testing.T) {
+		ctx := newReadOnlyTestContext("POST", "tenant-2")
+		err := middleware(lift.HandlerFunc(func(ctx *lift.Context) error {
+			assert.False(t, IsReadOnly(ctx))
+			return nil
+		})).Handle(ctx)
+
+		require.NoError(t, err)
+		assert.Empty(t, ctx.Response.Headers[HeaderReadOnly])
+	})
+}
+
+func TestReadOnlySwitchRefresh(t *testing.T) {
+	state := ReadOnlyState{All: true}
+	var sourceErr error
+	var reported []error
+	readOnly := NewReadOnlySwitch(ReadOnlyConfig{
+		Source: func(ctx context.Context) (ReadOnlyState, error) {
+			return state, sourceErr
+		},
+		RefreshInterval: time.Nanosecond,
+		OnError:         func(err error) { reported = append(reported, err) },
+	})
+
+	readOnly.Set(ReadOnlyState{})
+	ok, _ := readOnly.check(context.Background(), "tenant-1")
+	assert.True(t, ok, "stale state should be refreshed from the source")
+
+	// A failing source keeps the last known state
+	state, sourceErr = ReadOnlyState{}, errors.New("parameter store unavailable")
+	ok, _ = readOnly.check(context.Background(), "tenant-1")
+	assert.True(t, ok)
+	require.Len(t, reported, 1)
+	assert.True(t, readOnly.State().All)
+}
@@ -0,0 +1,332 @@
+package dynamorm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/dynamorm/pkg/core"
+)
+
+// ScanSegment is the unit of work handed to a worker: one segment of a
+// parallel scan, starting from Cursor
+type ScanSegment struct {
+	JobID         string `json:"job_id"`
+	Segment       int32  `json:"segment"`
+	TotalSegments int32  `json:"total_segments"`
+	Cursor        string `json:"cursor,omitempty"`
+}
+
+// ParseScanSegment decodes a segment from a task queue message body
+func ParseScanSegment(body []byte) (ScanSegment, error) {
+	var segment ScanSegment
+	if err := json.Unmarshal(body, &segment); err != nil {
+		return ScanSegment{}, fmt.Errorf("invalid scan segment: %w", err)
+	}
+	if segment.JobID == "" || segment.TotalSegments <= 0 || segment.Segment < 0 || segment.Segment >= segment.TotalSegments {
+		return ScanSegment{}, fmt.Errorf("invalid scan segment %d/%d for job %q", segment.Segment, segment.TotalSegments, segment.JobID)
+	}
+	return segment, nil
+}
+
+// SegmentDispatcher fans segments out to workers, typically by sending each
+// one as a message to a task queue whose consumer calls ProcessSegment
+type SegmentDispatcher interface {
+	DispatchSegments(ctx context.Context, segments []ScanSegment) error
+}
+
+// SegmentDispatcherFunc adapts a function to the SegmentDispatcher interface
+type SegmentDispatcherFunc func(ctx context.Context, segments []ScanSegment) error
+
+// DispatchSegments calls f(ctx, segments)
+func (f SegmentDispatcherFunc) DispatchSegments(ctx context.Context, segments []ScanSegment) error {
+	return f(ctx, segments)
+}
+
+// SegmentProgress records how far a segment has been scanned
+type SegmentProgress struct {
+	JobID         string    `json:"job_id"`
+	Segment       int32     `json:"segment"`
+	TotalSegments int32     `json:"total_segments"`
+	Cursor        string    `json:"cursor,omitempty"`
+	Processed     int64     `json:"processed"`
+	Pages         int       `json:"pages"`
+	Done          bool      `json:"done"`
+	Error         string    `json:"error,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ScanProgressStore persists segment progress so scans can be resumed
+type ScanProgressStore interface {
+	SaveProgress(ctx context.Context, progress *SegmentProgress) error
+	// LoadProgress returns the saved progress for a segment, or nil if there is none
+	LoadProgress(ctx context.Context, jobID string, segment int32) (*SegmentProgress, error)
+}
+
+// PageHandler processes one page of scanned items. Pages can be delivered
+// again after a failure, so handlers must be idempotent.
+type PageHandler[T any] func(ctx context.Context, segment ScanSegment, items []T) error
+
+// SegmentedScanConfig configures a SegmentedScan
+type SegmentedScanConfig struct {
+	// JobID identifies the scan; reusing it resumes a previous run
+	JobID string
+	// TotalSegments is the number of parallel segments (default: 4)
+	TotalSegments int32
+	// PageSize is the scan page size (default: 100)
+	PageSize int
+	// Query returns a new query for the table to scan, e.g.
+	// func(ctx context.Context) core.Query { return db.WithContext(ctx).Model(&Payment{}) }
+	Query func(ctx context.Context) core.Query
+	// Dispatcher fans segments and continuations out to workers
+	Dispatcher SegmentDispatcher
+	// Progress stores segment progress (default: in memory, which only
+	// suits tests and single-process runs)
+	Progress ScanProgressStore
+	// DeadlineReserve is the time left before the invocation deadline at
+	// which a worker stops and dispatches a continuation (default: 30s)
+	DeadlineReserve time.Duration
+}
+
+// SegmentedScan splits a table scan into parallel segments processed by
+// fan-out workers, for backfills and migrations over large tables. Start
+// dispatches every unfinished segment; each worker calls ProcessSegment,
+// which pages through its segment, saving the cursor after every page.
+// When the worker's deadline approaches it dispatches a continuation from
+// the saved cursor, so a segment may span many Lambda invocations, and
+// re-running Start after a failure resumes where each segment stopped.
+type SegmentedScan[T any] struct {
+	config SegmentedScanConfig
+}
+
+// NewSegmentedScan creates a segmented scan with the given configuration
+func NewSegmentedScan[T any](config SegmentedScanConfig) (*SegmentedScan[T], error) {
+	if config.JobID == "" {
+		return nil, fmt.Errorf("segmented scan requires a job ID")
+	}
+	if config.Query == nil {
+		return nil, fmt.Errorf("segmented scan requires a query")
+	}
+	if config.Dispatcher == nil {
+		return nil, fmt.Errorf("segmented scan requires a dispatcher")
+	}
+	if config.TotalSegments == 0 {
+		config.TotalSegments = 4
+	}
+	if config.TotalSegments < 0 {
+		return nil, fmt.Errorf("total segments must be positive, got %d", config.TotalSegments)
+	}
+	if config.PageSize == 0 {
+		config.PageSize = 100
+	}
+	if config.Progress == nil {
+		config.Progress = NewMemoryScanProgressStore()
+	}
+	if config.DeadlineReserve == 0 {
+		config.DeadlineReserve = 30 * time.Second
+	}
+
+	return &SegmentedScan[T]{config: config}, nil
+}
+
+// Start dispatches every segment that hasn't finished, from its saved
+// cursor, and returns the number of segments dispatched
+func (s *SegmentedScan[T]) Start(ctx context.Context) (int, error) {
+	var pending []ScanSegment
+	for segment := int32(0); segment < s.config.TotalSegments; segment++ {
+		progress, err := s.config.Progress.LoadProgress(ctx, s.config.JobID, segment)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load progress for segment %d: %w", segment, err)
+		}
+		if progress != nil && progress.Done {
+			continue
+		}
+
+		next := s.segment(segment)
+		if progress != nil {
+			next.Cursor = progress.Cursor
+		}
+		pending = append(pending, next)
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	if err := s.config.Dispatcher.DispatchSegments(ctx, pending); err != nil {
+		return 0, fmt.Errorf("failed to dispatch scan segments: %w", err)
+	}
+	return len(pending), nil
+}
+
+// ProcessSegment scans a segment page by page, passing each page to handle.
+// The saved cursor takes precedence over the one in the message, so
+// redelivered messages don't rescan completed pages. If the handler or the
+// scan fails the error is recorded and returned so the queue can retry.
+func (s *SegmentedScan[T]) ProcessSegment(ctx context.Context, segment ScanSegment, handle PageHandler[T]) error {
+	if segment.JobID != s.config.JobID || segment.TotalSegments != s.config.TotalSegments {
+		return fmt.Errorf("segment %d/%d of job %q doesn't belong to job %q with %d segments",
+			segment.Segment, segment.TotalSegments, segment.JobID, s.config.JobID, s.config.TotalSegments)
+	}
+
+	progress, err := s.config.Progress.LoadProgress(ctx, segment.JobID, segment.Segment)
+	if err != nil {
+		return fmt.Errorf("failed to load progress for segment %d: %w", segment.Segment, err)
+	}
+	if progress == nil {
+		progress = &SegmentProgress{
+			JobID:         segment.JobID,
+			Segment:       segment.Segment,
+			TotalSegments: segment.TotalSegments,
+			Cursor:        segment.Cursor,
+		}
+	}
+	if progress.Done {
+		return nil
+	}
+
+	for {
+		// Hand the rest of the segment to a fresh worker before the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < s.config.DeadlineReserve && progress.Pages > 0 {
+			continuation := s.segment(segment.Segment)
+			continuation.Cursor = progress.Cursor
+			if err := s.config.Dispatcher.DispatchSegments(ctx, []ScanSegment{continuation}); err != nil {
+				return fmt.Errorf("failed to dispatch continuation for segment %d: %w", segment.Segment, err)
+			}
+			return nil
+		}
+
+		items, nextCursor, err := s.fetchPage(ctx, segment.Segment, progress.Cursor)
+		if err == nil {
+			err = handle(ctx, s.segmentAt(segment.Segment, progress.Cursor), items)
+		}
+		if err != nil {
+			progress.Error = err.Error()
+			s.save(ctx, progress)
+			return fmt.Errorf("segment %d: %w", segment.Segment, err)
+		}
+
+		progress.Processed += int64(len(items))
+		progress.Pages++
+		progress.Cursor = nextCursor
+		progress.Done = nextCursor == ""
+		progress.Error = ""
+		if err := s.save(ctx, progress); err != nil {
+			return fmt.Errorf("failed to save progress for segment %d: %w", segment.Segment, err)
+		}
+
+		if progress.Done {
+			return nil
+		}
+	}
+}
+
+// Status summarizes the progress of every segment
+func (s *SegmentedScan[T]) Status(ctx context.Context) (*ScanStatus, error) {
+	status := &ScanStatus{
+		JobID:         s.config.JobID,
+		TotalSegments: s.config.TotalSegments,
+	}
+
+	for segment := int32(0); segment < s.config.TotalSegments; segment++ {
+		progress, err := s.config.Progress.LoadProgress(ctx, s.config.JobID, segment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load progress for segment %d: %w", segment, err)
+		}
+		if progress == nil {
+			continue
+		}
+
+		status.Processed += progress.Processed
+		if progress.Done {
+			status.Completed++
+		}
+		if progress.Error != "" {
+			status.Failed = append(status.Failed, segment)
+		}
+	}
+
+	status.Done = status.Completed == status.TotalSegments
+	return status, nil
+}
+
+// ScanStatus summarizes a segmented scan
+type ScanStatus struct {
+	JobID         string  `json:"job_id"`
+	TotalSegments int32   `json:"total_segments"`
+	Completed     int32   `json:"completed"`
+	Processed     int64   `json:"processed"`
+	Failed        []int32 `json:"failed,omitempty"`
+	Done          bool    `json:"done"`
+}
+
+// fetchPage reads one page of a segment
+func (s *SegmentedScan[T]) fetchPage(ctx context.Context, segment int32, cursor string) ([]T, string, error) {
+	query := s.config.Query(ctx).
+		ParallelScan(segment, s.config.TotalSegments).
+		Limit(s.config.PageSize)
+	if cursor != "" {
+		query = query.Cursor(cursor)
+	}
+
+	var items []T
+	result, err := query.AllPaginated(&items)
+	if err != nil {
+		return nil, "", err
+	}
+	return items, result.NextCursor, nil
+}
+
+// save stamps and stores progress
+func (s *SegmentedScan[T]) save(ctx context.Context, progress *SegmentProgress) error {
+	progress.UpdatedAt = time.Now()
+	return s.config.Progress.SaveProgress(ctx, progress)
+}
+
+func (s *SegmentedScan[T]) segment(segment int32) ScanSegment {
+	return ScanSegment{JobID: s.config.JobID, Segment: segment, TotalSegments: s.config.TotalSegments}
+}
+
+func (s *SegmentedScan[T]) segmentAt(segment int32, cursor string) ScanSegment {
+	next := s.segment(segment)
+	next.Cursor = cursor
+	return next
+}
+
+// MemoryScanProgressStore keeps segment progress in memory
+type MemoryScanProgressStore struct {
+	mu       sync.RWMutex
+	progress map[string]SegmentProgress
+}
+
+// NewMemoryScanProgressStore creates an in-memory progress store
+func NewMemoryScanProgressStore() *MemoryScanProgressStore {
+	return &MemoryScanProgressStore{progress: make(map[string]SegmentProgress)}
+}
+
+// SaveProgress stores a copy of the progress
+func (m *MemoryScanProgressStore) SaveProgress(ctx context.Context, progress *SegmentProgress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.progress[scanProgressKey(progress.JobID, progress.Segment)] = *progress
+	return nil
+}
+
+// LoadProgress returns a copy of the saved progress, or nil
+func (m *MemoryScanProgressStore) LoadProgress(ctx context.Context, jobID string, segment int32) (*SegmentProgress, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	progress, ok := m.progress[scanProgressKey(jobID, segment)]
+	if !ok {
+		return nil, nil
+	}
+	return &progress, nil
+}
+
+// scanProgressKey is the storage key for a segment's progress
+func scanProgressKey(jobID string, segment int32) string {
+	return fmt.Sprintf("scan#%s#%d", jobID, segment)
+}
@@ -0,0 +1,69 @@
+package dynamorm
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBScanProgressStore implements ScanProgressStore using DynamoDB.
+// Records are keyed by a string partition key named "pk", so progress can
+// share a table with other single-key records such as idempotency keys.
+type DynamoDBScanProgressStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBScanProgressStore creates a DynamoDB-backed scan progress store
+func NewDynamoDBScanProgressStore(client *dynamodb.Client, tableName string) *DynamoDBScanProgressStore {
+	return &DynamoDBScanProgressStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SaveProgress writes the segment's progress, replacing any previous record
+func (d *DynamoDBScanProgressStore) SaveProgress(ctx context.Context, progress *SegmentProgress) error {
+	item, err := attributevalue.MarshalMapWithOptions(progress, useJSONTags)
+	if err != nil {
+		return err
+	}
+	item["pk"] = &types.AttributeValueMemberS{Value: scanProgressKey(progress.JobID, progress.Segment)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	return err
+}
+
+// LoadProgress reads a segment's progress, returning nil if there is none
+func (d *DynamoDBScanProgressStore) LoadProgress(ctx context.Context, jobID string, segment int32) (*SegmentProgress, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: scanProgressKey(jobID, segment)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var progress SegmentProgress
+	if err := attributevalue.UnmarshalMapWithOptions(result.Item, &progress, func(o *attributevalue.DecoderOptions) { o.TagKey = "json" }); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// useJSONTags stores progress under the same attribute names as its JSON form
+func useJSONTags(o *attributevalue.EncoderOptions) {
+	o.TagKey = "json"
+}
@@ -0,0 +1,230 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/dynamorm/pkg/core"
+	"github.com/pay-theory/dynamorm/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScanTable serves parallel scan pages of ints; segment s holds pages[s]
+type fakeScanTable struct {
+	pages map[int32][][]int
+	fail  map[string]error
+}
+
+func (f *fakeScanTable) query(ctx context.Context) core.Query {
+	return &fakeScanQuery{table: f}
+}
+
+// fakeScanQuery implements the parts of core.Query used by SegmentedScan
+type fakeScanQuery struct {
+	*mocks.MockQuery
+	table   *fakeScanTable
+	segment int32
+	limit   int
+	cursor  string
+}
+
+func (q *fakeScanQuery) ParallelScan(segment int32, totalSegments int32) core.Query {
+	q.segment = segment
+	return q
+}
+
+func (q *fakeScanQuery) Limit(limit int) core.Query {
+	q.limit = limit
+	return q
+}
+
+func (q *fakeScanQuery) Cursor(cursor string) core.Query {
+	q.cursor = cursor
+	return q
+}
+
+func (q *fakeScanQuery) AllPaginated(dest any) (*core.PaginatedResult, error) {
+	page := 0
+	if q.cursor != "" {
+		page, _ = strconv.Atoi(q.cursor)
+	}
+	if err := q.table.fail[fmt.Sprintf("%d:%d", q.segment, page)]; err != nil {
+		return nil, err
+	}
+
+	pages := q.table.pages[q.segment]
+	result := &core.PaginatedResult{}
+	if page < len(pages) {
+		*dest.(*[]int) = pages[page]
+		result.Count = len(pages[page])
+	}
+	if page+1 < len(pages) {
+		result.NextCursor = strconv.Itoa(page + 1)
+		result.HasMore = true
+	}
+	return result, nil
+}
+
+// recordingDispatcher keeps dispatched segments instead of sending them
+type recordingDispatcher struct {
+	mu       sync.Mutex
+	segments []ScanSegment
+}
+
+func (d *recordingDispatcher) DispatchSegments(ctx context.Context, segments []ScanSegment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.segments = append(d.segments, segments...)
+	return nil
+}
+
+func newTestSegmentedScan(t *testing.T, table *fakeScanTable, dispatcher SegmentDispatcher) *SegmentedScan[int] {
+	scan, err := NewSegmentedScan[int](SegmentedScanConfig{
+		JobID:         "backfill",
+		TotalSegments: 2,
+		PageSize:      2,
+		Query:         table.query,
+		Dispatcher:    dispatcher,
+	})
+	require.NoError(t, err)
+	return scan
+}
+
+func TestSegmentedScanProcessesAllSegments(t *testing.T) {
+	table := &fakeScanTable{pages: map[int32][][]int{
+		0: {{1, 2}, {3, 4}, {5}},
+		1: {{6, 7}},
+	}}
+	dispatcher := &recordingDispatcher{}
+	scan := newTestSegmentedScan(t, table, dispatcher)
+
+	dispatched, err := scan.Start(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, dispatched)
+
+	var mu sync.Mutex
+	var seen []int
+	handler := func(ctx context.Context, segment ScanSegment, items []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, items...)
+		return nil
+	}
+	for _, segment := range dispatcher.segments {
+		require.NoError(t, scan.ProcessSegment(context.Background(), segment, handler))
+	}
+	assert.ElementsMatch(t, []int{1, 2, 3, 4, 5, 6, 7}, seen)
+
+	status, err := scan.Status(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Done)
+	assert.Equal(t, int64(7), status.Processed)
+	assert.Equal(t, int32(2), status.Completed)
+
+	// Nothing is left to dispatch, and redelivered segments are no-ops
+	dispatched, err = scan.Start(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, dispatched)
+	require.NoError(t, scan.ProcessSegment(context.Background(), dispatcher.segments[0], handler))
+	assert.Len(t, seen, 7)
+}
+
+func TestSegmentedScanResumesAfterFailure(t *testing.T) {
+	table := &fakeScanTable{
+		pages: map[int32][][]int{0: {{1, 2}, {3, 4}}, 1: {{5}}},
+		fail:  map[string]error{"0:1": errors.New("throttled")},
+	}
+	dispatcher := &recordingDispatcher{}
+	scan := newTestSegmentedScan(t, table, dispatcher)
+
+	var seen []int
+	handler := func(ctx context.Context, segment ScanSegment, items []int) error {
+		seen = append(seen, items...)
+		return nil
+	}
+
+	err := scan.ProcessSegment(context.Background(), ScanSegment{JobID: "backfill", Segment: 0, TotalSegments: 2}, handler)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "throttled")
+
+	status, err := scan.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int32{0}, status.Failed)
+	assert.False(t, status.Done)
+
+	// Restarting dispatches the failed segment from its saved cursor
+	delete(table.fail, "0:1")
+	_, err = scan.Start(context.Background())
+	require.NoError(t, err)
+	require.Len(t, dispatcher.segments, 2)
+	assert.Equal(t, "1", dispatcher.segments[0].Cursor)
+	assert.Equal(t, "", dispatcher.segments[1].Cursor)
+
+	for _, segment := range dispatcher.segments {
+		require.NoError(t, scan.ProcessSegment(context.Background(), segment, handler))
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, seen)
+
+	status, err = scan.Status(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Done)
+	assert.Empty(t, status.Failed)
+}
+
+func TestSegmentedScanContinuesBeforeDeadline(t *testing.T) {
+	table := &fakeScanTable{pages: map[int32][][]int{0: {{1}, {2}, {3}}}}
+	dispatcher := &recordingDispatcher{}
+	scan, err := NewSegmentedScan[int](SegmentedScanConfig{
+		JobID:           "backfill",
+		TotalSegments:   1,
+		Query:           table.query,
+		Dispatcher:      dispatcher,
+		DeadlineReserve: time.Minute,
+	})
+	require.NoError(t, err)
+
+	// Less time remains than the reserve, so one page is processed and the
+	// rest of the segment is handed to another worker
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var seen []int
+	handler := func(ctx context.Context, segment ScanSegment, items []int) error {
+		seen = append(seen, items...)
+		return nil
+	}
+	require.NoError(t, scan.ProcessSegment(ctx, ScanSegment{JobID: "backfill", TotalSegments: 1}, handler))
+	assert.Equal(t, []int{1}, seen)
+	require.Len(t, dispatcher.segments, 1)
+	assert.Equal(t, "1", dispatcher.segments[0].Cursor)
+
+	require.NoError(t, scan.ProcessSegment(context.Background(), dispatcher.segments[0], handler))
+	assert.Equal(t, []int{1, 2, 3}, seen)
+}
+
+func TestSegmentedScanValidation(t *testing.T) {
+	table := &fakeScanTable{}
+	_, err := NewSegmentedScan[int](SegmentedScanConfig{Query: table.query, Dispatcher: &recordingDispatcher{}})
+	assert.Error(t, err)
+	_, err = NewSegmentedScan[int](SegmentedScanConfig{JobID: "job", Dispatcher: &recordingDispatcher{}})
+	assert.Error(t, err)
+	_, err = NewSegmentedScan[int](SegmentedScanConfig{JobID: "job", Query: table.query})
+	assert.Error(t, err)
+
+	scan := newTestSegmentedScan(t, table, &recordingDispatcher{})
+	err = scan.ProcessSegment(context.Background(), ScanSegment{JobID: "other", TotalSegments: 2}, nil)
+	assert.Error(t, err)
+
+	segment, err := ParseScanSegment([]byte(`{"job_id":"backfill","segment":1,"total_segments":2,"cursor":"abc"}`))
+	require.NoError(t, err)
+	assert.Equal(t, ScanSegment{JobID: "backfill", Segment: 1, TotalSegments: 2, Cursor: "abc"}, segment)
+
+	_, err = ParseScanSegment([]byte(`{"job_id":"backfill","segment":2,"total_segments":2}`))
+	assert.Error(t, err)
+}
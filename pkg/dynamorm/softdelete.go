@@ -0,0 +1,189 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pay-theory/dynamorm/pkg/core"
+)
+
+// Soft-deleted items keep their data and gain a deleted_at timestamp (and
+// a deleted_by actor, for the audit trail). Queries skip them unless asked
+// not to, Restore removes the marker, and a PurgeJob removes them for good
+// once their retention period has passed. Models opt in by declaring the
+// attributes, e.g.
+//
+//	DeletedAt *time.Time `dynamorm:"attr:deleted_at,omitempty" json:"deleted_at,omitempty"`
+//	DeletedBy string     `dynamorm:"attr:deleted_by,omitempty" json:"deleted_by,omitempty"`
+const (
+	DeletedAtAttribute = "deleted_at"
+	DeletedByAttribute = "deleted_by"
+)
+
+// ErrSoftDeleted is returned by Get for an item that has been soft deleted
+var ErrSoftDeleted = errors.New("item is soft deleted")
+
+// SoftDeletable is implemented by models that are soft deleted
+type SoftDeletable interface {
+	// SoftDeletedAt returns when the item was deleted, or the zero time
+	SoftDeletedAt() time.Time
+}
+
+// IsSoftDeleted reports whether item is a soft-deleted model
+func IsSoftDeleted(item any) bool {
+	model, ok := item.(SoftDeletable)
+	return ok && !model.SoftDeletedAt().IsZero()
+}
+
+// GetWithDeleted retrieves an item by primary key, including soft-deleted
+// items, e.g. to show it in a recycle bin before restoring it
+func (d *DynamORMWrapper) GetWithDeleted(ctx context.Context, key any, result any) error {
+	return d.read(ctx, func(db core.DB) error {
+		return db.Model(result).
+			Where("ID", "=", key).
+			First(result)
+	})
+}
+
+// SoftDelete marks an item as deleted by deletedBy. It fails with a
+// condition error if the item doesn't exist or is already deleted, so the
+// original deletion time and actor are never overwritten.
+func (d *DynamORMWrapper) SoftDelete(ctx context.Context, model any, key any, deletedBy string) error {
+	if err := d.checkWritable(); err != nil {
+		return err
+	}
+
+	update := d.db.WithContext(ctx).Model(model).
+		Where("ID", "=", key).
+		UpdateBuilder().
+		Set(DeletedAtAttribute, time.Now().UTC())
+	if deletedBy != "" {
+		update = update.Set(DeletedByAttribute, deletedBy)
+	}
+
+	return update.
+		ConditionExists("ID").
+		ConditionNotExists(DeletedAtAttribute).
+		Execute()
+}
+
+// Restore undoes a soft delete. It fails with a condition error if the
+// item doesn't exist or isn't deleted.
+func (d *DynamORMWrapper) Restore(ctx context.Context, model any, key any) error {
+	if err := d.checkWritable(); err != nil {
+		return err
+	}
+
+	return d.db.WithContext(ctx).Model(model).
+		Where("ID", "=", key).
+		UpdateBuilder().
+		Remove(DeletedAtAttribute).
+		Remove(DeletedByAttribute).
+		ConditionExists(DeletedAtAttribute).
+		Execute()
+}
+
+// PurgeConfig configures a PurgeJob
+type PurgeConfig struct {
+	// JobID identifies the purge run; reusing it resumes a previous run
+	JobID string
+	// Retention is how long soft-deleted items are kept (default: 30 days)
+	Retention time.Duration
+	// Query returns a new query for the table to purge, as for SegmentedScan
+	Query func(ctx context.Context) core.Query
+	// Dispatcher fans segments out to workers
+	Dispatcher SegmentDispatcher
+	// Progress stores segment progress (default: in memory)
+	Progress ScanProgressStore
+	// TotalSegments is the number of parallel segments (default: 4)
+	TotalSegments int32
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// PurgeFunc permanently deletes one item
+type PurgeFunc[T SoftDeletable] func(ctx context.Context, item T) error
+
+// PurgeJob permanently deletes items that were soft deleted more than the
+// retention period ago. It runs as a SegmentedScan over the deleted items,
+// so it suits a scheduled handler calling Start and a queue consumer
+// calling ProcessSegment.
+type PurgeJob[T SoftDeletable] struct {
+	scan      *SegmentedScan[T]
+	purge     PurgeFunc[T]
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewPurgeJob creates a purge job that deletes expired items with purge
+func NewPurgeJob[T SoftDeletable](config PurgeConfig, purge PurgeFunc[T]) (*PurgeJob[T], error) {
+	if purge == nil {
+		return nil, fmt.Errorf("purge job requires a purge function")
+	}
+	if config.Query == nil {
+		return nil, fmt.Errorf("purge job requires a query")
+	}
+	if config.Retention == 0 {
+		config.Retention = 30 * 24 * time.Hour
+	}
+	if config.Retention < 0 {
+		return nil, fmt.Errorf("retention must be positive, got %s", config.Retention)
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	query := config.Query
+	scan, err := NewSegmentedScan[T](SegmentedScanConfig{
+		JobID:         config.JobID,
+		TotalSegments: config.TotalSegments,
+		Dispatcher:    config.Dispatcher,
+		Progress:      config.Progress,
+		Query: func(ctx context.Context) core.Query {
+			return query(ctx).Filter(DeletedAtAttribute, "attribute_exists", nil)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PurgeJob[T]{
+		scan:      scan,
+		purge:     purge,
+		retention: config.Retention,
+		now:       config.Now,
+	}, nil
+}
+
+// Start dispatches every unfinished segment
+func (p *PurgeJob[T]) Start(ctx context.Context) (int, error) {
+	return p.scan.Start(ctx)
+}
+
+// ProcessSegment purges the expired items in a segment
+func (p *PurgeJob[T]) ProcessSegment(ctx context.Context, segment ScanSegment) error {
+	return p.scan.ProcessSegment(ctx, segment, p.purgePage)
+}
+
+// Status summarizes the progress of the purge
+func (p *PurgeJob[T]) Status(ctx context.Context) (*ScanStatus, error) {
+	return p.scan.Status(ctx)
+}
+
+// purgePage deletes the page's items whose retention has passed. The scan
+// filter only guarantees the marker exists, so the age is checked here.
+func (p *PurgeJob[T]) purgePage(ctx context.Context, segment ScanSegment, items []T) error {
+	cutoff := p.now().Add(-p.retention)
+	for _, item := range items {
+		deletedAt := item.SoftDeletedAt()
+		if deletedAt.IsZero() || deletedAt.After(cutoff) {
+			continue
+		}
+		if err := p.purge(ctx, item); err != nil {
+			return fmt.Errorf("failed to purge item deleted at %s: %w", deletedAt.Format(time.RFC3339), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/dynamorm/pkg/core"
+	"github.com/pay-theory/dynamorm/pkg/mocks"
+	liftmocks "github.com/pay-theory/lift/pkg/dynamorm/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type softDeleteModel struct {
+	ID        string     `dynamorm:"pk" json:"id"`
+	DeletedAt *time.Time `dynamorm:"attr:deleted_at,omitempty" json:"deleted_at,omitempty"`
+}
+
+func (m softDeleteModel) SoftDeletedAt() time.Time {
+	if m.DeletedAt == nil {
+		return time.Time{}
+	}
+	return *m.DeletedAt
+}
+
+func (m *softDeleteModel) markDeleted(at time.Time) *softDeleteModel {
+	m.DeletedAt = &at
+	return m
+}
+
+// newMockWrapper returns a wrapper whose queries all go to query
+func newMockWrapper(query *mocks.MockQuery) *DynamORMWrapper {
+	db := liftmocks.NewMockExtendedDB()
+	db.On("WithContext", mock.Anything).Return(db)
+	db.On("Model", mock.Anything).Return(query)
+	return &DynamORMWrapper{db: db}
+}
+
+func TestGetHidesSoftDeletedItems(t *testing.T) {
+	query := new(mocks.MockQuery)
+	query.On("Where", "ID", "=", "item-1").Return(query)
+	query.On("First", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*softDeleteModel).markDeleted(time.Now())
+	}).Return(nil)
+	wrapper := newMockWrapper(query)
+
+	var item softDeleteModel
+	err := wrapper.Get(context.Background(), "item-1", &item)
+	assert.ErrorIs(t, err, ErrSoftDeleted)
+
+	item = softDeleteModel{}
+	require.NoError(t, wrapper.GetWithDeleted(context.Background(), "item-1", &item))
+	assert.True(t, IsSoftDeleted(&item))
+}
+
+func TestQueryFiltersSoftDeletedItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    Query
+		operator string
+	}{
+		{name: "live items by default", query: Query{}, operator: "attribute_not_exists"},
+		{name: "deleted items only", query: Query{OnlyDeleted: true}, operator: "attribute_exists"},
+		{name: "all items", query: Query{IncludeDeleted: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := new(mocks.MockQuery)
+			query.On("Filter", DeletedAtAttribute, mock.Anything, nil).Return(query)
+			query.On("All", mock.Anything).Return(nil)
+			wrapper := newMockWrapper(query)
+
+			_, err := wrapper.Query(context.Background(), &tt.query)
+			require.NoError(t, err)
+			if tt.operator == "" {
+				query.AssertNotCalled(t, "Filter", DeletedAtAttribute, mock.Anything, nil)
+			} else {
+				query.AssertCalled(t, "Filter", DeletedAtAttribute, tt.operator, nil)
+			}
+		})
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	update := new(mocks.MockUpdateBuilder)
+	update.On("Set", DeletedAtAttribute, mock.AnythingOfType("time.Time")).Return(update)
+	update.On("Set", DeletedByAttribute, "user-1").Return(update)
+	update.On("Remove", mock.Anything).Return(update)
+	update.On("ConditionExists", mock.Anything).Return(update)
+	update.On("ConditionNotExists", DeletedAtAttribute).Return(update)
+	update.On("Execute").Return(nil)
+
+	query := new(mocks.MockQuery)
+	query.On("Where", "ID", "=", "item-1").Return(query)
+	query.On("UpdateBuilder").Return(update)
+	wrapper := newMockWrapper(query)
+
+	require.NoError(t, wrapper.SoftDelete(context.Background(), &softDeleteModel{}, "item-1", "user-1"))
+	// The item must exist and not already be deleted
+	update.AssertCalled(t, "ConditionExists", "ID")
+	update.AssertCalled(t, "ConditionNotExists", DeletedAtAttribute)
+
+	require.NoError(t, wrapper.Restore(context.Background(), &softDeleteModel{}, "item-1"))
+	update.AssertCalled(t, "Remove", DeletedAtAttribute)
+	update.AssertCalled(t, "Remove", DeletedByAttribute)
+	update.AssertCalled(t, "ConditionExists", DeletedAtAttribute)
+}
+
+// fakePurgeQuery serves one page of items to a purge job's scan
+type fakePurgeQuery struct {
+	*mocks.MockQuery
+	items   []softDeleteModel
+	filters []string
+}
+
+func (q *fakePurgeQuery) Filter(field string, op string, value any) core.Query {
+	q.filters = append(q.filters, field+" "+op)
+	return q
+}
+
+func (q *fakePurgeQuery) ParallelScan(segment int32, totalSegments int32) core.Query {
+	return q
+}
+
+func (q *fakePurgeQuery) Limit(limit int) core.Query {
+	return q
+}
+
+func (q *fakePurgeQuery) AllPaginated(dest any) (*core.PaginatedResult, error) {
+	*dest.(*[]softDeleteModel) = q.items
+	return &core.PaginatedResult{Count: len(q.items)}, nil
+}
+
+func TestPurgeJobDeletesExpiredItems(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	query := &fakePurgeQuery{items: []softDeleteModel{
+		*(&softDeleteModel{ID: "expired"}).markDeleted(now.Add(-31 * 24 * time.Hour)),
+		*(&softDeleteModel{ID: "recent"}).markDeleted(now.Add(-time.Hour)),
+		{ID: "live"},
+	}}
+
+	var purged []string
+	dispatcher := &recordingDispatcher{}
+	job, err := NewPurgeJob(PurgeConfig{
+		JobID:         "purge",
+		TotalSegments: 1,
+		Query:         func(ctx context.Context) core.Query { return query },
+		Dispatcher:    dispatcher,
+		Now:           func() time.Time { return now },
+	}, func(ctx context.Context, item softDeleteModel) error {
+		purged = append(purged, item.ID)
+		return nil
+	})
+	require.NoError(t, err)
+
+	dispatched, err := job.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, dispatched)
+	require.NoError(t, job.ProcessSegment(context.Background(), dispatcher.segments[0]))
+
+	assert.Equal(t, []string{"expired"}, purged)
+	assert.Equal(t, []string{"deleted_at attribute_exists"}, query.filters)
+
+	status, err := job.Status(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Done)
+}
+
+func TestPurgeJobReportsPurgeFailures(t *testing.T) {
+	query := &fakePurgeQuery{items: []softDeleteModel{
+		*(&softDeleteModel{ID: "expired"}).markDeleted(time.Now().Add(-48 * time.Hour)),
+	}}
+
+	job, err := NewPurgeJob(PurgeConfig{
+		JobID:      "purge",
+		Retention:  24 * time.Hour,
+		Query:      func(ctx context.Context) core.Query { return query },
+		Dispatcher: &recordingDispatcher{},
+	}, func(ctx context.Context, item softDeleteModel) error {
+		return errors.New("throttled")
+	})
+	require.NoError(t, err)
+
+	err = job.ProcessSegment(context.Background(), ScanSegment{JobID: "purge", Segment: 0, TotalSegments: 4})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "throttled")
+
+	_, err = NewPurgeJob[softDeleteModel](PurgeConfig{JobID: "purge"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,111 @@
+package imports
+
+import (
+	"errors"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// createBody is the body accepted by CreateHandler
+type createBody struct {
+	Format Format `json:"format"`
+}
+
+// jobView is a job as returned by the handlers
+type jobView struct {
+	*Job
+	ReportURL string `json:"report_url,omitempty"`
+}
+
+// CreateHandler creates a job for the caller's tenant and returns the URL
+// to upload its file to. Once the upload succeeds the client calls
+// StartHandler and polls GetHandler for progress:
+//
+//	catalog := app.Group("/catalog/imports")
+//	catalog.POST("", importer.CreateHandler()).Meta(lift.MetaAuth, "jwt").Meta(lift.MetaRoles, "catalog-admin")
+//	catalog.POST("/:id/start", importer.StartHandler()).Meta(lift.MetaAuth, "jwt").Meta(lift.MetaRoles, "catalog-admin")
+//	catalog.GET("/:id", importer.GetHandler()).Meta(lift.MetaAuth, "jwt").Meta(lift.MetaRoles, "catalog-admin")
+func (i *Importer[T]) CreateHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var body createBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+
+		upload, err := i.Create(ctx.Context, ctx.TenantID(), ctx.UserID(), body.Format)
+		if err != nil {
+			return jobError(err)
+		}
+		return ctx.Status(201).JSON(upload)
+	})
+}
+
+// StartHandler starts a job whose file has been uploaded
+func (i *Importer[T]) StartHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		if _, err := i.tenantJob(ctx); err != nil {
+			return err
+		}
+
+		job, err := i.Start(ctx.Context, ctx.Param("id"))
+		if err != nil {
+			return jobError(err)
+		}
+		return ctx.Status(202).JSON(i.view(ctx, job))
+	})
+}
+
+// GetHandler returns a job, its progress and, once it has finished with
+// rejected rows, a short-lived URL for the error report
+func (i *Importer[T]) GetHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		job, err := i.tenantJob(ctx)
+		if err != nil {
+			return err
+		}
+		return ctx.OK(i.view(ctx, job))
+	})
+}
+
+// tenantJob loads the job named by the id parameter, hiding jobs that
+// belong to another tenant
+func (i *Importer[T]) tenantJob(ctx *lift.Context) (*Job, error) {
+	job, err := i.Get(ctx.Context, ctx.Param("id"))
+	if err != nil {
+		return nil, jobError(err)
+	}
+	if tenantID := ctx.TenantID(); tenantID != "" && job.TenantID != tenantID {
+		return nil, jobError(ErrJobNotFound)
+	}
+	return job, nil
+}
+
+// view adds a report URL to jobs with rejected rows
+func (i *Importer[T]) view(ctx *lift.Context, job *Job) jobView {
+	view := jobView{Job: job}
+	if job.ReportKey != "" {
+		if url, err := i.ReportURL(ctx.Context, job); err == nil {
+			view.ReportURL = url
+		} else if ctx.Logger != nil {
+			ctx.Logger.Warn("Failed to sign import report URL", map[string]any{
+				"job_id": job.ID,
+				"error":  err.Error(),
+			})
+		}
+	}
+	return view
+}
+
+// jobError maps importer errors to HTTP errors
+func jobError(err error) error {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		return lift.NewLiftError("IMPORT_NOT_FOUND", "Import job not found", 404)
+	case errors.Is(err, ErrInvalidTransition):
+		return lift.NewLiftError("INVALID_IMPORT_STATE", err.Error(), 409)
+	case errors.Is(err, ErrInvalidJob):
+		return lift.NewLiftError("INVALID_IMPORT", err.Error(), 400)
+	default:
+		return lift.NewLiftError("IMPORT_FAILED", "Failed to process import", 500).WithCause(err)
+	}
+}
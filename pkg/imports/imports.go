@@ -0,0 +1,458 @@
+// Package imports runs bulk imports of CSV and JSON files. Clients upload a
+// file to S3 through a presigned URL and start the import; a worker parses
+// it, validates every row, upserts the valid rows in batches and writes the
+// rows it rejected to a downloadable error report. The job's progress is
+// saved after every batch so clients can poll it.
+//
+//	importer, err := imports.NewImporter(imports.Config[*Product]{
+//		Uploads:   payload.NewS3Store(s3Client, "catalog-imports"),
+//		Jobs:      imports.NewDynamoDBJobStore(dynamoClient, "import-jobs"),
+//		DecodeCSV: decodeProductRow,
+//		Write:     imports.DynamORMWriter[*Product](db),
+//		Dispatch:  enqueueImport,
+//	})
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pay-theory/lift/pkg/payload"
+	"github.com/pay-theory/lift/pkg/validation"
+)
+
+// Format is the format of an uploaded file
+type Format string
+
+const (
+	// FormatCSV is a CSV file whose first row names the columns
+	FormatCSV Format = "csv"
+	// FormatJSON is a JSON array of objects
+	FormatJSON Format = "json"
+)
+
+// Status is the state of an import job
+type Status string
+
+const (
+	StatusAwaitingUpload Status = "awaiting_upload"
+	StatusQueued         Status = "queued"
+	StatusProcessing     Status = "processing"
+	StatusCompleted      Status = "completed"
+	StatusFailed         Status = "failed"
+)
+
+var (
+	// ErrJobNotFound is returned for an unknown job ID
+	ErrJobNotFound = errors.New("import job not found")
+	// ErrInvalidTransition is returned when a job can't move to the requested state
+	ErrInvalidTransition = errors.New("import job can't be changed in its current state")
+	// ErrInvalidJob is returned when a new job is incomplete or malformed
+	ErrInvalidJob = errors.New("invalid import job")
+	// ErrUploadMissing is returned when a job is run before its file was uploaded
+	ErrUploadMissing = errors.New("import file has not been uploaded")
+)
+
+// Progress counts the rows of a job
+type Progress struct {
+	// Total is the number of rows in the file, known once it's parsed
+	Total int `json:"total"`
+	// Processed rows have been imported or rejected
+	Processed int `json:"processed"`
+	Imported  int `json:"imported"`
+	Rejected  int `json:"rejected"`
+}
+
+// Job is an import of one uploaded file
+type Job struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Format    Format    `json:"format"`
+	Status    Status    `json:"status"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	UploadKey string    `json:"upload_key"`
+	ReportKey string    `json:"report_key,omitempty"`
+	Progress  Progress  `json:"progress"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RowError is a problem with one row of a file
+type RowError struct {
+	// Row is the line of a CSV file, counting the header as line 1, or the
+	// 1-based position of the object in a JSON array
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// JobStore persists import jobs
+type JobStore interface {
+	Save(ctx context.Context, job *Job) error
+	// Get returns ErrJobNotFound for an unknown ID
+	Get(ctx context.Context, id string) (*Job, error)
+}
+
+// Config configures an Importer of items of type T
+type Config[T any] struct {
+	// Jobs stores jobs and their progress (default: in memory)
+	Jobs JobStore
+	// Uploads receives uploaded files and error reports; required
+	Uploads payload.Store
+	// Prefix prefixes upload and report object keys (default: "imports/")
+	Prefix string
+	// URLExpiry is the lifetime of upload and report URLs (default: 15m)
+	URLExpiry time.Duration
+	// MaxFileSize is the largest file that will be processed (default: 50MB)
+	MaxFileSize int64
+	// DecodeCSV converts a CSV row, keyed by column name, to an item. CSV
+	// uploads are refused if it's nil. JSON objects are decoded into T.
+	DecodeCSV func(row map[string]string) (T, error)
+	// Validate checks an item before it's written (default:
+	// validation.Validate). validation.ValidationErrors are reported per field.
+	Validate func(ctx context.Context, item T) error
+	// Write upserts a batch of valid items; required. See DynamORMWriter.
+	Write func(ctx context.Context, items []T) error
+	// BatchSize is the number of items passed to each Write (default: 25)
+	BatchSize int
+	// Dispatch, if set, hands started jobs to a worker that calls
+	// Importer.Execute, instead of running them within the starting request
+	Dispatch func(ctx context.Context, jobID string) error
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Importer imports uploaded files of items of type T
+type Importer[T any] struct {
+	config Config[T]
+}
+
+// NewImporter creates an importer
+func NewImporter[T any](config Config[T]) (*Importer[T], error) {
+	if config.Uploads == nil {
+		return nil, fmt.Errorf("imports: an upload store is required")
+	}
+	if config.Write == nil {
+		return nil, fmt.Errorf("imports: a write function is required")
+	}
+	if config.Jobs == nil {
+		config.Jobs = NewMemoryJobStore()
+	}
+	if config.Prefix == "" {
+		config.Prefix = "imports/"
+	}
+	if config.URLExpiry == 0 {
+		config.URLExpiry = payload.DefaultURLExpiry
+	}
+	if config.MaxFileSize == 0 {
+		config.MaxFileSize = 50 << 20
+	}
+	if config.Validate == nil {
+		config.Validate = func(ctx context.Context, item T) error {
+			return validation.Validate(item)
+		}
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 25
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return &Importer[T]{config: config}, nil
+}
+
+// Upload is a new job and where to upload its file
+type Upload struct {
+	Job *Job `json:"job"`
+	// URL accepts a PUT of the file with the Content-Type in ContentType
+	URL         string    `json:"upload_url"`
+	ContentType string    `json:"content_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Create records a job awaiting its file and returns a URL to upload it to
+func (i *Importer[T]) Create(ctx context.Context, tenantID, createdBy string, format Format) (*Upload, error) {
+	switch format {
+	case FormatJSON:
+	case FormatCSV:
+		if i.config.DecodeCSV == nil {
+			return nil, fmt.Errorf("%w: CSV imports are not supported", ErrInvalidJob)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidJob, format)
+	}
+
+	now := i.config.Now().UTC()
+	job := &Job{
+		ID:        uuid.NewString(),
+		TenantID:  tenantID,
+		Format:    format,
+		Status:    StatusAwaitingUpload,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	job.UploadKey = i.key(job, "."+string(format))
+
+	contentType := contentTypes[format]
+	url, err := i.config.Uploads.PresignPut(ctx, job.UploadKey, contentType, i.config.URLExpiry)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.config.Jobs.Save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return &Upload{
+		Job:         job,
+		URL:         url,
+		ContentType: contentType,
+		ExpiresAt:   now.Add(i.config.URLExpiry),
+	}, nil
+}
+
+// Start queues a job whose file has been uploaded, running it immediately
+// unless a Dispatch function is configured
+func (i *Importer[T]) Start(ctx context.Context, id string) (*Job, error) {
+	job, err := i.config.Jobs.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusAwaitingUpload {
+		return nil, ErrInvalidTransition
+	}
+
+	job.Status = StatusQueued
+	job.UpdatedAt = i.config.Now().UTC()
+	if err := i.config.Jobs.Save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	if i.config.Dispatch != nil {
+		if err := i.config.Dispatch(ctx, job.ID); err != nil {
+			return nil, fmt.Errorf("failed to dispatch import %s: %w", job.ID, err)
+		}
+		return job, nil
+	}
+
+	executed, err := i.Execute(ctx, job.ID)
+	if executed == nil {
+		return nil, err
+	}
+	// A failed run is recorded on the job rather than returned
+	return executed, nil
+}
+
+// Get returns a job
+func (i *Importer[T]) Get(ctx context.Context, id string) (*Job, error) {
+	return i.config.Jobs.Get(ctx, id)
+}
+
+// ReportURL returns a presigned URL for a job's error report
+func (i *Importer[T]) ReportURL(ctx context.Context, job *Job) (string, error) {
+	if job.ReportKey == "" {
+		return "", ErrInvalidTransition
+	}
+	return i.config.Uploads.PresignGet(ctx, job.ReportKey, i.config.URLExpiry)
+}
+
+// Execute imports a queued job's file. Failed jobs can be executed again;
+// rows are upserted, so rows written before the failure are overwritten.
+func (i *Importer[T]) Execute(ctx context.Context, id string) (*Job, error) {
+	job, err := i.config.Jobs.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusQueued && job.Status != StatusFailed {
+		return nil, ErrInvalidTransition
+	}
+
+	job.Status = StatusProcessing
+	job.Progress = Progress{}
+	job.ReportKey = ""
+	job.Error = ""
+	if err := i.save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	err = i.run(ctx, job)
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusCompleted
+	}
+
+	if saveErr := i.save(ctx, job); saveErr != nil {
+		return nil, saveErr
+	}
+	return job, err
+}
+
+// run parses, validates and writes the job's file
+func (i *Importer[T]) run(ctx context.Context, job *Job) error {
+	obj, err := i.config.Uploads.Get(ctx, job.UploadKey, i.config.MaxFileSize)
+	if errors.Is(err, payload.ErrNotFound) {
+		return ErrUploadMissing
+	}
+	if err != nil {
+		return err
+	}
+
+	var rows []row[T]
+	switch job.Format {
+	case FormatCSV:
+		rows, err = parseCSV(obj.Body, i.config.DecodeCSV)
+	case FormatJSON:
+		rows, err = parseJSON[T](obj.Body)
+	default:
+		err = fmt.Errorf("%w: unknown format %q", ErrInvalidJob, job.Format)
+	}
+	if err != nil {
+		return err
+	}
+	job.Progress.Total = len(rows)
+
+	var rejected []RowError
+	batch := make([]T, 0, i.config.BatchSize)
+	pending := 0
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := i.config.Write(ctx, batch); err != nil {
+				return fmt.Errorf("failed to write rows: %w", err)
+			}
+			job.Progress.Imported += len(batch)
+		}
+		job.Progress.Processed += pending
+		batch, pending = batch[:0], 0
+		return i.save(ctx, job)
+	}
+
+	for _, r := range rows {
+		pending++
+		if r.err == nil {
+			r.err = i.config.Validate(ctx, r.item)
+		}
+		if r.err != nil {
+			rejected = append(rejected, rowErrors(r.line, r.err)...)
+			job.Progress.Rejected++
+			continue
+		}
+
+		batch = append(batch, r.item)
+		if len(batch) == i.config.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if len(rejected) > 0 {
+		body, err := report(rejected)
+		if err != nil {
+			return err
+		}
+		key := i.key(job, "-errors.csv")
+		if err := i.config.Uploads.Put(ctx, key, body, "text/csv"); err != nil {
+			return fmt.Errorf("failed to store error report: %w", err)
+		}
+		job.ReportKey = key
+	}
+	return nil
+}
+
+// save records the job's progress
+func (i *Importer[T]) save(ctx context.Context, job *Job) error {
+	job.UpdatedAt = i.config.Now().UTC()
+	return i.config.Jobs.Save(ctx, job)
+}
+
+// key builds an object key for the job
+func (i *Importer[T]) key(job *Job, suffix string) string {
+	if job.TenantID != "" {
+		return fmt.Sprintf("%s%s/%s%s", i.config.Prefix, job.TenantID, job.ID, suffix)
+	}
+	return fmt.Sprintf("%s%s%s", i.config.Prefix, job.ID, suffix)
+}
+
+// contentTypes are the upload content types of each format
+var contentTypes = map[Format]string{
+	FormatCSV:  "text/csv",
+	FormatJSON: "application/json",
+}
+
+// rowErrors converts a row's decode or validation error to report entries
+func rowErrors(line int, err error) []RowError {
+	var fields validation.ValidationErrors
+	if errors.As(err, &fields) && len(fields) > 0 {
+		errs := make([]RowError, len(fields))
+		for n, field := range fields {
+			errs[n] = RowError{Row: line, Field: field.Field, Message: field.Message}
+		}
+		return errs
+	}
+
+	var field validation.ValidationError
+	if errors.As(err, &field) {
+		return []RowError{{Row: line, Field: field.Field, Message: field.Message}}
+	}
+	return []RowError{{Row: line, Message: err.Error()}}
+}
+
+// BatchPutter upserts a batch of items; *dynamorm.DynamORMWrapper is one
+type BatchPutter interface {
+	BatchPut(ctx context.Context, items []any) error
+}
+
+// DynamORMWriter returns a Write function that upserts items with DynamORM
+// batch writes. Use dynamorm.Open for a connection outside of a request.
+func DynamORMWriter[T any](db BatchPutter) func(ctx context.Context, items []T) error {
+	return func(ctx context.Context, items []T) error {
+		batch := make([]any, len(items))
+		for n, item := range items {
+			batch[n] = item
+		}
+		return db.BatchPut(ctx, batch)
+	}
+}
+
+// MemoryJobStore keeps jobs in memory, for tests and local development
+type MemoryJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+// NewMemoryJobStore creates an in-memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: make(map[string]Job)}
+}
+
+// Save stores a copy of the job
+func (m *MemoryJobStore) Save(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.jobs[job.ID] = *job
+	return nil
+}
+
+// Get returns a copy of the job
+func (m *MemoryJobStore) Get(ctx context.Context, id string) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
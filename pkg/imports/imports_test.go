@@ -0,0 +1,275 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUploads keeps uploads and reports in memory
+type memoryUploads struct {
+	objects map[string]*payload.Object
+}
+
+func (s *memoryUploads) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	s.objects[key] = &payload.Object{Body: body, ContentType: contentType, Size: int64(len(body))}
+	return nil
+}
+
+func (s *memoryUploads) Get(ctx context.Context, key string, maxSize int64) (*payload.Object, error) {
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, payload.ErrNotFound
+	}
+	return obj, nil
+}
+
+func (s *memoryUploads) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (string, error) {
+	return "https://imports.s3.amazonaws.com/" + key + "?X-Amz-Signature=put", nil
+}
+
+func (s *memoryUploads) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "https://imports.s3.amazonaws.com/" + key + "?X-Amz-Signature=get", nil
+}
+
+func (s *memoryUploads) Delete(ctx context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+type testProduct struct {
+	SKU   string  `json:"sku" validate:"required"`
+	Name  string  `json:"name" validate:"required"`
+	Price float64 `json:"price" validate:"min=0"`
+}
+
+func decodeTestProduct(values map[string]string) (*testProduct, error) {
+	price, err := strconv.ParseFloat(values["price"], 64)
+	if err != nil {
+		return nil, errors.New("price must be a number")
+	}
+	return &testProduct{SKU: values["sku"], Name: values["name"], Price: price}, nil
+}
+
+// testCatalog records written batches
+type testCatalog struct {
+	products map[string]*testProduct
+	batches  []int
+	writeErr error
+}
+
+func (c *testCatalog) BatchPut(ctx context.Context, items []any) error {
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+	c.batches = append(c.batches, len(items))
+	for _, item := range items {
+		product := item.(*testProduct)
+		c.products[product.SKU] = product
+	}
+	return nil
+}
+
+func newTestImporter(t *testing.T) (*Importer[*testProduct], *memoryUploads, *testCatalog) {
+	uploads := &memoryUploads{objects: make(map[string]*payload.Object)}
+	catalog := &testCatalog{products: make(map[string]*testProduct)}
+	importer, err := NewImporter(Config[*testProduct]{
+		Uploads:   uploads,
+		DecodeCSV: decodeTestProduct,
+		Write:     DynamORMWriter[*testProduct](catalog),
+		BatchSize: 2,
+	})
+	require.NoError(t, err)
+	return importer, uploads, catalog
+}
+
+func TestImportCSV(t *testing.T) {
+	importer, uploads, catalog := newTestImporter(t)
+	ctx := context.Background()
+
+	upload, err := importer.Create(ctx, "tenant-a", "admin-1", FormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAwaitingUpload, upload.Job.Status)
+	assert.Equal(t, "imports/tenant-a/"+upload.Job.ID+".csv", upload.Job.UploadKey)
+	assert.Equal(t, "text/csv", upload.ContentType)
+	assert.Contains(t, upload.URL, "X-Amz-Signature=put")
+
+	file := "\xef\xbb\xbfsku, name ,price\n" +
+		"A-1,Widget,9.99\n" +
+		"A-2,,4.50\n" +
+		"A-3,Gadget,cheap\n" +
+		"A-4,Gizmo\n" +
+		"A-5,Doohickey,1\n" +
+		"A-6,Thing,2\n"
+	require.NoError(t, uploads.Put(ctx, upload.Job.UploadKey, []byte(file), "text/csv"))
+
+	job, err := importer.Start(ctx, upload.Job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, job.Status)
+	assert.Equal(t, Progress{Total: 6, Processed: 6, Imported: 3, Rejected: 3}, job.Progress)
+	assert.Equal(t, []int{2, 1}, catalog.batches)
+	assert.Equal(t, "Widget", catalog.products["A-1"].Name)
+
+	require.NotEmpty(t, job.ReportKey)
+	report := string(uploads.objects[job.ReportKey].Body)
+	assert.Equal(t, "row,field,message\n"+
+		"3,Name,field is required\n"+
+		"4,,price must be a number\n"+
+		"5,,\"expected 3 columns, found 2\"\n", report)
+
+	url, err := importer.ReportURL(ctx, job)
+	require.NoError(t, err)
+	assert.Contains(t, url, "X-Amz-Signature=get")
+
+	_, err = importer.Start(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+}
+
+func TestImportJSON(t *testing.T) {
+	importer, uploads, catalog := newTestImporter(t)
+	ctx := context.Background()
+
+	upload, err := importer.Create(ctx, "", "admin-1", FormatJSON)
+	require.NoError(t, err)
+	require.NoError(t, uploads.Put(ctx, upload.Job.UploadKey, []byte(`[
+		{"sku": "B-1", "name": "Widget", "price": 3},
+		{"sku": "B-2", "name": "Gadget", "price": "free"}
+	]`), "application/json"))
+
+	job, err := importer.Start(ctx, upload.Job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Progress{Total: 2, Processed: 2, Imported: 1, Rejected: 1}, job.Progress)
+	assert.Contains(t, catalog.products, "B-1")
+	assert.Contains(t, string(uploads.objects[job.ReportKey].Body), `2,,"field price: expected float64, found string"`)
+}
+
+func TestImportFailures(t *testing.T) {
+	importer, uploads, catalog := newTestImporter(t)
+	ctx := context.Background()
+
+	t.Run("missing upload", func(t *testing.T) {
+		upload, err := importer.Create(ctx, "tenant-a", "admin-1", FormatCSV)
+		require.NoError(t, err)
+		job, err := importer.Start(ctx, upload.Job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, job.Status)
+		assert.Equal(t, ErrUploadMissing.Error(), job.Error)
+	})
+
+	t.Run("failed writes can be retried", func(t *testing.T) {
+		upload, err := importer.Create(ctx, "tenant-a", "admin-1", FormatJSON)
+		require.NoError(t, err)
+		require.NoError(t, uploads.Put(ctx, upload.Job.UploadKey, []byte(`[{"sku":"C-1","name":"Widget"}]`), "application/json"))
+
+		catalog.writeErr = errors.New("throttled")
+		job, err := importer.Start(ctx, upload.Job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, job.Status)
+		assert.Contains(t, job.Error, "throttled")
+
+		catalog.writeErr = nil
+		job, err = importer.Execute(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusCompleted, job.Status)
+		assert.Equal(t, 1, job.Progress.Imported)
+		assert.Empty(t, job.ReportKey)
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		upload, err := importer.Create(ctx, "tenant-a", "admin-1", FormatJSON)
+		require.NoError(t, err)
+		require.NoError(t, uploads.Put(ctx, upload.Job.UploadKey, []byte(`{"sku":"C-1"}`), "application/json"))
+
+		job, err := importer.Start(ctx, upload.Job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, job.Status)
+		assert.True(t, strings.HasPrefix(job.Error, ErrInvalidJob.Error()))
+	})
+
+	t.Run("unsupported formats", func(t *testing.T) {
+		_, err := importer.Create(ctx, "tenant-a", "admin-1", "xlsx")
+		assert.ErrorIs(t, err, ErrInvalidJob)
+
+		jsonOnly, err := NewImporter(Config[*testProduct]{Uploads: uploads, Write: DynamORMWriter[*testProduct](catalog)})
+		require.NoError(t, err)
+		_, err = jsonOnly.Create(ctx, "tenant-a", "admin-1", FormatCSV)
+		assert.ErrorIs(t, err, ErrInvalidJob)
+	})
+}
+
+func TestImportDispatch(t *testing.T) {
+	uploads := &memoryUploads{objects: make(map[string]*payload.Object)}
+	var dispatched []string
+	importer, err := NewImporter(Config[*testProduct]{
+		Uploads: uploads,
+		Write:   func(ctx context.Context, items []*testProduct) error { return nil },
+		Dispatch: func(ctx context.Context, jobID string) error {
+			dispatched = append(dispatched, jobID)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	upload, err := importer.Create(context.Background(), "tenant-a", "admin-1", FormatJSON)
+	require.NoError(t, err)
+	job, err := importer.Start(context.Background(), upload.Job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, job.Status)
+	assert.Equal(t, []string{job.ID}, dispatched)
+}
+
+func TestHandlers(t *testing.T) {
+	importer, uploads, _ := newTestImporter(t)
+
+	call := func(handler lift.Handler, tenant, id, body string) (*lift.Context, error) {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method: "POST",
+			Path:   "/catalog/imports",
+			Body:   []byte(body),
+		}))
+		ctx.SetUserID("admin-1")
+		ctx.SetTenantID(tenant)
+		ctx.SetParam("id", id)
+		return ctx, handler.Handle(ctx)
+	}
+
+	ctx, err := call(importer.CreateHandler(), "tenant-a", "", `{"format":"csv"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 201, ctx.Response.StatusCode)
+	upload := ctx.Response.Body.(*Upload)
+	assert.Equal(t, "admin-1", upload.Job.CreatedBy)
+
+	// Jobs from other tenants are hidden
+	_, err = call(importer.StartHandler(), "tenant-b", upload.Job.ID, "")
+	var liftErr *lift.LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 404, liftErr.StatusCode)
+
+	require.NoError(t, uploads.Put(context.Background(), upload.Job.UploadKey, []byte("sku,name,price\nA-1,,1\n"), "text/csv"))
+	ctx, err = call(importer.StartHandler(), "tenant-a", upload.Job.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, 202, ctx.Response.StatusCode)
+
+	ctx, err = call(importer.GetHandler(), "tenant-a", upload.Job.ID, "")
+	require.NoError(t, err)
+	view := ctx.Response.Body.(jobView)
+	assert.Equal(t, 1, view.Progress.Rejected)
+	assert.Contains(t, view.ReportURL, "X-Amz-Signature=get")
+
+	_, err = call(importer.StartHandler(), "tenant-a", upload.Job.ID, "")
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 409, liftErr.StatusCode)
+
+	_, err = call(importer.CreateHandler(), "tenant-a", "", `{"format":"xml"}`)
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 400, liftErr.StatusCode)
+}
@@ -0,0 +1,125 @@
+package imports
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBJobStore implements JobStore using DynamoDB. Jobs are stored
+// under the "pk" partition key and removed by the table's TTL 30 days
+// after they were last updated.
+type DynamoDBJobStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBJobStore creates a new DynamoDB-backed job store
+func NewDynamoDBJobStore(client *dynamodb.Client, tableName string) *DynamoDBJobStore {
+	return &DynamoDBJobStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// DynamoDBJobRecord represents the DynamoDB item structure
+type DynamoDBJobRecord struct {
+	PK        string    `dynamodbav:"pk"`
+	TenantID  string    `dynamodbav:"tenant_id,omitempty"`
+	Format    string    `dynamodbav:"format"`
+	Status    string    `dynamodbav:"status"`
+	CreatedBy string    `dynamodbav:"created_by,omitempty"`
+	UploadKey string    `dynamodbav:"upload_key"`
+	ReportKey string    `dynamodbav:"report_key,omitempty"`
+	Total     int       `dynamodbav:"total"`
+	Processed int       `dynamodbav:"processed"`
+	Imported  int       `dynamodbav:"imported"`
+	Rejected  int       `dynamodbav:"rejected"`
+	Error     string    `dynamodbav:"error,omitempty"`
+	CreatedAt time.Time `dynamodbav:"created_at"`
+	UpdatedAt time.Time `dynamodbav:"updated_at"`
+	TTL       int64     `dynamodbav:"ttl"`
+}
+
+// Save creates or replaces a job
+func (d *DynamoDBJobStore) Save(ctx context.Context, job *Job) error {
+	record := DynamoDBJobRecord{
+		PK:        job.ID,
+		TenantID:  job.TenantID,
+		Format:    string(job.Format),
+		Status:    string(job.Status),
+		CreatedBy: job.CreatedBy,
+		UploadKey: job.UploadKey,
+		ReportKey: job.ReportKey,
+		Total:     job.Progress.Total,
+		Processed: job.Progress.Processed,
+		Imported:  job.Progress.Imported,
+		Rejected:  job.Progress.Rejected,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+		TTL:       job.UpdatedAt.Add(30 * 24 * time.Hour).Unix(),
+	}
+
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	}
+
+	_, err = d.client.PutItem(ctx, input)
+	return err
+}
+
+// Get retrieves a job
+func (d *DynamoDBJobStore) Get(ctx context.Context, id string) (*Job, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: id},
+		},
+		// Clients poll for progress written by the worker
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := d.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, ErrJobNotFound
+	}
+
+	var record DynamoDBJobRecord
+	if err := attributevalue.UnmarshalMap(result.Item, &record); err != nil {
+		return nil, err
+	}
+
+	return &Job{
+		ID:        record.PK,
+		TenantID:  record.TenantID,
+		Format:    Format(record.Format),
+		Status:    Status(record.Status),
+		CreatedBy: record.CreatedBy,
+		UploadKey: record.UploadKey,
+		ReportKey: record.ReportKey,
+		Progress: Progress{
+			Total:     record.Total,
+			Processed: record.Processed,
+			Imported:  record.Imported,
+			Rejected:  record.Rejected,
+		},
+		Error:     record.Error,
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+	}, nil
+}
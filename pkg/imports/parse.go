@@ -0,0 +1,104 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// row is a decoded row, or the reason it couldn't be decoded
+type row[T any] struct {
+	line int
+	item T
+	err  error
+}
+
+// parseCSV decodes each row of a CSV file. A malformed file is an error;
+// rows that can't be decoded are returned with their error.
+func parseCSV[T any](body []byte, decode func(map[string]string) (T, error)) ([]row[T], error) {
+	// Spreadsheets often save CSV with a byte order mark
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: CSV header can't be read: %v", ErrInvalidJob, err)
+	}
+	for n := range header {
+		header[n] = strings.TrimSpace(header[n])
+	}
+
+	var rows []row[T]
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed CSV: %v", ErrInvalidJob, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(record) != len(header) {
+			rows = append(rows, row[T]{line: line, err: fmt.Errorf("expected %d columns, found %d", len(header), len(record))})
+			continue
+		}
+		values := make(map[string]string, len(header))
+		for n, name := range header {
+			values[name] = strings.TrimSpace(record[n])
+		}
+
+		item, err := decode(values)
+		rows = append(rows, row[T]{line: line, item: item, err: err})
+	}
+	return rows, nil
+}
+
+// parseJSON decodes each object of a JSON array into T. A file that isn't
+// an array is an error; objects that can't be decoded are returned with
+// their error.
+func parseJSON[T any](body []byte) ([]row[T], error) {
+	var records []json.RawMessage
+	if err := json.Unmarshal(body, &records); err != nil {
+		return nil, fmt.Errorf("%w: file must be a JSON array: %v", ErrInvalidJob, err)
+	}
+
+	rows := make([]row[T], len(records))
+	for n, record := range records {
+		rows[n].line = n + 1
+		if err := json.Unmarshal(record, &rows[n].item); err != nil {
+			rows[n].err = jsonRowError(err)
+		}
+	}
+	return rows, nil
+}
+
+// jsonRowError names the field of a JSON decoding error
+func jsonRowError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Errorf("field %s: expected %s, found %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+	return err
+}
+
+// report renders rejected rows as a CSV error report
+func report(errs []RowError) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write([]string{"row", "field", "message"}); err != nil {
+		return nil, err
+	}
+	for _, e := range errs {
+		if err := writer.Write([]string{strconv.Itoa(e.Row), e.Field, e.Message}); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
@@ -3,26 +3,30 @@ package adapters
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // TriggerType represents the type of Lambda trigger
 type TriggerType string
 
 const (
-	TriggerAPIGateway   TriggerType = "api_gateway"
-	TriggerAPIGatewayV2 TriggerType = "api_gateway_v2"
-	TriggerSQS          TriggerType = "sqs"
-	TriggerS3           TriggerType = "s3"
-	TriggerEventBridge  TriggerType = "eventbridge"
-	TriggerWebSocket    TriggerType = "websocket"
-	TriggerUnknown      TriggerType = "unknown"
+	TriggerAPIGateway     TriggerType = "api_gateway"
+	TriggerAPIGatewayV2   TriggerType = "api_gateway_v2"
+	TriggerSQS            TriggerType = "sqs"
+	TriggerS3             TriggerType = "s3"
+	TriggerSNS            TriggerType = "sns"
+	TriggerEventBridge    TriggerType = "eventbridge"
+	TriggerWebSocket      TriggerType = "websocket"
+	TriggerDynamoDBStream TriggerType = "dynamodb_stream"
+	TriggerCognito        TriggerType = "cognito"
+	TriggerUnknown        TriggerType = "unknown"
 )
 
 // Request represents a normalized request from any event source
 type Request struct {
 	// Event metadata
 	TriggerType TriggerType `json:"trigger_type"`
-	RawEvent    any `json:"raw_event,omitempty"`
+	RawEvent    any         `json:"raw_event,omitempty"`
 	EventID     string      `json:"event_id,omitempty"`
 	Timestamp   string      `json:"timestamp,omitempty"`
 
@@ -33,12 +37,14 @@ type Request struct {
 	QueryParams map[string]string `json:"query_params,omitempty"`
 	PathParams  map[string]string `json:"path_params,omitempty"`
 	Body        []byte            `json:"body,omitempty"`
+	// Stage is the API Gateway stage that received the request
+	Stage string `json:"stage,omitempty"`
 
 	// Event-specific data
 	Records    []any          `json:"records,omitempty"`
 	Detail     map[string]any `json:"detail,omitempty"`
-	Source     string                 `json:"source,omitempty"`
-	DetailType string                 `json:"detail_type,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	DetailType string         `json:"detail_type,omitempty"`
 
 	// Additional metadata for specific event types (e.g., WebSocket)
 	Metadata map[string]any `json:"metadata,omitempty"`
@@ -75,8 +81,11 @@ func NewAdapterRegistry() *AdapterRegistry {
 	registry.Register(NewAPIGatewayV2Adapter())
 	registry.Register(NewSQSAdapter())
 	registry.Register(NewS3Adapter())
+	registry.Register(NewSNSAdapter())
 	registry.Register(NewEventBridgeAdapter())
 	registry.Register(NewWebSocketAdapter())
+	registry.Register(NewDynamoDBStreamsAdapter())
+	registry.Register(NewCognitoAdapter())
 
 	return registry
 }
@@ -135,6 +144,26 @@ func (b *BaseAdapter) GetTriggerType() TriggerType {
 	return b.triggerType
 }
 
+// StripPathPrefix removes prefix from path when it matches whole path
+// segments, so "/prod" is stripped from "/prod/users" but not "/products".
+// The result is never empty.
+func StripPathPrefix(path, prefix string) (string, bool) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" || !strings.HasPrefix(path, prefix) {
+		return path, false
+	}
+
+	rest := path[len(prefix):]
+	switch {
+	case rest == "":
+		return "/", true
+	case strings.HasPrefix(rest, "/"):
+		return rest, true
+	default:
+		return path, false
+	}
+}
+
 // extractStringField safely extracts a string field from a map
 func extractStringField(data map[string]any, key string) string {
 	if value, exists := data[key]; exists {
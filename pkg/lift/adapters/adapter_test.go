@@ -334,8 +334,11 @@ func TestAdapterRegistry_ListSupportedTriggers(t *testing.T) {
 		TriggerAPIGatewayV2,
 		TriggerSQS,
 		TriggerS3,
+		TriggerSNS,
 		TriggerEventBridge,
 		TriggerWebSocket,
+		TriggerDynamoDBStream,
+		TriggerCognito,
 	}
 
 	if len(triggers) != len(expectedTriggers) {
@@ -158,5 +158,6 @@ func (a *APIGatewayAdapter) Adapt(rawEvent any) (*Request, error) {
 		QueryParams: queryParams,
 		PathParams:  pathParams,
 		Body:        body,
+		Stage:       extractStringField(requestContext, "stage"),
 	}, nil
 }
@@ -84,16 +84,7 @@ func (a *APIGatewayV2Adapter) Adapt(rawEvent any) (*Request, error) {
 	// the stage in the path. We need to strip it for proper routing.
 	stage := extractStringField(requestContext, "stage")
 	if stage != "" && stage != "$default" {
-		// Check if path starts with stage prefix
-		stagePrefix := "/" + stage
-		if strings.HasPrefix(path, stagePrefix) {
-			// Strip stage prefix from path
-			path = strings.TrimPrefix(path, stagePrefix)
-			// Handle case where path becomes empty after stripping
-			if path == "" {
-				path = "/"
-			}
-		}
+		path, _ = StripPathPrefix(path, stage)
 	}
 
 	// Extract headers (case-insensitive)
@@ -148,5 +139,6 @@ func (a *APIGatewayV2Adapter) Adapt(rawEvent any) (*Request, error) {
 		QueryParams: queryParams,
 		PathParams:  pathParams,
 		Body:        body,
+		Stage:       stage,
 	}, nil
 }
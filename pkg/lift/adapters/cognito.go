@@ -0,0 +1,180 @@
+package adapters
+
+import (
+	"fmt"
+)
+
+// CognitoAdapter handles Cognito user pool trigger events
+type CognitoAdapter struct {
+	BaseAdapter
+}
+
+// NewCognitoAdapter creates a new Cognito adapter
+func NewCognitoAdapter() *CognitoAdapter {
+	return &CognitoAdapter{
+		BaseAdapter: BaseAdapter{triggerType: TriggerCognito},
+	}
+}
+
+// CanHandle checks if this adapter can handle the given event
+func (a *CognitoAdapter) CanHandle(event any) bool {
+	eventMap, ok := event.(map[string]any)
+	if !ok {
+		return false
+	}
+	return extractStringField(eventMap, "triggerSource") != "" && extractStringField(eventMap, "userPoolId") != ""
+}
+
+// Validate checks if the event has the required Cognito trigger structure
+func (a *CognitoAdapter) Validate(event any) error {
+	eventMap, ok := event.(map[string]any)
+	if !ok {
+		return fmt.Errorf("event must be a map[string]any")
+	}
+	for _, field := range []string{"triggerSource", "userPoolId"} {
+		if extractStringField(eventMap, field) == "" {
+			return fmt.Errorf("missing required field: %s", field)
+		}
+	}
+	return nil
+}
+
+// Adapt converts a Cognito trigger event to a normalized Request. The raw
+// event is kept as it is: Cognito expects the function to return the same
+// event with its response filled in.
+func (a *CognitoAdapter) Adapt(rawEvent any) (*Request, error) {
+	if err := a.Validate(rawEvent); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	eventMap := rawEvent.(map[string]any)
+	trigger := ParseCognitoTrigger(eventMap)
+
+	return &Request{
+		TriggerType: TriggerCognito,
+		RawEvent:    rawEvent,
+		Source:      "aws:cognito-idp",
+		DetailType:  trigger.TriggerSource,
+		Detail:      trigger.Request,
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+		Metadata: map[string]any{
+			"triggerSource": trigger.TriggerSource,
+			"userPoolId":    trigger.UserPoolID,
+			"userName":      trigger.UserName,
+			"clientId":      trigger.ClientID,
+		},
+	}, nil
+}
+
+// CognitoTrigger is a Cognito user pool trigger event. Request and Response
+// share their maps with the raw event, so changes to Response are returned
+// to Cognito.
+type CognitoTrigger struct {
+	// TriggerSource names the trigger, e.g. "PreSignUp_SignUp" or
+	// "TokenGeneration_Authentication"
+	TriggerSource string
+	// Version is the event version; pre token generation events of version
+	// "2" and later can customize access tokens
+	Version    string
+	Region     string
+	UserPoolID string
+	UserName   string
+	// ClientID is the app client that made the request, if any
+	ClientID string
+	// UserAttributes are the user's attributes, e.g. "email" and
+	// "custom:tenant_id"
+	UserAttributes map[string]string
+	Request        map[string]any
+	Response       map[string]any
+}
+
+// ParseCognitoTrigger reads a Cognito trigger event, adding an empty
+// response to events that have none
+func ParseCognitoTrigger(event map[string]any) *CognitoTrigger {
+	request := cognitoSection(event, "request")
+	response := cognitoSection(event, "response")
+
+	attributes := make(map[string]string)
+	for name, value := range extractMapField(request, "userAttributes") {
+		if s, ok := value.(string); ok {
+			attributes[name] = s
+		}
+	}
+
+	return &CognitoTrigger{
+		TriggerSource:  extractStringField(event, "triggerSource"),
+		Version:        extractStringField(event, "version"),
+		Region:         extractStringField(event, "region"),
+		UserPoolID:     extractStringField(event, "userPoolId"),
+		UserName:       extractStringField(event, "userName"),
+		ClientID:       extractStringField(extractMapField(event, "callerContext"), "clientId"),
+		UserAttributes: attributes,
+		Request:        request,
+		Response:       response,
+	}
+}
+
+// AutoConfirmUser confirms the user without a confirmation code (pre
+// sign-up)
+func (t *CognitoTrigger) AutoConfirmUser() {
+	t.Response["autoConfirmUser"] = true
+}
+
+// AutoVerifyEmail marks the user's email verified (pre sign-up)
+func (t *CognitoTrigger) AutoVerifyEmail() {
+	t.Response["autoVerifyEmail"] = true
+}
+
+// AutoVerifyPhone marks the user's phone number verified (pre sign-up)
+func (t *CognitoTrigger) AutoVerifyPhone() {
+	t.Response["autoVerifyPhone"] = true
+}
+
+// AddClaims adds or overrides ID token claims (pre token generation). For
+// version 2 and later events the claims are added to the access token too.
+func (t *CognitoTrigger) AddClaims(claims map[string]string) {
+	for _, details := range t.tokenOverrides() {
+		add := cognitoSection(details, "claimsToAddOrOverride")
+		for name, value := range claims {
+			add[name] = value
+		}
+	}
+}
+
+// SuppressClaims removes claims from the issued tokens (pre token
+// generation)
+func (t *CognitoTrigger) SuppressClaims(claims ...string) {
+	for _, details := range t.tokenOverrides() {
+		suppress, _ := details["claimsToSuppress"].([]any)
+		for _, claim := range claims {
+			suppress = append(suppress, claim)
+		}
+		details["claimsToSuppress"] = suppress
+	}
+}
+
+// tokenOverrides returns the response sections that customize tokens: the
+// claimsOverrideDetails of version 1 events, or the ID and access token
+// sections of claimsAndScopeOverrideDetails in later versions
+func (t *CognitoTrigger) tokenOverrides() []map[string]any {
+	if t.Version == "" || t.Version == "1" {
+		return []map[string]any{cognitoSection(t.Response, "claimsOverrideDetails")}
+	}
+	details := cognitoSection(t.Response, "claimsAndScopeOverrideDetails")
+	return []map[string]any{
+		cognitoSection(details, "idTokenGeneration"),
+		cognitoSection(details, "accessTokenGeneration"),
+	}
+}
+
+// cognitoSection returns a nested object of the event, adding it if it is
+// missing or null so that changes to it reach the returned event
+func cognitoSection(parent map[string]any, name string) map[string]any {
+	child, ok := parent[name].(map[string]any)
+	if !ok {
+		child = make(map[string]any)
+		parent[name] = child
+	}
+	return child
+}
@@ -0,0 +1,86 @@
+package adapters
+
+import (
+	"testing"
+)
+
+func cognitoEvent(triggerSource, version string) map[string]any {
+	return map[string]any{
+		"version":       version,
+		"triggerSource": triggerSource,
+		"region":        "us-east-1",
+		"userPoolId":    "us-east-1_abc123",
+		"userName":      "user-1",
+		"callerContext": map[string]any{"awsSdkVersion": "aws-sdk-unknown-unknown", "clientId": "client-1"},
+		"request": map[string]any{
+			"userAttributes": map[string]any{
+				"email":            "ada@example.com",
+				"custom:tenant_id": "tenant-1",
+			},
+		},
+		"response": nil,
+	}
+}
+
+func TestCognitoAdapter(t *testing.T) {
+	event := cognitoEvent("PreSignUp_SignUp", "1")
+
+	registry := NewAdapterRegistry()
+	req, err := registry.DetectAndAdapt(event)
+	if err != nil {
+		t.Fatalf("DetectAndAdapt failed: %v", err)
+	}
+	if req.TriggerType != TriggerCognito || req.DetailType != "PreSignUp_SignUp" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if req.Metadata["clientId"] != "client-1" || req.Metadata["userName"] != "user-1" {
+		t.Errorf("unexpected metadata: %v", req.Metadata)
+	}
+
+	if NewCognitoAdapter().CanHandle(map[string]any{"triggerSource": "PreSignUp_SignUp"}) {
+		t.Error("events without a user pool aren't Cognito triggers")
+	}
+}
+
+func TestCognitoTriggerPreSignUp(t *testing.T) {
+	event := cognitoEvent("PreSignUp_SignUp", "1")
+	trigger := ParseCognitoTrigger(event)
+
+	if trigger.UserAttributes["custom:tenant_id"] != "tenant-1" {
+		t.Errorf("unexpected attributes: %v", trigger.UserAttributes)
+	}
+
+	trigger.AutoConfirmUser()
+	trigger.AutoVerifyEmail()
+
+	response := event["response"].(map[string]any)
+	if response["autoConfirmUser"] != true || response["autoVerifyEmail"] != true {
+		t.Errorf("changes didn't reach the event: %v", response)
+	}
+}
+
+func TestCognitoTriggerPreTokenGeneration(t *testing.T) {
+	event := cognitoEvent("TokenGeneration_Authentication", "1")
+	trigger := ParseCognitoTrigger(event)
+	trigger.AddClaims(map[string]string{"tenant_id": "tenant-1"})
+	trigger.SuppressClaims("email")
+
+	details := event["response"].(map[string]any)["claimsOverrideDetails"].(map[string]any)
+	if details["claimsToAddOrOverride"].(map[string]any)["tenant_id"] != "tenant-1" {
+		t.Errorf("claim not added: %v", details)
+	}
+	if suppressed := details["claimsToSuppress"].([]any); len(suppressed) != 1 || suppressed[0] != "email" {
+		t.Errorf("claim not suppressed: %v", details)
+	}
+
+	event = cognitoEvent("TokenGeneration_Authentication", "2")
+	ParseCognitoTrigger(event).AddClaims(map[string]string{"tenant_id": "tenant-1"})
+
+	overrides := event["response"].(map[string]any)["claimsAndScopeOverrideDetails"].(map[string]any)
+	for _, token := range []string{"idTokenGeneration", "accessTokenGeneration"} {
+		claims := overrides[token].(map[string]any)["claimsToAddOrOverride"].(map[string]any)
+		if claims["tenant_id"] != "tenant-1" {
+			t.Errorf("claim not added to %s: %v", token, overrides)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DynamoDBStreamsAdapter handles DynamoDB Streams events
+type DynamoDBStreamsAdapter struct {
+	BaseAdapter
+}
+
+// NewDynamoDBStreamsAdapter creates a new DynamoDB Streams adapter
+func NewDynamoDBStreamsAdapter() *DynamoDBStreamsAdapter {
+	return &DynamoDBStreamsAdapter{
+		BaseAdapter: BaseAdapter{triggerType: TriggerDynamoDBStream},
+	}
+}
+
+// CanHandle checks if this adapter can handle the given event
+func (a *DynamoDBStreamsAdapter) CanHandle(event any) bool {
+	eventMap, ok := event.(map[string]any)
+	if !ok {
+		return false
+	}
+
+	records, ok := eventMap["Records"].([]any)
+	if !ok || len(records) == 0 {
+		return false
+	}
+	firstRecord, ok := records[0].(map[string]any)
+	if !ok {
+		return false
+	}
+
+	// DynamoDB stream records have eventSource "aws:dynamodb"
+	return extractStringField(firstRecord, "eventSource") == "aws:dynamodb"
+}
+
+// Validate checks if the event has the required DynamoDB Streams structure
+func (a *DynamoDBStreamsAdapter) Validate(event any) error {
+	eventMap, ok := event.(map[string]any)
+	if !ok {
+		return fmt.Errorf("event must be a map[string]any")
+	}
+
+	records, ok := eventMap["Records"].([]any)
+	if !ok {
+		return fmt.Errorf("missing required field: records")
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("records slice cannot be empty")
+	}
+
+	firstRecord, ok := records[0].(map[string]any)
+	if !ok {
+		return fmt.Errorf("records must contain map objects")
+	}
+	for _, field := range []string{"eventSource", "eventName", "dynamodb"} {
+		if _, exists := firstRecord[field]; !exists {
+			return fmt.Errorf("missing required field in record: %s", field)
+		}
+	}
+
+	return nil
+}
+
+// Adapt converts a DynamoDB Streams event to a normalized Request
+func (a *DynamoDBStreamsAdapter) Adapt(rawEvent any) (*Request, error) {
+	if err := a.Validate(rawEvent); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	eventMap := rawEvent.(map[string]any)
+	records := extractSliceField(eventMap, "Records")
+
+	var eventID, timestamp string
+	if firstRecord, ok := records[0].(map[string]any); ok {
+		eventID = extractStringField(firstRecord, "eventID")
+		timestamp = approximateCreationTime(extractMapField(firstRecord, "dynamodb"))
+	}
+
+	return &Request{
+		TriggerType: TriggerDynamoDBStream,
+		RawEvent:    rawEvent,
+		EventID:     eventID,
+		Timestamp:   timestamp,
+		Records:     records,
+		Source:      "aws:dynamodb",
+	}, nil
+}
+
+// DynamoDBStreamRecord is a stream record with its keys and images
+// converted from DynamoDB attribute values to plain JSON values. Numbers
+// are json.Number, binary values are base64 strings and sets are slices.
+type DynamoDBStreamRecord struct {
+	EventID        string         `json:"eventID"`
+	EventName      string         `json:"eventName"` // INSERT, MODIFY or REMOVE
+	TableName      string         `json:"tableName"`
+	SequenceNumber string         `json:"sequenceNumber"`
+	Keys           map[string]any `json:"keys"`
+	NewImage       map[string]any `json:"newImage,omitempty"`
+	OldImage       map[string]any `json:"oldImage,omitempty"`
+	// UserIdentity is set when DynamoDB itself made the change, as when
+	// TTL expires an item
+	UserIdentity *StreamUserIdentity `json:"userIdentity,omitempty"`
+}
+
+// StreamUserIdentity identifies who made a stream record's change
+type StreamUserIdentity struct {
+	Type        string `json:"type"`
+	PrincipalID string `json:"principalId"`
+}
+
+// IsTTLExpiry reports whether the record is the removal of an item whose
+// TTL expired, rather than a delete by the application
+func (r *DynamoDBStreamRecord) IsTTLExpiry() bool {
+	return r.EventName == "REMOVE" && r.UserIdentity != nil &&
+		r.UserIdentity.Type == "Service" && r.UserIdentity.PrincipalID == "dynamodb.amazonaws.com"
+}
+
+// SplitDynamoDBStreamRecords returns one request per record of a DynamoDB
+// Streams batch. Each request's body is the record as a JSON
+// DynamoDBStreamRecord, so typed handlers can decode it directly, and its
+// metadata holds the event name, sequence number, table name and keys.
+func SplitDynamoDBStreamRecords(req *Request) []*Request {
+	requests := make([]*Request, 0, len(req.Records))
+	for _, raw := range req.Records {
+		record, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		stream := extractMapField(record, "dynamodb")
+		arn := extractStringField(record, "eventSourceARN")
+
+		parsed := DynamoDBStreamRecord{
+			EventID:        extractStringField(record, "eventID"),
+			EventName:      extractStringField(record, "eventName"),
+			TableName:      StreamTableName(arn),
+			SequenceNumber: extractStringField(stream, "SequenceNumber"),
+			Keys:           UnmarshalStreamImage(extractMapField(stream, "Keys")),
+		}
+		if image, ok := stream["NewImage"].(map[string]any); ok {
+			parsed.NewImage = UnmarshalStreamImage(image)
+		}
+		if image, ok := stream["OldImage"].(map[string]any); ok {
+			parsed.OldImage = UnmarshalStreamImage(image)
+		}
+		if identity, ok := record["userIdentity"].(map[string]any); ok {
+			parsed.UserIdentity = &StreamUserIdentity{
+				Type:        extractStringField(identity, "type"),
+				PrincipalID: extractStringField(identity, "principalId"),
+			}
+		}
+		body, err := json.Marshal(parsed)
+		if err != nil {
+			continue
+		}
+
+		requests = append(requests, &Request{
+			TriggerType: TriggerDynamoDBStream,
+			RawEvent:    record,
+			EventID:     parsed.EventID,
+			Timestamp:   approximateCreationTime(stream),
+			Headers:     make(map[string]string),
+			QueryParams: make(map[string]string),
+			Body:        body,
+			Records:     []any{record},
+			Source:      "aws:dynamodb",
+			Metadata: map[string]any{
+				"eventName":      parsed.EventName,
+				"sequenceNumber": parsed.SequenceNumber,
+				"tableName":      parsed.TableName,
+				"eventSourceARN": arn,
+				"keys":           parsed.Keys,
+			},
+		})
+	}
+	return requests
+}
+
+// StreamTableName extracts the table name from a stream ARN such as
+// arn:aws:dynamodb:us-east-1:123456789012:table/orders/stream/2024-06-01T00:00:00.000
+func StreamTableName(arn string) string {
+	_, rest, ok := strings.Cut(arn, ":table/")
+	if !ok {
+		return ""
+	}
+	table, _, _ := strings.Cut(rest, "/")
+	return table
+}
+
+// UnmarshalStreamImage converts a map of DynamoDB JSON attribute values,
+// as found in stream records, to plain values
+func UnmarshalStreamImage(image map[string]any) map[string]any {
+	values := make(map[string]any, len(image))
+	for name, attribute := range image {
+		values[name] = unmarshalStreamAttribute(attribute)
+	}
+	return values
+}
+
+func unmarshalStreamAttribute(raw any) any {
+	attribute, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+	for kind, value := range attribute {
+		switch kind {
+		case "S", "B", "BOOL", "SS", "BS":
+			return value
+		case "N":
+			if str, ok := value.(string); ok {
+				return json.Number(str)
+			}
+		case "NULL":
+			return nil
+		case "M":
+			if m, ok := value.(map[string]any); ok {
+				return UnmarshalStreamImage(m)
+			}
+		case "L":
+			if list, ok := value.([]any); ok {
+				values := make([]any, len(list))
+				for i, item := range list {
+					values[i] = unmarshalStreamAttribute(item)
+				}
+				return values
+			}
+		case "NS":
+			if list, ok := value.([]any); ok {
+				values := make([]any, 0, len(list))
+				for _, item := range list {
+					if str, ok := item.(string); ok {
+						values = append(values, json.Number(str))
+					}
+				}
+				return values
+			}
+		}
+	}
+	return nil
+}
+
+// approximateCreationTime reads the record's creation time, sent as epoch
+// seconds
+func approximateCreationTime(stream map[string]any) string {
+	switch v := stream["ApproximateCreationDateTime"].(type) {
+	case float64:
+		return fmt.Sprintf("%d", int64(v))
+	case json.Number:
+		return v.String()
+	case string:
+		return v
+	}
+	return ""
+}
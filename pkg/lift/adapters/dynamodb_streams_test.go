@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSplitDynamoDBStreamRecords(t *testing.T) {
+	event := map[string]any{
+		"Records": []any{
+			map[string]any{
+				"eventID":        "e1",
+				"eventName":      "MODIFY",
+				"eventSource":    "aws:dynamodb",
+				"eventSourceARN": "arn:aws:dynamodb:us-east-1:123456789012:table/orders/stream/2024-06-01T00:00:00.000",
+				"dynamodb": map[string]any{
+					"ApproximateCreationDateTime": float64(1717408800),
+					"SequenceNumber":              "100",
+					"Keys": map[string]any{
+						"pk": map[string]any{"S": "TENANT#acme#ORDER#42"},
+					},
+					"NewImage": map[string]any{
+						"pk":     map[string]any{"S": "TENANT#acme#ORDER#42"},
+						"total":  map[string]any{"N": "1250"},
+						"paid":   map[string]any{"BOOL": true},
+						"note":   map[string]any{"NULL": true},
+						"tags":   map[string]any{"SS": []any{"rush", "gift"}},
+						"sizes":  map[string]any{"NS": []any{"1", "2.5"}},
+						"lines":  map[string]any{"L": []any{map[string]any{"M": map[string]any{"sku": map[string]any{"S": "A1"}}}}},
+						"secret": map[string]any{"B": "AAE="},
+					},
+					"OldImage": map[string]any{
+						"total": map[string]any{"N": "1000"},
+					},
+				},
+			},
+			map[string]any{
+				"eventID":        "e2",
+				"eventName":      "REMOVE",
+				"eventSource":    "aws:dynamodb",
+				"eventSourceARN": "arn:aws:dynamodb:us-east-1:123456789012:table/orders/stream/2024-06-01T00:00:00.000",
+				"dynamodb": map[string]any{
+					"SequenceNumber": "101",
+					"Keys":           map[string]any{"pk": map[string]any{"S": "TENANT#acme#ORDER#43"}},
+				},
+				"userIdentity": map[string]any{
+					"type":        "Service",
+					"principalId": "dynamodb.amazonaws.com",
+				},
+			},
+		},
+	}
+
+	adapter := NewDynamoDBStreamsAdapter()
+	if !adapter.CanHandle(event) {
+		t.Fatal("CanHandle() = false for a DynamoDB stream event")
+	}
+	if NewSQSAdapter().CanHandle(event) {
+		t.Error("SQS adapter claims a DynamoDB stream event")
+	}
+
+	batch, err := adapter.Adapt(event)
+	if err != nil {
+		t.Fatalf("Adapt() error = %v", err)
+	}
+	if batch.TriggerType != TriggerDynamoDBStream || batch.EventID != "e1" || batch.Timestamp != "1717408800" {
+		t.Errorf("batch = %+v", batch)
+	}
+
+	requests := SplitDynamoDBStreamRecords(batch)
+	if len(requests) != 2 {
+		t.Fatalf("SplitDynamoDBStreamRecords() returned %d requests, want 2", len(requests))
+	}
+
+	first := requests[0]
+	if first.Metadata["tableName"] != "orders" || first.Metadata["sequenceNumber"] != "100" || first.Metadata["eventName"] != "MODIFY" {
+		t.Errorf("first metadata = %v", first.Metadata)
+	}
+
+	var record DynamoDBStreamRecord
+	decoder := json.NewDecoder(bytes.NewReader(first.Body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil {
+		t.Fatalf("body is not a DynamoDBStreamRecord: %v", err)
+	}
+	if record.Keys["pk"] != "TENANT#acme#ORDER#42" || record.TableName != "orders" {
+		t.Errorf("record = %+v", record)
+	}
+	if record.NewImage["total"] != json.Number("1250") || record.OldImage["total"] != json.Number("1000") {
+		t.Errorf("total = %v -> %v, want numbers", record.OldImage["total"], record.NewImage["total"])
+	}
+	if record.NewImage["paid"] != true || record.NewImage["note"] != nil || record.NewImage["secret"] != "AAE=" {
+		t.Errorf("NewImage = %v", record.NewImage)
+	}
+	if tags, _ := record.NewImage["tags"].([]any); len(tags) != 2 {
+		t.Errorf("tags = %v, want a string set", record.NewImage["tags"])
+	}
+	if sizes, _ := record.NewImage["sizes"].([]any); len(sizes) != 2 || sizes[1] != json.Number("2.5") {
+		t.Errorf("sizes = %v, want a number set", record.NewImage["sizes"])
+	}
+	lines, _ := record.NewImage["lines"].([]any)
+	if line, _ := lines[0].(map[string]any); len(lines) != 1 || line["sku"] != "A1" {
+		t.Errorf("lines = %v, want a list of maps", record.NewImage["lines"])
+	}
+
+	if requests[1].Metadata["eventName"] != "REMOVE" || requests[1].EventID != "e2" {
+		t.Errorf("second request = %+v", requests[1])
+	}
+
+	if record.IsTTLExpiry() {
+		t.Error("IsTTLExpiry() = true for a MODIFY")
+	}
+	var removed DynamoDBStreamRecord
+	if err := json.Unmarshal(requests[1].Body, &removed); err != nil {
+		t.Fatalf("body is not a DynamoDBStreamRecord: %v", err)
+	}
+	if !removed.IsTTLExpiry() {
+		t.Errorf("IsTTLExpiry() = false for a removal by the TTL service, identity %+v", removed.UserIdentity)
+	}
+}
+
+func TestStreamTableName(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:dynamodb:us-east-1:123456789012:table/orders/stream/2024-06-01T00:00:00.000": "orders",
+		"arn:aws:dynamodb:us-east-1:123456789012:table/orders":                                "orders",
+		"arn:aws:sqs:us-east-1:123456789012:orders":                                           "",
+	}
+	for arn, want := range tests {
+		if got := StreamTableName(arn); got != want {
+			t.Errorf("StreamTableName(%q) = %q, want %q", arn, got, want)
+		}
+	}
+}
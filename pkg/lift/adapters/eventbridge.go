@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
@@ -69,6 +70,14 @@ func (a *EventBridgeAdapter) Adapt(rawEvent any) (*Request, error) {
 	// Extract resources (for scheduled events)
 	resources := extractSliceField(eventMap, "resources")
 
+	// The detail is also the body, so typed handlers decode it directly
+	var body []byte
+	if raw, ok := eventMap["detail"]; ok && raw != nil {
+		if encoded, err := json.Marshal(raw); err == nil {
+			body = encoded
+		}
+	}
+
 	// Determine the actual trigger type based on source
 	triggerType := TriggerEventBridge
 	switch source {
@@ -87,6 +96,7 @@ func (a *EventBridgeAdapter) Adapt(rawEvent any) (*Request, error) {
 		Source:      source,
 		DetailType:  detailType,
 		Detail:      detail,
+		Body:        body,
 		Records:     resources,
 	}, nil
 }
@@ -1,7 +1,10 @@
 package adapters
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 )
 
 // S3Adapter handles S3 events
@@ -115,3 +118,100 @@ func (a *S3Adapter) Adapt(rawEvent any) (*Request, error) {
 		DetailType:  eventName,
 	}, nil
 }
+
+// S3Object describes the object an S3 event notification record is about
+type S3Object struct {
+	EventName string `json:"eventName"` // e.g. ObjectCreated:Put
+	EventTime string `json:"eventTime"`
+	Region    string `json:"region,omitempty"`
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionID string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer,omitempty"`
+}
+
+// ParseS3Record reads the object of an S3 notification record. Object keys
+// arrive URL-encoded and are returned decoded.
+func ParseS3Record(record map[string]any) S3Object {
+	s3Data := extractMapField(record, "s3")
+	object := extractMapField(s3Data, "object")
+	return S3Object{
+		EventName: extractStringField(record, "eventName"),
+		EventTime: extractStringField(record, "eventTime"),
+		Region:    extractStringField(record, "awsRegion"),
+		Bucket:    extractStringField(extractMapField(s3Data, "bucket"), "name"),
+		Key:       decodeS3Key(extractStringField(object, "key")),
+		Size:      extractInt64Field(object, "size"),
+		ETag:      extractStringField(object, "eTag"),
+		VersionID: extractStringField(object, "versionId"),
+		Sequencer: extractStringField(object, "sequencer"),
+	}
+}
+
+// SplitS3Records returns one request per record of an S3 notification.
+// Each request's body is the record's S3Object as JSON, so typed handlers
+// can decode it directly, and its metadata holds the bucket and key.
+func SplitS3Records(req *Request) []*Request {
+	requests := make([]*Request, 0, len(req.Records))
+	for _, raw := range req.Records {
+		record, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		object := ParseS3Record(record)
+		body, err := json.Marshal(object)
+		if err != nil {
+			continue
+		}
+
+		requests = append(requests, &Request{
+			TriggerType: TriggerS3,
+			RawEvent:    record,
+			EventID:     extractStringField(extractMapField(record, "responseElements"), "x-amz-request-id"),
+			Timestamp:   object.EventTime,
+			Headers:     make(map[string]string),
+			QueryParams: make(map[string]string),
+			Body:        body,
+			Records:     []any{record},
+			Source:      "aws:s3",
+			DetailType:  object.EventName,
+			Metadata: map[string]any{
+				"bucket": object.Bucket,
+				"key":    object.Key,
+			},
+		})
+	}
+	return requests
+}
+
+// decodeS3Key decodes a notification's form-encoded object key, falling
+// back to the raw key if it isn't valid encoding
+func decodeS3Key(key string) string {
+	decoded, err := url.QueryUnescape(key)
+	if err != nil {
+		return key
+	}
+	return decoded
+}
+
+// extractInt64Field reads a JSON number field, decoded either as float64
+// or json.Number
+func extractInt64Field(data map[string]any, key string) int64 {
+	switch v := data[key].(type) {
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
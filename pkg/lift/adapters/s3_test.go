@@ -0,0 +1,83 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func s3Record(key string, size any) map[string]any {
+	return map[string]any{
+		"eventVersion": "2.1",
+		"eventSource":  "aws:s3",
+		"awsRegion":    "us-east-1",
+		"eventTime":    "2024-06-01T12:00:00.000Z",
+		"eventName":    "ObjectCreated:Put",
+		"s3": map[string]any{
+			"bucket": map[string]any{"name": "settlements"},
+			"object": map[string]any{
+				"key":       key,
+				"size":      size,
+				"eTag":      "d41d8cd98f00b204e9800998ecf8427e",
+				"versionId": "v1",
+				"sequencer": "0055AED6DCD90281E5",
+			},
+		},
+	}
+}
+
+func TestParseS3Record(t *testing.T) {
+	object := ParseS3Record(s3Record("reports/June+2024/file%281%29.csv", float64(1048576)))
+	want := S3Object{
+		EventName: "ObjectCreated:Put",
+		EventTime: "2024-06-01T12:00:00.000Z",
+		Region:    "us-east-1",
+		Bucket:    "settlements",
+		Key:       "reports/June 2024/file(1).csv",
+		Size:      1048576,
+		ETag:      "d41d8cd98f00b204e9800998ecf8427e",
+		VersionID: "v1",
+		Sequencer: "0055AED6DCD90281E5",
+	}
+	if object != want {
+		t.Errorf("ParseS3Record() = %+v, want %+v", object, want)
+	}
+
+	if size := ParseS3Record(s3Record("a", json.Number("42"))).Size; size != 42 {
+		t.Errorf("Size from json.Number = %d, want 42", size)
+	}
+	if key := ParseS3Record(s3Record("100%", float64(0))).Key; key != "100%" {
+		t.Errorf("Key with invalid encoding = %q, want it unchanged", key)
+	}
+}
+
+func TestSplitS3Records(t *testing.T) {
+	event := map[string]any{"Records": []any{s3Record("a.csv", float64(10)), s3Record("b.csv", float64(20))}}
+
+	batch, err := NewS3Adapter().Adapt(event)
+	if err != nil {
+		t.Fatalf("Adapt() error = %v", err)
+	}
+	requests := SplitS3Records(batch)
+	if len(requests) != 2 {
+		t.Fatalf("SplitS3Records() returned %d requests, want 2", len(requests))
+	}
+
+	second := requests[1]
+	if second.TriggerType != TriggerS3 || second.DetailType != "ObjectCreated:Put" {
+		t.Errorf("request trigger = %s, detail type = %q", second.TriggerType, second.DetailType)
+	}
+	if second.Metadata["bucket"] != "settlements" || second.Metadata["key"] != "b.csv" {
+		t.Errorf("metadata = %v", second.Metadata)
+	}
+	if len(second.Records) != 1 {
+		t.Errorf("request has %d records, want 1", len(second.Records))
+	}
+
+	var object S3Object
+	if err := json.Unmarshal(second.Body, &object); err != nil {
+		t.Fatalf("body is not an S3Object: %v", err)
+	}
+	if object.Key != "b.csv" || object.Size != 20 {
+		t.Errorf("body object = %+v", object)
+	}
+}
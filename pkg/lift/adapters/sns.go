@@ -0,0 +1,219 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SNSAdapter handles SNS events
+type SNSAdapter struct {
+	BaseAdapter
+}
+
+// NewSNSAdapter creates a new SNS adapter
+func NewSNSAdapter() *SNSAdapter {
+	return &SNSAdapter{
+		BaseAdapter: BaseAdapter{triggerType: TriggerSNS},
+	}
+}
+
+// CanHandle checks if this adapter can handle the given event
+func (a *SNSAdapter) CanHandle(event any) bool {
+	eventMap, ok := event.(map[string]any)
+	if !ok {
+		return false
+	}
+
+	recordsSlice, ok := eventMap["Records"].([]any)
+	if !ok || len(recordsSlice) == 0 {
+		return false
+	}
+
+	firstRecord, ok := recordsSlice[0].(map[string]any)
+	if !ok {
+		return false
+	}
+
+	// SNS records have EventSource "aws:sns" (capitalised, unlike SQS and S3)
+	return extractStringField(firstRecord, "EventSource") == "aws:sns"
+}
+
+// Validate checks if the event has the required SNS structure
+func (a *SNSAdapter) Validate(event any) error {
+	eventMap, ok := event.(map[string]any)
+	if !ok {
+		return fmt.Errorf("event must be a map[string]any")
+	}
+
+	recordsSlice, ok := eventMap["Records"].([]any)
+	if !ok {
+		return fmt.Errorf("missing required field: records")
+	}
+	if len(recordsSlice) == 0 {
+		return fmt.Errorf("records slice cannot be empty")
+	}
+
+	firstRecord, ok := recordsSlice[0].(map[string]any)
+	if !ok {
+		return fmt.Errorf("records must contain map objects")
+	}
+	for _, field := range []string{"EventSource", "Sns"} {
+		if _, exists := firstRecord[field]; !exists {
+			return fmt.Errorf("missing required field in record: %s", field)
+		}
+	}
+
+	return nil
+}
+
+// Adapt converts an SNS event to a normalized Request
+func (a *SNSAdapter) Adapt(rawEvent any) (*Request, error) {
+	if err := a.Validate(rawEvent); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	eventMap := rawEvent.(map[string]any)
+	records := extractSliceField(eventMap, "Records")
+
+	var message SNSMessage
+	if firstRecord, ok := records[0].(map[string]any); ok {
+		message = ParseSNSRecord(firstRecord)
+	}
+
+	return &Request{
+		TriggerType: TriggerSNS,
+		RawEvent:    rawEvent,
+		EventID:     message.MessageID,
+		Timestamp:   message.Timestamp,
+		Records:     records,
+		Source:      "aws:sns",
+		DetailType:  message.Subject,
+	}, nil
+}
+
+// SNSMessage is a message published to an SNS topic
+type SNSMessage struct {
+	MessageID string `json:"messageId"`
+	TopicARN  string `json:"topicArn"`
+	Subject   string `json:"subject,omitempty"`
+	Message   string `json:"message"`
+	Timestamp string `json:"timestamp"`
+	// Attributes holds the String and Number message attributes; Binary
+	// attributes are skipped
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// TopicName returns the topic's name, the last segment of its ARN
+func (m SNSMessage) TopicName() string {
+	return m.TopicARN[strings.LastIndex(m.TopicARN, ":")+1:]
+}
+
+// ParseSNSRecord reads the message of an SNS event record
+func ParseSNSRecord(record map[string]any) SNSMessage {
+	return parseSNSEnvelope(extractMapField(record, "Sns"))
+}
+
+// UnwrapSNSEnvelope reads the SNS notification an SQS message body holds
+// when the queue's subscription has raw message delivery off. It reports
+// false for bodies that aren't SNS notifications, such as messages
+// delivered raw or sent to the queue directly.
+func UnwrapSNSEnvelope(body string) (SNSMessage, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(body), "{") {
+		return SNSMessage{}, false
+	}
+	var envelope map[string]any
+	if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+		return SNSMessage{}, false
+	}
+	if extractStringField(envelope, "Type") != "Notification" || extractStringField(envelope, "TopicArn") == "" {
+		return SNSMessage{}, false
+	}
+	if _, ok := envelope["Message"].(string); !ok {
+		return SNSMessage{}, false
+	}
+	return parseSNSEnvelope(envelope), true
+}
+
+// SplitSNSMessages returns one request per SNS message in an event: the
+// records of an SNS event, or the records of an SQS event whose bodies are
+// SNS notifications (SNS to SQS with raw message delivery off). Each
+// request's body is the published message and its headers are the message
+// attributes. Requests for SQS records keep the SQS trigger type and
+// message ID, so failures are reported per message in the batch response;
+// SQS records that aren't SNS notifications are skipped.
+func SplitSNSMessages(req *Request) []*Request {
+	requests := make([]*Request, 0, len(req.Records))
+	for _, raw := range req.Records {
+		record, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		var message SNSMessage
+		triggerType, eventID, source := TriggerSNS, "", "aws:sns"
+		metadata := make(map[string]any)
+		switch req.TriggerType {
+		case TriggerSNS:
+			message = ParseSNSRecord(record)
+			eventID = message.MessageID
+			metadata["subscriptionArn"] = extractStringField(record, "EventSubscriptionArn")
+		case TriggerSQS:
+			message, ok = UnwrapSNSEnvelope(extractStringField(record, "body"))
+			if !ok {
+				continue
+			}
+			triggerType, eventID, source = TriggerSQS, extractStringField(record, "messageId"), "aws:sqs"
+			metadata["messageId"] = eventID
+			metadata["receiptHandle"] = extractStringField(record, "receiptHandle")
+			metadata["eventSourceARN"] = extractStringField(record, "eventSourceARN")
+		default:
+			continue
+		}
+		metadata["topicArn"] = message.TopicARN
+		metadata["snsMessageId"] = message.MessageID
+
+		headers := make(map[string]string, len(message.Attributes))
+		for name, value := range message.Attributes {
+			headers[name] = value
+		}
+
+		requests = append(requests, &Request{
+			TriggerType: triggerType,
+			RawEvent:    record,
+			EventID:     eventID,
+			Timestamp:   message.Timestamp,
+			Headers:     headers,
+			QueryParams: make(map[string]string),
+			Body:        []byte(message.Message),
+			Records:     []any{record},
+			Source:      source,
+			DetailType:  message.Subject,
+			Metadata:    metadata,
+		})
+	}
+	return requests
+}
+
+// parseSNSEnvelope reads an SNS notification, which has the same shape in
+// SNS event records and in SQS message bodies
+func parseSNSEnvelope(envelope map[string]any) SNSMessage {
+	message := SNSMessage{
+		MessageID: extractStringField(envelope, "MessageId"),
+		TopicARN:  extractStringField(envelope, "TopicArn"),
+		Subject:   extractStringField(envelope, "Subject"),
+		Message:   extractStringField(envelope, "Message"),
+		Timestamp: extractStringField(envelope, "Timestamp"),
+	}
+	if attributes := extractMapField(envelope, "MessageAttributes"); len(attributes) > 0 {
+		message.Attributes = make(map[string]string, len(attributes))
+		for name, value := range attributes {
+			attribute, ok := value.(map[string]any)
+			if !ok || strings.HasPrefix(extractStringField(attribute, "Type"), "Binary") {
+				continue
+			}
+			message.Attributes[name] = extractStringField(attribute, "Value")
+		}
+	}
+	return message
+}
@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func snsEnvelope(messageID, topic, message string) map[string]any {
+	return map[string]any{
+		"Type":      "Notification",
+		"MessageId": messageID,
+		"TopicArn":  "arn:aws:sns:us-east-1:123456789012:" + topic,
+		"Subject":   "payment",
+		"Message":   message,
+		"Timestamp": "2024-06-01T12:00:00.000Z",
+		"MessageAttributes": map[string]any{
+			"tenant_id": map[string]any{"Type": "String", "Value": "tenant-1"},
+			"attempt":   map[string]any{"Type": "Number", "Value": "2"},
+			"signature": map[string]any{"Type": "Binary", "Value": "AQID"},
+		},
+	}
+}
+
+func TestSNSAdapter(t *testing.T) {
+	event := map[string]any{
+		"Records": []any{
+			map[string]any{
+				"EventSource":          "aws:sns",
+				"EventSubscriptionArn": "arn:aws:sns:us-east-1:123456789012:payment-events:sub",
+				"Sns":                  snsEnvelope("msg-1", "payment-events", `{"id":"pay_1"}`),
+			},
+		},
+	}
+
+	registry := NewAdapterRegistry()
+	req, err := registry.DetectAndAdapt(event)
+	if err != nil {
+		t.Fatalf("DetectAndAdapt failed: %v", err)
+	}
+	if req.TriggerType != TriggerSNS || req.EventID != "msg-1" {
+		t.Errorf("got trigger %s event %s, want sns msg-1", req.TriggerType, req.EventID)
+	}
+
+	requests := SplitSNSMessages(req)
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	got := requests[0]
+	if string(got.Body) != `{"id":"pay_1"}` {
+		t.Errorf("body = %s", got.Body)
+	}
+	if got.Headers["tenant_id"] != "tenant-1" || got.Headers["attempt"] != "2" {
+		t.Errorf("headers = %v", got.Headers)
+	}
+	if _, ok := got.Headers["signature"]; ok {
+		t.Errorf("binary attributes should be skipped")
+	}
+	if got.Metadata["topicArn"] != "arn:aws:sns:us-east-1:123456789012:payment-events" {
+		t.Errorf("topicArn = %v", got.Metadata["topicArn"])
+	}
+}
+
+func TestSplitSNSMessagesUnwrapsSQSBodies(t *testing.T) {
+	envelope, err := json.Marshal(snsEnvelope("sns-1", "payment-events", "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &Request{
+		TriggerType: TriggerSQS,
+		Records: []any{
+			map[string]any{"messageId": "sqs-1", "eventSource": "aws:sqs", "body": string(envelope)},
+			map[string]any{"messageId": "sqs-2", "eventSource": "aws:sqs", "body": "raw delivery"},
+		},
+	}
+
+	requests := SplitSNSMessages(req)
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	got := requests[0]
+	if got.TriggerType != TriggerSQS || got.EventID != "sqs-1" {
+		t.Errorf("got trigger %s event %s, want sqs sqs-1", got.TriggerType, got.EventID)
+	}
+	if string(got.Body) != "hello" || got.Metadata["snsMessageId"] != "sns-1" {
+		t.Errorf("got body %q metadata %v", got.Body, got.Metadata)
+	}
+}
+
+func TestUnwrapSNSEnvelope(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		ok   bool
+	}{
+		{"notification", `{"Type":"Notification","TopicArn":"arn:aws:sns:us-east-1:1:t","Message":"m"}`, true},
+		{"empty message", `{"Type":"Notification","TopicArn":"arn:aws:sns:us-east-1:1:t","Message":""}`, true},
+		{"subscription confirmation", `{"Type":"SubscriptionConfirmation","TopicArn":"arn:aws:sns:us-east-1:1:t","Message":"m"}`, false},
+		{"no message", `{"Type":"Notification","TopicArn":"arn:aws:sns:us-east-1:1:t"}`, false},
+		{"application JSON", `{"Type":"Notification"}`, false},
+		{"not JSON", `Notification`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, ok := UnwrapSNSEnvelope(tt.body)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && message.TopicName() != "t" {
+				t.Errorf("topic name = %q", message.TopicName())
+			}
+		})
+	}
+}
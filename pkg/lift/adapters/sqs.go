@@ -100,7 +100,7 @@ func (a *SQSAdapter) Adapt(rawEvent any) (*Request, error) {
 	if len(records) > 0 {
 		if firstRecord, ok := records[0].(map[string]any); ok {
 			eventID = extractStringField(firstRecord, "messageId")
-			timestamp = extractStringField(firstRecord, "attributes.SentTimestamp")
+			timestamp = extractStringField(extractMapField(firstRecord, "attributes"), "SentTimestamp")
 		}
 	}
 
@@ -113,3 +113,65 @@ func (a *SQSAdapter) Adapt(rawEvent any) (*Request, error) {
 		Source:      "aws:sqs",
 	}, nil
 }
+
+// SQSBatchResponse is the partial batch response read by Lambda when the
+// event source mapping has ReportBatchItemFailures enabled. Only the listed
+// messages are retried; an empty list deletes the whole batch.
+type SQSBatchResponse struct {
+	BatchItemFailures []SQSBatchItemFailure `json:"batchItemFailures"`
+}
+
+// SQSBatchItemFailure identifies a message to retry by its message ID
+type SQSBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// SplitSQSRecords returns one request per record of an SQS batch request.
+// Each request's body is the message body, its headers are the message's
+// string attributes, and its metadata holds the message ID, receipt handle,
+// queue ARN and, for FIFO queues, the message group ID.
+func SplitSQSRecords(req *Request) []*Request {
+	requests := make([]*Request, 0, len(req.Records))
+	for _, raw := range req.Records {
+		record, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		attributes := extractMapField(record, "attributes")
+
+		headers := make(map[string]string)
+		for name, value := range extractMapField(record, "messageAttributes") {
+			if attribute, ok := value.(map[string]any); ok {
+				if str := extractStringField(attribute, "stringValue"); str != "" {
+					headers[name] = str
+				}
+			}
+		}
+
+		metadata := map[string]any{
+			"messageId":      extractStringField(record, "messageId"),
+			"receiptHandle":  extractStringField(record, "receiptHandle"),
+			"eventSourceARN": extractStringField(record, "eventSourceARN"),
+		}
+		if groupID := extractStringField(attributes, "MessageGroupId"); groupID != "" {
+			metadata["messageGroupId"] = groupID
+		}
+		if count := extractStringField(attributes, "ApproximateReceiveCount"); count != "" {
+			metadata["approximateReceiveCount"] = count
+		}
+
+		requests = append(requests, &Request{
+			TriggerType: TriggerSQS,
+			RawEvent:    record,
+			EventID:     extractStringField(record, "messageId"),
+			Timestamp:   extractStringField(attributes, "SentTimestamp"),
+			Headers:     headers,
+			QueryParams: make(map[string]string),
+			Body:        []byte(extractStringField(record, "body")),
+			Records:     []any{record},
+			Source:      "aws:sqs",
+			Metadata:    metadata,
+		})
+	}
+	return requests
+}
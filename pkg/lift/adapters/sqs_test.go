@@ -0,0 +1,78 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSplitSQSRecords(t *testing.T) {
+	event := map[string]any{
+		"Records": []any{
+			map[string]any{
+				"messageId":      "m1",
+				"receiptHandle":  "rh1",
+				"body":           `{"amount":100}`,
+				"eventSource":    "aws:sqs",
+				"eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:payments.fifo",
+				"attributes": map[string]any{
+					"SentTimestamp":           "1717408800000",
+					"MessageGroupId":          "merchant-1",
+					"ApproximateReceiveCount": "2",
+				},
+				"messageAttributes": map[string]any{
+					"tenant": map[string]any{"stringValue": "t1", "dataType": "String"},
+					"blob":   map[string]any{"binaryValue": "AAE=", "dataType": "Binary"},
+				},
+			},
+			map[string]any{
+				"messageId":     "m2",
+				"receiptHandle": "rh2",
+				"body":          "plain",
+				"eventSource":   "aws:sqs",
+			},
+		},
+	}
+
+	batch, err := NewSQSAdapter().Adapt(event)
+	if err != nil {
+		t.Fatalf("Adapt() error = %v", err)
+	}
+	if batch.Timestamp != "1717408800000" {
+		t.Errorf("batch Timestamp = %q, want SentTimestamp of the first record", batch.Timestamp)
+	}
+
+	requests := SplitSQSRecords(batch)
+	if len(requests) != 2 {
+		t.Fatalf("SplitSQSRecords() returned %d requests, want 2", len(requests))
+	}
+
+	first := requests[0]
+	if first.EventID != "m1" || string(first.Body) != `{"amount":100}` {
+		t.Errorf("first request = %q %q", first.EventID, first.Body)
+	}
+	if first.Headers["tenant"] != "t1" {
+		t.Errorf("tenant header = %q, want t1", first.Headers["tenant"])
+	}
+	if _, ok := first.Headers["blob"]; ok {
+		t.Error("binary attributes should not become headers")
+	}
+	if first.Metadata["messageGroupId"] != "merchant-1" || first.Metadata["approximateReceiveCount"] != "2" {
+		t.Errorf("first metadata = %v", first.Metadata)
+	}
+	if len(first.Records) != 1 || first.TriggerType != TriggerSQS {
+		t.Errorf("first request should be a single-record SQS request")
+	}
+	if _, ok := requests[1].Metadata["messageGroupId"]; ok {
+		t.Error("standard queue records have no message group")
+	}
+}
+
+func TestSQSBatchResponseJSON(t *testing.T) {
+	data, err := json.Marshal(SQSBatchResponse{BatchItemFailures: []SQSBatchItemFailure{{ItemIdentifier: "m1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"batchItemFailures":[{"itemIdentifier":"m1"}]}` {
+		t.Errorf("json = %s", data)
+	}
+}
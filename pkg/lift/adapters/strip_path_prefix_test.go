@@ -0,0 +1,48 @@
+package adapters
+
+import "testing"
+
+func TestStripPathPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         string
+		stripped     bool
+	}{
+		{"/prod/users", "prod", "/users", true},
+		{"/prod/users", "/prod/", "/users", true},
+		{"/prod", "prod", "/", true},
+		{"/products", "prod", "/products", false},
+		{"/users", "prod", "/users", false},
+		{"/users", "", "/users", false},
+		{"/v1/payments/123", "/v1/payments", "/123", true},
+	}
+
+	for _, tt := range tests {
+		got, stripped := StripPathPrefix(tt.path, tt.prefix)
+		if got != tt.want || stripped != tt.stripped {
+			t.Errorf("StripPathPrefix(%q, %q) = %q, %v; want %q, %v", tt.path, tt.prefix, got, stripped, tt.want, tt.stripped)
+		}
+	}
+}
+
+func TestAPIGatewayV2StageDoesNotStripPartialSegment(t *testing.T) {
+	adapter := NewAPIGatewayV2Adapter()
+	req, err := adapter.Adapt(map[string]any{
+		"version":  "2.0",
+		"routeKey": "GET /products",
+		"rawPath":  "/products",
+		"requestContext": map[string]any{
+			"stage": "prod",
+			"http":  map[string]any{"method": "GET", "path": "/products"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Adapt() error = %v", err)
+	}
+	if req.Path != "/products" {
+		t.Errorf("Path = %q, want /products", req.Path)
+	}
+	if req.Stage != "prod" {
+		t.Errorf("Stage = %q, want prod", req.Stage)
+	}
+}
@@ -3,9 +3,11 @@ package lift
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
+	"runtime/debug"
 	"sync"
 	"time"
 
@@ -56,6 +58,7 @@ type App struct {
 	router      *Router      // HTTP router
 	eventRouter *EventRouter // Non-HTTP event router
 	middleware  []Middleware
+	preRouting  []Middleware
 	config      *Config
 
 	// Event handling
@@ -77,6 +80,126 @@ type App struct {
 
 	// Response buffering
 	hasInterceptingMiddleware bool
+
+	// Error reporting
+	errorObservers []ErrorObserver
+	errorHandlers  []ErrorHandler
+
+	// Response streaming (function URLs with RESPONSE_STREAM invoke mode)
+	responseStreaming bool
+
+	// Signed URL issuing
+	urlSigner  URLSigner
+	urlAuditor SignedURLAuditor
+
+	// Content negotiation for ctx.Render
+	renderers        []Renderer
+	binaryMediaTypes []string
+
+	// Registered routes and their metadata
+	routes []*Route
+
+	// Route registration errors, returned by Start
+	routeErrs []error
+
+	// Mounted sub-applications
+	mounts []*mount
+
+	// Stage and base path prefixes removed before routing
+	basePathStripping *basePathStripping
+
+	// SNS topic handlers, in registration order
+	snsTopics []*snsTopic
+
+	// Deployment stage
+	environment Environment
+
+	// Limits for ctx.Form and uploads
+	multipartLimits MultipartLimits
+
+	// How request bodies are decoded
+	decodeMode       DecodeMode
+	stageDecodeModes []stageDecodeMode
+	maxJSONDepth     int
+
+	// Problem+json error responses, when enabled
+	problems *ProblemOptions
+}
+
+// ErrorObserver is notified of every error that reaches the application error handler
+type ErrorObserver func(ctx *Context, err error)
+
+// WithErrorObserver registers an observer for handler errors, typically used to
+// forward server errors to an external error tracker
+func WithErrorObserver(observer ErrorObserver) AppOption {
+	return func(app *App) {
+		app.errorObservers = append(app.errorObservers, observer)
+	}
+}
+
+// ErrorHandler maps an error that reached the application, such as a
+// domain error, to the error to respond with. It can also write the
+// response itself and return nil.
+type ErrorHandler func(ctx *Context, err error) error
+
+// OnError registers an error handler, so domain errors are mapped to
+// responses in one place instead of in every handler:
+//
+//	app.OnError(func(ctx *lift.Context, err error) error {
+//		var apiErr APIError
+//		if errors.As(err, &apiErr) && apiErr.Type == "conflict" {
+//			return lift.NewLiftError("CONFLICT", apiErr.Message, 409).WithCause(err)
+//		}
+//		return err
+//	})
+//
+// Handlers run in registration order on errors from handlers and
+// middleware, each receiving the previous one's result, until one returns
+// nil. Panics that escape the middleware reach them as *PanicError; with
+// the Recovery middleware installed they arrive as its PANIC_RECOVERED
+// *LiftError instead. A mounted application's handlers run first for its
+// routes. Error observers see the original error.
+func (a *App) OnError(handler ErrorHandler) *App {
+	a.errorHandlers = append(a.errorHandlers, handler)
+	return a
+}
+
+// mapError runs the error handlers; nil means the response is written
+func (a *App) mapError(ctx *Context, err error) error {
+	for _, handler := range a.errorHandlers {
+		if err = handler(ctx, err); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// resolveError notifies the error observers and runs the error handlers,
+// rendering a problem response when they're enabled. It returns the error
+// left for the caller to render, or nil once the response is written.
+func (a *App) resolveError(ctx *Context, err error) error {
+	a.notifyErrorObservers(ctx, err)
+
+	if err = a.mapError(ctx, err); err == nil {
+		return nil
+	}
+
+	if a.problems != nil {
+		a.problems.writeProblem(ctx, err, nil)
+		return nil
+	}
+	return err
+}
+
+// route runs a request through the router, returning panics as
+// *PanicError so they reach the error handlers
+func (a *App) route(ctx *Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r, debug.Stack())
+		}
+	}()
+	return a.router.Handle(ctx)
 }
 
 // New creates a new Lift application
@@ -88,6 +211,7 @@ func New(options ...AppOption) *App {
 		config:          DefaultConfig(),
 		adapterRegistry: adapters.NewAdapterRegistry(),
 		features:        make(map[string]bool),
+		environment:     EnvironmentFromEnv(),
 		started:         false,
 	}
 
@@ -111,33 +235,45 @@ func (a *App) Use(middleware Middleware) *App {
 	return a
 }
 
+// Pre adds middleware that runs before route matching, so it can change
+// the method, path or query the request is routed by (e.g. rewriting legacy
+// paths). Pre middleware can't use route metadata or path parameters.
+func (a *App) Pre(middleware Middleware) *App {
+	a.preRouting = append(a.preRouting, middleware)
+	return a
+}
+
 // GET registers a GET route
-func (a *App) GET(path string, handler any) error {
+func (a *App) GET(path string, handler any) *Route {
 	return a.Handle("GET", path, handler)
 }
 
 // POST registers a POST route
-func (a *App) POST(path string, handler any) error {
+func (a *App) POST(path string, handler any) *Route {
 	return a.Handle("POST", path, handler)
 }
 
 // PUT registers a PUT route
-func (a *App) PUT(path string, handler any) error {
+func (a *App) PUT(path string, handler any) *Route {
 	return a.Handle("PUT", path, handler)
 }
 
 // DELETE registers a DELETE route
-func (a *App) DELETE(path string, handler any) error {
+func (a *App) DELETE(path string, handler any) *Route {
 	return a.Handle("DELETE", path, handler)
 }
 
 // PATCH registers a PATCH route
-func (a *App) PATCH(path string, handler any) error {
+func (a *App) PATCH(path string, handler any) *Route {
 	return a.Handle("PATCH", path, handler)
 }
 
-// Handle registers a route with the specified method and path
-func (a *App) Handle(method, path string, handler any) error {
+// Handle registers a route with the specified method and path. The returned
+// Route accepts metadata; registration errors are reported by its Err method
+// and returned by Start, so an app with a bad route never serves requests.
+func (a *App) Handle(method, path string, handler any) *Route {
+	route := newRoute(method, path)
+
 	// Check if this is an event trigger type
 	triggerType := parseTriggerType(method)
 	if triggerType != TriggerUnknown && triggerType != TriggerAPIGateway {
@@ -146,39 +282,46 @@ func (a *App) Handle(method, path string, handler any) error {
 		switch v := handler.(type) {
 		case EventHandler:
 			eventHandler = v
+		case Handler:
+			eventHandler = EventHandlerFunc(v.Handle)
 		case func(*Context) error:
 			eventHandler = EventHandlerFunc(v)
 		default:
 			// Convert to event handler
 			h, err := convertHandlerUsingReflection(handler)
 			if err != nil {
-				return fmt.Errorf("unsupported handler type: %w", err)
+				a.routeError(route, fmt.Errorf("unsupported handler type: %w", err))
+				return route
 			}
 			eventHandler = EventHandlerFunc(h.Handle)
 		}
 
 		a.eventRouter.AddEventRoute(triggerType, path, eventHandler)
-		return nil
+		a.addRoute(route)
+		return route
 	}
 
 	// This is an HTTP route
-	var h Handler
-	switch v := handler.(type) {
-	case Handler:
-		h = v
-	case func(*Context) error:
-		h = HandlerFunc(v)
-	default:
-		// Use reflection to support additional handler types
-		reflectedHandler, err := convertHandlerUsingReflection(handler)
-		if err != nil {
-			return fmt.Errorf("unsupported handler type: %w", err)
-		}
-		h = reflectedHandler
+	h, err := asHandler(handler)
+	if err != nil {
+		a.routeError(route, fmt.Errorf("unsupported handler type: %w", err))
+		return route
 	}
 
+	route.signature = signatureOf(h)
 	a.router.AddRoute(method, path, h)
-	return nil
+	a.router.setRouteInfo(route)
+	a.addRoute(route)
+	return route
+}
+
+// routeError records a route registration error on the route and the app
+func (a *App) routeError(route *Route, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	route.err = err
+	a.routeErrs = append(a.routeErrs, fmt.Errorf("%s %s: %w", route.Method, route.Path, err))
 }
 
 // WithConfig sets the application configuration
@@ -187,6 +330,11 @@ func (a *App) WithConfig(config *Config) *App {
 	return a
 }
 
+// Config returns a copy of the application configuration
+func (a *App) Config() Config {
+	return *a.config
+}
+
 // WithLogger sets the application logger
 func (a *App) WithLogger(logger Logger) *App {
 	a.logger = logger
@@ -205,54 +353,8 @@ func (a *App) WithDatabase(db any) *App {
 	return a
 }
 
-// Group creates a new route group with the specified prefix
-func (a *App) Group(prefix string) *RouteGroup {
-	return &RouteGroup{
-		app:    a,
-		prefix: prefix,
-	}
-}
-
-// RouteGroup represents a group of routes with a common prefix
-type RouteGroup struct {
-	app    *App
-	prefix string
-}
-
-// GET registers a GET route in this group
-func (rg *RouteGroup) GET(path string, handler any) error {
-	return rg.app.GET(rg.prefix+path, handler)
-}
-
-// POST registers a POST route in this group
-func (rg *RouteGroup) POST(path string, handler any) error {
-	return rg.app.POST(rg.prefix+path, handler)
-}
-
-// PUT registers a PUT route in this group
-func (rg *RouteGroup) PUT(path string, handler any) error {
-	return rg.app.PUT(rg.prefix+path, handler)
-}
-
-// DELETE registers a DELETE route in this group
-func (rg *RouteGroup) DELETE(path string, handler any) error {
-	return rg.app.DELETE(rg.prefix+path, handler)
-}
-
-// PATCH registers a PATCH route in this group
-func (rg *RouteGroup) PATCH(path string, handler any) error {
-	return rg.app.PATCH(rg.prefix+path, handler)
-}
-
-// Group creates a sub-group with an additional prefix
-func (rg *RouteGroup) Group(prefix string) *RouteGroup {
-	return &RouteGroup{
-		app:    rg.app,
-		prefix: rg.prefix + prefix,
-	}
-}
-
-// Start prepares the application for handling requests
+// Start prepares the application for handling requests. It fails if any
+// route failed to register.
 func (a *App) Start() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -261,8 +363,13 @@ func (a *App) Start() error {
 		return nil
 	}
 
+	if len(a.routeErrs) > 0 {
+		return fmt.Errorf("route registration failed: %w", errors.Join(a.routeErrs...))
+	}
+
 	// Apply global middleware to router
 	a.router.SetMiddleware(a.middleware)
+	a.router.SetPreRoutingMiddleware(a.preRouting)
 
 	a.started = true
 	return nil
@@ -292,13 +399,36 @@ func (a *App) HandleRequest(ctx context.Context, event any) (any, error) {
 		return nil, err
 	}
 
+	// Remove deployment prefixes from HTTP paths before routing
+	var basePath string
+	if a.basePathStripping != nil && (req.TriggerType == adapters.TriggerAPIGateway || req.TriggerType == adapters.TriggerAPIGatewayV2) {
+		basePath = a.basePathStripping.strip(req)
+	}
+
 	// Create enhanced context
 	liftCtx := NewContext(ctx, req)
+	// Every trigger starts with the Lambda request ID; the RequestID
+	// middleware replaces it with one sent by the caller
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		liftCtx.SetRequestID(requestID)
+	}
+	if basePath != "" {
+		liftCtx.Set("base_path", basePath)
+	}
 
 	// Enable response buffering if any middleware needs it
 	if a.hasInterceptingMiddleware {
 		liftCtx.EnableResponseBuffering()
 	}
+	liftCtx.streamingEnabled = a.responseStreaming
+	liftCtx.SetURLSigner(a.urlSigner, a.urlAuditor)
+	liftCtx.SetRenderers(a.renderers, a.binaryMediaTypes)
+	liftCtx.SetEnvironment(a.environment)
+	liftCtx.SetMultipartLimits(a.multipartLimits)
+	liftCtx.SetDecodeMode(a.decodeModeIn(a.environment))
+	liftCtx.SetMaxJSONDepth(a.maxJSONDepth)
+	liftCtx.SetProblemOptions(a.problems)
+	liftCtx.SetErrorObserver(a.notifyErrorObservers)
 
 	// Set dependencies if available
 	if a.logger != nil {
@@ -346,13 +476,37 @@ func (a *App) HandleRequest(ctx context.Context, event any) (any, error) {
 		}
 	} else {
 		// HTTP event, use regular router
-		if err := a.router.Handle(liftCtx); err != nil {
+		if err := a.route(liftCtx); err != nil {
 			routeErr = err
 		}
 	}
 
-	// Handle any routing errors
+	// SQS batches answer with the messages to retry
+	if req.TriggerType == adapters.TriggerSQS {
+		if routeErr != nil {
+			a.notifyErrorObservers(liftCtx, routeErr)
+		}
+		return sqsBatchResponse(liftCtx, routeErr), nil
+	}
+	if req.TriggerType == adapters.TriggerDynamoDBStream {
+		if routeErr != nil {
+			a.notifyErrorObservers(liftCtx, routeErr)
+		}
+		return streamBatchResponse(liftCtx, routeErr), nil
+	}
+	// Cognito triggers answer with the event and the handler's changes to
+	// it; an error rejects the user's request with its message
+	if req.TriggerType == adapters.TriggerCognito {
+		if routeErr != nil {
+			a.notifyErrorObservers(liftCtx, routeErr)
+			return nil, routeErr
+		}
+		return req.RawEvent, nil
+	}
+
+	// Handle any routing errors; a stream the handler started won't be read
 	if routeErr != nil {
+		liftCtx.Response.closeStream()
 		return a.handleError(liftCtx, routeErr)
 	}
 
@@ -361,6 +515,11 @@ func (a *App) HandleRequest(ctx context.Context, event any) (any, error) {
 		return nil, err
 	}
 
+	// Streamed bodies are handed to the runtime as a reader
+	if liftCtx.Response.IsStreaming() {
+		return liftCtx.Response.StreamingResponse(), nil
+	}
+
 	// Return the response
 	return liftCtx.Response, nil
 }
@@ -379,6 +538,10 @@ func (a *App) parseEvent(event any) (*Request, error) {
 
 // handleError processes errors and returns appropriate responses
 func (a *App) handleError(ctx *Context, err error) (any, error) {
+	if err = a.resolveError(ctx, err); err == nil {
+		return ctx.Response, nil
+	}
+
 	// Handle Lift errors properly by setting appropriate status codes
 	if liftErr, ok := err.(*LiftError); ok {
 		resp := map[string]any{
@@ -403,6 +566,13 @@ func (a *App) handleError(ctx *Context, err error) (any, error) {
 	return ctx.Response, nil
 }
 
+// notifyErrorObservers passes an error to every registered observer
+func (a *App) notifyErrorObservers(ctx *Context, err error) {
+	for _, observer := range a.errorObservers {
+		observer(ctx, err)
+	}
+}
+
 // HandleTestRequest processes a test request directly through the router
 // This is used by the testing framework to bypass event parsing
 func (a *App) HandleTestRequest(ctx *Context) error {
@@ -411,8 +581,28 @@ func (a *App) HandleTestRequest(ctx *Context) error {
 		return err
 	}
 
+	if ctx.environment.Stage == 0 {
+		ctx.SetEnvironment(a.environment)
+	}
+	if ctx.decodeMode == DecodeDefault {
+		ctx.SetDecodeMode(a.decodeModeIn(ctx.Environment()))
+	}
+	if ctx.maxJSONDepth == 0 {
+		ctx.SetMaxJSONDepth(a.maxJSONDepth)
+	}
+	if ctx.problems == nil {
+		ctx.SetProblemOptions(a.problems)
+	}
+	if ctx.errorObserver == nil {
+		ctx.SetErrorObserver(a.notifyErrorObservers)
+	}
+
 	// Use the router directly to handle the request
-	if err := a.router.Handle(ctx); err != nil {
+	if err := a.route(ctx); err != nil {
+		if err = a.resolveError(ctx, err); err == nil {
+			return nil
+		}
+
 		// Handle Lift errors properly by setting appropriate status codes
 		if liftErr, ok := err.(*LiftError); ok {
 			ctx.Status(liftErr.StatusCode).JSON(map[string]any{
@@ -468,6 +658,46 @@ func (a *App) EventBridge(pattern string, handler any) error {
 	return nil
 }
 
+// EventBridgeMatch registers a handler for EventBridge events matching a
+// detail type and source, either of which may be empty to match any value
+// or use "*" wildcards. Unlike EventBridge, the handler runs behind the
+// app's middleware, and the event detail is the request body, so typed
+// handlers decode it directly:
+//
+//	app.EventBridgeMatch("Scheduled Event", "aws.events", runNightlyReconciliation)
+//	app.EventBridgeMatch("Order Placed", "com.acme.orders", lift.SimpleHandler(onOrderPlaced))
+//
+// Routes are tried in registration order, so register specific matches
+// before broad ones.
+func (a *App) EventBridgeMatch(detailType, source string, handler any) error {
+	h, err := asHandler(handler)
+	if err != nil {
+		return fmt.Errorf("invalid EventBridge handler: %w", err)
+	}
+
+	a.eventRouter.addRoute(&EventRoute{
+		TriggerType: TriggerEventBridge,
+		Pattern:     detailType + "|" + source,
+		Handler: EventHandlerFunc(func(ctx *Context) error {
+			return a.withMiddleware(h).Handle(ctx)
+		}),
+		Match: func(ctx *Context) bool {
+			return (detailType == "" || a.eventRouter.matchWildcardPattern(ctx.Request.DetailType, detailType)) &&
+				(source == "" || a.eventRouter.matchWildcardPattern(ctx.Request.Source, source))
+		},
+	})
+	return nil
+}
+
+// withMiddleware wraps an event handler in the app's middleware. The chain
+// is built per event so it includes middleware registered after the route.
+func (a *App) withMiddleware(h Handler) Handler {
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		h = a.middleware[i](h)
+	}
+	return h
+}
+
 // convertEventHandler converts various handler types to EventHandler
 func (a *App) convertEventHandler(handler any) (EventHandler, error) {
 	// Check if it's already an EventHandler
@@ -479,6 +709,10 @@ func (a *App) convertEventHandler(handler any) (EventHandler, error) {
 	if ehf, ok := handler.(func(*Context) error); ok {
 		return EventHandlerFunc(ehf), nil
 	}
+
+	if h, ok := handler.(Handler); ok {
+		return EventHandlerFunc(h.Handle), nil
+	}
 	
 	// Try to convert as HTTP handler and wrap it
 	httpHandler, err := convertHandlerUsingReflection(handler)
@@ -677,8 +911,12 @@ func parseTriggerType(s string) TriggerType {
 		return TriggerSQS
 	case "S3":
 		return TriggerS3
+	case "SNS":
+		return TriggerSNS
 	case "EventBridge":
 		return TriggerEventBridge
+	case "Cognito":
+		return TriggerCognito
 	case "CONNECT", "DISCONNECT", "MESSAGE":
 		return TriggerWebSocket
 	default:
@@ -690,12 +928,23 @@ func parseTriggerType(s string) TriggerType {
 	}
 }
 
-// RunLocalTest runs local testing logic when not in Lambda environment
+// RunLocalTest runs local testing logic when not in Lambda environment.
+// With LIFT_PRINT_ROUTES set it prints the route table instead.
 func (a *App) RunLocalTest() {
 	if a.IsLambda() {
 		return
 	}
 
+	// Print the route table for the `lift routes` command
+	if os.Getenv("LIFT_PRINT_ROUTES") != "" {
+		if err := a.WriteRoutes(os.Stdout); err != nil && a.logger != nil {
+			a.logger.Error("Error writing routes", map[string]any{
+				"error": err,
+			})
+		}
+		return
+	}
+
 	// Load test event from file
 	testFile := os.Getenv("TEST_FILE")
 	if testFile == "" {
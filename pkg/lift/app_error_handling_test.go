@@ -2,6 +2,7 @@ package lift
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/pay-theory/lift/pkg/lift/adapters"
@@ -83,7 +84,7 @@ func TestHandleRequestLiftErrorStatusCodes(t *testing.T) {
 
 			// Simulate HandleRequest flow
 			ctx := NewContext(context.Background(), req)
-			
+
 			// Start the app to transfer middleware
 			err := app.Start()
 			require.NoError(t, err)
@@ -143,7 +144,7 @@ func TestHandleRequestNonLiftError(t *testing.T) {
 
 	// Simulate HandleRequest flow
 	ctx := NewContext(context.Background(), req)
-	
+
 	// Start the app
 	err := app.Start()
 	require.NoError(t, err)
@@ -197,7 +198,7 @@ func TestMiddlewareLiftErrorStatusCodes(t *testing.T) {
 
 	// Simulate HandleRequest flow
 	ctx := NewContext(context.Background(), req)
-	
+
 	// Start the app
 	err := app.Start()
 	require.NoError(t, err)
@@ -220,4 +221,190 @@ func TestMiddlewareLiftErrorStatusCodes(t *testing.T) {
 
 	assert.Equal(t, "UNAUTHORIZED", respBody["code"])
 	assert.Equal(t, "Invalid API key", respBody["message"])
-}
\ No newline at end of file
+}
+func TestHandleErrorNotifiesObservers(t *testing.T) {
+	var observedRoute string
+	var observedErr error
+	app := New(WithErrorObserver(func(ctx *Context, err error) {
+		observedRoute = ctx.Route()
+		observedErr = err
+	}))
+
+	app.GET("/orders/:id", func(ctx *Context) error {
+		return SystemError("database unavailable")
+	})
+
+	req := NewRequest(&adapters.Request{
+		Method:      "GET",
+		Path:        "/orders/123",
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+		TriggerType: TriggerAPIGateway,
+	})
+	ctx := NewContext(context.Background(), req)
+	require.NoError(t, app.Start())
+
+	routerErr := app.router.Handle(ctx)
+	require.Error(t, routerErr)
+	_, err := app.handleError(ctx, routerErr)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/orders/:id", observedRoute)
+	assert.Equal(t, routerErr, observedErr)
+}
+
+type domainError struct {
+	Type    string
+	Message string
+}
+
+func (e domainError) Error() string {
+	return e.Message
+}
+
+func TestOnError(t *testing.T) {
+	var observed []error
+	app := New(WithErrorObserver(func(ctx *Context, err error) {
+		observed = append(observed, err)
+	}))
+
+	app.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			if ctx.Request.Path == "/guarded" {
+				return domainError{Type: "forbidden", Message: "account is suspended"}
+			}
+			return next.Handle(ctx)
+		})
+	})
+	app.GET("/users", func(ctx *Context) error {
+		return domainError{Type: "conflict", Message: "email already exists"}
+	})
+	app.GET("/guarded", func(ctx *Context) error {
+		return ctx.OK(nil)
+	})
+	app.GET("/panic", func(ctx *Context) error {
+		panic("nil map")
+	})
+	app.GET("/teapot", func(ctx *Context) error {
+		return domainError{Type: "teapot", Message: "short and stout"}
+	})
+	app.GET("/other", func(ctx *Context) error {
+		return NotFound("no such thing")
+	})
+
+	app.OnError(func(ctx *Context, err error) error {
+		var domainErr domainError
+		if !errors.As(err, &domainErr) {
+			return err
+		}
+		switch domainErr.Type {
+		case "conflict":
+			return NewLiftError("CONFLICT", domainErr.Message, 409).WithCause(err)
+		case "forbidden":
+			return AuthorizationError(domainErr.Message)
+		case "teapot":
+			return ctx.Status(418).JSON(map[string]string{"message": domainErr.Message})
+		}
+		return err
+	})
+	app.OnError(func(ctx *Context, err error) error {
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			assert.NotEmpty(t, panicErr.Stack)
+			return NewLiftError("UNAVAILABLE", "Try again later", 503)
+		}
+		return err
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   map[string]any
+	}{
+		{path: "/users", status: 409, body: map[string]any{"error": "CONFLICT", "message": "email already exists"}},
+		{path: "/guarded", status: 403, body: map[string]any{"error": "AUTHORIZATION_ERROR", "message": "account is suspended"}},
+		{path: "/panic", status: 503, body: map[string]any{"error": "UNAVAILABLE", "message": "Try again later"}},
+		{path: "/other", status: 404, body: map[string]any{"error": "NOT_FOUND", "message": "no such thing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			ctx := newMountContext("GET", tt.path)
+			require.NoError(t, app.HandleTestRequest(ctx))
+			assert.Equal(t, tt.status, ctx.Response.StatusCode)
+			assert.Equal(t, tt.body, ctx.Response.Body)
+		})
+	}
+
+	// Handlers can write the response themselves
+	ctx := newMountContext("GET", "/teapot")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 418, ctx.Response.StatusCode)
+	assert.Equal(t, map[string]string{"message": "short and stout"}, ctx.Response.Body)
+
+	// Observers see the original errors
+	require.Len(t, observed, 5)
+	assert.Equal(t, domainError{Type: "conflict", Message: "email already exists"}, observed[0])
+	assert.IsType(t, &PanicError{}, observed[2])
+}
+
+func TestPanicsWithoutErrorHandlers(t *testing.T) {
+	var observed error
+	app := New(WithErrorObserver(func(ctx *Context, err error) {
+		observed = err
+	}))
+	app.GET("/panic", func(ctx *Context) error {
+		panic("boom")
+	})
+
+	ctx := newMountContext("GET", "/panic")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 500, ctx.Response.StatusCode)
+	assert.IsType(t, &PanicError{}, observed)
+}
+
+func TestOnErrorInMountedApp(t *testing.T) {
+	var observed []error
+	sub := New(WithErrorObserver(func(ctx *Context, err error) {
+		observed = append(observed, err)
+	}))
+	sub.GET("/users", func(ctx *Context) error {
+		return domainError{Type: "conflict", Message: "email already exists"}
+	})
+	sub.GET("/panic", func(ctx *Context) error {
+		panic("boom")
+	})
+	sub.OnError(func(ctx *Context, err error) error {
+		var domainErr domainError
+		if errors.As(err, &domainErr) {
+			return NewLiftError("CONFLICT", domainErr.Message, 409).WithCause(err)
+		}
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			return NewLiftError("UNAVAILABLE", "Try again later", 503)
+		}
+		return err
+	})
+
+	var parentSaw []error
+	app := New()
+	app.OnError(func(ctx *Context, err error) error {
+		parentSaw = append(parentSaw, err)
+		return err
+	})
+	require.NoError(t, app.Mount("/accounts", sub))
+
+	ctx := newMountContext("GET", "/accounts/users")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 409, ctx.Response.StatusCode)
+	assert.Equal(t, map[string]any{"error": "CONFLICT", "message": "email already exists"}, ctx.Response.Body)
+
+	ctx = newMountContext("GET", "/accounts/panic")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 503, ctx.Response.StatusCode)
+
+	require.Len(t, observed, 2)
+	assert.IsType(t, domainError{}, observed[0])
+	assert.IsType(t, &PanicError{}, observed[1])
+	require.Len(t, parentSaw, 2, "the parent sees the sub-application's mapped errors")
+	assert.IsType(t, &LiftError{}, parentSaw[0])
+}
@@ -203,4 +203,49 @@ func TestHandleRequestWithDependencyInjection(t *testing.T) {
 	if string(bodyBytes) != expectedBody {
 		t.Errorf("Expected body %s, got %s", expectedBody, string(bodyBytes))
 	}
-}
\ No newline at end of file
+}
+
+func TestPreRoutingMiddleware(t *testing.T) {
+	app := New()
+
+	var order []string
+	app.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			order = append(order, "use:"+ctx.Route())
+			return next.Handle(ctx)
+		})
+	})
+	app.Pre(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			order = append(order, "pre:"+ctx.Route())
+			if ctx.Request.Path == "/v1/users/42" {
+				ctx.Request.Path = "/users/42"
+			}
+			return next.Handle(ctx)
+		})
+	})
+	app.GET("/users/:id", func(ctx *Context) error {
+		return ctx.Text(ctx.Param("id"))
+	})
+
+	event := map[string]any{
+		"resource":   "/v1/users/42",
+		"httpMethod": "GET",
+		"path":       "/v1/users/42",
+		"requestContext": map[string]any{
+			"requestId": "test-request-id",
+		},
+	}
+	response, err := app.HandleRequest(context.Background(), event)
+	if err != nil {
+		t.Fatalf("HandleRequest failed: %v", err)
+	}
+
+	resp := response.(*Response)
+	if resp.StatusCode != 200 || resp.Body != "42" {
+		t.Errorf("expected rewritten request to reach /users/:id, got %d %v", resp.StatusCode, resp.Body)
+	}
+	if len(order) != 2 || order[0] != "pre:" || order[1] != "use:/users/:id" {
+		t.Errorf("unexpected middleware order %v", order)
+	}
+}
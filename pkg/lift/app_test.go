@@ -3,6 +3,7 @@ package lift
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -31,29 +32,29 @@ func TestAppRoutes(t *testing.T) {
 	// Test route registration
 	err := app.GET("/test", func(ctx *Context) error {
 		return ctx.JSON(map[string]string{"message": "test"})
-	})
+	}).Err()
 	if err != nil {
 		t.Fatalf("GET route registration failed: %v", err)
 	}
 
 	err = app.POST("/users", func(ctx *Context) error {
 		return ctx.JSON(map[string]string{"created": "user"})
-	})
+	}).Err()
 	if err != nil {
 		t.Fatalf("POST route registration failed: %v", err)
 	}
 
-	// Test individual route registrations (method chaining not supported with error returns)
+	// Test individual route registrations
 	err = app.PUT("/update", func(ctx *Context) error {
 		return nil
-	})
+	}).Err()
 	if err != nil {
 		t.Fatalf("PUT route registration failed: %v", err)
 	}
 
 	err = app.DELETE("/delete", func(ctx *Context) error {
 		return nil
-	})
+	}).Err()
 	if err != nil {
 		t.Fatalf("DELETE route registration failed: %v", err)
 	}
@@ -79,6 +80,22 @@ func TestAppStart(t *testing.T) {
 	}
 }
 
+func TestAppStartReportsRouteErrors(t *testing.T) {
+	app := New()
+	app.GET("/ok", func(ctx *Context) error { return nil })
+	if app.POST("/bad", "not a handler").Err() == nil {
+		t.Fatal("expected the invalid handler to be rejected")
+	}
+
+	err := app.Start()
+	if err == nil || !strings.Contains(err.Error(), "POST /bad") {
+		t.Fatalf("expected Start() to report the failed route, got %v", err)
+	}
+	if app.started {
+		t.Error("App should not start with failed routes")
+	}
+}
+
 func TestAppWithConfig(t *testing.T) {
 	config := &Config{
 		LogLevel: "DEBUG",
@@ -0,0 +1,77 @@
+package lift
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+)
+
+// basePathStripping holds the prefixes removed from HTTP request paths
+type basePathStripping struct {
+	basePaths []string
+}
+
+// WithBasePathStripping removes deployment prefixes from request paths before
+// routing, so the same routes work behind execute-api URLs, stages and
+// custom domain path mappings. The API Gateway stage is stripped when the
+// path starts with it, for both REST (v1) and HTTP (v2) APIs, followed by
+// the longest matching base path:
+//
+//	app := lift.New(lift.WithBasePathStripping("/payments", "/v1/payments"))
+//
+// Prefixes only match whole path segments. The removed prefix is available
+// from ctx.BasePath().
+func WithBasePathStripping(basePaths ...string) AppOption {
+	return func(app *App) {
+		normalized := make([]string, 0, len(basePaths))
+		for _, basePath := range basePaths {
+			if trimmed := strings.Trim(basePath, "/"); trimmed != "" {
+				normalized = append(normalized, "/"+trimmed)
+			}
+		}
+		// Try longer base paths first so "/v1/payments" wins over "/v1"
+		sort.SliceStable(normalized, func(i, j int) bool {
+			return len(normalized[i]) > len(normalized[j])
+		})
+
+		app.basePathStripping = &basePathStripping{basePaths: normalized}
+	}
+}
+
+// strip removes the stage and base path from an HTTP request's path and
+// returns the removed prefix
+func (b *basePathStripping) strip(req *Request) string {
+	path := req.Path
+	removed := ""
+
+	if stage := req.Stage; stage != "" && stage != "$default" {
+		if stripped, ok := adapters.StripPathPrefix(path, stage); ok {
+			removed = "/" + strings.Trim(stage, "/")
+			path = stripped
+		}
+	}
+
+	for _, basePath := range b.basePaths {
+		if stripped, ok := adapters.StripPathPrefix(path, basePath); ok {
+			removed += basePath
+			path = stripped
+			break
+		}
+	}
+
+	if removed != "" {
+		req.Path = path
+		if req.Request != nil {
+			req.Request.Path = path
+		}
+	}
+	return removed
+}
+
+// BasePath returns the prefix removed from the request path by
+// WithBasePathStripping, or "" if nothing was removed
+func (c *Context) BasePath() string {
+	basePath, _ := c.Get("base_path").(string)
+	return basePath
+}
@@ -0,0 +1,73 @@
+package lift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func restEvent(stage, path string) map[string]any {
+	return map[string]any{
+		"resource":   "/{proxy+}",
+		"path":       path,
+		"httpMethod": "GET",
+		"headers":    map[string]any{},
+		"requestContext": map[string]any{
+			"stage":     stage,
+			"requestId": "req-1",
+		},
+	}
+}
+
+func TestWithBasePathStripping(t *testing.T) {
+	tests := []struct {
+		name         string
+		basePaths    []string
+		event        map[string]any
+		wantPath     string
+		wantBasePath string
+	}{
+		{"stage prefix", nil, restEvent("prod", "/prod/users"), "/users", "/prod"},
+		{"no stage in path", nil, restEvent("prod", "/users"), "/users", ""},
+		{"stage is a partial segment", nil, restEvent("prod", "/products"), "/products", ""},
+		{"base path mapping", []string{"/payments"}, restEvent("prod", "/payments/users"), "/users", "/payments"},
+		{"longest base path wins", []string{"v1", "/v1/payments"}, restEvent("prod", "/v1/payments/users"), "/users", "/v1/payments"},
+		{"stage and base path", []string{"payments"}, restEvent("prod", "/prod/payments/users"), "/users", "/prod/payments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(WithBasePathStripping(tt.basePaths...))
+
+			var gotPath, gotBasePath string
+			handler := func(ctx *Context) error {
+				gotPath = ctx.Request.Path
+				gotBasePath = ctx.BasePath()
+				return ctx.OK(nil)
+			}
+			app.GET("/users", handler)
+			app.GET("/products", handler)
+
+			_, err := app.HandleRequest(context.Background(), tt.event)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPath, gotPath)
+			assert.Equal(t, tt.wantBasePath, gotBasePath)
+		})
+	}
+}
+
+func TestBasePathStrippingDisabledByDefault(t *testing.T) {
+	app := New()
+
+	called := false
+	app.GET("/users", func(ctx *Context) error {
+		called = true
+		return ctx.OK(nil)
+	})
+
+	_, err := app.HandleRequest(context.Background(), restEvent("prod", "/prod/users"))
+	require.NoError(t, err)
+	assert.False(t, called)
+}
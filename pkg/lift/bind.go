@@ -0,0 +1,245 @@
+package lift
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/validation"
+)
+
+// bindTypedRequest decodes the body into v, fills `path` and `query` tagged
+// fields, and validates the result
+func bindTypedRequest(ctx *Context, v any) error {
+	target := reflect.ValueOf(v).Elem()
+	for target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		target = target.Elem()
+	}
+
+	bound := target.Kind() == reflect.Struct && hasParamFields(target.Type())
+
+	if ctx.Request != nil && len(ctx.Request.Body) > 0 {
+		if err := ctx.decodeBody(v); err != nil {
+			return err
+		}
+	} else if !bound && bodyExpected(ctx) {
+		return NewLiftError("EMPTY_BODY", "Request body is empty", 400)
+	}
+
+	if bound {
+		if err := bindParams(ctx, target, "path", "query"); err != nil {
+			return err
+		}
+	}
+
+	return validateBound(ctx, target.Addr().Interface())
+}
+
+// BindQuery fills the `query` tagged fields of the struct v points to from
+// the query string, converting them to the fields' types, and validates
+// it:
+//
+//	var params struct {
+//		Page    int       `query:"page" default:"1" validate:"min=1"`
+//		PerPage int       `query:"per_page" default:"10" validate:"min=1,max=100"`
+//		Status  []string  `query:"status"`
+//		Since   time.Time `query:"since"`
+//	}
+//	if err := ctx.BindQuery(&params); err != nil {
+//		return err
+//	}
+//
+// Fields can be strings, bools, numbers, time.Duration, time.Time (parsed
+// with ParseTime), pointers to them, or slices of them given as
+// comma-separated values. Absent parameters take the `default` tag's value.
+// Malformed values fail with 400 INVALID_PARAMETER and invalid ones with
+// 400 VALIDATION_ERROR.
+func (c *Context) BindQuery(v any) error {
+	return c.bindStruct(v, "query")
+}
+
+// BindPath fills the `path` tagged fields of the struct v points to from
+// the route's path parameters, like BindQuery:
+//
+//	var params struct {
+//		ProjectID string `path:"project_id" validate:"required"`
+//		Version   int    `path:"version"`
+//	}
+func (c *Context) BindPath(v any) error {
+	return c.bindStruct(v, "path")
+}
+
+func (c *Context) bindStruct(v any, source string) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind %s: expected a pointer to a struct, got %T", source, v)
+	}
+	if err := bindParams(c, target.Elem(), source); err != nil {
+		return err
+	}
+	return validateBound(c, v)
+}
+
+// validateBound validates a bound value with the context's validator
+func validateBound(ctx *Context, v any) error {
+	validate := validation.Validate
+	if ctx.validator != nil {
+		validate = ctx.validator.Validate
+	}
+	if err := validate(v); err != nil {
+		liftErr := NewLiftError("VALIDATION_ERROR", "Validation failed", 400).WithCause(err)
+		var validationErrors validation.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			liftErr = liftErr.WithDetail("errors", validationErrors)
+		}
+		return liftErr
+	}
+	return nil
+}
+
+// bodyExpected reports whether the request method carries a body
+func bodyExpected(ctx *Context) bool {
+	if ctx.Request == nil {
+		return true
+	}
+	switch ctx.Request.Method {
+	case "POST", "PUT", "PATCH":
+		return true
+	}
+	// Event triggers have no method and deliver their payload as the body
+	return ctx.Request.Method == ""
+}
+
+// hasParamFields reports whether a struct has `path` or `query` tagged fields
+func hasParamFields(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Tag.Get("path") != "" || field.Tag.Get("query") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// bindParams sets fields tagged with the sources, `path` or `query`, from
+// the request. Parameters that are absent take the `default` tag's value
+// if the field is still zero, and otherwise leave the field as it is, e.g.
+// as decoded from the body.
+func bindParams(ctx *Context, target reflect.Value, sources ...string) error {
+	typ := target.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		var source, name, value string
+		for _, candidate := range sources {
+			if name = field.Tag.Get(candidate); name != "" {
+				source = candidate
+				break
+			}
+		}
+		switch source {
+		case "path":
+			value = ctx.Param(name)
+		case "query":
+			value = ctx.Query(name)
+		default:
+			continue
+		}
+		if value == "" {
+			if value = field.Tag.Get("default"); value == "" || !target.Field(i).IsZero() {
+				continue
+			}
+		}
+
+		if err := setParam(ctx, target.Field(i), value); err != nil {
+			return NewLiftError("INVALID_PARAMETER", "Invalid "+source+" parameter: "+name, 400).
+				WithCause(err).
+				WithDetail("parameter", name)
+		}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setParam converts a parameter string into a field
+func setParam(ctx *Context, field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setParam(ctx, ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	switch field.Type() {
+	case durationType:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(parsed))
+		return nil
+	case timeType:
+		parsed, err := ctx.ParseTime(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	case reflect.Slice:
+		parts := strings.Split(value, ",")
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if field.Type().Elem().Kind() == reflect.Slice {
+				return errors.New("unsupported parameter type " + field.Type().String())
+			}
+			if err := setParam(ctx, slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	default:
+		return errors.New("unsupported parameter type " + field.Type().String())
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package lift
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listTasksQuery struct {
+	Page     int           `query:"page" default:"1" validate:"min=1"`
+	PerPage  int           `query:"per_page" default:"10" validate:"min=1,max=100"`
+	Status   []string      `query:"status"`
+	IDs      []int64       `query:"ids"`
+	Archived *bool         `query:"archived"`
+	Since    time.Time     `query:"since"`
+	Timeout  time.Duration `query:"timeout"`
+	Ignored  string
+}
+
+type taskPath struct {
+	ProjectID string `path:"project_id" validate:"required"`
+	Version   int    `path:"version"`
+}
+
+func bindContext(query, params map[string]string) *Context {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method:      "GET",
+		Path:        "/projects/p_1/tasks",
+		QueryParams: query,
+	}))
+	for key, value := range params {
+		ctx.SetParam(key, value)
+	}
+	return ctx
+}
+
+func TestBindQuery(t *testing.T) {
+	ctx := bindContext(map[string]string{
+		"page":     "3",
+		"status":   "open, blocked",
+		"ids":      "7,9",
+		"archived": "false",
+		"since":    "2024-03-01",
+		"timeout":  "1m30s",
+		"Ignored":  "x",
+	}, nil)
+	require.NoError(t, ctx.SetTimezone("America/Chicago"))
+
+	var query listTasksQuery
+	require.NoError(t, ctx.BindQuery(&query))
+	require.NotNil(t, query.Archived)
+	assert.False(t, *query.Archived)
+	chicago, _ := time.LoadLocation("America/Chicago")
+	assert.Equal(t, listTasksQuery{
+		Page:     3,
+		PerPage:  10,
+		Status:   []string{"open", "blocked"},
+		IDs:      []int64{7, 9},
+		Archived: query.Archived,
+		Since:    time.Date(2024, 3, 1, 0, 0, 0, 0, chicago),
+		Timeout:  90 * time.Second,
+	}, query)
+}
+
+func TestBindQueryErrors(t *testing.T) {
+	var query listTasksQuery
+	err := bindContext(map[string]string{"page": "two"}, nil).BindQuery(&query)
+	var liftErr *LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "INVALID_PARAMETER", liftErr.Code)
+	assert.Equal(t, "page", liftErr.Details["parameter"])
+
+	err = bindContext(map[string]string{"ids": "7,x"}, nil).BindQuery(&query)
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "ids", liftErr.Details["parameter"])
+
+	query = listTasksQuery{}
+	err = bindContext(map[string]string{"per_page": "500"}, nil).BindQuery(&query)
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "VALIDATION_ERROR", liftErr.Code)
+	assert.Equal(t, 400, liftErr.StatusCode)
+
+	assert.Error(t, bindContext(nil, nil).BindQuery(query), "requires a pointer")
+}
+
+func TestBindPath(t *testing.T) {
+	var path taskPath
+	require.NoError(t, bindContext(nil, map[string]string{"project_id": "p_1", "version": "2"}).BindPath(&path))
+	assert.Equal(t, taskPath{ProjectID: "p_1", Version: 2}, path)
+
+	// Only path parameters are bound
+	path = taskPath{}
+	err := bindContext(map[string]string{"project_id": "p_1"}, nil).BindPath(&path)
+	var liftErr *LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "VALIDATION_ERROR", liftErr.Code)
+}
@@ -0,0 +1,169 @@
+package lift
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// Standard headers used to capture client metadata
+const (
+	HeaderClientSDK       = "X-Client-SDK"
+	HeaderClientVersion   = "X-Client-Version"
+	HeaderClientPlatform  = "X-Client-Platform"
+	HeaderClientOSVersion = "X-Client-OS-Version"
+	HeaderDeviceID        = "X-Device-ID"
+)
+
+const clientMetadataKey = "client_metadata"
+
+// ClientMetadata describes the client application that sent the request
+type ClientMetadata struct {
+	// SDKName and SDKVersion are parsed from the X-Client-SDK header ("name/version")
+	SDKName    string `json:"sdk_name,omitempty"`
+	SDKVersion string `json:"sdk_version,omitempty"`
+	// AppVersion is the version of the calling application
+	AppVersion string `json:"app_version,omitempty"`
+	// Platform is the client platform, normalized to lower case (e.g. "ios", "android", "web")
+	Platform  string `json:"platform,omitempty"`
+	OSVersion string `json:"os_version,omitempty"`
+	DeviceID  string `json:"device_id,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// ParseClientMetadata extracts client metadata from request headers using the standard header names
+func ParseClientMetadata(headers map[string]string) *ClientMetadata {
+	meta := &ClientMetadata{
+		AppVersion: headerValue(headers, HeaderClientVersion),
+		Platform:   strings.ToLower(headerValue(headers, HeaderClientPlatform)),
+		OSVersion:  headerValue(headers, HeaderClientOSVersion),
+		DeviceID:   headerValue(headers, HeaderDeviceID),
+		UserAgent:  headerValue(headers, "User-Agent"),
+	}
+
+	if sdk := headerValue(headers, HeaderClientSDK); sdk != "" {
+		if name, version, ok := strings.Cut(sdk, "/"); ok {
+			meta.SDKName = strings.TrimSpace(name)
+			meta.SDKVersion = strings.TrimSpace(version)
+		} else {
+			meta.SDKName = sdk
+		}
+	}
+
+	return meta
+}
+
+// IsEmpty reports whether no client metadata was supplied
+func (m *ClientMetadata) IsEmpty() bool {
+	return m.SDKName == "" && m.SDKVersion == "" && m.AppVersion == "" &&
+		m.Platform == "" && m.OSVersion == "" && m.DeviceID == ""
+}
+
+// LogFields returns the non-empty metadata as structured log fields
+func (m *ClientMetadata) LogFields() map[string]any {
+	fields := make(map[string]any)
+	add := func(key, value string) {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	add("client_sdk", m.SDKName)
+	add("client_sdk_version", m.SDKVersion)
+	add("client_version", m.AppVersion)
+	add("client_platform", m.Platform)
+	add("client_os_version", m.OSVersion)
+	add("device_id", m.DeviceID)
+	return fields
+}
+
+// AuditContext converts the metadata for audit events and fraud scoring
+func (m *ClientMetadata) AuditContext() security.ClientContext {
+	return security.ClientContext{
+		DeviceID:   m.DeviceID,
+		Platform:   m.Platform,
+		AppVersion: m.AppVersion,
+		SDKVersion: m.SDKVersion,
+	}
+}
+
+// Client returns metadata about the calling client. Metadata is parsed from the
+// standard headers on first use unless middleware has already set it.
+func (c *Context) Client() *ClientMetadata {
+	if meta, ok := c.Get(clientMetadataKey).(*ClientMetadata); ok {
+		return meta
+	}
+
+	var headers map[string]string
+	if c.Request != nil {
+		headers = c.Request.Headers
+	}
+	meta := ParseClientMetadata(headers)
+	c.Set(clientMetadataKey, meta)
+	return meta
+}
+
+// SetClient overrides the client metadata for the request
+func (c *Context) SetClient(meta *ClientMetadata) {
+	c.Set(clientMetadataKey, meta)
+}
+
+// CompareVersions compares two dotted version strings numerically, returning
+// -1, 0 or 1. A leading "v" and any pre-release or build suffix are ignored, and
+// missing components count as zero, so "1.2" equals "1.2.0".
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for len(pa) < len(pb) {
+		pa = append(pa, 0)
+	}
+	for len(pb) < len(pa) {
+		pb = append(pb, 0)
+	}
+
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil
+	}
+
+	segments := strings.Split(version, ".")
+	parts := make([]int, len(segments))
+	for i, segment := range segments {
+		n, err := strconv.Atoi(segment)
+		if err != nil {
+			n = 0
+		}
+		parts[i] = n
+	}
+	return parts
+}
+
+// headerValue performs a case-insensitive header lookup
+func headerValue(headers map[string]string, name string) string {
+	if headers == nil {
+		return ""
+	}
+	if value, ok := headers[name]; ok {
+		return value
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package lift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClientMetadata(t *testing.T) {
+	meta := ParseClientMetadata(map[string]string{
+		"x-client-sdk":      "paytheory-ios/2.4.1",
+		"X-Client-Version":  "5.2.0",
+		"X-Client-Platform": "iOS",
+		"X-Device-ID":       "device_123",
+	})
+
+	assert.Equal(t, "paytheory-ios", meta.SDKName)
+	assert.Equal(t, "2.4.1", meta.SDKVersion)
+	assert.Equal(t, "5.2.0", meta.AppVersion)
+	assert.Equal(t, "ios", meta.Platform)
+	assert.Equal(t, "device_123", meta.LogFields()["device_id"])
+	assert.False(t, meta.IsEmpty())
+	assert.True(t, ParseClientMetadata(nil).IsEmpty())
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, CompareVersions("1.2", "1.2.0"))
+	assert.Equal(t, 0, CompareVersions("v2.0.0", "2.0.0-beta"))
+	assert.Equal(t, -1, CompareVersions("1.9.9", "1.10.0"))
+	assert.Equal(t, 1, CompareVersions("3.0", "2.99.99"))
+}
+
+func TestAuditEventsIncludeClient(t *testing.T) {
+	var audited map[string]any
+	var event *security.AuditEvent
+	app := New(WithSecurityMiddleware(SecurityConfig{
+		AuditLogger: func(ctx *Context, _ string, data map[string]any) {
+			audited = data
+			event = WithSecurity(ctx).AuditEvent("request", "list_payments")
+		},
+	}))
+	app.GET("/payments", func(ctx *Context) error { return ctx.Text("ok") })
+
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method: "GET",
+		Path:   "/payments",
+		Headers: map[string]string{
+			"X-Client-SDK":      "paytheory-ios/2.4.1",
+			"X-Client-Version":  "5.2.0",
+			"X-Client-Platform": "iOS",
+			"X-Device-ID":       "device_123",
+		},
+	}))
+	require.NoError(t, app.HandleTestRequest(ctx))
+
+	expected := security.ClientContext{DeviceID: "device_123", Platform: "ios", AppVersion: "5.2.0", SDKVersion: "2.4.1"}
+	assert.Equal(t, expected, audited["client"])
+	require.NotNil(t, event)
+	assert.Equal(t, expected, event.Client)
+	assert.Equal(t, "/payments", event.Resource)
+}
@@ -0,0 +1,83 @@
+package lift
+
+import (
+	"fmt"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+)
+
+// CognitoTrigger is a Cognito user pool trigger event
+type CognitoTrigger = adapters.CognitoTrigger
+
+// Cognito registers a handler for Cognito user pool triggers whose
+// triggerSource matches pattern, which may use "*" wildcards, e.g.
+// "PreSignUp_*", "PostConfirmation_ConfirmSignUp" or "TokenGeneration_*".
+// The handler runs behind the app's middleware and changes the event's
+// response through ctx.CognitoTrigger(); the changed event is returned to
+// Cognito. An error rejects the user's request, and Cognito shows its
+// message to the user.
+//
+//	app.Cognito("TokenGeneration_*", func(ctx *lift.Context) error {
+//		trigger, err := ctx.CognitoTrigger()
+//		if err != nil {
+//			return err
+//		}
+//		trigger.AddClaims(map[string]string{"tenant_id": trigger.UserAttributes["custom:tenant_id"]})
+//		return nil
+//	})
+//
+// Routes are tried in registration order, so register specific trigger
+// sources before broad ones.
+func (a *App) Cognito(pattern string, handler any) error {
+	h, err := asHandler(handler)
+	if err != nil {
+		return fmt.Errorf("invalid Cognito handler: %w", err)
+	}
+
+	a.eventRouter.addRoute(&EventRoute{
+		TriggerType: TriggerCognito,
+		Pattern:     pattern,
+		Handler: EventHandlerFunc(func(ctx *Context) error {
+			if ctx.Logger != nil {
+				ctx.Logger = ctx.Logger.WithField("trigger_source", ctx.Request.DetailType)
+			}
+			return a.withMiddleware(h).Handle(ctx)
+		}),
+		Match: func(ctx *Context) bool {
+			return pattern == "" || a.eventRouter.matchWildcardPattern(ctx.Request.DetailType, pattern)
+		},
+	})
+	return nil
+}
+
+// CognitoTrigger returns the Cognito trigger event the current request is
+// about. Changes to its Response are returned to Cognito.
+func (c *Context) CognitoTrigger() (*CognitoTrigger, error) {
+	if c.Request == nil || c.Request.TriggerType != TriggerCognito {
+		return nil, fmt.Errorf("not a Cognito trigger")
+	}
+	event, ok := c.Request.RawEvent.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid Cognito trigger event")
+	}
+	return adapters.ParseCognitoTrigger(event), nil
+}
+
+// CognitoTriggerSource returns the triggerSource of a Cognito trigger, or
+// "" for other events
+func (c *Context) CognitoTriggerSource() string {
+	if c.Request == nil || c.Request.TriggerType != TriggerCognito {
+		return ""
+	}
+	return c.Request.DetailType
+}
+
+// CognitoUserAttributes returns the user attributes of a Cognito trigger,
+// or nil for other events
+func (c *Context) CognitoUserAttributes() map[string]string {
+	trigger, err := c.CognitoTrigger()
+	if err != nil {
+		return nil
+	}
+	return trigger.UserAttributes
+}
@@ -0,0 +1,79 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cognitoTriggerEvent(triggerSource string) map[string]any {
+	return map[string]any{
+		"version":       "1",
+		"triggerSource": triggerSource,
+		"region":        "us-east-1",
+		"userPoolId":    "us-east-1_abc123",
+		"userName":      "user-1",
+		"callerContext": map[string]any{"clientId": "client-1"},
+		"request": map[string]any{
+			"userAttributes": map[string]any{
+				"email":            "ada@example.com",
+				"custom:tenant_id": "tenant-1",
+			},
+		},
+		"response": map[string]any{},
+	}
+}
+
+func TestCognitoTriggers(t *testing.T) {
+	var middlewareRuns int
+
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			middlewareRuns++
+			return next.Handle(ctx)
+		})
+	})
+	require.NoError(t, app.Cognito("PreSignUp_*", func(ctx *Context) error {
+		if ctx.CognitoUserAttributes()["email"] == "blocked@example.com" {
+			return errors.New("sign-up is not allowed")
+		}
+		trigger, err := ctx.CognitoTrigger()
+		if err != nil {
+			return err
+		}
+		trigger.AutoConfirmUser()
+		return nil
+	}))
+	require.NoError(t, app.Cognito("TokenGeneration_*", func(ctx *Context) error {
+		assert.Equal(t, "TokenGeneration_Authentication", ctx.CognitoTriggerSource())
+		trigger, err := ctx.CognitoTrigger()
+		if err != nil {
+			return err
+		}
+		trigger.AddClaims(map[string]string{"tenant_id": trigger.UserAttributes["custom:tenant_id"]})
+		return nil
+	}))
+
+	result, err := app.HandleRequest(context.Background(), cognitoTriggerEvent("PreSignUp_SignUp"))
+	require.NoError(t, err)
+	response := result.(map[string]any)["response"].(map[string]any)
+	assert.Equal(t, true, response["autoConfirmUser"])
+
+	result, err = app.HandleRequest(context.Background(), cognitoTriggerEvent("TokenGeneration_Authentication"))
+	require.NoError(t, err)
+	details := result.(map[string]any)["response"].(map[string]any)["claimsOverrideDetails"].(map[string]any)
+	assert.Equal(t, "tenant-1", details["claimsToAddOrOverride"].(map[string]any)["tenant_id"])
+	assert.Equal(t, 2, middlewareRuns)
+
+	blocked := cognitoTriggerEvent("PreSignUp_SignUp")
+	blocked["request"].(map[string]any)["userAttributes"].(map[string]any)["email"] = "blocked@example.com"
+	_, err = app.HandleRequest(context.Background(), blocked)
+	assert.EqualError(t, err, "sign-up is not allowed")
+
+	_, err = app.HandleRequest(context.Background(), cognitoTriggerEvent("PostConfirmation_ConfirmSignUp"))
+	assert.Error(t, err, "triggers without a handler fail")
+}
@@ -2,7 +2,6 @@ package lift
 
 import (
 	"context"
-	"encoding/json"
 	"time"
 )
 
@@ -34,6 +33,10 @@ type Context struct {
 	// Lambda-specific
 	RequestID string
 
+	// Matched route pattern (e.g. "/users/:id")
+	route     string
+	routeInfo *Route
+
 	// Performance tracking
 	startTime time.Time
 
@@ -44,6 +47,35 @@ type Context struct {
 	// Response buffering
 	responseBuffer   *ResponseBuffer
 	bufferingEnabled bool
+
+	// Response streaming
+	streamingEnabled bool
+
+	// Signed URL issuing
+	urlSigner  URLSigner
+	urlAuditor SignedURLAuditor
+
+	// Content negotiation
+	renderers        []Renderer
+	binaryMediaTypes []string
+
+	// Deployment stage
+	environment Environment
+
+	// Form parsing
+	multipartLimits MultipartLimits
+	form            *Form
+
+	// Request body decoding
+	decodeMode   DecodeMode
+	maxJSONDepth int
+
+	// Problem+json error responses
+	problems *ProblemOptions
+
+	// Error reporting for failures that don't reach the app's error
+	// pipeline, such as individual batch records
+	errorObserver ErrorObserver
 }
 
 // NewContext creates a new enhanced context
@@ -105,6 +137,15 @@ func (c *Context) UserID() string {
 	return ""
 }
 
+// ActorID returns who is really acting when the user is being
+// impersonated, e.g. by a support agent, or "" otherwise
+func (c *Context) ActorID() string {
+	if actorID, ok := c.values["actor_id"].(string); ok {
+		return actorID
+	}
+	return ""
+}
+
 // TenantID retrieves the current tenant ID from context
 func (c *Context) TenantID() string {
 	if tenantID, ok := c.values["tenant_id"].(string); ok {
@@ -121,6 +162,16 @@ func (c *Context) AccountID() string {
 	return ""
 }
 
+// Route returns the route pattern that matched the request, or "" if no route matched
+func (c *Context) Route() string {
+	return c.route
+}
+
+// SetRoute records the matched route pattern (used by router)
+func (c *Context) SetRoute(pattern string) {
+	c.route = pattern
+}
+
 // SetParam sets a path parameter (used by router)
 func (c *Context) SetParam(key, value string) {
 	c.params[key] = value
@@ -200,8 +251,8 @@ func (c *Context) ParseRequest(v any) error {
 	}
 
 	// Parse JSON
-	if err := json.Unmarshal(c.Request.Body, v); err != nil {
-		return NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+	if err := c.decodeBody(v); err != nil {
+		return err
 	}
 
 	// Validate if validator is available
@@ -248,10 +299,15 @@ func (c *Context) SetValidator(validator Validator) {
 	c.validator = validator
 }
 
-// SetRequestID sets the request ID in the context
+// SetRequestID sets the request ID in the context. It is also carried by
+// the embedded context.Context, where service clients read it with
+// RequestIDFromContext.
 func (c *Context) SetRequestID(requestID string) {
 	c.RequestID = requestID
 	c.Set("request_id", requestID)
+	if c.Context != nil {
+		c.Context = WithRequestID(c.Context, requestID)
+	}
 }
 
 // GetRequestID returns the request ID from the context
@@ -436,6 +492,13 @@ func (c *Context) SetClaims(claims map[string]any) {
 	if accountID, ok := claims["account_id"].(string); ok && accountID != "" {
 		c.Set("account_id", accountID)
 	}
+
+	// Extract the actor of impersonation tokens (RFC 8693 act claim)
+	if actor, ok := claims["act"].(map[string]any); ok {
+		if sub, ok := actor["sub"].(string); ok && sub != "" {
+			c.Set("actor_id", sub)
+		}
+	}
 }
 
 // Claims returns the JWT claims from the context
@@ -0,0 +1,361 @@
+package lift
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/validation"
+)
+
+// CSVOptions configures CSV binding
+type CSVOptions struct {
+	// Delimiter is the field separator (default: ',')
+	Delimiter rune
+	// HeaderAliases maps alternative header names to the csv tag they fill,
+	// e.g. {"Merchant #": "merchant_id"}
+	HeaderAliases map[string]string
+	// MaxRows limits the number of data rows (0 means unlimited)
+	MaxRows int
+	// MaxErrors stops parsing after this many rows have failed (default: 100).
+	// A row that fails several columns counts once.
+	MaxErrors int
+	// TimeLayouts are tried in order for time.Time fields (default: RFC3339, "2006-01-02")
+	TimeLayouts []string
+	// DisableValidation skips struct validation of each row
+	DisableValidation bool
+}
+
+// CSVRowError describes a problem with one row of a CSV upload. Row numbers
+// match the line numbers a spreadsheet shows, so the header is row 1.
+type CSVRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e CSVRowError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("row %d, column %s: %s", e.Row, e.Column, e.Message)
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// CSVResult holds the rows that bound successfully and the errors for rows that did not
+type CSVResult[T any] struct {
+	Rows   []T           `json:"rows"`
+	Errors []CSVRowError `json:"errors,omitempty"`
+}
+
+// csvField maps a CSV column to a struct field
+type csvField struct {
+	pos    int
+	index  int
+	column string
+	name   string
+}
+
+// ParseCSV binds CSV data to a slice of T using `csv:"header"` struct tags.
+// Header matching is case-insensitive. Rows that fail conversion or validation
+// are reported in the result rather than aborting the parse; the returned
+// error is reserved for problems with the file itself, such as a missing
+// required column.
+func ParseCSV[T any](r io.Reader, opts CSVOptions) (*CSVResult[T], error) {
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+	if opts.MaxErrors == 0 {
+		opts.MaxErrors = 100
+	}
+	if len(opts.TimeLayouts) == 0 {
+		opts.TimeLayouts = []string{time.RFC3339, "2006-01-02"}
+	}
+
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, NewLiftError("CSV_INVALID_TARGET", "CSV rows must bind to a struct type", 500)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = opts.Delimiter
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, NewLiftError("CSV_EMPTY", "CSV file is empty", 400)
+	}
+	if err != nil {
+		return nil, NewLiftError("CSV_INVALID", "CSV header could not be read", 400).WithCause(err)
+	}
+
+	columns, err := mapCSVColumns(typ, header, opts.HeaderAliases)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CSVResult[T]{}
+	// rows counts data rows read and failedRows those that failed; Errors
+	// can hold several entries for one row
+	rows, failedRows := 0, 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			row := 0
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				row = parseErr.StartLine
+			}
+			if opts.MaxRows > 0 && rows >= opts.MaxRows {
+				return result, csvTooManyRows(opts.MaxRows)
+			}
+			rows++
+			result.Errors = append(result.Errors, CSVRowError{Row: row, Message: "malformed CSV row"})
+			if failedRows++; failedRows >= opts.MaxErrors {
+				break
+			}
+			continue
+		}
+		if isBlankCSVRecord(record) {
+			continue
+		}
+		row, _ := reader.FieldPos(0)
+		if opts.MaxRows > 0 && rows >= opts.MaxRows {
+			return result, csvTooManyRows(opts.MaxRows)
+		}
+		rows++
+
+		var item T
+		rowErrors := bindCSVRecord(reflect.ValueOf(&item).Elem(), record, columns, row, opts)
+		if len(rowErrors) == 0 && !opts.DisableValidation {
+			rowErrors = validateCSVRow(item, columns, row)
+		}
+
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			if failedRows++; failedRows >= opts.MaxErrors {
+				break
+			}
+			continue
+		}
+		result.Rows = append(result.Rows, item)
+	}
+
+	return result, nil
+}
+
+// csvTooManyRows is the error for a file with more than max data rows
+func csvTooManyRows(max int) *LiftError {
+	return NewLiftError("CSV_TOO_MANY_ROWS", fmt.Sprintf("CSV files are limited to %d rows", max), 413)
+}
+
+// BindCSV binds the request body as CSV. Any row error fails the whole upload
+// with a 422 whose details list every row-indexed error.
+func BindCSV[T any](ctx *Context, opts CSVOptions) ([]T, error) {
+	if ctx.Request == nil || len(ctx.Request.Body) == 0 {
+		return nil, NewLiftError("EMPTY_BODY", "Request body is empty", 400)
+	}
+
+	result, err := ParseCSV[T](bytes.NewReader(ctx.Request.Body), opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, NewLiftError("CSV_VALIDATION_ERROR", fmt.Sprintf("%d CSV rows failed validation", countCSVRows(result.Errors)), 422).
+			WithDetail("errors", result.Errors)
+	}
+	return result.Rows, nil
+}
+
+// mapCSVColumns matches header cells to struct fields
+func mapCSVColumns(typ reflect.Type, header []string, aliases map[string]string) ([]csvField, error) {
+	byTag := make(map[string]csvField)
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = sf.Name
+		}
+		byTag[strings.ToLower(tag)] = csvField{index: i, column: tag, name: sf.Name}
+	}
+
+	normalizedAliases := make(map[string]string, len(aliases))
+	for alias, tag := range aliases {
+		normalizedAliases[strings.ToLower(strings.TrimSpace(alias))] = strings.ToLower(tag)
+	}
+
+	var columns []csvField
+	seen := make(map[int]bool)
+	for pos, cell := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff")))
+		if tag, ok := normalizedAliases[key]; ok {
+			key = tag
+		}
+		if field, ok := byTag[key]; ok {
+			field.pos = pos
+			field.column = strings.TrimSpace(cell)
+			columns = append(columns, field)
+			seen[field.index] = true
+		}
+	}
+
+	// Columns for required fields must be present
+	var missing []string
+	for _, field := range byTag {
+		if seen[field.index] {
+			continue
+		}
+		if strings.Contains(typ.Field(field.index).Tag.Get("validate"), "required") {
+			missing = append(missing, field.column)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, NewLiftError("CSV_MISSING_COLUMNS", "CSV is missing required columns", 400).
+			WithDetail("missing_columns", missing)
+	}
+
+	return columns, nil
+}
+
+func bindCSVRecord(item reflect.Value, record []string, columns []csvField, row int, opts CSVOptions) []CSVRowError {
+	var rowErrors []CSVRowError
+	for _, field := range columns {
+		if field.pos >= len(record) {
+			continue
+		}
+		raw := strings.TrimSpace(record[field.pos])
+		if raw == "" {
+			continue
+		}
+		if err := setCSVValue(item.Field(field.index), raw, opts.TimeLayouts); err != nil {
+			rowErrors = append(rowErrors, CSVRowError{Row: row, Column: field.column, Message: err.Error()})
+		}
+	}
+	return rowErrors
+}
+
+func validateCSVRow(item any, columns []csvField, row int) []CSVRowError {
+	err := validation.Validate(item)
+	if err == nil {
+		return nil
+	}
+
+	columnFor := make(map[string]string, len(columns))
+	for _, field := range columns {
+		columnFor[field.name] = field.column
+	}
+
+	var validationErrors validation.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []CSVRowError{{Row: row, Message: err.Error()}}
+	}
+
+	rowErrors := make([]CSVRowError, 0, len(validationErrors))
+	for _, ve := range validationErrors {
+		column := columnFor[ve.Field]
+		if column == "" {
+			column = ve.Field
+		}
+		rowErrors = append(rowErrors, CSVRowError{Row: row, Column: column, Message: ve.Message})
+	}
+	return rowErrors
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func setCSVValue(field reflect.Value, raw string, timeLayouts []string) error {
+	if field.Kind() == reflect.Ptr {
+		value := reflect.New(field.Type().Elem())
+		if err := setCSVValue(value.Elem(), raw, timeLayouts); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+
+	if field.Type() == timeType {
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid date %q", raw)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.ToLower(raw))
+		if err != nil {
+			switch strings.ToLower(raw) {
+			case "yes", "y":
+				b = true
+			case "no", "n":
+				b = false
+			default:
+				return fmt.Errorf("invalid boolean %q", raw)
+			}
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.ReplaceAll(raw, ",", ""), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.ReplaceAll(raw, ",", ""), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", ""), field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+func isBlankCSVRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// countCSVRows counts the distinct rows with errors
+func countCSVRows(rowErrors []CSVRowError) int {
+	rows := make(map[int]bool)
+	for _, e := range rowErrors {
+		rows[e.Row] = true
+	}
+	return len(rows)
+}
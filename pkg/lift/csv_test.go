@@ -0,0 +1,116 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type csvPayment struct {
+	MerchantID string    `csv:"merchant_id" validate:"required"`
+	Amount     int64     `csv:"amount" validate:"required,min=1"`
+	Email      string    `csv:"email" validate:"omitempty,email"`
+	Recurring  bool      `csv:"recurring"`
+	Settled    time.Time `csv:"settled_on"`
+	Note       *string   `csv:"note"`
+	Internal   string    `csv:"-"`
+}
+
+func TestParseCSV(t *testing.T) {
+	input := strings.Join([]string{
+		"Merchant #,AMOUNT,email,recurring,settled_on,note",
+		"m-1,\"1,250\",a@example.com,yes,2024-03-01,first",
+		"m-2,abc,b@example.com,no,2024-03-02,",
+		",,,,,",
+		"m-3,-4,c@example.com,n,2024-03-03,",
+	}, "\n")
+
+	result, err := ParseCSV[csvPayment](strings.NewReader(input), CSVOptions{
+		HeaderAliases: map[string]string{"Merchant #": "merchant_id"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.Rows, 1)
+	row := result.Rows[0]
+	assert.Equal(t, "m-1", row.MerchantID)
+	assert.Equal(t, int64(1250), row.Amount)
+	assert.True(t, row.Recurring)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), row.Settled)
+	require.NotNil(t, row.Note)
+	assert.Equal(t, "first", *row.Note)
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, CSVRowError{Row: 3, Column: "AMOUNT", Message: `invalid integer "abc"`}, result.Errors[0])
+	assert.Equal(t, 5, result.Errors[1].Row)
+	assert.Equal(t, "AMOUNT", result.Errors[1].Column)
+}
+
+func TestParseCSVMissingRequiredColumns(t *testing.T) {
+	_, err := ParseCSV[csvPayment](strings.NewReader("email\na@example.com\n"), CSVOptions{})
+
+	var liftErr *LiftError
+	require.True(t, errors.As(err, &liftErr))
+	assert.Equal(t, "CSV_MISSING_COLUMNS", liftErr.Code)
+	assert.Equal(t, []string{"amount", "merchant_id"}, liftErr.Details["missing_columns"])
+}
+
+func TestParseCSVLimits(t *testing.T) {
+	input := "merchant_id,amount\nm-1,1\nm-2,2\nm-3,3\n"
+
+	_, err := ParseCSV[csvPayment](strings.NewReader(input), CSVOptions{MaxRows: 2})
+	var liftErr *LiftError
+	require.True(t, errors.As(err, &liftErr))
+	assert.Equal(t, 413, liftErr.StatusCode)
+
+	_, err = ParseCSV[csvPayment](strings.NewReader(""), CSVOptions{})
+	require.True(t, errors.As(err, &liftErr))
+	assert.Equal(t, "CSV_EMPTY", liftErr.Code)
+
+	result, err := ParseCSV[csvPayment](strings.NewReader("merchant_id;amount\nm-1;x\nm-2;y\n"), CSVOptions{Delimiter: ';', MaxErrors: 1})
+	require.NoError(t, err)
+	assert.Len(t, result.Errors, 1)
+}
+
+func TestParseCSVLimitsCountRowsNotErrors(t *testing.T) {
+	// Each bad row has two column errors
+	input := "merchant_id,amount,settled_on\nm-1,x,never\nm-2,1,2024-03-01\nm-3,y,never\n"
+
+	result, err := ParseCSV[csvPayment](strings.NewReader(input), CSVOptions{MaxRows: 3, MaxErrors: 2})
+	require.NoError(t, err, "three rows fit MaxRows however many errors they have")
+	assert.Len(t, result.Rows, 1)
+	assert.Len(t, result.Errors, 4)
+
+	result, err = ParseCSV[csvPayment](strings.NewReader(input), CSVOptions{MaxErrors: 1})
+	require.NoError(t, err)
+	assert.Len(t, result.Errors, 2, "the first failed row is reported in full")
+	assert.Empty(t, result.Rows, "parsing stops at the first failed row")
+}
+
+func TestBindCSV(t *testing.T) {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		Headers: map[string]string{"Content-Type": "text/csv"},
+		Body:    []byte("merchant_id,amount,email\nm-1,100,a@example.com\nm-2,200,not-an-email\n"),
+	}))
+
+	_, err := BindCSV[csvPayment](ctx, CSVOptions{})
+	var liftErr *LiftError
+	require.True(t, errors.As(err, &liftErr))
+	assert.Equal(t, 422, liftErr.StatusCode)
+
+	rowErrors, ok := liftErr.Details["errors"].([]CSVRowError)
+	require.True(t, ok)
+	require.Len(t, rowErrors, 1)
+	assert.Equal(t, 3, rowErrors[0].Row)
+	assert.Equal(t, "email", rowErrors[0].Column)
+
+	ctx.Request.Body = []byte("merchant_id,amount\nm-1,100\n")
+	rows, err := BindCSV[csvPayment](ctx, CSVOptions{})
+	require.NoError(t, err)
+	assert.Len(t, rows, 1)
+}
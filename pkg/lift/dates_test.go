@@ -0,0 +1,110 @@
+package lift
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDateContext(t *testing.T, timezone string, query map[string]string) *Context {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method:      "GET",
+		Path:        "/audit",
+		QueryParams: query,
+	}))
+	if timezone != "" {
+		require.NoError(t, ctx.SetTimezone(timezone))
+	}
+	return ctx
+}
+
+func TestContextParseAndFormatTime(t *testing.T) {
+	ctx := newDateContext(t, "", nil)
+	assert.Equal(t, time.UTC, ctx.Location())
+	assert.Error(t, ctx.SetTimezone("Mars/Olympus_Mons"))
+
+	ctx = newDateContext(t, "America/Chicago", nil)
+
+	parsed, err := ctx.ParseTime("2024-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01T06:00:00Z", parsed.UTC().Format(time.RFC3339))
+
+	parsed, err = ctx.ParseTime("2024-07-01T09:30")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-07-01T14:30:00Z", parsed.UTC().Format(time.RFC3339))
+
+	// Explicit offsets win over the request timezone
+	parsed, err = ctx.ParseTime("2024-07-01T09:30:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-07-01T04:30:00-05:00", ctx.FormatTime(parsed))
+
+	_, err = ctx.ParseTime("03/01/2024")
+	assert.Error(t, err)
+}
+
+func TestContextQueryDateRange(t *testing.T) {
+	now := func() time.Time { return time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC) }
+	rules := DateRangeRules{MaxSpan: 31 * 24 * time.Hour, Now: now}
+
+	ctx := newDateContext(t, "America/New_York", map[string]string{"from": "2024-03-01", "to": "2024-03-31"})
+	r, err := ctx.QueryDateRange("from", "to", rules)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01T05:00:00Z", r.UTC().From.Format(time.RFC3339))
+	// The whole last day is included, across the DST change
+	assert.Equal(t, "2024-04-01T04:00:00Z", r.UTC().To.Format(time.RFC3339))
+	assert.True(t, r.Contains(time.Date(2024, 3, 31, 23, 59, 0, 0, ctx.Location())))
+	assert.False(t, r.Contains(time.Date(2024, 4, 1, 0, 0, 0, 0, ctx.Location())))
+
+	cases := map[string]map[string]string{
+		"invalid date":  {"from": "yesterday", "to": "2024-03-02"},
+		"reversed":      {"from": "2024-03-05", "to": "2024-03-01"},
+		"too long":      {"from": "2024-01-01", "to": "2024-03-01"},
+		"open ended":    {"from": "2024-03-01"},
+		"in the future": {"from": "2024-05-01", "to": "2024-05-02"},
+	}
+	for name, query := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := newDateContext(t, "UTC", query).QueryDateRange("from", "to", rules)
+			var liftErr *LiftError
+			require.ErrorAs(t, err, &liftErr)
+			assert.Equal(t, 400, liftErr.StatusCode)
+		})
+	}
+
+	// Without MaxSpan or Required, an open range is allowed
+	r, err = newDateContext(t, "UTC", map[string]string{"from": "2024-03-01"}).QueryDateRange("from", "to", DateRangeRules{Now: now})
+	require.NoError(t, err)
+	assert.True(t, r.To.IsZero())
+
+	_, err = newDateContext(t, "UTC", nil).QueryDateRange("from", "to", DateRangeRules{Required: true})
+	assert.Error(t, err)
+}
+
+func TestDateJSON(t *testing.T) {
+	type settlement struct {
+		On   Date `json:"on"`
+		Next Date `json:"next"`
+	}
+
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+	// 03:00 UTC on March 2nd is still March 1st in Chicago
+	on := DateOf(time.Date(2024, 3, 2, 3, 0, 0, 0, time.UTC).In(chicago))
+
+	data, err := json.Marshal(settlement{On: on})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"on":"2024-03-01","next":null}`, string(data))
+
+	var decoded settlement
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, on, decoded.On)
+	assert.True(t, decoded.Next.IsZero())
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, chicago), decoded.On.In(chicago))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"on":"2024-13-01"}`), &decoded))
+}
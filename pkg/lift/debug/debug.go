@@ -0,0 +1,157 @@
+// Package debug adds endpoints for diagnosing a deployed application:
+// pprof profiles, a sanitized configuration dump, the route table and
+// connection pool statistics. They are opt-in, require authentication and
+// are only available outside production unless enabled there:
+//
+//	debug.Register(app, debug.Config{
+//		Auth:   []lift.Middleware{middleware.RequireIAM("arn:aws:sts::123456789012:assumed-role/oncall/*")},
+//		Config: settings,
+//		Pools:  map[string]resources.ConnectionPool{"postgres": pool},
+//	})
+//
+// The endpoints, under Prefix (default /debug):
+//
+//	GET /debug/config           application and runtime configuration
+//	GET /debug/routes           registered routes and their metadata
+//	GET /debug/stats            pool, runtime and custom statistics
+//	GET /debug/pprof            available profiles
+//	GET /debug/pprof/profile    CPU profile (?seconds=5)
+//	GET /debug/pprof/trace      execution trace (?seconds=1)
+//	GET /debug/pprof/:name      heap, goroutine, allocs, block, mutex... (?debug=1 for text)
+//
+// EnableRemoteProfiling instead profiles the next invocations when asked
+// by a management event or SSM parameter, and uploads the profiles to S3.
+package debug
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/resources"
+)
+
+// Config configures the debug endpoints
+type Config struct {
+	// Prefix is the path the endpoints are registered under (default: /debug)
+	Prefix string
+	// Auth authenticates and authorizes callers, e.g. middleware.RequireIAM
+	// or middleware.JWT with middleware.RequireRole (required)
+	Auth []lift.Middleware
+	// Stages the endpoints are available in; elsewhere they answer 404
+	// (default: lift.Dev|lift.Staging)
+	Stages lift.Stage
+
+	// Config is the application's own configuration, dumped by /config
+	// with secrets redacted
+	Config any
+	// Pools are the connection pools reported by /stats
+	Pools map[string]resources.ConnectionPool
+	// Stats are other statistics reported by /stats, such as
+	// dynamorm.DAXRouter.Stats
+	Stats map[string]func() any
+
+	// MaxProfileDuration caps CPU profiles and traces (default: 30 seconds)
+	MaxProfileDuration time.Duration
+}
+
+// Register adds the debug endpoints to the application and returns their
+// group
+func Register(app *lift.App, config Config) (*lift.RouteGroup, error) {
+	if len(config.Auth) == 0 {
+		return nil, fmt.Errorf("debug endpoints require authentication")
+	}
+	if config.Prefix == "" {
+		config.Prefix = "/debug"
+	}
+	if config.Stages == 0 {
+		config.Stages = lift.Dev | lift.Staging
+	}
+	if config.MaxProfileDuration == 0 {
+		config.MaxProfileDuration = 30 * time.Second
+	}
+
+	// The stage check comes first so production answers 404 without
+	// revealing that authentication is needed
+	middleware := append([]lift.Middleware{lift.AvailableIn(config.Stages)}, config.Auth...)
+	group := app.Group(config.Prefix, middleware...)
+
+	endpoints := &endpoints{app: app, config: config}
+	group.GET("/config", endpoints.configHandler)
+	group.GET("/routes", endpoints.routesHandler)
+	group.GET("/stats", endpoints.statsHandler)
+	group.GET("/pprof", endpoints.profilesHandler)
+	group.GET("/pprof/:name", endpoints.profileHandler)
+	return group, nil
+}
+
+type endpoints struct {
+	app    *lift.App
+	config Config
+}
+
+// configHandler dumps the configuration with secrets redacted
+func (e *endpoints) configHandler(ctx *lift.Context) error {
+	environment := e.app.Environment()
+	appConfig := e.app.Config()
+	dump := map[string]any{
+		"environment": map[string]any{
+			"stage": environment.Stage.String(),
+			"name":  environment.Name,
+		},
+		"app": Sanitize(appConfig),
+		"runtime": map[string]any{
+			"go_version":       runtime.Version(),
+			"gomaxprocs":       runtime.GOMAXPROCS(0),
+			"function_name":    os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+			"function_version": os.Getenv("AWS_LAMBDA_FUNCTION_VERSION"),
+			"memory_mb":        os.Getenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE"),
+			"region":           os.Getenv("AWS_REGION"),
+		},
+	}
+	if e.config.Config != nil {
+		dump["config"] = Sanitize(e.config.Config)
+	}
+	return ctx.OK(dump)
+}
+
+// routesHandler lists the registered routes
+func (e *endpoints) routesHandler(ctx *lift.Context) error {
+	routes := e.app.Routes()
+	infos := make([]lift.RouteInfo, len(routes))
+	for i, route := range routes {
+		infos[i] = route.Info()
+	}
+	return ctx.OK(map[string]any{"routes": infos, "count": len(infos)})
+}
+
+// statsHandler reports pool, runtime and custom statistics
+func (e *endpoints) statsHandler(ctx *lift.Context) error {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	pools := make(map[string]resources.PoolStats, len(e.config.Pools))
+	for name, pool := range e.config.Pools {
+		pools[name] = pool.Stats()
+	}
+	stats := make(map[string]any, len(e.config.Stats))
+	for name, stat := range e.config.Stats {
+		stats[name] = stat()
+	}
+
+	return ctx.OK(map[string]any{
+		"pools": pools,
+		"stats": stats,
+		"runtime": map[string]any{
+			"goroutines":     runtime.NumGoroutine(),
+			"heap_alloc":     memory.HeapAlloc,
+			"heap_inuse":     memory.HeapInuse,
+			"heap_objects":   memory.HeapObjects,
+			"sys":            memory.Sys,
+			"num_gc":         memory.NumGC,
+			"pause_total_ns": memory.PauseTotalNs,
+		},
+	})
+}
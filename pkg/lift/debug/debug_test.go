@@ -0,0 +1,133 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/resources"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type settings struct {
+	TableName   string `json:"table_name"`
+	DatabaseURL string `json:"database_url"`
+	APIKey      string `json:"api_key"`
+	Nested      struct {
+		SigningSecret string `json:"signing_secret"`
+	} `json:"nested"`
+}
+
+type fakePool struct{ resources.ConnectionPool }
+
+func (fakePool) Stats() resources.PoolStats { return resources.PoolStats{Active: 2, Idle: 3} }
+
+func newDebugApp(t *testing.T, stage lift.Stage) *lift.App {
+	t.Helper()
+	app := lift.New(lift.WithStage(stage))
+	app.GET("/orders/:id", func(ctx *lift.Context) error { return nil })
+
+	config := settings{TableName: "orders", DatabaseURL: "postgres://app:hunter2@db:5432/orders", APIKey: "sk_live_1"}
+	config.Nested.SigningSecret = "shh"
+	_, err := Register(app, Config{
+		Auth:   []lift.Middleware{middleware.RequireIAM("arn:aws:sts::123456789012:assumed-role/oncall/*")},
+		Config: config,
+		Pools:  map[string]resources.ConnectionPool{"postgres": fakePool{}},
+		Stats:  map[string]func() any{"cache": func() any { return map[string]int{"hits": 7} }},
+	})
+	require.NoError(t, err)
+	return app
+}
+
+func get(t *testing.T, app *lift.App, path, arn string) *lift.Response {
+	t.Helper()
+	return getQuery(t, app, path, nil, arn)
+}
+
+func getQuery(t *testing.T, app *lift.App, path string, query map[string]any, arn string) *lift.Response {
+	t.Helper()
+	identity := map[string]any{}
+	if arn != "" {
+		identity["userArn"] = arn
+	}
+	resp, err := app.HandleRequest(context.Background(), map[string]any{
+		"resource":              path,
+		"httpMethod":            "GET",
+		"path":                  path,
+		"requestContext":        map[string]any{"requestId": "req-1", "identity": identity},
+		"queryStringParameters": query,
+	})
+	require.NoError(t, err)
+	return resp.(*lift.Response)
+}
+
+func decode(t *testing.T, resp *lift.Response) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(resp.Body)
+	require.NoError(t, err)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(data, &body))
+	return body
+}
+
+const oncall = "arn:aws:sts::123456789012:assumed-role/oncall/ada"
+
+func TestRegisterRequiresAuth(t *testing.T) {
+	_, err := Register(lift.New(), Config{})
+	assert.Error(t, err)
+}
+
+func TestDebugEndpointsAccess(t *testing.T) {
+	app := newDebugApp(t, lift.Staging)
+	assert.Equal(t, 401, get(t, app, "/debug/routes", "").StatusCode)
+	assert.Equal(t, 403, get(t, app, "/debug/routes", "arn:aws:iam::123456789012:user/mallory").StatusCode)
+	assert.Equal(t, 200, get(t, app, "/debug/routes", oncall).StatusCode)
+
+	// Production hides them, even from allowed callers
+	prod := newDebugApp(t, lift.Prod)
+	assert.Equal(t, 404, get(t, prod, "/debug/routes", oncall).StatusCode)
+	assert.Equal(t, 404, get(t, prod, "/debug/routes", "").StatusCode)
+}
+
+func TestConfigDump(t *testing.T) {
+	body := decode(t, get(t, newDebugApp(t, lift.Dev), "/debug/config", oncall))
+	config := body["config"].(map[string]any)
+	assert.Equal(t, "orders", config["table_name"])
+	assert.Equal(t, "postgres://app:xxxxx@db:5432/orders", config["database_url"])
+	assert.Equal(t, redacted, config["api_key"])
+	assert.Equal(t, redacted, config["nested"].(map[string]any)["signing_secret"])
+	assert.Equal(t, "dev", body["environment"].(map[string]any)["stage"])
+}
+
+func TestRoutesAndStats(t *testing.T) {
+	app := newDebugApp(t, lift.Dev)
+	routes := decode(t, get(t, app, "/debug/routes", oncall))
+	assert.Contains(t, routes["routes"], map[string]any{"method": "GET", "path": "/orders/:id"})
+
+	stats := decode(t, get(t, app, "/debug/stats", oncall))
+	assert.Equal(t, float64(2), stats["pools"].(map[string]any)["postgres"].(map[string]any)["active"])
+	assert.Equal(t, map[string]any{"hits": float64(7)}, stats["stats"].(map[string]any)["cache"])
+	assert.Positive(t, stats["runtime"].(map[string]any)["goroutines"])
+}
+
+func TestProfiles(t *testing.T) {
+	app := newDebugApp(t, lift.Dev)
+
+	resp := get(t, app, "/debug/pprof/goroutine", oncall)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.True(t, resp.IsBase64Encoded)
+	data := resp.Body.([]byte)
+	// pprof profiles are gzipped protobufs
+	assert.Equal(t, []byte{0x1f, 0x8b}, data[:2])
+
+	assert.Equal(t, 404, get(t, app, "/debug/pprof/nope", oncall).StatusCode)
+
+	start := time.Now()
+	resp = getQuery(t, app, "/debug/pprof/profile", map[string]any{"seconds": "0.1"}, oncall)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,107 @@
+package debug
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// profilesHandler lists the available profiles
+func (e *endpoints) profilesHandler(ctx *lift.Context) error {
+	profiles := pprof.Profiles()
+	list := make([]map[string]any, 0, len(profiles)+2)
+	for _, profile := range profiles {
+		list = append(list, map[string]any{"name": profile.Name(), "count": profile.Count()})
+	}
+	list = append(list,
+		map[string]any{"name": "profile", "description": "CPU profile"},
+		map[string]any{"name": "trace", "description": "execution trace"},
+	)
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["name"].(string) < list[j]["name"].(string)
+	})
+	return ctx.OK(map[string]any{"profiles": list})
+}
+
+// profileHandler writes a profile in pprof format, or as text with
+// ?debug=1
+func (e *endpoints) profileHandler(ctx *lift.Context) error {
+	name := ctx.Param("name")
+	switch name {
+	case "profile":
+		return e.timedProfile(ctx, 5*time.Second, func(buf *bytes.Buffer) error {
+			return pprof.StartCPUProfile(buf)
+		}, pprof.StopCPUProfile)
+	case "trace":
+		return e.timedProfile(ctx, time.Second, func(buf *bytes.Buffer) error {
+			return trace.Start(buf)
+		}, trace.Stop)
+	}
+
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		return lift.NotFound("Unknown profile: " + name)
+	}
+	if name == "heap" && ctx.Query("gc") != "" {
+		runtime.GC()
+	}
+	debug, _ := strconv.Atoi(ctx.Query("debug"))
+
+	var buf bytes.Buffer
+	if err := profile.WriteTo(&buf, debug); err != nil {
+		return lift.NewLiftError("PROFILE_FAILED", "Failed to write profile", 500).WithCause(err)
+	}
+	if debug > 0 {
+		return ctx.Text(buf.String())
+	}
+	return writeProfile(ctx, name, buf.Bytes())
+}
+
+// timedProfile records a profile for ?seconds, bounded by
+// MaxProfileDuration and the time left before the invocation times out
+func (e *endpoints) timedProfile(ctx *lift.Context, fallback time.Duration, start func(*bytes.Buffer) error, stop func()) error {
+	duration := fallback
+	if seconds := ctx.Query("seconds"); seconds != "" {
+		parsed, err := strconv.ParseFloat(seconds, 64)
+		if err != nil || parsed <= 0 {
+			return lift.ParameterError("seconds", "seconds must be a positive number")
+		}
+		duration = time.Duration(parsed * float64(time.Second))
+	}
+	if duration > e.config.MaxProfileDuration {
+		duration = e.config.MaxProfileDuration
+	}
+	// Leave time to respond before the Lambda deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline) - time.Second; remaining < duration {
+			duration = remaining
+		}
+	}
+	if duration <= 0 {
+		return lift.NewLiftError("PROFILE_TIMEOUT", "Not enough time left to profile", 503)
+	}
+
+	var buf bytes.Buffer
+	if err := start(&buf); err != nil {
+		// Only one CPU profile or trace can run at a time
+		return lift.NewLiftError("PROFILE_IN_PROGRESS", "A profile is already being recorded", 409).WithCause(err)
+	}
+	select {
+	case <-time.After(duration):
+	case <-ctx.Done():
+	}
+	stop()
+	return writeProfile(ctx, ctx.Param("name"), buf.Bytes())
+}
+
+func writeProfile(ctx *lift.Context, name string, data []byte) error {
+	ctx.Response.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	ctx.Response.Header("X-Content-Type-Options", "nosniff")
+	return ctx.Response.Binary(data)
+}
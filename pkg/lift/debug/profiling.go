@@ -0,0 +1,373 @@
+package debug
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// ProfileRequest asks for the next invocations to be profiled. It is the
+// detail of a management event, or the value of an SSM parameter:
+//
+//	aws events put-events --entries '[{
+//		"Source": "lift.management",
+//		"DetailType": "Profile Invocations",
+//		"Detail": "{\"invocations\": 5, \"profiles\": [\"cpu\", \"heap\"]}"
+//	}]'
+type ProfileRequest struct {
+	// ID identifies the request; each is applied once per instance. Events
+	// default to the event ID and SSM parameters to their version, "v4".
+	ID string `json:"id,omitempty"`
+	// Invocations is how many invocations to profile, up to MaxInvocations
+	Invocations int `json:"invocations"`
+	// Profiles to capture: "cpu" or a runtime/pprof profile such as "heap",
+	// "allocs", "goroutine", "block" or "mutex" (default: cpu and heap)
+	Profiles []string `json:"profiles,omitempty"`
+	// ExpiresAt is when instances stop applying the request (default:
+	// RequestTTL after the event was sent, or 15 minutes after the SSM
+	// parameter changed)
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// ProfileTriggerSource loads the current profile request, or nil if there is
+// none
+type ProfileTriggerSource interface {
+	LoadProfileRequest(ctx context.Context) (*ProfileRequest, error)
+}
+
+// ProfileTriggerSourceFunc adapts a function to the ProfileTriggerSource
+// interface
+type ProfileTriggerSourceFunc func(ctx context.Context) (*ProfileRequest, error)
+
+// LoadProfileRequest calls f(ctx)
+func (f ProfileTriggerSourceFunc) LoadProfileRequest(ctx context.Context) (*ProfileRequest, error) {
+	return f(ctx)
+}
+
+// SSMParameterGetter is the subset of the SSM client used by
+// SSMProfileTriggerSource
+type SSMParameterGetter interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// SSMProfileTriggerSource reads a profile request as JSON from an SSM
+// parameter. Unlike management events, which reach the one instance they
+// invoke, a parameter is seen by every running instance.
+func SSMProfileTriggerSource(client SSMParameterGetter, parameter string) ProfileTriggerSource {
+	return ProfileTriggerSourceFunc(func(ctx context.Context) (*ProfileRequest, error) {
+		result, err := client.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(parameter)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get profile request from SSM parameter %s: %w", parameter, err)
+		}
+		if result.Parameter == nil || strings.TrimSpace(aws.ToString(result.Parameter.Value)) == "" {
+			return nil, nil
+		}
+
+		var request ProfileRequest
+		if err := json.Unmarshal([]byte(*result.Parameter.Value), &request); err != nil {
+			return nil, fmt.Errorf("invalid profile request in SSM parameter %s: %w", parameter, err)
+		}
+		if request.ID == "" {
+			request.ID = fmt.Sprintf("v%d", result.Parameter.Version)
+		}
+		if request.ExpiresAt.IsZero() && result.Parameter.LastModifiedDate != nil {
+			request.ExpiresAt = result.Parameter.LastModifiedDate.Add(defaultRequestTTL)
+		}
+		return &request, nil
+	})
+}
+
+const defaultRequestTTL = 15 * time.Minute
+
+// RemoteProfilingConfig configures remote profiling
+type RemoteProfilingConfig struct {
+	// Uploader stores the profiles, e.g. payload.S3FileUploader (required)
+	Uploader lift.FileUploader
+	// Bucket the profiles are stored in (required)
+	Bucket string
+	// Prefix of the profiles' keys (default: profiles). Keys are
+	// prefix/request ID/function/invocation request ID/profile.pprof.
+	Prefix string
+
+	// Source and DetailType of the management events that trigger
+	// profiling (default: lift.management and Profile Invocations)
+	Source     string
+	DetailType string
+	// Trigger is polled for profile requests, e.g. SSMProfileTriggerSource
+	Trigger ProfileTriggerSource
+	// RefreshInterval is how often Trigger is polled (default: 1 minute)
+	RefreshInterval time.Duration
+
+	// MaxInvocations caps the invocations one request profiles (default: 20)
+	MaxInvocations int
+	// RequestTTL is how long management events without ExpiresAt apply
+	// (default: 15 minutes)
+	RequestTTL time.Duration
+	// OnError is called when polling, profiling or uploading fails. Failures
+	// never fail the invocation being profiled.
+	OnError func(err error)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// RemoteProfiler profiles invocations on request, so production functions
+// can be profiled without redeploying them
+type RemoteProfiler struct {
+	config   RemoteProfilingConfig
+	function string
+
+	mu          sync.Mutex
+	remaining   int
+	profiles    []string
+	requestID   string
+	applied     map[string]bool
+	refreshedAt time.Time
+}
+
+// EnableRemoteProfiling profiles invocations when a management event or the
+// trigger source asks for it. The handler for management events and the
+// profiling middleware are added to the app; events routed with
+// App.EventBridge rather than EventBridgeMatch skip the app's middleware
+// and so are never profiled.
+//
+// Anyone who can put events on the function's bus can start profiling, and
+// profiles reveal code and data, so give the bucket the same protection as
+// the function's logs.
+func EnableRemoteProfiling(app *lift.App, config RemoteProfilingConfig) (*RemoteProfiler, error) {
+	if config.Uploader == nil || config.Bucket == "" {
+		return nil, fmt.Errorf("remote profiling requires an uploader and bucket")
+	}
+	if config.Prefix == "" {
+		config.Prefix = "profiles"
+	}
+	if config.Source == "" {
+		config.Source = "lift.management"
+	}
+	if config.DetailType == "" {
+		config.DetailType = "Profile Invocations"
+	}
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = time.Minute
+	}
+	if config.MaxInvocations == 0 {
+		config.MaxInvocations = 20
+	}
+	if config.RequestTTL == 0 {
+		config.RequestTTL = defaultRequestTTL
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	profiler := &RemoteProfiler{
+		config:   config,
+		function: os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+		applied:  make(map[string]bool),
+	}
+	if profiler.function == "" {
+		profiler.function = "local"
+	}
+	if err := app.EventBridgeMatch(config.DetailType, config.Source, profiler.handleEvent); err != nil {
+		return nil, err
+	}
+	app.Use(profiler.Middleware())
+	return profiler, nil
+}
+
+// Arm profiles the next invocations as the request asks. Requests that
+// have expired, or were already applied, are ignored.
+func (p *RemoteProfiler) Arm(request ProfileRequest) error {
+	if request.Invocations <= 0 {
+		return fmt.Errorf("profile request must ask for at least one invocation")
+	}
+	if len(request.Profiles) == 0 {
+		request.Profiles = []string{"cpu", "heap"}
+	}
+	for _, name := range request.Profiles {
+		if name != "cpu" && pprof.Lookup(name) == nil {
+			return fmt.Errorf("unknown profile: %s", name)
+		}
+	}
+	if request.Invocations > p.config.MaxInvocations {
+		request.Invocations = p.config.MaxInvocations
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if request.ID != "" {
+		if p.applied[request.ID] {
+			return nil
+		}
+		p.applied[request.ID] = true
+	}
+	if !request.ExpiresAt.IsZero() && !p.config.Now().Before(request.ExpiresAt) {
+		return nil
+	}
+	p.remaining = request.Invocations
+	p.profiles = request.Profiles
+	p.requestID = request.ID
+	if p.requestID == "" {
+		p.requestID = p.config.Now().UTC().Format("20060102T150405Z")
+	}
+	return nil
+}
+
+// Remaining returns how many invocations are still to be profiled
+func (p *RemoteProfiler) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.remaining
+}
+
+// handleEvent arms the profiler from a management event
+func (p *RemoteProfiler) handleEvent(ctx *lift.Context) error {
+	var request ProfileRequest
+	if err := json.Unmarshal(ctx.Request.Body, &request); err != nil {
+		return lift.NewLiftError("INVALID_PROFILE_REQUEST", "Invalid profile request", 400).WithCause(err)
+	}
+	if request.ID == "" {
+		request.ID = ctx.Request.EventID
+	}
+	if request.ExpiresAt.IsZero() {
+		if sent, err := time.Parse(time.RFC3339, ctx.Request.Timestamp); err == nil {
+			request.ExpiresAt = sent.Add(p.config.RequestTTL)
+		}
+	}
+	if err := p.Arm(request); err != nil {
+		return lift.NewLiftError("INVALID_PROFILE_REQUEST", err.Error(), 400)
+	}
+	if ctx.Logger != nil {
+		ctx.Logger.Info("Remote profiling armed", map[string]any{
+			"profile_request": request.ID,
+			"invocations":     p.Remaining(),
+		})
+	}
+	return nil
+}
+
+// Middleware profiles invocations while the profiler is armed. Profiles
+// are uploaded before the invocation returns, as the instance may be
+// frozen afterwards.
+func (p *RemoteProfiler) Middleware() lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			p.refreshIfStale(ctx.Context)
+
+			requestID, profiles, ok := p.take()
+			if !ok {
+				return next.Handle(ctx)
+			}
+
+			var cpu bytes.Buffer
+			cpuStarted := false
+			for _, name := range profiles {
+				if name != "cpu" {
+					continue
+				}
+				// Only one CPU profile can run at a time
+				if err := pprof.StartCPUProfile(&cpu); err != nil {
+					p.reportError(fmt.Errorf("failed to start CPU profile: %w", err))
+				} else {
+					cpuStarted = true
+				}
+			}
+
+			err := next.Handle(ctx)
+
+			if cpuStarted {
+				pprof.StopCPUProfile()
+			}
+			invocation := ctx.GetRequestID()
+			if invocation == "" {
+				invocation = p.config.Now().UTC().Format("20060102T150405.000000000Z")
+			}
+			prefix := strings.Join([]string{p.config.Prefix, requestID, p.function, invocation}, "/")
+			for _, name := range profiles {
+				var data bytes.Buffer
+				if name == "cpu" {
+					if !cpuStarted {
+						continue
+					}
+					data = cpu
+				} else if profileErr := pprof.Lookup(name).WriteTo(&data, 0); profileErr != nil {
+					p.reportError(fmt.Errorf("failed to write %s profile: %w", name, profileErr))
+					continue
+				}
+				p.upload(ctx.Context, prefix+"/"+name+".pprof", &data)
+			}
+			return err
+		})
+	}
+}
+
+// take claims one profiled invocation, if the profiler is armed
+func (p *RemoteProfiler) take() (string, []string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.remaining == 0 {
+		return "", nil, false
+	}
+	p.remaining--
+	return p.requestID, p.profiles, true
+}
+
+// refreshIfStale polls the trigger source once per RefreshInterval
+func (p *RemoteProfiler) refreshIfStale(ctx context.Context) {
+	if p.config.Trigger == nil {
+		return
+	}
+	p.mu.Lock()
+	now := p.config.Now()
+	if !p.refreshedAt.IsZero() && now.Sub(p.refreshedAt) < p.config.RefreshInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.refreshedAt = now
+	p.mu.Unlock()
+
+	request, err := p.config.Trigger.LoadProfileRequest(ctx)
+	if err != nil {
+		p.reportError(err)
+		return
+	}
+	if request == nil {
+		return
+	}
+	if request.ExpiresAt.IsZero() {
+		// Without a time to measure from, the request would apply to every
+		// new instance forever
+		p.reportError(fmt.Errorf("profile request %s has no expiry", request.ID))
+		return
+	}
+	if err := p.Arm(*request); err != nil {
+		p.reportError(err)
+	}
+}
+
+func (p *RemoteProfiler) upload(ctx context.Context, key string, data *bytes.Buffer) {
+	err := p.config.Uploader.UploadFile(ctx, &lift.FileUpload{
+		Bucket:      p.config.Bucket,
+		Key:         key,
+		Body:        data,
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		p.reportError(fmt.Errorf("failed to upload profile %s: %w", key, err))
+	}
+}
+
+func (p *RemoteProfiler) reportError(err error) {
+	if p.config.OnError != nil {
+		p.config.OnError(err)
+	}
+}
@@ -0,0 +1,219 @@
+package debug
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryUploader struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (u *memoryUploader) UploadFile(ctx context.Context, file *lift.FileUpload) error {
+	data, err := io.ReadAll(file.Body)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.files == nil {
+		u.files = map[string][]byte{}
+	}
+	u.files[file.Bucket+"/"+file.Key] = data
+	return nil
+}
+
+func (u *memoryUploader) keys() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	keys := make([]string, 0, len(u.files))
+	for key := range u.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type fakeSSM struct {
+	value    string
+	version  int64
+	modified time.Time
+	calls    int
+}
+
+func (f *fakeSSM) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	f.calls++
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{
+		Name:             params.Name,
+		Value:            aws.String(f.value),
+		Version:          f.version,
+		LastModifiedDate: aws.Time(f.modified),
+	}}, nil
+}
+
+var profilingNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func newProfiledApp(t *testing.T, config RemoteProfilingConfig) (*lift.App, *RemoteProfiler) {
+	t.Helper()
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "orders-api")
+	app := lift.New()
+	app.GET("/orders", func(ctx *lift.Context) error { return ctx.OK(map[string]any{"orders": []string{}}) })
+	config.Bucket = "diagnostics"
+	config.Now = func() time.Time { return profilingNow }
+	profiler, err := EnableRemoteProfiling(app, config)
+	require.NoError(t, err)
+	return app, profiler
+}
+
+func sendManagementEvent(t *testing.T, app *lift.App, id string, detail map[string]any) {
+	t.Helper()
+	_, err := app.HandleRequest(context.Background(), map[string]any{
+		"id":          id,
+		"source":      "lift.management",
+		"detail-type": "Profile Invocations",
+		"time":        profilingNow.Add(-time.Minute).Format(time.RFC3339),
+		"detail":      detail,
+	})
+	require.NoError(t, err)
+}
+
+func listOrders(t *testing.T, app *lift.App, requestID string) {
+	t.Helper()
+	resp, err := app.HandleRequest(lift.WithRequestID(context.Background(), requestID), map[string]any{
+		"resource":       "/orders",
+		"httpMethod":     "GET",
+		"path":           "/orders",
+		"requestContext": map[string]any{"requestId": requestID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.(*lift.Response).StatusCode)
+}
+
+func TestEnableRemoteProfilingRequiresUploader(t *testing.T) {
+	_, err := EnableRemoteProfiling(lift.New(), RemoteProfilingConfig{Bucket: "diagnostics"})
+	assert.Error(t, err)
+}
+
+func TestRemoteProfilingFromManagementEvent(t *testing.T) {
+	uploader := &memoryUploader{}
+	app, profiler := newProfiledApp(t, RemoteProfilingConfig{Uploader: uploader})
+
+	listOrders(t, app, "req-0")
+	assert.Empty(t, uploader.keys())
+
+	sendManagementEvent(t, app, "evt-1", map[string]any{"invocations": 2, "profiles": []string{"heap", "goroutine"}})
+	assert.Equal(t, 2, profiler.Remaining())
+
+	for _, requestID := range []string{"req-1", "req-2", "req-3"} {
+		listOrders(t, app, requestID)
+	}
+	assert.Equal(t, []string{
+		"diagnostics/profiles/evt-1/orders-api/req-1/goroutine.pprof",
+		"diagnostics/profiles/evt-1/orders-api/req-1/heap.pprof",
+		"diagnostics/profiles/evt-1/orders-api/req-2/goroutine.pprof",
+		"diagnostics/profiles/evt-1/orders-api/req-2/heap.pprof",
+	}, uploader.keys())
+	assert.Equal(t, 0, profiler.Remaining())
+
+	// Redelivered events are applied once
+	sendManagementEvent(t, app, "evt-1", map[string]any{"invocations": 2, "profiles": []string{"heap"}})
+	assert.Equal(t, 0, profiler.Remaining())
+}
+
+func TestRemoteProfilingCPU(t *testing.T) {
+	uploader := &memoryUploader{}
+	app, _ := newProfiledApp(t, RemoteProfilingConfig{Uploader: uploader})
+
+	sendManagementEvent(t, app, "evt-cpu", map[string]any{"invocations": 1})
+	listOrders(t, app, "req-1")
+	assert.Equal(t, []string{
+		"diagnostics/profiles/evt-cpu/orders-api/req-1/cpu.pprof",
+		"diagnostics/profiles/evt-cpu/orders-api/req-1/heap.pprof",
+	}, uploader.keys())
+}
+
+func TestRemoteProfilingLimits(t *testing.T) {
+	var errs []error
+	app, profiler := newProfiledApp(t, RemoteProfilingConfig{
+		Uploader:       &memoryUploader{},
+		MaxInvocations: 3,
+		OnError:        func(err error) { errs = append(errs, err) },
+	})
+
+	sendManagementEvent(t, app, "evt-many", map[string]any{"invocations": 100, "profiles": []string{"heap"}})
+	assert.Equal(t, 3, profiler.Remaining())
+
+	require.NoError(t, profiler.Arm(ProfileRequest{ID: "old", Invocations: 5, ExpiresAt: profilingNow.Add(-time.Second)}))
+	assert.Equal(t, 3, profiler.Remaining())
+
+	assert.Error(t, profiler.Arm(ProfileRequest{Invocations: 1, Profiles: []string{"secrets"}}))
+	assert.Error(t, profiler.Arm(ProfileRequest{Invocations: 0}))
+	assert.Empty(t, errs)
+}
+
+func TestRemoteProfilingFromSSM(t *testing.T) {
+	client := &fakeSSM{
+		value:    `{"invocations": 1, "profiles": ["heap"]}`,
+		version:  4,
+		modified: profilingNow.Add(-time.Minute),
+	}
+	uploader := &memoryUploader{}
+	app, profiler := newProfiledApp(t, RemoteProfilingConfig{
+		Uploader: uploader,
+		Trigger:  SSMProfileTriggerSource(client, "/orders-api/profile"),
+	})
+
+	listOrders(t, app, "req-1")
+	listOrders(t, app, "req-2")
+	assert.Equal(t, []string{"diagnostics/profiles/v4/orders-api/req-1/heap.pprof"}, uploader.keys())
+	assert.Equal(t, 1, client.calls, "polled once per refresh interval")
+	assert.Equal(t, 0, profiler.Remaining())
+}
+
+func TestSSMProfileTriggerSource(t *testing.T) {
+	request, err := SSMProfileTriggerSource(&fakeSSM{}, "/profile").LoadProfileRequest(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, request)
+
+	_, err = SSMProfileTriggerSource(&fakeSSM{value: "{"}, "/profile").LoadProfileRequest(context.Background())
+	assert.Error(t, err)
+
+	request, err = SSMProfileTriggerSource(&fakeSSM{
+		value:    `{"id": "incident-42", "invocations": 3}`,
+		modified: profilingNow,
+	}, "/profile").LoadProfileRequest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &ProfileRequest{ID: "incident-42", Invocations: 3, ExpiresAt: profilingNow.Add(15 * time.Minute)}, request)
+}
+
+func TestRemoteProfilingUploadFailures(t *testing.T) {
+	var errs []error
+	app, _ := newProfiledApp(t, RemoteProfilingConfig{
+		Uploader: uploaderFunc(func(ctx context.Context, file *lift.FileUpload) error { return errors.New("access denied") }),
+		OnError:  func(err error) { errs = append(errs, err) },
+	})
+
+	sendManagementEvent(t, app, "evt-1", map[string]any{"invocations": 1, "profiles": []string{"heap"}})
+	listOrders(t, app, "req-1")
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "access denied")
+}
+
+type uploaderFunc func(ctx context.Context, file *lift.FileUpload) error
+
+func (f uploaderFunc) UploadFile(ctx context.Context, file *lift.FileUpload) error {
+	return f(ctx, file)
+}
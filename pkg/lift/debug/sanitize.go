@@ -0,0 +1,81 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces secret values
+const redacted = "[REDACTED]"
+
+// secretKeys are substrings of the names of fields that hold secrets
+var secretKeys = []string{
+	"password", "passwd", "secret", "token", "key", "credential",
+	"private", "auth", "dsn", "signature", "cookie", "session",
+}
+
+// Sanitize returns a copy of v, as it would be encoded to JSON, with the
+// values of secret-looking fields redacted and passwords removed from URLs.
+// Values that can't be encoded are replaced by the error.
+func Sanitize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	return sanitizeValue(decoded)
+}
+
+func sanitizeValue(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if isSecretKey(key) && field != nil && field != "" {
+				value[key] = redacted
+				continue
+			}
+			value[key] = sanitizeValue(field)
+		}
+		return value
+	case []any:
+		for i, item := range value {
+			value[i] = sanitizeValue(item)
+		}
+		return value
+	case string:
+		return redactURLPassword(value)
+	default:
+		return value
+	}
+}
+
+func isSecretKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(lower, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURLPassword removes the password from URLs such as database
+// connection strings
+func redactURLPassword(s string) string {
+	if !strings.Contains(s, "://") || !strings.Contains(s, "@") {
+		return s
+	}
+	parsed, err := url.Parse(s)
+	if err != nil || parsed.User == nil {
+		return s
+	}
+	if _, ok := parsed.User.Password(); !ok {
+		return s
+	}
+	parsed.User = url.UserPassword(parsed.User.Username(), "xxxxx")
+	return parsed.String()
+}
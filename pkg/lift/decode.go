@@ -0,0 +1,501 @@
+package lift
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// DecodeMode controls how request bodies are decoded by ctx.ParseRequest
+// and typed handlers
+type DecodeMode int
+
+const (
+	// DecodeDefault decodes like encoding/json: unknown fields are
+	// ignored and fields of the wrong type fail the request
+	DecodeDefault DecodeMode = iota
+	// DecodeLenient skips unknown fields and fields of the wrong type,
+	// recording a DecodeWarning for each instead of failing, so old
+	// clients keep working while they migrate. Malformed JSON still fails.
+	DecodeLenient
+	// DecodeStrict rejects unknown fields, bodies sent without a JSON
+	// Content-Type and bodies nested deeper than the maximum JSON depth,
+	// with errors that say what was wrong
+	DecodeStrict
+)
+
+// DefaultMaxJSONDepth is how deeply strict decoding lets objects and
+// arrays nest
+const DefaultMaxJSONDepth = 32
+
+// ParseDecodeMode returns the decode mode a name such as "lenient" or
+// "strict" refers to, for reading it from configuration. Unrecognised
+// names are DecodeDefault.
+func ParseDecodeMode(name string) DecodeMode {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "lenient":
+		return DecodeLenient
+	case "strict":
+		return DecodeStrict
+	}
+	return DecodeDefault
+}
+
+// String returns the mode's name
+func (m DecodeMode) String() string {
+	switch m {
+	case DecodeLenient:
+		return "lenient"
+	case DecodeStrict:
+		return "strict"
+	}
+	return "default"
+}
+
+// MetaDecode overrides the app's decode mode for a route: "default",
+// "lenient" or "strict"
+const MetaDecode = "decode"
+
+// DecodeWarningMetric counts fields skipped by lenient decoding, by route
+// and kind
+const DecodeWarningMetric = "request.decode_warning"
+
+// Decode warning kinds
+const (
+	WarningUnknownField = "unknown_field"
+	WarningTypeMismatch = "type_mismatch"
+)
+
+// DecodeWarning describes a field lenient decoding skipped
+type DecodeWarning struct {
+	// Field is the field's path in the body, e.g. "items[2].qty"
+	Field string `json:"field"`
+	// Kind is WarningUnknownField or WarningTypeMismatch
+	Kind string `json:"kind"`
+	// Expected is the Go type of the field, for type mismatches
+	Expected string `json:"expected,omitempty"`
+	// Got is the JSON type that was sent, for type mismatches
+	Got string `json:"got,omitempty"`
+}
+
+const decodeWarningsKey = "decode_warnings"
+
+// WithDecodeMode sets how request bodies are decoded; routes can override
+// it with MetaDecode
+func WithDecodeMode(mode DecodeMode) AppOption {
+	return func(app *App) {
+		app.decodeMode = mode
+	}
+}
+
+// WithDecodeModeIn sets how request bodies are decoded in the given
+// stages, overriding WithDecodeMode there:
+//
+//	app := lift.New(
+//		lift.WithDecodeModeIn(lift.Prod, lift.DecodeStrict),
+//		lift.WithDecodeModeIn(lift.Dev|lift.Staging, lift.DecodeLenient),
+//	)
+func WithDecodeModeIn(stages Stage, mode DecodeMode) AppOption {
+	return func(app *App) {
+		app.stageDecodeModes = append(app.stageDecodeModes, stageDecodeMode{stages: stages, mode: mode})
+	}
+}
+
+// WithMaxJSONDepth sets how deeply strict decoding lets objects and arrays
+// nest (default: DefaultMaxJSONDepth)
+func WithMaxJSONDepth(depth int) AppOption {
+	return func(app *App) {
+		app.maxJSONDepth = depth
+	}
+}
+
+type stageDecodeMode struct {
+	stages Stage
+	mode   DecodeMode
+}
+
+// decodeModeIn returns the app's decode mode in an environment
+func (a *App) decodeModeIn(environment Environment) DecodeMode {
+	mode := a.decodeMode
+	for _, staged := range a.stageDecodeModes {
+		if environment.In(staged.stages) {
+			mode = staged.mode
+		}
+	}
+	return mode
+}
+
+// SetDecodeMode sets how request bodies are decoded for this request
+func (c *Context) SetDecodeMode(mode DecodeMode) {
+	c.decodeMode = mode
+}
+
+// SetMaxJSONDepth sets how deeply strict decoding lets objects and arrays
+// nest for this request
+func (c *Context) SetMaxJSONDepth(depth int) {
+	c.maxJSONDepth = depth
+}
+
+// Decode decodes the request body into v in the route's decode mode, like
+// ParseRequest but without validating it, for middleware that validates
+// bodies itself. An empty body leaves v untouched.
+func (c *Context) Decode(v any) error {
+	if c.Request == nil || len(c.Request.Body) == 0 {
+		return nil
+	}
+	return c.decodeBody(v)
+}
+
+// DecodeWarnings returns the fields lenient decoding skipped, so handlers
+// can report them to clients
+func (c *Context) DecodeWarnings() []DecodeWarning {
+	warnings, _ := c.Get(decodeWarningsKey).([]DecodeWarning)
+	return warnings
+}
+
+// effectiveDecodeMode returns the route's decode mode, or the request's
+func (c *Context) effectiveDecodeMode() DecodeMode {
+	switch value, _ := c.RouteMeta(MetaDecode); value {
+	case "default":
+		return DecodeDefault
+	case "lenient":
+		return DecodeLenient
+	case "strict":
+		return DecodeStrict
+	}
+	return c.decodeMode
+}
+
+// decodeBody decodes the request body into v in the effective decode mode
+func (c *Context) decodeBody(v any) error {
+	switch c.effectiveDecodeMode() {
+	case DecodeLenient:
+		return c.decodeLenient(v)
+	case DecodeStrict:
+		return c.decodeStrict(v)
+	}
+	if err := json.Unmarshal(c.Request.Body, v); err != nil {
+		return NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+	}
+	return nil
+}
+
+// decodeLenient decodes the body, skipping fields that don't fit v
+func (c *Context) decodeLenient(v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(c.Request.Body))
+	decoder.UseNumber()
+	var tree any
+	if err := decoder.Decode(&tree); err != nil {
+		return NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+	}
+
+	var warnings []DecodeWarning
+	tree, _ = lenientClean(tree, reflect.TypeOf(v), "", &warnings)
+	cleaned, err := json.Marshal(tree)
+	if err != nil {
+		return NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+	}
+	if err := json.Unmarshal(cleaned, v); err != nil {
+		return NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+	}
+
+	if len(warnings) > 0 {
+		c.Set(decodeWarningsKey, append(c.DecodeWarnings(), warnings...))
+		c.recordDecodeWarnings(warnings)
+	}
+	return nil
+}
+
+// decodeStrict decodes a JSON body that must match v exactly
+func (c *Context) decodeStrict(v any) error {
+	contentType := c.Request.GetHeader("Content-Type")
+	if contentType == "" {
+		return NewLiftError("CONTENT_TYPE_REQUIRED", "Content-Type header is required", 400)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return NewLiftError("UNSUPPORTED_MEDIA_TYPE", "Request body must be JSON", 415).
+			WithDetail("content_type", contentType)
+	}
+
+	maxDepth := c.maxJSONDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxJSONDepth
+	}
+	if err := checkJSONDepth(c.Request.Body, maxDepth); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(c.Request.Body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return strictDecodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return NewLiftError("INVALID_JSON", "Request body must contain a single JSON value", 400)
+	}
+	return nil
+}
+
+// checkJSONDepth fails if objects and arrays in body nest deeper than
+// maxDepth
+func checkJSONDepth(body []byte, maxDepth int) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			if depth++; depth > maxDepth {
+				return NewLiftError("JSON_TOO_DEEP", fmt.Sprintf("Request body is nested deeper than %d levels", maxDepth), 400).
+					WithDetail("max_depth", maxDepth)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// strictDecodeError describes why a body didn't decode in strict mode
+func strictDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return NewLiftError("INVALID_FIELD_TYPE", fmt.Sprintf("Field %q must be %s, not %s", typeErr.Field, typeErr.Type, typeErr.Value), 400).
+			WithCause(err).
+			WithDetail("field", typeErr.Field).
+			WithDetail("expected", typeErr.Type.String()).
+			WithDetail("got", typeErr.Value)
+	}
+	// encoding/json reports unknown fields only in the error message
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return NewLiftError("UNKNOWN_FIELD", fmt.Sprintf("Unknown field %q", field), 400).
+			WithCause(err).
+			WithDetail("field", field)
+	}
+	return NewLiftError("INVALID_JSON", "Invalid JSON in request body", 400).WithCause(err)
+}
+
+// recordDecodeWarnings logs and counts skipped fields
+func (c *Context) recordDecodeWarnings(warnings []DecodeWarning) {
+	route := c.route
+	if c.Request != nil {
+		route = c.Request.Method + " " + route
+	}
+	if c.Metrics != nil {
+		for _, warning := range warnings {
+			c.Metrics.Counter(DecodeWarningMetric, map[string]string{"route": route, "kind": warning.Kind}).Inc()
+		}
+	}
+	if c.Logger != nil {
+		c.Logger.Warn("Request body decoded with warnings", map[string]any{
+			"route":    route,
+			"warnings": warnings,
+		})
+	}
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// lenientClean removes the parts of a decoded JSON value that don't fit
+// type t, recording a warning for each. It reports false if the value
+// itself doesn't fit.
+func lenientClean(value any, t reflect.Type, path string, warnings *[]DecodeWarning) (any, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if value == nil || t.Kind() == reflect.Interface {
+		return value, true
+	}
+
+	mismatch := func() (any, bool) {
+		*warnings = append(*warnings, DecodeWarning{
+			Field:    strings.TrimPrefix(path, "."),
+			Kind:     WarningTypeMismatch,
+			Expected: t.String(),
+			Got:      jsonType(value),
+		})
+		return nil, false
+	}
+
+	// Types that decode themselves are checked by decoding
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		if !decodes(value, t) {
+			return mismatch()
+		}
+		return value, true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		fields := jsonFields(t)
+		for _, key := range slices.Sorted(maps.Keys(object)) {
+			field := object[key]
+			info, ok := fields.lookup(key)
+			if !ok {
+				*warnings = append(*warnings, DecodeWarning{Field: strings.TrimPrefix(path+"."+key, "."), Kind: WarningUnknownField})
+				delete(object, key)
+				continue
+			}
+			if info.quoted {
+				continue
+			}
+			if cleaned, ok := lenientClean(field, info.typ, path+"."+key, warnings); ok {
+				object[key] = cleaned
+			} else {
+				delete(object, key)
+			}
+		}
+		return object, true
+
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		if t.Key().Kind() != reflect.String {
+			if !decodes(value, t) {
+				return mismatch()
+			}
+			return value, true
+		}
+		for _, key := range slices.Sorted(maps.Keys(object)) {
+			element := object[key]
+			if cleaned, ok := lenientClean(element, t.Elem(), path+"."+key, warnings); ok {
+				object[key] = cleaned
+			} else {
+				delete(object, key)
+			}
+		}
+		return object, true
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is sent base64 encoded
+			if !decodes(value, t) {
+				return mismatch()
+			}
+			return value, true
+		}
+		array, ok := value.([]any)
+		if !ok {
+			return mismatch()
+		}
+		kept := array[:0]
+		for n, element := range array {
+			if cleaned, ok := lenientClean(element, t.Elem(), fmt.Sprintf("%s[%d]", path, n), warnings); ok {
+				kept = append(kept, cleaned)
+			}
+		}
+		return kept, true
+	}
+
+	if !decodes(value, t) {
+		return mismatch()
+	}
+	return value, true
+}
+
+// decodes reports whether a decoded JSON value decodes into type t
+func decodes(value any, t reflect.Type) bool {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(raw, reflect.New(t).Interface()) == nil
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case json.Number, float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
+
+type jsonField struct {
+	typ reflect.Type
+	// quoted fields use the ",string" option
+	quoted bool
+}
+
+// jsonFieldSet holds a struct's JSON fields by name
+type jsonFieldSet map[string]jsonField
+
+// lookup finds a field like encoding/json: by exact name, then ignoring case
+func (s jsonFieldSet) lookup(key string) (jsonField, bool) {
+	if field, ok := s[key]; ok {
+		return field, true
+	}
+	for name, field := range s {
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// jsonFields returns the fields encoding/json decodes into a struct,
+// including those promoted from embedded structs
+func jsonFields(t reflect.Type) jsonFieldSet {
+	fields := jsonFieldSet{}
+	for n := 0; n < t.NumField(); n++ {
+		field := t.Field(n)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+			for promoted, info := range jsonFields(embedded) {
+				if _, ok := fields[promoted]; !ok {
+					fields[promoted] = info
+				}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = jsonField{typ: field.Type, quoted: strings.Contains(options, "string")}
+	}
+	return fields
+}
@@ -0,0 +1,176 @@
+package lift
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type decodeItem struct {
+	SKU string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+type decodeAudit struct {
+	Source string `json:"source"`
+}
+
+type decodeOrder struct {
+	decodeAudit
+	Amount    int            `json:"amount"`
+	Currency  string         `json:"currency"`
+	Items     []decodeItem   `json:"items"`
+	Limits    map[string]int `json:"limits"`
+	CreatedAt time.Time      `json:"created_at"`
+	Reference int64          `json:"reference,string"`
+}
+
+func newDecodeContext(path, body string) *Context {
+	return NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method: "POST",
+		Path:   path,
+		Body:   []byte(body),
+	}))
+}
+
+func TestLenientDecode(t *testing.T) {
+	app := New(WithDecodeMode(DecodeLenient))
+	handler := SimpleHandler(func(ctx *Context, req decodeOrder) (map[string]any, error) {
+		return map[string]any{"order": req, "warnings": ctx.DecodeWarnings()}, nil
+	})
+	app.POST("/orders", handler)
+	app.POST("/strict-orders", handler).Meta(MetaDecode, "default")
+
+	body := `{
+		"amount": "12",
+		"currency": "EUR",
+		"legacy_id": 7,
+		"source": "api",
+		"items": [{"sku": "a", "qty": 1}, {"sku": "b", "qty": "two", "colour": "red"}],
+		"limits": {"daily": 5, "weekly": "lots"},
+		"created_at": "yesterday",
+		"reference": "9007199254740993"
+	}`
+
+	metrics := &countingMetrics{}
+	ctx := newDecodeContext("/orders", body)
+	ctx.Metrics = metrics
+	require.NoError(t, app.HandleTestRequest(ctx))
+	require.Equal(t, 200, ctx.Response.StatusCode)
+
+	result := ctx.Response.Body.(map[string]any)
+	assert.Equal(t, decodeOrder{
+		decodeAudit: decodeAudit{Source: "api"},
+		Currency:    "EUR",
+		Items:       []decodeItem{{SKU: "a", Qty: 1}, {SKU: "b"}},
+		Limits:      map[string]int{"daily": 5},
+		Reference:   9007199254740993,
+	}, result["order"])
+	assert.ElementsMatch(t, []DecodeWarning{
+		{Field: "amount", Kind: WarningTypeMismatch, Expected: "int", Got: "string"},
+		{Field: "legacy_id", Kind: WarningUnknownField},
+		{Field: "items[1].qty", Kind: WarningTypeMismatch, Expected: "int", Got: "string"},
+		{Field: "items[1].colour", Kind: WarningUnknownField},
+		{Field: "limits.weekly", Kind: WarningTypeMismatch, Expected: "int", Got: "string"},
+		{Field: "created_at", Kind: WarningTypeMismatch, Expected: "time.Time", Got: "string"},
+	}, result["warnings"])
+	assert.Equal(t, 6, metrics.counts[DecodeWarningMetric])
+
+	// Malformed JSON still fails
+	ctx = newDecodeContext("/orders", `{"amount": `)
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 400, ctx.Response.StatusCode)
+
+	// Routes can opt out
+	ctx = newDecodeContext("/strict-orders", body)
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 400, ctx.Response.StatusCode)
+}
+
+func TestLenientDecodeParseRequest(t *testing.T) {
+	app := New()
+	app.POST("/items", func(ctx *Context) error {
+		var item decodeItem
+		if err := ctx.ParseRequest(&item); err != nil {
+			return err
+		}
+		return ctx.OK(map[string]any{"item": item, "warnings": ctx.DecodeWarnings()})
+	}).Meta(MetaDecode, "lenient")
+	app.POST("/default", func(ctx *Context) error {
+		var item decodeItem
+		if err := ctx.ParseRequest(&item); err != nil {
+			return err
+		}
+		return ctx.OK(item)
+	})
+
+	ctx := newDecodeContext("/items", `{"sku": "a", "qty": 1.5}`)
+	require.NoError(t, app.HandleTestRequest(ctx))
+	require.Equal(t, 200, ctx.Response.StatusCode)
+	result := ctx.Response.Body.(map[string]any)
+	assert.Equal(t, decodeItem{SKU: "a"}, result["item"])
+	assert.Equal(t, []DecodeWarning{{Field: "qty", Kind: WarningTypeMismatch, Expected: "int", Got: "number"}}, result["warnings"])
+
+	ctx = newDecodeContext("/default", `{"sku": "a", "qty": 1.5}`)
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 400, ctx.Response.StatusCode)
+}
+
+func TestStrictDecode(t *testing.T) {
+	options := []AppOption{
+		WithDecodeModeIn(Prod, DecodeStrict),
+		WithDecodeModeIn(Dev, DecodeLenient),
+		WithMaxJSONDepth(3),
+	}
+	handler := SimpleHandler(func(ctx *Context, req decodeItem) (decodeItem, error) {
+		return req, nil
+	})
+	prod := New(append(options, WithStage(Prod))...)
+	prod.POST("/items", handler)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{name: "valid", contentType: "application/json", body: `{"sku": "a", "qty": 1}`, status: 200},
+		{name: "JSON media type", contentType: "application/vnd.api+json; charset=utf-8", body: `{"sku": "a"}`, status: 200},
+		{name: "no content type", body: `{"sku": "a"}`, status: 400, code: "CONTENT_TYPE_REQUIRED"},
+		{name: "not JSON", contentType: "text/plain", body: `{"sku": "a"}`, status: 415, code: "UNSUPPORTED_MEDIA_TYPE"},
+		{name: "unknown field", contentType: "application/json", body: `{"sku": "a", "colour": "red"}`, status: 400, code: "UNKNOWN_FIELD"},
+		{name: "wrong type", contentType: "application/json", body: `{"qty": "two"}`, status: 400, code: "INVALID_FIELD_TYPE"},
+		{name: "too deep", contentType: "application/json", body: `{"sku": [[[1]]]}`, status: 400, code: "JSON_TOO_DEEP"},
+		{name: "trailing value", contentType: "application/json", body: `{"sku": "a"} {"sku": "b"}`, status: 400, code: "INVALID_JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newDecodeContext("/items", tt.body)
+			if tt.contentType != "" {
+				ctx.Request.Headers = map[string]string{"content-type": tt.contentType}
+			}
+			require.NoError(t, prod.HandleTestRequest(ctx))
+			assert.Equal(t, tt.status, ctx.Response.StatusCode)
+			if tt.code != "" {
+				assert.Equal(t, tt.code, ctx.Response.Body.(map[string]any)["error"])
+			}
+		})
+	}
+
+	// The same app decodes leniently in development
+	dev := New(append(options, WithStage(Dev))...)
+	dev.POST("/items", handler)
+	ctx := newDecodeContext("/items", `{"sku": "a", "colour": "red"}`)
+	require.NoError(t, dev.HandleTestRequest(ctx))
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	assert.Equal(t, []DecodeWarning{{Field: "colour", Kind: WarningUnknownField}}, ctx.DecodeWarnings())
+
+	assert.Equal(t, DecodeStrict, ParseDecodeMode(" Strict "))
+	assert.Equal(t, DecodeDefault, ParseDecodeMode(""))
+	assert.Equal(t, "lenient", DecodeLenient.String())
+}
@@ -0,0 +1,162 @@
+package lift
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+)
+
+// streamFailureKey holds the sequence number of the first failed record of
+// a DynamoDB stream batch
+const streamFailureKey = "stream_batch_failure"
+
+// StreamOption configures a DynamoDB stream registration
+type StreamOption func(*streamOptions)
+
+type streamOptions struct {
+	tenant func(record *adapters.DynamoDBStreamRecord) string
+	filter func(record *adapters.DynamoDBStreamRecord) bool
+}
+
+// StreamTenant sets each record's tenant ID from the record, before any
+// middleware runs
+func StreamTenant(fn func(record *adapters.DynamoDBStreamRecord) string) StreamOption {
+	return func(o *streamOptions) { o.tenant = fn }
+}
+
+// StreamTenantFromKey reads the tenant ID from a key attribute whose value
+// starts with prefix, up to the next "#". With prefix "TENANT#", a key of
+// "TENANT#acme#ORDER#42" belongs to tenant "acme".
+func StreamTenantFromKey(attribute, prefix string) StreamOption {
+	return StreamTenant(func(record *adapters.DynamoDBStreamRecord) string {
+		value, _ := record.Keys[attribute].(string)
+		rest, ok := strings.CutPrefix(value, prefix)
+		if !ok {
+			return ""
+		}
+		tenant, _, _ := strings.Cut(rest, "#")
+		return tenant
+	})
+}
+
+// StreamFilter skips records for which fn returns false; skipped records
+// don't reach the middleware or the handler
+func StreamFilter(fn func(record *adapters.DynamoDBStreamRecord) bool) StreamOption {
+	return func(o *streamOptions) { o.filter = fn }
+}
+
+// StreamTTLExpiries only passes on the removals of items whose TTL expired,
+// e.g. to react to abandoned carts or lapsed holds
+func StreamTTLExpiries() StreamOption {
+	return StreamFilter((*adapters.DynamoDBStreamRecord).IsTTLExpiry)
+}
+
+// Stream registers a handler for the DynamoDB stream of a table. The
+// handler runs once per record, in order, behind the app's middleware, so
+// logging, tracing and tenant-aware middleware see each record the way
+// they see an HTTP request. The request body is the record as a JSON
+// adapters.DynamoDBStreamRecord, so typed handlers decode it directly:
+//
+//	app.Stream("orders", lift.SimpleHandler(func(ctx *lift.Context, rec adapters.DynamoDBStreamRecord) (any, error) {
+//		return nil, index(rec.NewImage)
+//	}), lift.StreamTenantFromKey("pk", "TENANT#"))
+//
+// Processing stops at the first failed record and Lambda resumes the shard
+// from it; enable ReportBatchItemFailures on the event source mapping.
+func (a *App) Stream(table string, handler any, opts ...StreamOption) error {
+	h, err := asHandler(handler)
+	if err != nil {
+		return fmt.Errorf("invalid stream handler: %w", err)
+	}
+	var options streamOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	a.eventRouter.AddEventRoute(TriggerDynamoDBStream, table, EventHandlerFunc(func(ctx *Context) error {
+		chain := a.withMiddleware(h)
+
+		for _, req := range adapters.SplitDynamoDBStreamRecords(ctx.Request.Request) {
+			recordCtx := ctx.recordContext(req)
+			if options.tenant != nil || options.filter != nil {
+				record, err := recordCtx.StreamRecord()
+				if err == nil && options.filter != nil && !options.filter(record) {
+					continue
+				}
+				if err == nil && options.tenant != nil {
+					if tenant := options.tenant(record); tenant != "" {
+						recordCtx.SetTenantID(tenant)
+					}
+				}
+			}
+
+			if err := runRecord(chain, recordCtx); err != nil {
+				sequence, _ := req.Metadata["sequenceNumber"].(string)
+				if ctx.Logger != nil {
+					ctx.Logger.Warn("Stream record failed", map[string]any{
+						"event_id":        req.EventID,
+						"sequence_number": sequence,
+						"error":           err.Error(),
+					})
+				}
+				ctx.Set(streamFailureKey, sequence)
+				return nil
+			}
+		}
+		return nil
+	}))
+	return nil
+}
+
+// StreamRecord decodes the DynamoDB stream record of the current request
+func (c *Context) StreamRecord() (*adapters.DynamoDBStreamRecord, error) {
+	if c.Request == nil || c.Request.TriggerType != TriggerDynamoDBStream {
+		return nil, fmt.Errorf("not a DynamoDB stream record")
+	}
+	var record adapters.DynamoDBStreamRecord
+	if err := json.Unmarshal(c.Request.Body, &record); err != nil {
+		return nil, fmt.Errorf("invalid stream record: %w", err)
+	}
+	return &record, nil
+}
+
+// runRecord runs a handler for one record of a batch, recovering panics
+func runRecord(h Handler, ctx *Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r, debug.Stack())
+		}
+	}()
+	if err := h.Handle(ctx); err != nil {
+		return err
+	}
+	// Middleware such as error handlers may turn a failure into a response
+	if ctx.Response.StatusCode >= 500 {
+		return fmt.Errorf("record failed with status %d", ctx.Response.StatusCode)
+	}
+	return nil
+}
+
+// streamBatchResponse builds the partial batch response for a DynamoDB
+// stream event, which shares the SQS response shape but identifies records
+// by sequence number. If the handler failed outright the whole batch is
+// retried from its first record.
+func streamBatchResponse(ctx *Context, handlerErr error) *adapters.SQSBatchResponse {
+	response := &adapters.SQSBatchResponse{BatchItemFailures: []adapters.SQSBatchItemFailure{}}
+
+	sequence, _ := ctx.Get(streamFailureKey).(string)
+	if sequence == "" && handlerErr != nil && len(ctx.Request.Records) > 0 {
+		if record, ok := ctx.Request.Records[0].(map[string]any); ok {
+			if stream, ok := record["dynamodb"].(map[string]any); ok {
+				sequence, _ = stream["SequenceNumber"].(string)
+			}
+		}
+	}
+	if sequence != "" {
+		response.BatchItemFailures = append(response.BatchItemFailures, adapters.SQSBatchItemFailure{ItemIdentifier: sequence})
+	}
+	return response
+}
@@ -0,0 +1,124 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamEvent(table string, orderIDs ...string) map[string]any {
+	records := make([]any, 0, len(orderIDs))
+	for i, id := range orderIDs {
+		key := map[string]any{"pk": map[string]any{"S": "TENANT#acme#ORDER#" + id}}
+		records = append(records, map[string]any{
+			"eventID":        fmt.Sprintf("e%d", i+1),
+			"eventName":      "INSERT",
+			"eventSource":    "aws:dynamodb",
+			"eventSourceARN": "arn:aws:dynamodb:us-east-1:123456789012:table/" + table + "/stream/2024-06-01T00:00:00.000",
+			"dynamodb": map[string]any{
+				"SequenceNumber": fmt.Sprintf("%d", 100+i),
+				"Keys":           key,
+				"NewImage": map[string]any{
+					"pk": key["pk"],
+					"id": map[string]any{"S": id},
+				},
+			},
+		})
+	}
+	return map[string]any{"Records": records}
+}
+
+func TestStreamRunsRecordsThroughMiddleware(t *testing.T) {
+	var seen []string
+	var tenants []string
+
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			tenants = append(tenants, ctx.TenantID())
+			return next.Handle(ctx)
+		})
+	})
+	require.NoError(t, app.Stream("orders", SimpleHandler(func(ctx *Context, rec adapters.DynamoDBStreamRecord) (struct{}, error) {
+		seen = append(seen, rec.NewImage["id"].(string))
+		return struct{}{}, nil
+	}), StreamTenantFromKey("pk", "TENANT#")))
+	require.NoError(t, app.Stream("invoices", func(ctx *Context) error {
+		return errors.New("wrong table")
+	}))
+
+	resp, err := app.HandleRequest(context.Background(), streamEvent("orders", "1", "2"))
+	require.NoError(t, err)
+	assert.Empty(t, resp.(*adapters.SQSBatchResponse).BatchItemFailures)
+	assert.Equal(t, []string{"1", "2"}, seen)
+	assert.Equal(t, []string{"acme", "acme"}, tenants, "middleware runs per record with the tenant from the key")
+}
+
+func TestStreamStopsAtFirstFailure(t *testing.T) {
+	var seen []string
+	app := New()
+	require.NoError(t, app.Stream("orders", func(ctx *Context) error {
+		record, err := ctx.StreamRecord()
+		require.NoError(t, err)
+		id := record.NewImage["id"].(string)
+		seen = append(seen, id)
+		if id == "2" {
+			panic("corrupt order")
+		}
+		return nil
+	}))
+
+	resp, err := app.HandleRequest(context.Background(), streamEvent("orders", "1", "2", "3"))
+	require.NoError(t, err)
+	batch := resp.(*adapters.SQSBatchResponse)
+	require.Len(t, batch.BatchItemFailures, 1)
+	assert.Equal(t, "101", batch.BatchItemFailures[0].ItemIdentifier, "the shard resumes from the failed record")
+	assert.Equal(t, []string{"1", "2"}, seen)
+
+	resp, err = app.HandleRequest(context.Background(), streamEvent("payments", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, "100", resp.(*adapters.SQSBatchResponse).BatchItemFailures[0].ItemIdentifier, "unrouted batches are retried")
+}
+
+func TestStreamTTLExpiries(t *testing.T) {
+	event := streamEvent("carts", "1", "2", "3")
+	records := event["Records"].([]any)
+	// Cart 1 is deleted by the application, cart 2 expires and cart 3 is
+	// inserted
+	for _, raw := range records[:2] {
+		record := raw.(map[string]any)
+		record["eventName"] = "REMOVE"
+		stream := record["dynamodb"].(map[string]any)
+		stream["OldImage"] = stream["NewImage"]
+		delete(stream, "NewImage")
+	}
+	records[1].(map[string]any)["userIdentity"] = map[string]any{
+		"type":        "Service",
+		"principalId": "dynamodb.amazonaws.com",
+	}
+
+	var expired []string
+	var middlewareCalls int
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			middlewareCalls++
+			return next.Handle(ctx)
+		})
+	})
+	require.NoError(t, app.Stream("carts", SimpleHandler(func(ctx *Context, rec adapters.DynamoDBStreamRecord) (struct{}, error) {
+		expired = append(expired, rec.OldImage["id"].(string))
+		return struct{}{}, nil
+	}), StreamTTLExpiries(), StreamTenantFromKey("pk", "TENANT#")))
+
+	resp, err := app.HandleRequest(context.Background(), event)
+	require.NoError(t, err)
+	assert.Empty(t, resp.(*adapters.SQSBatchResponse).BatchItemFailures)
+	assert.Equal(t, []string{"2"}, expired)
+	assert.Equal(t, 1, middlewareCalls, "filtered records skip the middleware")
+}
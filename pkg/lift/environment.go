@@ -0,0 +1,194 @@
+package lift
+
+import (
+	"os"
+	"strings"
+)
+
+// Stage is a deployment stage. Stages are flags, so a set of them can be
+// passed where one is expected:
+//
+//	app.Use(lift.OnlyIn(lift.Dev|lift.Staging, middleware.RequestLogger()))
+//
+// Being constants rather than strings, a misspelt stage doesn't compile.
+type Stage uint8
+
+const (
+	// Dev is local development and personal sandboxes
+	Dev Stage = 1 << iota
+	// Staging is pre-production
+	Staging
+	// Prod serves customers
+	Prod
+)
+
+// stageNames are the STAGE values recognised for each stage
+var stageNames = map[string]Stage{
+	"dev":         Dev,
+	"development": Dev,
+	"local":       Dev,
+	"test":        Dev,
+	"staging":     Staging,
+	"stage":       Staging,
+	"stg":         Staging,
+	"qa":          Staging,
+	"prod":        Prod,
+	"production":  Prod,
+	"live":        Prod,
+}
+
+// ParseStage returns the stage a name such as "dev", "staging" or
+// "production" refers to. Unrecognised names are Prod, so a misconfigured
+// stage never enables something meant only for development.
+func ParseStage(name string) Stage {
+	if stage, ok := stageNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return stage
+	}
+	return Prod
+}
+
+// Has reports whether s includes any of the stages in other
+func (s Stage) Has(other Stage) bool {
+	return s&other != 0
+}
+
+// String returns the stage's name, or names joined by "|" for a set
+func (s Stage) String() string {
+	var names []string
+	if s.Has(Dev) {
+		names = append(names, "dev")
+	}
+	if s.Has(Staging) {
+		names = append(names, "staging")
+	}
+	if s.Has(Prod) {
+		names = append(names, "prod")
+	}
+	if len(names) == 0 {
+		return "unknown"
+	}
+	return strings.Join(names, "|")
+}
+
+// Environment describes where the application is deployed
+type Environment struct {
+	// Stage is the deployment stage
+	Stage Stage
+	// Name is the stage as configured, e.g. "sandbox-ada"
+	Name string
+}
+
+// IsDev reports whether the application runs in development
+func (e Environment) IsDev() bool { return e.Stage == Dev }
+
+// IsStaging reports whether the application runs in staging
+func (e Environment) IsStaging() bool { return e.Stage == Staging }
+
+// IsProd reports whether the application serves customers
+func (e Environment) IsProd() bool { return e.Stage == Prod }
+
+// In reports whether the environment is one of the stages
+func (e Environment) In(stages Stage) bool { return stages.Has(e.Stage) }
+
+// EnvironmentFromEnv reads the environment from the STAGE environment
+// variable, the one the observability packages label telemetry with. An
+// unset or unrecognised STAGE is Prod.
+func EnvironmentFromEnv() Environment {
+	name := os.Getenv("STAGE")
+	return Environment{Stage: ParseStage(name), Name: name}
+}
+
+// WithStage sets the application's stage instead of reading it from STAGE
+func WithStage(stage Stage) AppOption {
+	return func(app *App) {
+		app.environment = Environment{Stage: stage, Name: stage.String()}
+	}
+}
+
+// WithEnvironment sets the application's environment instead of reading it
+// from STAGE
+func WithEnvironment(environment Environment) AppOption {
+	return func(app *App) {
+		app.environment = environment
+	}
+}
+
+// Environment returns where the application is deployed
+func (a *App) Environment() Environment {
+	return a.environment
+}
+
+// Environment returns where the application handling the request is
+// deployed. Contexts created outside an application read STAGE.
+func (c *Context) Environment() Environment {
+	if c.environment.Stage == 0 {
+		return EnvironmentFromEnv()
+	}
+	return c.environment
+}
+
+// SetEnvironment sets the environment reported by Environment
+func (c *Context) SetEnvironment(environment Environment) {
+	c.environment = environment
+}
+
+// OnlyIn applies middleware only in the given stages; elsewhere requests
+// skip it:
+//
+//	app.Use(lift.OnlyIn(lift.Dev, lift.VerboseErrors()))
+func OnlyIn(stages Stage, middleware Middleware) Middleware {
+	return func(next Handler) Handler {
+		wrapped := middleware(next)
+		return HandlerFunc(func(ctx *Context) error {
+			if ctx.Environment().In(stages) {
+				return wrapped.Handle(ctx)
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// ExceptIn applies middleware in every stage but the given ones
+func ExceptIn(stages Stage, middleware Middleware) Middleware {
+	return OnlyIn(^stages, middleware)
+}
+
+// AvailableIn hides routes outside the given stages by answering 404. Use
+// it for debug endpoints and API explorers such as Swagger UI:
+//
+//	debug := app.Group("/debug", lift.AvailableIn(lift.Dev|lift.Staging))
+//	debug.GET("/config", showConfig)
+func AvailableIn(stages Stage) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			if !ctx.Environment().In(stages) {
+				return NotFound("Route not found")
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// VerboseErrors adds the underlying cause to error responses, and turns
+// other errors into 500s that say what went wrong. Causes can expose
+// internals, so only use it with OnlyIn outside Prod.
+func VerboseErrors() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			err := next.Handle(ctx)
+			if err == nil {
+				return nil
+			}
+			liftErr, ok := err.(*LiftError)
+			if !ok {
+				return NewLiftError("INTERNAL_ERROR", "Internal server error", 500).
+					WithCause(err).
+					WithDetail("cause", err.Error())
+			}
+			if liftErr.Cause != nil {
+				liftErr.WithDetail("cause", liftErr.Cause.Error())
+			}
+			return liftErr
+		})
+	}
+}
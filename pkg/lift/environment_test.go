@@ -0,0 +1,86 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStage(t *testing.T) {
+	assert.Equal(t, Dev, ParseStage("dev"))
+	assert.Equal(t, Dev, ParseStage(" Development "))
+	assert.Equal(t, Staging, ParseStage("staging"))
+	assert.Equal(t, Prod, ParseStage("production"))
+	// Anything unrecognised is treated as production
+	assert.Equal(t, Prod, ParseStage("sandbox-ada"))
+	assert.Equal(t, Prod, ParseStage(""))
+
+	assert.Equal(t, "dev|staging", (Dev | Staging).String())
+	assert.True(t, (Dev | Staging).Has(Staging))
+	assert.False(t, (Dev | Staging).Has(Prod))
+}
+
+func TestEnvironment(t *testing.T) {
+	t.Setenv("STAGE", "staging")
+	assert.Equal(t, Environment{Stage: Staging, Name: "staging"}, New().Environment())
+	assert.True(t, New(WithStage(Dev)).Environment().IsDev())
+
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{}))
+	assert.True(t, ctx.Environment().IsStaging())
+}
+
+func TestStageMiddleware(t *testing.T) {
+	run := func(stage Stage, middleware Middleware, handler HandlerFunc) (*Context, error) {
+		ctx := NewContext(context.Background(), NewRequest(&adapters.Request{Method: "GET", Path: "/debug"}))
+		ctx.SetEnvironment(Environment{Stage: stage})
+		return ctx, middleware(handler).Handle(ctx)
+	}
+	tag := func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			ctx.Response.Header("X-Debug", "1")
+			return next.Handle(ctx)
+		})
+	}
+	ok := func(ctx *Context) error { return ctx.OK("ok") }
+
+	ctx, err := run(Dev, OnlyIn(Dev|Staging, tag), ok)
+	require.NoError(t, err)
+	assert.Equal(t, "1", ctx.Response.Headers["X-Debug"])
+
+	ctx, err = run(Prod, OnlyIn(Dev|Staging, tag), ok)
+	require.NoError(t, err)
+	assert.Empty(t, ctx.Response.Headers["X-Debug"])
+	assert.Equal(t, "ok", ctx.Response.Body)
+
+	ctx, err = run(Prod, ExceptIn(Dev, tag), ok)
+	require.NoError(t, err)
+	assert.Equal(t, "1", ctx.Response.Headers["X-Debug"])
+
+	_, err = run(Staging, AvailableIn(Dev|Staging), ok)
+	require.NoError(t, err)
+	_, err = run(Prod, AvailableIn(Dev|Staging), ok)
+	var liftErr *LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 404, liftErr.StatusCode)
+}
+
+func TestVerboseErrors(t *testing.T) {
+	err := VerboseErrors()(HandlerFunc(func(ctx *Context) error {
+		return errors.New("connection refused")
+	})).Handle(NewContext(context.Background(), NewRequest(&adapters.Request{})))
+	var liftErr *LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 500, liftErr.StatusCode)
+	assert.Equal(t, "connection refused", liftErr.Details["cause"])
+
+	err = VerboseErrors()(HandlerFunc(func(ctx *Context) error {
+		return NotFound("Order not found").WithCause(errors.New("no item for order_1"))
+	})).Handle(NewContext(context.Background(), NewRequest(&adapters.Request{})))
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 404, liftErr.StatusCode)
+	assert.Equal(t, "no item for order_1", liftErr.Details["cause"])
+}
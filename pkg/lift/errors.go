@@ -49,6 +49,25 @@ func (e *LiftError) Unwrap() error {
 	return e.Cause
 }
 
+// PanicError is a panic recovered from a handler or middleware, passed to
+// the app's error handlers
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// NewPanicError creates a PanicError for a recovered value; pass
+// debug.Stack() from the deferred recover so the stack is the panicking
+// goroutine's
+func NewPanicError(value any, stack []byte) *PanicError {
+	return &PanicError{Value: value, Stack: stack}
+}
+
+// Error implements the error interface
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
 // NewLiftError creates a new LiftError
 func NewLiftError(code, message string, statusCode int) *LiftError {
 	return &LiftError{
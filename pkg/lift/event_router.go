@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
 )
 
 // EventHandler represents a handler for non-HTTP events
@@ -24,6 +26,8 @@ type EventRoute struct {
 	TriggerType TriggerType
 	Pattern     string // For matching specific sources, queues, buckets, etc.
 	Handler     EventHandler
+	// Match replaces Pattern for routes that match on more than one field
+	Match func(ctx *Context) bool
 }
 
 // EventRouter handles routing for non-HTTP Lambda events
@@ -41,15 +45,18 @@ func NewEventRouter() *EventRouter {
 
 // AddEventRoute adds a route for a specific event type
 func (er *EventRouter) AddEventRoute(triggerType TriggerType, pattern string, handler EventHandler) {
-	route := &EventRoute{
+	er.addRoute(&EventRoute{
 		TriggerType: triggerType,
 		Pattern:     pattern,
 		Handler:     handler,
-	}
+	})
+}
 
+// addRoute appends a route for its trigger type
+func (er *EventRouter) addRoute(route *EventRoute) {
 	er.mu.Lock()
 	defer er.mu.Unlock()
-	er.routes[triggerType] = append(er.routes[triggerType], route)
+	er.routes[route.TriggerType] = append(er.routes[route.TriggerType], route)
 }
 
 // FindEventHandler finds the appropriate handler for an event
@@ -78,6 +85,10 @@ func (er *EventRouter) FindEventHandler(ctx *Context) (EventHandler, error) {
 
 // matchesPattern checks if the event matches the route pattern
 func (er *EventRouter) matchesPattern(ctx *Context, route *EventRoute) bool {
+	if route.Match != nil {
+		return route.Match(ctx)
+	}
+
 	// If pattern is empty or "*", it matches everything
 	if route.Pattern == "" || route.Pattern == "*" {
 		return true
@@ -90,6 +101,10 @@ func (er *EventRouter) matchesPattern(ctx *Context, route *EventRoute) bool {
 		return er.matchS3Pattern(ctx, route.Pattern)
 	case TriggerEventBridge:
 		return er.matchEventBridgePattern(ctx, route.Pattern)
+	case TriggerDynamoDBStream:
+		return er.matchStreamPattern(ctx, route.Pattern)
+	case TriggerSNS:
+		return er.matchSNSPattern(ctx, route.Pattern)
 	default:
 		return true // Default to match for unknown types
 	}
@@ -113,6 +128,34 @@ func (er *EventRouter) matchSQSPattern(ctx *Context, pattern string) bool {
 	return false
 }
 
+// matchStreamPattern matches DynamoDB table names
+func (er *EventRouter) matchStreamPattern(ctx *Context, pattern string) bool {
+	if len(ctx.Request.Records) == 0 {
+		return false
+	}
+
+	if record, ok := ctx.Request.Records[0].(map[string]any); ok {
+		if eventSourceARN, ok := record["eventSourceARN"].(string); ok {
+			return er.matchWildcardPattern(adapters.StreamTableName(eventSourceARN), pattern)
+		}
+	}
+
+	return false
+}
+
+// matchSNSPattern matches SNS topic names or ARNs
+func (er *EventRouter) matchSNSPattern(ctx *Context, pattern string) bool {
+	if len(ctx.Request.Records) == 0 {
+		return false
+	}
+
+	if record, ok := ctx.Request.Records[0].(map[string]any); ok {
+		return matchSNSTopic(adapters.ParseSNSRecord(record).TopicARN, pattern)
+	}
+
+	return false
+}
+
 // matchS3Pattern matches S3 bucket names and object keys
 func (er *EventRouter) matchS3Pattern(ctx *Context, pattern string) bool {
 	var bucketName, objectKey string
@@ -415,23 +458,16 @@ func (ctx *Context) ParseS3Event() (*S3Event, error) {
 
 	// Use the first record
 	if recordMap, ok := ctx.Request.Records[0].(map[string]any); ok {
-		s3Data := getMapField(recordMap, "s3")
-		bucket := getMapField(s3Data, "bucket")
-		object := getMapField(s3Data, "object")
+		object := adapters.ParseS3Record(recordMap)
 
 		event := &S3Event{
 			EventSource: getStringField(recordMap, "eventSource"),
-			EventName:   getStringField(recordMap, "eventName"),
-			EventTime:   getStringField(recordMap, "eventTime"),
-			Bucket:      getStringField(bucket, "name"),
-			ObjectKey:   getStringField(object, "key"),
-			S3Data:      s3Data,
-		}
-
-		// Parse object size if available
-		if sizeStr := getStringField(object, "size"); sizeStr != "" {
-			// Convert string to int64 if needed
-			event.ObjectSize = 0 // Default value
+			EventName:   object.EventName,
+			EventTime:   object.EventTime,
+			Bucket:      object.Bucket,
+			ObjectKey:   object.Key,
+			ObjectSize:  object.Size,
+			S3Data:      getMapField(recordMap, "s3"),
 		}
 
 		return event, nil
@@ -0,0 +1,70 @@
+package lift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eventBridgeEvent(detailType, source string, detail map[string]any) map[string]any {
+	return map[string]any{
+		"version":     "0",
+		"id":          "eb-1",
+		"detail-type": detailType,
+		"source":      source,
+		"time":        "2024-06-01T12:00:00Z",
+		"resources":   []any{"arn:aws:events:us-east-1:123456789012:rule/nightly-reconciliation"},
+		"detail":      detail,
+	}
+}
+
+type orderPlaced struct {
+	OrderID string `json:"order_id" validate:"required"`
+	Amount  int    `json:"amount"`
+}
+
+func TestEventBridgeMatch(t *testing.T) {
+	var calls []string
+	var middlewareRuns int
+
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			middlewareRuns++
+			return next.Handle(ctx)
+		})
+	})
+	require.NoError(t, app.EventBridgeMatch("Scheduled Event", "aws.events", func(ctx *Context) error {
+		calls = append(calls, "cron:"+ctx.GetScheduledRuleName())
+		return nil
+	}))
+	require.NoError(t, app.EventBridgeMatch("Order Placed", "com.acme.*", SimpleHandler(func(ctx *Context, event orderPlaced) (struct{}, error) {
+		calls = append(calls, "order:"+event.OrderID)
+		return struct{}{}, nil
+	})))
+	require.NoError(t, app.EventBridgeMatch("", "com.acme.orders", func(ctx *Context) error {
+		calls = append(calls, "orders:"+ctx.Request.DetailType)
+		return nil
+	}))
+
+	handle := func(event map[string]any) int {
+		result, err := app.HandleRequest(context.Background(), event)
+		require.NoError(t, err)
+		resp, ok := result.(*Response)
+		require.True(t, ok)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, 200, handle(eventBridgeEvent("Scheduled Event", "aws.events", map[string]any{})))
+	assert.Equal(t, 200, handle(eventBridgeEvent("Order Placed", "com.acme.orders", map[string]any{"order_id": "o-1", "amount": 500})))
+	assert.Equal(t, 200, handle(eventBridgeEvent("Order Shipped", "com.acme.orders", map[string]any{"order_id": "o-1"})))
+
+	assert.Equal(t, []string{"cron:nightly-reconciliation", "order:o-1", "orders:Order Shipped"}, calls)
+	assert.Equal(t, 3, middlewareRuns, "matched events run through the middleware chain")
+
+	assert.GreaterOrEqual(t, handle(eventBridgeEvent("Order Placed", "com.acme.orders", map[string]any{"amount": 1})), 400, "typed details are validated")
+	assert.Len(t, calls, 3)
+	assert.GreaterOrEqual(t, handle(eventBridgeEvent("Invoice Paid", "com.acme.billing", map[string]any{})), 400, "unmatched events have no handler")
+}
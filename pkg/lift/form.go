@@ -0,0 +1,343 @@
+package lift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+)
+
+// MultipartLimits bound the forms ctx.Form accepts. Zero fields use the
+// defaults.
+type MultipartLimits struct {
+	// MaxFileSize is the largest file accepted (default: 10MB)
+	MaxFileSize int64
+	// MaxFiles is the most files in one form (default: 10)
+	MaxFiles int
+	// MaxFields is the most non-file values in one form (default: 100)
+	MaxFields int
+	// MaxFieldSize is the largest non-file value (default: 64KB)
+	MaxFieldSize int64
+}
+
+func (l MultipartLimits) withDefaults() MultipartLimits {
+	if l.MaxFileSize == 0 {
+		l.MaxFileSize = 10 << 20
+	}
+	if l.MaxFiles == 0 {
+		l.MaxFiles = 10
+	}
+	if l.MaxFields == 0 {
+		l.MaxFields = 100
+	}
+	if l.MaxFieldSize == 0 {
+		l.MaxFieldSize = 64 << 10
+	}
+	return l
+}
+
+// WithMultipartLimits sets the limits for parsing forms and uploads
+func WithMultipartLimits(limits MultipartLimits) AppOption {
+	return func(app *App) {
+		app.multipartLimits = limits
+	}
+}
+
+// SetMultipartLimits sets the limits for parsing forms and uploads for
+// this request
+func (c *Context) SetMultipartLimits(limits MultipartLimits) {
+	c.multipartLimits = limits
+}
+
+// errFileTooLarge is returned by readers of uploads over MaxFileSize
+var errFileTooLarge = errors.New("file exceeds the maximum size")
+
+// Form is a parsed application/x-www-form-urlencoded or
+// multipart/form-data body
+type Form struct {
+	Values url.Values
+	Files  map[string][]*FormFile
+}
+
+// FormFile is a file uploaded in a multipart form
+type FormFile struct {
+	// Field is the name of the form field
+	Field string
+	// Filename is the name the client gave the file; don't use it as a
+	// path without sanitizing it
+	Filename    string
+	ContentType string
+	Size        int64
+	Header      textproto.MIMEHeader
+
+	content []byte
+}
+
+// Open returns a reader for the file's content
+func (f *FormFile) Open() io.Reader {
+	return bytes.NewReader(f.content)
+}
+
+// Bytes returns the file's content
+func (f *FormFile) Bytes() []byte {
+	return f.content
+}
+
+// Form parses the request body as a URL-encoded or multipart form. It is
+// parsed once; later calls return the same Form. Multipart files are held
+// in memory, so handlers that pass large files on should use
+// UploadFormFile or MultipartReader instead.
+func (c *Context) Form() (*Form, error) {
+	if c.form != nil {
+		return c.form, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(c.Request.GetHeader("Content-Type"))
+	if err != nil {
+		return nil, NewLiftError("UNSUPPORTED_MEDIA_TYPE", "Request body is not a form", 415)
+	}
+	limits := c.multipartLimits.withDefaults()
+
+	var form *Form
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		form, err = parseURLEncodedForm(c.Request.Body, limits)
+	case "multipart/form-data":
+		if params["boundary"] == "" {
+			return nil, NewLiftError("INVALID_FORM", "Multipart boundary is missing", 400)
+		}
+		form, err = parseMultipartForm(multipart.NewReader(bytes.NewReader(c.Request.Body), params["boundary"]), limits)
+	default:
+		return nil, NewLiftError("UNSUPPORTED_MEDIA_TYPE", "Request body is not a form", 415)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.form = form
+	return form, nil
+}
+
+// FormValue returns the first value of a form field, or "" if the field is
+// missing or the body isn't a valid form. Use Form to tell those apart.
+func (c *Context) FormValue(name string) string {
+	form, err := c.Form()
+	if err != nil {
+		return ""
+	}
+	return form.Values.Get(name)
+}
+
+// FormFile returns the first file uploaded in a form field
+func (c *Context) FormFile(name string) (*FormFile, error) {
+	form, err := c.Form()
+	if err != nil {
+		return nil, err
+	}
+	if files := form.Files[name]; len(files) > 0 {
+		return files[0], nil
+	}
+	return nil, NewLiftError("MISSING_FILE", fmt.Sprintf("File %q is required", name), 400).WithDetail("field", name)
+}
+
+// MultipartReader returns a reader over the parts of a multipart body, to
+// process them one at a time without collecting them into a Form
+func (c *Context) MultipartReader() (*multipart.Reader, error) {
+	mediaType, params, err := mime.ParseMediaType(c.Request.GetHeader("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, NewLiftError("UNSUPPORTED_MEDIA_TYPE", "Request body is not a multipart form", 415)
+	}
+	if params["boundary"] == "" {
+		return nil, NewLiftError("INVALID_FORM", "Multipart boundary is missing", 400)
+	}
+	return multipart.NewReader(bytes.NewReader(c.Request.Body), params["boundary"]), nil
+}
+
+// FileUpload is a file to store
+type FileUpload struct {
+	Bucket string
+	Key    string
+	// Body is read to the end; its size isn't known in advance
+	Body        io.Reader
+	ContentType string
+	Filename    string
+}
+
+// FileUploader stores uploaded files, such as payload.S3FileUploader
+type FileUploader interface {
+	UploadFile(ctx context.Context, file *FileUpload) error
+}
+
+// UploadedFile describes a file stored by UploadFormFile
+type UploadedFile struct {
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// UploadFormFile streams the first file in a multipart form field to
+// bucket/key, without copying it into memory first:
+//
+//	uploaded, err := ctx.UploadFormFile("document", uploader, "uploads", "docs/"+id)
+//
+// Files over MaxFileSize fail with 413 FILE_TOO_LARGE; the uploader is
+// expected to abandon the partial upload when reading the body fails.
+func (c *Context) UploadFormFile(name string, uploader FileUploader, bucket, key string) (*UploadedFile, error) {
+	reader, err := c.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	limits := c.multipartLimits.withDefaults()
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, NewLiftError("MISSING_FILE", fmt.Sprintf("File %q is required", name), 400).WithDetail("field", name)
+		}
+		if err != nil {
+			return nil, NewLiftError("INVALID_FORM", "Malformed multipart body", 400).WithCause(err)
+		}
+		if part.FormName() != name || part.FileName() == "" {
+			continue
+		}
+
+		body := &limitedReader{reader: part, remaining: limits.MaxFileSize}
+		upload := &FileUpload{
+			Bucket:      bucket,
+			Key:         key,
+			Body:        body,
+			ContentType: partContentType(part),
+			Filename:    part.FileName(),
+		}
+		if err := uploader.UploadFile(c.Context, upload); err != nil {
+			// Uploaders may wrap the read error beyond recognition
+			if body.remaining < 0 || errors.Is(err, errFileTooLarge) {
+				return nil, fileTooLarge(name, limits.MaxFileSize)
+			}
+			return nil, NewLiftError("UPLOAD_FAILED", "Failed to store file", 500).WithCause(err)
+		}
+		return &UploadedFile{
+			Bucket:      bucket,
+			Key:         key,
+			Filename:    upload.Filename,
+			ContentType: upload.ContentType,
+			Size:        body.read,
+		}, nil
+	}
+}
+
+func parseURLEncodedForm(body []byte, limits MultipartLimits) (*Form, error) {
+	if int64(len(body)) > limits.MaxFieldSize*int64(limits.MaxFields) {
+		return nil, NewLiftError("FORM_TOO_LARGE", "Form is too large", 413)
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, NewLiftError("INVALID_FORM", "Malformed form body", 400).WithCause(err)
+	}
+	if len(values) > limits.MaxFields {
+		return nil, NewLiftError("FORM_TOO_LARGE", "Form has too many fields", 413)
+	}
+	return &Form{Values: values, Files: map[string][]*FormFile{}}, nil
+}
+
+func parseMultipartForm(reader *multipart.Reader, limits MultipartLimits) (*Form, error) {
+	form := &Form{Values: url.Values{}, Files: map[string][]*FormFile{}}
+	fields, files := 0, 0
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return nil, NewLiftError("INVALID_FORM", "Malformed multipart body", 400).WithCause(err)
+		}
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+
+		if part.FileName() == "" {
+			if fields++; fields > limits.MaxFields {
+				return nil, NewLiftError("FORM_TOO_LARGE", "Form has too many fields", 413)
+			}
+			value, err := readLimited(part, limits.MaxFieldSize)
+			if errors.Is(err, errFileTooLarge) {
+				return nil, NewLiftError("FORM_TOO_LARGE", fmt.Sprintf("Field %q is too large", name), 413).WithDetail("field", name)
+			}
+			if err != nil {
+				return nil, NewLiftError("INVALID_FORM", "Malformed multipart body", 400).WithCause(err)
+			}
+			form.Values.Add(name, string(value))
+			continue
+		}
+
+		if files++; files > limits.MaxFiles {
+			return nil, NewLiftError("FORM_TOO_LARGE", "Form has too many files", 413)
+		}
+		content, err := readLimited(part, limits.MaxFileSize)
+		if errors.Is(err, errFileTooLarge) {
+			return nil, fileTooLarge(name, limits.MaxFileSize)
+		}
+		if err != nil {
+			return nil, NewLiftError("INVALID_FORM", "Malformed multipart body", 400).WithCause(err)
+		}
+		form.Files[name] = append(form.Files[name], &FormFile{
+			Field:       name,
+			Filename:    part.FileName(),
+			ContentType: partContentType(part),
+			Size:        int64(len(content)),
+			Header:      part.Header,
+			content:     content,
+		})
+	}
+}
+
+// readLimited reads a part, failing with errFileTooLarge past limit bytes
+func readLimited(part io.Reader, limit int64) ([]byte, error) {
+	return io.ReadAll(&limitedReader{reader: part, remaining: limit})
+}
+
+// limitedReader fails with errFileTooLarge rather than truncating, and
+// counts what it read
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+	read      int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, errFileTooLarge
+	}
+	// Read one byte past the limit to tell "exactly the limit" from "over"
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	r.read += int64(n)
+	if r.remaining < 0 {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+func partContentType(part *multipart.Part) string {
+	if contentType := part.Header.Get("Content-Type"); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+func fileTooLarge(field string, limit int64) *LiftError {
+	return NewLiftError("FILE_TOO_LARGE", fmt.Sprintf("File %q exceeds %d bytes", field, limit), 413).
+		WithDetail("field", field).
+		WithDetail("max_size", limit)
+}
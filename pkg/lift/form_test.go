@@ -0,0 +1,170 @@
+package lift
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multipartBody builds a form with a "title" field and a "document" file
+func multipartBody(t *testing.T, file []byte) ([]byte, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("title", "Q3 report"))
+	part, err := writer.CreateFormFile("document", "report.pdf")
+	require.NoError(t, err)
+	_, err = part.Write(file)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return body.Bytes(), writer.FormDataContentType()
+}
+
+func formContext(contentType string, body []byte) *Context {
+	return NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method:  "POST",
+		Path:    "/documents",
+		Headers: map[string]string{"content-type": contentType},
+		Body:    body,
+	}))
+}
+
+type recordingUploader struct {
+	upload  *FileUpload
+	content []byte
+}
+
+func (u *recordingUploader) UploadFile(ctx context.Context, file *FileUpload) error {
+	u.upload = file
+	content, err := io.ReadAll(file.Body)
+	u.content = content
+	return err
+}
+
+func TestForm(t *testing.T) {
+	t.Run("parses multipart forms", func(t *testing.T) {
+		body, contentType := multipartBody(t, []byte("%PDF-1.7"))
+		ctx := formContext(contentType, body)
+
+		assert.Equal(t, "Q3 report", ctx.FormValue("title"))
+		file, err := ctx.FormFile("document")
+		require.NoError(t, err)
+		assert.Equal(t, "report.pdf", file.Filename)
+		assert.Equal(t, "application/octet-stream", file.ContentType)
+		assert.Equal(t, int64(8), file.Size)
+		content, err := io.ReadAll(file.Open())
+		require.NoError(t, err)
+		assert.Equal(t, "%PDF-1.7", string(content))
+
+		_, err = ctx.FormFile("missing")
+		var liftErr *LiftError
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, "MISSING_FILE", liftErr.Code)
+	})
+
+	t.Run("parses URL-encoded forms", func(t *testing.T) {
+		ctx := formContext("application/x-www-form-urlencoded", []byte("title=Q3+report&tag=a&tag=b"))
+		assert.Equal(t, "Q3 report", ctx.FormValue("title"))
+		form, err := ctx.Form()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, form.Values["tag"])
+	})
+
+	t.Run("rejects other bodies", func(t *testing.T) {
+		ctx := formContext("application/json", []byte(`{"title":"Q3 report"}`))
+		assert.Empty(t, ctx.FormValue("title"))
+		_, err := ctx.Form()
+		var liftErr *LiftError
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, 415, liftErr.StatusCode)
+	})
+
+	t.Run("enforces size limits", func(t *testing.T) {
+		body, contentType := multipartBody(t, bytes.Repeat([]byte("x"), 101))
+		ctx := formContext(contentType, body)
+		ctx.SetMultipartLimits(MultipartLimits{MaxFileSize: 100})
+		_, err := ctx.FormFile("document")
+		var liftErr *LiftError
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, "FILE_TOO_LARGE", liftErr.Code)
+
+		body, contentType = multipartBody(t, bytes.Repeat([]byte("x"), 100))
+		ctx = formContext(contentType, body)
+		ctx.SetMultipartLimits(MultipartLimits{MaxFileSize: 100})
+		_, err = ctx.FormFile("document")
+		require.NoError(t, err)
+	})
+}
+
+func TestUploadFormFile(t *testing.T) {
+	file := bytes.Repeat([]byte("0123456789"), 1000)
+	body, contentType := multipartBody(t, file)
+
+	uploader := &recordingUploader{}
+	uploaded, err := formContext(contentType, body).UploadFormFile("document", uploader, "uploads", "docs/doc_1")
+	require.NoError(t, err)
+	assert.Equal(t, &UploadedFile{
+		Bucket:      "uploads",
+		Key:         "docs/doc_1",
+		Filename:    "report.pdf",
+		ContentType: "application/octet-stream",
+		Size:        int64(len(file)),
+	}, uploaded)
+	assert.Equal(t, file, uploader.content)
+
+	ctx := formContext(contentType, body)
+	ctx.SetMultipartLimits(MultipartLimits{MaxFileSize: 5000})
+	_, err = ctx.UploadFormFile("document", &recordingUploader{}, "uploads", "docs/doc_2")
+	var liftErr *LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 413, liftErr.StatusCode)
+
+	_, err = formContext(contentType, body).UploadFormFile("photo", uploader, "uploads", "photos/1")
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "MISSING_FILE", liftErr.Code)
+
+	failing := uploaderFunc(func(ctx context.Context, file *FileUpload) error {
+		return errors.New("access denied")
+	})
+	_, err = formContext(contentType, body).UploadFormFile("document", failing, "uploads", "docs/doc_3")
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "UPLOAD_FAILED", liftErr.Code)
+}
+
+type uploaderFunc func(ctx context.Context, file *FileUpload) error
+
+func (f uploaderFunc) UploadFile(ctx context.Context, file *FileUpload) error { return f(ctx, file) }
+
+func TestFormFromBase64Event(t *testing.T) {
+	body, contentType := multipartBody(t, []byte{0x89, 'P', 'N', 'G', 0x00})
+	app := New()
+	app.POST("/documents", func(ctx *Context) error {
+		file, err := ctx.FormFile("document")
+		if err != nil {
+			return err
+		}
+		return ctx.OK(map[string]any{"title": ctx.FormValue("title"), "size": file.Size})
+	})
+
+	resp, err := app.HandleRequest(context.Background(), map[string]any{
+		"resource":        "/documents",
+		"httpMethod":      "POST",
+		"path":            "/documents",
+		"requestContext":  map[string]any{"requestId": "req-1"},
+		"headers":         map[string]any{"Content-Type": contentType},
+		"body":            base64.StdEncoding.EncodeToString(body),
+		"isBase64Encoded": true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"title": "Q3 report", "size": int64(5)}, resp.(*Response).Body)
+	assert.True(t, strings.HasPrefix(contentType, "multipart/form-data"))
+}
@@ -0,0 +1,131 @@
+package lift
+
+import "sync"
+
+// Group creates a route group with a common path prefix. Middleware passed
+// here, or added later with the group's Use, wraps only the group's routes
+// and runs after the app's middleware:
+//
+//	api := app.Group("/api/v1", authenticate)
+//	admin := api.Group("/admin", requireAdmin)
+//	admin.GET("/users", listUsers) // app middleware, authenticate, requireAdmin
+func (a *App) Group(prefix string, middleware ...Middleware) *RouteGroup {
+	return &RouteGroup{
+		app:        a,
+		prefix:     prefix,
+		middleware: middleware,
+	}
+}
+
+// RouteGroup represents a group of routes with a common prefix and
+// middleware. Nested groups inherit the prefix and middleware of their
+// parents.
+type RouteGroup struct {
+	app    *App
+	parent *RouteGroup
+	prefix string
+
+	mu         sync.RWMutex
+	middleware []Middleware
+}
+
+// Use adds middleware to the group. It applies to all of the group's
+// routes, including those registered before it was added, and to the
+// routes of nested groups.
+func (rg *RouteGroup) Use(middleware ...Middleware) *RouteGroup {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	rg.middleware = append(rg.middleware, middleware...)
+	return rg
+}
+
+// Group creates a nested group with an additional prefix and middleware
+func (rg *RouteGroup) Group(prefix string, middleware ...Middleware) *RouteGroup {
+	return &RouteGroup{
+		app:        rg.app,
+		parent:     rg,
+		prefix:     rg.prefix + prefix,
+		middleware: middleware,
+	}
+}
+
+// Prefix returns the group's full path prefix
+func (rg *RouteGroup) Prefix() string {
+	return rg.prefix
+}
+
+// GET registers a GET route in this group
+func (rg *RouteGroup) GET(path string, handler any) *Route {
+	return rg.Handle("GET", path, handler)
+}
+
+// POST registers a POST route in this group
+func (rg *RouteGroup) POST(path string, handler any) *Route {
+	return rg.Handle("POST", path, handler)
+}
+
+// PUT registers a PUT route in this group
+func (rg *RouteGroup) PUT(path string, handler any) *Route {
+	return rg.Handle("PUT", path, handler)
+}
+
+// DELETE registers a DELETE route in this group
+func (rg *RouteGroup) DELETE(path string, handler any) *Route {
+	return rg.Handle("DELETE", path, handler)
+}
+
+// PATCH registers a PATCH route in this group
+func (rg *RouteGroup) PATCH(path string, handler any) *Route {
+	return rg.Handle("PATCH", path, handler)
+}
+
+// Handle registers a route in this group with the specified method
+func (rg *RouteGroup) Handle(method, path string, handler any) *Route {
+	h, err := asHandler(handler)
+	if err != nil {
+		// Let the app record the registration error on the route
+		return rg.app.Handle(method, rg.prefix+path, handler)
+	}
+	return rg.app.Handle(method, rg.prefix+path, &groupHandler{group: rg, handler: h})
+}
+
+// chain returns the group's middleware, outermost (root group) first
+func (rg *RouteGroup) chain() []Middleware {
+	var chain []Middleware
+	if rg.parent != nil {
+		chain = rg.parent.chain()
+	}
+
+	rg.mu.RLock()
+	defer rg.mu.RUnlock()
+	return append(chain, rg.middleware...)
+}
+
+// groupHandler applies the group's middleware when a route is handled, so
+// middleware added with Use after the route was registered still applies
+type groupHandler struct {
+	group   *RouteGroup
+	handler Handler
+}
+
+// Handle runs the handler inside the group's middleware
+func (g *groupHandler) Handle(ctx *Context) error {
+	chain := g.group.chain()
+	h := g.handler
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	return h.Handle(ctx)
+}
+
+// asHandler converts the handler types accepted by route registration
+func asHandler(handler any) (Handler, error) {
+	switch v := handler.(type) {
+	case Handler:
+		return v, nil
+	case func(*Context) error:
+		return HandlerFunc(v), nil
+	default:
+		return convertHandlerUsingReflection(handler)
+	}
+}
@@ -0,0 +1,100 @@
+package lift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagMiddleware appends name to the "trace" context value
+func tagMiddleware(name string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			trace, _ := ctx.Get("trace").([]string)
+			ctx.Set("trace", append(trace, name))
+			return next.Handle(ctx)
+		})
+	}
+}
+
+func groupRequest(t *testing.T, app *App, method, path string) (*Context, []string) {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method: method,
+		Path:   path,
+	}))
+	require.NoError(t, app.HandleTestRequest(ctx))
+	trace, _ := ctx.Get("trace").([]string)
+	return ctx, trace
+}
+
+func TestRouteGroupMiddleware(t *testing.T) {
+	app := New()
+	app.Use(tagMiddleware("app"))
+
+	handler := func(ctx *Context) error {
+		trace, _ := ctx.Get("trace").([]string)
+		ctx.Set("trace", append(trace, "handler"))
+		return ctx.OK(nil)
+	}
+
+	api := app.Group("/api", tagMiddleware("api"))
+	api.GET("/users", handler)
+
+	admin := api.Group("/admin", tagMiddleware("admin"))
+	admin.DELETE("/users/:id", handler)
+
+	// Use applies to routes registered before it, including nested groups
+	api.Use(tagMiddleware("api-late"))
+
+	app.GET("/health", handler)
+
+	_, trace := groupRequest(t, app, "GET", "/api/users")
+	assert.Equal(t, []string{"app", "api", "api-late", "handler"}, trace)
+
+	ctx, trace := groupRequest(t, app, "DELETE", "/api/admin/users/42")
+	assert.Equal(t, []string{"app", "api", "api-late", "admin", "handler"}, trace)
+	assert.Equal(t, "42", ctx.Param("id"))
+
+	_, trace = groupRequest(t, app, "GET", "/health")
+	assert.Equal(t, []string{"app", "handler"}, trace)
+
+	assert.Equal(t, "/api/admin", admin.Prefix())
+}
+
+func TestRouteGroupMiddlewareCanShortCircuit(t *testing.T) {
+	app := New()
+	deny := func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			return Unauthorized("Authentication required")
+		})
+	}
+
+	admin := app.Group("/admin", deny)
+	admin.GET("/stats", func(ctx *Context) error { return ctx.OK(nil) }).Meta(MetaSummary, "Admin stats")
+	app.GET("/public", func(ctx *Context) error { return ctx.OK(nil) })
+
+	ctx, _ := groupRequest(t, app, "GET", "/admin/stats")
+	assert.Equal(t, 401, ctx.Response.StatusCode)
+
+	ctx, _ = groupRequest(t, app, "GET", "/public")
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+
+	// Group routes are registered with the app, metadata included
+	var found bool
+	for _, route := range app.Routes() {
+		if route.Path == "/admin/stats" {
+			found = true
+			assert.Equal(t, "Admin stats", route.Metadata()[MetaSummary])
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRouteGroupUnsupportedHandler(t *testing.T) {
+	app := New()
+	route := app.Group("/api", tagMiddleware("api")).GET("/bad", 42)
+	assert.Error(t, route.Err())
+}
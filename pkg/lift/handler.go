@@ -1,5 +1,7 @@
 package lift
 
+import "reflect"
+
 // Handler represents a request handler
 type Handler interface {
 	Handle(ctx *Context) error
@@ -43,33 +45,123 @@ func wrapHandler(handler any) Handler {
 	}
 }
 
+// TypedOption customizes a handler created by SimpleHandler
+type TypedOption func(*typedOptions)
+
+type typedOptions struct {
+	status      int
+	errorMapper func(ctx *Context, err error) error
+}
+
+// WithStatus sets the status code of successful responses (default: 200)
+func WithStatus(code int) TypedOption {
+	return func(o *typedOptions) { o.status = code }
+}
+
+// WithErrorMapper maps errors returned by the handler before they reach the
+// error handler, e.g. to turn domain errors into LiftErrors:
+//
+//	lift.SimpleHandler(getAccount, lift.WithErrorMapper(func(ctx *lift.Context, err error) error {
+//		if errors.Is(err, store.ErrNotFound) {
+//			return lift.NotFound("account not found")
+//		}
+//		return err
+//	}))
+//
+// Parsing and validation errors are already LiftErrors and aren't mapped.
+func WithErrorMapper(mapper func(ctx *Context, err error) error) TypedOption {
+	return func(o *typedOptions) { o.errorMapper = mapper }
+}
+
 // SimpleHandler creates a Handler from a typed handler function
-// This is the main convenience function for creating type-safe handlers
-func SimpleHandler[Req, Resp any](handler func(ctx *Context, req Req) (Resp, error)) Handler {
-	return &typedHandlerAdapter[Req, Resp]{
+// This is the main convenience function for creating type-safe handlers.
+//
+// The request is decoded from the JSON body, then fields tagged `path:"id"`
+// or `query:"limit"` are filled from path and query parameters, and the
+// result is validated with its `validate` tags. The response is serialized
+// as JSON:
+//
+//	type GetOrderRequest struct {
+//		ID     string `path:"id" validate:"required"`
+//		Expand bool   `query:"expand"`
+//	}
+//
+//	app.GET("/orders/:id", lift.SimpleHandler(getOrder))
+//	app.POST("/orders", lift.SimpleHandler(createOrder, lift.WithStatus(201)))
+func SimpleHandler[Req, Resp any](handler func(ctx *Context, req Req) (Resp, error), opts ...TypedOption) Handler {
+	adapter := &typedHandlerAdapter[Req, Resp]{
 		handler: TypedHandlerFunc[Req, Resp](handler),
 	}
+	for _, opt := range opts {
+		opt(&adapter.options)
+	}
+	return adapter
 }
 
 // typedHandlerAdapter adapts a TypedHandler to the Handler interface
 type typedHandlerAdapter[Req, Resp any] struct {
 	handler TypedHandler[Req, Resp]
+	options typedOptions
+	// bind decodes the request (default: bindTypedRequest)
+	bind func(ctx *Context, req *Req) error
+}
+
+// signature describes the handler for OpenAPI generation
+func (adapter *typedHandlerAdapter[Req, Resp]) signature() *handlerSignature {
+	status := adapter.options.status
+	if status == 0 {
+		status = 200
+	}
+	return &handlerSignature{
+		request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		response: reflect.TypeOf((*Resp)(nil)).Elem(),
+		status:   status,
+	}
+}
+
+// handlerSignature is the request and response of a typed handler
+type handlerSignature struct {
+	request  reflect.Type
+	response reflect.Type
+	status   int
+}
+
+// signatureOf returns the signature of a typed handler, looking through
+// route group wrappers, or nil for untyped handlers
+func signatureOf(h Handler) *handlerSignature {
+	switch v := h.(type) {
+	case interface{ signature() *handlerSignature }:
+		return v.signature()
+	case *groupHandler:
+		return signatureOf(v.handler)
+	}
+	return nil
 }
 
 // Handle adapts the typed handler to the generic Handler interface
 func (adapter *typedHandlerAdapter[Req, Resp]) Handle(ctx *Context) error {
 	// Parse the request into the expected type
 	var req Req
-	if err := ctx.ParseRequest(&req); err != nil {
+	bind := adapter.bind
+	if bind == nil {
+		bind = func(ctx *Context, req *Req) error { return bindTypedRequest(ctx, req) }
+	}
+	if err := bind(ctx, &req); err != nil {
 		return err
 	}
 
 	// Call the typed handler
 	resp, err := adapter.handler.Handle(ctx, req)
 	if err != nil {
+		if adapter.options.errorMapper != nil {
+			return adapter.options.errorMapper(ctx, err)
+		}
 		return err
 	}
 
 	// Set the response as JSON
+	if adapter.options.status != 0 {
+		ctx.Status(adapter.options.status)
+	}
 	return ctx.JSON(resp)
 }
@@ -0,0 +1,92 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedOrderRequest struct {
+	ID     string   `json:"-" path:"id"`
+	Expand bool     `json:"-" query:"expand"`
+	Limit  *int     `json:"-" query:"limit"`
+	Fields []string `json:"-" query:"fields"`
+}
+
+type typedCreateRequest struct {
+	Name   string `json:"name" validate:"required"`
+	Amount int    `json:"amount" validate:"min=1"`
+}
+
+type typedOrder struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name,omitempty"`
+	Expand bool     `json:"expand"`
+	Limit  int      `json:"limit"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+var errOrderMissing = errors.New("order missing")
+
+func TestSimpleHandlerBindsParamsAndValidates(t *testing.T) {
+	app := New()
+	app.GET("/orders/:id", SimpleHandler(func(ctx *Context, req typedOrderRequest) (typedOrder, error) {
+		if req.ID == "missing" {
+			return typedOrder{}, errOrderMissing
+		}
+		order := typedOrder{ID: req.ID, Expand: req.Expand, Fields: req.Fields}
+		if req.Limit != nil {
+			order.Limit = *req.Limit
+		}
+		return order, nil
+	}, WithErrorMapper(func(ctx *Context, err error) error {
+		if errors.Is(err, errOrderMissing) {
+			return NotFound("order not found")
+		}
+		return err
+	})))
+	app.POST("/orders", SimpleHandler(func(ctx *Context, req typedCreateRequest) (typedOrder, error) {
+		return typedOrder{ID: "ord_1", Name: req.Name}, nil
+	}, WithStatus(201)))
+
+	request := func(method, path string, query map[string]string, body string) *Context {
+		ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+			Method:      method,
+			Path:        path,
+			QueryParams: query,
+			Body:        []byte(body),
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	ctx := request("GET", "/orders/ord_9", map[string]string{"expand": "true", "limit": "5", "fields": "id,name"}, "")
+	require.Equal(t, 200, ctx.Response.StatusCode)
+	assert.Equal(t, typedOrder{ID: "ord_9", Expand: true, Limit: 5, Fields: []string{"id", "name"}}, ctx.Response.Body)
+
+	assert.Equal(t, 400, request("GET", "/orders/ord_9", map[string]string{"limit": "many"}, "").Response.StatusCode)
+	assert.Equal(t, 404, request("GET", "/orders/missing", nil, "").Response.StatusCode)
+
+	ctx = request("POST", "/orders", nil, `{"name":"Widget","amount":3}`)
+	assert.Equal(t, 201, ctx.Response.StatusCode)
+	assert.Equal(t, "Widget", ctx.Response.Body.(typedOrder).Name)
+
+	assert.Equal(t, 400, request("POST", "/orders", nil, "").Response.StatusCode, "empty body")
+	assert.Equal(t, 400, request("POST", "/orders", nil, `{"name":`).Response.StatusCode, "invalid JSON")
+	assert.Equal(t, 400, request("POST", "/orders", nil, `{"amount":3}`).Response.StatusCode, "missing name")
+}
+
+func TestBindTypedRequestValidationDetails(t *testing.T) {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{Method: "POST", Body: []byte(`{"amount":0}`)}))
+
+	var req typedCreateRequest
+	err := bindTypedRequest(ctx, &req)
+	var liftErr *LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "VALIDATION_ERROR", liftErr.Code)
+	assert.Contains(t, liftErr.Details, "errors")
+}
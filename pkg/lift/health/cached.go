@@ -0,0 +1,195 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// CachedHealth serves the overall health from a cache that is refreshed in
+// the background, so high-frequency load balancer health checks (ALB, API
+// Gateway) cost a pointer read instead of running every component check.
+//
+// Start refreshes on a ticker. On Lambda, where background goroutines are
+// frozen between invocations, Status also triggers a single asynchronous
+// refresh when the cached result is older than the refresh interval.
+type CachedHealth struct {
+	manager  HealthManager
+	interval time.Duration
+	timeout  time.Duration
+	now      func() time.Time
+
+	status     atomic.Pointer[HealthStatus]
+	refreshing atomic.Bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// CachedHealthConfig configures a CachedHealth
+type CachedHealthConfig struct {
+	// RefreshInterval is how often the checks run (default: 30 seconds)
+	RefreshInterval time.Duration
+
+	// Timeout bounds each refresh (default: 10 seconds)
+	Timeout time.Duration
+
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// DefaultCachedHealthConfig returns sensible defaults
+func DefaultCachedHealthConfig() CachedHealthConfig {
+	return CachedHealthConfig{
+		RefreshInterval: 30 * time.Second,
+		Timeout:         10 * time.Second,
+	}
+}
+
+// NewCachedHealth creates a health cache for manager. Until the first
+// refresh completes the cached status is StatusUnknown.
+func NewCachedHealth(manager HealthManager, config CachedHealthConfig) *CachedHealth {
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = 30 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return &CachedHealth{
+		manager:  manager,
+		interval: config.RefreshInterval,
+		timeout:  config.Timeout,
+		now:      config.Now,
+	}
+}
+
+// Start refreshes the cache immediately and then every refresh interval
+// until ctx is done or Stop is called. Calling Start again has no effect.
+func (c *CachedHealth) Start(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		return
+	}
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		c.Refresh(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.Refresh(ctx)
+			}
+		}
+	}(c.done)
+}
+
+// Stop stops the background refresher and waits for it to exit
+func (c *CachedHealth) Stop() {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel, c.done = nil, nil
+	c.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Refresh runs all health checks and caches the overall result
+func (c *CachedHealth) Refresh(ctx context.Context) HealthStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	overall := c.manager.OverallHealth(ctx)
+	c.status.Store(&overall)
+	return overall
+}
+
+// Status returns the cached overall health without running any checks.
+// A stale result triggers one background refresh; the stale result is
+// still returned so the caller never waits on the checks.
+func (c *CachedHealth) Status() HealthStatus {
+	cached := c.status.Load()
+	if cached == nil || c.now().Sub(cached.Timestamp) >= c.interval {
+		c.refreshAsync()
+	}
+
+	if cached == nil {
+		return HealthStatus{
+			Status:    StatusUnknown,
+			Timestamp: c.now(),
+			Message:   "Health not checked yet",
+		}
+	}
+	return *cached
+}
+
+// refreshAsync starts a refresh unless one is already running
+func (c *CachedHealth) refreshAsync() {
+	if !c.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.refreshing.Store(false)
+		c.Refresh(context.Background())
+	}()
+}
+
+// HTTPHandler returns a net/http handler that answers with the cached
+// status: 200 for healthy or degraded, 503 otherwise
+func (c *CachedHealth) HTTPHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := c.Status()
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(cachedHealthHTTPStatus(status.Status))
+		w.Write([]byte(status.Status))
+	}
+}
+
+// Handler returns a lift handler that answers with the cached status, for
+// apps that route load balancer health checks through lift, e.g.
+//
+//	cache := health.NewCachedHealth(manager, health.DefaultCachedHealthConfig())
+//	cache.Start(context.Background())
+//	app.GET("/health", cache.Handler())
+func (c *CachedHealth) Handler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		status := c.Status()
+		ctx.Response.Header("Cache-Control", "no-store")
+		return ctx.Status(cachedHealthHTTPStatus(status.Status)).Text(status.Status)
+	})
+}
+
+// cachedHealthHTTPStatus maps a health status to the status code load
+// balancers act on; degraded services keep receiving traffic
+func cachedHealthHTTPStatus(status string) int {
+	switch status {
+	case StatusHealthy, StatusDegraded:
+		return http.StatusOK
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
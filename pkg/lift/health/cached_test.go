@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+)
+
+// countingManager returns an uncached manager whose single checker counts
+// its runs
+func countingManager(status string, runs *int32) HealthManager {
+	config := DefaultHealthManagerConfig()
+	config.CacheEnabled = false
+	manager := NewHealthManager(config)
+	manager.RegisterChecker("counted", NewCustomHealthChecker("counted", func(ctx context.Context) HealthStatus {
+		atomic.AddInt32(runs, 1)
+		return HealthStatus{Status: status, Timestamp: time.Now()}
+	}))
+	return manager
+}
+
+func TestCachedHealth_ServesCachedStatus(t *testing.T) {
+	var runs int32
+	cache := NewCachedHealth(countingManager(StatusHealthy, &runs), CachedHealthConfig{RefreshInterval: time.Hour})
+
+	cache.Refresh(context.Background())
+	handler := cache.HTTPHandler()
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if w.Body.String() != StatusHealthy {
+			t.Fatalf("expected body %q, got %q", StatusHealthy, w.Body.String())
+		}
+	}
+
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("expected checks to run once, ran %d times", got)
+	}
+}
+
+func TestCachedHealth_UnknownUntilFirstRefresh(t *testing.T) {
+	var runs int32
+	cache := NewCachedHealth(countingManager(StatusHealthy, &runs), DefaultCachedHealthConfig())
+
+	status := cache.Status()
+	if status.Status != StatusUnknown {
+		t.Errorf("expected status %s, got %s", StatusUnknown, status.Status)
+	}
+
+	// The first Status call starts a refresh in the background
+	deadline := time.Now().Add(time.Second)
+	for cache.Status().Status != StatusHealthy {
+		if time.Now().After(deadline) {
+			t.Fatal("cache was not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCachedHealth_RefreshesStaleStatus(t *testing.T) {
+	var runs int32
+	now := time.Now()
+	var clock atomic.Int64
+	clock.Store(now.UnixNano())
+
+	cache := NewCachedHealth(countingManager(StatusHealthy, &runs), CachedHealthConfig{
+		RefreshInterval: time.Minute,
+		Now:             func() time.Time { return time.Unix(0, clock.Load()) },
+	})
+	cache.Refresh(context.Background())
+
+	cache.Status()
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Fatalf("fresh status should not refresh, ran %d times", got)
+	}
+
+	clock.Store(now.Add(2 * time.Minute).UnixNano())
+	cache.Status()
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&runs) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("stale status was not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCachedHealth_StartStop(t *testing.T) {
+	var runs int32
+	cache := NewCachedHealth(countingManager(StatusUnhealthy, &runs), CachedHealthConfig{RefreshInterval: 5 * time.Millisecond})
+
+	cache.Start(context.Background())
+	cache.Start(context.Background())
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&runs) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("background refresher did not run")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cache.Stop()
+	cache.Stop()
+
+	stopped := atomic.LoadInt32(&runs)
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt32(&runs); got != stopped {
+		t.Errorf("refresher kept running after Stop: %d runs, then %d", stopped, got)
+	}
+
+	w := httptest.NewRecorder()
+	cache.HTTPHandler()(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestCachedHealth_LiftHandler(t *testing.T) {
+	var runs int32
+	cache := NewCachedHealth(countingManager(StatusDegraded, &runs), CachedHealthConfig{RefreshInterval: time.Hour})
+	cache.Refresh(context.Background())
+
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:      "GET",
+		Path:        "/health",
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+		TriggerType: lift.TriggerAPIGateway,
+	}))
+
+	if err := cache.Handler().Handle(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctx.Response.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, ctx.Response.StatusCode)
+	}
+	if ctx.Response.Body != StatusDegraded {
+		t.Errorf("expected body %q, got %v", StatusDegraded, ctx.Response.Body)
+	}
+}
+
+func TestHealthEndpoints_UsesCache(t *testing.T) {
+	var runs int32
+	manager := countingManager(StatusHealthy, &runs)
+	cache := NewCachedHealth(manager, CachedHealthConfig{RefreshInterval: time.Hour})
+	cache.Refresh(context.Background())
+
+	config := DefaultHealthEndpointsConfig()
+	config.Cache = cache
+	endpoints := NewHealthEndpoints(manager, config)
+
+	for _, handler := range []http.HandlerFunc{endpoints.HealthHandler, endpoints.ReadinessHandler} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("expected checks to run once, ran %d times", got)
+	}
+}
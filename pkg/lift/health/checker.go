@@ -80,8 +80,24 @@ type DefaultHealthManager struct {
 	// Cache
 	cache   map[string]cachedResult
 	cacheMu sync.RWMutex
+
+	// Status transitions
+	lastStatus map[string]string
+	hooks      []StatusChangeHook
+	statusMu   sync.Mutex
+}
+
+// StatusChange describes a component moving from one health status to another
+type StatusChange struct {
+	Component string
+	// Previous is "" the first time a component is checked
+	Previous string
+	Current  HealthStatus
 }
 
+// StatusChangeHook is called when a component's health status changes
+type StatusChangeHook func(ctx context.Context, change StatusChange)
+
 // cachedResult stores cached health check results
 type cachedResult struct {
 	status    HealthStatus
@@ -112,9 +128,19 @@ func NewHealthManager(config HealthManagerConfig) *DefaultHealthManager {
 		cacheEnabled:   config.CacheEnabled,
 		cacheDuration:  config.CacheDuration,
 		cache:          make(map[string]cachedResult),
+		lastStatus:     make(map[string]string),
 	}
 }
 
+// OnStatusChange registers a hook called, synchronously, whenever a fresh
+// check result differs from the component's previous status
+func (hm *DefaultHealthManager) OnStatusChange(hook StatusChangeHook) {
+	hm.statusMu.Lock()
+	defer hm.statusMu.Unlock()
+
+	hm.hooks = append(hm.hooks, hook)
+}
+
 // RegisterChecker registers a health checker
 func (hm *DefaultHealthManager) RegisterChecker(name string, checker HealthChecker) error {
 	hm.mu.Lock()
@@ -341,9 +367,29 @@ func (hm *DefaultHealthManager) performCheck(ctx context.Context, name string, c
 		hm.cacheResult(name, status)
 	}
 
+	hm.recordStatus(ctx, name, status)
+
 	return status
 }
 
+// recordStatus notifies the status change hooks when a component's status changes
+func (hm *DefaultHealthManager) recordStatus(ctx context.Context, name string, status HealthStatus) {
+	hm.statusMu.Lock()
+	previous := hm.lastStatus[name]
+	hm.lastStatus[name] = status.Status
+	hooks := hm.hooks
+	hm.statusMu.Unlock()
+
+	if previous == status.Status {
+		return
+	}
+
+	change := StatusChange{Component: name, Previous: previous, Current: status}
+	for _, hook := range hooks {
+		hook(ctx, change)
+	}
+}
+
 // getCachedResult retrieves a cached health check result
 func (hm *DefaultHealthManager) getCachedResult(name string) *HealthStatus {
 	hm.cacheMu.RLock()
@@ -18,6 +18,7 @@ type HealthEndpoints struct {
 	enableCORS           bool
 	corsOrigins          []string
 	timeout              time.Duration
+	cache                *CachedHealth
 }
 
 // HealthEndpointsConfig configures health endpoints
@@ -33,6 +34,10 @@ type HealthEndpointsConfig struct {
 
 	// Timeout for health checks
 	Timeout time.Duration
+
+	// Cache, when set, answers /health and /health/ready from its cached
+	// status instead of running the checks on every request
+	Cache *CachedHealth
 }
 
 // NewHealthEndpoints creates new health endpoints
@@ -43,6 +48,7 @@ func NewHealthEndpoints(manager HealthManager, config HealthEndpointsConfig) *He
 		enableCORS:           config.EnableCORS,
 		corsOrigins:          config.CORSOrigins,
 		timeout:              config.Timeout,
+		cache:                config.Cache,
 	}
 }
 
@@ -90,10 +96,7 @@ func (he *HealthEndpoints) HealthHandler(w http.ResponseWriter, r *http.Request)
 
 	he.setCORSHeaders(w)
 
-	ctx, cancel := context.WithTimeout(r.Context(), he.timeout)
-	defer cancel()
-
-	overall := he.manager.OverallHealth(ctx)
+	overall := he.overallHealth(r.Context())
 
 	// Determine HTTP status code
 	statusCode := he.healthStatusToHTTPStatus(overall.Status)
@@ -117,10 +120,7 @@ func (he *HealthEndpoints) ReadinessHandler(w http.ResponseWriter, r *http.Reque
 
 	he.setCORSHeaders(w)
 
-	ctx, cancel := context.WithTimeout(r.Context(), he.timeout)
-	defer cancel()
-
-	overall := he.manager.OverallHealth(ctx)
+	overall := he.overallHealth(r.Context())
 
 	// For readiness, we consider degraded as ready (service can handle traffic)
 	// Only unhealthy or unknown should return non-200
@@ -216,6 +216,19 @@ func (he *HealthEndpoints) ComponentsHandler(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(response)
 }
 
+// overallHealth returns the cached overall health when a cache is
+// configured, and runs all checks otherwise
+func (he *HealthEndpoints) overallHealth(ctx context.Context) HealthStatus {
+	if he.cache != nil {
+		return he.cache.Status()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, he.timeout)
+	defer cancel()
+
+	return he.manager.OverallHealth(ctx)
+}
+
 // healthStatusToHTTPStatus converts health status to HTTP status code
 func (he *HealthEndpoints) healthStatusToHTTPStatus(status string) int {
 	switch status {
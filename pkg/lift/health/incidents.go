@@ -0,0 +1,314 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultPagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+	DefaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+	// DefaultOpsGenieURL is the OpsGenie API base URL (use https://api.eu.opsgenie.com for EU accounts)
+	DefaultOpsGenieURL = "https://api.opsgenie.com"
+)
+
+// HTTPClient is the subset of *http.Client used by incident providers
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Incident describes a component that has become unhealthy
+type Incident struct {
+	// DedupKey identifies the incident so repeated triggers and the
+	// eventual resolve refer to the same incident
+	DedupKey  string
+	Summary   string
+	Source    string
+	Component string
+	Status    HealthStatus
+}
+
+// IncidentProvider opens and resolves incidents in an on-call system
+type IncidentProvider interface {
+	Trigger(ctx context.Context, incident Incident) error
+	Resolve(ctx context.Context, dedupKey string) error
+}
+
+// IncidentHookConfig configures NewIncidentHook
+type IncidentHookConfig struct {
+	// Provider receives trigger and resolve calls
+	Provider IncidentProvider
+
+	// Service is used as the incident source and in deduplication keys
+	Service string
+
+	// Critical lists the components that open incidents; empty means all
+	Critical []string
+
+	// OnError is called when the provider fails; errors are otherwise dropped
+	OnError func(err error)
+}
+
+// NewIncidentHook returns a StatusChangeHook that triggers an incident when a
+// critical component becomes unhealthy and resolves it once the component is
+// healthy again. Each component has its own deduplication key
+// ("<service>:health:<component>"), so flapping checks update one incident
+// instead of opening many:
+//
+//	manager.OnStatusChange(health.NewIncidentHook(health.IncidentHookConfig{
+//		Provider: health.NewPagerDutyProvider(health.PagerDutyConfig{RoutingKey: key}),
+//		Service:  "payments",
+//		Critical: []string{"database", "card-processor"},
+//	}))
+//
+// Provider calls are made synchronously so they complete before Lambda
+// freezes the execution environment.
+func NewIncidentHook(config IncidentHookConfig) StatusChangeHook {
+	critical := make(map[string]bool, len(config.Critical))
+	for _, name := range config.Critical {
+		critical[name] = true
+	}
+
+	return func(ctx context.Context, change StatusChange) {
+		if config.Provider == nil {
+			return
+		}
+		if len(critical) > 0 && !critical[change.Component] {
+			return
+		}
+
+		dedupKey := IncidentDedupKey(config.Service, change.Component)
+
+		var err error
+		switch {
+		case change.Current.Status == StatusUnhealthy:
+			err = config.Provider.Trigger(ctx, Incident{
+				DedupKey:  dedupKey,
+				Summary:   incidentSummary(config.Service, change),
+				Source:    config.Service,
+				Component: change.Component,
+				Status:    change.Current,
+			})
+		case change.Current.Status == StatusHealthy && change.Previous == StatusUnhealthy:
+			err = config.Provider.Resolve(ctx, dedupKey)
+		}
+
+		if err != nil && config.OnError != nil {
+			config.OnError(fmt.Errorf("health incident for %s: %w", change.Component, err))
+		}
+	}
+}
+
+// IncidentDedupKey returns the deduplication key used for a component's incidents
+func IncidentDedupKey(service, component string) string {
+	if service == "" {
+		return "health:" + component
+	}
+	return service + ":health:" + component
+}
+
+func incidentSummary(service string, change StatusChange) string {
+	summary := fmt.Sprintf("%s is unhealthy", change.Component)
+	if service != "" {
+		summary = fmt.Sprintf("%s: %s", service, summary)
+	}
+	if change.Current.Message != "" {
+		summary += ": " + change.Current.Message
+	} else if change.Current.Error != "" {
+		summary += ": " + change.Current.Error
+	}
+	return summary
+}
+
+// PagerDutyConfig configures a PagerDutyProvider
+type PagerDutyConfig struct {
+	// RoutingKey is the integration key of an Events API v2 integration
+	RoutingKey string
+
+	// Severity of triggered incidents (critical, error, warning, info); defaults to critical
+	Severity string
+
+	// URL defaults to DefaultPagerDutyEventsURL
+	URL string
+
+	// Timeout for each request (default: 5s)
+	Timeout time.Duration
+
+	// HTTPClient defaults to an http.Client with Timeout
+	HTTPClient HTTPClient
+}
+
+// PagerDutyProvider sends incidents to the PagerDuty Events API v2
+type PagerDutyProvider struct {
+	config PagerDutyConfig
+}
+
+// NewPagerDutyProvider creates a PagerDuty incident provider
+func NewPagerDutyProvider(config PagerDutyConfig) *PagerDutyProvider {
+	if config.Severity == "" {
+		config.Severity = "critical"
+	}
+	if config.URL == "" {
+		config.URL = DefaultPagerDutyEventsURL
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: config.Timeout}
+	}
+	return &PagerDutyProvider{config: config}
+}
+
+// Trigger opens (or updates) the incident for incident.DedupKey
+func (p *PagerDutyProvider) Trigger(ctx context.Context, incident Incident) error {
+	return p.send(ctx, map[string]any{
+		"routing_key":  p.config.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    incident.DedupKey,
+		"payload": map[string]any{
+			"summary":        incident.Summary,
+			"source":         incident.Source,
+			"severity":       p.config.Severity,
+			"component":      incident.Component,
+			"timestamp":      incident.Status.Timestamp.Format(time.RFC3339),
+			"custom_details": incidentDetails(incident),
+		},
+	})
+}
+
+// Resolve resolves the incident for dedupKey
+func (p *PagerDutyProvider) Resolve(ctx context.Context, dedupKey string) error {
+	return p.send(ctx, map[string]any{
+		"routing_key":  p.config.RoutingKey,
+		"event_action": "resolve",
+		"dedup_key":    dedupKey,
+	})
+}
+
+func (p *PagerDutyProvider) send(ctx context.Context, event map[string]any) error {
+	return postJSON(ctx, p.config.HTTPClient, p.config.URL, nil, event, "pagerduty")
+}
+
+// OpsGenieConfig configures an OpsGenieProvider
+type OpsGenieConfig struct {
+	// APIKey is an OpsGenie API integration key
+	APIKey string
+
+	// Priority of created alerts (P1-P5); defaults to P1
+	Priority string
+
+	// URL defaults to DefaultOpsGenieURL
+	URL string
+
+	// Timeout for each request (default: 5s)
+	Timeout time.Duration
+
+	// HTTPClient defaults to an http.Client with Timeout
+	HTTPClient HTTPClient
+}
+
+// OpsGenieProvider sends incidents to the OpsGenie Alert API, using the
+// deduplication key as the alert alias
+type OpsGenieProvider struct {
+	config OpsGenieConfig
+}
+
+// NewOpsGenieProvider creates an OpsGenie incident provider
+func NewOpsGenieProvider(config OpsGenieConfig) *OpsGenieProvider {
+	if config.Priority == "" {
+		config.Priority = "P1"
+	}
+	if config.URL == "" {
+		config.URL = DefaultOpsGenieURL
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: config.Timeout}
+	}
+	return &OpsGenieProvider{config: config}
+}
+
+// Trigger creates an alert aliased to incident.DedupKey; OpsGenie
+// deduplicates open alerts with the same alias
+func (p *OpsGenieProvider) Trigger(ctx context.Context, incident Incident) error {
+	return p.send(ctx, p.config.URL+"/v2/alerts", map[string]any{
+		"message":     truncate(incident.Summary, 130),
+		"alias":       incident.DedupKey,
+		"description": incident.Status.Message,
+		"priority":    p.config.Priority,
+		"source":      incident.Source,
+		"entity":      incident.Component,
+		"details":     incidentDetails(incident),
+	})
+}
+
+// Resolve closes the alert aliased to dedupKey
+func (p *OpsGenieProvider) Resolve(ctx context.Context, dedupKey string) error {
+	endpoint := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", p.config.URL, url.PathEscape(dedupKey))
+	return p.send(ctx, endpoint, map[string]any{"note": "Component recovered"})
+}
+
+func (p *OpsGenieProvider) send(ctx context.Context, endpoint string, body map[string]any) error {
+	headers := map[string]string{"Authorization": "GenieKey " + p.config.APIKey}
+	return postJSON(ctx, p.config.HTTPClient, endpoint, headers, body, "opsgenie")
+}
+
+// incidentDetails flattens a status into string details accepted by both providers
+func incidentDetails(incident Incident) map[string]string {
+	details := map[string]string{
+		"component": incident.Component,
+		"status":    incident.Status.Status,
+	}
+	if incident.Status.Error != "" {
+		details["error"] = incident.Status.Error
+	}
+	for key, value := range incident.Status.Details {
+		details[key] = fmt.Sprint(value)
+	}
+	return details
+}
+
+func postJSON(ctx context.Context, client HTTPClient, endpoint string, headers map[string]string, body any, provider string) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", provider, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", provider, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", provider, resp.StatusCode)
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,185 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type recordingHTTPClient struct {
+	requests []*http.Request
+	bodies   []map[string]any
+	status   int
+}
+
+func (c *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	raw, _ := io.ReadAll(req.Body)
+	var body map[string]any
+	_ = json.Unmarshal(raw, &body)
+	c.requests = append(c.requests, req)
+	c.bodies = append(c.bodies, body)
+	return &http.Response{StatusCode: c.status, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+type recordingProvider struct {
+	triggered []Incident
+	resolved  []string
+	err       error
+}
+
+func (p *recordingProvider) Trigger(ctx context.Context, incident Incident) error {
+	p.triggered = append(p.triggered, incident)
+	return p.err
+}
+
+func (p *recordingProvider) Resolve(ctx context.Context, dedupKey string) error {
+	p.resolved = append(p.resolved, dedupKey)
+	return p.err
+}
+
+func TestHealthManager_StatusChangeHooks(t *testing.T) {
+	config := DefaultHealthManagerConfig()
+	config.CacheEnabled = false
+	manager := NewHealthManager(config)
+
+	status := StatusHealthy
+	manager.RegisterChecker("database", NewCustomHealthChecker("database", func(ctx context.Context) HealthStatus {
+		return HealthStatus{Status: status, Message: "connection refused"}
+	}))
+	manager.RegisterChecker("cache", NewAlwaysUnhealthyChecker("cache"))
+
+	provider := &recordingProvider{}
+	var changes []StatusChange
+	manager.OnStatusChange(func(ctx context.Context, change StatusChange) {
+		changes = append(changes, change)
+	})
+	manager.OnStatusChange(NewIncidentHook(IncidentHookConfig{
+		Provider: provider,
+		Service:  "payments",
+		Critical: []string{"database"},
+	}))
+
+	ctx := context.Background()
+	manager.CheckComponent(ctx, "database")
+	manager.CheckComponent(ctx, "database")
+	if len(changes) != 1 || changes[0].Previous != "" || changes[0].Current.Status != StatusHealthy {
+		t.Fatalf("expected one initial transition, got %+v", changes)
+	}
+
+	status = StatusUnhealthy
+	manager.CheckComponent(ctx, "database")
+	manager.CheckComponent(ctx, "database")
+	manager.CheckComponent(ctx, "cache")
+	if len(provider.triggered) != 1 {
+		t.Fatalf("expected 1 incident, got %d", len(provider.triggered))
+	}
+	incident := provider.triggered[0]
+	if incident.DedupKey != "payments:health:database" {
+		t.Errorf("unexpected dedup key %q", incident.DedupKey)
+	}
+	if incident.Summary != "payments: database is unhealthy: connection refused" {
+		t.Errorf("unexpected summary %q", incident.Summary)
+	}
+
+	status = StatusDegraded
+	manager.CheckComponent(ctx, "database")
+	status = StatusHealthy
+	manager.CheckComponent(ctx, "database")
+	if len(provider.resolved) != 0 {
+		t.Errorf("recovery via degraded should not resolve, got %v", provider.resolved)
+	}
+
+	status = StatusUnhealthy
+	manager.CheckComponent(ctx, "database")
+	status = StatusHealthy
+	manager.CheckComponent(ctx, "database")
+	if len(provider.resolved) != 1 || provider.resolved[0] != "payments:health:database" {
+		t.Errorf("expected database incident to be resolved, got %v", provider.resolved)
+	}
+}
+
+func TestIncidentHook_OnError(t *testing.T) {
+	var reported error
+	hook := NewIncidentHook(IncidentHookConfig{
+		Provider: &recordingProvider{err: errors.New("boom")},
+		OnError:  func(err error) { reported = err },
+	})
+
+	hook(context.Background(), StatusChange{Component: "queue", Current: HealthStatus{Status: StatusUnhealthy}})
+	if reported == nil || reported.Error() != "health incident for queue: boom" {
+		t.Errorf("unexpected error %v", reported)
+	}
+}
+
+func TestPagerDutyProvider(t *testing.T) {
+	client := &recordingHTTPClient{status: 202}
+	provider := NewPagerDutyProvider(PagerDutyConfig{RoutingKey: "rk", HTTPClient: client})
+
+	ctx := context.Background()
+	err := provider.Trigger(ctx, Incident{
+		DedupKey:  "payments:health:database",
+		Summary:   "database is unhealthy",
+		Source:    "payments",
+		Component: "database",
+		Status:    HealthStatus{Status: StatusUnhealthy, Error: "timeout"},
+	})
+	if err != nil {
+		t.Fatalf("trigger failed: %v", err)
+	}
+	if err := provider.Resolve(ctx, "payments:health:database"); err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+
+	if client.requests[0].URL.String() != DefaultPagerDutyEventsURL {
+		t.Errorf("unexpected URL %s", client.requests[0].URL)
+	}
+	trigger := client.bodies[0]
+	if trigger["event_action"] != "trigger" || trigger["dedup_key"] != "payments:health:database" || trigger["routing_key"] != "rk" {
+		t.Errorf("unexpected trigger event %v", trigger)
+	}
+	payload := trigger["payload"].(map[string]any)
+	if payload["severity"] != "critical" || payload["component"] != "database" {
+		t.Errorf("unexpected payload %v", payload)
+	}
+	if payload["custom_details"].(map[string]any)["error"] != "timeout" {
+		t.Errorf("expected error in custom details, got %v", payload["custom_details"])
+	}
+	if client.bodies[1]["event_action"] != "resolve" {
+		t.Errorf("unexpected resolve event %v", client.bodies[1])
+	}
+
+	client.status = 429
+	if err := provider.Resolve(ctx, "key"); err == nil || err.Error() != "pagerduty returned status 429" {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+func TestOpsGenieProvider(t *testing.T) {
+	client := &recordingHTTPClient{status: 202}
+	provider := NewOpsGenieProvider(OpsGenieConfig{
+		APIKey:     "key",
+		URL:        "https://api.eu.opsgenie.com",
+		HTTPClient: client,
+	})
+
+	ctx := context.Background()
+	provider.Trigger(ctx, Incident{DedupKey: "payments:health:database", Summary: "database is unhealthy", Component: "database"})
+	provider.Resolve(ctx, "payments:health:database")
+
+	create := client.requests[0]
+	if create.URL.String() != "https://api.eu.opsgenie.com/v2/alerts" || create.Header.Get("Authorization") != "GenieKey key" {
+		t.Errorf("unexpected create request %s %v", create.URL, create.Header)
+	}
+	if client.bodies[0]["alias"] != "payments:health:database" || client.bodies[0]["priority"] != "P1" {
+		t.Errorf("unexpected alert %v", client.bodies[0])
+	}
+
+	closeURL := client.requests[1].URL.String()
+	if closeURL != "https://api.eu.opsgenie.com/v2/alerts/payments:health:database/close?identifierType=alias" {
+		t.Errorf("unexpected close URL %s", closeURL)
+	}
+}
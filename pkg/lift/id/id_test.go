@@ -0,0 +1,167 @@
+package id
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestULIDRoundTrip(t *testing.T) {
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	gen := NewGenerator(func() time.Time { return at }, nil)
+
+	u := gen.ULID()
+	s := u.String()
+	assert.Len(t, s, 26)
+	assert.Equal(t, at, u.Time().UTC())
+
+	parsed, err := ParseULID(s)
+	require.NoError(t, err)
+	assert.Equal(t, u, parsed)
+
+	parsed, err = ParseULID(strings.ToLower(s))
+	require.NoError(t, err)
+	assert.Equal(t, u, parsed)
+
+	// The spec's example
+	parsed, err = ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1469922850259), parsed.Time().UnixMilli())
+	assert.Equal(t, "01ARZ3NDEKTSV4RRFFQ69G5FAV", parsed.String())
+}
+
+func TestParseULIDErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"01ARZ3NDEKTSV4RRFFQ69G5FA",
+		"81ARZ3NDEKTSV4RRFFQ69G5FAV",
+		"01ARZ3NDEKTSV4RRFFQ69G5FAU",
+	} {
+		_, err := ParseULID(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestUUIDRoundTrip(t *testing.T) {
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	gen := NewGenerator(func() time.Time { return at }, nil)
+
+	u := gen.UUID()
+	s := u.String()
+	assert.Equal(t, 7, u.Version())
+	assert.Equal(t, byte('7'), s[14])
+	assert.Contains(t, "89ab", string(s[19]))
+	assert.Equal(t, at, u.Time().UTC())
+
+	parsed, err := ParseUUID(s)
+	require.NoError(t, err)
+	assert.Equal(t, u, parsed)
+
+	_, err = ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	assert.Error(t, err, "version 1 UUIDs are rejected")
+	_, err = ParseUUID("not-a-uuid")
+	assert.Error(t, err)
+}
+
+func TestIDsAreMonotonic(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	gen := NewGenerator(func() time.Time { return now }, nil)
+
+	var ulids, uuids []string
+	for i := 0; i < 1000; i++ {
+		switch i {
+		case 300:
+			now = now.Add(time.Millisecond)
+		case 600:
+			// The clock stepping back doesn't break the order
+			now = now.Add(-time.Second)
+		}
+		ulids = append(ulids, gen.ULID().String())
+		uuids = append(uuids, gen.UUID().String())
+	}
+
+	assert.True(t, sort.StringsAreSorted(ulids))
+	assert.True(t, sort.StringsAreSorted(uuids))
+	assert.Len(t, unique(ulids), 1000)
+	assert.Len(t, unique(uuids), 1000)
+}
+
+func TestCounterOverflowCarriesIntoTimestamp(t *testing.T) {
+	at := time.UnixMilli(1000)
+	gen := NewGenerator(func() time.Time { return at }, bytes.NewReader(bytes.Repeat([]byte{0xff}, 32)))
+
+	first := gen.ULID()
+	second := gen.ULID()
+	assert.Equal(t, -1, first.Compare(second))
+	assert.Equal(t, int64(1001), second.Time().UnixMilli())
+
+	var u UUID
+	putTimestamp(u[:], 1000)
+	for i := 6; i < 16; i++ {
+		u[i] = 0xff
+	}
+	u[6], u[8] = 0x7f, 0xbf
+	before := u
+	u.incrementRandom()
+	assert.Equal(t, -1, before.Compare(u))
+	assert.Equal(t, 7, u.Version())
+	assert.Equal(t, byte(0x80), u[8]&0xc0)
+	assert.Equal(t, int64(1001), u.Time().UnixMilli())
+}
+
+func TestPrefixedIDs(t *testing.T) {
+	customer := New("cust")
+	assert.True(t, strings.HasPrefix(customer, "cust_"))
+	assert.NoError(t, Validate(customer, "cust"))
+	assert.Error(t, Validate(customer, "ord"))
+
+	prefix, u, err := Parse(New("ledger_entry"))
+	require.NoError(t, err)
+	assert.Equal(t, "ledger_entry", prefix)
+	assert.False(t, u.IsZero())
+
+	for _, s := range []string{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "_01ARZ3NDEKTSV4RRFFQ69G5FAV", "cust_123"} {
+		_, _, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestULIDRange(t *testing.T) {
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	u := NewGenerator(func() time.Time { return at }, nil).ULID()
+
+	assert.Equal(t, 1, u.Compare(MinULID(at)))
+	assert.Equal(t, -1, u.Compare(MaxULID(at)))
+	assert.Equal(t, -1, MaxULID(at).Compare(MinULID(at.Add(time.Millisecond))))
+}
+
+func TestJSON(t *testing.T) {
+	type order struct {
+		ID   ULID `json:"id"`
+		Ref  UUID `json:"ref"`
+		Name string
+	}
+	in := order{ID: NewULID(), Ref: NewUUID(), Name: "test"}
+
+	data, err := json.Marshal(in)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), in.ID.String())
+
+	var out order
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+}
+
+func unique(values []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
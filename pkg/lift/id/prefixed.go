@@ -0,0 +1,35 @@
+package id
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Separator separates a prefixed ID's prefix from its ULID
+const Separator = "_"
+
+// Parse splits a prefixed ID into its prefix and ULID
+func Parse(s string) (prefix string, u ULID, err error) {
+	i := strings.LastIndex(s, Separator)
+	if i <= 0 {
+		return "", ULID{}, fmt.Errorf("invalid ID %q: no prefix", s)
+	}
+	u, err = ParseULID(s[i+len(Separator):])
+	if err != nil {
+		return "", ULID{}, fmt.Errorf("invalid ID %q: %w", s, err)
+	}
+	return s[:i], u, nil
+}
+
+// Validate checks that s is an ID with the given prefix, e.g. to reject
+// an order ID passed where a customer ID belongs
+func Validate(s, prefix string) error {
+	got, _, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	if got != prefix {
+		return fmt.Errorf("invalid ID %q: expected prefix %q", s, prefix)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package id
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// ULID is a universally unique lexicographically sortable identifier: a
+// 48-bit millisecond timestamp followed by 80 random bits, written as 26
+// characters of Crockford's base32
+type ULID [16]byte
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var crockfordValues = func() [256]byte {
+	var values [256]byte
+	for i := range values {
+		values[i] = 0xff
+	}
+	for i := 0; i < len(crockford); i++ {
+		values[crockford[i]] = byte(i)
+		values[crockford[i]|0x20] = byte(i) // lower case
+	}
+	// Crockford's aliases for easily confused characters
+	for alias, value := range map[byte]byte{'O': 0, 'o': 0, 'I': 1, 'i': 1, 'L': 1, 'l': 1} {
+		values[alias] = value
+	}
+	return values
+}()
+
+// ParseULID parses a ULID, accepting lower case and Crockford's aliases
+// (O for 0, I and L for 1)
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	if len(s) != 26 {
+		return u, fmt.Errorf("invalid ULID %q: must be 26 characters", s)
+	}
+	// 26 characters hold 130 bits, so the first can't exceed 7
+	if crockfordValues[s[0]] > 7 {
+		return u, fmt.Errorf("invalid ULID %q: out of range", s)
+	}
+
+	var bit uint
+	for i := 0; i < len(s); i++ {
+		value := crockfordValues[s[i]]
+		if value == 0xff {
+			return u, fmt.Errorf("invalid ULID %q: invalid character %q", s, s[i])
+		}
+		for j := 4; j >= 0; j-- {
+			// The first two bits of the encoding are padding
+			if bit >= 2 && value&(1<<j) != 0 {
+				pos := bit - 2
+				u[pos/8] |= 0x80 >> (pos % 8)
+			}
+			bit++
+		}
+	}
+	return u, nil
+}
+
+// String returns the canonical upper case encoding
+func (u ULID) String() string {
+	var out [26]byte
+	var bit uint
+	for i := range out {
+		var value byte
+		for j := 0; j < 5; j++ {
+			value <<= 1
+			if bit >= 2 {
+				pos := bit - 2
+				value |= (u[pos/8] >> (7 - pos%8)) & 1
+			}
+			bit++
+		}
+		out[i] = crockford[value]
+	}
+	return string(out[:])
+}
+
+// Time returns when the ULID was generated, to the millisecond
+func (u ULID) Time() time.Time {
+	return time.UnixMilli(int64(u.timestamp()))
+}
+
+// IsZero reports whether u is the zero ULID
+func (u ULID) IsZero() bool {
+	return u == ULID{}
+}
+
+// Compare returns -1, 0 or 1 as u sorts before, with or after other
+func (u ULID) Compare(other ULID) int {
+	return compareBytes(u[:], other[:])
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u ULID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *ULID) UnmarshalText(text []byte) error {
+	parsed, err := ParseULID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// Value implements driver.Valuer
+func (u ULID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+func (u ULID) timestamp() uint64 {
+	return readTimestamp(u[:])
+}
+
+// MinULID returns the smallest ULID generated at t, e.g. as the lower bound
+// of a DynamoDB key range for items created since t
+func MinULID(t time.Time) ULID {
+	var u ULID
+	putTimestamp(u[:], uint64(t.UnixMilli()))
+	return u
+}
+
+// MaxULID returns the largest ULID generated at t
+func MaxULID(t time.Time) ULID {
+	u := MinULID(t)
+	for i := 6; i < len(u); i++ {
+		u[i] = 0xff
+	}
+	return u
+}
+
+func compareBytes(a, b []byte) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
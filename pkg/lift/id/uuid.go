@@ -0,0 +1,120 @@
+package id
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// UUID is an RFC 9562 version 7 UUID: a 48-bit millisecond timestamp,
+// followed by random bits around the version and variant fields
+type UUID [16]byte
+
+// ParseUUID parses a version 7 UUID in its hyphenated form
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	digits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %w", s, err)
+	}
+	if u.Version() != 7 {
+		return UUID{}, fmt.Errorf("invalid UUID %q: version %d, not 7", s, u.Version())
+	}
+	if u[8]&0xc0 != 0x80 {
+		return UUID{}, fmt.Errorf("invalid UUID %q: not an RFC 9562 variant", s)
+	}
+	return u, nil
+}
+
+// String returns the lower case hyphenated form
+func (u UUID) String() string {
+	var out [36]byte
+	hex.Encode(out[0:8], u[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], u[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], u[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], u[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], u[10:])
+	return string(out[:])
+}
+
+// Version returns the UUID version, 7 for UUIDs from this package
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// Time returns when the UUID was generated, to the millisecond
+func (u UUID) Time() time.Time {
+	return time.UnixMilli(int64(u.timestamp()))
+}
+
+// IsZero reports whether u is the nil UUID
+func (u UUID) IsZero() bool {
+	return u == UUID{}
+}
+
+// Compare returns -1, 0 or 1 as u sorts before, with or after other
+func (u UUID) Compare(other UUID) int {
+	return compareBytes(u[:], other[:])
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(text []byte) error {
+	parsed, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// Value implements driver.Valuer
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+func (u UUID) timestamp() uint64 {
+	return readTimestamp(u[:])
+}
+
+// incrementRandom adds one to the 74 random bits, skipping the version
+// and variant fields, and carries into the timestamp when they overflow
+func (u *UUID) incrementRandom() {
+	// Bits 0-61 of the counter are the variant octet's low six bits and
+	// the last seven bytes; bits 62-73 are the low 12 bits of bytes 6-7
+	for i := 15; i >= 9; i-- {
+		u[i]++
+		if u[i] != 0 {
+			return
+		}
+	}
+	if u[8]&0x3f != 0x3f {
+		u[8]++
+		return
+	}
+	u[8] &^= 0x3f
+
+	if u[7] != 0xff {
+		u[7]++
+		return
+	}
+	u[7] = 0
+	if u[6]&0x0f != 0x0f {
+		u[6]++
+		return
+	}
+	u[6] &^= 0x0f
+	putTimestamp(u[:], u.timestamp()+1)
+}
@@ -0,0 +1,154 @@
+package lift
+
+import (
+	"context"
+	"maps"
+	"runtime/debug"
+	"time"
+)
+
+// Latency budget metric names
+const (
+	// BudgetExceededMetric counts requests whose handler ran past the
+	// route's MetaLatencyBudget
+	BudgetExceededMetric = "handler.budget_exceeded"
+	// HandlerTimeoutMetric counts requests aborted at the route's MetaTimeout
+	HandlerTimeoutMetric = "handler.timeout"
+)
+
+// withLatencyBudget enforces the latency budget and hard timeout declared
+// in the route's metadata:
+//
+//	app.GET("/quotes/:id", getQuote).
+//		Meta(lift.MetaLatencyBudget, "200ms").
+//		Meta(lift.MetaTimeout, "2s")
+//
+// A handler that runs past its budget still succeeds, but is logged and
+// counted under BudgetExceededMetric. One that runs past its timeout is
+// abandoned with 504 HANDLER_TIMEOUT; its context is cancelled so
+// downstream calls made with it give up too, and nothing it writes
+// afterwards reaches the response. Metadata that doesn't parse
+// as a positive duration is ignored.
+func withLatencyBudget(route *Route, handler Handler) Handler {
+	budget := routeDuration(route, MetaLatencyBudget)
+	timeout := routeDuration(route, MetaTimeout)
+	if budget == 0 && timeout == 0 {
+		return handler
+	}
+
+	return HandlerFunc(func(ctx *Context) error {
+		start := time.Now()
+		var err error
+		if timeout > 0 {
+			err = handleWithTimeout(ctx, handler, timeout)
+		} else {
+			err = handler.Handle(ctx)
+		}
+
+		elapsed := time.Since(start)
+		if budget > 0 && elapsed > budget {
+			ctx.recordLatency(BudgetExceededMetric, "Handler exceeded its latency budget", map[string]any{
+				"budget":   budget.String(),
+				"duration": elapsed.String(),
+			})
+		}
+		return err
+	})
+}
+
+// handleWithTimeout runs the handler against a shadow of ctx, abandoning
+// it once the timeout passes. Only a handler that finishes in time has its
+// response and context changes copied back, so an abandoned handler, which
+// keeps running until it notices its context is done, can't race with or
+// overwrite the 504. A panic in the handler is returned as a *PanicError
+// carrying the handler goroutine's stack.
+func handleWithTimeout(ctx *Context, handler Handler, timeout time.Duration) error {
+	parent := ctx.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	deadline, cancel := context.WithTimeout(parent, timeout)
+	shadow := ctx.shadow(deadline)
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- NewPanicError(r, debug.Stack())
+			}
+		}()
+		done <- handler.Handle(shadow)
+	}()
+
+	select {
+	case err := <-done:
+		cancel()
+		ctx.adoptShadow(shadow, parent)
+		return err
+	case <-deadline.Done():
+		cancel()
+		ctx.recordLatency(HandlerTimeoutMetric, "Handler timed out", map[string]any{
+			"timeout": timeout.String(),
+		})
+		return NewLiftError("HANDLER_TIMEOUT", "Request timed out", 504).
+			WithDetail("timeout", timeout.String()).
+			WithCause(deadline.Err())
+	}
+}
+
+// shadow copies the context for a handler that may be abandoned, with its
+// own response, values and parameters
+func (c *Context) shadow(deadline context.Context) *Context {
+	shadow := *c
+	shadow.Context = deadline
+	shadow.Response = &Response{
+		StatusCode:      c.Response.StatusCode,
+		Body:            c.Response.Body,
+		Headers:         maps.Clone(c.Response.Headers),
+		IsBase64Encoded: c.Response.IsBase64Encoded,
+		written:         c.Response.written,
+	}
+	shadow.values = maps.Clone(c.values)
+	shadow.params = maps.Clone(c.params)
+	if c.bufferingEnabled {
+		shadow.responseBuffer = NewResponseBuffer()
+	}
+	return &shadow
+}
+
+// adoptShadow takes over a finished shadow's changes, keeping the
+// Response and buffer that middleware may already hold and restoring the
+// context the deadline was derived from
+func (c *Context) adoptShadow(shadow *Context, parent context.Context) {
+	response, buffer := c.Response, c.responseBuffer
+	*c = *shadow
+	c.Context = parent
+	*response = *shadow.Response
+	c.Response = response
+	c.responseBuffer = buffer
+	c.captureResponseData()
+}
+
+// routeDuration returns a duration from the route's metadata, or 0 when
+// it isn't set or isn't a positive duration
+func routeDuration(route *Route, key string) time.Duration {
+	raw, ok := route.GetMeta(key)
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+func (c *Context) recordLatency(metric, message string, fields map[string]any) {
+	if c.Metrics != nil {
+		c.Metrics.Counter(metric, map[string]string{"route": c.Request.Method + " " + c.route}).Inc()
+	}
+	if c.Logger != nil {
+		fields["route"] = c.Request.Method + " " + c.route
+		c.Logger.Warn(message, fields)
+	}
+}
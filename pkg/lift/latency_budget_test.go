@@ -0,0 +1,97 @@
+package lift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyBudget(t *testing.T) {
+	app := New()
+	app.GET("/fast", func(ctx *Context) error { return ctx.Text("ok") }).
+		Meta(MetaLatencyBudget, "1s")
+	app.GET("/slow", func(ctx *Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return ctx.Text("ok")
+	}).Meta(MetaLatencyBudget, "5ms")
+
+	metrics := &countingMetrics{}
+	for _, path := range []string{"/fast", "/slow"} {
+		ctx := newMountContext("GET", path)
+		ctx.Metrics = metrics
+		require.NoError(t, app.HandleTestRequest(ctx))
+		assert.Equal(t, 200, ctx.Response.StatusCode, path)
+	}
+	assert.Equal(t, 1, metrics.counts[BudgetExceededMetric])
+}
+
+func TestLatencyTimeout(t *testing.T) {
+	app := New()
+	cancelled := make(chan struct{})
+	app.GET("/reports", func(ctx *Context) error {
+		<-ctx.Done()
+		// A late write lands on the abandoned handler's own response
+		ctx.Response.Header("X-Late", "true")
+		err := ctx.Text("late")
+		close(cancelled)
+		return err
+	}).Meta(MetaTimeout, "10ms")
+	app.GET("/quotes", func(ctx *Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		return ctx.Text("ok")
+	}).Meta(MetaTimeout, "1s")
+
+	metrics := &countingMetrics{}
+	ctx := newMountContext("GET", "/reports")
+	ctx.Metrics = metrics
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 504, ctx.Response.StatusCode)
+	assert.Equal(t, 1, metrics.counts[HandlerTimeoutMetric])
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("timed out handler's context wasn't cancelled")
+	}
+	assert.Equal(t, 504, ctx.Response.StatusCode)
+	assert.Empty(t, ctx.Response.Headers["X-Late"])
+
+	ctx = newMountContext("GET", "/quotes")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline, "context is restored after the handler returns")
+}
+
+func TestLatencyTimeoutPanics(t *testing.T) {
+	var observed error
+	app := New(WithErrorObserver(func(ctx *Context, err error) {
+		observed = err
+	}))
+	app.GET("/boom", func(ctx *Context) error { panic("boom") }).Meta(MetaTimeout, "1s")
+
+	ctx := newMountContext("GET", "/boom")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 500, ctx.Response.StatusCode)
+
+	var panicErr *PanicError
+	require.ErrorAs(t, observed, &panicErr, "the handler's panic is returned as a PanicError")
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.Contains(t, string(panicErr.Stack), "latency_budget_test.go", "the stack is the handler goroutine's")
+}
+
+func TestLatencyBudgetIgnoresInvalidMetadata(t *testing.T) {
+	app := New()
+	app.GET("/reports", func(ctx *Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+		return ctx.Text("ok")
+	}).Meta(MetaTimeout, "soon").Meta(MetaLatencyBudget, "-1s")
+
+	ctx := newMountContext("GET", "/reports")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+}
@@ -0,0 +1,252 @@
+package lift
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// MountOption configures how a sub-application is mounted
+type MountOption func(*mount)
+
+// WithMountNamespace overrides the namespace used for a mounted application's
+// logs and metrics. It defaults to the mount prefix, e.g. "payments" for "/payments".
+func WithMountNamespace(namespace string) MountOption {
+	return func(m *mount) {
+		m.namespace = namespace
+	}
+}
+
+// mount is a sub-application mounted into a parent
+type mount struct {
+	prefix    string
+	namespace string
+	app       *App
+}
+
+// Mount composes an independently developed application into this one, so
+// several services can be deployed as a single Lambda. Every HTTP route of sub
+// is registered under prefix and its event routes are merged as-is. Requests
+// to mounted routes run this application's middleware first, then sub's own
+// middleware, with the logger tagged with the namespace and metric names
+// prefixed by it. Sub's Pre middleware runs before routing for requests
+// under prefix, seeing the path without it.
+//
+// Routes and their metadata are copied when Mount is called, so register them
+// on sub first. Middleware added to sub afterwards is still applied.
+func (a *App) Mount(prefix string, sub *App, opts ...MountOption) error {
+	if sub == nil || sub == a {
+		return fmt.Errorf("cannot mount application at %q: invalid sub-application", prefix)
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		return fmt.Errorf("mount prefix must start with '/': %q", prefix)
+	}
+
+	m := &mount{
+		prefix:    prefix,
+		namespace: strings.ReplaceAll(strings.Trim(prefix, "/"), "/", "."),
+		app:       sub,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.namespace == "" {
+		return fmt.Errorf("a namespace is required to mount an application at the root")
+	}
+
+	// Routes that failed to register in the sub-application would be
+	// silently missing from the parent
+	sub.mu.RLock()
+	routeErrs := sub.routeErrs
+	sub.mu.RUnlock()
+	if len(routeErrs) > 0 {
+		return fmt.Errorf("cannot mount %q: %w", m.namespace, errors.Join(routeErrs...))
+	}
+
+	// Check every route before registering any so a conflict leaves the
+	// parent untouched
+	var conflicts []string
+	sub.router.forEachRoute(func(method, pattern string, _ Handler) {
+		if a.router.hasRoute(method, m.path(pattern)) {
+			conflicts = append(conflicts, method+" "+m.path(pattern))
+		}
+	})
+	if len(conflicts) > 0 {
+		return fmt.Errorf("cannot mount %q: routes already registered: %s", m.namespace, strings.Join(conflicts, ", "))
+	}
+
+	sub.router.forEachRoute(func(method, pattern string, handler Handler) {
+		route := newRoute(method, m.path(pattern))
+		info := sub.router.routeInfo(method, pattern)
+		for k, v := range info.Metadata() {
+			route.Meta(k, v)
+		}
+		if info != nil {
+			route.signature = info.signature
+		}
+		a.router.AddRoute(method, route.Path, m.wrap(handler))
+		a.router.setRouteInfo(route)
+		a.addRoute(route)
+	})
+	for triggerType, routes := range sub.eventRouter.GetRoutes() {
+		for _, route := range routes {
+			a.eventRouter.addRoute(&EventRoute{
+				TriggerType: triggerType,
+				Pattern:     route.Pattern,
+				Handler:     m.wrapEvent(route.Handler),
+				Match:       route.Match,
+			})
+		}
+	}
+	for _, route := range sub.Routes() {
+		if triggerType := parseTriggerType(route.Method); triggerType != TriggerAPIGateway && triggerType != TriggerUnknown {
+			a.addRoute(route)
+		}
+	}
+
+	a.mu.Lock()
+	a.mounts = append(a.mounts, m)
+	a.preRouting = append(a.preRouting, m.preRoute)
+	a.mu.Unlock()
+	return nil
+}
+
+// Mounts returns the namespaces of mounted applications keyed by prefix
+func (a *App) Mounts() map[string]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	mounts := make(map[string]string, len(a.mounts))
+	for _, m := range a.mounts {
+		mounts[m.prefix] = m.namespace
+	}
+	return mounts
+}
+
+// path returns the mounted path for a route pattern of the sub-application
+func (m *mount) path(pattern string) string {
+	if pattern == "/" && m.prefix != "" {
+		return m.prefix
+	}
+	return m.prefix + pattern
+}
+
+// subPath returns the sub-application's path for a request path under the
+// mount prefix
+func (m *mount) subPath(path string) (string, bool) {
+	switch {
+	case m.prefix == "":
+		return path, true
+	case path == m.prefix:
+		return "/", true
+	case strings.HasPrefix(path, m.prefix+"/"):
+		return path[len(m.prefix):], true
+	}
+	return "", false
+}
+
+// preRoute runs the sub-application's Pre middleware for requests under the
+// mount prefix. The middleware sees the sub-application's path, and the
+// prefix is put back before the parent matches the route.
+func (m *mount) preRoute(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) error {
+		path, ok := m.subPath(ctx.Request.Path)
+		if !ok || len(m.app.preRouting) == 0 {
+			return next.Handle(ctx)
+		}
+
+		handler := Handler(HandlerFunc(func(ctx *Context) error {
+			ctx.Request.Path = m.path(ctx.Request.Path)
+			return next.Handle(ctx)
+		}))
+		for i := len(m.app.preRouting) - 1; i >= 0; i-- {
+			handler = m.app.preRouting[i](handler)
+		}
+		ctx.Request.Path = path
+		return handler.Handle(ctx)
+	})
+}
+
+// wrap applies the sub-application's middleware and namespace to a handler
+func (m *mount) wrap(handler Handler) Handler {
+	return HandlerFunc(func(ctx *Context) error {
+		final := handler
+		for i := len(m.app.middleware) - 1; i >= 0; i-- {
+			final = m.app.middleware[i](final)
+		}
+		return m.run(ctx, final.Handle)
+	})
+}
+
+// wrapEvent applies the sub-application's namespace and error handling to an
+// event handler. It adds no middleware: routes registered with helpers that
+// run the app's middleware, such as EventBridgeMatch or Stream, already run
+// the sub-application's, and plain SQS, S3 and EventBridge routes run without
+// any, as they would on the sub-application.
+func (m *mount) wrapEvent(handler EventHandler) EventHandler {
+	return EventHandlerFunc(func(ctx *Context) error {
+		return m.run(ctx, handler.HandleEvent)
+	})
+}
+
+// run executes fn with the sub-application's dependencies on the context and
+// restores the parent's afterwards, so the parent's middleware observes the
+// request with its own logger and metrics. Errors, including panics, go to
+// the sub-application's observers and error handlers before the parent's.
+func (m *mount) run(ctx *Context, fn func(*Context) error) (err error) {
+	logger, metrics, db := ctx.Logger, ctx.Metrics, ctx.DB
+	defer func() {
+		ctx.Logger, ctx.Metrics, ctx.DB = logger, metrics, db
+	}()
+
+	if m.app.logger != nil {
+		ctx.Logger = m.app.logger
+	}
+	if ctx.Logger != nil {
+		ctx.Logger = ctx.Logger.WithField("app", m.namespace)
+	}
+	if m.app.metrics != nil {
+		ctx.Metrics = m.app.metrics
+	}
+	if ctx.Metrics != nil {
+		ctx.Metrics = &namespacedMetrics{collector: ctx.Metrics, prefix: m.namespace + "."}
+	}
+	if m.app.db != nil {
+		ctx.DB = m.app.db
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r, debug.Stack())
+		}
+		if err != nil {
+			m.app.notifyErrorObservers(ctx, err)
+			err = m.app.mapError(ctx, err)
+		}
+	}()
+	return fn(ctx)
+}
+
+// namespacedMetrics prefixes every metric name with the mount namespace
+type namespacedMetrics struct {
+	collector MetricsCollector
+	prefix    string
+}
+
+func (n *namespacedMetrics) Counter(name string, tags ...map[string]string) Counter {
+	return n.collector.Counter(n.prefix+name, tags...)
+}
+
+func (n *namespacedMetrics) Histogram(name string, tags ...map[string]string) Histogram {
+	return n.collector.Histogram(n.prefix+name, tags...)
+}
+
+func (n *namespacedMetrics) Gauge(name string, tags ...map[string]string) Gauge {
+	return n.collector.Gauge(n.prefix+name, tags...)
+}
+
+func (n *namespacedMetrics) Flush() error {
+	return n.collector.Flush()
+}
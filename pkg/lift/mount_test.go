@@ -0,0 +1,246 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fieldLogger struct {
+	NoOpLogger
+	fields map[string]any
+}
+
+func (l *fieldLogger) WithField(key string, value any) Logger {
+	fields := map[string]any{key: value}
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	return &fieldLogger{fields: fields}
+}
+
+type nameRecordingMetrics struct {
+	NoOpMetrics
+	names []string
+}
+
+func (m *nameRecordingMetrics) Counter(name string, tags ...map[string]string) Counter {
+	m.names = append(m.names, name)
+	return &NoOpCounter{}
+}
+
+func newMountContext(method, path string) *Context {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method: method,
+		Path:   path,
+	}))
+	ctx.Logger = &fieldLogger{}
+	return ctx
+}
+
+func TestMount(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx *Context) error {
+				order = append(order, name)
+				return next.Handle(ctx)
+			})
+		}
+	}
+
+	payments := New()
+	payments.Use(trace("payments"))
+	require.NoError(t, payments.GET("/charges/:id", func(ctx *Context) error {
+		ctx.Metrics.Counter("charges.read").Inc()
+		logger := ctx.Logger.(*fieldLogger)
+		return ctx.JSON(map[string]any{"id": ctx.Param("id"), "app": logger.fields["app"]})
+	}).Err())
+	require.NoError(t, payments.GET("/", func(ctx *Context) error {
+		return ctx.Text("payments")
+	}).Err())
+
+	metrics := &nameRecordingMetrics{}
+	app := New()
+	app.WithMetrics(metrics)
+	app.Use(trace("root"))
+	require.NoError(t, app.GET("/health", func(ctx *Context) error {
+		return ctx.Text("ok")
+	}).Err())
+	require.NoError(t, app.Mount("/payments", payments))
+
+	ctx := newMountContext("GET", "/payments/charges/ch_1")
+	ctx.Metrics = metrics
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	assert.Equal(t, map[string]any{"id": "ch_1", "app": "payments"}, ctx.Response.Body)
+	assert.Equal(t, []string{"root", "payments"}, order)
+	assert.Equal(t, []string{"payments.charges.read"}, metrics.names)
+
+	// The parent's dependencies are restored once the mounted handler returns
+	assert.Same(t, metrics, ctx.Metrics)
+	assert.Nil(t, ctx.Logger.(*fieldLogger).fields)
+
+	ctx = newMountContext("GET", "/payments")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, "payments", ctx.Response.Body)
+
+	// Sub-app middleware doesn't leak into the parent's own routes
+	order = nil
+	ctx = newMountContext("GET", "/health")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, []string{"root"}, order)
+
+	assert.Equal(t, map[string]string{"/payments": "payments"}, app.Mounts())
+}
+
+func TestMountConflicts(t *testing.T) {
+	sub := New()
+	require.NoError(t, sub.GET("/charges", func(ctx *Context) error { return nil }).Err())
+	require.NoError(t, sub.POST("/refunds", func(ctx *Context) error { return nil }).Err())
+
+	app := New()
+	require.NoError(t, app.GET("/payments/charges", func(ctx *Context) error { return nil }).Err())
+
+	err := app.Mount("/payments", sub)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GET /payments/charges")
+
+	// Nothing from the sub-app is registered when the mount fails
+	assert.False(t, app.router.hasRoute("POST", "/payments/refunds"))
+	assert.Empty(t, app.Mounts())
+
+	assert.Error(t, app.Mount("payments", sub))
+	assert.Error(t, app.Mount("", sub))
+	assert.Error(t, app.Mount("/self", app))
+	require.NoError(t, app.Mount("", sub, WithMountNamespace("billing")))
+	assert.True(t, app.router.hasRoute("POST", "/refunds"))
+}
+
+func TestMountConflictsIgnoreParamNames(t *testing.T) {
+	sub := New()
+	require.NoError(t, sub.GET("/charges/:pid", func(ctx *Context) error { return nil }).Err())
+
+	app := New()
+	require.NoError(t, app.GET("/payments/charges/:id", func(ctx *Context) error { return nil }).Err())
+
+	err := app.Mount("/payments", sub)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GET /payments/charges/:pid")
+}
+
+func TestMountRunsSubAppPreMiddleware(t *testing.T) {
+	var seen []string
+	sub := New()
+	sub.Pre(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			seen = append(seen, ctx.Request.Path)
+			if ctx.Request.Path == "/v1/charges/ch_1" {
+				ctx.Request.Path = "/charges/ch_1"
+			}
+			return next.Handle(ctx)
+		})
+	})
+	require.NoError(t, sub.GET("/charges/:id", func(ctx *Context) error {
+		return ctx.Text(ctx.Param("id"))
+	}).Err())
+
+	app := New()
+	require.NoError(t, app.GET("/health", func(ctx *Context) error {
+		return ctx.Text("ok")
+	}).Err())
+	require.NoError(t, app.Mount("/payments", sub))
+
+	ctx := newMountContext("GET", "/payments/v1/charges/ch_1")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	assert.Equal(t, "ch_1", ctx.Response.Body)
+	assert.Equal(t, []string{"/v1/charges/ch_1"}, seen, "Pre middleware sees the sub-application's path")
+
+	// Requests outside the prefix skip it
+	ctx = newMountContext("GET", "/health")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, "ok", ctx.Response.Body)
+	assert.Len(t, seen, 1)
+}
+
+func TestMountRejectsSubAppRouteErrors(t *testing.T) {
+	sub := New()
+	require.Error(t, sub.GET("/bad", 42).Err())
+
+	app := New()
+	err := app.Mount("/payments", sub)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GET /bad")
+	assert.Empty(t, app.Routes())
+}
+
+func TestMountNotifiesSubAppErrorObservers(t *testing.T) {
+	var observed []error
+	sub := New(WithErrorObserver(func(ctx *Context, err error) {
+		observed = append(observed, err)
+	}))
+	require.NoError(t, sub.GET("/fail", func(ctx *Context) error {
+		return errors.New("boom")
+	}).Err())
+
+	app := New()
+	require.NoError(t, app.Mount("/ledger", sub))
+
+	ctx := newMountContext("GET", "/ledger/fail")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 500, ctx.Response.StatusCode)
+	require.Len(t, observed, 1)
+	assert.EqualError(t, observed[0], "boom")
+}
+
+func TestMountMergesEventRoutes(t *testing.T) {
+	handled := ""
+	sub := New()
+	require.NoError(t, sub.SQS("settlements", func(ctx *Context) error {
+		handled = ctx.Logger.(*fieldLogger).fields["app"].(string)
+		return nil
+	}))
+
+	app := New()
+	require.NoError(t, app.Mount("/settlements", sub))
+
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		TriggerType: adapters.TriggerSQS,
+		Records: []any{map[string]any{
+			"eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:settlements",
+		}},
+	}))
+	ctx.Logger = &fieldLogger{}
+	require.NoError(t, app.GetEventRouter().HandleEvent(ctx))
+	assert.Equal(t, "settlements", handled)
+}
+
+func TestMountKeepsEventRouteMatchers(t *testing.T) {
+	var calls []string
+	sub := New()
+	require.NoError(t, sub.EventBridgeMatch("Order Placed", "com.acme.orders", func(ctx *Context) error {
+		calls = append(calls, "placed")
+		return nil
+	}))
+	require.NoError(t, sub.EventBridgeMatch("Order Shipped", "com.acme.orders", func(ctx *Context) error {
+		calls = append(calls, "shipped")
+		return nil
+	}))
+
+	app := New()
+	require.NoError(t, app.Mount("/orders", sub))
+
+	_, err := app.HandleRequest(context.Background(), eventBridgeEvent("Order Shipped", "com.acme.orders", map[string]any{}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shipped"}, calls)
+
+	result, err := app.HandleRequest(context.Background(), eventBridgeEvent("Invoice Paid", "com.acme.billing", map[string]any{}))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, result.(*Response).StatusCode, 400, "unmatched events have no handler")
+	assert.Equal(t, []string{"shipped"}, calls)
+}
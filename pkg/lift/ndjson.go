@@ -0,0 +1,224 @@
+package lift
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+)
+
+// ContentTypeNDJSON is the media type for newline-delimited JSON
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// maxNDJSONLineSize bounds a single line so a malformed upload can't exhaust memory
+const maxNDJSONLineSize = 1024 * 1024
+
+// NDJSONLineError describes a failure on a single NDJSON line
+type NDJSONLineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *NDJSONLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// NDJSONResult summarizes a line-oriented import
+type NDJSONResult struct {
+	Processed int               `json:"processed"`
+	Failed    int               `json:"failed"`
+	Errors    []NDJSONLineError `json:"errors,omitempty"`
+}
+
+// HasErrors reports whether any line failed
+func (r *NDJSONResult) HasErrors() bool {
+	return r.Failed > 0
+}
+
+// NDJSONDecoder reads newline-delimited JSON one line at a time
+type NDJSONDecoder struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewNDJSONDecoder creates a decoder reading from r
+func NewNDJSONDecoder(r io.Reader) *NDJSONDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+	return &NDJSONDecoder{scanner: scanner}
+}
+
+// Decode reads the next non-blank line into v. It returns io.EOF when the input
+// is exhausted and an *NDJSONLineError when a line is not valid JSON.
+func (d *NDJSONDecoder) Decode(v any) error {
+	for d.scanner.Scan() {
+		d.line++
+		data := bytes.TrimSpace(d.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return &NDJSONLineError{Line: d.line, Message: err.Error()}
+		}
+		return nil
+	}
+
+	if err := d.scanner.Err(); err != nil {
+		return &NDJSONLineError{Line: d.line + 1, Message: err.Error()}
+	}
+	return io.EOF
+}
+
+// Line returns the number of the line most recently read (1-based)
+func (d *NDJSONDecoder) Line() int {
+	return d.line
+}
+
+// DecodeNDJSON decodes every line of r into a T and passes it to fn. Lines that
+// fail to decode, or for which fn returns an error, are recorded in the result
+// and processing continues with the next line. The returned error is only set
+// when the input itself can't be read.
+func DecodeNDJSON[T any](r io.Reader, fn func(line int, item T) error) (*NDJSONResult, error) {
+	result := &NDJSONResult{}
+	decoder := NewNDJSONDecoder(r)
+
+	for {
+		var item T
+		err := decoder.Decode(&item)
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+
+		var lineErr *NDJSONLineError
+		if errors.As(err, &lineErr) {
+			if decoder.scanner.Err() != nil {
+				return result, lineErr
+			}
+			result.fail(lineErr.Line, lineErr.Message)
+			continue
+		}
+
+		if err := fn(decoder.Line(), item); err != nil {
+			result.fail(decoder.Line(), lineErrorMessage(err))
+			continue
+		}
+		result.Processed++
+	}
+}
+
+func (r *NDJSONResult) fail(line int, message string) {
+	r.Failed++
+	r.Errors = append(r.Errors, NDJSONLineError{Line: line, Message: message})
+}
+
+// lineErrorMessage returns a client-safe message for a per-line error
+func lineErrorMessage(err error) string {
+	var liftErr *LiftError
+	if errors.As(err, &liftErr) {
+		return liftErr.Message
+	}
+	return err.Error()
+}
+
+// NDJSONEncoder writes values as newline-delimited JSON
+type NDJSONEncoder struct {
+	w io.Writer
+}
+
+// NewNDJSONEncoder creates an encoder writing to w
+func NewNDJSONEncoder(w io.Writer) *NDJSONEncoder {
+	return &NDJSONEncoder{w: w}
+}
+
+// Encode writes v followed by a newline
+func (e *NDJSONEncoder) Encode(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return NewLiftError("MARSHAL_ERROR", "Failed to marshal NDJSON line", 500).WithCause(err)
+	}
+	data = append(data, '\n')
+	_, err = e.w.Write(data)
+	return err
+}
+
+// IsNDJSON reports whether the request body is newline-delimited JSON
+func (c *Context) IsNDJSON() bool {
+	if c.Request == nil {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(headerValue(c.Request.Headers, "Content-Type"))
+	return err == nil && mediaType == ContentTypeNDJSON
+}
+
+// NDJSONDecoder returns a decoder over the request body
+func (c *Context) NDJSONDecoder() *NDJSONDecoder {
+	var body []byte
+	if c.Request != nil {
+		body = c.Request.Body
+	}
+	return NewNDJSONDecoder(bytes.NewReader(body))
+}
+
+// NDJSON writes the items produced by iter as newline-delimited JSON, streaming
+// the response when the app has response streaming enabled
+func (c *Context) NDJSON(iter StreamIterator) error {
+	if c.Response.IsWritten() {
+		return NewLiftError("RESPONSE_WRITTEN", "Response has already been written", 500)
+	}
+
+	if c.streamingEnabled {
+		return c.streamFrom(ContentTypeNDJSON, func(w io.Writer) error {
+			return writeNDJSON(c.Context, w, iter)
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := writeNDJSON(c.Context, &buf, iter); err != nil {
+		return err
+	}
+
+	c.Response.Body = buf.String()
+	c.Response.Header("Content-Type", ContentTypeNDJSON)
+	c.Response.written = true
+	c.captureResponseData()
+	return nil
+}
+
+// writeNDJSON encodes every item from iter to w, one per line
+func writeNDJSON(ctx context.Context, w io.Writer, iter StreamIterator) error {
+	encoder := NewNDJSONEncoder(w)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		item, err := iter.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+}
+
+// NDJSONImportResult writes an import summary. The status is 200 when every
+// line succeeded, 207 when some lines failed and 422 when all of them did.
+func (c *Context) NDJSONImportResult(result *NDJSONResult) error {
+	status := 200
+	switch {
+	case result.Failed > 0 && result.Processed == 0:
+		status = 422
+	case result.Failed > 0:
+		status = 207
+	}
+	return c.Status(status).JSON(result)
+}
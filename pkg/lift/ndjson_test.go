@@ -0,0 +1,81 @@
+package lift
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ndjsonRecord struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+func TestDecodeNDJSON(t *testing.T) {
+	input := strings.Join([]string{
+		`{"id":"a","amount":100}`,
+		``,
+		`{"id":"b","amount":`,
+		`{"id":"c","amount":-5}`,
+		`{"id":"d","amount":250}`,
+	}, "\n")
+
+	var imported []string
+	result, err := DecodeNDJSON(strings.NewReader(input), func(line int, item ndjsonRecord) error {
+		if item.Amount < 0 {
+			return ValidationError("amount must be positive")
+		}
+		imported = append(imported, item.ID)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "d"}, imported)
+	assert.Equal(t, 2, result.Processed)
+	assert.Equal(t, 2, result.Failed)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 3, result.Errors[0].Line)
+	assert.Equal(t, 4, result.Errors[1].Line)
+	assert.Equal(t, "amount must be positive", result.Errors[1].Message)
+}
+
+func TestContextNDJSON(t *testing.T) {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		Headers: map[string]string{"content-type": "application/x-ndjson; charset=utf-8"},
+		Body:    []byte("{\"id\":\"a\"}\n{\"id\":\"b\"}\n"),
+	}))
+	assert.True(t, ctx.IsNDJSON())
+
+	decoder := ctx.NDJSONDecoder()
+	var record ndjsonRecord
+	require.NoError(t, decoder.Decode(&record))
+	assert.Equal(t, "a", record.ID)
+
+	require.NoError(t, ctx.NDJSON(SliceIterator([]ndjsonRecord{{ID: "x", Amount: 1}, {ID: "y", Amount: 2}})))
+	assert.Equal(t, ContentTypeNDJSON, ctx.Response.Headers["Content-Type"])
+	assert.Equal(t, "{\"id\":\"x\",\"amount\":1}\n{\"id\":\"y\",\"amount\":2}\n", ctx.Response.Body)
+}
+
+func TestNDJSONImportResult(t *testing.T) {
+	tests := []struct {
+		result *NDJSONResult
+		status int
+	}{
+		{&NDJSONResult{Processed: 3}, 200},
+		{&NDJSONResult{Processed: 2, Failed: 1}, 207},
+		{&NDJSONResult{Failed: 2}, 422},
+	}
+
+	for _, tt := range tests {
+		ctx := NewContext(context.Background(), NewRequest(nil))
+		require.NoError(t, ctx.NDJSONImportResult(tt.result))
+		assert.Equal(t, tt.status, ctx.Response.StatusCode)
+	}
+
+	lineErr := &NDJSONLineError{Line: 7, Message: "bad"}
+	assert.Equal(t, "line 7: bad", lineErr.Error())
+}
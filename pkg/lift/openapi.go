@@ -0,0 +1,217 @@
+package lift
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pay-theory/lift/pkg/lift/openapi"
+)
+
+// OpenAPIOptions describes the API in documents built by OpenAPISpec
+type OpenAPIOptions struct {
+	// Title names the API (default: "API")
+	Title string
+	// Version is the API's version, not the OpenAPI version (default: "1.0.0")
+	Version     string
+	Description string
+	Servers     []openapi.Server
+	// SecuritySchemes describes the schemes named by routes' MetaAuth
+	// metadata, e.g. {"jwt": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"}}
+	SecuritySchemes map[string]*openapi.SecurityScheme
+	// Tags describes the tags routes are grouped by. Tags used by routes
+	// but not described here are listed after them.
+	Tags []openapi.Tag
+}
+
+// errorSchemaName is the component schema of error responses
+const errorSchemaName = "Error"
+
+// OpenAPISpec builds an OpenAPI 3.1 document from the registered HTTP
+// routes. Routes registered with SimpleHandler are described in full:
+// their request's `path` and `query` fields become parameters, its other
+// fields the request body, and the response type the success response,
+// with the constraints of `validate` tags. Other routes are listed with
+// their path parameters only. A route's summary, description, tags and
+// deprecation are copied to its operation, and its MetaAuth and MetaScopes
+// become its security requirement.
+func (a *App) OpenAPISpec(opts ...OpenAPIOptions) *openapi.Document {
+	var options OpenAPIOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.Title == "" {
+		options.Title = "API"
+	}
+	if options.Version == "" {
+		options.Version = "1.0.0"
+	}
+
+	generator := openapi.NewGenerator()
+	generator.Schemas()[errorSchemaName] = &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"code":    {Type: "string"},
+			"message": {Type: "string"},
+			"details": {Type: "object"},
+		},
+		Required: []string{"code", "message"},
+	}
+
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       options.Title,
+			Version:     options.Version,
+			Description: options.Description,
+		},
+		Servers: options.Servers,
+		Paths:   make(map[string]openapi.PathItem),
+		Tags:    slices.Clone(options.Tags),
+	}
+
+	for _, route := range a.Routes() {
+		if parseTriggerType(route.Method) != TriggerAPIGateway {
+			continue
+		}
+		path := openAPIPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = openapi.PathItem{}
+		}
+		op := route.operation(generator)
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+		for _, tag := range op.Tags {
+			if !slices.ContainsFunc(doc.Tags, func(t openapi.Tag) bool { return t.Name == tag }) {
+				doc.Tags = append(doc.Tags, openapi.Tag{Name: tag})
+			}
+		}
+	}
+
+	doc.Components = &openapi.Components{
+		Schemas:         generator.Schemas(),
+		SecuritySchemes: options.SecuritySchemes,
+	}
+	return doc
+}
+
+// ServeOpenAPI registers a public GET route that serves the document built
+// by OpenAPISpec, e.g. app.ServeOpenAPI("/openapi.json"). The document is
+// built on the first request, once every route has been registered.
+func (a *App) ServeOpenAPI(path string, opts ...OpenAPIOptions) *Route {
+	var (
+		once sync.Once
+		doc  *openapi.Document
+	)
+	return a.GET(path, func(ctx *Context) error {
+		once.Do(func() { doc = a.OpenAPISpec(opts...) })
+		return ctx.JSON(doc)
+	}).Meta(MetaAuth, "none")
+}
+
+// operation describes the route as an OpenAPI operation
+func (r *Route) operation(generator *openapi.Generator) *openapi.Operation {
+	summary, _ := r.GetMeta(MetaSummary)
+	description, _ := r.GetMeta(MetaDescription)
+	op := &openapi.Operation{
+		OperationID: operationID(r.Method, r.Path),
+		Summary:     summary,
+		Description: description,
+		Tags:        r.TagList(),
+		Deprecated:  r.IsDeprecated(),
+		Responses: map[string]*openapi.Response{
+			"default": {
+				Description: "Error",
+				Content:     openapi.JSONContent(openapi.Ref(errorSchemaName)),
+			},
+		},
+	}
+
+	if scheme, ok := r.GetMeta(MetaAuth); ok && scheme != "none" {
+		raw, _ := r.GetMeta(MetaScopes)
+		scopes := splitMeta(raw)
+		op.Security = []openapi.SecurityRequirement{{scheme: scopes}}
+	}
+
+	sig := r.signature
+	if sig == nil {
+		op.Parameters = pathParameters(r.Path, nil)
+		op.Responses["200"] = &openapi.Response{Description: http.StatusText(200)}
+		return op
+	}
+
+	params := generator.Parameters(sig.request)
+	op.Parameters = pathParameters(r.Path, params)
+
+	if r.Method != "GET" && r.Method != "HEAD" {
+		if body := generator.BodySchema(sig.request); body != nil {
+			op.RequestBody = &openapi.RequestBody{
+				Required: len(params) == 0,
+				Content:  openapi.JSONContent(body),
+			}
+		}
+	}
+
+	op.Responses[strconv.Itoa(sig.status)] = &openapi.Response{
+		Description: http.StatusText(sig.status),
+		Content:     openapi.JSONContent(generator.Schema(sig.response)),
+	}
+	return op
+}
+
+// pathParameters adds the path's parameters that the request type doesn't
+// declare, as strings
+func pathParameters(path string, params []*openapi.Parameter) []*openapi.Parameter {
+	declared := make(map[string]bool, len(params))
+	for _, param := range params {
+		if param.In == "path" {
+			declared[param.Name] = true
+		}
+	}
+
+	var undeclared []*openapi.Parameter
+	for _, segment := range strings.Split(path, "/") {
+		name, ok := strings.CutPrefix(segment, ":")
+		if !ok || declared[name] {
+			continue
+		}
+		undeclared = append(undeclared, &openapi.Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &openapi.Schema{Type: "string"},
+		})
+	}
+	return append(undeclared, params...)
+}
+
+// openAPIPath converts a route pattern's :param segments to {param}
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for n, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[n] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID derives an ID from the method and path, e.g.
+// "GET /orders/:id/items" becomes "getOrdersByIdItems"
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			id.WriteString("By")
+			segment = name
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		}) {
+			id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return id.String()
+}
@@ -0,0 +1,284 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	invalidSchemaName = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// Generator derives schemas from Go types. Named struct types become
+// component schemas, referenced wherever they're used, so each is
+// described once.
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// NewGenerator creates a schema generator
+func NewGenerator() *Generator {
+	return &Generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// Schemas returns the component schemas generated so far, by name
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+// Schema returns the schema of a type's JSON form
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "Duration in nanoseconds"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t, false)
+		}
+		return g.component(t)
+	default:
+		// Interfaces and anything else may hold any JSON value
+		return &Schema{}
+	}
+}
+
+// BodySchema returns the schema of a request type's body: its fields
+// without `path` or `query` tags. It returns nil for a type without body
+// fields, such as a struct of only path and query parameters.
+func (g *Generator) BodySchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || !hasParamFields(t) {
+		return g.Schema(t)
+	}
+
+	schema := g.structSchema(t, true)
+	if len(schema.Properties) == 0 {
+		return nil
+	}
+	return schema
+}
+
+// Parameters returns the path and query parameters of a request type's
+// `path` and `query` tagged fields
+func (g *Generator) Parameters(t reflect.Type) []*Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []*Parameter
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() {
+			continue
+		}
+		in, name := "path", field.Tag.Get("path")
+		if name == "" {
+			in, name = "query", field.Tag.Get("query")
+		}
+		if name == "" {
+			continue
+		}
+
+		schema := g.Schema(field.Type)
+		required := applyValidation(schema, field)
+		if value := field.Tag.Get("default"); value != "" {
+			schema.Default = defaultValue(schema, value)
+		}
+		params = append(params, &Parameter{
+			Name:     name,
+			In:       in,
+			Required: in == "path" || required,
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+// component registers a named struct as a component schema and refers to it
+func (g *Generator) component(t reflect.Type) *Schema {
+	if name, ok := g.names[t]; ok {
+		return Ref(name)
+	}
+
+	name := invalidSchemaName.ReplaceAllString(t.Name(), "_")
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = invalidSchemaName.ReplaceAllString(pkg[strings.LastIndex(pkg, "/")+1:]+"."+t.Name(), "_")
+	}
+	for base, n := name, 2; g.schemas[name] != nil; n++ {
+		name = base + strconv.Itoa(n)
+	}
+
+	// Register before describing the fields so recursive types refer to it
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t, false)
+	return Ref(name)
+}
+
+// structSchema describes a struct's JSON fields, optionally leaving out
+// fields bound from path and query parameters
+func (g *Generator) structSchema(t reflect.Type, bodyOnly bool) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous && isStruct(field.Type) {
+			// Embedded structs' fields are promoted into this one
+			continue
+		}
+		if bodyOnly && (field.Tag.Get("path") != "" || field.Tag.Get("query") != "") {
+			continue
+		}
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+
+		property := g.Schema(field.Type)
+		constraints := property
+		if property.Ref != "" {
+			// Constraints describe the field, not the shared component
+			constraints = &Schema{}
+		}
+		if applyValidation(constraints, field) {
+			schema.Required = append(schema.Required, name)
+		}
+		if value := field.Tag.Get("default"); value != "" {
+			constraints.Default = defaultValue(constraints, value)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// applyValidation adds the constraints of a field's `validate` tag to its
+// schema, and reports whether the field is required
+func applyValidation(schema *Schema, field reflect.StructField) bool {
+	required := false
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				schema.Enum = append(schema.Enum, option)
+			}
+		case "min", "max":
+			limit, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			switch schema.Type {
+			case "string":
+				if name == "min" {
+					schema.MinLength = &limit
+				} else {
+					schema.MaxLength = &limit
+				}
+			case "integer", "number":
+				bound := float64(limit)
+				if name == "min" {
+					schema.Minimum = &bound
+				} else {
+					schema.Maximum = &bound
+				}
+			}
+		}
+	}
+	return required
+}
+
+// defaultValue converts a `default` tag to the schema's type
+func defaultValue(schema *Schema, value string) any {
+	switch schema.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// jsonName returns a field's JSON name, and false for fields json skips
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+// hasParamFields reports whether a struct has `path` or `query` tagged fields
+func hasParamFields(t reflect.Type) bool {
+	for _, field := range reflect.VisibleFields(t) {
+		if field.Tag.Get("path") != "" || field.Tag.Get("query") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	Line1 string `json:"line1" validate:"required"`
+	City  string `json:"city"`
+}
+
+type Customer struct {
+	ID        string            `json:"id"`
+	Email     string            `json:"email" validate:"required,email"`
+	Name      string            `json:"name" validate:"min=1,max=100"`
+	Status    string            `json:"status" validate:"oneof=active suspended"`
+	Age       int               `json:"age,omitempty" validate:"min=18"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Address   *address          `json:"address,omitempty"`
+	Referrer  *Customer         `json:"referrer,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Internal  string            `json:"-"`
+	secret    string
+}
+
+func TestSchemaComponents(t *testing.T) {
+	g := NewGenerator()
+
+	schema := g.Schema(reflect.TypeOf(&Customer{}))
+	assert.Equal(t, "#/components/schemas/Customer", schema.Ref)
+
+	customer := g.Schemas()["Customer"]
+	require.NotNil(t, customer)
+	assert.Equal(t, "object", customer.Type)
+	assert.Equal(t, []string{"email"}, customer.Required)
+	assert.NotContains(t, customer.Properties, "Internal")
+	assert.NotContains(t, customer.Properties, "secret")
+
+	props := customer.Properties
+	assert.Equal(t, "email", props["email"].Format)
+	assert.Equal(t, 1, *props["name"].MinLength)
+	assert.Equal(t, 100, *props["name"].MaxLength)
+	assert.Equal(t, []any{"active", "suspended"}, props["status"].Enum)
+	assert.Equal(t, float64(18), *props["age"].Minimum)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, props["tags"])
+	assert.Equal(t, &Schema{Type: "string"}, props["labels"].AdditionalProperties)
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, props["created_at"])
+	assert.Equal(t, "#/components/schemas/address", props["address"].Ref)
+	// Recursive types refer to their own component
+	assert.Equal(t, "#/components/schemas/Customer", props["referrer"].Ref)
+	assert.Equal(t, []string{"line1"}, g.Schemas()["address"].Required)
+}
+
+type getCustomerRequest struct {
+	ID     string `path:"id"`
+	Expand bool   `query:"expand"`
+	Limit  int    `query:"limit" default:"20" validate:"required,max=100"`
+	Note   string `json:"note"`
+}
+
+func TestParametersAndBody(t *testing.T) {
+	g := NewGenerator()
+	typ := reflect.TypeOf(getCustomerRequest{})
+
+	params := g.Parameters(typ)
+	require.Len(t, params, 3)
+	assert.Equal(t, &Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}, params[0])
+	assert.Equal(t, "query", params[1].In)
+	assert.False(t, params[1].Required)
+	assert.True(t, params[2].Required)
+	assert.Equal(t, int64(20), params[2].Schema.Default)
+	assert.Equal(t, float64(100), *params[2].Schema.Maximum)
+
+	body := g.BodySchema(typ)
+	require.NotNil(t, body)
+	assert.Equal(t, []string{"note"}, keys(body.Properties))
+
+	type paramsOnly struct {
+		ID string `path:"id"`
+	}
+	assert.Nil(t, g.BodySchema(reflect.TypeOf(paramsOnly{})))
+}
+
+func TestSchemaNameCollisions(t *testing.T) {
+	g := NewGenerator()
+	g.Schemas()["Customer"] = &Schema{Type: "object"}
+
+	assert.Equal(t, "#/components/schemas/openapi.Customer", g.Schema(reflect.TypeOf(Customer{})).Ref)
+}
+
+func keys(m map[string]*Schema) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
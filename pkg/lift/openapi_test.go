@@ -0,0 +1,90 @@
+package lift
+
+import (
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type specOrder struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount" validate:"required,min=1"`
+}
+
+type specCreateOrder struct {
+	CustomerID string `path:"customer_id"`
+	Amount     int    `json:"amount" validate:"required,min=1"`
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	app := New()
+	api := app.Group("/api")
+	api.POST("/customers/:customer_id/orders", SimpleHandler(func(ctx *Context, req specCreateOrder) (specOrder, error) {
+		return specOrder{}, nil
+	}, WithStatus(201))).
+		Summary("Create an order").
+		Tags("orders").
+		Meta(MetaAuth, "jwt").
+		Meta(MetaScopes, "orders:write, orders:read")
+	app.GET("/health/:check", func(ctx *Context) error { return nil }).Meta(MetaAuth, "none").Deprecated()
+	app.Handle("SQS", "orders-queue", func(ctx *Context) error { return nil })
+
+	doc := app.OpenAPISpec(OpenAPIOptions{
+		Title:   "Orders",
+		Version: "2.0.0",
+		Tags:    []openapi.Tag{{Name: "orders", Description: "Order management"}},
+	})
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+	assert.Equal(t, "Orders", doc.Info.Title)
+	require.Len(t, doc.Paths, 2, "event routes aren't documented")
+
+	op := doc.Paths["/api/customers/{customer_id}/orders"]["post"]
+	require.NotNil(t, op)
+	assert.Equal(t, "postApiCustomersByCustomerIdOrders", op.OperationID)
+	assert.Equal(t, "Create an order", op.Summary)
+	assert.Equal(t, []string{"orders"}, op.Tags)
+	assert.False(t, op.Deprecated)
+	assert.Equal(t, []openapi.Tag{{Name: "orders", Description: "Order management"}}, doc.Tags)
+	assert.Equal(t, []openapi.SecurityRequirement{{"jwt": {"orders:write", "orders:read"}}}, op.Security)
+
+	require.Len(t, op.Parameters, 1)
+	assert.Equal(t, "customer_id", op.Parameters[0].Name)
+	assert.Equal(t, "path", op.Parameters[0].In)
+
+	require.NotNil(t, op.RequestBody)
+	body := op.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, []string{"amount"}, body.Required)
+	assert.NotContains(t, body.Properties, "CustomerID")
+
+	created := op.Responses["201"]
+	require.NotNil(t, created)
+	assert.Equal(t, "#/components/schemas/specOrder", created.Content["application/json"].Schema.Ref)
+	assert.Contains(t, doc.Components.Schemas, "specOrder")
+	assert.Contains(t, doc.Components.Schemas, "Error")
+
+	health := doc.Paths["/health/{check}"]["get"]
+	require.NotNil(t, health)
+	assert.True(t, health.Deprecated)
+	assert.Empty(t, health.Security)
+	require.Len(t, health.Parameters, 1)
+	assert.Equal(t, "check", health.Parameters[0].Name)
+	assert.Contains(t, health.Responses, "200")
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	app := New()
+	app.GET("/orders", func(ctx *Context) error { return nil })
+	route := app.ServeOpenAPI("/openapi.json")
+
+	auth, _ := route.GetMeta(MetaAuth)
+	assert.Equal(t, "none", auth)
+
+	ctx := newMountContext("GET", "/openapi.json")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	doc, ok := ctx.Response.Body.(*openapi.Document)
+	require.True(t, ok)
+	assert.Contains(t, doc.Paths, "/orders")
+}
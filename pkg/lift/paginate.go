@@ -0,0 +1,39 @@
+package lift
+
+import (
+	"strconv"
+
+	"github.com/pay-theory/lift/pkg/lift/pagination"
+)
+
+// Paginate reads the page a list request asks for from the limit and
+// cursor query parameters, and decodes the cursor into a DynamoDB start
+// key. A missing limit is DefaultLimit and one over MaxLimit is capped:
+//
+//	page, err := ctx.Paginate(pagination.Options{MaxLimit: 50})
+//
+// Malformed limits fail with 400 PARAMETER_ERROR, and cursors that
+// weren't issued by the options' codec with 400 INVALID_CURSOR.
+func (c *Context) Paginate(opts ...pagination.Options) (pagination.Request, error) {
+	var options pagination.Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	options = options.WithDefaults()
+
+	limit := options.DefaultLimit
+	if value := c.Query(options.LimitParam); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return pagination.Request{}, ParameterError(options.LimitParam, "limit must be a positive integer")
+		}
+		limit = min(parsed, options.MaxLimit)
+	}
+
+	request, err := pagination.NewRequest(limit, c.Query(options.CursorParam), options)
+	if err != nil {
+		return pagination.Request{}, NewLiftError("INVALID_CURSOR", "Cursor is invalid", 400).
+			WithDetail("parameter", options.CursorParam)
+	}
+	return request, nil
+}
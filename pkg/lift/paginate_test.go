@@ -0,0 +1,65 @@
+package lift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/lift/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func paginateContext(query map[string]string) *Context {
+	return NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method:      "GET",
+		Path:        "/orders",
+		QueryParams: query,
+	}))
+}
+
+func TestContextPaginateDefaults(t *testing.T) {
+	page, err := paginateContext(nil).Paginate()
+	require.NoError(t, err)
+	assert.Equal(t, 20, page.Limit)
+	assert.Empty(t, page.Cursor)
+	assert.Nil(t, page.StartKey)
+}
+
+func TestContextPaginateLimit(t *testing.T) {
+	page, err := paginateContext(map[string]string{"limit": "500"}).Paginate(pagination.Options{MaxLimit: 50})
+	require.NoError(t, err)
+	assert.Equal(t, 50, page.Limit)
+
+	page, err = paginateContext(map[string]string{"size": "5"}).Paginate(pagination.Options{LimitParam: "size"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, page.Limit)
+
+	for _, limit := range []string{"0", "-1", "ten"} {
+		_, err := paginateContext(map[string]string{"limit": limit}).Paginate()
+		var liftErr *LiftError
+		require.ErrorAs(t, err, &liftErr, limit)
+		assert.Equal(t, 400, liftErr.StatusCode)
+	}
+}
+
+func TestContextPaginateCursor(t *testing.T) {
+	codec := pagination.Codec{Secret: []byte("cursor-secret")}
+	key := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "TENANT#t_1"},
+		"sk": &types.AttributeValueMemberS{Value: "ORDER#o_9"},
+	}
+	cursor, err := codec.EncodeKey(key)
+	require.NoError(t, err)
+
+	page, err := paginateContext(map[string]string{"cursor": cursor}).Paginate(pagination.Options{Codec: codec})
+	require.NoError(t, err)
+	assert.Equal(t, key, page.StartKey)
+
+	_, err = paginateContext(map[string]string{"cursor": cursor + "x"}).Paginate(pagination.Options{Codec: codec})
+	var liftErr *LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, "INVALID_CURSOR", liftErr.Code)
+	assert.Equal(t, 400, liftErr.StatusCode)
+}
@@ -0,0 +1,225 @@
+// Package pagination provides cursor-based pagination for list endpoints:
+// opaque cursors that carry a DynamoDB LastEvaluatedKey between requests,
+// and standard response types so every endpoint pages the same way.
+//
+// Handlers read the limit and cursor with ctx.Paginate and answer with
+// NewResponse:
+//
+//	page, err := ctx.Paginate()
+//	if err != nil {
+//		return err
+//	}
+//	result, err := client.Query(ctx, &dynamodb.QueryInput{
+//		TableName:              aws.String("orders"),
+//		KeyConditionExpression: aws.String("pk = :tenant"),
+//		ExclusiveStartKey:      page.StartKey,
+//		Limit:                  aws.Int32(int32(page.Limit)),
+//	})
+//	...
+//	next, err := page.Next(result.LastEvaluatedKey)
+//	...
+//	return ctx.OK(pagination.NewResponse(orders, next, page.Limit))
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrInvalidCursor is returned for cursors that weren't issued by the codec
+// decoding them, or have been altered
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// maxCursorLength bounds the cursors decoded; DynamoDB keys are at most
+// 2KB of partition key and 1KB of sort key
+const maxCursorLength = 8192
+
+// Codec encodes DynamoDB keys as URL-safe cursors. A Codec with a Secret
+// signs its cursors so clients can't craft a start key, e.g. to begin a
+// scan in another tenant's partition; the zero Codec doesn't sign them.
+type Codec struct {
+	// Secret signs cursors with HMAC-SHA256
+	Secret []byte
+}
+
+// keyAttribute is a key attribute in a cursor. Key attributes are always
+// strings, numbers or binary.
+type keyAttribute struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+	B []byte  `json:"B,omitempty"`
+}
+
+// EncodeKey returns the cursor for a LastEvaluatedKey, or "" for an empty
+// key, meaning there are no more pages
+func (c Codec) EncodeKey(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	attributes := make(map[string]keyAttribute, len(key))
+	for name, value := range key {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			attributes[name] = keyAttribute{S: &v.Value}
+		case *types.AttributeValueMemberN:
+			attributes[name] = keyAttribute{N: &v.Value}
+		case *types.AttributeValueMemberB:
+			attributes[name] = keyAttribute{B: v.Value}
+		default:
+			return "", fmt.Errorf("unsupported key attribute %s of type %T", name, value)
+		}
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return "", err
+	}
+
+	cursor := base64.RawURLEncoding.EncodeToString(data)
+	if len(c.Secret) > 0 {
+		cursor += "." + base64.RawURLEncoding.EncodeToString(c.sign(cursor))
+	}
+	return cursor, nil
+}
+
+// DecodeKey returns the ExclusiveStartKey for a cursor, or nil for "",
+// meaning the first page. Cursors that don't decode, or whose signature
+// doesn't match, fail with ErrInvalidCursor.
+func (c Codec) DecodeKey(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	if len(cursor) > maxCursorLength {
+		return nil, ErrInvalidCursor
+	}
+	if len(c.Secret) > 0 {
+		payload, signature, ok := strings.Cut(cursor, ".")
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(signature)
+		if err != nil || !hmac.Equal(decoded, c.sign(payload)) {
+			return nil, ErrInvalidCursor
+		}
+		cursor = payload
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var attributes map[string]keyAttribute
+	if err := json.Unmarshal(data, &attributes); err != nil || len(attributes) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	key := make(map[string]types.AttributeValue, len(attributes))
+	for name, attribute := range attributes {
+		switch {
+		case attribute.S != nil:
+			key[name] = &types.AttributeValueMemberS{Value: *attribute.S}
+		case attribute.N != nil:
+			key[name] = &types.AttributeValueMemberN{Value: *attribute.N}
+		case attribute.B != nil:
+			key[name] = &types.AttributeValueMemberB{Value: attribute.B}
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return key, nil
+}
+
+func (c Codec) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// EncodeKey encodes a LastEvaluatedKey as an unsigned cursor
+func EncodeKey(key map[string]types.AttributeValue) (string, error) {
+	return Codec{}.EncodeKey(key)
+}
+
+// DecodeKey decodes an unsigned cursor into an ExclusiveStartKey
+func DecodeKey(cursor string) (map[string]types.AttributeValue, error) {
+	return Codec{}.DecodeKey(cursor)
+}
+
+// Options configure how a page is read from the request
+type Options struct {
+	// DefaultLimit is the page size when the request doesn't ask for one
+	// (default: 20)
+	DefaultLimit int
+	// MaxLimit caps the page size a request can ask for (default: 100)
+	MaxLimit int
+	// LimitParam and CursorParam are the query parameters read (default:
+	// limit and cursor)
+	LimitParam  string
+	CursorParam string
+	// Codec decodes cursors into DynamoDB start keys
+	Codec Codec
+	// Opaque skips decoding, for cursors that aren't DynamoDB keys, such
+	// as dynamorm's NextCursor
+	Opaque bool
+}
+
+// WithDefaults returns the options with defaults filled in
+func (o Options) WithDefaults() Options {
+	if o.DefaultLimit == 0 {
+		o.DefaultLimit = 20
+	}
+	if o.MaxLimit == 0 {
+		o.MaxLimit = 100
+	}
+	if o.DefaultLimit > o.MaxLimit {
+		o.DefaultLimit = o.MaxLimit
+	}
+	if o.LimitParam == "" {
+		o.LimitParam = "limit"
+	}
+	if o.CursorParam == "" {
+		o.CursorParam = "cursor"
+	}
+	return o
+}
+
+// Request is the page a request asks for
+type Request struct {
+	// Limit is the page size, between 1 and MaxLimit
+	Limit int
+	// Cursor is the cursor as sent, "" for the first page
+	Cursor string
+	// StartKey is the decoded cursor, nil for the first page or with
+	// Options.Opaque
+	StartKey map[string]types.AttributeValue
+
+	codec Codec
+}
+
+// NewRequest creates a Request for a page size and cursor, decoding the
+// cursor unless the options say it is opaque
+func NewRequest(limit int, cursor string, opts Options) (Request, error) {
+	request := Request{Limit: limit, Cursor: cursor, codec: opts.Codec}
+	if opts.Opaque {
+		return request, nil
+	}
+	key, err := opts.Codec.DecodeKey(cursor)
+	if err != nil {
+		return Request{}, err
+	}
+	request.StartKey = key
+	return request, nil
+}
+
+// Next returns the cursor for the page after this one, encoding
+// LastEvaluatedKey with the same codec the request's cursor was decoded
+// with
+func (r Request) Next(lastEvaluatedKey map[string]types.AttributeValue) (string, error) {
+	return r.codec.EncodeKey(lastEvaluatedKey)
+}
@@ -0,0 +1,140 @@
+package pagination
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func orderKey() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk":      &types.AttributeValueMemberS{Value: "TENANT#t_1"},
+		"sk":      &types.AttributeValueMemberN{Value: "1700000000"},
+		"version": &types.AttributeValueMemberB{Value: []byte{1, 2, 3}},
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for name, codec := range map[string]Codec{
+		"unsigned": {},
+		"signed":   {Secret: []byte("cursor-secret")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cursor, err := codec.EncodeKey(orderKey())
+			require.NoError(t, err)
+			assert.NotEmpty(t, cursor)
+
+			key, err := codec.DecodeKey(cursor)
+			require.NoError(t, err)
+			assert.Equal(t, orderKey(), key)
+		})
+	}
+}
+
+func TestCodecEmptyKey(t *testing.T) {
+	cursor, err := EncodeKey(nil)
+	require.NoError(t, err)
+	assert.Empty(t, cursor)
+
+	key, err := DecodeKey("")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+}
+
+func TestCodecRejectsInvalidCursors(t *testing.T) {
+	signed := Codec{Secret: []byte("cursor-secret")}
+	cursor, err := signed.EncodeKey(orderKey())
+	require.NoError(t, err)
+	unsigned, err := EncodeKey(orderKey())
+	require.NoError(t, err)
+
+	_, err = Codec{Secret: []byte("other-secret")}.DecodeKey(cursor)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = signed.DecodeKey(unsigned)
+	assert.ErrorIs(t, err, ErrInvalidCursor, "unsigned cursors are rejected by a signing codec")
+
+	for _, cursor := range []string{"not base64!", "bnVsbA", "e30", "eyJwayI6e319"} {
+		_, err := DecodeKey(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func TestCodecRejectsUnsupportedAttributes(t *testing.T) {
+	_, err := EncodeKey(map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberBOOL{Value: true},
+	})
+	assert.Error(t, err)
+}
+
+func TestOptionsWithDefaults(t *testing.T) {
+	opts := Options{}.WithDefaults()
+	assert.Equal(t, 20, opts.DefaultLimit)
+	assert.Equal(t, 100, opts.MaxLimit)
+	assert.Equal(t, "limit", opts.LimitParam)
+	assert.Equal(t, "cursor", opts.CursorParam)
+
+	opts = Options{MaxLimit: 10}.WithDefaults()
+	assert.Equal(t, 10, opts.DefaultLimit)
+}
+
+func TestRequestNext(t *testing.T) {
+	codec := Codec{Secret: []byte("cursor-secret")}
+	first, err := NewRequest(25, "", Options{Codec: codec})
+	require.NoError(t, err)
+	assert.Nil(t, first.StartKey)
+
+	next, err := first.Next(orderKey())
+	require.NoError(t, err)
+
+	second, err := NewRequest(25, next, Options{Codec: codec})
+	require.NoError(t, err)
+	assert.Equal(t, orderKey(), second.StartKey)
+
+	last, err := second.Next(nil)
+	require.NoError(t, err)
+	assert.Empty(t, last)
+}
+
+func TestRequestOpaque(t *testing.T) {
+	request, err := NewRequest(10, "dynamorm-cursor", Options{Opaque: true})
+	require.NoError(t, err)
+	assert.Equal(t, "dynamorm-cursor", request.Cursor)
+	assert.Nil(t, request.StartKey)
+}
+
+func TestNewResponse(t *testing.T) {
+	response := NewResponse[string](nil, "", 20)
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":[],"pagination":{"limit":20,"has_more":false}}`, string(data))
+
+	response = NewResponse([]string{"a", "b"}, "next", 2)
+	assert.True(t, response.Pagination.HasMore)
+	assert.Equal(t, "next", response.Pagination.NextCursor)
+}
+
+func TestNewOffsetResponse(t *testing.T) {
+	response := NewOffsetResponse([]int{1, 2, 3}, 2, 3, 10)
+	assert.Equal(t, 4, response.Pagination.TotalPages)
+	require.NotNil(t, response.Pagination.NextPage)
+	assert.Equal(t, 3, *response.Pagination.NextPage)
+	require.NotNil(t, response.Pagination.PrevPage)
+	assert.Equal(t, 1, *response.Pagination.PrevPage)
+
+	last := NewOffsetResponse([]int{10}, 4, 3, 10)
+	assert.Nil(t, last.Pagination.NextPage)
+
+	empty := NewOffsetResponse[int](nil, 1, 3, 0)
+	assert.Equal(t, 0, empty.Pagination.TotalPages)
+	assert.Nil(t, empty.Pagination.NextPage)
+	assert.Nil(t, empty.Pagination.PrevPage)
+	assert.NotNil(t, empty.Data)
+
+	assert.Equal(t, 0, Offset(1, 20))
+	assert.Equal(t, 40, Offset(3, 20))
+	assert.Equal(t, 0, Offset(0, 20))
+}
@@ -0,0 +1,81 @@
+package pagination
+
+// PaginatedResponse is a page of items and how to fetch the next one
+type PaginatedResponse[T any] struct {
+	Data       []T        `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// Pagination describes a page in a cursor-paginated list
+type Pagination struct {
+	Limit int `json:"limit"`
+	// NextCursor is passed as the cursor parameter to fetch the next page
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// NewResponse returns a page of items, with more to come when next isn't
+// empty
+func NewResponse[T any](items []T, next string, limit int) PaginatedResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return PaginatedResponse[T]{
+		Data: items,
+		Pagination: Pagination{
+			Limit:      limit,
+			NextCursor: next,
+			HasMore:    next != "",
+		},
+	}
+}
+
+// OffsetResponse is a numbered page of items, for stores that can count
+// and skip, such as SQL databases
+type OffsetResponse[T any] struct {
+	Data       []T              `json:"data"`
+	Pagination OffsetPagination `json:"pagination"`
+}
+
+// OffsetPagination describes a numbered page
+type OffsetPagination struct {
+	Page       int   `json:"page"`
+	PerPage    int   `json:"per_page"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+	NextPage   *int  `json:"next_page,omitempty"`
+	PrevPage   *int  `json:"prev_page,omitempty"`
+}
+
+// NewOffsetResponse returns page number page, of perPage items, out of
+// total items
+func NewOffsetResponse[T any](items []T, page, perPage int, total int64) OffsetResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	pagination := OffsetPagination{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	}
+	if perPage > 0 {
+		pagination.TotalPages = int((total + int64(perPage) - 1) / int64(perPage))
+	}
+	if page < pagination.TotalPages {
+		next := page + 1
+		pagination.NextPage = &next
+	}
+	if page > 1 {
+		prev := page - 1
+		pagination.PrevPage = &prev
+	}
+	return OffsetResponse[T]{Data: items, Pagination: pagination}
+}
+
+// Offset returns the number of items before a page, for OFFSET clauses
+func Offset(page, perPage int) int {
+	if page < 1 {
+		return 0
+	}
+	return (page - 1) * perPage
+}
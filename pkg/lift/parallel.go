@@ -0,0 +1,272 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Task is one unit of work run by Parallel
+type Task struct {
+	// Name identifies the task in results and errors (default: "task-<n>")
+	Name string
+	// Run does the work. ctx is cancelled when the task times out, so Run
+	// should pass it to the calls it makes.
+	Run func(ctx context.Context) (any, error)
+	// Timeout bounds the task; it never runs past the invocation deadline
+	Timeout time.Duration
+	// Optional tasks' failures are recorded in the results without making
+	// Parallel return an error, e.g. for recommendations on a product page
+	Optional bool
+}
+
+// NewTask creates a required task
+func NewTask(name string, run func(ctx context.Context) (any, error)) Task {
+	return Task{Name: name, Run: run}
+}
+
+// ParallelOptions configures ParallelWithOptions
+type ParallelOptions struct {
+	// MaxConcurrency is the number of tasks run at once (default: 8)
+	MaxConcurrency int
+	// DeadlineMargin is left of the invocation's remaining time when tasks
+	// are cut off, so the handler can still respond (default: 500ms)
+	DeadlineMargin time.Duration
+	// FailFast cancels the remaining tasks when a required task fails
+	FailFast bool
+}
+
+// TaskError is the error of a failed task
+type TaskError struct {
+	Task     string
+	Err      error
+	TimedOut bool
+}
+
+func (e *TaskError) Error() string {
+	if e.TimedOut {
+		return fmt.Sprintf("task %s timed out: %v", e.Task, e.Err)
+	}
+	return fmt.Sprintf("task %s failed: %v", e.Task, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// TaskResult is the outcome of one task
+type TaskResult struct {
+	Name     string
+	Value    any
+	Err      *TaskError
+	Duration time.Duration
+}
+
+// ParallelResults holds the outcome of every task, in the order given
+type ParallelResults struct {
+	results []TaskResult
+}
+
+// Results returns every task's result
+func (r *ParallelResults) Results() []TaskResult {
+	return append([]TaskResult(nil), r.results...)
+}
+
+// Get returns the named task's result
+func (r *ParallelResults) Get(name string) (TaskResult, bool) {
+	for _, result := range r.results {
+		if result.Name == name {
+			return result, true
+		}
+	}
+	return TaskResult{}, false
+}
+
+// Value returns the named task's value, or nil if it failed
+func (r *ParallelResults) Value(name string) any {
+	result, _ := r.Get(name)
+	return result.Value
+}
+
+// Failed returns the results of failed tasks
+func (r *ParallelResults) Failed() []TaskResult {
+	var failed []TaskResult
+	for _, result := range r.results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// ParallelValue returns the named task's value as a T
+func ParallelValue[T any](results *ParallelResults, name string) (T, error) {
+	var zero T
+	result, ok := results.Get(name)
+	if !ok {
+		return zero, fmt.Errorf("no task named %s", name)
+	}
+	if result.Err != nil {
+		return zero, result.Err
+	}
+	if result.Value == nil {
+		return zero, nil
+	}
+	value, ok := result.Value.(T)
+	if !ok {
+		return zero, fmt.Errorf("task %s returned %T, not %T", name, result.Value, zero)
+	}
+	return value, nil
+}
+
+// Parallel runs tasks concurrently with the default options. See
+// ParallelWithOptions.
+//
+//	results, err := lift.Parallel(ctx,
+//		lift.NewTask("customer", func(c context.Context) (any, error) { return customers.Get(c, id) }),
+//		lift.NewTask("orders", func(c context.Context) (any, error) { return orders.ListFor(c, id) }),
+//		lift.Task{Name: "offers", Run: loadOffers, Timeout: 300 * time.Millisecond, Optional: true},
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	customer, _ := lift.ParallelValue[*Customer](results, "customer")
+func Parallel(ctx *Context, tasks ...Task) (*ParallelResults, error) {
+	return ParallelWithOptions(ctx, ParallelOptions{}, tasks...)
+}
+
+// ParallelWithOptions runs tasks with bounded concurrency and returns every
+// task's result, including partial results when some fail. Tasks are cut
+// off before the invocation deadline and panics are recovered as errors.
+//
+// If a required task fails the returned error is a *LiftError: the task's
+// own LiftError, 504 TASK_TIMEOUT for a timeout, or 500 TASK_FAILED,
+// with the *TaskError as its cause.
+func ParallelWithOptions(ctx *Context, opts ParallelOptions, tasks ...Task) (*ParallelResults, error) {
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = 8
+	}
+	if opts.DeadlineMargin <= 0 {
+		opts.DeadlineMargin = 500 * time.Millisecond
+	}
+
+	parent := context.Background()
+	if ctx != nil && ctx.Context != nil {
+		parent = ctx.Context
+	}
+	runCtx, cancel := context.WithCancel(parent)
+	defer cancel()
+	if deadline, ok := parent.Deadline(); ok {
+		var cancelDeadline context.CancelFunc
+		runCtx, cancelDeadline = context.WithDeadline(runCtx, deadline.Add(-opts.DeadlineMargin))
+		defer cancelDeadline()
+	}
+
+	results := make([]TaskResult, len(tasks))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, opts.MaxConcurrency)
+	for i, task := range tasks {
+		if task.Name == "" {
+			task.Name = fmt.Sprintf("task-%d", i+1)
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-runCtx.Done():
+			results[i] = TaskResult{Name: task.Name, Err: taskError(task.Name, runCtx.Err())}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, task Task) {
+			defer func() { <-slots; wg.Done() }()
+			results[i] = runTask(runCtx, task)
+			if results[i].Err != nil && !task.Optional && opts.FailFast {
+				cancel()
+			}
+		}(i, task)
+	}
+	wg.Wait()
+
+	parallel := &ParallelResults{results: results}
+	var failed *TaskError
+	for i, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		if ctx != nil && ctx.Logger != nil {
+			ctx.Logger.Warn("Parallel task failed", map[string]any{
+				"task":     result.Name,
+				"optional": tasks[i].Optional,
+				"error":    result.Err.Error(),
+				"duration": result.Duration.String(),
+			})
+		}
+		if failed == nil && !tasks[i].Optional {
+			failed = result.Err
+		}
+	}
+	if failed == nil {
+		return parallel, nil
+	}
+
+	var liftErr *LiftError
+	switch {
+	case errors.As(failed.Err, &liftErr):
+		return parallel, liftErr
+	case failed.TimedOut:
+		return parallel, NewLiftError("TASK_TIMEOUT", fmt.Sprintf("Task %s timed out", failed.Task), 504).WithCause(failed)
+	default:
+		return parallel, NewLiftError("TASK_FAILED", fmt.Sprintf("Task %s failed", failed.Task), 500).WithCause(failed)
+	}
+}
+
+// runTask runs one task, abandoning it if it outlives its context
+func runTask(ctx context.Context, task Task) TaskResult {
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.Timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		value any
+		err   error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		if task.Run == nil {
+			done <- outcome{err: errors.New("task has no Run function")}
+			return
+		}
+		value, err := task.Run(ctx)
+		done <- outcome{value: value, err: err}
+	}()
+
+	result := TaskResult{Name: task.Name}
+	select {
+	case out := <-done:
+		result.Value = out.value
+		if out.err != nil {
+			result.Value = nil
+			result.Err = taskError(task.Name, out.err)
+		}
+	case <-ctx.Done():
+		result.Err = taskError(task.Name, ctx.Err())
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// taskError wraps a task's error, noting whether it ran out of time
+func taskError(name string, err error) *TaskError {
+	return &TaskError{Task: name, Err: err, TimedOut: errors.Is(err, context.DeadlineExceeded)}
+}
@@ -0,0 +1,156 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newParallelTestContext(parent context.Context) *Context {
+	return NewContext(parent, NewRequest(&adapters.Request{Method: "GET", Path: "/customers/cus_1"}))
+}
+
+func TestParallel(t *testing.T) {
+	ctx := newParallelTestContext(context.Background())
+
+	results, err := Parallel(ctx,
+		NewTask("customer", func(ctx context.Context) (any, error) { return "Ada", nil }),
+		NewTask("orders", func(ctx context.Context) (any, error) { return []string{"ord_1", "ord_2"}, nil }),
+		Task{Name: "offers", Optional: true, Run: func(ctx context.Context) (any, error) {
+			return nil, errors.New("offers unavailable")
+		}},
+	)
+	require.NoError(t, err)
+
+	customer, err := ParallelValue[string](results, "customer")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", customer)
+
+	orders, err := ParallelValue[[]string](results, "orders")
+	require.NoError(t, err)
+	assert.Len(t, orders, 2)
+
+	_, err = ParallelValue[int](results, "customer")
+	assert.Error(t, err)
+
+	require.Len(t, results.Failed(), 1)
+	assert.Equal(t, "offers", results.Failed()[0].Name)
+	assert.Nil(t, results.Value("offers"))
+}
+
+func TestParallelBoundsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	task := func(ctx context.Context) (any, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil, nil
+	}
+
+	tasks := make([]Task, 6)
+	for i := range tasks {
+		tasks[i] = Task{Run: task}
+	}
+	results, err := ParallelWithOptions(newParallelTestContext(context.Background()), ParallelOptions{MaxConcurrency: 2}, tasks...)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), peak.Load())
+	assert.Equal(t, "task-6", results.Results()[5].Name)
+}
+
+func TestParallelErrors(t *testing.T) {
+	t.Run("wraps failures with the task name", func(t *testing.T) {
+		dbErr := errors.New("connection reset")
+		results, err := Parallel(newParallelTestContext(context.Background()),
+			NewTask("customer", func(ctx context.Context) (any, error) { return "Ada", nil }),
+			NewTask("orders", func(ctx context.Context) (any, error) { return nil, dbErr }),
+		)
+
+		var liftErr *LiftError
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, 500, liftErr.StatusCode)
+		assert.Equal(t, "TASK_FAILED", liftErr.Code)
+		assert.ErrorIs(t, err, dbErr)
+
+		var taskErr *TaskError
+		require.ErrorAs(t, err, &taskErr)
+		assert.Equal(t, "orders", taskErr.Task)
+
+		// Partial results are still returned
+		assert.Equal(t, "Ada", results.Value("customer"))
+	})
+
+	t.Run("keeps the status of task LiftErrors", func(t *testing.T) {
+		_, err := Parallel(newParallelTestContext(context.Background()),
+			NewTask("customer", func(ctx context.Context) (any, error) { return nil, NotFound("customer not found") }),
+		)
+		var liftErr *LiftError
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, 404, liftErr.StatusCode)
+	})
+
+	t.Run("times out slow tasks", func(t *testing.T) {
+		results, err := Parallel(newParallelTestContext(context.Background()),
+			Task{Name: "slow", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}},
+		)
+		var liftErr *LiftError
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, 504, liftErr.StatusCode)
+		assert.True(t, results.Failed()[0].Err.TimedOut)
+	})
+
+	t.Run("stops before the invocation deadline", func(t *testing.T) {
+		parent, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := ParallelWithOptions(newParallelTestContext(parent), ParallelOptions{DeadlineMargin: 40 * time.Millisecond},
+			Task{Name: "stuck", Run: func(ctx context.Context) (any, error) {
+				time.Sleep(time.Second)
+				return nil, nil
+			}},
+		)
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("recovers panics", func(t *testing.T) {
+		_, err := Parallel(newParallelTestContext(context.Background()),
+			NewTask("boom", func(ctx context.Context) (any, error) { panic("nil map") }),
+		)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Task boom failed")
+	})
+
+	t.Run("fail fast cancels the rest", func(t *testing.T) {
+		results, err := ParallelWithOptions(newParallelTestContext(context.Background()), ParallelOptions{FailFast: true},
+			NewTask("fails", func(ctx context.Context) (any, error) { return nil, errors.New("bad input") }),
+			NewTask("waits", func(ctx context.Context) (any, error) {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(time.Second):
+					return "done", nil
+				}
+			}),
+		)
+		require.Error(t, err)
+		waits, _ := results.Get("waits")
+		require.NotNil(t, waits.Err)
+		assert.ErrorIs(t, waits.Err, context.Canceled)
+	})
+}
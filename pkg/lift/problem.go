@@ -0,0 +1,137 @@
+package lift
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem document. Extensions are written as
+// top-level members next to the standard ones.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// MarshalJSON writes the standard members and the extensions
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]any, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		doc[key] = value
+	}
+	doc["type"] = p.Type
+	doc["title"] = p.Title
+	doc["status"] = p.Status
+	if p.Detail != "" {
+		doc["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		doc["instance"] = p.Instance
+	}
+	return json.Marshal(doc)
+}
+
+// ProblemOptions configures problem+json error responses
+type ProblemOptions struct {
+	// TypeBaseURI turns error codes into problem types, e.g.
+	// "https://errors.example.com/" gives NOT_FOUND the type
+	// "https://errors.example.com/not-found". Without it every problem has
+	// the type "about:blank".
+	TypeBaseURI string
+
+	// Extend adds members to each problem, such as the tenant or request ID
+	Extend func(ctx *Context, problem *Problem, err error)
+}
+
+// WithProblemDetails makes the app answer every LiftError and framework
+// error with an RFC 7807 application/problem+json document instead of the
+// default error body:
+//
+//	app := lift.New(lift.WithProblemDetails(lift.ProblemOptions{
+//		TypeBaseURI: "https://errors.example.com/",
+//		Extend: func(ctx *lift.Context, problem *lift.Problem, err error) {
+//			problem.Extensions["request_id"] = ctx.GetRequestID()
+//			problem.Extensions["tenant_id"] = ctx.TenantID()
+//		},
+//	}))
+//
+// The error code is kept in a "code" member and LiftError details in a
+// "details" member. Server errors never expose their cause.
+func WithProblemDetails(opts ProblemOptions) AppOption {
+	return func(app *App) {
+		app.problems = &opts
+	}
+}
+
+// NewProblem builds the problem document for an error
+func (o *ProblemOptions) NewProblem(ctx *Context, err error) *Problem {
+	liftErr := &LiftError{}
+	if !errors.As(err, &liftErr) {
+		liftErr = NewLiftError("INTERNAL_ERROR", "Internal server error", 500)
+	}
+	status := liftErr.StatusCode
+	if status == 0 {
+		status = 500
+	}
+
+	problem := &Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     liftErr.Message,
+		Extensions: map[string]any{"code": liftErr.Code},
+	}
+	if o.TypeBaseURI != "" && liftErr.Code != "" {
+		problem.Type = o.TypeBaseURI + strings.ReplaceAll(strings.ToLower(liftErr.Code), "_", "-")
+	}
+	if len(liftErr.Details) > 0 {
+		problem.Extensions["details"] = liftErr.Details
+	}
+	if ctx.Request != nil {
+		problem.Instance = ctx.Request.Path
+	}
+	if o.Extend != nil {
+		o.Extend(ctx, problem, err)
+	}
+	return problem
+}
+
+// SetProblemOptions sets the options WriteProblem renders problems with
+// for this request
+func (c *Context) SetProblemOptions(opts *ProblemOptions) {
+	c.problems = opts
+}
+
+// WriteProblem answers the request with the problem document for err. It
+// uses the app's WithProblemDetails options when they're set, so the type
+// and extensions match the app's other problems. extend, if not nil, adds
+// members specific to this error, such as the fields that failed
+// validation.
+func (c *Context) WriteProblem(err error, extend func(problem *Problem)) error {
+	opts := c.problems
+	if opts == nil {
+		opts = &ProblemOptions{}
+	}
+	return opts.writeProblem(c, err, extend)
+}
+
+// writeProblem answers the request with the problem document for err
+func (o *ProblemOptions) writeProblem(ctx *Context, err error, extend func(problem *Problem)) error {
+	problem := o.NewProblem(ctx, err)
+	if extend != nil {
+		extend(problem)
+	}
+	if err := ctx.Status(problem.Status).JSON(problem); err != nil {
+		return err
+	}
+	ctx.Response.Header("Content-Type", ProblemContentType)
+	return nil
+}
@@ -0,0 +1,83 @@
+package lift
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemDetails(t *testing.T) {
+	app := New(WithProblemDetails(ProblemOptions{
+		TypeBaseURI: "https://errors.example.com/",
+		Extend: func(ctx *Context, problem *Problem, err error) {
+			problem.Extensions["request_id"] = ctx.GetRequestID()
+			if tenantID := ctx.TenantID(); tenantID != "" {
+				problem.Extensions["tenant_id"] = tenantID
+			}
+		},
+	}))
+	app.GET("/orders/:id", func(ctx *Context) error {
+		ctx.Set("tenant_id", "ten_1")
+		return NotFound("order not found").WithDetail("order_id", ctx.Param("id"))
+	})
+	app.GET("/crash", func(ctx *Context) error {
+		return errors.New("connection refused by db-primary")
+	})
+
+	problemJSON := func(ctx *Context) map[string]any {
+		t.Helper()
+		assert.Equal(t, ProblemContentType, ctx.Response.Headers["Content-Type"])
+		encoded, err := json.Marshal(ctx.Response.Body)
+		require.NoError(t, err)
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal(encoded, &doc))
+		return doc
+	}
+
+	ctx := newMountContext("GET", "/orders/ord_1")
+	ctx.SetRequestID("req-1")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 404, ctx.Response.StatusCode)
+	assert.Equal(t, map[string]any{
+		"type":       "https://errors.example.com/not-found",
+		"title":      "Not Found",
+		"status":     float64(404),
+		"detail":     "order not found",
+		"instance":   "/orders/ord_1",
+		"code":       "NOT_FOUND",
+		"details":    map[string]any{"order_id": "ord_1"},
+		"request_id": "req-1",
+		"tenant_id":  "ten_1",
+	}, problemJSON(ctx))
+
+	// Server errors don't expose their cause
+	ctx = newMountContext("GET", "/crash")
+	ctx.SetRequestID("req-2")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 500, ctx.Response.StatusCode)
+	assert.Equal(t, map[string]any{
+		"type":       "https://errors.example.com/internal-error",
+		"title":      "Internal Server Error",
+		"status":     float64(500),
+		"detail":     "Internal server error",
+		"instance":   "/crash",
+		"code":       "INTERNAL_ERROR",
+		"request_id": "req-2",
+	}, problemJSON(ctx))
+}
+
+func TestProblemDetailsDefaults(t *testing.T) {
+	opts := &ProblemOptions{}
+	problem := opts.NewProblem(newMountContext("POST", "/payments"), NewLiftError("CARD_DECLINED", "The card was declined", 402))
+	assert.Equal(t, &Problem{
+		Type:       "about:blank",
+		Title:      "Payment Required",
+		Status:     402,
+		Detail:     "The card was declined",
+		Instance:   "/payments",
+		Extensions: map[string]any{"code": "CARD_DECLINED"},
+	}, problem)
+}
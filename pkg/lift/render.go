@@ -0,0 +1,472 @@
+package lift
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Renderer encodes response data as one media type for ctx.Render
+type Renderer interface {
+	// MediaType is the Content-Type of rendered bodies, e.g. "application/xml"
+	MediaType() string
+	// Render encodes data
+	Render(data any) ([]byte, error)
+}
+
+// DefaultBinaryMediaTypes are rendered as base64 encoded bodies marked
+// isBase64Encoded. REST APIs must list them in their binary media types so
+// API Gateway decodes them; HTTP APIs and function URLs always do.
+var DefaultBinaryMediaTypes = []string{
+	"application/msgpack",
+	"application/x-msgpack",
+	"application/octet-stream",
+}
+
+type rendererFunc struct {
+	mediaType string
+	render    func(data any) ([]byte, error)
+}
+
+func (r rendererFunc) MediaType() string               { return r.mediaType }
+func (r rendererFunc) Render(data any) ([]byte, error) { return r.render(data) }
+
+// NewRenderer creates a Renderer for mediaType from an encoding function
+func NewRenderer(mediaType string, render func(data any) ([]byte, error)) Renderer {
+	return rendererFunc{mediaType: mediaType, render: render}
+}
+
+// jsonRenderer is written with ctx.JSON, so the body stays a value that
+// middleware can inspect
+type jsonRenderer struct{}
+
+func (jsonRenderer) MediaType() string               { return "application/json" }
+func (jsonRenderer) Render(data any) ([]byte, error) { return json.Marshal(data) }
+
+// JSONRenderer renders application/json
+func JSONRenderer() Renderer {
+	return jsonRenderer{}
+}
+
+// XMLRenderer renders application/xml with encoding/xml. XML ignores json
+// tags, so fields hidden with `json:"-"` also need `xml:"-"`; maps can't be
+// rendered.
+func XMLRenderer() Renderer {
+	return NewRenderer("application/xml", func(data any) ([]byte, error) {
+		body, err := xml.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), body...), nil
+	})
+}
+
+// CSVRenderer renders text/csv from a slice of structs, a slice of maps or
+// [][]string. Struct columns are named like BindCSV binds them: by csv tag,
+// else by field name. Fields tagged `csv:"-"`, or `json:"-"` without a csv
+// tag, are left out.
+func CSVRenderer() Renderer {
+	return NewRenderer("text/csv", renderCSV)
+}
+
+// MsgpackRenderer renders application/msgpack, naming fields by their json
+// tags so the payload matches the JSON one
+func MsgpackRenderer() Renderer {
+	return NewRenderer("application/msgpack", func(data any) ([]byte, error) {
+		var buf bytes.Buffer
+		encoder := msgpack.NewEncoder(&buf)
+		encoder.SetCustomStructTag("json")
+		encoder.SetOmitEmpty(true)
+		if err := encoder.Encode(data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// DefaultRenderers returns the renderers ctx.Render uses unless
+// WithRenderers changes them, in order of preference: JSON, XML, CSV and
+// msgpack
+func DefaultRenderers() []Renderer {
+	return []Renderer{JSONRenderer(), XMLRenderer(), CSVRenderer(), MsgpackRenderer()}
+}
+
+var defaultRenderers = DefaultRenderers()
+
+// WithRenderers registers renderers for ctx.Render. A renderer replaces the
+// default for the same media type; others are preferred after the
+// defaults.
+func WithRenderers(renderers ...Renderer) AppOption {
+	return func(app *App) {
+		if app.renderers == nil {
+			app.renderers = DefaultRenderers()
+		}
+		for _, renderer := range renderers {
+			replaced := false
+			for i, existing := range app.renderers {
+				if mediaTypeBase(existing.MediaType()) == mediaTypeBase(renderer.MediaType()) {
+					app.renderers[i] = renderer
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				app.renderers = append(app.renderers, renderer)
+			}
+		}
+	}
+}
+
+// WithBinaryMediaTypes adds media types that ctx.Render base64 encodes,
+// e.g. for a protobuf renderer. Prefixes ending in "/*" match any subtype.
+func WithBinaryMediaTypes(mediaTypes ...string) AppOption {
+	return func(app *App) {
+		if app.binaryMediaTypes == nil {
+			app.binaryMediaTypes = append([]string(nil), DefaultBinaryMediaTypes...)
+		}
+		app.binaryMediaTypes = append(app.binaryMediaTypes, mediaTypes...)
+	}
+}
+
+// SetRenderers sets the renderers and binary media types used by Render
+// for this request; nil keeps the defaults
+func (c *Context) SetRenderers(renderers []Renderer, binaryMediaTypes []string) {
+	c.renderers = renderers
+	c.binaryMediaTypes = binaryMediaTypes
+}
+
+// Render writes data in the format the client asked for in its Accept
+// header, using the application's renderers:
+//
+//	return ctx.Render(invoices) // JSON, XML, CSV or msgpack
+//
+// Clients that send no Accept header get the first renderer's format
+// (JSON by default). If no renderer matches, Render returns a 406
+// NOT_ACCEPTABLE error listing the available media types.
+func (c *Context) Render(data any) error {
+	renderers := c.renderers
+	if renderers == nil {
+		renderers = defaultRenderers
+	}
+	offers := make([]string, len(renderers))
+	for i, renderer := range renderers {
+		offers[i] = renderer.MediaType()
+	}
+
+	// The format depends on Accept whichever one this client gets
+	if vary := c.Response.Headers["Vary"]; vary == "" {
+		c.Response.Header("Vary", "Accept")
+	} else if !strings.Contains(strings.ToLower(vary), "accept") {
+		c.Response.Header("Vary", vary+", Accept")
+	}
+
+	chosen := c.Accepts(offers...)
+	if chosen == "" {
+		return NewLiftError("NOT_ACCEPTABLE", "None of the requested media types can be rendered", 406).
+			WithDetail("available", offers)
+	}
+
+	var renderer Renderer
+	for _, r := range renderers {
+		if r.MediaType() == chosen {
+			renderer = r
+			break
+		}
+	}
+	if _, ok := renderer.(jsonRenderer); ok {
+		return c.JSON(data)
+	}
+
+	body, err := renderer.Render(data)
+	if err != nil {
+		return NewLiftError("RENDER_FAILED", fmt.Sprintf("Failed to render response as %s", chosen), 500).WithCause(err)
+	}
+	if err := c.Response.encoded(chosen, body, c.isBinaryMediaType(chosen)); err != nil {
+		return err
+	}
+	c.captureResponseData()
+	return nil
+}
+
+// Accepts returns the offered media type the client prefers according to
+// its Accept header, or "" if it accepts none of them. Without an Accept
+// header the first offer is returned. Ties go to the earlier offer.
+func (c *Context) Accepts(offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	var accept string
+	if c.Request != nil {
+		accept = strings.TrimSpace(c.Request.GetHeader("Accept"))
+	}
+	if accept == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(ranges, mediaTypeBase(offer)); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// isBinaryMediaType reports whether bodies of mediaType are base64 encoded
+func (c *Context) isBinaryMediaType(mediaType string) bool {
+	binaryMediaTypes := c.binaryMediaTypes
+	if binaryMediaTypes == nil {
+		binaryMediaTypes = DefaultBinaryMediaTypes
+	}
+	mediaType = mediaTypeBase(mediaType)
+	for _, binary := range binaryMediaTypes {
+		if prefix, ok := strings.CutSuffix(binary, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == binary {
+			return true
+		}
+	}
+	return false
+}
+
+// encoded sets an encoded body of contentType. Binary bodies are base64
+// encoded and marked isBase64Encoded for API Gateway.
+func (r *Response) encoded(contentType string, body []byte, binary bool) error {
+	if r.written {
+		return NewLiftError("RESPONSE_WRITTEN", "Response has already been written", 500)
+	}
+
+	if binary {
+		r.Body = base64.StdEncoding.EncodeToString(body)
+		r.IsBase64Encoded = true
+	} else {
+		r.Body = string(body)
+		if strings.HasPrefix(contentType, "text/") && !strings.Contains(contentType, "charset") {
+			contentType += "; charset=utf-8"
+		}
+	}
+	r.Header("Content-Type", contentType)
+	r.written = true
+	return nil
+}
+
+// acceptRange is one media range of an Accept header
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		if mediaType == "*" {
+			mediaType = "*/*"
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(name), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific range matching
+// mediaType, or 0 if none does
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, 0
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.mediaType == mediaType:
+			s = 3
+		case r.mediaType == mainType+"/*":
+			s = 2
+		case r.mediaType == "*/*":
+			s = 1
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// mediaTypeBase returns a media type without parameters, in lower case
+func mediaTypeBase(mediaType string) string {
+	base, _, _ := strings.Cut(mediaType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+// renderCSV writes a slice of structs, a slice of maps or [][]string as CSV
+func renderCSV(data any) ([]byte, error) {
+	if rows, ok := data.([][]string); ok {
+		return writeCSV(rows)
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return writeCSV(nil)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct || v.Kind() == reflect.Map {
+		// A single record is one row
+		slice := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+		slice.Index(0).Set(v)
+		v = slice
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot render %s as CSV", v.Type())
+	}
+
+	elem := v.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	switch elem.Kind() {
+	case reflect.Struct:
+		return renderCSVStructs(v, elem)
+	case reflect.Map:
+		if elem.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot render %s as CSV", elem)
+		}
+		return renderCSVMaps(v)
+	default:
+		return nil, fmt.Errorf("cannot render %s as CSV", elem)
+	}
+}
+
+func renderCSVStructs(v reflect.Value, typ reflect.Type) ([]byte, error) {
+	var header []string
+	var fields []int
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("csv")
+		if tag == "-" || (tag == "" && sf.Tag.Get("json") == "-") {
+			continue
+		}
+		if tag == "" {
+			tag = sf.Name
+		}
+		header = append(header, tag)
+		fields = append(fields, i)
+	}
+
+	rows := [][]string{header}
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		for item.Kind() == reflect.Pointer || item.Kind() == reflect.Interface {
+			if item.IsNil() {
+				break
+			}
+			item = item.Elem()
+		}
+		if item.Kind() != reflect.Struct {
+			continue
+		}
+		row := make([]string, len(fields))
+		for j, field := range fields {
+			row[j] = csvValue(item.Field(field))
+		}
+		rows = append(rows, row)
+	}
+	return writeCSV(rows)
+}
+
+func renderCSVMaps(v reflect.Value) ([]byte, error) {
+	// Columns are every key in any row, sorted
+	seen := make(map[string]bool)
+	var header []string
+	for i := 0; i < v.Len(); i++ {
+		item := reflect.Indirect(v.Index(i))
+		if !item.IsValid() {
+			continue
+		}
+		for _, key := range item.MapKeys() {
+			if name := key.String(); !seen[name] {
+				seen[name] = true
+				header = append(header, name)
+			}
+		}
+	}
+	sort.Strings(header)
+
+	rows := [][]string{header}
+	for i := 0; i < v.Len(); i++ {
+		item := reflect.Indirect(v.Index(i))
+		if !item.IsValid() {
+			continue
+		}
+		row := make([]string, len(header))
+		for j, name := range header {
+			if value := item.MapIndex(reflect.ValueOf(name).Convert(item.Type().Key())); value.IsValid() {
+				row[j] = csvValue(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return writeCSV(rows)
+}
+
+// csvValue formats one cell; times use RFC 3339 as BindCSV expects
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	case fmt.Stringer:
+		return value.String()
+	case []byte:
+		return string(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func writeCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,139 @@
+package lift
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type renderedInvoice struct {
+	ID       string    `json:"id" xml:"id" csv:"invoice_id"`
+	Amount   int       `json:"amount" xml:"amount" csv:"amount"`
+	IssuedAt time.Time `json:"issued_at" xml:"issued_at" csv:"issued_at"`
+	Secret   string    `json:"-" xml:"-"`
+}
+
+func renderContext(accept string) *Context {
+	headers := map[string]string{}
+	if accept != "" {
+		headers["accept"] = accept
+	}
+	return NewContext(context.Background(), NewRequest(&adapters.Request{Method: "GET", Path: "/invoices", Headers: headers}))
+}
+
+func TestRender(t *testing.T) {
+	issued := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	invoices := []renderedInvoice{{ID: "inv_1", Amount: 1250, IssuedAt: issued, Secret: "s"}}
+
+	t.Run("defaults to JSON", func(t *testing.T) {
+		ctx := renderContext("")
+		require.NoError(t, ctx.Render(invoices))
+		assert.Equal(t, "application/json", ctx.Response.Headers["Content-Type"])
+		assert.Equal(t, "Accept", ctx.Response.Headers["Vary"])
+		assert.Equal(t, invoices, ctx.Response.Body)
+	})
+
+	t.Run("renders CSV", func(t *testing.T) {
+		ctx := renderContext("text/csv")
+		require.NoError(t, ctx.Render(invoices))
+		assert.Equal(t, "text/csv; charset=utf-8", ctx.Response.Headers["Content-Type"])
+		assert.Equal(t, "invoice_id,amount,issued_at\ninv_1,1250,2026-03-01T12:00:00Z\n", ctx.Response.Body)
+	})
+
+	t.Run("renders XML", func(t *testing.T) {
+		ctx := renderContext("application/json;q=0.5, application/xml")
+		require.NoError(t, ctx.Render(invoices[0]))
+		assert.Equal(t, "application/xml", ctx.Response.Headers["Content-Type"])
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+			`<renderedInvoice><id>inv_1</id><amount>1250</amount><issued_at>2026-03-01T12:00:00Z</issued_at></renderedInvoice>`,
+			ctx.Response.Body)
+	})
+
+	t.Run("renders msgpack as a binary body", func(t *testing.T) {
+		ctx := renderContext("application/msgpack")
+		require.NoError(t, ctx.Render(map[string]any{"id": "inv_1", "amount": 1250}))
+		assert.Equal(t, "application/msgpack", ctx.Response.Headers["Content-Type"])
+		require.True(t, ctx.Response.IsBase64Encoded)
+
+		body, err := base64.StdEncoding.DecodeString(ctx.Response.Body.(string))
+		require.NoError(t, err)
+		var decoded map[string]any
+		require.NoError(t, msgpack.Unmarshal(body, &decoded))
+		assert.Equal(t, "inv_1", decoded["id"])
+	})
+
+	t.Run("rejects unrenderable media types", func(t *testing.T) {
+		ctx := renderContext("image/png")
+		err := ctx.Render(invoices)
+		var liftErr *LiftError
+		require.True(t, errors.As(err, &liftErr))
+		assert.Equal(t, 406, liftErr.StatusCode)
+		assert.False(t, ctx.Response.IsWritten())
+	})
+}
+
+func TestAccepts(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "text/csv"}
+	assert.Equal(t, "application/json", renderContext("").Accepts(offers...))
+	assert.Equal(t, "application/json", renderContext("*/*").Accepts(offers...))
+	assert.Equal(t, "text/csv", renderContext("text/*, application/*;q=0.9").Accepts(offers...))
+	assert.Equal(t, "application/xml", renderContext("application/*, application/json;q=0").Accepts(offers...))
+	assert.Equal(t, "", renderContext("text/html").Accepts(offers...))
+}
+
+func TestCSVRenderer(t *testing.T) {
+	body, err := CSVRenderer().Render([]map[string]any{{"b": 2, "a": "x"}, {"c": nil}})
+	require.NoError(t, err)
+	assert.Equal(t, "a,b,c\nx,2,\n,,\n", string(body))
+
+	body, err = CSVRenderer().Render([][]string{{"a", "b"}, {"1", "two, three"}})
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n1,\"two, three\"\n", string(body))
+
+	_, err = CSVRenderer().Render(42)
+	assert.Error(t, err)
+}
+
+func TestWithRenderers(t *testing.T) {
+	yaml := NewRenderer("application/yaml", func(data any) ([]byte, error) {
+		return []byte("id: inv_1\n"), nil
+	})
+	protobuf := NewRenderer("application/x-protobuf", func(data any) ([]byte, error) {
+		return []byte{0x0a, 0x05}, nil
+	})
+	app := New(WithRenderers(yaml, protobuf), WithBinaryMediaTypes("application/x-protobuf"))
+	app.GET("/invoices", func(ctx *Context) error {
+		return ctx.Render(map[string]string{"id": "inv_1"})
+	})
+
+	request := func(accept string) *Response {
+		resp, err := app.HandleRequest(context.Background(), map[string]any{
+			"resource":       "/invoices",
+			"httpMethod":     "GET",
+			"path":           "/invoices",
+			"requestContext": map[string]any{"requestId": "req-1"},
+			"headers":        map[string]any{"Accept": accept},
+		})
+		require.NoError(t, err)
+		return resp.(*Response)
+	}
+
+	resp := request("application/yaml")
+	assert.Equal(t, "id: inv_1\n", resp.Body)
+	assert.False(t, resp.IsBase64Encoded)
+
+	resp = request("application/x-protobuf")
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x0a, 0x05}), resp.Body)
+	assert.True(t, resp.IsBase64Encoded)
+
+	// The defaults are still available
+	resp = request("text/csv")
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Headers["Content-Type"])
+}
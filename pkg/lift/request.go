@@ -43,13 +43,16 @@ func NewRequest(adapterReq *adapters.Request) *Request {
 
 // Re-export constants from adapters
 const (
-	TriggerAPIGateway   = adapters.TriggerAPIGateway
-	TriggerAPIGatewayV2 = adapters.TriggerAPIGatewayV2
-	TriggerSQS          = adapters.TriggerSQS
-	TriggerS3           = adapters.TriggerS3
-	TriggerEventBridge  = adapters.TriggerEventBridge
-	TriggerWebSocket    = adapters.TriggerWebSocket
-	TriggerUnknown      = adapters.TriggerUnknown
+	TriggerAPIGateway     = adapters.TriggerAPIGateway
+	TriggerAPIGatewayV2   = adapters.TriggerAPIGatewayV2
+	TriggerSQS            = adapters.TriggerSQS
+	TriggerS3             = adapters.TriggerS3
+	TriggerSNS            = adapters.TriggerSNS
+	TriggerEventBridge    = adapters.TriggerEventBridge
+	TriggerWebSocket      = adapters.TriggerWebSocket
+	TriggerDynamoDBStream = adapters.TriggerDynamoDBStream
+	TriggerCognito        = adapters.TriggerCognito
+	TriggerUnknown        = adapters.TriggerUnknown
 )
 
 // RequestContext provides backward compatibility for accessing request context
@@ -0,0 +1,68 @@
+package lift
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// Headers carrying the request ID between services. Callers may send
+// either; the ID is returned and forwarded as HeaderRequestID.
+const (
+	HeaderRequestID     = "X-Request-ID"
+	HeaderCorrelationID = "X-Correlation-ID"
+)
+
+// maxRequestIDLength bounds IDs accepted from callers
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID, so code that
+// only sees a context.Context (service clients, stores) can propagate it
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID set with WithRequestID, or
+// the Lambda request ID of the invocation
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return requestID
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		return lc.AwsRequestID
+	}
+	return ""
+}
+
+// IncomingRequestID returns the request ID sent in headers, preferring
+// X-Request-ID over X-Correlation-ID. IDs that are too long or contain
+// characters other than letters, digits and "-_.:" are ignored so they
+// can't be used to forge log lines.
+func IncomingRequestID(headers map[string]string) string {
+	for _, name := range []string{HeaderRequestID, HeaderCorrelationID} {
+		if requestID := headerValue(headers, name); validRequestID(requestID) {
+			return requestID
+		}
+	}
+	return ""
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package lift
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncomingRequestID(t *testing.T) {
+	assert.Equal(t, "req-1", IncomingRequestID(map[string]string{"x-request-id": "req-1", "X-Correlation-ID": "corr-1"}))
+	assert.Equal(t, "corr-1", IncomingRequestID(map[string]string{"X-Correlation-ID": "corr-1"}))
+	assert.Equal(t, "corr-1", IncomingRequestID(map[string]string{"X-Request-ID": "bad\nid", "X-Correlation-ID": "corr-1"}))
+	assert.Empty(t, IncomingRequestID(map[string]string{"X-Request-ID": strings.Repeat("a", 129)}))
+	assert.Empty(t, IncomingRequestID(nil))
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	assert.Empty(t, RequestIDFromContext(context.Background()))
+
+	lambdaCtx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda-1"})
+	assert.Equal(t, "lambda-1", RequestIDFromContext(lambdaCtx))
+	assert.Equal(t, "req-1", RequestIDFromContext(WithRequestID(lambdaCtx, "req-1")))
+
+	ctx := NewContext(context.Background(), NewRequest(nil))
+	ctx.SetRequestID("req-2")
+	assert.Equal(t, "req-2", ctx.GetRequestID())
+	assert.Equal(t, "req-2", RequestIDFromContext(ctx.Context))
+}
+
+func TestHandleRequestUsesLambdaRequestID(t *testing.T) {
+	var got []string
+	var mu sync.Mutex
+	record := func(ctx *Context) error {
+		mu.Lock()
+		got = append(got, ctx.RequestID+"|"+RequestIDFromContext(ctx.Context))
+		mu.Unlock()
+		return nil
+	}
+
+	app := New()
+	app.GET("/ping", record)
+	require.NoError(t, app.SQS("settlements", SQSRecords(record)))
+
+	lambdaCtx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda-1"})
+	_, err := app.HandleRequest(lambdaCtx, map[string]any{
+		"resource":       "/ping",
+		"httpMethod":     "GET",
+		"path":           "/ping",
+		"requestContext": map[string]any{"requestId": "apigw-1"},
+		"headers":        map[string]any{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lambda-1|lambda-1"}, got)
+
+	// A message sent with a request ID keeps it; others share the batch's
+	got = nil
+	event := sqsEvent("settlements", "", `{}`, `{}`)
+	records := event["Records"].([]any)
+	records[0].(map[string]any)["messageAttributes"] = map[string]any{
+		"X-Correlation-ID": map[string]any{"stringValue": "producer-1", "dataType": "String"},
+	}
+	_, err = app.HandleRequest(lambdaCtx, event)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"producer-1|producer-1", "lambda-1|lambda-1"}, got)
+}
@@ -0,0 +1,282 @@
+package lift
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Well-known route metadata keys
+const (
+	// MetaAuth names the authentication scheme a route requires ("none" for public routes)
+	MetaAuth = "auth"
+	// MetaRoles is a comma-separated list of roles, any of which grants access
+	MetaRoles = "roles"
+	// MetaScopes is a comma-separated list of scopes, all of which are required
+	MetaScopes = "scopes"
+	// MetaSLO is the latency objective for the route, e.g. "300ms"
+	MetaSLO = "slo"
+	// MetaSLOTarget is the fraction of requests that must meet the SLO, e.g. "99.9%"
+	MetaSLOTarget = "slo_target"
+	// MetaLatencyBudget is how long the handler is expected to take, e.g.
+	// "200ms"; slower requests are logged and counted
+	MetaLatencyBudget = "latency_budget"
+	// MetaTimeout is the hard ceiling on the handler, e.g. "2s"; slower
+	// requests are aborted with 504
+	MetaTimeout = "timeout"
+	// MetaSummary is a one-line description of the route for documentation
+	MetaSummary = "summary"
+	// MetaDescription is a longer description of the route for documentation
+	MetaDescription = "description"
+	// MetaTags is a comma-separated list of tags grouping the route in
+	// documentation and labelling its metrics
+	MetaTags = "tags"
+	// MetaDeprecated flags a route that clients should stop calling
+	MetaDeprecated = "deprecated"
+	// MetaBreakGlass flags a sensitive route; its value is the break-glass
+	// scope an active emergency grant must cover
+	MetaBreakGlass = "break_glass"
+	// MetaDualControl flags a high-risk route; its value names the action a
+	// second user must approve before the handler runs
+	MetaDualControl = "dual_control"
+	// MetaStepUp flags a sensitive route; its value is the authentication
+	// context class (acr) the caller must have recently authenticated with
+	MetaStepUp = "step_up"
+	// MetaNoImpersonation flags a route that impersonating actors may not
+	// call, such as changing the user's password
+	MetaNoImpersonation = "no_impersonation"
+	// MetaEncrypt marks response data to encrypt for the calling client: "*"
+	// for the whole body, or a comma-separated list of dotted field paths
+	MetaEncrypt = "encrypt"
+)
+
+// Route is a registered route. Metadata attached with Meta is queryable at
+// runtime through the request context and used by middleware and tooling.
+type Route struct {
+	Method string
+	Path   string
+
+	mu       sync.RWMutex
+	metadata map[string]string
+	err      error
+
+	// signature is the typed handler's request and response, if known
+	signature *handlerSignature
+}
+
+// RouteInfo is a serializable snapshot of a route
+type RouteInfo struct {
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Summary    string            `json:"summary,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+func newRoute(method, path string) *Route {
+	return &Route{
+		Method:   method,
+		Path:     path,
+		metadata: make(map[string]string),
+	}
+}
+
+// Meta attaches a metadata value to the route
+func (r *Route) Meta(key, value string) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metadata[key] = value
+	return r
+}
+
+// Summary sets the route's one-line description
+func (r *Route) Summary(summary string) *Route {
+	return r.Meta(MetaSummary, summary)
+}
+
+// Description sets the route's longer description
+func (r *Route) Description(description string) *Route {
+	return r.Meta(MetaDescription, description)
+}
+
+// Tags adds tags to the route, e.g. app.GET("/users/:id", h).Tags("users")
+func (r *Route) Tags(tags ...string) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	all := splitMeta(r.metadata[MetaTags])
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(all, tag) {
+			all = append(all, tag)
+		}
+	}
+	r.metadata[MetaTags] = strings.Join(all, ",")
+	return r
+}
+
+// Deprecated flags the route as deprecated
+func (r *Route) Deprecated() *Route {
+	return r.Meta(MetaDeprecated, "true")
+}
+
+// IsDeprecated reports whether the route is flagged as deprecated
+func (r *Route) IsDeprecated() bool {
+	value, _ := r.GetMeta(MetaDeprecated)
+	deprecated, _ := strconv.ParseBool(value)
+	return deprecated
+}
+
+// TagList returns the route's tags
+func (r *Route) TagList() []string {
+	value, _ := r.GetMeta(MetaTags)
+	return splitMeta(value)
+}
+
+// GetMeta returns a metadata value and whether it was set
+func (r *Route) GetMeta(key string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	value, ok := r.metadata[key]
+	return value, ok
+}
+
+// Metadata returns a copy of the route's metadata
+func (r *Route) Metadata() map[string]string {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	metadata := make(map[string]string, len(r.metadata))
+	for k, v := range r.metadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// Err returns the error from registering the route, if any
+func (r *Route) Err() error {
+	return r.err
+}
+
+// Info returns a serializable snapshot of the route
+func (r *Route) Info() RouteInfo {
+	summary, _ := r.GetMeta(MetaSummary)
+	return RouteInfo{
+		Method:     r.Method,
+		Path:       r.Path,
+		Summary:    summary,
+		Tags:       r.TagList(),
+		Deprecated: r.IsDeprecated(),
+		Metadata:   r.Metadata(),
+	}
+}
+
+// Routes returns every registered route sorted by path and method
+func (a *App) Routes() []*Route {
+	a.mu.RLock()
+	routes := append([]*Route(nil), a.routes...)
+	a.mu.RUnlock()
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// Route returns the route registered for the method and pattern, or nil
+func (a *App) Route(method, pattern string) *Route {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, route := range a.routes {
+		if route.Method == method && route.Path == pattern {
+			return route
+		}
+	}
+	return nil
+}
+
+// WriteRoutes writes the registered routes and their metadata as JSON, the
+// format read by the `lift routes` command
+func (a *App) WriteRoutes(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(a.routeInfos())
+}
+
+// ServeRoutes registers a GET route listing the registered routes and
+// their metadata in the format written by WriteRoutes, e.g.
+// app.ServeRoutes("/_routes"). The route uses the app's default
+// authentication; mark it public with .Meta(MetaAuth, "none") only if
+// the route list may be disclosed.
+func (a *App) ServeRoutes(path string) *Route {
+	return a.GET(path, func(ctx *Context) error {
+		return ctx.OK(map[string]any{"routes": a.routeInfos()})
+	})
+}
+
+func (a *App) routeInfos() []RouteInfo {
+	routes := a.Routes()
+	infos := make([]RouteInfo, 0, len(routes))
+	for _, route := range routes {
+		infos = append(infos, route.Info())
+	}
+	return infos
+}
+
+// addRoute records a registered route
+func (a *App) addRoute(route *Route) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.routes = append(a.routes, route)
+}
+
+// RouteMeta returns a metadata value of the matched route and whether it was set
+func (c *Context) RouteMeta(key string) (string, bool) {
+	return c.routeInfo.GetMeta(key)
+}
+
+// RouteMetadata returns a copy of the matched route's metadata
+func (c *Context) RouteMetadata() map[string]string {
+	return c.routeInfo.Metadata()
+}
+
+// RouteLabels returns low-cardinality metric labels for the matched route:
+// its pattern, its first tag and whether it's deprecated
+func (c *Context) RouteLabels() map[string]string {
+	labels := map[string]string{"route": c.route}
+	if tags := c.routeInfo.TagList(); len(tags) > 0 {
+		labels["tag"] = tags[0]
+	}
+	if c.routeInfo.IsDeprecated() {
+		labels["deprecated"] = "true"
+	}
+	return labels
+}
+
+// splitMeta splits a comma-separated metadata value
+func splitMeta(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
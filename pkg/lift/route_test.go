@@ -0,0 +1,106 @@
+package lift
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteMetadata(t *testing.T) {
+	app := New()
+
+	var seen map[string]string
+	var auth string
+	route := app.GET("/users/:id", func(ctx *Context) error {
+		seen = ctx.RouteMetadata()
+		auth, _ = ctx.RouteMeta(MetaAuth)
+		return ctx.JSON(nil)
+	}).Meta(MetaAuth, "jwt").Meta(MetaSLO, "300ms")
+	require.NoError(t, route.Err())
+	require.NoError(t, app.POST("/users", func(ctx *Context) error { return nil }).Err())
+
+	ctx := newMountContext("GET", "/users/42")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, "jwt", auth)
+	assert.Equal(t, map[string]string{"auth": "jwt", "slo": "300ms"}, seen)
+
+	// Routes without metadata report nothing
+	ctx = newMountContext("POST", "/users")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	_, ok := ctx.RouteMeta(MetaAuth)
+	assert.False(t, ok)
+
+	assert.Same(t, route, app.Route("GET", "/users/:id"))
+	assert.Nil(t, app.Route("DELETE", "/users/:id"))
+
+	routes := app.Routes()
+	require.Len(t, routes, 2)
+	assert.Equal(t, "POST", routes[0].Method)
+	assert.Equal(t, "/users/:id", routes[1].Path)
+}
+
+func TestRouteRegistrationError(t *testing.T) {
+	app := New()
+
+	route := app.POST("/invalid", "not a handler").Meta(MetaAuth, "jwt")
+	assert.Error(t, route.Err())
+	assert.Empty(t, app.Routes())
+}
+
+func TestWriteRoutes(t *testing.T) {
+	app := New()
+	app.GET("/health", func(ctx *Context) error { return nil }).Meta(MetaAuth, "none")
+
+	sub := New()
+	sub.GET("/charges", func(ctx *Context) error { return nil }).Meta(MetaRoles, "admin")
+	require.NoError(t, app.Mount("/payments", sub))
+
+	var buf bytes.Buffer
+	require.NoError(t, app.WriteRoutes(&buf))
+
+	var routes []RouteInfo
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &routes))
+	assert.Equal(t, []RouteInfo{
+		{Method: "GET", Path: "/health", Metadata: map[string]string{"auth": "none"}},
+		{Method: "GET", Path: "/payments/charges", Metadata: map[string]string{"roles": "admin"}},
+	}, routes)
+}
+
+func TestRouteDocumentation(t *testing.T) {
+	app := New()
+	route := app.GET("/users/:id", func(ctx *Context) error {
+		return ctx.OK(ctx.RouteLabels())
+	}).Summary("Get user").Description("Returns one user").Tags("users", "accounts").Tags("users").Deprecated()
+	app.ServeRoutes("/_routes")
+
+	assert.Equal(t, []string{"users", "accounts"}, route.TagList())
+	assert.True(t, route.IsDeprecated())
+	assert.Equal(t, RouteInfo{
+		Method:     "GET",
+		Path:       "/users/:id",
+		Summary:    "Get user",
+		Tags:       []string{"users", "accounts"},
+		Deprecated: true,
+		Metadata: map[string]string{
+			MetaSummary:     "Get user",
+			MetaDescription: "Returns one user",
+			MetaTags:        "users,accounts",
+			MetaDeprecated:  "true",
+		},
+	}, route.Info())
+
+	ctx := newMountContext("GET", "/users/42")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, map[string]string{"route": "/users/:id", "tag": "users", "deprecated": "true"}, ctx.Response.Body)
+
+	ctx = newMountContext("GET", "/_routes")
+	require.NoError(t, app.HandleTestRequest(ctx))
+	require.Equal(t, 200, ctx.Response.StatusCode)
+	routes := ctx.Response.Body.(map[string]any)["routes"].([]RouteInfo)
+	require.Len(t, routes, 2)
+	assert.Equal(t, "/_routes", routes[0].Path)
+	assert.Equal(t, "Get user", routes[1].Summary)
+}
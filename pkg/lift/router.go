@@ -13,8 +13,14 @@ type Router struct {
 	// Parameter routes (e.g., /users/:id)
 	paramRoutes map[string][]*paramRoute // method -> []paramRoute
 
+	// Route metadata
+	info map[string]*Route // "method pattern" -> route
+
 	// Global middleware
 	middleware []Middleware
+
+	// Middleware run before route matching
+	preRouting []Middleware
 }
 
 // paramRoute represents a route with parameters
@@ -29,6 +35,7 @@ func NewRouter() *Router {
 	return &Router{
 		routes:      make(map[string]map[string]Handler),
 		paramRoutes: make(map[string][]*paramRoute),
+		info:        make(map[string]*Route),
 		middleware:  make([]Middleware, 0),
 	}
 }
@@ -62,21 +69,92 @@ func (r *Router) addParamRoute(method, path string, handler Handler) {
 	r.paramRoutes[method] = append(r.paramRoutes[method], route)
 }
 
+// setRouteInfo records the metadata for a registered route
+func (r *Router) setRouteInfo(route *Route) {
+	r.info[route.Method+" "+route.Path] = route
+}
+
+// routeInfo returns the metadata for a route, or nil
+func (r *Router) routeInfo(method, pattern string) *Route {
+	return r.info[method+" "+pattern]
+}
+
+// hasRoute reports whether a route is registered for the method and
+// pattern. Parameter names are ignored, so /x/:id and /x/:pid conflict.
+func (r *Router) hasRoute(method, pattern string) bool {
+	if _, exists := r.routes[method][pattern]; exists {
+		return true
+	}
+	shape := routeShape(pattern)
+	for _, route := range r.paramRoutes[method] {
+		if routeShape(route.pattern) == shape {
+			return true
+		}
+	}
+	return false
+}
+
+// routeShape replaces a pattern's parameter names with ":", so patterns
+// matching the same paths compare equal
+func routeShape(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = ":"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// forEachRoute calls fn for every registered route
+func (r *Router) forEachRoute(fn func(method, pattern string, handler Handler)) {
+	for method, routes := range r.routes {
+		for path, handler := range routes {
+			fn(method, path, handler)
+		}
+	}
+	for method, routes := range r.paramRoutes {
+		for _, route := range routes {
+			fn(method, route.pattern, route.handler)
+		}
+	}
+}
+
 // SetMiddleware sets the global middleware stack
 func (r *Router) SetMiddleware(middleware []Middleware) {
 	r.middleware = middleware
 }
 
+// SetPreRoutingMiddleware sets the middleware run before route matching
+func (r *Router) SetPreRoutingMiddleware(middleware []Middleware) {
+	r.preRouting = middleware
+}
+
 // Handle processes a request through the router
 func (r *Router) Handle(ctx *Context) error {
+	if len(r.preRouting) == 0 {
+		return r.dispatch(ctx)
+	}
+
+	handler := Handler(HandlerFunc(r.dispatch))
+	for i := len(r.preRouting) - 1; i >= 0; i-- {
+		handler = r.preRouting[i](handler)
+	}
+	return handler.Handle(ctx)
+}
+
+// dispatch matches the request to a route and runs it through the middleware chain
+func (r *Router) dispatch(ctx *Context) error {
 	method := ctx.Request.Method
 	path := ctx.Request.Path
 
 	// Find the handler
-	handler, params := r.findHandler(method, path)
+	handler, params, pattern := r.findRoute(method, path)
 	if handler == nil {
 		return fmt.Errorf("route not found: %s %s", method, path)
 	}
+	ctx.SetRoute(pattern)
+	ctx.routeInfo = r.routeInfo(method, pattern)
 
 	// Set path parameters in context
 	for key, value := range params {
@@ -84,7 +162,7 @@ func (r *Router) Handle(ctx *Context) error {
 	}
 
 	// Apply middleware chain
-	finalHandler := handler
+	finalHandler := withLatencyBudget(ctx.routeInfo, handler)
 	for i := len(r.middleware) - 1; i >= 0; i-- {
 		finalHandler = r.middleware[i](finalHandler)
 	}
@@ -95,10 +173,16 @@ func (r *Router) Handle(ctx *Context) error {
 
 // findHandler finds a handler for the given method and path
 func (r *Router) findHandler(method, path string) (Handler, map[string]string) {
+	handler, params, _ := r.findRoute(method, path)
+	return handler, params
+}
+
+// findRoute finds a handler for the given method and path along with the matched route pattern
+func (r *Router) findRoute(method, path string) (Handler, map[string]string, string) {
 	// Try exact match first
 	if methodRoutes, exists := r.routes[method]; exists {
 		if handler, exists := methodRoutes[path]; exists {
-			return handler, nil
+			return handler, nil, path
 		}
 	}
 
@@ -106,12 +190,12 @@ func (r *Router) findHandler(method, path string) (Handler, map[string]string) {
 	if paramRoutes, exists := r.paramRoutes[method]; exists {
 		for _, route := range paramRoutes {
 			if params := matchPattern(route.pattern, path); params != nil {
-				return route.handler, params
+				return route.handler, params, route.pattern
 			}
 		}
 	}
 
-	return nil, nil
+	return nil, nil, ""
 }
 
 // extractParams extracts parameter names from a route pattern
@@ -0,0 +1,89 @@
+package lift
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+)
+
+// S3Objects registers a handler for S3 event notifications matching
+// pattern (see S3). The handler runs once per object, behind the app's
+// middleware, so logging, tracing and error handling see each object the
+// way they see an HTTP request. The request body is the object as a JSON
+// adapters.S3Object, and ctx.S3Object returns it:
+//
+//	app.S3Objects("uploads/*.csv", func(ctx *lift.Context) error {
+//		object, err := ctx.S3Object()
+//		if err != nil {
+//			return err
+//		}
+//		return importSettlements(ctx, object.Bucket, object.Key)
+//	})
+//
+// Every object is processed even if an earlier one fails; the failures are
+// returned together so the invocation is retried.
+func (a *App) S3Objects(pattern string, handler any) error {
+	h, err := asHandler(handler)
+	if err != nil {
+		return fmt.Errorf("invalid S3 handler: %w", err)
+	}
+
+	a.eventRouter.AddEventRoute(TriggerS3, pattern, EventHandlerFunc(func(ctx *Context) error {
+		chain := a.withMiddleware(h)
+
+		var failures []error
+		for _, req := range adapters.SplitS3Records(ctx.Request.Request) {
+			if err := runRecord(chain, ctx.recordContext(req)); err != nil {
+				if ctx.Logger != nil {
+					ctx.Logger.Warn("S3 object failed", map[string]any{
+						"bucket": req.Metadata["bucket"],
+						"key":    req.Metadata["key"],
+						"error":  err.Error(),
+					})
+				}
+				failures = append(failures, fmt.Errorf("%s/%s: %w", req.Metadata["bucket"], req.Metadata["key"], err))
+			}
+		}
+		return errors.Join(failures...)
+	}))
+	return nil
+}
+
+// S3Object returns the object the current S3 event is about: the first
+// record of an S3 notification (the only one inside S3Objects), or the
+// detail of an EventBridge "Object Created"/"Object Deleted" event
+func (c *Context) S3Object() (*adapters.S3Object, error) {
+	if c.Request == nil {
+		return nil, fmt.Errorf("not an S3 event")
+	}
+
+	switch {
+	case c.Request.TriggerType == TriggerS3:
+		if len(c.Request.Records) == 0 {
+			return nil, fmt.Errorf("no S3 records found")
+		}
+		record, ok := c.Request.Records[0].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid S3 record format")
+		}
+		object := adapters.ParseS3Record(record)
+		return &object, nil
+
+	case c.Request.TriggerType == TriggerEventBridge && c.Request.Source == "aws.s3":
+		bucket := getMapField(c.Request.Detail, "bucket")
+		object := getMapField(c.Request.Detail, "object")
+		size, _ := object["size"].(float64)
+		return &adapters.S3Object{
+			EventName: c.Request.DetailType,
+			EventTime: c.Request.Timestamp,
+			Bucket:    getStringField(bucket, "name"),
+			Key:       getStringField(object, "key"),
+			Size:      int64(size),
+			ETag:      getStringField(object, "etag"),
+			VersionID: getStringField(object, "version-id"),
+			Sequencer: getStringField(object, "sequencer"),
+		}, nil
+	}
+	return nil, fmt.Errorf("not an S3 event")
+}
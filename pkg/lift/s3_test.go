@@ -0,0 +1,107 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func s3Notification(keys ...string) map[string]any {
+	records := make([]any, len(keys))
+	for i, key := range keys {
+		records[i] = map[string]any{
+			"eventSource": "aws:s3",
+			"eventName":   "ObjectCreated:Put",
+			"eventTime":   "2024-06-01T12:00:00.000Z",
+			"awsRegion":   "us-east-1",
+			"s3": map[string]any{
+				"bucket": map[string]any{"name": "settlements"},
+				"object": map[string]any{"key": key, "size": float64(100 * (i + 1))},
+			},
+		}
+	}
+	return map[string]any{"Records": records}
+}
+
+func TestS3Objects(t *testing.T) {
+	var seen []string
+	var middlewareRuns int
+
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			middlewareRuns++
+			return next.Handle(ctx)
+		})
+	})
+	require.NoError(t, app.S3Objects("settlements/*.csv", SimpleHandler(func(ctx *Context, object adapters.S3Object) (struct{}, error) {
+		fromCtx, err := ctx.S3Object()
+		require.NoError(t, err)
+		assert.Equal(t, object, *fromCtx)
+
+		seen = append(seen, object.Key)
+		if object.Key == "bad.csv" {
+			return struct{}{}, errors.New("malformed file")
+		}
+		return struct{}{}, nil
+	})))
+
+	result, err := app.HandleRequest(context.Background(), s3Notification("June+report.csv", "bad.csv", "july.csv"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"June report.csv", "bad.csv", "july.csv"}, seen, "every object is processed")
+	assert.Equal(t, 3, middlewareRuns, "each object runs through the middleware chain")
+	resp, ok := result.(*Response)
+	require.True(t, ok)
+	assert.Equal(t, 500, resp.StatusCode, "a failed object fails the invocation")
+}
+
+func TestContextS3Object(t *testing.T) {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		TriggerType: TriggerS3,
+		Records:     s3Notification("a.csv")["Records"].([]any),
+	}))
+	object, err := ctx.S3Object()
+	require.NoError(t, err)
+	assert.Equal(t, &adapters.S3Object{
+		EventName: "ObjectCreated:Put",
+		EventTime: "2024-06-01T12:00:00.000Z",
+		Region:    "us-east-1",
+		Bucket:    "settlements",
+		Key:       "a.csv",
+		Size:      100,
+	}, object)
+
+	event, err := ctx.ParseS3Event()
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), event.ObjectSize)
+
+	ctx = NewContext(context.Background(), NewRequest(&adapters.Request{
+		TriggerType: TriggerEventBridge,
+		Source:      "aws.s3",
+		DetailType:  "Object Created",
+		Timestamp:   "2024-06-01T12:00:00Z",
+		Detail: map[string]any{
+			"bucket": map[string]any{"name": "settlements"},
+			"object": map[string]any{"key": "b.csv", "size": float64(5), "etag": "e1", "version-id": "v2"},
+		},
+	}))
+	object, err = ctx.S3Object()
+	require.NoError(t, err)
+	assert.Equal(t, &adapters.S3Object{
+		EventName: "Object Created",
+		EventTime: "2024-06-01T12:00:00Z",
+		Bucket:    "settlements",
+		Key:       "b.csv",
+		Size:      5,
+		ETag:      "e1",
+		VersionID: "v2",
+	}, object)
+
+	_, err = NewContext(context.Background(), NewRequest(&adapters.Request{TriggerType: TriggerSQS})).S3Object()
+	assert.Error(t, err)
+}
@@ -0,0 +1,257 @@
+package lift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Request schema version metric names
+const (
+	// SchemaVersionMetric counts requests by route and schema version
+	SchemaVersionMetric = "request.schema_version"
+	// DeprecatedSchemaMetric counts requests sent with a deprecated schema
+	// version, by route and version
+	DeprecatedSchemaMetric = "request.schema_deprecated"
+)
+
+// DefaultSchemaVersionHeader is the header a request names its schema
+// version in, unless configured with VersionHeader
+const DefaultSchemaVersionHeader = "X-Schema-Version"
+
+const schemaVersionKey = "schema_version"
+
+// RequestVersions registers the schema versions a route's request has
+// been sent in. Older versions are decoded and validated as their own
+// types, then upgraded version by version to the current one, so the
+// handler only ever sees the current request:
+//
+//	versions := lift.NewRequestVersions[CreateOrderV3]("3", lift.VersionField("schema_version"))
+//	lift.AddRequestVersion(versions, "2", "3", upgradeOrderV2)
+//	lift.AddRequestVersion(versions, "1", "2", upgradeOrderV1, lift.DeprecatedVersion())
+//
+//	app.POST("/orders", lift.VersionedHandler(versions, createOrder, lift.WithStatus(201)))
+//
+// Requests are counted under SchemaVersionMetric; deprecated versions are
+// also counted under DeprecatedSchemaMetric, logged, and answered with a
+// "Deprecation: true" header.
+type RequestVersions[Req any] struct {
+	current   string
+	detection versionDetection
+	versions  map[string]*schemaVersion
+}
+
+// schemaVersion is one registered version of a request
+type schemaVersion struct {
+	request reflect.Type
+	// next is the version upgrade produces, or "" for the current version
+	next       string
+	upgrade    func(any) (any, error)
+	deprecated bool
+}
+
+type versionDetection struct {
+	header   string
+	field    string
+	fallback string
+}
+
+// RequestVersionsOption configures how a request's schema version is detected
+type RequestVersionsOption func(*versionDetection)
+
+// VersionHeader reads the version from the named header (default:
+// DefaultSchemaVersionHeader)
+func VersionHeader(name string) RequestVersionsOption {
+	return func(d *versionDetection) { d.header = name }
+}
+
+// VersionField reads the version from a top-level field of the JSON body
+// when the header is absent. The field may be a string or a number.
+func VersionField(name string) RequestVersionsOption {
+	return func(d *versionDetection) { d.field = name }
+}
+
+// DefaultVersion is assumed for requests that don't name a version
+// (default: the current version). Set it to the oldest version when
+// clients predate versioning.
+func DefaultVersion(version string) RequestVersionsOption {
+	return func(d *versionDetection) { d.fallback = version }
+}
+
+// VersionOption configures a registered version
+type VersionOption func(*schemaVersion)
+
+// DeprecatedVersion flags a version clients should stop sending
+func DeprecatedVersion() VersionOption {
+	return func(v *schemaVersion) { v.deprecated = true }
+}
+
+// NewRequestVersions creates a registry whose current version is Req
+func NewRequestVersions[Req any](current string, opts ...RequestVersionsOption) *RequestVersions[Req] {
+	detection := versionDetection{header: DefaultSchemaVersionHeader, fallback: current}
+	for _, opt := range opts {
+		opt(&detection)
+	}
+	return &RequestVersions[Req]{
+		current:   current,
+		detection: detection,
+		versions: map[string]*schemaVersion{
+			current: {request: reflect.TypeOf((*Req)(nil)).Elem()},
+		},
+	}
+}
+
+// AddRequestVersion registers version, sent as Old, and the upgrade to the
+// already registered version to, whose request is New. It panics if to
+// isn't registered as New or version is already registered, since either
+// is a mistake in the route's setup.
+func AddRequestVersion[Old, New, Req any](versions *RequestVersions[Req], version, to string, upgrade func(Old) (New, error), opts ...VersionOption) {
+	if _, ok := versions.versions[version]; ok {
+		panic(fmt.Sprintf("lift: request schema version %q is already registered", version))
+	}
+	target, ok := versions.versions[to]
+	if !ok || target.request != reflect.TypeOf((*New)(nil)).Elem() {
+		panic(fmt.Sprintf("lift: request schema version %q upgrades to %q, which isn't registered as %s",
+			version, to, reflect.TypeOf((*New)(nil)).Elem()))
+	}
+
+	v := &schemaVersion{
+		request: reflect.TypeOf((*Old)(nil)).Elem(),
+		next:    to,
+		upgrade: func(old any) (any, error) {
+			return upgrade(old.(Old))
+		},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	versions.versions[version] = v
+}
+
+// Current returns the current version
+func (r *RequestVersions[Req]) Current() string {
+	return r.current
+}
+
+// Versions returns the registered versions, sorted
+func (r *RequestVersions[Req]) Versions() []string {
+	names := make([]string, 0, len(r.versions))
+	for name := range r.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bind decodes the request in the version it was sent in and upgrades it
+// to the current version
+func (r *RequestVersions[Req]) bind(ctx *Context, req *Req) error {
+	version := r.detect(ctx)
+	v, ok := r.versions[version]
+	if !ok {
+		return NewLiftError("UNSUPPORTED_SCHEMA_VERSION", fmt.Sprintf("Unsupported request schema version %q", version), 400).
+			WithDetail("supported", r.Versions())
+	}
+	ctx.Set(schemaVersionKey, version)
+	r.record(ctx, version, v)
+
+	if version == r.current {
+		return bindTypedRequest(ctx, req)
+	}
+
+	decoded := reflect.New(v.request)
+	if err := bindTypedRequest(ctx, decoded.Interface()); err != nil {
+		return err
+	}
+	value := decoded.Elem().Interface()
+	for v.next != "" {
+		upgraded, err := v.upgrade(value)
+		if err != nil {
+			var liftErr *LiftError
+			if errors.As(err, &liftErr) {
+				return liftErr
+			}
+			return NewLiftError("SCHEMA_UPGRADE_FAILED", "Request can't be upgraded to the current schema version", 400).
+				WithDetail("version", version).
+				WithCause(err)
+		}
+		value, v = upgraded, r.versions[v.next]
+	}
+	*req = value.(Req)
+	return nil
+}
+
+// detect returns the version the request names, or the default
+func (r *RequestVersions[Req]) detect(ctx *Context) string {
+	if r.detection.header != "" {
+		if version := strings.TrimSpace(ctx.Header(r.detection.header)); version != "" {
+			return version
+		}
+	}
+	if r.detection.field != "" && ctx.Request != nil && len(ctx.Request.Body) > 0 {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(ctx.Request.Body, &fields) == nil {
+			if raw, ok := fields[r.detection.field]; ok {
+				var version string
+				if json.Unmarshal(raw, &version) == nil && version != "" {
+					return version
+				}
+				var number json.Number
+				if json.Unmarshal(raw, &number) == nil {
+					return number.String()
+				}
+			}
+		}
+	}
+	return r.detection.fallback
+}
+
+// record counts the request's version and flags deprecated ones
+func (r *RequestVersions[Req]) record(ctx *Context, version string, v *schemaVersion) {
+	route := ctx.Route()
+	if ctx.Request != nil {
+		route = ctx.Request.Method + " " + route
+	}
+	tags := map[string]string{"route": route, "version": version}
+	if ctx.Metrics != nil {
+		ctx.Metrics.Counter(SchemaVersionMetric, tags).Inc()
+	}
+	if !v.deprecated {
+		return
+	}
+
+	ctx.Response.Header("Deprecation", "true")
+	if ctx.Metrics != nil {
+		ctx.Metrics.Counter(DeprecatedSchemaMetric, tags).Inc()
+	}
+	if ctx.Logger != nil {
+		ctx.Logger.Warn("Request sent with a deprecated schema version", map[string]any{
+			"route":   route,
+			"version": version,
+			"current": r.current,
+		})
+	}
+}
+
+// SchemaVersion returns the schema version the request was sent in, for
+// routes registered with VersionedHandler
+func (c *Context) SchemaVersion() string {
+	version, _ := c.Get(schemaVersionKey).(string)
+	return version
+}
+
+// VersionedHandler creates a Handler like SimpleHandler whose request may
+// be sent in any version registered with versions
+func VersionedHandler[Req, Resp any](versions *RequestVersions[Req], handler func(ctx *Context, req Req) (Resp, error), opts ...TypedOption) Handler {
+	adapter := &typedHandlerAdapter[Req, Resp]{
+		handler: TypedHandlerFunc[Req, Resp](handler),
+		bind:    versions.bind,
+	}
+	for _, opt := range opts {
+		opt(&adapter.options)
+	}
+	return adapter
+}
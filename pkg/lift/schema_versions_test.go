@@ -0,0 +1,116 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderV1 struct {
+	Amount string `json:"amount" validate:"required"`
+}
+
+type orderV2 struct {
+	AmountCents int `json:"amount_cents" validate:"required"`
+}
+
+type orderV3 struct {
+	Amount   int    `json:"amount" validate:"required"`
+	Currency string `json:"currency" validate:"required"`
+}
+
+func TestVersionedHandler(t *testing.T) {
+	versions := NewRequestVersions[orderV3]("3", VersionField("schema_version"), DefaultVersion("1"))
+	AddRequestVersion(versions, "2", "3", func(v orderV2) (orderV3, error) {
+		return orderV3{Amount: v.AmountCents, Currency: "USD"}, nil
+	})
+	AddRequestVersion(versions, "1", "2", func(v orderV1) (orderV2, error) {
+		if v.Amount == "free" {
+			return orderV2{}, errors.New("amount isn't a number")
+		}
+		return orderV2{AmountCents: len(v.Amount) * 100}, nil
+	}, DeprecatedVersion())
+	assert.Equal(t, []string{"1", "2", "3"}, versions.Versions())
+
+	app := New()
+	app.POST("/orders", VersionedHandler(versions, func(ctx *Context, req orderV3) (map[string]any, error) {
+		return map[string]any{"order": req, "version": ctx.SchemaVersion()}, nil
+	}, WithStatus(201)))
+
+	metrics := &countingMetrics{}
+	request := func(body string, headers map[string]string) *Context {
+		ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+			Method:  "POST",
+			Path:    "/orders",
+			Headers: headers,
+			Body:    []byte(body),
+		}))
+		ctx.Metrics = metrics
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		headers map[string]string
+		order   orderV3
+		version string
+	}{
+		{
+			name:    "current version from the header",
+			body:    `{"amount": 500, "currency": "EUR"}`,
+			headers: map[string]string{DefaultSchemaVersionHeader: "3"},
+			order:   orderV3{Amount: 500, Currency: "EUR"},
+			version: "3",
+		},
+		{
+			name:    "version from a numeric body field",
+			body:    `{"schema_version": 2, "amount_cents": 250}`,
+			order:   orderV3{Amount: 250, Currency: "USD"},
+			version: "2",
+		},
+		{
+			name:    "oldest version by default, upgraded twice",
+			body:    `{"amount": "12"}`,
+			order:   orderV3{Amount: 200, Currency: "USD"},
+			version: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := request(tt.body, tt.headers)
+			require.Equal(t, 201, ctx.Response.StatusCode)
+			body := ctx.Response.Body.(map[string]any)
+			assert.Equal(t, tt.order, body["order"])
+			assert.Equal(t, tt.version, body["version"])
+		})
+	}
+	assert.Equal(t, 3, metrics.counts[SchemaVersionMetric])
+	assert.Equal(t, 1, metrics.counts[DeprecatedSchemaMetric])
+
+	deprecated := request(`{"amount": "1"}`, map[string]string{DefaultSchemaVersionHeader: "1"})
+	assert.Equal(t, "true", deprecated.Response.Headers["Deprecation"])
+
+	// Old versions are validated with their own tags
+	assert.Equal(t, 400, request(`{"schema_version": "2", "amount": 5}`, nil).Response.StatusCode)
+	assert.Equal(t, 400, request(`{"amount": "free"}`, nil).Response.StatusCode)
+	assert.Equal(t, 400, request(`{}`, map[string]string{DefaultSchemaVersionHeader: "9"}).Response.StatusCode)
+}
+
+func TestAddRequestVersionPanicsOnBadChain(t *testing.T) {
+	versions := NewRequestVersions[orderV3]("3")
+	assert.Panics(t, func() {
+		AddRequestVersion(versions, "1", "2", func(v orderV1) (orderV2, error) { return orderV2{}, nil })
+	}, "version 2 isn't registered")
+	assert.Panics(t, func() {
+		AddRequestVersion(versions, "2", "3", func(v orderV1) (orderV2, error) { return orderV2{}, nil })
+	}, "version 3 isn't an orderV2")
+	assert.Panics(t, func() {
+		AddRequestVersion(versions, "3", "3", func(v orderV3) (orderV3, error) { return v, nil })
+	})
+}
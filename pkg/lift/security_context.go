@@ -35,6 +35,9 @@ func (sc *SecurityContext) SetPrincipal(principal *security.Principal) {
 	sc.Set("roles", principal.Roles)
 	sc.Set("scopes", principal.Scopes)
 	sc.Set("auth_method", principal.AuthMethod)
+	if principal.Actor != nil {
+		sc.Set("actor_id", principal.Actor.UserID)
+	}
 
 	// Update request tracking
 	principal.RequestID = sc.requestID
@@ -155,9 +158,52 @@ func (sc *SecurityContext) ToAuditMap() map[string]any {
 		}
 	}
 
+	// Add residency tags set by the data residency middleware
+	if region, ok := sc.Get("data_residency").(string); ok {
+		auditData["data_residency"] = region
+	}
+	if region, ok := sc.Get("serving_region").(string); ok {
+		auditData["serving_region"] = region
+	}
+
+	// Add the calling client application and device
+	if client := sc.Client(); !client.IsEmpty() {
+		auditData["client"] = client.AuditContext()
+	}
+
 	return auditData
 }
 
+// AuditEvent returns an audit event describing the request, for audit
+// analytics and risk scoring
+func (sc *SecurityContext) AuditEvent(eventType, action string) *security.AuditEvent {
+	result := "success"
+	if sc.Response.StatusCode >= 400 {
+		result = "failure"
+	}
+
+	event := &security.AuditEvent{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now(),
+		EventType: eventType,
+		Source:    "lift",
+		UserID:    sc.UserID(),
+		TenantID:  sc.TenantID(),
+		Action:    action,
+		Resource:  sc.Request.Path,
+		Result:    result,
+		IPAddress: sc.GetClientIP(),
+		UserAgent: sc.GetUserAgent(),
+		RequestID: sc.requestID,
+		Duration:  sc.Duration(),
+		Client:    sc.Client().AuditContext(),
+	}
+	if sc.principal != nil {
+		event.Security.AuthMethod = sc.principal.AuthMethod
+	}
+	return event
+}
+
 // RequireAuthentication returns an error if not authenticated
 func (sc *SecurityContext) RequireAuthentication() error {
 	if !sc.IsAuthenticated() {
@@ -0,0 +1,194 @@
+package lift
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// maxSignedURLTTL is the longest lifetime SignedRedirect will issue (SigV4 limit)
+const maxSignedURLTTL = 7 * 24 * time.Hour
+
+// SignedURLRequest describes a URL to be signed
+type SignedURLRequest struct {
+	Bucket  string
+	Key     string
+	Expires time.Time
+	// IPAddress restricts the URL to a CIDR range. Signers that can't enforce
+	// IP restrictions must return an error rather than ignore it.
+	IPAddress string
+	// ContentDisposition overrides the Content-Disposition of the download
+	ContentDisposition string
+}
+
+// URLSigner issues presigned URLs for stored objects
+type URLSigner interface {
+	SignURL(ctx context.Context, req *SignedURLRequest) (string, error)
+}
+
+// SignedURLConstraints restrict a URL issued by SignedRedirect
+type SignedURLConstraints struct {
+	// IPAddress restricts the URL to a CIDR range, e.g. "203.0.113.0/24"
+	IPAddress string
+	// BindClientIP restricts the URL to the IP address of the requesting client
+	BindClientIP bool
+	// Filename makes the download an attachment with this file name
+	Filename string
+	// JSON responds with 200 and the URL in the body instead of a 302 redirect,
+	// for clients that can't follow cross-origin redirects
+	JSON bool
+}
+
+// SignedURLAudit records which caller was issued which URL. The signature is
+// never included.
+type SignedURLAudit struct {
+	RequestID string    `json:"request_id"`
+	UserID    string    `json:"user_id,omitempty"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	IPAddress string    `json:"ip_restriction,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SignedURLAuditor receives an audit record for every URL issued
+type SignedURLAuditor func(ctx *Context, audit *SignedURLAudit)
+
+// SignedURLResponse is the body written when constraints request JSON
+type SignedURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WithURLSigner sets the signer used by ctx.SignedRedirect
+func WithURLSigner(signer URLSigner) AppOption {
+	return func(app *App) {
+		app.urlSigner = signer
+	}
+}
+
+// WithSignedURLAuditor registers an auditor for URLs issued by ctx.SignedRedirect,
+// e.g. to write them to a compliance audit trail. Issued URLs are always logged.
+func WithSignedURLAuditor(auditor SignedURLAuditor) AppOption {
+	return func(app *App) {
+		app.urlAuditor = auditor
+	}
+}
+
+// SetURLSigner sets the signer used by SignedRedirect for this request
+func (c *Context) SetURLSigner(signer URLSigner, auditor SignedURLAuditor) {
+	c.urlSigner = signer
+	c.urlAuditor = auditor
+}
+
+// SignedRedirect issues a presigned URL for bucket/key valid for ttl and
+// responds with a 302 redirect to it (or 200 with the URL when
+// constraints.JSON is set). Every issued URL is logged and audited with the
+// calling user.
+func (c *Context) SignedRedirect(bucket, key string, ttl time.Duration, constraints SignedURLConstraints) error {
+	if c.urlSigner == nil {
+		return NewLiftError("SIGNER_NOT_CONFIGURED", "No URL signer is configured", 500)
+	}
+	if ttl <= 0 || ttl > maxSignedURLTTL {
+		return NewLiftError("INVALID_URL_TTL", "Signed URL lifetime must be between 0 and 7 days", 500)
+	}
+
+	clientIP := c.clientIP()
+	ipRestriction := constraints.IPAddress
+	if constraints.BindClientIP {
+		ip := net.ParseIP(clientIP)
+		if ip == nil {
+			return NewLiftError("CLIENT_IP_UNKNOWN", "Client IP address could not be determined", 400)
+		}
+		if ip.To4() != nil {
+			ipRestriction = ip.String() + "/32"
+		} else {
+			ipRestriction = ip.String() + "/128"
+		}
+	}
+	if ipRestriction != "" {
+		if _, _, err := net.ParseCIDR(ipRestriction); err != nil {
+			return NewLiftError("INVALID_IP_RESTRICTION", "IP restriction must be a CIDR range", 500).WithCause(err)
+		}
+	}
+
+	issuedAt := time.Now()
+	req := &SignedURLRequest{
+		Bucket:    bucket,
+		Key:       key,
+		Expires:   issuedAt.Add(ttl),
+		IPAddress: ipRestriction,
+	}
+	if constraints.Filename != "" {
+		req.ContentDisposition = `attachment; filename="` + sanitizeFilename(constraints.Filename) + `"`
+	}
+
+	url, err := c.urlSigner.SignURL(c.Context, req)
+	if err != nil {
+		return NewLiftError("URL_SIGNING_FAILED", "Failed to create signed URL", 500).WithCause(err)
+	}
+
+	audit := &SignedURLAudit{
+		RequestID: c.RequestID,
+		UserID:    c.UserID(),
+		TenantID:  c.TenantID(),
+		ClientIP:  clientIP,
+		Bucket:    bucket,
+		Key:       key,
+		IPAddress: ipRestriction,
+		IssuedAt:  issuedAt,
+		ExpiresAt: req.Expires,
+	}
+	if c.Logger != nil {
+		c.Logger.Info("Signed URL issued", map[string]any{
+			"user_id":        audit.UserID,
+			"tenant_id":      audit.TenantID,
+			"client_ip":      audit.ClientIP,
+			"bucket":         audit.Bucket,
+			"key":            audit.Key,
+			"ip_restriction": audit.IPAddress,
+			"expires_at":     audit.ExpiresAt,
+		})
+	}
+	if c.urlAuditor != nil {
+		c.urlAuditor(c, audit)
+	}
+
+	// Signed URLs are per-caller and must never be cached by intermediaries
+	c.Response.Header("Cache-Control", "no-store")
+
+	if constraints.JSON {
+		return c.Status(200).JSON(SignedURLResponse{URL: url, ExpiresAt: req.Expires})
+	}
+
+	c.Response.Header("Location", url)
+	return c.Status(302).Text("")
+}
+
+// clientIP returns the requesting client's IP address, or "" when unknown
+func (c *Context) clientIP() string {
+	if c.Request == nil {
+		return ""
+	}
+	ip, err := security.ExtractClientIP(c.Request.Headers, c.Request.RequestContext())
+	if err != nil {
+		return ""
+	}
+	return ip
+}
+
+// sanitizeFilename strips characters that would break a quoted header value
+func sanitizeFilename(name string) string {
+	out := make([]rune, 0, len(name))
+	for _, r := range name {
+		if r == '"' || r == '\\' || r < 0x20 || r == 0x7f {
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
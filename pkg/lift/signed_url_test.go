@@ -0,0 +1,107 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSigner struct {
+	requests []*SignedURLRequest
+	err      error
+}
+
+func (s *recordingSigner) SignURL(ctx context.Context, req *SignedURLRequest) (string, error) {
+	s.requests = append(s.requests, req)
+	if s.err != nil {
+		return "", s.err
+	}
+	return "https://cdn.example.com/" + req.Key + "?Signature=abc", nil
+}
+
+func newSignedURLContext() *Context {
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{
+		Method:  "GET",
+		Path:    "/exports/1",
+		Headers: map[string]string{"X-Forwarded-For": "203.0.113.7"},
+	}))
+	ctx.RequestID = "req-1"
+	ctx.SetUserID("user-1")
+	ctx.SetTenantID("tenant-1")
+	return ctx
+}
+
+func TestSignedRedirect(t *testing.T) {
+	signer := &recordingSigner{}
+	var audits []*SignedURLAudit
+
+	ctx := newSignedURLContext()
+	ctx.SetURLSigner(signer, func(ctx *Context, audit *SignedURLAudit) {
+		audits = append(audits, audit)
+	})
+
+	err := ctx.SignedRedirect("exports", "tenant-1/report.csv", 5*time.Minute, SignedURLConstraints{
+		BindClientIP: true,
+		Filename:     `report "final".csv`,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 302, ctx.Response.StatusCode)
+	assert.Equal(t, "https://cdn.example.com/tenant-1/report.csv?Signature=abc", ctx.Response.Headers["Location"])
+	assert.Equal(t, "no-store", ctx.Response.Headers["Cache-Control"])
+
+	require.Len(t, signer.requests, 1)
+	assert.Equal(t, "203.0.113.7/32", signer.requests[0].IPAddress)
+	assert.Equal(t, `attachment; filename="report final.csv"`, signer.requests[0].ContentDisposition)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), signer.requests[0].Expires, time.Second)
+
+	require.Len(t, audits, 1)
+	assert.Equal(t, "user-1", audits[0].UserID)
+	assert.Equal(t, "tenant-1", audits[0].TenantID)
+	assert.Equal(t, "req-1", audits[0].RequestID)
+	assert.Equal(t, "tenant-1/report.csv", audits[0].Key)
+}
+
+func TestSignedRedirectJSON(t *testing.T) {
+	ctx := newSignedURLContext()
+	ctx.SetURLSigner(&recordingSigner{}, nil)
+
+	require.NoError(t, ctx.SignedRedirect("exports", "a.csv", time.Minute, SignedURLConstraints{JSON: true, IPAddress: "10.0.0.0/8"}))
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+
+	body, ok := ctx.Response.Body.(SignedURLResponse)
+	require.True(t, ok)
+	assert.Contains(t, body.URL, "a.csv")
+}
+
+func TestSignedRedirectErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		signer      URLSigner
+		ttl         time.Duration
+		constraints SignedURLConstraints
+		code        string
+	}{
+		{"no signer", nil, time.Minute, SignedURLConstraints{}, "SIGNER_NOT_CONFIGURED"},
+		{"ttl too long", &recordingSigner{}, 8 * 24 * time.Hour, SignedURLConstraints{}, "INVALID_URL_TTL"},
+		{"bad cidr", &recordingSigner{}, time.Minute, SignedURLConstraints{IPAddress: "nope"}, "INVALID_IP_RESTRICTION"},
+		{"signer failure", &recordingSigner{err: errors.New("boom")}, time.Minute, SignedURLConstraints{}, "URL_SIGNING_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newSignedURLContext()
+			ctx.SetURLSigner(tt.signer, nil)
+
+			err := ctx.SignedRedirect("exports", "a.csv", tt.ttl, tt.constraints)
+			var liftErr *LiftError
+			require.True(t, errors.As(err, &liftErr))
+			assert.Equal(t, tt.code, liftErr.Code)
+		})
+	}
+}
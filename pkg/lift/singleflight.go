@@ -0,0 +1,102 @@
+package lift
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// singleflightCall is an in-flight Singleflight call
+type singleflightCall struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+// singleflightGroup deduplicates calls across every invocation handled by
+// the container, since Lambda may run them concurrently on one instance
+var singleflightGroup = struct {
+	sync.Mutex
+	calls map[string]*singleflightCall
+}{calls: make(map[string]*singleflightCall)}
+
+// Singleflight runs fn once for concurrent callers using the same key, in
+// this invocation or any other running in the container, and gives every
+// caller its result. Use it for expensive lookups many requests make at
+// once, such as fetching configuration or resolving a tenant:
+//
+//	value, err := ctx.Singleflight("tenant:"+tenantID, func(c context.Context) (any, error) {
+//		return tenants.Get(c, tenantID)
+//	})
+//
+// Results aren't cached: a call made after fn returns runs it again.
+// fn receives a context that keeps the first caller's values but isn't
+// cancelled with it, so one caller timing out doesn't fail the others;
+// each caller stops waiting when its own context is done. Keys should
+// include anything the result depends on, such as the tenant ID.
+//
+// Calls are counted with the singleflight.executed and singleflight.shared
+// counters when ctx.Metrics is set.
+func (c *Context) Singleflight(key string, fn func(ctx context.Context) (any, error)) (any, error) {
+	parent := c.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	singleflightGroup.Lock()
+	call, shared := singleflightGroup.calls[key]
+	if !shared {
+		call = &singleflightCall{done: make(chan struct{})}
+		singleflightGroup.calls[key] = call
+	}
+	singleflightGroup.Unlock()
+
+	if shared {
+		c.countSingleflight("singleflight.shared")
+	} else {
+		c.countSingleflight("singleflight.executed")
+		go runSingleflight(context.WithoutCancel(parent), key, call, fn)
+	}
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-parent.Done():
+		return nil, parent.Err()
+	}
+}
+
+// SingleflightValue runs Singleflight and asserts the result's type
+func SingleflightValue[T any](c *Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	value, err := c.Singleflight(key, func(ctx context.Context) (any, error) {
+		return fn(ctx)
+	})
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("singleflight %s returned %T, not %T", key, value, zero)
+	}
+	return typed, nil
+}
+
+func runSingleflight(ctx context.Context, key string, call *singleflightCall, fn func(ctx context.Context) (any, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.value, call.err = nil, fmt.Errorf("singleflight %s panicked: %v", key, r)
+		}
+		singleflightGroup.Lock()
+		delete(singleflightGroup.calls, key)
+		singleflightGroup.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn(ctx)
+}
+
+func (c *Context) countSingleflight(name string) {
+	if c.Metrics != nil {
+		c.Metrics.Counter(name).Inc()
+	}
+}
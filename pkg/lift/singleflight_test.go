@@ -0,0 +1,140 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingMetrics struct {
+	NoOpMetrics
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (m *countingMetrics) Counter(name string, tags ...map[string]string) Counter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]int)
+	}
+	m.counts[name]++
+	return &NoOpCounter{}
+}
+
+func TestSingleflight(t *testing.T) {
+	metrics := &countingMetrics{}
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	fetch := func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		<-release
+		return "config-v1", nil
+	}
+
+	var wg sync.WaitGroup
+	values := make([]any, 5)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := newParallelTestContext(context.Background())
+			ctx.Metrics = metrics
+			value, err := ctx.Singleflight("config", fetch)
+			assert.NoError(t, err)
+			values[i] = value
+		}(i)
+	}
+
+	require.Eventually(t, func() bool {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		return metrics.counts["singleflight.executed"]+metrics.counts["singleflight.shared"] == 5
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, value := range values {
+		assert.Equal(t, "config-v1", value)
+	}
+	assert.Equal(t, 1, metrics.counts["singleflight.executed"])
+	assert.Equal(t, 4, metrics.counts["singleflight.shared"])
+
+	// Results aren't cached once the call completes
+	_, err := newParallelTestContext(context.Background()).Singleflight("config", func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		return "config-v2", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestSingleflightErrors(t *testing.T) {
+	t.Run("shares errors", func(t *testing.T) {
+		lookupErr := errors.New("tenant store unavailable")
+		_, err := newParallelTestContext(context.Background()).Singleflight("tenant:t1", func(ctx context.Context) (any, error) {
+			return nil, lookupErr
+		})
+		assert.ErrorIs(t, err, lookupErr)
+	})
+
+	t.Run("recovers panics", func(t *testing.T) {
+		_, err := newParallelTestContext(context.Background()).Singleflight("tenant:t2", func(ctx context.Context) (any, error) {
+			panic("nil map")
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "panicked")
+	})
+
+	t.Run("a cancelled caller doesn't fail the others", func(t *testing.T) {
+		release := make(chan struct{})
+		fetch := func(ctx context.Context) (any, error) {
+			select {
+			case <-release:
+				return "tenant", nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		parent, cancel := context.WithCancel(context.Background())
+		first := newParallelTestContext(parent)
+		firstErr := make(chan error, 1)
+		go func() {
+			_, err := first.Singleflight("tenant:t3", fetch)
+			firstErr <- err
+		}()
+		require.Eventually(t, func() bool {
+			singleflightGroup.Lock()
+			defer singleflightGroup.Unlock()
+			return singleflightGroup.calls["tenant:t3"] != nil
+		}, time.Second, time.Millisecond)
+
+		secondValue := make(chan any, 1)
+		go func() {
+			value, _ := newParallelTestContext(context.Background()).Singleflight("tenant:t3", fetch)
+			secondValue <- value
+		}()
+
+		cancel()
+		assert.ErrorIs(t, <-firstErr, context.Canceled)
+		close(release)
+		assert.Equal(t, "tenant", <-secondValue)
+	})
+}
+
+func TestSingleflightValue(t *testing.T) {
+	ctx := newParallelTestContext(context.Background())
+
+	count, err := SingleflightValue(ctx, "count", func(ctx context.Context) (int, error) { return 3, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
@@ -0,0 +1,172 @@
+package lift
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+)
+
+// snsTopic is a handler registered with App.SNS
+type snsTopic struct {
+	topic   string
+	handler Handler
+}
+
+// SNS registers a handler for messages published to an SNS topic, whether
+// the function subscribes to the topic directly or reads a queue
+// subscribed to it with raw message delivery off. topic matches the end of
+// the topic ARN, usually the topic name; "*" matches every topic.
+//
+// The handler runs once per message, behind the app's middleware, with the
+// published message as the request body and the message attributes as
+// headers, so typed handlers decode messages directly:
+//
+//	app.SNS("payment-events", lift.SimpleHandler(onPaymentEvent))
+//
+// A batch may hold messages from several topics; each goes to the first
+// handler registered for its topic. Failed messages from a queue are
+// reported in the batch response so only they are retried; a failed message
+// from a direct subscription fails the invocation. Register topics before
+// broad SQS routes, which would otherwise claim the queue's batches.
+func (a *App) SNS(topic string, handler any) error {
+	h, err := asHandler(handler)
+	if err != nil {
+		return fmt.Errorf("invalid SNS handler: %w", err)
+	}
+
+	a.mu.Lock()
+	first := len(a.snsTopics) == 0
+	a.snsTopics = append(a.snsTopics, &snsTopic{topic: topic, handler: h})
+	a.mu.Unlock()
+
+	if first {
+		for _, triggerType := range []TriggerType{TriggerSNS, TriggerSQS} {
+			a.eventRouter.addRoute(&EventRoute{
+				TriggerType: triggerType,
+				Pattern:     "sns",
+				Handler:     EventHandlerFunc(a.handleSNSMessages),
+				Match:       a.matchesSNSTopics,
+			})
+		}
+	}
+	return nil
+}
+
+// SNSMessage returns the SNS message the current request is about: the
+// first record of an SNS event (the only one inside SNS handlers), or the
+// notification in the body of the first record of an SQS event
+func (c *Context) SNSMessage() (*adapters.SNSMessage, error) {
+	if c.Request == nil || len(c.Request.Records) == 0 {
+		return nil, fmt.Errorf("not an SNS message")
+	}
+	record, ok := c.Request.Records[0].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid record format")
+	}
+
+	switch c.Request.TriggerType {
+	case TriggerSNS:
+		message := adapters.ParseSNSRecord(record)
+		return &message, nil
+	case TriggerSQS:
+		body, _ := record["body"].(string)
+		if message, ok := adapters.UnwrapSNSEnvelope(body); ok {
+			return &message, nil
+		}
+	}
+	return nil, fmt.Errorf("not an SNS message")
+}
+
+// handleSNSMessages dispatches each message of an SNS or SQS event to the
+// handler for its topic
+func (a *App) handleSNSMessages(ctx *Context) error {
+	requests := adapters.SplitSNSMessages(ctx.Request.Request)
+
+	// Queue messages that aren't SNS notifications have no topic to route
+	// by; fail them rather than deleting them unprocessed
+	if ctx.Request.TriggerType == TriggerSQS {
+		split := make(map[string]bool, len(requests))
+		for _, req := range requests {
+			split[req.EventID] = true
+		}
+		for _, raw := range ctx.Request.Records {
+			if record, ok := raw.(map[string]any); ok {
+				if id, _ := record["messageId"].(string); !split[id] {
+					ctx.FailSQSMessage(id)
+				}
+			}
+		}
+	}
+
+	var failures []error
+	for _, req := range requests {
+		topicARN, _ := req.Metadata["topicArn"].(string)
+
+		err := fmt.Errorf("no SNS handler for topic %s", topicARN)
+		if h := a.snsHandler(topicARN); h != nil {
+			err = runRecord(a.withMiddleware(h), ctx.recordContext(req))
+		}
+		if err == nil {
+			continue
+		}
+
+		if ctx.Logger != nil {
+			ctx.Logger.Warn("SNS message failed", map[string]any{
+				"topic_arn":  topicARN,
+				"message_id": req.Metadata["snsMessageId"],
+				"error":      err.Error(),
+			})
+		}
+		if req.TriggerType == TriggerSQS {
+			ctx.FailSQSMessage(req.EventID)
+			continue
+		}
+		failures = append(failures, fmt.Errorf("message %s: %w", req.EventID, err))
+	}
+	return errors.Join(failures...)
+}
+
+// matchesSNSTopics claims events whose first message was published to a
+// registered topic, leaving plain SQS messages to SQS routes
+func (a *App) matchesSNSTopics(ctx *Context) bool {
+	if len(ctx.Request.Records) == 0 {
+		return false
+	}
+	record, ok := ctx.Request.Records[0].(map[string]any)
+	if !ok {
+		return false
+	}
+
+	var message adapters.SNSMessage
+	if ctx.Request.TriggerType == TriggerSNS {
+		message = adapters.ParseSNSRecord(record)
+	} else {
+		body, _ := record["body"].(string)
+		if message, ok = adapters.UnwrapSNSEnvelope(body); !ok {
+			return false
+		}
+	}
+	return a.snsHandler(message.TopicARN) != nil
+}
+
+// snsHandler returns the first handler registered for a topic ARN
+func (a *App) snsHandler(topicARN string) Handler {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, topic := range a.snsTopics {
+		if matchSNSTopic(topicARN, topic.topic) {
+			return topic.handler
+		}
+	}
+	return nil
+}
+
+// matchSNSTopic matches a topic ARN against a topic name or ARN suffix
+func matchSNSTopic(topicARN, pattern string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	return topicARN == pattern || strings.HasSuffix(topicARN, ":"+pattern)
+}
@@ -0,0 +1,145 @@
+package lift
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snsNotification(messageID, topic, message string) map[string]any {
+	return map[string]any{
+		"Type":      "Notification",
+		"MessageId": messageID,
+		"TopicArn":  "arn:aws:sns:us-east-1:123456789012:" + topic,
+		"Message":   message,
+		"Timestamp": "2024-06-01T12:00:00.000Z",
+		"MessageAttributes": map[string]any{
+			"tenant_id": map[string]any{"Type": "String", "Value": "tenant-1"},
+		},
+	}
+}
+
+func snsEvent(notifications ...map[string]any) map[string]any {
+	records := make([]any, len(notifications))
+	for i, notification := range notifications {
+		records[i] = map[string]any{"EventSource": "aws:sns", "Sns": notification}
+	}
+	return map[string]any{"Records": records}
+}
+
+func sqsOfSNS(t *testing.T, notifications ...map[string]any) map[string]any {
+	records := make([]any, len(notifications))
+	for i, notification := range notifications {
+		body, err := json.Marshal(notification)
+		require.NoError(t, err)
+		records[i] = map[string]any{
+			"messageId":      notification["MessageId"].(string) + "-sqs",
+			"receiptHandle":  "handle",
+			"eventSource":    "aws:sqs",
+			"eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:payments-queue",
+			"body":           string(body),
+		}
+	}
+	return map[string]any{"Records": records}
+}
+
+type paymentEvent struct {
+	ID string `json:"id"`
+}
+
+func TestSNSDirectSubscription(t *testing.T) {
+	var seen []string
+	var middlewareRuns int
+
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			middlewareRuns++
+			return next.Handle(ctx)
+		})
+	})
+	require.NoError(t, app.SNS("payment-events", SimpleHandler(func(ctx *Context, event paymentEvent) (struct{}, error) {
+		assert.Equal(t, "tenant-1", ctx.Header("tenant_id"))
+		message, err := ctx.SNSMessage()
+		require.NoError(t, err)
+		assert.Equal(t, "payment-events", message.TopicName())
+
+		seen = append(seen, event.ID)
+		return struct{}{}, nil
+	})))
+
+	_, err := app.HandleRequest(context.Background(), snsEvent(
+		snsNotification("m1", "payment-events", `{"id":"pay_1"}`),
+		snsNotification("m2", "payment-events", `{"id":"pay_2"}`),
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pay_1", "pay_2"}, seen)
+	assert.Equal(t, 2, middlewareRuns)
+}
+
+func TestSNSThroughSQSReportsFailedMessages(t *testing.T) {
+	var payments, refunds []string
+
+	app := New()
+	require.NoError(t, app.SNS("payment-events", SimpleHandler(func(ctx *Context, event paymentEvent) (struct{}, error) {
+		payments = append(payments, event.ID)
+		if event.ID == "pay_bad" {
+			return struct{}{}, errors.New("ledger unavailable")
+		}
+		return struct{}{}, nil
+	})))
+	require.NoError(t, app.SNS("refund-events", func(ctx *Context) error {
+		refunds = append(refunds, string(ctx.Request.Body))
+		return nil
+	}))
+	require.NoError(t, app.SQS("*", func(ctx *Context) error {
+		t.Fatal("SNS notifications should not reach the SQS route")
+		return nil
+	}))
+
+	result, err := app.HandleRequest(context.Background(), sqsOfSNS(t,
+		snsNotification("m1", "payment-events", `{"id":"pay_1"}`),
+		snsNotification("m2", "refund-events", "re_1"),
+		snsNotification("m3", "payment-events", `{"id":"pay_bad"}`),
+		snsNotification("m4", "unknown-events", "ignored"),
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"pay_1", "pay_bad"}, payments)
+	assert.Equal(t, []string{"re_1"}, refunds)
+	assert.Equal(t, &adapters.SQSBatchResponse{BatchItemFailures: []adapters.SQSBatchItemFailure{
+		{ItemIdentifier: "m3-sqs"},
+		{ItemIdentifier: "m4-sqs"},
+	}}, result)
+}
+
+func TestSNSLeavesPlainSQSMessagesToSQSRoutes(t *testing.T) {
+	var bodies []string
+
+	app := New()
+	require.NoError(t, app.SNS("payment-events", func(ctx *Context) error {
+		t.Fatal("plain SQS messages should not reach the SNS handler")
+		return nil
+	}))
+	require.NoError(t, app.SQS("payments-queue", SQSRecords(func(ctx *Context) error {
+		bodies = append(bodies, string(ctx.Request.Body))
+		return nil
+	})))
+
+	_, err := app.HandleRequest(context.Background(), map[string]any{
+		"Records": []any{map[string]any{
+			"messageId":      "plain-1",
+			"receiptHandle":  "handle",
+			"eventSource":    "aws:sqs",
+			"eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:payments-queue",
+			"body":           "raw message",
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"raw message"}, bodies)
+}
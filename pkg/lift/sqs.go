@@ -0,0 +1,220 @@
+package lift
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+)
+
+// sqsFailuresKey holds the message IDs reported as failed for an SQS batch
+const sqsFailuresKey = "sqs_batch_failures"
+
+// SQSOption configures SQSRecords
+type SQSOption func(*sqsOptions)
+
+type sqsOptions struct {
+	concurrency int
+}
+
+// SQSConcurrency processes up to n records of a standard queue batch at once
+// (default: 1). FIFO batches are always processed in order.
+func SQSConcurrency(n int) SQSOption {
+	return func(o *sqsOptions) { o.concurrency = n }
+}
+
+// SQSRecords runs handler once per record of an SQS batch, each in its own
+// Context whose request body is the message body and whose headers are the
+// message's string attributes, so typed handlers decode messages directly:
+//
+//	app.SQS("settlements", lift.SQSRecords(lift.SimpleHandler(settle)))
+//
+// A record with an X-Request-ID or X-Correlation-ID attribute is handled
+// with that request ID; other records share the batch's.
+//
+// Records whose handler returns an error or panics are passed to the app's
+// error observers and reported in the batch item failures returned to
+// Lambda, so only they are retried; enable
+// ReportBatchItemFailures on the event source mapping. In a FIFO batch the
+// first failure also fails every later record, preserving order within
+// message groups.
+func SQSRecords(handler any, opts ...SQSOption) Handler {
+	h, err := asHandler(handler)
+	if err != nil {
+		panic(fmt.Sprintf("invalid SQS record handler: %v", err))
+	}
+	options := sqsOptions{concurrency: 1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return HandlerFunc(func(ctx *Context) error {
+		requests := adapters.SplitSQSRecords(ctx.Request.Request)
+
+		fifo := false
+		for _, req := range requests {
+			if _, ok := req.Metadata["messageGroupId"]; ok {
+				fifo = true
+				break
+			}
+		}
+
+		// Workers read a snapshot of the batch's values and collect
+		// failures locally; the batch context is only written once they
+		// are done
+		values := ctx.recordValues()
+		var mu sync.Mutex
+		var failed []string
+
+		run := func(req *adapters.Request) (err error) {
+			recordCtx := ctx.recordContextWith(req, values)
+			defer func() {
+				if r := recover(); r != nil {
+					err = NewPanicError(r, debug.Stack())
+				}
+				if err != nil {
+					recordCtx.observeError(err)
+				}
+			}()
+			return h.Handle(recordCtx)
+		}
+		fail := func(req *adapters.Request, err error) {
+			if ctx.Logger != nil {
+				ctx.Logger.Warn("SQS record failed", map[string]any{
+					"message_id": req.EventID,
+					"error":      err.Error(),
+				})
+			}
+			mu.Lock()
+			failed = append(failed, req.EventID)
+			mu.Unlock()
+		}
+
+		if fifo || options.concurrency <= 1 {
+			for i, req := range requests {
+				if err := run(req); err != nil {
+					fail(req, err)
+					if fifo {
+						for _, rest := range requests[i+1:] {
+							failed = append(failed, rest.EventID)
+						}
+						break
+					}
+				}
+			}
+		} else {
+			var wg sync.WaitGroup
+			slots := make(chan struct{}, options.concurrency)
+			for _, req := range requests {
+				wg.Add(1)
+				slots <- struct{}{}
+				go func(req *adapters.Request) {
+					defer func() { <-slots; wg.Done() }()
+					if err := run(req); err != nil {
+						fail(req, err)
+					}
+				}(req)
+			}
+			wg.Wait()
+		}
+
+		for _, id := range failed {
+			ctx.FailSQSMessage(id)
+		}
+		return nil
+	})
+}
+
+// SetErrorObserver sets the observer told about failures handled outside
+// the app's error pipeline, such as failed batch records, for this request
+func (c *Context) SetErrorObserver(observer ErrorObserver) {
+	c.errorObserver = observer
+}
+
+// observeError reports a failure to the error observer, if any
+func (c *Context) observeError(err error) {
+	if c.errorObserver != nil {
+		c.errorObserver(c, err)
+	}
+}
+
+// FailSQSMessage reports a message of the current SQS batch as failed so
+// only it is retried. Batch-mode handlers registered with App.SQS use it to
+// report partial failures instead of failing the whole batch.
+func (c *Context) FailSQSMessage(messageID string) {
+	failed, _ := c.Get(sqsFailuresKey).([]string)
+	c.Set(sqsFailuresKey, append(failed, messageID))
+}
+
+// recordContext creates the Context for one record of an event batch. It
+// shares the batch's dependencies and a copy of its values.
+func (c *Context) recordContext(req *adapters.Request) *Context {
+	return c.recordContextWith(req, c.values)
+}
+
+// recordValues copies the values records inherit, for handing to
+// recordContextWith from concurrent workers
+func (c *Context) recordValues() map[string]any {
+	values := make(map[string]any, len(c.values))
+	for key, value := range c.values {
+		if key != sqsFailuresKey {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// recordContextWith creates a record Context inheriting values instead of
+// the batch's current values
+func (c *Context) recordContextWith(req *adapters.Request, values map[string]any) *Context {
+	child := NewContext(c.Context, NewRequest(req))
+	child.Logger = c.Logger
+	child.Metrics = c.Metrics
+	child.DB = c.DB
+	child.RequestID = c.RequestID
+	child.validator = c.validator
+	child.errorObserver = c.errorObserver
+	for key, value := range values {
+		if key != sqsFailuresKey {
+			child.values[key] = value
+		}
+	}
+	// A producer's request ID, sent as a message attribute, follows the
+	// message so its processing can be traced back to the request
+	if requestID := IncomingRequestID(req.Headers); requestID != "" {
+		child.SetRequestID(requestID)
+		if child.Logger != nil {
+			child.Logger = child.Logger.WithField("request_id", requestID)
+		}
+	}
+	return child
+}
+
+// sqsBatchResponse builds the partial batch response for an SQS event. If
+// the handler failed without reporting individual messages, every message
+// is reported so the whole batch is retried.
+func sqsBatchResponse(ctx *Context, handlerErr error) *adapters.SQSBatchResponse {
+	response := &adapters.SQSBatchResponse{BatchItemFailures: []adapters.SQSBatchItemFailure{}}
+
+	failed, _ := ctx.Get(sqsFailuresKey).([]string)
+	if handlerErr != nil && len(failed) == 0 {
+		for _, raw := range ctx.Request.Records {
+			if record, ok := raw.(map[string]any); ok {
+				if id, _ := record["messageId"].(string); id != "" {
+					failed = append(failed, id)
+				}
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(failed))
+	for _, id := range failed {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		response.BatchItemFailures = append(response.BatchItemFailures, adapters.SQSBatchItemFailure{ItemIdentifier: id})
+	}
+	return response
+}
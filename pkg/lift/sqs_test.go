@@ -0,0 +1,134 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sqsEvent(queue string, groupID string, bodies ...string) map[string]any {
+	records := make([]any, 0, len(bodies))
+	for i, body := range bodies {
+		attributes := map[string]any{"SentTimestamp": "1717408800000"}
+		if groupID != "" {
+			attributes["MessageGroupId"] = groupID
+		}
+		records = append(records, map[string]any{
+			"messageId":      fmt.Sprintf("m%d", i+1),
+			"receiptHandle":  fmt.Sprintf("rh%d", i+1),
+			"body":           body,
+			"eventSource":    "aws:sqs",
+			"eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:" + queue,
+			"attributes":     attributes,
+		})
+	}
+	return map[string]any{"Records": records}
+}
+
+type settlement struct {
+	Amount int `json:"amount" validate:"min=1"`
+}
+
+func failedIDs(t *testing.T, resp any) []string {
+	batch, ok := resp.(*adapters.SQSBatchResponse)
+	require.True(t, ok, "SQS events return a batch response, got %T", resp)
+	ids := []string{}
+	for _, failure := range batch.BatchItemFailures {
+		ids = append(ids, failure.ItemIdentifier)
+	}
+	return ids
+}
+
+func TestSQSRecordsReportsFailedRecords(t *testing.T) {
+	var mu sync.Mutex
+	var settled []int
+	var observed []error
+
+	app := New(WithErrorObserver(func(ctx *Context, err error) {
+		mu.Lock()
+		observed = append(observed, err)
+		mu.Unlock()
+	}))
+	require.NoError(t, app.SQS("settlements", SQSRecords(SimpleHandler(func(ctx *Context, req settlement) (struct{}, error) {
+		if req.Amount == 13 {
+			panic("unlucky")
+		}
+		if req.Amount == 7 {
+			return struct{}{}, errors.New("ledger unavailable")
+		}
+		mu.Lock()
+		settled = append(settled, req.Amount)
+		mu.Unlock()
+		return struct{}{}, nil
+	}), SQSConcurrency(4))))
+
+	resp, err := app.HandleRequest(context.Background(), sqsEvent("settlements", "",
+		`{"amount":5}`, `{"amount":7}`, `not json`, `{"amount":13}`, `{"amount":9}`, `{"amount":0}`))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"m2", "m3", "m4", "m6"}, failedIDs(t, resp))
+	assert.ElementsMatch(t, []int{5, 9}, settled)
+
+	// Every failed record reaches the observers, panics with their stack
+	require.Len(t, observed, 4)
+	var panicErr *PanicError
+	for _, err := range observed {
+		if errors.As(err, &panicErr) {
+			break
+		}
+	}
+	require.NotNil(t, panicErr)
+	assert.Equal(t, "unlucky", panicErr.Value)
+	assert.Contains(t, string(panicErr.Stack), "sqs_test.go")
+}
+
+func TestSQSRecordsFIFOFailsRemainingRecords(t *testing.T) {
+	var processed []string
+	app := New()
+	require.NoError(t, app.SQS("payments.fifo", SQSRecords(func(ctx *Context) error {
+		processed = append(processed, string(ctx.Request.Body))
+		if string(ctx.Request.Body) == "b" {
+			return errors.New("declined")
+		}
+		return nil
+	}, SQSConcurrency(8))))
+
+	resp, err := app.HandleRequest(context.Background(), sqsEvent("payments.fifo", "merchant-1", "a", "b", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m2", "m3"}, failedIDs(t, resp))
+	assert.Equal(t, []string{"a", "b"}, processed, "records after a FIFO failure aren't processed")
+}
+
+func TestSQSBatchModeFailures(t *testing.T) {
+	app := New()
+	require.NoError(t, app.SQS("partial", func(ctx *Context) error {
+		messages, err := ctx.ParseSQSMessages()
+		require.NoError(t, err)
+		for _, msg := range messages {
+			if msg.Body == "bad" {
+				ctx.FailSQSMessage(msg.MessageID)
+			}
+		}
+		return nil
+	}))
+	require.NoError(t, app.SQS("broken", func(ctx *Context) error {
+		return errors.New("database down")
+	}))
+
+	resp, err := app.HandleRequest(context.Background(), sqsEvent("partial", "", "ok", "bad", "ok"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m2"}, failedIDs(t, resp))
+
+	resp, err = app.HandleRequest(context.Background(), sqsEvent("broken", "", "a", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m1", "m2"}, failedIDs(t, resp), "a failed batch is retried in full")
+
+	resp, err = app.HandleRequest(context.Background(), sqsEvent("partial", "", "ok"))
+	require.NoError(t, err)
+	assert.Empty(t, failedIDs(t, resp))
+}
@@ -0,0 +1,222 @@
+package lift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// StreamIterator produces items for a streamed response. Next returns io.EOF
+// once there are no more items.
+type StreamIterator interface {
+	Next(ctx context.Context) (any, error)
+}
+
+// StreamIteratorFunc adapts a function to the StreamIterator interface
+type StreamIteratorFunc func(ctx context.Context) (any, error)
+
+// Next calls f(ctx)
+func (f StreamIteratorFunc) Next(ctx context.Context) (any, error) {
+	return f(ctx)
+}
+
+// SliceIterator iterates over an in-memory slice
+func SliceIterator[T any](items []T) StreamIterator {
+	i := 0
+	return StreamIteratorFunc(func(ctx context.Context) (any, error) {
+		if i >= len(items) {
+			return nil, io.EOF
+		}
+		item := items[i]
+		i++
+		return item, nil
+	})
+}
+
+// PageFetcher loads one page of items starting at cursor. It returns the
+// cursor for the following page, or "" when there are no more pages.
+type PageFetcher[T any] func(ctx context.Context, cursor string) (items []T, next string, err error)
+
+// PagedIterator iterates over paginated results (such as DynamoDB queries or
+// scans using LastEvaluatedKey) one page at a time, so only a single page is
+// held in memory
+func PagedIterator[T any](fetch PageFetcher[T]) StreamIterator {
+	var (
+		page    []T
+		pos     int
+		cursor  string
+		started bool
+	)
+
+	return StreamIteratorFunc(func(ctx context.Context) (any, error) {
+		for pos >= len(page) {
+			if started && cursor == "" {
+				return nil, io.EOF
+			}
+			items, next, err := fetch(ctx, cursor)
+			if err != nil {
+				return nil, err
+			}
+			started = true
+			page, pos, cursor = items, 0, next
+		}
+		item := page[pos]
+		pos++
+		return item, nil
+	})
+}
+
+// JSONStream writes the items produced by iter as a JSON array. When response
+// streaming is enabled for the app (WithResponseStreaming) the array is written
+// to the client incrementally; otherwise items are encoded one at a time into
+// the response body so the decoded result set is never held in memory.
+func (c *Context) JSONStream(iter StreamIterator) error {
+	if c.Response.IsWritten() {
+		return NewLiftError("RESPONSE_WRITTEN", "Response has already been written", 500)
+	}
+
+	if c.streamingEnabled {
+		return c.streamFrom("application/json", func(w io.Writer) error {
+			return writeJSONArray(c.Context, w, iter)
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONArray(c.Context, &buf, iter); err != nil {
+		return err
+	}
+
+	// json.RawMessage keeps the pre-encoded array intact through response marshaling
+	err := c.Response.JSON(json.RawMessage(buf.Bytes()))
+	if err == nil {
+		c.captureResponseData()
+	}
+	return err
+}
+
+// streamFrom sets a response body that write fills from its own goroutine.
+// An error from write ends the stream with that error, so the consumer sees
+// a failed read rather than a truncated body, and is passed to the error
+// observers. Closing the body, as the runtime does once it is done reading,
+// makes write's next write fail so the goroutine exits.
+func (c *Context) streamFrom(contentType string, write func(w io.Writer) error) error {
+	reader, writer := io.Pipe()
+	if err := c.Response.Stream(reader, contentType); err != nil {
+		reader.Close()
+		return err
+	}
+
+	go func() {
+		err := write(writer)
+		if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			c.observeError(err)
+		}
+		writer.CloseWithError(err)
+	}()
+	return nil
+}
+
+// writeJSONArray encodes every item from iter to w as a JSON array
+func writeJSONArray(ctx context.Context, w io.Writer, iter StreamIterator) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	for first := true; ; first = false {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		item, err := iter.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return NewLiftError("MARSHAL_ERROR", "Failed to marshal stream item", 500).WithCause(err)
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// streamBody marks a body set with Response.Stream, so a reader assigned to
+// Body directly isn't mistaken for a stream
+type streamBody struct {
+	io.Reader
+}
+
+// Close closes the underlying reader if it is an io.Closer
+func (b *streamBody) Close() error {
+	if closer, ok := b.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Stream sets a reader as the response body. Streamed bodies are only
+// delivered incrementally by Lambda function URLs using RESPONSE_STREAM
+// invoke mode; see App.HandleRequest. The runtime closes the body, if it is
+// an io.Closer, once it stops reading.
+func (r *Response) Stream(body io.Reader, contentType string) error {
+	if r.written {
+		return NewLiftError("RESPONSE_WRITTEN", "Response has already been written", 500)
+	}
+
+	r.Body = &streamBody{Reader: body}
+	r.Header("Content-Type", contentType)
+	r.written = true
+	return nil
+}
+
+// IsStreaming reports whether the response body was set with Stream
+func (r *Response) IsStreaming() bool {
+	_, ok := r.Body.(*streamBody)
+	return ok
+}
+
+// StreamingResponse converts a streamed response into the Lambda streaming response type
+func (r *Response) StreamingResponse() *events.LambdaFunctionURLStreamingResponse {
+	resp := &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: r.StatusCode,
+		Headers:    r.Headers,
+	}
+	if body, ok := r.Body.(*streamBody); ok {
+		resp.Body = body
+	}
+	return resp
+}
+
+// closeStream closes a streamed body that won't be delivered, stopping the
+// goroutine writing it
+func (r *Response) closeStream() {
+	if body, ok := r.Body.(*streamBody); ok {
+		body.Close()
+	}
+}
+
+// WithResponseStreaming enables incremental responses from ctx.JSONStream.
+// The function must be invoked through a function URL with RESPONSE_STREAM
+// invoke mode and built with the lambda.norpc tag.
+func WithResponseStreaming() AppOption {
+	return func(app *App) {
+		app.responseStreaming = true
+	}
+}
@@ -0,0 +1,135 @@
+package lift
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamItem struct {
+	ID string `json:"id"`
+}
+
+func TestJSONStreamBuffered(t *testing.T) {
+	pages := map[string][]streamItem{
+		"":   {{ID: "a"}, {ID: "b"}},
+		"p2": {{ID: "c"}},
+	}
+	next := map[string]string{"": "p2", "p2": ""}
+	calls := 0
+
+	iter := PagedIterator(func(ctx context.Context, cursor string) ([]streamItem, string, error) {
+		calls++
+		return pages[cursor], next[cursor], nil
+	})
+
+	ctx := NewContext(context.Background(), NewRequest(nil))
+	require.NoError(t, ctx.JSONStream(iter))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "application/json", ctx.Response.Headers["Content-Type"])
+
+	data, err := json.Marshal(ctx.Response)
+	require.NoError(t, err)
+	var lambdaResp struct {
+		Body string `json:"body"`
+	}
+	require.NoError(t, json.Unmarshal(data, &lambdaResp))
+	assert.Equal(t, `[{"id":"a"},{"id":"b"},{"id":"c"}]`, lambdaResp.Body)
+}
+
+func TestJSONStreamEmptyAndErrors(t *testing.T) {
+	ctx := NewContext(context.Background(), NewRequest(nil))
+	require.NoError(t, ctx.JSONStream(SliceIterator([]streamItem{})))
+	assert.Equal(t, json.RawMessage("[]"), ctx.Response.Body)
+
+	failing := StreamIteratorFunc(func(ctx context.Context) (any, error) {
+		return nil, errors.New("scan failed")
+	})
+	ctx = NewContext(context.Background(), NewRequest(nil))
+	assert.Error(t, ctx.JSONStream(failing))
+	assert.False(t, ctx.Response.IsWritten())
+}
+
+func TestJSONStreamWithResponseStreaming(t *testing.T) {
+	app := New(WithResponseStreaming())
+	app.GET("/items", func(ctx *Context) error {
+		return ctx.JSONStream(SliceIterator([]streamItem{{ID: "a"}, {ID: "b"}}))
+	})
+
+	ctx := NewContext(context.Background(), NewRequest(&adapters.Request{Method: "GET", Path: "/items"}))
+	ctx.streamingEnabled = true
+	require.NoError(t, app.HandleTestRequest(ctx))
+	require.True(t, ctx.Response.IsStreaming())
+
+	streaming := ctx.Response.StreamingResponse()
+	assert.Equal(t, 200, streaming.StatusCode)
+	body, err := io.ReadAll(streaming.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"id":"a"},{"id":"b"}]`, string(body))
+}
+
+func TestJSONStreamPropagatesMidStreamErrors(t *testing.T) {
+	var observed error
+	ctx := NewContext(context.Background(), NewRequest(nil))
+	ctx.streamingEnabled = true
+	ctx.SetErrorObserver(func(ctx *Context, err error) {
+		observed = err
+	})
+
+	sent := false
+	iter := StreamIteratorFunc(func(ctx context.Context) (any, error) {
+		if sent {
+			return nil, errors.New("scan failed")
+		}
+		sent = true
+		return streamItem{ID: "a"}, nil
+	})
+	require.NoError(t, ctx.JSONStream(iter))
+
+	body, err := io.ReadAll(ctx.Response.StreamingResponse().Body)
+	assert.EqualError(t, err, "scan failed", "the reader fails instead of ending early")
+	assert.Equal(t, `[{"id":"a"}`, string(body))
+	assert.EqualError(t, observed, "scan failed")
+}
+
+func TestClosingStreamStopsWriter(t *testing.T) {
+	ctx := NewContext(context.Background(), NewRequest(nil))
+	ctx.streamingEnabled = true
+
+	stopped := make(chan struct{})
+	ctx.SetErrorObserver(func(ctx *Context, err error) {
+		t.Errorf("closing the stream isn't an error: %v", err)
+	})
+	require.NoError(t, ctx.streamFrom("application/json", func(w io.Writer) error {
+		defer close(stopped)
+		for {
+			if _, err := io.WriteString(w, "{}"); err != nil {
+				return err
+			}
+		}
+	}))
+
+	body := ctx.Response.StreamingResponse()
+	buf := make([]byte, 16)
+	_, err := body.Read(buf)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	<-stopped
+}
+
+func TestIsStreamingRequiresStream(t *testing.T) {
+	resp := NewResponse()
+	resp.Body = strings.NewReader("plain")
+	assert.False(t, resp.IsStreaming(), "a reader set as the body isn't a stream")
+
+	resp = NewResponse()
+	require.NoError(t, resp.Stream(strings.NewReader("streamed"), "text/plain"))
+	assert.True(t, resp.IsStreaming())
+}
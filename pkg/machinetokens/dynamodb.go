@@ -0,0 +1,171 @@
+package machinetokens
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBStore implements Store using a DynamoDB table with a string
+// partition key "pk" and sort key "sk". Each tenant is one partition,
+// "tenant#<id>", holding its tokens as "token#<id>", so listing a tenant's
+// tokens is one query. A second item, "hash#<hash>", points from each
+// secret's hash to its token for Lookup. Both are removed by the table's
+// TTL a day after the token expires or is revoked.
+type DynamoDBStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBStore creates a DynamoDB-backed store
+func NewDynamoDBStore(client *dynamodb.Client, tableName string) *DynamoDBStore {
+	return &DynamoDBStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// hashPointer is the item that finds a token by its secret's hash
+type hashPointer struct {
+	TenantID string `dynamodbav:"tenant_id"`
+	TokenID  string `dynamodbav:"token_id"`
+}
+
+// Save implements Store
+func (d *DynamoDBStore) Save(ctx context.Context, token *Token) error {
+	ttl := expiry(token)
+	if err := d.put(ctx, tokenKey(token.TenantID, token.ID), token, ttl); err != nil {
+		return err
+	}
+	return d.put(ctx, hashKey(token.Hash), hashPointer{TenantID: token.TenantID, TokenID: token.ID}, ttl)
+}
+
+// Get implements Store
+func (d *DynamoDBStore) Get(ctx context.Context, tenantID, id string) (*Token, error) {
+	var token Token
+	if err := d.get(ctx, tokenKey(tenantID, id), &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Lookup implements Store
+func (d *DynamoDBStore) Lookup(ctx context.Context, hash string) (*Token, error) {
+	var pointer hashPointer
+	if err := d.get(ctx, hashKey(hash), &pointer); err != nil {
+		return nil, err
+	}
+	return d.Get(ctx, pointer.TenantID, pointer.TokenID)
+}
+
+// List implements Store
+func (d *DynamoDBStore) List(ctx context.Context, tenantID string) ([]*Token, error) {
+	tokens := []*Token{}
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":     &types.AttributeValueMemberS{Value: "tenant#" + tenantID},
+			":prefix": &types.AttributeValueMemberS{Value: "token#"},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			var token Token
+			if err := attributevalue.UnmarshalMap(item, &token); err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, &token)
+		}
+	}
+	return tokens, nil
+}
+
+// Touch implements Store
+func (d *DynamoDBStore) Touch(ctx context.Context, tenantID, id string, at time.Time) error {
+	value, err := attributevalue.Marshal(at)
+	if err != nil {
+		return err
+	}
+	_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(d.tableName),
+		Key:                       tokenKey(tenantID, id),
+		UpdateExpression:          aws.String("SET last_used_at = :at"),
+		ConditionExpression:       aws.String("attribute_exists(pk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":at": value},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrTokenNotFound
+	}
+	return err
+}
+
+// put stores an item under the key
+func (d *DynamoDBStore) put(ctx context.Context, key map[string]types.AttributeValue, value any, ttl int64) error {
+	item, err := attributevalue.MarshalMap(value)
+	if err != nil {
+		return err
+	}
+	for name, v := range key {
+		item[name] = v
+	}
+	item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(ttl, 10)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	return err
+}
+
+// get reads the item under the key into value, or returns ErrTokenNotFound
+func (d *DynamoDBStore) get(ctx context.Context, key map[string]types.AttributeValue, value any) error {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       key,
+		// Revocations must be seen as soon as the cache expires
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+	if result.Item == nil {
+		return ErrTokenNotFound
+	}
+	return attributevalue.UnmarshalMap(result.Item, value)
+}
+
+// expiry is when a token's items can be removed: a day after it stops
+// working, so "invalid token" investigations can still find it
+func expiry(token *Token) int64 {
+	end := token.ExpiresAt
+	if !token.RevokedAt.IsZero() && token.RevokedAt.Before(end) {
+		end = token.RevokedAt
+	}
+	return end.Add(24 * time.Hour).Unix()
+}
+
+func tokenKey(tenantID, id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "tenant#" + tenantID},
+		"sk": &types.AttributeValueMemberS{Value: "token#" + id},
+	}
+}
+
+func hashKey(hash string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "hash#" + hash},
+		"sk": &types.AttributeValueMemberS{Value: "token"},
+	}
+}
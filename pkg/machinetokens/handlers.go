@@ -0,0 +1,138 @@
+package machinetokens
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// issueBody is the body accepted by POST /tokens
+type issueBody struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required"`
+	// ExpiresIn is the token's lifetime, e.g. "720h"
+	ExpiresIn string `json:"expires_in"`
+}
+
+// App returns the machine token API, to be mounted into an application
+// that authenticates requests with middleware.RouteAuth:
+//
+//	GET    /tokens              list the tenant's tokens (machine_tokens:manage)
+//	POST   /tokens              issue a token (machine_tokens:manage)
+//	GET    /tokens/:id          get a token (machine_tokens:manage)
+//	POST   /tokens/:id/rotate   rotate a token (machine_tokens:manage)
+//	DELETE /tokens/:id          revoke a token (machine_tokens:manage)
+//	GET    /self                the calling token (machine token)
+//	POST   /self/rotate         rotate the calling token (machine token)
+//
+// Management routes use the app's default auth scheme and act on the
+// caller's own tenant. The /self routes are called with the machine token
+// itself, so integrations can rotate their credentials unattended; the
+// response carries the new secret, and the old one keeps working for
+// Config.RotationGrace.
+func (s *Service) App() *lift.App {
+	app := lift.New()
+	app.GET("/tokens", s.listTokens).Meta(lift.MetaScopes, ScopeManage)
+	app.POST("/tokens", s.issueToken).Meta(lift.MetaScopes, ScopeManage)
+	app.GET("/tokens/:id", s.getToken).Meta(lift.MetaScopes, ScopeManage)
+	app.POST("/tokens/:id/rotate", s.rotateToken).Meta(lift.MetaScopes, ScopeManage)
+	app.DELETE("/tokens/:id", s.revokeToken).Meta(lift.MetaScopes, ScopeManage)
+
+	app.GET("/self", s.self).Meta(lift.MetaAuth, AuthScheme)
+	app.POST("/self/rotate", s.rotateSelf).Meta(lift.MetaAuth, AuthScheme)
+	return app
+}
+
+func (s *Service) listTokens(ctx *lift.Context) error {
+	tokens, err := s.List(ctx.Context, ctx.TenantID())
+	if err != nil {
+		return tokenError(err)
+	}
+	return ctx.OK(map[string]any{"tokens": tokens})
+}
+
+func (s *Service) issueToken(ctx *lift.Context) error {
+	var body issueBody
+	if err := ctx.ParseRequest(&body); err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if body.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(body.ExpiresIn)
+		if err != nil || parsed <= 0 {
+			return lift.NewLiftError("INVALID_EXPIRY", "expires_in must be a positive duration, e.g. \"720h\"", 400)
+		}
+		ttl = parsed
+	}
+
+	issued, err := s.Issue(ctx.Context, IssueRequest{
+		TenantID:  ctx.TenantID(),
+		Name:      body.Name,
+		Scopes:    body.Scopes,
+		TTL:       ttl,
+		CreatedBy: ctx.UserID(),
+	})
+	if err != nil {
+		return tokenError(err)
+	}
+	return ctx.Created(issued)
+}
+
+func (s *Service) getToken(ctx *lift.Context) error {
+	token, err := s.Get(ctx.Context, ctx.TenantID(), ctx.Param("id"))
+	if err != nil {
+		return tokenError(err)
+	}
+	return ctx.OK(token)
+}
+
+func (s *Service) rotateToken(ctx *lift.Context) error {
+	issued, err := s.Rotate(ctx.Context, ctx.TenantID(), ctx.Param("id"), ctx.UserID())
+	if err != nil {
+		return tokenError(err)
+	}
+	return ctx.Created(issued)
+}
+
+func (s *Service) revokeToken(ctx *lift.Context) error {
+	if err := s.Revoke(ctx.Context, ctx.TenantID(), ctx.Param("id")); err != nil {
+		return tokenError(err)
+	}
+	ctx.Response.Status(204)
+	return nil
+}
+
+func (s *Service) self(ctx *lift.Context) error {
+	token := FromContext(ctx)
+	if token == nil {
+		return lift.Unauthorized("Machine token required")
+	}
+	return ctx.OK(token)
+}
+
+func (s *Service) rotateSelf(ctx *lift.Context) error {
+	token := FromContext(ctx)
+	if token == nil {
+		return lift.Unauthorized("Machine token required")
+	}
+	issued, err := s.Rotate(ctx.Context, token.TenantID, token.ID, token.ID)
+	if err != nil {
+		return tokenError(err)
+	}
+	return ctx.Created(issued)
+}
+
+// tokenError maps service errors to HTTP errors
+func tokenError(err error) error {
+	switch {
+	case errors.Is(err, ErrTokenNotFound):
+		return lift.NotFound("Machine token not found")
+	case errors.Is(err, ErrInvalidScopes):
+		return lift.NewLiftError("INVALID_SCOPES", err.Error(), 400)
+	case errors.Is(err, ErrTokenInactive):
+		return lift.NewLiftError("TOKEN_INACTIVE", "Machine token has expired or been revoked", 409)
+	default:
+		return lift.NewLiftError("MACHINE_TOKENS_FAILED", "Failed to manage machine tokens", 500).WithCause(err)
+	}
+}
@@ -0,0 +1,402 @@
+// Package machinetokens issues long-lived credentials for machines: CI
+// jobs, integrations and other services calling the API on a tenant's
+// behalf. Each token is scoped to one tenant, carries an explicit set of
+// scopes and always expires. Only the SHA-256 hash of its secret is
+// stored, and its last use is tracked so unused tokens can be found and
+// revoked.
+//
+// Service.Middleware authenticates requests bearing a token, caching
+// lookups, and is meant to be a middleware.RouteAuth scheme. The mounted
+// API lets tenant administrators issue, list, rotate and revoke tokens,
+// and lets a token rotate itself before it expires:
+//
+//	tokens, _ := machinetokens.NewService(machinetokens.Config{
+//		Store:  machinetokens.NewDynamoDBStore(client, "machine-tokens"),
+//		Scopes: []string{"payments:read", "payments:write"},
+//	})
+//	app.Use(middleware.RouteAuth(middleware.RouteAuthOptions{
+//		Schemes: map[string]lift.Middleware{
+//			"jwt":                    middleware.JWT(jwtConfig),
+//			machinetokens.AuthScheme: tokens.Middleware(),
+//		},
+//		Default: "jwt",
+//	}))
+//	app.Mount("/machine-tokens", tokens.App())
+//	app.GET("/payments", listPayments).
+//		Meta(lift.MetaAuth, machinetokens.AuthScheme).
+//		Meta(lift.MetaScopes, "payments:read")
+package machinetokens
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/id"
+	"github.com/pay-theory/lift/pkg/middleware"
+)
+
+const (
+	// AuthScheme is the lift.MetaAuth value of routes called with machine
+	// tokens, and the name to register Service.Middleware under in
+	// middleware.RouteAuth
+	AuthScheme = "machine_token"
+	// ScopeManage is required to issue, list, rotate and revoke a tenant's
+	// tokens through the mounted API
+	ScopeManage = "machine_tokens:manage"
+)
+
+var (
+	// ErrTokenNotFound is returned for tokens that don't exist in the tenant
+	ErrTokenNotFound = errors.New("machine token not found")
+	// ErrInvalidToken is returned for secrets that are unknown, expired or
+	// revoked
+	ErrInvalidToken = errors.New("invalid machine token")
+	// ErrInvalidScopes is returned when a token is requested without scopes
+	// or with scopes not in Config.Scopes
+	ErrInvalidScopes = errors.New("invalid machine token scopes")
+	// ErrTokenInactive is returned when rotating a token that has expired
+	// or been revoked
+	ErrTokenInactive = errors.New("machine token is no longer active")
+)
+
+// Token is a machine token's metadata. The secret is returned only when
+// the token is issued.
+type Token struct {
+	ID        string    `json:"id" dynamodbav:"id"`
+	TenantID  string    `json:"tenant_id" dynamodbav:"tenant_id"`
+	Name      string    `json:"name" dynamodbav:"name"`
+	Hash      string    `json:"-" dynamodbav:"hash"`
+	Scopes    []string  `json:"scopes" dynamodbav:"scopes"`
+	CreatedBy string    `json:"created_by,omitempty" dynamodbav:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at" dynamodbav:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" dynamodbav:"expires_at"`
+	// RevokedAt is when the token was revoked; zero if it wasn't
+	RevokedAt  time.Time `json:"revoked_at,omitempty" dynamodbav:"revoked_at,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty" dynamodbav:"last_used_at,omitempty"`
+	// RotatedFrom and RotatedTo link a token to the one it replaced and the
+	// one that replaced it
+	RotatedFrom string `json:"rotated_from,omitempty" dynamodbav:"rotated_from,omitempty"`
+	RotatedTo   string `json:"rotated_to,omitempty" dynamodbav:"rotated_to,omitempty"`
+}
+
+// IsActive reports whether the token can authenticate requests at the given time
+func (t *Token) IsActive(now time.Time) bool {
+	if !t.RevokedAt.IsZero() && !now.Before(t.RevokedAt) {
+		return false
+	}
+	return now.Before(t.ExpiresAt)
+}
+
+// IssuedToken is a newly issued token with its secret, which can't be
+// recovered later
+type IssuedToken struct {
+	*Token
+	Secret string `json:"secret"`
+}
+
+// IssueRequest describes a token to issue
+type IssueRequest struct {
+	TenantID string
+	Name     string
+	// Scopes must be a non-empty subset of Config.Scopes
+	Scopes []string
+	// TTL is how long the token lasts (default: Config.DefaultTTL, capped
+	// at Config.MaxTTL)
+	TTL time.Duration
+	// CreatedBy is the user or token issuing it, for the audit trail
+	CreatedBy string
+}
+
+// Store persists tokens
+type Store interface {
+	// Save creates or replaces a token
+	Save(ctx context.Context, token *Token) error
+	// Get returns ErrTokenNotFound for unknown tokens
+	Get(ctx context.Context, tenantID, id string) (*Token, error)
+	// Lookup returns the token with the given secret hash, or
+	// ErrTokenNotFound
+	Lookup(ctx context.Context, hash string) (*Token, error)
+	// List returns the tenant's tokens
+	List(ctx context.Context, tenantID string) ([]*Token, error)
+	// Touch records that a token was used
+	Touch(ctx context.Context, tenantID, id string, at time.Time) error
+}
+
+// Config configures the Service
+type Config struct {
+	// Store persists tokens (required)
+	Store Store
+	// Scopes are the scopes tokens may be granted (required)
+	Scopes []string
+	// Prefix starts every secret, so secret scanners recognize leaked
+	// tokens (default: "mt")
+	Prefix string
+	// DefaultTTL is how long tokens last when no TTL is requested (default: 90 days)
+	DefaultTTL time.Duration
+	// MaxTTL caps requested TTLs (default: 1 year)
+	MaxTTL time.Duration
+	// RotationGrace is how long a rotated token keeps working, so clients
+	// can switch over without downtime (default: 24h)
+	RotationGrace time.Duration
+	// CacheTTL is how long validated tokens are cached (default: 1m).
+	// Rotations and revocations made through this Service take effect
+	// immediately on this instance; other instances see them within CacheTTL.
+	CacheTTL time.Duration
+	// LastUsedInterval is how often a token's last use is written, so busy
+	// tokens don't write on every request (default: 5m)
+	LastUsedInterval time.Duration
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Service issues and validates machine tokens
+type Service struct {
+	config Config
+
+	mu    sync.RWMutex
+	cache map[string]cachedToken
+}
+
+type cachedToken struct {
+	token   *Token
+	expires time.Time
+}
+
+// NewService creates a Service with the given configuration
+func NewService(config Config) (*Service, error) {
+	if config.Store == nil {
+		return nil, fmt.Errorf("machine tokens require a store")
+	}
+	if len(config.Scopes) == 0 {
+		return nil, fmt.Errorf("machine tokens require grantable scopes")
+	}
+	if config.Prefix == "" {
+		config.Prefix = "mt"
+	}
+	if config.DefaultTTL == 0 {
+		config.DefaultTTL = 90 * 24 * time.Hour
+	}
+	if config.MaxTTL == 0 {
+		config.MaxTTL = 365 * 24 * time.Hour
+	}
+	if config.DefaultTTL > config.MaxTTL {
+		config.DefaultTTL = config.MaxTTL
+	}
+	if config.RotationGrace == 0 {
+		config.RotationGrace = 24 * time.Hour
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = time.Minute
+	}
+	if config.LastUsedInterval == 0 {
+		config.LastUsedInterval = 5 * time.Minute
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Service{config: config, cache: make(map[string]cachedToken)}, nil
+}
+
+// Issue creates a token and returns it with its secret
+func (s *Service) Issue(ctx context.Context, req IssueRequest) (*IssuedToken, error) {
+	return s.issue(ctx, req, "")
+}
+
+// issue creates a token, optionally as the replacement of another
+func (s *Service) issue(ctx context.Context, req IssueRequest, rotatedFrom string) (*IssuedToken, error) {
+	if req.TenantID == "" {
+		return nil, fmt.Errorf("machine tokens require a tenant")
+	}
+	scopes, err := s.validScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := req.TTL
+	if ttl <= 0 {
+		ttl = s.config.DefaultTTL
+	}
+	if ttl > s.config.MaxTTL {
+		ttl = s.config.MaxTTL
+	}
+
+	secret, err := s.newSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := s.config.Now().UTC()
+	token := &Token{
+		ID:          id.New("mtk"),
+		TenantID:    req.TenantID,
+		Name:        strings.TrimSpace(req.Name),
+		Hash:        middleware.HashAPIKey(secret),
+		Scopes:      scopes,
+		CreatedBy:   req.CreatedBy,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+		RotatedFrom: rotatedFrom,
+	}
+	if err := s.config.Store.Save(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to save machine token: %w", err)
+	}
+	return &IssuedToken{Token: token, Secret: secret}, nil
+}
+
+// Rotate replaces a token with a new one with the same name, scopes and
+// lifetime. The old token keeps working for Config.RotationGrace.
+func (s *Service) Rotate(ctx context.Context, tenantID, tokenID, rotatedBy string) (*IssuedToken, error) {
+	old, err := s.config.Store.Get(ctx, tenantID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.config.Now()
+	if !old.IsActive(now) {
+		return nil, ErrTokenInactive
+	}
+
+	issued, err := s.issue(ctx, IssueRequest{
+		TenantID:  old.TenantID,
+		Name:      old.Name,
+		Scopes:    old.Scopes,
+		TTL:       old.ExpiresAt.Sub(old.CreatedAt),
+		CreatedBy: rotatedBy,
+	}, old.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	old.RotatedTo = issued.ID
+	if end := now.Add(s.config.RotationGrace).UTC(); end.Before(old.ExpiresAt) {
+		old.ExpiresAt = end
+	}
+	if err := s.config.Store.Save(ctx, old); err != nil {
+		return nil, fmt.Errorf("issued token %s but failed to retire token %s: %w", issued.ID, old.ID, err)
+	}
+	s.invalidate(old.Hash)
+	return issued, nil
+}
+
+// Revoke stops a token from authenticating requests
+func (s *Service) Revoke(ctx context.Context, tenantID, tokenID string) error {
+	token, err := s.config.Store.Get(ctx, tenantID, tokenID)
+	if err != nil {
+		return err
+	}
+	if token.RevokedAt.IsZero() {
+		token.RevokedAt = s.config.Now().UTC()
+		if err := s.config.Store.Save(ctx, token); err != nil {
+			return fmt.Errorf("failed to revoke machine token: %w", err)
+		}
+	}
+	s.invalidate(token.Hash)
+	return nil
+}
+
+// Get returns one of the tenant's tokens
+func (s *Service) Get(ctx context.Context, tenantID, tokenID string) (*Token, error) {
+	return s.config.Store.Get(ctx, tenantID, tokenID)
+}
+
+// List returns the tenant's tokens, newest first
+func (s *Service) List(ctx context.Context, tenantID string) ([]*Token, error) {
+	tokens, err := s.config.Store.List(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list machine tokens: %w", err)
+	}
+	slices.SortFunc(tokens, func(a, b *Token) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return tokens, nil
+}
+
+// Authenticate returns the active token with the given secret, or
+// ErrInvalidToken. Tokens are cached for Config.CacheTTL, and their last
+// use is recorded at most once per Config.LastUsedInterval.
+func (s *Service) Authenticate(ctx context.Context, secret string) (*Token, error) {
+	if !strings.HasPrefix(secret, s.config.Prefix+"_") {
+		return nil, ErrInvalidToken
+	}
+	hash := middleware.HashAPIKey(secret)
+	now := s.config.Now()
+
+	s.mu.RLock()
+	cached, ok := s.cache[hash]
+	s.mu.RUnlock()
+
+	token := cached.token
+	refresh := !ok || !now.Before(cached.expires)
+	if refresh {
+		var err error
+		token, err = s.config.Store.Lookup(ctx, hash)
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, ErrInvalidToken
+		}
+		if err != nil {
+			// Don't cache failures so the next request retries
+			return nil, fmt.Errorf("failed to look up machine token: %w", err)
+		}
+		cached = cachedToken{token: token, expires: now.Add(s.config.CacheTTL)}
+	}
+	if !token.IsActive(now) {
+		s.invalidate(hash)
+		return nil, ErrInvalidToken
+	}
+
+	if now.Sub(token.LastUsedAt) >= s.config.LastUsedInterval {
+		// Tracking is best effort; it never fails the request
+		if err := s.config.Store.Touch(ctx, token.TenantID, token.ID, now.UTC()); err == nil {
+			touched := *token
+			touched.LastUsedAt = now.UTC()
+			cached.token = &touched
+			token = &touched
+			refresh = true
+		}
+	}
+
+	if refresh {
+		s.mu.Lock()
+		s.cache[hash] = cached
+		s.mu.Unlock()
+	}
+	return token, nil
+}
+
+// validScopes checks requested scopes are grantable
+func (s *Service) validScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScopes)
+	}
+	valid := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(s.config.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %q can't be granted", ErrInvalidScopes, scope)
+		}
+		if !slices.Contains(valid, scope) {
+			valid = append(valid, scope)
+		}
+	}
+	slices.Sort(valid)
+	return valid, nil
+}
+
+// newSecret generates a prefixed random secret
+func (s *Service) newSecret() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate machine token: %w", err)
+	}
+	return s.config.Prefix + "_" + base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// invalidate drops a cached token
+func (s *Service) invalidate(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, hash)
+}
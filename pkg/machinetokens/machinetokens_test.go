@@ -0,0 +1,269 @@
+package machinetokens
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/middleware"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T, config Config) (*Service, *time.Time) {
+	t.Helper()
+	now := testNow
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	if config.Scopes == nil {
+		config.Scopes = []string{"payments:read", "payments:write", ScopeManage}
+	}
+	config.Now = func() time.Time { return now }
+	service, err := NewService(config)
+	require.NoError(t, err)
+	return service, &now
+}
+
+// countingStore counts lookups and touches, and can fail lookups
+type countingStore struct {
+	*MemoryStore
+	lookups   int
+	touches   int
+	lookupErr error
+}
+
+func (c *countingStore) Lookup(ctx context.Context, hash string) (*Token, error) {
+	c.lookups++
+	if c.lookupErr != nil {
+		return nil, c.lookupErr
+	}
+	return c.MemoryStore.Lookup(ctx, hash)
+}
+
+func (c *countingStore) Touch(ctx context.Context, tenantID, id string, at time.Time) error {
+	c.touches++
+	return c.MemoryStore.Touch(ctx, tenantID, id, at)
+}
+
+func TestNewServiceRequiresStoreAndScopes(t *testing.T) {
+	_, err := NewService(Config{Scopes: []string{"payments:read"}})
+	assert.Error(t, err)
+	_, err = NewService(Config{Store: NewMemoryStore()})
+	assert.Error(t, err)
+}
+
+func TestIssue(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(t, Config{MaxTTL: 30 * 24 * time.Hour})
+
+	issued, err := service.Issue(ctx, IssueRequest{
+		TenantID: "acme",
+		Name:     "ci",
+		Scopes:   []string{"payments:write", "payments:read", "payments:read"},
+		TTL:      365 * 24 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `^mt_`, issued.Secret)
+	assert.Equal(t, []string{"payments:read", "payments:write"}, issued.Scopes)
+	assert.Equal(t, testNow.Add(30*24*time.Hour), issued.ExpiresAt, "TTL is capped")
+	assert.Equal(t, middleware.HashAPIKey(issued.Secret), issued.Hash)
+
+	_, err = service.Issue(ctx, IssueRequest{TenantID: "acme", Scopes: []string{"admin"}})
+	assert.ErrorIs(t, err, ErrInvalidScopes)
+	_, err = service.Issue(ctx, IssueRequest{TenantID: "acme"})
+	assert.ErrorIs(t, err, ErrInvalidScopes)
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{MemoryStore: NewMemoryStore()}
+	service, now := newTestService(t, Config{Store: store, DefaultTTL: time.Hour})
+
+	issued, err := service.Issue(ctx, IssueRequest{TenantID: "acme", Scopes: []string{"payments:read"}})
+	require.NoError(t, err)
+
+	token, err := service.Authenticate(ctx, issued.Secret)
+	require.NoError(t, err)
+	assert.Equal(t, issued.ID, token.ID)
+	assert.Equal(t, testNow, token.LastUsedAt)
+
+	*now = now.Add(30 * time.Second)
+	_, err = service.Authenticate(ctx, issued.Secret)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.lookups, "validated tokens are cached")
+	assert.Equal(t, 1, store.touches, "last use is written at most every LastUsedInterval")
+
+	*now = now.Add(5 * time.Minute)
+	_, err = service.Authenticate(ctx, issued.Secret)
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.lookups)
+	assert.Equal(t, 2, store.touches)
+	stored, _ := service.Get(ctx, "acme", issued.ID)
+	assert.Equal(t, *now, stored.LastUsedAt)
+
+	_, err = service.Authenticate(ctx, "mt_unknown")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = service.Authenticate(ctx, "sk_live_other")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	*now = now.Add(time.Hour)
+	_, err = service.Authenticate(ctx, issued.Secret)
+	assert.ErrorIs(t, err, ErrInvalidToken, "expired tokens are rejected")
+}
+
+func TestRotateAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService(t, Config{RotationGrace: time.Hour})
+
+	old, err := service.Issue(ctx, IssueRequest{TenantID: "acme", Name: "ci", Scopes: []string{"payments:read"}})
+	require.NoError(t, err)
+	_, err = service.Authenticate(ctx, old.Secret)
+	require.NoError(t, err)
+
+	rotated, err := service.Rotate(ctx, "acme", old.ID, "ada")
+	require.NoError(t, err)
+	assert.Equal(t, "ci", rotated.Name)
+	assert.Equal(t, old.Scopes, rotated.Scopes)
+	assert.Equal(t, old.ID, rotated.RotatedFrom)
+	assert.Equal(t, old.ExpiresAt.Sub(old.CreatedAt), rotated.ExpiresAt.Sub(rotated.CreatedAt))
+
+	retired, err := service.Get(ctx, "acme", old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rotated.ID, retired.RotatedTo)
+	assert.Equal(t, testNow.Add(time.Hour), retired.ExpiresAt)
+
+	// The old token works during the grace window, then stops
+	_, err = service.Authenticate(ctx, old.Secret)
+	require.NoError(t, err)
+	*now = now.Add(time.Hour)
+	_, err = service.Authenticate(ctx, old.Secret)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = service.Rotate(ctx, "acme", old.ID, "ada")
+	assert.ErrorIs(t, err, ErrTokenInactive)
+
+	require.NoError(t, service.Revoke(ctx, "acme", rotated.ID))
+	_, err = service.Authenticate(ctx, rotated.Secret)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	assert.ErrorIs(t, service.Revoke(ctx, "globex", rotated.ID), ErrTokenNotFound, "tokens are per tenant")
+}
+
+func TestMiddleware(t *testing.T) {
+	store := &countingStore{MemoryStore: NewMemoryStore()}
+	store.lookupErr = errors.New("throttled")
+	service, _ := newTestService(t, Config{Store: store})
+	issued, err := service.Issue(context.Background(), IssueRequest{TenantID: "acme", Scopes: []string{"payments:read"}})
+	require.NoError(t, err)
+
+	app := lift.New()
+	app.Use(service.Middleware())
+	app.GET("/payments", func(ctx *lift.Context) error {
+		assert.Equal(t, issued.ID, FromContext(ctx).ID)
+		return ctx.OK(map[string]string{"tenant": ctx.TenantID(), "user": ctx.UserID()})
+	})
+
+	request := func(authorization string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  "GET",
+			Path:    "/payments",
+			Headers: map[string]string{"Authorization": authorization},
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	assert.Equal(t, 503, request("Bearer "+issued.Secret).Response.StatusCode, "lookup failures aren't cached")
+	store.lookupErr = nil
+
+	resp := request("Bearer " + issued.Secret)
+	require.Equal(t, 200, resp.Response.StatusCode)
+	assert.Equal(t, map[string]string{"tenant": "acme", "user": issued.ID}, resp.Response.Body)
+
+	assert.Equal(t, 401, request("").Response.StatusCode)
+	assert.Equal(t, 401, request("Bearer mt_wrong").Response.StatusCode)
+}
+
+func TestApp(t *testing.T) {
+	service, _ := newTestService(t, Config{})
+
+	app := lift.New()
+	app.Use(middleware.RouteAuth(middleware.RouteAuthOptions{
+		Schemes: map[string]lift.Middleware{
+			"admin": func(next lift.Handler) lift.Handler {
+				return lift.HandlerFunc(func(ctx *lift.Context) error {
+					principal := &security.Principal{UserID: ctx.Header("X-User"), TenantID: "acme"}
+					if principal.UserID == "root" {
+						principal.Scopes = []string{ScopeManage}
+					}
+					lift.WithSecurity(ctx).SetPrincipal(principal)
+					return next.Handle(ctx)
+				})
+			},
+			AuthScheme: service.Middleware(),
+		},
+		Default: "admin",
+	}))
+	require.NoError(t, app.Mount("/machine-tokens", service.App()))
+
+	request := func(method, path string, headers map[string]string, body any) *lift.Context {
+		var data []byte
+		if body != nil {
+			var err error
+			data, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		headers["Content-Type"] = "application/json"
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  method,
+			Path:    path,
+			Headers: headers,
+			Body:    data,
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+	admin := func() map[string]string { return map[string]string{"X-User": "root"} }
+
+	resp := request("POST", "/machine-tokens/tokens", admin(), map[string]any{
+		"name":       "ci",
+		"scopes":     []string{"payments:read"},
+		"expires_in": "720h",
+	})
+	require.Equal(t, 201, resp.Response.StatusCode)
+	issued := resp.Response.Body.(*IssuedToken)
+	assert.Equal(t, "root", issued.CreatedBy)
+	assert.Equal(t, testNow.Add(720*time.Hour), issued.ExpiresAt)
+
+	resp = request("GET", "/machine-tokens/tokens", admin(), nil)
+	require.Equal(t, 200, resp.Response.StatusCode)
+	assert.Len(t, resp.Response.Body.(map[string]any)["tokens"], 1)
+
+	// The token rotates itself
+	bearer := map[string]string{"Authorization": "Bearer " + issued.Secret}
+	resp = request("GET", "/machine-tokens/self", bearer, nil)
+	require.Equal(t, 200, resp.Response.StatusCode)
+	assert.Equal(t, issued.ID, resp.Response.Body.(*Token).ID)
+
+	resp = request("POST", "/machine-tokens/self/rotate", map[string]string{"Authorization": "Bearer " + issued.Secret}, nil)
+	require.Equal(t, 201, resp.Response.StatusCode)
+	rotated := resp.Response.Body.(*IssuedToken)
+	assert.Equal(t, issued.ID, rotated.RotatedFrom)
+	assert.NotEqual(t, issued.Secret, rotated.Secret)
+
+	assert.Equal(t, 403, request("GET", "/machine-tokens/tokens", map[string]string{"X-User": "ada"}, nil).Response.StatusCode)
+	assert.Equal(t, 401, request("GET", "/machine-tokens/self", map[string]string{}, nil).Response.StatusCode)
+	assert.Equal(t, 400, request("POST", "/machine-tokens/tokens", admin(), map[string]any{
+		"name": "ci", "scopes": []string{"admin"},
+	}).Response.StatusCode)
+	assert.Equal(t, 404, request("DELETE", "/machine-tokens/tokens/mtk_missing", admin(), nil).Response.StatusCode)
+	assert.Equal(t, 204, request("DELETE", "/machine-tokens/tokens/"+rotated.ID, admin(), nil).Response.StatusCode)
+	assert.Equal(t, 401, request("GET", "/machine-tokens/self", map[string]string{"Authorization": "Bearer " + rotated.Secret}, nil).Response.StatusCode)
+}
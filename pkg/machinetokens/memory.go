@@ -0,0 +1,88 @@
+package machinetokens
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store for tests and local development
+type MemoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+	hashes map[string]string
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens: make(map[string]Token),
+		hashes: make(map[string]string),
+	}
+}
+
+// Save implements Store
+func (m *MemoryStore) Save(ctx context.Context, token *Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := *token
+	saved.Scopes = slices.Clone(token.Scopes)
+	m.tokens[token.TenantID+"#"+token.ID] = saved
+	m.hashes[token.Hash] = token.TenantID + "#" + token.ID
+	return nil
+}
+
+// Get implements Store
+func (m *MemoryStore) Get(ctx context.Context, tenantID, id string) (*Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.get(tenantID + "#" + id)
+}
+
+// Lookup implements Store
+func (m *MemoryStore) Lookup(ctx context.Context, hash string) (*Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.hashes[hash]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return m.get(key)
+}
+
+// List implements Store
+func (m *MemoryStore) List(ctx context.Context, tenantID string) ([]*Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tokens := []*Token{}
+	for _, token := range m.tokens {
+		if token.TenantID == tenantID {
+			token.Scopes = slices.Clone(token.Scopes)
+			tokens = append(tokens, &token)
+		}
+	}
+	return tokens, nil
+}
+
+// Touch implements Store
+func (m *MemoryStore) Touch(ctx context.Context, tenantID, id string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.tokens[tenantID+"#"+id]
+	if !ok {
+		return ErrTokenNotFound
+	}
+	token.LastUsedAt = at
+	m.tokens[tenantID+"#"+id] = token
+	return nil
+}
+
+func (m *MemoryStore) get(key string) (*Token, error) {
+	token, ok := m.tokens[key]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	token.Scopes = slices.Clone(token.Scopes)
+	return &token, nil
+}
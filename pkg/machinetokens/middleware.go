@@ -0,0 +1,64 @@
+package machinetokens
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+const tokenContextKey = "machine_token"
+
+// Middleware authenticates requests with a machine token sent as a bearer
+// token. The token's tenant and scopes become the request principal, so
+// route scopes, ctx.TenantID() and tenant isolation work as they do for
+// JWTs, and the token itself is available from FromContext.
+func (s *Service) Middleware() lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			secret, ok := strings.CutPrefix(ctx.Header("Authorization"), "Bearer ")
+			if !ok || secret == "" {
+				return lift.Unauthorized("Machine token required")
+			}
+
+			token, err := s.Authenticate(ctx.Context, strings.TrimSpace(secret))
+			if errors.Is(err, ErrInvalidToken) {
+				return lift.Unauthorized("Invalid machine token")
+			}
+			if err != nil {
+				if ctx.Logger != nil {
+					ctx.Logger.Error("Machine token lookup failed", map[string]any{
+						"error": err.Error(),
+					})
+				}
+				return lift.NewLiftError("AUTH_UNAVAILABLE", "Unable to verify machine token", 503).WithCause(err)
+			}
+
+			ctx.Set(tokenContextKey, token)
+			lift.WithSecurity(ctx).SetPrincipal(&security.Principal{
+				UserID:     token.ID,
+				TenantID:   token.TenantID,
+				Scopes:     token.Scopes,
+				AuthMethod: AuthScheme,
+				IssuedAt:   token.CreatedAt,
+				ExpiresAt:  token.ExpiresAt,
+			})
+
+			if ctx.Logger != nil {
+				ctx.Logger = ctx.Logger.WithField("machine_token_id", token.ID).
+					WithField("tenant_id", token.TenantID).
+					WithField("auth_method", AuthScheme)
+			}
+
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// FromContext returns the machine token that authenticated the request,
+// or nil if the request wasn't authenticated with one
+func FromContext(ctx *lift.Context) *Token {
+	token, _ := ctx.Get(tokenContextKey).(*Token)
+	return token
+}
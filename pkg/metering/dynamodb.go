@@ -0,0 +1,238 @@
+package metering
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBOutbox implements Outbox with a DynamoDB table keyed by a string
+// partition key "pk". Undelivered events carry an "outbox" attribute, so a
+// sparse global secondary index with partition key "outbox" (S) and sort
+// key "next_attempt" (N) finds the due ones. Delivered and abandoned events
+// are kept, to recognize records added again, until the "ttl" attribute
+// expires them.
+type DynamoDBOutbox struct {
+	client    *dynamodb.Client
+	tableName string
+	// IndexName is the outbox index (default: "outbox")
+	IndexName string
+	// Retention is how long delivered and abandoned events are kept
+	// (default: 35 days)
+	Retention time.Duration
+}
+
+// NewDynamoDBOutbox creates a DynamoDB-backed outbox
+func NewDynamoDBOutbox(client *dynamodb.Client, tableName string) *DynamoDBOutbox {
+	return &DynamoDBOutbox{
+		client:    client,
+		tableName: tableName,
+		IndexName: "outbox",
+		Retention: 35 * 24 * time.Hour,
+	}
+}
+
+// Add implements Outbox
+func (d *DynamoDBOutbox) Add(ctx context.Context, event *BillingEvent, at time.Time) error {
+	put, err := d.put(event, at)
+	if err != nil {
+		return err
+	}
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           put.TableName,
+		Item:                put.Item,
+		ConditionExpression: put.ConditionExpression,
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrDuplicateEvent
+	}
+	return err
+}
+
+// TransactItem returns the write that adds an event, due at once, for
+// callers recording usage in the same transaction as the change that
+// caused it. The transaction is cancelled if the event is already in the
+// outbox.
+//
+//	event, err := emitter.Event(ctx, usage)
+//	...
+//	outboxPut, err := outbox.TransactItem(event)
+//	...
+//	client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+//		TransactItems: []types.TransactWriteItem{orderPut, outboxPut},
+//	})
+func (d *DynamoDBOutbox) TransactItem(event *BillingEvent) (types.TransactWriteItem, error) {
+	put, err := d.put(event, event.RecordedAt)
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{Put: put}, nil
+}
+
+// Due implements Outbox. The index is eventually consistent, so it may
+// return events that were just delivered; their claims fail.
+func (d *DynamoDBOutbox) Due(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	result, err := d.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		IndexName:              aws.String(d.IndexName),
+		KeyConditionExpression: aws.String("outbox = :pending AND next_attempt <= :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: string(outboxPending)},
+			":now":     numberValue(now.UnixMilli()),
+		},
+		Limit: aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]OutboxEntry, 0, len(result.Items))
+	for _, item := range result.Items {
+		entry, err := readEntry(item)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Claim implements Outbox, conditional on the attempt count the entry was
+// read with
+func (d *DynamoDBOutbox) Claim(ctx context.Context, entry OutboxEntry, until time.Time) (bool, error) {
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.tableName),
+		Key:                 eventKey(entry.Event.ID),
+		UpdateExpression:    aws.String("SET attempts = :next, next_attempt = :until"),
+		ConditionExpression: aws.String("attempts = :attempts AND outbox = :pending"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":next":     numberValue(int64(entry.Attempts + 1)),
+			":attempts": numberValue(int64(entry.Attempts)),
+			":until":    numberValue(until.UnixMilli()),
+			":pending":  &types.AttributeValueMemberS{Value: string(outboxPending)},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delivered implements Outbox
+func (d *DynamoDBOutbox) Delivered(ctx context.Context, id string) error {
+	return d.finish(ctx, id, outboxDelivered, "")
+}
+
+// Failed implements Outbox
+func (d *DynamoDBOutbox) Failed(ctx context.Context, id, message string, retryAt time.Time) error {
+	if retryAt.IsZero() {
+		return d.finish(ctx, id, outboxAbandoned, message)
+	}
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(d.tableName),
+		Key:              eventKey(id),
+		UpdateExpression: aws.String("SET last_error = :error, next_attempt = :retry"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":error": &types.AttributeValueMemberS{Value: message},
+			":retry": numberValue(retryAt.UnixMilli()),
+		},
+	})
+	return err
+}
+
+// finish takes an event out of the outbox index and lets it expire
+func (d *DynamoDBOutbox) finish(ctx context.Context, id string, status outboxStatus, message string) error {
+	values := map[string]types.AttributeValue{
+		":status": &types.AttributeValueMemberS{Value: string(status)},
+		":ttl":    numberValue(time.Now().Add(d.Retention).Unix()),
+	}
+	update := "SET #status = :status, #ttl = :ttl"
+	if message != "" {
+		update += ", last_error = :error"
+		values[":error"] = &types.AttributeValueMemberS{Value: message}
+	}
+
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(d.tableName),
+		Key:              eventKey(id),
+		UpdateExpression: aws.String(update + " REMOVE outbox"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+			"#ttl":    "ttl",
+		},
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
+
+func (d *DynamoDBOutbox) put(event *BillingEvent, at time.Time) (*types.Put, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode billing event %s: %w", event.ID, err)
+	}
+	return &types.Put{
+		TableName: aws.String(d.tableName),
+		Item: map[string]types.AttributeValue{
+			"pk":           &types.AttributeValueMemberS{Value: "billing_event#" + event.ID},
+			"event":        &types.AttributeValueMemberS{Value: string(body)},
+			"status":       &types.AttributeValueMemberS{Value: string(outboxPending)},
+			"outbox":       &types.AttributeValueMemberS{Value: string(outboxPending)},
+			"attempts":     numberValue(0),
+			"next_attempt": numberValue(at.UnixMilli()),
+		},
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	}, nil
+}
+
+func readEntry(item map[string]types.AttributeValue) (OutboxEntry, error) {
+	body, _ := item["event"].(*types.AttributeValueMemberS)
+	if body == nil {
+		return OutboxEntry{}, fmt.Errorf("outbox item has no event")
+	}
+	var event BillingEvent
+	if err := json.Unmarshal([]byte(body.Value), &event); err != nil {
+		return OutboxEntry{}, fmt.Errorf("invalid outbox event: %w", err)
+	}
+
+	attempts, _ := readNumber(item["attempts"])
+	nextAttempt, _ := readNumber(item["next_attempt"])
+	entry := OutboxEntry{
+		Event:       &event,
+		Attempts:    int(attempts),
+		NextAttempt: time.UnixMilli(nextAttempt),
+	}
+	if lastError, ok := item["last_error"].(*types.AttributeValueMemberS); ok {
+		entry.LastError = lastError.Value
+	}
+	return entry, nil
+}
+
+func eventKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "billing_event#" + id},
+	}
+}
+
+func numberValue(n int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+func readNumber(av types.AttributeValue) (int64, error) {
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("missing number attribute")
+	}
+	return strconv.ParseInt(n.Value, 10, 64)
+}
@@ -0,0 +1,387 @@
+// Package metering turns usage records into billing events and delivers
+// them to a billing service through an outbox.
+//
+// Recording usage only writes the event to the outbox; Flush delivers
+// outstanding events later, retrying failures with backoff. An event's ID
+// is derived from its usage record's idempotency key, so recording the
+// same usage twice stores one event, and a delivery that is retried after
+// an ambiguous failure carries the same ID. Billing services that ignore
+// event IDs they have already seen therefore bill each usage record
+// exactly once.
+package metering
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// EventTypeUsageRecorded is the type of billing events for usage
+const EventTypeUsageRecorded = "usage.recorded"
+
+// ErrDuplicateEvent is returned by outboxes for events they already hold
+var ErrDuplicateEvent = errors.New("billing event already recorded")
+
+// UsageRecord is a quantity of a metered resource a tenant used
+type UsageRecord struct {
+	// ID makes recording idempotent: records with the same tenant and ID
+	// are billed once. Use the idempotency key of the request that caused
+	// the usage, or the ID of the thing that was used.
+	ID       string
+	TenantID string
+	// Metric names what was used, e.g. "orders" or "api_calls"
+	Metric   string
+	Quantity float64
+	// Unit is the unit of Quantity, e.g. "count" or "GB"
+	Unit       string
+	Dimensions map[string]string
+	// OccurredAt is when the usage happened (default: now)
+	OccurredAt time.Time
+}
+
+// Subscription is the subscription usage is billed against
+type Subscription struct {
+	Plan   string
+	Status string
+	// BillingCycle is "monthly", "quarterly" or "yearly" (default monthly)
+	BillingCycle string
+	// StartDate anchors the billing periods; without it periods are
+	// calendar months, quarters or years in UTC
+	StartDate time.Time
+}
+
+// SubscriptionResolver returns the subscription a tenant's usage is billed
+// against, or nil if the tenant has none
+type SubscriptionResolver func(ctx context.Context, tenantID string) (*Subscription, error)
+
+// BillingEvent is usage as delivered to the billing service
+type BillingEvent struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Source     string            `json:"source"`
+	TenantID   string            `json:"tenant_id"`
+	Metric     string            `json:"metric"`
+	Quantity   float64           `json:"quantity"`
+	Unit       string            `json:"unit,omitempty"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	RecordedAt time.Time         `json:"recorded_at"`
+
+	// The subscription the usage falls under, if it was resolved
+	Plan               string    `json:"plan,omitempty"`
+	SubscriptionStatus string    `json:"subscription_status,omitempty"`
+	BillingCycle       string    `json:"billing_cycle,omitempty"`
+	PeriodStart        time.Time `json:"period_start,omitempty"`
+	PeriodEnd          time.Time `json:"period_end,omitempty"`
+}
+
+// Publisher delivers billing events. It should treat an event it was
+// already given as delivered.
+type Publisher interface {
+	Publish(ctx context.Context, event *BillingEvent) error
+}
+
+// PublisherFunc adapts a function to a Publisher, e.g. one that puts
+// events on an EventBridge bus:
+//
+//	metering.PublisherFunc(func(ctx context.Context, event *metering.BillingEvent) error {
+//		detail, _ := json.Marshal(event)
+//		out, err := bus.PutEvents(ctx, &eventbridge.PutEventsInput{Entries: []types.PutEventsRequestEntry{{
+//			EventBusName: aws.String("billing"),
+//			Source:       aws.String(event.Source),
+//			DetailType:   aws.String(event.Type),
+//			Detail:       aws.String(string(detail)),
+//		}}})
+//		if err == nil && out.FailedEntryCount > 0 {
+//			err = fmt.Errorf("event %s was not accepted", event.ID)
+//		}
+//		return err
+//	})
+type PublisherFunc func(ctx context.Context, event *BillingEvent) error
+
+// Publish implements Publisher
+func (f PublisherFunc) Publish(ctx context.Context, event *BillingEvent) error {
+	return f(ctx, event)
+}
+
+// EmitterConfig configures an Emitter
+type EmitterConfig struct {
+	// Outbox holds events until they are delivered (default in-memory, for
+	// tests only)
+	Outbox Outbox
+	// Publisher delivers events; required to Flush
+	Publisher Publisher
+	// Subscriptions resolves the subscription usage is billed against.
+	// Without it events carry no plan or billing period.
+	Subscriptions SubscriptionResolver
+	// Source identifies the emitting service in events (default
+	// "lift.metering")
+	Source string
+	// BatchSize is how many events one Flush delivers at most (default: 25)
+	BatchSize int
+	// MaxAttempts is how often delivery of an event is attempted before it
+	// is abandoned (default: 8)
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry, doubling with each
+	// further attempt up to an hour (default: 30s)
+	RetryBackoff time.Duration
+	// Now returns the current time (default time.Now)
+	Now func() time.Time
+}
+
+// Emitter records usage as billing events and delivers them
+type Emitter struct {
+	config EmitterConfig
+}
+
+// FlushResult counts what a Flush did
+type FlushResult struct {
+	Delivered int `json:"delivered"`
+	// Retrying events failed and will be attempted again
+	Retrying int `json:"retrying"`
+	// Abandoned events failed their last attempt
+	Abandoned int `json:"abandoned"`
+}
+
+// NewEmitter creates a usage emitter
+func NewEmitter(config EmitterConfig) (*Emitter, error) {
+	if config.Outbox == nil {
+		config.Outbox = NewMemoryOutbox()
+	}
+	if config.Source == "" {
+		config.Source = "lift.metering"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 25
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = 30 * time.Second
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Emitter{config: config}, nil
+}
+
+// Event converts a usage record into its billing event without recording
+// it, for callers that write the event to the outbox in the same
+// transaction as the change that caused the usage (see
+// DynamoDBOutbox.TransactItem)
+func (e *Emitter) Event(ctx context.Context, record UsageRecord) (*BillingEvent, error) {
+	switch {
+	case record.ID == "":
+		return nil, fmt.Errorf("usage record requires an ID")
+	case record.TenantID == "":
+		return nil, fmt.Errorf("usage record requires a tenant")
+	case record.Metric == "":
+		return nil, fmt.Errorf("usage record requires a metric")
+	}
+
+	now := e.config.Now()
+	if record.OccurredAt.IsZero() {
+		record.OccurredAt = now
+	}
+
+	event := &BillingEvent{
+		ID:         EventID(record.TenantID, record.ID),
+		Type:       EventTypeUsageRecorded,
+		Source:     e.config.Source,
+		TenantID:   record.TenantID,
+		Metric:     record.Metric,
+		Quantity:   record.Quantity,
+		Unit:       record.Unit,
+		Dimensions: record.Dimensions,
+		OccurredAt: record.OccurredAt,
+		RecordedAt: now,
+	}
+
+	if e.config.Subscriptions != nil {
+		subscription, err := e.config.Subscriptions(ctx, record.TenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve subscription for tenant %s: %w", record.TenantID, err)
+		}
+		if subscription != nil {
+			event.Plan = subscription.Plan
+			event.SubscriptionStatus = subscription.Status
+			event.BillingCycle = subscription.cycle()
+			event.PeriodStart, event.PeriodEnd = subscription.Period(record.OccurredAt)
+		}
+	}
+	return event, nil
+}
+
+// Record converts a usage record into a billing event and adds it to the
+// outbox for delivery. Recording a record again returns its event without
+// adding another.
+func (e *Emitter) Record(ctx context.Context, record UsageRecord) (*BillingEvent, error) {
+	event, err := e.Event(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.config.Outbox.Add(ctx, event, event.RecordedAt); err != nil && !errors.Is(err, ErrDuplicateEvent) {
+		return nil, fmt.Errorf("failed to record usage %s: %w", event.ID, err)
+	}
+	return event, nil
+}
+
+// RecordFromContext records usage for the request's tenant. Records without
+// an ID are keyed by the request's Idempotency-Key header, or its request
+// ID, and the metric, so a retried request isn't billed twice.
+func (e *Emitter) RecordFromContext(ctx *lift.Context, record UsageRecord) (*BillingEvent, error) {
+	if record.TenantID == "" {
+		record.TenantID = ctx.TenantID()
+	}
+	if record.ID == "" {
+		key := ctx.Header("Idempotency-Key")
+		if key == "" {
+			key = ctx.RequestID
+		}
+		if key != "" {
+			record.ID = key + "#" + record.Metric
+		}
+	}
+	return e.Record(ctx.Context, record)
+}
+
+// Flush delivers events whose delivery is due. Each event is claimed before
+// it is published, so concurrent flushes don't deliver the same event at
+// the same time; a flush that stops after claiming an event leaves it to be
+// retried once the claim lapses.
+func (e *Emitter) Flush(ctx context.Context) (FlushResult, error) {
+	var result FlushResult
+	if e.config.Publisher == nil {
+		return result, fmt.Errorf("metering emitter has no publisher")
+	}
+
+	now := e.config.Now()
+	entries, err := e.config.Outbox.Due(ctx, now, e.config.BatchSize)
+	if err != nil {
+		return result, fmt.Errorf("failed to read billing outbox: %w", err)
+	}
+
+	var failures []error
+	for _, entry := range entries {
+		attempt := entry.Attempts + 1
+		retryAt := now.Add(e.backoff(attempt))
+		claimed, err := e.config.Outbox.Claim(ctx, entry, retryAt)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("claim %s: %w", entry.Event.ID, err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		publishErr := e.config.Publisher.Publish(ctx, entry.Event)
+		if publishErr == nil {
+			if err := e.config.Outbox.Delivered(ctx, entry.Event.ID); err != nil {
+				// The event will be published again; the billing service
+				// recognizes its ID
+				failures = append(failures, fmt.Errorf("mark %s delivered: %w", entry.Event.ID, err))
+				continue
+			}
+			result.Delivered++
+			continue
+		}
+
+		if attempt >= e.config.MaxAttempts {
+			retryAt = time.Time{}
+			result.Abandoned++
+		} else {
+			result.Retrying++
+		}
+		if err := e.config.Outbox.Failed(ctx, entry.Event.ID, publishErr.Error(), retryAt); err != nil {
+			failures = append(failures, fmt.Errorf("mark %s failed: %w", entry.Event.ID, err))
+		}
+	}
+	return result, errors.Join(failures...)
+}
+
+// FlushHandler returns a handler that flushes the outbox, for a schedule
+// or for the outbox table's stream:
+//
+//	app.EventBridge("billing-flush", emitter.FlushHandler())
+func (e *Emitter) FlushHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		result, err := e.Flush(ctx.Context)
+		if ctx.Logger != nil && (result.Delivered > 0 || result.Retrying > 0 || result.Abandoned > 0) {
+			ctx.Logger.Info("Flushed billing events", map[string]any{
+				"delivered": result.Delivered,
+				"retrying":  result.Retrying,
+				"abandoned": result.Abandoned,
+			})
+		}
+		if ctx.Logger != nil && result.Abandoned > 0 {
+			ctx.Logger.Error("Abandoned billing events after their last delivery attempt", map[string]any{
+				"abandoned":    result.Abandoned,
+				"max_attempts": e.config.MaxAttempts,
+			})
+		}
+		return err
+	})
+}
+
+// backoff is the claim on, and the delay before retrying, an attempt
+func (e *Emitter) backoff(attempt int) time.Duration {
+	delay := e.config.RetryBackoff
+	for i := 1; i < attempt && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, time.Hour)
+}
+
+// EventID is the ID of the billing event for a tenant's usage record
+func EventID(tenantID, recordID string) string {
+	sum := sha256.Sum256([]byte(tenantID + "\x00" + recordID))
+	return "usage_" + hex.EncodeToString(sum[:16])
+}
+
+// Period returns the billing period containing t
+func (s Subscription) Period(t time.Time) (start, end time.Time) {
+	months := 1
+	switch s.cycle() {
+	case "quarterly":
+		months = 3
+	case "yearly":
+		months = 12
+	}
+
+	anchor := s.StartDate
+	if anchor.IsZero() {
+		t = t.UTC()
+		anchor = time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Count whole periods from the anchor, correcting for the days of the
+	// month the estimate ignores
+	elapsed := (t.Year()-anchor.Year())*12 + int(t.Month()-anchor.Month())
+	n := elapsed / months
+	if elapsed < 0 && elapsed%months != 0 {
+		n--
+	}
+	start = anchor.AddDate(0, n*months, 0)
+	if start.After(t) {
+		n--
+		start = anchor.AddDate(0, n*months, 0)
+	}
+	return start, anchor.AddDate(0, (n+1)*months, 0)
+}
+
+func (s Subscription) cycle() string {
+	switch s.BillingCycle {
+	case "quarterly":
+		return "quarterly"
+	case "yearly", "annual", "annually":
+		return "yearly"
+	default:
+		return "monthly"
+	}
+}
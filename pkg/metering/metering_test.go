@@ -0,0 +1,235 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPublisher struct {
+	events []*BillingEvent
+	err    error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *BillingEvent) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestRecordIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutbox()
+	publisher := &recordingPublisher{}
+	emitter, err := NewEmitter(EmitterConfig{Outbox: outbox, Publisher: publisher})
+	require.NoError(t, err)
+
+	usage := UsageRecord{ID: "order-1", TenantID: "tenant-1", Metric: "orders", Quantity: 1}
+	first, err := emitter.Record(ctx, usage)
+	require.NoError(t, err)
+	second, err := emitter.Record(ctx, usage)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, EventTypeUsageRecorded, first.Type)
+
+	other, err := emitter.Record(ctx, UsageRecord{ID: "order-1", TenantID: "tenant-2", Metric: "orders", Quantity: 1})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, other.ID, "record IDs are scoped to the tenant")
+
+	result, err := emitter.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Delivered)
+	assert.Len(t, publisher.events, 2)
+
+	// Recording delivered usage again doesn't deliver it again
+	_, err = emitter.Record(ctx, usage)
+	require.NoError(t, err)
+	result, err = emitter.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Delivered)
+}
+
+func TestRecordValidation(t *testing.T) {
+	emitter, err := NewEmitter(EmitterConfig{})
+	require.NoError(t, err)
+
+	for _, usage := range []UsageRecord{
+		{TenantID: "tenant-1", Metric: "orders"},
+		{ID: "order-1", Metric: "orders"},
+		{ID: "order-1", TenantID: "tenant-1"},
+	} {
+		_, err := emitter.Record(context.Background(), usage)
+		assert.Error(t, err)
+	}
+}
+
+func TestRecordResolvesSubscription(t *testing.T) {
+	emitter, err := NewEmitter(EmitterConfig{
+		Subscriptions: func(ctx context.Context, tenantID string) (*Subscription, error) {
+			return &Subscription{
+				Plan:         "professional",
+				Status:       "active",
+				BillingCycle: "monthly",
+				StartDate:    time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC),
+			}, nil
+		},
+	})
+	require.NoError(t, err)
+
+	event, err := emitter.Record(context.Background(), UsageRecord{
+		ID:         "order-1",
+		TenantID:   "tenant-1",
+		Metric:     "orders",
+		Quantity:   1,
+		OccurredAt: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "professional", event.Plan)
+	assert.Equal(t, "active", event.SubscriptionStatus)
+	assert.Equal(t, time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC), event.PeriodStart)
+	assert.Equal(t, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), event.PeriodEnd)
+
+	failing, err := NewEmitter(EmitterConfig{
+		Subscriptions: func(ctx context.Context, tenantID string) (*Subscription, error) {
+			return nil, errors.New("tenant registry unavailable")
+		},
+	})
+	require.NoError(t, err)
+	_, err = failing.Record(context.Background(), UsageRecord{ID: "order-1", TenantID: "tenant-1", Metric: "orders"})
+	assert.Error(t, err)
+}
+
+func TestSubscriptionPeriod(t *testing.T) {
+	at := time.Date(2026, 5, 5, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		subscription Subscription
+		start, end   time.Time
+	}{
+		{
+			name:         "calendar month",
+			subscription: Subscription{},
+			start:        time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+			end:          time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "calendar quarter",
+			subscription: Subscription{BillingCycle: "quarterly"},
+			start:        time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			end:          time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "anchored year",
+			subscription: Subscription{BillingCycle: "annual", StartDate: time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)},
+			start:        time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC),
+			end:          time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "anchored month",
+			subscription: Subscription{StartDate: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)},
+			start:        time.Date(2026, 4, 5, 12, 0, 0, 0, time.UTC),
+			end:          time.Date(2026, 5, 5, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.subscription.Period(at)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+		})
+	}
+}
+
+func TestFlushRetriesAndAbandons(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 5, 5, 10, 0, 0, 0, time.UTC)
+	outbox := NewMemoryOutbox()
+	publisher := &recordingPublisher{err: errors.New("billing service unavailable")}
+	emitter, err := NewEmitter(EmitterConfig{
+		Outbox:       outbox,
+		Publisher:    publisher,
+		MaxAttempts:  3,
+		RetryBackoff: time.Minute,
+		Now:          func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	event, err := emitter.Record(ctx, UsageRecord{ID: "order-1", TenantID: "tenant-1", Metric: "orders", Quantity: 1})
+	require.NoError(t, err)
+
+	result, err := emitter.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Retrying)
+
+	// Not due again until the backoff has passed
+	result, err = emitter.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, FlushResult{}, result)
+
+	now = now.Add(time.Minute)
+	result, err = emitter.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Retrying)
+
+	due, err := outbox.Due(ctx, now.Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, 2, due[0].Attempts)
+	assert.Equal(t, now.Add(2*time.Minute), due[0].NextAttempt)
+	assert.Equal(t, "billing service unavailable", due[0].LastError)
+
+	now = now.Add(2 * time.Minute)
+	result, err = emitter.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Abandoned)
+
+	due, err = outbox.Due(ctx, now.Add(24*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	assert.ErrorIs(t, outbox.Add(ctx, event, now), ErrDuplicateEvent)
+}
+
+func TestClaimIsExclusive(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	outbox := NewMemoryOutbox()
+	require.NoError(t, outbox.Add(ctx, &BillingEvent{ID: "usage_1"}, now))
+
+	due, err := outbox.Due(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	claimed, err := outbox.Claim(ctx, due[0], now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = outbox.Claim(ctx, due[0], now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed, "a second flusher holding the same entry loses")
+}
+
+func TestRecordFromContext(t *testing.T) {
+	emitter, err := NewEmitter(EmitterConfig{})
+	require.NoError(t, err)
+
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  "POST",
+		Path:    "/orders",
+		Headers: map[string]string{"Idempotency-Key": "req-1"},
+	}))
+	ctx.SetTenantID("tenant-1")
+
+	event, err := emitter.RecordFromContext(ctx, UsageRecord{Metric: "orders", Quantity: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-1", event.TenantID)
+	assert.Equal(t, EventID("tenant-1", "req-1#orders"), event.ID)
+}
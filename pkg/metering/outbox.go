@@ -0,0 +1,140 @@
+package metering
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OutboxEntry is an undelivered billing event
+type OutboxEntry struct {
+	Event *BillingEvent
+	// Attempts counts the delivery attempts claimed so far
+	Attempts int
+	// NextAttempt is when the event is next due for delivery
+	NextAttempt time.Time
+	LastError   string
+}
+
+// Outbox holds billing events until they are delivered
+type Outbox interface {
+	// Add stores an event, due for delivery at the given time. It returns
+	// ErrDuplicateEvent if the outbox already holds an event with its ID,
+	// whether delivered or not.
+	Add(ctx context.Context, event *BillingEvent, at time.Time) error
+	// Due returns up to limit undelivered events due for delivery, oldest
+	// first
+	Due(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error)
+	// Claim counts a delivery attempt for an entry returned by Due and
+	// makes it due again at until. It returns false if the entry has been
+	// claimed since it was read.
+	Claim(ctx context.Context, entry OutboxEntry, until time.Time) (bool, error)
+	// Delivered marks an event delivered
+	Delivered(ctx context.Context, id string) error
+	// Failed records why delivery failed and when to retry it; a zero
+	// retryAt abandons the event
+	Failed(ctx context.Context, id, message string, retryAt time.Time) error
+}
+
+type outboxStatus string
+
+const (
+	outboxPending   outboxStatus = "pending"
+	outboxDelivered outboxStatus = "delivered"
+	outboxAbandoned outboxStatus = "abandoned"
+)
+
+type memoryOutboxEntry struct {
+	OutboxEntry
+	status outboxStatus
+}
+
+// MemoryOutbox is an in-memory Outbox
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	entries map[string]*memoryOutboxEntry
+}
+
+// NewMemoryOutbox creates an empty outbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{entries: make(map[string]*memoryOutboxEntry)}
+}
+
+// Add implements Outbox
+func (o *MemoryOutbox) Add(ctx context.Context, event *BillingEvent, at time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.entries[event.ID]; ok {
+		return ErrDuplicateEvent
+	}
+	o.entries[event.ID] = &memoryOutboxEntry{
+		OutboxEntry: OutboxEntry{Event: event, NextAttempt: at},
+		status:      outboxPending,
+	}
+	return nil
+}
+
+// Due implements Outbox
+func (o *MemoryOutbox) Due(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var due []OutboxEntry
+	for _, entry := range o.entries {
+		if entry.status == outboxPending && !entry.NextAttempt.After(now) {
+			due = append(due, entry.OutboxEntry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttempt.Before(due[j].NextAttempt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Claim implements Outbox
+func (o *MemoryOutbox) Claim(ctx context.Context, entry OutboxEntry, until time.Time) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stored, ok := o.entries[entry.Event.ID]
+	if !ok || stored.status != outboxPending || stored.Attempts != entry.Attempts {
+		return false, nil
+	}
+	stored.Attempts++
+	stored.NextAttempt = until
+	return true, nil
+}
+
+// Delivered implements Outbox
+func (o *MemoryOutbox) Delivered(ctx context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if entry, ok := o.entries[id]; ok {
+		entry.status = outboxDelivered
+	}
+	return nil
+}
+
+// Failed implements Outbox
+func (o *MemoryOutbox) Failed(ctx context.Context, id, message string, retryAt time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry, ok := o.entries[id]
+	if !ok {
+		return nil
+	}
+	entry.LastError = message
+	if retryAt.IsZero() {
+		entry.status = outboxAbandoned
+	} else {
+		entry.NextAttempt = retryAt
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package metering
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pay-theory/lift/pkg/services"
+)
+
+// ServicePublisher delivers billing events to an internal billing service
+// by POSTing each event as JSON, with its ID as the Idempotency-Key header.
+// The service answers 2xx for events it accepts, and 409 Conflict for
+// events it has already accepted, which count as delivered.
+type ServicePublisher struct {
+	client  *services.ServiceClient
+	service string
+	path    string
+}
+
+// NewServicePublisher creates a publisher for the named service's endpoint
+//
+//	metering.NewServicePublisher(client, "billing", "/v1/usage-events")
+func NewServicePublisher(client *services.ServiceClient, serviceName, path string) *ServicePublisher {
+	return &ServicePublisher{client: client, service: serviceName, path: path}
+}
+
+// Publish implements Publisher
+func (p *ServicePublisher) Publish(ctx context.Context, event *BillingEvent) error {
+	resp, err := p.client.Call(ctx, &services.ServiceRequest{
+		ServiceName: p.service,
+		Method:      http.MethodPost,
+		Path:        p.path,
+		Headers:     map[string]string{"Idempotency-Key": event.ID},
+		Body:        event,
+		TenantID:    event.TenantID,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusConflict {
+		return fmt.Errorf("%s rejected billing event %s with status %d", p.service, event.ID, resp.StatusCode)
+	}
+	return nil
+}
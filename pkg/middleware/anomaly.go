@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// anomalySeverities maps severity names accepted by AnomalyFindingsHandler
+var anomalySeverities = map[string]security.AnomalySeverity{
+	"low":    security.AnomalyLow,
+	"medium": security.AnomalyMedium,
+	"high":   security.AnomalyHigh,
+}
+
+// AnomalyFindingsHandler lists the analyzer's findings for the caller's
+// tenant, newest first. Query parameters narrow the results:
+//
+//	GET /security/findings?user_id=u1&detector=velocity&severity=medium&since=2024-06-01T00:00:00Z&limit=50
+//
+// Findings name users and resources, so restrict the route with metadata,
+// e.g. .Meta(lift.MetaRoles, "security-admin").
+func AnomalyFindingsHandler(analyzer *security.AnomalyAnalyzer) lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		filter := security.FindingFilter{
+			TenantID: ctx.TenantID(),
+			UserID:   ctx.Query("user_id"),
+			Detector: ctx.Query("detector"),
+			Limit:    100,
+		}
+
+		if value := ctx.Query("severity"); value != "" {
+			severity, ok := anomalySeverities[value]
+			if !ok {
+				return lift.NewLiftError("INVALID_SEVERITY", "Severity must be low, medium or high", 400)
+			}
+			filter.MinSeverity = severity
+		}
+		if value := ctx.Query("since"); value != "" {
+			since, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return lift.NewLiftError("INVALID_SINCE", "Since must be an RFC 3339 timestamp", 400).WithCause(err)
+			}
+			filter.Since = since
+		}
+		if value := ctx.Query("limit"); value != "" {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > 1000 {
+				return lift.NewLiftError("INVALID_LIMIT", "Limit must be between 1 and 1000", 400)
+			}
+			filter.Limit = limit
+		}
+
+		findings, err := analyzer.Findings(ctx.Context, filter)
+		if err != nil {
+			return lift.NewLiftError("FINDINGS_UNAVAILABLE", "Failed to list anomaly findings", 500).WithCause(err)
+		}
+		if findings == nil {
+			findings = []security.AnomalyFinding{}
+		}
+		return ctx.OK(map[string]any{"findings": findings})
+	})
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomalyFindingsHandler(t *testing.T) {
+	analyzer := security.NewAnomalyAnalyzer(security.AnomalyConfig{
+		Detectors: []security.AccessDetector{&security.OffHoursDetector{}},
+	})
+	night := time.Date(2024, 6, 3, 2, 0, 0, 0, time.UTC)
+	for _, entry := range []security.AuditLogEntry{
+		{ID: "1", TenantID: "tenant-1", UserID: "u1", EntryType: "request", Timestamp: night},
+		{ID: "2", TenantID: "tenant-1", UserID: "u2", EntryType: "request", Timestamp: night},
+		{ID: "3", TenantID: "tenant-2", UserID: "u3", EntryType: "request", Timestamp: night},
+	} {
+		_, err := analyzer.Observe(context.Background(), entry)
+		require.NoError(t, err)
+	}
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.SetTenantID("tenant-1")
+			return next.Handle(ctx)
+		})
+	})
+	app.GET("/security/findings", AnomalyFindingsHandler(analyzer))
+
+	request := func(query map[string]string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:      "GET",
+			Path:        "/security/findings",
+			QueryParams: query,
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	ctx := request(nil)
+	require.Equal(t, 200, ctx.Response.StatusCode)
+	findings := ctx.Response.Body.(map[string]any)["findings"].([]security.AnomalyFinding)
+	assert.Len(t, findings, 2, "scoped to the caller's tenant")
+
+	ctx = request(map[string]string{"user_id": "u1"})
+	findings = ctx.Response.Body.(map[string]any)["findings"].([]security.AnomalyFinding)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "u1", findings[0].UserID)
+
+	ctx = request(map[string]string{"severity": "high"})
+	findings = ctx.Response.Body.(map[string]any)["findings"].([]security.AnomalyFinding)
+	assert.Empty(t, findings)
+
+	assert.Equal(t, 400, request(map[string]string{"severity": "urgent"}).Response.StatusCode)
+	assert.Equal(t, 400, request(map[string]string{"since": "yesterday"}).Response.StatusCode)
+	assert.Equal(t, 400, request(map[string]string{"limit": "0"}).Response.StatusCode)
+}
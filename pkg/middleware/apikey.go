@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/id"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+const apiKeyContextKey = "api_key"
+
+// ErrAPIKeyNotFound is returned when revoking a key that doesn't exist
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKey is the metadata stored for an API key. Only the SHA-256 hash of
+// the secret is stored; the secret itself is shown once when the key is
+// issued.
+type APIKey struct {
+	ID       string   `json:"id"`
+	Hash     string   `json:"hash"`
+	Name     string   `json:"name,omitempty"`
+	TenantID string   `json:"tenant_id"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// RateLimitTier selects the key's limit from RateLimitConfig.TierLimits
+	RateLimitTier string `json:"rate_limit_tier,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the key stops working; zero means never. Rotation
+	// sets it to the end of the old key's grace window.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	RevokedAt time.Time `json:"revoked_at,omitempty"`
+	// RotatedTo is the ID of the key that replaced this one
+	RotatedTo string `json:"rotated_to,omitempty"`
+}
+
+// IsActive reports whether the key can authenticate requests at the given time
+func (k *APIKey) IsActive(now time.Time) bool {
+	if !k.RevokedAt.IsZero() && !now.Before(k.RevokedAt) {
+		return false
+	}
+	return k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt)
+}
+
+// HasScope reports whether the key was granted a scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// KeyStore stores API keys by the hash of their secret
+type KeyStore interface {
+	// Lookup returns the key with the given hash, or nil if none exists
+	Lookup(ctx context.Context, hash string) (*APIKey, error)
+	// Put creates or replaces a key
+	Put(ctx context.Context, key *APIKey) error
+}
+
+// HashAPIKey returns the hash an API key secret is stored under
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAPIKey generates a new secret for the key described by template,
+// stores it and returns the secret. The prefix (e.g. "sk_live") makes keys
+// recognizable to secret scanners; the secret cannot be recovered later.
+func IssueAPIKey(ctx context.Context, store KeyStore, prefix string, template APIKey) (string, *APIKey, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(random)
+	if prefix != "" {
+		secret = prefix + "_" + secret
+	}
+
+	key := template
+	key.ID = id.New("key")
+	key.Hash = HashAPIKey(secret)
+	key.Scopes = slices.Clone(template.Scopes)
+	key.CreatedAt = time.Now().UTC()
+	key.RevokedAt = time.Time{}
+	key.RotatedTo = ""
+	if err := store.Put(ctx, &key); err != nil {
+		return "", nil, err
+	}
+	return secret, &key, nil
+}
+
+// RotateAPIKey issues a replacement for old with the same tenant, scopes
+// and tier, and lets old keep working for the grace window so clients can
+// switch over without downtime. A zero grace expires old immediately.
+func RotateAPIKey(ctx context.Context, store KeyStore, prefix string, old *APIKey, grace time.Duration) (string, *APIKey, error) {
+	template := *old
+	template.ExpiresAt = time.Time{}
+	secret, key, err := IssueAPIKey(ctx, store, prefix, template)
+	if err != nil {
+		return "", nil, err
+	}
+
+	retired := *old
+	retired.RotatedTo = key.ID
+	if end := time.Now().Add(grace); retired.ExpiresAt.IsZero() || end.Before(retired.ExpiresAt) {
+		retired.ExpiresAt = end
+	}
+	if err := store.Put(ctx, &retired); err != nil {
+		return "", nil, fmt.Errorf("issued key %s but failed to retire key %s: %w", key.ID, old.ID, err)
+	}
+	return secret, key, nil
+}
+
+// RevokeAPIKey stops the key with the given hash from authenticating
+// requests immediately
+func RevokeAPIKey(ctx context.Context, store KeyStore, hash string) error {
+	key, err := store.Lookup(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return ErrAPIKeyNotFound
+	}
+	key.RevokedAt = time.Now().UTC()
+	return store.Put(ctx, key)
+}
+
+// APIKeyFromContext returns the API key that authenticated the request, or
+// nil if the request wasn't authenticated with one
+func APIKeyFromContext(ctx *lift.Context) *APIKey {
+	key, _ := ctx.Get(apiKeyContextKey).(*APIKey)
+	return key
+}
+
+// APIKeyAuthConfig configures APIKeyAuth
+type APIKeyAuthConfig struct {
+	// Store looks up keys by hash
+	Store KeyStore
+	// Header carries the key (default: "X-API-Key")
+	Header string
+	// QueryParam, if set, also accepts the key from the query string. Query
+	// strings end up in access logs, so prefer the header.
+	QueryParam string
+	// Optional lets requests without a key through unauthenticated;
+	// requests with an invalid key are still rejected
+	Optional bool
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// APIKeyAuth authenticates requests by API key. The key's tenant and
+// scopes become the request principal, so RouteAuth, ctx.TenantID() and
+// tenant isolation work as they do for JWTs, and the key itself is
+// available from APIKeyFromContext.
+//
+//	store := middleware.NewDynamoDBKeyStore(client, "api-keys")
+//	app.Use(middleware.APIKeyAuth(middleware.APIKeyAuthConfig{Store: store}))
+func APIKeyAuth(config APIKeyAuthConfig) lift.Middleware {
+	if config.Store == nil {
+		panic("APIKeyAuth requires a key store")
+	}
+	if config.Header == "" {
+		config.Header = "X-API-Key"
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			secret := ctx.Header(config.Header)
+			if secret == "" && config.QueryParam != "" {
+				secret = ctx.Query(config.QueryParam)
+			}
+			if secret == "" {
+				if config.Optional {
+					return next.Handle(ctx)
+				}
+				return lift.Unauthorized("API key required")
+			}
+
+			key, err := config.Store.Lookup(ctx.Context, HashAPIKey(secret))
+			if err != nil {
+				if ctx.Logger != nil {
+					ctx.Logger.Error("API key lookup failed", map[string]any{
+						"error": err.Error(),
+					})
+				}
+				return lift.NewLiftError("AUTH_UNAVAILABLE", "Unable to verify API key", 503).WithCause(err)
+			}
+			if key == nil || !key.IsActive(config.Now()) {
+				return lift.Unauthorized("Invalid API key")
+			}
+
+			ctx.Set(apiKeyContextKey, key)
+			lift.WithSecurity(ctx).SetPrincipal(&security.Principal{
+				UserID:     key.ID,
+				TenantID:   key.TenantID,
+				Scopes:     key.Scopes,
+				AuthMethod: "api_key",
+				IssuedAt:   key.CreatedAt,
+				ExpiresAt:  key.ExpiresAt,
+			})
+
+			if ctx.Logger != nil {
+				ctx.Logger = ctx.Logger.WithField("api_key_id", key.ID).
+					WithField("tenant_id", key.TenantID).
+					WithField("auth_method", "api_key")
+			}
+
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// MemoryKeyStore implements KeyStore in memory, for tests and development
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey
+}
+
+// NewMemoryKeyStore creates a new in-memory key store
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys: make(map[string]*APIKey),
+	}
+}
+
+// Lookup returns a copy of the key with the given hash
+func (m *MemoryKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, exists := m.keys[hash]
+	if !exists {
+		return nil, nil
+	}
+
+	copied := *key
+	copied.Scopes = slices.Clone(key.Scopes)
+	return &copied, nil
+}
+
+// Put stores a copy of the key
+func (m *MemoryKeyStore) Put(ctx context.Context, key *APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *key
+	copied.Scopes = slices.Clone(key.Scopes)
+	m.keys[key.Hash] = &copied
+	return nil
+}
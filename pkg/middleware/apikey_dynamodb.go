@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBKeyStore implements KeyStore using DynamoDB. Keys are stored
+// under the "pk" partition key, as the hash of their secret, so the table
+// shape matches CreateIdempotencyTable. Expired and revoked keys are
+// removed by the table's TTL a day after they stop working.
+type DynamoDBKeyStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBKeyStore creates a new DynamoDB-backed API key store
+func NewDynamoDBKeyStore(client *dynamodb.Client, tableName string) *DynamoDBKeyStore {
+	return &DynamoDBKeyStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// DynamoDBAPIKeyRecord represents the DynamoDB item structure
+type DynamoDBAPIKeyRecord struct {
+	PK            string    `dynamodbav:"pk"`
+	ID            string    `dynamodbav:"id"`
+	Name          string    `dynamodbav:"name,omitempty"`
+	TenantID      string    `dynamodbav:"tenant_id"`
+	Scopes        []string  `dynamodbav:"scopes,omitempty"`
+	RateLimitTier string    `dynamodbav:"rate_limit_tier,omitempty"`
+	CreatedAt     time.Time `dynamodbav:"created_at"`
+	ExpiresAt     int64     `dynamodbav:"expires_at,omitempty"`
+	RevokedAt     int64     `dynamodbav:"revoked_at,omitempty"`
+	RotatedTo     string    `dynamodbav:"rotated_to,omitempty"`
+	TTL           int64     `dynamodbav:"ttl,omitempty"`
+}
+
+// Lookup retrieves the key with the given hash
+func (d *DynamoDBKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: hash},
+		},
+		// Revocations must take effect immediately
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := d.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var record DynamoDBAPIKeyRecord
+	if err := attributevalue.UnmarshalMap(result.Item, &record); err != nil {
+		return nil, err
+	}
+
+	key := &APIKey{
+		ID:            record.ID,
+		Hash:          record.PK,
+		Name:          record.Name,
+		TenantID:      record.TenantID,
+		Scopes:        record.Scopes,
+		RateLimitTier: record.RateLimitTier,
+		CreatedAt:     record.CreatedAt,
+		RotatedTo:     record.RotatedTo,
+	}
+	if record.ExpiresAt > 0 {
+		key.ExpiresAt = time.Unix(record.ExpiresAt, 0)
+	}
+	if record.RevokedAt > 0 {
+		key.RevokedAt = time.Unix(record.RevokedAt, 0)
+	}
+
+	return key, nil
+}
+
+// Put creates or replaces a key
+func (d *DynamoDBKeyStore) Put(ctx context.Context, key *APIKey) error {
+	record := DynamoDBAPIKeyRecord{
+		PK:            key.Hash,
+		ID:            key.ID,
+		Name:          key.Name,
+		TenantID:      key.TenantID,
+		Scopes:        key.Scopes,
+		RateLimitTier: key.RateLimitTier,
+		CreatedAt:     key.CreatedAt,
+		RotatedTo:     key.RotatedTo,
+	}
+
+	// Keep dead keys for a day so "invalid key" investigations can find them
+	end := key.ExpiresAt
+	if !key.ExpiresAt.IsZero() {
+		record.ExpiresAt = key.ExpiresAt.Unix()
+	}
+	if !key.RevokedAt.IsZero() {
+		record.RevokedAt = key.RevokedAt.Unix()
+		if end.IsZero() || key.RevokedAt.Before(end) {
+			end = key.RevokedAt
+		}
+	}
+	if !end.IsZero() {
+		record.TTL = end.Add(24 * time.Hour).Unix()
+	}
+
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	}
+
+	_, err = d.client.PutItem(ctx, input)
+	return err
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createAPIKeyTestContext creates a test context with the given headers and query
+func createAPIKeyTestContext(headers, query map[string]string) *lift.Context {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	if query == nil {
+		query = make(map[string]string)
+	}
+	adapterReq := &adapters.Request{
+		Method:      "GET",
+		Path:        "/orders",
+		Headers:     headers,
+		QueryParams: query,
+		PathParams:  make(map[string]string),
+	}
+	return lift.NewContext(context.Background(), lift.NewRequest(adapterReq))
+}
+
+// failingKeyStore fails every lookup
+type failingKeyStore struct{ MemoryKeyStore }
+
+func (f *failingKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	return nil, errors.New("throttled")
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	store := NewMemoryKeyStore()
+	secret, key, err := IssueAPIKey(context.Background(), store, "sk_test", APIKey{
+		TenantID:      "tenant-1",
+		Scopes:        []string{"orders:read"},
+		RateLimitTier: "gold",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, secret, "sk_test_")
+	assert.Equal(t, HashAPIKey(secret), key.Hash)
+
+	var seen *lift.Context
+	handler := APIKeyAuth(APIKeyAuthConfig{Store: store, QueryParam: "api_key"})(
+		lift.HandlerFunc(func(ctx *lift.Context) error {
+			seen = ctx
+			return nil
+		}))
+
+	t.Run("attaches key metadata", func(t *testing.T) {
+		require.NoError(t, handler.Handle(createAPIKeyTestContext(map[string]string{"X-API-Key": secret}, nil)))
+
+		require.NotNil(t, APIKeyFromContext(seen))
+		assert.Equal(t, key.ID, APIKeyFromContext(seen).ID)
+		assert.Equal(t, "gold", APIKeyFromContext(seen).RateLimitTier)
+		assert.Equal(t, "tenant-1", seen.TenantID())
+
+		principal, _ := seen.Get("principal").(*security.Principal)
+		require.NotNil(t, principal)
+		assert.Equal(t, "api_key", principal.AuthMethod)
+		assert.True(t, principal.HasScope("orders:read"))
+	})
+
+	t.Run("accepts the query parameter", func(t *testing.T) {
+		require.NoError(t, handler.Handle(createAPIKeyTestContext(nil, map[string]string{"api_key": secret})))
+		assert.Equal(t, key.ID, APIKeyFromContext(seen).ID)
+	})
+
+	t.Run("rejects missing and unknown keys", func(t *testing.T) {
+		err := handler.Handle(createAPIKeyTestContext(nil, nil))
+		require.Error(t, err)
+		assert.Equal(t, 401, err.(*lift.LiftError).StatusCode)
+
+		err = handler.Handle(createAPIKeyTestContext(map[string]string{"X-API-Key": "sk_test_unknown"}, nil))
+		require.Error(t, err)
+		assert.Equal(t, 401, err.(*lift.LiftError).StatusCode)
+	})
+
+	t.Run("optional allows anonymous requests", func(t *testing.T) {
+		optional := APIKeyAuth(APIKeyAuthConfig{Store: store, Optional: true})(lift.HandlerFunc(func(ctx *lift.Context) error {
+			assert.Nil(t, APIKeyFromContext(ctx))
+			return nil
+		}))
+		require.NoError(t, optional.Handle(createAPIKeyTestContext(nil, nil)))
+
+		err := optional.Handle(createAPIKeyTestContext(map[string]string{"X-API-Key": "sk_test_unknown"}, nil))
+		require.Error(t, err)
+	})
+
+	t.Run("rejects revoked keys", func(t *testing.T) {
+		_, revoked, err := IssueAPIKey(context.Background(), store, "", APIKey{TenantID: "tenant-1"})
+		require.NoError(t, err)
+		require.NoError(t, RevokeAPIKey(context.Background(), store, revoked.Hash))
+
+		stored, err := store.Lookup(context.Background(), revoked.Hash)
+		require.NoError(t, err)
+		assert.False(t, stored.IsActive(time.Now()))
+		assert.ErrorIs(t, RevokeAPIKey(context.Background(), store, "missing"), ErrAPIKeyNotFound)
+	})
+
+	t.Run("store failures return 503", func(t *testing.T) {
+		failing := APIKeyAuth(APIKeyAuthConfig{Store: &failingKeyStore{}})(handler)
+		err := failing.Handle(createAPIKeyTestContext(map[string]string{"X-API-Key": secret}, nil))
+		require.Error(t, err)
+		assert.Equal(t, 503, err.(*lift.LiftError).StatusCode)
+	})
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	store := NewMemoryKeyStore()
+	oldSecret, oldKey, err := IssueAPIKey(context.Background(), store, "sk", APIKey{
+		TenantID: "tenant-1",
+		Scopes:   []string{"orders:read"},
+	})
+	require.NoError(t, err)
+
+	newSecret, newKey, err := RotateAPIKey(context.Background(), store, "sk", oldKey, time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldSecret, newSecret)
+	assert.Equal(t, oldKey.TenantID, newKey.TenantID)
+	assert.Equal(t, oldKey.Scopes, newKey.Scopes)
+	assert.True(t, newKey.ExpiresAt.IsZero())
+
+	retired, err := store.Lookup(context.Background(), oldKey.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, newKey.ID, retired.RotatedTo)
+
+	now := time.Now()
+	for _, tt := range []struct {
+		name   string
+		now    time.Time
+		secret string
+		ok     bool
+	}{
+		{name: "old key during grace window", now: now.Add(30 * time.Minute), secret: oldSecret, ok: true},
+		{name: "old key after grace window", now: now.Add(2 * time.Hour), secret: oldSecret, ok: false},
+		{name: "new key after grace window", now: now.Add(2 * time.Hour), secret: newSecret, ok: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := APIKeyAuth(APIKeyAuthConfig{
+				Store: store,
+				Now:   func() time.Time { return tt.now },
+			})(lift.HandlerFunc(func(ctx *lift.Context) error { return nil }))
+
+			err := handler.Handle(createAPIKeyTestContext(map[string]string{"X-API-Key": tt.secret}, nil))
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRateLimitTierLimits(t *testing.T) {
+	limiter := &rateLimiter{config: RateLimitConfig{
+		DefaultLimit: 10,
+		TierLimits:   map[string]int{"gold": 1000},
+	}}
+
+	ctx := createAPIKeyTestContext(nil, nil)
+	assert.Equal(t, 10, limiter.getLimit(ctx))
+
+	ctx.Set(apiKeyContextKey, &APIKey{ID: "key_1", RateLimitTier: "gold"})
+	assert.Equal(t, 1000, limiter.getLimit(ctx))
+}
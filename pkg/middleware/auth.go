@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -21,6 +24,22 @@ type JWTClaims struct {
 	AccountID string   `json:"account_id"`
 	Roles     []string `json:"roles"`
 	Scopes    []string `json:"scopes"`
+	SessionID string   `json:"sid,omitempty"`
+	// ACR, AMR and AuthTime describe how the user authenticated (OIDC)
+	ACR      string           `json:"acr,omitempty"`
+	AMR      []string         `json:"amr,omitempty"`
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	// Actor is who is acting as the subject of an impersonation token
+	// (RFC 8693)
+	Actor *ActorClaims `json:"act,omitempty"`
+}
+
+// ActorClaims identify the actor of an impersonation token
+type ActorClaims struct {
+	Subject         string `json:"sub"`
+	TenantID        string `json:"tenant_id,omitempty"`
+	ImpersonationID string `json:"imp_id,omitempty"`
+	ReasonCode      string `json:"reason_code,omitempty"`
 }
 
 // JWTValidator handles JWT token validation
@@ -28,6 +47,8 @@ type JWTValidator struct {
 	config    security.JWTConfig
 	publicKey *rsa.PublicKey
 	secretKey []byte
+	jwks      *security.JWKS
+	methods   []string
 }
 
 // NewJWTValidator creates a new JWT validator
@@ -36,6 +57,27 @@ func NewJWTValidator(config security.JWTConfig) (*JWTValidator, error) {
 		config: config,
 	}
 
+	if config.JWKSURL != "" {
+		switch config.SigningMethod {
+		case "":
+			validator.methods = []string{"RS256", "ES256"}
+		case "RS256", "ES256":
+			validator.methods = []string{config.SigningMethod}
+		default:
+			return nil, fmt.Errorf("unsupported signing method for JWKS: %s", config.SigningMethod)
+		}
+
+		jwks, err := security.NewJWKS(security.JWKSConfig{
+			URL:      config.JWKSURL,
+			CacheTTL: config.JWKSCacheTTL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		validator.jwks = jwks
+		return validator, nil
+	}
+
 	// Load keys based on signing method
 	switch config.SigningMethod {
 	case "RS256":
@@ -64,7 +106,21 @@ func NewJWTValidator(config security.JWTConfig) (*JWTValidator, error) {
 
 // ValidateToken validates a JWT token and returns the claims
 func (v *JWTValidator) ValidateToken(tokenString string) (*JWTClaims, error) {
+	return v.ValidateTokenContext(context.Background(), tokenString)
+}
+
+// ValidateTokenContext validates a JWT token and returns the claims; ctx
+// bounds any JWKS fetch
+func (v *JWTValidator) ValidateTokenContext(ctx context.Context, tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (any, error) {
+		if v.jwks != nil {
+			if !slices.Contains(v.methods, token.Method.Alg()) {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			kid, _ := token.Header["kid"].(string)
+			return v.jwks.Key(ctx, kid)
+		}
+
 		// Verify signing method
 		switch v.config.SigningMethod {
 		case "RS256":
@@ -95,6 +151,11 @@ func (v *JWTValidator) ValidateToken(tokenString string) (*JWTClaims, error) {
 		return nil, fmt.Errorf("invalid token claims")
 	}
 
+	// Map configured claims and run custom validators
+	if err := v.applyClaimConfig(tokenString, claims); err != nil {
+		return nil, err
+	}
+
 	// Validate standard claims
 	if err := v.validateStandardClaims(claims); err != nil {
 		return nil, err
@@ -108,6 +169,33 @@ func (v *JWTValidator) ValidateToken(tokenString string) (*JWTClaims, error) {
 	return claims, nil
 }
 
+// applyClaimConfig reads the user and tenant IDs from the configured claims
+// and runs the claim validators. The signature has been verified by now,
+// so the claims are simply decoded again as a map.
+func (v *JWTValidator) applyClaimConfig(tokenString string, claims *JWTClaims) error {
+	if v.config.UserIDClaim == "" && v.config.TenantIDClaim == "" && len(v.config.ClaimValidators) == 0 {
+		return nil
+	}
+
+	all := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, all); err != nil {
+		return fmt.Errorf("invalid token claims: %w", err)
+	}
+	if v.config.UserIDClaim != "" {
+		claims.Subject, _ = all[v.config.UserIDClaim].(string)
+	}
+	if v.config.TenantIDClaim != "" {
+		claims.TenantID, _ = all[v.config.TenantIDClaim].(string)
+	}
+
+	for _, validate := range v.config.ClaimValidators {
+		if err := validate(all); err != nil {
+			return fmt.Errorf("claim validation failed: %w", err)
+		}
+	}
+	return nil
+}
+
 // validateStandardClaims validates the standard JWT claims
 func (v *JWTValidator) validateStandardClaims(claims *JWTClaims) error {
 	now := time.Now()
@@ -179,7 +267,17 @@ func (v *JWTValidator) validateCustomClaims(claims *JWTClaims) error {
 	return nil
 }
 
-// JWT creates JWT authentication middleware
+// JWT creates JWT authentication middleware. Tokens are verified with a
+// key file, a shared secret or, given config.JWKSURL, the issuer's
+// published keys:
+//
+//	app.Use(middleware.JWT(security.JWTConfig{
+//		JWKSURL:       "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_abc123/.well-known/jwks.json",
+//		Issuer:        "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_abc123",
+//		TenantIDClaim: "custom:tenant_id",
+//	}))
+//
+// The verified user and tenant IDs become ctx.UserID() and ctx.TenantID().
 func JWT(config security.JWTConfig) lift.Middleware {
 	validator, err := NewJWTValidator(config)
 	if err != nil {
@@ -199,7 +297,7 @@ func JWT(config security.JWTConfig) lift.Middleware {
 			}
 
 			// Validate token
-			claims, err := validator.ValidateToken(token)
+			claims, err := validator.ValidateTokenContext(ctx.Context, token)
 			if err != nil {
 				return lift.Unauthorized(fmt.Sprintf("Invalid token: %v", err))
 			}
@@ -209,6 +307,10 @@ func JWT(config security.JWTConfig) lift.Middleware {
 				return lift.AuthorizationError("Tenant ID is required")
 			}
 
+			if err := checkRevocation(ctx, config.Revocation, revocationSubject(claims)); err != nil {
+				return err
+			}
+
 			// Create principal from claims
 			principal := createPrincipalFromClaims(claims, ctx)
 
@@ -220,6 +322,7 @@ func JWT(config security.JWTConfig) lift.Middleware {
 				ctx.Logger = ctx.Logger.WithField("user_id", principal.UserID).
 					WithField("tenant_id", principal.TenantID).
 					WithField("auth_method", "jwt")
+				ctx.Logger = withActorFields(ctx.Logger, principal)
 			}
 
 			return next.Handle(ctx)
@@ -250,10 +353,17 @@ func JWTOptional(config security.JWTConfig) lift.Middleware {
 				principal = security.AnonymousPrincipal()
 			} else {
 				// Validate token
-				claims, err := validator.ValidateToken(token)
+				claims, err := validator.ValidateTokenContext(ctx.Context, token)
 				if err != nil {
 					// Invalid token, use anonymous principal
 					principal = security.AnonymousPrincipal()
+				} else if err := checkRevocation(ctx, config.Revocation, revocationSubject(claims)); err != nil {
+					// Revoked tokens are treated as invalid, but an
+					// unreachable revocation list still fails the request
+					if !errors.Is(err, security.ErrTokenRevoked) {
+						return err
+					}
+					principal = security.AnonymousPrincipal()
 				} else {
 					// Valid token, create principal from claims
 					principal = createPrincipalFromClaims(claims, ctx)
@@ -268,6 +378,7 @@ func JWTOptional(config security.JWTConfig) lift.Middleware {
 				ctx.Logger = ctx.Logger.WithField("user_id", principal.UserID).
 					WithField("tenant_id", principal.TenantID).
 					WithField("auth_method", principal.AuthMethod)
+				ctx.Logger = withActorFields(ctx.Logger, principal)
 			}
 
 			return next.Handle(ctx)
@@ -350,6 +461,43 @@ func extractBearerToken(ctx *lift.Context) string {
 	return strings.TrimPrefix(authHeader, bearerPrefix)
 }
 
+// checkRevocation rejects tokens on the revocation list. Revoked tokens
+// fail with 401; a list that can't be consulted fails with 503.
+func checkRevocation(ctx *lift.Context, revocations *security.Revocations, subject security.RevocationSubject) error {
+	if revocations == nil {
+		return nil
+	}
+	err := revocations.Check(ctx.Context, subject)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, security.ErrTokenRevoked):
+		return lift.Unauthorized("Token has been revoked").WithCause(err)
+	}
+
+	if ctx.Logger != nil {
+		ctx.Logger.Error("Token revocation check failed", map[string]any{
+			"user_id": subject.UserID,
+			"error":   err.Error(),
+		})
+	}
+	return lift.NewLiftError("REVOCATION_UNAVAILABLE", "Unable to verify token status", 503).WithCause(err)
+}
+
+// revocationSubject identifies validated claims for revocation checks
+func revocationSubject(claims *JWTClaims) security.RevocationSubject {
+	subject := security.RevocationSubject{
+		TokenID:   claims.ID,
+		SessionID: claims.SessionID,
+		UserID:    claims.Subject,
+		TenantID:  claims.TenantID,
+	}
+	if claims.IssuedAt != nil {
+		subject.IssuedAt = claims.IssuedAt.Time
+	}
+	return subject
+}
+
 // createPrincipalFromClaims creates a Principal from JWT claims
 func createPrincipalFromClaims(claims *JWTClaims, ctx *lift.Context) *security.Principal {
 	var expiresAt time.Time
@@ -364,19 +512,51 @@ func createPrincipalFromClaims(claims *JWTClaims, ctx *lift.Context) *security.P
 		issuedAt = time.Now()
 	}
 
+	authContext := security.AuthContext{ACR: claims.ACR, AMR: claims.AMR}
+	if claims.AuthTime != nil {
+		authContext.AuthTime = claims.AuthTime.Time
+	}
+
+	var actor *security.Actor
+	if claims.Actor != nil && claims.Actor.Subject != "" {
+		actor = &security.Actor{
+			UserID:          claims.Actor.Subject,
+			TenantID:        claims.Actor.TenantID,
+			ImpersonationID: claims.Actor.ImpersonationID,
+			ReasonCode:      claims.Actor.ReasonCode,
+		}
+	}
+
 	return &security.Principal{
-		UserID:     claims.Subject,
-		TenantID:   claims.TenantID,
-		AccountID:  claims.AccountID,
-		Roles:      claims.Roles,
-		Scopes:     claims.Scopes,
-		AuthMethod: "jwt",
-		IssuedAt:   issuedAt,
-		ExpiresAt:  expiresAt,
-		IPAddress:  ctx.Header("X-Real-IP"),
-		UserAgent:  ctx.Header("User-Agent"),
-		RequestID:  ctx.RequestID,
+		UserID:      claims.Subject,
+		TenantID:    claims.TenantID,
+		AccountID:   claims.AccountID,
+		Roles:       claims.Roles,
+		Scopes:      claims.Scopes,
+		AuthMethod:  "jwt",
+		IssuedAt:    issuedAt,
+		ExpiresAt:   expiresAt,
+		SessionID:   claims.SessionID,
+		AuthContext: authContext,
+		Actor:       actor,
+		IPAddress:   ctx.Header("X-Real-IP"),
+		UserAgent:   ctx.Header("User-Agent"),
+		RequestID:   ctx.RequestID,
+	}
+}
+
+// withActorFields tags the logger with the actor of an impersonated
+// principal, so every log line names both identities
+func withActorFields(logger lift.Logger, principal *security.Principal) lift.Logger {
+	if principal.Actor == nil {
+		return logger
 	}
+	return logger.WithFields(map[string]any{
+		"actor_id":         principal.Actor.UserID,
+		"actor_tenant_id":  principal.Actor.TenantID,
+		"impersonation_id": principal.Actor.ImpersonationID,
+		"reason_code":      principal.Actor.ReasonCode,
+	})
 }
 
 // loadRSAPublicKey loads an RSA public key from a PEM file
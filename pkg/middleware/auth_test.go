@@ -1,6 +1,19 @@
 package middleware
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -173,3 +186,167 @@ func createTestToken(t *testing.T, config security.JWTConfig, claims map[string]
 
 	return tokenString
 }
+
+func TestJWTRejectsRevokedTokens(t *testing.T) {
+	config := security.JWTConfig{
+		SigningMethod: "HS256",
+		SecretKey:     "test-secret",
+		Issuer:        "test-issuer",
+		Audience:      []string{"test-audience"},
+	}
+	revocations, err := security.NewRevocations(security.RevocationConfig{List: security.NewMemoryRevocationList()})
+	if err != nil {
+		t.Fatalf("Failed to create revocations: %v", err)
+	}
+	config.Revocation = revocations
+
+	handler := JWT(config)(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return ctx.OK(nil)
+	}))
+	call := func(token string) error {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Headers: map[string]string{"Authorization": "Bearer " + token},
+		}))
+		return handler.Handle(ctx)
+	}
+
+	token := createTestToken(t, config, map[string]any{
+		"sub":       "user123",
+		"tenant_id": "tenant1",
+		"sid":       "session1",
+		"iat":       time.Now().Add(-time.Minute).Unix(),
+	})
+	if err := call(token); err != nil {
+		t.Fatalf("Expected valid token, got error: %v", err)
+	}
+
+	if err := revocations.RevokeSession(context.Background(), "session1"); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+	err = call(token)
+	var liftErr *lift.LiftError
+	if !errors.As(err, &liftErr) || liftErr.StatusCode != 401 {
+		t.Errorf("Expected 401 for revoked token, got %v", err)
+	}
+
+	if err := revocations.SuspendTenant(context.Background(), "tenant1"); err != nil {
+		t.Fatalf("Failed to suspend tenant: %v", err)
+	}
+	fresh := createTestToken(t, config, map[string]any{"sub": "user456", "tenant_id": "tenant1"})
+	if err := call(fresh); !errors.As(err, &liftErr) || liftErr.StatusCode != 401 {
+		t.Errorf("Expected 401 for suspended tenant, got %v", err)
+	}
+}
+
+func TestJWTWithJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		x, y := make([]byte, 32), make([]byte, 32)
+		ecKey.X.FillBytes(x)
+		ecKey.Y.FillBytes(y)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa-1", "use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec-1", "use": "sig", "crv": "P-256",
+				"x": base64.RawURLEncoding.EncodeToString(x),
+				"y": base64.RawURLEncoding.EncodeToString(y),
+			},
+		}})
+	}))
+	defer server.Close()
+
+	config := security.JWTConfig{
+		JWKSURL:       server.URL,
+		Issuer:        "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_abc123",
+		TenantIDClaim: "custom:tenant_id",
+		ClaimValidators: []func(claims map[string]any) error{
+			func(claims map[string]any) error {
+				if claims["token_use"] != "access" {
+					return fmt.Errorf("token_use must be access")
+				}
+				return nil
+			},
+		},
+	}
+	sign := func(method jwt.SigningMethod, kid string, key any, overrides jwt.MapClaims) string {
+		claims := jwt.MapClaims{
+			"iss":              config.Issuer,
+			"sub":              "user123",
+			"custom:tenant_id": "tenant1",
+			"token_use":        "access",
+			"iat":              time.Now().Unix(),
+			"exp":              time.Now().Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return signed
+	}
+
+	var userID, tenantID string
+	handler := JWT(config)(lift.HandlerFunc(func(ctx *lift.Context) error {
+		userID, tenantID = ctx.UserID(), ctx.TenantID()
+		return ctx.OK(nil)
+	}))
+	call := func(token string) error {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Headers: map[string]string{"Authorization": "Bearer " + token},
+		}))
+		return handler.Handle(ctx)
+	}
+
+	for name, token := range map[string]string{
+		"RS256": sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, nil),
+		"ES256": sign(jwt.SigningMethodES256, "ec-1", ecKey, nil),
+	} {
+		userID, tenantID = "", ""
+		if err := call(token); err != nil {
+			t.Fatalf("%s: expected valid token, got error: %v", name, err)
+		}
+		if userID != "user123" || tenantID != "tenant1" {
+			t.Errorf("%s: got user %q and tenant %q, want claims mapped into the context", name, userID, tenantID)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected the key set to be fetched once, got %d fetches", fetches.Load())
+	}
+
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": config.Issuer, "sub": "user123"})
+	forged, _ := hmac.SignedString([]byte("guessed"))
+	for name, token := range map[string]string{
+		"HMAC token":         forged,
+		"key of other type":  sign(jwt.SigningMethodRS256, "ec-1", rsaKey, nil),
+		"failed validator":   sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{"token_use": "id"}),
+		"wrong issuer":       sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{"iss": "https://evil.example"}),
+		"missing tenant key": sign(jwt.SigningMethodES256, "ec-2", ecKey, nil),
+	} {
+		var liftErr *lift.LiftError
+		if err := call(token); !errors.As(err, &liftErr) || liftErr.StatusCode != 401 {
+			t.Errorf("%s: expected 401, got %v", name, err)
+		}
+	}
+
+	if _, err := NewJWTValidator(security.JWTConfig{JWKSURL: server.URL, SigningMethod: "HS256"}); err == nil {
+		t.Error("Expected HS256 with a JWKS to be rejected")
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// BodyLimitConfig configures BodyLimit
+type BodyLimitConfig struct {
+	// MaxSize is the largest body accepted, after decompression (default: 1MB)
+	MaxSize int64
+	// MaxCompressedSize is the largest compressed body accepted (default: MaxSize)
+	MaxCompressedSize int64
+	// DisableDecompression passes compressed bodies through unchanged
+	DisableDecompression bool
+}
+
+// BodyLimit rejects request bodies larger than MaxSize with 413 and
+// decompresses bodies sent with a gzip or deflate Content-Encoding, so
+// ParseRequest and later middleware see the plain body. API Gateway has
+// already decoded base64 bodies by the time middleware runs. The size
+// limit applies to the decompressed body, so small compressed payloads
+// can't expand without bound.
+func BodyLimit(config BodyLimitConfig) lift.Middleware {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 1024 * 1024
+	}
+	if config.MaxCompressedSize <= 0 {
+		config.MaxCompressedSize = config.MaxSize
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if ctx.Request == nil || len(ctx.Request.Body) == 0 {
+				return next.Handle(ctx)
+			}
+
+			encoding := strings.TrimSpace(strings.ToLower(ctx.Request.GetHeader("Content-Encoding")))
+			if encoding == "" || encoding == "identity" || config.DisableDecompression {
+				if int64(len(ctx.Request.Body)) > config.MaxSize {
+					return bodyTooLarge(config.MaxSize)
+				}
+				return next.Handle(ctx)
+			}
+
+			if int64(len(ctx.Request.Body)) > config.MaxCompressedSize {
+				return bodyTooLarge(config.MaxCompressedSize)
+			}
+
+			body, err := decompressBody(ctx.Request.Body, encoding, config.MaxSize)
+			if err != nil {
+				return err
+			}
+
+			ctx.Request.Body = body
+			if ctx.Request.Request != nil {
+				ctx.Request.Request.Body = body
+			}
+			for name := range ctx.Request.Headers {
+				switch strings.ToLower(name) {
+				case "content-encoding":
+					delete(ctx.Request.Headers, name)
+				case "content-length":
+					ctx.Request.Headers[name] = strconv.Itoa(len(body))
+				}
+			}
+
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// decompressBody removes each encoding in a Content-Encoding list, last
+// applied first, reading at most maxSize bytes
+func decompressBody(body []byte, encoding string, maxSize int64) ([]byte, error) {
+	codings := strings.Split(encoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		var reader io.Reader
+		var err error
+		switch coding := strings.TrimSpace(codings[i]); coding {
+		case "identity":
+			continue
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(bytes.NewReader(body))
+		case "deflate":
+			reader, err = deflateReader(body)
+		default:
+			return nil, lift.NewLiftError("UNSUPPORTED_CONTENT_ENCODING",
+				fmt.Sprintf("Content-Encoding %q is not supported", coding), 415)
+		}
+		if err != nil {
+			return nil, invalidBodyEncoding(err)
+		}
+
+		body, err = io.ReadAll(io.LimitReader(reader, maxSize+1))
+		if err != nil {
+			return nil, invalidBodyEncoding(err)
+		}
+		if int64(len(body)) > maxSize {
+			return nil, bodyTooLarge(maxSize)
+		}
+	}
+	return body, nil
+}
+
+// deflateReader reads a "deflate" body, which should be zlib-wrapped but
+// is sent as raw deflate by some clients
+func deflateReader(body []byte) (io.Reader, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(body))
+	if errors.Is(err, zlib.ErrHeader) {
+		return flate.NewReader(bytes.NewReader(body)), nil
+	}
+	return reader, err
+}
+
+func bodyTooLarge(maxSize int64) *lift.LiftError {
+	return lift.NewLiftError("PAYLOAD_TOO_LARGE", fmt.Sprintf("Request body exceeds %d bytes", maxSize), 413)
+}
+
+func invalidBodyEncoding(err error) *lift.LiftError {
+	return lift.NewLiftError("INVALID_CONTENT_ENCODING", "Request body does not match its Content-Encoding", 400).WithCause(err)
+}
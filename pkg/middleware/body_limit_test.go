@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compressBody(t *testing.T, encoding string, body []byte) []byte {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		var err error
+		writer, err = flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+	}
+	_, err := writer.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func createBodyTestContext(body []byte, headers map[string]string) *lift.Context {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	return lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:      "POST",
+		Path:        "/orders",
+		Headers:     headers,
+		QueryParams: make(map[string]string),
+		PathParams:  make(map[string]string),
+		Body:        body,
+	}))
+}
+
+func TestBodyLimit(t *testing.T) {
+	var parsed map[string]string
+	handler := BodyLimit(BodyLimitConfig{MaxSize: 64})(lift.HandlerFunc(func(ctx *lift.Context) error {
+		parsed = nil
+		return ctx.ParseRequest(&parsed)
+	}))
+	payload := []byte(`{"order":"ord_1"}`)
+
+	for _, tt := range []struct {
+		name     string
+		header   string
+		encoding string
+	}{
+		{name: "gzip", header: "gzip", encoding: "gzip"},
+		{name: "zlib deflate", header: "deflate", encoding: "deflate"},
+		{name: "raw deflate", header: "deflate", encoding: "raw-deflate"},
+	} {
+		t.Run("decompresses "+tt.name, func(t *testing.T) {
+			ctx := createBodyTestContext(compressBody(t, tt.encoding, payload), map[string]string{
+				"content-encoding": tt.header,
+				"Content-Length":   "99",
+			})
+			require.NoError(t, handler.Handle(ctx))
+			assert.Equal(t, "ord_1", parsed["order"])
+			assert.Empty(t, ctx.Request.GetHeader("Content-Encoding"))
+			assert.Equal(t, "17", ctx.Request.GetHeader("Content-Length"))
+		})
+	}
+
+	t.Run("passes plain bodies through", func(t *testing.T) {
+		require.NoError(t, handler.Handle(createBodyTestContext(payload, nil)))
+		assert.Equal(t, "ord_1", parsed["order"])
+	})
+
+	t.Run("rejects large bodies", func(t *testing.T) {
+		large := []byte(`{"order":"` + strings.Repeat("x", 100) + `"}`)
+		err := handler.Handle(createBodyTestContext(large, nil))
+		require.Error(t, err)
+		assert.Equal(t, 413, err.(*lift.LiftError).StatusCode)
+
+		// A small compressed body is measured after decompression
+		compressed := compressBody(t, "gzip", large)
+		require.Less(t, len(compressed), 64)
+		err = handler.Handle(createBodyTestContext(compressed, map[string]string{"Content-Encoding": "gzip"}))
+		require.Error(t, err)
+		assert.Equal(t, 413, err.(*lift.LiftError).StatusCode)
+	})
+
+	t.Run("rejects corrupt and unsupported encodings", func(t *testing.T) {
+		err := handler.Handle(createBodyTestContext(payload, map[string]string{"Content-Encoding": "gzip"}))
+		require.Error(t, err)
+		assert.Equal(t, 400, err.(*lift.LiftError).StatusCode)
+
+		err = handler.Handle(createBodyTestContext(payload, map[string]string{"Content-Encoding": "br"}))
+		require.Error(t, err)
+		assert.Equal(t, 415, err.(*lift.LiftError).StatusCode)
+	})
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/observability"
+)
+
+// Breadcrumbs records the request's log entries, up to max (default: 50), so
+// errors reported through observability.ErrorObserver carry the entries
+// leading up to them. Place it after RequestID and auth middleware so their
+// logs are included.
+func Breadcrumbs(max int) Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			observability.WithBreadcrumbs(ctx, max)
+			return next.Handle(ctx)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// BreakGlassOptions configures BreakGlassGuard
+type BreakGlassOptions struct {
+	// RequireReason also requires a justification on every request to a
+	// flagged route, in addition to the reason given for the grant
+	RequireReason bool
+	// ReasonHeader carries the per-request justification
+	// (default: "X-Access-Reason")
+	ReasonHeader string
+}
+
+// BreakGlassGuard requires an active break-glass grant for routes flagged
+// with lift.MetaBreakGlass, whose value names the scope the grant must
+// cover. Every access is recorded against the grant in the audit trail.
+//
+//	bg, _ := security.NewBreakGlass(security.BreakGlassConfig{Storage: auditStorage})
+//	app.Use(middleware.BreakGlassGuard(bg, middleware.BreakGlassOptions{RequireReason: true}))
+//	app.POST("/break-glass", middleware.BreakGlassGrantHandler(bg))
+//	app.GET("/patients/:id/records", getRecords).Meta(lift.MetaBreakGlass, "patient-records")
+//
+// Register it after authentication so the user and tenant are known.
+// Unflagged routes pass through untouched.
+func BreakGlassGuard(bg *security.BreakGlass, opts BreakGlassOptions) lift.Middleware {
+	if opts.ReasonHeader == "" {
+		opts.ReasonHeader = "X-Access-Reason"
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			scope, ok := ctx.RouteMeta(lift.MetaBreakGlass)
+			if !ok || scope == "" {
+				return next.Handle(ctx)
+			}
+			if ctx.UserID() == "" {
+				return lift.Unauthorized("Authentication required")
+			}
+
+			reason := strings.TrimSpace(ctx.Header(opts.ReasonHeader))
+			if opts.RequireReason && reason == "" {
+				return lift.NewLiftError("ACCESS_REASON_REQUIRED", "A reason is required to access this resource", 400).
+					WithDetail("header", opts.ReasonHeader)
+			}
+
+			grant, err := bg.ActiveGrant(ctx.Context, ctx.TenantID(), ctx.UserID(), scope)
+			if err != nil {
+				return lift.NewLiftError("BREAK_GLASS_CHECK_FAILED", "Failed to check break-glass access", 500).WithCause(err)
+			}
+			if grant == nil {
+				return lift.NewLiftError("BREAK_GLASS_REQUIRED", "Emergency access is required for this resource", 403).
+					WithDetail("scope", scope)
+			}
+
+			// Fail closed: access that can't be audited isn't allowed
+			if err := bg.RecordAccess(ctx.Context, grant, ctx.Request.Method, ctx.Request.Path, reason); err != nil {
+				return lift.NewLiftError("BREAK_GLASS_AUDIT_FAILED", "Failed to record break-glass access", 500).WithCause(err)
+			}
+
+			ctx.Set("break_glass_grant", grant)
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// breakGlassBody is the body accepted by BreakGlassGrantHandler
+type breakGlassBody struct {
+	Scope    string `json:"scope"`
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+// BreakGlassGrantHandler issues a grant to the authenticated user. The body
+// names the scope, a reason, and optionally a duration such as "30m":
+//
+//	{"scope": "patient-records", "reason": "ER admission, patient unconscious", "duration": "30m"}
+//
+// Restrict who may break glass with route metadata, e.g.
+// .Meta(lift.MetaRoles, "clinician").
+func BreakGlassGrantHandler(bg *security.BreakGlass) lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		if ctx.UserID() == "" {
+			return lift.Unauthorized("Authentication required")
+		}
+
+		var body breakGlassBody
+		if err := ctx.ParseRequest(&body); err != nil {
+			return err
+		}
+
+		var duration time.Duration
+		if body.Duration != "" {
+			parsed, err := time.ParseDuration(body.Duration)
+			if err != nil {
+				return lift.NewLiftError("INVALID_DURATION", "Duration must be like \"30m\"", 400).WithCause(err)
+			}
+			duration = parsed
+		}
+
+		ip, _ := security.ExtractClientIP(ctx.Request.Headers, ctx.Request.RequestContext())
+		grant, err := bg.Grant(ctx.Context, security.BreakGlassRequest{
+			TenantID:  ctx.TenantID(),
+			UserID:    ctx.UserID(),
+			Scope:     body.Scope,
+			Reason:    body.Reason,
+			Duration:  duration,
+			IPAddress: ip,
+		})
+		switch {
+		case errors.Is(err, security.ErrBreakGlassReason), errors.Is(err, security.ErrBreakGlassRequest):
+			return lift.NewLiftError("INVALID_BREAK_GLASS_REQUEST", err.Error(), 400)
+		case err != nil:
+			return lift.NewLiftError("BREAK_GLASS_FAILED", "Failed to grant break-glass access", 500).WithCause(err)
+		}
+		return ctx.Created(grant)
+	})
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakGlassGuard(t *testing.T) {
+	storage := security.NewInMemoryAuditStorage()
+	bg, err := security.NewBreakGlass(security.BreakGlassConfig{Storage: storage})
+	require.NoError(t, err)
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.SetUserID(ctx.Header("X-User"))
+			ctx.SetTenantID("tenant-1")
+			return next.Handle(ctx)
+		})
+	})
+	app.Use(BreakGlassGuard(bg, BreakGlassOptions{RequireReason: true}))
+
+	ok := func(ctx *lift.Context) error { return ctx.Text("ok") }
+	app.GET("/patients/:id", ok)
+	app.GET("/patients/:id/records", ok).Meta(lift.MetaBreakGlass, "patient-records")
+	app.POST("/break-glass", BreakGlassGrantHandler(bg))
+
+	request := func(method, path, user, reason, body string) *lift.Context {
+		headers := map[string]string{"X-User": user}
+		if reason != "" {
+			headers["X-Access-Reason"] = reason
+		}
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  method,
+			Path:    path,
+			Headers: headers,
+			Body:    []byte(body),
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	assert.Equal(t, 200, request("GET", "/patients/42", "dr-1", "", "").Response.StatusCode)
+	assert.Equal(t, 401, request("GET", "/patients/42/records", "", "triage", "").Response.StatusCode)
+	assert.Equal(t, 400, request("GET", "/patients/42/records", "dr-1", "", "").Response.StatusCode)
+	assert.Equal(t, 403, request("GET", "/patients/42/records", "dr-1", "triage", "").Response.StatusCode)
+
+	assert.Equal(t, 400, request("POST", "/break-glass", "dr-1", "", `{"scope":"patient-records","reason":"now"}`).Response.StatusCode)
+	assert.Equal(t, 400, request("POST", "/break-glass", "dr-1", "", `{"scope":"patient-records","reason":"ER admission","duration":"soon"}`).Response.StatusCode)
+
+	granted := request("POST", "/break-glass", "dr-1", "", `{"scope":"patient-records","reason":"ER admission, patient unconscious","duration":"30m"}`)
+	require.Equal(t, 201, granted.Response.StatusCode)
+	grant := granted.Response.Body.(*security.BreakGlassGrant)
+	assert.Equal(t, "tenant-1", grant.TenantID)
+
+	assert.Equal(t, 200, request("GET", "/patients/42/records", "dr-1", "triage", "").Response.StatusCode)
+	assert.Equal(t, 403, request("GET", "/patients/42/records", "dr-2", "triage", "").Response.StatusCode)
+
+	accesses, err := storage.Query(context.Background(), security.AuditFilter{EntryType: security.BreakGlassAccessEntry})
+	require.NoError(t, err)
+	require.Len(t, accesses, 1)
+	assert.Equal(t, grant.ID, accesses[0].AuditID)
+	assert.Equal(t, "/patients/42/records", accesses[0].Request.Resource)
+	assert.Equal(t, "triage", accesses[0].Metadata["reason"])
+
+	body, err := json.Marshal(grant)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"scope":"patient-records"`)
+}
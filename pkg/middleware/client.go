@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// ClientMetadataOptions configures client metadata capture
+type ClientMetadataOptions struct {
+	// Extract overrides how metadata is read from the request (default: lift.ParseClientMetadata)
+	Extract func(ctx *lift.Context) *lift.ClientMetadata
+	// DisableLogFields stops client metadata from being added to the request logger
+	DisableLogFields bool
+}
+
+// ClientMetadata captures client SDK, version, platform and device metadata
+// into ctx.Client() and adds it to the request logger
+func ClientMetadata(opts ClientMetadataOptions) Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			var meta *lift.ClientMetadata
+			if opts.Extract != nil {
+				meta = opts.Extract(ctx)
+			}
+			if meta == nil {
+				meta = lift.ParseClientMetadata(ctx.Request.Headers)
+			}
+			ctx.SetClient(meta)
+
+			if !opts.DisableLogFields && ctx.Logger != nil {
+				if fields := meta.LogFields(); len(fields) > 0 {
+					ctx.Logger = ctx.Logger.WithFields(fields)
+				}
+			}
+
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// ClientVersionRule sets a minimum client version for matching requests
+type ClientVersionRule struct {
+	// PathPrefix limits the rule to paths with this prefix (empty matches all paths)
+	PathPrefix string
+	// Methods limits the rule to these HTTP methods (empty matches all methods)
+	Methods []string
+	// Platform limits the rule to one client platform (empty matches all platforms)
+	Platform string
+	// MinVersion is the lowest accepted client app version
+	MinVersion string
+}
+
+// ClientVersionPolicy configures minimum client version enforcement
+type ClientVersionPolicy struct {
+	Rules []ClientVersionRule
+	// RequireVersion rejects requests that don't report a client version
+	RequireVersion bool
+	// Message is returned to rejected clients (default: "Client version is no longer supported")
+	Message string
+}
+
+// MinimumClientVersion rejects requests from clients older than the policy allows
+// with 426 Upgrade Required. Apply it globally with path rules, or wrap
+// individual route handlers for per-route minimums.
+func MinimumClientVersion(policy ClientVersionPolicy) Middleware {
+	if policy.Message == "" {
+		policy.Message = "Client version is no longer supported"
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			client := ctx.Client()
+
+			for _, rule := range policy.Rules {
+				if !rule.matches(ctx, client) {
+					continue
+				}
+
+				if client.AppVersion == "" {
+					if !policy.RequireVersion {
+						continue
+					}
+				} else if lift.CompareVersions(client.AppVersion, rule.MinVersion) >= 0 {
+					continue
+				}
+
+				if ctx.Logger != nil {
+					ctx.Logger.Warn("Client version rejected", map[string]any{
+						"client_version":  client.AppVersion,
+						"client_platform": client.Platform,
+						"min_version":     rule.MinVersion,
+					})
+				}
+
+				return lift.NewLiftError("CLIENT_UPGRADE_REQUIRED", policy.Message, 426).
+					WithDetail("min_version", rule.MinVersion).
+					WithDetail("platform", client.Platform)
+			}
+
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// RequireClientVersion is shorthand for a single minimum version applied to every platform
+func RequireClientVersion(minVersion string) Middleware {
+	return MinimumClientVersion(ClientVersionPolicy{
+		Rules: []ClientVersionRule{{MinVersion: minVersion}},
+	})
+}
+
+func (r ClientVersionRule) matches(ctx *lift.Context, client *lift.ClientMetadata) bool {
+	if r.PathPrefix != "" && !strings.HasPrefix(ctx.Request.Path, r.PathPrefix) {
+		return false
+	}
+	if r.Platform != "" && !strings.EqualFold(r.Platform, client.Platform) {
+		return false
+	}
+	if len(r.Methods) > 0 {
+		matched := false
+		for _, method := range r.Methods {
+			if strings.EqualFold(method, ctx.Request.Method) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
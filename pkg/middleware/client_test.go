@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createClientTestContext(method, path string, headers map[string]string) *lift.Context {
+	adapterReq := &adapters.Request{
+		Method:      method,
+		Path:        path,
+		Headers:     headers,
+		QueryParams: make(map[string]string),
+		PathParams:  make(map[string]string),
+	}
+	return lift.NewContext(context.Background(), lift.NewRequest(adapterReq))
+}
+
+func TestClientMetadataMiddleware(t *testing.T) {
+	ctx := createClientTestContext("GET", "/payments", map[string]string{
+		"X-Client-Platform": "android",
+		"X-Device-ID":       "device_123",
+	})
+
+	var seen *lift.ClientMetadata
+	handler := ClientMetadata(ClientMetadataOptions{})(lift.HandlerFunc(func(ctx *lift.Context) error {
+		seen = ctx.Client()
+		return nil
+	}))
+
+	require.NoError(t, handler.Handle(ctx))
+	require.NotNil(t, seen)
+	assert.Equal(t, "android", seen.Platform)
+	assert.Equal(t, "device_123", seen.AuditContext().DeviceID)
+}
+
+func TestMinimumClientVersion(t *testing.T) {
+	ok := lift.HandlerFunc(func(ctx *lift.Context) error { return nil })
+	handler := MinimumClientVersion(ClientVersionPolicy{
+		Rules: []ClientVersionRule{
+			{PathPrefix: "/payments", Platform: "ios", MinVersion: "5.0.0"},
+			{PathPrefix: "/payments", Methods: []string{"POST"}, MinVersion: "4.2"},
+		},
+	})(ok)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		headers  map[string]string
+		rejected bool
+	}{
+		{"old ios client", "GET", "/payments", map[string]string{"X-Client-Platform": "ios", "X-Client-Version": "4.9.0"}, true},
+		{"current ios client", "GET", "/payments", map[string]string{"X-Client-Platform": "ios", "X-Client-Version": "5.0.1"}, false},
+		{"old android read", "GET", "/payments", map[string]string{"X-Client-Platform": "android", "X-Client-Version": "4.0"}, false},
+		{"old android write", "POST", "/payments", map[string]string{"X-Client-Platform": "android", "X-Client-Version": "4.0"}, true},
+		{"other route", "GET", "/health", map[string]string{"X-Client-Platform": "ios", "X-Client-Version": "1.0"}, false},
+		{"unversioned client", "POST", "/payments", map[string]string{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := handler.Handle(createClientTestContext(tt.method, tt.path, tt.headers))
+			if !tt.rejected {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			liftErr := err.(*lift.LiftError)
+			assert.Equal(t, 426, liftErr.StatusCode)
+			assert.Equal(t, "CLIENT_UPGRADE_REQUIRED", liftErr.Code)
+		})
+	}
+}
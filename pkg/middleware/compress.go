@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// CompressConfig configures CompressWithConfig
+type CompressConfig struct {
+	// MinSize is the smallest body that is compressed, in bytes (default: 1KB)
+	MinSize int
+	// Level is the gzip compression level (default: gzip.DefaultCompression)
+	Level int
+	// SkipContentTypes are content type prefixes that are already compressed
+	// (default: images, audio, video, fonts and archives)
+	SkipContentTypes []string
+}
+
+// defaultSkipContentTypes are formats that don't shrink when gzipped
+var defaultSkipContentTypes = []string{
+	"image/", "audio/", "video/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-bzip2", "application/x-7z-compressed", "application/zstd",
+	"application/pdf",
+}
+
+// Compress gzips response bodies of 1KB or more for clients that send
+// Accept-Encoding: gzip. See CompressWithConfig.
+func Compress() lift.Middleware {
+	return CompressWithConfig(CompressConfig{})
+}
+
+// CompressWithConfig gzips response bodies of at least MinSize bytes for
+// clients that accept gzip. The compressed body is base64 encoded and the
+// response marked isBase64Encoded, which API Gateway decodes before sending
+// it on; REST APIs also need "*/*" in their binary media types. Error
+// responses returned as a LiftError are rendered after middleware runs, so
+// they're never compressed.
+func CompressWithConfig(config CompressConfig) lift.Middleware {
+	if config.MinSize <= 0 {
+		config.MinSize = 1024
+	}
+	if config.Level == 0 {
+		config.Level = gzip.DefaultCompression
+	}
+	if config.SkipContentTypes == nil {
+		config.SkipContentTypes = defaultSkipContentTypes
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if err := next.Handle(ctx); err != nil {
+				return err
+			}
+
+			resp := ctx.Response
+			if resp.Body == nil || resp.StatusCode == 204 || resp.StatusCode == 304 ||
+				resp.Headers["Content-Encoding"] != "" || skipContentType(resp.Headers["Content-Type"], config.SkipContentTypes) {
+				return nil
+			}
+
+			body, err := uncompressedBody(resp)
+			if err != nil || len(body) < config.MinSize {
+				return nil
+			}
+
+			// The response now depends on Accept-Encoding, whether or not
+			// this client gets it compressed
+			addVary(ctx, "Accept-Encoding")
+			if !acceptsGzip(ctx.Request.GetHeader("Accept-Encoding")) {
+				return nil
+			}
+
+			var buf bytes.Buffer
+			writer, err := gzip.NewWriterLevel(&buf, config.Level)
+			if err != nil {
+				return lift.NewLiftError("COMPRESSION_FAILED", "Failed to compress response", 500).WithCause(err)
+			}
+			if _, err := writer.Write(body); err != nil {
+				return lift.NewLiftError("COMPRESSION_FAILED", "Failed to compress response", 500).WithCause(err)
+			}
+			if err := writer.Close(); err != nil {
+				return lift.NewLiftError("COMPRESSION_FAILED", "Failed to compress response", 500).WithCause(err)
+			}
+			if buf.Len() >= len(body) {
+				return nil
+			}
+
+			resp.Body = base64.StdEncoding.EncodeToString(buf.Bytes())
+			resp.IsBase64Encoded = true
+			resp.Header("Content-Encoding", "gzip")
+			if _, ok := resp.Headers["Content-Length"]; ok {
+				resp.Headers["Content-Length"] = strconv.Itoa(buf.Len())
+			}
+			return nil
+		})
+	}
+}
+
+// uncompressedBody returns the bytes the client would receive
+func uncompressedBody(resp *lift.Response) ([]byte, error) {
+	if encoded, ok := resp.Body.(string); ok && resp.IsBase64Encoded {
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+	return responseBodyBytes(resp.Body)
+}
+
+// skipContentType reports whether the content type is already compressed
+func skipContentType(contentType string, skip []string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range skip {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "x-gzip" && coding != "*" {
+			continue
+		}
+		params = strings.ReplaceAll(strings.ToLower(params), " ", "")
+		if q, ok := strings.CutPrefix(params, "q="); ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createCompressTestContext(acceptEncoding string) *lift.Context {
+	headers := make(map[string]string)
+	if acceptEncoding != "" {
+		headers["accept-encoding"] = acceptEncoding
+	}
+	return lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:      "GET",
+		Path:        "/products",
+		Headers:     headers,
+		QueryParams: make(map[string]string),
+		PathParams:  make(map[string]string),
+	}))
+}
+
+func gunzipBody(t *testing.T, resp *lift.Response) string {
+	require.True(t, resp.IsBase64Encoded)
+	compressed, err := base64.StdEncoding.DecodeString(resp.Body.(string))
+	require.NoError(t, err)
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestCompress(t *testing.T) {
+	products := make([]map[string]string, 100)
+	for i := range products {
+		products[i] = map[string]string{"name": "Widget", "description": "A very ordinary widget"}
+	}
+	handler := Compress()(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return ctx.JSON(products)
+	}))
+
+	t.Run("compresses large JSON for gzip clients", func(t *testing.T) {
+		ctx := createCompressTestContext("br;q=1.0, gzip;q=0.8")
+		require.NoError(t, handler.Handle(ctx))
+
+		assert.Equal(t, "gzip", ctx.Response.Headers["Content-Encoding"])
+		assert.Equal(t, "Accept-Encoding", ctx.Response.Headers["Vary"])
+		assert.True(t, strings.HasPrefix(gunzipBody(t, ctx.Response), `[{"description":"A very ordinary widget"`))
+	})
+
+	t.Run("leaves other clients alone", func(t *testing.T) {
+		for _, header := range []string{"", "br", "gzip;q=0"} {
+			ctx := createCompressTestContext(header)
+			require.NoError(t, handler.Handle(ctx))
+			assert.Empty(t, ctx.Response.Headers["Content-Encoding"], header)
+			assert.False(t, ctx.Response.IsBase64Encoded)
+			assert.Equal(t, "Accept-Encoding", ctx.Response.Headers["Vary"])
+		}
+	})
+
+	t.Run("skips small bodies", func(t *testing.T) {
+		small := Compress()(lift.HandlerFunc(func(ctx *lift.Context) error {
+			return ctx.JSON(map[string]string{"status": "ok"})
+		}))
+		ctx := createCompressTestContext("gzip")
+		require.NoError(t, small.Handle(ctx))
+		assert.Empty(t, ctx.Response.Headers["Content-Encoding"])
+		assert.Empty(t, ctx.Response.Headers["Vary"])
+	})
+
+	t.Run("skips compressed content types", func(t *testing.T) {
+		image := Compress()(lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.Response.Header("Content-Type", "image/png")
+			ctx.Response.Body = bytes.Repeat([]byte{0}, 4096)
+			return nil
+		}))
+		ctx := createCompressTestContext("gzip")
+		require.NoError(t, image.Handle(ctx))
+		assert.Empty(t, ctx.Response.Headers["Content-Encoding"])
+	})
+
+	t.Run("compresses binary responses", func(t *testing.T) {
+		binary := Compress()(lift.HandlerFunc(func(ctx *lift.Context) error {
+			return ctx.Response.Binary([]byte(strings.Repeat("csv,row\n", 500)))
+		}))
+		ctx := createCompressTestContext("gzip")
+		require.NoError(t, binary.Handle(ctx))
+		assert.Equal(t, strings.Repeat("csv,row\n", 500), gunzipBody(t, ctx.Response))
+	})
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// CORSConfig configures cross-origin resource sharing
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins, e.g. "https://app.example.com".
+	// A "*" within an entry matches one or more characters, as in
+	// "https://*.example.com"; "*" alone allows any origin.
+	AllowOrigins []string
+	// AllowOriginPatterns are regular expressions matched against the whole
+	// origin, e.g. `https://pr-\d+\.preview\.example\.com`
+	AllowOriginPatterns []string
+	// AllowOriginFunc decides origins the lists don't allow, e.g. by
+	// looking them up in the tenant's configuration
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods are the methods preflights allow (default: GET, HEAD,
+	// POST, PUT, PATCH, DELETE)
+	AllowMethods []string
+	// AllowHeaders are the request headers preflights allow (default:
+	// Accept, Authorization, Content-Type, Idempotency-Key, X-Request-ID,
+	// X-Tenant-ID)
+	AllowHeaders []string
+	// ExposeHeaders are response headers browsers let scripts read
+	ExposeHeaders []string
+	// AllowCredentials lets browsers send cookies and authorization
+	// headers. Allowed origins are then echoed back, since browsers refuse
+	// credentialed responses that allow "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight results, rounded down
+	// to seconds; zero leaves it to the browser
+	MaxAge time.Duration
+}
+
+// corsPolicy is a CORSConfig prepared for matching, with its response
+// headers rendered once
+type corsPolicy struct {
+	anyOrigin bool
+	origins   map[string]bool
+	wildcards [][2]string
+	patterns  []*regexp.Regexp
+	allowFunc func(origin string) bool
+
+	credentials   bool
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+// CORS answers preflight requests and adds cross-origin headers to
+// responses for allowed origins. Preflights, OPTIONS requests with an
+// Access-Control-Request-Method header, are answered with 204 without
+// reaching the handler; other OPTIONS requests are handled as usual.
+// Responses to disallowed origins carry no CORS headers, so browsers block
+// them.
+//
+//	app.Use(middleware.CORS(middleware.CORSConfig{
+//		AllowOrigins:     []string{"https://app.example.com", "https://*.example.com"},
+//		ExposeHeaders:    []string{"X-Request-ID"},
+//		AllowCredentials: true,
+//		MaxAge:           10 * time.Minute,
+//	}))
+//
+// It panics if an origin pattern doesn't compile.
+func CORS(config CORSConfig) lift.Middleware {
+	policy := newCORSPolicy(config)
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			origin := ctx.Header("Origin")
+			preflight := ctx.Request.Method == "OPTIONS" && ctx.Header("Access-Control-Request-Method") != ""
+
+			if preflight {
+				addVary(ctx, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+				if origin != "" && policy.allows(origin) {
+					policy.setOrigin(ctx, origin)
+					ctx.Response.Header("Access-Control-Allow-Methods", policy.allowMethods)
+					ctx.Response.Header("Access-Control-Allow-Headers", policy.allowHeaders)
+					if policy.maxAge != "" {
+						ctx.Response.Header("Access-Control-Max-Age", policy.maxAge)
+					}
+				}
+				ctx.Response.Status(204)
+				return nil
+			}
+
+			if !policy.anyOrigin || policy.credentials {
+				// The response depends on the origin, so caches must too
+				addVary(ctx, "Origin")
+			}
+			if origin != "" && policy.allows(origin) {
+				policy.setOrigin(ctx, origin)
+				if policy.exposeHeaders != "" {
+					ctx.Response.Header("Access-Control-Expose-Headers", policy.exposeHeaders)
+				}
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+func newCORSPolicy(config CORSConfig) *corsPolicy {
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	}
+	if len(config.AllowHeaders) == 0 {
+		config.AllowHeaders = []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "X-Request-ID", "X-Tenant-ID"}
+	}
+
+	policy := &corsPolicy{
+		origins:       make(map[string]bool),
+		allowFunc:     config.AllowOriginFunc,
+		credentials:   config.AllowCredentials,
+		allowMethods:  strings.Join(config.AllowMethods, ", "),
+		allowHeaders:  strings.Join(config.AllowHeaders, ", "),
+		exposeHeaders: strings.Join(config.ExposeHeaders, ", "),
+	}
+	if seconds := int64(config.MaxAge / time.Second); seconds > 0 {
+		policy.maxAge = strconv.FormatInt(seconds, 10)
+	}
+
+	for _, origin := range config.AllowOrigins {
+		switch {
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(strings.ToLower(origin), "*")
+			policy.wildcards = append(policy.wildcards, [2]string{prefix, suffix})
+		default:
+			policy.origins[strings.ToLower(origin)] = true
+		}
+	}
+	for _, pattern := range config.AllowOriginPatterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			panic(fmt.Sprintf("invalid CORS origin pattern %q: %v", pattern, err))
+		}
+		policy.patterns = append(policy.patterns, re)
+	}
+	return policy
+}
+
+// allows reports whether an origin may make cross-origin requests
+func (p *corsPolicy) allows(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if p.origins[lower] {
+		return true
+	}
+	for _, wildcard := range p.wildcards {
+		prefix, suffix := wildcard[0], wildcard[1]
+		if len(lower) > len(prefix)+len(suffix) && strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return p.allowFunc != nil && p.allowFunc(origin)
+}
+
+func (p *corsPolicy) setOrigin(ctx *lift.Context, origin string) {
+	if p.anyOrigin && !p.credentials {
+		ctx.Response.Header("Access-Control-Allow-Origin", "*")
+		return
+	}
+	ctx.Response.Header("Access-Control-Allow-Origin", origin)
+	if p.credentials {
+		ctx.Response.Header("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// addVary adds to the response's Vary header rather than replacing it
+func addVary(ctx *lift.Context, value string) {
+	if existing := ctx.Response.Headers["Vary"]; existing != "" {
+		value = existing + ", " + value
+	}
+	ctx.Response.Header("Vary", value)
+}
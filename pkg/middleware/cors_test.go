@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func corsRequest(t *testing.T, middleware lift.Middleware, method string, headers map[string]string) (*lift.Context, bool) {
+	t.Helper()
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  method,
+		Path:    "/orders",
+		Headers: headers,
+	}))
+	reached := false
+	err := middleware(lift.HandlerFunc(func(ctx *lift.Context) error {
+		reached = true
+		return ctx.Text("ok")
+	})).Handle(ctx)
+	require.NoError(t, err)
+	return ctx, reached
+}
+
+func TestCORSOriginMatching(t *testing.T) {
+	cors := CORS(CORSConfig{
+		AllowOrigins:        []string{"https://app.example.com", "https://*.tenants.example.com"},
+		AllowOriginPatterns: []string{`https://pr-\d+\.preview\.example\.com`},
+		AllowOriginFunc: func(origin string) bool {
+			return origin == "https://partner.example.net"
+		},
+		ExposeHeaders: []string{"X-Request-ID", "X-RateLimit-Remaining"},
+	})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"https://acme.tenants.example.com", true},
+		{"https://.tenants.example.com", false},
+		{"https://tenants.example.com", false},
+		{"https://pr-42.preview.example.com", true},
+		{"https://pr-42.preview.example.com.evil.com", false},
+		{"https://partner.example.net", true},
+		{"https://evil.example.org", false},
+		{"http://app.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			ctx, reached := corsRequest(t, cors, "GET", map[string]string{"Origin": tt.origin})
+			assert.True(t, reached)
+			assert.Equal(t, "Origin", ctx.Response.Headers["Vary"])
+			if tt.allowed {
+				assert.Equal(t, tt.origin, ctx.Response.Headers["Access-Control-Allow-Origin"])
+				assert.Equal(t, "X-Request-ID, X-RateLimit-Remaining", ctx.Response.Headers["Access-Control-Expose-Headers"])
+			} else {
+				assert.NotContains(t, ctx.Response.Headers, "Access-Control-Allow-Origin")
+			}
+			assert.NotContains(t, ctx.Response.Headers, "Access-Control-Allow-Credentials")
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	ctx, _ := corsRequest(t, CORS(CORSConfig{AllowOrigins: []string{"*"}}), "GET", map[string]string{"Origin": "https://app.example.com"})
+	assert.Equal(t, "*", ctx.Response.Headers["Access-Control-Allow-Origin"])
+	assert.NotContains(t, ctx.Response.Headers, "Vary")
+
+	// Browsers reject "*" on credentialed responses, so the origin is echoed
+	ctx, _ = corsRequest(t, CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}), "GET", map[string]string{"Origin": "https://app.example.com"})
+	assert.Equal(t, "https://app.example.com", ctx.Response.Headers["Access-Control-Allow-Origin"])
+	assert.Equal(t, "true", ctx.Response.Headers["Access-Control-Allow-Credentials"])
+	assert.Equal(t, "Origin", ctx.Response.Headers["Vary"])
+}
+
+func TestCORSPreflight(t *testing.T) {
+	cors := CORS(CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	ctx, reached := corsRequest(t, cors, "OPTIONS", map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "authorization",
+	})
+	assert.False(t, reached, "preflights are answered by the middleware")
+	assert.Equal(t, 204, ctx.Response.StatusCode)
+	assert.Equal(t, "https://app.example.com", ctx.Response.Headers["Access-Control-Allow-Origin"])
+	assert.Equal(t, "GET, POST", ctx.Response.Headers["Access-Control-Allow-Methods"])
+	assert.Equal(t, "Authorization, Content-Type", ctx.Response.Headers["Access-Control-Allow-Headers"])
+	assert.Equal(t, "true", ctx.Response.Headers["Access-Control-Allow-Credentials"])
+	assert.Equal(t, "600", ctx.Response.Headers["Access-Control-Max-Age"])
+	assert.True(t, strings.HasPrefix(ctx.Response.Headers["Vary"], "Origin"))
+
+	ctx, reached = corsRequest(t, cors, "OPTIONS", map[string]string{
+		"Origin":                        "https://evil.example.org",
+		"Access-Control-Request-Method": "POST",
+	})
+	assert.False(t, reached)
+	assert.Equal(t, 204, ctx.Response.StatusCode)
+	assert.NotContains(t, ctx.Response.Headers, "Access-Control-Allow-Origin")
+	assert.NotContains(t, ctx.Response.Headers, "Access-Control-Allow-Methods")
+
+	// OPTIONS requests that aren't preflights reach the handler
+	_, reached = corsRequest(t, cors, "OPTIONS", map[string]string{"Origin": "https://app.example.com"})
+	assert.True(t, reached)
+}
+
+func TestCORSKeepsExistingVary(t *testing.T) {
+	cors := CORS(CORSConfig{AllowOrigins: []string{"https://app.example.com"}})
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  "GET",
+		Path:    "/orders",
+		Headers: map[string]string{"Origin": "https://app.example.com"},
+	}))
+	ctx.Response.Header("Vary", "Accept-Encoding")
+
+	require.NoError(t, cors(lift.HandlerFunc(func(ctx *lift.Context) error { return nil })).Handle(ctx))
+	assert.Equal(t, "Accept-Encoding, Origin", ctx.Response.Headers["Vary"])
+}
+
+func TestCORSInvalidPatternPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		CORS(CORSConfig{AllowOriginPatterns: []string{"https://(unclosed"}})
+	})
+}
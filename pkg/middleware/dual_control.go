@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// DualControlOptions configures DualControlGuard
+type DualControlOptions struct {
+	// Require decides whether a request to a flagged route needs approval,
+	// e.g. only refunds over a threshold (default: always)
+	Require func(ctx *lift.Context, action string) bool
+	// ApprovalHeader carries the ID of an approved action when the request
+	// is retried (default: "X-Approval-ID")
+	ApprovalHeader string
+	// ReasonHeader carries the requester's justification
+	// (default: "X-Action-Reason")
+	ReasonHeader string
+}
+
+// DualControlGuard holds requests to routes flagged with lift.MetaDualControl
+// until a second user approves them. The first request is stored as a
+// pending action and answered with 202 Accepted; once approved, the
+// requester repeats the identical request with the action's ID in the
+// approval header and the handler runs exactly once.
+//
+//	dc, _ := security.NewDualControl(security.DualControlConfig{Store: store, Audit: auditStorage})
+//	app.Use(middleware.DualControlGuard(dc, middleware.DualControlOptions{
+//		Require: func(ctx *lift.Context, action string) bool { return refundAmount(ctx) > 10_000 },
+//	}))
+//	app.POST("/refunds", createRefund).Meta(lift.MetaDualControl, "refund")
+//	app.GET("/approvals", middleware.DualControlPendingHandler(dc)).Meta(lift.MetaRoles, "supervisor")
+//	app.POST("/approvals/:id/approve", middleware.DualControlApproveHandler(dc)).Meta(lift.MetaRoles, "supervisor")
+//	app.POST("/approvals/:id/reject", middleware.DualControlRejectHandler(dc)).Meta(lift.MetaRoles, "supervisor")
+//
+// Register it after authentication so the user and tenant are known. If the
+// handler fails the approval is released so the request can be retried.
+func DualControlGuard(dc *security.DualControl, opts DualControlOptions) lift.Middleware {
+	if opts.ApprovalHeader == "" {
+		opts.ApprovalHeader = "X-Approval-ID"
+	}
+	if opts.ReasonHeader == "" {
+		opts.ReasonHeader = "X-Action-Reason"
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			name, ok := ctx.RouteMeta(lift.MetaDualControl)
+			if !ok || name == "" {
+				return next.Handle(ctx)
+			}
+			if opts.Require != nil && !opts.Require(ctx, name) {
+				return next.Handle(ctx)
+			}
+			if ctx.UserID() == "" {
+				return lift.Unauthorized("Authentication required")
+			}
+
+			req := security.DualControlRequest{
+				Action:   name,
+				TenantID: ctx.TenantID(),
+				UserID:   ctx.UserID(),
+				Method:   ctx.Request.Method,
+				Path:     ctx.Request.Path,
+				Payload:  ctx.Request.Body,
+				Reason:   strings.TrimSpace(ctx.Header(opts.ReasonHeader)),
+			}
+
+			id := strings.TrimSpace(ctx.Header(opts.ApprovalHeader))
+			if id == "" {
+				action, err := dc.Request(ctx.Context, req)
+				if err != nil {
+					return lift.NewLiftError("APPROVAL_REQUEST_FAILED", "Failed to request approval", 500).WithCause(err)
+				}
+				return ctx.Status(202).JSON(action)
+			}
+
+			action, err := dc.Consume(ctx.Context, id, req)
+			if err != nil {
+				return dualControlError(err)
+			}
+
+			err = next.Handle(ctx)
+			if err != nil || ctx.Response.StatusCode >= 400 {
+				if releaseErr := dc.Release(ctx.Context, action); releaseErr != nil && ctx.Logger != nil {
+					ctx.Logger.Error("Failed to release approval", map[string]any{
+						"approval_id": action.ID,
+						"error":       releaseErr.Error(),
+					})
+				}
+				return err
+			}
+
+			// The handler has already run, so an audit failure can only be
+			// reported, not undone
+			if err := dc.RecordExecution(ctx.Context, action); err != nil && ctx.Logger != nil {
+				ctx.Logger.Error("Failed to audit approved execution", map[string]any{
+					"approval_id": action.ID,
+					"error":       err.Error(),
+				})
+			}
+			ctx.Set("dual_control_action", action)
+			return nil
+		})
+	}
+}
+
+// dualControlDecision is the optional body accepted by the approve and
+// reject handlers
+type dualControlDecision struct {
+	Note string `json:"note"`
+}
+
+// DualControlApproveHandler approves the pending action named by the :id
+// path parameter on behalf of the authenticated user, who must not be the
+// requester. An optional body carries a note: {"note": "verified with customer"}.
+// Restrict who may approve with route metadata, e.g.
+// .Meta(lift.MetaRoles, "supervisor").
+func DualControlApproveHandler(dc *security.DualControl) lift.Handler {
+	return dualControlDecisionHandler(dc.Approve)
+}
+
+// DualControlRejectHandler rejects the pending action named by the :id path
+// parameter, like DualControlApproveHandler
+func DualControlRejectHandler(dc *security.DualControl) lift.Handler {
+	return dualControlDecisionHandler(dc.Reject)
+}
+
+func dualControlDecisionHandler(decide func(ctx context.Context, tenantID, id, approverID, note string) (*security.PendingAction, error)) lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		if ctx.UserID() == "" {
+			return lift.Unauthorized("Authentication required")
+		}
+
+		var body dualControlDecision
+		if len(ctx.Request.Body) > 0 {
+			if err := ctx.ParseRequest(&body); err != nil {
+				return err
+			}
+		}
+
+		action, err := decide(ctx.Context, ctx.TenantID(), ctx.Param("id"), ctx.UserID(), body.Note)
+		if err != nil {
+			return dualControlError(err)
+		}
+		return ctx.OK(action)
+	})
+}
+
+// DualControlPendingHandler lists the tenant's actions awaiting approval
+func DualControlPendingHandler(dc *security.DualControl) lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		actions, err := dc.Pending(ctx.Context, ctx.TenantID())
+		if err != nil {
+			return lift.NewLiftError("APPROVALS_UNAVAILABLE", "Failed to list pending approvals", 500).WithCause(err)
+		}
+		return ctx.OK(map[string]any{"approvals": actions})
+	})
+}
+
+// dualControlError maps workflow errors to responses
+func dualControlError(err error) error {
+	switch {
+	case errors.Is(err, security.ErrApprovalNotFound):
+		return lift.NotFound("Approval not found")
+	case errors.Is(err, security.ErrApprovalExpired):
+		return lift.NewLiftError("APPROVAL_EXPIRED", err.Error(), 410)
+	case errors.Is(err, security.ErrSelfApproval), errors.Is(err, security.ErrApprovalMismatch):
+		return lift.NewLiftError("APPROVAL_FORBIDDEN", err.Error(), 403)
+	case errors.Is(err, security.ErrApprovalNotPending), errors.Is(err, security.ErrApprovalNotApproved),
+		errors.Is(err, security.ErrApprovalConflict):
+		return lift.NewLiftError("APPROVAL_CONFLICT", err.Error(), 409)
+	default:
+		return lift.NewLiftError("APPROVAL_FAILED", "Failed to process approval", 500).WithCause(err)
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualControlGuard(t *testing.T) {
+	storage := security.NewInMemoryAuditStorage()
+	dc, err := security.NewDualControl(security.DualControlConfig{
+		Store: security.NewMemoryApprovalStore(),
+		Audit: storage,
+	})
+	require.NoError(t, err)
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.SetUserID(ctx.Header("X-User"))
+			ctx.SetTenantID("tenant-1")
+			return next.Handle(ctx)
+		})
+	})
+	app.Use(DualControlGuard(dc, DualControlOptions{
+		Require: func(ctx *lift.Context, action string) bool { return ctx.Query("small") == "" },
+	}))
+
+	refunds, failures := 0, 0
+	app.POST("/refunds", func(ctx *lift.Context) error {
+		if ctx.Header("X-Fail") != "" {
+			failures++
+			return lift.NewLiftError("PROCESSOR_DOWN", "Processor unavailable", 503)
+		}
+		refunds++
+		return ctx.Created(map[string]string{"status": "refunded"})
+	}).Meta(lift.MetaDualControl, "refund")
+	app.GET("/approvals", DualControlPendingHandler(dc))
+	app.POST("/approvals/:id/approve", DualControlApproveHandler(dc))
+	app.POST("/approvals/:id/reject", DualControlRejectHandler(dc))
+
+	request := func(method, path, user string, headers map[string]string, body string) *lift.Context {
+		all := map[string]string{"X-User": user}
+		for k, v := range headers {
+			all[k] = v
+		}
+		query := map[string]string{}
+		if path == "/refunds?small=1" {
+			path, query["small"] = "/refunds", "1"
+		}
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:      method,
+			Path:        path,
+			Headers:     all,
+			QueryParams: query,
+			Body:        []byte(body),
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	body := `{"amount":50000}`
+	assert.Equal(t, 201, request("POST", "/refunds?small=1", "alice", nil, body).Response.StatusCode, "requests not requiring approval run directly")
+	assert.Equal(t, 401, request("POST", "/refunds", "", nil, body).Response.StatusCode)
+
+	held := request("POST", "/refunds", "alice", map[string]string{"X-Action-Reason": "duplicate charge"}, body)
+	require.Equal(t, 202, held.Response.StatusCode)
+	action := held.Response.Body.(*security.PendingAction)
+	assert.Equal(t, "duplicate charge", action.Reason)
+	assert.Equal(t, 1, refunds)
+
+	pending := request("GET", "/approvals", "bob", nil, "")
+	require.Equal(t, 200, pending.Response.StatusCode)
+	assert.Len(t, pending.Response.Body.(map[string]any)["approvals"], 1)
+
+	approval := map[string]string{"X-Approval-ID": action.ID}
+	assert.Equal(t, 409, request("POST", "/refunds", "alice", approval, body).Response.StatusCode, "not approved yet")
+	assert.Equal(t, 403, request("POST", "/approvals/"+action.ID+"/approve", "alice", nil, "").Response.StatusCode)
+	assert.Equal(t, 404, request("POST", "/approvals/missing/approve", "bob", nil, "").Response.StatusCode)
+	assert.Equal(t, 200, request("POST", "/approvals/"+action.ID+"/approve", "bob", nil, `{"note":"verified"}`).Response.StatusCode)
+
+	assert.Equal(t, 403, request("POST", "/refunds", "alice", approval, `{"amount":99999}`).Response.StatusCode)
+	assert.Equal(t, 503, request("POST", "/refunds", "alice", map[string]string{"X-Approval-ID": action.ID, "X-Fail": "1"}, body).Response.StatusCode)
+	assert.Equal(t, 1, failures)
+
+	assert.Equal(t, 201, request("POST", "/refunds", "alice", approval, body).Response.StatusCode, "a failed execution releases the approval")
+	assert.Equal(t, 2, refunds)
+	assert.Equal(t, 409, request("POST", "/refunds", "alice", approval, body).Response.StatusCode, "approvals execute once")
+	assert.Equal(t, 2, refunds)
+
+	entries, err := storage.Query(context.Background(), security.AuditFilter{
+		AuditID:   action.ID,
+		EntryType: security.DualControlExecutedEntry,
+	})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	rejected := request("POST", "/refunds", "alice", nil, `{"amount":1}`).Response.Body.(*security.PendingAction)
+	assert.Equal(t, 200, request("POST", "/approvals/"+rejected.ID+"/reject", "bob", nil, "").Response.StatusCode)
+	assert.Equal(t, 409, request("POST", "/refunds", "alice", map[string]string{"X-Approval-ID": rejected.ID}, `{"amount":1}`).Response.StatusCode)
+}
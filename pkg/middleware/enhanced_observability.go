@@ -43,6 +43,10 @@ type EnhancedObservabilityConfig struct {
 
 	// Custom dimensions/tags
 	DefaultTags map[string]string `json:"default_tags"`
+
+	// TenantDimensions buckets long-tail tenants in the tenant_id metric tag.
+	// Without it every tenant ID becomes its own dimension value.
+	TenantDimensions *observability.TenantCardinalityGuard `json:"-"`
 }
 
 // EnhancedObservabilityMiddleware provides comprehensive observability with logging, metrics, and tracing
@@ -88,9 +92,16 @@ func EnhancedObservabilityMiddleware(config EnhancedObservabilityConfig) lift.Mi
 			for k, v := range config.DefaultTags {
 				baseTags[k] = v
 			}
+			for k, v := range ctx.RouteLabels() {
+				baseTags[k] = v
+			}
 			baseTags["method"] = ctx.Request.Method
 			baseTags["path"] = ctx.Request.Path
-			baseTags["tenant_id"] = tenantID
+			if config.TenantDimensions != nil {
+				baseTags["tenant_id"] = config.TenantDimensions.TrackRequest(ctx, tenantID)
+			} else {
+				baseTags["tenant_id"] = tenantID
+			}
 			baseTags["operation"] = operation
 
 			// Initialize observability components
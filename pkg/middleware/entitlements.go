@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// TenantEntitlements is what a tenant's subscription allows
+type TenantEntitlements struct {
+	Plan string `json:"plan"`
+	// Features are per-tenant overrides of the plan: true grants a feature
+	// the plan doesn't include, false withholds one it does
+	Features map[string]bool `json:"features,omitempty"`
+	// Active is false while the subscription has lapsed, e.g. past due or
+	// cancelled
+	Active bool `json:"active"`
+}
+
+// EntitlementResolver returns a tenant's entitlements, typically from the
+// tenant registry
+type EntitlementResolver func(ctx context.Context, tenantID string) (*TenantEntitlements, error)
+
+// Plan is a subscription plan and the features it includes
+type Plan struct {
+	Name     string
+	Features []string
+}
+
+// EntitlementConfig configures Entitlements
+type EntitlementConfig struct {
+	// Resolve looks up the tenant's plan and feature overrides
+	Resolve EntitlementResolver
+	// Plans lists the plans from cheapest to most expensive. A tenant has
+	// its plan's features, and is pointed at the cheapest plan that
+	// includes a feature it lacks.
+	Plans []Plan
+	// UpgradeURL is returned with 402 responses so clients can link to
+	// billing
+	UpgradeURL string
+	// CacheTTL is how long resolved entitlements are cached (default: 5m)
+	CacheTTL time.Duration
+}
+
+type cachedEntitlements struct {
+	entitlements *TenantEntitlements
+	expires      time.Time
+}
+
+// entitlementChecker resolves and caches tenants' entitlements
+type entitlementChecker struct {
+	config EntitlementConfig
+
+	mu    sync.RWMutex
+	cache map[string]cachedEntitlements
+}
+
+// Entitlements makes tenants' subscription entitlements available to
+// RequireFeature and HasFeature. Entitlements are only looked up for
+// requests that check a feature. It must run after authentication sets the
+// tenant ID.
+//
+//	app.Use(middleware.Entitlements(middleware.EntitlementConfig{
+//		Resolve: lookupTenantPlan,
+//		Plans: []middleware.Plan{
+//			{Name: "starter", Features: []string{"reviews"}},
+//			{Name: "professional", Features: []string{"reviews", "advancedSearch"}},
+//		},
+//		UpgradeURL: "https://app.example.com/billing",
+//	}))
+//	search := app.Group("/search", middleware.RequireFeature("advancedSearch"))
+func Entitlements(config EntitlementConfig) lift.Middleware {
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 5 * time.Minute
+	}
+	checker := &entitlementChecker{config: config, cache: make(map[string]cachedEntitlements)}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.Set("entitlement_checker", checker)
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// RequireFeature allows requests from tenants entitled to a feature. Others
+// are answered with 402 Payment Required when a higher plan includes the
+// feature or their subscription has lapsed, with the plan to upgrade to and
+// the upgrade URL in the error details, and with 403 when the feature is
+// withheld from the tenant or not sold on any plan. It requires the
+// Entitlements middleware.
+func RequireFeature(feature string) lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			checker, ok := ctx.Get("entitlement_checker").(*entitlementChecker)
+			if !ok {
+				return fmt.Errorf("RequireFeature requires the Entitlements middleware")
+			}
+			if ctx.TenantID() == "" {
+				return lift.AuthorizationError("Tenant ID is required")
+			}
+
+			entitlements, err := checker.resolve(ctx)
+			if err != nil {
+				return lift.NewLiftError("ENTITLEMENTS_UNAVAILABLE", "Failed to check plan entitlements", 503).WithCause(err)
+			}
+			if err := checker.check(entitlements, feature); err != nil {
+				return err
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// HasFeature reports whether the current tenant is entitled to a feature,
+// for handlers that vary their behaviour by plan rather than refuse the
+// request. It is false without the Entitlements middleware or a tenant, or
+// when the entitlements can't be resolved.
+func HasFeature(ctx *lift.Context, feature string) bool {
+	checker, ok := ctx.Get("entitlement_checker").(*entitlementChecker)
+	if !ok || ctx.TenantID() == "" {
+		return false
+	}
+	entitlements, err := checker.resolve(ctx)
+	if err != nil {
+		return false
+	}
+	return checker.check(entitlements, feature) == nil
+}
+
+// resolve returns the request tenant's entitlements, looking them up once
+// per request and caching them across requests
+func (c *entitlementChecker) resolve(ctx *lift.Context) (*TenantEntitlements, error) {
+	if entitlements, ok := ctx.Get("tenant_entitlements").(*TenantEntitlements); ok {
+		return entitlements, nil
+	}
+	tenantID := ctx.TenantID()
+
+	c.mu.RLock()
+	cached, ok := c.cache[tenantID]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		ctx.Set("tenant_entitlements", cached.entitlements)
+		return cached.entitlements, nil
+	}
+
+	entitlements, err := c.config.Resolve(ctx.Context, tenantID)
+	if err != nil {
+		// Don't cache failures so the next request retries
+		return nil, err
+	}
+	if entitlements == nil {
+		return nil, fmt.Errorf("no entitlements for tenant %s", tenantID)
+	}
+
+	c.mu.Lock()
+	c.cache[tenantID] = cachedEntitlements{entitlements: entitlements, expires: time.Now().Add(c.config.CacheTTL)}
+	c.mu.Unlock()
+	ctx.Set("tenant_entitlements", entitlements)
+	return entitlements, nil
+}
+
+// check returns the error for a tenant that isn't entitled to a feature
+func (c *entitlementChecker) check(entitlements *TenantEntitlements, feature string) error {
+	if !entitlements.Active {
+		return c.upgradeError("SUBSCRIPTION_INACTIVE", "Your subscription is not active", entitlements, feature, entitlements.Plan)
+	}
+
+	if enabled, ok := entitlements.Features[feature]; ok {
+		if enabled {
+			return nil
+		}
+		return lift.NewLiftError("FEATURE_DISABLED", "This feature is disabled for your account", 403).
+			WithDetail("feature", feature)
+	}
+
+	current := slices.IndexFunc(c.config.Plans, func(plan Plan) bool { return plan.Name == entitlements.Plan })
+	if current >= 0 && slices.Contains(c.config.Plans[current].Features, feature) {
+		return nil
+	}
+	for i, plan := range c.config.Plans {
+		if i > current && slices.Contains(plan.Features, feature) {
+			return c.upgradeError("UPGRADE_REQUIRED", "Your plan does not include this feature", entitlements, feature, plan.Name)
+		}
+	}
+	return lift.NewLiftError("FEATURE_NOT_AVAILABLE", "This feature is not available for your account", 403).
+		WithDetail("feature", feature)
+}
+
+func (c *entitlementChecker) upgradeError(code, message string, entitlements *TenantEntitlements, feature, plan string) error {
+	err := lift.NewLiftError(code, message, 402).
+		WithDetail("feature", feature).
+		WithDetail("current_plan", entitlements.Plan).
+		WithDetail("required_plan", plan)
+	if c.config.UpgradeURL != "" {
+		err = err.WithDetail("upgrade_url", c.config.UpgradeURL)
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireFeature(t *testing.T) {
+	tenants := map[string]*TenantEntitlements{
+		"starter":  {Plan: "starter", Active: true},
+		"pro":      {Plan: "professional", Active: true},
+		"comped":   {Plan: "starter", Active: true, Features: map[string]bool{"advancedSearch": true}},
+		"withheld": {Plan: "professional", Active: true, Features: map[string]bool{"advancedSearch": false}},
+		"lapsed":   {Plan: "professional", Active: false},
+	}
+	lookups := 0
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.SetTenantID(ctx.Header("X-Tenant"))
+			return next.Handle(ctx)
+		})
+	})
+	config := EntitlementConfig{
+		Resolve: func(ctx context.Context, tenantID string) (*TenantEntitlements, error) {
+			lookups++
+			if tenantID == "broken" {
+				return nil, errors.New("registry unavailable")
+			}
+			return tenants[tenantID], nil
+		},
+		Plans: []Plan{
+			{Name: "starter", Features: []string{"reviews"}},
+			{Name: "professional", Features: []string{"reviews", "advancedSearch"}},
+		},
+		UpgradeURL: "https://app.example.com/billing",
+	}
+	app.Use(Entitlements(config))
+
+	search := app.Group("/search", RequireFeature("advancedSearch"))
+	search.GET("", func(ctx *lift.Context) error { return ctx.Text("ok") })
+	app.GET("/reviews", func(ctx *lift.Context) error {
+		return ctx.OK(map[string]bool{"recommendations": HasFeature(ctx, "recommendations"), "reviews": HasFeature(ctx, "reviews")})
+	})
+
+	request := func(path, tenant string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  "GET",
+			Path:    path,
+			Headers: map[string]string{"X-Tenant": tenant},
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+	assert.Equal(t, 200, request("/search", "pro").Response.StatusCode)
+	assert.Equal(t, 200, request("/search", "comped").Response.StatusCode)
+
+	assert.Equal(t, 402, request("/search", "starter").Response.StatusCode)
+
+	assert.Equal(t, 402, request("/search", "lapsed").Response.StatusCode)
+	assert.Equal(t, 403, request("/search", "withheld").Response.StatusCode)
+	assert.Equal(t, 503, request("/search", "broken").Response.StatusCode)
+	assert.Equal(t, 403, request("/search", "").Response.StatusCode)
+
+	before := lookups
+	assert.Equal(t, 200, request("/search", "pro").Response.StatusCode)
+	assert.Equal(t, before, lookups, "entitlements are cached")
+
+	reviews := request("/reviews", "starter")
+	assert.Equal(t, map[string]bool{"recommendations": false, "reviews": true}, reviews.Response.Body)
+
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Method: "GET", Path: "/search"}))
+	ctx.SetTenantID("starter")
+	err := Entitlements(config)(RequireFeature("advancedSearch")(lift.HandlerFunc(func(ctx *lift.Context) error { return nil }))).Handle(ctx)
+	var liftErr *lift.LiftError
+	require.True(t, errors.As(err, &liftErr))
+	assert.Equal(t, "UPGRADE_REQUIRED", liftErr.Code)
+	assert.Equal(t, "professional", liftErr.Details["required_plan"])
+	assert.Equal(t, "https://app.example.com/billing", liftErr.Details["upgrade_url"])
+}
+
+func TestRequireFeatureWithoutEntitlements(t *testing.T) {
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Method: "GET", Path: "/search"}))
+	ctx.SetTenantID("tenant-1")
+
+	handler := RequireFeature("advancedSearch")(lift.HandlerFunc(func(ctx *lift.Context) error { return nil }))
+	assert.Error(t, handler.Handle(ctx))
+	assert.False(t, HasFeature(ctx, "advancedSearch"))
+}
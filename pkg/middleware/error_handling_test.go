@@ -8,6 +8,7 @@ import (
 	"github.com/pay-theory/lift/pkg/lift"
 	"github.com/pay-theory/lift/pkg/lift/adapters"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestErrorHandlingImprovements(t *testing.T) {
@@ -21,10 +22,11 @@ func TestErrorHandlingImprovements(t *testing.T) {
 		handler := middleware(panicHandler)
 		ctx := createErrorTestContext("GET", "/test", nil)
 
-		// This should not panic and should handle the error gracefully
+		// This should not panic and should return the error for the app to render
 		err := handler.Handle(ctx)
-		assert.NoError(t, err, "Recovery middleware should handle panic gracefully")
-		assert.Equal(t, 500, ctx.Response.StatusCode, "Should set 500 status code")
+		var liftErr *lift.LiftError
+		require.ErrorAs(t, err, &liftErr, "Recovery middleware should handle panic gracefully")
+		assert.Equal(t, 500, liftErr.StatusCode, "Should use 500 status code")
 	})
 
 	t.Run("Error handler middleware processes LiftErrors correctly", func(t *testing.T) {
@@ -0,0 +1,293 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// FraudDecision is what happens to a scored request
+type FraudDecision string
+
+const (
+	// FraudAllow lets the request through
+	FraudAllow FraudDecision = "allow"
+	// FraudReview lets the request through flagged for review; handlers
+	// read the score with FraudScoreFromContext, e.g. to hold a payout
+	FraudReview FraudDecision = "review"
+	// FraudBlock rejects the request with 403 Forbidden
+	FraudBlock FraudDecision = "block"
+)
+
+// FraudFeatures describes a request to a fraud scorer
+type FraudFeatures struct {
+	Method    string `json:"method"`
+	Route     string `json:"route"`
+	Path      string `json:"path"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	AccountID string `json:"account_id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	DeviceID  string `json:"device_id,omitempty"`
+	// Amount and Currency are set by FraudConfig.Features for money
+	// movement routes
+	Amount   int64  `json:"amount,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// Attributes carry any other signals the scorer understands
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// FraudScore is a scorer's assessment of a request
+type FraudScore struct {
+	// Score is the risk from 0 (none) to 1 (certain fraud)
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+	// Decision is set from the configured thresholds
+	Decision FraudDecision `json:"decision"`
+	// Degraded is set when the scorer failed or ran out of time and the
+	// decision is FraudConfig.Unavailable
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// FraudScorer scores requests for fraud risk
+type FraudScorer interface {
+	Score(ctx context.Context, features *FraudFeatures) (*FraudScore, error)
+}
+
+// NoopFraudScorer scores every request 0, for environments without a
+// fraud service
+type NoopFraudScorer struct{}
+
+// Score returns a zero score
+func (NoopFraudScorer) Score(ctx context.Context, features *FraudFeatures) (*FraudScore, error) {
+	return &FraudScore{}, nil
+}
+
+// HTTPFraudScorerConfig configures an HTTPFraudScorer
+type HTTPFraudScorerConfig struct {
+	// Endpoint receives features as a JSON POST and answers with
+	// {"score": 0.42, "reasons": ["new_device"]}
+	Endpoint string
+	// Headers are added to every request, e.g. an API key
+	Headers map[string]string
+	// Client sends requests (default: a client with a 5s timeout; the
+	// middleware's budget usually ends requests sooner)
+	Client *http.Client
+}
+
+// HTTPFraudScorer scores requests with a remote fraud service
+type HTTPFraudScorer struct {
+	config HTTPFraudScorerConfig
+}
+
+// NewHTTPFraudScorer creates a scorer backed by an HTTP fraud service
+func NewHTTPFraudScorer(config HTTPFraudScorerConfig) (*HTTPFraudScorer, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("fraud scorer requires an endpoint")
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &HTTPFraudScorer{config: config}, nil
+}
+
+// Score posts the features to the fraud service
+func (s *HTTPFraudScorer) Score(ctx context.Context, features *FraudFeatures) (*FraudScore, error) {
+	body, err := json.Marshal(features)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fraud features: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fraud service returned status %d", resp.StatusCode)
+	}
+
+	var score FraudScore
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&score); err != nil {
+		return nil, fmt.Errorf("failed to decode fraud score: %w", err)
+	}
+	return &score, nil
+}
+
+// FraudConfig configures FraudScoring
+type FraudConfig struct {
+	// Scorer scores requests (default: NoopFraudScorer)
+	Scorer FraudScorer
+	// Features adds route-specific signals, such as the amount, to the
+	// features read from the request
+	Features func(ctx *lift.Context, features *FraudFeatures) error
+	// Budget is how long a request waits for its score (default: 200ms)
+	Budget time.Duration
+	// ReviewThreshold and BlockThreshold are the scores at which requests
+	// are flagged for review (default: 0.5) and blocked (default: 0.9)
+	ReviewThreshold float64
+	BlockThreshold  float64
+	// Unavailable is the decision when the scorer fails or exceeds the
+	// budget (default: FraudAllow)
+	Unavailable FraudDecision
+	// OnDecision is called for every scored request, e.g. to record
+	// decisions for model training
+	OnDecision func(ctx *lift.Context, features *FraudFeatures, score *FraudScore)
+}
+
+// FraudScoring scores each request before the handler runs, blocking
+// requests at or above the block threshold and flagging those at or above
+// the review threshold. The score is available to handlers with
+// FraudScoreFromContext:
+//
+//	payments := app.Group("/payments", middleware.FraudScoring(middleware.FraudConfig{
+//		Scorer:   scorer,
+//		Features: func(ctx *lift.Context, f *middleware.FraudFeatures) error {
+//			f.Amount, f.Currency = paymentAmount(ctx)
+//			return nil
+//		},
+//	}))
+//
+// Scoring runs in the background for at most the budget, so a slow fraud
+// service delays requests by a bounded amount and then degrades to the
+// Unavailable decision. Register it after authentication so the tenant and
+// user are known.
+func FraudScoring(config FraudConfig) lift.Middleware {
+	if config.Scorer == nil {
+		config.Scorer = NoopFraudScorer{}
+	}
+	if config.Budget == 0 {
+		config.Budget = 200 * time.Millisecond
+	}
+	if config.ReviewThreshold == 0 {
+		config.ReviewThreshold = 0.5
+	}
+	if config.BlockThreshold == 0 {
+		config.BlockThreshold = 0.9
+	}
+	if config.Unavailable == "" {
+		config.Unavailable = FraudAllow
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			features := fraudFeatures(ctx)
+			if config.Features != nil {
+				if err := config.Features(ctx, features); err != nil {
+					var liftErr *lift.LiftError
+					if errors.As(err, &liftErr) {
+						return liftErr
+					}
+					return lift.NewLiftError("INVALID_REQUEST", "Invalid request", 400).WithCause(err)
+				}
+			}
+
+			score, err := scoreWithin(ctx.Context, config.Scorer, features, config.Budget)
+			if err != nil {
+				if ctx.Logger != nil {
+					ctx.Logger.Warn("Fraud scoring unavailable", map[string]any{
+						"route": features.Route,
+						"error": err.Error(),
+					})
+				}
+				score = &FraudScore{Decision: config.Unavailable, Degraded: true}
+			} else {
+				score.Decision = config.decide(score.Score)
+			}
+
+			ctx.Set("fraud_score", score)
+			if config.OnDecision != nil {
+				config.OnDecision(ctx, features, score)
+			}
+			if score.Decision == FraudBlock {
+				return lift.NewLiftError("FRAUD_BLOCKED", "Request declined", 403)
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// FraudScoreFromContext returns the score FraudScoring assigned to the
+// request, or nil if it wasn't scored
+func FraudScoreFromContext(ctx *lift.Context) *FraudScore {
+	score, _ := ctx.Get("fraud_score").(*FraudScore)
+	return score
+}
+
+func (c FraudConfig) decide(score float64) FraudDecision {
+	switch {
+	case score >= c.BlockThreshold:
+		return FraudBlock
+	case score >= c.ReviewThreshold:
+		return FraudReview
+	default:
+		return FraudAllow
+	}
+}
+
+// scoreWithin runs the scorer in the background and waits at most budget
+// for its answer. The scorer's context is cancelled when the budget runs
+// out, so well-behaved scorers stop work the request no longer waits for.
+func scoreWithin(ctx context.Context, scorer FraudScorer, features *FraudFeatures, budget time.Duration) (*FraudScore, error) {
+	scoreCtx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	type outcome struct {
+		score *FraudScore
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		score, err := scorer.Score(scoreCtx, features)
+		done <- outcome{score, err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err == nil && result.score == nil {
+			return nil, fmt.Errorf("fraud scorer returned no score")
+		}
+		return result.score, result.err
+	case <-scoreCtx.Done():
+		return nil, fmt.Errorf("fraud scoring exceeded %s budget: %w", budget, scoreCtx.Err())
+	}
+}
+
+// fraudFeatures reads the standard features from the request
+func fraudFeatures(ctx *lift.Context) *FraudFeatures {
+	ip := ctx.Header("X-Forwarded-For")
+	if first, _, found := strings.Cut(ip, ","); found {
+		ip = first
+	}
+	if ip == "" {
+		ip = ctx.Header("X-Real-IP")
+	}
+	return &FraudFeatures{
+		Method:    ctx.Request.Method,
+		Route:     ctx.Route(),
+		Path:      ctx.Request.Path,
+		TenantID:  ctx.TenantID(),
+		UserID:    ctx.UserID(),
+		AccountID: ctx.AccountID(),
+		IP:        strings.TrimSpace(ip),
+		UserAgent: ctx.Header("User-Agent"),
+		DeviceID:  ctx.Header("X-Device-ID"),
+	}
+}
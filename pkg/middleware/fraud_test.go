@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scoreByAmount scores requests by amount, and stalls or fails on request
+type scoreByAmount struct {
+	seen []*FraudFeatures
+}
+
+func (s *scoreByAmount) Score(ctx context.Context, features *FraudFeatures) (*FraudScore, error) {
+	s.seen = append(s.seen, features)
+	switch features.Attributes["mode"] {
+	case "slow":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case "error":
+		return nil, errors.New("model unavailable")
+	}
+	return &FraudScore{Score: float64(features.Amount) / 1000, Reasons: []string{"amount"}}, nil
+}
+
+func TestFraudScoring(t *testing.T) {
+	scorer := &scoreByAmount{}
+	var decisions []FraudDecision
+
+	app := lift.New()
+	payments := app.Group("/payments", FraudScoring(FraudConfig{
+		Scorer: scorer,
+		Budget: 20 * time.Millisecond,
+		Features: func(ctx *lift.Context, f *FraudFeatures) error {
+			amount, err := strconv.ParseInt(ctx.Header("X-Amount"), 10, 64)
+			if err != nil {
+				return err
+			}
+			f.Amount, f.Currency = amount, "USD"
+			f.Attributes = map[string]any{"mode": ctx.Header("X-Mode")}
+			return nil
+		},
+		OnDecision: func(ctx *lift.Context, features *FraudFeatures, score *FraudScore) {
+			decisions = append(decisions, score.Decision)
+		},
+	}))
+	payments.POST("", func(ctx *lift.Context) error {
+		return ctx.Created(FraudScoreFromContext(ctx))
+	})
+
+	request := func(amount, mode string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method: "POST",
+			Path:   "/payments",
+			Headers: map[string]string{
+				"X-Amount":        amount,
+				"X-Mode":          mode,
+				"X-Forwarded-For": "203.0.113.7, 10.0.0.1",
+				"User-Agent":      "checkout/2.1",
+			},
+		}))
+		ctx.SetTenantID("tenant-1")
+		ctx.SetUserID("user-1")
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	ctx := request("100", "")
+	assert.Equal(t, 201, ctx.Response.StatusCode)
+	assert.Equal(t, &FraudScore{Score: 0.1, Reasons: []string{"amount"}, Decision: FraudAllow}, ctx.Response.Body)
+	features := scorer.seen[0]
+	assert.Equal(t, "203.0.113.7", features.IP)
+	assert.Equal(t, "checkout/2.1", features.UserAgent)
+	assert.Equal(t, "tenant-1", features.TenantID)
+	assert.Equal(t, "user-1", features.UserID)
+	assert.Equal(t, "/payments", features.Route)
+
+	ctx = request("600", "")
+	assert.Equal(t, 201, ctx.Response.StatusCode, "review lets the request through")
+	assert.Equal(t, FraudReview, FraudScoreFromContext(ctx).Decision)
+
+	ctx = request("950", "")
+	assert.Equal(t, 403, ctx.Response.StatusCode)
+
+	// Slow and failing scorers degrade to the Unavailable decision
+	start := time.Now()
+	ctx = request("950", "slow")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 201, ctx.Response.StatusCode)
+	assert.True(t, FraudScoreFromContext(ctx).Degraded)
+	ctx = request("950", "error")
+	assert.Equal(t, 201, ctx.Response.StatusCode)
+
+	assert.Equal(t, []FraudDecision{FraudAllow, FraudReview, FraudBlock, FraudAllow, FraudAllow}, decisions)
+
+	assert.Equal(t, 400, request("lots", "").Response.StatusCode)
+}
+
+func TestFraudScoringFailClosed(t *testing.T) {
+	app := lift.New()
+	app.Use(FraudScoring(FraudConfig{
+		Scorer:      &scoreByAmount{},
+		Unavailable: FraudBlock,
+		Features: func(ctx *lift.Context, f *FraudFeatures) error {
+			f.Attributes = map[string]any{"mode": "error"}
+			return nil
+		},
+	}))
+	app.GET("/payouts", func(ctx *lift.Context) error { return ctx.OK(nil) })
+
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Method: "GET", Path: "/payouts"}))
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 403, ctx.Response.StatusCode)
+}
+
+func TestHTTPFraudScorer(t *testing.T) {
+	var received FraudFeatures
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key-1", r.Header.Get("X-Api-Key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.Amount < 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"score":0.42,"reasons":["new_device"]}`))
+	}))
+	defer server.Close()
+
+	scorer, err := NewHTTPFraudScorer(HTTPFraudScorerConfig{Endpoint: server.URL, Headers: map[string]string{"X-Api-Key": "key-1"}})
+	require.NoError(t, err)
+
+	score, err := scorer.Score(context.Background(), &FraudFeatures{Method: "POST", Route: "/payments", Amount: 5000, DeviceID: "d-1"})
+	require.NoError(t, err)
+	assert.Equal(t, &FraudScore{Score: 0.42, Reasons: []string{"new_device"}}, score)
+	assert.Equal(t, "d-1", received.DeviceID)
+	assert.Equal(t, int64(5000), received.Amount)
+
+	_, err = scorer.Score(context.Background(), &FraudFeatures{Amount: -1})
+	assert.ErrorContains(t, err, "status 502")
+
+	_, err = NewHTTPFraudScorer(HTTPFraudScorerConfig{})
+	assert.Error(t, err)
+
+	score, err = NoopFraudScorer{}.Score(context.Background(), &FraudFeatures{})
+	require.NoError(t, err)
+	assert.Zero(t, score.Score)
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// RequireIAM allows requests signed with SigV4 by one of the given IAM
+// principals. The route must use API Gateway's AWS_IAM authorization,
+// which verifies the signature; this checks who signed. Principals are
+// ARNs, and a trailing "*" matches any suffix, such as every session of an
+// assumed role:
+//
+//	debug.Use(middleware.RequireIAM("arn:aws:sts::123456789012:assumed-role/oncall/*"))
+//
+// The caller's ARN is stored as "iam_arn".
+func RequireIAM(principals ...string) lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			arn := IAMCaller(ctx)
+			if arn == "" {
+				return lift.Unauthorized("IAM authentication required")
+			}
+			for _, principal := range principals {
+				if arn == principal || (strings.HasSuffix(principal, "*") && strings.HasPrefix(arn, strings.TrimSuffix(principal, "*"))) {
+					ctx.Set("iam_arn", arn)
+					return next.Handle(ctx)
+				}
+			}
+			return lift.AuthorizationError("IAM principal is not allowed")
+		})
+	}
+}
+
+// IAMCaller returns the ARN of the IAM principal API Gateway authenticated
+// the request as, or "" for requests without IAM authorization
+func IAMCaller(ctx *lift.Context) string {
+	event, ok := ctx.Request.RawEvent.(map[string]any)
+	if !ok {
+		return ""
+	}
+	requestContext, _ := event["requestContext"].(map[string]any)
+	// REST APIs
+	if identity, ok := requestContext["identity"].(map[string]any); ok {
+		if arn, _ := identity["userArn"].(string); arn != "" {
+			return arn
+		}
+	}
+	// HTTP APIs
+	if authorizer, ok := requestContext["authorizer"].(map[string]any); ok {
+		if iam, ok := authorizer["iam"].(map[string]any); ok {
+			arn, _ := iam["userArn"].(string)
+			return arn
+		}
+	}
+	return ""
+}
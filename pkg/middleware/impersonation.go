@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// ImpersonationGuard records every request made with an impersonation
+// token in the audit trail, with both the actor and the impersonated
+// user, and refuses routes flagged with lift.MetaNoImpersonation:
+//
+//	imp, _ := security.NewImpersonation(security.ImpersonationConfig{
+//		Storage:     auditStorage,
+//		ReasonCodes: []string{"customer_request", "incident"},
+//	})
+//	app.Use(middleware.JWT(jwtConfig))
+//	app.Use(middleware.ImpersonationGuard(imp))
+//	app.PUT("/me/password", changePassword).Meta(lift.MetaNoImpersonation, "true")
+//
+// Register it after middleware.JWT, which sets the principal's actor from
+// the token's act claim. Requests that aren't impersonated pass through
+// untouched.
+func ImpersonationGuard(imp *security.Impersonation) lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			principal, ok := ctx.Get("principal").(*security.Principal)
+			if !ok || principal == nil || !principal.IsImpersonated() {
+				return next.Handle(ctx)
+			}
+
+			if _, denied := ctx.RouteMeta(lift.MetaNoImpersonation); denied {
+				return lift.NewLiftError("IMPERSONATION_NOT_ALLOWED", "This action can't be taken while impersonating", 403)
+			}
+
+			// Fail closed: impersonated access that can't be audited isn't allowed
+			if err := imp.RecordAccess(ctx.Context, principal, ctx.Request.Method, ctx.Request.Path); err != nil {
+				return lift.NewLiftError("IMPERSONATION_AUDIT_FAILED", "Failed to record impersonated access", 500).WithCause(err)
+			}
+
+			return next.Handle(ctx)
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/pay-theory/lift/pkg/services"
 )
 
 // JWTConfig holds configuration for JWT middleware
@@ -36,6 +39,15 @@ type JWTConfig struct {
 
 	// Optional: custom token extractor
 	Extractor func(ctx *lift.Context) (string, error)
+
+	// ForwardToken passes the validated token to service calls made with
+	// ctx.Context, so a services.ServiceClient with a TokenExchanger calls
+	// downstream services on behalf of the user
+	ForwardToken bool
+
+	// Revocation, if set, rejects revoked tokens through ErrorHandler, or
+	// with 503 if the revocation list can't be consulted
+	Revocation *security.Revocations
 }
 
 // DefaultJWTConfig returns a default JWT configuration
@@ -114,8 +126,18 @@ func JWTAuth(config JWTConfig) lift.Middleware {
 				}
 			}
 
+			if err := checkRevocation(ctx, config.Revocation, mapClaimsRevocationSubject(claims)); err != nil {
+				if errors.Is(err, security.ErrTokenRevoked) {
+					return config.ErrorHandler(ctx, security.ErrTokenRevoked)
+				}
+				return err
+			}
+
 			// Set claims in context
 			ctx.SetClaims(claims)
+			if config.ForwardToken {
+				ctx.Context = services.WithSubjectToken(ctx.Context, tokenString)
+			}
 
 			// Continue to next handler
 			return next.Handle(ctx)
@@ -123,6 +145,19 @@ func JWTAuth(config JWTConfig) lift.Middleware {
 	}
 }
 
+// mapClaimsRevocationSubject identifies map claims for revocation checks
+func mapClaimsRevocationSubject(claims jwt.MapClaims) security.RevocationSubject {
+	subject := security.RevocationSubject{}
+	subject.TokenID, _ = claims["jti"].(string)
+	subject.SessionID, _ = claims["sid"].(string)
+	subject.UserID, _ = claims.GetSubject()
+	subject.TenantID, _ = claims["tenant_id"].(string)
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		subject.IssuedAt = iat.Time
+	}
+	return subject
+}
+
 // createExtractor creates a token extractor based on the lookup string
 func createExtractor(lookup string) func(*lift.Context) (string, error) {
 	parts := strings.Split(lookup, ":")
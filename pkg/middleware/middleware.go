@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/id"
 )
 
 // Middleware represents a middleware function
@@ -55,71 +56,6 @@ func Logger() Middleware {
 	}
 }
 
-// Recover provides panic recovery and graceful error handling
-func Recover() Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			defer func() {
-				if r := recover(); r != nil {
-					if ctx.Logger != nil {
-						ctx.Logger.Error("Handler panicked", map[string]any{
-							"panic": "[REDACTED_PANIC_DETAIL]", // Sanitized for security
-						})
-					}
-
-					// Set error response
-					if err := ctx.Response.Status(500).JSON(map[string]any{
-						"error": "Internal server error",
-						"code":  "PANIC_RECOVERED",
-					}); err != nil {
-						// Log that we couldn't send the error response
-						if ctx.Logger != nil {
-							ctx.Logger.Error("Failed to send panic recovery response", map[string]any{
-								"response_error": "[SANITIZED_ERROR]", // Sanitized for security
-							})
-						}
-					}
-				}
-			}()
-
-			return next.Handle(ctx)
-		})
-	}
-}
-
-// CORS provides cross-origin resource sharing headers
-func CORS(allowedOrigins []string) Middleware {
-	return func(next lift.Handler) lift.Handler {
-		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			origin := ctx.Header("Origin")
-
-			// Check if origin is allowed
-			allowed := false
-			for _, allowedOrigin := range allowedOrigins {
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					allowed = true
-					break
-				}
-			}
-
-			if allowed {
-				ctx.Response.Header("Access-Control-Allow-Origin", origin)
-				ctx.Response.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				ctx.Response.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-				ctx.Response.Header("Access-Control-Allow-Credentials", "true")
-			}
-
-			// Handle preflight requests
-			if ctx.Request.Method == "OPTIONS" {
-				ctx.Response.Status(204)
-				return nil
-			}
-
-			return next.Handle(ctx)
-		})
-	}
-}
-
 // Timeout adds request timeout handling
 func Timeout(duration time.Duration) Middleware {
 	return func(next lift.Handler) lift.Handler {
@@ -170,22 +106,37 @@ func Metrics() Middleware {
 	}
 }
 
-// RequestID generates and sets a unique request ID
+// RequestID sets the request ID used to correlate logs, errors and
+// downstream calls. It takes the ID sent in X-Request-ID or
+// X-Correlation-ID, then the Lambda request ID, and generates one if
+// neither is present. The ID is added to the logger, carried by
+// ctx.Context for service clients, and returned in X-Request-ID (and in
+// X-Correlation-ID when the caller sent it).
 func RequestID() Middleware {
 	return func(next lift.Handler) lift.Handler {
 		return lift.HandlerFunc(func(ctx *lift.Context) error {
-			// Check if request ID already exists in headers
-			requestID := ctx.Header("X-Request-ID")
+			var headers map[string]string
+			if ctx.Request != nil {
+				headers = ctx.Request.Headers
+			}
+
+			requestID := lift.IncomingRequestID(headers)
 			if requestID == "" {
-				// Generate a simple request ID (in production, use UUID)
-				requestID = fmt.Sprintf("req_%d", time.Now().UnixNano())
+				requestID = ctx.RequestID
 			}
+			if requestID == "" {
+				requestID = id.New("req")
+			}
+			ctx.SetRequestID(requestID)
 
-			// Set in context
-			ctx.RequestID = requestID
+			if ctx.Logger != nil {
+				ctx.Logger = ctx.Logger.WithField("request_id", requestID)
+			}
 
-			// Add to response headers
-			ctx.Response.Header("X-Request-ID", requestID)
+			ctx.Response.Header(lift.HeaderRequestID, requestID)
+			if ctx.Request != nil && ctx.Request.GetHeader(lift.HeaderCorrelationID) != "" {
+				ctx.Response.Header(lift.HeaderCorrelationID, requestID)
+			}
 
 			return next.Handle(ctx)
 		})
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	run := func(headers map[string]string, lambdaID string) (*lift.Context, string) {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Headers: headers}))
+		ctx.RequestID = lambdaID
+		var seen string
+		err := RequestID()(lift.HandlerFunc(func(ctx *lift.Context) error {
+			seen = lift.RequestIDFromContext(ctx.Context)
+			return nil
+		})).Handle(ctx)
+		require.NoError(t, err)
+		return ctx, seen
+	}
+
+	t.Run("uses the caller's request ID", func(t *testing.T) {
+		ctx, seen := run(map[string]string{"x-request-id": "req-1"}, "lambda-1")
+		assert.Equal(t, "req-1", ctx.RequestID)
+		assert.Equal(t, "req-1", seen)
+		assert.Equal(t, "req-1", ctx.Response.Headers[lift.HeaderRequestID])
+		assert.Empty(t, ctx.Response.Headers[lift.HeaderCorrelationID])
+	})
+
+	t.Run("echoes a correlation ID", func(t *testing.T) {
+		ctx, _ := run(map[string]string{"X-Correlation-ID": "corr-1"}, "")
+		assert.Equal(t, "corr-1", ctx.RequestID)
+		assert.Equal(t, "corr-1", ctx.Response.Headers[lift.HeaderRequestID])
+		assert.Equal(t, "corr-1", ctx.Response.Headers[lift.HeaderCorrelationID])
+	})
+
+	t.Run("falls back to the Lambda request ID", func(t *testing.T) {
+		ctx, seen := run(map[string]string{"X-Request-ID": "forged\r\nline"}, "lambda-1")
+		assert.Equal(t, "lambda-1", ctx.RequestID)
+		assert.Equal(t, "lambda-1", seen)
+	})
+
+	t.Run("generates a request ID", func(t *testing.T) {
+		ctx, _ := run(nil, "")
+		assert.True(t, strings.HasPrefix(ctx.RequestID, "req_"), ctx.RequestID)
+		assert.Equal(t, ctx.RequestID, ctx.Response.Headers[lift.HeaderRequestID])
+	})
+}
+
+func TestIAMCaller(t *testing.T) {
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{RawEvent: map[string]any{
+		"requestContext": map[string]any{
+			"authorizer": map[string]any{"iam": map[string]any{"userArn": "arn:aws:iam::123456789012:user/ada"}},
+		},
+	}}))
+	assert.Equal(t, "arn:aws:iam::123456789012:user/ada", IAMCaller(ctx))
+
+	err := RequireIAM("arn:aws:iam::123456789012:user/*")(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return nil
+	})).Handle(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/ada", ctx.Get("iam_arn"))
+
+	err = RequireIAM("arn:aws:iam::123456789012:role/oncall")(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return nil
+	})).Handle(ctx)
+	var liftErr *lift.LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 403, liftErr.StatusCode)
+}
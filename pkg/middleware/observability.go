@@ -168,17 +168,23 @@ func ObservabilityMiddleware(config ObservabilityConfig) lift.Middleware {
 	}
 }
 
-// MetricsOnlyMiddleware provides lightweight metrics collection without logging
+// MetricsOnlyMiddleware provides lightweight metrics collection without
+// logging. Metrics are labelled with the request's method and path and the
+// matched route's labels (see lift.Context.RouteLabels).
 func MetricsOnlyMiddleware(metrics lift.MetricsCollector) lift.Middleware {
 	return func(next lift.Handler) lift.Handler {
 		return lift.HandlerFunc(func(ctx *lift.Context) error {
 			start := time.Now()
 
+			labels := func() map[string]string {
+				tags := ctx.RouteLabels()
+				tags["method"] = ctx.Request.Method
+				tags["path"] = ctx.Request.Path
+				return tags
+			}
+
 			// Record request
-			counter := metrics.Counter("http.requests", map[string]string{
-				"method": ctx.Request.Method,
-				"path":   ctx.Request.Path,
-			})
+			counter := metrics.Counter("http.requests", labels())
 			counter.Inc()
 
 			// Execute handler
@@ -186,19 +192,14 @@ func MetricsOnlyMiddleware(metrics lift.MetricsCollector) lift.Middleware {
 
 			// Record duration
 			duration := time.Since(start)
-			histogram := metrics.Histogram("http.duration", map[string]string{
-				"method": ctx.Request.Method,
-				"path":   ctx.Request.Path,
-				"status": fmt.Sprintf("%d", ctx.Response.StatusCode),
-			})
+			durationTags := labels()
+			durationTags["status"] = fmt.Sprintf("%d", ctx.Response.StatusCode)
+			histogram := metrics.Histogram("http.duration", durationTags)
 			histogram.Observe(float64(duration.Milliseconds()))
 
 			// Record errors
 			if err != nil {
-				errorCounter := metrics.Counter("http.errors", map[string]string{
-					"method": ctx.Request.Method,
-					"path":   ctx.Request.Path,
-				})
+				errorCounter := metrics.Counter("http.errors", labels())
 				errorCounter.Inc()
 			}
 
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// PermissionResolver returns the permissions a user has in a tenant, such
+// as rbac.Service.Permissions. Resolvers should cache, as every request
+// that checks a permission calls them.
+type PermissionResolver func(ctx context.Context, tenantID, userID string) ([]string, error)
+
+// Permissions checks permissions with one resolver, resolving the user's
+// grants at most once per request. The grants are cached on the request
+// under a key of their own, so checks with another resolver never see them.
+type Permissions struct {
+	resolve PermissionResolver
+	key     string
+}
+
+// permissionsInstances numbers Permissions so each has its own cache key
+var permissionsInstances atomic.Uint64
+
+// NewPermissions creates a permission checker for a resolver. Share it
+// between middleware and handlers that check the same grants:
+//
+//	perms := middleware.NewPermissions(roles.Permissions)
+//	refunds := app.Group("/refunds", perms.Require("refunds:create"))
+//	approve := perms.Has(ctx, "refunds:approve")
+func NewPermissions(resolve PermissionResolver) *Permissions {
+	return &Permissions{
+		resolve: resolve,
+		key:     fmt.Sprintf("permissions.%d", permissionsInstances.Add(1)),
+	}
+}
+
+// Require allows requests from users granted every one of the
+// permissions. Permissions are "resource:action" strings; a grant of
+// "resource:*" covers every action on the resource and "*" covers
+// everything. It must run after authentication sets the user and tenant
+// IDs.
+func (p *Permissions) Require(permissions ...string) lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if ctx.UserID() == "" {
+				return lift.Unauthorized("Authentication required")
+			}
+			granted, err := p.granted(ctx)
+			if err != nil {
+				return lift.NewLiftError("PERMISSIONS_UNAVAILABLE", "Failed to check permissions", 503).WithCause(err)
+			}
+			for _, permission := range permissions {
+				if !PermissionGranted(granted, permission) {
+					return lift.NewLiftError("PERMISSION_DENIED", "You do not have permission to do this", 403).
+						WithDetail("permission", permission)
+				}
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// Has reports whether the current user has a permission, for handlers that
+// vary their behaviour rather than refuse the request. It is false without
+// a user, or when the permissions can't be resolved.
+func (p *Permissions) Has(ctx *lift.Context, permission string) bool {
+	if ctx.UserID() == "" {
+		return false
+	}
+	granted, err := p.granted(ctx)
+	return err == nil && PermissionGranted(granted, permission)
+}
+
+// granted looks up the user's permissions once per request
+func (p *Permissions) granted(ctx *lift.Context) ([]string, error) {
+	if granted, ok := ctx.Get(p.key).([]string); ok {
+		return granted, nil
+	}
+	granted, err := p.resolve(ctx.Context, ctx.TenantID(), ctx.UserID())
+	if err != nil {
+		return nil, err
+	}
+	ctx.Set(p.key, granted)
+	return granted, nil
+}
+
+// RequirePermission is NewPermissions(resolve).Require(permissions...),
+// for routes that check permissions only in middleware:
+//
+//	refunds := app.Group("/refunds", middleware.RequirePermission(roles.Permissions, "refunds:create"))
+func RequirePermission(resolve PermissionResolver, permissions ...string) lift.Middleware {
+	return NewPermissions(resolve).Require(permissions...)
+}
+
+// HasPermission is NewPermissions(resolve).Has(ctx, permission). It
+// resolves the permissions on every call; use a shared Permissions to
+// resolve them once per request.
+func HasPermission(ctx *lift.Context, resolve PermissionResolver, permission string) bool {
+	return NewPermissions(resolve).Has(ctx, permission)
+}
+
+// PermissionGranted reports whether the granted permissions cover
+// permission, allowing for "resource:*" and "*" wildcards
+func PermissionGranted(granted []string, permission string) bool {
+	resource, _, _ := strings.Cut(permission, ":")
+	for _, grant := range granted {
+		if grant == permission || grant == "*" || grant == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequirePermission(t *testing.T) {
+	grants := map[string][]string{
+		"ada":   {"refunds:create", "refunds:read"},
+		"grace": {"refunds:*"},
+		"root":  {"*"},
+		"linus": {"payments:read"},
+	}
+	lookups := 0
+	resolve := func(ctx context.Context, tenantID, userID string) ([]string, error) {
+		lookups++
+		if tenantID != "acme" {
+			return nil, errors.New("store unavailable")
+		}
+		return grants[userID], nil
+	}
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.SetUserID(ctx.Header("X-User"))
+			ctx.SetTenantID(ctx.Header("X-Tenant"))
+			return next.Handle(ctx)
+		})
+	})
+	perms := NewPermissions(resolve)
+	refunds := app.Group("/refunds", perms.Require("refunds:create", "refunds:read"))
+	refunds.POST("", func(ctx *lift.Context) error {
+		return ctx.OK(map[string]bool{"approve": perms.Has(ctx, "refunds:approve")})
+	})
+
+	request := func(user, tenant string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  "POST",
+			Path:    "/refunds",
+			Headers: map[string]string{"X-User": user, "X-Tenant": tenant},
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	ctx := request("ada", "acme")
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	assert.Equal(t, map[string]bool{"approve": false}, ctx.Response.Body)
+	assert.Equal(t, 1, lookups, "permissions resolved once per request")
+
+	ctx = request("grace", "acme")
+	assert.Equal(t, map[string]bool{"approve": true}, ctx.Response.Body)
+	assert.Equal(t, 200, request("root", "acme").Response.StatusCode)
+
+	assert.Equal(t, 403, request("linus", "acme").Response.StatusCode)
+	assert.Equal(t, 401, request("", "acme").Response.StatusCode)
+	assert.Equal(t, 503, request("ada", "globex").Response.StatusCode)
+}
+
+func TestPermissionsCachedPerResolver(t *testing.T) {
+	billing := func(ctx context.Context, tenantID, userID string) ([]string, error) {
+		return []string{"invoices:read"}, nil
+	}
+	support := func(ctx context.Context, tenantID, userID string) ([]string, error) {
+		return []string{"tickets:read"}, nil
+	}
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.SetUserID("ada")
+			ctx.SetTenantID("acme")
+			return next.Handle(ctx)
+		})
+	})
+	app.Group("/invoices",
+		RequirePermission(billing, "invoices:read"),
+		RequirePermission(support, "invoices:read"),
+	).GET("", func(ctx *lift.Context) error {
+		return ctx.OK(nil)
+	})
+
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method: "GET",
+		Path:   "/invoices",
+	}))
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 403, ctx.Response.StatusCode, "the second check doesn't reuse the first resolver's grants")
+}
+
+func TestPermissionGranted(t *testing.T) {
+	assert.True(t, PermissionGranted([]string{"refunds:create"}, "refunds:create"))
+	assert.True(t, PermissionGranted([]string{"refunds:*"}, "refunds:create"))
+	assert.True(t, PermissionGranted([]string{"*"}, "refunds:create"))
+	assert.False(t, PermissionGranted([]string{"refunds:read"}, "refunds:create"))
+	assert.False(t, PermissionGranted([]string{"refund:*"}, "refunds:create"))
+	assert.False(t, PermissionGranted(nil, "refunds:create"))
+}
@@ -28,6 +28,7 @@ type RateLimitConfig struct {
 	// Multi-tenant settings
 	TenantLimits map[string]int `json:"tenant_limits"` // Per-tenant limits
 	UserLimits   map[string]int `json:"user_limits"`   // Per-user limits
+	TierLimits   map[string]int `json:"tier_limits"`   // Per API key rate limit tier
 
 	// Key generation
 	KeyPrefix     string                            `json:"key_prefix"`
@@ -230,6 +231,13 @@ func (r *rateLimiter) getLimit(ctx *lift.Context) int {
 		}
 	}
 
+	// Check the limit for the API key's tier
+	if key := APIKeyFromContext(ctx); key != nil && key.RateLimitTier != "" {
+		if limit, exists := r.config.TierLimits[key.RateLimitTier]; exists {
+			return limit
+		}
+	}
+
 	// Check tenant-specific limits
 	if tenantID := ctx.TenantID(); tenantID != "" {
 		if limit, exists := r.config.TenantLimits[tenantID]; exists {
@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/utils/sanitization"
+)
+
+// StackFrame is a single frame of a captured panic stack
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// PanicEvent describes a recovered panic
+type PanicEvent struct {
+	// Value is the value passed to panic. It is never logged or reported.
+	Value any `json:"-"`
+	// Message is the panic value formatted as a string, after redaction
+	Message string `json:"message"`
+	// Type is the Go type of the panic value
+	Type string `json:"type"`
+	// Fingerprint groups panics raised from the same code path
+	Fingerprint string       `json:"fingerprint"`
+	Frames      []StackFrame `json:"frames"`
+	Stack       string       `json:"stack"`
+
+	RequestID string    `json:"request_id,omitempty"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RecoveryOptions configures panic recovery
+type RecoveryOptions struct {
+	// MetricName is the counter incremented for every panic (default: "panics_total")
+	MetricName string
+	// FingerprintDepth is the number of application frames used for the fingerprint (default: 5)
+	FingerprintDepth int
+	// MaxFrames caps the number of captured stack frames (default: 32)
+	MaxFrames int
+	// LogPanicValue includes the panic message in logs. Off by default because
+	// panic values can carry sensitive request data.
+	LogPanicValue bool
+	// OnPanic is called after the panic has been logged and counted
+	OnPanic func(ctx *lift.Context, event *PanicEvent)
+	// Redact scrubs the event before it is logged or reported (default:
+	// RedactPanicEvent)
+	Redact func(event *PanicEvent)
+	// Response builds the error returned to the client (default: a 500
+	// PANIC_RECOVERED error carrying the fingerprint and request ID)
+	Response func(ctx *lift.Context, event *PanicEvent) *lift.LiftError
+}
+
+// Recover provides panic recovery and graceful error handling
+func Recover() Middleware {
+	return RecoverWithOptions(RecoveryOptions{})
+}
+
+// Recovery is RecoverWithOptions for app.Use. Register it first so it
+// covers the middleware after it, and report panics to an error tracker
+// through the app's error observers:
+//
+//	app := lift.New(lift.WithErrorObserver(observability.ErrorObserver(sentryReporter)))
+//	app.Use(middleware.Recovery(middleware.RecoveryOptions{}))
+func Recovery(opts RecoveryOptions) lift.Middleware {
+	return lift.Middleware(RecoverWithOptions(opts))
+}
+
+// RecoverWithOptions provides panic recovery with stack capture, fingerprinting
+// and an error metric tagged by fingerprint. The panic is always returned as a
+// *lift.LiftError carrying the captured stack, even if a hook panics too, so
+// the app's error handlers, observers and problem responses see it like any
+// other error.
+func RecoverWithOptions(opts RecoveryOptions) Middleware {
+	if opts.MetricName == "" {
+		opts.MetricName = "panics_total"
+	}
+	if opts.FingerprintDepth <= 0 {
+		opts.FingerprintDepth = 5
+	}
+	if opts.MaxFrames <= 0 {
+		opts.MaxFrames = 32
+	}
+	if opts.Redact == nil {
+		opts.Redact = RedactPanicEvent
+	}
+	if opts.Response == nil {
+		opts.Response = panicResponse
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					event := NewPanicEvent(r, 3, opts.MaxFrames, opts.FingerprintDepth)
+					enrichPanicEvent(ctx, event)
+					err = handlePanic(ctx, event, opts)
+				}
+			}()
+
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// NewPanicEvent builds a panic event from a recovered value. skip is the number
+// of stack frames to skip, counted from the caller of NewPanicEvent.
+func NewPanicEvent(value any, skip, maxFrames, fingerprintDepth int) *PanicEvent {
+	frames := captureFrames(skip+1, maxFrames)
+
+	event := &PanicEvent{
+		Value:     value,
+		Message:   fmt.Sprintf("%v", value),
+		Type:      fmt.Sprintf("%T", value),
+		Frames:    frames,
+		Stack:     formatFrames(frames),
+		Timestamp: time.Now(),
+	}
+	event.Fingerprint = PanicFingerprint(event.Type, frames, fingerprintDepth)
+
+	return event
+}
+
+// PanicFingerprint computes a stable grouping key from the panic type and the
+// top application frames. Line numbers are excluded so that unrelated edits to
+// the same file don't split a group.
+func PanicFingerprint(panicType string, frames []StackFrame, depth int) string {
+	h := sha256.New()
+	h.Write([]byte(panicType))
+
+	used := 0
+	for _, frame := range frames {
+		if used >= depth {
+			break
+		}
+		if isRuntimeFrame(frame.Function) {
+			continue
+		}
+		h.Write([]byte{0})
+		h.Write([]byte(frame.Function))
+		used++
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// captureFrames collects up to max stack frames, skipping the runtime's panic machinery
+func captureFrames(skip, max int) []StackFrame {
+	pcs := make([]uintptr, max+16)
+	n := runtime.Callers(skip+1, pcs)
+	iter := runtime.CallersFrames(pcs[:n])
+
+	frames := make([]StackFrame, 0, max)
+	for {
+		frame, more := iter.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			frames = append(frames, StackFrame{
+				Function: frame.Function,
+				File:     frame.File,
+				Line:     frame.Line,
+			})
+		}
+		if !more || len(frames) >= max {
+			break
+		}
+	}
+
+	return frames
+}
+
+// isRuntimeFrame reports whether a frame belongs to the runtime or this package's
+// recovery plumbing and should be ignored for fingerprinting
+func isRuntimeFrame(function string) bool {
+	return strings.HasPrefix(function, "runtime.") ||
+		strings.Contains(function, "pkg/middleware.RecoverWithOptions")
+}
+
+func formatFrames(frames []StackFrame) string {
+	var b strings.Builder
+	for _, frame := range frames {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+	return b.String()
+}
+
+// enrichPanicEvent adds request context to a panic event
+func enrichPanicEvent(ctx *lift.Context, event *PanicEvent) {
+	event.RequestID = ctx.RequestID
+	event.TenantID = ctx.TenantID()
+	event.UserID = ctx.UserID()
+	if ctx.Request != nil {
+		event.Method = ctx.Request.Method
+		event.Path = ctx.Request.Path
+	}
+}
+
+// RedactPanicEvent masks credentials, card-like numbers and email
+// addresses in the panic message
+func RedactPanicEvent(event *PanicEvent) {
+	event.Message = sanitization.SanitizeMessage(event.Message)
+}
+
+// panicResponse is the default error returned for a panic
+func panicResponse(ctx *lift.Context, event *PanicEvent) *lift.LiftError {
+	return lift.NewLiftError("PANIC_RECOVERED", "Internal server error", 500).
+		WithDetail("fingerprint", event.Fingerprint).
+		WithRequestID(ctx.RequestID)
+}
+
+// handlePanic logs and counts a panic, then returns the error to
+// respond with. Each hook runs guarded, so a failing one can't prevent the
+// response.
+func handlePanic(ctx *lift.Context, event *PanicEvent, opts RecoveryOptions) *lift.LiftError {
+	guard := func(name string, fn func()) {
+		defer func() {
+			if r := recover(); r != nil && ctx.Logger != nil {
+				ctx.Logger.Error("Panic recovery hook panicked", map[string]any{
+					"hook":        name,
+					"fingerprint": event.Fingerprint,
+				})
+			}
+		}()
+		fn()
+	}
+
+	guard("redact", func() { opts.Redact(event) })
+
+	if ctx.Logger != nil {
+		fields := map[string]any{
+			"panic":       "[REDACTED_PANIC_DETAIL]", // Sanitized for security
+			"panic_type":  event.Type,
+			"fingerprint": event.Fingerprint,
+			"stack":       event.Stack,
+		}
+		if opts.LogPanicValue {
+			fields["panic"] = event.Message
+		}
+		ctx.Logger.Error("Handler panicked", fields)
+	}
+
+	if ctx.Metrics != nil {
+		method := ""
+		if ctx.Request != nil {
+			method = ctx.Request.Method
+		}
+		ctx.Metrics.Counter(opts.MetricName, map[string]string{
+			"fingerprint": event.Fingerprint,
+			"method":      method,
+		}).Inc()
+	}
+
+	if opts.OnPanic != nil {
+		guard("on_panic", func() { opts.OnPanic(ctx, event) })
+	}
+
+	return panicError(ctx, event, opts)
+}
+
+// panicError builds the panic's LiftError, falling back to the default one
+// if the configured builder fails. The stack is attached for error
+// observers; it isn't part of the response.
+func panicError(ctx *lift.Context, event *PanicEvent, opts RecoveryOptions) (liftErr *lift.LiftError) {
+	defer func() {
+		if r := recover(); r != nil {
+			liftErr = panicResponse(ctx, event)
+		}
+		if liftErr.StackTrace == "" {
+			liftErr.StackTrace = event.Stack
+		}
+	}()
+
+	if liftErr = opts.Response(ctx, event); liftErr == nil {
+		liftErr = panicResponse(ctx, event)
+	}
+	return liftErr
+}
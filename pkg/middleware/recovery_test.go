@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/observability"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createRecoveryTestContext() *lift.Context {
+	adapterReq := &adapters.Request{
+		Method:      "GET",
+		Path:        "/orders",
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+		PathParams:  make(map[string]string),
+	}
+	return lift.NewContext(context.Background(), lift.NewRequest(adapterReq))
+}
+
+func panicNilMap(ctx *lift.Context) error {
+	var m map[string]int
+	m["boom"] = 1
+	return nil
+}
+
+func panicWithString(ctx *lift.Context) error {
+	panic("something went wrong")
+}
+
+func TestRecoverWithOptions(t *testing.T) {
+	t.Run("recovers and returns a 500 error", func(t *testing.T) {
+		ctx := createRecoveryTestContext()
+		logger := &mockLogger{}
+		ctx.Logger = logger
+
+		err := Recover()(lift.HandlerFunc(panicWithString)).Handle(ctx)
+		var liftErr *lift.LiftError
+		require.ErrorAs(t, err, &liftErr, "panics are returned as a LiftError")
+		assert.Equal(t, "PANIC_RECOVERED", liftErr.Code)
+		assert.Equal(t, 500, liftErr.StatusCode)
+		assert.NotEmpty(t, liftErr.Details["fingerprint"])
+
+		require.Len(t, logger.logs, 1)
+		assert.Equal(t, "[REDACTED_PANIC_DETAIL]", logger.logs[0]["panic"])
+		assert.Contains(t, logger.logs[0]["stack"], "panicWithString")
+	})
+
+	t.Run("captures event with stack and request context", func(t *testing.T) {
+		var reported *PanicEvent
+		onPanic := func(ctx *lift.Context, event *PanicEvent) {
+			reported = event
+		}
+
+		ctx := createRecoveryTestContext()
+		ctx.RequestID = "req_123"
+		metrics := &mockMetrics{metrics: make(map[string]any)}
+		ctx.Metrics = metrics
+
+		RecoverWithOptions(RecoveryOptions{OnPanic: onPanic})(lift.HandlerFunc(panicNilMap)).Handle(ctx)
+
+		require.NotNil(t, reported)
+		assert.Equal(t, "req_123", reported.RequestID)
+		assert.Equal(t, "/orders", reported.Path)
+		assert.Equal(t, "runtime.plainError", reported.Type)
+		require.NotEmpty(t, reported.Frames)
+		assert.True(t, strings.HasSuffix(reported.Frames[0].Function, "panicNilMap"))
+		assert.Equal(t, 1, metrics.metrics["panics_total"])
+	})
+
+	t.Run("fingerprints group by code path", func(t *testing.T) {
+		fingerprintOf := func(h lift.HandlerFunc) string {
+			var fingerprint string
+			mw := RecoverWithOptions(RecoveryOptions{
+				OnPanic: func(ctx *lift.Context, event *PanicEvent) {
+					fingerprint = event.Fingerprint
+				},
+			})
+			mw(h).Handle(createRecoveryTestContext())
+			return fingerprint
+		}
+
+		first := fingerprintOf(panicWithString)
+		assert.Equal(t, first, fingerprintOf(panicWithString))
+		assert.NotEqual(t, first, fingerprintOf(panicNilMap))
+	})
+}
+
+func TestPanicFingerprint(t *testing.T) {
+	frames := []StackFrame{
+		{Function: "runtime.gopanic"},
+		{Function: "example.com/app.handler", Line: 10},
+		{Function: "example.com/app.main", Line: 20},
+	}
+	moved := []StackFrame{
+		{Function: "example.com/app.handler", Line: 42},
+		{Function: "example.com/app.main", Line: 99},
+	}
+
+	assert.Equal(t, PanicFingerprint("string", frames, 5), PanicFingerprint("string", moved, 5))
+	assert.NotEqual(t, PanicFingerprint("string", frames, 5), PanicFingerprint("error", frames, 5))
+	assert.Len(t, PanicFingerprint("string", frames, 5), 16)
+}
+
+func TestRecoveryRedactsAndAlwaysResponds(t *testing.T) {
+	t.Run("redacts the panic message before the hook", func(t *testing.T) {
+		var reported *PanicEvent
+		mw := RecoverWithOptions(RecoveryOptions{
+			OnPanic: func(ctx *lift.Context, event *PanicEvent) {
+				reported = event
+			},
+		})
+		mw(lift.HandlerFunc(func(ctx *lift.Context) error {
+			panic("charge failed for jane@example.com card 4111 1111 1111 1111 password=hunter2 Authorization: Bearer eyJhbGciOi.x.y")
+		})).Handle(createRecoveryTestContext())
+
+		require.NotNil(t, reported)
+		for _, secret := range []string{"jane@example.com", "4111", "hunter2", "eyJhbGciOi"} {
+			assert.NotContains(t, reported.Message, secret)
+		}
+		assert.Contains(t, reported.Message, "charge failed for")
+	})
+
+	t.Run("panicking hooks don't prevent the response", func(t *testing.T) {
+		ctx := createRecoveryTestContext()
+		mw := RecoverWithOptions(RecoveryOptions{
+			OnPanic: func(ctx *lift.Context, event *PanicEvent) {
+				panic("hook broke")
+			},
+			Response: func(ctx *lift.Context, event *PanicEvent) *lift.LiftError {
+				panic("response builder broke")
+			},
+		})
+
+		err := mw(lift.HandlerFunc(panicWithString)).Handle(ctx)
+		var liftErr *lift.LiftError
+		require.ErrorAs(t, err, &liftErr)
+		assert.Equal(t, "PANIC_RECOVERED", liftErr.Code)
+		assert.Equal(t, 500, liftErr.StatusCode)
+	})
+
+	t.Run("custom response through app.Use", func(t *testing.T) {
+		app := lift.New()
+		app.Use(Recovery(RecoveryOptions{
+			Response: func(ctx *lift.Context, event *PanicEvent) *lift.LiftError {
+				return lift.NewLiftError("SERVICE_UNAVAILABLE", "Try again later", 503)
+			},
+		}))
+		app.GET("/orders", panicWithString)
+
+		ctx := createRecoveryTestContext()
+		require.NoError(t, app.HandleTestRequest(ctx))
+		assert.Equal(t, 503, ctx.Response.StatusCode)
+		assert.Equal(t, "SERVICE_UNAVAILABLE", ctx.Response.Body.(map[string]any)["error"])
+	})
+
+	t.Run("panics reach the app's error handlers and observers", func(t *testing.T) {
+		var observed error
+		app := lift.New(lift.WithErrorObserver(func(ctx *lift.Context, err error) {
+			observed = err
+		}))
+		app.Use(Recovery(RecoveryOptions{}))
+		app.GET("/orders", panicWithString)
+		app.OnError(func(ctx *lift.Context, err error) error {
+			var liftErr *lift.LiftError
+			if errors.As(err, &liftErr) && liftErr.Code == "PANIC_RECOVERED" {
+				return lift.NewLiftError("UNAVAILABLE", "Try again later", 503)
+			}
+			return err
+		})
+
+		ctx := createRecoveryTestContext()
+		require.NoError(t, app.HandleTestRequest(ctx))
+		assert.Equal(t, 503, ctx.Response.StatusCode)
+		assert.Equal(t, "UNAVAILABLE", ctx.Response.Body.(map[string]any)["error"])
+
+		var liftErr *lift.LiftError
+		require.ErrorAs(t, observed, &liftErr)
+		assert.Equal(t, "PANIC_RECOVERED", liftErr.Code)
+	})
+	t.Run("panics are reported once, through the error observer", func(t *testing.T) {
+		reporter := &recordingErrorReporter{}
+		app := lift.New(lift.WithErrorObserver(observability.ErrorObserver(reporter)))
+		app.Use(Recovery(RecoveryOptions{}))
+		app.GET("/orders", panicWithString)
+
+		ctx := createRecoveryTestContext()
+		require.NoError(t, app.HandleTestRequest(ctx))
+		assert.Equal(t, 500, ctx.Response.StatusCode)
+
+		require.Len(t, reporter.events, 1)
+		event := reporter.events[0]
+		assert.Equal(t, observability.ErrorLevelFatal, event.Level)
+		assert.Equal(t, "PANIC_RECOVERED", event.Code)
+		assert.NotEmpty(t, event.Fingerprint)
+		assert.Contains(t, event.Stack, "panicWithString")
+		assert.NotContains(t, ctx.Response.Body.(map[string]any), "stack_trace")
+	})
+}
+
+// recordingErrorReporter captures reported error events
+type recordingErrorReporter struct {
+	events []*observability.ErrorEvent
+}
+
+func (r *recordingErrorReporter) Report(ctx context.Context, event *observability.ErrorEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recordingErrorReporter) Flush(timeout time.Duration) error { return nil }
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/validation"
+)
+
+// ProblemField describes a field that failed validation
+type ProblemField struct {
+	// Field is the field's path, e.g. "items[0].sku"
+	Field string `json:"field"`
+	// Constraint is the rule that failed, e.g. "min=3"
+	Constraint string `json:"constraint"`
+	// Code is a machine-readable reason, e.g. "too_short"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// RequestValidationConfig configures ValidateRequest
+type RequestValidationConfig struct {
+	// Validator validates request bodies (default: built-in rules with
+	// fields named by their json tags)
+	Validator *validation.Validator
+	// Status is the status of invalid requests (default: 422)
+	Status int
+	// Type is the problem type URI (default: the app's problem type for
+	// VALIDATION_ERROR, or "about:blank")
+	Type string
+	// Title is the problem title (default: "Validation failed")
+	Title string
+	// Locale picks the language of messages (default: the first
+	// Accept-Language tag)
+	Locale func(ctx *lift.Context) string
+}
+
+const validatedRequestKey = "validated_request"
+
+// ValidateRequest decodes JSON request bodies into T, in the same decode
+// mode as ctx.ParseRequest, and validates them by their `validate` tags.
+// Invalid requests get an application/problem+json response, rendered
+// with the app's WithProblemDetails options, whose "errors" member lists
+// each failing field, its constraint and a code:
+//
+//	validate := middleware.ValidateRequest[CreateUser](middleware.RequestValidationConfig{})
+//	app.POST("/users", validate(lift.HandlerFunc(createUser)))
+//
+// Handlers read the validated request with ValidatedRequest.
+func ValidateRequest[T any](config RequestValidationConfig) lift.Middleware {
+	if config.Validator == nil {
+		config.Validator = validation.New().FieldNames("json")
+	}
+	if config.Status == 0 {
+		config.Status = 422
+	}
+	if config.Title == "" {
+		config.Title = "Validation failed"
+	}
+	if config.Locale == nil {
+		config.Locale = acceptLanguage
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			var req T
+			if err := ctx.Decode(&req); err != nil {
+				var liftErr *lift.LiftError
+				if !errors.As(err, &liftErr) {
+					return err
+				}
+				return ctx.WriteProblem(liftErr, func(problem *lift.Problem) {
+					problem.Extensions["errors"] = []ProblemField{}
+				})
+			}
+
+			err := config.Validator.ValidateLocale(&req, config.Locale(ctx))
+			if err == nil {
+				ctx.Set(validatedRequestKey, req)
+				return next.Handle(ctx)
+			}
+
+			var validationErrors validation.ValidationErrors
+			if !errors.As(err, &validationErrors) {
+				return err
+			}
+			fields := make([]ProblemField, 0, len(validationErrors))
+			for _, ve := range validationErrors {
+				constraint := ve.Tag
+				if ve.Param != "" {
+					constraint += "=" + ve.Param
+				}
+				fields = append(fields, ProblemField{
+					Field:      ve.Field,
+					Constraint: constraint,
+					Code:       ve.Code,
+					Message:    ve.Message,
+				})
+			}
+			problemErr := lift.NewLiftError("VALIDATION_ERROR", "One or more fields failed validation", config.Status).WithCause(err)
+			return ctx.WriteProblem(problemErr, func(problem *lift.Problem) {
+				problem.Title = config.Title
+				if config.Type != "" {
+					problem.Type = config.Type
+				}
+				problem.Extensions["errors"] = fields
+			})
+		})
+	}
+}
+
+// ValidatedRequest returns the request ValidateRequest validated
+func ValidatedRequest[T any](ctx *lift.Context) (T, bool) {
+	req, ok := ctx.Get(validatedRequestKey).(T)
+	return req, ok
+}
+
+// acceptLanguage returns the first language of the Accept-Language header
+func acceptLanguage(ctx *lift.Context) string {
+	header := ctx.Header("Accept-Language")
+	if header == "" {
+		header = ctx.Header("accept-language")
+	}
+	language, _, _ := strings.Cut(header, ",")
+	language, _, _ = strings.Cut(language, ";")
+	return strings.TrimSpace(language)
+}
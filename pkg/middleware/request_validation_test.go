@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validatedLine struct {
+	SKU string `json:"sku" validate:"required"`
+	Qty int    `json:"qty" validate:"min=1"`
+}
+
+type validatedOrder struct {
+	Email    string          `json:"email" validate:"required,email"`
+	Currency string          `json:"currency" validate:"required,currency"`
+	Note     string          `json:"note" validate:"omitempty,min=3"`
+	Lines    []validatedLine `json:"lines" validate:"required"`
+}
+
+func newOrderValidator() *validation.Validator {
+	return validation.New().
+		FieldNames("json").
+		RegisterRule("currency", "invalid_currency", "{field} must be an ISO 4217 code", func(field reflect.Value, param string) bool {
+			code := field.String()
+			return code == "" || (len(code) == 3 && strings.ToUpper(code) == code)
+		}).
+		RegisterMessages("fr", map[string]string{
+			validation.CodeRequired: "{field} est obligatoire",
+			validation.CodeTooSmall: "{field} doit être au moins {param}",
+		})
+}
+
+func TestValidateRequest(t *testing.T) {
+	handler := ValidateRequest[validatedOrder](RequestValidationConfig{Validator: newOrderValidator()})(
+		lift.HandlerFunc(func(ctx *lift.Context) error {
+			order, ok := ValidatedRequest[validatedOrder](ctx)
+			require.True(t, ok)
+			return ctx.OK(order)
+		}))
+
+	t.Run("valid request", func(t *testing.T) {
+		ctx := createValidationTestContext(&adapters.Request{
+			Method: "POST",
+			Path:   "/orders",
+			Body:   []byte(`{"email": "ada@example.com", "currency": "EUR", "lines": [{"sku": "a", "qty": 2}]}`),
+		})
+		require.NoError(t, handler.Handle(ctx))
+		assert.Equal(t, 200, ctx.Response.StatusCode)
+		assert.Equal(t, "EUR", ctx.Response.Body.(validatedOrder).Currency)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		ctx := createValidationTestContext(&adapters.Request{
+			Method: "POST",
+			Path:   "/orders",
+			Body:   []byte(`{"email": "ada", "currency": "eur", "note": "hi", "lines": [{"sku": "a", "qty": 2}, {"qty": 0}]}`),
+		})
+		require.NoError(t, handler.Handle(ctx))
+		assert.Equal(t, 422, ctx.Response.StatusCode)
+		assert.Equal(t, "application/problem+json", ctx.Response.Headers["Content-Type"])
+
+		problem := ctx.Response.Body.(*lift.Problem)
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, "Validation failed", problem.Title)
+		assert.Equal(t, 422, problem.Status)
+		assert.Equal(t, "/orders", problem.Instance)
+		assert.Equal(t, "VALIDATION_ERROR", problem.Extensions["code"])
+		assert.Equal(t, []ProblemField{
+			{Field: "email", Constraint: "email", Code: validation.CodeInvalidEmail, Message: "field must be a valid email address"},
+			{Field: "currency", Constraint: "currency", Code: "invalid_currency", Message: "currency must be an ISO 4217 code"},
+			{Field: "note", Constraint: "min=3", Code: validation.CodeTooShort, Message: "field must be at least 3 characters"},
+			{Field: "lines[1].sku", Constraint: "required", Code: validation.CodeRequired, Message: "field is required"},
+			{Field: "lines[1].qty", Constraint: "min=1", Code: validation.CodeTooSmall, Message: "field must be at least 1"},
+		}, problem.Extensions["errors"])
+	})
+
+	t.Run("translated messages", func(t *testing.T) {
+		ctx := createValidationTestContext(&adapters.Request{
+			Method:  "POST",
+			Path:    "/orders",
+			Headers: map[string]string{"Accept-Language": "fr-CA,fr;q=0.9,en;q=0.8"},
+			Body:    []byte(`{"email": "ada@example.com", "lines": [{"sku": "a"}]}`),
+		})
+		require.NoError(t, handler.Handle(ctx))
+		assert.Equal(t, []ProblemField{
+			{Field: "currency", Constraint: "required", Code: validation.CodeRequired, Message: "currency est obligatoire"},
+			{Field: "lines[0].qty", Constraint: "min=1", Code: validation.CodeTooSmall, Message: "lines[0].qty doit être au moins 1"},
+		}, ctx.Response.Body.(*lift.Problem).Extensions["errors"])
+	})
+
+	t.Run("malformed JSON", func(t *testing.T) {
+		ctx := createValidationTestContext(&adapters.Request{
+			Method: "POST",
+			Path:   "/orders",
+			Body:   []byte(`{"email": `),
+		})
+		require.NoError(t, handler.Handle(ctx))
+		assert.Equal(t, 400, ctx.Response.StatusCode)
+		problem := ctx.Response.Body.(*lift.Problem)
+		assert.Equal(t, "Invalid JSON in request body", problem.Detail)
+		assert.Empty(t, problem.Extensions["errors"])
+	})
+
+	t.Run("decodes in the request's decode mode", func(t *testing.T) {
+		ctx := createValidationTestContext(&adapters.Request{
+			Method:  "POST",
+			Path:    "/orders",
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    []byte(`{"email": "ada@example.com", "currency": "EUR", "lines": [{"sku": "a", "qty": 2}], "coupon": "SAVE10"}`),
+		})
+		ctx.SetDecodeMode(lift.DecodeStrict)
+		require.NoError(t, handler.Handle(ctx))
+		assert.Equal(t, 400, ctx.Response.StatusCode)
+		assert.Equal(t, `Unknown field "coupon"`, ctx.Response.Body.(*lift.Problem).Detail)
+	})
+}
+
+func TestValidateRequestUsesAppProblemOptions(t *testing.T) {
+	app := lift.New(lift.WithProblemDetails(lift.ProblemOptions{
+		TypeBaseURI: "https://errors.example.com/",
+		Extend: func(ctx *lift.Context, problem *lift.Problem, err error) {
+			problem.Extensions["tenant_id"] = ctx.Header("X-Tenant-ID")
+		},
+	}))
+	validate := ValidateRequest[validatedOrder](RequestValidationConfig{Validator: newOrderValidator()})
+	app.POST("/orders", validate(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return ctx.Created(nil)
+	})))
+
+	ctx := createValidationTestContext(&adapters.Request{
+		Method:  "POST",
+		Path:    "/orders",
+		Headers: map[string]string{"X-Tenant-ID": "tenant-1"},
+		Body:    []byte(`{"email": "ada@example.com", "currency": "EUR"}`),
+	})
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 422, ctx.Response.StatusCode)
+	assert.Equal(t, lift.ProblemContentType, ctx.Response.Headers["Content-Type"])
+
+	problem := ctx.Response.Body.(*lift.Problem)
+	assert.Equal(t, "https://errors.example.com/validation-error", problem.Type)
+	assert.Equal(t, "tenant-1", problem.Extensions["tenant_id"])
+	assert.Equal(t, []ProblemField{
+		{Field: "lines", Constraint: "required", Code: validation.CodeRequired, Message: "field is required"},
+	}, problem.Extensions["errors"])
+}
@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// ResidencyForwardedHeader marks a request proxied to its home region, so a
+// misrouted request is rejected rather than proxied again
+const ResidencyForwardedHeader = "X-Lift-Residency-Forwarded"
+
+// ResidencyMode is what happens to a request served outside its tenant's
+// home region
+type ResidencyMode string
+
+const (
+	// ResidencyReject rejects cross-region requests with 421 Misdirected Request
+	ResidencyReject ResidencyMode = "reject"
+	// ResidencyProxy forwards cross-region requests to the home region's deployment
+	ResidencyProxy ResidencyMode = "proxy"
+)
+
+// ResidencyResolver maps a tenant to the region its data must stay in
+type ResidencyResolver interface {
+	HomeRegion(ctx context.Context, tenantID string) (string, error)
+}
+
+// ResidencyResolverFunc adapts a function to the ResidencyResolver interface
+type ResidencyResolverFunc func(ctx context.Context, tenantID string) (string, error)
+
+// HomeRegion calls f(ctx, tenantID)
+func (f ResidencyResolverFunc) HomeRegion(ctx context.Context, tenantID string) (string, error) {
+	return f(ctx, tenantID)
+}
+
+// StaticResidency resolves home regions from a fixed map of tenant ID to region
+type StaticResidency map[string]string
+
+// HomeRegion returns the tenant's region, or "" if it isn't listed
+func (s StaticResidency) HomeRegion(ctx context.Context, tenantID string) (string, error) {
+	return s[tenantID], nil
+}
+
+// ResidencyDecision records how a request was routed
+type ResidencyDecision struct {
+	TenantID   string `json:"tenant_id"`
+	HomeRegion string `json:"home_region"`
+	Region     string `json:"region"`
+	// Action is "local", "proxied" or "rejected"
+	Action string `json:"action"`
+	Path   string `json:"path"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ResidencyConfig configures DataResidency
+type ResidencyConfig struct {
+	// Region is the region this deployment serves (default: AWS_REGION)
+	Region string
+	// Resolver maps tenants to home regions
+	Resolver ResidencyResolver
+	// Mode handles cross-region requests (default: ResidencyReject)
+	Mode ResidencyMode
+	// Endpoints maps regions to the base URLs of their deployments; required
+	// for ResidencyProxy
+	Endpoints map[string]string
+	// Client sends proxied requests (default: a client with a 25s timeout)
+	Client *http.Client
+	// DefaultRegion is used for tenants the resolver doesn't know; empty
+	// rejects them, since their residency can't be guaranteed
+	DefaultRegion string
+	// CacheTTL caches resolved regions per tenant (default: 5m)
+	CacheTTL time.Duration
+	// OnDecision is called for every tenant request, e.g. to record the
+	// decision in the audit trail
+	OnDecision func(ctx *lift.Context, decision ResidencyDecision)
+}
+
+type residencyEntry struct {
+	region   string
+	loadedAt time.Time
+}
+
+// DataResidency keeps each tenant's requests in its home region. Requests
+// for tenants homed elsewhere are rejected or proxied to the home region's
+// deployment. It tags the request with the tenant's home region and the
+// serving region, which the security middleware's audit events include:
+//
+//	app.Use(middleware.JWT(jwtConfig))
+//	app.Use(middleware.DataResidency(middleware.ResidencyConfig{
+//		Resolver: tenantRegions,
+//		Mode:     middleware.ResidencyProxy,
+//		Endpoints: map[string]string{
+//			"eu-west-1": "https://api.eu.example.com",
+//			"us-east-1": "https://api.us.example.com",
+//		},
+//	}))
+//
+// Register it after authentication so the tenant is known. Requests without
+// a tenant pass through.
+func DataResidency(config ResidencyConfig) lift.Middleware {
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Mode == "" {
+		config.Mode = ResidencyReject
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 25 * time.Second}
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 5 * time.Minute
+	}
+
+	var mu sync.RWMutex
+	cache := make(map[string]residencyEntry)
+
+	resolve := func(ctx context.Context, tenantID string) (string, error) {
+		mu.RLock()
+		entry, ok := cache[tenantID]
+		mu.RUnlock()
+		if ok && time.Since(entry.loadedAt) < config.CacheTTL {
+			return entry.region, nil
+		}
+
+		region, err := config.Resolver.HomeRegion(ctx, tenantID)
+		if err != nil {
+			return "", err
+		}
+		if region == "" {
+			region = config.DefaultRegion
+		}
+
+		mu.Lock()
+		cache[tenantID] = residencyEntry{region: region, loadedAt: time.Now()}
+		mu.Unlock()
+		return region, nil
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			tenantID := ctx.TenantID()
+			if tenantID == "" {
+				return next.Handle(ctx)
+			}
+
+			decision := ResidencyDecision{TenantID: tenantID, Region: config.Region, Path: ctx.Request.Path}
+			record := func(action string, err error) {
+				decision.Action = action
+				if err != nil {
+					decision.Error = err.Error()
+				}
+				if config.OnDecision != nil {
+					config.OnDecision(ctx, decision)
+				}
+			}
+
+			home, err := resolve(ctx.Context, tenantID)
+			if err != nil {
+				record("rejected", err)
+				return lift.NewLiftError("RESIDENCY_UNAVAILABLE", "Failed to determine data residency", 503).WithCause(err)
+			}
+			if home == "" {
+				record("rejected", nil)
+				return lift.NewLiftError("RESIDENCY_UNKNOWN", "No home region is configured for this tenant", 403)
+			}
+
+			decision.HomeRegion = home
+			ctx.Set("data_residency", home)
+			ctx.Set("serving_region", config.Region)
+
+			if home == config.Region {
+				record("local", nil)
+				return next.Handle(ctx)
+			}
+
+			endpoint, hasEndpoint := config.Endpoints[home]
+			if config.Mode != ResidencyProxy || !hasEndpoint || ctx.Header(ResidencyForwardedHeader) != "" {
+				record("rejected", nil)
+				return lift.NewLiftError("WRONG_REGION", "This tenant's data is served from another region", 421).
+					WithDetail("home_region", home)
+			}
+
+			if err := proxyToRegion(ctx, config.Client, endpoint, config.Region); err != nil {
+				record("rejected", err)
+				return lift.NewLiftError("RESIDENCY_PROXY_FAILED", "Failed to reach the tenant's home region", 502).WithCause(err)
+			}
+			record("proxied", nil)
+			return nil
+		})
+	}
+}
+
+// proxyToRegion forwards the request to another regional deployment and
+// writes its response
+func proxyToRegion(ctx *lift.Context, client *http.Client, baseURL, fromRegion string) error {
+	target := strings.TrimSuffix(baseURL, "/") + ctx.Request.Path
+	if len(ctx.Request.QueryParams) > 0 {
+		query := url.Values{}
+		for key, value := range ctx.Request.QueryParams {
+			query.Set(key, value)
+		}
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx.Context, ctx.Request.Method, target, bytes.NewReader(ctx.Request.Body))
+	if err != nil {
+		return err
+	}
+	for name, value := range ctx.Request.Headers {
+		if !hopByHopHeader(name) {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Header.Set(ResidencyForwardedHeader, fromRegion)
+	if ctx.RequestID != "" {
+		req.Header.Set(lift.HeaderRequestID, ctx.RequestID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// API Gateway caps responses at 10MB
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read response from %s: %w", baseURL, err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	ctx.Response.Status(resp.StatusCode)
+	if isTextContent(contentType) {
+		err = ctx.Response.Text(string(body))
+	} else {
+		err = ctx.Response.Binary(body)
+	}
+	if err != nil {
+		return err
+	}
+	for name := range resp.Header {
+		if !hopByHopHeader(name) {
+			ctx.Response.Header(name, resp.Header.Get(name))
+		}
+	}
+	return nil
+}
+
+// hopByHopHeader reports whether a header applies to a single connection
+// and must not be forwarded
+func hopByHopHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+		"Te", "Trailer", "Transfer-Encoding", "Upgrade", "Host", "Content-Length":
+		return true
+	}
+	return false
+}
+
+// isTextContent reports whether a content type can be returned as text
+func isTextContent(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "" ||
+		strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml")
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func residencyApp(config ResidencyConfig) (*lift.App, *[]ResidencyDecision) {
+	var decisions []ResidencyDecision
+	config.OnDecision = func(ctx *lift.Context, decision ResidencyDecision) {
+		decisions = append(decisions, decision)
+	}
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.SetTenantID(ctx.Header("X-Tenant"))
+			return next.Handle(ctx)
+		})
+	})
+	app.Use(DataResidency(config))
+	app.GET("/payments", func(ctx *lift.Context) error {
+		audit := lift.NewSecurityContext(ctx).ToAuditMap()
+		return ctx.OK(map[string]any{"served_by": "us-east-1", "residency": audit["data_residency"]})
+	})
+	return app, &decisions
+}
+
+func residencyRequest(t *testing.T, app *lift.App, tenant string, headers map[string]string) *lift.Context {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["X-Tenant"] = tenant
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:      "GET",
+		Path:        "/payments",
+		Headers:     headers,
+		QueryParams: map[string]string{"limit": "10"},
+	}))
+	require.NoError(t, app.HandleTestRequest(ctx))
+	return ctx
+}
+
+func TestDataResidencyReject(t *testing.T) {
+	app, decisions := residencyApp(ResidencyConfig{
+		Region:   "us-east-1",
+		Resolver: StaticResidency{"acme": "us-east-1", "globex": "eu-west-1"},
+	})
+
+	ctx := residencyRequest(t, app, "acme", nil)
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	assert.Equal(t, "us-east-1", ctx.Response.Body.(map[string]any)["residency"])
+
+	ctx = residencyRequest(t, app, "globex", nil)
+	assert.Equal(t, 421, ctx.Response.StatusCode)
+
+	// Unknown tenants are rejected without a default region
+	ctx = residencyRequest(t, app, "initech", nil)
+	assert.Equal(t, 403, ctx.Response.StatusCode)
+
+	// Requests without a tenant pass through
+	ctx = residencyRequest(t, app, "", nil)
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+
+	require.Len(t, *decisions, 3)
+	assert.Equal(t, "local", (*decisions)[0].Action)
+	assert.Equal(t, ResidencyDecision{TenantID: "globex", HomeRegion: "eu-west-1", Region: "us-east-1", Action: "rejected", Path: "/payments"}, (*decisions)[1])
+}
+
+func TestDataResidencyProxy(t *testing.T) {
+	var forwarded *http.Request
+	eu := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Served-By", "eu-west-1")
+		w.WriteHeader(201)
+		_, _ = w.Write([]byte(`{"served_by":"eu-west-1"}`))
+	}))
+	defer eu.Close()
+
+	app, decisions := residencyApp(ResidencyConfig{
+		Region:    "us-east-1",
+		Resolver:  StaticResidency{"globex": "eu-west-1", "hooli": "ap-south-1"},
+		Mode:      ResidencyProxy,
+		Endpoints: map[string]string{"eu-west-1": eu.URL},
+	})
+
+	ctx := residencyRequest(t, app, "globex", map[string]string{"Authorization": "Bearer token", "Connection": "keep-alive"})
+	assert.Equal(t, 201, ctx.Response.StatusCode)
+	assert.Equal(t, `{"served_by":"eu-west-1"}`, ctx.Response.Body)
+	assert.Equal(t, "application/json", ctx.Response.Headers["Content-Type"])
+	assert.Equal(t, "eu-west-1", ctx.Response.Headers["X-Served-By"])
+
+	require.NotNil(t, forwarded)
+	assert.Equal(t, "/payments", forwarded.URL.Path)
+	assert.Equal(t, "10", forwarded.URL.Query().Get("limit"))
+	assert.Equal(t, "Bearer token", forwarded.Header.Get("Authorization"))
+	assert.Equal(t, "us-east-1", forwarded.Header.Get(ResidencyForwardedHeader))
+	assert.Equal(t, "proxied", (*decisions)[0].Action)
+
+	// A request that was already forwarded is never proxied again
+	ctx = residencyRequest(t, app, "globex", map[string]string{ResidencyForwardedHeader: "ap-south-1"})
+	assert.Equal(t, 421, ctx.Response.StatusCode)
+
+	// Regions without an endpoint are rejected
+	ctx = residencyRequest(t, app, "hooli", nil)
+	assert.Equal(t, 421, ctx.Response.StatusCode)
+}
+
+func TestDataResidencyResolverFailure(t *testing.T) {
+	calls := 0
+	app, _ := residencyApp(ResidencyConfig{
+		Region:        "us-east-1",
+		DefaultRegion: "us-east-1",
+		Resolver: ResidencyResolverFunc(func(ctx context.Context, tenantID string) (string, error) {
+			calls++
+			if tenantID == "broken" {
+				return "", errors.New("table unavailable")
+			}
+			return "", nil
+		}),
+	})
+
+	assert.Equal(t, 503, residencyRequest(t, app, "broken", nil).Response.StatusCode)
+
+	// Unknown tenants use the default region, and lookups are cached
+	assert.Equal(t, 200, residencyRequest(t, app, "acme", nil).Response.StatusCode)
+	assert.Equal(t, 200, residencyRequest(t, app, "acme", nil).Response.StatusCode)
+	assert.Equal(t, 2, calls)
+}
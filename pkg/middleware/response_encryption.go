@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// ResponseEncryptionConfig configures ResponseEncryption
+type ResponseEncryptionConfig struct {
+	// Client identifies the calling API client (default: the "client_id"
+	// JWT claim, falling back to the account ID)
+	Client func(ctx *lift.Context) string
+	// Optional sends plaintext to clients without a registered key. By
+	// default such requests are refused before the handler runs.
+	Optional bool
+}
+
+// ResponseEncryption encrypts response data as JWE for the calling API
+// client on routes flagged with lift.MetaEncrypt, so card and bank details
+// pass through gateways, logs and caches only as ciphertext:
+//
+//	keys := security.StaticJWEKeys{"acme-pos": acmeKey}
+//	app.Use(middleware.ResponseEncryption(keys, middleware.ResponseEncryptionConfig{}))
+//	app.GET("/cards/:id", getCard).Meta(lift.MetaEncrypt, "number,cvv,billing.postal_code")
+//	app.GET("/cards/:id/export", exportCard).Meta(lift.MetaEncrypt, "*")
+//
+// Each listed field is replaced by a compact JWE of its JSON value; paths
+// that traverse an array apply to every element, and missing fields are
+// skipped. With "*" the whole body becomes a JWE sent as application/jose.
+// Error responses aren't encrypted. Register it after authentication.
+func ResponseEncryption(keys security.JWEKeyStore, config ResponseEncryptionConfig) lift.Middleware {
+	if config.Client == nil {
+		config.Client = defaultEncryptionClient
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			spec, ok := ctx.RouteMeta(lift.MetaEncrypt)
+			if !ok || strings.TrimSpace(spec) == "" {
+				return next.Handle(ctx)
+			}
+
+			// Resolve the key first so a client that can't receive the
+			// response never triggers the handler's side effects
+			clientID := config.Client(ctx)
+			var key *security.JWEKey
+			if clientID != "" {
+				var err error
+				key, err = keys.EncryptionKey(ctx.Context, clientID)
+				if err != nil {
+					return lift.NewLiftError("ENCRYPTION_KEY_UNAVAILABLE", "Failed to load the client encryption key", 503).WithCause(err)
+				}
+			}
+			if key == nil {
+				if config.Optional {
+					return next.Handle(ctx)
+				}
+				return lift.NewLiftError("ENCRYPTION_KEY_REQUIRED", "An encryption key must be registered to call this endpoint", 403).
+					WithDetail("client_id", clientID)
+			}
+
+			if err := next.Handle(ctx); err != nil {
+				return err
+			}
+			if ctx.Response.StatusCode >= 400 || ctx.Response.Body == nil {
+				return nil
+			}
+
+			body, err := responseBodyBytes(ctx.Response.Body)
+			if err != nil {
+				return lift.NewLiftError("RESPONSE_ENCRYPTION_FAILED", "Failed to encrypt response", 500).WithCause(err)
+			}
+
+			if strings.TrimSpace(spec) == "*" {
+				jwe, err := security.EncryptJWE(key, body, "json")
+				if err != nil {
+					return lift.NewLiftError("RESPONSE_ENCRYPTION_FAILED", "Failed to encrypt response", 500).WithCause(err)
+				}
+				ctx.Response.Body = jwe
+				ctx.Response.Header("Content-Type", "application/jose")
+				return nil
+			}
+
+			var doc any
+			if err := json.Unmarshal(body, &doc); err != nil {
+				return lift.NewLiftError("RESPONSE_ENCRYPTION_FAILED", "Failed to encrypt response", 500).WithCause(err)
+			}
+			for _, path := range strings.Split(spec, ",") {
+				if path = strings.TrimSpace(path); path == "" {
+					continue
+				}
+				if err := encryptField(doc, strings.Split(path, "."), key); err != nil {
+					return lift.NewLiftError("RESPONSE_ENCRYPTION_FAILED", "Failed to encrypt response", 500).
+						WithCause(err).
+						WithDetail("field", path)
+				}
+			}
+			ctx.Response.Body = doc
+			return nil
+		})
+	}
+}
+
+// encryptField replaces the value at path within node with its JWE
+func encryptField(node any, path []string, key *security.JWEKey) error {
+	switch v := node.(type) {
+	case []any:
+		for _, item := range v {
+			if err := encryptField(item, path, key); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		value, ok := v[path[0]]
+		if !ok || value == nil {
+			return nil
+		}
+		if len(path) > 1 {
+			return encryptField(value, path[1:], key)
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		jwe, err := security.EncryptJWE(key, plaintext, "json")
+		if err != nil {
+			return err
+		}
+		v[path[0]] = jwe
+	}
+	return nil
+}
+
+// defaultEncryptionClient identifies the client by its "client_id" claim
+// or account
+func defaultEncryptionClient(ctx *lift.Context) string {
+	if clientID, ok := ctx.GetClaim("client_id").(string); ok && clientID != "" {
+		return clientID
+	}
+	return ctx.AccountID()
+}
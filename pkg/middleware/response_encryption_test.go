@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseEncryption(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys := security.StaticJWEKeys{"acme": {ID: "acme-2024", Key: &privateKey.PublicKey}}
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.Set("account_id", ctx.Header("X-Client"))
+			return next.Handle(ctx)
+		})
+	})
+	app.Use(ResponseEncryption(keys, ResponseEncryptionConfig{}))
+
+	calls := 0
+	card := func(ctx *lift.Context) error {
+		calls++
+		return ctx.OK(map[string]any{
+			"id":      "card_1",
+			"number":  "4111111111111111",
+			"billing": map[string]any{"postal_code": "94107", "country": "US"},
+			"history": []map[string]any{{"last4": "1111"}, {"last4": "4242"}},
+		})
+	}
+	app.GET("/cards/:id", card).Meta(lift.MetaEncrypt, "number, billing.postal_code, history.last4, missing.field")
+	app.GET("/cards/:id/export", card).Meta(lift.MetaEncrypt, "*")
+	app.GET("/cards/:id/brand", card)
+	app.GET("/cards/:id/fail", func(ctx *lift.Context) error {
+		return lift.NotFound("Card not found")
+	}).Meta(lift.MetaEncrypt, "*")
+
+	request := func(path, client string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  "GET",
+			Path:    path,
+			Headers: map[string]string{"X-Client": client},
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+	decrypt := func(jwe any) string {
+		plaintext, header, err := security.DecryptJWE(privateKey, jwe.(string))
+		require.NoError(t, err)
+		assert.Equal(t, "acme-2024", header.KeyID)
+		return string(plaintext)
+	}
+
+	fields := request("/cards/1", "acme")
+	require.Equal(t, 200, fields.Response.StatusCode)
+	body := fields.Response.Body.(map[string]any)
+	assert.Equal(t, "card_1", body["id"])
+	assert.Equal(t, `"4111111111111111"`, decrypt(body["number"]))
+	billing := body["billing"].(map[string]any)
+	assert.Equal(t, `"94107"`, decrypt(billing["postal_code"]))
+	assert.Equal(t, "US", billing["country"])
+	history := body["history"].([]any)
+	assert.Equal(t, `"4242"`, decrypt(history[1].(map[string]any)["last4"]))
+
+	whole := request("/cards/1/export", "acme")
+	assert.Equal(t, "application/jose", whole.Response.Headers["Content-Type"])
+	assert.Contains(t, decrypt(whole.Response.Body), `"number":"4111111111111111"`)
+
+	assert.Equal(t, 404, request("/cards/1/fail", "acme").Response.StatusCode)
+
+	plain := request("/cards/1/brand", "globex")
+	assert.Equal(t, "4111111111111111", plain.Response.Body.(map[string]any)["number"], "unflagged routes are untouched")
+
+	before := calls
+	assert.Equal(t, 403, request("/cards/1", "globex").Response.StatusCode)
+	assert.Equal(t, before, calls, "clients without a key are refused before the handler runs")
+}
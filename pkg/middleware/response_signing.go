@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// ResponseSigningConfig configures ResponseSigning
+type ResponseSigningConfig struct {
+	// Header carries the detached JWS (default: "X-JWS-Signature")
+	Header string
+	// FailOpen sends the response unsigned if signing fails. By default the
+	// request fails instead, since consumers of signed endpoints treat a
+	// missing signature as tampering.
+	FailOpen bool
+	// Now returns the signing time (default: time.Now)
+	Now func() time.Time
+}
+
+// ResponseSigning signs successful response bodies with a detached JWS, so
+// consumers of high-integrity endpoints can verify a body came from this
+// service unmodified. The protected header carries the key ID, signing time,
+// request path and status code:
+//
+//	signer, _ := security.NewKMSSigner(kmsClient, "alias/api-response-signing", "ES256")
+//	ledger := app.Group("/balances", middleware.ResponseSigning(signer, middleware.ResponseSigningConfig{}))
+//	app.GET("/.well-known/response-signing-key", middleware.ResponseSigningKeyHandler(signer))
+//
+// Consumers verify with security.VerifyDetachedJWS over the raw body bytes.
+// Error responses aren't signed.
+func ResponseSigning(signer security.ResponseSigner, config ResponseSigningConfig) lift.Middleware {
+	if config.Header == "" {
+		config.Header = "X-JWS-Signature"
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if err := next.Handle(ctx); err != nil {
+				return err
+			}
+
+			fail := func(err error) error {
+				if config.FailOpen {
+					if ctx.Logger != nil {
+						ctx.Logger.Warn("Response sent unsigned", map[string]any{"error": err.Error()})
+					}
+					return nil
+				}
+				return lift.NewLiftError("RESPONSE_SIGNING_FAILED", "Failed to sign response", 500).WithCause(err)
+			}
+
+			// json.Marshal is deterministic, so these are the bytes the
+			// response is serialized to
+			body, err := responseBodyBytes(ctx.Response.Body)
+			if err != nil {
+				return fail(err)
+			}
+
+			jws, err := security.SignDetachedJWS(ctx.Context, signer, security.JWSHeader{
+				IssuedAt: config.Now().Unix(),
+				Path:     ctx.Request.Path,
+				Status:   ctx.Response.StatusCode,
+			}, body)
+			if err != nil {
+				return fail(err)
+			}
+			ctx.Response.Header(config.Header, jws)
+			return nil
+		})
+	}
+}
+
+// ResponseSigningKeyHandler publishes the signer's public key as PEM along
+// with its key ID and algorithm. The key is fetched from KMS once and cached.
+func ResponseSigningKeyHandler(signer *security.KMSSigner) lift.Handler {
+	var (
+		mu      sync.Mutex
+		encoded string
+	)
+
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if encoded == "" {
+			key, err := signer.PublicKey(ctx.Context)
+			if err != nil {
+				return lift.NewLiftError("SIGNING_KEY_UNAVAILABLE", "Failed to load the response signing key", 503).WithCause(err)
+			}
+			der, err := x509.MarshalPKIXPublicKey(key)
+			if err != nil {
+				return lift.NewLiftError("SIGNING_KEY_UNAVAILABLE", "Failed to encode the response signing key", 500).WithCause(err)
+			}
+			encoded = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		}
+
+		ctx.Response.Header("Cache-Control", "public, max-age=3600")
+		return ctx.OK(map[string]any{
+			"kid":        signer.KeyID(),
+			"alg":        signer.Algorithm(),
+			"public_key": encoded,
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKMS struct {
+	key *ecdsa.PrivateKey
+	err error
+}
+
+func (f *fakeKMS) Sign(ctx context.Context, input *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	signature, err := f.key.Sign(rand.Reader, input.Message, crypto.SHA256)
+	return &kms.SignOutput{Signature: signature}, err
+}
+
+func (f *fakeKMS) GetPublicKey(ctx context.Context, input *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error) {
+	der, err := x509.MarshalPKIXPublicKey(&f.key.PublicKey)
+	return &kms.GetPublicKeyOutput{PublicKey: der}, err
+}
+
+func TestResponseSigning(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	client := &fakeKMS{key: key}
+	signer, err := security.NewKMSSigner(client, "alias/api-signing", "ES256")
+	require.NoError(t, err)
+
+	app := lift.New()
+	balances := app.Group("/balances", ResponseSigning(signer, ResponseSigningConfig{}))
+	balances.GET("/:id", func(ctx *lift.Context) error {
+		return ctx.OK(map[string]any{"id": ctx.Param("id"), "balance": 1250})
+	})
+	balances.GET("/missing", func(ctx *lift.Context) error {
+		return lift.NotFound("balance not found")
+	})
+	app.GET("/signing-key", ResponseSigningKeyHandler(signer))
+
+	request := func(path string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Method: "GET", Path: path}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	ctx := request("/balances/acct-1")
+	require.Equal(t, 200, ctx.Response.StatusCode)
+	jws := ctx.Response.Headers["X-JWS-Signature"]
+	require.NotEmpty(t, jws)
+
+	body, err := json.Marshal(ctx.Response.Body)
+	require.NoError(t, err)
+	header, err := security.VerifyDetachedJWS(jws, body, &key.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, "/balances/acct-1", header.Path)
+	assert.Equal(t, 200, header.Status)
+	assert.Equal(t, "alias/api-signing", header.KeyID)
+
+	ctx = request("/balances/missing")
+	assert.Equal(t, 404, ctx.Response.StatusCode)
+	assert.Empty(t, ctx.Response.Headers["X-JWS-Signature"])
+
+	// The published key verifies signatures
+	ctx = request("/signing-key")
+	require.Equal(t, 200, ctx.Response.StatusCode)
+	published := ctx.Response.Body.(map[string]any)
+	block, _ := pem.Decode([]byte(published["public_key"].(string)))
+	require.NotNil(t, block)
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+	_, err = security.VerifyDetachedJWS(jws, body, publicKey)
+	assert.NoError(t, err)
+
+	// Signing failures fail the request unless FailOpen is set
+	client.err = errors.New("throttled")
+	assert.Equal(t, 500, request("/balances/acct-1").Response.StatusCode)
+
+	open := lift.New()
+	open.Use(ResponseSigning(signer, ResponseSigningConfig{FailOpen: true}))
+	open.GET("/balance", func(ctx *lift.Context) error { return ctx.OK(map[string]int{"balance": 1}) })
+	openCtx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Method: "GET", Path: "/balance"}))
+	require.NoError(t, open.HandleTestRequest(openCtx))
+	assert.Equal(t, 200, openCtx.Response.StatusCode)
+	assert.Empty(t, openCtx.Response.Headers["X-JWS-Signature"])
+}
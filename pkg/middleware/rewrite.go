@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// RewriteOriginalPathKey is the context key holding the path before a rewrite
+const RewriteOriginalPathKey = "rewrite_original_path"
+
+// PathRewrite translates one path pattern to another. Patterns use ":name"
+// segments and may end in "*" to match the rest of the path:
+//
+//	{From: "/v1/customers/:id/*", To: "/customers/:id/*"}
+type PathRewrite struct {
+	// Method limits the rule to one HTTP method; "" matches every method
+	Method string
+	From   string
+	To     string
+	// Query adds query parameters, unless present, when the rule matches
+	Query map[string]string
+}
+
+// ParsePathRewrite parses the "[METHOD] /from -> /to" shorthand used in
+// declarative config
+func ParsePathRewrite(rule string) (PathRewrite, error) {
+	from, to, ok := strings.Cut(rule, "->")
+	if !ok {
+		return PathRewrite{}, fmt.Errorf("rewrite rule %q must look like \"/from -> /to\"", rule)
+	}
+
+	var rewrite PathRewrite
+	fields := strings.Fields(from)
+	switch len(fields) {
+	case 1:
+		rewrite.From = fields[0]
+	case 2:
+		rewrite.Method, rewrite.From = strings.ToUpper(fields[0]), fields[1]
+	default:
+		return PathRewrite{}, fmt.Errorf("rewrite rule %q must look like \"[METHOD] /from -> /to\"", rule)
+	}
+	rewrite.To = strings.TrimSpace(to)
+
+	if err := rewrite.validate(); err != nil {
+		return PathRewrite{}, err
+	}
+	return rewrite, nil
+}
+
+// validate checks that both patterns are paths and To only uses captured values
+func (r PathRewrite) validate() error {
+	if !strings.HasPrefix(r.From, "/") || !strings.HasPrefix(r.To, "/") {
+		return fmt.Errorf("rewrite %s -> %s: paths must start with /", r.From, r.To)
+	}
+
+	captured := map[string]bool{}
+	for _, segment := range strings.Split(r.From, "/") {
+		if strings.HasPrefix(segment, ":") || segment == "*" {
+			captured[segment] = true
+		}
+	}
+	for _, segment := range strings.Split(r.To, "/") {
+		if (strings.HasPrefix(segment, ":") || segment == "*") && !captured[segment] {
+			return fmt.Errorf("rewrite %s -> %s: %s is not captured by the source path", r.From, r.To, segment)
+		}
+	}
+	return nil
+}
+
+// rewrite returns the rewritten path and whether the rule matched
+func (r PathRewrite) rewrite(method, path string) (string, bool) {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return "", false
+	}
+
+	from := strings.Split(strings.Trim(r.From, "/"), "/")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	values := map[string]string{}
+
+	for i, segment := range from {
+		if segment == "*" && i == len(from)-1 {
+			values["*"] = strings.Join(parts[i:], "/")
+			break
+		}
+		if i >= len(parts) {
+			return "", false
+		}
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			values[segment] = parts[i]
+		case segment != parts[i]:
+			return "", false
+		}
+		if i == len(from)-1 && len(parts) != len(from) {
+			return "", false
+		}
+	}
+
+	to := strings.Split(strings.Trim(r.To, "/"), "/")
+	out := make([]string, 0, len(to))
+	for _, segment := range to {
+		if value, ok := values[segment]; ok {
+			if value != "" {
+				out = append(out, value)
+			}
+			continue
+		}
+		out = append(out, segment)
+	}
+	return "/" + strings.Join(out, "/"), true
+}
+
+// RewriteConfig configures Rewrite
+type RewriteConfig struct {
+	// Paths are tried in order; the first matching rule is applied
+	Paths []PathRewrite
+	// DefaultQuery adds query parameters to every request that lacks them
+	DefaultQuery map[string]string
+	// SetHeaders sets request headers, replacing any sent by the client
+	SetHeaders map[string]string
+	// StripHeaders removes request headers; a trailing "*" removes every
+	// header with the prefix (e.g. "X-Internal-*")
+	StripHeaders []string
+}
+
+// Rewrite rewrites request paths, query parameters and headers before
+// routing, typically to serve a legacy API from lift routes. It must be
+// registered with App.Pre so the rewritten path is used to select the route:
+//
+//	rewrite, err := middleware.Rewrite(middleware.RewriteConfig{
+//		Paths:        []middleware.PathRewrite{{From: "/api/v1/users/:id", To: "/users/:id"}},
+//		StripHeaders: []string{"X-Internal-*"},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	app.Pre(rewrite)
+//
+// The original path is available as ctx.Get(RewriteOriginalPathKey).
+func Rewrite(config RewriteConfig) (lift.Middleware, error) {
+	for _, rule := range config.Paths {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if ctx.Request == nil {
+				return next.Handle(ctx)
+			}
+			req := ctx.Request
+
+			if len(config.StripHeaders) > 0 {
+				for name := range req.Headers {
+					if headerMatches(name, config.StripHeaders) {
+						delete(req.Headers, name)
+					}
+				}
+			}
+			for name, value := range config.SetHeaders {
+				if req.Headers == nil {
+					req.Headers = make(map[string]string)
+				}
+				// Remove differently-cased copies before setting the canonical name
+				for existing := range req.Headers {
+					if strings.EqualFold(existing, name) {
+						delete(req.Headers, existing)
+					}
+				}
+				req.Headers[http.CanonicalHeaderKey(name)] = value
+			}
+
+			for _, rule := range config.Paths {
+				path, ok := rule.rewrite(req.Method, req.Path)
+				if !ok {
+					continue
+				}
+				ctx.Set(RewriteOriginalPathKey, req.Path)
+				req.Path = path
+				if req.Request != nil {
+					req.Request.Path = path
+				}
+				addDefaultQuery(req, rule.Query)
+				break
+			}
+			addDefaultQuery(req, config.DefaultQuery)
+
+			if req.Request != nil {
+				req.Request.Headers = req.Headers
+				req.Request.QueryParams = req.QueryParams
+			}
+			return next.Handle(ctx)
+		})
+	}, nil
+}
+
+// addDefaultQuery sets query parameters that the request doesn't already have
+func addDefaultQuery(req *lift.Request, defaults map[string]string) {
+	for key, value := range defaults {
+		if req.QueryParams == nil {
+			req.QueryParams = make(map[string]string)
+		}
+		if _, exists := req.QueryParams[key]; !exists {
+			req.QueryParams[key] = value
+		}
+	}
+}
+
+// headerMatches reports whether a header name matches any pattern, case-insensitively
+func headerMatches(name string, patterns []string) bool {
+	lower := strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(lower, prefix) {
+				return true
+			}
+		} else if lower == pattern {
+			return true
+		}
+	}
+	return false
+}
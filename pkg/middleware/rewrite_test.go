@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathRewrite(t *testing.T) {
+	tests := []struct {
+		rule   string
+		method string
+		path   string
+		want   string
+		ok     bool
+	}{
+		{"/v1/users/:id -> /users/:id", "GET", "/v1/users/42", "/users/42", true},
+		{"/v1/users/:id -> /users/:id", "GET", "/v1/users/42/cards", "", false},
+		{"/v1/users/:id -> /users/:id", "GET", "/v1/users", "", false},
+		{"/legacy/* -> /api/*", "GET", "/legacy/a/b/c", "/api/a/b/c", true},
+		{"/legacy/* -> /api/*", "GET", "/legacy", "/api", true},
+		{"POST /charge -> /payments", "GET", "/charge", "", false},
+		{"post /charge -> /payments", "POST", "/charge", "/payments", true},
+		{"/accounts/:account/users/:user -> /users/:user/accounts/:account", "GET", "/accounts/a1/users/u1", "/users/u1/accounts/a1", true},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParsePathRewrite(tt.rule)
+		require.NoError(t, err, tt.rule)
+		got, ok := rule.rewrite(tt.method, tt.path)
+		assert.Equal(t, tt.ok, ok, "%s %s", tt.rule, tt.path)
+		assert.Equal(t, tt.want, got, "%s %s", tt.rule, tt.path)
+	}
+
+	for _, invalid := range []string{"/a", "GET POST /a -> /b", "/a -> /b/:id", "a -> /b"} {
+		_, err := ParsePathRewrite(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	rewrite, err := Rewrite(RewriteConfig{
+		Paths: []PathRewrite{
+			{From: "/api/v1/users/:id", To: "/users/:id", Query: map[string]string{"legacy": "true"}},
+		},
+		DefaultQuery: map[string]string{"version": "2"},
+		SetHeaders:   map[string]string{"x-source": "gateway"},
+		StripHeaders: []string{"X-Internal-*", "X-Debug"},
+	})
+	require.NoError(t, err)
+
+	app := lift.New()
+	app.Pre(rewrite)
+	var seen *lift.Context
+	app.GET("/users/:id", func(ctx *lift.Context) error {
+		seen = ctx
+		return ctx.Text(ctx.Param("id"))
+	})
+
+	ctx := newPredicateContext("GET", "/api/v1/users/42", map[string]string{
+		"x-internal-tenant": "spoofed",
+		"X-Debug":           "1",
+		"X-Source":          "client",
+		"Accept":            "application/json",
+	})
+	ctx.Request.QueryParams = map[string]string{"version": "1"}
+	require.NoError(t, app.HandleTestRequest(ctx))
+
+	require.NotNil(t, seen)
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	assert.Equal(t, "42", seen.Param("id"))
+	assert.Equal(t, "/api/v1/users/42", seen.Get(RewriteOriginalPathKey))
+	assert.Equal(t, map[string]string{"version": "1", "legacy": "true"}, seen.Request.QueryParams)
+	assert.Equal(t, map[string]string{"X-Source": "gateway", "Accept": "application/json"}, seen.Request.Headers)
+
+	_, err = Rewrite(RewriteConfig{Paths: []PathRewrite{{From: "/a", To: "/b/:id"}}})
+	assert.Error(t, err)
+}
+
+func TestRewriteFromStackConfig(t *testing.T) {
+	config, err := ParseStackConfig([]byte(`
+middleware:
+  - name: request_id
+  - name: rewrite
+    config:
+      paths:
+        - "GET /v1/ping -> /ping"
+      default_query:
+        format: json
+      strip_headers: ["X-Internal-*"]
+`), "yaml")
+	require.NoError(t, err)
+
+	app := lift.New()
+	require.NoError(t, config.Apply(app, StackOptions{}))
+	app.GET("/ping", func(ctx *lift.Context) error { return ctx.Text(ctx.Query("format")) })
+
+	ctx := newPredicateContext("GET", "/v1/ping", nil)
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 200, ctx.Response.StatusCode)
+	assert.Equal(t, "json", ctx.Response.Body)
+
+	config.Middleware[1].Config["default_query"] = []any{"format=json"}
+	assert.Error(t, config.Validate(StackOptions{}))
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// AuthSchemeNone marks a route as public when used as its lift.MetaAuth
+// value, or every route without lift.MetaAuth when used as the default
+const AuthSchemeNone = "none"
+
+// RouteAuthOptions configures RouteAuth
+type RouteAuthOptions struct {
+	// Schemes maps lift.MetaAuth values to authentication middleware,
+	// e.g. {"jwt": JWT(config)}
+	Schemes map[string]lift.Middleware
+	// Default is the scheme used for routes without lift.MetaAuth metadata.
+	// Empty rejects those routes, so a route missing its metadata fails
+	// closed; set AuthSchemeNone to leave them public.
+	Default string
+}
+
+// RouteAuth authenticates and authorizes requests from route metadata, so
+// each route declares its requirements where it is registered:
+//
+//	app.GET("/admin", handler).Meta(lift.MetaAuth, "jwt").Meta(lift.MetaRoles, "admin")
+//
+// The scheme named by lift.MetaAuth authenticates the request. lift.MetaRoles
+// then requires any one of its comma-separated roles and lift.MetaScopes every
+// one of its scopes.
+func RouteAuth(opts RouteAuthOptions) lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		authorized := lift.HandlerFunc(func(ctx *lift.Context) error {
+			if err := authorizeRoute(ctx); err != nil {
+				return err
+			}
+			return next.Handle(ctx)
+		})
+
+		schemes := make(map[string]lift.Handler, len(opts.Schemes))
+		for name, authenticate := range opts.Schemes {
+			schemes[name] = authenticate(authorized)
+		}
+
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			scheme, ok := ctx.RouteMeta(lift.MetaAuth)
+			if !ok {
+				scheme = opts.Default
+			}
+			switch scheme {
+			case AuthSchemeNone:
+				return authorized.Handle(ctx)
+			case "":
+				return lift.Unauthorized("Authentication required")
+			}
+
+			handler, ok := schemes[scheme]
+			if !ok {
+				return lift.NewLiftError("UNKNOWN_AUTH_SCHEME", fmt.Sprintf("Route requires unknown auth scheme: %s", scheme), 500)
+			}
+			return handler.Handle(ctx)
+		})
+	}
+}
+
+// authorizeRoute checks the principal against the route's role and scope requirements
+func authorizeRoute(ctx *lift.Context) error {
+	roles := splitMeta(ctx, lift.MetaRoles)
+	scopes := splitMeta(ctx, lift.MetaScopes)
+	return authorizePrincipal(ctx, roles, scopes)
+}
+
+// authorizePrincipal requires a principal with any of the roles and all of the scopes
+func authorizePrincipal(ctx *lift.Context, roles, scopes []string) error {
+	if len(roles) == 0 && len(scopes) == 0 {
+		return nil
+	}
+
+	// The authentication middleware stores the principal in the context values
+	principal, _ := ctx.Get("principal").(*security.Principal)
+	if principal == nil {
+		return lift.Unauthorized("Authentication required")
+	}
+	if len(roles) > 0 && !principal.HasAnyRole(roles...) {
+		return lift.AuthorizationError(fmt.Sprintf("Required roles: %v", roles))
+	}
+	for _, scope := range scopes {
+		if !principal.HasScope(scope) {
+			return lift.AuthorizationError(fmt.Sprintf("Required scope: %s", scope))
+		}
+	}
+	return nil
+}
+
+// splitMeta returns the comma-separated values of a route metadata key
+func splitMeta(ctx *lift.Context, key string) []string {
+	raw, ok := ctx.RouteMeta(key)
+	if !ok {
+		return nil
+	}
+
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
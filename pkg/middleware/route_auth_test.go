@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// headerAuth authenticates requests carrying an X-Test-Roles header
+func headerAuth(next lift.Handler) lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		roles := ctx.Header("X-Test-Roles")
+		if roles == "" {
+			return lift.Unauthorized("Missing credentials")
+		}
+		lift.WithSecurity(ctx).SetPrincipal(&security.Principal{
+			UserID: "user-1",
+			Roles:  []string{roles},
+			Scopes: []string{"payments:read"},
+		})
+		return next.Handle(ctx)
+	})
+}
+
+func routeAuthRequest(t *testing.T, app *lift.App, path, roles string) int {
+	headers := map[string]string{}
+	if roles != "" {
+		headers["X-Test-Roles"] = roles
+	}
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  "GET",
+		Path:    path,
+		Headers: headers,
+	}))
+	require.NoError(t, app.HandleTestRequest(ctx))
+	return ctx.Response.StatusCode
+}
+
+func TestRouteAuth(t *testing.T) {
+	app := lift.New()
+	app.Use(RouteAuth(RouteAuthOptions{
+		Schemes: map[string]lift.Middleware{"header": headerAuth},
+		Default: "header",
+	}))
+
+	ok := func(ctx *lift.Context) error { return ctx.Text("ok") }
+	app.GET("/public", ok).Meta(lift.MetaAuth, AuthSchemeNone)
+	app.GET("/default", ok)
+	app.GET("/admin", ok).Meta(lift.MetaAuth, "header").Meta(lift.MetaRoles, "admin, owner")
+	app.GET("/scoped", ok).Meta(lift.MetaScopes, "payments:read,payments:write")
+	app.GET("/misconfigured", ok).Meta(lift.MetaAuth, "saml")
+
+	assert.Equal(t, 200, routeAuthRequest(t, app, "/public", ""))
+	assert.Equal(t, 401, routeAuthRequest(t, app, "/default", ""))
+	assert.Equal(t, 200, routeAuthRequest(t, app, "/default", "viewer"))
+	assert.Equal(t, 403, routeAuthRequest(t, app, "/admin", "viewer"))
+	assert.Equal(t, 200, routeAuthRequest(t, app, "/admin", "owner"))
+	assert.Equal(t, 403, routeAuthRequest(t, app, "/scoped", "owner"))
+	assert.Equal(t, 500, routeAuthRequest(t, app, "/misconfigured", "owner"))
+}
+
+func TestRouteAuthRequiresPrincipalForPublicRoles(t *testing.T) {
+	app := lift.New()
+	app.Use(RouteAuth(RouteAuthOptions{Default: AuthSchemeNone}))
+	app.GET("/reports", func(ctx *lift.Context) error { return ctx.Text("ok") }).Meta(lift.MetaRoles, "admin")
+
+	assert.Equal(t, 401, routeAuthRequest(t, app, "/reports", ""))
+}
+
+func TestRouteAuthFailsClosedWithoutDefault(t *testing.T) {
+	app := lift.New()
+	app.Use(RouteAuth(RouteAuthOptions{
+		Schemes: map[string]lift.Middleware{"header": headerAuth},
+	}))
+
+	ok := func(ctx *lift.Context) error { return ctx.Text("ok") }
+	app.GET("/forgotten", ok)
+	app.GET("/public", ok).Meta(lift.MetaAuth, AuthSchemeNone)
+
+	assert.Equal(t, 401, routeAuthRequest(t, app, "/forgotten", ""))
+	assert.Equal(t, 401, routeAuthRequest(t, app, "/forgotten", "owner"))
+	assert.Equal(t, 200, routeAuthRequest(t, app, "/public", ""))
+}
@@ -0,0 +1,329 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// RouteToggle disables a route at runtime
+type RouteToggle struct {
+	// Method is the HTTP method to disable; "" or "*" matches every method
+	Method string `json:"method,omitempty"`
+	// Route is the registered route pattern (e.g. "/payments/:id"). A
+	// trailing "/*" disables every route under the prefix.
+	Route string `json:"route"`
+	// Status is 503 (temporarily disabled, the default) or 410 (retired)
+	Status int `json:"status,omitempty"`
+	// Message is returned to callers instead of the default message
+	Message string `json:"message,omitempty"`
+	// RetryAfter sets the Retry-After header, in seconds, on 503 responses
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// matches reports whether the toggle applies to the method and route pattern
+func (t RouteToggle) matches(method, route string) bool {
+	if t.Method != "" && t.Method != "*" && !strings.EqualFold(t.Method, method) {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(t.Route, "/*"); ok {
+		return route == prefix || strings.HasPrefix(route, prefix+"/")
+	}
+	return t.Route == route
+}
+
+// validate checks the toggle and fills in its defaults
+func (t *RouteToggle) validate() error {
+	if t.Route == "" {
+		return fmt.Errorf("route toggle requires a route")
+	}
+	switch t.Status {
+	case 0:
+		t.Status = 503
+	case 503, 410:
+	default:
+		return fmt.Errorf("route toggle for %s: status must be 503 or 410, got %d", t.Route, t.Status)
+	}
+	return nil
+}
+
+func routeToggleKey(method, route string) string {
+	if method == "" {
+		method = "*"
+	}
+	return strings.ToUpper(method) + " " + route
+}
+
+// ToggleSource loads the current set of disabled routes
+type ToggleSource interface {
+	LoadToggles(ctx context.Context) ([]RouteToggle, error)
+}
+
+// ToggleSourceFunc adapts a function to the ToggleSource interface
+type ToggleSourceFunc func(ctx context.Context) ([]RouteToggle, error)
+
+// LoadToggles calls f(ctx)
+func (f ToggleSourceFunc) LoadToggles(ctx context.Context) ([]RouteToggle, error) {
+	return f(ctx)
+}
+
+// ParseRouteToggles parses a JSON array of route toggles
+func ParseRouteToggles(data []byte) ([]RouteToggle, error) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	var toggles []RouteToggle
+	if err := json.Unmarshal(data, &toggles); err != nil {
+		return nil, fmt.Errorf("invalid route toggles: %w", err)
+	}
+	return toggles, nil
+}
+
+// EnvToggleSource reads toggles as JSON from an environment variable, so a
+// route can be disabled by updating the function configuration
+func EnvToggleSource(name string) ToggleSource {
+	return ToggleSourceFunc(func(ctx context.Context) ([]RouteToggle, error) {
+		return ParseRouteToggles([]byte(os.Getenv(name)))
+	})
+}
+
+// SSMParameterGetter is the subset of the SSM client used by SSMToggleSource
+type SSMParameterGetter interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// SSMToggleSource reads toggles as JSON from an SSM parameter, so routes can
+// be disabled across every running instance without a deploy
+func SSMToggleSource(client SSMParameterGetter, parameter string) ToggleSource {
+	return ToggleSourceFunc(func(ctx context.Context) ([]RouteToggle, error) {
+		result, err := client.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(parameter)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get route toggles from SSM parameter %s: %w", parameter, err)
+		}
+		if result.Parameter == nil || result.Parameter.Value == nil {
+			return nil, nil
+		}
+		return ParseRouteToggles([]byte(*result.Parameter.Value))
+	})
+}
+
+// RouteTogglesOptions configures RouteToggles
+type RouteTogglesOptions struct {
+	// Source provides toggles; without one only Disable/Enable apply
+	Source ToggleSource
+	// RefreshInterval is how long loaded toggles are used before the source
+	// is read again (default: 30s)
+	RefreshInterval time.Duration
+	// OnError is called when the source fails or returns invalid toggles.
+	// The last good set of toggles stays in effect.
+	OnError func(err error)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// RouteToggles short-circuits disabled routes with a 503 or 410 response so a
+// misbehaving endpoint can be switched off, or an API retired, without a
+// deploy. Toggles are read from a source, refreshed lazily on requests, and
+// can be overridden in process with Disable and Enable.
+type RouteToggles struct {
+	opts RouteTogglesOptions
+
+	mu        sync.RWMutex
+	loaded    []RouteToggle
+	loadedAt  time.Time
+	overrides map[string]*RouteToggle
+}
+
+// NewRouteToggles creates route toggles with the given options
+func NewRouteToggles(opts RouteTogglesOptions) *RouteToggles {
+	if opts.RefreshInterval == 0 {
+		opts.RefreshInterval = 30 * time.Second
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	return &RouteToggles{
+		opts:      opts,
+		overrides: make(map[string]*RouteToggle),
+	}
+}
+
+// RouteTogglesMiddleware creates middleware that rejects disabled routes
+func RouteTogglesMiddleware(opts RouteTogglesOptions) lift.Middleware {
+	return NewRouteToggles(opts).Middleware()
+}
+
+// Middleware returns the toggles as middleware. It must run after routing,
+// which is always the case for middleware registered with App.Use.
+func (t *RouteToggles) Middleware() lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if ctx.Request == nil {
+				return next.Handle(ctx)
+			}
+
+			t.refreshIfStale(ctx.Context)
+
+			toggle, disabled := t.Lookup(ctx.Request.Method, ctx.Route())
+			if !disabled {
+				return next.Handle(ctx)
+			}
+
+			if ctx.Logger != nil {
+				ctx.Logger.Warn("Request to disabled route rejected", map[string]any{
+					"method": ctx.Request.Method,
+					"route":  ctx.Route(),
+					"status": toggle.Status,
+				})
+			}
+			return disabledRouteError(ctx, toggle)
+		})
+	}
+}
+
+// Lookup returns the toggle disabling a method and route pattern, if any
+func (t *RouteToggles) Lookup(method, route string) (RouteToggle, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// Overrides win over the source, including explicit re-enables
+	for _, key := range []string{routeToggleKey(method, route), routeToggleKey("*", route)} {
+		if override, exists := t.overrides[key]; exists {
+			if override == nil {
+				return RouteToggle{}, false
+			}
+			return *override, true
+		}
+	}
+	for _, override := range t.overrides {
+		if override != nil && override.matches(method, route) {
+			return *override, true
+		}
+	}
+
+	for _, toggle := range t.loaded {
+		if toggle.matches(method, route) {
+			return toggle, true
+		}
+	}
+	return RouteToggle{}, false
+}
+
+// Disable disables a route in this process, taking precedence over the source
+func (t *RouteToggles) Disable(toggle RouteToggle) error {
+	if err := toggle.validate(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.overrides[routeToggleKey(toggle.Method, toggle.Route)] = &toggle
+	return nil
+}
+
+// Enable re-enables a route in this process, even if the source disables it.
+// Use Reset to defer to the source again.
+func (t *RouteToggles) Enable(method, route string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.overrides[routeToggleKey(method, route)] = nil
+}
+
+// Reset removes the in-process override for a route
+func (t *RouteToggles) Reset(method, route string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.overrides, routeToggleKey(method, route))
+}
+
+// Toggles returns the toggles currently in effect, source toggles first
+func (t *RouteToggles) Toggles() []RouteToggle {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	toggles := append([]RouteToggle(nil), t.loaded...)
+	for _, override := range t.overrides {
+		if override != nil {
+			toggles = append(toggles, *override)
+		}
+	}
+	return toggles
+}
+
+// Refresh reloads toggles from the source. On error the previous toggles are kept.
+func (t *RouteToggles) Refresh(ctx context.Context) error {
+	if t.opts.Source == nil {
+		return nil
+	}
+
+	toggles, err := t.opts.Source.LoadToggles(ctx)
+	if err == nil {
+		for i := range toggles {
+			if err = toggles[i].validate(); err != nil {
+				break
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Retry after the refresh interval rather than on every request
+	t.loadedAt = t.opts.Now()
+	if err != nil {
+		return err
+	}
+	t.loaded = toggles
+	return nil
+}
+
+// refreshIfStale reloads toggles when the refresh interval has passed
+func (t *RouteToggles) refreshIfStale(ctx context.Context) {
+	if t.opts.Source == nil {
+		return
+	}
+
+	t.mu.RLock()
+	stale := t.loadedAt.IsZero() || t.opts.Now().Sub(t.loadedAt) >= t.opts.RefreshInterval
+	t.mu.RUnlock()
+	if !stale {
+		return
+	}
+
+	if err := t.Refresh(ctx); err != nil && t.opts.OnError != nil {
+		t.opts.OnError(err)
+	}
+}
+
+// disabledRouteError builds the response for a disabled route
+func disabledRouteError(ctx *lift.Context, toggle RouteToggle) error {
+	if toggle.Status == 410 {
+		message := toggle.Message
+		if message == "" {
+			message = "This endpoint has been retired"
+		}
+		return lift.NewLiftError("ROUTE_GONE", message, 410)
+	}
+
+	message := toggle.Message
+	if message == "" {
+		message = "This endpoint is temporarily unavailable"
+	}
+	if toggle.RetryAfter > 0 {
+		ctx.Response.Header("Retry-After", strconv.Itoa(toggle.RetryAfter))
+	}
+	return lift.NewLiftError("ROUTE_DISABLED", message, 503)
+}
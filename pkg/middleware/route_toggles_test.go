@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSSM struct {
+	value string
+	err   error
+}
+
+func (f *fakeSSM) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Value: aws.String(f.value)}}, nil
+}
+
+func TestRouteToggles(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	source := &fakeSSM{value: `[
+		{"method": "POST", "route": "/payments", "message": "Payments are paused", "retry_after": 60},
+		{"route": "/v1/*", "status": 410}
+	]`}
+	var loadErrors []error
+	toggles := NewRouteToggles(RouteTogglesOptions{
+		Source:  SSMToggleSource(source, "/payments/prod/route-toggles"),
+		OnError: func(err error) { loadErrors = append(loadErrors, err) },
+		Now:     func() time.Time { return now },
+	})
+
+	app := lift.New()
+	app.Use(toggles.Middleware())
+	ok := func(ctx *lift.Context) error { return ctx.Text("ok") }
+	app.GET("/payments", ok)
+	app.POST("/payments", ok)
+	app.GET("/v1/payments/:id", ok)
+
+	assert.Equal(t, 200, toggleRequest(t, app, "GET", "/payments"))
+	assert.Equal(t, 503, toggleRequest(t, app, "POST", "/payments"))
+	assert.Equal(t, 410, toggleRequest(t, app, "GET", "/v1/payments/123"))
+
+	// In-process overrides win over the source
+	toggles.Enable("POST", "/payments")
+	require.NoError(t, toggles.Disable(RouteToggle{Route: "/payments", Status: 410}))
+	assert.Equal(t, 200, toggleRequest(t, app, "POST", "/payments"))
+	assert.Equal(t, 410, toggleRequest(t, app, "GET", "/payments"))
+	toggles.Reset("POST", "/payments")
+	toggles.Reset("", "/payments")
+
+	// Source changes are picked up after the refresh interval, and a bad
+	// update keeps the last good toggles
+	source.value = `[{"route": "/payments", "status": 500}]`
+	now = now.Add(time.Minute)
+	assert.Equal(t, 503, toggleRequest(t, app, "POST", "/payments"))
+	require.Len(t, loadErrors, 1)
+
+	source.value = `[]`
+	now = now.Add(time.Minute)
+	assert.Equal(t, 200, toggleRequest(t, app, "POST", "/payments"))
+	assert.Empty(t, toggles.Toggles())
+}
+
+func TestRouteTogglesResponse(t *testing.T) {
+	toggles := NewRouteToggles(RouteTogglesOptions{})
+	require.NoError(t, toggles.Disable(RouteToggle{Method: "POST", Route: "/refunds", Message: "Refunds are paused", RetryAfter: 120}))
+	assert.Error(t, toggles.Disable(RouteToggle{Route: "/refunds", Status: 404}))
+
+	app := lift.New()
+	app.Use(toggles.Middleware())
+	app.POST("/refunds", func(ctx *lift.Context) error { return ctx.Text("ok") })
+
+	ctx := newPredicateContext("POST", "/refunds", nil)
+	require.NoError(t, app.HandleTestRequest(ctx))
+	assert.Equal(t, 503, ctx.Response.StatusCode)
+	assert.Equal(t, "120", ctx.Response.Headers["Retry-After"])
+	assert.Contains(t, fmt.Sprint(ctx.Response.Body), "Refunds are paused")
+}
+
+func TestEnvToggleSource(t *testing.T) {
+	t.Setenv("LIFT_ROUTE_TOGGLES", `[{"method": "DELETE", "route": "/accounts/:id"}]`)
+	toggles, err := EnvToggleSource("LIFT_ROUTE_TOGGLES").LoadToggles(context.Background())
+	require.NoError(t, err)
+	require.Len(t, toggles, 1)
+	assert.Equal(t, "/accounts/:id", toggles[0].Route)
+
+	_, err = SSMToggleSource(&fakeSSM{err: errors.New("throttled")}, "p").LoadToggles(context.Background())
+	assert.Error(t, err)
+}
+
+func toggleRequest(t *testing.T, app *lift.App, method, path string) int {
+	ctx := newPredicateContext(method, path, nil)
+	require.NoError(t, app.HandleTestRequest(ctx))
+	return ctx.Response.StatusCode
+}
@@ -0,0 +1,423 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/utils/sanitization"
+)
+
+// ShadowRequest is a copy of a production request sent to a shadow target
+type ShadowRequest struct {
+	Method      string
+	Path        string
+	Headers     map[string]string
+	QueryParams map[string]string
+	Body        []byte
+}
+
+// ShadowResponse is a target's response to a shadow request
+type ShadowResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// ShadowTarget serves mirrored requests, typically a candidate rewrite of
+// the production handler
+type ShadowTarget interface {
+	Shadow(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error)
+}
+
+// ShadowTargetFunc adapts a function to the ShadowTarget interface
+type ShadowTargetFunc func(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error)
+
+// Shadow calls f(ctx, req)
+func (f ShadowTargetFunc) Shadow(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error) {
+	return f(ctx, req)
+}
+
+// ShadowApp sends mirrored requests through a candidate lift app in the
+// same process, routing them with the candidate's own routes and middleware
+func ShadowApp(app *lift.App) ShadowTarget {
+	return ShadowTargetFunc(func(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error) {
+		shadowCtx := lift.NewContext(ctx, lift.NewRequest(&adapters.Request{
+			TriggerType: adapters.TriggerAPIGateway,
+			Method:      req.Method,
+			Path:        req.Path,
+			Headers:     req.Headers,
+			QueryParams: req.QueryParams,
+			Body:        req.Body,
+		}))
+		if err := app.HandleTestRequest(shadowCtx); err != nil {
+			return nil, err
+		}
+
+		body, err := responseBodyBytes(shadowCtx.Response.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &ShadowResponse{StatusCode: shadowCtx.Response.StatusCode, Body: body}, nil
+	})
+}
+
+// ShadowEndpoint sends mirrored requests to a candidate deployment over
+// HTTP. Pass nil to use a default client.
+func ShadowEndpoint(baseURL string, client *http.Client) ShadowTarget {
+	if client == nil {
+		client = &http.Client{}
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return ShadowTargetFunc(func(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error) {
+		target := baseURL + req.Path
+		if len(req.QueryParams) > 0 {
+			query := url.Values{}
+			for key, value := range req.QueryParams {
+				query.Set(key, value)
+			}
+			target += "?" + query.Encode()
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, req.Method, target, bytes.NewReader(req.Body))
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range req.Headers {
+			httpReq.Header.Set(name, value)
+		}
+
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+		if err != nil {
+			return nil, err
+		}
+		return &ShadowResponse{StatusCode: resp.StatusCode, Body: body}, nil
+	})
+}
+
+// ShadowDiff compares a production response with the shadow target's
+type ShadowDiff struct {
+	Method         string        `json:"method"`
+	Route          string        `json:"route"`
+	Path           string        `json:"path"`
+	PrimaryStatus  int           `json:"primary_status"`
+	ShadowStatus   int           `json:"shadow_status,omitempty"`
+	Fields         []string      `json:"fields,omitempty"`
+	PrimaryLatency time.Duration `json:"primary_latency"`
+	ShadowLatency  time.Duration `json:"shadow_latency"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// Matched reports whether the shadow response matched production
+func (d ShadowDiff) Matched() bool {
+	return d.Error == "" && d.PrimaryStatus == d.ShadowStatus && len(d.Fields) == 0
+}
+
+// ShadowConfig configures ShadowTraffic
+type ShadowConfig struct {
+	// Target serves the mirrored requests
+	Target ShadowTarget
+	// SampleRate is the fraction of eligible requests mirrored, from 0 to 1
+	SampleRate float64
+	// Methods are the HTTP methods mirrored (default: GET and HEAD). Only
+	// add write methods when the target can't cause side effects.
+	Methods []string
+	// Redact prepares a request for mirroring. The default removes
+	// credential headers and masks sensitive JSON body fields.
+	Redact func(req *ShadowRequest)
+	// IgnoreFields are dotted JSON paths left out of the comparison, such
+	// as timestamps and generated IDs (e.g. "meta.request_id")
+	IgnoreFields []string
+	// Timeout bounds each shadow request (default: 5s)
+	Timeout time.Duration
+	// OnResult receives every comparison
+	OnResult func(diff ShadowDiff)
+	// Metrics receives shadow.requests, shadow.mismatches and shadow.errors
+	// counters and a shadow.latency_delta_ms histogram
+	Metrics lift.MetricsCollector
+	// Random returns a number in [0, 1) for sampling (default: math/rand)
+	Random func() float64
+}
+
+// ShadowTraffic mirrors sampled production requests to a shadow target and
+// records how its responses differ
+type ShadowTraffic struct {
+	config  ShadowConfig
+	methods map[string]bool
+	ignore  map[string]bool
+	wg      sync.WaitGroup
+}
+
+// NewShadowTraffic creates shadow traffic mirroring
+func NewShadowTraffic(config ShadowConfig) (*ShadowTraffic, error) {
+	if config.Target == nil {
+		return nil, fmt.Errorf("shadow traffic requires a target")
+	}
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("sample rate must be between 0 and 1, got %v", config.SampleRate)
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = []string{"GET", "HEAD"}
+	}
+	if config.Redact == nil {
+		config.Redact = RedactShadowRequest
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Random == nil {
+		config.Random = rand.Float64
+	}
+
+	s := &ShadowTraffic{
+		config:  config,
+		methods: make(map[string]bool),
+		ignore:  make(map[string]bool),
+	}
+	for _, method := range config.Methods {
+		s.methods[strings.ToUpper(method)] = true
+	}
+	for _, field := range config.IgnoreFields {
+		s.ignore[field] = true
+	}
+	return s, nil
+}
+
+// Middleware serves the request normally, then mirrors sampled requests to
+// the target in the background. The production response is never delayed
+// or changed by the shadow target.
+//
+//	shadow, err := middleware.NewShadowTraffic(middleware.ShadowConfig{
+//		Target:       middleware.ShadowEndpoint("https://candidate.internal", nil),
+//		SampleRate:   0.05,
+//		IgnoreFields: []string{"generated_at"},
+//		OnResult: func(diff middleware.ShadowDiff) {
+//			if !diff.Matched() {
+//				logger.Warn("shadow mismatch", map[string]any{"route": diff.Route, "fields": diff.Fields})
+//			}
+//		},
+//	})
+//	app.Use(shadow.Middleware())
+//
+// Lambda freezes the execution environment between invocations, so call
+// Wait before the handler returns if shadow results must not be lost.
+func (s *ShadowTraffic) Middleware() lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if ctx.Request == nil || !s.methods[strings.ToUpper(ctx.Request.Method)] || s.config.Random() >= s.config.SampleRate {
+				return next.Handle(ctx)
+			}
+
+			// Copy the request before the handler can change it
+			req := &ShadowRequest{
+				Method:      ctx.Request.Method,
+				Path:        ctx.Request.Path,
+				Headers:     copyStringMap(ctx.Request.Headers),
+				QueryParams: copyStringMap(ctx.Request.QueryParams),
+				Body:        append([]byte(nil), ctx.Request.Body...),
+			}
+
+			start := time.Now()
+			err := next.Handle(ctx)
+			primaryLatency := time.Since(start)
+
+			status, body := primaryResult(ctx, err)
+			diff := ShadowDiff{
+				Method:         req.Method,
+				Route:          ctx.Route(),
+				Path:           req.Path,
+				PrimaryStatus:  status,
+				PrimaryLatency: primaryLatency,
+			}
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.mirror(context.WithoutCancel(ctx.Context), req, body, diff)
+			}()
+			return err
+		})
+	}
+}
+
+// Wait blocks until in-flight shadow requests finish
+func (s *ShadowTraffic) Wait() {
+	s.wg.Wait()
+}
+
+// mirror sends the request to the target and records the comparison
+func (s *ShadowTraffic) mirror(ctx context.Context, req *ShadowRequest, primaryBody []byte, diff ShadowDiff) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	s.config.Redact(req)
+
+	start := time.Now()
+	resp, err := s.config.Target.Shadow(ctx, req)
+	diff.ShadowLatency = time.Since(start)
+
+	if err != nil {
+		diff.Error = err.Error()
+	} else {
+		diff.ShadowStatus = resp.StatusCode
+		diff.Fields = s.compareBodies(primaryBody, resp.Body)
+	}
+	s.record(diff)
+}
+
+// record reports a comparison to metrics and the callback
+func (s *ShadowTraffic) record(diff ShadowDiff) {
+	if metrics := s.config.Metrics; metrics != nil {
+		tags := map[string]string{"route": diff.Route, "method": diff.Method}
+		metrics.Counter("shadow.requests", tags).Inc()
+		switch {
+		case diff.Error != "":
+			metrics.Counter("shadow.errors", tags).Inc()
+		case !diff.Matched():
+			metrics.Counter("shadow.mismatches", tags).Inc()
+		}
+		if diff.Error == "" {
+			metrics.Histogram("shadow.latency_delta_ms", tags).Observe(float64(diff.ShadowLatency-diff.PrimaryLatency) / float64(time.Millisecond))
+		}
+	}
+
+	if s.config.OnResult != nil {
+		s.config.OnResult(diff)
+	}
+}
+
+// compareBodies returns the JSON paths whose values differ. Bodies that
+// aren't JSON are compared as a whole and reported as "body".
+func (s *ShadowTraffic) compareBodies(primary, shadow []byte) []string {
+	var primaryValue, shadowValue any
+	if json.Unmarshal(primary, &primaryValue) != nil || json.Unmarshal(shadow, &shadowValue) != nil {
+		if bytes.Equal(bytes.TrimSpace(primary), bytes.TrimSpace(shadow)) {
+			return nil
+		}
+		return []string{"body"}
+	}
+
+	var fields []string
+	s.diffJSON("", primaryValue, shadowValue, &fields)
+	sort.Strings(fields)
+	return fields
+}
+
+// diffJSON collects the paths at which two decoded JSON values differ
+func (s *ShadowTraffic) diffJSON(path string, a, b any, fields *[]string) {
+	if path != "" && s.ignore[path] {
+		return
+	}
+
+	aMap, aIsMap := a.(map[string]any)
+	bMap, bIsMap := b.(map[string]any)
+	if aIsMap && bIsMap {
+		for key, value := range aMap {
+			s.diffJSON(joinPath(path, key), value, bMap[key], fields)
+		}
+		for key, value := range bMap {
+			if _, ok := aMap[key]; !ok {
+				s.diffJSON(joinPath(path, key), nil, value, fields)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "body"
+		}
+		*fields = append(*fields, path)
+	}
+}
+
+// RedactShadowRequest removes credentials and masks sensitive fields so
+// shadow targets never see production secrets. Authorization, cookie and
+// API key headers are removed; JSON body fields are masked with the same
+// rules used for log sanitization.
+func RedactShadowRequest(req *ShadowRequest) {
+	for name := range req.Headers {
+		switch strings.ToLower(name) {
+		case "authorization", "cookie", "x-api-key", "x-amz-security-token", "proxy-authorization":
+			delete(req.Headers, name)
+		}
+	}
+
+	if len(req.Body) == 0 {
+		return
+	}
+	var body map[string]any
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		return
+	}
+	if redacted, err := json.Marshal(sanitization.SanitizeMap(body)); err == nil {
+		req.Body = redacted
+	}
+}
+
+// primaryResult returns the production status and body, including the
+// error response the app will write for a handler error
+func primaryResult(ctx *lift.Context, err error) (int, []byte) {
+	if err != nil {
+		var liftErr *lift.LiftError
+		if errors.As(err, &liftErr) {
+			body, _ := json.Marshal(map[string]any{"error": liftErr.Code, "message": liftErr.Message})
+			return liftErr.StatusCode, body
+		}
+		return 500, nil
+	}
+
+	body, _ := responseBodyBytes(ctx.Response.Body)
+	return ctx.Response.StatusCode, body
+}
+
+// responseBodyBytes encodes a lift response body for comparison
+func responseBodyBytes(body any) ([]byte, error) {
+	switch v := body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shadowResults collects comparisons from background goroutines
+type shadowResults struct {
+	mu    sync.Mutex
+	diffs []ShadowDiff
+}
+
+func (r *shadowResults) record(diff ShadowDiff) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.diffs = append(r.diffs, diff)
+}
+
+func shadowContext(method, path string, headers map[string]string, body string) *lift.Context {
+	return lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  method,
+		Path:    path,
+		Headers: headers,
+		Body:    []byte(body),
+	}))
+}
+
+func TestShadowTrafficComparesResponses(t *testing.T) {
+	candidate := lift.New()
+	candidate.GET("/payments/:id", func(ctx *lift.Context) error {
+		return ctx.OK(map[string]any{
+			"id":           ctx.Param("id"),
+			"amount":       1250,
+			"status":       "settled",
+			"generated_at": "later",
+		})
+	})
+
+	results := &shadowResults{}
+	shadow, err := NewShadowTraffic(ShadowConfig{
+		Target:       ShadowApp(candidate),
+		SampleRate:   1,
+		IgnoreFields: []string{"generated_at"},
+		OnResult:     results.record,
+	})
+	require.NoError(t, err)
+
+	primary := shadow.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return ctx.OK(map[string]any{
+			"id":           "pay_1",
+			"amount":       1250,
+			"status":       "pending",
+			"generated_at": "now",
+		})
+	}))
+
+	ctx := shadowContext("GET", "/payments/pay_1", nil, "")
+	require.NoError(t, primary.Handle(ctx))
+	shadow.Wait()
+
+	require.Len(t, results.diffs, 1)
+	diff := results.diffs[0]
+	assert.Equal(t, 200, diff.PrimaryStatus)
+	assert.Equal(t, 200, diff.ShadowStatus)
+	assert.Equal(t, []string{"status"}, diff.Fields)
+	assert.False(t, diff.Matched())
+
+	// The production response is untouched
+	assert.Equal(t, "pending", ctx.Response.Body.(map[string]any)["status"])
+}
+
+func TestShadowTrafficSamplingAndMethods(t *testing.T) {
+	calls := 0
+	target := ShadowTargetFunc(func(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error) {
+		calls++
+		return &ShadowResponse{StatusCode: 200}, nil
+	})
+
+	roll := 0.5
+	shadow, err := NewShadowTraffic(ShadowConfig{
+		Target:     target,
+		SampleRate: 0.25,
+		Random:     func() float64 { return roll },
+	})
+	require.NoError(t, err)
+	handler := shadow.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error { return nil }))
+
+	require.NoError(t, handler.Handle(shadowContext("GET", "/payments", nil, "")))
+	roll = 0.1
+	require.NoError(t, handler.Handle(shadowContext("GET", "/payments", nil, "")))
+	require.NoError(t, handler.Handle(shadowContext("POST", "/payments", nil, `{}`)))
+	shadow.Wait()
+
+	assert.Equal(t, 1, calls, "only sampled GET requests are mirrored")
+
+	_, err = NewShadowTraffic(ShadowConfig{Target: target, SampleRate: 2})
+	assert.Error(t, err)
+	_, err = NewShadowTraffic(ShadowConfig{SampleRate: 1})
+	assert.Error(t, err)
+}
+
+func TestShadowTrafficRedactsRequests(t *testing.T) {
+	var mirrored *ShadowRequest
+	shadow, err := NewShadowTraffic(ShadowConfig{
+		Target: ShadowTargetFunc(func(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error) {
+			mirrored = req
+			return &ShadowResponse{StatusCode: 200}, nil
+		}),
+		SampleRate: 1,
+		Methods:    []string{"POST"},
+	})
+	require.NoError(t, err)
+
+	handler := shadow.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error { return nil }))
+	headers := map[string]string{"Authorization": "Bearer secret", "Cookie": "session=1", "X-Request-ID": "req-1"}
+	ctx := shadowContext("POST", "/customers", headers, `{"name":"Ada","password":"hunter2"}`)
+	require.NoError(t, handler.Handle(ctx))
+	shadow.Wait()
+
+	require.NotNil(t, mirrored)
+	assert.Equal(t, map[string]string{"X-Request-ID": "req-1"}, mirrored.Headers)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(mirrored.Body, &body))
+	assert.NotEqual(t, "hunter2", body["password"])
+
+	// The production request keeps its credentials
+	assert.Equal(t, "Bearer secret", ctx.Request.Headers["Authorization"])
+}
+
+func TestShadowTrafficRecordsErrors(t *testing.T) {
+	results := &shadowResults{}
+	shadow, err := NewShadowTraffic(ShadowConfig{
+		Target: ShadowTargetFunc(func(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error) {
+			return &ShadowResponse{StatusCode: 200, Body: []byte(`{"id":"cus_1"}`)}, nil
+		}),
+		SampleRate: 1,
+		OnResult:   results.record,
+	})
+	require.NoError(t, err)
+
+	notFound := lift.NewLiftError("NOT_FOUND", "Customer not found", 404)
+	handler := shadow.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error { return notFound }))
+	assert.Equal(t, notFound, handler.Handle(shadowContext("GET", "/customers/cus_1", nil, "")))
+	shadow.Wait()
+
+	failing, err := NewShadowTraffic(ShadowConfig{
+		Target: ShadowTargetFunc(func(ctx context.Context, req *ShadowRequest) (*ShadowResponse, error) {
+			return nil, errors.New("connection refused")
+		}),
+		SampleRate: 1,
+		OnResult:   results.record,
+	})
+	require.NoError(t, err)
+	ok := failing.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error { return ctx.OK("fine") }))
+	require.NoError(t, ok.Handle(shadowContext("GET", "/health", nil, "")))
+
+	failing.Wait()
+	require.Len(t, results.diffs, 2)
+	assert.Equal(t, 404, results.diffs[0].PrimaryStatus)
+	assert.Equal(t, 200, results.diffs[0].ShadowStatus)
+	assert.ElementsMatch(t, []string{"error", "id", "message"}, results.diffs[0].Fields)
+	assert.Equal(t, "connection refused", results.diffs[1].Error)
+}
@@ -0,0 +1,523 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// SLO metric names. Each is tagged with an "slo" dimension holding the SLO name.
+const (
+	SLORequestsMetric = "slo_requests"
+	SLOGoodMetric     = "slo_good_requests"
+	SLOBadMetric      = "slo_bad_requests"
+	SLOBurnRateMetric = "slo_burn_rate"
+)
+
+// DefaultRouteSLOTarget is the target for SLOs declared with route metadata
+// when the route doesn't set lift.MetaSLOTarget
+const DefaultRouteSLOTarget = 0.999
+
+// RouteSLO declares a service level objective for a route,
+// e.g. 99.9% of requests succeed in under 300ms
+type RouteSLO struct {
+	// Name identifies the SLO in metrics and alarms (default: "<method> <route>")
+	Name string
+	// Method restricts the SLO to one HTTP method (empty matches all methods)
+	Method string
+	// Route is the route pattern as registered (e.g. "/payments/:id").
+	// A trailing "*" matches every route with that prefix.
+	Route string
+	// Target is the fraction of requests that must be good (e.g. 0.999)
+	Target float64
+	// LatencyThreshold marks slower requests as bad (zero disables the latency objective)
+	LatencyThreshold time.Duration
+	// Window is the rolling window used for the local burn rate (default: 1 hour)
+	Window time.Duration
+}
+
+// SLOOptions configures SLO tracking. Besides the declared SLOs, routes
+// registered with lift.MetaSLO metadata (e.g. .Meta("slo", "300ms")) are
+// tracked with that latency threshold.
+type SLOOptions struct {
+	SLOs []RouteSLO
+	// IsGood decides whether a request met its objective; by default a request
+	// is good when it returned no 5xx error and finished within the latency threshold
+	IsGood func(ctx *lift.Context, err error, duration time.Duration, slo RouteSLO) bool
+	// Now overrides the clock (for testing)
+	Now func() time.Time
+}
+
+// SLOStatus is a point-in-time view of an SLO in the local window
+type SLOStatus struct {
+	Name     string  `json:"name"`
+	Target   float64 `json:"target"`
+	Total    int64   `json:"total"`
+	Good     int64   `json:"good"`
+	Bad      int64   `json:"bad"`
+	BurnRate float64 `json:"burn_rate"`
+}
+
+// SLOTracker records request outcomes against declared SLOs
+type SLOTracker struct {
+	opts  SLOOptions
+	slos  []RouteSLO
+	state map[string]*sloWindow
+	mu    sync.Mutex
+
+	// routeSLOs caches SLOs declared with route metadata by name (nil when invalid)
+	routeSLOs map[string]*RouteSLO
+}
+
+// sloWindow keeps per-minute good/bad counts over the SLO window
+type sloWindow struct {
+	buckets map[int64]*sloBucket
+	window  time.Duration
+}
+
+type sloBucket struct {
+	good int64
+	bad  int64
+}
+
+// NewSLOTracker creates a tracker for the given SLOs
+func NewSLOTracker(opts SLOOptions) *SLOTracker {
+	if opts.IsGood == nil {
+		opts.IsGood = defaultSLOIsGood
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	tracker := &SLOTracker{
+		opts:      opts,
+		state:     make(map[string]*sloWindow),
+		routeSLOs: make(map[string]*RouteSLO),
+	}
+	for _, slo := range opts.SLOs {
+		tracker.addLocked(slo)
+	}
+
+	return tracker
+}
+
+// addLocked normalizes and registers an SLO
+func (t *SLOTracker) addLocked(slo RouteSLO) RouteSLO {
+	if slo.Name == "" {
+		slo.Name = strings.TrimSpace(slo.Method + " " + slo.Route)
+	}
+	if slo.Window == 0 {
+		slo.Window = time.Hour
+	}
+	t.slos = append(t.slos, slo)
+	t.state[slo.Name] = &sloWindow{
+		buckets: make(map[int64]*sloBucket),
+		window:  slo.Window,
+	}
+	return slo
+}
+
+// SLOMiddleware creates middleware that tracks requests against route SLOs
+func SLOMiddleware(opts SLOOptions) Middleware {
+	return NewSLOTracker(opts).Middleware()
+}
+
+// Middleware returns the tracker as middleware
+func (t *SLOTracker) Middleware() Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			matched := t.match(ctx)
+			if len(matched) == 0 {
+				return next.Handle(ctx)
+			}
+
+			start := t.opts.Now()
+			err := next.Handle(ctx)
+			duration := t.opts.Now().Sub(start)
+
+			for _, slo := range matched {
+				good := t.opts.IsGood(ctx, err, duration, slo)
+				burnRate := t.Record(slo.Name, good)
+				t.emit(ctx, slo, good, burnRate)
+			}
+
+			return err
+		})
+	}
+}
+
+// Record adds an outcome for the named SLO and returns the burn rate over its window
+func (t *SLOTracker) Record(name string, good bool) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.state[name]
+	if !ok {
+		return 0
+	}
+
+	now := t.opts.Now()
+	minute := now.Unix() / 60
+	bucket, ok := w.buckets[minute]
+	if !ok {
+		bucket = &sloBucket{}
+		w.buckets[minute] = bucket
+	}
+	if good {
+		bucket.good++
+	} else {
+		bucket.bad++
+	}
+
+	w.prune(now)
+	return t.statusLocked(name).BurnRate
+}
+
+// Status returns the current status of every SLO
+func (t *SLOTracker) Status() []SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.opts.Now()
+	statuses := make([]SLOStatus, 0, len(t.slos))
+	for _, slo := range t.slos {
+		t.state[slo.Name].prune(now)
+		statuses = append(statuses, t.statusLocked(slo.Name))
+	}
+	return statuses
+}
+
+// SLOs returns the normalized SLO declarations, including those declared with
+// route metadata that have seen traffic
+func (t *SLOTracker) SLOs() []RouteSLO {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]RouteSLO, len(t.slos))
+	copy(out, t.slos)
+	return out
+}
+
+func (t *SLOTracker) statusLocked(name string) SLOStatus {
+	status := SLOStatus{Name: name}
+	for _, slo := range t.slos {
+		if slo.Name == name {
+			status.Target = slo.Target
+			break
+		}
+	}
+
+	for _, bucket := range t.state[name].buckets {
+		status.Good += bucket.good
+		status.Bad += bucket.bad
+	}
+	status.Total = status.Good + status.Bad
+	status.BurnRate = BurnRate(status.Good, status.Bad, status.Target)
+	return status
+}
+
+// prune drops buckets that have fallen out of the window
+func (w *sloWindow) prune(now time.Time) {
+	oldest := now.Add(-w.window).Unix() / 60
+	for minute := range w.buckets {
+		if minute <= oldest {
+			delete(w.buckets, minute)
+		}
+	}
+}
+
+// BurnRate returns how fast the error budget is being consumed: 1.0 spends the
+// budget exactly over the SLO period, 14.4 exhausts a 30 day budget in ~2 days
+func BurnRate(good, bad int64, target float64) float64 {
+	total := good + bad
+	budget := 1 - target
+	if total == 0 || budget <= 0 {
+		return 0
+	}
+	return (float64(bad) / float64(total)) / budget
+}
+
+func (t *SLOTracker) match(ctx *lift.Context) []RouteSLO {
+	route := ctx.Route()
+	if route == "" && ctx.Request != nil {
+		route = ctx.Request.Path
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var matched []RouteSLO
+	if slo := t.routeSLOLocked(ctx, route); slo != nil {
+		matched = append(matched, *slo)
+	}
+	for _, slo := range t.slos {
+		if _, fromRoute := t.routeSLOs[slo.Name]; fromRoute {
+			continue
+		}
+		if slo.Method != "" && !strings.EqualFold(slo.Method, ctx.Request.Method) {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(slo.Route, "*"); ok {
+			if !strings.HasPrefix(route, prefix) {
+				continue
+			}
+		} else if slo.Route != "" && slo.Route != route {
+			continue
+		}
+		matched = append(matched, slo)
+	}
+	return matched
+}
+
+// routeSLOLocked returns the SLO declared by the matched route's metadata, or
+// nil. Declared SLOs with the same name take precedence.
+func (t *SLOTracker) routeSLOLocked(ctx *lift.Context, route string) *RouteSLO {
+	threshold, ok := ctx.RouteMeta(lift.MetaSLO)
+	if !ok {
+		return nil
+	}
+
+	name := strings.TrimSpace(ctx.Request.Method + " " + route)
+	if slo, seen := t.routeSLOs[name]; seen {
+		return slo
+	}
+	if _, declared := t.state[name]; declared {
+		return nil
+	}
+
+	latency, err := time.ParseDuration(threshold)
+	target := DefaultRouteSLOTarget
+	if raw, ok := ctx.RouteMeta(lift.MetaSLOTarget); ok && err == nil {
+		target, err = parseSLOTarget(raw)
+	}
+	if err != nil {
+		t.routeSLOs[name] = nil
+		if ctx.Logger != nil {
+			ctx.Logger.Warn("Ignoring invalid route SLO metadata", map[string]any{
+				"route": name,
+				"error": err.Error(),
+			})
+		}
+		return nil
+	}
+
+	slo := t.addLocked(RouteSLO{
+		Name:             name,
+		Method:           ctx.Request.Method,
+		Route:            route,
+		Target:           target,
+		LatencyThreshold: latency,
+	})
+	t.routeSLOs[name] = &slo
+	return &slo
+}
+
+// parseSLOTarget parses a target written as a fraction ("0.999") or percentage ("99.9%")
+func parseSLOTarget(raw string) (float64, error) {
+	value := strings.TrimSpace(raw)
+	percent := strings.HasSuffix(value, "%")
+	target, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid SLO target %q", raw)
+	}
+	if percent || target > 1 {
+		target /= 100
+	}
+	if target <= 0 || target >= 1 {
+		return 0, fmt.Errorf("SLO target %q must be between 0 and 100%%", raw)
+	}
+	return target, nil
+}
+
+func (t *SLOTracker) emit(ctx *lift.Context, slo RouteSLO, good bool, burnRate float64) {
+	if ctx.Metrics == nil {
+		return
+	}
+
+	tags := map[string]string{"slo": slo.Name}
+	ctx.Metrics.Counter(SLORequestsMetric, tags).Inc()
+	if good {
+		ctx.Metrics.Counter(SLOGoodMetric, tags).Inc()
+	} else {
+		ctx.Metrics.Counter(SLOBadMetric, tags).Inc()
+	}
+	ctx.Metrics.Gauge(SLOBurnRateMetric, tags).Set(burnRate)
+}
+
+func defaultSLOIsGood(ctx *lift.Context, err error, duration time.Duration, slo RouteSLO) bool {
+	if slo.LatencyThreshold > 0 && duration > slo.LatencyThreshold {
+		return false
+	}
+	if err != nil {
+		if liftErr, ok := err.(*lift.LiftError); ok {
+			return liftErr.StatusCode < 500
+		}
+		return false
+	}
+	return ctx.Response.StatusCode < 500
+}
+
+// BurnRateWindow is one burn-rate alert condition
+type BurnRateWindow struct {
+	// Window is the alarm evaluation window
+	Window time.Duration
+	// BurnRate is the threshold that triggers the alarm
+	BurnRate float64
+	// Severity is added to the alarm name (e.g. "page", "ticket")
+	Severity string
+}
+
+// DefaultBurnRateWindows are the multi-window thresholds recommended for a 30 day SLO
+var DefaultBurnRateWindows = []BurnRateWindow{
+	{Window: time.Hour, BurnRate: 14.4, Severity: "page"},
+	{Window: 6 * time.Hour, BurnRate: 6, Severity: "page"},
+	{Window: 24 * time.Hour, BurnRate: 3, Severity: "ticket"},
+}
+
+// SLOAlarmOptions configures generated alarm definitions
+type SLOAlarmOptions struct {
+	// Namespace is the CloudWatch namespace the SLO metrics are published to
+	Namespace string
+	// Prefix is prepended to alarm names (e.g. "<app>-<stage>")
+	Prefix string
+	// Windows defaults to DefaultBurnRateWindows
+	Windows []BurnRateWindow
+	// Dimensions are added to every metric in addition to the slo dimension
+	Dimensions map[string]string
+	// Actions are the alarm action ARNs (e.g. an SNS topic)
+	Actions []string
+}
+
+// SLOAlarm is a CloudWatch metric-math alarm definition. Field names match the
+// AWS::CloudWatch::Alarm resource so definitions can be passed straight to
+// CloudFormation or a CDK CfnAlarm.
+type SLOAlarm struct {
+	AlarmName          string           `json:"AlarmName"`
+	AlarmDescription   string           `json:"AlarmDescription"`
+	ComparisonOperator string           `json:"ComparisonOperator"`
+	Threshold          float64          `json:"Threshold"`
+	EvaluationPeriods  int              `json:"EvaluationPeriods"`
+	DatapointsToAlarm  int              `json:"DatapointsToAlarm"`
+	TreatMissingData   string           `json:"TreatMissingData"`
+	Metrics            []SLOAlarmMetric `json:"Metrics"`
+	AlarmActions       []string         `json:"AlarmActions,omitempty"`
+}
+
+// SLOAlarmMetric is a metric or expression in an alarm's metric math
+type SLOAlarmMetric struct {
+	ID         string         `json:"Id"`
+	Expression string         `json:"Expression,omitempty"`
+	Label      string         `json:"Label,omitempty"`
+	MetricStat *SLOMetricStat `json:"MetricStat,omitempty"`
+	ReturnData bool           `json:"ReturnData"`
+}
+
+// SLOMetricStat selects a metric and statistic
+type SLOMetricStat struct {
+	Metric SLOMetricRef `json:"Metric"`
+	Period int          `json:"Period"`
+	Stat   string       `json:"Stat"`
+}
+
+// SLOMetricRef identifies a CloudWatch metric
+type SLOMetricRef struct {
+	Namespace  string               `json:"Namespace"`
+	MetricName string               `json:"MetricName"`
+	Dimensions []SLOMetricDimension `json:"Dimensions"`
+}
+
+// SLOMetricDimension is a CloudWatch metric dimension
+type SLOMetricDimension struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// AlarmDefinitions generates burn-rate alarms for every SLO
+func (t *SLOTracker) AlarmDefinitions(opts SLOAlarmOptions) []SLOAlarm {
+	windows := opts.Windows
+	if len(windows) == 0 {
+		windows = DefaultBurnRateWindows
+	}
+
+	var alarms []SLOAlarm
+	for _, slo := range t.SLOs() {
+		for _, w := range windows {
+			alarms = append(alarms, sloAlarm(slo, w, opts))
+		}
+	}
+	return alarms
+}
+
+func sloAlarm(slo RouteSLO, w BurnRateWindow, opts SLOAlarmOptions) SLOAlarm {
+	period := int(w.Window.Seconds())
+
+	dimensions := []SLOMetricDimension{{Name: "slo", Value: slo.Name}}
+	for name, value := range opts.Dimensions {
+		dimensions = append(dimensions, SLOMetricDimension{Name: name, Value: value})
+	}
+	metric := func(id, name string) SLOAlarmMetric {
+		return SLOAlarmMetric{
+			ID: id,
+			MetricStat: &SLOMetricStat{
+				Metric: SLOMetricRef{Namespace: opts.Namespace, MetricName: name, Dimensions: dimensions},
+				Period: period,
+				Stat:   "Sum",
+			},
+		}
+	}
+
+	name := sloAlarmName(slo.Name)
+	if opts.Prefix != "" {
+		name = opts.Prefix + "-" + name
+	}
+	name = fmt.Sprintf("%s-burn-%s-%s", name, formatWindow(w.Window), w.Severity)
+
+	return SLOAlarm{
+		AlarmName: strings.TrimSuffix(name, "-"),
+		AlarmDescription: fmt.Sprintf("SLO %s (%.3f%% target) burning error budget at over %.1fx over %s",
+			slo.Name, slo.Target*100, w.BurnRate, formatWindow(w.Window)),
+		ComparisonOperator: "GreaterThanThreshold",
+		Threshold:          w.BurnRate,
+		EvaluationPeriods:  1,
+		DatapointsToAlarm:  1,
+		TreatMissingData:   "notBreaching",
+		Metrics: []SLOAlarmMetric{
+			metric("bad", SLOBadMetric),
+			metric("total", SLORequestsMetric),
+			{
+				ID:         "burn_rate",
+				Expression: fmt.Sprintf("(bad / total) / %.6g", 1-slo.Target),
+				Label:      "Burn rate",
+				ReturnData: true,
+			},
+		},
+		AlarmActions: opts.Actions,
+	}
+}
+
+// sloAlarmName converts an SLO name such as "GET /payments/:id" into an alarm-safe name
+func sloAlarmName(name string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func formatWindow(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
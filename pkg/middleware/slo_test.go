@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createSLOTestContext(method, route string) *lift.Context {
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  method,
+		Path:    route,
+		Headers: make(map[string]string),
+	}))
+	ctx.SetRoute(route)
+	return ctx
+}
+
+func TestSLOTracker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewSLOTracker(SLOOptions{
+		SLOs: []RouteSLO{
+			{Method: "GET", Route: "/payments/:id", Target: 0.99, LatencyThreshold: 300 * time.Millisecond},
+			{Name: "api", Route: "/payments*", Target: 0.9},
+		},
+		Now: func() time.Time { return now },
+	})
+
+	ok := lift.HandlerFunc(func(ctx *lift.Context) error { return nil })
+	slow := lift.HandlerFunc(func(ctx *lift.Context) error {
+		now = now.Add(500 * time.Millisecond)
+		return nil
+	})
+	failing := lift.HandlerFunc(func(ctx *lift.Context) error { return lift.SystemError("down") })
+	notFound := lift.HandlerFunc(func(ctx *lift.Context) error { return lift.NotFound("missing") })
+
+	metrics := &mockMetrics{metrics: make(map[string]any)}
+	for _, h := range []lift.Handler{ok, ok, slow, failing, notFound} {
+		ctx := createSLOTestContext("GET", "/payments/:id")
+		ctx.Metrics = metrics
+		tracker.Middleware()(h).Handle(ctx)
+	}
+	// Unmatched routes are ignored
+	tracker.Middleware()(failing).Handle(createSLOTestContext("GET", "/health"))
+
+	statuses := tracker.Status()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "GET /payments/:id", statuses[0].Name)
+	assert.Equal(t, int64(5), statuses[0].Total)
+	assert.Equal(t, int64(2), statuses[0].Bad)
+	assert.InDelta(t, 40.0, statuses[0].BurnRate, 0.0001)
+
+	assert.Equal(t, int64(1), statuses[1].Bad, "latency threshold only applies to the route SLO")
+	assert.Equal(t, 10, metrics.metrics[SLORequestsMetric])
+
+	// Outcomes expire once they leave the window
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, int64(0), tracker.Status()[0].Total)
+}
+
+func TestSLOTrackerRouteMetadata(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewSLOTracker(SLOOptions{
+		SLOs: []RouteSLO{{Method: "GET", Route: "/declared", Target: 0.9}},
+		Now:  func() time.Time { return now },
+	})
+
+	app := lift.New()
+	app.Use(lift.Middleware(tracker.Middleware()))
+	slow := func(ctx *lift.Context) error {
+		now = now.Add(400 * time.Millisecond)
+		return ctx.Text("ok")
+	}
+	app.GET("/payments/:id", slow).Meta(lift.MetaSLO, "300ms").Meta(lift.MetaSLOTarget, "99%")
+	app.GET("/declared", slow).Meta(lift.MetaSLO, "1s")
+	app.GET("/invalid", slow).Meta(lift.MetaSLO, "fast")
+
+	for _, path := range []string{"/payments/1", "/payments/2", "/declared", "/invalid"} {
+		require.NoError(t, app.HandleTestRequest(createSLOTestContext("GET", path)))
+	}
+
+	statuses := tracker.Status()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "GET /declared", statuses[0].Name)
+	assert.Equal(t, 0.9, statuses[0].Target, "declared SLOs take precedence over route metadata")
+	assert.Equal(t, int64(0), statuses[0].Bad)
+
+	assert.Equal(t, "GET /payments/:id", statuses[1].Name)
+	assert.Equal(t, 0.99, statuses[1].Target)
+	assert.Equal(t, int64(2), statuses[1].Bad)
+	assert.Equal(t, 300*time.Millisecond, tracker.SLOs()[1].LatencyThreshold)
+}
+
+func TestParseSLOTarget(t *testing.T) {
+	for raw, expected := range map[string]float64{"0.999": 0.999, "99.9%": 0.999, "99.5": 0.995} {
+		target, err := parseSLOTarget(raw)
+		require.NoError(t, err)
+		assert.InDelta(t, expected, target, 1e-9, raw)
+	}
+	for _, raw := range []string{"", "100%", "abc", "0"} {
+		_, err := parseSLOTarget(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestSLOAlarmDefinitions(t *testing.T) {
+	tracker := NewSLOTracker(SLOOptions{
+		SLOs: []RouteSLO{{Method: "POST", Route: "/payments", Target: 0.999}},
+	})
+
+	alarms := tracker.AlarmDefinitions(SLOAlarmOptions{
+		Namespace: "PayTheory/Payments",
+		Prefix:    "payments-prod",
+		Actions:   []string{"arn:aws:sns:us-east-1:123456789012:alerts"},
+	})
+	require.Len(t, alarms, len(DefaultBurnRateWindows))
+
+	alarm := alarms[0]
+	assert.Equal(t, "payments-prod-post-payments-burn-1h-page", alarm.AlarmName)
+	assert.Equal(t, 14.4, alarm.Threshold)
+	require.Len(t, alarm.Metrics, 3)
+	assert.Equal(t, "(bad / total) / 0.001", alarm.Metrics[2].Expression)
+	assert.True(t, alarm.Metrics[2].ReturnData)
+	assert.Equal(t, 3600, alarm.Metrics[0].MetricStat.Period)
+	assert.Equal(t, SLOBadMetric, alarm.Metrics[0].MetricStat.Metric.MetricName)
+	assert.Equal(t, "POST /payments", alarm.Metrics[0].MetricStat.Metric.Dimensions[0].Value)
+}
+
+func TestBurnRate(t *testing.T) {
+	assert.Equal(t, 0.0, BurnRate(0, 0, 0.999))
+	assert.InDelta(t, 1.0, BurnRate(999, 1, 0.999), 0.0001)
+	assert.Equal(t, 0.0, BurnRate(1, 1, 1))
+}
@@ -0,0 +1,616 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/dynamorm"
+	"github.com/pay-theory/lift/pkg/lift"
+	"gopkg.in/yaml.v3"
+)
+
+// StackConfigVersion is the current middleware stack config version
+const StackConfigVersion = 1
+
+// StackConfig declares an application's middleware stack, in order:
+//
+//	version: 1
+//	middleware:
+//	  - name: request_id
+//	  - name: cors
+//	    config:
+//	      origins: ["https://app.example.com"]
+//	  - name: auth
+//	    config:
+//	      provider: jwt
+//	  - name: rate_limit
+//	    config:
+//	      limit: 100
+//	      window: 1m
+//	    environments:
+//	      prod:
+//	        config:
+//	          limit: 1000
+//
+// Configs are validated against each middleware's schema when the stack is
+// built, so a typo fails at init instead of silently changing behavior.
+type StackConfig struct {
+	Version    int               `json:"version" yaml:"version"`
+	Middleware []MiddlewareEntry `json:"middleware" yaml:"middleware"`
+}
+
+// MiddlewareEntry configures one middleware in the stack
+type MiddlewareEntry struct {
+	// Name selects a middleware registered in the StackRegistry
+	Name string `json:"name" yaml:"name"`
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Config holds the middleware's settings
+	Config map[string]any `json:"config,omitempty" yaml:"config,omitempty"`
+	// Environments overrides Enabled and individual Config keys per environment
+	Environments map[string]EnvironmentOverride `json:"environments,omitempty" yaml:"environments,omitempty"`
+}
+
+// EnvironmentOverride overrides a middleware entry in one environment
+type EnvironmentOverride struct {
+	Enabled *bool          `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Config  map[string]any `json:"config,omitempty" yaml:"config,omitempty"`
+}
+
+// resolve returns whether the entry is enabled and its config for an environment
+func (e MiddlewareEntry) resolve(environment string) (bool, map[string]any) {
+	enabled := e.Enabled == nil || *e.Enabled
+	config := make(map[string]any, len(e.Config))
+	for key, value := range e.Config {
+		config[key] = value
+	}
+
+	if override, ok := e.Environments[environment]; ok {
+		if override.Enabled != nil {
+			enabled = *override.Enabled
+		}
+		for key, value := range override.Config {
+			config[key] = value
+		}
+	}
+	return enabled, config
+}
+
+// LoadStackConfig reads a stack config from a .yaml, .yml or .json file
+func LoadStackConfig(path string) (*StackConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read middleware config: %w", err)
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	config, err := ParseStackConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseStackConfig parses a stack config in the given format ("yaml", "yml" or "json").
+// Unknown fields are rejected.
+func ParseStackConfig(data []byte, format string) (*StackConfig, error) {
+	var config StackConfig
+
+	switch format {
+	case "yaml", "yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("invalid middleware config: %w", err)
+		}
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("invalid middleware config: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported middleware config format %q", format)
+	}
+
+	return &config, nil
+}
+
+// FieldType is the type of a middleware config field
+type FieldType string
+
+const (
+	FieldString     FieldType = "string"
+	FieldInt        FieldType = "int"
+	FieldBool       FieldType = "bool"
+	FieldDuration   FieldType = "duration"
+	FieldStringList FieldType = "string_list"
+	FieldStringMap  FieldType = "string_map"
+)
+
+// FieldSchema describes one middleware config field
+type FieldSchema struct {
+	Type     FieldType
+	Required bool
+	// Enum restricts string fields to the listed values
+	Enum []string
+	// Default is used when the field is not set
+	Default any
+}
+
+// StackSchema describes the config fields a middleware accepts
+type StackSchema map[string]FieldSchema
+
+// StackFactory builds a middleware from validated params
+type StackFactory func(params StackParams, opts StackOptions) (lift.Middleware, error)
+
+// StackRegistry maps middleware names to their schemas and factories
+type StackRegistry struct {
+	entries map[string]stackRegistration
+}
+
+type stackRegistration struct {
+	schema     StackSchema
+	factory    StackFactory
+	preRouting bool
+}
+
+// NewStackRegistry creates a registry with the built-in middleware:
+// request_id, logger, recover, metrics, error_handler, cors, timeout,
+// security_headers, rate_limit, auth and rewrite
+func NewStackRegistry() *StackRegistry {
+	r := &StackRegistry{entries: make(map[string]stackRegistration)}
+
+	r.RegisterPreRouting("rewrite", StackSchema{
+		"paths":         {Type: FieldStringList},
+		"default_query": {Type: FieldStringMap},
+		"set_headers":   {Type: FieldStringMap},
+		"strip_headers": {Type: FieldStringList},
+	}, func(params StackParams, _ StackOptions) (lift.Middleware, error) {
+		config := RewriteConfig{
+			DefaultQuery: params.StringMap("default_query"),
+			SetHeaders:   params.StringMap("set_headers"),
+			StripHeaders: params.Strings("strip_headers"),
+		}
+		for _, rule := range params.Strings("paths") {
+			rewrite, err := ParsePathRewrite(rule)
+			if err != nil {
+				return nil, err
+			}
+			config.Paths = append(config.Paths, rewrite)
+		}
+		return Rewrite(config)
+	})
+
+	r.Register("request_id", nil, func(StackParams, StackOptions) (lift.Middleware, error) {
+		return lift.Middleware(RequestID()), nil
+	})
+	r.Register("logger", nil, func(StackParams, StackOptions) (lift.Middleware, error) {
+		return lift.Middleware(Logger()), nil
+	})
+	r.Register("recover", nil, func(StackParams, StackOptions) (lift.Middleware, error) {
+		return lift.Middleware(Recover()), nil
+	})
+	r.Register("metrics", nil, func(StackParams, StackOptions) (lift.Middleware, error) {
+		return lift.Middleware(Metrics()), nil
+	})
+	r.Register("error_handler", nil, func(StackParams, StackOptions) (lift.Middleware, error) {
+		return lift.Middleware(ErrorHandler()), nil
+	})
+
+	r.Register("cors", StackSchema{
+		"origins":         {Type: FieldStringList, Required: true},
+		"origin_patterns": {Type: FieldStringList},
+		"methods":         {Type: FieldStringList},
+		"headers":         {Type: FieldStringList},
+		"expose_headers":  {Type: FieldStringList},
+		"credentials":     {Type: FieldBool},
+		"max_age":         {Type: FieldDuration},
+	}, func(params StackParams, _ StackOptions) (lift.Middleware, error) {
+		patterns := params.Strings("origin_patterns")
+		for _, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid origin pattern %q: %w", pattern, err)
+			}
+		}
+		return CORS(CORSConfig{
+			AllowOrigins:        params.Strings("origins"),
+			AllowOriginPatterns: patterns,
+			AllowMethods:        params.Strings("methods"),
+			AllowHeaders:        params.Strings("headers"),
+			ExposeHeaders:       params.Strings("expose_headers"),
+			AllowCredentials:    params.Bool("credentials"),
+			MaxAge:              params.Duration("max_age"),
+		}), nil
+	})
+
+	r.Register("timeout", StackSchema{
+		"duration": {Type: FieldDuration, Required: true},
+	}, func(params StackParams, _ StackOptions) (lift.Middleware, error) {
+		return lift.Middleware(Timeout(params.Duration("duration"))), nil
+	})
+
+	r.Register("security_headers", StackSchema{
+		"preset": {Type: FieldString, Enum: []string{"default", "strict", "api"}, Default: "default"},
+	}, func(params StackParams, _ StackOptions) (lift.Middleware, error) {
+		switch params.String("preset") {
+		case "strict":
+			return StrictSecurityHeaders(), nil
+		case "api":
+			return APISecurityHeaders(), nil
+		default:
+			return SecurityHeaders(DefaultSecurityHeadersConfig()), nil
+		}
+	})
+
+	r.Register("rate_limit", StackSchema{
+		"limit":  {Type: FieldInt, Required: true},
+		"window": {Type: FieldDuration, Required: true},
+		"burst":  {Type: FieldInt},
+		"by":     {Type: FieldString, Enum: []string{"tenant", "user", "ip", "endpoint"}, Default: "tenant"},
+	}, func(params StackParams, opts StackOptions) (lift.Middleware, error) {
+		if opts.DynamORM == nil {
+			return nil, fmt.Errorf("rate_limit requires StackOptions.DynamORM")
+		}
+		keyFuncs := map[string]func(*lift.Context) *RateLimitKey{
+			"tenant":   tenantKeyFunc,
+			"user":     userKeyFunc,
+			"ip":       ipKeyFunc,
+			"endpoint": endpointKeyFunc,
+		}
+		return RateLimitMiddleware(RateLimitConfig{
+			DynamORM:      opts.DynamORM,
+			DefaultLimit:  params.Int("limit"),
+			DefaultWindow: params.Duration("window"),
+			Window:        params.Duration("window"),
+			BurstLimit:    params.Int("burst"),
+			KeyFunc:       keyFuncs[params.String("by")],
+			ErrorHandler:  defaultErrorHandler,
+		}), nil
+	})
+
+	r.Register("auth", StackSchema{
+		"provider": {Type: FieldString, Required: true},
+		"roles":    {Type: FieldStringList},
+		"scopes":   {Type: FieldStringList},
+	}, func(params StackParams, opts StackOptions) (lift.Middleware, error) {
+		provider, ok := opts.AuthProviders[params.String("provider")]
+		if !ok {
+			return nil, fmt.Errorf("unknown auth provider %q", params.String("provider"))
+		}
+		roles, scopes := params.Strings("roles"), params.Strings("scopes")
+		return func(next lift.Handler) lift.Handler {
+			return provider(lift.HandlerFunc(func(ctx *lift.Context) error {
+				if err := authorizePrincipal(ctx, roles, scopes); err != nil {
+					return err
+				}
+				return next.Handle(ctx)
+			}))
+		}, nil
+	})
+
+	return r
+}
+
+// Register adds or replaces a middleware. A nil schema accepts no config.
+func (r *StackRegistry) Register(name string, schema StackSchema, factory StackFactory) {
+	r.entries[name] = stackRegistration{schema: schema, factory: factory}
+}
+
+// RegisterPreRouting adds or replaces a middleware that Apply registers with
+// App.Pre, for middleware that changes how a request is routed
+func (r *StackRegistry) RegisterPreRouting(name string, schema StackSchema, factory StackFactory) {
+	r.entries[name] = stackRegistration{schema: schema, factory: factory, preRouting: true}
+}
+
+// Names returns the registered middleware names, sorted
+func (r *StackRegistry) Names() []string {
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StackOptions provides what a stack config can't express, such as auth
+// providers with their keys, and selects the environment
+type StackOptions struct {
+	// Environment selects per-environment overrides (e.g. "prod")
+	Environment string
+	// Registry defaults to NewStackRegistry()
+	Registry *StackRegistry
+	// AuthProviders are referenced by name from auth entries
+	AuthProviders map[string]lift.Middleware
+	// DynamORM backs rate_limit entries
+	DynamORM *dynamorm.DynamORMWrapper
+}
+
+// StackConfigError lists every problem found while validating a stack config
+type StackConfigError struct {
+	Problems []string
+}
+
+func (e *StackConfigError) Error() string {
+	return "invalid middleware config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the config against the registry's schemas for the
+// environment. All problems are reported together as a *StackConfigError.
+func (c *StackConfig) Validate(opts StackOptions) error {
+	_, err := c.resolve(opts)
+	return err
+}
+
+// Build validates the config and returns the middleware stack in order,
+// including pre-routing middleware. Use Apply to register each middleware
+// in the right phase.
+func (c *StackConfig) Build(opts StackOptions) ([]lift.Middleware, error) {
+	built, err := c.build(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stack := make([]lift.Middleware, 0, len(built))
+	for _, entry := range built {
+		stack = append(stack, entry.middleware)
+	}
+	return stack, nil
+}
+
+// Apply builds the stack and adds it to the app, using App.Pre for
+// pre-routing middleware and App.Use for the rest
+func (c *StackConfig) Apply(app *lift.App, opts StackOptions) error {
+	built, err := c.build(opts)
+	if err != nil {
+		return err
+	}
+	for _, entry := range built {
+		if entry.preRouting {
+			app.Pre(entry.middleware)
+		} else {
+			app.Use(entry.middleware)
+		}
+	}
+	return nil
+}
+
+type builtMiddleware struct {
+	middleware lift.Middleware
+	preRouting bool
+}
+
+// build validates the config and creates every enabled middleware
+func (c *StackConfig) build(opts StackOptions) ([]builtMiddleware, error) {
+	resolved, err := c.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	built := make([]builtMiddleware, 0, len(resolved))
+	for _, entry := range resolved {
+		mw, err := entry.registration.factory(entry.params, opts)
+		if err != nil {
+			return nil, fmt.Errorf("middleware %s: %w", entry.name, err)
+		}
+		built = append(built, builtMiddleware{middleware: mw, preRouting: entry.registration.preRouting})
+	}
+	return built, nil
+}
+
+type resolvedEntry struct {
+	name         string
+	registration stackRegistration
+	params       StackParams
+}
+
+// resolve validates every enabled entry and converts its config to params
+func (c *StackConfig) resolve(opts StackOptions) ([]resolvedEntry, error) {
+	registry := opts.Registry
+	if registry == nil {
+		registry = NewStackRegistry()
+	}
+
+	var problems []string
+	if c.Version != 0 && c.Version != StackConfigVersion {
+		problems = append(problems, fmt.Sprintf("unsupported version %d", c.Version))
+	}
+
+	var resolved []resolvedEntry
+	for i, entry := range c.Middleware {
+		path := fmt.Sprintf("middleware[%d]", i)
+		if entry.Name != "" {
+			path += " (" + entry.Name + ")"
+		}
+
+		registration, ok := registry.entries[entry.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown middleware %q", path, entry.Name))
+			continue
+		}
+
+		enabled, config := entry.resolve(opts.Environment)
+		if !enabled {
+			continue
+		}
+
+		params, fieldProblems := registration.schema.convert(config)
+		for _, problem := range fieldProblems {
+			problems = append(problems, path+": "+problem)
+		}
+		resolved = append(resolved, resolvedEntry{name: entry.Name, registration: registration, params: params})
+	}
+
+	if len(problems) > 0 {
+		return nil, &StackConfigError{Problems: problems}
+	}
+	return resolved, nil
+}
+
+// convert validates a raw config against the schema and converts it to typed params
+func (s StackSchema) convert(config map[string]any) (StackParams, []string) {
+	var problems []string
+	params := StackParams{}
+
+	for key := range config {
+		if _, ok := s[key]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown field %q", key))
+		}
+	}
+
+	for key, field := range s {
+		raw, ok := config[key]
+		if !ok || raw == nil {
+			if field.Required {
+				problems = append(problems, fmt.Sprintf("missing required field %q", key))
+			} else if field.Default != nil {
+				params[key] = field.Default
+			}
+			continue
+		}
+
+		value, err := field.convert(raw)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("field %q: %v", key, err))
+			continue
+		}
+		params[key] = value
+	}
+
+	sort.Strings(problems)
+	return params, problems
+}
+
+// convert converts a decoded YAML or JSON value to the field's Go type
+func (f FieldSchema) convert(raw any) (any, error) {
+	switch f.Type {
+	case FieldString:
+		value, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string")
+		}
+		if len(f.Enum) > 0 && !containsString(f.Enum, value) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(f.Enum, ", "))
+		}
+		return value, nil
+
+	case FieldInt:
+		switch value := raw.(type) {
+		case int:
+			return value, nil
+		case float64:
+			// JSON numbers decode as float64
+			if value == math.Trunc(value) {
+				return int(value), nil
+			}
+		}
+		return nil, fmt.Errorf("expected an integer")
+
+	case FieldBool:
+		value, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		return value, nil
+
+	case FieldDuration:
+		value, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a duration such as \"30s\"")
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("expected a positive duration such as \"30s\"")
+		}
+		return duration, nil
+
+	case FieldStringList:
+		items, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected a list of strings")
+		}
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			value, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings")
+			}
+			values = append(values, value)
+		}
+		return values, nil
+
+	case FieldStringMap:
+		items, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected a map of strings")
+		}
+		values := make(map[string]string, len(items))
+		for key, item := range items {
+			value, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a map of strings")
+			}
+			values[key] = value
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("unsupported field type %q", f.Type)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// StackParams holds a middleware's validated config. Getters return the
+// zero value for fields that are unset.
+type StackParams map[string]any
+
+// String returns a string field
+func (p StackParams) String(key string) string {
+	value, _ := p[key].(string)
+	return value
+}
+
+// Int returns an int field
+func (p StackParams) Int(key string) int {
+	value, _ := p[key].(int)
+	return value
+}
+
+// Bool returns a bool field
+func (p StackParams) Bool(key string) bool {
+	value, _ := p[key].(bool)
+	return value
+}
+
+// Duration returns a duration field
+func (p StackParams) Duration(key string) time.Duration {
+	value, _ := p[key].(time.Duration)
+	return value
+}
+
+// Strings returns a string list field
+func (p StackParams) Strings(key string) []string {
+	value, _ := p[key].([]string)
+	return value
+}
+
+// StringMap returns a string map field
+func (p StackParams) StringMap(key string) map[string]string {
+	value, _ := p[key].(map[string]string)
+	return value
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStackYAML = `
+version: 1
+middleware:
+  - name: request_id
+  - name: cors
+    config:
+      origins: ["https://app.example.com"]
+    environments:
+      dev:
+        config:
+          origins: ["*"]
+  - name: timeout
+    config:
+      duration: 5s
+    environments:
+      dev:
+        enabled: false
+  - name: auth
+    config:
+      provider: header
+      roles: [admin]
+`
+
+func TestStackConfigBuild(t *testing.T) {
+	config, err := ParseStackConfig([]byte(testStackYAML), "yaml")
+	require.NoError(t, err)
+
+	// Environment overrides replace individual keys and can disable entries
+	entry := config.Middleware[1]
+	_, cors := entry.resolve("dev")
+	assert.Equal(t, []any{"*"}, cors["origins"])
+	enabled, _ := config.Middleware[2].resolve("dev")
+	assert.False(t, enabled)
+
+	provider := func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if roles := ctx.Header("X-Test-Roles"); roles != "" {
+				ctx.Set("principal", &security.Principal{UserID: "user-1", Roles: []string{roles}})
+			}
+			return next.Handle(ctx)
+		})
+	}
+	opts := StackOptions{
+		Environment:   "prod",
+		AuthProviders: map[string]lift.Middleware{"header": provider},
+	}
+
+	stack, err := config.Build(opts)
+	require.NoError(t, err)
+	assert.Len(t, stack, 4)
+
+	opts.Environment = "dev"
+	stack, err = config.Build(opts)
+	require.NoError(t, err)
+	assert.Len(t, stack, 3)
+
+	app := lift.New()
+	require.NoError(t, config.Apply(app, opts))
+	app.GET("/reports", func(ctx *lift.Context) error { return ctx.Text("ok") })
+	assert.Equal(t, 401, routeAuthRequest(t, app, "/reports", ""))
+	assert.Equal(t, 403, routeAuthRequest(t, app, "/reports", "viewer"))
+	assert.Equal(t, 200, routeAuthRequest(t, app, "/reports", "admin"))
+}
+
+func TestStackConfigValidation(t *testing.T) {
+	config, err := ParseStackConfig([]byte(`{
+		"version": 1,
+		"middleware": [
+			{"name": "rate_limit", "config": {"limit": 10.5, "window": "soon", "by": "planet", "burst": 5}},
+			{"name": "cors", "config": {"origin": "https://app.example.com"}},
+			{"name": "compression"}
+		]
+	}`), "json")
+	require.NoError(t, err)
+
+	err = config.Validate(StackOptions{})
+	var configErr *StackConfigError
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, []string{
+		`middleware[0] (rate_limit): field "by": must be one of tenant, user, ip, endpoint`,
+		`middleware[0] (rate_limit): field "limit": expected an integer`,
+		`middleware[0] (rate_limit): field "window": expected a positive duration such as "30s"`,
+		`middleware[1] (cors): missing required field "origins"`,
+		`middleware[1] (cors): unknown field "origin"`,
+		`middleware[2] (compression): unknown middleware "compression"`,
+	}, configErr.Problems)
+
+	// Unknown top-level fields are rejected when parsing
+	_, err = ParseStackConfig([]byte("version: 1\nmiddlewares: []\n"), "yaml")
+	assert.Error(t, err)
+
+	// Factories can still fail on missing runtime options
+	config, err = ParseStackConfig([]byte(`{"middleware": [{"name": "auth", "config": {"provider": "saml"}}]}`), "json")
+	require.NoError(t, err)
+	_, err = config.Build(StackOptions{})
+	assert.EqualError(t, err, `middleware auth: unknown auth provider "saml"`)
+}
+
+func TestStackConfigCustomMiddleware(t *testing.T) {
+	registry := NewStackRegistry()
+	var gotTimeout time.Duration
+	registry.Register("deadline", StackSchema{
+		"after": {Type: FieldDuration, Default: time.Second},
+	}, func(params StackParams, _ StackOptions) (lift.Middleware, error) {
+		gotTimeout = params.Duration("after")
+		return func(next lift.Handler) lift.Handler { return next }, nil
+	})
+	assert.Contains(t, registry.Names(), "deadline")
+
+	path := filepath.Join(t.TempDir(), "middleware.yml")
+	require.NoError(t, os.WriteFile(path, []byte("middleware:\n  - name: deadline\n"), 0o600))
+
+	config, err := LoadStackConfig(path)
+	require.NoError(t, err)
+	_, err = config.Build(StackOptions{Registry: registry})
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, gotTimeout)
+
+	_, err = LoadStackConfig(filepath.Join(t.TempDir(), "middleware.toml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepUpGuard(t *testing.T) {
+	storage := security.NewInMemoryAuditStorage()
+	su, err := security.NewStepUp(security.StepUpConfig{Audit: storage})
+	require.NoError(t, err)
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if acr := ctx.Header("X-ACR"); acr != "" {
+				ctx.SetClaims(map[string]any{
+					"sub":       "user-1",
+					"tenant_id": "tenant-1",
+					"acr":       acr,
+					"amr":       []any{"pwd", "otp"},
+					"auth_time": float64(time.Now().Add(-time.Minute).Unix()),
+				})
+			}
+			return next.Handle(ctx)
+		})
+	})
+	app.Use(StepUpGuard(su))
+
+	ok := func(ctx *lift.Context) error { return ctx.Text("ok") }
+	app.GET("/payments", ok)
+	app.POST("/payments/:id/refunds", ok).Meta(lift.MetaStepUp, "mfa")
+
+	request := func(method, path, acr string) *lift.Context {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  method,
+			Path:    path,
+			Headers: map[string]string{"X-ACR": acr},
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	assert.Equal(t, 200, request("GET", "/payments", "pwd").Response.StatusCode)
+	assert.Equal(t, 401, request("POST", "/payments/pay_1/refunds", "").Response.StatusCode)
+
+	challenged := request("POST", "/payments/pay_1/refunds", "pwd")
+	assert.Equal(t, 401, challenged.Response.StatusCode)
+	challenge := challenged.Response.Headers["WWW-Authenticate"]
+	assert.Contains(t, challenge, `error="insufficient_user_authentication"`)
+	assert.Contains(t, challenge, `acr_values="mfa"`)
+	assert.Contains(t, challenge, "max_age=300")
+
+	assert.Equal(t, 200, request("POST", "/payments/pay_1/refunds", "mfa").Response.StatusCode)
+
+	accesses, err := storage.Query(context.Background(), security.AuditFilter{EntryType: security.StepUpAccessEntry})
+	require.NoError(t, err)
+	require.Len(t, accesses, 1)
+	assert.Equal(t, "user-1", accesses[0].UserID)
+	assert.Equal(t, "/payments/pay_1/refunds", accesses[0].Request.Resource)
+}
+
+func TestStepUpGuardReadsPrincipal(t *testing.T) {
+	su, err := security.NewStepUp(security.StepUpConfig{Audit: security.NewInMemoryAuditStorage()})
+	require.NoError(t, err)
+
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{Method: "POST", Path: "/consents/1"}))
+	lift.WithSecurity(ctx).SetPrincipal(&security.Principal{
+		UserID:      "user-1",
+		TenantID:    "tenant-1",
+		AuthContext: security.AuthContext{ACR: "mfa", AuthTime: time.Now()},
+	})
+
+	auth := RequestAuthContext(ctx)
+	assert.NoError(t, su.Check(auth, "mfa"))
+}
@@ -0,0 +1,443 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// AuthFailureStore defines the interface for tracking failed authentication attempts
+type AuthFailureStore interface {
+	// Get retrieves the failure record for a key, returning nil if none exists
+	Get(ctx context.Context, key string) (*AuthFailureRecord, error)
+	// IncrementFailure records a failed attempt and returns the updated record
+	IncrementFailure(ctx context.Context, key string, expiresAt time.Time) (*AuthFailureRecord, error)
+	// Lock locks the key out until the given time
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset clears all failures and any lockout for a key
+	Reset(ctx context.Context, key string) error
+}
+
+// AuthFailureRecord tracks failed authentication attempts for a single identifier
+type AuthFailureRecord struct {
+	Key          string    `json:"key"`
+	Failures     int       `json:"failures"`
+	FirstFailure time.Time `json:"first_failure"`
+	LastFailure  time.Time `json:"last_failure"`
+	LockedUntil  time.Time `json:"locked_until,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// IsLocked reports whether the record is locked out at the given time
+func (r *AuthFailureRecord) IsLocked(now time.Time) bool {
+	return !r.LockedUntil.IsZero() && now.Before(r.LockedUntil)
+}
+
+// TarpitIdentifier describes one dimension that failed attempts are tracked by
+type TarpitIdentifier struct {
+	// Name is used in the storage key (e.g. "ip", "user", "apikey")
+	Name string
+	// Extract returns the identifier value, or "" when it is not present on the request
+	Extract func(ctx *lift.Context) string
+	// Hash stores a SHA-256 digest of the value instead of the raw value
+	Hash bool
+}
+
+// TarpitByIP tracks failed attempts per client IP address
+func TarpitByIP() TarpitIdentifier {
+	return TarpitIdentifier{
+		Name: "ip",
+		Extract: func(ctx *lift.Context) string {
+			ip, err := security.ExtractClientIP(ctx.Request.Headers, ctx.Request.RequestContext())
+			if err != nil {
+				return ""
+			}
+			return ip
+		},
+	}
+}
+
+// TarpitByUser tracks failed attempts per user ID
+func TarpitByUser() TarpitIdentifier {
+	return TarpitIdentifier{
+		Name: "user",
+		Extract: func(ctx *lift.Context) string {
+			return ctx.UserID()
+		},
+	}
+}
+
+// TarpitByAPIKey tracks failed attempts per API key read from the given header.
+// Keys are hashed so raw credentials are never written to the store.
+func TarpitByAPIKey(header string) TarpitIdentifier {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return TarpitIdentifier{
+		Name: "apikey",
+		Extract: func(ctx *lift.Context) string {
+			return ctx.Header(header)
+		},
+		Hash: true,
+	}
+}
+
+// TarpitOptions configures the auth failure tarpit
+type TarpitOptions struct {
+	// Store is the backend for failure records
+	Store AuthFailureStore
+	// Identifiers to track failures by (default: client IP)
+	Identifiers []TarpitIdentifier
+	// KeyPrefix namespaces the storage keys (default: "tarpit")
+	KeyPrefix string
+
+	// FreeAttempts is the number of failures allowed before delays start (default: 3)
+	FreeAttempts int
+	// BaseDelay is the first backoff delay, doubled for every further failure (default: 1 second)
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay (default: 5 minutes)
+	MaxDelay time.Duration
+	// LockoutThreshold is the number of failures that triggers a lockout (default: 10)
+	LockoutThreshold int
+	// LockoutDuration is how long a lockout lasts (default: 15 minutes)
+	LockoutDuration time.Duration
+	// FailureWindow is how long failures are remembered (default: 1 hour)
+	FailureWindow time.Duration
+
+	// IsFailure decides whether a request counts as a failed auth attempt
+	// (default: a 401 error or response)
+	IsFailure func(ctx *lift.Context, err error) bool
+	// DisableResetOnSuccess keeps failure counts after a successful request
+	DisableResetOnSuccess bool
+	// OnLockout is called when an identifier becomes locked out
+	OnLockout func(ctx *lift.Context, record *AuthFailureRecord)
+}
+
+// Tarpit applies progressive delays and temporary lockouts to identifiers
+// that repeatedly fail authentication
+type Tarpit struct {
+	opts TarpitOptions
+}
+
+// NewTarpit creates a new tarpit with the given options
+func NewTarpit(opts TarpitOptions) *Tarpit {
+	// Set defaults
+	if opts.Store == nil {
+		opts.Store = NewMemoryAuthFailureStore()
+	}
+	if len(opts.Identifiers) == 0 {
+		opts.Identifiers = []TarpitIdentifier{TarpitByIP()}
+	}
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = "tarpit"
+	}
+	if opts.FreeAttempts == 0 {
+		opts.FreeAttempts = 3
+	}
+	if opts.BaseDelay == 0 {
+		opts.BaseDelay = time.Second
+	}
+	if opts.MaxDelay == 0 {
+		opts.MaxDelay = 5 * time.Minute
+	}
+	if opts.LockoutThreshold == 0 {
+		opts.LockoutThreshold = 10
+	}
+	if opts.LockoutDuration == 0 {
+		opts.LockoutDuration = 15 * time.Minute
+	}
+	if opts.FailureWindow == 0 {
+		opts.FailureWindow = time.Hour
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = defaultIsAuthFailure
+	}
+
+	return &Tarpit{opts: opts}
+}
+
+// TarpitMiddleware creates middleware that tarpits repeated auth failures
+func TarpitMiddleware(opts TarpitOptions) Middleware {
+	return NewTarpit(opts).Middleware()
+}
+
+// Middleware returns the tarpit as middleware
+func (t *Tarpit) Middleware() Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			keys := t.keysFor(ctx)
+			if len(keys) == 0 {
+				return next.Handle(ctx)
+			}
+
+			// Reject requests from identifiers that are locked out or backing off
+			now := time.Now()
+			tracked := make([]string, 0, len(keys))
+			for _, key := range keys {
+				record, err := t.opts.Store.Get(ctx.Context, key)
+				if err != nil {
+					// Fail open - the tarpit must not take down authentication
+					if ctx.Logger != nil {
+						ctx.Logger.Error("Tarpit lookup failed", map[string]any{
+							"error": err.Error(),
+						})
+					}
+					continue
+				}
+				if record == nil {
+					continue
+				}
+				tracked = append(tracked, key)
+
+				if record.IsLocked(now) {
+					return t.lockedError(ctx, record, now)
+				}
+				if retryAt := record.LastFailure.Add(t.Delay(record.Failures)); now.Before(retryAt) {
+					return t.backoffError(ctx, retryAt.Sub(now))
+				}
+			}
+
+			err := next.Handle(ctx)
+
+			if t.opts.IsFailure(ctx, err) {
+				t.recordFailure(ctx, keys)
+			} else if err == nil && ctx.Response.StatusCode < 400 && !t.opts.DisableResetOnSuccess {
+				for _, key := range tracked {
+					if resetErr := t.opts.Store.Reset(ctx.Context, key); resetErr != nil && ctx.Logger != nil {
+						ctx.Logger.Warn("Failed to reset tarpit record", map[string]any{
+							"error": resetErr.Error(),
+						})
+					}
+				}
+			}
+
+			return err
+		})
+	}
+}
+
+// Delay returns the backoff delay that applies after the given number of failures
+func (t *Tarpit) Delay(failures int) time.Duration {
+	if failures < t.opts.FreeAttempts {
+		return 0
+	}
+
+	delay := t.opts.BaseDelay
+	for i := t.opts.FreeAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= t.opts.MaxDelay {
+			return t.opts.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Status returns the failure record for an identifier value, or nil if none exists
+func (t *Tarpit) Status(ctx context.Context, identifier, value string) (*AuthFailureRecord, error) {
+	key, err := t.keyForName(identifier, value)
+	if err != nil {
+		return nil, err
+	}
+	return t.opts.Store.Get(ctx, key)
+}
+
+// Unlock clears failures and any lockout for an identifier value.
+// This is intended for support tooling and self-service unlock flows.
+func (t *Tarpit) Unlock(ctx context.Context, identifier, value string) error {
+	key, err := t.keyForName(identifier, value)
+	if err != nil {
+		return err
+	}
+	return t.opts.Store.Reset(ctx, key)
+}
+
+// recordFailure increments the failure count for each key and locks out keys over the threshold
+func (t *Tarpit) recordFailure(ctx *lift.Context, keys []string) {
+	now := time.Now()
+	for _, key := range keys {
+		record, err := t.opts.Store.IncrementFailure(ctx.Context, key, now.Add(t.opts.FailureWindow))
+		if err != nil {
+			if ctx.Logger != nil {
+				ctx.Logger.Error("Failed to record auth failure", map[string]any{
+					"error": err.Error(),
+				})
+			}
+			continue
+		}
+
+		if record.Failures < t.opts.LockoutThreshold || record.IsLocked(now) {
+			continue
+		}
+
+		record.LockedUntil = now.Add(t.opts.LockoutDuration)
+		if err := t.opts.Store.Lock(ctx.Context, key, record.LockedUntil); err != nil {
+			if ctx.Logger != nil {
+				ctx.Logger.Error("Failed to lock out identifier", map[string]any{
+					"error": err.Error(),
+				})
+			}
+			continue
+		}
+
+		if ctx.Logger != nil {
+			ctx.Logger.Warn("Identifier locked out after repeated auth failures", map[string]any{
+				"failures":     record.Failures,
+				"locked_until": record.LockedUntil,
+			})
+		}
+		if t.opts.OnLockout != nil {
+			t.opts.OnLockout(ctx, record)
+		}
+	}
+}
+
+// backoffError returns a 429 response asking the client to slow down
+func (t *Tarpit) backoffError(ctx *lift.Context, retryAfter time.Duration) error {
+	seconds := retryAfterSeconds(retryAfter)
+	ctx.Response.Header("Retry-After", strconv.Itoa(seconds))
+	return lift.NewLiftError("AUTH_BACKOFF", "Too many failed authentication attempts, please retry later", 429).
+		WithDetail("retry_after", seconds)
+}
+
+// lockedError returns a 423 response for a locked out identifier
+func (t *Tarpit) lockedError(ctx *lift.Context, record *AuthFailureRecord, now time.Time) error {
+	seconds := retryAfterSeconds(record.LockedUntil.Sub(now))
+	ctx.Response.Header("Retry-After", strconv.Itoa(seconds))
+	return lift.NewLiftError("AUTH_LOCKED", "Too many failed authentication attempts, access is temporarily locked", 423).
+		WithDetail("retry_after", seconds).
+		WithDetail("locked_until", record.LockedUntil.UTC().Format(time.RFC3339))
+}
+
+// keysFor builds storage keys for every identifier present on the request
+func (t *Tarpit) keysFor(ctx *lift.Context) []string {
+	keys := make([]string, 0, len(t.opts.Identifiers))
+	for _, identifier := range t.opts.Identifiers {
+		if value := identifier.Extract(ctx); value != "" {
+			keys = append(keys, t.buildKey(identifier, value))
+		}
+	}
+	return keys
+}
+
+// keyForName builds the storage key for a named identifier
+func (t *Tarpit) keyForName(name, value string) (string, error) {
+	for _, identifier := range t.opts.Identifiers {
+		if identifier.Name == name {
+			return t.buildKey(identifier, value), nil
+		}
+	}
+	return "", fmt.Errorf("unknown tarpit identifier: %s", name)
+}
+
+// buildKey creates the storage key for an identifier value
+func (t *Tarpit) buildKey(identifier TarpitIdentifier, value string) string {
+	if identifier.Hash {
+		sum := sha256.Sum256([]byte(value))
+		value = hex.EncodeToString(sum[:])
+	}
+	return fmt.Sprintf("%s:%s:%s", t.opts.KeyPrefix, identifier.Name, value)
+}
+
+// defaultIsAuthFailure treats 401 errors and responses as failed auth attempts
+func defaultIsAuthFailure(ctx *lift.Context, err error) bool {
+	if liftErr, ok := err.(*lift.LiftError); ok {
+		return liftErr.StatusCode == 401
+	}
+	return err == nil && ctx.Response.StatusCode == 401
+}
+
+// retryAfterSeconds rounds a duration up to whole seconds for the Retry-After header
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// MemoryAuthFailureStore provides an in-memory implementation of AuthFailureStore
+// This is suitable for single-instance applications or testing
+type MemoryAuthFailureStore struct {
+	mu      sync.RWMutex
+	records map[string]*AuthFailureRecord
+}
+
+// NewMemoryAuthFailureStore creates a new in-memory auth failure store
+func NewMemoryAuthFailureStore() *MemoryAuthFailureStore {
+	return &MemoryAuthFailureStore{
+		records: make(map[string]*AuthFailureRecord),
+	}
+}
+
+// Get retrieves a record by key
+func (m *MemoryAuthFailureStore) Get(ctx context.Context, key string) (*AuthFailureRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	record, exists := m.records[key]
+	if !exists || time.Now().After(record.ExpiresAt) {
+		return nil, nil
+	}
+
+	copied := *record
+	return &copied, nil
+}
+
+// IncrementFailure records a failed attempt
+func (m *MemoryAuthFailureStore) IncrementFailure(ctx context.Context, key string, expiresAt time.Time) (*AuthFailureRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	record, exists := m.records[key]
+	if !exists || now.After(record.ExpiresAt) {
+		record = &AuthFailureRecord{
+			Key:          key,
+			FirstFailure: now,
+		}
+		m.records[key] = record
+	}
+
+	record.Failures++
+	record.LastFailure = now
+	if expiresAt.After(record.ExpiresAt) {
+		record.ExpiresAt = expiresAt
+	}
+
+	copied := *record
+	return &copied, nil
+}
+
+// Lock locks a key out until the given time
+func (m *MemoryAuthFailureStore) Lock(ctx context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, exists := m.records[key]
+	if !exists {
+		record = &AuthFailureRecord{Key: key}
+		m.records[key] = record
+	}
+
+	record.LockedUntil = until
+	if until.After(record.ExpiresAt) {
+		record.ExpiresAt = until
+	}
+	return nil
+}
+
+// Reset removes a record
+func (m *MemoryAuthFailureStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, key)
+	return nil
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBAuthFailureStore implements AuthFailureStore using DynamoDB.
+// Records share the key schema and TTL attribute used by CreateIdempotencyTable,
+// so the same helper can be used to create the table.
+type DynamoDBAuthFailureStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBAuthFailureStore creates a new DynamoDB-backed auth failure store
+func NewDynamoDBAuthFailureStore(client *dynamodb.Client, tableName string) *DynamoDBAuthFailureStore {
+	return &DynamoDBAuthFailureStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// DynamoDBAuthFailureRecord represents the DynamoDB item structure
+type DynamoDBAuthFailureRecord struct {
+	PK           string    `dynamodbav:"pk"`
+	Failures     int       `dynamodbav:"failures"`
+	FirstFailure time.Time `dynamodbav:"first_failure"`
+	LastFailure  time.Time `dynamodbav:"last_failure"`
+	LockedUntil  int64     `dynamodbav:"locked_until,omitempty"`
+	TTL          int64     `dynamodbav:"ttl"`
+}
+
+// Get retrieves the failure record for a key
+func (d *DynamoDBAuthFailureStore) Get(ctx context.Context, key string) (*AuthFailureRecord, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := d.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	record, err := d.toRecord(key, result.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	// DynamoDB TTL deletion is lazy, so expired items may still be returned
+	if time.Now().After(record.ExpiresAt) {
+		return nil, nil
+	}
+
+	return record, nil
+}
+
+// IncrementFailure atomically records a failed attempt. The record's TTL
+// only moves later, so it can't cut short an active lockout.
+func (d *DynamoDBAuthFailureStore) IncrementFailure(ctx context.Context, key string, expiresAt time.Time) (*AuthFailureRecord, error) {
+	now := time.Now()
+	nowValue, err := attributevalue.Marshal(now)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression: aws.String("ADD failures :one SET last_failure = :now, first_failure = if_not_exists(first_failure, :now), #ttl = if_not_exists(#ttl, :ttl)"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": &types.AttributeValueMemberN{Value: "1"},
+			":now": nowValue,
+			":ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	}
+
+	result, err := d.client.UpdateItem(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := d.toRecord(key, result.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	if record.ExpiresAt.Before(expiresAt) {
+		if err := d.extendTTL(ctx, key, expiresAt); err != nil {
+			return nil, err
+		}
+		record.ExpiresAt = time.Unix(expiresAt.Unix(), 0)
+	}
+
+	return record, nil
+}
+
+// Lock locks a key out until the given time, keeping the item alive at
+// least as long as the lockout
+func (d *DynamoDBAuthFailureStore) Lock(ctx context.Context, key string, until time.Time) error {
+	untilValue := &types.AttributeValueMemberN{Value: strconv.FormatInt(until.Unix(), 10)}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression: aws.String("SET locked_until = :until, #ttl = if_not_exists(#ttl, :until)"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":until": untilValue,
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	}
+
+	result, err := d.client.UpdateItem(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	var updated struct {
+		TTL int64 `dynamodbav:"ttl"`
+	}
+	if err := attributevalue.UnmarshalMap(result.Attributes, &updated); err != nil {
+		return err
+	}
+	if updated.TTL < until.Unix() {
+		return d.extendTTL(ctx, key, until)
+	}
+	return nil
+}
+
+// extendTTL raises a record's TTL to expiresAt. DynamoDB has no max in
+// update expressions, so the update is conditional on the stored TTL being
+// earlier; a concurrent writer that already set a later one wins, and a
+// record reset in between isn't recreated.
+func (d *DynamoDBAuthFailureStore) extendTTL(ctx context.Context, key string, expiresAt time.Time) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression:    aws.String("SET #ttl = :ttl"),
+		ConditionExpression: aws.String("attribute_exists(pk) AND (attribute_not_exists(#ttl) OR #ttl < :ttl)"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)},
+		},
+	}
+
+	_, err := d.client.UpdateItem(ctx, input)
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return nil
+	}
+	return err
+}
+
+// Reset removes the record for a key
+func (d *DynamoDBAuthFailureStore) Reset(ctx context.Context, key string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+		},
+	}
+
+	_, err := d.client.DeleteItem(ctx, input)
+	return err
+}
+
+// toRecord converts a DynamoDB item into an AuthFailureRecord
+func (d *DynamoDBAuthFailureStore) toRecord(key string, item map[string]types.AttributeValue) (*AuthFailureRecord, error) {
+	var dbRecord DynamoDBAuthFailureRecord
+	if err := attributevalue.UnmarshalMap(item, &dbRecord); err != nil {
+		return nil, err
+	}
+
+	record := &AuthFailureRecord{
+		Key:          key,
+		Failures:     dbRecord.Failures,
+		FirstFailure: dbRecord.FirstFailure,
+		LastFailure:  dbRecord.LastFailure,
+		ExpiresAt:    time.Unix(dbRecord.TTL, 0),
+	}
+	if dbRecord.LockedUntil > 0 {
+		record.LockedUntil = time.Unix(dbRecord.LockedUntil, 0)
+	}
+
+	return record, nil
+}
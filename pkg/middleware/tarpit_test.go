@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTarpitTestContext creates a test context with the given API key
+func createTarpitTestContext(apiKey string) *lift.Context {
+	adapterReq := &adapters.Request{
+		Method:      "POST",
+		Path:        "/login",
+		Headers:     map[string]string{"X-API-Key": apiKey},
+		QueryParams: make(map[string]string),
+		PathParams:  make(map[string]string),
+	}
+	return lift.NewContext(context.Background(), lift.NewRequest(adapterReq))
+}
+
+func TestTarpitDelay(t *testing.T) {
+	tarpit := NewTarpit(TarpitOptions{
+		FreeAttempts: 2,
+		BaseDelay:    time.Second,
+		MaxDelay:     5 * time.Second,
+	})
+
+	assert.Equal(t, time.Duration(0), tarpit.Delay(0))
+	assert.Equal(t, time.Duration(0), tarpit.Delay(1))
+	assert.Equal(t, time.Second, tarpit.Delay(2))
+	assert.Equal(t, 2*time.Second, tarpit.Delay(3))
+	assert.Equal(t, 4*time.Second, tarpit.Delay(4))
+	assert.Equal(t, 5*time.Second, tarpit.Delay(5))
+	assert.Equal(t, 5*time.Second, tarpit.Delay(50))
+}
+
+func TestTarpitMiddleware(t *testing.T) {
+	failing := lift.HandlerFunc(func(ctx *lift.Context) error {
+		return lift.Unauthorized("invalid credentials")
+	})
+
+	t.Run("returns 429 while backing off", func(t *testing.T) {
+		store := NewMemoryAuthFailureStore()
+		handler := TarpitMiddleware(TarpitOptions{
+			Store:        store,
+			Identifiers:  []TarpitIdentifier{TarpitByAPIKey("")},
+			FreeAttempts: 1,
+			BaseDelay:    time.Minute,
+		})(failing)
+
+		err := handler.Handle(createTarpitTestContext("key_123"))
+		require.Error(t, err)
+		assert.Equal(t, 401, err.(*lift.LiftError).StatusCode)
+
+		ctx := createTarpitTestContext("key_123")
+		err = handler.Handle(ctx)
+		require.Error(t, err)
+		liftErr := err.(*lift.LiftError)
+		assert.Equal(t, 429, liftErr.StatusCode)
+		assert.Equal(t, "AUTH_BACKOFF", liftErr.Code)
+		assert.Equal(t, "60", ctx.Response.Headers["Retry-After"])
+
+		// Other identifiers are unaffected
+		err = handler.Handle(createTarpitTestContext("key_456"))
+		assert.Equal(t, 401, err.(*lift.LiftError).StatusCode)
+	})
+
+	t.Run("locks out after threshold and unlocks", func(t *testing.T) {
+		store := NewMemoryAuthFailureStore()
+		var lockedOut *AuthFailureRecord
+		tarpit := NewTarpit(TarpitOptions{
+			Store:            store,
+			Identifiers:      []TarpitIdentifier{TarpitByAPIKey("")},
+			FreeAttempts:     10,
+			LockoutThreshold: 3,
+			OnLockout: func(ctx *lift.Context, record *AuthFailureRecord) {
+				lockedOut = record
+			},
+		})
+		handler := tarpit.Middleware()(failing)
+
+		for i := 0; i < 3; i++ {
+			err := handler.Handle(createTarpitTestContext("key_123"))
+			assert.Equal(t, 401, err.(*lift.LiftError).StatusCode)
+		}
+		require.NotNil(t, lockedOut)
+		assert.Equal(t, 3, lockedOut.Failures)
+
+		err := handler.Handle(createTarpitTestContext("key_123"))
+		require.Error(t, err)
+		assert.Equal(t, 423, err.(*lift.LiftError).StatusCode)
+		assert.Equal(t, "AUTH_LOCKED", err.(*lift.LiftError).Code)
+
+		status, err := tarpit.Status(context.Background(), "apikey", "key_123")
+		require.NoError(t, err)
+		require.NotNil(t, status)
+		assert.True(t, status.IsLocked(time.Now()))
+
+		require.NoError(t, tarpit.Unlock(context.Background(), "apikey", "key_123"))
+		err = handler.Handle(createTarpitTestContext("key_123"))
+		assert.Equal(t, 401, err.(*lift.LiftError).StatusCode)
+
+		assert.Error(t, tarpit.Unlock(context.Background(), "unknown", "key_123"))
+	})
+
+	t.Run("resets failures on success", func(t *testing.T) {
+		store := NewMemoryAuthFailureStore()
+		succeed := false
+		handler := TarpitMiddleware(TarpitOptions{
+			Store:        store,
+			Identifiers:  []TarpitIdentifier{TarpitByAPIKey("")},
+			FreeAttempts: 5,
+		})(lift.HandlerFunc(func(ctx *lift.Context) error {
+			if succeed {
+				return ctx.JSON(map[string]string{"status": "ok"})
+			}
+			return lift.Unauthorized("invalid credentials")
+		}))
+
+		_ = handler.Handle(createTarpitTestContext("key_123"))
+		_ = handler.Handle(createTarpitTestContext("key_123"))
+
+		tarpit := NewTarpit(TarpitOptions{Store: store, Identifiers: []TarpitIdentifier{TarpitByAPIKey("")}})
+		record, err := tarpit.Status(context.Background(), "apikey", "key_123")
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, 2, record.Failures)
+
+		succeed = true
+		require.NoError(t, handler.Handle(createTarpitTestContext("key_123")))
+
+		record, err = tarpit.Status(context.Background(), "apikey", "key_123")
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("does not store raw API keys", func(t *testing.T) {
+		store := NewMemoryAuthFailureStore()
+		handler := TarpitMiddleware(TarpitOptions{
+			Store:       store,
+			Identifiers: []TarpitIdentifier{TarpitByAPIKey("")},
+		})(failing)
+
+		_ = handler.Handle(createTarpitTestContext("secret_key"))
+
+		require.Len(t, store.records, 1)
+		for key := range store.records {
+			assert.NotContains(t, key, "secret_key")
+		}
+	})
+}
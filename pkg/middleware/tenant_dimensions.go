@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/observability"
+)
+
+// TenantDimensionOptions configures tenant tagging of metrics and logs
+type TenantDimensionOptions struct {
+	// Guard limits the number of distinct tenant dimension values (required)
+	Guard *observability.TenantCardinalityGuard
+	// TenantIDFunc overrides how the tenant is resolved (default: ctx.TenantID())
+	TenantIDFunc func(*lift.Context) string
+	// DisableLogFields stops tenant fields from being added to the request logger
+	DisableLogFields bool
+}
+
+// TenantDimensions records each request against the cardinality guard, wraps
+// ctx.Metrics so configured metrics carry the guarded tenant dimension, and
+// adds the tenant to the request logger. Logs always carry the real tenant ID;
+// only metrics are bucketed.
+func TenantDimensions(opts TenantDimensionOptions) Middleware {
+	if opts.TenantIDFunc == nil {
+		opts.TenantIDFunc = func(ctx *lift.Context) string {
+			return ctx.TenantID()
+		}
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			tenantID := opts.TenantIDFunc(ctx)
+			if tenantID == "" || opts.Guard == nil {
+				return next.Handle(ctx)
+			}
+
+			dimension := opts.Guard.TrackRequest(ctx, tenantID)
+
+			if ctx.Metrics != nil {
+				ctx.Metrics = observability.TenantMetrics(ctx.Metrics, opts.Guard, tenantID)
+			}
+
+			if !opts.DisableLogFields && ctx.Logger != nil {
+				ctx.Logger = ctx.Logger.WithFields(map[string]any{
+					"tenant_id":        tenantID,
+					"tenant_dimension": dimension,
+				})
+			}
+
+			return next.Handle(ctx)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// TimezoneResolver returns the IANA timezone configured for a tenant, or ""
+// if the tenant hasn't configured one
+type TimezoneResolver func(ctx context.Context, tenantID string) (string, error)
+
+// TenantTimezoneConfig configures TenantTimezone
+type TenantTimezoneConfig struct {
+	// Resolve looks up the tenant's timezone, typically from tenant settings
+	Resolve TimezoneResolver
+	// Default is used when the tenant has no timezone or lookup fails (default: "UTC")
+	Default string
+	// Header, if set, lets clients override the timezone per request
+	// (e.g. "X-Timezone"); invalid values are ignored
+	Header string
+	// CacheTTL is how long resolved timezones are cached (default: 5m)
+	CacheTTL time.Duration
+}
+
+type cachedTimezone struct {
+	name    string
+	expires time.Time
+}
+
+// TenantTimezone sets each request's timezone to the tenant's configured
+// timezone, so ctx.ParseTime, ctx.FormatTime and ctx.QueryDateRange use the
+// tenant's local time. It must run after authentication sets the tenant ID.
+func TenantTimezone(config TenantTimezoneConfig) lift.Middleware {
+	if config.Default == "" {
+		config.Default = "UTC"
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 5 * time.Minute
+	}
+
+	var mu sync.RWMutex
+	cache := make(map[string]cachedTimezone)
+
+	resolve := func(ctx *lift.Context, tenantID string) string {
+		mu.RLock()
+		cached, ok := cache[tenantID]
+		mu.RUnlock()
+		if ok && time.Now().Before(cached.expires) {
+			return cached.name
+		}
+
+		name, err := config.Resolve(ctx.Context, tenantID)
+		if err != nil {
+			if ctx.Logger != nil {
+				ctx.Logger.Warn("Failed to resolve tenant timezone", map[string]any{
+					"tenant_id": tenantID,
+					"error":     err.Error(),
+				})
+			}
+			// Don't cache failures so the next request retries
+			return ""
+		}
+
+		mu.Lock()
+		cache[tenantID] = cachedTimezone{name: name, expires: time.Now().Add(config.CacheTTL)}
+		mu.Unlock()
+		return name
+	}
+
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			name := ""
+			if config.Header != "" {
+				name = ctx.Header(config.Header)
+			}
+			if name != "" && ctx.SetTimezone(name) == nil {
+				return next.Handle(ctx)
+			}
+
+			if tenantID := ctx.TenantID(); tenantID != "" && config.Resolve != nil {
+				name = resolve(ctx, tenantID)
+			}
+			if name == "" || ctx.SetTimezone(name) != nil {
+				if err := ctx.SetTimezone(config.Default); err != nil {
+					return lift.NewLiftError("INVALID_TIMEZONE", "Default timezone is invalid", 500).WithCause(err)
+				}
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantTimezone(t *testing.T) {
+	lookups := 0
+	middleware := TenantTimezone(TenantTimezoneConfig{
+		Resolve: func(ctx context.Context, tenantID string) (string, error) {
+			lookups++
+			switch tenantID {
+			case "chicago":
+				return "America/Chicago", nil
+			case "broken":
+				return "", errors.New("settings unavailable")
+			case "typo":
+				return "America/Chicag", nil
+			}
+			return "", nil
+		},
+		Default: "America/New_York",
+		Header:  "X-Timezone",
+	})
+
+	locationFor := func(tenantID string, headers map[string]string) string {
+		var location string
+		handler := middleware(lift.HandlerFunc(func(ctx *lift.Context) error {
+			location = ctx.Location().String()
+			return nil
+		}))
+		ctx := newPredicateContext("GET", "/reports", headers)
+		ctx.SetTenantID(tenantID)
+		require.NoError(t, handler.Handle(ctx))
+		return location
+	}
+
+	assert.Equal(t, "America/Chicago", locationFor("chicago", nil))
+	assert.Equal(t, "America/Chicago", locationFor("chicago", nil))
+	assert.Equal(t, 1, lookups, "resolved timezones are cached")
+
+	assert.Equal(t, "America/New_York", locationFor("unset", nil))
+	assert.Equal(t, "America/New_York", locationFor("broken", nil))
+	assert.Equal(t, "America/New_York", locationFor("typo", nil))
+	assert.Equal(t, "America/New_York", locationFor("", nil))
+
+	assert.Equal(t, "Europe/London", locationFor("chicago", map[string]string{"X-Timezone": "Europe/London"}))
+	assert.Equal(t, "America/Chicago", locationFor("chicago", map[string]string{"X-Timezone": "Nowhere/Special"}))
+}
@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// VelocityStore keeps running totals of amounts per key
+type VelocityStore interface {
+	// Increment atomically adds amount to the key's total unless the new
+	// total would exceed limit, in which case the total is left unchanged.
+	// It returns the resulting total and whether the amount was added. A
+	// limit of 0 or less adds unconditionally.
+	Increment(ctx context.Context, key string, amount, limit int64, expiresAt time.Time) (int64, bool, error)
+	// Get returns the key's total, or 0 if it has none
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+// VelocityLimitConfig configures a VelocityLimiter
+type VelocityLimitConfig struct {
+	// Store holds the running totals (default: in-memory)
+	Store VelocityStore
+	// Name namespaces the storage keys, e.g. "daily_transfer"
+	Name string
+	// Limit is the largest total allowed per key per window, in the
+	// amount's smallest unit (e.g. cents)
+	Limit int64
+	// Window is the period totals accumulate over (default: 24 hours).
+	// Whole-day windows start at midnight in Location.
+	Window time.Duration
+	// Location aligns whole-day windows (default: UTC)
+	Location *time.Location
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// VelocityResult describes a key's usage of a velocity limit
+type VelocityResult struct {
+	Allowed   bool      `json:"allowed"`
+	Limit     int64     `json:"limit"`
+	Total     int64     `json:"total"`
+	Remaining int64     `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// VelocityLimiter enforces a limit on the sum of amounts per key per
+// window, such as a daily transfer limit per account. Unlike rate limiting
+// it counts value, not requests:
+//
+//	daily, _ := middleware.NewVelocityLimiter(middleware.VelocityLimitConfig{
+//		Store:    middleware.NewDynamoDBVelocityStore(client, "velocity"),
+//		Name:     "daily_transfer",
+//		Limit:    25_000_00,
+//		Location: eastern,
+//	})
+//
+//	result, err := daily.Consume(ctx.Context, ctx.AccountID(), req.AmountCents)
+//	if err != nil {
+//		return err
+//	}
+//	if !result.Allowed {
+//		return middleware.VelocityExceeded(result)
+//	}
+//	if err := transfer(req); err != nil {
+//		daily.Release(ctx.Context, ctx.AccountID(), req.AmountCents)
+//		return err
+//	}
+type VelocityLimiter struct {
+	config VelocityLimitConfig
+}
+
+// NewVelocityLimiter creates a velocity limiter
+func NewVelocityLimiter(config VelocityLimitConfig) (*VelocityLimiter, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("velocity limit requires a name")
+	}
+	if config.Limit <= 0 {
+		return nil, fmt.Errorf("velocity limit must be positive")
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryVelocityStore()
+	}
+	if config.Window == 0 {
+		config.Window = 24 * time.Hour
+	}
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &VelocityLimiter{config: config}, nil
+}
+
+// Consume adds amount to the key's total for the current window if it stays
+// within the limit. A result that isn't allowed leaves the total unchanged.
+func (v *VelocityLimiter) Consume(ctx context.Context, key string, amount int64) (*VelocityResult, error) {
+	if amount < 0 {
+		return nil, fmt.Errorf("velocity amounts must not be negative")
+	}
+	start, end := v.window(v.config.Now())
+	total, added, err := v.config.Store.Increment(ctx, v.key(key, start), amount, v.config.Limit, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update velocity total: %w", err)
+	}
+	return v.result(total, added, end), nil
+}
+
+// Release subtracts a previously consumed amount, e.g. after the payment it
+// was consumed for fails or is voided. Amounts consumed in an earlier
+// window have already expired and aren't released.
+func (v *VelocityLimiter) Release(ctx context.Context, key string, amount int64) error {
+	start, end := v.window(v.config.Now())
+	if _, _, err := v.config.Store.Increment(ctx, v.key(key, start), -amount, 0, end); err != nil {
+		return fmt.Errorf("failed to release velocity amount: %w", err)
+	}
+	return nil
+}
+
+// Usage reports the key's total for the current window
+func (v *VelocityLimiter) Usage(ctx context.Context, key string) (*VelocityResult, error) {
+	start, end := v.window(v.config.Now())
+	total, err := v.config.Store.Get(ctx, v.key(key, start))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read velocity total: %w", err)
+	}
+	return v.result(total, total <= v.config.Limit, end), nil
+}
+
+// window returns the bounds of the window containing now
+func (v *VelocityLimiter) window(now time.Time) (time.Time, time.Time) {
+	const day = 24 * time.Hour
+	if v.config.Window%day != 0 {
+		start := now.Truncate(v.config.Window)
+		return start, start.Add(v.config.Window)
+	}
+
+	// Count calendar days in Location so windows start at local midnight
+	// regardless of DST
+	y, m, d := now.In(v.config.Location).Date()
+	days := int(v.config.Window / day)
+	dayNumber := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / int64(day/time.Second))
+	start := time.Date(y, m, d-dayNumber%days, 0, 0, 0, 0, v.config.Location)
+	return start, start.AddDate(0, 0, days)
+}
+
+func (v *VelocityLimiter) key(key string, start time.Time) string {
+	return "velocity:" + v.config.Name + ":" + key + ":" + strconv.FormatInt(start.Unix(), 10)
+}
+
+func (v *VelocityLimiter) result(total int64, allowed bool, resetAt time.Time) *VelocityResult {
+	return &VelocityResult{
+		Allowed:   allowed,
+		Limit:     v.config.Limit,
+		Total:     total,
+		Remaining: max(v.config.Limit-total, 0),
+		ResetAt:   resetAt,
+	}
+}
+
+// VelocityExceeded is the error for a request over a velocity limit
+func VelocityExceeded(result *VelocityResult) *lift.LiftError {
+	return lift.NewLiftError("VELOCITY_LIMIT_EXCEEDED", "Amount exceeds the limit for this period", 429).
+		WithDetail("limit", result.Limit).
+		WithDetail("remaining", result.Remaining).
+		WithDetail("reset_at", result.ResetAt)
+}
+
+// VelocityLimit consumes each request's amount before the handler runs and
+// releases it again if the handler fails, so only completed requests count
+// toward the limit. key and amount read the request; returning an empty key
+// skips the check.
+//
+//	transfers := app.Group("/transfers", middleware.VelocityLimit(daily,
+//		func(ctx *lift.Context) string { return ctx.AccountID() },
+//		func(ctx *lift.Context) (int64, error) { return transferAmount(ctx) },
+//	))
+//	transfers.POST("", createTransfer)
+func VelocityLimit(limiter *VelocityLimiter, key func(ctx *lift.Context) string, amount func(ctx *lift.Context) (int64, error)) lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			id := key(ctx)
+			if id == "" {
+				return next.Handle(ctx)
+			}
+			value, err := amount(ctx)
+			if err != nil {
+				var liftErr *lift.LiftError
+				if errors.As(err, &liftErr) {
+					return liftErr
+				}
+				return lift.NewLiftError("INVALID_AMOUNT", "Invalid amount", 400).WithCause(err)
+			}
+
+			result, err := limiter.Consume(ctx.Context, id, value)
+			if err != nil {
+				return lift.NewLiftError("VELOCITY_CHECK_FAILED", "Failed to check velocity limit", 500).WithCause(err)
+			}
+			if !result.Allowed {
+				return VelocityExceeded(result)
+			}
+
+			err = next.Handle(ctx)
+			if err != nil || ctx.Response.StatusCode >= 400 {
+				if releaseErr := limiter.Release(ctx.Context, id, value); releaseErr != nil && ctx.Logger != nil {
+					ctx.Logger.Error("Failed to release velocity amount", map[string]any{
+						"limit": limiter.config.Name,
+						"error": releaseErr.Error(),
+					})
+				}
+			}
+			return err
+		})
+	}
+}
+
+// MemoryVelocityStore provides an in-memory implementation of VelocityStore
+// This is suitable for single-instance applications or testing
+type MemoryVelocityStore struct {
+	mu      sync.Mutex
+	totals  map[string]int64
+	expires map[string]time.Time
+}
+
+// NewMemoryVelocityStore creates a new in-memory velocity store
+func NewMemoryVelocityStore() *MemoryVelocityStore {
+	return &MemoryVelocityStore{
+		totals:  make(map[string]int64),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// Increment adds amount to the key's total if it stays within limit
+func (m *MemoryVelocityStore) Increment(ctx context.Context, key string, amount, limit int64, expiresAt time.Time) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if time.Now().After(m.expires[key]) {
+		delete(m.totals, key)
+	}
+	total := m.totals[key] + amount
+	if limit > 0 && total > limit {
+		return m.totals[key], false, nil
+	}
+	m.totals[key] = total
+	m.expires[key] = expiresAt
+	return total, true, nil
+}
+
+// Get returns the key's total
+func (m *MemoryVelocityStore) Get(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if time.Now().After(m.expires[key]) {
+		return 0, nil
+	}
+	return m.totals[key], nil
+}
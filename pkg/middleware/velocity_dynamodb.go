@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBVelocityStore implements VelocityStore with DynamoDB atomic
+// counters. Items share the key schema and TTL attribute used by
+// CreateIdempotencyTable, so the same helper can be used to create the table.
+type DynamoDBVelocityStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBVelocityStore creates a new DynamoDB-backed velocity store
+func NewDynamoDBVelocityStore(client *dynamodb.Client, tableName string) *DynamoDBVelocityStore {
+	return &DynamoDBVelocityStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// Increment atomically adds amount to the key's total. The limit is
+// enforced by a condition on the stored total, so concurrent requests can't
+// overshoot it.
+func (d *DynamoDBVelocityStore) Increment(ctx context.Context, key string, amount, limit int64, expiresAt time.Time) (int64, bool, error) {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression: aws.String("ADD #total :amount SET #ttl = :ttl"),
+		ExpressionAttributeNames: map[string]string{
+			"#total": "total",
+			"#ttl":   "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":amount": &types.AttributeValueMemberN{Value: strconv.FormatInt(amount, 10)},
+			":ttl":    &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	}
+	if limit > 0 {
+		if amount > limit {
+			total, err := d.Get(ctx, key)
+			return total, false, err
+		}
+		input.ConditionExpression = aws.String("attribute_not_exists(#total) OR #total <= :headroom")
+		input.ExpressionAttributeValues[":headroom"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(limit-amount, 10)}
+	}
+
+	result, err := d.client.UpdateItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			total, err := d.Get(ctx, key)
+			return total, false, err
+		}
+		return 0, false, err
+	}
+
+	total, err := numberAttribute(result.Attributes["total"])
+	return total, true, err
+}
+
+// Get returns the key's total
+func (d *DynamoDBVelocityStore) Get(ctx context.Context, key string) (int64, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if result.Item == nil {
+		return 0, nil
+	}
+
+	// DynamoDB TTL deletion is lazy, so expired items may still be returned
+	if ttl, err := numberAttribute(result.Item["ttl"]); err == nil && ttl > 0 && time.Now().Unix() > ttl {
+		return 0, nil
+	}
+	return numberAttribute(result.Item["total"])
+}
+
+// numberAttribute reads an integer number attribute, treating a missing
+// attribute as 0
+func numberAttribute(av types.AttributeValue) (int64, error) {
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(n.Value, 10, 64)
+}
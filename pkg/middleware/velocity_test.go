@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVelocityLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := NewVelocityLimiter(VelocityLimitConfig{Name: "daily_transfer", Limit: 1000})
+	require.NoError(t, err)
+
+	result, err := limiter.Consume(ctx, "acct-1", 600)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(400), result.Remaining)
+
+	result, err = limiter.Consume(ctx, "acct-1", 500)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(600), result.Total, "a rejected amount isn't counted")
+
+	result, err = limiter.Consume(ctx, "acct-2", 500)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed, "keys are limited independently")
+
+	require.NoError(t, limiter.Release(ctx, "acct-1", 200))
+	result, err = limiter.Consume(ctx, "acct-1", 600)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1000), result.Total)
+
+	usage, err := limiter.Usage(ctx, "acct-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), usage.Total)
+	assert.Equal(t, int64(0), usage.Remaining)
+
+	_, err = limiter.Consume(ctx, "acct-1", -5)
+	assert.Error(t, err)
+	_, err = NewVelocityLimiter(VelocityLimitConfig{Name: "no_limit"})
+	assert.Error(t, err)
+}
+
+func TestVelocityLimiterWindows(t *testing.T) {
+	eastern, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	daily, err := NewVelocityLimiter(VelocityLimitConfig{Name: "daily", Limit: 1, Location: eastern})
+	require.NoError(t, err)
+	start, end := daily.window(time.Date(2024, 3, 10, 23, 0, 0, 0, eastern))
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, eastern), start, "daily windows start at local midnight")
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, eastern), end)
+	assert.Equal(t, 23*time.Hour, end.Sub(start), "DST days are shorter")
+
+	weekly, err := NewVelocityLimiter(VelocityLimitConfig{Name: "weekly", Limit: 1, Window: 7 * 24 * time.Hour})
+	require.NoError(t, err)
+	start, end = weekly.window(time.Date(2024, 6, 5, 12, 0, 0, 0, time.UTC))
+	assert.True(t, !start.After(time.Date(2024, 6, 5, 12, 0, 0, 0, time.UTC)) && end.Sub(start) == 7*24*time.Hour)
+	again, _ := weekly.window(start.Add(6 * 24 * time.Hour))
+	assert.Equal(t, start, again)
+
+	hourly, err := NewVelocityLimiter(VelocityLimitConfig{Name: "hourly", Limit: 1, Window: time.Hour})
+	require.NoError(t, err)
+	start, end = hourly.window(time.Date(2024, 6, 5, 12, 30, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 6, 5, 12, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 6, 5, 13, 0, 0, 0, time.UTC), end)
+}
+
+func TestVelocityLimitMiddleware(t *testing.T) {
+	limiter, err := NewVelocityLimiter(VelocityLimitConfig{Name: "daily_transfer", Limit: 1000})
+	require.NoError(t, err)
+
+	app := lift.New()
+	transfers := app.Group("/transfers", VelocityLimit(limiter,
+		func(ctx *lift.Context) string { return ctx.Header("X-Account") },
+		func(ctx *lift.Context) (int64, error) {
+			var body struct {
+				Amount int64 `json:"amount"`
+			}
+			if err := json.Unmarshal(ctx.Request.Body, &body); err != nil {
+				return 0, err
+			}
+			return body.Amount, nil
+		},
+	))
+	transfers.POST("", func(ctx *lift.Context) error {
+		if ctx.Header("X-Fail") != "" {
+			return lift.NewLiftError("BANK_UNAVAILABLE", "Bank unavailable", 503)
+		}
+		return ctx.Created(map[string]string{"status": "sent"})
+	})
+
+	request := func(account, body string, fail bool) *lift.Context {
+		headers := map[string]string{"X-Account": account}
+		if fail {
+			headers["X-Fail"] = "1"
+		}
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  "POST",
+			Path:    "/transfers",
+			Headers: headers,
+			Body:    []byte(body),
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	assert.Equal(t, 201, request("acct-1", `{"amount":700}`, false).Response.StatusCode)
+	assert.Equal(t, 429, request("acct-1", `{"amount":400}`, false).Response.StatusCode)
+	assert.Equal(t, 503, request("acct-1", `{"amount":300}`, true).Response.StatusCode)
+	assert.Equal(t, 201, request("acct-1", `{"amount":300}`, false).Response.StatusCode, "failed transfers don't count")
+	assert.Equal(t, 400, request("acct-1", `not json`, false).Response.StatusCode)
+	assert.Equal(t, 201, request("", `{"amount":5000}`, false).Response.StatusCode, "requests without a key aren't limited")
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// Predicate decides whether conditional middleware applies to a request
+type Predicate func(ctx *lift.Context) bool
+
+// When applies mw only to requests matching the predicate, so auth, rate
+// limits and the like can be scoped to part of an application:
+//
+//	app.Use(middleware.When(middleware.PathPrefix("/admin"), middleware.RequireRole("admin")))
+//
+// Requests that don't match go straight to the next handler.
+func When[M ~func(lift.Handler) lift.Handler](predicate Predicate, mw M) lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		applied := mw(next)
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if predicate(ctx) {
+				return applied.Handle(ctx)
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// Unless applies mw to every request except those matching the predicate
+func Unless[M ~func(lift.Handler) lift.Handler](predicate Predicate, mw M) lift.Middleware {
+	return When(Not(predicate), mw)
+}
+
+// And matches when p and every other predicate match
+func (p Predicate) And(others ...Predicate) Predicate {
+	return All(append([]Predicate{p}, others...)...)
+}
+
+// Or matches when p or any other predicate matches
+func (p Predicate) Or(others ...Predicate) Predicate {
+	return Any(append([]Predicate{p}, others...)...)
+}
+
+// All matches when every predicate matches
+func All(predicates ...Predicate) Predicate {
+	return func(ctx *lift.Context) bool {
+		for _, predicate := range predicates {
+			if !predicate(ctx) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any matches when at least one predicate matches
+func Any(predicates ...Predicate) Predicate {
+	return func(ctx *lift.Context) bool {
+		for _, predicate := range predicates {
+			if predicate(ctx) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts a predicate
+func Not(predicate Predicate) Predicate {
+	return func(ctx *lift.Context) bool {
+		return !predicate(ctx)
+	}
+}
+
+// PathPrefix matches requests whose path starts with any of the prefixes.
+// A prefix matches whole path segments, so "/admin" doesn't match "/administrator".
+func PathPrefix(prefixes ...string) Predicate {
+	return func(ctx *lift.Context) bool {
+		if ctx.Request == nil {
+			return false
+		}
+		path := ctx.Request.Path
+		for _, prefix := range prefixes {
+			prefix = strings.TrimSuffix(prefix, "/")
+			if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MethodIn matches requests with any of the HTTP methods
+func MethodIn(methods ...string) Predicate {
+	return func(ctx *lift.Context) bool {
+		if ctx.Request == nil {
+			return false
+		}
+		for _, method := range methods {
+			if strings.EqualFold(ctx.Request.Method, method) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HeaderEquals matches requests where the header (matched case-insensitively)
+// has exactly the given value
+func HeaderEquals(name, value string) Predicate {
+	return func(ctx *lift.Context) bool {
+		if ctx.Request == nil {
+			return false
+		}
+		return ctx.Request.GetHeader(name) == value
+	}
+}
+
+// TenantIn matches requests from any of the tenants
+func TenantIn(tenantIDs ...string) Predicate {
+	tenants := make(map[string]bool, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		tenants[tenantID] = true
+	}
+	return func(ctx *lift.Context) bool {
+		return tenants[ctx.TenantID()]
+	}
+}
+
+// Percentage matches the given percentage (0-100) of requests. Requests with
+// a tenant ID are bucketed by tenant so a tenant is consistently in or out,
+// which makes it suitable for gradual rollouts; other requests are sampled
+// at random.
+func Percentage(percent float64) Predicate {
+	return func(ctx *lift.Context) bool {
+		if percent <= 0 {
+			return false
+		}
+		if percent >= 100 {
+			return true
+		}
+
+		var bucket float64
+		if tenantID := ctx.TenantID(); tenantID != "" {
+			h := fnv.New32a()
+			h.Write([]byte(tenantID))
+			bucket = float64(h.Sum32()%10000) / 100
+		} else {
+			bucket = rand.Float64() * 100
+		}
+		return bucket < percent
+	}
+}
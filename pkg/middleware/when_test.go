@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPredicateContext(method, path string, headers map[string]string) *lift.Context {
+	return lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  method,
+		Path:    path,
+		Headers: headers,
+	}))
+}
+
+func TestWhen(t *testing.T) {
+	applied := 0
+	counting := func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			applied++
+			return next.Handle(ctx)
+		})
+	}
+	ok := lift.HandlerFunc(func(ctx *lift.Context) error { return nil })
+
+	// Both lift.Middleware and the package's Middleware type are accepted
+	handler := When(PathPrefix("/admin"), Middleware(counting))(ok)
+	handler.Handle(newPredicateContext("GET", "/admin/users", nil))
+	handler.Handle(newPredicateContext("GET", "/administrator", nil))
+	handler.Handle(newPredicateContext("GET", "/public", nil))
+	assert.Equal(t, 1, applied)
+
+	applied = 0
+	handler = Unless(MethodIn("GET", "HEAD"), lift.Middleware(counting))(ok)
+	handler.Handle(newPredicateContext("GET", "/payments", nil))
+	handler.Handle(newPredicateContext("post", "/payments", nil))
+	assert.Equal(t, 1, applied)
+}
+
+func TestPredicates(t *testing.T) {
+	ctx := newPredicateContext("POST", "/payments/123", map[string]string{"x-client": "mobile"})
+	ctx.SetTenantID("tenant-a")
+
+	assert.True(t, PathPrefix("/payments/")(ctx))
+	assert.True(t, PathPrefix("/")(ctx))
+	assert.False(t, PathPrefix("/pay")(ctx))
+	assert.True(t, MethodIn("PUT", "POST")(ctx))
+	assert.True(t, HeaderEquals("X-Client", "mobile")(ctx))
+	assert.False(t, HeaderEquals("X-Client", "web")(ctx))
+	assert.True(t, TenantIn("tenant-b", "tenant-a")(ctx))
+	assert.False(t, TenantIn("tenant-b")(ctx))
+
+	assert.True(t, PathPrefix("/payments").And(MethodIn("POST"), TenantIn("tenant-a"))(ctx))
+	assert.False(t, PathPrefix("/payments").And(MethodIn("GET"))(ctx))
+	assert.True(t, MethodIn("GET").Or(HeaderEquals("X-Client", "mobile"))(ctx))
+	assert.False(t, Any()(ctx))
+	assert.True(t, All()(ctx))
+	assert.True(t, Not(TenantIn("tenant-b"))(ctx))
+}
+
+func TestPercentage(t *testing.T) {
+	ctx := newPredicateContext("GET", "/", nil)
+	assert.False(t, Percentage(0)(ctx))
+	assert.True(t, Percentage(100)(ctx))
+
+	// Tenants are consistently in or out of the rollout
+	half := Percentage(50)
+	matched := 0
+	for i := 0; i < 1000; i++ {
+		tenantCtx := newPredicateContext("GET", "/", nil)
+		tenantCtx.SetTenantID(fmt.Sprintf("tenant-%d", i))
+		first := half(tenantCtx)
+		assert.Equal(t, first, half(tenantCtx))
+		if first {
+			matched++
+		}
+	}
+	assert.InDelta(t, 500, matched, 100)
+}
@@ -0,0 +1,183 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	recordKeyPrefix = "migration#"
+	lockKey         = "migration-lock"
+)
+
+// DynamoDBStore implements Store using a DynamoDB table with a string
+// partition key named "pk", the schema created by
+// middleware.CreateIdempotencyTable. Applied migrations are listed with a
+// scan, so the table should be dedicated to migrations.
+type DynamoDBStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBStore creates a DynamoDB-backed migration store
+func NewDynamoDBStore(client *dynamodb.Client, tableName string) *DynamoDBStore {
+	return &DynamoDBStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// dynamoDBRecord is the DynamoDB item structure for an applied migration
+type dynamoDBRecord struct {
+	PK         string    `dynamodbav:"pk"`
+	Version    int64     `dynamodbav:"version"`
+	Name       string    `dynamodbav:"name"`
+	AppliedAt  time.Time `dynamodbav:"applied_at"`
+	AppliedBy  string    `dynamodbav:"applied_by"`
+	DurationMS int64     `dynamodbav:"duration_ms"`
+}
+
+// Applied scans the table for applied migrations
+func (d *DynamoDBStore) Applied(ctx context.Context) ([]Record, error) {
+	var records []Record
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(d.tableName),
+		FilterExpression: aws.String("begins_with(pk, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: recordKeyPrefix},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	for {
+		result, err := d.client.Scan(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Items {
+			var dbRecord dynamoDBRecord
+			if err := attributevalue.UnmarshalMap(item, &dbRecord); err != nil {
+				return nil, err
+			}
+			records = append(records, Record{
+				Version:   dbRecord.Version,
+				Name:      dbRecord.Name,
+				AppliedAt: dbRecord.AppliedAt,
+				AppliedBy: dbRecord.AppliedBy,
+				Duration:  time.Duration(dbRecord.DurationMS) * time.Millisecond,
+			})
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+	return records, nil
+}
+
+// Save records an applied migration
+func (d *DynamoDBStore) Save(ctx context.Context, record Record) error {
+	av, err := attributevalue.MarshalMap(dynamoDBRecord{
+		PK:         recordKey(record.Version),
+		Version:    record.Version,
+		Name:       record.Name,
+		AppliedAt:  record.AppliedAt,
+		AppliedBy:  record.AppliedBy,
+		DurationMS: record.Duration.Milliseconds(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      av,
+	})
+	return err
+}
+
+// Delete removes an applied migration
+func (d *DynamoDBStore) Delete(ctx context.Context, version int64) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: recordKey(version)},
+		},
+	})
+	return err
+}
+
+// AcquireLock conditionally writes the lock item, succeeding if it doesn't
+// exist, has expired, or is already held by owner
+func (d *DynamoDBStore) AcquireLock(ctx context.Context, owner string, ttl time.Duration) error {
+	now := time.Now()
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]types.AttributeValue{
+			"pk":         &types.AttributeValueMemberS{Value: lockKey},
+			"owner":      &types.AttributeValueMemberS{Value: owner},
+			"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl).Unix(), 10)},
+			"ttl":        &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl).Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(pk) OR expires_at < :now OR #owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrLocked
+		}
+		return err
+	}
+	return nil
+}
+
+// ReleaseLock deletes the lock item if owner holds it
+func (d *DynamoDBStore) ReleaseLock(ctx context.Context, owner string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: lockKey},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			// The lock expired and was taken by another runner
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// recordKey is the partition key for an applied migration
+func recordKey(version int64) string {
+	return fmt.Sprintf("%s%020d", recordKeyPrefix, version)
+}
@@ -0,0 +1,172 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// Request is the body accepted by Handler
+type Request struct {
+	// Action is "up", "down" or "status"
+	Action string `json:"action"`
+	DryRun bool   `json:"dry_run"`
+	Target int64  `json:"target"`
+	Steps  int    `json:"steps"`
+}
+
+// Handler returns a lift handler that runs migrations, for a dedicated
+// migration function invoked from a deploy pipeline:
+//
+//	app.POST("/migrations", migrations.Handler(runner))
+//
+// It should only be reachable by operators; protect it with auth middleware
+// or keep it off public routes.
+func Handler(runner *Runner) lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var req Request
+		if err := ctx.ParseRequest(&req); err != nil {
+			return err
+		}
+
+		opts := Options{DryRun: req.DryRun, Target: req.Target, Steps: req.Steps}
+		var result *Result
+		var err error
+
+		switch req.Action {
+		case "status":
+			statuses, err := runner.Status(ctx.Context)
+			if err != nil {
+				return lift.NewLiftError("MIGRATION_STATUS_FAILED", "Failed to load migration status", 500).WithCause(err)
+			}
+			return ctx.OK(statuses)
+		case "up":
+			result, err = runner.Up(ctx.Context, opts)
+		case "down":
+			result, err = runner.Down(ctx.Context, opts)
+		default:
+			return lift.NewLiftError("INVALID_ACTION", "Action must be up, down or status", 400).
+				WithDetail("action", req.Action)
+		}
+
+		if errors.Is(err, ErrLocked) {
+			return lift.NewLiftError("MIGRATIONS_LOCKED", "Another migration run is in progress", 409).WithCause(err)
+		}
+		if err != nil {
+			return lift.NewLiftError("MIGRATION_FAILED", err.Error(), 500).
+				WithCause(err).
+				WithDetail("result", result)
+		}
+		return ctx.OK(result)
+	})
+}
+
+// Command runs migrations from the lift CLI. It implements cli.Command, so
+// a project's own CLI can register it:
+//
+//	c := cli.NewCLI(version)
+//	c.RegisterCommand(migrations.NewCommand(runner))
+type Command struct {
+	runner *Runner
+	out    io.Writer
+}
+
+// NewCommand creates a migrate command for runner that writes to stdout
+func NewCommand(runner *Runner) *Command {
+	return &Command{runner: runner, out: os.Stdout}
+}
+
+func (c *Command) Name() string        { return "migrate" }
+func (c *Command) Description() string { return "Apply, roll back or list data migrations" }
+func (c *Command) Usage() string {
+	return "lift migrate <up|down|status> [--dry-run] [--target=VERSION] [--steps=N]"
+}
+
+// Execute parses the action and flags and runs it
+func (c *Command) Execute(ctx context.Context, args []string) error {
+	action := "status"
+	var opts Options
+
+	for _, arg := range args {
+		var err error
+		switch {
+		case arg == "--dry-run":
+			opts.DryRun = true
+		case strings.HasPrefix(arg, "--target="):
+			opts.Target, err = strconv.ParseInt(strings.TrimPrefix(arg, "--target="), 10, 64)
+		case strings.HasPrefix(arg, "--steps="):
+			opts.Steps, err = strconv.Atoi(strings.TrimPrefix(arg, "--steps="))
+		case !strings.HasPrefix(arg, "--"):
+			action = arg
+		default:
+			return fmt.Errorf("unknown flag: %s", arg)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", arg, err)
+		}
+	}
+
+	var result *Result
+	var err error
+	switch action {
+	case "status":
+		return c.printStatus(ctx)
+	case "up":
+		result, err = c.runner.Up(ctx, opts)
+	case "down":
+		result, err = c.runner.Down(ctx, opts)
+	default:
+		return fmt.Errorf("unknown action %q; usage: %s", action, c.Usage())
+	}
+
+	if result != nil {
+		c.printResult(result)
+	}
+	return err
+}
+
+// printStatus writes the migration status as a table
+func (c *Command) printStatus(ctx context.Context) error {
+	statuses, err := c.runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+	return tw.Flush()
+}
+
+// printResult writes what a run did
+func (c *Command) printResult(result *Result) {
+	prefix := ""
+	if result.DryRun {
+		prefix = "[dry run] "
+	}
+	for _, line := range result.Log {
+		fmt.Fprintf(c.out, "%s%s\n", prefix, line)
+	}
+	for _, version := range result.Applied {
+		fmt.Fprintf(c.out, "%sapplied %d\n", prefix, version)
+	}
+	for _, version := range result.RolledBack {
+		fmt.Fprintf(c.out, "%srolled back %d\n", prefix, version)
+	}
+	if len(result.Applied) == 0 && len(result.RolledBack) == 0 {
+		fmt.Fprintf(c.out, "%snothing to do\n", prefix)
+	}
+}
@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps migration state in memory, for tests and local runs
+type MemoryStore struct {
+	mu          sync.Mutex
+	records     map[int64]Record
+	lockOwner   string
+	lockExpires time.Time
+}
+
+// NewMemoryStore creates an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[int64]Record)}
+}
+
+// Applied returns applied migrations ordered by version
+func (m *MemoryStore) Applied(ctx context.Context) ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := make([]Record, 0, len(m.records))
+	for _, record := range m.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+	return records, nil
+}
+
+// Save records an applied migration
+func (m *MemoryStore) Save(ctx context.Context, record Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[record.Version] = record
+	return nil
+}
+
+// Delete removes an applied migration
+func (m *MemoryStore) Delete(ctx context.Context, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, version)
+	return nil
+}
+
+// AcquireLock takes the lock unless another owner holds an unexpired one
+func (m *MemoryStore) AcquireLock(ctx context.Context, owner string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.lockOwner != "" && m.lockOwner != owner && time.Now().Before(m.lockExpires) {
+		return ErrLocked
+	}
+	m.lockOwner = owner
+	m.lockExpires = time.Now().Add(ttl)
+	return nil
+}
+
+// ReleaseLock releases the lock if owner holds it
+func (m *MemoryStore) ReleaseLock(ctx context.Context, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.lockOwner == owner {
+		m.lockOwner = ""
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+// Package migrations runs versioned data and schema migrations, tracking
+// which have been applied and serializing runs with a distributed lock.
+//
+//	runner := migrations.NewRunner(migrations.NewDynamoDBStore(client, "app-migrations"), migrations.Config{})
+//	runner.MustRegister(migrations.Migration{
+//		Version: 20240601,
+//		Name:    "backfill payment currency",
+//		Up: func(ctx context.Context, run *migrations.Run) error {
+//			// ...
+//			if run.DryRun {
+//				run.Logf("would update %d payments", len(payments))
+//				return nil
+//			}
+//			return save(ctx, payments)
+//		},
+//	})
+//
+// Runs are triggered from a dedicated Lambda with Handler or from the lift
+// CLI with Command.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrLocked is returned when another runner holds the migration lock
+	ErrLocked = errors.New("migrations are locked by another runner")
+	// ErrIrreversible is returned when rolling back a migration without a Down function
+	ErrIrreversible = errors.New("migration has no rollback")
+)
+
+// MigrationFunc applies or rolls back a migration. Functions must honour
+// run.DryRun by reporting what they would change without changing it.
+type MigrationFunc func(ctx context.Context, run *Run) error
+
+// Migration is a versioned change to data or schema. Versions are applied in
+// ascending order; a timestamp such as 20240601 keeps them unique across
+// branches.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      MigrationFunc
+	// Down rolls the migration back; nil makes it irreversible
+	Down MigrationFunc
+}
+
+// Run is passed to migration functions
+type Run struct {
+	Version int64
+	DryRun  bool
+	log     *[]string
+}
+
+// Logf records a message in the run's result
+func (r *Run) Logf(format string, args ...any) {
+	*r.log = append(*r.log, fmt.Sprintf("%d: %s", r.Version, fmt.Sprintf(format, args...)))
+}
+
+// Record is a migration that has been applied
+type Record struct {
+	Version   int64         `json:"version"`
+	Name      string        `json:"name"`
+	AppliedAt time.Time     `json:"applied_at"`
+	AppliedBy string        `json:"applied_by"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Store tracks applied migrations and holds the run lock
+type Store interface {
+	Applied(ctx context.Context) ([]Record, error)
+	Save(ctx context.Context, record Record) error
+	Delete(ctx context.Context, version int64) error
+	// AcquireLock takes the lock for owner until ttl elapses, returning
+	// ErrLocked if someone else holds an unexpired lock
+	AcquireLock(ctx context.Context, owner string, ttl time.Duration) error
+	ReleaseLock(ctx context.Context, owner string) error
+}
+
+// Config configures a Runner
+type Config struct {
+	// Owner identifies this runner in the lock and applied records
+	// (default: hostname and process ID)
+	Owner string
+	// LockTTL bounds how long a crashed runner can hold the lock (default: 15m)
+	LockTTL time.Duration
+}
+
+// Options controls a single run
+type Options struct {
+	// DryRun calls migrations with Run.DryRun set and records nothing
+	DryRun bool
+	// Target stops Up after this version, or Down before it; 0 means no limit
+	Target int64
+	// Steps limits the number of migrations; Down defaults to 1
+	Steps int
+}
+
+// Result reports what a run did
+type Result struct {
+	DryRun     bool     `json:"dry_run"`
+	Applied    []int64  `json:"applied,omitempty"`
+	RolledBack []int64  `json:"rolled_back,omitempty"`
+	Log        []string `json:"log,omitempty"`
+}
+
+// Status describes a registered migration
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Runner applies and rolls back registered migrations
+type Runner struct {
+	store      Store
+	config     Config
+	migrations []Migration
+}
+
+// NewRunner creates a runner backed by store
+func NewRunner(store Store, config Config) *Runner {
+	if config.Owner == "" {
+		host, _ := os.Hostname()
+		config.Owner = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+	if config.LockTTL == 0 {
+		config.LockTTL = 15 * time.Minute
+	}
+
+	return &Runner{store: store, config: config}
+}
+
+// Register adds migrations to the runner
+func (r *Runner) Register(migrations ...Migration) error {
+	for _, migration := range migrations {
+		if migration.Version <= 0 {
+			return fmt.Errorf("migration %q must have a positive version", migration.Name)
+		}
+		if migration.Up == nil {
+			return fmt.Errorf("migration %d has no Up function", migration.Version)
+		}
+		for _, existing := range r.migrations {
+			if existing.Version == migration.Version {
+				return fmt.Errorf("migration %d is registered twice", migration.Version)
+			}
+		}
+		r.migrations = append(r.migrations, migration)
+	}
+
+	sort.Slice(r.migrations, func(i, j int) bool {
+		return r.migrations[i].Version < r.migrations[j].Version
+	})
+	return nil
+}
+
+// MustRegister is like Register but panics on error
+func (r *Runner) MustRegister(migrations ...Migration) {
+	if err := r.Register(migrations...); err != nil {
+		panic(err)
+	}
+}
+
+// Status lists registered migrations and whether each has been applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, migration := range r.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up applies pending migrations in ascending order, stopping at the first
+// failure. Migrations below the newest applied version are still applied,
+// so those merged out of order aren't skipped.
+func (r *Runner) Up(ctx context.Context, opts Options) (*Result, error) {
+	return r.run(ctx, opts, func(applied map[int64]Record) []Migration {
+		var pending []Migration
+		for _, migration := range r.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if opts.Target > 0 && migration.Version > opts.Target {
+				break
+			}
+			pending = append(pending, migration)
+		}
+		return limit(pending, opts.Steps)
+	}, true)
+}
+
+// Down rolls back applied migrations, newest first. Without a Target only
+// the most recent migration is rolled back unless Steps says otherwise.
+func (r *Runner) Down(ctx context.Context, opts Options) (*Result, error) {
+	if opts.Target == 0 && opts.Steps == 0 {
+		opts.Steps = 1
+	}
+
+	return r.run(ctx, opts, func(applied map[int64]Record) []Migration {
+		var rollback []Migration
+		for i := len(r.migrations) - 1; i >= 0; i-- {
+			migration := r.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if opts.Target > 0 && migration.Version <= opts.Target {
+				break
+			}
+			rollback = append(rollback, migration)
+		}
+		return limit(rollback, opts.Steps)
+	}, false)
+}
+
+// run selects migrations under the lock and applies or rolls them back
+func (r *Runner) run(ctx context.Context, opts Options, selectMigrations func(map[int64]Record) []Migration, up bool) (*Result, error) {
+	// Dry runs change nothing, so they don't wait for a running migration
+	if !opts.DryRun {
+		if err := r.store.AcquireLock(ctx, r.config.Owner, r.config.LockTTL); err != nil {
+			return nil, err
+		}
+		defer r.store.ReleaseLock(context.WithoutCancel(ctx), r.config.Owner)
+	}
+
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{DryRun: opts.DryRun}
+	for _, migration := range selectMigrations(applied) {
+		run := &Run{Version: migration.Version, DryRun: opts.DryRun, log: &result.Log}
+
+		fn := migration.Up
+		if !up {
+			fn = migration.Down
+			if fn == nil {
+				return result, fmt.Errorf("migration %d: %w", migration.Version, ErrIrreversible)
+			}
+		}
+
+		start := time.Now()
+		if err := fn(ctx, run); err != nil {
+			return result, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+
+		if up {
+			if !opts.DryRun {
+				record := Record{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now().UTC(),
+					AppliedBy: r.config.Owner,
+					Duration:  time.Since(start),
+				}
+				if err := r.store.Save(ctx, record); err != nil {
+					return result, fmt.Errorf("migration %d applied but not recorded: %w", migration.Version, err)
+				}
+			}
+			result.Applied = append(result.Applied, migration.Version)
+		} else {
+			if !opts.DryRun {
+				if err := r.store.Delete(ctx, migration.Version); err != nil {
+					return result, fmt.Errorf("migration %d rolled back but still recorded: %w", migration.Version, err)
+				}
+			}
+			result.RolledBack = append(result.RolledBack, migration.Version)
+		}
+	}
+
+	return result, nil
+}
+
+// applied returns applied migrations keyed by version
+func (r *Runner) applied(ctx context.Context) (map[int64]Record, error) {
+	records, err := r.store.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// limit returns at most steps migrations; 0 means all
+func limit(migrations []Migration, steps int) []Migration {
+	if steps > 0 && len(migrations) > steps {
+		return migrations[:steps]
+	}
+	return migrations
+}
@@ -0,0 +1,197 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMigrations returns three migrations that record calls in data
+func testMigrations(data map[string]bool) []Migration {
+	step := func(key string, value bool) MigrationFunc {
+		return func(ctx context.Context, run *Run) error {
+			if run.DryRun {
+				run.Logf("would set %s=%v", key, value)
+				return nil
+			}
+			data[key] = value
+			return nil
+		}
+	}
+
+	return []Migration{
+		{Version: 3, Name: "third", Up: step("c", true)},
+		{Version: 1, Name: "first", Up: step("a", true), Down: step("a", false)},
+		{Version: 2, Name: "second", Up: step("b", true), Down: step("b", false)},
+	}
+}
+
+func newTestRunner(t *testing.T, data map[string]bool) (*Runner, *MemoryStore) {
+	store := NewMemoryStore()
+	runner := NewRunner(store, Config{Owner: "test"})
+	require.NoError(t, runner.Register(testMigrations(data)...))
+	return runner, store
+}
+
+func TestRunnerUpAndDown(t *testing.T) {
+	data := map[string]bool{}
+	runner, _ := newTestRunner(t, data)
+	ctx := context.Background()
+
+	result, err := runner.Up(ctx, Options{Target: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, result.Applied)
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, data)
+
+	result, err = runner.Up(ctx, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3}, result.Applied)
+
+	statuses, err := runner.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+	}
+
+	// Version 3 is irreversible, so rolling back stops there
+	result, err = runner.Down(ctx, Options{})
+	assert.ErrorIs(t, err, ErrIrreversible)
+	assert.Empty(t, result.RolledBack)
+}
+
+func TestRunnerDownToTarget(t *testing.T) {
+	data := map[string]bool{}
+	runner, _ := newTestRunner(t, data)
+	ctx := context.Background()
+
+	_, err := runner.Up(ctx, Options{Target: 2})
+	require.NoError(t, err)
+
+	result, err := runner.Down(ctx, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, result.RolledBack)
+	assert.False(t, data["b"])
+
+	_, err = runner.Up(ctx, Options{Steps: 1})
+	require.NoError(t, err)
+	result, err = runner.Down(ctx, Options{Steps: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, result.RolledBack)
+
+	statuses, err := runner.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.False(t, status.Applied)
+	}
+}
+
+func TestRunnerDryRun(t *testing.T) {
+	data := map[string]bool{}
+	runner, store := newTestRunner(t, data)
+
+	result, err := runner.Up(context.Background(), Options{DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []int64{1, 2, 3}, result.Applied)
+	assert.Equal(t, []string{"1: would set a=true", "2: would set b=true", "3: would set c=true"}, result.Log)
+	assert.Empty(t, data)
+
+	applied, err := store.Applied(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestRunnerLocking(t *testing.T) {
+	data := map[string]bool{}
+	runner, store := newTestRunner(t, data)
+	ctx := context.Background()
+
+	require.NoError(t, store.AcquireLock(ctx, "other", time.Minute))
+	_, err := runner.Up(ctx, Options{})
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Empty(t, data)
+
+	// Dry runs don't need the lock
+	_, err = runner.Up(ctx, Options{DryRun: true})
+	require.NoError(t, err)
+
+	require.NoError(t, store.ReleaseLock(ctx, "other"))
+	_, err = runner.Up(ctx, Options{})
+	require.NoError(t, err)
+
+	// The runner releases the lock when it finishes
+	require.NoError(t, store.AcquireLock(ctx, "other", time.Minute))
+}
+
+func TestRunnerStopsOnFailure(t *testing.T) {
+	runner, store := newTestRunner(t, map[string]bool{})
+	require.NoError(t, runner.Register(Migration{
+		Version: 2024,
+		Name:    "broken",
+		Up:      func(ctx context.Context, run *Run) error { return errors.New("boom") },
+	}))
+
+	result, err := runner.Up(context.Background(), Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+	assert.Equal(t, []int64{1, 2, 3}, result.Applied)
+
+	applied, err := store.Applied(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, applied, 3)
+
+	assert.Error(t, runner.Register(Migration{Version: 1, Up: func(ctx context.Context, run *Run) error { return nil }}))
+	assert.Error(t, runner.Register(Migration{Version: 9}))
+}
+
+func TestHandler(t *testing.T) {
+	data := map[string]bool{}
+	runner, _ := newTestRunner(t, data)
+	handler := Handler(runner)
+
+	request := func(body string) (*lift.Context, error) {
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method: "POST",
+			Path:   "/migrations",
+			Body:   []byte(body),
+		}))
+		return ctx, handler.Handle(ctx)
+	}
+
+	ctx, err := request(`{"action":"up","target":1}`)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Applied: []int64{1}}, ctx.Response.Body)
+
+	_, err = request(`{"action":"sideways"}`)
+	var liftErr *lift.LiftError
+	require.ErrorAs(t, err, &liftErr)
+	assert.Equal(t, 400, liftErr.StatusCode)
+}
+
+func TestCommand(t *testing.T) {
+	data := map[string]bool{}
+	runner, _ := newTestRunner(t, data)
+	var out bytes.Buffer
+	command := &Command{runner: runner, out: &out}
+
+	require.NoError(t, command.Execute(context.Background(), []string{"up", "--dry-run", "--target=1"}))
+	assert.Contains(t, out.String(), "[dry run] applied 1")
+	assert.Empty(t, data)
+
+	out.Reset()
+	require.NoError(t, command.Execute(context.Background(), []string{"up"}))
+	require.NoError(t, command.Execute(context.Background(), []string{"status"}))
+	assert.Contains(t, out.String(), "third")
+	assert.NotContains(t, out.String(), "pending")
+
+	assert.Error(t, command.Execute(context.Background(), []string{"up", "--steps=x"}))
+	assert.Error(t, command.Execute(context.Background(), []string{"sideways"}))
+}
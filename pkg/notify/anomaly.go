@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/security"
+)
+
+// AnomalyAlertTemplate is the default template for anomaly alerts
+const AnomalyAlertTemplate = "security-anomaly"
+
+// AnomalyAlertConfig configures the alerter returned by Service.AnomalyAlerter
+type AnomalyAlertConfig struct {
+	// Channel alerts are sent on (default: ChannelEmail)
+	Channel Channel
+	// To lists the recipients of every alert
+	To []string
+	// Template names the template to render (default: AnomalyAlertTemplate).
+	// It is registered with a plain default if the service has no template
+	// by that name.
+	Template string
+}
+
+// AnomalyAlerter returns a security.AnomalyAlerter that sends each finding to
+// every recipient. Template data holds the finding's fields by their JSON
+// names, with severity and timestamps as strings.
+func (s *Service) AnomalyAlerter(config AnomalyAlertConfig) security.AnomalyAlerter {
+	if config.Channel == "" {
+		config.Channel = ChannelEmail
+	}
+	if config.Template == "" {
+		config.Template = AnomalyAlertTemplate
+	}
+	if config.Template == AnomalyAlertTemplate && !s.config.Templates.has(templateKey("", AnomalyAlertTemplate)) {
+		_ = s.config.Templates.Register(AnomalyAlertTemplate, Template{
+			Subject: "[{{.severity}}] Anomalous access by {{.user_id}}",
+			Text:    "{{.description}}\n\nDetector: {{.detector}}\nTenant: {{.tenant_id}}\nUser: {{.user_id}}\nResource: {{.resource}}\nOccurred: {{.occurred_at}}\nFinding: {{.id}}",
+			SMS:     "[{{.severity}}] {{.detector}}: {{.user_id}} - {{.description}}",
+		})
+	}
+
+	return security.AnomalyAlerterFunc(func(ctx context.Context, finding *security.AnomalyFinding) error {
+		var failures []error
+		for _, to := range config.To {
+			_, err := s.Send(ctx, &Message{
+				Channel:  config.Channel,
+				TenantID: finding.TenantID,
+				To:       to,
+				Template: config.Template,
+				Data:     anomalyData(finding),
+				Tags:     map[string]string{"finding_id": finding.ID, "detector": finding.Detector},
+			})
+			if err != nil {
+				failures = append(failures, fmt.Errorf("%s: %w", to, err))
+			}
+		}
+		return errors.Join(failures...)
+	})
+}
+
+func anomalyData(finding *security.AnomalyFinding) map[string]any {
+	return map[string]any{
+		"id":          finding.ID,
+		"detector":    finding.Detector,
+		"severity":    finding.Severity.String(),
+		"tenant_id":   finding.TenantID,
+		"user_id":     finding.UserID,
+		"resource":    finding.Resource,
+		"description": finding.Description,
+		"entry_id":    finding.EntryID,
+		"occurred_at": finding.OccurredAt.Format(time.RFC3339),
+		"metadata":    finding.Metadata,
+	}
+}
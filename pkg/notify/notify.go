@@ -0,0 +1,265 @@
+// Package notify sends templated email and SMS notifications through SES, SNS
+// or Pinpoint, with per-tenant sender identities, send throttling and
+// suppression-list checks.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// Channel is a notification delivery channel
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Status is the outcome of a send
+type Status string
+
+const (
+	StatusSent       Status = "sent"
+	StatusSuppressed Status = "suppressed"
+)
+
+var (
+	// ErrThrottled is returned when a send exceeds the tenant's rate limit.
+	// Callers running from a queue should retry later.
+	ErrThrottled = errors.New("notification throttled")
+	// ErrNoProvider is returned when no sender is configured for a channel
+	ErrNoProvider = errors.New("no provider configured for channel")
+)
+
+// Message is a notification to render and send
+type Message struct {
+	Channel  Channel `json:"channel"`
+	TenantID string  `json:"tenant_id,omitempty"`
+	// To is an email address or an E.164 phone number
+	To string `json:"to"`
+	// Template names the template to render
+	Template string         `json:"template"`
+	Data     map[string]any `json:"data,omitempty"`
+	// Tags are passed through to the provider for delivery tracking
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// Result describes the outcome of a send
+type Result struct {
+	Status    Status `json:"status"`
+	MessageID string `json:"message_id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Email is a rendered email ready for a provider
+type Email struct {
+	From             string
+	ReplyTo          string
+	To               string
+	Subject          string
+	TextBody         string
+	HTMLBody         string
+	ConfigurationSet string
+	Tags             map[string]string
+}
+
+// SMS is a rendered text message ready for a provider
+type SMS struct {
+	To                string
+	Body              string
+	SenderID          string
+	OriginationNumber string
+	// Transactional selects the transactional route (default) over promotional
+	Transactional bool
+	Tags          map[string]string
+}
+
+// EmailSender delivers rendered emails
+type EmailSender interface {
+	SendEmail(ctx context.Context, email *Email) (messageID string, err error)
+}
+
+// SMSSender delivers rendered text messages
+type SMSSender interface {
+	SendSMS(ctx context.Context, sms *SMS) (messageID string, err error)
+}
+
+// SenderIdentity is the identity a tenant's notifications are sent from
+type SenderIdentity struct {
+	EmailFrom         string `json:"email_from"`
+	ReplyTo           string `json:"reply_to,omitempty"`
+	ConfigurationSet  string `json:"configuration_set,omitempty"`
+	SMSSenderID       string `json:"sms_sender_id,omitempty"`
+	OriginationNumber string `json:"origination_number,omitempty"`
+}
+
+// IdentityResolver returns the sender identity for a tenant
+type IdentityResolver interface {
+	Identity(ctx context.Context, tenantID string) (*SenderIdentity, error)
+}
+
+// StaticIdentities resolves identities from a map, falling back to Default
+type StaticIdentities struct {
+	Default SenderIdentity
+	Tenants map[string]SenderIdentity
+}
+
+// Identity implements IdentityResolver
+func (s *StaticIdentities) Identity(ctx context.Context, tenantID string) (*SenderIdentity, error) {
+	if identity, ok := s.Tenants[tenantID]; ok {
+		return &identity, nil
+	}
+	identity := s.Default
+	return &identity, nil
+}
+
+// Config configures a notification Service
+type Config struct {
+	Email       EmailSender
+	SMS         SMSSender
+	Templates   *Templates
+	Identities  IdentityResolver
+	Throttle    Throttler
+	Suppression SuppressionList
+}
+
+// Service renders and sends notifications
+type Service struct {
+	config Config
+}
+
+// New creates a notification service
+func New(config Config) *Service {
+	if config.Templates == nil {
+		config.Templates = NewTemplates()
+	}
+	if config.Identities == nil {
+		config.Identities = &StaticIdentities{}
+	}
+	return &Service{config: config}
+}
+
+// Send renders and delivers a message. Suppressed recipients are skipped and
+// reported in the result rather than as an error.
+func (s *Service) Send(ctx context.Context, msg *Message) (*Result, error) {
+	if msg.To == "" {
+		return nil, lift.NewLiftError("INVALID_RECIPIENT", "Notification recipient is required", 400)
+	}
+
+	address := normalizeAddress(msg.Channel, msg.To)
+
+	if s.config.Suppression != nil {
+		suppressed, reason, err := s.config.Suppression.IsSuppressed(ctx, msg.Channel, address)
+		if err != nil {
+			return nil, fmt.Errorf("suppression check failed: %w", err)
+		}
+		if suppressed {
+			return &Result{Status: StatusSuppressed, Reason: reason}, nil
+		}
+	}
+
+	if s.config.Throttle != nil {
+		allowed, err := s.config.Throttle.Allow(ctx, throttleKey(msg))
+		if err != nil {
+			return nil, fmt.Errorf("throttle check failed: %w", err)
+		}
+		if !allowed {
+			return nil, ErrThrottled
+		}
+	}
+
+	identity, err := s.config.Identities.Identity(ctx, msg.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sender identity: %w", err)
+	}
+
+	rendered, err := s.config.Templates.Render(msg.TenantID, msg.Template, msg.Channel, msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var messageID string
+	switch msg.Channel {
+	case ChannelEmail:
+		if s.config.Email == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNoProvider, msg.Channel)
+		}
+		messageID, err = s.config.Email.SendEmail(ctx, &Email{
+			From:             identity.EmailFrom,
+			ReplyTo:          identity.ReplyTo,
+			To:               address,
+			Subject:          rendered.Subject,
+			TextBody:         rendered.Text,
+			HTMLBody:         rendered.HTML,
+			ConfigurationSet: identity.ConfigurationSet,
+			Tags:             msg.Tags,
+		})
+	case ChannelSMS:
+		if s.config.SMS == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNoProvider, msg.Channel)
+		}
+		messageID, err = s.config.SMS.SendSMS(ctx, &SMS{
+			To:                address,
+			Body:              rendered.SMS,
+			SenderID:          identity.SMSSenderID,
+			OriginationNumber: identity.OriginationNumber,
+			Transactional:     true,
+			Tags:              msg.Tags,
+		})
+	default:
+		return nil, lift.NewLiftError("INVALID_CHANNEL", fmt.Sprintf("Unsupported notification channel: %s", msg.Channel), 400)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s notification: %w", msg.Channel, err)
+	}
+
+	return &Result{Status: StatusSent, MessageID: messageID}, nil
+}
+
+// SendFromContext sends a message on behalf of the current request, defaulting
+// the tenant to the request's tenant
+func (s *Service) SendFromContext(ctx *lift.Context, msg *Message) (*Result, error) {
+	if msg.TenantID == "" {
+		msg.TenantID = ctx.TenantID()
+	}
+
+	result, err := s.Send(ctx.Context, msg)
+	if ctx.Logger != nil {
+		fields := map[string]any{
+			"channel":  msg.Channel,
+			"template": msg.Template,
+		}
+		if result != nil {
+			fields["status"] = result.Status
+			fields["message_id"] = result.MessageID
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+			ctx.Logger.Warn("Notification failed", fields)
+		} else {
+			ctx.Logger.Info("Notification processed", fields)
+		}
+	}
+	return result, err
+}
+
+func throttleKey(msg *Message) string {
+	tenant := msg.TenantID
+	if tenant == "" {
+		tenant = "_"
+	}
+	return tenant + ":" + string(msg.Channel)
+}
+
+func normalizeAddress(channel Channel, address string) string {
+	address = strings.TrimSpace(address)
+	if channel == ChannelEmail {
+		return strings.ToLower(address)
+	}
+	return address
+}
@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/pay-theory/lift/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEmailSender struct {
+	sent []*Email
+}
+
+func (f *fakeEmailSender) SendEmail(ctx context.Context, email *Email) (string, error) {
+	f.sent = append(f.sent, email)
+	return "email-id", nil
+}
+
+type fakeSMSSender struct {
+	sent []*SMS
+	err  error
+	// failTo fails sends to this number only
+	failTo string
+}
+
+func (f *fakeSMSSender) SendSMS(ctx context.Context, sms *SMS) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if f.failTo != "" && sms.To == f.failTo {
+		return "", errors.New("provider rejected number")
+	}
+	f.sent = append(f.sent, sms)
+	return "sms-id", nil
+}
+
+func newTestTemplates(t *testing.T) *Templates {
+	templates := NewTemplates()
+	require.NoError(t, templates.Register("receipt", Template{
+		Subject: "Receipt for {{.Amount}}",
+		Text:    "Thanks {{.Name}}, you paid {{.Amount}}.",
+		HTML:    "<p>Thanks {{.Name}}</p>",
+		SMS:     "Paid {{.Amount}}",
+	}))
+	require.NoError(t, templates.RegisterTenant("tenant-b", "receipt", Template{
+		Subject: "Tenant B receipt",
+		SMS:     "B: {{.Amount}}",
+	}))
+	return templates
+}
+
+func TestSendRendersTemplatesWithTenantIdentity(t *testing.T) {
+	email := &fakeEmailSender{}
+	sms := &fakeSMSSender{}
+	service := New(Config{
+		Email:     email,
+		SMS:       sms,
+		Templates: newTestTemplates(t),
+		Identities: &StaticIdentities{
+			Default: SenderIdentity{EmailFrom: "no-reply@example.com"},
+			Tenants: map[string]SenderIdentity{
+				"tenant-a": {EmailFrom: "billing@tenant-a.com", ReplyTo: "help@tenant-a.com", SMSSenderID: "TENANTA"},
+			},
+		},
+	})
+
+	result, err := service.Send(context.Background(), &Message{
+		Channel:  ChannelEmail,
+		TenantID: "tenant-a",
+		To:       "Buyer@Example.com",
+		Template: "receipt",
+		Data:     map[string]any{"Name": "<Ann>", "Amount": "$5.00"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, result.Status)
+	assert.Equal(t, "email-id", result.MessageID)
+
+	require.Len(t, email.sent, 1)
+	sent := email.sent[0]
+	assert.Equal(t, "billing@tenant-a.com", sent.From)
+	assert.Equal(t, "help@tenant-a.com", sent.ReplyTo)
+	assert.Equal(t, "buyer@example.com", sent.To)
+	assert.Equal(t, "Receipt for $5.00", sent.Subject)
+	assert.Equal(t, "<p>Thanks &lt;Ann&gt;</p>", sent.HTMLBody)
+
+	_, err = service.Send(context.Background(), &Message{
+		Channel:  ChannelSMS,
+		TenantID: "tenant-b",
+		To:       "+15555550100",
+		Template: "receipt",
+		Data:     map[string]any{"Amount": "$7.00"},
+	})
+	require.NoError(t, err)
+	require.Len(t, sms.sent, 1)
+	assert.Equal(t, "B: $7.00", sms.sent[0].Body)
+	assert.True(t, sms.sent[0].Transactional)
+}
+
+func TestSendSkipsSuppressedRecipients(t *testing.T) {
+	email := &fakeEmailSender{}
+	suppression := NewMemorySuppressionList()
+	require.NoError(t, suppression.Suppress(context.Background(), ChannelEmail, "bounced@example.com", "bounce"))
+
+	service := New(Config{Email: email, Templates: newTestTemplates(t), Suppression: suppression})
+	result, err := service.Send(context.Background(), &Message{
+		Channel:  ChannelEmail,
+		To:       "BOUNCED@example.com",
+		Template: "receipt",
+		Data:     map[string]any{"Name": "x", "Amount": "1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusSuppressed, result.Status)
+	assert.Equal(t, "bounce", result.Reason)
+	assert.Empty(t, email.sent)
+}
+
+func TestSendErrors(t *testing.T) {
+	service := New(Config{Templates: newTestTemplates(t)})
+
+	_, err := service.Send(context.Background(), &Message{Channel: ChannelEmail, To: "a@example.com", Template: "receipt", Data: map[string]any{"Name": "x", "Amount": "1"}})
+	assert.ErrorIs(t, err, ErrNoProvider)
+
+	_, err = service.Send(context.Background(), &Message{Channel: ChannelEmail, To: "a@example.com", Template: "missing"})
+	var liftErr *lift.LiftError
+	require.True(t, errors.As(err, &liftErr))
+	assert.Equal(t, "TEMPLATE_NOT_FOUND", liftErr.Code)
+
+	service = New(Config{Email: &fakeEmailSender{}, Templates: newTestTemplates(t)})
+	_, err = service.Send(context.Background(), &Message{Channel: ChannelEmail, To: "a@example.com", Template: "receipt", Data: map[string]any{}})
+	require.True(t, errors.As(err, &liftErr))
+	assert.Equal(t, "TEMPLATE_RENDER_ERROR", liftErr.Code)
+}
+
+func TestMemoryThrottler(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	throttler := NewMemoryThrottler(RateLimit{PerSecond: 1, Burst: 2}, map[string]RateLimit{
+		"vip:sms": {PerSecond: 100, Burst: 100},
+	})
+	throttler.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		allowed, _ := throttler.Allow(ctx, "tenant:sms")
+		assert.True(t, allowed)
+	}
+	allowed, _ := throttler.Allow(ctx, "tenant:sms")
+	assert.False(t, allowed)
+
+	now = now.Add(time.Second)
+	allowed, _ = throttler.Allow(ctx, "tenant:sms")
+	assert.True(t, allowed)
+
+	for i := 0; i < 10; i++ {
+		allowed, _ = throttler.Allow(ctx, "vip:sms")
+		assert.True(t, allowed)
+	}
+
+	service := New(Config{SMS: &fakeSMSSender{}, Templates: newTestTemplates(t), Throttle: throttler})
+	_, err := service.Send(ctx, &Message{Channel: ChannelSMS, TenantID: "tenant", To: "+15555550100", Template: "receipt", Data: map[string]any{"Amount": "1"}})
+	assert.ErrorIs(t, err, ErrThrottled)
+}
+
+func TestSQSHandler(t *testing.T) {
+	sms := &fakeSMSSender{failTo: "+15555550199"}
+	service := New(Config{SMS: sms, Templates: newTestTemplates(t)})
+
+	app := lift.New()
+	require.NoError(t, app.SQS("notifications", service.SQSHandler()))
+
+	record := func(id, body string) map[string]any {
+		return map[string]any{
+			"messageId":      id,
+			"body":           body,
+			"eventSource":    "aws:sqs",
+			"eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:notifications",
+			"attributes":     map[string]any{},
+		}
+	}
+	resp, err := app.HandleRequest(context.Background(), map[string]any{"Records": []any{
+		record("1", `{"channel":"sms","to":"+15555550100","template":"receipt","data":{"Amount":"2"}}`),
+		record("2", `not json`),
+		record("3", `{"channel":"sms","to":"+15555550199","template":"receipt","data":{"Amount":"3"}}`),
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, sms.sent, 1)
+	assert.Equal(t, "Paid 2", sms.sent[0].Body)
+
+	batch, ok := resp.(*adapters.SQSBatchResponse)
+	require.True(t, ok, "SQS events return a batch response, got %T", resp)
+	require.Len(t, batch.BatchItemFailures, 1, "only the failed send is retried")
+	assert.Equal(t, "3", batch.BatchItemFailures[0].ItemIdentifier)
+}
+
+func TestAnomalyAlerterSendsToEveryRecipient(t *testing.T) {
+	email := &fakeEmailSender{}
+	service := New(Config{
+		Email:      email,
+		Identities: &StaticIdentities{Default: SenderIdentity{EmailFrom: "security@example.com"}},
+	})
+	alerter := service.AnomalyAlerter(AnomalyAlertConfig{To: []string{"oncall@example.com", "ciso@example.com"}})
+
+	err := alerter.Alert(context.Background(), &security.AnomalyFinding{
+		ID:          "finding-1",
+		Detector:    "velocity",
+		Severity:    security.AnomalyHigh,
+		TenantID:    "tenant-a",
+		UserID:      "u1",
+		Description: "240 accesses in 1m0s",
+		OccurredAt:  time.Date(2024, 6, 3, 2, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Len(t, email.sent, 2)
+	assert.Equal(t, "ciso@example.com", email.sent[1].To)
+	assert.Equal(t, "[high] Anomalous access by u1", email.sent[0].Subject)
+	assert.Contains(t, email.sent[0].TextBody, "240 accesses in 1m0s")
+	assert.Equal(t, "finding-1", email.sent[0].Tags["finding_id"])
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pinpoint"
+	"github.com/aws/aws-sdk-go-v2/service/pinpoint/types"
+)
+
+// PinpointAPI is the subset of the Pinpoint client used by PinpointSender
+type PinpointAPI interface {
+	SendMessages(ctx context.Context, params *pinpoint.SendMessagesInput, optFns ...func(*pinpoint.Options)) (*pinpoint.SendMessagesOutput, error)
+}
+
+// PinpointSender sends SMS through an Amazon Pinpoint project, which supports
+// dedicated origination numbers and registered sender IDs
+type PinpointSender struct {
+	client        PinpointAPI
+	applicationID string
+}
+
+// NewPinpointSender creates a Pinpoint SMS sender for the given project
+func NewPinpointSender(client PinpointAPI, applicationID string) *PinpointSender {
+	return &PinpointSender{client: client, applicationID: applicationID}
+}
+
+// SendSMS implements SMSSender
+func (s *PinpointSender) SendSMS(ctx context.Context, sms *SMS) (string, error) {
+	messageType := types.MessageTypePromotional
+	if sms.Transactional {
+		messageType = types.MessageTypeTransactional
+	}
+
+	message := &types.SMSMessage{
+		Body:        aws.String(sms.Body),
+		MessageType: messageType,
+	}
+	if sms.SenderID != "" {
+		message.SenderId = aws.String(sms.SenderID)
+	}
+	if sms.OriginationNumber != "" {
+		message.OriginationNumber = aws.String(sms.OriginationNumber)
+	}
+
+	out, err := s.client.SendMessages(ctx, &pinpoint.SendMessagesInput{
+		ApplicationId: aws.String(s.applicationID),
+		MessageRequest: &types.MessageRequest{
+			Addresses: map[string]types.AddressConfiguration{
+				sms.To: {ChannelType: types.ChannelTypeSms},
+			},
+			Context:              sms.Tags,
+			MessageConfiguration: &types.DirectMessageConfiguration{SMSMessage: message},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.MessageResponse == nil {
+		return "", fmt.Errorf("pinpoint returned no message response for %s", sms.To)
+	}
+
+	// Pinpoint reports per-address failures in the response rather than as an error
+	result, ok := out.MessageResponse.Result[sms.To]
+	if !ok {
+		return "", fmt.Errorf("pinpoint returned no result for %s", sms.To)
+	}
+	if status := result.DeliveryStatus; status != types.DeliveryStatusSuccessful {
+		return "", fmt.Errorf("pinpoint delivery %s: %s", status, aws.ToString(result.StatusMessage))
+	}
+	return aws.ToString(result.MessageId), nil
+}
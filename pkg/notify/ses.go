@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// SESAPI is the subset of the SES v2 client used by this package
+type SESAPI interface {
+	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+	GetSuppressedDestination(ctx context.Context, params *sesv2.GetSuppressedDestinationInput, optFns ...func(*sesv2.Options)) (*sesv2.GetSuppressedDestinationOutput, error)
+	PutSuppressedDestination(ctx context.Context, params *sesv2.PutSuppressedDestinationInput, optFns ...func(*sesv2.Options)) (*sesv2.PutSuppressedDestinationOutput, error)
+}
+
+// SESSender sends email through Amazon SES
+type SESSender struct {
+	client SESAPI
+}
+
+// NewSESSender creates an SES email sender
+func NewSESSender(client SESAPI) *SESSender {
+	return &SESSender{client: client}
+}
+
+// SendEmail implements EmailSender
+func (s *SESSender) SendEmail(ctx context.Context, email *Email) (string, error) {
+	body := &types.Body{}
+	if email.TextBody != "" {
+		body.Text = &types.Content{Data: aws.String(email.TextBody), Charset: aws.String("UTF-8")}
+	}
+	if email.HTMLBody != "" {
+		body.Html = &types.Content{Data: aws.String(email.HTMLBody), Charset: aws.String("UTF-8")}
+	}
+
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(email.From),
+		Destination:      &types.Destination{ToAddresses: []string{email.To}},
+		Content: &types.EmailContent{
+			Simple: &types.Message{
+				Subject: &types.Content{Data: aws.String(email.Subject), Charset: aws.String("UTF-8")},
+				Body:    body,
+			},
+		},
+		EmailTags: sesTags(email.Tags),
+	}
+	if email.ReplyTo != "" {
+		input.ReplyToAddresses = []string{email.ReplyTo}
+	}
+	if email.ConfigurationSet != "" {
+		input.ConfigurationSetName = aws.String(email.ConfigurationSet)
+	}
+
+	out, err := s.client.SendEmail(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.MessageId), nil
+}
+
+// SESSuppressionList uses the SES account-level suppression list for email
+// and delegates other channels to Fallback, if set
+type SESSuppressionList struct {
+	client   SESAPI
+	Fallback SuppressionList
+}
+
+// NewSESSuppressionList creates a suppression list backed by SES
+func NewSESSuppressionList(client SESAPI, fallback SuppressionList) *SESSuppressionList {
+	return &SESSuppressionList{client: client, Fallback: fallback}
+}
+
+// IsSuppressed implements SuppressionList
+func (l *SESSuppressionList) IsSuppressed(ctx context.Context, channel Channel, address string) (bool, string, error) {
+	if channel != ChannelEmail {
+		if l.Fallback == nil {
+			return false, "", nil
+		}
+		return l.Fallback.IsSuppressed(ctx, channel, address)
+	}
+
+	out, err := l.client.GetSuppressedDestination(ctx, &sesv2.GetSuppressedDestinationInput{
+		EmailAddress: aws.String(address),
+	})
+	if err != nil {
+		var notFound *types.NotFoundException
+		if errors.As(err, &notFound) {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+	if out.SuppressedDestination == nil {
+		return true, "", nil
+	}
+	return true, strings.ToLower(string(out.SuppressedDestination.Reason)), nil
+}
+
+// Suppress implements SuppressionList. SES only records bounce and complaint
+// reasons, so any other reason is stored as a bounce.
+func (l *SESSuppressionList) Suppress(ctx context.Context, channel Channel, address, reason string) error {
+	if channel != ChannelEmail {
+		if l.Fallback == nil {
+			return nil
+		}
+		return l.Fallback.Suppress(ctx, channel, address, reason)
+	}
+
+	sesReason := types.SuppressionListReasonBounce
+	if strings.EqualFold(reason, string(types.SuppressionListReasonComplaint)) {
+		sesReason = types.SuppressionListReasonComplaint
+	}
+	_, err := l.client.PutSuppressedDestination(ctx, &sesv2.PutSuppressedDestinationInput{
+		EmailAddress: aws.String(address),
+		Reason:       sesReason,
+	})
+	return err
+}
+
+func sesTags(tags map[string]string) []types.MessageTag {
+	if len(tags) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]types.MessageTag, 0, len(tags))
+	for _, k := range keys {
+		out = append(out, types.MessageTag{Name: aws.String(k), Value: aws.String(tags[k])})
+	}
+	return out
+}
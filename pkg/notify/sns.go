@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// SNSAPI is the subset of the SNS client used by SNSSender
+type SNSAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// SNSSender sends SMS directly to phone numbers through Amazon SNS
+type SNSSender struct {
+	client SNSAPI
+}
+
+// NewSNSSender creates an SNS SMS sender
+func NewSNSSender(client SNSAPI) *SNSSender {
+	return &SNSSender{client: client}
+}
+
+// SendSMS implements SMSSender
+func (s *SNSSender) SendSMS(ctx context.Context, sms *SMS) (string, error) {
+	smsType := "Promotional"
+	if sms.Transactional {
+		smsType = "Transactional"
+	}
+
+	attributes := map[string]types.MessageAttributeValue{
+		"AWS.SNS.SMS.SMSType": stringAttribute(smsType),
+	}
+	if sms.SenderID != "" {
+		attributes["AWS.SNS.SMS.SenderID"] = stringAttribute(sms.SenderID)
+	}
+	if sms.OriginationNumber != "" {
+		attributes["AWS.MM.SMS.OriginationNumber"] = stringAttribute(sms.OriginationNumber)
+	}
+
+	out, err := s.client.Publish(ctx, &sns.PublishInput{
+		PhoneNumber:       aws.String(sms.To),
+		Message:           aws.String(sms.Body),
+		MessageAttributes: attributes,
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.MessageId), nil
+}
+
+func stringAttribute(value string) types.MessageAttributeValue {
+	return types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// SuppressionList tracks recipients that must not be contacted, such as
+// addresses that hard-bounced, complained or opted out
+type SuppressionList interface {
+	IsSuppressed(ctx context.Context, channel Channel, address string) (bool, string, error)
+	Suppress(ctx context.Context, channel Channel, address, reason string) error
+}
+
+// MemorySuppressionList is an in-memory suppression list
+type MemorySuppressionList struct {
+	mu      sync.RWMutex
+	entries map[string]string
+}
+
+// NewMemorySuppressionList creates an empty suppression list
+func NewMemorySuppressionList() *MemorySuppressionList {
+	return &MemorySuppressionList{entries: make(map[string]string)}
+}
+
+// IsSuppressed implements SuppressionList
+func (l *MemorySuppressionList) IsSuppressed(ctx context.Context, channel Channel, address string) (bool, string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	reason, ok := l.entries[suppressionKey(channel, address)]
+	return ok, reason, nil
+}
+
+// Suppress implements SuppressionList
+func (l *MemorySuppressionList) Suppress(ctx context.Context, channel Channel, address, reason string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[suppressionKey(channel, address)] = reason
+	return nil
+}
+
+func suppressionKey(channel Channel, address string) string {
+	return string(channel) + ":" + normalizeAddress(channel, address)
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"encoding/json"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// SQSHandler returns a handler that sends one notification per SQS record.
+// Each record body is a JSON Message. Malformed messages are logged and
+// dropped; messages whose send fails are reported with ctx.FailSQSMessage so
+// only they are retried, so providers should treat repeated sends as
+// acceptable.
+//
+//	app.SQS("notifications", notifier.SQSHandler())
+func (s *Service) SQSHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		for _, record := range ctx.Request.Records {
+			recordMap, ok := record.(map[string]any)
+			if !ok {
+				continue
+			}
+			body, _ := recordMap["body"].(string)
+			messageID, _ := recordMap["messageId"].(string)
+
+			var msg Message
+			if err := json.Unmarshal([]byte(body), &msg); err != nil {
+				if ctx.Logger != nil {
+					ctx.Logger.Error("Dropping malformed notification task", map[string]any{
+						"message_id": messageID,
+						"error":      err.Error(),
+					})
+				}
+				continue
+			}
+
+			if _, err := s.SendFromContext(ctx, &msg); err != nil {
+				if ctx.Logger != nil {
+					ctx.Logger.Warn("Notification task failed", map[string]any{
+						"message_id": messageID,
+						"error":      err.Error(),
+					})
+				}
+				ctx.FailSQSMessage(messageID)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// Template is the source for a notification. Fields use Go template syntax;
+// HTML is rendered with contextual escaping. Empty fields are skipped.
+type Template struct {
+	Subject string
+	Text    string
+	HTML    string
+	SMS     string
+}
+
+// Rendered is a template rendered for one message
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+	SMS     string
+}
+
+type compiledTemplate struct {
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+	sms     *template.Template
+}
+
+// Templates holds named templates with optional per-tenant overrides
+type Templates struct {
+	mu        sync.RWMutex
+	templates map[string]*compiledTemplate
+}
+
+// NewTemplates creates an empty template set
+func NewTemplates() *Templates {
+	return &Templates{templates: make(map[string]*compiledTemplate)}
+}
+
+// Register adds a template used for every tenant without an override
+func (t *Templates) Register(name string, tmpl Template) error {
+	return t.register(templateKey("", name), tmpl)
+}
+
+// RegisterTenant adds a template override for one tenant
+func (t *Templates) RegisterTenant(tenantID, name string, tmpl Template) error {
+	return t.register(templateKey(tenantID, name), tmpl)
+}
+
+func (t *Templates) register(key string, tmpl Template) error {
+	compiled := &compiledTemplate{}
+	var err error
+
+	if compiled.subject, err = parseText(key+".subject", tmpl.Subject); err != nil {
+		return err
+	}
+	if compiled.text, err = parseText(key+".text", tmpl.Text); err != nil {
+		return err
+	}
+	if compiled.sms, err = parseText(key+".sms", tmpl.SMS); err != nil {
+		return err
+	}
+	if tmpl.HTML != "" {
+		compiled.html, err = htmltemplate.New(key + ".html").Option("missingkey=error").Parse(tmpl.HTML)
+		if err != nil {
+			return lift.NewLiftError("INVALID_TEMPLATE", "Failed to parse notification template", 500).WithCause(err)
+		}
+	}
+
+	t.mu.Lock()
+	t.templates[key] = compiled
+	t.mu.Unlock()
+	return nil
+}
+
+// Render renders the parts of the named template used by the channel,
+// preferring the tenant's override
+func (t *Templates) Render(tenantID, name string, channel Channel, data map[string]any) (*Rendered, error) {
+	t.mu.RLock()
+	compiled, ok := t.templates[templateKey(tenantID, name)]
+	if !ok {
+		compiled, ok = t.templates[templateKey("", name)]
+	}
+	t.mu.RUnlock()
+	if !ok {
+		return nil, lift.NewLiftError("TEMPLATE_NOT_FOUND", "Notification template not found: "+name, 500)
+	}
+
+	rendered := &Rendered{}
+	var err error
+	switch channel {
+	case ChannelSMS:
+		if compiled.sms == nil {
+			return nil, lift.NewLiftError("TEMPLATE_NOT_FOUND", "Notification template has no SMS body: "+name, 500)
+		}
+		if rendered.SMS, err = execute(compiled.sms, data); err != nil {
+			return nil, err
+		}
+	default:
+		if compiled.text == nil && compiled.html == nil {
+			return nil, lift.NewLiftError("TEMPLATE_NOT_FOUND", "Notification template has no email body: "+name, 500)
+		}
+		if rendered.Subject, err = execute(compiled.subject, data); err != nil {
+			return nil, err
+		}
+		if rendered.Text, err = execute(compiled.text, data); err != nil {
+			return nil, err
+		}
+		if compiled.html != nil {
+			var buf bytes.Buffer
+			if err := compiled.html.Execute(&buf, data); err != nil {
+				return nil, renderError(err)
+			}
+			rendered.HTML = buf.String()
+		}
+		// Subjects are a single header line
+		rendered.Subject = strings.Join(strings.Fields(rendered.Subject), " ")
+	}
+
+	return rendered, nil
+}
+
+func (t *Templates) has(key string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.templates[key]
+	return ok
+}
+
+func templateKey(tenantID, name string) string {
+	return tenantID + "/" + name
+}
+
+func parseText(name, source string) (*template.Template, error) {
+	if source == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, lift.NewLiftError("INVALID_TEMPLATE", "Failed to parse notification template", 500).WithCause(err)
+	}
+	return tmpl, nil
+}
+
+func execute(tmpl *template.Template, data map[string]any) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", renderError(err)
+	}
+	return buf.String(), nil
+}
+
+func renderError(err error) error {
+	return lift.NewLiftError("TEMPLATE_RENDER_ERROR", "Failed to render notification template", 500).WithCause(err)
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Throttler limits how quickly notifications are sent. Keys are
+// "<tenant>:<channel>".
+type Throttler interface {
+	Allow(ctx context.Context, key string) (bool, error)
+}
+
+// RateLimit is a token bucket rate
+type RateLimit struct {
+	// PerSecond is the sustained send rate
+	PerSecond float64
+	// Burst is the number of sends allowed at once
+	Burst int
+}
+
+// MemoryThrottler is a per-key token bucket held in memory. Limits apply per
+// Lambda instance; use a shared store when a global limit is required.
+type MemoryThrottler struct {
+	defaultLimit RateLimit
+	limits       map[string]RateLimit
+	now          func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryThrottler creates a throttler applying limit to every key. limits
+// overrides the rate for specific keys, e.g. {"tenant-1:sms": {...}}.
+func NewMemoryThrottler(limit RateLimit, limits map[string]RateLimit) *MemoryThrottler {
+	return &MemoryThrottler{
+		defaultLimit: limit,
+		limits:       limits,
+		now:          time.Now,
+		buckets:      make(map[string]*bucket),
+	}
+}
+
+// Allow implements Throttler
+func (t *MemoryThrottler) Allow(ctx context.Context, key string) (bool, error) {
+	limit, ok := t.limits[key]
+	if !ok {
+		limit = t.defaultLimit
+	}
+	if limit.PerSecond <= 0 {
+		return true, nil
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	b, ok := t.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		t.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * limit.PerSecond
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, nil
+	}
+	b.tokens--
+	return true, nil
+}
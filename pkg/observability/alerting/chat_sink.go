@@ -0,0 +1,480 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// ChannelTypeTeams is the channel type of a ChatSink posting to Microsoft Teams
+const ChannelTypeTeams ChannelType = "teams"
+
+// OperationalEventType identifies a kind of operational event
+type OperationalEventType string
+
+const (
+	EventCircuitBreakerOpened OperationalEventType = "circuit_breaker.opened"
+	EventCircuitBreakerClosed OperationalEventType = "circuit_breaker.closed"
+	EventDLQDepth             OperationalEventType = "dlq.depth"
+	EventBreachDetected       OperationalEventType = "security.breach_detected"
+	EventDeployCompleted      OperationalEventType = "deploy.completed"
+	EventAlert                OperationalEventType = "alert"
+)
+
+// OperationalEvent is a structured event worth telling the on-call channel about
+type OperationalEvent struct {
+	Type     OperationalEventType
+	Severity AlertSeverity
+	// Title and Message are used as-is when no template is configured for Type
+	Title   string
+	Message string
+	// Key distinguishes events of the same type for rate limiting, e.g. the
+	// breaker or queue name
+	Key string
+	// Fields are shown as name/value pairs under the message
+	Fields map[string]string
+	// Data is available to templates as .Data
+	Data        map[string]any
+	Service     string
+	Environment string
+	Timestamp   time.Time
+}
+
+// ChatFormat selects the webhook payload format
+type ChatFormat string
+
+const (
+	ChatFormatSlack ChatFormat = "slack"
+	ChatFormatTeams ChatFormat = "teams"
+)
+
+// ChatTemplate holds text/template sources for an event's title and message.
+// Templates are executed with the OperationalEvent, so they can use .Key,
+// .Service, .Data.depth and so on.
+type ChatTemplate struct {
+	Title   string
+	Message string
+}
+
+// DefaultChatTemplates are used for event types without a configured template
+var DefaultChatTemplates = map[OperationalEventType]ChatTemplate{
+	EventCircuitBreakerOpened: {
+		Title:   "Circuit breaker opened: {{.Key}}",
+		Message: "Calls to {{.Key}} are failing fast{{with .Data.failures}} after {{.}} consecutive failures{{end}}.",
+	},
+	EventCircuitBreakerClosed: {
+		Title:   "Circuit breaker closed: {{.Key}}",
+		Message: "Calls to {{.Key}} have recovered.",
+	},
+	EventDLQDepth: {
+		Title:   "Dead-letter queue backing up: {{.Key}}",
+		Message: "{{with .Data.depth}}{{.}} messages{{else}}Messages{{end}} waiting in {{.Key}}{{with .Data.threshold}} (threshold {{.}}){{end}}.",
+	},
+	EventBreachDetected: {
+		Title:   "Security breach detected{{with .Key}}: {{.}}{{end}}",
+		Message: "{{.Message}}",
+	},
+	EventDeployCompleted: {
+		Title:   "Deploy completed: {{.Service}}{{with .Data.version}} {{.}}{{end}}",
+		Message: "{{with .Environment}}Deployed to {{.}}.{{end}}{{with .Message}} {{.}}{{end}}",
+	},
+}
+
+// ChatSinkConfig configures a ChatSink
+type ChatSinkConfig struct {
+	// WebhookURL is the Slack incoming webhook or Teams connector URL
+	WebhookURL string
+	Format     ChatFormat
+	// Service and Environment label every message (defaults: AWS_LAMBDA_FUNCTION_NAME and STAGE)
+	Service     string
+	Environment string
+	// Templates override DefaultChatTemplates per event type
+	Templates map[OperationalEventType]ChatTemplate
+	// MinSeverity drops less severe events (default: info)
+	MinSeverity AlertSeverity
+	// RateLimit is the number of messages per event type and key allowed in
+	// each RateWindow (default: 1). Events over the limit are counted and
+	// reported with the next message that is sent.
+	RateLimit  int
+	RateWindow time.Duration // default: 5 minutes
+	// Timeout for each webhook request (default: 5 seconds)
+	Timeout    time.Duration
+	HTTPClient HTTPClient
+	// Now overrides the clock, for tests
+	Now func() time.Time
+}
+
+// HTTPClient defines the HTTP operations used by the sink
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ChatSink posts operational events to a Slack or Teams webhook. It also
+// implements AlertChannel so it can deliver AlertManager alerts.
+type ChatSink struct {
+	config    ChatSinkConfig
+	client    HTTPClient
+	templates map[OperationalEventType]*chatTemplate
+
+	mu     sync.Mutex
+	limits map[string]*chatRateState
+}
+
+type chatTemplate struct {
+	title   *template.Template
+	message *template.Template
+}
+
+type chatRateState struct {
+	windowStart time.Time
+	sent        int
+	suppressed  int
+}
+
+// NewChatSink creates a sink from configuration
+func NewChatSink(config ChatSinkConfig) (*ChatSink, error) {
+	if config.Format == "" {
+		config.Format = ChatFormatSlack
+	}
+	if config.Service == "" {
+		config.Service = os.Getenv("AWS_LAMBDA_FUNCTION_NAME")
+	}
+	if config.Environment == "" {
+		config.Environment = os.Getenv("STAGE")
+	}
+	if config.MinSeverity == "" {
+		config.MinSeverity = AlertSeverityInfo
+	}
+	if config.RateLimit <= 0 {
+		config.RateLimit = 1
+	}
+	if config.RateWindow <= 0 {
+		config.RateWindow = 5 * time.Minute
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	sink := &ChatSink{
+		config:    config,
+		client:    config.HTTPClient,
+		templates: make(map[OperationalEventType]*chatTemplate),
+		limits:    make(map[string]*chatRateState),
+	}
+	if err := sink.Validate(); err != nil {
+		return nil, err
+	}
+	if sink.client == nil {
+		sink.client = &http.Client{Timeout: config.Timeout}
+	}
+
+	sources := make(map[OperationalEventType]ChatTemplate, len(DefaultChatTemplates)+len(config.Templates))
+	for eventType, tmpl := range DefaultChatTemplates {
+		sources[eventType] = tmpl
+	}
+	for eventType, tmpl := range config.Templates {
+		sources[eventType] = tmpl
+	}
+	for eventType, source := range sources {
+		parsed, err := parseChatTemplate(eventType, source)
+		if err != nil {
+			return nil, err
+		}
+		sink.templates[eventType] = parsed
+	}
+
+	return sink, nil
+}
+
+// Notify posts an event to the webhook. Events below MinSeverity or over the
+// rate limit are dropped without error.
+func (s *ChatSink) Notify(ctx context.Context, event *OperationalEvent) error {
+	if severityRank(event.Severity) < severityRank(s.config.MinSeverity) {
+		return nil
+	}
+
+	e := *event
+	if e.Severity == "" {
+		e.Severity = AlertSeverityInfo
+	}
+	if e.Service == "" {
+		e.Service = s.config.Service
+	}
+	if e.Environment == "" {
+		e.Environment = s.config.Environment
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = s.config.Now()
+	}
+
+	allowed, suppressed := s.allow(string(e.Type) + "|" + e.Key)
+	if !allowed {
+		return nil
+	}
+
+	title, message, err := s.render(&e)
+	if err != nil {
+		return err
+	}
+	if suppressed > 0 {
+		message = strings.TrimSpace(fmt.Sprintf("%s\n_%d similar event(s) suppressed by rate limiting_", message, suppressed))
+	}
+
+	var payload any
+	if s.config.Format == ChatFormatTeams {
+		payload = teamsPayload(&e, title, message)
+	} else {
+		payload = slackPayload(&e, title, message)
+	}
+	return s.post(ctx, payload)
+}
+
+// Send implements AlertChannel
+func (s *ChatSink) Send(ctx context.Context, alert *Alert) error {
+	fields := make(map[string]string, len(alert.Labels)+2)
+	for k, v := range alert.Labels {
+		fields[k] = v
+	}
+	fields["value"] = fmt.Sprintf("%g", alert.Value)
+	fields["threshold"] = fmt.Sprintf("%g", alert.Threshold)
+
+	return s.Notify(ctx, &OperationalEvent{
+		Type:      EventAlert,
+		Severity:  alert.Severity,
+		Title:     alert.Name,
+		Message:   alert.Description,
+		Key:       alert.RuleID,
+		Fields:    fields,
+		Timestamp: alert.StartTime,
+	})
+}
+
+// Validate implements AlertChannel
+func (s *ChatSink) Validate() error {
+	u, err := url.Parse(s.config.WebhookURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("chat webhook URL must be an https URL")
+	}
+	if s.config.Format != ChatFormatSlack && s.config.Format != ChatFormatTeams {
+		return fmt.Errorf("unsupported chat format: %s", s.config.Format)
+	}
+	return nil
+}
+
+// GetType implements AlertChannel
+func (s *ChatSink) GetType() ChannelType {
+	if s.config.Format == ChatFormatTeams {
+		return ChannelTypeTeams
+	}
+	return ChannelTypeSlack
+}
+
+// GetConfig implements AlertChannel. The webhook URL is a secret and is omitted.
+func (s *ChatSink) GetConfig() map[string]any {
+	return map[string]any{
+		"format":       string(s.config.Format),
+		"service":      s.config.Service,
+		"environment":  s.config.Environment,
+		"min_severity": string(s.config.MinSeverity),
+		"rate_limit":   s.config.RateLimit,
+		"rate_window":  s.config.RateWindow.String(),
+	}
+}
+
+// allow applies the rate limit for a key and returns how many events were
+// suppressed since the last message that was sent
+func (s *ChatSink) allow(key string) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.config.Now()
+	state, ok := s.limits[key]
+	if !ok {
+		s.prune(now)
+		state = &chatRateState{windowStart: now}
+		s.limits[key] = state
+	} else if now.Sub(state.windowStart) >= s.config.RateWindow {
+		state.windowStart = now
+		state.sent = 0
+	}
+
+	if state.sent >= s.config.RateLimit {
+		state.suppressed++
+		return false, 0
+	}
+
+	state.sent++
+	suppressed := state.suppressed
+	state.suppressed = 0
+	return true, suppressed
+}
+
+// prune drops rate limit state with nothing pending once the window has passed
+func (s *ChatSink) prune(now time.Time) {
+	for key, state := range s.limits {
+		if state.suppressed == 0 && now.Sub(state.windowStart) >= s.config.RateWindow {
+			delete(s.limits, key)
+		}
+	}
+}
+
+func (s *ChatSink) render(e *OperationalEvent) (string, string, error) {
+	tmpl, ok := s.templates[e.Type]
+	if !ok {
+		title := e.Title
+		if title == "" {
+			title = string(e.Type)
+		}
+		return title, e.Message, nil
+	}
+
+	var title, message bytes.Buffer
+	if err := tmpl.title.Execute(&title, e); err != nil {
+		return "", "", fmt.Errorf("failed to render %s title: %w", e.Type, err)
+	}
+	if err := tmpl.message.Execute(&message, e); err != nil {
+		return "", "", fmt.Errorf("failed to render %s message: %w", e.Type, err)
+	}
+	return strings.TrimSpace(title.String()), strings.TrimSpace(message.String()), nil
+}
+
+func (s *ChatSink) post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post chat message: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("chat webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func parseChatTemplate(eventType OperationalEventType, source ChatTemplate) (*chatTemplate, error) {
+	title, err := template.New(string(eventType) + ".title").Parse(source.Title)
+	if err != nil {
+		return nil, fmt.Errorf("invalid title template for %s: %w", eventType, err)
+	}
+	message, err := template.New(string(eventType) + ".message").Parse(source.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template for %s: %w", eventType, err)
+	}
+	return &chatTemplate{title: title, message: message}, nil
+}
+
+func severityRank(severity AlertSeverity) int {
+	switch severity {
+	case AlertSeverityWarning:
+		return 1
+	case AlertSeverityError:
+		return 2
+	case AlertSeverityCritical:
+		return 3
+	default:
+		return 0
+	}
+}
+
+func severityColor(severity AlertSeverity) string {
+	switch severity {
+	case AlertSeverityWarning:
+		return "#DAA038"
+	case AlertSeverityError:
+		return "#E01E5A"
+	case AlertSeverityCritical:
+		return "#8B0000"
+	default:
+		return "#439FE0"
+	}
+}
+
+// sortedFields returns field names in a stable order
+func sortedFields(fields map[string]string) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func footer(e *OperationalEvent) string {
+	parts := []string{string(e.Severity)}
+	if e.Service != "" {
+		parts = append(parts, e.Service)
+	}
+	if e.Environment != "" {
+		parts = append(parts, e.Environment)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// slackPayload builds an incoming webhook message with a colored attachment
+func slackPayload(e *OperationalEvent, title, message string) map[string]any {
+	fields := make([]map[string]any, 0, len(e.Fields))
+	for _, name := range sortedFields(e.Fields) {
+		fields = append(fields, map[string]any{"title": name, "value": e.Fields[name], "short": true})
+	}
+
+	return map[string]any{
+		"text": title,
+		"attachments": []map[string]any{{
+			"color":  severityColor(e.Severity),
+			"title":  title,
+			"text":   message,
+			"fields": fields,
+			"footer": footer(e),
+			"ts":     e.Timestamp.Unix(),
+		}},
+	}
+}
+
+// teamsPayload builds a connector MessageCard
+func teamsPayload(e *OperationalEvent, title, message string) map[string]any {
+	facts := make([]map[string]string, 0, len(e.Fields))
+	for _, name := range sortedFields(e.Fields) {
+		facts = append(facts, map[string]string{"name": name, "value": e.Fields[name]})
+	}
+
+	return map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"themeColor": strings.TrimPrefix(severityColor(e.Severity), "#"),
+		"summary":    title,
+		"title":      title,
+		"text":       message,
+		"sections": []map[string]any{{
+			"facts":            facts,
+			"activitySubtitle": footer(e) + " · " + e.Timestamp.UTC().Format(time.RFC3339),
+		}},
+	}
+}
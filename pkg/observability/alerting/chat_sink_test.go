@@ -0,0 +1,159 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHTTPClient struct {
+	bodies []map[string]any
+	status int
+}
+
+func (c *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	raw, _ := io.ReadAll(req.Body)
+	var body map[string]any
+	_ = json.Unmarshal(raw, &body)
+	c.bodies = append(c.bodies, body)
+	return &http.Response{
+		StatusCode: c.status,
+		Body:       io.NopCloser(strings.NewReader("ok")),
+	}, nil
+}
+
+func newTestChatSink(t *testing.T, client *recordingHTTPClient, now *time.Time, config ChatSinkConfig) *ChatSink {
+	config.WebhookURL = "https://hooks.slack.com/services/T000/B000/XXXX"
+	config.Service = "payments"
+	config.Environment = "prod"
+	config.HTTPClient = client
+	config.Now = func() time.Time { return *now }
+	sink, err := NewChatSink(config)
+	require.NoError(t, err)
+	return sink
+}
+
+func TestChatSinkSlack(t *testing.T) {
+	client := &recordingHTTPClient{status: 200}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sink := newTestChatSink(t, client, &now, ChatSinkConfig{})
+
+	err := sink.Notify(context.Background(), &OperationalEvent{
+		Type:     EventDLQDepth,
+		Severity: AlertSeverityWarning,
+		Key:      "settlements-dlq",
+		Data:     map[string]any{"depth": 1200, "threshold": 100},
+		Fields:   map[string]string{"region": "us-east-1", "account": "123"},
+	})
+	require.NoError(t, err)
+	require.Len(t, client.bodies, 1)
+
+	body := client.bodies[0]
+	assert.Equal(t, "Dead-letter queue backing up: settlements-dlq", body["text"])
+	attachment := body["attachments"].([]any)[0].(map[string]any)
+	assert.Equal(t, "1200 messages waiting in settlements-dlq (threshold 100).", attachment["text"])
+	assert.Equal(t, "#DAA038", attachment["color"])
+	assert.Equal(t, "warning · payments · prod", attachment["footer"])
+	fields := attachment["fields"].([]any)
+	require.Len(t, fields, 2)
+	assert.Equal(t, "account", fields[0].(map[string]any)["title"])
+}
+
+func TestChatSinkTeams(t *testing.T) {
+	client := &recordingHTTPClient{status: 200}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sink := newTestChatSink(t, client, &now, ChatSinkConfig{
+		Format: ChatFormatTeams,
+		Templates: map[OperationalEventType]ChatTemplate{
+			EventDeployCompleted: {Title: "Shipped {{.Data.version}}", Message: "by {{.Data.actor}}"},
+		},
+	})
+	assert.Equal(t, ChannelTypeTeams, sink.GetType())
+	assert.NotContains(t, sink.GetConfig(), "webhook_url")
+
+	require.NoError(t, sink.Notify(context.Background(), &OperationalEvent{
+		Type: EventDeployCompleted,
+		Data: map[string]any{"version": "v1.4.2", "actor": "ci"},
+	}))
+
+	body := client.bodies[0]
+	assert.Equal(t, "MessageCard", body["@type"])
+	assert.Equal(t, "Shipped v1.4.2", body["title"])
+	assert.Equal(t, "by ci", body["text"])
+	assert.Equal(t, "439FE0", body["themeColor"])
+}
+
+func TestChatSinkRateLimit(t *testing.T) {
+	client := &recordingHTTPClient{status: 200}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sink := newTestChatSink(t, client, &now, ChatSinkConfig{RateWindow: time.Minute})
+
+	opened := func(key string) *OperationalEvent {
+		return &OperationalEvent{Type: EventCircuitBreakerOpened, Severity: AlertSeverityError, Key: key}
+	}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, sink.Notify(context.Background(), opened("card-processor")))
+	}
+	// Other keys have their own limit
+	require.NoError(t, sink.Notify(context.Background(), opened("bank-api")))
+	require.Len(t, client.bodies, 2)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, sink.Notify(context.Background(), opened("card-processor")))
+	require.Len(t, client.bodies, 3)
+
+	attachment := client.bodies[2]["attachments"].([]any)[0].(map[string]any)
+	assert.Contains(t, attachment["text"], "2 similar event(s) suppressed")
+}
+
+func TestChatSinkFiltersAndErrors(t *testing.T) {
+	client := &recordingHTTPClient{status: 500}
+	now := time.Now()
+	sink := newTestChatSink(t, client, &now, ChatSinkConfig{MinSeverity: AlertSeverityError})
+
+	require.NoError(t, sink.Notify(context.Background(), &OperationalEvent{Type: "cache.miss", Severity: AlertSeverityWarning}))
+	assert.Empty(t, client.bodies)
+
+	err := sink.Notify(context.Background(), &OperationalEvent{Type: "custom", Severity: AlertSeverityCritical, Title: "Custom"})
+	assert.EqualError(t, err, "chat webhook returned status 500")
+	assert.Equal(t, "Custom", client.bodies[0]["text"])
+
+	_, err = NewChatSink(ChatSinkConfig{WebhookURL: "http://insecure.example.com"})
+	assert.Error(t, err)
+	_, err = NewChatSink(ChatSinkConfig{
+		WebhookURL: "https://example.com/hook",
+		Templates:  map[OperationalEventType]ChatTemplate{"x": {Title: "{{.Broken"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestChatSinkAlertChannel(t *testing.T) {
+	client := &recordingHTTPClient{status: 200}
+	now := time.Now()
+	sink := newTestChatSink(t, client, &now, ChatSinkConfig{})
+
+	var channel AlertChannel = sink
+	require.NoError(t, channel.Send(context.Background(), &Alert{
+		RuleID:      "high-latency",
+		Name:        "High latency",
+		Description: "p99 above 2s",
+		Severity:    AlertSeverityCritical,
+		Value:       2.4,
+		Threshold:   2,
+		Labels:      map[string]string{"route": "/payments"},
+	}))
+
+	body := client.bodies[0]
+	assert.Equal(t, "High latency", body["text"])
+	attachment := body["attachments"].([]any)[0].(map[string]any)
+	assert.Equal(t, "p99 above 2s", attachment["text"])
+	assert.Len(t, attachment["fields"], 3)
+}
@@ -0,0 +1,371 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/utils/sanitization"
+)
+
+// Error event severity levels
+const (
+	ErrorLevelFatal   = "fatal"
+	ErrorLevelError   = "error"
+	ErrorLevelWarning = "warning"
+)
+
+// ErrorReporter sends errors to an external error tracker such as Sentry
+type ErrorReporter interface {
+	// Report sends a single error event
+	Report(ctx context.Context, event *ErrorEvent) error
+	// Flush waits up to timeout for buffered events to be delivered
+	Flush(timeout time.Duration) error
+}
+
+// ErrorEvent is a tracker-agnostic description of an error
+type ErrorEvent struct {
+	EventID   string    `json:"event_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	// ErrorType is the Go type of the error or panic value
+	ErrorType string `json:"error_type,omitempty"`
+	// Code is the LiftError code, when available
+	Code string `json:"code,omitempty"`
+	// Fingerprint overrides the tracker's default grouping when set
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Stack       string `json:"stack,omitempty"`
+
+	// Request context
+	Route     string `json:"route,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Path      string `json:"path,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	Breadcrumbs []Breadcrumb      `json:"breadcrumbs,omitempty"`
+
+	// Err is the original error, if any
+	Err error `json:"-"`
+}
+
+// NewErrorEvent creates an error event for err enriched with request context.
+// Panics, whether recovered by the Recovery middleware or by the app, are
+// reported as fatal. Messages that didn't come from a LiftError, and the
+// cause, are redacted since they can carry request data.
+func NewErrorEvent(ctx *lift.Context, err error) *ErrorEvent {
+	event := &ErrorEvent{
+		EventID:   newEventID(),
+		Timestamp: time.Now().UTC(),
+		Level:     ErrorLevelError,
+		Tags:      make(map[string]string),
+		Extra:     make(map[string]any),
+		Err:       err,
+	}
+
+	if err != nil {
+		event.Message = sanitization.SanitizeMessage(err.Error())
+		event.ErrorType = fmt.Sprintf("%T", err)
+
+		var liftErr *lift.LiftError
+		var panicErr *lift.PanicError
+		switch {
+		case errors.As(err, &liftErr):
+			event.Message = liftErr.Message
+			event.Code = liftErr.Code
+			event.Stack = liftErr.StackTrace
+			if liftErr.Cause != nil {
+				event.ErrorType = fmt.Sprintf("%T", liftErr.Cause)
+				event.Extra["cause"] = sanitization.SanitizeMessage(liftErr.Cause.Error())
+			}
+			if liftErr.TraceID != "" {
+				event.Tags["trace_id"] = liftErr.TraceID
+			}
+			if liftErr.Code == panicRecoveredCode {
+				event.Level = ErrorLevelFatal
+				event.Fingerprint, _ = liftErr.Details["fingerprint"].(string)
+			}
+		case errors.As(err, &panicErr):
+			event.Level = ErrorLevelFatal
+			event.ErrorType = fmt.Sprintf("%T", panicErr.Value)
+			event.Stack = string(panicErr.Stack)
+		}
+	}
+
+	if ctx != nil {
+		EnrichErrorEvent(ctx, event)
+	}
+
+	return event
+}
+
+// panicRecoveredCode is the code of the error the Recovery middleware
+// returns for a panic
+const panicRecoveredCode = "PANIC_RECOVERED"
+
+// EnrichErrorEvent copies route, tenant, user, request ID and breadcrumbs from the request context
+func EnrichErrorEvent(ctx *lift.Context, event *ErrorEvent) {
+	event.Route = ctx.Route()
+	event.RequestID = ctx.RequestID
+	event.TenantID = ctx.TenantID()
+	event.UserID = ctx.UserID()
+	if ctx.Request != nil {
+		event.Method = ctx.Request.Method
+		event.Path = ctx.Request.Path
+	}
+
+	if event.Tags == nil {
+		event.Tags = make(map[string]string)
+	}
+	if event.Route != "" {
+		event.Tags["route"] = event.Route
+	}
+	if event.TenantID != "" {
+		event.Tags["tenant_id"] = event.TenantID
+	}
+
+	if recorder := BreadcrumbsFromContext(ctx); recorder != nil {
+		event.Breadcrumbs = recorder.Breadcrumbs()
+	}
+}
+
+// ShouldReportError reports whether an error should be sent to the error tracker.
+// Server errors and LiftErrors marked WithLogging are reported; client errors are not.
+func ShouldReportError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var liftErr *lift.LiftError
+	if errors.As(err, &liftErr) {
+		return liftErr.StatusCode >= 500 || liftErr.LogError
+	}
+	return true
+}
+
+// ErrorObserver adapts a reporter for lift.WithErrorObserver so that errors
+// returned from handlers, and panics, are reported with request context. It
+// is the only path errors take to the reporter:
+//
+//	app := lift.New(lift.WithErrorObserver(observability.ErrorObserver(reporter)))
+func ErrorObserver(reporter ErrorReporter) lift.ErrorObserver {
+	return func(ctx *lift.Context, err error) {
+		if !ShouldReportError(err) {
+			return
+		}
+		if reportErr := reporter.Report(ctx.Context, NewErrorEvent(ctx, err)); reportErr != nil && ctx.Logger != nil {
+			ctx.Logger.Warn("Failed to report error", map[string]any{
+				"error": reportErr.Error(),
+			})
+		}
+	}
+}
+
+// SampledReporter forwards a fraction of events to another reporter.
+// Fatal events are always forwarded.
+type SampledReporter struct {
+	reporter ErrorReporter
+	rate     float64
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewSampledReporter creates a reporter that forwards events with the given probability (0-1)
+func NewSampledReporter(reporter ErrorReporter, rate float64) *SampledReporter {
+	if rate < 0 {
+		rate = 0
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	return &SampledReporter{
+		reporter: reporter,
+		rate:     rate,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Report forwards the event if it is sampled
+func (s *SampledReporter) Report(ctx context.Context, event *ErrorEvent) error {
+	if event.Level != ErrorLevelFatal && !s.sample() {
+		return nil
+	}
+	return s.reporter.Report(ctx, event)
+}
+
+// Flush flushes the underlying reporter
+func (s *SampledReporter) Flush(timeout time.Duration) error {
+	return s.reporter.Flush(timeout)
+}
+
+func (s *SampledReporter) sample() bool {
+	if s.rate >= 1 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Float64() < s.rate
+}
+
+// Breadcrumb is a log entry leading up to an error
+type Breadcrumb struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Level     string         `json:"level"`
+	Category  string         `json:"category,omitempty"`
+	Message   string         `json:"message"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+const breadcrumbsKey = "observability_breadcrumbs"
+
+// BreadcrumbRecorder keeps the most recent breadcrumbs for a request
+type BreadcrumbRecorder struct {
+	mu          sync.Mutex
+	breadcrumbs []Breadcrumb
+	max         int
+}
+
+// NewBreadcrumbRecorder creates a recorder that keeps up to max breadcrumbs (default: 50)
+func NewBreadcrumbRecorder(max int) *BreadcrumbRecorder {
+	if max <= 0 {
+		max = 50
+	}
+	return &BreadcrumbRecorder{max: max}
+}
+
+// Add records a breadcrumb, discarding the oldest one when full
+func (r *BreadcrumbRecorder) Add(crumb Breadcrumb) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if crumb.Timestamp.IsZero() {
+		crumb.Timestamp = time.Now().UTC()
+	}
+	if len(r.breadcrumbs) >= r.max {
+		r.breadcrumbs = r.breadcrumbs[1:]
+	}
+	r.breadcrumbs = append(r.breadcrumbs, crumb)
+}
+
+// Breadcrumbs returns a copy of the recorded breadcrumbs, oldest first
+func (r *BreadcrumbRecorder) Breadcrumbs() []Breadcrumb {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Breadcrumb, len(r.breadcrumbs))
+	copy(out, r.breadcrumbs)
+	return out
+}
+
+// WithBreadcrumbs attaches a breadcrumb recorder to the request and wraps the
+// request logger so every log entry is also recorded as a breadcrumb
+func WithBreadcrumbs(ctx *lift.Context, max int) *BreadcrumbRecorder {
+	recorder := NewBreadcrumbRecorder(max)
+	ctx.Set(breadcrumbsKey, recorder)
+	if ctx.Logger != nil {
+		ctx.Logger = &BreadcrumbLogger{Logger: ctx.Logger, recorder: recorder}
+	}
+	return recorder
+}
+
+// BreadcrumbsFromContext returns the request's breadcrumb recorder, if any
+func BreadcrumbsFromContext(ctx *lift.Context) *BreadcrumbRecorder {
+	recorder, _ := ctx.Get(breadcrumbsKey).(*BreadcrumbRecorder)
+	return recorder
+}
+
+// BreadcrumbLogger is a lift.Logger that records each entry as a breadcrumb
+// before passing it on to the wrapped logger
+type BreadcrumbLogger struct {
+	lift.Logger
+	recorder *BreadcrumbRecorder
+	fields   map[string]any
+}
+
+// NewBreadcrumbLogger wraps a logger so that its entries are recorded as breadcrumbs
+func NewBreadcrumbLogger(logger lift.Logger, recorder *BreadcrumbRecorder) *BreadcrumbLogger {
+	return &BreadcrumbLogger{Logger: logger, recorder: recorder}
+}
+
+// Debug logs a debug message
+func (l *BreadcrumbLogger) Debug(message string, fields ...map[string]any) {
+	l.record("debug", message, fields)
+	l.Logger.Debug(message, fields...)
+}
+
+// Info logs an info message
+func (l *BreadcrumbLogger) Info(message string, fields ...map[string]any) {
+	l.record("info", message, fields)
+	l.Logger.Info(message, fields...)
+}
+
+// Warn logs a warning message
+func (l *BreadcrumbLogger) Warn(message string, fields ...map[string]any) {
+	l.record("warning", message, fields)
+	l.Logger.Warn(message, fields...)
+}
+
+// Error logs an error message
+func (l *BreadcrumbLogger) Error(message string, fields ...map[string]any) {
+	l.record("error", message, fields)
+	l.Logger.Error(message, fields...)
+}
+
+// WithField returns a logger with an additional field that keeps recording breadcrumbs
+func (l *BreadcrumbLogger) WithField(key string, value any) lift.Logger {
+	return l.WithFields(map[string]any{key: value})
+}
+
+// WithFields returns a logger with additional fields that keeps recording breadcrumbs
+func (l *BreadcrumbLogger) WithFields(fields map[string]any) lift.Logger {
+	merged := make(map[string]any, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &BreadcrumbLogger{
+		Logger:   l.Logger.WithFields(fields),
+		recorder: l.recorder,
+		fields:   merged,
+	}
+}
+
+// record adds a log entry as a breadcrumb, sanitizing its fields the way
+// the loggers do before they leave the process
+func (l *BreadcrumbLogger) record(level, message string, fields []map[string]any) {
+	var data map[string]any
+	if len(l.fields) > 0 || len(fields) > 0 {
+		data = make(map[string]any)
+		for k, v := range l.fields {
+			data[k] = sanitization.SanitizeFieldValue(k, v)
+		}
+		for _, fieldMap := range fields {
+			for k, v := range fieldMap {
+				data[k] = sanitization.SanitizeFieldValue(k, v)
+			}
+		}
+	}
+	l.recorder.Add(Breadcrumb{
+		Level:    level,
+		Category: "log",
+		Message:  message,
+		Data:     data,
+	})
+}
+
+// newEventID returns a 32 character hex event ID as expected by most trackers
+func newEventID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
@@ -0,0 +1,116 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingReporter captures reported events
+type recordingReporter struct {
+	events []*ErrorEvent
+}
+
+func (r *recordingReporter) Report(ctx context.Context, event *ErrorEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recordingReporter) Flush(timeout time.Duration) error { return nil }
+
+func createErrorTestContext() *lift.Context {
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:      "POST",
+		Path:        "/payments/pay_123",
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+	}))
+	ctx.RequestID = "req_123"
+	ctx.SetRoute("/payments/:id")
+	ctx.SetTenantID("tenant_1")
+	ctx.SetUserID("user_1")
+	ctx.Logger = &lift.NoOpLogger{}
+	return ctx
+}
+
+func TestNewErrorEvent(t *testing.T) {
+	ctx := createErrorTestContext()
+	WithBreadcrumbs(ctx, 10)
+	ctx.Logger.Info("loading payment", map[string]any{"payment_id": "pay_123"})
+	ctx.Logger.WithField("step", "charge").Warn("processor slow")
+	ctx.Logger.Info("authorizing", map[string]any{"auth_token": "tok_secret"})
+
+	err := lift.SystemError("charge failed").WithCause(errors.New("timeout for jane@example.com"))
+	event := NewErrorEvent(ctx, err)
+
+	assert.Equal(t, "charge failed", event.Message)
+	assert.Equal(t, "SYSTEM_ERROR", event.Code)
+	assert.Equal(t, "/payments/:id", event.Route)
+	assert.Equal(t, "/payments/:id", event.Tags["route"])
+	assert.Equal(t, "tenant_1", event.TenantID)
+	assert.Equal(t, "user_1", event.UserID)
+	assert.Equal(t, "req_123", event.RequestID)
+	assert.Equal(t, "timeout for [REDACTED_EMAIL]", event.Extra["cause"])
+	assert.Len(t, event.EventID, 32)
+
+	require.Len(t, event.Breadcrumbs, 3)
+	assert.Equal(t, "loading payment", event.Breadcrumbs[0].Message)
+	assert.Equal(t, "pay_123", event.Breadcrumbs[0].Data["payment_id"])
+	assert.Equal(t, "warning", event.Breadcrumbs[1].Level)
+	assert.Equal(t, "charge", event.Breadcrumbs[1].Data["step"])
+	assert.Equal(t, "[REDACTED]", event.Breadcrumbs[2].Data["auth_token"])
+}
+
+func TestNewErrorEventReportsPanicsAsFatal(t *testing.T) {
+	event := NewErrorEvent(createErrorTestContext(), lift.NewPanicError("password=hunter2", []byte("main.handler()")))
+
+	assert.Equal(t, ErrorLevelFatal, event.Level)
+	assert.Equal(t, "main.handler()", event.Stack)
+	assert.Equal(t, "string", event.ErrorType)
+	assert.NotContains(t, event.Message, "hunter2")
+}
+
+func TestBreadcrumbRecorderDropsOldest(t *testing.T) {
+	recorder := NewBreadcrumbRecorder(2)
+	recorder.Add(Breadcrumb{Message: "one"})
+	recorder.Add(Breadcrumb{Message: "two"})
+	recorder.Add(Breadcrumb{Message: "three"})
+
+	crumbs := recorder.Breadcrumbs()
+	require.Len(t, crumbs, 2)
+	assert.Equal(t, "two", crumbs[0].Message)
+	assert.Equal(t, "three", crumbs[1].Message)
+}
+
+func TestErrorObserver(t *testing.T) {
+	reporter := &recordingReporter{}
+	observer := ErrorObserver(reporter)
+	ctx := createErrorTestContext()
+
+	observer(ctx, lift.NotFound("missing"))
+	observer(ctx, lift.NotFound("missing").WithLogging())
+	observer(ctx, errors.New("boom"))
+
+	require.Len(t, reporter.events, 2)
+	assert.Equal(t, "NOT_FOUND", reporter.events[0].Code)
+	assert.Equal(t, "*errors.errorString", reporter.events[1].ErrorType)
+}
+
+func TestSampledReporter(t *testing.T) {
+	reporter := &recordingReporter{}
+
+	none := NewSampledReporter(reporter, 0)
+	require.NoError(t, none.Report(context.Background(), &ErrorEvent{Level: ErrorLevelError}))
+	require.NoError(t, none.Report(context.Background(), &ErrorEvent{Level: ErrorLevelFatal}))
+	assert.Len(t, reporter.events, 1, "fatal events bypass sampling")
+
+	all := NewSampledReporter(reporter, 1)
+	require.NoError(t, all.Report(context.Background(), &ErrorEvent{Level: ErrorLevelError}))
+	assert.Len(t, reporter.events, 2)
+}
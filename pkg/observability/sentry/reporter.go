@@ -0,0 +1,272 @@
+// Package sentry provides an observability.ErrorReporter that sends events to
+// Sentry (or any Sentry-compatible service) using the store API.
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/observability"
+)
+
+// HTTPClient defines the HTTP operations used by the reporter
+// This interface allows for easy mocking and testing
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Config configures the Sentry reporter
+type Config struct {
+	// DSN is the project DSN, e.g. https://<key>@o0.ingest.sentry.io/<project>
+	DSN string
+	// Environment defaults to the STAGE environment variable
+	Environment string
+	// Release defaults to AWS_LAMBDA_FUNCTION_VERSION
+	Release string
+	// ServerName defaults to AWS_LAMBDA_FUNCTION_NAME
+	ServerName string
+	// Timeout for each request to Sentry (default: 2 seconds)
+	Timeout time.Duration
+	// HTTPClient overrides the default HTTP client
+	HTTPClient HTTPClient
+	// BeforeSend can modify or drop (by returning nil) events before they are sent
+	BeforeSend func(event *observability.ErrorEvent) *observability.ErrorEvent
+}
+
+// Reporter sends error events to Sentry
+type Reporter struct {
+	config    Config
+	storeURL  string
+	publicKey string
+	client    HTTPClient
+}
+
+// NewReporter creates a Sentry reporter from configuration
+func NewReporter(config Config) (*Reporter, error) {
+	storeURL, publicKey, err := parseDSN(config.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Environment == "" {
+		config.Environment = os.Getenv("STAGE")
+	}
+	if config.Release == "" {
+		config.Release = os.Getenv("AWS_LAMBDA_FUNCTION_VERSION")
+	}
+	if config.ServerName == "" {
+		config.ServerName = os.Getenv("AWS_LAMBDA_FUNCTION_NAME")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 2 * time.Second
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	return &Reporter{
+		config:    config,
+		storeURL:  storeURL,
+		publicKey: publicKey,
+		client:    client,
+	}, nil
+}
+
+// Report sends an event to Sentry. Events are sent synchronously because
+// Lambda may freeze the execution environment as soon as the handler returns.
+func (r *Reporter) Report(ctx context.Context, event *observability.ErrorEvent) error {
+	if r.config.BeforeSend != nil {
+		if event = r.config.BeforeSend(event); event == nil {
+			return nil
+		}
+	}
+
+	body, err := json.Marshal(r.buildPayload(event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal sentry event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create sentry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf(
+		"Sentry sentry_version=7, sentry_client=lift-go/1.0, sentry_timestamp=%d, sentry_key=%s",
+		time.Now().Unix(), r.publicKey,
+	))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send sentry event: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Flush is a no-op because events are delivered synchronously
+func (r *Reporter) Flush(timeout time.Duration) error {
+	return nil
+}
+
+// payload is the subset of the Sentry event schema populated by the reporter
+type payload struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Message     string            `json:"message,omitempty"`
+	Transaction string            `json:"transaction,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	User        *user             `json:"user,omitempty"`
+	Request     *request          `json:"request,omitempty"`
+	Exception   *exceptions       `json:"exception,omitempty"`
+	Breadcrumbs *breadcrumbs      `json:"breadcrumbs,omitempty"`
+}
+
+type user struct {
+	ID string `json:"id"`
+}
+
+type request struct {
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+type exceptions struct {
+	Values []exception `json:"values"`
+}
+
+type exception struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type breadcrumbs struct {
+	Values []breadcrumb `json:"values"`
+}
+
+type breadcrumb struct {
+	Timestamp float64        `json:"timestamp"`
+	Level     string         `json:"level,omitempty"`
+	Category  string         `json:"category,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+func (r *Reporter) buildPayload(event *observability.ErrorEvent) *payload {
+	p := &payload{
+		EventID:     event.EventID,
+		Timestamp:   event.Timestamp.UTC().Format(time.RFC3339Nano),
+		Level:       event.Level,
+		Platform:    "go",
+		Logger:      "lift",
+		Message:     event.Message,
+		Transaction: event.Route,
+		Environment: r.config.Environment,
+		Release:     r.config.Release,
+		ServerName:  r.config.ServerName,
+		Tags:        make(map[string]string),
+		Extra:       make(map[string]any),
+	}
+
+	for k, v := range event.Tags {
+		p.Tags[k] = v
+	}
+	for k, v := range event.Extra {
+		p.Extra[k] = v
+	}
+	if event.RequestID != "" {
+		p.Tags["request_id"] = event.RequestID
+	}
+	if event.Code != "" {
+		p.Tags["error_code"] = event.Code
+	}
+	if event.Stack != "" {
+		p.Extra["stack"] = event.Stack
+	}
+	if event.Fingerprint != "" {
+		p.Fingerprint = []string{event.Fingerprint}
+	}
+	if event.UserID != "" {
+		p.User = &user{ID: event.UserID}
+	}
+	if event.Method != "" || event.Path != "" {
+		p.Request = &request{Method: event.Method, URL: event.Path}
+	}
+	if event.ErrorType != "" {
+		p.Exception = &exceptions{Values: []exception{{
+			Type:  event.ErrorType,
+			Value: event.Message,
+		}}}
+	}
+
+	if len(event.Breadcrumbs) > 0 {
+		p.Breadcrumbs = &breadcrumbs{Values: make([]breadcrumb, 0, len(event.Breadcrumbs))}
+		for _, crumb := range event.Breadcrumbs {
+			p.Breadcrumbs.Values = append(p.Breadcrumbs.Values, breadcrumb{
+				Timestamp: float64(crumb.Timestamp.UnixNano()) / float64(time.Second),
+				Level:     crumb.Level,
+				Category:  crumb.Category,
+				Message:   crumb.Message,
+				Data:      crumb.Data,
+			})
+		}
+	}
+
+	return p
+}
+
+// parseDSN converts a DSN into the store endpoint URL and public key
+func parseDSN(dsn string) (string, string, error) {
+	if dsn == "" {
+		return "", "", fmt.Errorf("sentry DSN is required")
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid sentry DSN: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", fmt.Errorf("invalid sentry DSN: missing public key")
+	}
+
+	path := strings.Trim(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	projectID := path[idx+1:]
+	if projectID == "" {
+		return "", "", fmt.Errorf("invalid sentry DSN: missing project ID")
+	}
+
+	prefix := ""
+	if idx >= 0 {
+		prefix = "/" + path[:idx]
+	}
+
+	storeURL := fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, projectID)
+	return storeURL, u.User.Username(), nil
+}
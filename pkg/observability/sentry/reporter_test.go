@@ -0,0 +1,103 @@
+package sentry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/observability"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockHTTPClient records requests and returns a fixed status
+type mockHTTPClient struct {
+	requests []*http.Request
+	bodies   [][]byte
+	status   int
+}
+
+func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	m.requests = append(m.requests, req)
+	m.bodies = append(m.bodies, body)
+	return &http.Response{
+		StatusCode: m.status,
+		Body:       io.NopCloser(strings.NewReader("{}")),
+	}, nil
+}
+
+func TestParseDSN(t *testing.T) {
+	storeURL, key, err := parseDSN("https://abc123@o42.ingest.sentry.io/98765")
+	require.NoError(t, err)
+	assert.Equal(t, "https://o42.ingest.sentry.io/api/98765/store/", storeURL)
+	assert.Equal(t, "abc123", key)
+
+	storeURL, _, err = parseDSN("https://abc123@errors.example.com/sentry/7")
+	require.NoError(t, err)
+	assert.Equal(t, "https://errors.example.com/sentry/api/7/store/", storeURL)
+
+	_, _, err = parseDSN("https://o42.ingest.sentry.io/98765")
+	assert.Error(t, err)
+	_, _, err = parseDSN("")
+	assert.Error(t, err)
+}
+
+func TestReporterReport(t *testing.T) {
+	client := &mockHTTPClient{status: 200}
+	reporter, err := NewReporter(Config{
+		DSN:         "https://abc123@o42.ingest.sentry.io/98765",
+		Environment: "paytheorystudy",
+		HTTPClient:  client,
+	})
+	require.NoError(t, err)
+
+	err = reporter.Report(context.Background(), &observability.ErrorEvent{
+		EventID:     "0123456789abcdef0123456789abcdef",
+		Timestamp:   time.Now(),
+		Level:       observability.ErrorLevelFatal,
+		Message:     "assignment to entry in nil map",
+		ErrorType:   "runtime.plainError",
+		Fingerprint: "f00dfeed",
+		Route:       "/payments/:id",
+		Method:      "POST",
+		UserID:      "user_1",
+		RequestID:   "req_123",
+		Breadcrumbs: []observability.Breadcrumb{{Message: "loading payment", Timestamp: time.Now()}},
+	})
+	require.NoError(t, err)
+	require.Len(t, client.requests, 1)
+	assert.Contains(t, client.requests[0].Header.Get("X-Sentry-Auth"), "sentry_key=abc123")
+
+	var sent map[string]any
+	require.NoError(t, json.Unmarshal(client.bodies[0], &sent))
+	assert.Equal(t, "fatal", sent["level"])
+	assert.Equal(t, "/payments/:id", sent["transaction"])
+	assert.Equal(t, "paytheorystudy", sent["environment"])
+	assert.Equal(t, []any{"f00dfeed"}, sent["fingerprint"])
+	assert.Equal(t, "user_1", sent["user"].(map[string]any)["id"])
+	assert.Equal(t, "req_123", sent["tags"].(map[string]any)["request_id"])
+	assert.Len(t, sent["breadcrumbs"].(map[string]any)["values"], 1)
+}
+
+func TestReporterErrors(t *testing.T) {
+	client := &mockHTTPClient{status: 429}
+	reporter, err := NewReporter(Config{DSN: "https://abc123@sentry.example.com/1", HTTPClient: client})
+	require.NoError(t, err)
+
+	err = reporter.Report(context.Background(), &observability.ErrorEvent{Message: "boom"})
+	assert.Error(t, err)
+
+	dropping, err := NewReporter(Config{
+		DSN:        "https://abc123@sentry.example.com/1",
+		HTTPClient: client,
+		BeforeSend: func(event *observability.ErrorEvent) *observability.ErrorEvent { return nil },
+	})
+	require.NoError(t, err)
+	assert.NoError(t, dropping.Report(context.Background(), &observability.ErrorEvent{Message: "boom"}))
+	assert.Len(t, client.requests, 1)
+}
@@ -0,0 +1,300 @@
+package observability
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// TenantDimensionConfig configures how tenant IDs are attached to metrics
+type TenantDimensionConfig struct {
+	// TopN is the number of tenants that get their own dimension value (default: 50)
+	TopN int
+	// OtherValue is the dimension value used for long-tail tenants (default: "other")
+	OtherValue string
+	// DimensionName is the tag key for the tenant dimension (default: "tenant_id")
+	DimensionName string
+	// Metrics lists the metric names that carry the tenant dimension. A trailing
+	// "*" matches by prefix. Empty means every metric.
+	Metrics []string
+	// AlwaysTrack lists tenants that always get their own dimension value and
+	// don't count against TopN
+	AlwaysTrack []string
+	// Window is how often the top-N set is recomputed from request counts (default: 1h)
+	Window time.Duration
+	// MaxTracked bounds the number of tenants counted per window (default: 10 * TopN)
+	MaxTracked int
+	// Now overrides the clock, for tests
+	Now func() time.Time
+}
+
+// TenantCount is a tenant and the number of requests seen for it
+type TenantCount struct {
+	TenantID string `json:"tenant_id"`
+	Count    int64  `json:"count"`
+}
+
+// TenantCardinalityGuard decides which tenants get their own metric dimension
+// value. The busiest TopN tenants from the previous window keep their IDs and
+// every other tenant is bucketed into OtherValue, which keeps the number of
+// CloudWatch metric streams bounded no matter how many tenants there are.
+type TenantCardinalityGuard struct {
+	config TenantDimensionConfig
+
+	mu          sync.Mutex
+	counts      map[string]int64
+	admitted    map[string]bool
+	always      map[string]bool
+	windowStart time.Time
+	seeded      bool
+}
+
+// NewTenantCardinalityGuard creates a guard with the given configuration
+func NewTenantCardinalityGuard(config TenantDimensionConfig) *TenantCardinalityGuard {
+	if config.TopN <= 0 {
+		config.TopN = 50
+	}
+	if config.OtherValue == "" {
+		config.OtherValue = "other"
+	}
+	if config.DimensionName == "" {
+		config.DimensionName = "tenant_id"
+	}
+	if config.Window <= 0 {
+		config.Window = time.Hour
+	}
+	if config.MaxTracked <= 0 {
+		config.MaxTracked = config.TopN * 10
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	always := make(map[string]bool, len(config.AlwaysTrack))
+	for _, tenantID := range config.AlwaysTrack {
+		always[tenantID] = true
+	}
+
+	return &TenantCardinalityGuard{
+		config:      config,
+		counts:      make(map[string]int64),
+		admitted:    make(map[string]bool),
+		always:      always,
+		windowStart: config.Now(),
+	}
+}
+
+// Track records a request for the tenant and returns its dimension value
+func (g *TenantCardinalityGuard) Track(tenantID string) string {
+	if tenantID == "" {
+		return ""
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.rotate()
+	g.count(tenantID)
+
+	// Until the first window closes there's no ranking, so the first TopN
+	// tenants seen are admitted
+	if !g.seeded && !g.admitted[tenantID] && !g.always[tenantID] && len(g.admitted) < g.config.TopN {
+		g.admitted[tenantID] = true
+	}
+
+	return g.dimension(tenantID)
+}
+
+// tenantDimensionKey holds the guard and dimension a request was tracked with
+const tenantDimensionKey = "observability_tenant_dimension"
+
+type trackedTenant struct {
+	guard     *TenantCardinalityGuard
+	dimension string
+}
+
+// TrackRequest is Track for the request's tenant, recorded once per request.
+// Every middleware that tags metrics with the tenant goes through it, so a
+// request isn't counted twice when more than one of them is installed.
+func (g *TenantCardinalityGuard) TrackRequest(ctx *lift.Context, tenantID string) string {
+	if tracked, ok := ctx.Get(tenantDimensionKey).(trackedTenant); ok && tracked.guard == g {
+		return tracked.dimension
+	}
+
+	dimension := g.Track(tenantID)
+	ctx.Set(tenantDimensionKey, trackedTenant{guard: g, dimension: dimension})
+	return dimension
+}
+
+// Dimension returns the dimension value for the tenant without recording a request
+func (g *TenantCardinalityGuard) Dimension(tenantID string) string {
+	if tenantID == "" {
+		return ""
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.rotate()
+	return g.dimension(tenantID)
+}
+
+// AppliesTo reports whether the metric should carry the tenant dimension
+func (g *TenantCardinalityGuard) AppliesTo(metric string) bool {
+	if len(g.config.Metrics) == 0 {
+		return true
+	}
+	for _, pattern := range g.config.Metrics {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(metric, prefix) {
+				return true
+			}
+		} else if pattern == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// DimensionName returns the tag key used for the tenant dimension
+func (g *TenantCardinalityGuard) DimensionName() string {
+	return g.config.DimensionName
+}
+
+// TopTenants returns the tenants with the most requests in the current window
+func (g *TenantCardinalityGuard) TopTenants(n int) []TenantCount {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return topTenants(g.counts, n)
+}
+
+func (g *TenantCardinalityGuard) dimension(tenantID string) string {
+	if g.always[tenantID] || g.admitted[tenantID] {
+		return tenantID
+	}
+	return g.config.OtherValue
+}
+
+// count increments the tenant's request count. When the table is full the
+// least-counted tenant is evicted and the newcomer inherits its count, so a
+// tenant that becomes busy late in a window can still overtake the long tail.
+func (g *TenantCardinalityGuard) count(tenantID string) {
+	if _, ok := g.counts[tenantID]; ok {
+		g.counts[tenantID]++
+		return
+	}
+
+	var inherited int64
+	if len(g.counts) >= g.config.MaxTracked {
+		minID, minCount := "", int64(-1)
+		for id, c := range g.counts {
+			if minCount < 0 || c < minCount {
+				minID, minCount = id, c
+			}
+		}
+		delete(g.counts, minID)
+		inherited = minCount
+	}
+	g.counts[tenantID] = inherited + 1
+}
+
+// rotate recomputes the admitted set once the window has elapsed
+func (g *TenantCardinalityGuard) rotate() {
+	now := g.config.Now()
+	if now.Sub(g.windowStart) < g.config.Window {
+		return
+	}
+
+	admitted := make(map[string]bool, g.config.TopN)
+	ranked := topTenants(g.counts, len(g.counts))
+	for _, tc := range ranked {
+		if len(admitted) >= g.config.TopN {
+			break
+		}
+		if !g.always[tc.TenantID] {
+			admitted[tc.TenantID] = true
+		}
+	}
+
+	g.admitted = admitted
+	g.counts = make(map[string]int64)
+	g.windowStart = now
+	g.seeded = true
+}
+
+func topTenants(counts map[string]int64, n int) []TenantCount {
+	ranked := make([]TenantCount, 0, len(counts))
+	for id, c := range counts {
+		ranked = append(ranked, TenantCount{TenantID: id, Count: c})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].TenantID < ranked[j].TenantID
+	})
+	if n >= 0 && len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// TenantMetrics wraps a collector so that configured metrics are tagged with
+// the tenant's guarded dimension value
+func TenantMetrics(collector lift.MetricsCollector, guard *TenantCardinalityGuard, tenantID string) lift.MetricsCollector {
+	return &tenantMetricsCollector{
+		collector: collector,
+		guard:     guard,
+		value:     guard.Dimension(tenantID),
+	}
+}
+
+type tenantMetricsCollector struct {
+	collector lift.MetricsCollector
+	guard     *TenantCardinalityGuard
+	value     string
+}
+
+func (c *tenantMetricsCollector) Counter(name string, tags ...map[string]string) lift.Counter {
+	return c.collector.Counter(name, c.tags(name, tags)...)
+}
+
+func (c *tenantMetricsCollector) Histogram(name string, tags ...map[string]string) lift.Histogram {
+	return c.collector.Histogram(name, c.tags(name, tags)...)
+}
+
+func (c *tenantMetricsCollector) Gauge(name string, tags ...map[string]string) lift.Gauge {
+	return c.collector.Gauge(name, c.tags(name, tags)...)
+}
+
+func (c *tenantMetricsCollector) Flush() error {
+	return c.collector.Flush()
+}
+
+// tags merges the caller's tags and sets the tenant dimension. A raw tenant ID
+// supplied by the caller is replaced so it can't bypass the guard.
+func (c *tenantMetricsCollector) tags(name string, tags []map[string]string) []map[string]string {
+	key := c.guard.DimensionName()
+	applies := c.value != "" && c.guard.AppliesTo(name)
+
+	merged := make(map[string]string)
+	for _, t := range tags {
+		for k, v := range t {
+			merged[k] = v
+		}
+	}
+	if _, ok := merged[key]; !ok && !applies {
+		return tags
+	}
+
+	if applies {
+		merged[key] = c.value
+	} else {
+		delete(merged, key)
+	}
+	return []map[string]string{merged}
+}
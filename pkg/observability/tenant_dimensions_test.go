@@ -0,0 +1,116 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedMetric struct {
+	name string
+	tags map[string]string
+}
+
+type recordingCollector struct {
+	recorded []recordedMetric
+}
+
+func (c *recordingCollector) record(name string, tags []map[string]string) {
+	merged := make(map[string]string)
+	for _, t := range tags {
+		for k, v := range t {
+			merged[k] = v
+		}
+	}
+	c.recorded = append(c.recorded, recordedMetric{name: name, tags: merged})
+}
+
+func (c *recordingCollector) Counter(name string, tags ...map[string]string) lift.Counter {
+	c.record(name, tags)
+	return &lift.NoOpCounter{}
+}
+
+func (c *recordingCollector) Histogram(name string, tags ...map[string]string) lift.Histogram {
+	c.record(name, tags)
+	return &lift.NoOpHistogram{}
+}
+
+func (c *recordingCollector) Gauge(name string, tags ...map[string]string) lift.Gauge {
+	c.record(name, tags)
+	return &lift.NoOpGauge{}
+}
+
+func (c *recordingCollector) Flush() error { return nil }
+
+func TestTenantCardinalityGuardBucketsLongTail(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := NewTenantCardinalityGuard(TenantDimensionConfig{
+		TopN:        2,
+		AlwaysTrack: []string{"vip"},
+		Now:         func() time.Time { return now },
+	})
+
+	assert.Equal(t, "a", guard.Track("a"))
+	assert.Equal(t, "b", guard.Track("b"))
+	assert.Equal(t, "other", guard.Track("c"))
+	assert.Equal(t, "vip", guard.Track("vip"))
+	assert.Equal(t, "", guard.Track(""))
+
+	// c becomes the busiest tenant and is promoted once the window rolls over
+	for i := 0; i < 5; i++ {
+		guard.Track("c")
+	}
+	guard.Track("b")
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, "c", guard.Dimension("c"))
+	assert.Equal(t, "b", guard.Dimension("b"))
+	assert.Equal(t, "other", guard.Dimension("a"))
+	assert.Equal(t, "vip", guard.Dimension("vip"))
+}
+
+func TestTenantCardinalityGuardBoundsTracking(t *testing.T) {
+	guard := NewTenantCardinalityGuard(TenantDimensionConfig{TopN: 1, MaxTracked: 3})
+
+	for i := 0; i < 10; i++ {
+		guard.Track(fmt.Sprintf("tenant-%d", i))
+	}
+
+	assert.Len(t, guard.TopTenants(100), 3)
+}
+
+func TestTrackRequestCountsOncePerRequest(t *testing.T) {
+	guard := NewTenantCardinalityGuard(TenantDimensionConfig{TopN: 1})
+
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(nil))
+	assert.Equal(t, "a", guard.TrackRequest(ctx, "a"))
+	assert.Equal(t, "a", guard.TrackRequest(ctx, "a"), "a second middleware reuses the dimension")
+	assert.Equal(t, []TenantCount{{TenantID: "a", Count: 1}}, guard.TopTenants(1))
+
+	guard.TrackRequest(lift.NewContext(context.Background(), lift.NewRequest(nil)), "a")
+	assert.Equal(t, []TenantCount{{TenantID: "a", Count: 2}}, guard.TopTenants(1))
+}
+
+func TestTenantMetrics(t *testing.T) {
+	guard := NewTenantCardinalityGuard(TenantDimensionConfig{
+		TopN:    1,
+		Metrics: []string{"payments.*", "requests.total"},
+	})
+	guard.Track("big")
+	guard.Track("small")
+
+	collector := &recordingCollector{}
+	TenantMetrics(collector, guard, "big").Counter("payments.created", map[string]string{"method": "card"})
+	TenantMetrics(collector, guard, "small").Histogram("requests.total")
+	TenantMetrics(collector, guard, "small").Gauge("queue.depth", map[string]string{"tenant_id": "small"})
+
+	require.Len(t, collector.recorded, 3)
+	assert.Equal(t, map[string]string{"method": "card", "tenant_id": "big"}, collector.recorded[0].tags)
+	assert.Equal(t, map[string]string{"tenant_id": "other"}, collector.recorded[1].tags)
+	assert.Empty(t, collector.recorded[2].tags)
+}
@@ -0,0 +1,335 @@
+package payload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+const (
+	// HeaderPayloadRef carries the key of an uploaded request payload
+	HeaderPayloadRef = "X-Payload-Ref"
+	// HeaderPayloadOffloaded is set on responses whose body was moved to S3
+	HeaderPayloadOffloaded = "X-Payload-Offloaded"
+	// ContentTypePointer is the content type of an offloaded response
+	ContentTypePointer = "application/vnd.lift.payload+json"
+)
+
+// Defaults sized for API Gateway and Lambda, which cap synchronous payloads at 6MB
+const (
+	DefaultMaxRequestSize    = 50 * 1024 * 1024
+	DefaultResponseThreshold = 5 * 1024 * 1024
+	DefaultURLExpiry         = 15 * time.Minute
+)
+
+// SizeError is returned when a payload exceeds the configured size limit
+type SizeError struct {
+	Size    int64
+	MaxSize int64
+}
+
+// Error implements the error interface
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("payload size %d exceeds limit of %d bytes", e.Size, e.MaxSize)
+}
+
+// Policy configures payload offloading
+type Policy struct {
+	// MaxRequestSize is the largest request payload accepted via S3 (default: 50MB)
+	MaxRequestSize int64
+	// ResponseThreshold is the serialized body size above which responses are
+	// offloaded (default: 5MB). A negative value disables response offloading.
+	ResponseThreshold int
+	// AllowedContentTypes restricts uploaded request payloads (default: application/json)
+	AllowedContentTypes []string
+	// URLExpiry is how long presigned URLs remain valid (default: 15m)
+	URLExpiry time.Duration
+	// KeyPrefix is prepended to every object key (default: "payloads/")
+	KeyPrefix string
+	// DeleteAfterRead removes request payloads once they have been loaded
+	DeleteAfterRead bool
+}
+
+// UploadRequest is the body accepted by the upload URL handler
+type UploadRequest struct {
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// UploadTicket tells the client where to upload a large request payload and
+// how to reference it afterwards
+type UploadTicket struct {
+	Key       string            `json:"key"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	MaxSize   int64             `json:"max_size"`
+}
+
+// Pointer replaces the body of an offloaded response
+type Pointer struct {
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Offloader moves oversized request and response bodies through a Store
+type Offloader struct {
+	store  Store
+	policy Policy
+}
+
+// NewOffloader creates an offloader with the given store and policy
+func NewOffloader(store Store, policy Policy) *Offloader {
+	if policy.MaxRequestSize <= 0 {
+		policy.MaxRequestSize = DefaultMaxRequestSize
+	}
+	if policy.ResponseThreshold == 0 {
+		policy.ResponseThreshold = DefaultResponseThreshold
+	}
+	if len(policy.AllowedContentTypes) == 0 {
+		policy.AllowedContentTypes = []string{"application/json"}
+	}
+	if policy.URLExpiry <= 0 {
+		policy.URLExpiry = DefaultURLExpiry
+	}
+	if policy.KeyPrefix == "" {
+		policy.KeyPrefix = "payloads/"
+	}
+
+	return &Offloader{store: store, policy: policy}
+}
+
+// UploadURL issues a presigned upload URL for a request payload
+func (o *Offloader) UploadURL(ctx *lift.Context, req UploadRequest) (*UploadTicket, error) {
+	if req.ContentType == "" {
+		req.ContentType = o.policy.AllowedContentTypes[0]
+	}
+	if !o.allowedContentType(req.ContentType) {
+		return nil, lift.NewLiftError("UNSUPPORTED_PAYLOAD_TYPE", "Payload content type is not allowed", 415).
+			WithDetail("allowed", o.policy.AllowedContentTypes)
+	}
+	if req.Size > o.policy.MaxRequestSize {
+		return nil, payloadTooLarge(req.Size, o.policy.MaxRequestSize)
+	}
+
+	scope, err := o.requestScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := scope + uuid.New().String()
+	uploadURL, err := o.store.PresignPut(ctx.Context, key, req.ContentType, o.policy.URLExpiry)
+	if err != nil {
+		return nil, lift.NewLiftError("PAYLOAD_UPLOAD_ERROR", "Failed to create upload URL", 500).WithCause(err)
+	}
+
+	return &UploadTicket{
+		Key:       key,
+		URL:       uploadURL,
+		Method:    "PUT",
+		Headers:   map[string]string{"Content-Type": req.ContentType},
+		ExpiresAt: time.Now().Add(o.policy.URLExpiry),
+		MaxSize:   o.policy.MaxRequestSize,
+	}, nil
+}
+
+// UploadURLHandler returns a handler that issues upload tickets, for mounting
+// at an endpoint such as POST /payloads
+func (o *Offloader) UploadURLHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		var req UploadRequest
+		if len(ctx.Request.Body) > 0 {
+			if err := json.Unmarshal(ctx.Request.Body, &req); err != nil {
+				return lift.NewLiftError("INVALID_JSON", "Invalid upload request", 400).WithCause(err)
+			}
+		}
+
+		ticket, err := o.UploadURL(ctx, req)
+		if err != nil {
+			return err
+		}
+		return ctx.Created(ticket)
+	})
+}
+
+// Middleware loads referenced request payloads before the handler runs and
+// offloads oversized responses after it returns
+func (o *Offloader) Middleware() lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if err := o.loadRequest(ctx); err != nil {
+				return err
+			}
+
+			if err := next.Handle(ctx); err != nil {
+				return err
+			}
+
+			return o.offloadResponse(ctx)
+		})
+	}
+}
+
+// loadRequest replaces the request body with the referenced payload
+func (o *Offloader) loadRequest(ctx *lift.Context) error {
+	if ctx.Request == nil {
+		return nil
+	}
+	key := ctx.Request.GetHeader(HeaderPayloadRef)
+	if key == "" {
+		return nil
+	}
+
+	// Keys are scoped to the caller so no one else, even in the same
+	// tenant, can load their upload
+	scope, err := o.requestScope(ctx)
+	if err != nil {
+		return err
+	}
+	if id, ok := strings.CutPrefix(key, scope); !ok || uuid.Validate(id) != nil {
+		return lift.NewLiftError("INVALID_PAYLOAD_REF", "Payload reference is not valid for this caller", 403)
+	}
+
+	obj, err := o.store.Get(ctx.Context, key, o.policy.MaxRequestSize)
+	if err != nil {
+		var sizeErr *SizeError
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return lift.NewLiftError("PAYLOAD_NOT_FOUND", "Referenced payload was not found", 400)
+		case errors.As(err, &sizeErr):
+			return payloadTooLarge(sizeErr.Size, sizeErr.MaxSize)
+		default:
+			return lift.NewLiftError("PAYLOAD_LOAD_ERROR", "Failed to load payload", 502).WithCause(err)
+		}
+	}
+
+	if !o.allowedContentType(obj.ContentType) {
+		return lift.NewLiftError("UNSUPPORTED_PAYLOAD_TYPE", "Payload content type is not allowed", 415).
+			WithDetail("allowed", o.policy.AllowedContentTypes)
+	}
+
+	ctx.Request.Body = obj.Body
+	setHeader(ctx.Request.Headers, "Content-Type", obj.ContentType)
+
+	if o.policy.DeleteAfterRead {
+		if err := o.store.Delete(ctx.Context, key); err != nil && ctx.Logger != nil {
+			ctx.Logger.Warn("Failed to delete request payload", map[string]any{
+				"key":   key,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// offloadResponse moves an oversized response body to the store and replaces
+// it with a pointer to a presigned download URL
+func (o *Offloader) offloadResponse(ctx *lift.Context) error {
+	if o.policy.ResponseThreshold < 0 || ctx.Response == nil || ctx.Response.IsStreaming() {
+		return nil
+	}
+
+	body, err := responseBytes(ctx.Response.Body)
+	if err != nil {
+		return lift.NewLiftError("MARSHAL_ERROR", "Failed to marshal response body", 500).WithCause(err)
+	}
+	if len(body) <= o.policy.ResponseThreshold {
+		return nil
+	}
+
+	contentType := ctx.Response.Headers["Content-Type"]
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	key := o.policy.KeyPrefix + "responses/" + uuid.New().String()
+	if err := o.store.Put(ctx.Context, key, body, contentType); err != nil {
+		return lift.NewLiftError("PAYLOAD_STORE_ERROR", "Failed to store response payload", 500).WithCause(err)
+	}
+	url, err := o.store.PresignGet(ctx.Context, key, o.policy.URLExpiry)
+	if err != nil {
+		return lift.NewLiftError("PAYLOAD_STORE_ERROR", "Failed to create download URL", 500).WithCause(err)
+	}
+
+	ctx.Response.Body = Pointer{
+		URL:         url,
+		ContentType: contentType,
+		Size:        int64(len(body)),
+		ExpiresAt:   time.Now().Add(o.policy.URLExpiry),
+	}
+	ctx.Response.IsBase64Encoded = false
+	ctx.Response.Header("Content-Type", ContentTypePointer)
+	ctx.Response.Header(HeaderPayloadOffloaded, "true")
+	return nil
+}
+
+// requestScope returns the key prefix for request payloads uploaded by the
+// caller: their tenant and user. Anonymous callers can't offload requests,
+// since they would share one scope.
+func (o *Offloader) requestScope(ctx *lift.Context) (string, error) {
+	userID := ctx.UserID()
+	if userID == "" {
+		return "", lift.NewLiftError("UNAUTHORIZED", "Offloaded payloads require an authenticated caller", 401)
+	}
+	tenantID := ctx.TenantID()
+	if tenantID == "" {
+		tenantID = "_"
+	}
+	return o.policy.KeyPrefix + "requests/" + url.PathEscape(tenantID) + "/" + url.PathEscape(userID) + "/", nil
+}
+
+func (o *Offloader) allowedContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range o.policy.AllowedContentTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func payloadTooLarge(size, maxSize int64) error {
+	return lift.NewLiftError("PAYLOAD_TOO_LARGE", "Payload exceeds the maximum size", 413).
+		WithDetail("size", size).
+		WithDetail("max_size", maxSize)
+}
+
+// responseBytes serializes a response body the same way the Lambda response does
+func responseBytes(body any) ([]byte, error) {
+	switch v := body.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// setHeader replaces a header regardless of the case it was sent in
+func setHeader(headers map[string]string, name, value string) {
+	if headers == nil {
+		return
+	}
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			delete(headers, key)
+		}
+	}
+	headers[name] = value
+}
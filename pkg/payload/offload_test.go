@@ -0,0 +1,158 @@
+package payload
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps payloads in memory
+type memoryStore struct {
+	objects map[string]*Object
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: make(map[string]*Object)}
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	s.objects[key] = &Object{Body: body, ContentType: contentType, Size: int64(len(body))}
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string, maxSize int64) (*Object, error) {
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if maxSize > 0 && obj.Size > maxSize {
+		return nil, &SizeError{Size: obj.Size, MaxSize: maxSize}
+	}
+	return obj, nil
+}
+
+func (s *memoryStore) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (string, error) {
+	return "https://bucket.s3.amazonaws.com/" + key + "?X-Amz-Signature=put", nil
+}
+
+func (s *memoryStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "https://bucket.s3.amazonaws.com/" + key + "?X-Amz-Signature=get", nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func newPayloadContext(tenantID, userID string, headers map[string]string, body string) *lift.Context {
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  "POST",
+		Path:    "/imports",
+		Headers: headers,
+		Body:    []byte(body),
+	}))
+	if tenantID != "" {
+		ctx.SetTenantID(tenantID)
+	}
+	if userID != "" {
+		ctx.SetUserID(userID)
+	}
+	return ctx
+}
+
+func TestUploadURLHandler(t *testing.T) {
+	offloader := NewOffloader(newMemoryStore(), Policy{MaxRequestSize: 1024})
+
+	ctx := newPayloadContext("tenant-1", "user-1", nil, `{"content_type":"application/json","size":512}`)
+	require.NoError(t, offloader.UploadURLHandler().Handle(ctx))
+	assert.Equal(t, 201, ctx.Response.StatusCode)
+
+	ticket, ok := ctx.Response.Body.(*UploadTicket)
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(ticket.Key, "payloads/requests/tenant-1/user-1/"))
+	assert.Equal(t, "PUT", ticket.Method)
+	assert.Equal(t, "application/json", ticket.Headers["Content-Type"])
+
+	_, err := offloader.UploadURL(ctx, UploadRequest{ContentType: "text/csv"})
+	assert.Equal(t, 415, err.(*lift.LiftError).StatusCode)
+
+	_, err = offloader.UploadURL(ctx, UploadRequest{Size: 2048})
+	assert.Equal(t, 413, err.(*lift.LiftError).StatusCode)
+
+	// Anonymous callers would share a scope, so they can't offload
+	_, err = offloader.UploadURL(newPayloadContext("tenant-1", "", nil, ""), UploadRequest{})
+	assert.Equal(t, 401, err.(*lift.LiftError).StatusCode)
+}
+
+func TestMiddlewareLoadsRequestPayload(t *testing.T) {
+	store := newMemoryStore()
+	offloader := NewOffloader(store, Policy{DeleteAfterRead: true})
+	key := "payloads/requests/tenant-1/user-1/" + uuid.New().String()
+	require.NoError(t, store.Put(context.Background(), key, []byte(`{"rows":3}`), "application/json"))
+
+	var body string
+	handler := offloader.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error {
+		body = string(ctx.Request.Body)
+		return ctx.OK(map[string]string{"status": "ok"})
+	}))
+
+	ctx := newPayloadContext("tenant-1", "user-1", map[string]string{"x-payload-ref": key, "content-type": "text/plain"}, "")
+	require.NoError(t, handler.Handle(ctx))
+	assert.Equal(t, `{"rows":3}`, body)
+	assert.Equal(t, "application/json", ctx.Request.GetHeader("Content-Type"))
+	assert.Empty(t, store.objects)
+
+	// Other tenants, other users of the tenant and anonymous callers
+	// can't load the upload
+	for _, caller := range [][2]string{{"tenant-2", "user-1"}, {"tenant-1", "user-2"}, {"", ""}} {
+		ctx = newPayloadContext(caller[0], caller[1], map[string]string{HeaderPayloadRef: key}, "")
+		err := handler.Handle(ctx)
+		require.Error(t, err)
+		assert.Contains(t, []int{401, 403}, err.(*lift.LiftError).StatusCode, caller)
+	}
+
+	// Keys must be an upload ID directly in the caller's scope
+	ctx = newPayloadContext("tenant-1", "user-1", map[string]string{HeaderPayloadRef: "payloads/requests/tenant-1/user-1/../user-2/" + uuid.New().String()}, "")
+	err := handler.Handle(ctx)
+	require.Error(t, err)
+	assert.Equal(t, 403, err.(*lift.LiftError).StatusCode)
+
+	ctx = newPayloadContext("tenant-1", "user-1", map[string]string{HeaderPayloadRef: key}, "")
+	err = handler.Handle(ctx)
+	require.Error(t, err)
+	assert.Equal(t, "PAYLOAD_NOT_FOUND", err.(*lift.LiftError).Code)
+}
+
+func TestMiddlewareOffloadsLargeResponses(t *testing.T) {
+	store := newMemoryStore()
+	offloader := NewOffloader(store, Policy{ResponseThreshold: 16})
+
+	handler := offloader.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return ctx.OK(map[string]string{"report": strings.Repeat("x", 64)})
+	}))
+
+	ctx := newPayloadContext("", "", nil, "")
+	require.NoError(t, handler.Handle(ctx))
+
+	pointer, ok := ctx.Response.Body.(Pointer)
+	require.True(t, ok)
+	assert.Contains(t, pointer.URL, "payloads/responses/")
+	assert.Equal(t, "application/json", pointer.ContentType)
+	assert.Equal(t, ContentTypePointer, ctx.Response.Headers["Content-Type"])
+	assert.Equal(t, "true", ctx.Response.Headers[HeaderPayloadOffloaded])
+	require.Len(t, store.objects, 1)
+
+	small := offloader.Middleware()(lift.HandlerFunc(func(ctx *lift.Context) error {
+		return ctx.OK("tiny")
+	}))
+	ctx = newPayloadContext("", "", nil, "")
+	require.NoError(t, small.Handle(ctx))
+	assert.Equal(t, "tiny", ctx.Response.Body)
+}
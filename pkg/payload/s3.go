@@ -0,0 +1,133 @@
+package payload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// S3API is the subset of the S3 client used by S3Store
+type S3API interface {
+	PutObject(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, input *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// S3Presigner is the subset of the S3 presign client used to issue URLs
+type S3Presigner interface {
+	PresignPutObject(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+	PresignGetObject(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// S3Store stores payloads in an S3 bucket. Objects should be removed by a
+// bucket lifecycle rule; the store doesn't expire them itself.
+type S3Store struct {
+	client    S3API
+	presigner S3Presigner
+	bucket    string
+}
+
+// NewS3Store creates a store for the given bucket
+func NewS3Store(client *s3.Client, bucket string) *S3Store {
+	return &S3Store{client: client, presigner: s3.NewPresignClient(client), bucket: bucket}
+}
+
+// Put stores body under key
+func (s *S3Store) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(body),
+		ContentType:          aws.String(contentType),
+		ServerSideEncryption: types.ServerSideEncryptionAes256,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store payload %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get loads the object stored under key. Objects larger than maxSize are
+// rejected without reading the body.
+func (s *S3Store) Get(ctx context.Context, key string, maxSize int64) (*Object, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NoSuchKey" || apiErr.ErrorCode() == "NotFound") {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to load payload %s: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	size := aws.ToInt64(out.ContentLength)
+	if maxSize > 0 && size > maxSize {
+		return nil, &SizeError{Size: size, MaxSize: maxSize}
+	}
+
+	reader := out.Body
+	if maxSize > 0 {
+		reader = io.NopCloser(io.LimitReader(out.Body, maxSize+1))
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload %s: %w", key, err)
+	}
+	if maxSize > 0 && int64(len(body)) > maxSize {
+		return nil, &SizeError{Size: int64(len(body)), MaxSize: maxSize}
+	}
+
+	return &Object{
+		Body:        body,
+		ContentType: aws.ToString(out.ContentType),
+		Size:        int64(len(body)),
+	}, nil
+}
+
+// PresignPut returns a URL the client can PUT the payload to. The content type
+// is part of the signature, so the client must send the same Content-Type.
+func (s *S3Store) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (string, error) {
+	req, err := s.presigner.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(key),
+		ContentType:          aws.String(contentType),
+		ServerSideEncryption: types.ServerSideEncryptionAes256,
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+// PresignGet returns a URL the client can GET the payload from
+func (s *S3Store) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	req, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+// Delete removes the object stored under key
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
@@ -0,0 +1,95 @@
+package payload
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/cloudfront/sign"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// ErrIPRestrictionUnsupported is returned when a signer can't enforce an IP restriction
+var ErrIPRestrictionUnsupported = errors.New("signer cannot restrict URLs by IP address")
+
+// S3URLSigner issues S3 presigned GET URLs. S3 presigned URLs can only be
+// limited by expiry, so requests with an IP restriction are rejected; use
+// CloudFrontURLSigner for those.
+type S3URLSigner struct {
+	presigner S3Presigner
+}
+
+// NewS3URLSigner creates a signer, usually from s3.NewPresignClient(client)
+func NewS3URLSigner(presigner S3Presigner) *S3URLSigner {
+	return &S3URLSigner{presigner: presigner}
+}
+
+// SignURL implements lift.URLSigner
+func (s *S3URLSigner) SignURL(ctx context.Context, req *lift.SignedURLRequest) (string, error) {
+	if req.IPAddress != "" {
+		return "", ErrIPRestrictionUnsupported
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(req.Bucket),
+		Key:    aws.String(req.Key),
+	}
+	if req.ContentDisposition != "" {
+		input.ResponseContentDisposition = aws.String(req.ContentDisposition)
+	}
+
+	r, err := s.presigner.PresignGetObject(ctx, input, s3.WithPresignExpires(time.Until(req.Expires)))
+	if err != nil {
+		return "", err
+	}
+	return r.URL, nil
+}
+
+// CloudFrontURLSigner issues CloudFront signed URLs with a custom policy, which
+// supports both expiry and source IP restrictions
+type CloudFrontURLSigner struct {
+	keyID      string
+	privateKey *rsa.PrivateKey
+	// domains maps bucket names to the CloudFront domain serving them
+	domains map[string]string
+}
+
+// NewCloudFrontURLSigner creates a signer for the given key pair. domains maps
+// each bucket to the distribution domain that serves it, e.g.
+// {"merchant-exports": "exports.example.com"}.
+func NewCloudFrontURLSigner(keyID string, privateKey *rsa.PrivateKey, domains map[string]string) *CloudFrontURLSigner {
+	return &CloudFrontURLSigner{keyID: keyID, privateKey: privateKey, domains: domains}
+}
+
+// SignURL implements lift.URLSigner
+func (s *CloudFrontURLSigner) SignURL(ctx context.Context, req *lift.SignedURLRequest) (string, error) {
+	domain, ok := s.domains[req.Bucket]
+	if !ok {
+		return "", fmt.Errorf("no CloudFront domain configured for bucket %s", req.Bucket)
+	}
+
+	u := url.URL{
+		Scheme: "https",
+		Host:   domain,
+		Path:   "/" + strings.TrimPrefix(req.Key, "/"),
+	}
+	if req.ContentDisposition != "" {
+		// Requires the distribution to forward this query string to S3
+		u.RawQuery = url.Values{"response-content-disposition": {req.ContentDisposition}}.Encode()
+	}
+	resource := u.String()
+
+	condition := sign.Condition{DateLessThan: sign.NewAWSEpochTime(req.Expires)}
+	if req.IPAddress != "" {
+		condition.IPAddress = &sign.IPAddress{SourceIP: req.IPAddress}
+	}
+	policy := &sign.Policy{Statements: []sign.Statement{{Resource: resource, Condition: condition}}}
+
+	return sign.NewURLSigner(s.keyID, s.privateKey).SignWithPolicy(resource, policy)
+}
@@ -0,0 +1,51 @@
+package payload
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudFrontURLSigner(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signer := NewCloudFrontURLSigner("KEYID", key, map[string]string{"exports": "exports.example.com"})
+	signed, err := signer.SignURL(context.Background(), &lift.SignedURLRequest{
+		Bucket:    "exports",
+		Key:       "tenant-1/report.csv",
+		Expires:   time.Now().Add(time.Minute),
+		IPAddress: "203.0.113.7/32",
+	})
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "exports.example.com", u.Host)
+	assert.Equal(t, "/tenant-1/report.csv", u.Path)
+	assert.Equal(t, "KEYID", u.Query().Get("Key-Pair-Id"))
+
+	// CloudFront uses a URL-safe variant of base64
+	encoded := strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(u.Query().Get("Policy"))
+	policy, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	assert.Contains(t, string(policy), `"AWS:SourceIp":"203.0.113.7/32"`)
+
+	_, err = signer.SignURL(context.Background(), &lift.SignedURLRequest{Bucket: "unknown", Key: "a", Expires: time.Now()})
+	assert.Error(t, err)
+}
+
+func TestS3URLSignerRejectsIPRestriction(t *testing.T) {
+	signer := NewS3URLSigner(nil)
+	_, err := signer.SignURL(context.Background(), &lift.SignedURLRequest{Bucket: "b", Key: "k", IPAddress: "10.0.0.0/8"})
+	assert.ErrorIs(t, err, ErrIPRestrictionUnsupported)
+}
@@ -0,0 +1,35 @@
+// Package payload moves request and response bodies that exceed API Gateway
+// limits through S3. Clients upload large request bodies to a presigned URL and
+// reference the object in their request; oversized responses are written to S3
+// and replaced with a presigned download link.
+package payload
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by a Store when the object does not exist
+var ErrNotFound = errors.New("payload not found")
+
+// Object is a stored payload
+type Object struct {
+	Body        []byte
+	ContentType string
+	Size        int64
+}
+
+// Store persists payloads and issues presigned URLs for them
+type Store interface {
+	// Put stores body under key
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	// Get loads the object stored under key, reading at most maxSize bytes
+	Get(ctx context.Context, key string, maxSize int64) (*Object, error)
+	// PresignPut returns a URL the client can PUT the payload to
+	PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (string, error)
+	// PresignGet returns a URL the client can GET the payload from
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	// Delete removes the object stored under key
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,51 @@
+package payload
+
+import (
+	"context"
+	"fmt"
+	"mime"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// S3FileUploader stores uploads for ctx.UploadFormFile with the S3 upload
+// manager, which sends the file in parts as it is read, so memory use is
+// bounded by the part size rather than the file size. Failed uploads are
+// aborted.
+type S3FileUploader struct {
+	uploader S3UploaderAPI
+}
+
+// S3UploaderAPI is the subset of the S3 upload manager used by
+// S3FileUploader
+type S3UploaderAPI interface {
+	Upload(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error)
+}
+
+// NewS3FileUploader creates an uploader, usually from
+// manager.NewUploader(s3.NewFromConfig(cfg))
+func NewS3FileUploader(uploader S3UploaderAPI) *S3FileUploader {
+	return &S3FileUploader{uploader: uploader}
+}
+
+// UploadFile implements lift.FileUploader
+func (u *S3FileUploader) UploadFile(ctx context.Context, file *lift.FileUpload) error {
+	input := &s3.PutObjectInput{
+		Bucket:               aws.String(file.Bucket),
+		Key:                  aws.String(file.Key),
+		Body:                 file.Body,
+		ContentType:          aws.String(file.ContentType),
+		ServerSideEncryption: types.ServerSideEncryptionAes256,
+	}
+	if file.Filename != "" {
+		input.ContentDisposition = aws.String(mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	}
+	if _, err := u.uploader.Upload(ctx, input); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", file.Key, err)
+	}
+	return nil
+}
@@ -0,0 +1,533 @@
+package payments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+var (
+	// ErrChallengeNotFound is returned for unknown challenges
+	ErrChallengeNotFound = errors.New("payment challenge not found")
+	// ErrChallengeExpired is returned when the customer returns after the
+	// challenge expired
+	ErrChallengeExpired = errors.New("payment challenge expired")
+	// ErrInvalidChallengeTransition is returned when a challenge has already
+	// left the state a change expected it to be in
+	ErrInvalidChallengeTransition = errors.New("invalid payment challenge transition")
+)
+
+// ChallengeState is where a challenge is in its lifecycle
+type ChallengeState string
+
+const (
+	ChallengePending   ChallengeState = "pending"
+	ChallengeSucceeded ChallengeState = "succeeded"
+	ChallengeFailed    ChallengeState = "failed"
+	ChallengeExpired   ChallengeState = "expired"
+)
+
+// challengeTransitions lists the states each state may move to. Only
+// pending challenges change; the others are final.
+var challengeTransitions = map[ChallengeState][]ChallengeState{
+	ChallengePending: {ChallengeSucceeded, ChallengeFailed, ChallengeExpired},
+}
+
+// CanTransition reports whether a challenge may move from one state to
+// another
+func CanTransition(from, to ChallengeState) bool {
+	for _, state := range challengeTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Challenge is a payment waiting on the customer to complete a 3DS or other
+// SCA challenge
+type Challenge struct {
+	ID        string         `json:"id"`
+	PaymentID string         `json:"payment_id"`
+	TenantID  string         `json:"tenant_id,omitempty"`
+	State     ChallengeState `json:"state"`
+	// RedirectURL is the provider's challenge page
+	RedirectURL string `json:"redirect_url"`
+	// ReturnURL is where the customer is sent once the challenge is over
+	ReturnURL string `json:"return_url"`
+	// PaymentStatus is the payment's status when the challenge ended
+	PaymentStatus Status    `json:"payment_status,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CompletedAt   time.Time `json:"completed_at,omitempty"`
+}
+
+// ChallengeStore persists challenges between the redirect and the callback,
+// which usually arrive at different Lambda instances
+type ChallengeStore interface {
+	// Create saves a new challenge, leaving an existing one with the same
+	// ID as it is
+	Create(ctx context.Context, challenge *Challenge) error
+	// Get returns ErrChallengeNotFound for unknown challenges
+	Get(ctx context.Context, id string) (*Challenge, error)
+	// Transition saves the challenge if its stored state is still from,
+	// and returns ErrInvalidChallengeTransition otherwise
+	Transition(ctx context.Context, challenge *Challenge, from ChallengeState) error
+	// Expired returns up to limit pending challenges that expired before t
+	Expired(ctx context.Context, t time.Time, limit int) ([]*Challenge, error)
+}
+
+// MemoryChallengeStore is an in-memory challenge store
+type MemoryChallengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]Challenge
+}
+
+// NewMemoryChallengeStore creates an empty challenge store
+func NewMemoryChallengeStore() *MemoryChallengeStore {
+	return &MemoryChallengeStore{challenges: make(map[string]Challenge)}
+}
+
+// Create implements ChallengeStore
+func (s *MemoryChallengeStore) Create(ctx context.Context, challenge *Challenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.challenges[challenge.ID]; !ok {
+		s.challenges[challenge.ID] = *challenge
+	}
+	return nil
+}
+
+// Get implements ChallengeStore
+func (s *MemoryChallengeStore) Get(ctx context.Context, id string) (*Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, ok := s.challenges[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChallengeNotFound, id)
+	}
+	return &challenge, nil
+}
+
+// Transition implements ChallengeStore
+func (s *MemoryChallengeStore) Transition(ctx context.Context, challenge *Challenge, from ChallengeState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.challenges[challenge.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChallengeNotFound, challenge.ID)
+	}
+	if stored.State != from {
+		return fmt.Errorf("%w: %s is %s", ErrInvalidChallengeTransition, challenge.ID, stored.State)
+	}
+	s.challenges[challenge.ID] = *challenge
+	return nil
+}
+
+// Expired implements ChallengeStore
+func (s *MemoryChallengeStore) Expired(ctx context.Context, t time.Time, limit int) ([]*Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*Challenge
+	for _, challenge := range s.challenges {
+		if challenge.State == ChallengePending && challenge.ExpiresAt.Before(t) {
+			challenge := challenge
+			expired = append(expired, &challenge)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ExpiresAt.Before(expired[j].ExpiresAt) })
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+// ChallengeConfig configures a ChallengeFlow
+type ChallengeConfig struct {
+	// Service processes the payments; its provider must implement
+	// ChallengeCompleter
+	Service *Service
+	// Store persists pending challenges (default in-memory, which only
+	// works when the redirect and callback reach the same instance)
+	Store ChallengeStore
+	// BaseURL is the public URL the flow's handlers are mounted at, e.g.
+	// https://api.example.com/payments/challenges
+	BaseURL string
+	// TTL is how long the customer has to complete a challenge (default
+	// 15 minutes)
+	TTL time.Duration
+	// ExpiryBatchSize bounds the challenges ExpireAbandoned handles per run
+	// (default 100)
+	ExpiryBatchSize int
+	// Now returns the current time (default time.Now)
+	Now func() time.Time
+}
+
+// ChallengeFlow runs payments that may need a customer challenge. Begin
+// starts the payment and, when the provider asks for a challenge, persists
+// it and returns the URL to send the customer to. The customer is
+// redirected from there to the provider, which sends them back to the
+// callback; the flow then completes the payment and redirects the customer
+// to the return URL with the outcome. Challenges the customer abandons are
+// voided by ExpireAbandoned.
+//
+//	flow, _ := payments.NewChallengeFlow(payments.ChallengeConfig{
+//		Service: payer,
+//		Store:   challengeStore,
+//		BaseURL: "https://api.example.com/payments/challenges",
+//	})
+//	app.GET("/payments/challenges/:id", flow.RedirectHandler())
+//	app.GET("/payments/challenges/:id/callback", flow.CallbackHandler())
+//	app.POST("/payments/challenges/:id/callback", flow.CallbackHandler())
+//	app.EventBridge("expire-challenges", flow.ExpiryHandler())
+type ChallengeFlow struct {
+	config ChallengeConfig
+}
+
+// ChallengeResult is the outcome of beginning a payment
+type ChallengeResult struct {
+	Payment *Payment
+	// Challenge is nil when the payment needed no challenge
+	Challenge *Challenge
+	// ChallengeURL is where to send the customer to complete the challenge
+	ChallengeURL string
+}
+
+// NewChallengeFlow creates a challenge flow
+func NewChallengeFlow(config ChallengeConfig) (*ChallengeFlow, error) {
+	if config.Service == nil {
+		return nil, fmt.Errorf("challenge flow requires a payment service")
+	}
+	if _, ok := config.Service.Provider().(ChallengeCompleter); !ok {
+		return nil, fmt.Errorf("payment provider %s does not support challenges", config.Service.Provider().Name())
+	}
+	if _, err := url.Parse(config.BaseURL); err != nil || config.BaseURL == "" {
+		return nil, fmt.Errorf("challenge flow requires a valid base URL")
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.Store == nil {
+		config.Store = NewMemoryChallengeStore()
+	}
+	if config.TTL <= 0 {
+		config.TTL = 15 * time.Minute
+	}
+	if config.ExpiryBatchSize <= 0 {
+		config.ExpiryBatchSize = 100
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &ChallengeFlow{config: config}, nil
+}
+
+// ChallengeURL returns the URL that redirects the customer to a challenge
+func (f *ChallengeFlow) ChallengeURL(id string) string {
+	return f.config.BaseURL + "/" + url.PathEscape(id)
+}
+
+// CallbackURL returns the URL the provider returns the customer to
+func (f *ChallengeFlow) CallbackURL(id string) string {
+	return f.ChallengeURL(id) + "/callback"
+}
+
+// Begin processes a payment, persisting a pending challenge when the
+// provider asks for one. returnURL is where the customer is sent once the
+// challenge is over. Challenge IDs are derived from the tenant and
+// idempotency key, so retrying Begin resumes the same challenge.
+func (f *ChallengeFlow) Begin(ctx context.Context, req *PaymentRequest, returnURL string) (*ChallengeResult, error) {
+	if err := requireIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if returnURL == "" {
+		return nil, lift.NewLiftError("VALIDATION_ERROR", "A return URL is required for payment challenges", 400)
+	}
+
+	id := challengeID(req.TenantID, req.IdempotencyKey)
+	req.ReturnURL = f.CallbackURL(id)
+
+	payment, err := f.config.Service.ProcessPayment(ctx, req)
+	result := &ChallengeResult{Payment: payment}
+	if err != nil || payment.Status != StatusRequiresAction {
+		return result, err
+	}
+	if payment.NextAction == nil || payment.NextAction.RedirectURL == "" {
+		return nil, fmt.Errorf("payment %s requires action but has no challenge URL", payment.ID)
+	}
+
+	now := f.config.Now()
+	challenge := &Challenge{
+		ID:          id,
+		PaymentID:   payment.ID,
+		TenantID:    req.TenantID,
+		State:       ChallengePending,
+		RedirectURL: payment.NextAction.RedirectURL,
+		ReturnURL:   returnURL,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(f.config.TTL),
+	}
+	if err := f.config.Store.Create(ctx, challenge); err != nil {
+		return nil, fmt.Errorf("failed to save challenge for payment %s: %w", payment.ID, err)
+	}
+
+	result.Challenge = challenge
+	result.ChallengeURL = f.ChallengeURL(id)
+	return result, nil
+}
+
+// BeginFromContext begins a payment on behalf of the current request,
+// defaulting the tenant and idempotency key as ProcessPaymentFromContext does
+func (f *ChallengeFlow) BeginFromContext(ctx *lift.Context, req *PaymentRequest, returnURL string) (*ChallengeResult, error) {
+	if req.TenantID == "" {
+		req.TenantID = ctx.TenantID()
+	}
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = IdempotencyKey(ctx)
+	}
+	return f.Begin(ctx.Context, req, returnURL)
+}
+
+// Resume completes the payment once the provider returns the customer with
+// params. Declined challenges end in ChallengeFailed rather than an error.
+// Repeated callbacks return the challenge as it ended; callbacks after
+// expiry expire it and return ErrChallengeExpired.
+func (f *ChallengeFlow) Resume(ctx context.Context, id string, params map[string]string) (*Challenge, error) {
+	challenge, err := f.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.State != ChallengePending {
+		if challenge.State == ChallengeExpired {
+			return challenge, challengeExpiredError(id)
+		}
+		return challenge, nil
+	}
+	if !f.config.Now().Before(challenge.ExpiresAt) {
+		challenge, err := f.expire(ctx, challenge)
+		if err != nil {
+			return nil, err
+		}
+		if challenge.State == ChallengeExpired {
+			return challenge, challengeExpiredError(id)
+		}
+		return challenge, nil
+	}
+
+	payment, err := f.config.Service.CompleteChallenge(ctx, &ChallengeCompletion{
+		IdempotencyKey: "challenge:" + id,
+		PaymentID:      challenge.PaymentID,
+		Params:         params,
+	})
+	if payment == nil {
+		// Nothing happened at the provider, so the callback can be retried
+		return nil, err
+	}
+
+	next := ChallengeSucceeded
+	if payment.Status == StatusFailed {
+		next = ChallengeFailed
+	}
+	return f.transition(ctx, challenge, next, payment.Status)
+}
+
+// ExpireAbandoned voids the payments of pending challenges that have
+// expired and marks the challenges expired, returning how many it expired.
+// Run it on a schedule.
+func (f *ChallengeFlow) ExpireAbandoned(ctx context.Context) (int, error) {
+	challenges, err := f.config.Store.Expired(ctx, f.config.Now(), f.config.ExpiryBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired challenges: %w", err)
+	}
+
+	expired := 0
+	var errs []error
+	for _, challenge := range challenges {
+		challenge, err := f.expire(ctx, challenge)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if challenge.State == ChallengeExpired {
+			expired++
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+// RedirectHandler returns a handler that sends the customer to the
+// provider's challenge page. Challenges that are over send the customer to
+// the return URL instead. The route must have an :id parameter.
+func (f *ChallengeFlow) RedirectHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		challenge, err := f.get(ctx.Context, ctx.Param("id"))
+		if err != nil {
+			return err
+		}
+		if challenge.State == ChallengePending && !f.config.Now().Before(challenge.ExpiresAt) {
+			if challenge, err = f.expire(ctx.Context, challenge); err != nil {
+				return err
+			}
+		}
+		if challenge.State == ChallengePending {
+			return redirect(ctx, challenge.RedirectURL)
+		}
+		return f.returnCustomer(ctx, challenge)
+	})
+}
+
+// CallbackHandler returns a handler for the provider's return of the
+// customer, by query string or form post. It resumes the challenge and
+// redirects the customer to the return URL with payment_id and
+// challenge_status query parameters. The route must have an :id parameter.
+func (f *ChallengeFlow) CallbackHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		params := make(map[string]string, len(ctx.Request.QueryParams))
+		for key, value := range ctx.Request.QueryParams {
+			params[key] = value
+		}
+		if strings.HasPrefix(ctx.Request.GetHeader("Content-Type"), "application/x-www-form-urlencoded") {
+			form, err := url.ParseQuery(string(ctx.Request.Body))
+			if err != nil {
+				return lift.NewLiftError("INVALID_CHALLENGE_CALLBACK", "Challenge callback could not be parsed", 400).WithCause(err)
+			}
+			for key := range form {
+				params[key] = form.Get(key)
+			}
+		}
+
+		challenge, err := f.Resume(ctx.Context, ctx.Param("id"), params)
+		if challenge == nil {
+			return err
+		}
+		if ctx.Logger != nil {
+			ctx.Logger.Info("Payment challenge resumed", map[string]any{
+				"challenge_id": challenge.ID,
+				"payment_id":   challenge.PaymentID,
+				"state":        challenge.State,
+			})
+		}
+		return f.returnCustomer(ctx, challenge)
+	})
+}
+
+// ExpiryHandler returns a handler for scheduled events that runs
+// ExpireAbandoned
+func (f *ChallengeFlow) ExpiryHandler() lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		expired, err := f.ExpireAbandoned(ctx.Context)
+		if ctx.Logger != nil {
+			fields := map[string]any{"expired": expired}
+			if err != nil {
+				fields["error"] = err.Error()
+			}
+			ctx.Logger.Info("Expired abandoned payment challenges", fields)
+		}
+		if err != nil {
+			return err
+		}
+		return ctx.OK(map[string]any{"expired": expired})
+	})
+}
+
+func (f *ChallengeFlow) get(ctx context.Context, id string) (*Challenge, error) {
+	challenge, err := f.config.Store.Get(ctx, id)
+	if errors.Is(err, ErrChallengeNotFound) {
+		return nil, lift.NewLiftError("CHALLENGE_NOT_FOUND", "Payment challenge not found", 404).WithCause(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load challenge %s: %w", id, err)
+	}
+	return challenge, nil
+}
+
+// expire voids an abandoned challenge's payment and marks it expired. A
+// payment the provider completed in the meantime, such as by a late
+// callback, ends the challenge with the payment's status instead.
+func (f *ChallengeFlow) expire(ctx context.Context, challenge *Challenge) (*Challenge, error) {
+	payment, err := f.config.Service.Void(ctx, &VoidRequest{
+		IdempotencyKey: "challenge:" + challenge.ID + ":expire",
+		PaymentID:      challenge.PaymentID,
+		Reason:         "challenge_abandoned",
+	})
+	if errors.Is(err, ErrInvalidState) {
+		store := f.config.Service.config.Store
+		if store == nil {
+			return f.transition(ctx, challenge, ChallengeExpired, "")
+		}
+		stored, err := store.Get(ctx, challenge.PaymentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load payment %s: %w", challenge.PaymentID, err)
+		}
+		next := ChallengeSucceeded
+		if stored.Status == StatusFailed || stored.Status == StatusVoided {
+			next = ChallengeFailed
+		}
+		return f.transition(ctx, challenge, next, stored.Status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to void payment %s for expired challenge %s: %w", challenge.PaymentID, challenge.ID, err)
+	}
+	return f.transition(ctx, challenge, ChallengeExpired, payment.Status)
+}
+
+// transition moves a pending challenge to its final state. A concurrent
+// callback or expiry that got there first wins, and its result is returned.
+func (f *ChallengeFlow) transition(ctx context.Context, challenge *Challenge, to ChallengeState, status Status) (*Challenge, error) {
+	if !CanTransition(challenge.State, to) {
+		return nil, fmt.Errorf("%w: %s from %s to %s", ErrInvalidChallengeTransition, challenge.ID, challenge.State, to)
+	}
+
+	next := *challenge
+	next.State = to
+	next.PaymentStatus = status
+	next.CompletedAt = f.config.Now()
+	err := f.config.Store.Transition(ctx, &next, challenge.State)
+	if errors.Is(err, ErrInvalidChallengeTransition) {
+		return f.get(ctx, challenge.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save challenge %s: %w", challenge.ID, err)
+	}
+	return &next, nil
+}
+
+// returnCustomer redirects the customer to the challenge's return URL
+func (f *ChallengeFlow) returnCustomer(ctx *lift.Context, challenge *Challenge) error {
+	target, err := url.Parse(challenge.ReturnURL)
+	if err != nil {
+		return fmt.Errorf("invalid return URL for challenge %s: %w", challenge.ID, err)
+	}
+	query := target.Query()
+	query.Set("payment_id", challenge.PaymentID)
+	query.Set("challenge_status", string(challenge.State))
+	target.RawQuery = query.Encode()
+	return redirect(ctx, target.String())
+}
+
+func redirect(ctx *lift.Context, location string) error {
+	ctx.Response.Header("Location", location)
+	return ctx.Status(302).Text("")
+}
+
+func challengeExpiredError(id string) error {
+	return lift.NewLiftError("CHALLENGE_EXPIRED", "Payment challenge has expired", 410).
+		WithCause(fmt.Errorf("%w: %s", ErrChallengeExpired, id))
+}
+
+// challengeID derives a challenge's ID from the payment's idempotency key
+func challengeID(tenantID, idempotencyKey string) string {
+	sum := sha256.Sum256([]byte(tenantID + "\x00" + idempotencyKey))
+	return hex.EncodeToString(sum[:16])
+}
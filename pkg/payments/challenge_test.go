@@ -0,0 +1,169 @@
+package payments
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChallengeFlow(t *testing.T, now *time.Time) (*ChallengeFlow, *MemoryStore) {
+	service, _, store := newTestService()
+	flow, err := NewChallengeFlow(ChallengeConfig{
+		Service: service,
+		BaseURL: "https://api.example.com/payments/challenges/",
+		Now:     func() time.Time { return *now },
+	})
+	require.NoError(t, err)
+	return flow, store
+}
+
+func challengeRequest(method, id, path string, query map[string]string, headers map[string]string, body string) *lift.Context {
+	ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:      method,
+		Path:        path,
+		QueryParams: query,
+		Headers:     headers,
+		Body:        []byte(body),
+	}))
+	ctx.SetParam("id", id)
+	return ctx
+}
+
+func beginChallenge(t *testing.T, flow *ChallengeFlow, key string) *ChallengeResult {
+	result, err := flow.Begin(context.Background(), &PaymentRequest{
+		IdempotencyKey: key,
+		TenantID:       "tenant-1",
+		Amount:         5000,
+		Currency:       "usd",
+		PaymentMethod:  SandboxChallenge,
+		Capture:        true,
+	}, "https://shop.example.com/checkout/done")
+	require.NoError(t, err)
+	require.NotNil(t, result.Challenge)
+	return result
+}
+
+func TestChallengeFlow(t *testing.T) {
+	now := time.Now()
+	flow, store := newTestChallengeFlow(t, &now)
+	result := beginChallenge(t, flow, "order-1")
+
+	challenge := result.Challenge
+	assert.Equal(t, StatusRequiresAction, result.Payment.Status)
+	assert.Equal(t, "https://api.example.com/payments/challenges/"+challenge.ID, result.ChallengeURL)
+	assert.Contains(t, challenge.RedirectURL, url.QueryEscape(flow.CallbackURL(challenge.ID)))
+	assert.Equal(t, now.Add(15*time.Minute), challenge.ExpiresAt)
+
+	retried := beginChallenge(t, flow, "order-1")
+	assert.Equal(t, challenge.ID, retried.Challenge.ID, "retrying Begin resumes the same challenge")
+
+	redirectCtx := challengeRequest("GET", challenge.ID, "/payments/challenges/"+challenge.ID, nil, nil, "")
+	require.NoError(t, flow.RedirectHandler().Handle(redirectCtx))
+	assert.Equal(t, 302, redirectCtx.Response.StatusCode)
+	assert.Equal(t, challenge.RedirectURL, redirectCtx.Response.Headers["Location"])
+
+	callbackCtx := challengeRequest("POST", challenge.ID, "/payments/challenges/"+challenge.ID+"/callback", nil,
+		map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, "cres=abc")
+	require.NoError(t, flow.CallbackHandler().Handle(callbackCtx))
+	assert.Equal(t, 302, callbackCtx.Response.StatusCode)
+	location, err := url.Parse(callbackCtx.Response.Headers["Location"])
+	require.NoError(t, err)
+	assert.Equal(t, "shop.example.com", location.Host)
+	assert.Equal(t, result.Payment.ID, location.Query().Get("payment_id"))
+	assert.Equal(t, string(ChallengeSucceeded), location.Query().Get("challenge_status"))
+
+	payment, err := store.Get(context.Background(), result.Payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCaptured, payment.Status)
+
+	replayed, err := flow.Resume(context.Background(), challenge.ID, map[string]string{"result": "failed"})
+	require.NoError(t, err)
+	assert.Equal(t, ChallengeSucceeded, replayed.State, "repeated callbacks don't change a finished challenge")
+}
+
+func TestChallengeFlowFailedChallenge(t *testing.T) {
+	now := time.Now()
+	flow, store := newTestChallengeFlow(t, &now)
+	result := beginChallenge(t, flow, "order-2")
+
+	challenge, err := flow.Resume(context.Background(), result.Challenge.ID, map[string]string{"result": "failed"})
+	require.NoError(t, err)
+	assert.Equal(t, ChallengeFailed, challenge.State)
+	assert.Equal(t, StatusFailed, challenge.PaymentStatus)
+
+	payment, err := store.Get(context.Background(), result.Payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, payment.Status)
+}
+
+func TestChallengeFlowExpiresAbandonedChallenges(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	flow, store := newTestChallengeFlow(t, &now)
+	abandoned := beginChallenge(t, flow, "order-3")
+	late := beginChallenge(t, flow, "order-4")
+
+	expired, err := flow.ExpireAbandoned(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, expired)
+
+	now = now.Add(20 * time.Minute)
+	expired, err = flow.ExpireAbandoned(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, expired)
+
+	payment, err := store.Get(ctx, abandoned.Payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusVoided, payment.Status)
+
+	_, err = flow.Resume(ctx, late.Challenge.ID, nil)
+	assert.ErrorIs(t, err, ErrChallengeExpired)
+	assert.Equal(t, 410, statusCode(t, err))
+
+	redirectCtx := challengeRequest("GET", late.Challenge.ID, "/payments/challenges/"+late.Challenge.ID, nil, nil, "")
+	require.NoError(t, flow.RedirectHandler().Handle(redirectCtx))
+	assert.Contains(t, redirectCtx.Response.Headers["Location"], "challenge_status=expired")
+}
+
+func TestChallengeFlowExpiresOnLateCallback(t *testing.T) {
+	now := time.Now()
+	flow, _ := newTestChallengeFlow(t, &now)
+	result := beginChallenge(t, flow, "order-5")
+
+	now = now.Add(time.Hour)
+	challenge, err := flow.Resume(context.Background(), result.Challenge.ID, nil)
+	assert.Equal(t, 410, statusCode(t, err))
+	assert.Equal(t, ChallengeExpired, challenge.State)
+	assert.Equal(t, StatusVoided, challenge.PaymentStatus)
+}
+
+func TestChallengeTransitions(t *testing.T) {
+	assert.True(t, CanTransition(ChallengePending, ChallengeSucceeded))
+	assert.True(t, CanTransition(ChallengePending, ChallengeExpired))
+	assert.False(t, CanTransition(ChallengeSucceeded, ChallengeFailed))
+	assert.False(t, CanTransition(ChallengeExpired, ChallengePending))
+
+	_, err := NewMemoryChallengeStore().Get(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrChallengeNotFound)
+}
+
+func TestChallengeFlowPassesThroughUnchallengedPayments(t *testing.T) {
+	now := time.Now()
+	flow, _ := newTestChallengeFlow(t, &now)
+
+	result, err := flow.Begin(context.Background(), &PaymentRequest{
+		IdempotencyKey: "order-6",
+		Amount:         1000,
+		Currency:       "usd",
+		PaymentMethod:  SandboxApproved,
+	}, "https://shop.example.com/checkout/done")
+	require.NoError(t, err)
+	assert.Nil(t, result.Challenge)
+	assert.Equal(t, StatusAuthorized, result.Payment.Status)
+}
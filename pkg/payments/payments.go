@@ -0,0 +1,374 @@
+// Package payments takes payments through a pluggable provider. Handlers
+// use a Service, which validates requests, enforces idempotency keys and
+// records payment state, so a payment service can move between processors
+// by swapping the Provider it is configured with.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// Status is a payment's lifecycle state
+type Status string
+
+const (
+	StatusPending           Status = "pending"
+	StatusRequiresAction    Status = "requires_action"
+	StatusAuthorized        Status = "authorized"
+	StatusCaptured          Status = "captured"
+	StatusPartiallyRefunded Status = "partially_refunded"
+	StatusRefunded          Status = "refunded"
+	StatusVoided            Status = "voided"
+	StatusFailed            Status = "failed"
+)
+
+// IdempotencyKeyHeader is the request header idempotency keys are read from
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+var (
+	// ErrDeclined is returned when the provider declines a payment. The
+	// failed payment is returned alongside it so callers can record it.
+	ErrDeclined = errors.New("payment declined")
+	// ErrNotFound is returned for payments the provider or store doesn't know
+	ErrNotFound = errors.New("payment not found")
+	// ErrInvalidState is returned for operations the payment's status
+	// doesn't allow, such as capturing a voided payment
+	ErrInvalidState = errors.New("payment is not in a valid state for this operation")
+	// ErrIdempotencyConflict is returned when an idempotency key is reused
+	// for a different request
+	ErrIdempotencyConflict = errors.New("idempotency key reused with different parameters")
+)
+
+// PaymentRequest asks a provider to authorize, and optionally capture, a
+// payment. Amounts are in the currency's minor unit.
+type PaymentRequest struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	TenantID       string `json:"tenant_id,omitempty"`
+	Amount         int64  `json:"amount"`
+	Currency       string `json:"currency"`
+	// PaymentMethod is the provider's token for the card or account
+	PaymentMethod string `json:"payment_method"`
+	// Capture captures the payment immediately instead of only authorizing it
+	Capture     bool              `json:"capture"`
+	Description string            `json:"description,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// ReturnURL is where the provider sends the customer after a 3DS or
+	// other SCA challenge; ChallengeFlow sets it
+	ReturnURL string `json:"return_url,omitempty"`
+}
+
+// CaptureRequest captures an authorized payment
+type CaptureRequest struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	PaymentID      string `json:"payment_id"`
+	// Amount captures part of the authorization; 0 captures all of it
+	Amount int64 `json:"amount,omitempty"`
+}
+
+// RefundRequest refunds a captured payment
+type RefundRequest struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	PaymentID      string `json:"payment_id"`
+	// Amount refunds part of the payment; 0 refunds what remains
+	Amount int64  `json:"amount,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// VoidRequest cancels an authorized payment before capture, or a payment
+// whose challenge was abandoned
+type VoidRequest struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	PaymentID      string `json:"payment_id"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// Payment is a provider's view of a payment
+type Payment struct {
+	ID             string            `json:"id"`
+	Provider       string            `json:"provider"`
+	TenantID       string            `json:"tenant_id,omitempty"`
+	Status         Status            `json:"status"`
+	Amount         int64             `json:"amount"`
+	CapturedAmount int64             `json:"captured_amount"`
+	RefundedAmount int64             `json:"refunded_amount"`
+	Currency       string            `json:"currency"`
+	FailureCode    string            `json:"failure_code,omitempty"`
+	FailureMessage string            `json:"failure_message,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	// NextAction is set while the payment is StatusRequiresAction
+	NextAction *NextAction `json:"next_action,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// NextAction is what the customer must do before a payment can proceed
+type NextAction struct {
+	// RedirectURL is the provider's challenge page, e.g. the card issuer's
+	// 3DS access control server
+	RedirectURL string `json:"redirect_url"`
+}
+
+// ChallengeCompletion finishes a payment after its challenge
+type ChallengeCompletion struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	PaymentID      string `json:"payment_id"`
+	// Params are the parameters the provider returned with the customer,
+	// such as the 3DS result
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Refund is a completed refund
+type Refund struct {
+	ID        string    `json:"id"`
+	PaymentID string    `json:"payment_id"`
+	Amount    int64     `json:"amount"`
+	Currency  string    `json:"currency"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Payment is the refunded payment's state after the refund
+	Payment *Payment `json:"payment"`
+}
+
+// PaymentProcessor authorizes and captures new payments
+type PaymentProcessor interface {
+	ProcessPayment(ctx context.Context, req *PaymentRequest) (*Payment, error)
+}
+
+// Capturer captures authorized payments
+type Capturer interface {
+	Capture(ctx context.Context, req *CaptureRequest) (*Payment, error)
+}
+
+// Voider cancels authorized payments
+type Voider interface {
+	Void(ctx context.Context, req *VoidRequest) (*Payment, error)
+}
+
+// Refunder refunds captured payments
+type Refunder interface {
+	Refund(ctx context.Context, req *RefundRequest) (*Refund, error)
+}
+
+// ChallengeCompleter is implemented by providers that support 3DS and
+// other SCA challenges. ProcessPayment returns such payments with
+// StatusRequiresAction and a NextAction; CompleteChallenge authorizes (or
+// captures) them once the customer returns, or fails them with ErrDeclined.
+type ChallengeCompleter interface {
+	CompleteChallenge(ctx context.Context, req *ChallengeCompletion) (*Payment, error)
+}
+
+// Provider is a payment processor. Providers must honor idempotency keys:
+// repeating a request with the same key returns the original result rather
+// than moving money twice, and reusing a key for a different request fails
+// with ErrIdempotencyConflict.
+type Provider interface {
+	// Name identifies the provider in stored payments and logs
+	Name() string
+	PaymentProcessor
+	Capturer
+	Voider
+	Refunder
+}
+
+// Config configures a payment Service
+type Config struct {
+	// Provider processes payments (required)
+	Provider Provider
+	// Store records payment state so webhooks can be reconciled against
+	// it (optional)
+	Store Store
+}
+
+// Service takes payments through a provider
+type Service struct {
+	config Config
+}
+
+// New creates a payment service
+func New(config Config) *Service {
+	return &Service{config: config}
+}
+
+// Provider returns the service's provider
+func (s *Service) Provider() Provider {
+	return s.config.Provider
+}
+
+// ProcessPayment authorizes, and if req.Capture is set captures, a payment.
+// A declined payment is returned with StatusFailed along with an error
+// wrapping ErrDeclined.
+func (s *Service) ProcessPayment(ctx context.Context, req *PaymentRequest) (*Payment, error) {
+	if err := requireIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if req.Amount <= 0 {
+		return nil, lift.NewLiftError("INVALID_AMOUNT", "Payment amount must be positive", 400)
+	}
+	if len(req.Currency) != 3 {
+		return nil, lift.NewLiftError("INVALID_CURRENCY", "Payment currency must be a 3-letter ISO 4217 code", 400)
+	}
+	if req.PaymentMethod == "" {
+		return nil, lift.NewLiftError("INVALID_PAYMENT_METHOD", "Payment method is required", 400)
+	}
+	req.Currency = strings.ToUpper(req.Currency)
+
+	payment, err := s.config.Provider.ProcessPayment(ctx, req)
+	return s.record(ctx, payment, err)
+}
+
+// Capture captures an authorized payment
+func (s *Service) Capture(ctx context.Context, req *CaptureRequest) (*Payment, error) {
+	if err := requireIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if err := validateOperation(req.PaymentID, req.Amount); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.config.Provider.Capture(ctx, req)
+	return s.record(ctx, payment, err)
+}
+
+// Void cancels an authorized payment, or one awaiting a challenge
+func (s *Service) Void(ctx context.Context, req *VoidRequest) (*Payment, error) {
+	if err := requireIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if err := validateOperation(req.PaymentID, 0); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.config.Provider.Void(ctx, req)
+	return s.record(ctx, payment, err)
+}
+
+// Refund refunds a captured payment
+func (s *Service) Refund(ctx context.Context, req *RefundRequest) (*Refund, error) {
+	if err := requireIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if err := validateOperation(req.PaymentID, req.Amount); err != nil {
+		return nil, err
+	}
+
+	refund, err := s.config.Provider.Refund(ctx, req)
+	if err != nil {
+		return nil, providerError(err)
+	}
+	if _, err := s.record(ctx, refund.Payment, nil); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// CompleteChallenge finishes a payment whose challenge the customer has
+// completed. Most handlers use a ChallengeFlow instead.
+func (s *Service) CompleteChallenge(ctx context.Context, req *ChallengeCompletion) (*Payment, error) {
+	if err := requireIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if err := validateOperation(req.PaymentID, 0); err != nil {
+		return nil, err
+	}
+	completer, ok := s.config.Provider.(ChallengeCompleter)
+	if !ok {
+		return nil, lift.NewLiftError("CHALLENGE_UNSUPPORTED", fmt.Sprintf("Payment provider %s does not support challenges", s.config.Provider.Name()), 501)
+	}
+
+	payment, err := completer.CompleteChallenge(ctx, req)
+	return s.record(ctx, payment, err)
+}
+
+// ProcessPaymentFromContext processes a payment on behalf of the current
+// request, defaulting the tenant to the request's tenant and the
+// idempotency key to the request's Idempotency-Key header
+func (s *Service) ProcessPaymentFromContext(ctx *lift.Context, req *PaymentRequest) (*Payment, error) {
+	if req.TenantID == "" {
+		req.TenantID = ctx.TenantID()
+	}
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = IdempotencyKey(ctx)
+	}
+
+	payment, err := s.ProcessPayment(ctx.Context, req)
+	if ctx.Logger != nil {
+		fields := map[string]any{
+			"provider": s.config.Provider.Name(),
+			"amount":   req.Amount,
+			"currency": req.Currency,
+		}
+		if payment != nil {
+			fields["payment_id"] = payment.ID
+			fields["status"] = payment.Status
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+			ctx.Logger.Warn("Payment failed", fields)
+		} else {
+			ctx.Logger.Info("Payment processed", fields)
+		}
+	}
+	return payment, err
+}
+
+// IdempotencyKey returns the request's idempotency key. Handlers that
+// perform several payment operations per request should derive a distinct
+// key for each, such as key + ":capture".
+func IdempotencyKey(ctx *lift.Context) string {
+	return ctx.Header(IdempotencyKeyHeader)
+}
+
+// record saves the provider's result and converts its error
+func (s *Service) record(ctx context.Context, payment *Payment, err error) (*Payment, error) {
+	if payment != nil && s.config.Store != nil {
+		if saveErr := s.config.Store.Save(ctx, payment); saveErr != nil {
+			return nil, fmt.Errorf("failed to record payment %s: %w", payment.ID, saveErr)
+		}
+	}
+	if err != nil {
+		return payment, providerError(err)
+	}
+	return payment, nil
+}
+
+func requireIdempotencyKey(key string) error {
+	if key == "" {
+		return lift.NewLiftError("IDEMPOTENCY_KEY_REQUIRED", "An idempotency key is required for payment operations", 400)
+	}
+	return nil
+}
+
+func validateOperation(paymentID string, amount int64) error {
+	if paymentID == "" {
+		return lift.NewLiftError("INVALID_PAYMENT_ID", "Payment ID is required", 400)
+	}
+	if amount < 0 {
+		return lift.NewLiftError("INVALID_AMOUNT", "Amount cannot be negative", 400)
+	}
+	return nil
+}
+
+// providerError maps provider errors to HTTP errors; anything unrecognised
+// is reported as a provider failure
+func providerError(err error) error {
+	var liftErr *lift.LiftError
+	switch {
+	case errors.As(err, &liftErr):
+		return err
+	case errors.Is(err, ErrDeclined):
+		return lift.NewLiftError("PAYMENT_DECLINED", "Payment was declined", 402).WithCause(err)
+	case errors.Is(err, ErrNotFound):
+		return lift.NewLiftError("PAYMENT_NOT_FOUND", "Payment not found", 404).WithCause(err)
+	case errors.Is(err, ErrInvalidState):
+		return lift.NewLiftError("INVALID_PAYMENT_STATE", err.Error(), 409).WithCause(err)
+	case errors.Is(err, ErrIdempotencyConflict):
+		return lift.NewLiftError("IDEMPOTENCY_CONFLICT", "Idempotency key was already used for a different request", 409).WithCause(err)
+	}
+	return lift.NewLiftError("PAYMENT_PROVIDER_ERROR", "Payment provider request failed", 502).WithCause(err)
+}
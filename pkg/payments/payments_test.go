@@ -0,0 +1,220 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService() (*Service, *Sandbox, *MemoryStore) {
+	sandbox := NewSandbox(SandboxConfig{WebhookSecret: "whsec_test"})
+	store := NewMemoryStore()
+	return New(Config{Provider: sandbox, Store: store}), sandbox, store
+}
+
+func statusCode(t *testing.T, err error) int {
+	var liftErr *lift.LiftError
+	require.True(t, errors.As(err, &liftErr), "expected a LiftError, got %v", err)
+	return liftErr.StatusCode
+}
+
+func TestPaymentLifecycle(t *testing.T) {
+	ctx := context.Background()
+	service, _, store := newTestService()
+
+	payment, err := service.ProcessPayment(ctx, &PaymentRequest{
+		IdempotencyKey: "order-1",
+		Amount:         5000,
+		Currency:       "usd",
+		PaymentMethod:  SandboxApproved,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, payment.Status)
+	assert.Equal(t, "USD", payment.Currency)
+
+	payment, err = service.Capture(ctx, &CaptureRequest{IdempotencyKey: "order-1:capture", PaymentID: payment.ID})
+	require.NoError(t, err)
+	assert.Equal(t, StatusCaptured, payment.Status)
+	assert.Equal(t, int64(5000), payment.CapturedAmount)
+
+	refund, err := service.Refund(ctx, &RefundRequest{IdempotencyKey: "order-1:refund-1", PaymentID: payment.ID, Amount: 2000})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), refund.Amount)
+	assert.Equal(t, StatusPartiallyRefunded, refund.Payment.Status)
+
+	refund, err = service.Refund(ctx, &RefundRequest{IdempotencyKey: "order-1:refund-2", PaymentID: payment.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3000), refund.Amount)
+
+	stored, err := store.Get(ctx, payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRefunded, stored.Status)
+	assert.Equal(t, int64(5000), stored.RefundedAmount)
+
+	_, err = service.Void(ctx, &VoidRequest{IdempotencyKey: "order-1:void", PaymentID: payment.ID})
+	assert.Equal(t, 409, statusCode(t, err))
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	service, sandbox, _ := newTestService()
+	req := &PaymentRequest{IdempotencyKey: "order-2", Amount: 1200, Currency: "USD", PaymentMethod: SandboxApproved, Capture: true}
+
+	first, err := service.ProcessPayment(ctx, req)
+	require.NoError(t, err)
+	second, err := service.ProcessPayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Len(t, sandbox.Events(), 1)
+
+	changed := *req
+	changed.Amount = 1300
+	_, err = service.ProcessPayment(ctx, &changed)
+	assert.Equal(t, 409, statusCode(t, err))
+	assert.ErrorIs(t, err, ErrIdempotencyConflict)
+
+	_, err = service.ProcessPayment(ctx, &PaymentRequest{Amount: 100, Currency: "USD", PaymentMethod: SandboxApproved})
+	assert.Equal(t, 400, statusCode(t, err))
+}
+
+func TestDeclinedPaymentIsRecorded(t *testing.T) {
+	ctx := context.Background()
+	service, _, store := newTestService()
+
+	payment, err := service.ProcessPayment(ctx, &PaymentRequest{
+		IdempotencyKey: "order-3",
+		Amount:         900,
+		Currency:       "USD",
+		PaymentMethod:  SandboxDeclined,
+	})
+	assert.Equal(t, 402, statusCode(t, err))
+	assert.ErrorIs(t, err, ErrDeclined)
+	require.NotNil(t, payment)
+	assert.Equal(t, "card_declined", payment.FailureCode)
+
+	stored, err := store.Get(ctx, payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, stored.Status)
+}
+
+func TestReconcileIgnoresStaleEvents(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	authorized := &Payment{ID: "pay_1", Status: StatusAuthorized, Amount: 500}
+	captured := &Payment{ID: "pay_1", Status: StatusCaptured, Amount: 500, CapturedAmount: 500}
+
+	result, err := Reconcile(ctx, store, &WebhookEvent{ID: "evt_2", Type: EventPaymentCaptured, Payment: captured})
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+	assert.Equal(t, Status(""), result.Previous)
+
+	// The authorization event arrives late
+	result, err = Reconcile(ctx, store, &WebhookEvent{ID: "evt_1", Type: EventPaymentAuthorized, Payment: authorized})
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.Equal(t, StatusCaptured, result.Payment.Status)
+
+	// Redelivery changes nothing
+	result, err = Reconcile(ctx, store, &WebhookEvent{ID: "evt_2", Type: EventPaymentCaptured, Payment: captured})
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+
+	partial := &Payment{ID: "pay_1", Status: StatusPartiallyRefunded, Amount: 500, CapturedAmount: 500, RefundedAmount: 100}
+	more := &Payment{ID: "pay_1", Status: StatusPartiallyRefunded, Amount: 500, CapturedAmount: 500, RefundedAmount: 300}
+	_, err = Reconcile(ctx, store, &WebhookEvent{ID: "evt_4", Type: EventPaymentRefunded, Payment: more})
+	require.NoError(t, err)
+	result, err = Reconcile(ctx, store, &WebhookEvent{ID: "evt_3", Type: EventPaymentRefunded, Payment: partial})
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.Equal(t, int64(300), result.Payment.RefundedAmount)
+}
+
+func webhookRequest(headers map[string]string, body []byte) *lift.Context {
+	return lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Method:  "POST",
+		Path:    "/webhooks/payments",
+		Headers: headers,
+		Body:    body,
+	}))
+}
+
+func TestWebhookHandler(t *testing.T) {
+	ctx := context.Background()
+	service, sandbox, store := newTestService()
+	other := NewSandbox(SandboxConfig{WebhookSecret: "whsec_other"})
+
+	// A payment taken outside this service, e.g. through the provider's dashboard
+	payment, err := sandbox.ProcessPayment(ctx, &PaymentRequest{IdempotencyKey: "dash-1", Amount: 700, Currency: "USD", PaymentMethod: SandboxApproved, Capture: true})
+	require.NoError(t, err)
+	events := sandbox.Events()
+	require.Len(t, events, 1)
+
+	var changes []*Reconciliation
+	handler := service.WebhookHandler(func(ctx *lift.Context, result *Reconciliation) error {
+		changes = append(changes, result)
+		return nil
+	})
+
+	headers, body, err := sandbox.SignWebhook(events[0])
+	require.NoError(t, err)
+	liftCtx := webhookRequest(headers, body)
+	require.NoError(t, handler.Handle(liftCtx))
+	assert.Equal(t, 200, liftCtx.Response.StatusCode)
+	require.Len(t, changes, 1)
+	assert.Equal(t, EventPaymentCaptured, changes[0].Event.Type)
+
+	stored, err := store.Get(ctx, payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCaptured, stored.Status)
+
+	// Redelivery is acknowledged without another change
+	require.NoError(t, handler.Handle(webhookRequest(headers, body)))
+	assert.Len(t, changes, 1)
+
+	forgedHeaders, forgedBody, err := other.SignWebhook(events[0])
+	require.NoError(t, err)
+	err = handler.Handle(webhookRequest(forgedHeaders, forgedBody))
+	assert.Equal(t, 401, statusCode(t, err))
+
+	tampered := []byte(string(body[:len(body)-1]) + " }")
+	err = handler.Handle(webhookRequest(headers, tampered))
+	assert.Equal(t, 401, statusCode(t, err))
+}
+
+func TestWebhookRejectsOldDeliveries(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	sandbox := NewSandbox(SandboxConfig{WebhookSecret: "whsec_test", Now: func() time.Time { return now }})
+	event := &WebhookEvent{ID: "evt_1", Type: EventPaymentVoided, Payment: &Payment{ID: "pay_1", Status: StatusVoided}}
+
+	headers, body, err := sandbox.SignWebhook(event)
+	require.NoError(t, err)
+	parsed, err := sandbox.ParseWebhook(headers, body)
+	require.NoError(t, err)
+	assert.Equal(t, "pay_1", parsed.Payment.ID)
+
+	now = now.Add(10 * time.Minute)
+	_, err = sandbox.ParseWebhook(headers, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestProcessPaymentFromContextUsesRequestDefaults(t *testing.T) {
+	service, _, _ := newTestService()
+	ctx := webhookRequest(map[string]string{"Idempotency-Key": "req-key"}, nil)
+	ctx.Set("tenant_id", "tenant-1")
+
+	payment, err := service.ProcessPaymentFromContext(ctx, &PaymentRequest{Amount: 100, Currency: "USD", PaymentMethod: SandboxApproved})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-1", payment.TenantID)
+
+	body, err := json.Marshal(payment)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"status":"authorized"`)
+}
@@ -0,0 +1,413 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sandbox payment methods
+const (
+	// SandboxApproved is approved, as is any method not listed here
+	SandboxApproved = "tok_sandbox_approved"
+	// SandboxDeclined is declined with failure code "card_declined"
+	SandboxDeclined = "tok_sandbox_declined"
+	// SandboxChallenge requires a 3DS challenge. The challenge succeeds
+	// unless CompleteChallenge is passed the param result=failed.
+	SandboxChallenge = "tok_sandbox_challenge"
+)
+
+// SandboxSignatureHeader carries the sandbox's webhook signature,
+// "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">"
+const SandboxSignatureHeader = "Sandbox-Signature"
+
+// SandboxConfig configures a Sandbox provider
+type SandboxConfig struct {
+	// WebhookSecret signs and verifies webhook deliveries
+	WebhookSecret string
+	// WebhookTolerance bounds the age of a verified delivery, limiting
+	// replays (default: 5 minutes)
+	WebhookTolerance time.Duration
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Sandbox is an in-memory reference Provider. It moves no money, but it
+// follows the contract real adapters must: idempotency keys are honored,
+// payment state transitions are enforced and every change produces a
+// signed webhook event. Use it in tests and local development, and as the
+// model when writing an adapter for a processor.
+type Sandbox struct {
+	config SandboxConfig
+
+	mu          sync.Mutex
+	payments    map[string]*Payment
+	idempotency map[string]*sandboxResult
+	events      []*WebhookEvent
+	sequence    int
+	// captureAfterChallenge holds the capture flag of payments awaiting a
+	// challenge
+	captureAfterChallenge map[string]bool
+}
+
+// sandboxResult is a response saved under an idempotency key
+type sandboxResult struct {
+	fingerprint string
+	payment     *Payment
+	refund      *Refund
+	err         error
+}
+
+// NewSandbox creates a sandbox provider
+func NewSandbox(config SandboxConfig) *Sandbox {
+	if config.WebhookTolerance == 0 {
+		config.WebhookTolerance = 5 * time.Minute
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Sandbox{
+		config:                config,
+		payments:              make(map[string]*Payment),
+		idempotency:           make(map[string]*sandboxResult),
+		captureAfterChallenge: make(map[string]bool),
+	}
+}
+
+// Name implements Provider
+func (s *Sandbox) Name() string {
+	return "sandbox"
+}
+
+// ProcessPayment implements PaymentProcessor
+func (s *Sandbox) ProcessPayment(ctx context.Context, req *PaymentRequest) (*Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.idempotent("payment", req.IdempotencyKey, req, func() *sandboxResult {
+		now := s.config.Now()
+		payment := &Payment{
+			ID:        s.nextID("pay"),
+			Provider:  s.Name(),
+			TenantID:  req.TenantID,
+			Amount:    req.Amount,
+			Currency:  req.Currency,
+			Metadata:  req.Metadata,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		s.payments[payment.ID] = payment
+
+		if req.PaymentMethod == SandboxDeclined {
+			payment.Status = StatusFailed
+			payment.FailureCode = "card_declined"
+			payment.FailureMessage = "The card was declined"
+			s.emit(EventPaymentFailed, payment)
+			return &sandboxResult{payment: copyPayment(payment), err: fmt.Errorf("%w: %s", ErrDeclined, payment.FailureCode)}
+		}
+
+		if req.PaymentMethod == SandboxChallenge {
+			payment.Status = StatusRequiresAction
+			payment.NextAction = &NextAction{
+				RedirectURL: "https://sandbox.invalid/3ds/" + payment.ID + "?return_url=" + url.QueryEscape(req.ReturnURL),
+			}
+			s.captureAfterChallenge[payment.ID] = req.Capture
+			s.emit(EventPaymentRequiresAction, payment)
+			return &sandboxResult{payment: copyPayment(payment)}
+		}
+
+		s.authorize(payment, req.Capture)
+		return &sandboxResult{payment: copyPayment(payment)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(result.payment), result.err
+}
+
+// CompleteChallenge implements ChallengeCompleter
+func (s *Sandbox) CompleteChallenge(ctx context.Context, req *ChallengeCompletion) (*Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.idempotent("challenge", req.IdempotencyKey, req, func() *sandboxResult {
+		payment, err := s.payment(req.PaymentID, StatusRequiresAction)
+		if err != nil {
+			return &sandboxResult{err: err}
+		}
+		payment.NextAction = nil
+		payment.UpdatedAt = s.config.Now()
+		capture := s.captureAfterChallenge[payment.ID]
+		delete(s.captureAfterChallenge, payment.ID)
+
+		if req.Params["result"] == "failed" {
+			payment.Status = StatusFailed
+			payment.FailureCode = "authentication_failed"
+			payment.FailureMessage = "The customer failed the 3DS challenge"
+			s.emit(EventPaymentFailed, payment)
+			return &sandboxResult{payment: copyPayment(payment), err: fmt.Errorf("%w: %s", ErrDeclined, payment.FailureCode)}
+		}
+
+		s.authorize(payment, capture)
+		return &sandboxResult{payment: copyPayment(payment)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(result.payment), result.err
+}
+
+// authorize approves a payment, capturing it if asked. Callers hold s.mu.
+func (s *Sandbox) authorize(payment *Payment, capture bool) {
+	payment.Status = StatusAuthorized
+	event := EventPaymentAuthorized
+	if capture {
+		payment.Status = StatusCaptured
+		payment.CapturedAmount = payment.Amount
+		event = EventPaymentCaptured
+	}
+	s.emit(event, payment)
+}
+
+// Capture implements Capturer
+func (s *Sandbox) Capture(ctx context.Context, req *CaptureRequest) (*Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.idempotent("capture", req.IdempotencyKey, req, func() *sandboxResult {
+		payment, err := s.payment(req.PaymentID, StatusAuthorized)
+		if err != nil {
+			return &sandboxResult{err: err}
+		}
+		amount := req.Amount
+		if amount == 0 {
+			amount = payment.Amount
+		}
+		if amount > payment.Amount {
+			return &sandboxResult{err: fmt.Errorf("%w: capture of %d exceeds authorization of %d", ErrInvalidState, amount, payment.Amount)}
+		}
+
+		payment.Status = StatusCaptured
+		payment.CapturedAmount = amount
+		payment.UpdatedAt = s.config.Now()
+		s.emit(EventPaymentCaptured, payment)
+		return &sandboxResult{payment: copyPayment(payment)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(result.payment), result.err
+}
+
+// Void implements Voider
+func (s *Sandbox) Void(ctx context.Context, req *VoidRequest) (*Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.idempotent("void", req.IdempotencyKey, req, func() *sandboxResult {
+		payment, err := s.payment(req.PaymentID, StatusAuthorized, StatusRequiresAction)
+		if err != nil {
+			return &sandboxResult{err: err}
+		}
+
+		delete(s.captureAfterChallenge, payment.ID)
+		payment.NextAction = nil
+		payment.Status = StatusVoided
+		payment.UpdatedAt = s.config.Now()
+		s.emit(EventPaymentVoided, payment)
+		return &sandboxResult{payment: copyPayment(payment)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(result.payment), result.err
+}
+
+// Refund implements Refunder
+func (s *Sandbox) Refund(ctx context.Context, req *RefundRequest) (*Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.idempotent("refund", req.IdempotencyKey, req, func() *sandboxResult {
+		payment, err := s.payment(req.PaymentID, StatusCaptured, StatusPartiallyRefunded)
+		if err != nil {
+			return &sandboxResult{err: err}
+		}
+		remaining := payment.CapturedAmount - payment.RefundedAmount
+		amount := req.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount > remaining {
+			return &sandboxResult{err: fmt.Errorf("%w: refund of %d exceeds remaining %d", ErrInvalidState, amount, remaining)}
+		}
+
+		now := s.config.Now()
+		payment.RefundedAmount += amount
+		payment.Status = StatusPartiallyRefunded
+		if payment.RefundedAmount == payment.CapturedAmount {
+			payment.Status = StatusRefunded
+		}
+		payment.UpdatedAt = now
+		s.emit(EventPaymentRefunded, payment)
+
+		return &sandboxResult{refund: &Refund{
+			ID:        s.nextID("re"),
+			PaymentID: payment.ID,
+			Amount:    amount,
+			Currency:  payment.Currency,
+			Reason:    req.Reason,
+			CreatedAt: now,
+			Payment:   copyPayment(payment),
+		}}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	refund := *result.refund
+	refund.Payment = copyPayment(refund.Payment)
+	return &refund, nil
+}
+
+// Events returns the webhook events the sandbox has produced, oldest first
+func (s *Sandbox) Events() []*WebhookEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]*WebhookEvent, len(s.events))
+	copy(events, s.events)
+	return events
+}
+
+// SignWebhook encodes an event as the sandbox would deliver it, returning
+// the request headers and body
+func (s *Sandbox) SignWebhook(event *WebhookEvent) (map[string]string, []byte, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	timestamp := strconv.FormatInt(s.config.Now().Unix(), 10)
+	headers := map[string]string{
+		"Content-Type":         "application/json",
+		SandboxSignatureHeader: "t=" + timestamp + ",v1=" + s.signature(timestamp, body),
+	}
+	return headers, body, nil
+}
+
+// ParseWebhook implements WebhookParser
+func (s *Sandbox) ParseWebhook(headers map[string]string, body []byte) (*WebhookEvent, error) {
+	header := ""
+	for name, value := range headers {
+		if strings.EqualFold(name, SandboxSignatureHeader) {
+			header = value
+		}
+	}
+
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == "" || signature == "" {
+		return nil, fmt.Errorf("%w: missing %s header", ErrInvalidSignature, SandboxSignatureHeader)
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(timestamp, body))) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if age := s.config.Now().Sub(time.Unix(unix, 0)); age > s.config.WebhookTolerance || age < -s.config.WebhookTolerance {
+		return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	return &event, nil
+}
+
+// idempotent runs operation once per key, replaying its result for repeats
+// of the same request and rejecting reuse of the key for another request.
+// Callers hold s.mu.
+func (s *Sandbox) idempotent(operation, key string, req any, run func() *sandboxResult) (*sandboxResult, error) {
+	fingerprint, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	key = operation + ":" + key
+	if saved, ok := s.idempotency[key]; ok {
+		if saved.fingerprint != string(fingerprint) {
+			return nil, ErrIdempotencyConflict
+		}
+		return saved, nil
+	}
+
+	result := run()
+	result.fingerprint = string(fingerprint)
+	s.idempotency[key] = result
+	return result, nil
+}
+
+// payment finds a payment in one of the given states. Callers hold s.mu.
+func (s *Sandbox) payment(id string, states ...Status) (*Payment, error) {
+	payment, ok := s.payments[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	for _, state := range states {
+		if payment.Status == state {
+			return payment, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: payment %s is %s", ErrInvalidState, id, payment.Status)
+}
+
+// emit records a webhook event for a payment change. Callers hold s.mu.
+func (s *Sandbox) emit(eventType EventType, payment *Payment) {
+	s.events = append(s.events, &WebhookEvent{
+		ID:         s.nextID("evt"),
+		Type:       eventType,
+		OccurredAt: payment.UpdatedAt,
+		Payment:    copyPayment(payment),
+	})
+}
+
+func (s *Sandbox) nextID(prefix string) string {
+	s.sequence++
+	return fmt.Sprintf("%s_sandbox_%06d", prefix, s.sequence)
+}
+
+func (s *Sandbox) signature(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(s.config.WebhookSecret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func copyPayment(payment *Payment) *Payment {
+	if payment == nil {
+		return nil
+	}
+	clone := *payment
+	return &clone
+}
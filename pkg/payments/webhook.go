@@ -0,0 +1,201 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// ErrInvalidSignature is returned for webhook deliveries whose signature
+// doesn't verify
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// EventType is a provider webhook event type
+type EventType string
+
+const (
+	EventPaymentRequiresAction EventType = "payment.requires_action"
+	EventPaymentAuthorized     EventType = "payment.authorized"
+	EventPaymentCaptured       EventType = "payment.captured"
+	EventPaymentFailed         EventType = "payment.failed"
+	EventPaymentVoided         EventType = "payment.voided"
+	EventPaymentRefunded       EventType = "payment.refunded"
+)
+
+// WebhookEvent is a provider notification about a payment
+type WebhookEvent struct {
+	ID         string    `json:"id"`
+	Type       EventType `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// Payment is the provider's view of the payment when the event was sent
+	Payment *Payment `json:"payment"`
+}
+
+// WebhookParser verifies and decodes a provider's webhook deliveries.
+// Implementations return an error wrapping ErrInvalidSignature for
+// deliveries that don't verify.
+type WebhookParser interface {
+	ParseWebhook(headers map[string]string, body []byte) (*WebhookEvent, error)
+}
+
+// Store records payment state
+type Store interface {
+	// Get returns ErrNotFound for unknown payments
+	Get(ctx context.Context, paymentID string) (*Payment, error)
+	Save(ctx context.Context, payment *Payment) error
+}
+
+// MemoryStore is an in-memory payment store
+type MemoryStore struct {
+	mu       sync.RWMutex
+	payments map[string]Payment
+}
+
+// NewMemoryStore creates an empty payment store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{payments: make(map[string]Payment)}
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(ctx context.Context, paymentID string) (*Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	payment, ok := s.payments[paymentID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, paymentID)
+	}
+	return &payment, nil
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(ctx context.Context, payment *Payment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.payments[payment.ID] = *payment
+	return nil
+}
+
+// Reconciliation is the outcome of applying a webhook event
+type Reconciliation struct {
+	Event   *WebhookEvent
+	Payment *Payment
+	// Previous is the stored status before the event, empty if the
+	// payment wasn't stored
+	Previous Status
+	// Changed is false for duplicate and out-of-order events, which leave
+	// the stored payment as it was
+	Changed bool
+}
+
+// Reconcile applies a webhook event to the stored payment. Providers retry
+// deliveries and don't guarantee their order, so an event only replaces
+// the stored state when it is further along the payment's lifecycle.
+func Reconcile(ctx context.Context, store Store, event *WebhookEvent) (*Reconciliation, error) {
+	if event.Payment == nil || event.Payment.ID == "" {
+		return nil, lift.NewLiftError("INVALID_WEBHOOK_EVENT", "Webhook event has no payment", 400)
+	}
+	result := &Reconciliation{Event: event, Payment: event.Payment}
+
+	stored, err := store.Get(ctx, event.Payment.ID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		// Payments created outside this service are adopted as they are
+	case err != nil:
+		return nil, fmt.Errorf("failed to load payment %s: %w", event.Payment.ID, err)
+	default:
+		result.Previous = stored.Status
+		if !supersedes(event.Payment, stored) {
+			result.Payment = stored
+			return result, nil
+		}
+	}
+
+	if err := store.Save(ctx, event.Payment); err != nil {
+		return nil, fmt.Errorf("failed to record payment %s: %w", event.Payment.ID, err)
+	}
+	result.Changed = true
+	return result, nil
+}
+
+// Reconcile applies a webhook event to the service's store
+func (s *Service) Reconcile(ctx context.Context, event *WebhookEvent) (*Reconciliation, error) {
+	if s.config.Store == nil {
+		return nil, fmt.Errorf("payment reconciliation requires a store")
+	}
+	return Reconcile(ctx, s.config.Store, event)
+}
+
+// WebhookHandler returns a handler for the provider's webhook deliveries.
+// Each verified event is reconciled against the store and, when it changed
+// the stored payment, passed to onChange (which may be nil). Deliveries
+// that fail verification are rejected with 401; failures reconciling or in
+// onChange return an error so the provider retries.
+//
+//	app.POST("/webhooks/payments", payer.WebhookHandler(onPaymentChange))
+func (s *Service) WebhookHandler(onChange func(ctx *lift.Context, result *Reconciliation) error) lift.Handler {
+	return lift.HandlerFunc(func(ctx *lift.Context) error {
+		parser, ok := s.config.Provider.(WebhookParser)
+		if !ok {
+			return fmt.Errorf("payment provider %s does not support webhooks", s.config.Provider.Name())
+		}
+
+		event, err := parser.ParseWebhook(ctx.Request.Headers, ctx.Request.Body)
+		if errors.Is(err, ErrInvalidSignature) {
+			return lift.NewLiftError("INVALID_SIGNATURE", "Webhook signature is invalid", 401).WithCause(err)
+		}
+		if err != nil {
+			return lift.NewLiftError("INVALID_WEBHOOK_EVENT", "Webhook event could not be parsed", 400).WithCause(err)
+		}
+
+		result, err := s.Reconcile(ctx.Context, event)
+		if err != nil {
+			return err
+		}
+		if ctx.Logger != nil {
+			ctx.Logger.Info("Payment webhook reconciled", map[string]any{
+				"event_id":   event.ID,
+				"event_type": event.Type,
+				"payment_id": result.Payment.ID,
+				"status":     result.Payment.Status,
+				"changed":    result.Changed,
+			})
+		}
+		if result.Changed && onChange != nil {
+			if err := onChange(ctx, result); err != nil {
+				return err
+			}
+		}
+		return ctx.OK(map[string]any{"received": true})
+	})
+}
+
+// statusRank orders statuses along the payment lifecycle
+var statusRank = map[Status]int{
+	StatusPending:           0,
+	StatusRequiresAction:    1,
+	StatusAuthorized:        2,
+	StatusCaptured:          3,
+	StatusPartiallyRefunded: 4,
+	StatusRefunded:          5,
+	StatusVoided:            5,
+	StatusFailed:            5,
+}
+
+// supersedes reports whether next is further along than current. Partial
+// captures and refunds keep their status, so amounts break ties.
+func supersedes(next, current *Payment) bool {
+	if statusRank[next.Status] != statusRank[current.Status] {
+		return statusRank[next.Status] > statusRank[current.Status]
+	}
+	if next.Status != current.Status {
+		// Terminal states don't replace each other
+		return false
+	}
+	return next.CapturedAmount > current.CapturedAmount || next.RefundedAmount > current.RefundedAmount
+}
@@ -0,0 +1,167 @@
+package rbac
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBStore implements Store using a DynamoDB table with a string
+// partition key "pk" and sort key "sk". Each tenant is one partition,
+// "tenant#<id>", holding its roles as "role#<name>" and assignments as
+// "assignment#<user>#<role>", so a user's roles are one query.
+type DynamoDBStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBStore creates a DynamoDB-backed store
+func NewDynamoDBStore(client *dynamodb.Client, tableName string) *DynamoDBStore {
+	return &DynamoDBStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SaveRole implements Store
+func (d *DynamoDBStore) SaveRole(ctx context.Context, role *Role) error {
+	return d.put(ctx, role.TenantID, "role#"+role.Name, role)
+}
+
+// GetRole implements Store
+func (d *DynamoDBStore) GetRole(ctx context.Context, tenantID, name string) (*Role, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       itemKey(tenantID, "role#"+name),
+		// Permission changes must be seen as soon as the cache expires
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, ErrRoleNotFound
+	}
+
+	var role Role
+	if err := attributevalue.UnmarshalMap(result.Item, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// ListRoles implements Store
+func (d *DynamoDBStore) ListRoles(ctx context.Context, tenantID string) ([]*Role, error) {
+	roles := []*Role{}
+	err := d.query(ctx, tenantID, "role#", func(item map[string]types.AttributeValue) error {
+		var role Role
+		if err := attributevalue.UnmarshalMap(item, &role); err != nil {
+			return err
+		}
+		roles = append(roles, &role)
+		return nil
+	})
+	return roles, err
+}
+
+// DeleteRole implements Store
+func (d *DynamoDBStore) DeleteRole(ctx context.Context, tenantID, name string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       itemKey(tenantID, "role#"+name),
+	})
+	return err
+}
+
+// SaveAssignment implements Store
+func (d *DynamoDBStore) SaveAssignment(ctx context.Context, assignment *Assignment) error {
+	return d.put(ctx, assignment.TenantID, assignmentKey(assignment.UserID, assignment.Role), assignment)
+}
+
+// DeleteAssignment implements Store
+func (d *DynamoDBStore) DeleteAssignment(ctx context.Context, tenantID, userID, role string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       itemKey(tenantID, assignmentKey(userID, role)),
+	})
+	return err
+}
+
+// ListAssignments implements Store
+func (d *DynamoDBStore) ListAssignments(ctx context.Context, tenantID, userID string) ([]*Assignment, error) {
+	prefix := "assignment#"
+	if userID != "" {
+		prefix += userID + "#"
+	}
+	assignments := []*Assignment{}
+	err := d.query(ctx, tenantID, prefix, func(item map[string]types.AttributeValue) error {
+		var assignment Assignment
+		if err := attributevalue.UnmarshalMap(item, &assignment); err != nil {
+			return err
+		}
+		// User IDs containing "#" can share a prefix with another user's
+		if userID != "" && assignment.UserID != userID {
+			return nil
+		}
+		assignments = append(assignments, &assignment)
+		return nil
+	})
+	return assignments, err
+}
+
+// put stores an item in the tenant's partition
+func (d *DynamoDBStore) put(ctx context.Context, tenantID, sortKey string, value any) error {
+	item, err := attributevalue.MarshalMap(value)
+	if err != nil {
+		return err
+	}
+	for name, key := range itemKey(tenantID, sortKey) {
+		item[name] = key
+	}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	return err
+}
+
+// query calls fn with every item in the tenant's partition whose sort key
+// starts with prefix
+func (d *DynamoDBStore) query(ctx context.Context, tenantID, prefix string, fn func(map[string]types.AttributeValue) error) error {
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":     &types.AttributeValueMemberS{Value: "tenant#" + tenantID},
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func assignmentKey(userID, role string) string {
+	return "assignment#" + userID + "#" + role
+}
+
+func itemKey(tenantID, sortKey string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "tenant#" + tenantID},
+		"sk": &types.AttributeValueMemberS{Value: sortKey},
+	}
+}
@@ -0,0 +1,143 @@
+package rbac
+
+import (
+	"errors"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/middleware"
+)
+
+// roleBody is the body accepted by PUT /roles/:name
+type roleBody struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+// App returns the role management API, to be mounted into an application
+// that authenticates requests:
+//
+//	GET    /roles                        list roles (rbac:read)
+//	GET    /roles/:name                  get a role (rbac:read)
+//	PUT    /roles/:name                  create or replace a role (rbac:manage)
+//	DELETE /roles/:name                  delete an unassigned role (rbac:manage)
+//	GET    /users/:user_id/roles         list a user's roles (rbac:read)
+//	PUT    /users/:user_id/roles/:name   assign a role (rbac:manage)
+//	DELETE /users/:user_id/roles/:name   unassign a role (rbac:manage)
+//	GET    /users/:user_id/permissions   a user's effective permissions (rbac:read)
+//	GET    /export                       every user's effective permissions (rbac:read)
+//
+// Every route acts on the caller's own tenant.
+func (s *Service) App() *lift.App {
+	app := lift.New()
+	read := middleware.RequirePermission(s.Permissions, PermissionRead)
+	manage := middleware.RequirePermission(s.Permissions, PermissionManage)
+
+	reads := app.Group("", read)
+	reads.GET("/roles", s.listRoles)
+	reads.GET("/roles/:name", s.getRole)
+	reads.GET("/users/:user_id/roles", s.listUserRoles)
+	reads.GET("/users/:user_id/permissions", s.userPermissions)
+	reads.GET("/export", s.export)
+
+	writes := app.Group("", manage)
+	writes.PUT("/roles/:name", s.putRole)
+	writes.DELETE("/roles/:name", s.deleteRole)
+	writes.PUT("/users/:user_id/roles/:name", s.assign)
+	writes.DELETE("/users/:user_id/roles/:name", s.unassign)
+	return app
+}
+
+func (s *Service) listRoles(ctx *lift.Context) error {
+	roles, err := s.ListRoles(ctx.Context, ctx.TenantID())
+	if err != nil {
+		return rbacError(err)
+	}
+	return ctx.OK(map[string]any{"roles": roles})
+}
+
+func (s *Service) getRole(ctx *lift.Context) error {
+	role, err := s.GetRole(ctx.Context, ctx.TenantID(), ctx.Param("name"))
+	if err != nil {
+		return rbacError(err)
+	}
+	return ctx.OK(role)
+}
+
+func (s *Service) putRole(ctx *lift.Context) error {
+	var body roleBody
+	if err := ctx.ParseRequest(&body); err != nil {
+		return err
+	}
+	role, err := s.PutRole(ctx.Context, &Role{
+		TenantID:    ctx.TenantID(),
+		Name:        ctx.Param("name"),
+		Description: body.Description,
+		Permissions: body.Permissions,
+	})
+	if err != nil {
+		return rbacError(err)
+	}
+	return ctx.OK(role)
+}
+
+func (s *Service) deleteRole(ctx *lift.Context) error {
+	if err := s.DeleteRole(ctx.Context, ctx.TenantID(), ctx.Param("name")); err != nil {
+		return rbacError(err)
+	}
+	ctx.Response.Status(204)
+	return nil
+}
+
+func (s *Service) listUserRoles(ctx *lift.Context) error {
+	assignments, err := s.UserRoles(ctx.Context, ctx.TenantID(), ctx.Param("user_id"))
+	if err != nil {
+		return rbacError(err)
+	}
+	return ctx.OK(map[string]any{"roles": assignments})
+}
+
+func (s *Service) assign(ctx *lift.Context) error {
+	assignment, err := s.Assign(ctx.Context, ctx.TenantID(), ctx.Param("user_id"), ctx.Param("name"), ctx.UserID())
+	if err != nil {
+		return rbacError(err)
+	}
+	return ctx.OK(assignment)
+}
+
+func (s *Service) unassign(ctx *lift.Context) error {
+	if err := s.Unassign(ctx.Context, ctx.TenantID(), ctx.Param("user_id"), ctx.Param("name")); err != nil {
+		return rbacError(err)
+	}
+	ctx.Response.Status(204)
+	return nil
+}
+
+func (s *Service) userPermissions(ctx *lift.Context) error {
+	permissions, err := s.Permissions(ctx.Context, ctx.TenantID(), ctx.Param("user_id"))
+	if err != nil {
+		return rbacError(err)
+	}
+	return ctx.OK(map[string]any{"user_id": ctx.Param("user_id"), "permissions": permissions})
+}
+
+func (s *Service) export(ctx *lift.Context) error {
+	export, err := s.Export(ctx.Context, ctx.TenantID())
+	if err != nil {
+		return rbacError(err)
+	}
+	return ctx.OK(export)
+}
+
+// rbacError maps service errors to HTTP errors
+func rbacError(err error) error {
+	switch {
+	case errors.Is(err, ErrRoleNotFound):
+		return lift.NotFound("Role not found")
+	case errors.Is(err, ErrRoleInUse):
+		return lift.NewLiftError("ROLE_IN_USE", "Role is assigned to users", 409)
+	case errors.Is(err, ErrInvalidRole):
+		return lift.NewLiftError("INVALID_ROLE", err.Error(), 400)
+	default:
+		return lift.NewLiftError("RBAC_FAILED", "Failed to manage roles", 500).WithCause(err)
+	}
+}
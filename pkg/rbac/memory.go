@@ -0,0 +1,95 @@
+package rbac
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store for tests and local development
+type MemoryStore struct {
+	mu          sync.Mutex
+	roles       map[string]Role
+	assignments map[string]Assignment
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		roles:       make(map[string]Role),
+		assignments: make(map[string]Assignment),
+	}
+}
+
+// SaveRole implements Store
+func (m *MemoryStore) SaveRole(ctx context.Context, role *Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := *role
+	saved.Permissions = slices.Clone(role.Permissions)
+	m.roles[role.TenantID+"#"+role.Name] = saved
+	return nil
+}
+
+// GetRole implements Store
+func (m *MemoryStore) GetRole(ctx context.Context, tenantID, name string) (*Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	role, ok := m.roles[tenantID+"#"+name]
+	if !ok {
+		return nil, ErrRoleNotFound
+	}
+	role.Permissions = slices.Clone(role.Permissions)
+	return &role, nil
+}
+
+// ListRoles implements Store
+func (m *MemoryStore) ListRoles(ctx context.Context, tenantID string) ([]*Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	roles := []*Role{}
+	for _, role := range m.roles {
+		if role.TenantID == tenantID {
+			role.Permissions = slices.Clone(role.Permissions)
+			roles = append(roles, &role)
+		}
+	}
+	return roles, nil
+}
+
+// DeleteRole implements Store
+func (m *MemoryStore) DeleteRole(ctx context.Context, tenantID, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.roles, tenantID+"#"+name)
+	return nil
+}
+
+// SaveAssignment implements Store
+func (m *MemoryStore) SaveAssignment(ctx context.Context, assignment *Assignment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.assignments[assignment.TenantID+"#"+assignment.UserID+"#"+assignment.Role] = *assignment
+	return nil
+}
+
+// DeleteAssignment implements Store
+func (m *MemoryStore) DeleteAssignment(ctx context.Context, tenantID, userID, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.assignments, tenantID+"#"+userID+"#"+role)
+	return nil
+}
+
+// ListAssignments implements Store
+func (m *MemoryStore) ListAssignments(ctx context.Context, tenantID, userID string) ([]*Assignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assignments := []*Assignment{}
+	for _, assignment := range m.assignments {
+		if assignment.TenantID == tenantID && (userID == "" || assignment.UserID == userID) {
+			assignments = append(assignments, &assignment)
+		}
+	}
+	return assignments, nil
+}
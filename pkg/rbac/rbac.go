@@ -0,0 +1,400 @@
+// Package rbac manages roles and permissions per tenant. Each tenant
+// defines its own roles, each a named set of "resource:action"
+// permissions, and assigns them to its users. Resolved permissions are
+// cached, so Service.Permissions can back middleware.RequirePermission on
+// every request:
+//
+//	roles, _ := rbac.NewService(rbac.Config{
+//		Store: rbac.NewDynamoDBStore(client, "rbac"),
+//	})
+//	app.Use(middleware.JWT(jwtConfig))
+//	app.Mount("/rbac", roles.App())
+//	refunds := app.Group("/refunds", middleware.RequirePermission(roles.Permissions, "refunds:create"))
+//
+// The mounted API lets users with rbac:manage define roles and assign them
+// within their own tenant, and users with rbac:read list them and export
+// every user's effective permissions for access reviews. Grant the first
+// administrator with Service.Assign.
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/middleware"
+)
+
+// Permissions used by the mounted API
+const (
+	PermissionRead   = "rbac:read"
+	PermissionManage = "rbac:manage"
+)
+
+var (
+	// ErrRoleNotFound is returned for roles the tenant hasn't defined
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrRoleInUse is returned when deleting a role that is still assigned
+	ErrRoleInUse = errors.New("role is assigned to users")
+	// ErrInvalidRole is returned for role names and permissions that
+	// aren't well formed, or permissions not in Config.Permissions
+	ErrInvalidRole = errors.New("invalid role")
+)
+
+// Role is a named set of permissions defined by a tenant
+type Role struct {
+	TenantID    string    `json:"tenant_id" dynamodbav:"tenant_id"`
+	Name        string    `json:"name" dynamodbav:"name"`
+	Description string    `json:"description,omitempty" dynamodbav:"description,omitempty"`
+	Permissions []string  `json:"permissions" dynamodbav:"permissions"`
+	CreatedAt   time.Time `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" dynamodbav:"updated_at"`
+}
+
+// Assignment grants a role to a user
+type Assignment struct {
+	TenantID   string    `json:"tenant_id" dynamodbav:"tenant_id"`
+	UserID     string    `json:"user_id" dynamodbav:"user_id"`
+	Role       string    `json:"role" dynamodbav:"role"`
+	AssignedBy string    `json:"assigned_by,omitempty" dynamodbav:"assigned_by,omitempty"`
+	AssignedAt time.Time `json:"assigned_at" dynamodbav:"assigned_at"`
+}
+
+// Store persists roles and assignments
+type Store interface {
+	SaveRole(ctx context.Context, role *Role) error
+	// GetRole returns ErrRoleNotFound for unknown roles
+	GetRole(ctx context.Context, tenantID, name string) (*Role, error)
+	ListRoles(ctx context.Context, tenantID string) ([]*Role, error)
+	DeleteRole(ctx context.Context, tenantID, name string) error
+
+	SaveAssignment(ctx context.Context, assignment *Assignment) error
+	DeleteAssignment(ctx context.Context, tenantID, userID, role string) error
+	// ListAssignments returns a user's assignments, or every assignment in
+	// the tenant when userID is ""
+	ListAssignments(ctx context.Context, tenantID, userID string) ([]*Assignment, error)
+}
+
+// Config configures the Service
+type Config struct {
+	// Store persists roles and assignments (required)
+	Store Store
+	// Permissions, if set, are the only permissions roles may grant, so a
+	// typo can't create a role that silently grants nothing
+	Permissions []string
+	// CacheTTL is how long a user's resolved permissions are cached
+	// (default: 1m). Changes made through this Service take effect
+	// immediately on this instance; other instances see them within CacheTTL.
+	CacheTTL time.Duration
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Service manages roles and resolves users' permissions
+type Service struct {
+	config Config
+
+	mu    sync.RWMutex
+	cache map[string]cachedPermissions
+}
+
+type cachedPermissions struct {
+	permissions []string
+	expires     time.Time
+}
+
+// NewService creates a Service with the given configuration
+func NewService(config Config) (*Service, error) {
+	if config.Store == nil {
+		return nil, fmt.Errorf("rbac requires a store")
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = time.Minute
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Service{config: config, cache: make(map[string]cachedPermissions)}, nil
+}
+
+var (
+	roleNamePattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+	permissionPattern = regexp.MustCompile(`^(\*|[a-z0-9_.-]+:(\*|[a-z0-9_.-]+))$`)
+)
+
+// PutRole creates or replaces a tenant's role
+func (s *Service) PutRole(ctx context.Context, role *Role) (*Role, error) {
+	if !roleNamePattern.MatchString(role.Name) {
+		return nil, fmt.Errorf("%w: name must be lowercase letters, digits, '_', '.' or '-'", ErrInvalidRole)
+	}
+	permissions := slices.Clone(role.Permissions)
+	sort.Strings(permissions)
+	permissions = slices.Compact(permissions)
+	for _, permission := range permissions {
+		if !permissionPattern.MatchString(permission) {
+			return nil, fmt.Errorf("%w: permission %q must be resource:action", ErrInvalidRole, permission)
+		}
+		if len(s.config.Permissions) > 0 && !s.knownPermission(permission) {
+			return nil, fmt.Errorf("%w: unknown permission %q", ErrInvalidRole, permission)
+		}
+	}
+
+	now := s.config.Now()
+	saved := &Role{
+		TenantID:    role.TenantID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	existing, err := s.config.Store.GetRole(ctx, role.TenantID, role.Name)
+	if err != nil && !errors.Is(err, ErrRoleNotFound) {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	if existing != nil {
+		saved.CreatedAt = existing.CreatedAt
+	}
+	if err := s.config.Store.SaveRole(ctx, saved); err != nil {
+		return nil, fmt.Errorf("failed to save role: %w", err)
+	}
+	s.invalidateTenant(role.TenantID)
+	return saved, nil
+}
+
+// knownPermission reports whether a permission, or every permission a
+// wildcard covers, is in Config.Permissions
+func (s *Service) knownPermission(permission string) bool {
+	if slices.Contains(s.config.Permissions, permission) || permission == "*" {
+		return true
+	}
+	resource, action, _ := strings.Cut(permission, ":")
+	if action != "*" {
+		return false
+	}
+	return slices.ContainsFunc(s.config.Permissions, func(known string) bool {
+		knownResource, _, _ := strings.Cut(known, ":")
+		return knownResource == resource
+	})
+}
+
+// GetRole returns a tenant's role
+func (s *Service) GetRole(ctx context.Context, tenantID, name string) (*Role, error) {
+	return s.config.Store.GetRole(ctx, tenantID, name)
+}
+
+// ListRoles returns a tenant's roles sorted by name
+func (s *Service) ListRoles(ctx context.Context, tenantID string) ([]*Role, error) {
+	roles, err := s.config.Store.ListRoles(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+// DeleteRole deletes a tenant's role. Roles still assigned to users can't
+// be deleted; unassign them first.
+func (s *Service) DeleteRole(ctx context.Context, tenantID, name string) error {
+	if _, err := s.config.Store.GetRole(ctx, tenantID, name); err != nil {
+		return err
+	}
+	assignments, err := s.config.Store.ListAssignments(ctx, tenantID, "")
+	if err != nil {
+		return fmt.Errorf("failed to list assignments: %w", err)
+	}
+	if slices.ContainsFunc(assignments, func(a *Assignment) bool { return a.Role == name }) {
+		return ErrRoleInUse
+	}
+	if err := s.config.Store.DeleteRole(ctx, tenantID, name); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	s.invalidateTenant(tenantID)
+	return nil
+}
+
+// Assign grants a role to a user. assignedBy is recorded for audits.
+func (s *Service) Assign(ctx context.Context, tenantID, userID, role, assignedBy string) (*Assignment, error) {
+	if _, err := s.config.Store.GetRole(ctx, tenantID, role); err != nil {
+		return nil, err
+	}
+	assignment := &Assignment{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Role:       role,
+		AssignedBy: assignedBy,
+		AssignedAt: s.config.Now(),
+	}
+	if err := s.config.Store.SaveAssignment(ctx, assignment); err != nil {
+		return nil, fmt.Errorf("failed to save assignment: %w", err)
+	}
+	s.invalidate(tenantID, userID)
+	return assignment, nil
+}
+
+// Unassign removes a role from a user
+func (s *Service) Unassign(ctx context.Context, tenantID, userID, role string) error {
+	if err := s.config.Store.DeleteAssignment(ctx, tenantID, userID, role); err != nil {
+		return fmt.Errorf("failed to delete assignment: %w", err)
+	}
+	s.invalidate(tenantID, userID)
+	return nil
+}
+
+// UserRoles returns a user's assignments
+func (s *Service) UserRoles(ctx context.Context, tenantID, userID string) ([]*Assignment, error) {
+	assignments, err := s.config.Store.ListAssignments(ctx, tenantID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list assignments: %w", err)
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].Role < assignments[j].Role })
+	return assignments, nil
+}
+
+// Permissions returns a user's effective permissions, the union of their
+// roles' permissions, sorted. It is a middleware.PermissionResolver, and
+// caches for Config.CacheTTL.
+func (s *Service) Permissions(ctx context.Context, tenantID, userID string) ([]string, error) {
+	key := cacheKey(tenantID, userID)
+	s.mu.RLock()
+	cached, ok := s.cache[key]
+	s.mu.RUnlock()
+	if ok && s.config.Now().Before(cached.expires) {
+		return cached.permissions, nil
+	}
+
+	assignments, err := s.config.Store.ListAssignments(ctx, tenantID, userID)
+	if err != nil {
+		// Don't cache failures so the next request retries
+		return nil, fmt.Errorf("failed to list assignments: %w", err)
+	}
+	roles, err := s.rolesByName(ctx, tenantID, assignments)
+	if err != nil {
+		return nil, err
+	}
+	permissions := effectivePermissions(assignments, roles)
+
+	s.mu.Lock()
+	s.cache[key] = cachedPermissions{permissions: permissions, expires: s.config.Now().Add(s.config.CacheTTL)}
+	s.mu.Unlock()
+	return permissions, nil
+}
+
+var _ middleware.PermissionResolver = (*Service)(nil).Permissions
+
+// rolesByName loads the roles used by the assignments. A role deleted
+// since it was assigned grants nothing.
+func (s *Service) rolesByName(ctx context.Context, tenantID string, assignments []*Assignment) (map[string]*Role, error) {
+	roles := make(map[string]*Role, len(assignments))
+	for _, assignment := range assignments {
+		if _, ok := roles[assignment.Role]; ok {
+			continue
+		}
+		role, err := s.config.Store.GetRole(ctx, tenantID, assignment.Role)
+		if errors.Is(err, ErrRoleNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role: %w", err)
+		}
+		roles[assignment.Role] = role
+	}
+	return roles, nil
+}
+
+func effectivePermissions(assignments []*Assignment, roles map[string]*Role) []string {
+	permissions := []string{}
+	for _, assignment := range assignments {
+		if role, ok := roles[assignment.Role]; ok {
+			permissions = append(permissions, role.Permissions...)
+		}
+	}
+	sort.Strings(permissions)
+	return slices.Compact(permissions)
+}
+
+// UserPermissions is a user's roles and effective permissions in an
+// Export
+type UserPermissions struct {
+	UserID      string        `json:"user_id"`
+	Roles       []*Assignment `json:"roles"`
+	Permissions []string      `json:"permissions"`
+}
+
+// Export is a tenant's roles and every user's effective permissions, for
+// access reviews
+type Export struct {
+	TenantID    string             `json:"tenant_id"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Roles       []*Role            `json:"roles"`
+	Users       []*UserPermissions `json:"users"`
+}
+
+// Export returns the tenant's roles and every user's effective
+// permissions, read from the store rather than the cache
+func (s *Service) Export(ctx context.Context, tenantID string) (*Export, error) {
+	roles, err := s.ListRoles(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.config.Store.ListAssignments(ctx, tenantID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list assignments: %w", err)
+	}
+
+	rolesByName := make(map[string]*Role, len(roles))
+	for _, role := range roles {
+		rolesByName[role.Name] = role
+	}
+	byUser := make(map[string][]*Assignment)
+	for _, assignment := range assignments {
+		byUser[assignment.UserID] = append(byUser[assignment.UserID], assignment)
+	}
+
+	export := &Export{
+		TenantID:    tenantID,
+		GeneratedAt: s.config.Now(),
+		Roles:       roles,
+		Users:       make([]*UserPermissions, 0, len(byUser)),
+	}
+	for userID, userAssignments := range byUser {
+		sort.Slice(userAssignments, func(i, j int) bool { return userAssignments[i].Role < userAssignments[j].Role })
+		export.Users = append(export.Users, &UserPermissions{
+			UserID:      userID,
+			Roles:       userAssignments,
+			Permissions: effectivePermissions(userAssignments, rolesByName),
+		})
+	}
+	sort.Slice(export.Users, func(i, j int) bool { return export.Users[i].UserID < export.Users[j].UserID })
+	return export, nil
+}
+
+// invalidate drops a user's cached permissions
+func (s *Service) invalidate(tenantID, userID string) {
+	s.mu.Lock()
+	delete(s.cache, cacheKey(tenantID, userID))
+	s.mu.Unlock()
+}
+
+// invalidateTenant drops the cached permissions of every user in a tenant,
+// after one of its roles changed
+func (s *Service) invalidateTenant(tenantID string) {
+	prefix := cacheKey(tenantID, "")
+	s.mu.Lock()
+	for key := range s.cache {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.cache, key)
+		}
+	}
+	s.mu.Unlock()
+}
+
+func cacheKey(tenantID, userID string) string {
+	return tenantID + "\x00" + userID
+}
@@ -0,0 +1,227 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T, config Config) (*Service, *time.Time) {
+	t.Helper()
+	now := testNow
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	config.Now = func() time.Time { return now }
+	service, err := NewService(config)
+	require.NoError(t, err)
+	return service, &now
+}
+
+func TestNewServiceRequiresStore(t *testing.T) {
+	_, err := NewService(Config{})
+	assert.Error(t, err)
+}
+
+func TestPermissions(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(t, Config{})
+
+	_, err := service.PutRole(ctx, &Role{TenantID: "acme", Name: "support", Permissions: []string{"refunds:read", "customers:read"}})
+	require.NoError(t, err)
+	_, err = service.PutRole(ctx, &Role{TenantID: "acme", Name: "finance", Permissions: []string{"refunds:*", "refunds:read"}})
+	require.NoError(t, err)
+	_, err = service.PutRole(ctx, &Role{TenantID: "globex", Name: "support", Permissions: []string{"*"}})
+	require.NoError(t, err)
+
+	_, err = service.Assign(ctx, "acme", "ada", "support", "admin")
+	require.NoError(t, err)
+	_, err = service.Assign(ctx, "acme", "ada", "finance", "admin")
+	require.NoError(t, err)
+
+	permissions, err := service.Permissions(ctx, "acme", "ada")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customers:read", "refunds:*", "refunds:read"}, permissions)
+
+	permissions, err = service.Permissions(ctx, "globex", "ada")
+	require.NoError(t, err)
+	assert.Empty(t, permissions, "roles are per tenant")
+
+	_, err = service.Assign(ctx, "acme", "ada", "auditor", "admin")
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+
+	// Changes through the service take effect immediately
+	_, err = service.PutRole(ctx, &Role{TenantID: "acme", Name: "support", Permissions: []string{"customers:read"}})
+	require.NoError(t, err)
+	require.NoError(t, service.Unassign(ctx, "acme", "ada", "finance"))
+	permissions, err = service.Permissions(ctx, "acme", "ada")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customers:read"}, permissions)
+}
+
+func TestPermissionsCache(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	service, now := newTestService(t, Config{Store: store, CacheTTL: time.Minute})
+
+	_, err := service.PutRole(ctx, &Role{TenantID: "acme", Name: "support", Permissions: []string{"refunds:read"}})
+	require.NoError(t, err)
+	_, err = service.Assign(ctx, "acme", "ada", "support", "admin")
+	require.NoError(t, err)
+	_, err = service.Permissions(ctx, "acme", "ada")
+	require.NoError(t, err)
+
+	// Another instance revokes the role
+	require.NoError(t, store.DeleteAssignment(ctx, "acme", "ada", "support"))
+	permissions, err := service.Permissions(ctx, "acme", "ada")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"refunds:read"}, permissions)
+
+	*now = now.Add(time.Minute)
+	permissions, err = service.Permissions(ctx, "acme", "ada")
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+}
+
+func TestPutRoleValidation(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService(t, Config{Permissions: []string{"refunds:read", "refunds:create", "customers:read"}})
+
+	for _, role := range []*Role{
+		{TenantID: "acme", Name: "Support Team", Permissions: []string{"refunds:read"}},
+		{TenantID: "acme", Name: "support", Permissions: []string{"refunds"}},
+		{TenantID: "acme", Name: "support", Permissions: []string{"refunds:delete"}},
+		{TenantID: "acme", Name: "support", Permissions: []string{"payments:*"}},
+	} {
+		_, err := service.PutRole(ctx, role)
+		assert.ErrorIs(t, err, ErrInvalidRole, role.Name, role.Permissions)
+	}
+
+	role, err := service.PutRole(ctx, &Role{TenantID: "acme", Name: "support", Permissions: []string{"refunds:*", "customers:read"}})
+	require.NoError(t, err)
+	assert.Equal(t, testNow, role.CreatedAt)
+
+	*now = now.Add(time.Hour)
+	role, err = service.PutRole(ctx, &Role{TenantID: "acme", Name: "support", Permissions: []string{"customers:read"}})
+	require.NoError(t, err)
+	assert.Equal(t, testNow, role.CreatedAt)
+	assert.Equal(t, testNow.Add(time.Hour), role.UpdatedAt)
+}
+
+func TestDeleteRole(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(t, Config{})
+
+	_, err := service.PutRole(ctx, &Role{TenantID: "acme", Name: "support", Permissions: []string{"refunds:read"}})
+	require.NoError(t, err)
+	_, err = service.Assign(ctx, "acme", "ada", "support", "admin")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.DeleteRole(ctx, "acme", "support"), ErrRoleInUse)
+	require.NoError(t, service.Unassign(ctx, "acme", "ada", "support"))
+	require.NoError(t, service.DeleteRole(ctx, "acme", "support"))
+	assert.ErrorIs(t, service.DeleteRole(ctx, "acme", "support"), ErrRoleNotFound)
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(t, Config{})
+
+	_, err := service.PutRole(ctx, &Role{TenantID: "acme", Name: "support", Permissions: []string{"refunds:read"}})
+	require.NoError(t, err)
+	_, err = service.PutRole(ctx, &Role{TenantID: "acme", Name: "finance", Permissions: []string{"refunds:create", "refunds:read"}})
+	require.NoError(t, err)
+	for _, assignment := range [][2]string{{"grace", "support"}, {"ada", "support"}, {"ada", "finance"}} {
+		_, err = service.Assign(ctx, "acme", assignment[0], assignment[1], "admin")
+		require.NoError(t, err)
+	}
+
+	export, err := service.Export(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", export.TenantID)
+	assert.Equal(t, testNow, export.GeneratedAt)
+	require.Len(t, export.Roles, 2)
+	assert.Equal(t, "finance", export.Roles[0].Name)
+
+	require.Len(t, export.Users, 2)
+	assert.Equal(t, "ada", export.Users[0].UserID)
+	assert.Equal(t, []string{"refunds:create", "refunds:read"}, export.Users[0].Permissions)
+	require.Len(t, export.Users[0].Roles, 2)
+	assert.Equal(t, "finance", export.Users[0].Roles[0].Role)
+	assert.Equal(t, "admin", export.Users[0].Roles[0].AssignedBy)
+	assert.Equal(t, "grace", export.Users[1].UserID)
+	assert.Equal(t, []string{"refunds:read"}, export.Users[1].Permissions)
+}
+
+func TestApp(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(t, Config{})
+	_, err := service.PutRole(ctx, &Role{TenantID: "acme", Name: "admin", Permissions: []string{"rbac:*"}})
+	require.NoError(t, err)
+	_, err = service.PutRole(ctx, &Role{TenantID: "acme", Name: "reviewer", Permissions: []string{PermissionRead}})
+	require.NoError(t, err)
+	_, err = service.Assign(ctx, "acme", "root", "admin", "bootstrap")
+	require.NoError(t, err)
+	_, err = service.Assign(ctx, "acme", "auditor", "reviewer", "bootstrap")
+	require.NoError(t, err)
+
+	app := lift.New()
+	app.Use(func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			ctx.SetUserID(ctx.Header("X-User"))
+			ctx.SetTenantID("acme")
+			return next.Handle(ctx)
+		})
+	})
+	require.NoError(t, app.Mount("/rbac", service.App()))
+
+	request := func(method, path, user string, body any) *lift.Context {
+		var data []byte
+		if body != nil {
+			data, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		ctx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+			Method:  method,
+			Path:    path,
+			Headers: map[string]string{"X-User": user, "Content-Type": "application/json"},
+			Body:    data,
+		}))
+		require.NoError(t, app.HandleTestRequest(ctx))
+		return ctx
+	}
+
+	resp := request("PUT", "/rbac/roles/support", "root", map[string]any{"permissions": []string{"refunds:read"}})
+	require.Equal(t, 200, resp.Response.StatusCode)
+	assert.Equal(t, "support", resp.Response.Body.(*Role).Name)
+
+	resp = request("PUT", "/rbac/users/ada/roles/support", "root", nil)
+	require.Equal(t, 200, resp.Response.StatusCode)
+	assert.Equal(t, "root", resp.Response.Body.(*Assignment).AssignedBy)
+
+	resp = request("GET", "/rbac/users/ada/permissions", "auditor", nil)
+	require.Equal(t, 200, resp.Response.StatusCode)
+	assert.Equal(t, []string{"refunds:read"}, resp.Response.Body.(map[string]any)["permissions"])
+
+	resp = request("GET", "/rbac/export", "auditor", nil)
+	require.Equal(t, 200, resp.Response.StatusCode)
+	assert.Len(t, resp.Response.Body.(*Export).Users, 3)
+
+	assert.Equal(t, 403, request("PUT", "/rbac/users/auditor/roles/admin", "auditor", nil).Response.StatusCode)
+	assert.Equal(t, 403, request("GET", "/rbac/roles", "ada", nil).Response.StatusCode)
+	assert.Equal(t, 401, request("GET", "/rbac/roles", "", nil).Response.StatusCode)
+	assert.Equal(t, 404, request("PUT", "/rbac/users/ada/roles/owner", "root", nil).Response.StatusCode)
+	assert.Equal(t, 409, request("DELETE", "/rbac/roles/support", "root", nil).Response.StatusCode)
+	assert.Equal(t, 400, request("PUT", "/rbac/roles/support", "root", map[string]any{"permissions": []string{"refunds"}}).Response.StatusCode)
+
+	assert.Equal(t, 204, request("DELETE", "/rbac/users/ada/roles/support", "root", nil).Response.StatusCode)
+	assert.Equal(t, 204, request("DELETE", "/rbac/roles/support", "root", nil).Response.StatusCode)
+}
@@ -0,0 +1,526 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AnomalySeverity ranks findings so alerting can skip low-value noise
+type AnomalySeverity int
+
+const (
+	AnomalyLow AnomalySeverity = iota + 1
+	AnomalyMedium
+	AnomalyHigh
+)
+
+// String returns the severity's name
+func (s AnomalySeverity) String() string {
+	switch s {
+	case AnomalyLow:
+		return "low"
+	case AnomalyMedium:
+		return "medium"
+	case AnomalyHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the severity by name
+func (s AnomalySeverity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// AnomalyFinding is a suspicious access pattern flagged by a detector
+type AnomalyFinding struct {
+	ID          string          `json:"id"`
+	Detector    string          `json:"detector"`
+	Severity    AnomalySeverity `json:"severity"`
+	TenantID    string          `json:"tenant_id"`
+	UserID      string          `json:"user_id"`
+	Resource    string          `json:"resource,omitempty"`
+	Description string          `json:"description"`
+	// EntryID is the audit entry that triggered the finding
+	EntryID    string         `json:"entry_id"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	DetectedAt time.Time      `json:"detected_at"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+}
+
+// AccessProfile is the recent access history of one user, kept by the
+// analyzer and handed to detectors
+type AccessProfile struct {
+	TenantID string
+	UserID   string
+	// Recent holds the timestamps of accesses within the analyzer's window,
+	// oldest first, including the entry being inspected
+	Recent []time.Time
+	// Resources counts accesses per resource over the profile's lifetime,
+	// excluding the entry being inspected
+	Resources map[string]int
+	// Total counts accesses over the profile's lifetime, excluding the entry
+	// being inspected
+	Total int
+}
+
+// CountSince returns the number of recent accesses at or after t
+func (p *AccessProfile) CountSince(t time.Time) int {
+	i := sort.Search(len(p.Recent), func(i int) bool { return !p.Recent[i].Before(t) })
+	return len(p.Recent) - i
+}
+
+// AccessDetector inspects one audit entry against the user's profile and
+// returns a finding if the access looks suspicious. Detectors only fill in
+// Severity, Description and Metadata; the analyzer sets the rest.
+type AccessDetector interface {
+	Name() string
+	Detect(entry AuditLogEntry, profile *AccessProfile) *AnomalyFinding
+}
+
+// AnomalyAlerter delivers findings to people, e.g. through pkg/notify
+type AnomalyAlerter interface {
+	Alert(ctx context.Context, finding *AnomalyFinding) error
+}
+
+// AnomalyAlerterFunc adapts a function to the AnomalyAlerter interface
+type AnomalyAlerterFunc func(ctx context.Context, finding *AnomalyFinding) error
+
+// Alert calls f(ctx, finding)
+func (f AnomalyAlerterFunc) Alert(ctx context.Context, finding *AnomalyFinding) error {
+	return f(ctx, finding)
+}
+
+// FindingFilter defines filters for listing findings
+type FindingFilter struct {
+	TenantID    string          `json:"tenant_id,omitempty"`
+	UserID      string          `json:"user_id,omitempty"`
+	Detector    string          `json:"detector,omitempty"`
+	MinSeverity AnomalySeverity `json:"min_severity,omitempty"`
+	Since       time.Time       `json:"since,omitempty"`
+	Limit       int             `json:"limit,omitempty"`
+}
+
+// FindingStore persists findings
+type FindingStore interface {
+	SaveFinding(ctx context.Context, finding *AnomalyFinding) error
+	// ListFindings returns matching findings, newest first
+	ListFindings(ctx context.Context, filter FindingFilter) ([]AnomalyFinding, error)
+}
+
+// AnomalyConfig configures an AnomalyAnalyzer
+type AnomalyConfig struct {
+	// Detectors run against every entry (default: velocity, off-hours and
+	// unusual-resource detectors with their default settings)
+	Detectors []AccessDetector
+	// Findings stores findings (default: in memory)
+	Findings FindingStore
+	// Alerter is sent findings at or above AlertSeverity
+	Alerter AnomalyAlerter
+	// AlertSeverity is the minimum severity alerted on (default: AnomalyMedium)
+	AlertSeverity AnomalySeverity
+	// Window is how far back profiles keep access timestamps (default: 1h)
+	Window time.Duration
+	// Cooldown suppresses repeat findings from the same detector for the same
+	// user (default: 15m)
+	Cooldown time.Duration
+	// EntryTypes limits which audit entries are analyzed (default: request,
+	// data_access and break-glass access entries)
+	EntryTypes []string
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// AnomalyAnalyzer flags suspicious access patterns in the audit trail. It
+// consumes entries one at a time as they are written (Observe, or Wrap an
+// AuditStorage) or in bulk (Analyze, Scan), keeps a rolling access profile
+// per user, and runs each entry through pluggable detectors. Findings are
+// stored for review and alerted on above a severity threshold.
+//
+//	analyzer := security.NewAnomalyAnalyzer(security.AnomalyConfig{
+//		Alerter: notifier.AnomalyAlerter(notify.AnomalyAlertConfig{To: []string{"security@example.com"}}),
+//	})
+//	logger := security.NewBufferedAuditLogger(analyzer.Wrap(storage), 100, 5*time.Second)
+//
+// Profiles are held in memory, so a freshly started analyzer has no
+// baseline; Scan recent history to warm it up.
+type AnomalyAnalyzer struct {
+	config     AnomalyConfig
+	entryTypes map[string]bool
+
+	mu       sync.Mutex
+	profiles map[string]*AccessProfile
+	lastSeen map[string]time.Time
+}
+
+// NewAnomalyAnalyzer creates an analyzer
+func NewAnomalyAnalyzer(config AnomalyConfig) *AnomalyAnalyzer {
+	if config.Detectors == nil {
+		config.Detectors = []AccessDetector{
+			&VelocityDetector{},
+			&OffHoursDetector{},
+			&UnusualResourceDetector{},
+		}
+	}
+	if config.Findings == nil {
+		config.Findings = NewInMemoryFindingStore()
+	}
+	if config.AlertSeverity == 0 {
+		config.AlertSeverity = AnomalyMedium
+	}
+	if config.Window == 0 {
+		config.Window = time.Hour
+	}
+	if config.Cooldown == 0 {
+		config.Cooldown = 15 * time.Minute
+	}
+	if config.EntryTypes == nil {
+		config.EntryTypes = []string{"request", "data_access", BreakGlassAccessEntry}
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	entryTypes := make(map[string]bool, len(config.EntryTypes))
+	for _, entryType := range config.EntryTypes {
+		entryTypes[entryType] = true
+	}
+
+	return &AnomalyAnalyzer{
+		config:     config,
+		entryTypes: entryTypes,
+		profiles:   make(map[string]*AccessProfile),
+		lastSeen:   make(map[string]time.Time),
+	}
+}
+
+// Observe analyzes one entry and returns any new findings. Entries should
+// arrive in roughly chronological order.
+func (a *AnomalyAnalyzer) Observe(ctx context.Context, entry AuditLogEntry) ([]AnomalyFinding, error) {
+	if !a.entryTypes[entry.EntryType] || entry.UserID == "" {
+		return nil, nil
+	}
+
+	findings := a.inspect(entry)
+	for i := range findings {
+		if err := a.config.Findings.SaveFinding(ctx, &findings[i]); err != nil {
+			return findings, fmt.Errorf("failed to save anomaly finding: %w", err)
+		}
+		if a.config.Alerter != nil && findings[i].Severity >= a.config.AlertSeverity {
+			if err := a.config.Alerter.Alert(ctx, &findings[i]); err != nil {
+				return findings, fmt.Errorf("failed to send anomaly alert: %w", err)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// Analyze analyzes a batch of entries in chronological order
+func (a *AnomalyAnalyzer) Analyze(ctx context.Context, entries []AuditLogEntry) ([]AnomalyFinding, error) {
+	sorted := make([]AuditLogEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	var all []AnomalyFinding
+	for _, entry := range sorted {
+		findings, err := a.Observe(ctx, entry)
+		all = append(all, findings...)
+		if err != nil {
+			return all, err
+		}
+	}
+	return all, nil
+}
+
+// Scan queries audit storage and analyzes the matching entries
+func (a *AnomalyAnalyzer) Scan(ctx context.Context, storage AuditStorage, filter AuditFilter) ([]AnomalyFinding, error) {
+	entries, err := storage.Query(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit trail: %w", err)
+	}
+	return a.Analyze(ctx, entries)
+}
+
+// Findings lists stored findings
+func (a *AnomalyAnalyzer) Findings(ctx context.Context, filter FindingFilter) ([]AnomalyFinding, error) {
+	return a.config.Findings.ListFindings(ctx, filter)
+}
+
+// Wrap returns audit storage that analyzes entries after they are stored.
+// Analysis failures are not returned to the writer, since the entry itself
+// was stored; they are reported through onError if it is set.
+func (a *AnomalyAnalyzer) Wrap(storage AuditStorage, onError ...func(error)) AuditStorage {
+	return &analyzingStorage{AuditStorage: storage, analyzer: a, onError: onError}
+}
+
+// inspect updates the user's profile with entry and runs the detectors
+func (a *AnomalyAnalyzer) inspect(entry AuditLogEntry) []AnomalyFinding {
+	key := entry.TenantID + "#" + entry.UserID
+	resource := auditResource(entry)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	profile, ok := a.profiles[key]
+	if !ok {
+		profile = &AccessProfile{
+			TenantID:  entry.TenantID,
+			UserID:    entry.UserID,
+			Resources: make(map[string]int),
+		}
+		a.profiles[key] = profile
+	}
+
+	cutoff := entry.Timestamp.Add(-a.config.Window)
+	drop := sort.Search(len(profile.Recent), func(i int) bool { return !profile.Recent[i].Before(cutoff) })
+	profile.Recent = append(profile.Recent[drop:], entry.Timestamp)
+
+	var findings []AnomalyFinding
+	now := a.config.Now().UTC()
+	for _, detector := range a.config.Detectors {
+		finding := detector.Detect(entry, profile)
+		if finding == nil {
+			continue
+		}
+
+		cooldownKey := key + "#" + detector.Name()
+		if last, ok := a.lastSeen[cooldownKey]; ok && entry.Timestamp.Sub(last) < a.config.Cooldown {
+			continue
+		}
+		a.lastSeen[cooldownKey] = entry.Timestamp
+
+		finding.ID = generatePrefixedID("finding")
+		finding.Detector = detector.Name()
+		finding.TenantID = entry.TenantID
+		finding.UserID = entry.UserID
+		finding.Resource = resource
+		finding.EntryID = entry.ID
+		finding.OccurredAt = entry.Timestamp
+		finding.DetectedAt = now
+		findings = append(findings, *finding)
+	}
+
+	if resource != "" {
+		profile.Resources[resource]++
+	}
+	profile.Total++
+	return findings
+}
+
+// analyzingStorage feeds stored entries to an analyzer
+type analyzingStorage struct {
+	AuditStorage
+	analyzer *AnomalyAnalyzer
+	onError  []func(error)
+}
+
+func (s *analyzingStorage) Store(ctx context.Context, entry AuditLogEntry) error {
+	if err := s.AuditStorage.Store(ctx, entry); err != nil {
+		return err
+	}
+	s.report(s.analyzer.Observe(ctx, entry))
+	return nil
+}
+
+func (s *analyzingStorage) BatchStore(ctx context.Context, entries []AuditLogEntry) error {
+	if err := s.AuditStorage.BatchStore(ctx, entries); err != nil {
+		return err
+	}
+	s.report(s.analyzer.Analyze(ctx, entries))
+	return nil
+}
+
+func (s *analyzingStorage) report(_ []AnomalyFinding, err error) {
+	if err == nil {
+		return
+	}
+	for _, fn := range s.onError {
+		fn(err)
+	}
+}
+
+// auditResource returns the resource an entry touched
+func auditResource(entry AuditLogEntry) string {
+	switch {
+	case entry.Request != nil && entry.Request.Resource != "":
+		return entry.Request.Resource
+	case entry.DataAccess != nil && entry.DataAccess.DataType != "":
+		return entry.DataAccess.DataType
+	}
+	return ""
+}
+
+// VelocityDetector flags users making more accesses in a window than a
+// person plausibly would, e.g. scripted scraping with stolen credentials
+type VelocityDetector struct {
+	// Window is the period accesses are counted over (default: 1m)
+	Window time.Duration
+	// Threshold is the access count that triggers a finding (default: 60)
+	Threshold int
+}
+
+// Name implements AccessDetector
+func (d *VelocityDetector) Name() string { return "velocity" }
+
+// Detect implements AccessDetector
+func (d *VelocityDetector) Detect(entry AuditLogEntry, profile *AccessProfile) *AnomalyFinding {
+	window := d.Window
+	if window == 0 {
+		window = time.Minute
+	}
+	threshold := d.Threshold
+	if threshold == 0 {
+		threshold = 60
+	}
+
+	count := profile.CountSince(entry.Timestamp.Add(-window))
+	if count < threshold {
+		return nil
+	}
+
+	severity := AnomalyMedium
+	if count >= 2*threshold {
+		severity = AnomalyHigh
+	}
+	return &AnomalyFinding{
+		Severity:    severity,
+		Description: fmt.Sprintf("%d accesses in %s", count, window),
+		Metadata:    map[string]any{"count": count, "window": window.String()},
+	}
+}
+
+// OffHoursDetector flags access outside business hours
+type OffHoursDetector struct {
+	// Start and End bound business hours as hours of the day (default: 7–19)
+	Start, End int
+	// Weekends treats Saturday and Sunday as business days
+	Weekends bool
+	// Location is the time zone business hours are in (default: UTC)
+	Location *time.Location
+	// Severity of findings (default: AnomalyLow)
+	Severity AnomalySeverity
+}
+
+// Name implements AccessDetector
+func (d *OffHoursDetector) Name() string { return "off_hours" }
+
+// Detect implements AccessDetector
+func (d *OffHoursDetector) Detect(entry AuditLogEntry, profile *AccessProfile) *AnomalyFinding {
+	start, end := d.Start, d.End
+	if start == 0 && end == 0 {
+		start, end = 7, 19
+	}
+	location := d.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	local := entry.Timestamp.In(location)
+	weekend := local.Weekday() == time.Saturday || local.Weekday() == time.Sunday
+	if local.Hour() >= start && local.Hour() < end && (d.Weekends || !weekend) {
+		return nil
+	}
+
+	severity := d.Severity
+	if severity == 0 {
+		severity = AnomalyLow
+	}
+	return &AnomalyFinding{
+		Severity:    severity,
+		Description: "Access outside business hours at " + local.Format("Mon 15:04 MST"),
+		Metadata:    map[string]any{"local_time": local.Format(time.RFC3339)},
+	}
+}
+
+// UnusualResourceDetector flags the first access to a resource by a user
+// with an established history, e.g. a billing clerk opening clinical records
+type UnusualResourceDetector struct {
+	// MinHistory is how many accesses establish a baseline (default: 20);
+	// new users aren't flagged for everything they touch
+	MinHistory int
+	// Sensitive resources are flagged at high severity
+	Sensitive []string
+}
+
+// Name implements AccessDetector
+func (d *UnusualResourceDetector) Name() string { return "unusual_resource" }
+
+// Detect implements AccessDetector
+func (d *UnusualResourceDetector) Detect(entry AuditLogEntry, profile *AccessProfile) *AnomalyFinding {
+	minHistory := d.MinHistory
+	if minHistory == 0 {
+		minHistory = 20
+	}
+
+	resource := auditResource(entry)
+	if resource == "" || profile.Total < minHistory || profile.Resources[resource] > 0 {
+		return nil
+	}
+
+	severity := AnomalyMedium
+	for _, sensitive := range d.Sensitive {
+		if sensitive == resource {
+			severity = AnomalyHigh
+			break
+		}
+	}
+	return &AnomalyFinding{
+		Severity:    severity,
+		Description: fmt.Sprintf("First access to %s after %d accesses elsewhere", resource, profile.Total),
+		Metadata:    map[string]any{"distinct_resources": len(profile.Resources)},
+	}
+}
+
+// InMemoryFindingStore implements FindingStore for testing and development
+type InMemoryFindingStore struct {
+	findings []AnomalyFinding
+	mu       sync.RWMutex
+}
+
+// NewInMemoryFindingStore creates an in-memory finding store
+func NewInMemoryFindingStore() *InMemoryFindingStore {
+	return &InMemoryFindingStore{}
+}
+
+// SaveFinding stores a finding
+func (s *InMemoryFindingStore) SaveFinding(ctx context.Context, finding *AnomalyFinding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.findings = append(s.findings, *finding)
+	return nil
+}
+
+// ListFindings returns matching findings, newest first
+func (s *InMemoryFindingStore) ListFindings(ctx context.Context, filter FindingFilter) ([]AnomalyFinding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []AnomalyFinding
+	for i := len(s.findings) - 1; i >= 0; i-- {
+		finding := s.findings[i]
+		if filter.TenantID != "" && finding.TenantID != filter.TenantID {
+			continue
+		}
+		if filter.UserID != "" && finding.UserID != filter.UserID {
+			continue
+		}
+		if filter.Detector != "" && finding.Detector != filter.Detector {
+			continue
+		}
+		if finding.Severity < filter.MinSeverity {
+			continue
+		}
+		if !filter.Since.IsZero() && finding.OccurredAt.Before(filter.Since) {
+			continue
+		}
+
+		results = append(results, finding)
+		if filter.Limit > 0 && len(results) >= filter.Limit {
+			break
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,144 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func accessEntry(user, resource string, at time.Time) AuditLogEntry {
+	return AuditLogEntry{
+		ID:        fmt.Sprintf("%s-%s-%d", user, resource, at.UnixNano()),
+		TenantID:  "t1",
+		UserID:    user,
+		EntryType: "request",
+		Timestamp: at,
+		Request:   &AuditRequest{UserID: user, TenantID: "t1", Action: "GET", Resource: resource},
+	}
+}
+
+func TestAnomalyAnalyzerVelocity(t *testing.T) {
+	ctx := context.Background()
+	var alerted []*AnomalyFinding
+	analyzer := NewAnomalyAnalyzer(AnomalyConfig{
+		Detectors: []AccessDetector{&VelocityDetector{Window: time.Minute, Threshold: 5}},
+		Alerter: AnomalyAlerterFunc(func(ctx context.Context, finding *AnomalyFinding) error {
+			alerted = append(alerted, finding)
+			return nil
+		}),
+	})
+
+	start := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+	var entries []AuditLogEntry
+	for i := 0; i < 12; i++ {
+		entries = append(entries, accessEntry("u1", "/patients", start.Add(time.Duration(i)*time.Second)))
+	}
+	// Spread out, so never five in a minute
+	for i := 0; i < 12; i++ {
+		entries = append(entries, accessEntry("u2", "/patients", start.Add(time.Duration(i)*20*time.Second)))
+	}
+
+	findings, err := analyzer.Analyze(ctx, entries)
+	require.NoError(t, err)
+	require.Len(t, findings, 1, "the cooldown suppresses repeats")
+	assert.Equal(t, "velocity", findings[0].Detector)
+	assert.Equal(t, "u1", findings[0].UserID)
+	assert.Equal(t, AnomalyMedium, findings[0].Severity)
+	assert.Equal(t, start.Add(4*time.Second), findings[0].OccurredAt)
+	assert.Len(t, alerted, 1)
+
+	stored, err := analyzer.Findings(ctx, FindingFilter{TenantID: "t1"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+}
+
+func TestOffHoursDetector(t *testing.T) {
+	detector := &OffHoursDetector{}
+	profile := &AccessProfile{Resources: map[string]int{}}
+
+	weekday := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC) // Monday
+	assert.Nil(t, detector.Detect(accessEntry("u1", "/a", weekday.Add(9*time.Hour)), profile))
+	assert.NotNil(t, detector.Detect(accessEntry("u1", "/a", weekday.Add(3*time.Hour)), profile))
+	assert.NotNil(t, detector.Detect(accessEntry("u1", "/a", weekday.Add(19*time.Hour)), profile))
+	assert.NotNil(t, detector.Detect(accessEntry("u1", "/a", weekday.Add(-15*time.Hour)), profile), "Sunday")
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	local := &OffHoursDetector{Location: newYork, Weekends: true}
+	assert.Nil(t, local.Detect(accessEntry("u1", "/a", weekday.Add(14*time.Hour)), profile), "10:00 in New York")
+}
+
+func TestUnusualResourceDetector(t *testing.T) {
+	ctx := context.Background()
+	analyzer := NewAnomalyAnalyzer(AnomalyConfig{
+		Detectors: []AccessDetector{&UnusualResourceDetector{MinHistory: 3, Sensitive: []string{"/psych-notes"}}},
+	})
+
+	start := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+	entries := []AuditLogEntry{
+		accessEntry("u1", "/invoices", start),
+		accessEntry("u1", "/payments", start.Add(time.Minute)),
+		accessEntry("u1", "/invoices", start.Add(2*time.Minute)),
+		accessEntry("u1", "/invoices", start.Add(3*time.Minute)),
+		accessEntry("u1", "/psych-notes", start.Add(4*time.Minute)),
+		// Not analyzed: no user
+		{ID: "anon", TenantID: "t1", EntryType: "request", Timestamp: start.Add(5 * time.Minute)},
+	}
+
+	findings, err := analyzer.Analyze(ctx, entries)
+	require.NoError(t, err)
+	require.Len(t, findings, 1, "early resources are the baseline")
+	assert.Equal(t, "/psych-notes", findings[0].Resource)
+	assert.Equal(t, AnomalyHigh, findings[0].Severity)
+}
+
+func TestAnomalyAnalyzerWrapStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryAuditStorage()
+	analyzer := NewAnomalyAnalyzer(AnomalyConfig{
+		Detectors: []AccessDetector{&OffHoursDetector{}},
+	})
+	wrapped := analyzer.Wrap(storage)
+
+	night := time.Date(2024, 6, 3, 2, 0, 0, 0, time.UTC)
+	require.NoError(t, wrapped.Store(ctx, accessEntry("u1", "/a", night)))
+	require.NoError(t, wrapped.BatchStore(ctx, []AuditLogEntry{accessEntry("u2", "/a", night)}))
+
+	stored, err := storage.Query(ctx, AuditFilter{TenantID: "t1"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+
+	findings, err := analyzer.Findings(ctx, FindingFilter{TenantID: "t1", Detector: "off_hours"})
+	require.NoError(t, err)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "u2", findings[0].UserID, "newest first")
+
+	findings, err = analyzer.Findings(ctx, FindingFilter{MinSeverity: AnomalyMedium})
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestAnomalyAnalyzerScan(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryAuditStorage()
+	start := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, storage.Store(ctx, accessEntry("u1", "/a", start.Add(time.Duration(i)*time.Second))))
+	}
+
+	analyzer := NewAnomalyAnalyzer(AnomalyConfig{
+		Detectors: []AccessDetector{&VelocityDetector{Threshold: 3}},
+	})
+	findings, err := analyzer.Scan(ctx, storage, AuditFilter{TenantID: "t1"})
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+
+	data, err := json.Marshal(findings[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"severity":"medium"`)
+}
@@ -38,6 +38,7 @@ type AuditStorage interface {
 // AuditFilter defines filters for querying audit logs
 type AuditFilter struct {
 	UserID    string    `json:"user_id,omitempty"`
+	ActorID   string    `json:"actor_id,omitempty"`
 	TenantID  string    `json:"tenant_id,omitempty"`
 	AuditID   string    `json:"audit_id,omitempty"`
 	EntryType string    `json:"entry_type,omitempty"`
@@ -66,6 +67,8 @@ type AuditLogEntry struct {
 	AuditID       string                 `json:"audit_id"`
 	TenantID      string                 `json:"tenant_id"`
 	UserID        string                 `json:"user_id"`
+	// ActorID is who really acted when UserID was impersonated
+	ActorID       string                 `json:"actor_id,omitempty"`
 	EntryType     string                 `json:"entry_type"` // request, response, data_access, security_event
 	Timestamp     time.Time              `json:"timestamp"`
 	TTL           int64                  `json:"ttl"`
@@ -128,6 +131,7 @@ func (bal *BufferedAuditLogger) LogRequest(auditID string, request *AuditRequest
 		AuditID:   auditID,
 		TenantID:  request.TenantID,
 		UserID:    request.UserID,
+		ActorID:   request.ActorID,
 		EntryType: "request",
 		Timestamp: request.Timestamp,
 		TTL:       time.Now().Add(365 * 24 * time.Hour).Unix(), // 1 year retention
@@ -370,6 +374,11 @@ func (bal *BufferedAuditLogger) generateEntryID() string {
 
 // calculateChecksum calculates a checksum for audit entry integrity
 func (bal *BufferedAuditLogger) calculateChecksum(entry AuditLogEntry) string {
+	return auditChecksum(entry)
+}
+
+// auditChecksum calculates the checksum of an entry's contents
+func auditChecksum(entry AuditLogEntry) string {
 	// Create a copy without the checksum field
 	entryCopy := entry
 	entryCopy.Checksum = ""
@@ -447,6 +456,10 @@ func (imas *InMemoryAuditStorage) Query(ctx context.Context, filter AuditFilter)
 				continue
 			}
 
+			if filter.ActorID != "" && entry.ActorID != filter.ActorID {
+				continue
+			}
+
 			if filter.TenantID != "" && entry.TenantID != filter.TenantID {
 				continue
 			}
@@ -112,9 +112,18 @@ type AuditEvent struct {
 	DataAccessed []string               `json:"data_accessed"`
 	Compliance   ComplianceContext      `json:"compliance"`
 	Security     SecurityContext        `json:"security"`
+	Client       ClientContext          `json:"client,omitempty"`
 	Metadata     map[string]any `json:"metadata"`
 }
 
+// ClientContext identifies the client application and device behind an event
+type ClientContext struct {
+	DeviceID   string `json:"device_id,omitempty"`
+	Platform   string `json:"platform,omitempty"`
+	AppVersion string `json:"app_version,omitempty"`
+	SDKVersion string `json:"sdk_version,omitempty"`
+}
+
 // ComplianceContext provides compliance-specific context
 type ComplianceContext struct {
 	Framework    string   `json:"framework"`
@@ -0,0 +1,254 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/id"
+)
+
+// Audit entry types written by BreakGlass
+const (
+	BreakGlassGrantEntry  = "break_glass_grant"
+	BreakGlassAccessEntry = "break_glass_access"
+)
+
+var (
+	// ErrBreakGlassReason is returned when a grant is requested without an
+	// adequate reason
+	ErrBreakGlassReason = errors.New("a reason is required for break-glass access")
+	// ErrBreakGlassRequest is returned when a grant request is missing the
+	// user or scope
+	ErrBreakGlassRequest = errors.New("break-glass request requires a user and scope")
+)
+
+// BreakGlassGrant is time-boxed emergency access to a scope of sensitive routes
+type BreakGlassGrant struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	UserID    string    `json:"user_id"`
+	Scope     string    `json:"scope"`
+	Reason    string    `json:"reason"`
+	GrantedAt time.Time `json:"granted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ActiveAt reports whether the grant is in effect at t
+func (g *BreakGlassGrant) ActiveAt(t time.Time) bool {
+	return !t.Before(g.GrantedAt) && t.Before(g.ExpiresAt)
+}
+
+// BreakGlassRequest asks for emergency access
+type BreakGlassRequest struct {
+	TenantID string
+	UserID   string
+	Scope    string
+	Reason   string
+	// Duration is how long access is needed (default: the config's
+	// DefaultDuration, capped at MaxDuration)
+	Duration  time.Duration
+	IPAddress string
+}
+
+// BreakGlassConfig configures BreakGlass
+type BreakGlassConfig struct {
+	// Storage records grants and every access made under them. Writes go
+	// straight to storage rather than through a buffer, so a grant is usable
+	// as soon as it is issued.
+	Storage AuditStorage
+	// DefaultDuration is the grant length when none is requested (default: 15m)
+	DefaultDuration time.Duration
+	// MaxDuration caps requested durations (default: 1h)
+	MaxDuration time.Duration
+	// MinReasonLength rejects token reasons such as "x" (default: 10)
+	MinReasonLength int
+	// OnGrant is called for every issued grant, e.g. to page the security team
+	OnGrant func(ctx context.Context, grant *BreakGlassGrant)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// BreakGlass issues and checks time-boxed emergency access grants. Grants
+// and accesses are written to the audit trail, which is also where active
+// grants are looked up, so there is no access that isn't audited. Grants
+// expire on their own and can't be extended, only requested again with a
+// new reason.
+type BreakGlass struct {
+	config BreakGlassConfig
+}
+
+// NewBreakGlass creates a break-glass workflow backed by audit storage
+func NewBreakGlass(config BreakGlassConfig) (*BreakGlass, error) {
+	if config.Storage == nil {
+		return nil, fmt.Errorf("break-glass requires audit storage")
+	}
+	if config.DefaultDuration == 0 {
+		config.DefaultDuration = 15 * time.Minute
+	}
+	if config.MaxDuration == 0 {
+		config.MaxDuration = time.Hour
+	}
+	if config.DefaultDuration > config.MaxDuration {
+		config.DefaultDuration = config.MaxDuration
+	}
+	if config.MinReasonLength == 0 {
+		config.MinReasonLength = 10
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return &BreakGlass{config: config}, nil
+}
+
+// Grant issues emergency access and records it in the audit trail
+func (b *BreakGlass) Grant(ctx context.Context, req BreakGlassRequest) (*BreakGlassGrant, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if req.UserID == "" || req.Scope == "" {
+		return nil, ErrBreakGlassRequest
+	}
+	if len(reason) < b.config.MinReasonLength {
+		return nil, fmt.Errorf("%w: at least %d characters", ErrBreakGlassReason, b.config.MinReasonLength)
+	}
+
+	duration := req.Duration
+	if duration <= 0 {
+		duration = b.config.DefaultDuration
+	}
+	if duration > b.config.MaxDuration {
+		duration = b.config.MaxDuration
+	}
+
+	now := b.config.Now().UTC()
+	grant := &BreakGlassGrant{
+		ID:        generatePrefixedID("bg"),
+		TenantID:  req.TenantID,
+		UserID:    req.UserID,
+		Scope:     req.Scope,
+		Reason:    reason,
+		GrantedAt: now,
+		ExpiresAt: now.Add(duration),
+	}
+
+	entry := AuditLogEntry{
+		ID:        generatePrefixedID("entry"),
+		AuditID:   grant.ID,
+		TenantID:  grant.TenantID,
+		UserID:    grant.UserID,
+		EntryType: BreakGlassGrantEntry,
+		Timestamp: now,
+		TTL:       now.Add(365 * 24 * time.Hour).Unix(),
+		SecurityEvent: &SecurityEvent{
+			EventType:   "break_glass_granted",
+			Severity:    "high",
+			Description: reason,
+			Timestamp:   now,
+			Metadata: map[string]any{
+				"scope":      grant.Scope,
+				"ip_address": req.IPAddress,
+			},
+		},
+		Metadata: map[string]any{
+			"scope":      grant.Scope,
+			"reason":     grant.Reason,
+			"expires_at": grant.ExpiresAt.Format(time.RFC3339Nano),
+		},
+	}
+	entry.Checksum = auditChecksum(entry)
+
+	if err := b.config.Storage.Store(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record break-glass grant: %w", err)
+	}
+	if b.config.OnGrant != nil {
+		b.config.OnGrant(ctx, grant)
+	}
+	return grant, nil
+}
+
+// ActiveGrant returns the user's unexpired grant for scope with the latest
+// expiry, or nil if there is none
+func (b *BreakGlass) ActiveGrant(ctx context.Context, tenantID, userID, scope string) (*BreakGlassGrant, error) {
+	now := b.config.Now()
+	entries, err := b.config.Storage.Query(ctx, AuditFilter{
+		TenantID:  tenantID,
+		UserID:    userID,
+		EntryType: BreakGlassGrantEntry,
+		Since:     now.Add(-b.config.MaxDuration),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up break-glass grants: %w", err)
+	}
+
+	var active *BreakGlassGrant
+	for _, entry := range entries {
+		grant, ok := grantFromEntry(entry)
+		if !ok || grant.Scope != scope || !grant.ActiveAt(now) {
+			continue
+		}
+		if active == nil || grant.ExpiresAt.After(active.ExpiresAt) {
+			active = grant
+		}
+	}
+	return active, nil
+}
+
+// RecordAccess records an access made under a grant. reason is the
+// per-access justification, if one was captured.
+func (b *BreakGlass) RecordAccess(ctx context.Context, grant *BreakGlassGrant, action, resource, reason string) error {
+	now := b.config.Now().UTC()
+	entry := AuditLogEntry{
+		ID:        generatePrefixedID("entry"),
+		AuditID:   grant.ID,
+		TenantID:  grant.TenantID,
+		UserID:    grant.UserID,
+		EntryType: BreakGlassAccessEntry,
+		Timestamp: now,
+		TTL:       now.Add(365 * 24 * time.Hour).Unix(),
+		Request: &AuditRequest{
+			UserID:    grant.UserID,
+			TenantID:  grant.TenantID,
+			Action:    action,
+			Resource:  resource,
+			Timestamp: now,
+		},
+		Metadata: map[string]any{
+			"scope":  grant.Scope,
+			"reason": reason,
+		},
+	}
+	entry.Checksum = auditChecksum(entry)
+
+	if err := b.config.Storage.Store(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record break-glass access: %w", err)
+	}
+	return nil
+}
+
+// grantFromEntry rebuilds a grant from its audit entry
+func grantFromEntry(entry AuditLogEntry) (*BreakGlassGrant, bool) {
+	scope, _ := entry.Metadata["scope"].(string)
+	reason, _ := entry.Metadata["reason"].(string)
+	expiresAt, _ := entry.Metadata["expires_at"].(string)
+
+	expires, err := time.Parse(time.RFC3339Nano, expiresAt)
+	if err != nil || scope == "" {
+		return nil, false
+	}
+	return &BreakGlassGrant{
+		ID:        entry.AuditID,
+		TenantID:  entry.TenantID,
+		UserID:    entry.UserID,
+		Scope:     scope,
+		Reason:    reason,
+		GrantedAt: entry.Timestamp,
+		ExpiresAt: expires,
+	}, true
+}
+
+// generatePrefixedID generates a unique ID with the given prefix
+func generatePrefixedID(prefix string) string {
+	return id.New(prefix)
+}
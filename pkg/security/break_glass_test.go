@@ -0,0 +1,73 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakGlassGrantLifecycle(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryAuditStorage()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var paged []*BreakGlassGrant
+	bg, err := NewBreakGlass(BreakGlassConfig{
+		Storage: storage,
+		Now:     func() time.Time { return now },
+		OnGrant: func(ctx context.Context, grant *BreakGlassGrant) { paged = append(paged, grant) },
+	})
+	require.NoError(t, err)
+
+	_, err = bg.Grant(ctx, BreakGlassRequest{TenantID: "t1", UserID: "dr-1", Scope: "patient-records", Reason: "urgent"})
+	assert.ErrorIs(t, err, ErrBreakGlassReason)
+	_, err = bg.Grant(ctx, BreakGlassRequest{TenantID: "t1", UserID: "dr-1", Reason: "ER admission, patient unconscious"})
+	assert.ErrorIs(t, err, ErrBreakGlassRequest)
+
+	grant, err := bg.Grant(ctx, BreakGlassRequest{
+		TenantID: "t1",
+		UserID:   "dr-1",
+		Scope:    "patient-records",
+		Reason:   "ER admission, patient unconscious",
+		Duration: 4 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), grant.ExpiresAt, "duration is capped at MaxDuration")
+	assert.Len(t, paged, 1)
+
+	active, err := bg.ActiveGrant(ctx, "t1", "dr-1", "patient-records")
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, grant.ID, active.ID)
+	assert.Equal(t, grant.Reason, active.Reason)
+
+	// Grants are scoped to the user, tenant and scope
+	for _, lookup := range [][3]string{{"t2", "dr-1", "patient-records"}, {"t1", "dr-2", "patient-records"}, {"t1", "dr-1", "billing"}} {
+		other, err := bg.ActiveGrant(ctx, lookup[0], lookup[1], lookup[2])
+		require.NoError(t, err)
+		assert.Nil(t, other, "%v", lookup)
+	}
+
+	require.NoError(t, bg.RecordAccess(ctx, active, "GET", "/patients/42/records", "reviewing allergies"))
+
+	entries, err := storage.Query(ctx, AuditFilter{AuditID: grant.ID})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, auditChecksum(entry), entry.Checksum)
+	}
+
+	// Grants expire on their own
+	now = now.Add(time.Hour)
+	active, err = bg.ActiveGrant(ctx, "t1", "dr-1", "patient-records")
+	require.NoError(t, err)
+	assert.Nil(t, active)
+}
+
+func TestNewBreakGlassRequiresStorage(t *testing.T) {
+	_, err := NewBreakGlass(BreakGlassConfig{})
+	assert.Error(t, err)
+}
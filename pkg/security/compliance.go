@@ -103,6 +103,7 @@ type ComplianceReporter interface {
 // AuditRequest represents an auditable request
 type AuditRequest struct {
 	UserID      string            `json:"user_id"`
+	ActorID     string            `json:"actor_id,omitempty"`
 	TenantID    string            `json:"tenant_id"`
 	Action      string            `json:"action"`
 	Resource    string            `json:"resource"`
@@ -34,11 +34,19 @@ type SecurityConfig struct {
 // JWTConfig configures JWT authentication
 type JWTConfig struct {
 	// Signing configuration
-	SigningMethod  string `json:"signing_method"` // RS256, HS256
+	SigningMethod  string `json:"signing_method"` // RS256, ES256, HS256
 	PublicKeyPath  string `json:"public_key_path"`
 	PrivateKeyPath string `json:"private_key_path"`
 	SecretKey      string `json:"secret_key,omitempty"` // For HS256
 
+	// JWKSURL verifies RS256 and ES256 tokens against the keys an issuer
+	// such as Cognito or Auth0 publishes, instead of a key file. Keys are
+	// cached for JWKSCacheTTL (default: 1 hour) and refetched early when
+	// a token names an unknown key. SigningMethod restricts the accepted
+	// algorithm; empty accepts both.
+	JWKSURL      string        `json:"jwks_url,omitempty"`
+	JWKSCacheTTL time.Duration `json:"jwks_cache_ttl,omitempty"`
+
 	// Validation settings
 	Issuer   string        `json:"issuer"`
 	Audience []string      `json:"audience"`
@@ -48,9 +56,23 @@ type JWTConfig struct {
 	RequireTenantID bool                        `json:"require_tenant_id"`
 	ValidateTenant  func(tenantID string) error `json:"-"` // Custom validation function
 
+	// Claim mapping: the claims holding the user and tenant IDs, which
+	// become ctx.UserID() and ctx.TenantID() (defaults: "sub" and
+	// "tenant_id"; Cognito custom attributes look like "custom:tenant_id")
+	UserIDClaim   string `json:"user_id_claim,omitempty"`
+	TenantIDClaim string `json:"tenant_id_claim,omitempty"`
+
+	// ClaimValidators run against every verified token's claims, e.g. to
+	// require Cognito's token_use to be "access"
+	ClaimValidators []func(claims map[string]any) error `json:"-"`
+
 	// Key rotation
 	KeyRotation    bool          `json:"key_rotation"`
 	RotationPeriod time.Duration `json:"rotation_period"`
+
+	// Revocation, if set, rejects otherwise valid tokens that have been
+	// revoked by ID, session, user or tenant
+	Revocation *Revocations `json:"-"`
 }
 
 // APIKeyConfig configures API key authentication
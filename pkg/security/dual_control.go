@@ -0,0 +1,368 @@
+package security
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Audit entry types written by DualControl
+const (
+	DualControlRequestedEntry = "dual_control_requested"
+	DualControlDecidedEntry   = "dual_control_decided"
+	DualControlExecutedEntry  = "dual_control_executed"
+)
+
+// ApprovalStatus is the state of a pending action
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+	ApprovalExecuted ApprovalStatus = "executed"
+)
+
+var (
+	// ErrApprovalNotFound is returned for unknown approval IDs
+	ErrApprovalNotFound = errors.New("approval not found")
+	// ErrSelfApproval is returned when the requester tries to decide their
+	// own action
+	ErrSelfApproval = errors.New("actions must be approved by a different user")
+	// ErrApprovalNotPending is returned when deciding an action that was
+	// already decided
+	ErrApprovalNotPending = errors.New("action is not pending approval")
+	// ErrApprovalNotApproved is returned when executing an action that
+	// hasn't been approved, or was already executed
+	ErrApprovalNotApproved = errors.New("action has not been approved")
+	// ErrApprovalExpired is returned for actions past their expiry
+	ErrApprovalExpired = errors.New("approval has expired")
+	// ErrApprovalMismatch is returned when an approval is used for a request
+	// other than the one that was approved
+	ErrApprovalMismatch = errors.New("approval does not match this request")
+	// ErrApprovalConflict is returned by stores when an action changed
+	// status concurrently
+	ErrApprovalConflict = errors.New("approval was modified concurrently")
+)
+
+// PendingAction is a high-risk request held until a second user approves it
+type PendingAction struct {
+	ID          string `json:"id"`
+	Action      string `json:"action"`
+	TenantID    string `json:"tenant_id"`
+	RequestedBy string `json:"requested_by"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	// PayloadHash is the SHA-256 of the request body, so an approval only
+	// covers the exact request that was reviewed
+	PayloadHash  string         `json:"payload_hash"`
+	Reason       string         `json:"reason,omitempty"`
+	Status       ApprovalStatus `json:"status"`
+	DecidedBy    string         `json:"decided_by,omitempty"`
+	DecisionNote string         `json:"decision_note,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	ExpiresAt    time.Time      `json:"expires_at"`
+	DecidedAt    time.Time      `json:"decided_at,omitempty"`
+	ExecutedAt   time.Time      `json:"executed_at,omitempty"`
+}
+
+// DualControlRequest describes a request that needs approval, or that is
+// being executed under one
+type DualControlRequest struct {
+	Action   string
+	TenantID string
+	UserID   string
+	Method   string
+	Path     string
+	Payload  []byte
+	Reason   string
+}
+
+// ApprovalStore persists pending actions
+type ApprovalStore interface {
+	CreateAction(ctx context.Context, action *PendingAction) error
+	// GetAction returns nil if the tenant has no action with the ID
+	GetAction(ctx context.Context, tenantID, id string) (*PendingAction, error)
+	// UpdateAction writes the action if its stored status is still from,
+	// returning ErrApprovalConflict otherwise
+	UpdateAction(ctx context.Context, action *PendingAction, from ApprovalStatus) error
+	// ListActions returns the tenant's actions with the status, oldest first
+	ListActions(ctx context.Context, tenantID string, status ApprovalStatus) ([]PendingAction, error)
+}
+
+// DualControlConfig configures DualControl
+type DualControlConfig struct {
+	// Store holds pending actions
+	Store ApprovalStore
+	// Audit records every request, decision and execution
+	Audit AuditStorage
+	// TTL is how long an action can wait for approval and then execution
+	// (default: 24h)
+	TTL time.Duration
+	// OnRequest is called for every new action, e.g. to notify approvers
+	OnRequest func(ctx context.Context, action *PendingAction)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// DualControl implements four-eyes approval: a high-risk request is held as
+// a pending action until a different user approves it, and the approval is
+// then good for exactly one execution of the same request.
+type DualControl struct {
+	config DualControlConfig
+}
+
+// NewDualControl creates a dual-control workflow
+func NewDualControl(config DualControlConfig) (*DualControl, error) {
+	if config.Store == nil {
+		return nil, fmt.Errorf("dual control requires an approval store")
+	}
+	if config.Audit == nil {
+		return nil, fmt.Errorf("dual control requires audit storage")
+	}
+	if config.TTL == 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &DualControl{config: config}, nil
+}
+
+// Request holds a request for approval
+func (d *DualControl) Request(ctx context.Context, req DualControlRequest) (*PendingAction, error) {
+	if req.UserID == "" || req.Action == "" {
+		return nil, fmt.Errorf("dual control request requires a user and action")
+	}
+
+	now := d.config.Now().UTC()
+	action := &PendingAction{
+		ID:          generatePrefixedID("approval"),
+		Action:      req.Action,
+		TenantID:    req.TenantID,
+		RequestedBy: req.UserID,
+		Method:      req.Method,
+		Path:        req.Path,
+		PayloadHash: payloadHash(req.Payload),
+		Reason:      req.Reason,
+		Status:      ApprovalPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(d.config.TTL),
+	}
+	if err := d.config.Store.CreateAction(ctx, action); err != nil {
+		return nil, fmt.Errorf("failed to store pending action: %w", err)
+	}
+	if err := d.audit(ctx, action, DualControlRequestedEntry, action.RequestedBy); err != nil {
+		return nil, err
+	}
+	if d.config.OnRequest != nil {
+		d.config.OnRequest(ctx, action)
+	}
+	return action, nil
+}
+
+// Approve approves a pending action on behalf of approverID
+func (d *DualControl) Approve(ctx context.Context, tenantID, id, approverID, note string) (*PendingAction, error) {
+	return d.decide(ctx, tenantID, id, approverID, note, ApprovalApproved)
+}
+
+// Reject rejects a pending action on behalf of approverID
+func (d *DualControl) Reject(ctx context.Context, tenantID, id, approverID, note string) (*PendingAction, error) {
+	return d.decide(ctx, tenantID, id, approverID, note, ApprovalRejected)
+}
+
+func (d *DualControl) decide(ctx context.Context, tenantID, id, approverID, note string, status ApprovalStatus) (*PendingAction, error) {
+	action, err := d.load(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if approverID == "" || approverID == action.RequestedBy {
+		return nil, ErrSelfApproval
+	}
+	if action.Status != ApprovalPending {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotPending, action.Status)
+	}
+
+	action.Status = status
+	action.DecidedBy = approverID
+	action.DecisionNote = note
+	action.DecidedAt = d.config.Now().UTC()
+	if err := d.config.Store.UpdateAction(ctx, action, ApprovalPending); err != nil {
+		return nil, err
+	}
+	if err := d.audit(ctx, action, DualControlDecidedEntry, approverID); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// Consume marks an approved action as executed if req is the request that
+// was approved: same action, tenant, requester, method, path and payload.
+// Call Release if the execution then fails so it can be retried.
+func (d *DualControl) Consume(ctx context.Context, id string, req DualControlRequest) (*PendingAction, error) {
+	action, err := d.load(ctx, req.TenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if action.Action != req.Action || action.RequestedBy != req.UserID || action.Method != req.Method ||
+		action.Path != req.Path || action.PayloadHash != payloadHash(req.Payload) {
+		return nil, ErrApprovalMismatch
+	}
+	if action.Status != ApprovalApproved {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotApproved, action.Status)
+	}
+
+	action.Status = ApprovalExecuted
+	action.ExecutedAt = d.config.Now().UTC()
+	if err := d.config.Store.UpdateAction(ctx, action, ApprovalApproved); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// Release returns a consumed action to approved after a failed execution
+func (d *DualControl) Release(ctx context.Context, action *PendingAction) error {
+	action.Status = ApprovalApproved
+	action.ExecutedAt = time.Time{}
+	return d.config.Store.UpdateAction(ctx, action, ApprovalExecuted)
+}
+
+// RecordExecution audits a completed execution
+func (d *DualControl) RecordExecution(ctx context.Context, action *PendingAction) error {
+	return d.audit(ctx, action, DualControlExecutedEntry, action.RequestedBy)
+}
+
+// Pending lists the tenant's actions awaiting approval, oldest first
+func (d *DualControl) Pending(ctx context.Context, tenantID string) ([]PendingAction, error) {
+	actions, err := d.config.Store.ListActions(ctx, tenantID, ApprovalPending)
+	if err != nil {
+		return nil, err
+	}
+	now := d.config.Now()
+	pending := actions[:0]
+	for _, action := range actions {
+		if now.Before(action.ExpiresAt) {
+			pending = append(pending, action)
+		}
+	}
+	return pending, nil
+}
+
+// load reads an unexpired action
+func (d *DualControl) load(ctx context.Context, tenantID, id string) (*PendingAction, error) {
+	action, err := d.config.Store.GetAction(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending action: %w", err)
+	}
+	if action == nil {
+		return nil, ErrApprovalNotFound
+	}
+	if !d.config.Now().Before(action.ExpiresAt) {
+		return nil, ErrApprovalExpired
+	}
+	return action, nil
+}
+
+// audit records a step of the workflow. Workflow steps are written straight
+// to storage so the trail is complete before the step takes effect.
+func (d *DualControl) audit(ctx context.Context, action *PendingAction, entryType, userID string) error {
+	now := d.config.Now().UTC()
+	entry := AuditLogEntry{
+		ID:        generatePrefixedID("entry"),
+		AuditID:   action.ID,
+		TenantID:  action.TenantID,
+		UserID:    userID,
+		EntryType: entryType,
+		Timestamp: now,
+		TTL:       now.Add(365 * 24 * time.Hour).Unix(),
+		Metadata: map[string]any{
+			"action":       action.Action,
+			"status":       string(action.Status),
+			"requested_by": action.RequestedBy,
+			"decided_by":   action.DecidedBy,
+			"method":       action.Method,
+			"path":         action.Path,
+			"payload_hash": action.PayloadHash,
+			"reason":       action.Reason,
+			"note":         action.DecisionNote,
+		},
+	}
+	entry.Checksum = auditChecksum(entry)
+
+	if err := d.config.Audit.Store(ctx, entry); err != nil {
+		return fmt.Errorf("failed to audit %s: %w", entryType, err)
+	}
+	return nil
+}
+
+func payloadHash(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryApprovalStore implements ApprovalStore in memory for testing and
+// single-instance development
+type MemoryApprovalStore struct {
+	mu      sync.Mutex
+	actions map[string]PendingAction
+}
+
+// NewMemoryApprovalStore creates an in-memory approval store
+func NewMemoryApprovalStore() *MemoryApprovalStore {
+	return &MemoryApprovalStore{actions: make(map[string]PendingAction)}
+}
+
+// CreateAction implements ApprovalStore
+func (m *MemoryApprovalStore) CreateAction(ctx context.Context, action *PendingAction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actions[action.TenantID+"#"+action.ID] = *action
+	return nil
+}
+
+// GetAction implements ApprovalStore
+func (m *MemoryApprovalStore) GetAction(ctx context.Context, tenantID, id string) (*PendingAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	action, ok := m.actions[tenantID+"#"+id]
+	if !ok {
+		return nil, nil
+	}
+	return &action, nil
+}
+
+// UpdateAction implements ApprovalStore
+func (m *MemoryApprovalStore) UpdateAction(ctx context.Context, action *PendingAction, from ApprovalStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := action.TenantID + "#" + action.ID
+	if current, ok := m.actions[key]; !ok || current.Status != from {
+		return ErrApprovalConflict
+	}
+	m.actions[key] = *action
+	return nil
+}
+
+// ListActions implements ApprovalStore
+func (m *MemoryApprovalStore) ListActions(ctx context.Context, tenantID string, status ApprovalStatus) ([]PendingAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var actions []PendingAction
+	for _, action := range m.actions {
+		if action.TenantID == tenantID && action.Status == status {
+			actions = append(actions, action)
+		}
+	}
+	sortActions(actions)
+	return actions, nil
+}
+
+// sortActions orders actions oldest first
+func sortActions(actions []PendingAction) {
+	sort.Slice(actions, func(i, j int) bool { return actions[i].CreatedAt.Before(actions[j].CreatedAt) })
+}
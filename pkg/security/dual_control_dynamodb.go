@@ -0,0 +1,195 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBApprovalStore implements ApprovalStore using a DynamoDB table with
+// a string partition key "pk" and sort key "sk". A tenant's actions share a
+// partition, and items expire through the "ttl" attribute 30 days after the
+// action does; the audit trail is the permanent record.
+type DynamoDBApprovalStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBApprovalStore creates a DynamoDB-backed approval store
+func NewDynamoDBApprovalStore(client *dynamodb.Client, tableName string) *DynamoDBApprovalStore {
+	return &DynamoDBApprovalStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// dynamoDBPendingAction is the DynamoDB item structure for a pending action
+type dynamoDBPendingAction struct {
+	PK           string         `dynamodbav:"pk"` // "approval#<tenant>"
+	SK           string         `dynamodbav:"sk"` // action ID
+	TTL          int64          `dynamodbav:"ttl"`
+	Action       string         `dynamodbav:"action"`
+	TenantID     string         `dynamodbav:"tenant_id"`
+	RequestedBy  string         `dynamodbav:"requested_by"`
+	Method       string         `dynamodbav:"method"`
+	Path         string         `dynamodbav:"path"`
+	PayloadHash  string         `dynamodbav:"payload_hash"`
+	Reason       string         `dynamodbav:"reason,omitempty"`
+	Status       ApprovalStatus `dynamodbav:"status"`
+	DecidedBy    string         `dynamodbav:"decided_by,omitempty"`
+	DecisionNote string         `dynamodbav:"decision_note,omitempty"`
+	CreatedAt    time.Time      `dynamodbav:"created_at"`
+	ExpiresAt    time.Time      `dynamodbav:"expires_at"`
+	DecidedAt    time.Time      `dynamodbav:"decided_at"`
+	ExecutedAt   time.Time      `dynamodbav:"executed_at"`
+}
+
+func (i dynamoDBPendingAction) action() *PendingAction {
+	return &PendingAction{
+		ID:           i.SK,
+		Action:       i.Action,
+		TenantID:     i.TenantID,
+		RequestedBy:  i.RequestedBy,
+		Method:       i.Method,
+		Path:         i.Path,
+		PayloadHash:  i.PayloadHash,
+		Reason:       i.Reason,
+		Status:       i.Status,
+		DecidedBy:    i.DecidedBy,
+		DecisionNote: i.DecisionNote,
+		CreatedAt:    i.CreatedAt,
+		ExpiresAt:    i.ExpiresAt,
+		DecidedAt:    i.DecidedAt,
+		ExecutedAt:   i.ExecutedAt,
+	}
+}
+
+// CreateAction conditionally writes a new action
+func (d *DynamoDBApprovalStore) CreateAction(ctx context.Context, action *PendingAction) error {
+	return d.put(ctx, action, aws.String("attribute_not_exists(pk)"), nil)
+}
+
+// GetAction reads an action
+func (d *DynamoDBApprovalStore) GetAction(ctx context.Context, tenantID, id string) (*PendingAction, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: approvalKey(tenantID)},
+			"sk": &types.AttributeValueMemberS{Value: id},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var item dynamoDBPendingAction
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, err
+	}
+	return item.action(), nil
+}
+
+// UpdateAction writes the action if its stored status is still from
+func (d *DynamoDBApprovalStore) UpdateAction(ctx context.Context, action *PendingAction, from ApprovalStatus) error {
+	return d.put(ctx, action, aws.String("#status = :from"), map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberS{Value: string(from)},
+	})
+}
+
+// ListActions queries the tenant's partition for actions with the status
+func (d *DynamoDBApprovalStore) ListActions(ctx context.Context, tenantID string, status ApprovalStatus) ([]PendingAction, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		FilterExpression:       aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":     &types.AttributeValueMemberS{Value: approvalKey(tenantID)},
+			":status": &types.AttributeValueMemberS{Value: string(status)},
+		},
+	}
+
+	var actions []PendingAction
+	for {
+		result, err := d.client.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range result.Items {
+			var item dynamoDBPendingAction
+			if err := attributevalue.UnmarshalMap(raw, &item); err != nil {
+				return nil, err
+			}
+			actions = append(actions, *item.action())
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	sortActions(actions)
+	return actions, nil
+}
+
+func (d *DynamoDBApprovalStore) put(ctx context.Context, action *PendingAction, condition *string, values map[string]types.AttributeValue) error {
+	av, err := attributevalue.MarshalMap(dynamoDBPendingAction{
+		PK:           approvalKey(action.TenantID),
+		SK:           action.ID,
+		TTL:          action.ExpiresAt.Add(30 * 24 * time.Hour).Unix(),
+		Action:       action.Action,
+		TenantID:     action.TenantID,
+		RequestedBy:  action.RequestedBy,
+		Method:       action.Method,
+		Path:         action.Path,
+		PayloadHash:  action.PayloadHash,
+		Reason:       action.Reason,
+		Status:       action.Status,
+		DecidedBy:    action.DecidedBy,
+		DecisionNote: action.DecisionNote,
+		CreatedAt:    action.CreatedAt,
+		ExpiresAt:    action.ExpiresAt,
+		DecidedAt:    action.DecidedAt,
+		ExecutedAt:   action.ExecutedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:                 aws.String(d.tableName),
+		Item:                      av,
+		ConditionExpression:       condition,
+		ExpressionAttributeValues: values,
+	}
+	if values != nil {
+		input.ExpressionAttributeNames = map[string]string{"#status": "status"}
+	}
+
+	_, err = d.client.PutItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrApprovalConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// approvalKey is the partition key for a tenant's actions
+func approvalKey(tenantID string) string {
+	return "approval#" + tenantID
+}
@@ -0,0 +1,117 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualControlLifecycle(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryAuditStorage()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var notified []*PendingAction
+	dc, err := NewDualControl(DualControlConfig{
+		Store:     NewMemoryApprovalStore(),
+		Audit:     storage,
+		Now:       func() time.Time { return now },
+		OnRequest: func(ctx context.Context, action *PendingAction) { notified = append(notified, action) },
+	})
+	require.NoError(t, err)
+
+	req := DualControlRequest{
+		Action:   "refund",
+		TenantID: "t1",
+		UserID:   "alice",
+		Method:   "POST",
+		Path:     "/refunds",
+		Payload:  []byte(`{"amount":50000}`),
+		Reason:   "duplicate charge",
+	}
+	action, err := dc.Request(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalPending, action.Status)
+	assert.Equal(t, now.Add(24*time.Hour), action.ExpiresAt)
+	assert.Len(t, notified, 1)
+
+	pending, err := dc.Pending(ctx, "t1")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, action.ID, pending[0].ID)
+
+	_, err = dc.Consume(ctx, action.ID, req)
+	assert.ErrorIs(t, err, ErrApprovalNotApproved)
+	_, err = dc.Approve(ctx, "t1", action.ID, "alice", "")
+	assert.ErrorIs(t, err, ErrSelfApproval)
+	_, err = dc.Approve(ctx, "t2", action.ID, "bob", "")
+	assert.ErrorIs(t, err, ErrApprovalNotFound)
+
+	approved, err := dc.Approve(ctx, "t1", action.ID, "bob", "verified with customer")
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalApproved, approved.Status)
+	assert.Equal(t, "bob", approved.DecidedBy)
+	_, err = dc.Reject(ctx, "t1", action.ID, "carol", "")
+	assert.ErrorIs(t, err, ErrApprovalNotPending)
+
+	tampered := req
+	tampered.Payload = []byte(`{"amount":500000}`)
+	_, err = dc.Consume(ctx, action.ID, tampered)
+	assert.ErrorIs(t, err, ErrApprovalMismatch)
+	other := req
+	other.UserID = "mallory"
+	_, err = dc.Consume(ctx, action.ID, other)
+	assert.ErrorIs(t, err, ErrApprovalMismatch)
+
+	consumed, err := dc.Consume(ctx, action.ID, req)
+	require.NoError(t, err)
+	_, err = dc.Consume(ctx, action.ID, req)
+	assert.ErrorIs(t, err, ErrApprovalNotApproved, "an approval executes once")
+
+	require.NoError(t, dc.Release(ctx, consumed))
+	consumed, err = dc.Consume(ctx, action.ID, req)
+	require.NoError(t, err, "a released approval can be retried")
+	require.NoError(t, dc.RecordExecution(ctx, consumed))
+
+	entries, err := storage.Query(ctx, AuditFilter{AuditID: action.ID})
+	require.NoError(t, err)
+	types := map[string]int{}
+	for _, entry := range entries {
+		types[entry.EntryType]++
+		assert.NotEmpty(t, entry.Checksum)
+	}
+	assert.Equal(t, map[string]int{
+		DualControlRequestedEntry: 1,
+		DualControlDecidedEntry:   1,
+		DualControlExecutedEntry:  1,
+	}, types)
+}
+
+func TestDualControlExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	dc, err := NewDualControl(DualControlConfig{
+		Store: NewMemoryApprovalStore(),
+		Audit: NewInMemoryAuditStorage(),
+		TTL:   time.Hour,
+		Now:   func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	action, err := dc.Request(ctx, DualControlRequest{Action: "role_change", TenantID: "t1", UserID: "alice"})
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	_, err = dc.Approve(ctx, "t1", action.ID, "bob", "")
+	assert.ErrorIs(t, err, ErrApprovalExpired)
+
+	pending, err := dc.Pending(ctx, "t1")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	_, err = NewDualControl(DualControlConfig{Store: NewMemoryApprovalStore()})
+	assert.Error(t, err, "audit storage is required")
+}
@@ -0,0 +1,242 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Audit entry types written by Impersonation
+const (
+	ImpersonationStartEntry  = "impersonation_start"
+	ImpersonationAccessEntry = "impersonation_access"
+)
+
+var (
+	// ErrImpersonationReason is returned when impersonation is requested
+	// without one of the configured reason codes
+	ErrImpersonationReason = errors.New("a valid reason code is required for impersonation")
+	// ErrImpersonationRequest is returned when an impersonation request is
+	// missing the actor or subject, or names the actor as the subject
+	ErrImpersonationRequest = errors.New("impersonation request requires an actor and a different subject")
+)
+
+// Actor is the identity acting on behalf of a principal, such as a support
+// agent impersonating a customer
+type Actor struct {
+	UserID   string `json:"user_id"`
+	TenantID string `json:"tenant_id,omitempty"`
+	// ImpersonationID is the grant the actor is acting under
+	ImpersonationID string `json:"impersonation_id,omitempty"`
+	// ReasonCode is why the actor is impersonating the principal
+	ReasonCode string `json:"reason_code,omitempty"`
+}
+
+// ImpersonationGrant is time-limited permission for an actor to act as a
+// subject
+type ImpersonationGrant struct {
+	ID string `json:"id"`
+	// Actor is who is impersonating
+	ActorID       string `json:"actor_id"`
+	ActorTenantID string `json:"actor_tenant_id,omitempty"`
+	// Subject is who is being impersonated
+	TenantID   string    `json:"tenant_id"`
+	UserID     string    `json:"user_id"`
+	ReasonCode string    `json:"reason_code"`
+	Note       string    `json:"note,omitempty"`
+	GrantedAt  time.Time `json:"granted_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Actor returns the grant's actor, to attach to the subject's principal
+func (g *ImpersonationGrant) Actor() *Actor {
+	return &Actor{
+		UserID:          g.ActorID,
+		TenantID:        g.ActorTenantID,
+		ImpersonationID: g.ID,
+		ReasonCode:      g.ReasonCode,
+	}
+}
+
+// ImpersonationRequest asks for an actor to act as a subject
+type ImpersonationRequest struct {
+	ActorID       string
+	ActorTenantID string
+	TenantID      string
+	UserID        string
+	// ReasonCode must be one of the config's ReasonCodes
+	ReasonCode string
+	// Note is a free-text justification, such as a ticket number
+	Note string
+	// Duration is how long impersonation is needed (default: the config's
+	// DefaultDuration, capped at MaxDuration)
+	Duration  time.Duration
+	IPAddress string
+}
+
+// ImpersonationConfig configures Impersonation
+type ImpersonationConfig struct {
+	// Storage records grants and every request made under them (required)
+	Storage AuditStorage
+	// ReasonCodes are the accepted reasons, e.g. "customer_request" or
+	// "incident" (required)
+	ReasonCodes []string
+	// DefaultDuration is the grant length when none is requested (default: 15m)
+	DefaultDuration time.Duration
+	// MaxDuration caps requested durations (default: 1h)
+	MaxDuration time.Duration
+	// OnGrant is called for every issued grant, e.g. to notify the customer
+	OnGrant func(ctx context.Context, grant *ImpersonationGrant)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Impersonation issues time-limited grants for support agents to act as
+// customers. Grants and every request made under them are written to the
+// audit trail with both the actor and the subject, so it is always clear
+// who really did what.
+type Impersonation struct {
+	config ImpersonationConfig
+}
+
+// NewImpersonation creates an impersonation workflow backed by audit storage
+func NewImpersonation(config ImpersonationConfig) (*Impersonation, error) {
+	if config.Storage == nil {
+		return nil, fmt.Errorf("impersonation requires audit storage")
+	}
+	if len(config.ReasonCodes) == 0 {
+		return nil, fmt.Errorf("impersonation requires reason codes")
+	}
+	if config.DefaultDuration == 0 {
+		config.DefaultDuration = 15 * time.Minute
+	}
+	if config.MaxDuration == 0 {
+		config.MaxDuration = time.Hour
+	}
+	if config.DefaultDuration > config.MaxDuration {
+		config.DefaultDuration = config.MaxDuration
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return &Impersonation{config: config}, nil
+}
+
+// ReasonCodes returns the accepted reason codes
+func (i *Impersonation) ReasonCodes() []string {
+	return slices.Clone(i.config.ReasonCodes)
+}
+
+// Start issues an impersonation grant and records it in the audit trail
+func (i *Impersonation) Start(ctx context.Context, req ImpersonationRequest) (*ImpersonationGrant, error) {
+	if req.ActorID == "" || req.UserID == "" ||
+		(req.ActorID == req.UserID && req.ActorTenantID == req.TenantID) {
+		return nil, ErrImpersonationRequest
+	}
+	reasonCode := strings.TrimSpace(req.ReasonCode)
+	if !slices.Contains(i.config.ReasonCodes, reasonCode) {
+		return nil, fmt.Errorf("%w: one of %s", ErrImpersonationReason, strings.Join(i.config.ReasonCodes, ", "))
+	}
+
+	duration := req.Duration
+	if duration <= 0 {
+		duration = i.config.DefaultDuration
+	}
+	if duration > i.config.MaxDuration {
+		duration = i.config.MaxDuration
+	}
+
+	now := i.config.Now().UTC()
+	grant := &ImpersonationGrant{
+		ID:            generatePrefixedID("imp"),
+		ActorID:       req.ActorID,
+		ActorTenantID: req.ActorTenantID,
+		TenantID:      req.TenantID,
+		UserID:        req.UserID,
+		ReasonCode:    reasonCode,
+		Note:          strings.TrimSpace(req.Note),
+		GrantedAt:     now,
+		ExpiresAt:     now.Add(duration),
+	}
+
+	entry := AuditLogEntry{
+		ID:        generatePrefixedID("entry"),
+		AuditID:   grant.ID,
+		TenantID:  grant.TenantID,
+		UserID:    grant.UserID,
+		ActorID:   grant.ActorID,
+		EntryType: ImpersonationStartEntry,
+		Timestamp: now,
+		TTL:       now.Add(365 * 24 * time.Hour).Unix(),
+		SecurityEvent: &SecurityEvent{
+			EventType:   "impersonation_started",
+			Severity:    "high",
+			Description: grant.ReasonCode,
+			Timestamp:   now,
+			Metadata: map[string]any{
+				"actor_id":   grant.ActorID,
+				"ip_address": req.IPAddress,
+			},
+		},
+		Metadata: map[string]any{
+			"actor_tenant_id": grant.ActorTenantID,
+			"reason_code":     grant.ReasonCode,
+			"note":            grant.Note,
+			"expires_at":      grant.ExpiresAt.Format(time.RFC3339Nano),
+		},
+	}
+	entry.Checksum = auditChecksum(entry)
+
+	if err := i.config.Storage.Store(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record impersonation: %w", err)
+	}
+	if i.config.OnGrant != nil {
+		i.config.OnGrant(ctx, grant)
+	}
+	return grant, nil
+}
+
+// RecordAccess records a request made by an actor as the principal
+func (i *Impersonation) RecordAccess(ctx context.Context, principal *Principal, action, resource string) error {
+	if principal == nil || principal.Actor == nil {
+		return fmt.Errorf("principal is not impersonated")
+	}
+	actor := principal.Actor
+
+	now := i.config.Now().UTC()
+	entry := AuditLogEntry{
+		ID:        generatePrefixedID("entry"),
+		AuditID:   actor.ImpersonationID,
+		TenantID:  principal.TenantID,
+		UserID:    principal.UserID,
+		ActorID:   actor.UserID,
+		EntryType: ImpersonationAccessEntry,
+		Timestamp: now,
+		TTL:       now.Add(365 * 24 * time.Hour).Unix(),
+		Request: &AuditRequest{
+			UserID:    principal.UserID,
+			TenantID:  principal.TenantID,
+			ActorID:   actor.UserID,
+			Action:    action,
+			Resource:  resource,
+			Timestamp: now,
+			IPAddress: principal.IPAddress,
+			UserAgent: principal.UserAgent,
+			SessionID: principal.SessionID,
+		},
+		Metadata: map[string]any{
+			"actor_tenant_id": actor.TenantID,
+			"reason_code":     actor.ReasonCode,
+		},
+	}
+	entry.Checksum = auditChecksum(entry)
+
+	if err := i.config.Storage.Store(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record impersonated access: %w", err)
+	}
+	return nil
+}
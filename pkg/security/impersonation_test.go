@@ -0,0 +1,70 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationStart(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryAuditStorage()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	_, err := NewImpersonation(ImpersonationConfig{Storage: storage})
+	assert.Error(t, err, "reason codes are required")
+
+	var notified []*ImpersonationGrant
+	imp, err := NewImpersonation(ImpersonationConfig{
+		Storage:     storage,
+		ReasonCodes: []string{"customer_request", "incident"},
+		Now:         func() time.Time { return now },
+		OnGrant:     func(ctx context.Context, grant *ImpersonationGrant) { notified = append(notified, grant) },
+	})
+	require.NoError(t, err)
+
+	_, err = imp.Start(ctx, ImpersonationRequest{ActorID: "agent-7", TenantID: "t1", UserID: "cus_1"})
+	assert.ErrorIs(t, err, ErrImpersonationReason)
+	_, err = imp.Start(ctx, ImpersonationRequest{ActorID: "agent-7", TenantID: "t1", UserID: "cus_1", ReasonCode: "curious"})
+	assert.ErrorIs(t, err, ErrImpersonationReason)
+	_, err = imp.Start(ctx, ImpersonationRequest{TenantID: "t1", UserID: "cus_1", ReasonCode: "incident"})
+	assert.ErrorIs(t, err, ErrImpersonationRequest)
+	_, err = imp.Start(ctx, ImpersonationRequest{ActorID: "cus_1", TenantID: "t1", ActorTenantID: "t1", UserID: "cus_1", ReasonCode: "incident"})
+	assert.ErrorIs(t, err, ErrImpersonationRequest)
+
+	grant, err := imp.Start(ctx, ImpersonationRequest{
+		ActorID:       "agent-7",
+		ActorTenantID: "support",
+		TenantID:      "t1",
+		UserID:        "cus_1",
+		ReasonCode:    "incident",
+		Note:          "INC-311",
+		Duration:      3 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), grant.ExpiresAt, "duration is capped at MaxDuration")
+	assert.Len(t, notified, 1)
+
+	principal := &Principal{UserID: "cus_1", TenantID: "t1", Actor: grant.Actor()}
+	assert.True(t, principal.IsImpersonated())
+	assert.Equal(t, "agent-7", principal.ToAuditMap()["actor_id"])
+	require.NoError(t, imp.RecordAccess(ctx, principal, "GET", "/orders"))
+	assert.Error(t, imp.RecordAccess(ctx, &Principal{UserID: "cus_1"}, "GET", "/orders"))
+
+	entries, err := storage.Query(ctx, AuditFilter{AuditID: grant.ID})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, "cus_1", entry.UserID)
+		assert.Equal(t, "agent-7", entry.ActorID)
+		assert.Equal(t, auditChecksum(entry), entry.Checksum)
+	}
+
+	byActor, err := storage.Query(ctx, AuditFilter{ActorID: "agent-7", EntryType: ImpersonationStartEntry})
+	require.NoError(t, err)
+	require.Len(t, byActor, 1)
+	assert.Equal(t, "INC-311", byActor[0].Metadata["note"])
+}
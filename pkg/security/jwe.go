@@ -0,0 +1,169 @@
+package security
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JWE algorithms produced by EncryptJWE
+const (
+	JWEAlgRSAOAEP256 = "RSA-OAEP-256"
+	JWEEncA256GCM    = "A256GCM"
+)
+
+// ErrInvalidJWE is returned when a JWE can't be parsed or decrypted
+var ErrInvalidJWE = errors.New("invalid JWE")
+
+// JWEKey is an API client's public encryption key
+type JWEKey struct {
+	// ID is published as the JWE "kid" so the client can pick its private key
+	ID  string
+	Key *rsa.PublicKey
+}
+
+// ParseJWEKey reads an RSA public key from a PEM "PUBLIC KEY" block
+func ParseJWEKey(id string, pemData []byte) (*JWEKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("JWE keys must be RSA, got %T", parsed)
+	}
+	if key.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("JWE keys must be at least 2048 bits")
+	}
+	return &JWEKey{ID: id, Key: key}, nil
+}
+
+// JWEKeyStore resolves the encryption key registered for an API client
+type JWEKeyStore interface {
+	// EncryptionKey returns nil if the client has no key
+	EncryptionKey(ctx context.Context, clientID string) (*JWEKey, error)
+}
+
+// StaticJWEKeys is a JWEKeyStore backed by a map of client ID to key
+type StaticJWEKeys map[string]*JWEKey
+
+// EncryptionKey returns the client's key
+func (s StaticJWEKeys) EncryptionKey(ctx context.Context, clientID string) (*JWEKey, error) {
+	return s[clientID], nil
+}
+
+// JWEHeader is the protected header of a JWE
+type JWEHeader struct {
+	Algorithm  string `json:"alg"`
+	Encryption string `json:"enc"`
+	KeyID      string `json:"kid,omitempty"`
+	// ContentType is "json" when the plaintext is a JSON document
+	ContentType string `json:"cty,omitempty"`
+}
+
+// EncryptJWE encrypts plaintext for key as a compact JWE using RSA-OAEP-256
+// key wrapping and A256GCM content encryption
+func EncryptJWE(key *JWEKey, plaintext []byte, contentType string) (string, error) {
+	header, err := json.Marshal(JWEHeader{
+		Algorithm:   JWEAlgRSAOAEP256,
+		Encryption:  JWEEncA256GCM,
+		KeyID:       key.ID,
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", err
+	}
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+
+	cek := make([]byte, 32)
+	if _, err := rand.Read(cek); err != nil {
+		return "", err
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key.Key, cek, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap content key: %w", err)
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(encodedHeader))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		encodedHeader,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// DecryptJWE decrypts a compact JWE produced by EncryptJWE. It's what API
+// clients do with their private key; the service itself only encrypts.
+func DecryptJWE(privateKey *rsa.PrivateKey, jwe string) ([]byte, *JWEHeader, error) {
+	parts := strings.Split(jwe, ".")
+	if len(parts) != 5 {
+		return nil, nil, ErrInvalidJWE
+	}
+	decoded := make([][]byte, 5)
+	for i, part := range parts {
+		raw, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, nil, ErrInvalidJWE
+		}
+		decoded[i] = raw
+	}
+
+	var header JWEHeader
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		return nil, nil, ErrInvalidJWE
+	}
+	if header.Algorithm != JWEAlgRSAOAEP256 || header.Encryption != JWEEncA256GCM {
+		return nil, nil, fmt.Errorf("%w: unsupported algorithm %s/%s", ErrInvalidJWE, header.Algorithm, header.Encryption)
+	}
+
+	cek, err := rsa.DecryptOAEP(sha256.New(), nil, privateKey, decoded[1], nil)
+	if err != nil {
+		return nil, nil, ErrInvalidJWE
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, nil, ErrInvalidJWE
+	}
+	if len(decoded[2]) != gcm.NonceSize() {
+		return nil, nil, ErrInvalidJWE
+	}
+	plaintext, err := gcm.Open(nil, decoded[2], append(decoded[3], decoded[4]...), []byte(parts[0]))
+	if err != nil {
+		return nil, nil, ErrInvalidJWE
+	}
+	return plaintext, &header, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
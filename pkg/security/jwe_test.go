@@ -0,0 +1,58 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWERoundTrip(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	key, err := ParseJWEKey("acme-2024", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	jwe, err := EncryptJWE(key, []byte(`"4111111111111111"`), "json")
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(jwe, "."), 5)
+	assert.NotContains(t, jwe, "4111")
+
+	plaintext, header, err := DecryptJWE(privateKey, jwe)
+	require.NoError(t, err)
+	assert.Equal(t, `"4111111111111111"`, string(plaintext))
+	assert.Equal(t, JWEHeader{Algorithm: JWEAlgRSAOAEP256, Encryption: JWEEncA256GCM, KeyID: "acme-2024", ContentType: "json"}, *header)
+
+	again, err := EncryptJWE(key, []byte(`"4111111111111111"`), "json")
+	require.NoError(t, err)
+	assert.NotEqual(t, jwe, again, "each encryption uses a fresh content key and IV")
+
+	parts := strings.Split(jwe, ".")
+	parts[3] = parts[3][:len(parts[3])-2] + "AA"
+	_, _, err = DecryptJWE(privateKey, strings.Join(parts, "."))
+	assert.ErrorIs(t, err, ErrInvalidJWE)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, _, err = DecryptJWE(other, jwe)
+	assert.ErrorIs(t, err, ErrInvalidJWE)
+
+	keys := StaticJWEKeys{"acme": key}
+	found, err := keys.EncryptionKey(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Same(t, key, found)
+	missing, err := keys.EncryptionKey(context.Background(), "globex")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	_, err = ParseJWEKey("bad", []byte("not a key"))
+	assert.Error(t, err)
+}
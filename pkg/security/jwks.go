@@ -0,0 +1,240 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKSConfig configures a JWKS
+type JWKSConfig struct {
+	// URL of the JSON Web Key Set, e.g. a Cognito user pool's
+	// https://cognito-idp.<region>.amazonaws.com/<pool-id>/.well-known/jwks.json
+	// or an Auth0 tenant's https://<tenant>.auth0.com/.well-known/jwks.json
+	URL string
+	// HTTPClient fetches the key set (default: a client with a 10s timeout)
+	HTTPClient *http.Client
+	// CacheTTL is how long fetched keys are trusted before they're
+	// refetched (default: 1 hour)
+	CacheTTL time.Duration
+	// MinRefreshInterval limits refetches for tokens naming unknown keys,
+	// so forged key IDs can't be used to hammer the issuer, and is the
+	// first wait after a failed fetch; the wait doubles with each further
+	// failure, up to CacheTTL (default: 1 minute)
+	MinRefreshInterval time.Duration
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// JWKS caches the RSA and EC signing keys an issuer publishes as a JSON
+// Web Key Set. Keys are refetched when the cache expires or a token names
+// a key the cache doesn't have, which picks up key rotation; while the
+// issuer is unreachable the cached keys stay in use and fetches back off.
+type JWKS struct {
+	config JWKSConfig
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// failedAt, failures and fetchErr describe the fetches that failed
+	// since the last successful one
+	failedAt time.Time
+	failures int
+	fetchErr error
+}
+
+// NewJWKS creates a key set cache for config.URL. Keys are fetched on
+// first use.
+func NewJWKS(config JWKSConfig) (*JWKS, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("JWKS requires a URL")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = time.Hour
+	}
+	if config.MinRefreshInterval == 0 {
+		config.MinRefreshInterval = time.Minute
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &JWKS{config: config}, nil
+}
+
+// Key returns the signing key with ID kid, refetching the key set when the
+// cached copy is stale or doesn't have the key
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	now := j.config.Now()
+
+	j.mu.Lock()
+	keys, fetchedAt := j.keys, j.fetchedAt
+	backingOff := j.failures > 0 && now.Sub(j.failedAt) < j.backoff()
+	fetchErr := j.fetchErr
+	j.mu.Unlock()
+
+	if keys == nil || now.Sub(fetchedAt) >= j.config.CacheTTL ||
+		(lookupJWK(keys, kid) == nil && now.Sub(fetchedAt) >= j.config.MinRefreshInterval) {
+		if backingOff {
+			if keys == nil {
+				return nil, fetchErr
+			}
+		} else if fetched, err := j.fetch(ctx); err != nil {
+			j.mu.Lock()
+			j.failedAt, j.fetchErr = now, err
+			j.failures++
+			j.mu.Unlock()
+			if keys == nil {
+				return nil, err
+			}
+			// Keep using the cached keys while the issuer is unreachable
+		} else {
+			keys = fetched
+			j.mu.Lock()
+			j.keys, j.fetchedAt = fetched, now
+			j.failures, j.fetchErr = 0, nil
+			j.mu.Unlock()
+		}
+	}
+
+	key := lookupJWK(keys, kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// backoff is how long to wait after the last failed fetch before trying
+// again: MinRefreshInterval, doubled for each further failure up to
+// CacheTTL. The caller holds j.mu.
+func (j *JWKS) backoff() time.Duration {
+	wait := j.config.MinRefreshInterval
+	for i := 1; i < j.failures && wait < j.config.CacheTTL; i++ {
+		wait *= 2
+	}
+	if wait > j.config.CacheTTL {
+		wait = j.config.CacheTTL
+	}
+	return wait
+}
+
+// lookupJWK finds a key by ID; tokens without a key ID may only be
+// verified by a JWKS with a single key
+func lookupJWK(keys map[string]crypto.PublicKey, kid string) crypto.PublicKey {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return keys[kid]
+}
+
+// fetch downloads and parses the key set's signing keys
+func (j *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys of unsupported types rather than failing the set
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
+
+// jsonWebKey is a JWKS entry for an RSA or EC public key
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key is shorter than 2048 bits")
+		}
+		return key, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("EC point is not on the curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
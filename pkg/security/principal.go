@@ -20,6 +20,13 @@ type Principal struct {
 	IssuedAt   time.Time `json:"issued_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
 
+	// How the user authenticated, for step-up checks
+	AuthContext AuthContext `json:"auth_context"`
+
+	// Actor is set when someone else, such as a support agent, is acting
+	// as this user
+	Actor *Actor `json:"actor,omitempty"`
+
 	// Request context
 	IPAddress string `json:"ip_address"`
 	UserAgent string `json:"user_agent"`
@@ -84,6 +91,11 @@ func (p *Principal) HasAllRoles(roles ...string) bool {
 	return true
 }
 
+// IsImpersonated reports whether an actor is acting as the principal
+func (p *Principal) IsImpersonated() bool {
+	return p.Actor != nil
+}
+
 // IsExpired checks if the principal's authentication has expired
 func (p *Principal) IsExpired() bool {
 	return time.Now().After(p.ExpiresAt)
@@ -138,7 +150,7 @@ func (p *Principal) CanAccessResource(resource, action string) bool {
 
 // ToAuditMap converts the principal to a map for audit logging
 func (p *Principal) ToAuditMap() map[string]any {
-	return map[string]any{
+	audit := map[string]any{
 		"user_id":     p.UserID,
 		"tenant_id":   p.TenantID,
 		"account_id":  p.AccountID,
@@ -152,6 +164,13 @@ func (p *Principal) ToAuditMap() map[string]any {
 		"issued_at":   p.IssuedAt,
 		"expires_at":  p.ExpiresAt,
 	}
+	if p.Actor != nil {
+		audit["actor_id"] = p.Actor.UserID
+		audit["actor_tenant_id"] = p.Actor.TenantID
+		audit["impersonation_id"] = p.Actor.ImpersonationID
+		audit["reason_code"] = p.Actor.ReasonCode
+	}
+	return audit
 }
 
 // AnonymousPrincipal creates a principal for unauthenticated requests
@@ -0,0 +1,225 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// ErrInvalidSignature is returned when a detached JWS doesn't verify
+var ErrInvalidSignature = errors.New("invalid response signature")
+
+// ResponseSigner produces JWS signatures over a signing input
+type ResponseSigner interface {
+	// Algorithm is the JWS "alg" the signer produces, e.g. "ES256"
+	Algorithm() string
+	// KeyID is published as the JWS "kid" so consumers can pick a key
+	KeyID() string
+	// Sign returns the JWS signature bytes for the signing input
+	Sign(ctx context.Context, signingInput []byte) ([]byte, error)
+}
+
+// jwsAlgorithm describes how a JWS algorithm maps onto KMS
+type jwsAlgorithm struct {
+	kms  types.SigningAlgorithmSpec
+	hash crypto.Hash
+	// size is the byte length of each of r and s for ECDSA; 0 for RSA
+	size int
+	pss  bool
+}
+
+var jwsAlgorithms = map[string]jwsAlgorithm{
+	"ES256": {kms: types.SigningAlgorithmSpecEcdsaSha256, hash: crypto.SHA256, size: 32},
+	"ES384": {kms: types.SigningAlgorithmSpecEcdsaSha384, hash: crypto.SHA384, size: 48},
+	"ES512": {kms: types.SigningAlgorithmSpecEcdsaSha512, hash: crypto.SHA512, size: 66},
+	"RS256": {kms: types.SigningAlgorithmSpecRsassaPkcs1V15Sha256, hash: crypto.SHA256},
+	"RS384": {kms: types.SigningAlgorithmSpecRsassaPkcs1V15Sha384, hash: crypto.SHA384},
+	"RS512": {kms: types.SigningAlgorithmSpecRsassaPkcs1V15Sha512, hash: crypto.SHA512},
+	"PS256": {kms: types.SigningAlgorithmSpecRsassaPssSha256, hash: crypto.SHA256, pss: true},
+	"PS384": {kms: types.SigningAlgorithmSpecRsassaPssSha384, hash: crypto.SHA384, pss: true},
+	"PS512": {kms: types.SigningAlgorithmSpecRsassaPssSha512, hash: crypto.SHA512, pss: true},
+}
+
+// KMSAPI is the subset of the KMS client used by KMSSigner
+type KMSAPI interface {
+	Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error)
+	GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error)
+}
+
+// KMSSigner signs with an asymmetric KMS key, so the private key never
+// leaves KMS. Digests are computed locally, so payloads of any size can be
+// signed.
+type KMSSigner struct {
+	client    KMSAPI
+	keyID     string
+	algorithm string
+	spec      jwsAlgorithm
+}
+
+// NewKMSSigner creates a signer for a KMS key with SIGN_VERIFY usage.
+// algorithm is a JWS algorithm the key supports: ES256, ES384 or ES512 for
+// ECC_NIST keys, or RS*/PS* for RSA keys.
+func NewKMSSigner(client KMSAPI, keyID, algorithm string) (*KMSSigner, error) {
+	spec, ok := jwsAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWS algorithm: %s", algorithm)
+	}
+	if keyID == "" {
+		return nil, fmt.Errorf("KMS key ID is required")
+	}
+	return &KMSSigner{client: client, keyID: keyID, algorithm: algorithm, spec: spec}, nil
+}
+
+// Algorithm implements ResponseSigner
+func (s *KMSSigner) Algorithm() string { return s.algorithm }
+
+// KeyID implements ResponseSigner
+func (s *KMSSigner) KeyID() string { return s.keyID }
+
+// Sign implements ResponseSigner
+func (s *KMSSigner) Sign(ctx context.Context, signingInput []byte) ([]byte, error) {
+	hash := s.spec.hash.New()
+	hash.Write(signingInput)
+
+	out, err := s.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          hash.Sum(nil),
+		MessageType:      types.MessageTypeDigest,
+		SigningAlgorithm: s.spec.kms,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KMS sign failed: %w", err)
+	}
+	if s.spec.size == 0 {
+		return out.Signature, nil
+	}
+	// KMS returns DER-encoded ECDSA signatures; JWS uses fixed-size r || s
+	return ecdsaDERToJWS(out.Signature, s.spec.size)
+}
+
+// PublicKey fetches the key's public half for distribution to consumers
+func (s *KMSSigner) PublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	out, err := s.client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(s.keyID)})
+	if err != nil {
+		return nil, fmt.Errorf("KMS get public key failed: %w", err)
+	}
+	return x509.ParsePKIXPublicKey(out.PublicKey)
+}
+
+// JWSHeader is the protected header of a response signature
+type JWSHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	// IssuedAt is when the response was signed, in Unix seconds
+	IssuedAt int64 `json:"iat,omitempty"`
+	// Path and Status bind the signature to the response it was issued for,
+	// so a signed body can't be replayed as the answer to another request
+	Path   string `json:"path,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
+
+// SignDetachedJWS signs payload and returns a JWS in compact serialization
+// with the payload omitted ("header..signature", RFC 7515 Appendix F). The
+// header's alg and kid are set from the signer.
+func SignDetachedJWS(ctx context.Context, signer ResponseSigner, header JWSHeader, payload []byte) (string, error) {
+	header.Algorithm = signer.Algorithm()
+	header.KeyID = signer.KeyID()
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	encodedHeader := base64.RawURLEncoding.EncodeToString(headerJSON)
+	signingInput := encodedHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	signature, err := signer.Sign(ctx, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return encodedHeader + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyDetachedJWS verifies a detached JWS over payload with an ECDSA or
+// RSA public key and returns its protected header. Callers should check the
+// header's kid, path and status against what they expect.
+func VerifyDetachedJWS(jws string, payload []byte, key crypto.PublicKey) (*JWSHeader, error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return nil, fmt.Errorf("%w: not a detached JWS", ErrInvalidSignature)
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	var header JWSHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	spec, ok := jwsAlgorithms[header.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, header.Algorithm)
+	}
+
+	hash := spec.hash.New()
+	hash.Write([]byte(parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload)))
+	digest := hash.Sum(nil)
+
+	valid := false
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if spec.size > 0 && len(signature) == 2*spec.size {
+			r := new(big.Int).SetBytes(signature[:spec.size])
+			s := new(big.Int).SetBytes(signature[spec.size:])
+			valid = ecdsa.Verify(key, digest, r, s)
+		}
+	case *rsa.PublicKey:
+		switch {
+		case spec.size > 0:
+		case spec.pss:
+			valid = rsa.VerifyPSS(key, spec.hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		default:
+			valid = rsa.VerifyPKCS1v15(key, spec.hash, digest, signature) == nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+	return &header, nil
+}
+
+// ecdsaDERToJWS converts an ASN.1 DER ECDSA signature to the fixed-size
+// r || s form used by JWS
+func ecdsaDERToJWS(der []byte, size int) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("malformed ECDSA signature from KMS: %w", err)
+	}
+	if sig.R.BitLen() > size*8 || sig.S.BitLen() > size*8 {
+		return nil, fmt.Errorf("ECDSA signature from KMS doesn't match the algorithm's key size")
+	}
+
+	out := make([]byte, 2*size)
+	sig.R.FillBytes(out[:size])
+	sig.S.FillBytes(out[size:])
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS signs digests with a local key the way KMS does
+type fakeKMS struct {
+	key crypto.Signer
+}
+
+func (f *fakeKMS) Sign(ctx context.Context, input *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error) {
+	var signOpts crypto.SignerOpts
+	switch input.SigningAlgorithm {
+	case types.SigningAlgorithmSpecEcdsaSha256:
+		signOpts = crypto.SHA256
+	case types.SigningAlgorithmSpecRsassaPssSha256:
+		signOpts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	}
+	signature, err := f.key.Sign(rand.Reader, input.Message, signOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.SignOutput{Signature: signature, KeyId: input.KeyId}, nil
+}
+
+func (f *fakeKMS) GetPublicKey(ctx context.Context, input *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error) {
+	der, err := x509.MarshalPKIXPublicKey(f.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &kms.GetPublicKeyOutput{PublicKey: der, KeyId: input.KeyId}, nil
+}
+
+func TestKMSSignerDetachedJWS(t *testing.T) {
+	ctx := context.Background()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		algorithm string
+		key       crypto.Signer
+	}{
+		{"ES256", ecKey},
+		{"PS256", rsaKey},
+	} {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			signer, err := NewKMSSigner(&fakeKMS{key: tc.key}, "alias/signing", tc.algorithm)
+			require.NoError(t, err)
+
+			payload := []byte(`{"balance":1250}`)
+			jws, err := SignDetachedJWS(ctx, signer, JWSHeader{Path: "/balances/1", Status: 200}, payload)
+			require.NoError(t, err)
+			assert.Contains(t, jws, "..")
+
+			publicKey, err := signer.PublicKey(ctx)
+			require.NoError(t, err)
+
+			header, err := VerifyDetachedJWS(jws, payload, publicKey)
+			require.NoError(t, err)
+			assert.Equal(t, tc.algorithm, header.Algorithm)
+			assert.Equal(t, "alias/signing", header.KeyID)
+			assert.Equal(t, "/balances/1", header.Path)
+
+			_, err = VerifyDetachedJWS(jws, []byte(`{"balance":9999}`), publicKey)
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+
+	_, err = NewKMSSigner(&fakeKMS{key: ecKey}, "alias/signing", "HS256")
+	assert.Error(t, err)
+	_, err = VerifyDetachedJWS("not-a-jws", nil, ecKey.Public())
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
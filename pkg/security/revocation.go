@@ -0,0 +1,285 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTokenRevoked is returned for tokens revoked by ID, session, user
+	// or tenant
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrRevocationUnavailable is returned when the revocation list can't
+	// be consulted in time
+	ErrRevocationUnavailable = errors.New("revocation list unavailable")
+)
+
+// RevocationList is a token denylist. Each entry is keyed by a token ID,
+// session, user or tenant and holds a cutoff: tokens for that key issued at
+// or before the cutoff are revoked. Entries can be dropped once every token
+// they cover has expired.
+type RevocationList interface {
+	// Cutoffs returns the cutoffs recorded for keys; keys without an entry
+	// are absent from the result
+	Cutoffs(ctx context.Context, keys []string) (map[string]time.Time, error)
+	// Revoke records a cutoff for key, replacing any earlier entry, until
+	// expiresAt
+	Revoke(ctx context.Context, key string, cutoff, expiresAt time.Time) error
+}
+
+// RevocationSubject identifies a token for revocation checks
+type RevocationSubject struct {
+	TokenID   string // jti
+	SessionID string // sid
+	UserID    string
+	TenantID  string
+	// IssuedAt is the token's iat; tokens without one are revoked by any
+	// matching entry
+	IssuedAt time.Time
+}
+
+// keys returns the revocation keys that can cover the token
+func (s RevocationSubject) keys() []string {
+	keys := make([]string, 0, 4)
+	if s.TokenID != "" {
+		keys = append(keys, "token#"+s.TokenID)
+	}
+	if s.SessionID != "" {
+		keys = append(keys, "session#"+s.SessionID)
+	}
+	if s.UserID != "" {
+		keys = append(keys, "user#"+s.TenantID+"#"+s.UserID)
+	}
+	if s.TenantID != "" {
+		keys = append(keys, "tenant#"+s.TenantID)
+	}
+	return keys
+}
+
+// RevocationConfig configures a Revocations checker
+type RevocationConfig struct {
+	// List holds the revocation entries (required)
+	List RevocationList
+	// MaxTokenLifetime is the longest lifetime of any accepted token; user
+	// and session entries are kept this long (default: 24 hours)
+	MaxTokenLifetime time.Duration
+	// Timeout bounds each lookup so a slow list can't stall requests
+	// (default: 100ms)
+	Timeout time.Duration
+	// FailOpen accepts tokens when the list can't be consulted in time.
+	// By default they are rejected with ErrRevocationUnavailable.
+	FailOpen bool
+	// OnLookupError is called with lookup failures, e.g. to count them,
+	// whether or not the checker fails open
+	OnLookupError func(err error)
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Revocations revokes tokens and checks them against a RevocationList.
+// Set it as JWTConfig.Revocation and logout, credential compromise and
+// tenant suspension take effect on the next request rather than when the
+// tokens expire:
+//
+//	revocations, _ := security.NewRevocations(security.RevocationConfig{
+//		List: security.NewDynamoDBRevocationList(client, "revocations"),
+//	})
+//	app.Use(middleware.JWT(security.JWTConfig{..., Revocation: revocations}))
+//
+//	// Logout
+//	revocations.RevokeSession(ctx, principal.SessionID)
+type Revocations struct {
+	config RevocationConfig
+}
+
+// NewRevocations creates a revocation checker
+func NewRevocations(config RevocationConfig) (*Revocations, error) {
+	if config.List == nil {
+		return nil, fmt.Errorf("revocations require a revocation list")
+	}
+	if config.MaxTokenLifetime == 0 {
+		config.MaxTokenLifetime = 24 * time.Hour
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 100 * time.Millisecond
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Revocations{config: config}, nil
+}
+
+// Check returns ErrTokenRevoked if any entry covers the token, or
+// ErrRevocationUnavailable if the list didn't answer within the timeout
+// and the checker doesn't fail open
+func (r *Revocations) Check(ctx context.Context, subject RevocationSubject) error {
+	keys := subject.keys()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	cutoffs, err := r.config.List.Cutoffs(lookupCtx, keys)
+	if err != nil {
+		if r.config.OnLookupError != nil {
+			r.config.OnLookupError(err)
+		}
+		if r.config.FailOpen {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrRevocationUnavailable, err)
+	}
+
+	for _, key := range keys {
+		if cutoff, ok := cutoffs[key]; ok && !subject.IssuedAt.After(cutoff) {
+			return ErrTokenRevoked
+		}
+	}
+	return nil
+}
+
+// RevokeToken revokes a single token by its jti. Pass the token's expiry so
+// the entry lasts as long as the token could be used.
+func (r *Revocations) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	return r.config.List.Revoke(ctx, "token#"+tokenID, expiresAt, expiresAt)
+}
+
+// RevokeSession revokes every token issued so far for a session, as on
+// logout
+func (r *Revocations) RevokeSession(ctx context.Context, sessionID string) error {
+	now := r.config.Now()
+	return r.config.List.Revoke(ctx, "session#"+sessionID, now, now.Add(r.config.MaxTokenLifetime))
+}
+
+// RevokeUser revokes every token issued so far to a user, as when their
+// credentials are compromised or they log out everywhere. Tokens issued
+// afterwards are accepted.
+func (r *Revocations) RevokeUser(ctx context.Context, tenantID, userID string) error {
+	now := r.config.Now()
+	return r.config.List.Revoke(ctx, "user#"+tenantID+"#"+userID, now, now.Add(r.config.MaxTokenLifetime))
+}
+
+// SuspendTenant revokes every token for a tenant, including tokens issued
+// while it is suspended, until ReinstateTenant is called
+func (r *Revocations) SuspendTenant(ctx context.Context, tenantID string) error {
+	// The entry never expires and its cutoff is beyond any token's iat
+	return r.config.List.Revoke(ctx, "tenant#"+tenantID, time.Unix(1<<40, 0), time.Time{})
+}
+
+// ReinstateTenant ends a tenant's suspension. Tokens issued before now stay
+// revoked.
+func (r *Revocations) ReinstateTenant(ctx context.Context, tenantID string) error {
+	now := r.config.Now()
+	return r.config.List.Revoke(ctx, "tenant#"+tenantID, now, now.Add(r.config.MaxTokenLifetime))
+}
+
+// MemoryRevocationList is an in-memory RevocationList for tests and
+// single-instance deployments
+type MemoryRevocationList struct {
+	mu      sync.RWMutex
+	entries map[string]revocationEntry
+	now     func() time.Time
+}
+
+type revocationEntry struct {
+	cutoff    time.Time
+	expiresAt time.Time
+}
+
+// NewMemoryRevocationList creates an empty revocation list
+func NewMemoryRevocationList() *MemoryRevocationList {
+	return &MemoryRevocationList{entries: make(map[string]revocationEntry), now: time.Now}
+}
+
+// Cutoffs implements RevocationList
+func (l *MemoryRevocationList) Cutoffs(ctx context.Context, keys []string) (map[string]time.Time, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := l.now()
+	cutoffs := make(map[string]time.Time)
+	for _, key := range keys {
+		entry, ok := l.entries[key]
+		if ok && (entry.expiresAt.IsZero() || now.Before(entry.expiresAt)) {
+			cutoffs[key] = entry.cutoff
+		}
+	}
+	return cutoffs, nil
+}
+
+// Revoke implements RevocationList
+func (l *MemoryRevocationList) Revoke(ctx context.Context, key string, cutoff, expiresAt time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[key] = revocationEntry{cutoff: cutoff, expiresAt: expiresAt}
+	return nil
+}
+
+// RevocationCache is the subset of a cache client that CacheRevocationList
+// needs. Implement it over an ElastiCache Redis or Memcached client, e.g.
+// with MGET and SET ... PX.
+type RevocationCache interface {
+	// GetMulti returns the values of the keys that exist
+	GetMulti(ctx context.Context, keys []string) (map[string]string, error)
+	// Set stores a value; a ttl of 0 means it doesn't expire
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
+
+// CacheRevocationList stores revocation entries in a shared cache such as
+// ElastiCache, for single-digit-millisecond lookups. Entries expire through
+// the cache's TTL.
+type CacheRevocationList struct {
+	cache  RevocationCache
+	prefix string
+	now    func() time.Time
+}
+
+// NewCacheRevocationList creates a cache-backed revocation list whose keys
+// start with prefix, e.g. "revocation:"
+func NewCacheRevocationList(cache RevocationCache, prefix string) *CacheRevocationList {
+	return &CacheRevocationList{cache: cache, prefix: prefix, now: time.Now}
+}
+
+// Cutoffs implements RevocationList
+func (l *CacheRevocationList) Cutoffs(ctx context.Context, keys []string) (map[string]time.Time, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = l.prefix + key
+	}
+	values, err := l.cache.GetMulti(ctx, prefixed)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoffs := make(map[string]time.Time, len(values))
+	for _, key := range keys {
+		value, ok := values[l.prefix+key]
+		if !ok {
+			continue
+		}
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid revocation entry %s: %w", key, err)
+		}
+		cutoffs[key] = time.UnixMilli(millis)
+	}
+	return cutoffs, nil
+}
+
+// Revoke implements RevocationList
+func (l *CacheRevocationList) Revoke(ctx context.Context, key string, cutoff, expiresAt time.Time) error {
+	var ttl time.Duration
+	if !expiresAt.IsZero() {
+		ttl = expiresAt.Sub(l.now())
+		if ttl <= 0 {
+			return nil
+		}
+	}
+	return l.cache.Set(ctx, l.prefix+key, strconv.FormatInt(cutoff.UnixMilli(), 10), ttl)
+}
@@ -0,0 +1,93 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBRevocationList implements RevocationList using a DynamoDB table
+// with a string partition key "pk". Cutoffs are stored in the "cutoff"
+// attribute as Unix milliseconds, and entries expire through the "ttl"
+// attribute.
+type DynamoDBRevocationList struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBRevocationList creates a DynamoDB-backed revocation list
+func NewDynamoDBRevocationList(client *dynamodb.Client, tableName string) *DynamoDBRevocationList {
+	return &DynamoDBRevocationList{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// Cutoffs implements RevocationList with one strongly consistent batch
+// read, so a revocation is seen by the next request
+func (d *DynamoDBRevocationList) Cutoffs(ctx context.Context, keys []string) (map[string]time.Time, error) {
+	requestKeys := make([]map[string]types.AttributeValue, len(keys))
+	for i, key := range keys {
+		requestKeys[i] = map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+		}
+	}
+
+	cutoffs := make(map[string]time.Time)
+	request := map[string]types.KeysAndAttributes{
+		d.tableName: {Keys: requestKeys, ConsistentRead: aws.Bool(true)},
+	}
+	now := time.Now().Unix()
+	for len(request) > 0 {
+		result, err := d.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Responses[d.tableName] {
+			// DynamoDB TTL deletion is lazy, so expired items may still be returned
+			if ttl, _ := revocationNumber(item["ttl"]); ttl > 0 && now > ttl {
+				continue
+			}
+			key, _ := item["pk"].(*types.AttributeValueMemberS)
+			cutoff, err := revocationNumber(item["cutoff"])
+			if key == nil || err != nil {
+				return nil, fmt.Errorf("invalid revocation entry")
+			}
+			cutoffs[key.Value] = time.UnixMilli(cutoff)
+		}
+		request = result.UnprocessedKeys
+	}
+	return cutoffs, nil
+}
+
+// Revoke implements RevocationList
+func (d *DynamoDBRevocationList) Revoke(ctx context.Context, key string, cutoff, expiresAt time.Time) error {
+	item := map[string]types.AttributeValue{
+		"pk":     &types.AttributeValueMemberS{Value: key},
+		"cutoff": &types.AttributeValueMemberN{Value: strconv.FormatInt(cutoff.UnixMilli(), 10)},
+	}
+	if !expiresAt.IsZero() {
+		item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)}
+	}
+
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	return err
+}
+
+// revocationNumber reads an integer number attribute, treating a missing
+// attribute as 0
+func revocationNumber(av types.AttributeValue) (int64, error) {
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(n.Value, 10, 64)
+}
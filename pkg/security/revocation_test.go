@@ -0,0 +1,150 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRevocations(t *testing.T, list RevocationList, now *time.Time) *Revocations {
+	revocations, err := NewRevocations(RevocationConfig{
+		List: list,
+		Now:  func() time.Time { return *now },
+	})
+	require.NoError(t, err)
+	return revocations
+}
+
+func TestRevocations(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	revocations := newTestRevocations(t, NewMemoryRevocationList(), &now)
+
+	token := RevocationSubject{TokenID: "jti-1", SessionID: "sess-1", UserID: "user-1", TenantID: "tenant-1", IssuedAt: now.Add(-time.Minute)}
+	require.NoError(t, revocations.Check(ctx, token))
+
+	t.Run("token", func(t *testing.T) {
+		require.NoError(t, revocations.RevokeToken(ctx, "jti-1", now.Add(time.Hour)))
+		assert.ErrorIs(t, revocations.Check(ctx, token), ErrTokenRevoked)
+
+		other := token
+		other.TokenID = "jti-2"
+		assert.NoError(t, revocations.Check(ctx, other))
+	})
+
+	t.Run("session", func(t *testing.T) {
+		subject := RevocationSubject{SessionID: "sess-2", UserID: "user-2", IssuedAt: now.Add(-time.Minute)}
+		require.NoError(t, revocations.RevokeSession(ctx, "sess-2"))
+		assert.ErrorIs(t, revocations.Check(ctx, subject), ErrTokenRevoked)
+	})
+
+	t.Run("user revocation spares later tokens", func(t *testing.T) {
+		before := RevocationSubject{UserID: "user-3", TenantID: "tenant-1", IssuedAt: now.Add(-time.Second)}
+		after := RevocationSubject{UserID: "user-3", TenantID: "tenant-1", IssuedAt: now.Add(time.Second)}
+		sameUserOtherTenant := RevocationSubject{UserID: "user-3", TenantID: "tenant-2", IssuedAt: now.Add(-time.Second)}
+
+		require.NoError(t, revocations.RevokeUser(ctx, "tenant-1", "user-3"))
+		assert.ErrorIs(t, revocations.Check(ctx, before), ErrTokenRevoked)
+		assert.NoError(t, revocations.Check(ctx, after))
+		assert.NoError(t, revocations.Check(ctx, sameUserOtherTenant))
+	})
+
+	t.Run("tenant suspension", func(t *testing.T) {
+		subject := RevocationSubject{UserID: "user-4", TenantID: "tenant-9", IssuedAt: now.Add(time.Hour)}
+		require.NoError(t, revocations.SuspendTenant(ctx, "tenant-9"))
+		assert.ErrorIs(t, revocations.Check(ctx, subject), ErrTokenRevoked, "tokens issued during a suspension are revoked")
+
+		now = now.Add(2 * time.Hour)
+		require.NoError(t, revocations.ReinstateTenant(ctx, "tenant-9"))
+		assert.ErrorIs(t, revocations.Check(ctx, subject), ErrTokenRevoked, "tokens issued before reinstatement stay revoked")
+		subject.IssuedAt = now.Add(time.Second)
+		assert.NoError(t, revocations.Check(ctx, subject))
+	})
+}
+
+type slowRevocationList struct {
+	MemoryRevocationList
+}
+
+func (l *slowRevocationList) Cutoffs(ctx context.Context, keys []string) (map[string]time.Time, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRevocationsBoundLookupLatency(t *testing.T) {
+	ctx := context.Background()
+	subject := RevocationSubject{UserID: "user-1", IssuedAt: time.Now()}
+
+	var lookupErrors int
+	strict, err := NewRevocations(RevocationConfig{
+		List:          &slowRevocationList{},
+		Timeout:       10 * time.Millisecond,
+		OnLookupError: func(error) { lookupErrors++ },
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = strict.Check(ctx, subject)
+	assert.ErrorIs(t, err, ErrRevocationUnavailable)
+	assert.Less(t, time.Since(start), time.Second)
+
+	open, err := NewRevocations(RevocationConfig{
+		List:          &slowRevocationList{},
+		Timeout:       10 * time.Millisecond,
+		FailOpen:      true,
+		OnLookupError: func(error) { lookupErrors++ },
+	})
+	require.NoError(t, err)
+	assert.NoError(t, open.Check(ctx, subject))
+	assert.Equal(t, 2, lookupErrors)
+}
+
+type fakeRevocationCache struct {
+	values map[string]string
+	ttls   map[string]time.Duration
+	err    error
+}
+
+func (c *fakeRevocationCache) GetMulti(ctx context.Context, keys []string) (map[string]string, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	values := make(map[string]string)
+	for _, key := range keys {
+		if value, ok := c.values[key]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (c *fakeRevocationCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.values[key] = value
+	c.ttls[key] = ttl
+	return nil
+}
+
+func TestCacheRevocationList(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cache := &fakeRevocationCache{values: map[string]string{}, ttls: map[string]time.Duration{}}
+	list := NewCacheRevocationList(cache, "revocation:")
+	revocations := newTestRevocations(t, list, &now)
+
+	require.NoError(t, revocations.RevokeSession(ctx, "sess-1"))
+	assert.Contains(t, cache.values, "revocation:session#sess-1")
+	assert.InDelta(t, float64(24*time.Hour), float64(cache.ttls["revocation:session#sess-1"]), float64(time.Minute))
+
+	subject := RevocationSubject{SessionID: "sess-1", IssuedAt: now.Add(-time.Second)}
+	assert.ErrorIs(t, revocations.Check(ctx, subject), ErrTokenRevoked)
+
+	require.NoError(t, revocations.SuspendTenant(ctx, "tenant-1"))
+	assert.Equal(t, time.Duration(0), cache.ttls["revocation:tenant#tenant-1"], "suspensions don't expire")
+
+	cache.err = errors.New("connection refused")
+	assert.ErrorIs(t, revocations.Check(ctx, subject), ErrRevocationUnavailable)
+}
@@ -520,6 +520,27 @@ func (mrs *MLRiskScorer) extractBehavioralFeatures(ctx context.Context, event *A
 	userAgentRisk := mrs.getUserAgentRisk(event.UserAgent)
 	features = append(features, userAgentRisk)
 
+	// Device risk is always a feature so later features keep their
+	// positions; it is 0 when the client reported no metadata
+	deviceRisk := 0.0
+	if event.Client != (ClientContext{}) {
+		deviceRisk = mrs.getDeviceRisk(event.Client)
+	}
+	features = append(features, deviceRisk)
+
+	if deviceRisk > 0.5 {
+		factors = append(factors, RiskFactor{
+			ID:          "unidentified_device",
+			Name:        "Unidentified Device",
+			Category:    "behavioral",
+			Weight:      0.3,
+			Value:       deviceRisk,
+			Impact:      deviceRisk * 0.3,
+			Description: fmt.Sprintf("Request from %s client without a device ID", event.Client.Platform),
+			Mitigation:  "Require device registration for sensitive operations",
+		})
+	}
+
 	return features, factors
 }
 
@@ -653,6 +674,14 @@ func (mrs *MLRiskScorer) getUserAgentRisk(_ string) float64 {
 	return 0.2
 }
 
+func (mrs *MLRiskScorer) getDeviceRisk(client ClientContext) float64 {
+	// Clients that identify themselves but withhold a device ID are harder to track
+	if client.DeviceID == "" {
+		return 0.6
+	}
+	return 0.1
+}
+
 func (mrs *MLRiskScorer) getDataSensitivityRisk(dataAccessed []string) float64 {
 	if len(dataAccessed) == 0 {
 		return 0.1
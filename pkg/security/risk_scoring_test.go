@@ -0,0 +1,33 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceRiskFeatureKeepsFeaturePositions(t *testing.T) {
+	ctx := context.Background()
+	scorer := NewMLRiskScorer(RiskScoringConfig{Enabled: true})
+	event := &AuditEvent{Timestamp: time.Now(), Action: "read", IPAddress: "10.0.0.1"}
+
+	anonymous, _ := scorer.extractBehavioralFeatures(ctx, event)
+	anonymousAll, _, err := scorer.extractFeatures(ctx, event)
+	require.NoError(t, err)
+
+	event.Client = ClientContext{Platform: "ios", AppVersion: "5.2.0"}
+	identified, factors := scorer.extractBehavioralFeatures(ctx, event)
+	identifiedAll, _, err := scorer.extractFeatures(ctx, event)
+	require.NoError(t, err)
+
+	// Unknown clients score 0 in the same slot instead of dropping it
+	require.Len(t, identified, len(anonymous))
+	require.Len(t, identifiedAll, len(anonymousAll))
+	assert.Equal(t, 0.0, anonymous[len(anonymous)-1])
+	assert.Equal(t, 0.6, identified[len(identified)-1])
+	require.NotEmpty(t, factors)
+	assert.Equal(t, "unidentified_device", factors[len(factors)-1].ID)
+}
@@ -0,0 +1,278 @@
+package security
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SSO protocols an IdentityProvider speaks
+const (
+	SSOProtocolOIDC = "oidc"
+	SSOProtocolSAML = "saml"
+)
+
+var (
+	// ErrUnknownIssuer is returned for tokens and assertions from an issuer
+	// no tenant has registered
+	ErrUnknownIssuer = errors.New("unknown identity provider")
+	// ErrInvalidSSOToken is returned when an ID token or assertion fails
+	// validation; the wrapped message says why
+	ErrInvalidSSOToken = errors.New("invalid SSO credential")
+)
+
+// SSOAttributeMapping names the claims (OIDC) or attributes (SAML) an
+// identity provider uses for profile fields. Empty names use the defaults.
+type SSOAttributeMapping struct {
+	// Email defaults to "email" for OIDC and the SAML email attribute
+	// (http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress)
+	Email string
+	// Name defaults to "name" for OIDC and the SAML name attribute
+	Name string
+	// Groups defaults to "groups"
+	Groups string
+}
+
+// IdentityProvider is a tenant's enterprise IdP registration
+type IdentityProvider struct {
+	// TenantID is the tenant every identity from this IdP belongs to
+	TenantID string
+	// Protocol is SSOProtocolOIDC or SSOProtocolSAML
+	Protocol string
+	// Issuer is the OIDC issuer URL or the SAML IdP entity ID
+	Issuer string
+
+	// ClientID is our OIDC client ID, the required ID token audience
+	ClientID string
+	// JWKSURL serves the IdP's OIDC signing keys; keys are refetched when
+	// a token names one not yet seen, so IdP key rotation needs no change
+	JWKSURL string
+
+	// Audience is our SAML service provider entity ID
+	Audience string
+	// ACSURL, if set, must be the assertion's subject confirmation
+	// recipient
+	ACSURL string
+	// Certificates are the PEM signing certificates the IdP may use. List
+	// the old and new certificates together while the IdP rotates.
+	Certificates []string
+
+	// Attributes maps IdP claim names to profile fields
+	Attributes SSOAttributeMapping
+	// RoleMap maps IdP groups to roles; groups not listed grant nothing
+	RoleMap map[string][]string
+	// DefaultRoles are granted to every identity from this IdP
+	DefaultRoles []string
+
+	certs []*x509.Certificate
+}
+
+// IdentityProviderRegistry finds the IdP registration for an issuer
+type IdentityProviderRegistry interface {
+	// IdentityProvider returns ErrUnknownIssuer for unregistered issuers
+	IdentityProvider(ctx context.Context, protocol, issuer string) (*IdentityProvider, error)
+}
+
+// StaticIdentityProviders is an in-memory IdentityProviderRegistry
+type StaticIdentityProviders struct {
+	mu        sync.RWMutex
+	providers map[string]*IdentityProvider
+}
+
+// NewStaticIdentityProviders creates a registry of the given IdPs
+func NewStaticIdentityProviders(providers ...*IdentityProvider) (*StaticIdentityProviders, error) {
+	registry := &StaticIdentityProviders{providers: make(map[string]*IdentityProvider)}
+	for _, idp := range providers {
+		if err := registry.Register(idp); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register adds or replaces an IdP registration
+func (r *StaticIdentityProviders) Register(idp *IdentityProvider) error {
+	if err := idp.validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[idp.Protocol+"|"+idp.Issuer] = idp
+	return nil
+}
+
+// IdentityProvider returns the registration for the issuer
+func (r *StaticIdentityProviders) IdentityProvider(ctx context.Context, protocol, issuer string) (*IdentityProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	idp, ok := r.providers[protocol+"|"+issuer]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIssuer, issuer)
+	}
+	return idp, nil
+}
+
+// validate checks the registration and parses its certificates
+func (idp *IdentityProvider) validate() error {
+	if idp.TenantID == "" || idp.Issuer == "" {
+		return fmt.Errorf("identity provider requires a tenant ID and issuer")
+	}
+	switch idp.Protocol {
+	case SSOProtocolOIDC:
+		if idp.ClientID == "" || idp.JWKSURL == "" {
+			return fmt.Errorf("OIDC identity provider %s requires a client ID and JWKS URL", idp.Issuer)
+		}
+	case SSOProtocolSAML:
+		if idp.Audience == "" || len(idp.Certificates) == 0 {
+			return fmt.Errorf("SAML identity provider %s requires an audience and certificates", idp.Issuer)
+		}
+		certs, err := idp.signingCertificates()
+		if err != nil {
+			return err
+		}
+		idp.certs = certs
+	default:
+		return fmt.Errorf("unsupported SSO protocol: %q", idp.Protocol)
+	}
+	return nil
+}
+
+// signingCertificates returns the IdP's parsed certificates. Registries
+// that validate registrations parse them once.
+func (idp *IdentityProvider) signingCertificates() ([]*x509.Certificate, error) {
+	if idp.certs != nil {
+		return idp.certs, nil
+	}
+	certs := make([]*x509.Certificate, 0, len(idp.Certificates))
+	for _, data := range idp.Certificates {
+		block, _ := pem.Decode([]byte(data))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("identity provider %s has an invalid PEM certificate", idp.Issuer)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("identity provider %s has an invalid certificate: %w", idp.Issuer, err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// SSOIdentity is an identity asserted by a tenant's IdP
+type SSOIdentity struct {
+	Protocol  string
+	Issuer    string
+	TenantID  string
+	Subject   string
+	Email     string
+	Name      string
+	Groups    []string
+	Roles     []string
+	SessionID string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// Attributes holds every claim or attribute the IdP sent
+	Attributes map[string][]string
+}
+
+// Claims maps the identity into the standard claims model used by
+// lift.Context.SetClaims, so SSO users look like any other authenticated
+// user to handlers and authorization middleware
+func (i *SSOIdentity) Claims() map[string]any {
+	claims := map[string]any{
+		"iss":         i.Issuer,
+		"sub":         i.Subject,
+		"user_id":     i.Subject,
+		"tenant_id":   i.TenantID,
+		"roles":       i.Roles,
+		"auth_method": i.Protocol,
+	}
+	if i.Email != "" {
+		claims["email"] = i.Email
+	}
+	if i.Name != "" {
+		claims["name"] = i.Name
+	}
+	if len(i.Groups) > 0 {
+		claims["groups"] = i.Groups
+	}
+	if i.SessionID != "" {
+		claims["sid"] = i.SessionID
+	}
+	if !i.IssuedAt.IsZero() {
+		claims["iat"] = i.IssuedAt.Unix()
+	}
+	if !i.ExpiresAt.IsZero() {
+		claims["exp"] = i.ExpiresAt.Unix()
+	}
+	return claims
+}
+
+// Principal converts the identity to a Principal
+func (i *SSOIdentity) Principal() *Principal {
+	return &Principal{
+		UserID:     i.Subject,
+		TenantID:   i.TenantID,
+		Roles:      i.Roles,
+		AuthMethod: i.Protocol,
+		IssuedAt:   i.IssuedAt,
+		ExpiresAt:  i.ExpiresAt,
+		SessionID:  i.SessionID,
+	}
+}
+
+// identity builds an SSOIdentity from the IdP's claims or attributes,
+// mapping groups to roles
+func (idp *IdentityProvider) identity(subject string, attributes map[string][]string, emailDefault, nameDefault string) *SSOIdentity {
+	mapping := idp.Attributes
+	if mapping.Email == "" {
+		mapping.Email = emailDefault
+	}
+	if mapping.Name == "" {
+		mapping.Name = nameDefault
+	}
+	if mapping.Groups == "" {
+		mapping.Groups = "groups"
+	}
+
+	identity := &SSOIdentity{
+		Protocol:   idp.Protocol,
+		Issuer:     idp.Issuer,
+		TenantID:   idp.TenantID,
+		Subject:    subject,
+		Groups:     attributes[mapping.Groups],
+		Attributes: attributes,
+	}
+	if values := attributes[mapping.Email]; len(values) > 0 {
+		identity.Email = values[0]
+	}
+	if values := attributes[mapping.Name]; len(values) > 0 {
+		identity.Name = values[0]
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range idp.DefaultRoles {
+		roles[role] = true
+	}
+	for _, group := range identity.Groups {
+		for _, role := range idp.RoleMap[group] {
+			roles[role] = true
+		}
+	}
+	identity.Roles = make([]string, 0, len(roles))
+	for role := range roles {
+		identity.Roles = append(identity.Roles, role)
+	}
+	sort.Strings(identity.Roles)
+	return identity
+}
+
+// ssoError wraps a validation failure in ErrInvalidSSOToken
+func ssoError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSSOToken, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,159 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCValidatorConfig configures an OIDCValidator
+type OIDCValidatorConfig struct {
+	// Registry finds the IdP registration for a token's issuer (required)
+	Registry IdentityProviderRegistry
+	// HTTPClient fetches JWKS documents (default: a client with a 10s timeout)
+	HTTPClient *http.Client
+	// KeyCacheTTL is how long fetched signing keys are trusted before
+	// they're refetched (default: 1 hour)
+	KeyCacheTTL time.Duration
+	// MinRefreshInterval limits refetches for tokens naming unknown keys,
+	// so forged key IDs can't be used to hammer the IdP (default: 1 minute)
+	MinRefreshInterval time.Duration
+	// Leeway tolerates clock skew in exp, nbf and iat (default: 1 minute)
+	Leeway time.Duration
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// OIDCValidator validates OIDC ID tokens from tenants' enterprise IdPs
+type OIDCValidator struct {
+	config OIDCValidatorConfig
+
+	mu   sync.Mutex
+	jwks map[string]*JWKS
+}
+
+// NewOIDCValidator creates an ID token validator
+func NewOIDCValidator(config OIDCValidatorConfig) (*OIDCValidator, error) {
+	if config.Registry == nil {
+		return nil, fmt.Errorf("OIDC validator requires an identity provider registry")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.KeyCacheTTL == 0 {
+		config.KeyCacheTTL = time.Hour
+	}
+	if config.MinRefreshInterval == 0 {
+		config.MinRefreshInterval = time.Minute
+	}
+	if config.Leeway == 0 {
+		config.Leeway = time.Minute
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &OIDCValidator{config: config, jwks: make(map[string]*JWKS)}, nil
+}
+
+// Validate verifies an ID token's signature, issuer, audience and
+// lifetime, and the nonce sent with the authentication request if nonce
+// isn't empty
+func (v *OIDCValidator) Validate(ctx context.Context, idToken, nonce string) (*SSOIdentity, error) {
+	unverified, _, err := jwt.NewParser().ParseUnverified(idToken, jwt.MapClaims{})
+	if err != nil {
+		return nil, ssoError("malformed ID token: %v", err)
+	}
+	issuer, _ := unverified.Claims.GetIssuer()
+	idp, err := v.config.Registry.IdentityProvider(ctx, SSOProtocolOIDC, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(idp.Issuer),
+		jwt.WithAudience(idp.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.config.Leeway),
+		jwt.WithTimeFunc(v.config.Now),
+	)
+	claims := jwt.MapClaims{}
+	_, err = parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keySet(idp.JWKSURL).Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, ssoError("%v", err)
+	}
+
+	// A token for several audiences must name us as the authorized party
+	audience, _ := claims.GetAudience()
+	if azp, ok := claims["azp"].(string); (ok || len(audience) > 1) && azp != idp.ClientID {
+		return nil, ssoError("token is not authorized for client %s", idp.ClientID)
+	}
+	if nonce != "" {
+		if claimed, _ := claims["nonce"].(string); claimed != nonce {
+			return nil, ssoError("nonce mismatch")
+		}
+	}
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, ssoError("token has no subject")
+	}
+
+	identity := idp.identity(subject, claimAttributes(claims), "email", "name")
+	if sid, ok := claims["sid"].(string); ok {
+		identity.SessionID = sid
+	}
+	if iat, _ := claims.GetIssuedAt(); iat != nil {
+		identity.IssuedAt = iat.Time
+	}
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		identity.ExpiresAt = exp.Time
+	}
+	return identity, nil
+}
+
+// keySet returns the cached key set published at jwksURL
+func (v *OIDCValidator) keySet(jwksURL string) *JWKS {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	jwks, ok := v.jwks[jwksURL]
+	if !ok {
+		jwks, _ = NewJWKS(JWKSConfig{
+			URL:                jwksURL,
+			HTTPClient:         v.config.HTTPClient,
+			CacheTTL:           v.config.KeyCacheTTL,
+			MinRefreshInterval: v.config.MinRefreshInterval,
+			Now:                v.config.Now,
+		})
+		v.jwks[jwksURL] = jwks
+	}
+	return jwks
+}
+
+// claimAttributes flattens string and string-list claims into attributes
+func claimAttributes(claims jwt.MapClaims) map[string][]string {
+	attributes := make(map[string][]string, len(claims))
+	for name, value := range claims {
+		switch v := value.(type) {
+		case string:
+			attributes[name] = []string{v}
+		case []any:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+			attributes[name] = values
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,305 @@
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+)
+
+// SAML 2.0 identifiers
+const (
+	samlProtocolNS    = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlAssertionNS   = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlStatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlBearer        = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+	samlEmailAttribute = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"
+	samlNameAttribute  = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name"
+)
+
+// SAMLValidatorConfig configures a SAMLValidator
+type SAMLValidatorConfig struct {
+	// Registry finds the IdP registration for an assertion's issuer
+	// (required)
+	Registry IdentityProviderRegistry
+	// Leeway tolerates clock skew in assertion time limits
+	// (default: 1 minute)
+	Leeway time.Duration
+	// MaxResponseSize bounds the decoded response (default: 256KB)
+	MaxResponseSize int
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+	// ReplayStore records accepted assertions (default: an in-memory
+	// store, which only sees the assertions its own container accepted;
+	// use DynamoDBSAMLReplayStore to reject replays across containers)
+	ReplayStore SAMLReplayStore
+}
+
+// SAMLValidator validates SAML 2.0 responses posted to an assertion
+// consumer service by tenants' enterprise IdPs. Signatures are verified
+// with goxmldsig: assertions must be signed with SHA-2 by a registered,
+// currently valid certificate, either directly or through the enclosing
+// response. Signatures must carry their certificate in KeyInfo when the IdP
+// has more than one. Encrypted assertions aren't supported.
+type SAMLValidator struct {
+	config SAMLValidatorConfig
+}
+
+// NewSAMLValidator creates a SAML response validator
+func NewSAMLValidator(config SAMLValidatorConfig) (*SAMLValidator, error) {
+	if config.Registry == nil {
+		return nil, fmt.Errorf("SAML validator requires an identity provider registry")
+	}
+	if config.Leeway == 0 {
+		config.Leeway = time.Minute
+	}
+	if config.MaxResponseSize == 0 {
+		config.MaxResponseSize = 256 * 1024
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if config.ReplayStore == nil {
+		config.ReplayStore = newMemorySAMLReplayStore(config.Now)
+	}
+	return &SAMLValidator{config: config}, nil
+}
+
+// Validate verifies a base64 SAMLResponse form value and returns the
+// asserted identity. requestID is the ID of the AuthnRequest that started
+// the login; pass "" to accept IdP-initiated logins.
+func (v *SAMLValidator) Validate(ctx context.Context, samlResponse, requestID string) (*SSOIdentity, error) {
+	if base64.StdEncoding.DecodedLen(len(samlResponse)) > v.config.MaxResponseSize {
+		return nil, ssoError("SAML response is too large")
+	}
+	data, err := decodeXMLBase64(samlResponse)
+	if err != nil {
+		return nil, ssoError("SAML response is not base64: %v", err)
+	}
+	doc, err := parseSAMLXML(data)
+	if err != nil {
+		return nil, ssoError("%v", err)
+	}
+	root := doc.Root()
+	if !samlIs(root, samlProtocolNS, "Response") {
+		return nil, ssoError("not a SAML response")
+	}
+
+	if status := samlChild(root, samlProtocolNS, "Status"); status != nil {
+		if code := samlChild(status, samlProtocolNS, "StatusCode"); code == nil || samlAttr(code, "Value") != samlStatusSuccess {
+			value := ""
+			if code != nil {
+				value = samlAttr(code, "Value")
+			}
+			return nil, ssoError("IdP returned status %q", value)
+		}
+	}
+	if samlChild(root, samlAssertionNS, "EncryptedAssertion") != nil {
+		return nil, ssoError("encrypted assertions are not supported")
+	}
+	assertions := samlChildren(root, samlAssertionNS, "Assertion")
+	if len(assertions) != 1 {
+		return nil, ssoError("SAML response must contain exactly one assertion")
+	}
+	assertion := assertions[0]
+	if samlAttr(assertion, "ID") == "" {
+		return nil, ssoError("assertion has no ID")
+	}
+
+	issuer := ""
+	if el := samlChild(assertion, samlAssertionNS, "Issuer"); el != nil {
+		issuer = samlText(el)
+	}
+	if el := samlChild(root, samlAssertionNS, "Issuer"); el != nil && samlText(el) != issuer {
+		return nil, ssoError("response and assertion issuers differ")
+	}
+	idp, err := v.config.Registry.IdentityProvider(ctx, SSOProtocolSAML, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything below reads the signed content only
+	now := v.config.Now()
+	if root, assertion, err = v.verifySignatures(root, assertion, idp, now); err != nil {
+		return nil, err
+	}
+	if el := samlChild(assertion, samlAssertionNS, "Issuer"); el == nil || samlText(el) != issuer {
+		return nil, ssoError("signed assertion is not from %s", issuer)
+	}
+
+	if requestID != "" && samlAttr(root, "InResponseTo") != requestID {
+		return nil, ssoError("response is not for request %s", requestID)
+	}
+	if destination := samlAttr(root, "Destination"); idp.ACSURL != "" && destination != "" && destination != idp.ACSURL {
+		return nil, ssoError("response destination %s is not %s", destination, idp.ACSURL)
+	}
+
+	expiresAt, err := v.checkConditions(assertion, idp, now)
+	if err != nil {
+		return nil, err
+	}
+	subject, confirmationExpiry, err := v.checkSubject(assertion, idp, requestID, now)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.IsZero() || confirmationExpiry.Before(expiresAt) {
+		expiresAt = confirmationExpiry
+	}
+
+	identity := idp.identity(subject, samlAttributes(assertion), samlEmailAttribute, samlNameAttribute)
+	identity.IssuedAt, _ = time.Parse(time.RFC3339Nano, samlAttr(assertion, "IssueInstant"))
+	identity.ExpiresAt = expiresAt
+	if authn := samlChild(assertion, samlAssertionNS, "AuthnStatement"); authn != nil {
+		identity.SessionID = samlAttr(authn, "SessionIndex")
+		if instant, err := time.Parse(time.RFC3339Nano, samlAttr(authn, "AuthnInstant")); err == nil {
+			identity.IssuedAt = instant
+		}
+		if sessionEnd, err := time.Parse(time.RFC3339Nano, samlAttr(authn, "SessionNotOnOrAfter")); err == nil {
+			identity.ExpiresAt = sessionEnd
+		}
+	}
+
+	fresh, err := v.config.ReplayStore.MarkUsed(ctx, issuer+"|"+samlAttr(assertion, "ID"), expiresAt.Add(v.config.Leeway))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record SAML assertion: %w", err)
+	}
+	if !fresh {
+		return nil, ssoError("assertion has already been used")
+	}
+	return identity, nil
+}
+
+// verifySignatures requires a valid signature on the assertion or on the
+// response enclosing it, verifies both when both are signed, and returns
+// the signed response and assertion
+func (v *SAMLValidator) verifySignatures(root, assertion *etree.Element, idp *IdentityProvider, now time.Time) (*etree.Element, *etree.Element, error) {
+	certs, err := idp.signingCertificates()
+	if err != nil {
+		return nil, nil, err
+	}
+	idCounts := samlIDCounts(root)
+
+	signed := false
+	response, err := verifyEnvelopedSignature(root, certs, idCounts, now)
+	switch {
+	case err == nil:
+		signed = true
+		assertions := samlChildren(response, samlAssertionNS, "Assertion")
+		if len(assertions) != 1 {
+			return nil, nil, ssoError("SAML response must contain exactly one assertion")
+		}
+		assertion = assertions[0]
+	case err == errUnsigned:
+		response = root
+	default:
+		return nil, nil, ssoError("response signature: %v", err)
+	}
+
+	verified, err := verifyEnvelopedSignature(assertion, certs, idCounts, now)
+	switch {
+	case err == nil:
+		assertion = verified
+	case err == errUnsigned:
+		if !signed {
+			return nil, nil, ssoError("assertion is not signed")
+		}
+	default:
+		return nil, nil, ssoError("assertion signature: %v", err)
+	}
+	return response, assertion, nil
+}
+
+// checkConditions enforces the assertion's validity window and audience,
+// returning when it stops being valid
+func (v *SAMLValidator) checkConditions(assertion *etree.Element, idp *IdentityProvider, now time.Time) (time.Time, error) {
+	conditions := samlChild(assertion, samlAssertionNS, "Conditions")
+	if conditions == nil {
+		return time.Time{}, ssoError("assertion has no conditions")
+	}
+	if value := samlAttr(conditions, "NotBefore"); value != "" {
+		notBefore, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil || now.Add(v.config.Leeway).Before(notBefore) {
+			return time.Time{}, ssoError("assertion is not yet valid")
+		}
+	}
+	var notOnOrAfter time.Time
+	if value := samlAttr(conditions, "NotOnOrAfter"); value != "" {
+		var err error
+		notOnOrAfter, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil || !now.Add(-v.config.Leeway).Before(notOnOrAfter) {
+			return time.Time{}, ssoError("assertion has expired")
+		}
+	}
+
+	// Every audience restriction must name us
+	restrictions := samlChildren(conditions, samlAssertionNS, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return time.Time{}, ssoError("assertion has no audience restriction")
+	}
+	for _, restriction := range restrictions {
+		allowed := false
+		for _, audience := range samlChildren(restriction, samlAssertionNS, "Audience") {
+			if samlText(audience) == idp.Audience {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return time.Time{}, ssoError("assertion is not for audience %s", idp.Audience)
+		}
+	}
+	return notOnOrAfter, nil
+}
+
+// checkSubject returns the subject's name ID after finding a bearer
+// confirmation that is current, addressed to our ACS and, for SP-initiated
+// logins, answers our request
+func (v *SAMLValidator) checkSubject(assertion *etree.Element, idp *IdentityProvider, requestID string, now time.Time) (string, time.Time, error) {
+	subject := samlChild(assertion, samlAssertionNS, "Subject")
+	if subject == nil {
+		return "", time.Time{}, ssoError("assertion has no subject")
+	}
+	nameID := samlChild(subject, samlAssertionNS, "NameID")
+	if nameID == nil || samlText(nameID) == "" {
+		return "", time.Time{}, ssoError("assertion has no name ID")
+	}
+
+	for _, confirmation := range samlChildren(subject, samlAssertionNS, "SubjectConfirmation") {
+		if samlAttr(confirmation, "Method") != samlBearer {
+			continue
+		}
+		data := samlChild(confirmation, samlAssertionNS, "SubjectConfirmationData")
+		if data == nil {
+			continue
+		}
+		expiry, err := time.Parse(time.RFC3339Nano, samlAttr(data, "NotOnOrAfter"))
+		if err != nil || !now.Add(-v.config.Leeway).Before(expiry) {
+			continue
+		}
+		if idp.ACSURL != "" && samlAttr(data, "Recipient") != idp.ACSURL {
+			continue
+		}
+		if requestID != "" && samlAttr(data, "InResponseTo") != requestID {
+			continue
+		}
+		return samlText(nameID), expiry, nil
+	}
+	return "", time.Time{}, ssoError("assertion has no valid bearer subject confirmation")
+}
+
+// samlAttributes collects the assertion's attribute values by name
+func samlAttributes(assertion *etree.Element) map[string][]string {
+	attributes := make(map[string][]string)
+	for _, statement := range samlChildren(assertion, samlAssertionNS, "AttributeStatement") {
+		for _, attribute := range samlChildren(statement, samlAssertionNS, "Attribute") {
+			name := samlAttr(attribute, "Name")
+			for _, value := range samlChildren(attribute, samlAssertionNS, "AttributeValue") {
+				attributes[name] = append(attributes[name], strings.TrimSpace(samlText(value)))
+			}
+		}
+	}
+	return attributes
+}
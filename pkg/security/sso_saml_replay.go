@@ -0,0 +1,53 @@
+package security
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SAMLReplayStore records the assertions a SAMLValidator has accepted, so a
+// captured response can't be replayed. Entries only need to live until the
+// assertion expires.
+type SAMLReplayStore interface {
+	// MarkUsed records key until expiresAt, returning false if it was
+	// already recorded and hasn't expired
+	MarkUsed(ctx context.Context, key string, expiresAt time.Time) (bool, error)
+}
+
+// MemorySAMLReplayStore is an in-memory SAMLReplayStore. Each Lambda
+// container keeps its own entries, so a response replayed to another
+// container is accepted; use DynamoDBSAMLReplayStore when the ACS runs on
+// more than one instance.
+type MemorySAMLReplayStore struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+// NewMemorySAMLReplayStore creates an empty replay store
+func NewMemorySAMLReplayStore() *MemorySAMLReplayStore {
+	return newMemorySAMLReplayStore(time.Now)
+}
+
+func newMemorySAMLReplayStore(now func() time.Time) *MemorySAMLReplayStore {
+	return &MemorySAMLReplayStore{seen: make(map[string]time.Time), now: now}
+}
+
+// MarkUsed implements SAMLReplayStore
+func (s *MemorySAMLReplayStore) MarkUsed(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, expiry := range s.seen {
+		if now.After(expiry) {
+			delete(s.seen, k)
+		}
+	}
+	if _, ok := s.seen[key]; ok {
+		return false, nil
+	}
+	s.seen[key] = expiresAt
+	return true, nil
+}
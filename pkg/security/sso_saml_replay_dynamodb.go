@@ -0,0 +1,55 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBSAMLReplayStore implements SAMLReplayStore using a DynamoDB table
+// with a string partition key "pk", shared by every container. Entries
+// expire through the "ttl" attribute; enable TTL on it so the table doesn't
+// grow.
+type DynamoDBSAMLReplayStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoDBSAMLReplayStore creates a DynamoDB-backed replay store
+func NewDynamoDBSAMLReplayStore(client *dynamodb.Client, tableName string) *DynamoDBSAMLReplayStore {
+	return &DynamoDBSAMLReplayStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// MarkUsed implements SAMLReplayStore with a conditional put, so concurrent
+// replays to different containers can't both succeed
+func (d *DynamoDBSAMLReplayStore) MarkUsed(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]types.AttributeValue{
+			"pk":  &types.AttributeValueMemberS{Value: "saml#" + key},
+			"ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)},
+		},
+		// DynamoDB TTL deletion is lazy, so expired items may still exist
+		ConditionExpression:      aws.String("attribute_not_exists(pk) OR #ttl < :now"),
+		ExpressionAttributeNames: map[string]string{"#ttl": "ttl"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,162 @@
+package security
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+// XML signature algorithms rejected even though goxmldsig supports them
+var weakXMLSignatureAlgorithms = map[string]bool{
+	dsig.RSASHA1SignatureMethod:              true,
+	dsig.ECDSASHA1SignatureMethod:            true,
+	"http://www.w3.org/2000/09/xmldsig#sha1": true,
+}
+
+// parseSAMLXML parses a document, rejecting DTDs. encoding/xml checks
+// well-formedness first, since etree reads raw tokens.
+func parseSAMLXML(data []byte) (*etree.Document, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		if _, ok := token.(xml.Directive); ok {
+			return nil, fmt.Errorf("invalid XML: DTDs are not allowed")
+		}
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("invalid XML: %w", err)
+	}
+	if doc.Root() == nil {
+		return nil, fmt.Errorf("invalid XML: no root element")
+	}
+	return doc, nil
+}
+
+// samlIs reports whether the element has the namespace and local name
+func samlIs(el *etree.Element, space, local string) bool {
+	return el.Tag == local && el.NamespaceURI() == space
+}
+
+// samlChildren returns the direct children with the namespace and name
+func samlChildren(el *etree.Element, space, local string) []*etree.Element {
+	var matches []*etree.Element
+	for _, child := range el.ChildElements() {
+		if samlIs(child, space, local) {
+			matches = append(matches, child)
+		}
+	}
+	return matches
+}
+
+// samlChild returns the first direct child with the namespace and name
+func samlChild(el *etree.Element, space, local string) *etree.Element {
+	if matches := samlChildren(el, space, local); len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
+
+// samlAttr returns an unprefixed attribute's value
+func samlAttr(el *etree.Element, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Space == "" && attr.Key == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// samlText returns the element's character data, trimmed
+func samlText(el *etree.Element) string {
+	return strings.TrimSpace(el.Text())
+}
+
+// samlIDCounts counts the ID attributes in the document, so a signed
+// element's ID can be required to be unique
+func samlIDCounts(root *etree.Element) map[string]int {
+	counts := make(map[string]int)
+	var walk func(*etree.Element)
+	walk = func(el *etree.Element) {
+		for _, attr := range el.Attr {
+			if attr.Space == "" && (attr.Key == "ID" || attr.Key == "Id" || attr.Key == "id") {
+				counts[attr.Value]++
+			}
+		}
+		for _, child := range el.ChildElements() {
+			walk(child)
+		}
+	}
+	walk(root)
+	return counts
+}
+
+// errUnsigned is returned for elements without an enveloped signature
+var errUnsigned = errors.New("element is not signed")
+
+// verifyEnvelopedSignature verifies the element's enveloped XML signature
+// with goxmldsig and returns the signed content. Callers must read only
+// from the returned element: it is exactly what the signature covers, so a
+// signed element moved elsewhere can't vouch for unsigned content.
+func verifyEnvelopedSignature(el *etree.Element, certs []*x509.Certificate, idCounts map[string]int, now time.Time) (*etree.Element, error) {
+	signatures := samlChildren(el, dsig.Namespace, "Signature")
+	if len(signatures) == 0 {
+		return nil, errUnsigned
+	}
+	if len(signatures) > 1 {
+		return nil, fmt.Errorf("element has more than one signature")
+	}
+	if id := samlAttr(el, "ID"); idCounts[id] != 1 {
+		return nil, fmt.Errorf("signed element ID %q is not unique", id)
+	}
+	if signedInfo := samlChild(signatures[0], dsig.Namespace, "SignedInfo"); signedInfo != nil {
+		methods := samlChildren(signedInfo, dsig.Namespace, "SignatureMethod")
+		for _, reference := range samlChildren(signedInfo, dsig.Namespace, "Reference") {
+			methods = append(methods, samlChildren(reference, dsig.Namespace, "DigestMethod")...)
+		}
+		for _, method := range methods {
+			if algorithm := samlAttr(method, "Algorithm"); weakXMLSignatureAlgorithms[algorithm] {
+				return nil, fmt.Errorf("unsupported signature algorithm %q", algorithm)
+			}
+		}
+	}
+
+	// Carry the namespaces declared on ancestors, so the element
+	// canonicalizes the same once detached from the document
+	parentContext, err := etreeutils.NSBuildParentContext(el)
+	if err != nil {
+		return nil, err
+	}
+	detached, err := etreeutils.NSDetatch(parentContext, el)
+	if err != nil {
+		return nil, err
+	}
+
+	validation := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certs})
+	validation.IdAttribute = "ID"
+	validation.Clock = dsig.NewFakeClockAt(now)
+	return validation.Validate(detached)
+}
+
+// decodeXMLBase64 decodes base64 that may be wrapped across lines
+func decodeXMLBase64(value string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+}
@@ -0,0 +1,415 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSAMLXML(t *testing.T) {
+	doc, err := parseSAMLXML([]byte(`<samlp:Response xmlns:samlp="urn:p" xmlns:saml="urn:a" ID="r1">` +
+		`<saml:Assertion ID="a1"><saml:Subject> jo </saml:Subject></saml:Assertion></samlp:Response>`))
+	require.NoError(t, err)
+	assert.True(t, samlIs(doc.Root(), "urn:p", "Response"))
+	assertion := samlChild(doc.Root(), "urn:a", "Assertion")
+	require.NotNil(t, assertion)
+	assert.Equal(t, "a1", samlAttr(assertion, "ID"))
+	assert.Equal(t, "jo", samlText(samlChild(assertion, "urn:a", "Subject")))
+	assert.Equal(t, map[string]int{"r1": 1, "a1": 1}, samlIDCounts(doc.Root()))
+
+	_, err = parseSAMLXML([]byte(`<!DOCTYPE r [<!ENTITY e "x">]><r>&e;</r>`))
+	assert.ErrorContains(t, err, "DTDs are not allowed")
+	_, err = parseSAMLXML([]byte(`<r><a></b></r>`))
+	assert.Error(t, err)
+}
+
+// samlTestKey is an IdP signing key and its certificate
+type samlTestKey struct {
+	key  *rsa.PrivateKey
+	der  []byte
+	cert string
+}
+
+func newSAMLTestKey(t *testing.T, name string) samlTestKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return samlTestKey{key: key, der: der, cert: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+// sign adds an enveloped signature to the element with the ID, the way an
+// IdP would: exclusive canonicalization, RSA SHA-256 and the certificate in
+// KeyInfo
+func (k samlTestKey) sign(t *testing.T, document, id string) string {
+	doc, err := parseSAMLXML([]byte(document))
+	require.NoError(t, err)
+	target := doc.FindElement("//*[@ID='" + id + "']")
+	require.NotNil(t, target)
+
+	parentContext, err := etreeutils.NSBuildParentContext(target)
+	require.NoError(t, err)
+	detached, err := etreeutils.NSDetatch(parentContext, target)
+	require.NoError(t, err)
+	signer, err := dsig.NewSigningContext(k.key, [][]byte{k.der})
+	require.NoError(t, err)
+	signer.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signed, err := signer.SignEnveloped(detached)
+	require.NoError(t, err)
+
+	if parent := target.Parent(); parent != nil {
+		parent.InsertChildAt(target.Index(), signed)
+		parent.RemoveChild(target)
+	} else {
+		doc.SetRoot(signed)
+	}
+	out, err := doc.WriteToString()
+	require.NoError(t, err)
+	return out
+}
+
+const samlTestResponse = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="resp-1" InResponseTo="req-1" Destination="https://api.example.com/sso/acme/acs" IssueInstant="2024-06-01T12:00:00Z" Version="2.0">
+  <saml:Issuer>https://idp.acme.example/saml</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+  <saml:Assertion ID="assertion-1" IssueInstant="2024-06-01T12:00:00Z" Version="2.0">
+    <saml:Issuer>https://idp.acme.example/saml</saml:Issuer>
+    <saml:Subject>
+      <saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">NAME_ID</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData InResponseTo="req-1" NotOnOrAfter="2024-06-01T12:05:00Z" Recipient="https://api.example.com/sso/acme/acs"/>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotBefore="2024-06-01T11:59:00Z" NotOnOrAfter="2024-06-01T12:10:00Z">
+      <saml:AudienceRestriction><saml:Audience>AUDIENCE</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AuthnStatement AuthnInstant="2024-06-01T11:59:30Z" SessionIndex="session-9" SessionNotOnOrAfter="2024-06-01T20:00:00Z"/>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"><saml:AttributeValue>jo@acme.example</saml:AttributeValue></saml:Attribute>
+      <saml:Attribute Name="groups"><saml:AttributeValue>Finance</saml:AttributeValue><saml:AttributeValue>Everyone</saml:AttributeValue></saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>
+</samlp:Response>`
+
+func samlResponse(nameID, audience string) string {
+	return strings.NewReplacer("NAME_ID", nameID, "AUDIENCE", audience).Replace(samlTestResponse)
+}
+
+func encodeSAML(document string) string {
+	return base64.StdEncoding.EncodeToString([]byte(document))
+}
+
+func TestSAMLValidator(t *testing.T) {
+	oldKey, newKey, otherKey := newSAMLTestKey(t, "old"), newSAMLTestKey(t, "new"), newSAMLTestKey(t, "other")
+	registry, err := NewStaticIdentityProviders(&IdentityProvider{
+		TenantID:     "acme",
+		Protocol:     SSOProtocolSAML,
+		Issuer:       "https://idp.acme.example/saml",
+		Audience:     "https://api.example.com/saml",
+		ACSURL:       "https://api.example.com/sso/acme/acs",
+		Certificates: []string{oldKey.cert, newKey.cert},
+		RoleMap:      map[string][]string{"Finance": {"payments:read", "refunds:write"}},
+		DefaultRoles: []string{"member"},
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 1, 0, 0, time.UTC)
+	store := newMemorySAMLReplayStore(func() time.Time { return now })
+	validator, err := NewSAMLValidator(SAMLValidatorConfig{Registry: registry, Now: func() time.Time { return now }, ReplayStore: store})
+	require.NoError(t, err)
+	ctx := context.Background()
+	audience := "https://api.example.com/saml"
+
+	signed := newKey.sign(t, samlResponse("jo@acme.example", audience), "assertion-1")
+	identity, err := validator.Validate(ctx, encodeSAML(signed), "req-1")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", identity.TenantID)
+	assert.Equal(t, "jo@acme.example", identity.Subject)
+	assert.Equal(t, "jo@acme.example", identity.Email)
+	assert.Equal(t, []string{"Finance", "Everyone"}, identity.Groups)
+	assert.Equal(t, []string{"member", "payments:read", "refunds:write"}, identity.Roles)
+	assert.Equal(t, "session-9", identity.SessionID)
+	assert.Equal(t, time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC), identity.ExpiresAt)
+
+	claims := identity.Claims()
+	assert.Equal(t, "jo@acme.example", claims["sub"])
+	assert.Equal(t, "acme", claims["tenant_id"])
+	assert.Equal(t, "saml", claims["auth_method"])
+	assert.Equal(t, "acme", identity.Principal().TenantID)
+
+	_, err = validator.Validate(ctx, encodeSAML(signed), "req-1")
+	assert.ErrorContains(t, err, "already been used")
+
+	// Instances sharing a replay store reject each other's assertions
+	other, err := NewSAMLValidator(SAMLValidatorConfig{Registry: registry, Now: func() time.Time { return now }, ReplayStore: store})
+	require.NoError(t, err)
+	_, err = other.Validate(ctx, encodeSAML(signed), "req-1")
+	assert.ErrorContains(t, err, "already been used")
+
+	// Certificates from before the rotation still verify
+	byOld := oldKey.sign(t, strings.Replace(samlResponse("old@acme.example", audience), "assertion-1", "assertion-2", 2), "assertion-2")
+	_, err = validator.Validate(ctx, encodeSAML(byOld), "req-1")
+	assert.NoError(t, err)
+
+	// A signed response vouches for its assertion
+	responseSigned := newKey.sign(t, strings.Replace(samlResponse("resp@acme.example", audience), "assertion-1", "assertion-3", 2), "resp-1")
+	_, err = validator.Validate(ctx, encodeSAML(responseSigned), "req-1")
+	assert.NoError(t, err)
+
+	fresh := func(nameID, id string) string {
+		return strings.Replace(samlResponse(nameID, audience), "assertion-1", id, 2)
+	}
+	cases := map[string]struct {
+		response  string
+		requestID string
+		want      string
+	}{
+		"untrusted key":  {otherKey.sign(t, fresh("x@acme.example", "a-4"), "a-4"), "req-1", "Could not verify certificate against trusted certs"},
+		"unsigned":       {fresh("x@acme.example", "a-5"), "req-1", "not signed"},
+		"wrong request":  {newKey.sign(t, fresh("x@acme.example", "a-6"), "a-6"), "req-2", "not for request"},
+		"wrong audience": {newKey.sign(t, strings.Replace(fresh("x@acme.example", "a-7"), audience, "https://other.example", 1), "a-7"), "req-1", "not for audience"},
+		"tampered":       {strings.Replace(newKey.sign(t, fresh("x@acme.example", "a-8"), "a-8"), "x@acme.example</saml:NameID>", "ceo@acme.example</saml:NameID>", 1), "req-1", "Signature could not be verified"},
+		"SHA-1":          {strings.Replace(newKey.sign(t, fresh("x@acme.example", "a-15"), "a-15"), "2001/04/xmldsig-more#rsa-sha256", "2000/09/xmldsig#rsa-sha1", 1), "req-1", "unsupported signature algorithm"},
+		"unknown issuer": {strings.ReplaceAll(newKey.sign(t, fresh("x@acme.example", "a-9"), "a-9"), "https://idp.acme.example/saml", "https://evil.example"), "req-1", "unknown identity provider"},
+		"duplicated ID":  {strings.Replace(newKey.sign(t, fresh("x@acme.example", "a-10"), "a-10"), `<samlp:Status>`, `<samlp:Extensions ID="a-10"/><samlp:Status>`, 1), "req-1", "not unique"},
+		"not a response": {`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"/>`, "", "not a SAML response"},
+		"failure status": {strings.Replace(fresh("x@acme.example", "a-11"), "status:Success", "status:Requester", 1), "req-1", "IdP returned status"},
+		"two assertions": {strings.Replace(fresh("x@acme.example", "a-12"), "</samlp:Response>", `<saml:Assertion ID="extra"/></samlp:Response>`, 1), "req-1", "exactly one assertion"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := validator.Validate(ctx, encodeSAML(tc.response), tc.requestID)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+
+	// Signature wrapping: the signed assertion moved out of place doesn't
+	// vouch for an unsigned one put where the validator reads
+	wrapped := newKey.sign(t, fresh("x@acme.example", "a-13"), "a-13")
+	start, end := strings.Index(wrapped, "<saml:Assertion "), strings.Index(wrapped, "</saml:Assertion>")+len("</saml:Assertion>")
+	original := wrapped[start:end]
+	evil := strings.Replace(strings.Replace(original, `ID="a-13"`, `ID="evil"`, 1), "x@acme.example</saml:NameID>", "ceo@acme.example</saml:NameID>", 1)
+	evil = evil[:strings.Index(evil, "<ds:Signature")] + evil[strings.Index(evil, "</ds:Signature>")+len("</ds:Signature>"):]
+	wrapped = wrapped[:start] + `<samlp:Extensions>` + original + `</samlp:Extensions>` + evil + wrapped[end:]
+	_, err = validator.Validate(ctx, encodeSAML(wrapped), "req-1")
+	assert.ErrorContains(t, err, "not signed")
+
+	// Expired assertions are rejected
+	now = now.Add(15 * time.Minute)
+	_, err = validator.Validate(ctx, encodeSAML(newKey.sign(t, fresh("x@acme.example", "a-14"), "a-14")), "req-1")
+	assert.ErrorIs(t, err, ErrInvalidSSOToken)
+}
+
+func jwkFor(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestOIDCValidator(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var keys atomic.Value
+	keys.Store([]map[string]string{jwkFor("k1", &key1.PublicKey)})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys.Load()})
+	}))
+	defer server.Close()
+
+	registry, err := NewStaticIdentityProviders(&IdentityProvider{
+		TenantID:     "globex",
+		Protocol:     SSOProtocolOIDC,
+		Issuer:       "https://login.globex.example",
+		ClientID:     "lift-app",
+		JWKSURL:      server.URL,
+		RoleMap:      map[string][]string{"admins": {"admin"}},
+		DefaultRoles: []string{"member"},
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	validator, err := NewOIDCValidator(OIDCValidatorConfig{Registry: registry, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	token := func(kid string, key *rsa.PrivateKey, overrides jwt.MapClaims) string {
+		claims := jwt.MapClaims{
+			"iss":    "https://login.globex.example",
+			"sub":    "00u1",
+			"aud":    "lift-app",
+			"iat":    now.Unix(),
+			"exp":    now.Add(time.Hour).Unix(),
+			"nonce":  "n-1",
+			"email":  "sam@globex.example",
+			"name":   "Sam",
+			"groups": []string{"admins"},
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = kid
+		signed, err := tok.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	identity, err := validator.Validate(ctx, token("k1", key1, nil), "n-1")
+	require.NoError(t, err)
+	assert.Equal(t, "globex", identity.TenantID)
+	assert.Equal(t, "00u1", identity.Subject)
+	assert.Equal(t, "sam@globex.example", identity.Email)
+	assert.Equal(t, "Sam", identity.Name)
+	assert.Equal(t, []string{"admin", "member"}, identity.Roles)
+	assert.WithinDuration(t, now.Add(time.Hour), identity.ExpiresAt, 0)
+	assert.Equal(t, "oidc", identity.Claims()["auth_method"])
+
+	cases := map[string]struct {
+		token string
+		nonce string
+		want  string
+	}{
+		"wrong audience": {token("k1", key1, jwt.MapClaims{"aud": "other-app"}), "", "audience"},
+		"expired":        {token("k1", key1, jwt.MapClaims{"exp": now.Add(-time.Hour).Unix()}), "", "expired"},
+		"no expiry":      {token("k1", key1, jwt.MapClaims{"exp": nil}), "", "exp"},
+		"nonce mismatch": {token("k1", key1, nil), "n-2", "nonce"},
+		"unknown issuer": {token("k1", key1, jwt.MapClaims{"iss": "https://evil.example"}), "", "unknown identity provider"},
+		"wrong key":      {token("k1", key2, nil), "", "verification"},
+		"other azp":      {token("k1", key1, jwt.MapClaims{"aud": []string{"lift-app", "other-app"}, "azp": "other-app"}), "", "not authorized"},
+		"malformed":      {"not-a-jwt", "", "malformed"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := validator.Validate(ctx, tc.token, tc.nonce)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+
+	// Rotation: a token naming a new key refetches the JWKS, at most once
+	// per refresh interval
+	fetchesBefore := fetches.Load()
+	keys.Store([]map[string]string{jwkFor("k1", &key1.PublicKey), jwkFor("k2", &key2.PublicKey)})
+	_, err = validator.Validate(ctx, token("k2", key2, nil), "")
+	assert.ErrorContains(t, err, "unknown signing key")
+	assert.Equal(t, fetchesBefore, fetches.Load(), "keys were fetched within the refresh interval")
+
+	now = now.Add(2 * time.Minute)
+	_, err = validator.Validate(ctx, token("k2", key2, nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, fetchesBefore+1, fetches.Load())
+}
+
+func TestJWKSBacksOffAfterFailedFetches(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var failing atomic.Bool
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{jwkFor("k1", &key.PublicKey)}})
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	jwks, err := NewJWKS(JWKSConfig{URL: server.URL, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = jwks.Key(ctx, "k1")
+	require.NoError(t, err)
+	require.Equal(t, int32(1), fetches.Load())
+
+	// Once the cache expires and the issuer is down, the cached keys stay
+	// in use and the failed fetch isn't retried on every request
+	failing.Store(true)
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		got, err := jwks.Key(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, &key.PublicKey, got)
+	}
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// The wait doubles with each failure
+	now = now.Add(time.Minute)
+	_, err = jwks.Key(ctx, "k1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), fetches.Load())
+	now = now.Add(time.Minute)
+	_, err = jwks.Key(ctx, "k1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), fetches.Load(), "refetched within the backoff")
+
+	// A successful fetch clears the backoff
+	failing.Store(false)
+	now = now.Add(time.Minute)
+	_, err = jwks.Key(ctx, "k1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), fetches.Load())
+
+	// Without cached keys, requests within the backoff get the last error
+	cold, err := NewJWKS(JWKSConfig{URL: server.URL, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	failing.Store(true)
+	_, err = cold.Key(ctx, "k1")
+	require.Error(t, err)
+	_, again := cold.Key(ctx, "k1")
+	assert.Equal(t, err, again)
+	assert.Equal(t, int32(5), fetches.Load())
+}
+
+func TestIdentityProviderValidation(t *testing.T) {
+	_, err := NewStaticIdentityProviders(&IdentityProvider{TenantID: "t", Protocol: SSOProtocolSAML, Issuer: "i", Audience: "a", Certificates: []string{"not pem"}})
+	assert.Error(t, err)
+	_, err = NewStaticIdentityProviders(&IdentityProvider{TenantID: "t", Protocol: SSOProtocolOIDC, Issuer: "i"})
+	assert.Error(t, err)
+	_, err = NewStaticIdentityProviders(&IdentityProvider{TenantID: "t", Protocol: "ldap", Issuer: "i"})
+	assert.Error(t, err)
+	_, err = NewOIDCValidator(OIDCValidatorConfig{})
+	assert.Error(t, err)
+	_, err = NewSAMLValidator(SAMLValidatorConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepUpCheck(t *testing.T) {
+	now := time.Now()
+	su, err := NewStepUp(StepUpConfig{
+		Levels: []string{"pwd", "mfa", "hwk"},
+		Audit:  NewInMemoryAuditStorage(),
+		Now:    func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	recent := now.Add(-time.Minute)
+	assert.NoError(t, su.Check(AuthContext{ACR: "mfa", AuthTime: recent}, "mfa"))
+	assert.NoError(t, su.Check(AuthContext{ACR: "hwk", AuthTime: recent}, "mfa"), "stronger levels satisfy weaker requirements")
+	assert.NoError(t, su.Check(AuthContext{ACR: "pwd", AMR: []string{"pwd", "mfa"}, AuthTime: recent}, "mfa"))
+
+	assert.ErrorIs(t, su.Check(AuthContext{ACR: "pwd", AuthTime: recent}, "mfa"), ErrStepUpRequired)
+	assert.ErrorIs(t, su.Check(AuthContext{ACR: "unknown", AuthTime: recent}, "mfa"), ErrStepUpRequired)
+	assert.ErrorIs(t, su.Check(AuthContext{ACR: "mfa"}, "mfa"), ErrStepUpRequired, "auth_time is required")
+	assert.ErrorIs(t, su.Check(AuthContext{ACR: "mfa", AuthTime: now.Add(-time.Hour)}, "mfa"), ErrStepUpRequired)
+
+	_, err = NewStepUp(StepUpConfig{})
+	assert.Error(t, err)
+}
+
+func TestStepUpRecordAccess(t *testing.T) {
+	storage := NewInMemoryAuditStorage()
+	su, err := NewStepUp(StepUpConfig{Audit: storage})
+	require.NoError(t, err)
+
+	auth := AuthContext{ACR: "mfa", AMR: []string{"pwd", "otp"}, AuthTime: time.Now()}
+	require.NoError(t, su.RecordAccess(context.Background(), "tenant-1", "user-1", "mfa", auth, "POST", "/refunds"))
+
+	entries, err := storage.Query(context.Background(), AuditFilter{EntryType: StepUpAccessEntry})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "user-1", entries[0].UserID)
+	assert.Equal(t, "/refunds", entries[0].Request.Resource)
+	assert.Equal(t, "mfa", entries[0].Metadata["acr"])
+	assert.NotEmpty(t, entries[0].Checksum)
+}
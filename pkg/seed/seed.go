@@ -0,0 +1,334 @@
+// Package seed loads fixture data such as tenants, users and products into
+// DynamoDB Local, a mock store or a dev table, on dev server start or in
+// test setup.
+//
+// Fixture files are YAML or JSON. Each top-level key names a table, and
+// each item under it is named so later items can refer to it:
+//
+//	tenants:
+//	  acme:
+//	    id: '{{ id "ten" }}'
+//	    name: Acme
+//	users:
+//	  ada:
+//	    id: '{{ id "usr" }}'
+//	    tenant_id: '{{ ref "acme.id" }}'
+//	    email: ada@acme.test
+//	    created_at: '{{ now }}'
+//
+// Strings are Go templates with these functions:
+//
+//	id "usr"        a new prefixed ID, e.g. "usr_01J9ZKQ5X8M3V7TQW6Y2H4N0PB"
+//	ref "acme.id"   a field of an item seeded earlier
+//	now             the current time, RFC 3339
+//	var "region"    a value from Config.Vars
+//
+// Items are seeded in file order, so an item can only refer to items
+// above it or in earlier files. Templates always produce strings.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift/id"
+	"gopkg.in/yaml.v3"
+)
+
+// Config configures a Seeder
+type Config struct {
+	// Sink stores the seeded items (required)
+	Sink Sink
+	// NewID generates the IDs of the id function (default: id.New)
+	NewID func(prefix string) string
+	// Now returns the time of the now function (default: time.Now)
+	Now func() time.Time
+	// Vars are the values of the var function
+	Vars map[string]any
+}
+
+// Seeder loads fixture files and seeds their items
+type Seeder struct {
+	config   Config
+	fixtures []fixture
+	refs     map[string]string
+}
+
+// fixture is one named item of a fixture file
+type fixture struct {
+	source string
+	table  string
+	ref    string
+	item   map[string]any
+}
+
+// Result holds the items a run seeded
+type Result struct {
+	// Counts is the number of items seeded per table
+	Counts map[string]int
+	items  map[string]map[string]any
+}
+
+// New creates a Seeder with the given configuration
+func New(config Config) (*Seeder, error) {
+	if config.Sink == nil {
+		return nil, fmt.Errorf("seed: a sink is required")
+	}
+	if config.NewID == nil {
+		config.NewID = id.New
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Seeder{config: config, refs: make(map[string]string)}, nil
+}
+
+// LoadDir loads the .yaml, .yml and .json files in dir, in name order
+func (s *Seeder) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			if err := s.LoadFile(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFile loads a fixture file
+func (s *Seeder) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	return s.Add(path, data)
+}
+
+// Add loads fixtures from data, naming them source in errors
+func (s *Seeder) Add(source string, data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("seed: %s: %w", source, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	tables := doc.Content[0]
+	if tables.Kind != yaml.MappingNode {
+		return fmt.Errorf("seed: %s: expected a map of tables", source)
+	}
+
+	var fixtures []fixture
+	for n := 0; n < len(tables.Content); n += 2 {
+		table, items := tables.Content[n].Value, tables.Content[n+1]
+		switch items.Kind {
+		case yaml.MappingNode:
+			for m := 0; m < len(items.Content); m += 2 {
+				f, err := decodeFixture(source, table, items.Content[m].Value, items.Content[m+1])
+				if err != nil {
+					return err
+				}
+				fixtures = append(fixtures, f)
+			}
+		case yaml.SequenceNode:
+			// Unnamed items can't be referred to
+			for _, item := range items.Content {
+				f, err := decodeFixture(source, table, "", item)
+				if err != nil {
+					return err
+				}
+				fixtures = append(fixtures, f)
+			}
+		default:
+			return fmt.Errorf("seed: %s: %s: expected named items or a list of items", source, table)
+		}
+	}
+
+	for _, f := range fixtures {
+		if f.ref == "" {
+			continue
+		}
+		if previous, ok := s.refs[f.ref]; ok {
+			return fmt.Errorf("seed: %s: %s.%s: item %q is already defined in %s", source, f.table, f.ref, f.ref, previous)
+		}
+		s.refs[f.ref] = source
+	}
+	s.fixtures = append(s.fixtures, fixtures...)
+	return nil
+}
+
+func decodeFixture(source, table, ref string, node *yaml.Node) (fixture, error) {
+	var item map[string]any
+	if err := node.Decode(&item); err != nil || item == nil {
+		return fixture{}, fmt.Errorf("seed: %s: %s.%s: expected an item's fields", source, table, ref)
+	}
+	return fixture{source: source, table: table, ref: ref, item: item}, nil
+}
+
+// Run seeds every loaded item. Each run generates new IDs.
+func (s *Seeder) Run(ctx context.Context) (*Result, error) {
+	result := &Result{
+		Counts: make(map[string]int),
+		items:  make(map[string]map[string]any),
+	}
+	now := s.config.Now().UTC().Format(time.RFC3339)
+
+	for _, f := range s.fixtures {
+		name := f.table + "." + f.ref
+		if f.ref == "" {
+			name = f.table + "[" + fmt.Sprint(result.Counts[f.table]) + "]"
+		}
+
+		funcs := template.FuncMap{
+			"id":  s.config.NewID,
+			"now": func() string { return now },
+			"ref": func(path string) (any, error) {
+				value, ok := result.lookup(path)
+				if !ok {
+					return nil, fmt.Errorf("no seeded value %q; items can only refer to items seeded before them", path)
+				}
+				return value, nil
+			},
+			"var": func(key string) (any, error) {
+				value, ok := s.config.Vars[key]
+				if !ok {
+					return nil, fmt.Errorf("no var %q", key)
+				}
+				return value, nil
+			},
+		}
+
+		item, err := render(f.item, funcs)
+		if err != nil {
+			return result, fmt.Errorf("seed: %s: %s: %w", f.source, name, err)
+		}
+		if err := s.config.Sink.Put(ctx, f.table, item.(map[string]any)); err != nil {
+			return result, fmt.Errorf("seed: %s: %s: %w", f.source, name, err)
+		}
+		if f.ref != "" {
+			result.items[f.ref] = item.(map[string]any)
+		}
+		result.Counts[f.table]++
+	}
+	return result, nil
+}
+
+// MustRun runs the seeder in a test, failing it on error
+func (s *Seeder) MustRun(t testing.TB) *Result {
+	t.Helper()
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("failed to seed fixtures: %v", err)
+	}
+	return result
+}
+
+// render executes the templates in a fixture value
+func render(value any, funcs template.FuncMap) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New("").Option("missingkey=error").Funcs(funcs).Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, nil); err != nil {
+			return nil, err
+		}
+		return out.String(), nil
+	case map[string]any:
+		rendered := make(map[string]any, len(v))
+		for key, field := range v {
+			r, err := render(field, funcs)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			rendered[key] = r
+		}
+		return rendered, nil
+	case []any:
+		rendered := make([]any, len(v))
+		for n, element := range v {
+			r, err := render(element, funcs)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", n, err)
+			}
+			rendered[n] = r
+		}
+		return rendered, nil
+	}
+	return value, nil
+}
+
+// Item returns a seeded item by name, or nil
+func (r *Result) Item(ref string) map[string]any {
+	return r.items[ref]
+}
+
+// Ref returns a field of a seeded item, e.g. Ref("acme.id"), or nil
+func (r *Result) Ref(path string) any {
+	value, _ := r.lookup(path)
+	return value
+}
+
+// String returns a field of a seeded item as a string, e.g. String("ada.id")
+func (r *Result) String(path string) string {
+	value, _ := r.lookup(path)
+	s, _ := value.(string)
+	return s
+}
+
+// Total returns the number of items seeded
+func (r *Result) Total() int {
+	total := 0
+	for _, count := range r.Counts {
+		total += count
+	}
+	return total
+}
+
+// Tables returns the seeded tables, sorted
+func (r *Result) Tables() []string {
+	tables := make([]string, 0, len(r.Counts))
+	for table := range r.Counts {
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+	return tables
+}
+
+// lookup resolves a dotted path: an item name, then nested fields
+func (r *Result) lookup(path string) (any, bool) {
+	parts := strings.Split(path, ".")
+	item, ok := r.items[parts[0]]
+	if !ok {
+		return nil, false
+	}
+	var value any = item
+	for _, part := range parts[1:] {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = fields[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
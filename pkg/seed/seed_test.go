@@ -0,0 +1,161 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+func newTestSeeder(t *testing.T, sink Sink) *Seeder {
+	t.Helper()
+	ids := 0
+	seeder, err := New(Config{
+		Sink: sink,
+		NewID: func(prefix string) string {
+			ids++
+			return fmt.Sprintf("%s_%d", prefix, ids)
+		},
+		Now:  func() time.Time { return testNow },
+		Vars: map[string]any{"plan": "enterprise"},
+	})
+	require.NoError(t, err)
+	return seeder
+}
+
+const tenantsYAML = `
+tenants:
+  acme:
+    id: '{{ id "ten" }}'
+    name: Acme
+    plan: '{{ var "plan" }}'
+    limits:
+      seats: 25
+users:
+  ada:
+    id: '{{ id "usr" }}'
+    tenant_id: '{{ ref "acme.id" }}'
+    email: ada@acme.test
+    created_at: '{{ now }}'
+    roles: [admin, '{{ ref "acme.plan" }}']
+`
+
+const productsJSON = `{
+  "products": [
+    {"id": "{{ id \"prd\" }}", "tenant_id": "{{ ref \"acme.id\" }}", "seats": "{{ ref \"acme.limits.seats\" }}"},
+    {"id": "{{ id \"prd\" }}", "tenant_id": "{{ ref \"acme.id\" }}", "price": 1200}
+  ]
+}`
+
+func TestSeeder(t *testing.T) {
+	sink := NewMemorySink()
+	seeder := newTestSeeder(t, sink)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "01_tenants.yaml"), []byte(tenantsYAML), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "02_products.json"), []byte(productsJSON), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a fixture"), 0o600))
+	require.NoError(t, seeder.LoadDir(dir))
+
+	result := seeder.MustRun(t)
+	assert.Equal(t, map[string]int{"tenants": 1, "users": 1, "products": 2}, result.Counts)
+	assert.Equal(t, 4, result.Total())
+	assert.Equal(t, []string{"products", "tenants", "users"}, result.Tables())
+
+	assert.Equal(t, "ten_1", result.String("acme.id"))
+	assert.Equal(t, map[string]any{
+		"id":         "usr_2",
+		"tenant_id":  "ten_1",
+		"email":      "ada@acme.test",
+		"created_at": "2026-10-16T09:00:00Z",
+		"roles":      []any{"admin", "enterprise"},
+	}, result.Item("ada"))
+
+	products := sink.Items("products")
+	require.Len(t, products, 2)
+	assert.Equal(t, map[string]any{"id": "prd_3", "tenant_id": "ten_1", "seats": "25"}, products[0])
+	assert.Equal(t, 1200, products[1]["price"], "values without templates keep their type")
+
+	// Each run generates new IDs
+	again := seeder.MustRun(t)
+	assert.NotEqual(t, result.String("acme.id"), again.String("acme.id"))
+}
+
+func TestSeederErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		err   string
+	}{
+		{
+			name:  "forward reference",
+			files: []string{"users:\n  ada:\n    tenant_id: '{{ ref \"acme.id\" }}'\ntenants:\n  acme:\n    id: t1\n"},
+			err:   `users.ada: tenant_id: template: :1:3: executing "" at <ref "acme.id">: error calling ref: no seeded value "acme.id"`,
+		},
+		{
+			name:  "unknown var",
+			files: []string{"tenants:\n  acme:\n    region: '{{ var \"region\" }}'\n"},
+			err:   `no var "region"`,
+		},
+		{
+			name:  "duplicate name",
+			files: []string{"tenants:\n  acme:\n    id: t1\n", "users:\n  acme:\n    id: u1\n"},
+			err:   `users.acme: item "acme" is already defined in file0`,
+		},
+		{
+			name:  "not a map of tables",
+			files: []string{"- id: t1\n"},
+			err:   "expected a map of tables",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seeder := newTestSeeder(t, NewMemorySink())
+			var err error
+			for n, file := range tt.files {
+				if err = seeder.Add(fmt.Sprintf("file%d", n), []byte(file)); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				_, err = seeder.Run(context.Background())
+			}
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	_, err := New(Config{})
+	assert.Error(t, err, "a sink is required")
+}
+
+type keyedStore map[string]any
+
+func (k keyedStore) Put(ctx context.Context, table, key string, item any) error {
+	k[table+"/"+key] = item
+	return nil
+}
+
+func TestSinks(t *testing.T) {
+	store := keyedStore{}
+	seeder := newTestSeeder(t, KeyedSink(store, ""))
+	require.NoError(t, seeder.Add("tenants.yaml", []byte(tenantsYAML)))
+	seeder.MustRun(t)
+	assert.Contains(t, store, "tenants/ten_1")
+	assert.Contains(t, store, "users/usr_2")
+
+	failing := newTestSeeder(t, SinkFunc(func(ctx context.Context, table string, item map[string]any) error {
+		return errors.New("table not found")
+	}))
+	require.NoError(t, failing.Add("tenants.yaml", []byte(tenantsYAML)))
+	_, err := failing.Run(context.Background())
+	assert.EqualError(t, err, "seed: tenants.yaml: tenants.acme: table not found")
+}
@@ -0,0 +1,100 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Sink stores seeded items
+type Sink interface {
+	Put(ctx context.Context, table string, item map[string]any) error
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ctx context.Context, table string, item map[string]any) error
+
+// Put implements Sink
+func (f SinkFunc) Put(ctx context.Context, table string, item map[string]any) error {
+	return f(ctx, table, item)
+}
+
+// PutItemAPI is the part of the DynamoDB client DynamoDBSink uses
+type PutItemAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// DynamoDBSink puts items into DynamoDB, e.g. DynamoDB Local
+type DynamoDBSink struct {
+	Client PutItemAPI
+	// Tables maps fixture table names to table names, e.g.
+	// {"users": "app-users-dev"}. Unmapped names are used as is.
+	Tables map[string]string
+}
+
+// Put implements Sink
+func (d *DynamoDBSink) Put(ctx context.Context, table string, item map[string]any) error {
+	if name, ok := d.Tables[table]; ok {
+		table = name
+	}
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item:      av,
+	})
+	return err
+}
+
+// KeyedStore is a store that keys items explicitly, such as
+// testing.MockDynamORM
+type KeyedStore interface {
+	Put(ctx context.Context, table, key string, item any) error
+}
+
+// KeyedSink puts items into a KeyedStore under their keyAttribute
+// (default: "id")
+func KeyedSink(store KeyedStore, keyAttribute string) Sink {
+	if keyAttribute == "" {
+		keyAttribute = "id"
+	}
+	return SinkFunc(func(ctx context.Context, table string, item map[string]any) error {
+		key, ok := item[keyAttribute]
+		if !ok {
+			return fmt.Errorf("item has no %s", keyAttribute)
+		}
+		return store.Put(ctx, table, fmt.Sprint(key), item)
+	})
+}
+
+// MemorySink keeps seeded items in memory, for tests
+type MemorySink struct {
+	mu     sync.Mutex
+	tables map[string][]map[string]any
+}
+
+// NewMemorySink creates an empty sink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{tables: make(map[string][]map[string]any)}
+}
+
+// Put implements Sink
+func (m *MemorySink) Put(ctx context.Context, table string, item map[string]any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tables[table] = append(m.tables[table], item)
+	return nil
+}
+
+// Items returns the items seeded into table, in order
+func (m *MemorySink) Items(table string) []map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]map[string]any(nil), m.tables[table]...)
+}
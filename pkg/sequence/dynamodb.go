@@ -0,0 +1,223 @@
+package sequence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBStore implements Store with a DynamoDB table keyed by a string
+// partition key "pk". Counters are stored under "counter#<name>" with their
+// value in "value"; allocations are stored under "allocation#<key>" and
+// expire through the "ttl" attribute.
+type DynamoDBStore struct {
+	client    *dynamodb.Client
+	tableName string
+	// AllocationTTL is how long idempotent allocations are remembered
+	// (default: 7 days)
+	AllocationTTL time.Duration
+	// MaxAttempts bounds the retries of a contended gapless allocation
+	// (default: 10)
+	MaxAttempts int
+}
+
+// NewDynamoDBStore creates a DynamoDB-backed sequence store
+func NewDynamoDBStore(client *dynamodb.Client, tableName string) *DynamoDBStore {
+	return &DynamoDBStore{
+		client:        client,
+		tableName:     tableName,
+		AllocationTTL: 7 * 24 * time.Hour,
+		MaxAttempts:   10,
+	}
+}
+
+// Allocate implements Store
+func (d *DynamoDBStore) Allocate(ctx context.Context, req AllocateRequest) (Allocation, error) {
+	if req.Mode == GapTolerant {
+		return d.allocateGapTolerant(ctx, req)
+	}
+	return d.allocateGapless(ctx, req)
+}
+
+// allocateGapless reads the counter and, in one transaction, moves it on by
+// one if no one else has and records the allocation
+func (d *DynamoDBStore) allocateGapless(ctx context.Context, req AllocateRequest) (Allocation, error) {
+	for attempt := 0; attempt < d.MaxAttempts; attempt++ {
+		if allocation, ok, err := d.allocation(ctx, req.Key); err != nil || ok {
+			return allocation, err
+		}
+
+		current, err := d.counterValue(ctx, req.Counter)
+		if err != nil {
+			return Allocation{}, err
+		}
+		allocation := Allocation{Counter: req.Counter, Value: current + 1, At: req.At}
+
+		update := &types.Update{
+			TableName:           aws.String(d.tableName),
+			Key:                 counterKey(req.Counter),
+			UpdateExpression:    aws.String("SET #value = :next"),
+			ConditionExpression: aws.String("#value = :current"),
+			ExpressionAttributeNames: map[string]string{
+				"#value": "value",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":next":    numberValue(allocation.Value),
+				":current": numberValue(current),
+			},
+		}
+		if current == 0 {
+			update.ConditionExpression = aws.String("attribute_not_exists(#value)")
+			delete(update.ExpressionAttributeValues, ":current")
+		}
+		items := []types.TransactWriteItem{{Update: update}}
+		if req.Key != "" {
+			items = append(items, types.TransactWriteItem{Put: &types.Put{
+				TableName:           aws.String(d.tableName),
+				Item:                d.allocationItem(req.Key, allocation),
+				ConditionExpression: aws.String("attribute_not_exists(pk)"),
+			}})
+		}
+
+		_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			// Another allocation moved the counter, or one with the same
+			// key got there first; the next attempt sees which
+			select {
+			case <-ctx.Done():
+				return Allocation{}, ctx.Err()
+			case <-time.After(time.Duration(attempt*attempt) * 5 * time.Millisecond):
+			}
+			continue
+		}
+		if err != nil {
+			return Allocation{}, err
+		}
+		return allocation, nil
+	}
+	return Allocation{}, fmt.Errorf("%w: %s", ErrContention, req.Counter)
+}
+
+// allocateGapTolerant increments the counter atomically. If an allocation
+// with the same key is recorded concurrently, that one is returned and the
+// value allocated here is skipped.
+func (d *DynamoDBStore) allocateGapTolerant(ctx context.Context, req AllocateRequest) (Allocation, error) {
+	if allocation, ok, err := d.allocation(ctx, req.Key); err != nil || ok {
+		return allocation, err
+	}
+
+	result, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(d.tableName),
+		Key:              counterKey(req.Counter),
+		UpdateExpression: aws.String("ADD #value :one"),
+		ExpressionAttributeNames: map[string]string{
+			"#value": "value",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": numberValue(1),
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return Allocation{}, err
+	}
+	value, err := readNumber(result.Attributes["value"])
+	if err != nil {
+		return Allocation{}, err
+	}
+	allocation := Allocation{Counter: req.Counter, Value: value, At: req.At}
+	if req.Key == "" {
+		return allocation, nil
+	}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.tableName),
+		Item:                d.allocationItem(req.Key, allocation),
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		existing, _, err := d.allocation(ctx, req.Key)
+		return existing, err
+	}
+	if err != nil {
+		return Allocation{}, err
+	}
+	return allocation, nil
+}
+
+// allocation returns the recorded allocation for a key, if any
+func (d *DynamoDBStore) allocation(ctx context.Context, key string) (Allocation, bool, error) {
+	if key == "" {
+		return Allocation{}, false, nil
+	}
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.tableName),
+		Key:            map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "allocation#" + key}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Allocation{}, false, err
+	}
+	if result.Item == nil {
+		return Allocation{}, false, nil
+	}
+
+	counter, _ := result.Item["counter"].(*types.AttributeValueMemberS)
+	value, err := readNumber(result.Item["value"])
+	if err != nil || counter == nil {
+		return Allocation{}, false, fmt.Errorf("invalid sequence allocation %s", key)
+	}
+	at, _ := readNumber(result.Item["at"])
+	return Allocation{Counter: counter.Value, Value: value, At: time.UnixMilli(at)}, true, nil
+}
+
+func (d *DynamoDBStore) counterValue(ctx context.Context, counter string) (int64, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.tableName),
+		Key:            counterKey(counter),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if result.Item == nil {
+		return 0, nil
+	}
+	return readNumber(result.Item["value"])
+}
+
+func (d *DynamoDBStore) allocationItem(key string, allocation Allocation) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk":      &types.AttributeValueMemberS{Value: "allocation#" + key},
+		"counter": &types.AttributeValueMemberS{Value: allocation.Counter},
+		"value":   numberValue(allocation.Value),
+		"at":      numberValue(allocation.At.UnixMilli()),
+		"ttl":     numberValue(allocation.At.Add(d.AllocationTTL).Unix()),
+	}
+}
+
+func counterKey(counter string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "counter#" + counter},
+	}
+}
+
+func numberValue(n int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+func readNumber(av types.AttributeValue) (int64, error) {
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("missing number attribute")
+	}
+	return strconv.ParseInt(n.Value, 10, 64)
+}
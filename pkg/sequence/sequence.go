@@ -0,0 +1,269 @@
+// Package sequence allocates human-facing document numbers, such as invoice
+// and order numbers, from per-tenant, per-year counters.
+//
+// Numbers are allocated in one of two modes. Gapless allocation moves the
+// counter and records the allocation in one transaction, so every number
+// is handed out exactly once and, as long as callers retry failed requests
+// with the same idempotency key, none is skipped; allocations for a counter
+// are serialized. Gap-tolerant allocation is a single atomic increment: it
+// is cheaper and never contends, but a number is skipped when a caller
+// fails between allocating it and using it.
+package sequence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// Mode is how numbers are allocated
+type Mode int
+
+const (
+	// Gapless allocates every number exactly once, in order
+	Gapless Mode = iota
+	// GapTolerant allocates with an atomic increment and may skip numbers
+	GapTolerant
+)
+
+// ErrContention is returned when a gapless allocation kept losing races
+// with concurrent allocations for the same counter
+var ErrContention = errors.New("sequence allocation contended")
+
+// AllocateRequest asks a store for a counter's next value
+type AllocateRequest struct {
+	Counter string
+	// Key makes the allocation idempotent: allocations with the same key
+	// return the first allocation, even if it was from another counter
+	Key  string
+	Mode Mode
+	// At is recorded with the allocation
+	At time.Time
+}
+
+// Allocation is a value allocated from a counter
+type Allocation struct {
+	Counter string
+	Value   int64
+	At      time.Time
+}
+
+// Store keeps counters and their idempotent allocations
+type Store interface {
+	Allocate(ctx context.Context, req AllocateRequest) (Allocation, error)
+}
+
+// Config configures a Generator
+type Config struct {
+	// Name distinguishes sequences sharing a store, e.g. "invoice"
+	Name string
+	// Store keeps the counters (default in-memory, for tests only)
+	Store Store
+	// Mode is how numbers are allocated (default Gapless)
+	Mode Mode
+	// Format is the template numbers are formatted with. {seq} is the
+	// number, optionally zero-padded as {seq:6}; {year} and {yy} are the
+	// year it was allocated in and {tenant} the tenant ID (default "{seq}").
+	//
+	//	"INV-{year}-{seq:6}" → INV-2026-000042
+	Format string
+	// Location decides which year a number belongs to (default UTC)
+	Location *time.Location
+	// Now returns the current time (default time.Now)
+	Now func() time.Time
+}
+
+// Generator allocates numbers for tenants. Each tenant's numbering restarts
+// at 1 every year.
+type Generator struct {
+	config Config
+	format []formatPart
+}
+
+// Number is an allocated number
+type Number struct {
+	TenantID string `json:"tenant_id"`
+	Year     int    `json:"year"`
+	Value    int64  `json:"value"`
+	// Formatted is the number rendered with the generator's format
+	Formatted string `json:"formatted"`
+}
+
+// String returns the formatted number
+func (n Number) String() string {
+	return n.Formatted
+}
+
+// New creates a number generator
+func New(config Config) (*Generator, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("sequence requires a name")
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	if config.Format == "" {
+		config.Format = "{seq}"
+	}
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	format, err := parseFormat(config.Format)
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{config: config, format: format}, nil
+}
+
+// Next allocates the tenant's next number. Repeating a call with the same
+// idempotency key returns the same number, so a retried request doesn't
+// consume another; without a key every call allocates.
+func (g *Generator) Next(ctx context.Context, tenantID, idempotencyKey string) (Number, error) {
+	if tenantID == "" {
+		return Number{}, fmt.Errorf("sequence %s requires a tenant", g.config.Name)
+	}
+
+	now := g.config.Now().In(g.config.Location)
+	req := AllocateRequest{
+		Counter: g.counter(tenantID, now.Year()),
+		Mode:    g.config.Mode,
+		At:      now,
+	}
+	if idempotencyKey != "" {
+		req.Key = g.config.Name + "#" + tenantID + "#" + idempotencyKey
+	}
+
+	allocation, err := g.config.Store.Allocate(ctx, req)
+	if err != nil {
+		return Number{}, fmt.Errorf("failed to allocate %s number: %w", g.config.Name, err)
+	}
+
+	number := Number{
+		TenantID: tenantID,
+		Year:     allocation.At.In(g.config.Location).Year(),
+		Value:    allocation.Value,
+	}
+	number.Formatted = g.render(number)
+	return number, nil
+}
+
+// NextFromContext allocates the next number for the request's tenant,
+// keyed by the request's Idempotency-Key header
+func (g *Generator) NextFromContext(ctx *lift.Context) (Number, error) {
+	return g.Next(ctx.Context, ctx.TenantID(), ctx.Header("Idempotency-Key"))
+}
+
+// Format renders a number with the generator's format, e.g. to display
+// numbers stored as values
+func (g *Generator) Format(tenantID string, year int, value int64) string {
+	return g.render(Number{TenantID: tenantID, Year: year, Value: value})
+}
+
+func (g *Generator) counter(tenantID string, year int) string {
+	return g.config.Name + "#" + tenantID + "#" + strconv.Itoa(year)
+}
+
+// formatPart is a literal or a placeholder of a number format
+type formatPart struct {
+	literal string
+	field   string
+	width   int
+}
+
+var placeholderPattern = regexp.MustCompile(`\{(seq|year|yy|tenant)(?::(\d+))?\}`)
+
+func parseFormat(format string) ([]formatPart, error) {
+	var parts []formatPart
+	hasSeq := false
+	last := 0
+	for _, match := range placeholderPattern.FindAllStringSubmatchIndex(format, -1) {
+		if match[0] > last {
+			parts = append(parts, formatPart{literal: format[last:match[0]]})
+		}
+		part := formatPart{field: format[match[2]:match[3]]}
+		if match[4] >= 0 {
+			part.width, _ = strconv.Atoi(format[match[4]:match[5]])
+		}
+		hasSeq = hasSeq || part.field == "seq"
+		parts = append(parts, part)
+		last = match[1]
+	}
+	if last < len(format) {
+		parts = append(parts, formatPart{literal: format[last:]})
+	}
+
+	if !hasSeq {
+		return nil, fmt.Errorf("sequence format %q has no {seq} placeholder", format)
+	}
+	return parts, nil
+}
+
+func (g *Generator) render(number Number) string {
+	var b strings.Builder
+	for _, part := range g.format {
+		var value string
+		switch part.field {
+		case "":
+			b.WriteString(part.literal)
+			continue
+		case "seq":
+			value = strconv.FormatInt(number.Value, 10)
+		case "year":
+			value = strconv.Itoa(number.Year)
+		case "yy":
+			value = fmt.Sprintf("%02d", number.Year%100)
+		case "tenant":
+			value = number.TenantID
+		}
+		if pad := part.width - len(value); pad > 0 {
+			b.WriteString(strings.Repeat("0", pad))
+		}
+		b.WriteString(value)
+	}
+	return b.String()
+}
+
+// MemoryStore is an in-memory Store
+type MemoryStore struct {
+	mu          sync.Mutex
+	counters    map[string]int64
+	allocations map[string]Allocation
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters:    make(map[string]int64),
+		allocations: make(map[string]Allocation),
+	}
+}
+
+// Allocate implements Store. Both modes are gapless in memory.
+func (s *MemoryStore) Allocate(ctx context.Context, req AllocateRequest) (Allocation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.Key != "" {
+		if allocation, ok := s.allocations[req.Key]; ok {
+			return allocation, nil
+		}
+	}
+
+	s.counters[req.Counter]++
+	allocation := Allocation{Counter: req.Counter, Value: s.counters[req.Counter], At: req.At}
+	if req.Key != "" {
+		s.allocations[req.Key] = allocation
+	}
+	return allocation, nil
+}
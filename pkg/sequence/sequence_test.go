@@ -0,0 +1,97 @@
+package sequence
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorNumbersPerTenantAndYear(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC)
+	gen, err := New(Config{
+		Name:   "invoice",
+		Format: "INV-{year}-{seq:6}",
+		Now:    func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	first, err := gen.Next(ctx, "tenant-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "INV-2026-000001", first.String())
+
+	second, err := gen.Next(ctx, "tenant-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), second.Value)
+
+	other, err := gen.Next(ctx, "tenant-2", "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), other.Value, "tenants are numbered separately")
+
+	now = now.Add(2 * time.Hour)
+	newYear, err := gen.Next(ctx, "tenant-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "INV-2027-000001", newYear.Formatted)
+}
+
+func TestGeneratorIdempotency(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)
+	gen, err := New(Config{Name: "order", Format: "{tenant}-{yy}-{seq}", Now: func() time.Time { return now }})
+	require.NoError(t, err)
+
+	number, err := gen.Next(ctx, "acme", "req-1")
+	require.NoError(t, err)
+	assert.Equal(t, "acme-26-1", number.Formatted)
+
+	now = now.Add(time.Second)
+	retried, err := gen.Next(ctx, "acme", "req-1")
+	require.NoError(t, err)
+	assert.Equal(t, number, retried, "a retry across the year boundary keeps its number")
+
+	next, err := gen.Next(ctx, "acme", "req-2")
+	require.NoError(t, err)
+	assert.Equal(t, "acme-27-1", next.Formatted)
+}
+
+func TestGeneratorConcurrentAllocationsAreGapless(t *testing.T) {
+	gen, err := New(Config{Name: "invoice"})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	seen := make(map[int64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			number, err := gen.Next(context.Background(), "tenant-1", "")
+			assert.NoError(t, err)
+			mu.Lock()
+			seen[number.Value] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for i := int64(1); i <= 50; i++ {
+		assert.True(t, seen[i], "number %d was skipped", i)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	_, err := New(Config{Name: "invoice", Format: "INV-{year}"})
+	assert.Error(t, err, "formats need a {seq} placeholder")
+
+	_, err = New(Config{})
+	assert.Error(t, err)
+
+	gen, err := New(Config{Name: "invoice", Format: "{year}/{seq:4}"})
+	require.NoError(t, err)
+	assert.Equal(t, "2026/0042", gen.Format("tenant-1", 2026, 42))
+	assert.Equal(t, "2026/123456", gen.Format("tenant-1", 2026, 123456))
+}
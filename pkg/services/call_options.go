@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCallBudgetExhausted is returned when too little of the caller's deadline
+// remains to make a service call
+var ErrCallBudgetExhausted = errors.New("not enough remaining deadline budget for a service call")
+
+// CallOptions overrides the client's timeout and retry settings for calls made
+// with a context. Zero values keep the client defaults.
+type CallOptions struct {
+	// Timeout bounds each attempt; ServiceRequest.Timeout still takes precedence
+	Timeout time.Duration
+	// MaxRetries replaces the client's retry count
+	MaxRetries int
+	// DisableRetries makes a single attempt regardless of MaxRetries
+	DisableRetries bool
+	// DeadlineReserve replaces the client's DeadlineReserve
+	DeadlineReserve time.Duration
+}
+
+type callOptionsKey struct{}
+
+// WithCallOptions returns a context whose service calls use the given
+// overrides. Handlers use it to tighten limits for a slow or optional
+// downstream:
+//
+//	callCtx := services.WithCallOptions(ctx.Context, services.CallOptions{
+//		Timeout:        2 * time.Second,
+//		DisableRetries: true,
+//	})
+//	resp, err := client.Call(callCtx, request)
+//
+// Every attempt is also capped by the context deadline (for Lambda, the
+// invocation deadline) minus the reserve, so one slow downstream can't use
+// the whole invocation budget.
+func WithCallOptions(ctx context.Context, opts CallOptions) context.Context {
+	return context.WithValue(ctx, callOptionsKey{}, opts)
+}
+
+// CallOptionsFromContext returns the call overrides set on a context
+func CallOptionsFromContext(ctx context.Context) CallOptions {
+	opts, _ := ctx.Value(callOptionsKey{}).(CallOptions)
+	return opts
+}
+
+// maxRetries returns the retry count for calls made with ctx
+func (c *ServiceClient) maxRetries(ctx context.Context) int {
+	opts := CallOptionsFromContext(ctx)
+	switch {
+	case opts.DisableRetries:
+		return 0
+	case opts.MaxRetries > 0:
+		return opts.MaxRetries
+	default:
+		return c.retryPolicy.MaxRetries
+	}
+}
+
+// remainingBudget returns the time left on ctx's deadline after the reserve,
+// and false when ctx has no deadline
+func (c *ServiceClient) remainingBudget(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	reserve := c.config.DeadlineReserve
+	if opts := CallOptionsFromContext(ctx); opts.DeadlineReserve > 0 {
+		reserve = opts.DeadlineReserve
+	}
+	return time.Until(deadline) - reserve, true
+}
+
+// attemptTimeout returns the timeout for the next attempt: the requested
+// timeout capped by the remaining budget
+func (c *ServiceClient) attemptTimeout(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	remaining, ok := c.remainingBudget(ctx)
+	if !ok {
+		return timeout, nil
+	}
+	if remaining < c.config.MinAttemptTimeout {
+		return 0, ErrCallBudgetExhausted
+	}
+	if remaining < timeout {
+		return remaining, nil
+	}
+	return timeout, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowHTTPClient fails with 503 or blocks until the attempt's context ends
+type slowHTTPClient struct {
+	block     bool
+	attempts  int
+	deadlines []time.Duration
+}
+
+func (c *slowHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.attempts++
+	if deadline, ok := req.Context().Deadline(); ok {
+		c.deadlines = append(c.deadlines, time.Until(deadline))
+	}
+	if c.block {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return &http.Response{StatusCode: 503, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func newTestServiceClient(httpClient HTTPClient) *ServiceClient {
+	client := NewServiceClient(nil, ServiceClientConfig{
+		RetryBackoff:      time.Millisecond,
+		DeadlineReserve:   50 * time.Millisecond,
+		MinAttemptTimeout: 20 * time.Millisecond,
+	})
+	client.httpClient = httpClient
+	return client
+}
+
+var testInstance = &ServiceInstance{
+	ID:       "payments-1",
+	Endpoint: ServiceEndpoint{Protocol: "http", Host: "payments.internal", Port: 8080},
+}
+
+func TestCallOptionsRetries(t *testing.T) {
+	httpClient := &slowHTTPClient{}
+	client := newTestServiceClient(httpClient)
+	request := &ServiceRequest{ServiceName: "payments", Method: "GET", Path: "/health", Timeout: time.Second}
+
+	_, err := client.executeRequest(context.Background(), testInstance, request)
+	require.Error(t, err)
+	assert.Equal(t, 4, httpClient.attempts)
+
+	httpClient.attempts = 0
+	ctx := WithCallOptions(context.Background(), CallOptions{MaxRetries: 1})
+	_, err = client.executeRequest(ctx, testInstance, request)
+	require.Error(t, err)
+	assert.Equal(t, 2, httpClient.attempts)
+
+	httpClient.attempts = 0
+	ctx = WithCallOptions(context.Background(), CallOptions{MaxRetries: 5, DisableRetries: true})
+	_, err = client.executeRequest(ctx, testInstance, request)
+	require.Error(t, err)
+	assert.Equal(t, 1, httpClient.attempts)
+}
+
+func TestCallOptionsDeadlineBudget(t *testing.T) {
+	httpClient := &slowHTTPClient{block: true}
+	client := newTestServiceClient(httpClient)
+	request := &ServiceRequest{ServiceName: "payments", Method: "GET", Path: "/slow", Timeout: 10 * time.Second}
+
+	// The invocation has 200ms left; attempts are capped to leave the reserve
+	invocation, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.executeRequest(invocation, testInstance, request)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+	assert.NoError(t, invocation.Err(), "the call must return before the invocation deadline")
+	require.NotEmpty(t, httpClient.deadlines)
+	assert.LessOrEqual(t, httpClient.deadlines[0], 150*time.Millisecond)
+
+	// With the budget spent no attempt is made
+	httpClient.attempts = 0
+	tight, cancelTight := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancelTight()
+	_, err = client.executeRequest(tight, testInstance, request)
+	assert.ErrorIs(t, err, ErrCallBudgetExhausted)
+	assert.Equal(t, 0, httpClient.attempts)
+}
+
+func TestCallOptionsTimeoutOverride(t *testing.T) {
+	assert.Equal(t, CallOptions{}, CallOptionsFromContext(context.Background()))
+
+	ctx := WithCallOptions(context.Background(), CallOptions{Timeout: 2 * time.Second, DeadlineReserve: time.Second})
+	opts := CallOptionsFromContext(ctx)
+	assert.Equal(t, 2*time.Second, opts.Timeout)
+
+	client := newTestServiceClient(&slowHTTPClient{})
+	deadlineCtx, cancel := context.WithTimeout(ctx, 1500*time.Millisecond)
+	defer cancel()
+	remaining, ok := client.remainingBudget(deadlineCtx)
+	require.True(t, ok)
+	assert.LessOrEqual(t, remaining, 500*time.Millisecond)
+}
+
+func TestServerErrorsRetryOnlyIdempotentRequests(t *testing.T) {
+	httpClient := &slowHTTPClient{}
+	client := newTestServiceClient(httpClient)
+
+	// A POST may have been acted on before the server failed
+	request := &ServiceRequest{ServiceName: "payments", Method: "POST", Path: "/charges", Timeout: time.Second}
+	_, err := client.executeRequest(context.Background(), testInstance, request)
+	require.Error(t, err)
+	assert.Equal(t, 1, httpClient.attempts)
+
+	// An idempotency key makes it safe to repeat
+	httpClient.attempts = 0
+	request.Headers = map[string]string{"Idempotency-Key": "charge-1"}
+	_, err = client.executeRequest(context.Background(), testInstance, request)
+	require.Error(t, err)
+	assert.Equal(t, 4, httpClient.attempts)
+
+	// Error text alone never makes an attempt retryable
+	assert.False(t, client.isRetryableError(errors.New("server error status code: 503")))
+	assert.False(t, client.isRetryableError(errors.New("dial failed: timeout")))
+	assert.True(t, client.isRetryableError(errors.New("timeout waiting for response")))
+}
+
+// failingDiscovery fails every lookup
+type failingDiscovery struct{}
+
+func (failingDiscovery) Register(context.Context, *ServiceConfig) error { return nil }
+func (failingDiscovery) Deregister(context.Context, string) error       { return nil }
+func (failingDiscovery) Discover(context.Context, string) ([]*ServiceInstance, error) {
+	return nil, errors.New("unavailable")
+}
+func (failingDiscovery) Watch(context.Context, string) (<-chan []*ServiceInstance, error) {
+	return nil, nil
+}
+func (failingDiscovery) HealthCheck(context.Context, *ServiceInstance) (*HealthStatus, error) {
+	return nil, nil
+}
+
+func TestCallLeavesRequestUnchanged(t *testing.T) {
+	client := newTestServiceClient(&slowHTTPClient{})
+	client.registry = NewServiceRegistry(RegistryConfig{}, failingDiscovery{}, nil)
+
+	request := &ServiceRequest{ServiceName: "payments", Method: "GET", Path: "/health"}
+	ctx := WithCallOptions(context.Background(), CallOptions{Timeout: 2 * time.Second})
+	_, err := client.Call(ctx, request)
+	require.Error(t, err)
+
+	assert.Equal(t, &ServiceRequest{ServiceName: "payments", Method: "GET", Path: "/health"}, request)
+}
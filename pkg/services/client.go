@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/id"
 )
 
 // Tracer interface for distributed tracing (placeholder)
@@ -64,6 +66,34 @@ type ServiceClientConfig struct {
 	EnableCircuitBreaker bool          `json:"enable_circuit_breaker"`
 	TenantIsolation      bool          `json:"tenant_isolation"`
 	UserAgent            string        `json:"user_agent"`
+
+	// DeadlineReserve is the time left on the caller's deadline (usually the
+	// Lambda invocation deadline) that calls never use, so the handler still
+	// has time to respond when a downstream is slow (default: 250ms)
+	DeadlineReserve time.Duration `json:"deadline_reserve"`
+
+	// MinAttemptTimeout is the shortest attempt worth making; calls and
+	// retries are skipped when less budget remains (default: 100ms)
+	MinAttemptTimeout time.Duration `json:"min_attempt_timeout"`
+
+	// TokenExchanger exchanges the user token set with WithSubjectToken for
+	// a token for the target service, sent as the Authorization header.
+	// Wrap it with NewCachingTokenExchanger to reuse exchanged tokens.
+	TokenExchanger TokenExchanger `json:"-"`
+
+	// TokenAudiences maps service names to the audience requested for them
+	// (default: the service name)
+	TokenAudiences map[string]string `json:"token_audiences,omitempty"`
+
+	// Authorizer authorizes calls not made on behalf of a user, such as
+	// calls to third-party APIs with a ClientCredentialsManager
+	Authorizer RequestAuthorizer `json:"-"`
+}
+
+// RequestAuthorizer supplies the Authorization header value for a service
+// call, or "" to send none
+type RequestAuthorizer interface {
+	Authorization(ctx context.Context, request *ServiceRequest) (string, error)
 }
 
 // ServiceRequest represents a service call request
@@ -124,6 +154,14 @@ func NewServiceClient(registry *ServiceRegistry, config ServiceClientConfig) *Se
 		config.UserAgent = "lift-service-client/1.0"
 	}
 
+	if config.DeadlineReserve == 0 {
+		config.DeadlineReserve = 250 * time.Millisecond
+	}
+
+	if config.MinAttemptTimeout == 0 {
+		config.MinAttemptTimeout = 100 * time.Millisecond
+	}
+
 	client := &ServiceClient{
 		registry:   registry,
 		config:     config,
@@ -145,16 +183,28 @@ func NewServiceClient(registry *ServiceRegistry, config ServiceClientConfig) *Se
 func (c *ServiceClient) Call(ctx context.Context, request *ServiceRequest) (*ServiceResponse, error) {
 	start := time.Now()
 
+	// Defaults are filled in on a copy so the caller's request is left as is
+	req := *request
+	request = &req
+
 	// Set defaults
 	if request.LoadBalanceStrategy == "" {
 		request.LoadBalanceStrategy = RoundRobin
 	}
 
+	// Per-call overrides from the context apply when the request doesn't set its own
+	overrides := CallOptionsFromContext(ctx)
+	if request.Timeout == 0 {
+		request.Timeout = overrides.Timeout
+	}
 	if request.Timeout == 0 {
 		request.Timeout = c.config.DefaultTimeout
 	}
 
-	// Add request ID if not provided
+	// Propagate the caller's request ID, generating one if there is none
+	if request.RequestID == "" {
+		request.RequestID = lift.RequestIDFromContext(ctx)
+	}
 	if request.RequestID == "" {
 		request.RequestID = c.generateRequestID()
 	}
@@ -209,35 +259,63 @@ func (c *ServiceClient) executeRequest(ctx context.Context, instance *ServiceIns
 	)
 
 	// Prepare request body
-	var bodyReader io.Reader
+	var bodyBytes []byte
 	if request.Body != nil {
-		bodyBytes, err := json.Marshal(request.Body)
+		var err error
+		bodyBytes, err = json.Marshal(request.Body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, request.Method, url, bodyReader)
+	// Calls on behalf of a user carry a token exchanged for this service
+	authorization, err := c.authorize(ctx, request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, fmt.Errorf("authorization for %s failed: %w", request.ServiceName, err)
 	}
 
-	// Set headers
-	c.setRequestHeaders(httpReq, request, instance)
-
-	// Execute with retry policy
+	// Execute with retry policy, building a new request for each attempt so
+	// every attempt gets its own timeout and a fresh body
 	var response *ServiceResponse
-	err = c.executeWithRetry(ctx, func() error {
+	err = c.executeWithRetry(ctx, c.maxRetries(ctx), func() error {
+		timeout, err := c.attemptTimeout(ctx, request.Timeout)
+		if err != nil {
+			return err
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var bodyReader io.Reader
+		if bodyBytes != nil {
+			bodyReader = bytes.NewReader(bodyBytes)
+		}
+		httpReq, err := http.NewRequestWithContext(attemptCtx, request.Method, url, bodyReader)
+		if err != nil {
+			return fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+		c.setRequestHeaders(httpReq, request, instance)
+		if authorization != "" {
+			httpReq.Header.Set("Authorization", authorization)
+		}
+
 		resp, execErr := c.httpClient.Do(httpReq)
 		if execErr != nil {
+			// An attempt that ran out of its own time is retryable as long as
+			// the caller's context is still live and the request is safe to
+			// repeat
+			if attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				err := fmt.Errorf("attempt timeout after %s: %w", timeout, execErr)
+				if isIdempotent(request) {
+					return &retryableError{err: err}
+				}
+				return err
+			}
 			return execErr
 		}
 		defer resp.Body.Close()
 
 		// Read response body
-		bodyBytes, readErr := io.ReadAll(resp.Body)
+		respBody, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
 			return fmt.Errorf("failed to read response body: %w", readErr)
 		}
@@ -246,7 +324,7 @@ func (c *ServiceClient) executeRequest(ctx context.Context, instance *ServiceIns
 		response = &ServiceResponse{
 			StatusCode: resp.StatusCode,
 			Headers:    make(map[string]string),
-			Body:       bodyBytes,
+			Body:       respBody,
 			Duration:   time.Since(start),
 			Instance:   instance,
 			Metadata:   make(map[string]any),
@@ -259,9 +337,15 @@ func (c *ServiceClient) executeRequest(ctx context.Context, instance *ServiceIns
 			}
 		}
 
-		// Check if response indicates an error that should be retried
+		// Check if response indicates an error that should be retried. The
+		// server may have acted on the request, so only requests that are
+		// safe to repeat are retried.
 		if c.isRetryableStatusCode(resp.StatusCode) {
-			return fmt.Errorf("retryable status code: %d", resp.StatusCode)
+			err := fmt.Errorf("server error status code: %d", resp.StatusCode)
+			if isIdempotent(request) {
+				return &retryableError{err: err}
+			}
+			return err
 		}
 
 		return nil
@@ -283,7 +367,7 @@ func (c *ServiceClient) setRequestHeaders(req *http.Request, request *ServiceReq
 
 	// Set request ID for tracing
 	if request.RequestID != "" {
-		req.Header.Set("X-Request-ID", request.RequestID)
+		req.Header.Set(lift.HeaderRequestID, request.RequestID)
 	}
 
 	// Set tenant context
@@ -320,11 +404,11 @@ func (c *ServiceClient) addTracingHeaders(req *http.Request, request *ServiceReq
 }
 
 // executeWithRetry executes a function with retry logic
-func (c *ServiceClient) executeWithRetry(ctx context.Context, fn func() error) error {
+func (c *ServiceClient) executeWithRetry(ctx context.Context, maxRetries int, fn func() error) error {
 	var lastErr error
 	backoff := c.retryPolicy.InitialBackoff
 
-	for attempt := 0; attempt <= c.retryPolicy.MaxRetries; attempt++ {
+	for attempt := 0; attempt <= maxRetries; attempt++ {
 		// Execute function
 		err := fn()
 		if err == nil {
@@ -334,7 +418,12 @@ func (c *ServiceClient) executeWithRetry(ctx context.Context, fn func() error) e
 		lastErr = err
 
 		// Check if we should retry
-		if attempt == c.retryPolicy.MaxRetries || !c.isRetryableError(err) {
+		if attempt == maxRetries || !c.isRetryableError(err) {
+			break
+		}
+
+		// Don't retry when waiting would leave too little budget for another attempt
+		if remaining, ok := c.remainingBudget(ctx); ok && remaining-backoff < c.config.MinAttemptTimeout {
 			break
 		}
 
@@ -368,6 +457,11 @@ func (c *ServiceClient) isRetryableStatusCode(statusCode int) bool {
 
 // isRetryableError checks if an error is retryable
 func (c *ServiceClient) isRetryableError(err error) bool {
+	var retryable *retryableError
+	if errors.As(err, &retryable) {
+		return true
+	}
+
 	errStr := err.Error()
 	for _, retryableErr := range c.retryPolicy.RetryableErrors {
 		if contains(errStr, retryableErr) {
@@ -377,6 +471,31 @@ func (c *ServiceClient) isRetryableError(err error) bool {
 	return false
 }
 
+// retryableError marks an attempt failure that is safe to retry: a retryable
+// status code or an attempt timeout on an idempotent request
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+// isIdempotent reports whether a request can be repeated without side
+// effects: an idempotent method, or any method with an idempotency key
+func isIdempotent(request *ServiceRequest) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	for key, value := range request.Headers {
+		if value != "" && http.CanonicalHeaderKey(key) == "Idempotency-Key" {
+			return true
+		}
+	}
+	return false
+}
+
 // recordMetrics records service call metrics
 func (c *ServiceClient) recordMetrics(serviceName, status string, duration time.Duration, err error) {
 	if !c.config.EnableMetrics || c.metrics == nil {
@@ -406,17 +525,17 @@ func (c *ServiceClient) recordMetrics(serviceName, status string, duration time.
 
 // generateRequestID generates a unique request ID
 func (c *ServiceClient) generateRequestID() string {
-	return fmt.Sprintf("req_%d", time.Now().UnixNano())
+	return id.New("req")
 }
 
 // generateTraceID generates a trace ID for distributed tracing
 func (c *ServiceClient) generateTraceID() string {
-	return fmt.Sprintf("trace_%d", time.Now().UnixNano())
+	return id.New("trace")
 }
 
 // generateSpanID generates a span ID for distributed tracing
 func (c *ServiceClient) generateSpanID() string {
-	return fmt.Sprintf("span_%d", time.Now().UnixNano())
+	return id.New("span")
 }
 
 // Typed service client interfaces
@@ -634,7 +753,7 @@ func (u *UserServiceClient) ListUsers(ctx context.Context, filters *UserFilters)
 
 // Utility functions
 
-// contains checks if a string contains a substring
+// contains checks if a string starts with a substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[:len(substr)] == substr
 }
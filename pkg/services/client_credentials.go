@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClientCredentials is returned when a token endpoint refuses a client
+// credentials grant
+var ErrClientCredentials = errors.New("client credentials grant failed")
+
+// ClientCredentials identify this service to a third party's OAuth 2.0
+// token endpoint
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Params are extra form parameters some providers require, such as
+	// audience or resource
+	Params map[string]string
+}
+
+// ClientCredentialsSource resolves the credentials a tenant uses for an
+// integration, typically from a secrets store. The tenant is "" for calls
+// made without one.
+type ClientCredentialsSource func(ctx context.Context, tenantID, service string) (*ClientCredentials, error)
+
+// ClientCredentialsConfig configures a ClientCredentialsManager
+type ClientCredentialsConfig struct {
+	// Credentials resolves credentials per tenant and service (required)
+	Credentials ClientCredentialsSource
+	// RefreshBefore is how long before expiry a token is refreshed
+	// (default: 1 minute)
+	RefreshBefore time.Duration
+	// DefaultTTL bounds caching of tokens issued without an expiry
+	// (default: 5 minutes)
+	DefaultTTL time.Duration
+	// HTTPClient sends token requests (default: a secure production client)
+	HTTPClient HTTPClient
+	// Metrics receives service_client.token_refresh_failures counts
+	Metrics MetricsCollector
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// ClientCredentialsManager fetches, caches, and refreshes client
+// credentials tokens for outbound integrations, per tenant and service.
+// Set it as a ServiceClient's Authorizer to authorize every call with the
+// tenant's token for the target service.
+type ClientCredentialsManager struct {
+	config ClientCredentialsConfig
+
+	mu      sync.Mutex
+	tokens  map[string]*ExchangedToken
+	refresh map[string]*sync.Mutex
+}
+
+// NewClientCredentialsManager creates a client credentials token manager
+func NewClientCredentialsManager(config ClientCredentialsConfig) (*ClientCredentialsManager, error) {
+	if config.Credentials == nil {
+		return nil, fmt.Errorf("client credentials manager requires a credentials source")
+	}
+	if config.RefreshBefore == 0 {
+		config.RefreshBefore = time.Minute
+	}
+	if config.DefaultTTL == 0 {
+		config.DefaultTTL = 5 * time.Minute
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = NewSecureHTTPClient(ProductionHTTPClientConfig())
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &ClientCredentialsManager{
+		config:  config,
+		tokens:  make(map[string]*ExchangedToken),
+		refresh: make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// Token returns the tenant's token for service, fetching a new one when
+// none is cached or the cached one is due for refresh. A token that is due
+// but not yet expired is still returned if the refresh fails, so a token
+// endpoint outage only fails calls once tokens actually expire.
+func (m *ClientCredentialsManager) Token(ctx context.Context, tenantID, service string) (*ExchangedToken, error) {
+	key := tenantID + "|" + service
+
+	if token := m.cached(key); token != nil && m.config.Now().Add(m.config.RefreshBefore).Before(token.ExpiresAt) {
+		return token, nil
+	}
+
+	// One refresh per tenant and service at a time; callers waiting on it
+	// use the token it fetched
+	lock := m.refreshLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	cached := m.cached(key)
+	now := m.config.Now()
+	if cached != nil && now.Add(m.config.RefreshBefore).Before(cached.ExpiresAt) {
+		return cached, nil
+	}
+
+	token, err := m.fetch(ctx, tenantID, service)
+	if err != nil {
+		m.recordRefreshFailure(service, err)
+		if cached != nil && now.Before(cached.ExpiresAt) {
+			return cached, nil
+		}
+		return nil, err
+	}
+	if token.ExpiresAt.IsZero() {
+		token.ExpiresAt = now.Add(m.config.DefaultTTL)
+	}
+
+	m.mu.Lock()
+	m.tokens[key] = token
+	m.mu.Unlock()
+	return token, nil
+}
+
+// Invalidate drops the tenant's cached token for service, such as after the
+// service rejects it as revoked
+func (m *ClientCredentialsManager) Invalidate(tenantID, service string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tokens, tenantID+"|"+service)
+}
+
+// Authorization implements RequestAuthorizer with the request tenant's
+// token for the request's service
+func (m *ClientCredentialsManager) Authorization(ctx context.Context, request *ServiceRequest) (string, error) {
+	token, err := m.Token(ctx, request.TenantID, request.ServiceName)
+	if err != nil {
+		return "", err
+	}
+	return token.TokenType + " " + token.AccessToken, nil
+}
+
+func (m *ClientCredentialsManager) cached(key string) *ExchangedToken {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tokens[key]
+}
+
+func (m *ClientCredentialsManager) refreshLock(key string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.refresh[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.refresh[key] = lock
+	}
+	return lock
+}
+
+// fetch requests a new token with the tenant's credentials
+func (m *ClientCredentialsManager) fetch(ctx context.Context, tenantID, service string) (*ExchangedToken, error) {
+	creds, err := m.config.Credentials(ctx, tenantID, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve client credentials: %w", err)
+	}
+	if creds == nil || creds.TokenURL == "" {
+		return nil, fmt.Errorf("%w: no credentials for %s", ErrClientCredentials, service)
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(creds.Scopes) > 0 {
+		form.Set("scope", strings.Join(creds.Scopes, " "))
+	}
+	for name, value := range creds.Params {
+		form.Set(name, value)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, creds.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(creds.ClientID), url.QueryEscape(creds.ClientSecret))
+
+	resp, err := m.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return readTokenResponse(resp, ErrClientCredentials, m.config.Now())
+}
+
+func (m *ClientCredentialsManager) recordRefreshFailure(service string, err error) {
+	if m.config.Metrics == nil {
+		return
+	}
+	reason := "request"
+	if errors.Is(err, ErrClientCredentials) {
+		reason = "refused"
+	}
+	m.config.Metrics.Counter("service_client.token_refresh_failures", map[string]string{
+		"service": service,
+		"reason":  reason,
+	}).Inc()
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingMetrics counts counter increments by name
+type countingMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int
+	tags   []map[string]string
+}
+
+func (m *countingMetrics) Counter(name string, tags map[string]string) Counter {
+	return countingCounter{m: m, name: name, tags: tags}
+}
+func (m *countingMetrics) Histogram(string, map[string]string) Histogram { return nil }
+func (m *countingMetrics) Gauge(string, map[string]string) Gauge         { return nil }
+func (m *countingMetrics) Flush() error                                  { return nil }
+
+type countingCounter struct {
+	m    *countingMetrics
+	name string
+	tags map[string]string
+}
+
+func (c countingCounter) Inc() {
+	c.m.mu.Lock()
+	defer c.m.mu.Unlock()
+	if c.m.counts == nil {
+		c.m.counts = make(map[string]int)
+	}
+	c.m.counts[c.name]++
+	c.m.tags = append(c.m.tags, c.tags)
+}
+
+func tenantCredentials(ctx context.Context, tenantID, service string) (*ClientCredentials, error) {
+	if tenantID == "unknown" {
+		return nil, errors.New("no integration configured")
+	}
+	return &ClientCredentials{
+		TokenURL:     "https://auth." + service + ".example/oauth/token",
+		ClientID:     tenantID + "-client",
+		ClientSecret: tenantID + "-secret",
+		Scopes:       []string{"charges:read", "charges:write"},
+		Params:       map[string]string{"audience": service},
+	}, nil
+}
+
+func TestClientCredentialsManager(t *testing.T) {
+	server := &tokenServer{status: 200, response: `{"access_token":"cc-token","token_type":"bearer","expires_in":600}`}
+	metrics := &countingMetrics{}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	manager, err := NewClientCredentialsManager(ClientCredentialsConfig{
+		Credentials: tenantCredentials,
+		HTTPClient:  server,
+		Metrics:     metrics,
+		Now:         func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	token, err := manager.Token(context.Background(), "tenant-a", "processor")
+	require.NoError(t, err)
+	assert.Equal(t, &ExchangedToken{AccessToken: "cc-token", TokenType: "Bearer", ExpiresAt: now.Add(10 * time.Minute)}, token)
+
+	form := server.forms[0]
+	assert.Equal(t, "client_credentials", form.Get("grant_type"))
+	assert.Equal(t, "charges:read charges:write", form.Get("scope"))
+	assert.Equal(t, "processor", form.Get("audience"))
+	assert.Equal(t, "tenant-a-client:tenant-a-secret", server.auth[0])
+
+	// Cached per tenant until the refresh window
+	_, err = manager.Token(context.Background(), "tenant-a", "processor")
+	require.NoError(t, err)
+	assert.Len(t, server.forms, 1)
+	_, err = manager.Token(context.Background(), "tenant-b", "processor")
+	require.NoError(t, err)
+	assert.Len(t, server.forms, 2)
+	assert.Equal(t, "tenant-b-client:tenant-b-secret", server.auth[1])
+
+	// A failed refresh keeps serving the unexpired token and is counted
+	now = now.Add(9*time.Minute + 30*time.Second)
+	server.status, server.response = 503, `{"error":"temporarily_unavailable"}`
+	token, err = manager.Token(context.Background(), "tenant-a", "processor")
+	require.NoError(t, err)
+	assert.Equal(t, "cc-token", token.AccessToken)
+	assert.Equal(t, 1, metrics.counts["service_client.token_refresh_failures"])
+	assert.Equal(t, map[string]string{"service": "processor", "reason": "refused"}, metrics.tags[0])
+
+	// Once expired, the failure is returned
+	now = now.Add(time.Minute)
+	_, err = manager.Token(context.Background(), "tenant-a", "processor")
+	assert.ErrorIs(t, err, ErrClientCredentials)
+	assert.Equal(t, 2, metrics.counts["service_client.token_refresh_failures"])
+
+	// Successful refresh replaces the token
+	server.status, server.response = 200, `{"access_token":"cc-token-2","expires_in":600}`
+	token, err = manager.Token(context.Background(), "tenant-a", "processor")
+	require.NoError(t, err)
+	assert.Equal(t, "cc-token-2", token.AccessToken)
+
+	manager.Invalidate("tenant-a", "processor")
+	forms := len(server.forms)
+	_, err = manager.Token(context.Background(), "tenant-a", "processor")
+	require.NoError(t, err)
+	assert.Len(t, server.forms, forms+1, "invalidated tokens are fetched again")
+
+	_, err = manager.Token(context.Background(), "unknown", "processor")
+	assert.ErrorContains(t, err, "no integration configured")
+	assert.Equal(t, map[string]string{"service": "processor", "reason": "request"}, metrics.tags[len(metrics.tags)-1])
+}
+
+func TestServiceClientAuthorizer(t *testing.T) {
+	server := &tokenServer{status: 200, response: `{"access_token":"cc-token","token_type":"Bearer","expires_in":600}`}
+	manager, err := NewClientCredentialsManager(ClientCredentialsConfig{Credentials: tenantCredentials, HTTPClient: server})
+	require.NoError(t, err)
+
+	httpClient := &headerHTTPClient{}
+	client := NewServiceClient(nil, ServiceClientConfig{Authorizer: manager})
+	client.httpClient = httpClient
+
+	_, err = client.executeRequest(context.Background(), testInstance, &ServiceRequest{
+		ServiceName: "processor", TenantID: "tenant-a", Method: "POST", Path: "/charges", Timeout: time.Second,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer cc-token", httpClient.headers[0].Get("Authorization"))
+	assert.Equal(t, "tenant-a-client:tenant-a-secret", server.auth[0])
+
+	_, err = client.executeRequest(context.Background(), testInstance, &ServiceRequest{
+		ServiceName: "processor", TenantID: "unknown", Method: "POST", Path: "/charges", Timeout: time.Second,
+	})
+	assert.ErrorContains(t, err, "authorization for processor failed")
+}
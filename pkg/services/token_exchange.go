@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// OAuth 2.0 token exchange (RFC 8693) identifiers
+const (
+	TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	AccessTokenType        = "urn:ietf:params:oauth:token-type:access_token"
+	JWTTokenType           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// ErrTokenExchange is returned when the authorization server refuses an
+// exchange
+var ErrTokenExchange = errors.New("token exchange failed")
+
+// ExchangedToken is a token issued for a downstream audience
+type ExchangedToken struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// TokenExchanger exchanges an inbound user token for a token accepted by a
+// downstream audience, on behalf of the same user
+type TokenExchanger interface {
+	Exchange(ctx context.Context, subjectToken, audience string) (*ExchangedToken, error)
+}
+
+// OAuthTokenExchangerConfig configures an OAuthTokenExchanger
+type OAuthTokenExchangerConfig struct {
+	// TokenURL is the authorization server's token endpoint
+	TokenURL string
+	// ClientID and ClientSecret authenticate this service to the
+	// authorization server with HTTP basic auth
+	ClientID     string
+	ClientSecret string
+	// Scope optionally narrows the exchanged token
+	Scope string
+	// SubjectTokenType describes inbound tokens (default: AccessTokenType)
+	SubjectTokenType string
+	// HTTPClient sends token requests (default: a secure production client)
+	HTTPClient HTTPClient
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// OAuthTokenExchanger performs RFC 8693 token exchange against an OAuth 2.0
+// authorization server
+type OAuthTokenExchanger struct {
+	config OAuthTokenExchangerConfig
+}
+
+// NewOAuthTokenExchanger creates a token exchanger
+func NewOAuthTokenExchanger(config OAuthTokenExchangerConfig) (*OAuthTokenExchanger, error) {
+	if config.TokenURL == "" {
+		return nil, fmt.Errorf("token exchange requires a token URL")
+	}
+	if config.SubjectTokenType == "" {
+		config.SubjectTokenType = AccessTokenType
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = NewSecureHTTPClient(ProductionHTTPClientConfig())
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &OAuthTokenExchanger{config: config}, nil
+}
+
+// Exchange requests a token for audience on behalf of the subject token
+func (e *OAuthTokenExchanger) Exchange(ctx context.Context, subjectToken, audience string) (*ExchangedToken, error) {
+	form := url.Values{
+		"grant_type":           {TokenExchangeGrantType},
+		"subject_token":        {subjectToken},
+		"subject_token_type":   {e.config.SubjectTokenType},
+		"requested_token_type": {AccessTokenType},
+		"audience":             {audience},
+	}
+	if e.config.Scope != "" {
+		form.Set("scope", e.config.Scope)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if e.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(e.config.ClientID), url.QueryEscape(e.config.ClientSecret))
+	}
+
+	resp, err := e.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return readTokenResponse(resp, ErrTokenExchange, e.config.Now())
+}
+
+// readTokenResponse parses an OAuth 2.0 token endpoint response, wrapping
+// refusals in failure
+func readTokenResponse(resp *http.Response, failure error, now time.Time) (*ExchangedToken, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: status %d", failure, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		if result.Error != "" {
+			return nil, fmt.Errorf("%w: %s: %s", failure, result.Error, result.ErrorDescription)
+		}
+		return nil, fmt.Errorf("%w: status %d", failure, resp.StatusCode)
+	}
+
+	token := &ExchangedToken{AccessToken: result.AccessToken, TokenType: result.TokenType}
+	if token.TokenType == "" || strings.EqualFold(token.TokenType, "bearer") || strings.EqualFold(token.TokenType, "N_A") {
+		token.TokenType = "Bearer"
+	}
+	if result.ExpiresIn > 0 {
+		token.ExpiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// CachingTokenExchanger caches exchanged tokens per subject token and
+// audience until shortly before they expire, so a handler making several
+// downstream calls exchanges once per audience
+type CachingTokenExchanger struct {
+	exchanger TokenExchanger
+	// skew is how long before expiry a cached token is replaced
+	skew time.Duration
+	// ttl bounds caching of tokens issued without an expiry
+	ttl time.Duration
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]*ExchangedToken
+}
+
+// NewCachingTokenExchanger wraps an exchanger with a cache. Tokens are
+// replaced 30 seconds before expiry; tokens without an expiry are cached
+// for a minute.
+func NewCachingTokenExchanger(exchanger TokenExchanger) *CachingTokenExchanger {
+	return &CachingTokenExchanger{
+		exchanger: exchanger,
+		skew:      30 * time.Second,
+		ttl:       time.Minute,
+		now:       time.Now,
+		tokens:    make(map[string]*ExchangedToken),
+	}
+}
+
+// Exchange returns a cached token for the subject and audience, exchanging
+// a new one when none is fresh
+func (c *CachingTokenExchanger) Exchange(ctx context.Context, subjectToken, audience string) (*ExchangedToken, error) {
+	// Key on a digest so raw user tokens aren't held as map keys
+	digest := sha256.Sum256([]byte(subjectToken))
+	key := hex.EncodeToString(digest[:]) + "|" + audience
+	now := c.now()
+
+	c.mu.Lock()
+	if token, ok := c.tokens[key]; ok && now.Add(c.skew).Before(token.ExpiresAt) {
+		c.mu.Unlock()
+		return token, nil
+	}
+	c.mu.Unlock()
+
+	token, err := c.exchanger.Exchange(ctx, subjectToken, audience)
+	if err != nil {
+		return nil, err
+	}
+	cached := *token
+	if cached.ExpiresAt.IsZero() {
+		cached.ExpiresAt = now.Add(c.ttl + c.skew)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, t := range c.tokens {
+		if !now.Before(t.ExpiresAt) {
+			delete(c.tokens, k)
+		}
+	}
+	c.tokens[key] = &cached
+	return token, nil
+}
+
+type subjectTokenKey struct{}
+
+// WithSubjectToken returns a context whose service calls are made on behalf
+// of the user the token was issued to
+func WithSubjectToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, subjectTokenKey{}, token)
+}
+
+// SubjectTokenFromContext returns the token set with WithSubjectToken
+func SubjectTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(subjectTokenKey{}).(string)
+	return token
+}
+
+// OnBehalfOf forwards the request's bearer token to service calls made with
+// ctx.Context, so a ServiceClient with a TokenExchanger calls downstream
+// services as the user. Register it after authentication.
+func OnBehalfOf() lift.Middleware {
+	return func(next lift.Handler) lift.Handler {
+		return lift.HandlerFunc(func(ctx *lift.Context) error {
+			if token, ok := strings.CutPrefix(ctx.Header("Authorization"), "Bearer "); ok && token != "" {
+				ctx.Context = WithSubjectToken(ctx.Context, token)
+			}
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// authorize returns the Authorization header value for a call: a token
+// exchanged for the request's service when the call is made on behalf of a
+// user, otherwise the configured Authorizer's, or "" for neither
+func (c *ServiceClient) authorize(ctx context.Context, request *ServiceRequest) (string, error) {
+	if request.Headers["Authorization"] != "" {
+		return "", nil
+	}
+	subject := SubjectTokenFromContext(ctx)
+	if c.config.TokenExchanger == nil || subject == "" {
+		if c.config.Authorizer != nil {
+			return c.config.Authorizer.Authorization(ctx, request)
+		}
+		return "", nil
+	}
+
+	audience := c.config.TokenAudiences[request.ServiceName]
+	if audience == "" {
+		audience = request.ServiceName
+	}
+	token, err := c.config.TokenExchanger.Exchange(ctx, subject, audience)
+	if err != nil {
+		return "", err
+	}
+	return token.TokenType + " " + token.AccessToken, nil
+}
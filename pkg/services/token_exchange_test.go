@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/lift/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenServer answers token exchange requests and records them
+type tokenServer struct {
+	forms    []url.Values
+	auth     []string
+	status   int
+	response string
+}
+
+func (s *tokenServer) Do(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	form, _ := url.ParseQuery(string(body))
+	s.forms = append(s.forms, form)
+	user, pass, _ := req.BasicAuth()
+	s.auth = append(s.auth, user+":"+pass)
+
+	status, response := s.status, s.response
+	if status == 0 {
+		status = 200
+		response = `{"access_token":"downstream-` + form.Get("audience") + `","issued_token_type":"urn:ietf:params:oauth:token-type:access_token","token_type":"Bearer","expires_in":300}`
+	}
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(response))}, nil
+}
+
+func TestOAuthTokenExchanger(t *testing.T) {
+	server := &tokenServer{}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	exchanger, err := NewOAuthTokenExchanger(OAuthTokenExchangerConfig{
+		TokenURL:     "https://auth.internal/oauth2/token",
+		ClientID:     "orders-service",
+		ClientSecret: "s3cret",
+		Scope:        "payments:read",
+		HTTPClient:   server,
+		Now:          func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	token, err := exchanger.Exchange(context.Background(), "user-token", "payments")
+	require.NoError(t, err)
+	assert.Equal(t, &ExchangedToken{AccessToken: "downstream-payments", TokenType: "Bearer", ExpiresAt: now.Add(5 * time.Minute)}, token)
+
+	form := server.forms[0]
+	assert.Equal(t, TokenExchangeGrantType, form.Get("grant_type"))
+	assert.Equal(t, "user-token", form.Get("subject_token"))
+	assert.Equal(t, AccessTokenType, form.Get("subject_token_type"))
+	assert.Equal(t, "payments", form.Get("audience"))
+	assert.Equal(t, "payments:read", form.Get("scope"))
+	assert.Equal(t, "orders-service:s3cret", server.auth[0])
+
+	server.status, server.response = 400, `{"error":"invalid_target","error_description":"unknown audience"}`
+	_, err = exchanger.Exchange(context.Background(), "user-token", "ledger")
+	assert.ErrorIs(t, err, ErrTokenExchange)
+	assert.Contains(t, err.Error(), "invalid_target")
+
+	_, err = NewOAuthTokenExchanger(OAuthTokenExchangerConfig{})
+	assert.Error(t, err)
+}
+
+func TestCachingTokenExchanger(t *testing.T) {
+	server := &tokenServer{}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	exchanger, err := NewOAuthTokenExchanger(OAuthTokenExchangerConfig{
+		TokenURL:   "https://auth.internal/oauth2/token",
+		HTTPClient: server,
+		Now:        func() time.Time { return now },
+	})
+	require.NoError(t, err)
+	cache := NewCachingTokenExchanger(exchanger)
+	cache.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := cache.Exchange(ctx, "alice-token", "payments")
+		require.NoError(t, err)
+	}
+	assert.Len(t, server.forms, 1, "tokens are reused per subject and audience")
+
+	_, err = cache.Exchange(ctx, "alice-token", "ledger")
+	require.NoError(t, err)
+	_, err = cache.Exchange(ctx, "bob-token", "payments")
+	require.NoError(t, err)
+	assert.Len(t, server.forms, 3)
+
+	now = now.Add(4*time.Minute + 45*time.Second)
+	_, err = cache.Exchange(ctx, "alice-token", "payments")
+	require.NoError(t, err)
+	assert.Len(t, server.forms, 4, "tokens close to expiry are replaced")
+}
+
+// headerHTTPClient records request headers and answers 200
+type headerHTTPClient struct {
+	headers []http.Header
+}
+
+func (c *headerHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.headers = append(c.headers, req.Header.Clone())
+	return &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestServiceClientCallsOnBehalfOfUser(t *testing.T) {
+	server := &tokenServer{}
+	exchanger, err := NewOAuthTokenExchanger(OAuthTokenExchangerConfig{TokenURL: "https://auth.internal/oauth2/token", HTTPClient: server})
+	require.NoError(t, err)
+
+	httpClient := &headerHTTPClient{}
+	client := NewServiceClient(nil, ServiceClientConfig{
+		TokenExchanger: NewCachingTokenExchanger(exchanger),
+		TokenAudiences: map[string]string{"payments": "https://payments.internal"},
+	})
+	client.httpClient = httpClient
+	request := func() *ServiceRequest {
+		return &ServiceRequest{ServiceName: "payments", Method: "GET", Path: "/balances", Timeout: time.Second}
+	}
+
+	_, err = client.executeRequest(context.Background(), testInstance, request())
+	require.NoError(t, err)
+	assert.Empty(t, httpClient.headers[0].Get("Authorization"), "calls without a user token aren't exchanged")
+
+	// OnBehalfOf captures the inbound token for calls made with ctx.Context
+	var callCtx context.Context
+	handler := OnBehalfOf()(lift.HandlerFunc(func(ctx *lift.Context) error {
+		callCtx = ctx.Context
+		return nil
+	}))
+	liftCtx := lift.NewContext(context.Background(), lift.NewRequest(&adapters.Request{
+		Headers: map[string]string{"Authorization": "Bearer alice-token"},
+	}))
+	require.NoError(t, handler.Handle(liftCtx))
+	assert.Equal(t, "alice-token", SubjectTokenFromContext(callCtx))
+
+	_, err = client.executeRequest(callCtx, testInstance, request())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer downstream-https://payments.internal", httpClient.headers[1].Get("Authorization"))
+
+	explicit := request()
+	explicit.Headers = map[string]string{"Authorization": "Bearer service-token"}
+	_, err = client.executeRequest(callCtx, testInstance, explicit)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer service-token", httpClient.headers[2].Get("Authorization"), "explicit credentials win")
+	assert.Len(t, server.forms, 1)
+}
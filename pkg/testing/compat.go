@@ -0,0 +1,346 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+)
+
+// CompatOptions configures CompareApps
+type CompatOptions struct {
+	FixtureOptions
+	// IgnoreFields lists dotted JSON paths expected to differ between
+	// versions, such as "request_id" or "data.created_at". Array elements
+	// are addressed by index, e.g. "items.0.id".
+	IgnoreFields []string
+	// CompareHeaders lists response headers that must also match
+	CompareHeaders []string
+	// Runs replays each fixture this many times per app and compares the
+	// median latency (default: 1)
+	Runs int
+	// SlowerThreshold flags a fixture when the candidate's latency exceeds
+	// the baseline's by more than this fraction, e.g. 0.5 for 50%; 0
+	// disables latency regressions
+	SlowerThreshold float64
+}
+
+// CompatResult compares the baseline and candidate responses to one fixture
+type CompatResult struct {
+	Fixture         string `json:"fixture"`
+	Method          string `json:"method"`
+	Path            string `json:"path"`
+	BaselineStatus  int    `json:"baseline_status"`
+	CandidateStatus int    `json:"candidate_status"`
+	// Fields lists the JSON paths whose values differ; "body" means the
+	// bodies differ and at least one isn't JSON
+	Fields []string `json:"fields,omitempty"`
+	// Headers lists compared headers whose values differ
+	Headers          []string      `json:"headers,omitempty"`
+	BaselineLatency  time.Duration `json:"baseline_latency"`
+	CandidateLatency time.Duration `json:"candidate_latency"`
+	// Slower is set when the latency increase exceeds SlowerThreshold
+	Slower bool `json:"slower,omitempty"`
+	// Error is set when either app failed to handle the fixture
+	Error string `json:"error,omitempty"`
+}
+
+// StatusChanged reports whether the candidate returned a different status
+func (r CompatResult) StatusChanged() bool {
+	return r.BaselineStatus != r.CandidateStatus
+}
+
+// LatencyDelta returns the candidate's latency minus the baseline's
+func (r CompatResult) LatencyDelta() time.Duration {
+	return r.CandidateLatency - r.BaselineLatency
+}
+
+// Compatible reports whether the candidate's response matched the
+// baseline's. Latency regressions don't affect compatibility.
+func (r CompatResult) Compatible() bool {
+	return r.Error == "" && !r.StatusChanged() && len(r.Fields) == 0 && len(r.Headers) == 0
+}
+
+// CompatReport is the result of replaying a fixture corpus against two
+// versions of an app
+type CompatReport struct {
+	Results []CompatResult `json:"results"`
+}
+
+// Compatible reports whether every fixture produced matching responses
+func (r *CompatReport) Compatible() bool {
+	return len(r.Incompatible()) == 0
+}
+
+// Incompatible returns the fixtures whose responses differed
+func (r *CompatReport) Incompatible() []CompatResult {
+	var results []CompatResult
+	for _, result := range r.Results {
+		if !result.Compatible() {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// Slower returns the fixtures flagged as latency regressions
+func (r *CompatReport) Slower() []CompatResult {
+	var results []CompatResult
+	for _, result := range r.Results {
+		if result.Slower {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// MedianLatencyDelta returns the median of the per-fixture latency deltas
+func (r *CompatReport) MedianLatencyDelta() time.Duration {
+	deltas := make([]time.Duration, 0, len(r.Results))
+	for _, result := range r.Results {
+		if result.Error == "" {
+			deltas = append(deltas, result.LatencyDelta())
+		}
+	}
+	return median(deltas)
+}
+
+// WriteText writes a human-readable summary of the differences
+func (r *CompatReport) WriteText(w io.Writer) error {
+	incompatible := r.Incompatible()
+	slower := r.Slower()
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d fixtures, %d incompatible, %d slower, median latency delta %s\n",
+		len(r.Results), len(incompatible), len(slower), r.MedianLatencyDelta())
+
+	for _, result := range r.Results {
+		if result.Compatible() && !result.Slower {
+			continue
+		}
+
+		var problems []string
+		if result.Error != "" {
+			problems = append(problems, "error: "+result.Error)
+		}
+		if result.StatusChanged() {
+			problems = append(problems, fmt.Sprintf("status %d -> %d", result.BaselineStatus, result.CandidateStatus))
+		}
+		if len(result.Fields) > 0 {
+			problems = append(problems, "fields: "+strings.Join(result.Fields, ", "))
+		}
+		if len(result.Headers) > 0 {
+			problems = append(problems, "headers: "+strings.Join(result.Headers, ", "))
+		}
+		if result.Slower {
+			problems = append(problems, fmt.Sprintf("latency %s -> %s", result.BaselineLatency, result.CandidateLatency))
+		}
+		fmt.Fprintf(tw, "%s\t%s %s\t%s\n", result.Fixture, result.Method, result.Path, strings.Join(problems, "; "))
+	}
+	return tw.Flush()
+}
+
+// CompareApps replays fixtures against a baseline and a candidate version of
+// an app and reports field-level response differences, status changes and
+// latency deltas. Use it to check a refactor or dependency upgrade against
+// traffic recorded with FixtureConverter:
+//
+//	fixtures, _ := testing.LoadFixtures("testdata/fixtures")
+//	report := testing.CompareApps(newApp(legacyConfig), newApp(candidateConfig), fixtures, testing.CompatOptions{
+//		IgnoreFields: []string{"request_id", "data.created_at"},
+//	})
+//	testing.AssertCompatible(t, report)
+func CompareApps(baseline, candidate *lift.App, fixtures []*Fixture, opts CompatOptions) *CompatReport {
+	if opts.Runs < 1 {
+		opts.Runs = 1
+	}
+	ignore := make(map[string]bool, len(opts.IgnoreFields))
+	for _, field := range opts.IgnoreFields {
+		ignore[field] = true
+	}
+
+	report := &CompatReport{Results: make([]CompatResult, 0, len(fixtures))}
+	for _, fixture := range fixtures {
+		report.Results = append(report.Results, compareFixture(baseline, candidate, fixture, opts, ignore))
+	}
+	return report
+}
+
+// AssertCompatible fails the test with the report's summary if any fixture
+// produced a different response, or was slower when SlowerThreshold is set
+func AssertCompatible(t *testing.T, report *CompatReport) {
+	t.Helper()
+	if report.Compatible() && len(report.Slower()) == 0 {
+		return
+	}
+
+	var summary bytes.Buffer
+	_ = report.WriteText(&summary)
+	t.Errorf("candidate responses differ from baseline:\n%s", summary.String())
+}
+
+// compareFixture replays one fixture against both apps
+func compareFixture(baseline, candidate *lift.App, fixture *Fixture, opts CompatOptions, ignore map[string]bool) CompatResult {
+	result := CompatResult{Fixture: fixture.Name}
+	var event map[string]any
+	if err := json.Unmarshal(fixture.Event, &event); err == nil {
+		result.Method, result.Path = eventRoute(event)
+	}
+
+	var baselineResp, candidateResp *lift.Response
+	baselineTimes := make([]time.Duration, 0, opts.Runs)
+	candidateTimes := make([]time.Duration, 0, opts.Runs)
+
+	// Alternate between the apps so warm-up and noise affect both equally
+	for i := 0; i < opts.Runs; i++ {
+		var err error
+		start := time.Now()
+		if baselineResp, err = ReplayFixture(baseline, fixture, opts.FixtureOptions); err != nil {
+			result.Error = fmt.Sprintf("baseline: %v", err)
+			return result
+		}
+		baselineTimes = append(baselineTimes, time.Since(start))
+
+		start = time.Now()
+		if candidateResp, err = ReplayFixture(candidate, fixture, opts.FixtureOptions); err != nil {
+			result.Error = fmt.Sprintf("candidate: %v", err)
+			return result
+		}
+		candidateTimes = append(candidateTimes, time.Since(start))
+	}
+
+	result.BaselineStatus = baselineResp.StatusCode
+	result.CandidateStatus = candidateResp.StatusCode
+	result.BaselineLatency = median(baselineTimes)
+	result.CandidateLatency = median(candidateTimes)
+	if opts.SlowerThreshold > 0 && result.BaselineLatency > 0 {
+		increase := float64(result.LatencyDelta()) / float64(result.BaselineLatency)
+		result.Slower = increase > opts.SlowerThreshold
+	}
+
+	baselineBody, baselineErr := responseBytes(baselineResp)
+	candidateBody, candidateErr := responseBytes(candidateResp)
+	if baselineErr != nil || candidateErr != nil {
+		result.Error = "response body could not be encoded"
+		return result
+	}
+	result.Fields = compareBodies(baselineBody, candidateBody, ignore)
+
+	for _, name := range opts.CompareHeaders {
+		if headerValue(baselineResp.Headers, name) != headerValue(candidateResp.Headers, name) {
+			result.Headers = append(result.Headers, name)
+		}
+	}
+	return result
+}
+
+// compareBodies returns the JSON paths whose values differ. Bodies that
+// aren't JSON are compared as a whole and reported as "body".
+func compareBodies(baseline, candidate []byte, ignore map[string]bool) []string {
+	var baselineValue, candidateValue any
+	if json.Unmarshal(baseline, &baselineValue) != nil || json.Unmarshal(candidate, &candidateValue) != nil {
+		if bytes.Equal(bytes.TrimSpace(baseline), bytes.TrimSpace(candidate)) {
+			return nil
+		}
+		return []string{"body"}
+	}
+
+	var fields []string
+	diffJSON("", baselineValue, candidateValue, ignore, &fields)
+	sort.Strings(fields)
+	return fields
+}
+
+// diffJSON collects the paths at which two decoded JSON values differ,
+// descending into objects and arrays
+func diffJSON(path string, a, b any, ignore map[string]bool, fields *[]string) {
+	if path != "" && ignore[path] {
+		return
+	}
+
+	aMap, aIsMap := a.(map[string]any)
+	bMap, bIsMap := b.(map[string]any)
+	if aIsMap && bIsMap {
+		for key, value := range aMap {
+			diffJSON(jsonPath(path, key), value, bMap[key], ignore, fields)
+		}
+		for key, value := range bMap {
+			if _, ok := aMap[key]; !ok {
+				diffJSON(jsonPath(path, key), nil, value, ignore, fields)
+			}
+		}
+		return
+	}
+
+	aSlice, aIsSlice := a.([]any)
+	bSlice, bIsSlice := b.([]any)
+	if aIsSlice && bIsSlice {
+		for i := 0; i < len(aSlice) || i < len(bSlice); i++ {
+			var aValue, bValue any
+			if i < len(aSlice) {
+				aValue = aSlice[i]
+			}
+			if i < len(bSlice) {
+				bValue = bSlice[i]
+			}
+			diffJSON(jsonPath(path, strconv.Itoa(i)), aValue, bValue, ignore, fields)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "body"
+		}
+		*fields = append(*fields, path)
+	}
+}
+
+// responseBytes encodes a lift response body for comparison
+func responseBytes(resp *lift.Response) ([]byte, error) {
+	switch body := resp.Body.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(body), nil
+	case []byte:
+		return body, nil
+	default:
+		return json.Marshal(body)
+	}
+}
+
+// headerValue looks up a header case-insensitively
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+func jsonPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,106 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compatFixture(t *testing.T, name, method, path string) *Fixture {
+	line, err := json.Marshal(map[string]any{"method": method, "path": path, "status": 200})
+	require.NoError(t, err)
+
+	converter := &FixtureConverter{}
+	fixture, err := converter.ConvertLogLine(name, line)
+	require.NoError(t, err)
+	return fixture
+}
+
+func TestCompareApps(t *testing.T) {
+	baseline := lift.New()
+	baseline.GET("/users", func(ctx *lift.Context) error {
+		return ctx.OK(map[string]any{
+			"request_id": "a",
+			"users":      []any{map[string]any{"id": "1", "name": "Ada"}},
+		})
+	})
+	baseline.GET("/health", func(ctx *lift.Context) error {
+		return ctx.OK(map[string]string{"status": "ok"})
+	})
+	baseline.GET("/legacy", func(ctx *lift.Context) error {
+		return ctx.OK(nil)
+	})
+
+	candidate := lift.New()
+	candidate.GET("/users", func(ctx *lift.Context) error {
+		return ctx.OK(map[string]any{
+			"request_id": "b",
+			"users":      []any{map[string]any{"id": "1", "name": "Ada Lovelace"}, map[string]any{"id": "2"}},
+		})
+	})
+	candidate.GET("/health", func(ctx *lift.Context) error {
+		return ctx.OK(map[string]string{"status": "ok"})
+	})
+	candidate.GET("/legacy", func(ctx *lift.Context) error {
+		return lift.NewLiftError("GONE", "legacy endpoint removed", 410)
+	})
+
+	fixtures := []*Fixture{
+		compatFixture(t, "list-users", "GET", "/users"),
+		compatFixture(t, "health", "GET", "/health"),
+		compatFixture(t, "legacy", "GET", "/legacy"),
+	}
+
+	report := CompareApps(baseline, candidate, fixtures, CompatOptions{
+		IgnoreFields: []string{"request_id"},
+		Runs:         3,
+	})
+	require.Len(t, report.Results, 3)
+	assert.False(t, report.Compatible())
+
+	users := report.Results[0]
+	assert.Equal(t, "GET", users.Method)
+	assert.Equal(t, "/users", users.Path)
+	assert.Equal(t, []string{"users.0.name", "users.1"}, users.Fields)
+	assert.False(t, users.StatusChanged())
+	assert.Positive(t, users.BaselineLatency)
+
+	assert.True(t, report.Results[1].Compatible())
+
+	legacy := report.Results[2]
+	assert.True(t, legacy.StatusChanged())
+	assert.Equal(t, 200, legacy.BaselineStatus)
+	assert.Equal(t, 410, legacy.CandidateStatus)
+
+	assert.Len(t, report.Incompatible(), 2)
+
+	var summary bytes.Buffer
+	require.NoError(t, report.WriteText(&summary))
+	assert.Contains(t, summary.String(), "3 fixtures, 2 incompatible")
+	assert.Contains(t, summary.String(), "fields: users.0.name, users.1")
+	assert.Contains(t, summary.String(), "status 200 -> 410")
+	assert.NotContains(t, summary.String(), "health")
+}
+
+func TestCompareAppsHeadersAndIdenticalApps(t *testing.T) {
+	newApp := func(version string) *lift.App {
+		app := lift.New()
+		app.GET("/version", func(ctx *lift.Context) error {
+			ctx.Response.Header("X-Version", version)
+			return ctx.OK("ok")
+		})
+		return app
+	}
+	fixtures := []*Fixture{compatFixture(t, "version", "GET", "/version")}
+
+	report := CompareApps(newApp("1"), newApp("1"), fixtures, CompatOptions{CompareHeaders: []string{"X-Version"}})
+	AssertCompatible(t, report)
+
+	report = CompareApps(newApp("1"), newApp("2"), fixtures, CompatOptions{CompareHeaders: []string{"x-version"}})
+	assert.Equal(t, []string{"x-version"}, report.Results[0].Headers)
+	assert.Empty(t, report.Results[0].Fields)
+}
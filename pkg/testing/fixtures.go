@@ -0,0 +1,578 @@
+package testing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/pay-theory/lift/pkg/utils/sanitization"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RedactedValue replaces sensitive header values in fixtures
+const RedactedValue = "[REDACTED]"
+
+// Fixture is a replayable Lambda event captured from production, together
+// with the response the app is expected to produce for it
+type Fixture struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Source      string             `json:"source,omitempty"`
+	Event       json.RawMessage    `json:"event"`
+	Expect      FixtureExpectation `json:"expect"`
+}
+
+// FixtureExpectation describes the expected response for a fixture
+type FixtureExpectation struct {
+	Status int `json:"status,omitempty"`
+	// Body, when set, must match the response body as JSON
+	Body json.RawMessage `json:"body,omitempty"`
+	// BodyContains lists substrings the response body must contain
+	BodyContains []string          `json:"body_contains,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+}
+
+// LogFieldMapping names the log line fields that hold request data. Fields are
+// looked up at the top level of the line and then in a nested "fields" object.
+type LogFieldMapping struct {
+	Method    string
+	Path      string
+	Headers   string
+	Query     string
+	Body      string
+	Status    string
+	RequestID string
+}
+
+// DefaultLogFieldMapping matches the field names used by the observability middleware
+var DefaultLogFieldMapping = LogFieldMapping{
+	Method:    "method",
+	Path:      "path",
+	Headers:   "headers",
+	Query:     "query",
+	Body:      "body",
+	Status:    "status",
+	RequestID: "request_id",
+}
+
+// FixtureConverter turns captured events and structured log lines into fixtures
+type FixtureConverter struct {
+	// Fields maps log line fields to request data (default: DefaultLogFieldMapping)
+	Fields LogFieldMapping
+	// RedactHeaders lists headers whose values are replaced with RedactedValue.
+	// Authorization, Cookie and API key headers are always redacted.
+	RedactHeaders []string
+	// DropHeaders lists headers removed from fixtures entirely
+	DropHeaders []string
+	// Source is recorded on every fixture, e.g. the log group the lines came from
+	Source string
+}
+
+var alwaysRedacted = []string{"authorization", "cookie", "set-cookie", "x-api-key", "x-amz-security-token"}
+
+// secretFieldNames are substrings of query parameter and body field names that
+// are always redacted, on top of the log sanitization rules
+var secretFieldNames = []string{"key", "signature", "session", "passwd", "dsn"}
+
+// ConvertEvent builds a fixture from a raw Lambda event. Sensitive headers,
+// cookies, authorizer and caller identity data are redacted, and sensitive
+// query parameters and body fields are masked; the rest of the event is kept
+// as captured.
+func (c *FixtureConverter) ConvertEvent(name string, raw []byte) (*Fixture, error) {
+	var event map[string]any
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, fmt.Errorf("event is not a JSON object: %w", err)
+	}
+
+	c.sanitizeEvent(event)
+
+	encoded, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Fixture{
+		Name:   fixtureName(name, event),
+		Source: c.Source,
+		Event:  encoded,
+	}, nil
+}
+
+// ConvertLogLine builds a fixture from a structured JSON log line by
+// synthesizing an API Gateway v2 event from the logged request. The logged
+// status, if any, becomes the expected status. The event is redacted the same
+// way as in ConvertEvent.
+func (c *FixtureConverter) ConvertLogLine(name string, line []byte) (*Fixture, error) {
+	var entry map[string]any
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return nil, fmt.Errorf("log line is not a JSON object: %w", err)
+	}
+
+	fields := c.Fields
+	if fields.Method == "" {
+		fields = DefaultLogFieldMapping
+	}
+
+	method, _ := logField(entry, fields.Method).(string)
+	path, _ := logField(entry, fields.Path).(string)
+	if method == "" || path == "" {
+		return nil, fmt.Errorf("log line has no %s and %s fields", fields.Method, fields.Path)
+	}
+	requestID, _ := logField(entry, fields.RequestID).(string)
+
+	headers := stringMap(logField(entry, fields.Headers))
+	anyHeaders := make(map[string]any, len(headers))
+	for k, v := range headers {
+		anyHeaders[k] = v
+	}
+
+	event := map[string]any{
+		"version":  "2.0",
+		"routeKey": "$default",
+		"rawPath":  path,
+		"headers":  anyHeaders,
+		"requestContext": map[string]any{
+			"requestId": requestID,
+			"stage":     "$default",
+			"http": map[string]any{
+				"method": strings.ToUpper(method),
+				"path":   path,
+			},
+		},
+		"isBase64Encoded": false,
+	}
+	if query := stringMap(logField(entry, fields.Query)); len(query) > 0 {
+		event["queryStringParameters"] = query
+	}
+	switch body := logField(entry, fields.Body).(type) {
+	case string:
+		event["body"] = body
+	case nil:
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		event["body"] = string(encoded)
+	}
+	c.sanitizeEvent(event)
+
+	encoded, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &Fixture{
+		Name:   fixtureName(name, event),
+		Source: c.Source,
+		Event:  encoded,
+	}
+	if status, ok := logField(entry, fields.Status).(float64); ok {
+		fixture.Expect.Status = int(status)
+	}
+	return fixture, nil
+}
+
+// ConvertLogs converts every log line in r that describes a request. Lines
+// that aren't JSON or don't carry a method and path are skipped.
+func (c *FixtureConverter) ConvertLogs(r io.Reader) ([]*Fixture, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var fixtures []*Fixture
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		fixture, err := c.ConvertLogLine("", []byte(line))
+		if err != nil {
+			continue
+		}
+		fixture.Description = fmt.Sprintf("converted from log line %d", lineNo)
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, scanner.Err()
+}
+
+// sanitizeEvent redacts everything in an API Gateway event that identifies or
+// authenticates the caller, and masks sensitive query parameters and body
+// fields with the rules used for log sanitization
+func (c *FixtureConverter) sanitizeEvent(event map[string]any) {
+	for _, key := range []string{"headers", "multiValueHeaders"} {
+		if headers, ok := event[key].(map[string]any); ok {
+			c.sanitizeHeaders(headers)
+		}
+	}
+
+	// API Gateway v2 moves cookies out of the headers
+	if cookies, ok := event["cookies"].([]any); ok {
+		for i, cookie := range cookies {
+			name, _, _ := strings.Cut(fmt.Sprint(cookie), "=")
+			cookies[i] = name + "=" + RedactedValue
+		}
+	}
+
+	if rc, ok := event["requestContext"].(map[string]any); ok {
+		for _, key := range []string{"authorizer", "identity", "authentication"} {
+			if value, ok := rc[key]; ok {
+				rc[key] = redactAll(value)
+			}
+		}
+		if httpContext, ok := rc["http"].(map[string]any); ok {
+			if _, ok := httpContext["sourceIp"]; ok {
+				httpContext["sourceIp"] = RedactedValue
+			}
+		}
+	}
+
+	for _, key := range []string{"queryStringParameters", "multiValueQueryStringParameters"} {
+		if query, ok := event[key].(map[string]any); ok {
+			event[key] = sanitizeFields(query)
+		}
+	}
+	if raw, ok := event["rawQueryString"].(string); ok && raw != "" {
+		event["rawQueryString"] = sanitizeQuery(raw)
+	}
+
+	if body, ok := event["body"].(string); ok && body != "" {
+		encoded, _ := event["isBase64Encoded"].(bool)
+		event["body"] = sanitizeBody(body, encoded, eventContentType(event))
+	}
+}
+
+func (c *FixtureConverter) sanitizeHeaders(headers map[string]any) {
+	redact := make(map[string]bool)
+	for _, h := range append(alwaysRedacted, c.RedactHeaders...) {
+		redact[strings.ToLower(h)] = true
+	}
+	drop := make(map[string]bool)
+	for _, h := range c.DropHeaders {
+		drop[strings.ToLower(h)] = true
+	}
+
+	for key, value := range headers {
+		lower := strings.ToLower(key)
+		switch {
+		case drop[lower]:
+			delete(headers, key)
+		case redact[lower]:
+			if _, multi := value.([]any); multi {
+				headers[key] = []any{RedactedValue}
+			} else {
+				headers[key] = RedactedValue
+			}
+		}
+	}
+}
+
+// redactAll replaces every value in v, keeping the structure so fixtures
+// still show which fields the event carried
+func redactAll(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, field := range value {
+			value[key] = redactAll(field)
+		}
+		return value
+	case []any:
+		for i, item := range value {
+			value[i] = redactAll(item)
+		}
+		return value
+	case nil:
+		return nil
+	default:
+		return RedactedValue
+	}
+}
+
+// sanitizeFields masks sensitive fields in a decoded JSON object, including
+// nested objects
+func sanitizeFields(fields map[string]any) map[string]any {
+	for key, value := range fields {
+		switch nested := value.(type) {
+		case map[string]any:
+			fields[key] = sanitizeFields(nested)
+		case []any:
+			for i, item := range nested {
+				if m, ok := item.(map[string]any); ok {
+					nested[i] = sanitizeFields(m)
+				} else {
+					nested[i] = sanitizeField(key, item)
+				}
+			}
+		default:
+			fields[key] = sanitizeField(key, value)
+		}
+	}
+	return fields
+}
+
+// sanitizeField masks a single query parameter or body field value
+func sanitizeField(key string, value any) any {
+	lower := strings.ToLower(key)
+	for _, name := range secretFieldNames {
+		if strings.Contains(lower, name) && value != nil {
+			return RedactedValue
+		}
+	}
+	return sanitization.SanitizeFieldValue(key, value)
+}
+
+// sanitizeQuery masks sensitive parameters in a raw query string
+func sanitizeQuery(raw string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return RedactedValue
+	}
+	for key, list := range values {
+		for i, value := range list {
+			list[i] = fmt.Sprint(sanitizeField(key, value))
+		}
+	}
+	return values.Encode()
+}
+
+// sanitizeBody masks sensitive fields in JSON and form bodies. Bodies that
+// can't be inspected, such as binary or plain text, are redacted entirely.
+func sanitizeBody(body string, base64Encoded bool, contentType string) string {
+	if base64Encoded {
+		return RedactedValue
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(body), &fields); err == nil {
+		encoded, err := json.Marshal(sanitizeFields(fields))
+		if err != nil {
+			return RedactedValue
+		}
+		return string(encoded)
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return sanitizeQuery(body)
+	}
+	return RedactedValue
+}
+
+// eventContentType returns the event's Content-Type header, lowercased
+func eventContentType(event map[string]any) string {
+	headers, _ := event["headers"].(map[string]any)
+	for key, value := range headers {
+		if strings.EqualFold(key, "content-type") {
+			contentType, _ := value.(string)
+			return strings.ToLower(contentType)
+		}
+	}
+	return ""
+}
+
+// WriteFixtures writes each fixture to dir as <name>.json. Names that collide
+// get a numeric suffix.
+func WriteFixtures(dir string, fixtures []*Fixture) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	used := make(map[string]int)
+	for _, fixture := range fixtures {
+		name := fixture.Name
+		used[name]++
+		if n := used[name]; n > 1 {
+			name = fmt.Sprintf("%s-%d", name, n)
+			renamed := *fixture
+			renamed.Name = name
+			fixture = &renamed
+		}
+
+		data, err := json.MarshalIndent(fixture, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode fixture %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFixtures reads every *.json fixture in dir, sorted by file name
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	fixtures := make([]*Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+		}
+		if fixture.Name == "" {
+			fixture.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+		fixtures = append(fixtures, &fixture)
+	}
+	return fixtures, nil
+}
+
+// FixtureOptions configures fixture replay
+type FixtureOptions struct {
+	// Headers are set on every replayed event, e.g. a test token in place of
+	// a redacted Authorization header
+	Headers map[string]string
+}
+
+// ReplayFixture runs a fixture's event through the app and returns the response
+func ReplayFixture(app *lift.App, fixture *Fixture, opts FixtureOptions) (*lift.Response, error) {
+	var event map[string]any
+	if err := json.Unmarshal(fixture.Event, &event); err != nil {
+		return nil, fmt.Errorf("fixture %s has an invalid event: %w", fixture.Name, err)
+	}
+
+	if len(opts.Headers) > 0 {
+		headers, _ := event["headers"].(map[string]any)
+		if headers == nil {
+			headers = make(map[string]any)
+		}
+		for k, v := range opts.Headers {
+			for existing := range headers {
+				if strings.EqualFold(existing, k) {
+					delete(headers, existing)
+				}
+			}
+			headers[k] = v
+		}
+		event["headers"] = headers
+	}
+
+	result, err := app.HandleRequest(context.Background(), event)
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := result.(*lift.Response)
+	if !ok {
+		return nil, fmt.Errorf("fixture %s produced an unexpected %T", fixture.Name, result)
+	}
+	return resp, nil
+}
+
+// RunFixtures replays every fixture against the app as a subtest and checks
+// the expected status, headers and body
+func RunFixtures(t *testing.T, app *lift.App, fixtures []*Fixture, opts FixtureOptions) {
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.Name, func(t *testing.T) {
+			resp, err := ReplayFixture(app, fixture, opts)
+			require.NoError(t, err)
+
+			if fixture.Expect.Status != 0 {
+				assert.Equal(t, fixture.Expect.Status, resp.StatusCode, "status code mismatch")
+			}
+			for key, expected := range fixture.Expect.Headers {
+				assert.Equal(t, expected, resp.Headers[key], "header %s mismatch", key)
+			}
+
+			body := responseBodyString(t, resp)
+			if len(fixture.Expect.Body) > 0 {
+				assert.JSONEq(t, string(fixture.Expect.Body), body, "body mismatch")
+			}
+			for _, fragment := range fixture.Expect.BodyContains {
+				assert.Contains(t, body, fragment)
+			}
+		})
+	}
+}
+
+func responseBodyString(t *testing.T, resp *lift.Response) string {
+	switch body := resp.Body.(type) {
+	case nil:
+		return ""
+	case string:
+		return body
+	case []byte:
+		return string(body)
+	default:
+		encoded, err := json.Marshal(body)
+		require.NoError(t, err)
+		return string(encoded)
+	}
+}
+
+// logField looks up a field at the top level of a log line and then in its
+// nested "fields" object
+func logField(entry map[string]any, name string) any {
+	if name == "" {
+		return nil
+	}
+	if value, ok := entry[name]; ok {
+		return value
+	}
+	if nested, ok := entry["fields"].(map[string]any); ok {
+		return nested[name]
+	}
+	return nil
+}
+
+func stringMap(value any) map[string]string {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fixtureName derives a file-safe fixture name, falling back to the event's
+// method and path
+func fixtureName(name string, event map[string]any) string {
+	if name == "" {
+		method, path := eventRoute(event)
+		name = method + " " + path
+	}
+	name = strings.Trim(unsafeNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		name = "fixture"
+	}
+	return name
+}
+
+func eventRoute(event map[string]any) (string, string) {
+	if method, ok := event["httpMethod"].(string); ok {
+		path, _ := event["path"].(string)
+		return method, path
+	}
+	if rc, ok := event["requestContext"].(map[string]any); ok {
+		if h, ok := rc["http"].(map[string]any); ok {
+			method, _ := h["method"].(string)
+			path, _ := h["path"].(string)
+			return method, path
+		}
+	}
+	return "event", ""
+}
@@ -0,0 +1,162 @@
+package testing
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtureConverterConvertLogs(t *testing.T) {
+	logs := strings.Join([]string{
+		"START RequestId: 123",
+		`{"level":"info","message":"Request completed","request_id":"req-1","fields":{"method":"post","path":"/payments","status":422,"headers":{"Authorization":"Bearer secret","X-Trace":"abc","Content-Type":"application/json"},"query":{"dry_run":"true"},"body":{"amount":-1}}}`,
+		`{"level":"info","message":"Cold start"}`,
+	}, "\n")
+
+	converter := &FixtureConverter{DropHeaders: []string{"X-Trace"}, Source: "prod"}
+	fixtures, err := converter.ConvertLogs(strings.NewReader(logs))
+	require.NoError(t, err)
+	require.Len(t, fixtures, 1)
+
+	fixture := fixtures[0]
+	assert.Equal(t, "post-payments", fixture.Name)
+	assert.Equal(t, "prod", fixture.Source)
+	assert.Equal(t, 422, fixture.Expect.Status)
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(fixture.Event, &event))
+	headers := event["headers"].(map[string]any)
+	assert.Equal(t, RedactedValue, headers["Authorization"])
+	assert.NotContains(t, headers, "X-Trace")
+	assert.Equal(t, `{"amount":-1}`, event["body"])
+	assert.Equal(t, "req-1", event["requestContext"].(map[string]any)["requestId"])
+}
+
+func TestFixtureConverterConvertEvent(t *testing.T) {
+	raw := `{"httpMethod":"GET","path":"/users/42","headers":{"cookie":"session=abc"},"multiValueHeaders":{"Cookie":["session=abc"]}}`
+
+	fixture, err := (&FixtureConverter{}).ConvertEvent("", []byte(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "get-users-42", fixture.Name)
+	assert.NotContains(t, string(fixture.Event), "session=abc")
+}
+
+func TestFixtureConverterRedactsCallerData(t *testing.T) {
+	converter := &FixtureConverter{}
+
+	t.Run("api gateway v2", func(t *testing.T) {
+		raw := `{
+			"version": "2.0",
+			"rawPath": "/payments",
+			"rawQueryString": "page=2&token=qs-secret",
+			"cookies": ["session=cookie-secret", "theme=dark"],
+			"headers": {"content-type": "application/json"},
+			"queryStringParameters": {"page": "2", "token": "qs-secret"},
+			"requestContext": {
+				"http": {"method": "POST", "path": "/payments", "sourceIp": "203.0.113.7"},
+				"authorizer": {"jwt": {"claims": {"sub": "user-secret", "tenant_id": "tenant-1"}, "scopes": ["payments:write"]}},
+				"authentication": {"clientCert": {"clientCertPem": "cert-secret"}}
+			},
+			"body": "{\"amount\":100,\"card_number\":\"4111111111111111\",\"payer\":{\"password\":\"body-secret\"}}"
+		}`
+
+		fixture, err := converter.ConvertEvent("", []byte(raw))
+		require.NoError(t, err)
+		event := string(fixture.Event)
+		for _, secret := range []string{"qs-secret", "cookie-secret", "dark", "203.0.113.7", "user-secret", "tenant-1", "payments:write", "cert-secret", "4111111111111111", "body-secret"} {
+			assert.NotContains(t, event, secret)
+		}
+
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(fixture.Event, &decoded))
+		assert.Equal(t, []any{"session=" + RedactedValue, "theme=" + RedactedValue}, decoded["cookies"])
+		assert.Equal(t, "2", decoded["queryStringParameters"].(map[string]any)["page"])
+		assert.Contains(t, decoded["rawQueryString"], "page=2")
+		assert.Contains(t, decoded["body"], `"amount":100`)
+	})
+
+	t.Run("api gateway v1", func(t *testing.T) {
+		raw := `{
+			"httpMethod": "POST",
+			"path": "/login",
+			"headers": {"Content-Type": "application/x-www-form-urlencoded"},
+			"multiValueQueryStringParameters": {"api_key": ["key-secret"]},
+			"requestContext": {
+				"identity": {"sourceIp": "198.51.100.4", "accessKey": "access-secret", "cognitoIdentityId": "identity-secret"},
+				"authorizer": {"principalId": "principal-secret"}
+			},
+			"body": "username=ada&password=form-secret"
+		}`
+
+		fixture, err := converter.ConvertEvent("", []byte(raw))
+		require.NoError(t, err)
+		event := string(fixture.Event)
+		for _, secret := range []string{"key-secret", "198.51.100.4", "access-secret", "identity-secret", "principal-secret", "form-secret"} {
+			assert.NotContains(t, event, secret)
+		}
+		assert.Contains(t, event, "username=ada")
+	})
+
+	t.Run("opaque bodies", func(t *testing.T) {
+		fixture, err := converter.ConvertEvent("", []byte(`{"httpMethod":"POST","path":"/upload","isBase64Encoded":true,"body":"c2VjcmV0"}`))
+		require.NoError(t, err)
+		assert.NotContains(t, string(fixture.Event), "c2VjcmV0")
+
+		fixture, err = converter.ConvertLogLine("", []byte(`{"method":"POST","path":"/notes","body":"my pin is 1234"}`))
+		require.NoError(t, err)
+		assert.NotContains(t, string(fixture.Event), "1234")
+	})
+
+	t.Run("log lines", func(t *testing.T) {
+		fixture, err := converter.ConvertLogLine("", []byte(
+			`{"method":"GET","path":"/search","query":{"q":"shoes","access_token":"log-secret"},"body":{"secret":"log-body-secret"}}`,
+		))
+		require.NoError(t, err)
+		assert.NotContains(t, string(fixture.Event), "log-secret")
+		assert.NotContains(t, string(fixture.Event), "log-body-secret")
+	})
+}
+
+func TestFixturesRoundTripAndReplay(t *testing.T) {
+	app := lift.New()
+	app.POST("/payments", func(ctx *lift.Context) error {
+		if ctx.Request.GetHeader("Authorization") != "Bearer test" {
+			return lift.Unauthorized("missing token")
+		}
+		var req struct {
+			Amount int `json:"amount"`
+		}
+		if err := ctx.ParseRequest(&req); err != nil {
+			return err
+		}
+		if req.Amount <= 0 {
+			return lift.NewLiftError("INVALID_AMOUNT", "amount must be positive", 422)
+		}
+		return ctx.OK(map[string]int{"amount": req.Amount})
+	})
+
+	converter := &FixtureConverter{}
+	fixture, err := converter.ConvertLogLine("negative amount", []byte(
+		`{"method":"POST","path":"/payments","status":422,"headers":{"Authorization":"Bearer prod"},"body":"{\"amount\":-5}"}`,
+	))
+	require.NoError(t, err)
+	fixture.Expect.BodyContains = []string{"INVALID_AMOUNT"}
+
+	dir := t.TempDir()
+	require.NoError(t, WriteFixtures(dir, []*Fixture{fixture, fixture}))
+
+	loaded, err := LoadFixtures(dir)
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.ElementsMatch(t, []string{"negative-amount", "negative-amount-2"}, []string{loaded[0].Name, loaded[1].Name})
+
+	RunFixtures(t, app, loaded, FixtureOptions{Headers: map[string]string{"Authorization": "Bearer test"}})
+
+	resp, err := ReplayFixture(app, fixture, FixtureOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode)
+}
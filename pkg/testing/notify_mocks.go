@@ -0,0 +1,131 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pay-theory/lift/pkg/notify"
+)
+
+// =============================================================================
+// Notification Provider Mocks
+// =============================================================================
+
+// MockEmailSender records emails instead of sending them
+type MockEmailSender struct {
+	mu     sync.Mutex
+	sent   []*notify.Email
+	errors map[string]error
+}
+
+// NewMockEmailSender creates a new mock email sender
+func NewMockEmailSender() *MockEmailSender {
+	return &MockEmailSender{errors: make(map[string]error)}
+}
+
+// WithError makes sends to the given address fail
+func (m *MockEmailSender) WithError(address string, err error) *MockEmailSender {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[address] = err
+	return m
+}
+
+// SendEmail implements notify.EmailSender
+func (m *MockEmailSender) SendEmail(ctx context.Context, email *notify.Email) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err, ok := m.errors[email.To]; ok {
+		return "", err
+	}
+	m.sent = append(m.sent, email)
+	return fmt.Sprintf("mock-email-%d", len(m.sent)), nil
+}
+
+// Sent returns the emails sent so far
+func (m *MockEmailSender) Sent() []*notify.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*notify.Email(nil), m.sent...)
+}
+
+// SentTo returns the emails sent to an address
+func (m *MockEmailSender) SentTo(address string) []*notify.Email {
+	var out []*notify.Email
+	for _, email := range m.Sent() {
+		if email.To == address {
+			out = append(out, email)
+		}
+	}
+	return out
+}
+
+// Reset clears recorded emails and configured errors
+func (m *MockEmailSender) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = nil
+	m.errors = make(map[string]error)
+}
+
+// MockSMSSender records text messages instead of sending them
+type MockSMSSender struct {
+	mu     sync.Mutex
+	sent   []*notify.SMS
+	errors map[string]error
+}
+
+// NewMockSMSSender creates a new mock SMS sender
+func NewMockSMSSender() *MockSMSSender {
+	return &MockSMSSender{errors: make(map[string]error)}
+}
+
+// WithError makes sends to the given phone number fail
+func (m *MockSMSSender) WithError(number string, err error) *MockSMSSender {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[number] = err
+	return m
+}
+
+// SendSMS implements notify.SMSSender
+func (m *MockSMSSender) SendSMS(ctx context.Context, sms *notify.SMS) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err, ok := m.errors[sms.To]; ok {
+		return "", err
+	}
+	m.sent = append(m.sent, sms)
+	return fmt.Sprintf("mock-sms-%d", len(m.sent)), nil
+}
+
+// Sent returns the text messages sent so far
+func (m *MockSMSSender) Sent() []*notify.SMS {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*notify.SMS(nil), m.sent...)
+}
+
+// Reset clears recorded messages and configured errors
+func (m *MockSMSSender) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = nil
+	m.errors = make(map[string]error)
+}
+
+// NewMockNotifier creates a notification service backed by mock senders
+func NewMockNotifier(templates *notify.Templates, identities notify.IdentityResolver) (*notify.Service, *MockEmailSender, *MockSMSSender) {
+	email := NewMockEmailSender()
+	sms := NewMockSMSSender()
+	service := notify.New(notify.Config{
+		Email:      email,
+		SMS:        sms,
+		Templates:  templates,
+		Identities: identities,
+	})
+	return service, email, sms
+}
@@ -0,0 +1,413 @@
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Scenario is an end-to-end test of a multi-step flow run against an
+// in-process app, such as creating a tenant, signing in, placing an order
+// and checking the webhook and audit entry it produced:
+//
+//	NewScenario(app).
+//		Step("create tenant", Request("POST", "/tenants").
+//			JSON(map[string]any{"name": "Acme"}).
+//			ExpectStatus(201).
+//			Capture("tenant_id", "$.id")).
+//		Step("sign in", Request("POST", "/sessions").
+//			JSON(map[string]any{"tenant_id": "{{tenant_id}}"}).
+//			Capture("token", "$.token")).
+//		Step("create order", Request("POST", "/orders").
+//			Header("Authorization", "Bearer {{token}}").
+//			JSON(map[string]any{"amount": 1200}).
+//			ExpectStatus(201).
+//			ExpectJSON("$.tenant_id", "{{tenant_id}}").
+//			Capture("order_id", "$.id")).
+//		Eventually("webhook delivered", time.Second, func(state *ScenarioState) error {
+//			return webhooks.Delivered("order.created", state.String("order_id"))
+//		}).
+//		Check("audit entry", func(t *testing.T, state *ScenarioState) {
+//			assert.Len(t, audit.EntriesFor(state.String("order_id")), 1)
+//		}).
+//		Run(t)
+//
+// Values captured by one step are available to later steps as {{name}} in
+// paths, headers, query parameters, bodies and expectations. Each step runs
+// as a subtest; once one fails, the remaining steps are skipped. Mocks are
+// wired into the app as usual and checked from Check and Eventually steps.
+type Scenario struct {
+	app     *lift.App
+	headers map[string]string
+	vars    map[string]any
+	steps   []scenarioStep
+}
+
+type scenarioStep struct {
+	name string
+	run  func(t *testing.T, state *ScenarioState)
+}
+
+// NewScenario creates a scenario against an app
+func NewScenario(app *lift.App) *Scenario {
+	return &Scenario{
+		app:     app,
+		headers: make(map[string]string),
+		vars:    make(map[string]any),
+	}
+}
+
+// Header sets a header on every request. Headers that use variables that
+// aren't captured yet, such as "Bearer {{token}}" before signing in, are
+// left out until they are.
+func (s *Scenario) Header(key, value string) *Scenario {
+	s.headers[key] = value
+	return s
+}
+
+// Set sets a variable before the scenario runs
+func (s *Scenario) Set(name string, value any) *Scenario {
+	s.vars[name] = value
+	return s
+}
+
+// Step adds a request to the scenario
+func (s *Scenario) Step(name string, req *ScenarioRequest) *Scenario {
+	s.steps = append(s.steps, scenarioStep{
+		name: name,
+		run: func(t *testing.T, state *ScenarioState) {
+			resp := req.run(t, s.app, s.headers, state)
+			state.responses[name] = resp
+			state.Last = resp
+		},
+	})
+	return s
+}
+
+// Check adds a step that asserts on side effects, e.g. by inspecting mocks
+func (s *Scenario) Check(name string, check func(t *testing.T, state *ScenarioState)) *Scenario {
+	s.steps = append(s.steps, scenarioStep{name: name, run: check})
+	return s
+}
+
+// Eventually adds a step that polls condition until it returns nil, for
+// side effects that happen asynchronously such as webhook deliveries
+func (s *Scenario) Eventually(name string, timeout time.Duration, condition func(state *ScenarioState) error) *Scenario {
+	s.steps = append(s.steps, scenarioStep{
+		name: name,
+		run: func(t *testing.T, state *ScenarioState) {
+			deadline := time.Now().Add(timeout)
+			for {
+				err := condition(state)
+				if err == nil {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("condition not met within %s: %v", timeout, err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		},
+	})
+	return s
+}
+
+// Run runs the scenario's steps in order and returns the final state
+func (s *Scenario) Run(t *testing.T) *ScenarioState {
+	t.Helper()
+	state := &ScenarioState{
+		vars:      make(map[string]any, len(s.vars)),
+		responses: make(map[string]*TestResponse),
+	}
+	for name, value := range s.vars {
+		state.vars[name] = value
+	}
+
+	failed := ""
+	for _, step := range s.steps {
+		step := step
+		passed := t.Run(step.name, func(t *testing.T) {
+			if failed != "" {
+				t.Skipf("step %q failed", failed)
+			}
+			step.run(t, state)
+		})
+		if !passed && failed == "" {
+			failed = step.name
+		}
+	}
+	return state
+}
+
+// ScenarioState holds the variables and responses of a running scenario
+type ScenarioState struct {
+	vars      map[string]any
+	responses map[string]*TestResponse
+	requests  int
+
+	// Last is the response of the most recent request step
+	Last *TestResponse
+}
+
+// Get returns a variable
+func (s *ScenarioState) Get(name string) any {
+	return s.vars[name]
+}
+
+// String returns a variable formatted as a string
+func (s *ScenarioState) String(name string) string {
+	value, ok := s.vars[name]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Set sets a variable for later steps
+func (s *ScenarioState) Set(name string, value any) {
+	s.vars[name] = value
+}
+
+// Response returns the response of a request step
+func (s *ScenarioState) Response(step string) *TestResponse {
+	return s.responses[step]
+}
+
+var scenarioVariable = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// Expand replaces {{name}} references in text with variables
+func (s *ScenarioState) Expand(text string) (string, error) {
+	var missing []string
+	expanded := scenarioVariable.ReplaceAllStringFunc(text, func(match string) string {
+		name := scenarioVariable.FindStringSubmatch(match)[1]
+		value, ok := s.vars[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		return fmt.Sprint(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("unknown scenario variables: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// expandValue expands variables in the strings of a JSON value. A string
+// that is a single reference is replaced by the variable itself, so
+// numbers and objects keep their type.
+func (s *ScenarioState) expandValue(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if match := scenarioVariable.FindStringSubmatch(v); match != nil && match[0] == v {
+			if variable, ok := s.vars[match[1]]; ok {
+				return variable, nil
+			}
+		}
+		return s.Expand(v)
+	case map[string]any:
+		for key, element := range v {
+			expanded, err := s.expandValue(element)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+	case []any:
+		for i, element := range v {
+			expanded, err := s.expandValue(element)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	}
+	return value, nil
+}
+
+// expandJSON expands variables in a value after converting it to its JSON
+// form
+func (s *ScenarioState) expandJSON(value any) (any, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	return s.expandValue(decoded)
+}
+
+// ScenarioRequest is a request step of a scenario
+type ScenarioRequest struct {
+	method   string
+	path     string
+	body     any
+	headers  map[string]string
+	query    map[string]string
+	status   int
+	expected []scenarioExpectation
+	contains []string
+	captures []scenarioCapture
+	then     []func(t *testing.T, resp *TestResponse, state *ScenarioState)
+}
+
+type scenarioExpectation struct {
+	path  string
+	value any
+}
+
+type scenarioCapture struct {
+	name string
+	path string
+}
+
+// Request creates a request step
+func Request(method, path string) *ScenarioRequest {
+	return &ScenarioRequest{
+		method:  strings.ToUpper(method),
+		path:    path,
+		headers: make(map[string]string),
+		query:   make(map[string]string),
+	}
+}
+
+// JSON sets the request body, sent as JSON
+func (r *ScenarioRequest) JSON(body any) *ScenarioRequest {
+	r.body = body
+	return r
+}
+
+// Header sets a request header
+func (r *ScenarioRequest) Header(key, value string) *ScenarioRequest {
+	r.headers[key] = value
+	return r
+}
+
+// Query sets a query parameter
+func (r *ScenarioRequest) Query(key, value string) *ScenarioRequest {
+	r.query[key] = value
+	return r
+}
+
+// ExpectStatus fails the step unless the response has this status
+func (r *ScenarioRequest) ExpectStatus(status int) *ScenarioRequest {
+	r.status = status
+	return r
+}
+
+// ExpectJSON checks the value at a JSON path, e.g. "$.items[0].id"
+func (r *ScenarioRequest) ExpectJSON(path string, expected any) *ScenarioRequest {
+	r.expected = append(r.expected, scenarioExpectation{path: path, value: expected})
+	return r
+}
+
+// ExpectBodyContains checks that the response body contains text
+func (r *ScenarioRequest) ExpectBodyContains(text string) *ScenarioRequest {
+	r.contains = append(r.contains, text)
+	return r
+}
+
+// Capture stores the value at a JSON path of the response as a variable
+func (r *ScenarioRequest) Capture(name, path string) *ScenarioRequest {
+	r.captures = append(r.captures, scenarioCapture{name: name, path: path})
+	return r
+}
+
+// Then adds custom assertions on the response
+func (r *ScenarioRequest) Then(fn func(t *testing.T, resp *TestResponse, state *ScenarioState)) *ScenarioRequest {
+	r.then = append(r.then, fn)
+	return r
+}
+
+// run sends the request through the app and checks the response
+func (r *ScenarioRequest) run(t *testing.T, app *lift.App, defaults map[string]string, state *ScenarioState) *TestResponse {
+	state.requests++
+	path, err := state.Expand(r.path)
+	require.NoError(t, err)
+
+	headers := make(map[string]any)
+	for key, value := range defaults {
+		if expanded, err := state.Expand(value); err == nil {
+			headers[key] = expanded
+		}
+	}
+	for key, value := range r.headers {
+		expanded, err := state.Expand(value)
+		require.NoError(t, err, "header %s", key)
+		headers[key] = expanded
+	}
+
+	event := map[string]any{
+		"version":  "2.0",
+		"routeKey": "$default",
+		"rawPath":  path,
+		"headers":  headers,
+		"requestContext": map[string]any{
+			"requestId": fmt.Sprintf("scenario-%d", state.requests),
+			"stage":     "$default",
+			"http": map[string]any{
+				"method": r.method,
+				"path":   path,
+			},
+		},
+		"isBase64Encoded": false,
+	}
+	if len(r.query) > 0 {
+		query := make(map[string]any, len(r.query))
+		for key, value := range r.query {
+			expanded, err := state.Expand(value)
+			require.NoError(t, err, "query parameter %s", key)
+			query[key] = expanded
+		}
+		event["queryStringParameters"] = query
+	}
+	if r.body != nil {
+		body, err := state.expandJSON(r.body)
+		require.NoError(t, err, "request body")
+		encoded, err := json.Marshal(body)
+		require.NoError(t, err, "request body")
+		event["body"] = string(encoded)
+		if headerValue(r.headers, "Content-Type") == "" && headerValue(defaults, "Content-Type") == "" {
+			headers["Content-Type"] = "application/json"
+		}
+	}
+
+	result, err := app.HandleRequest(context.Background(), event)
+	require.NoError(t, err, "%s %s", r.method, path)
+	response, ok := result.(*lift.Response)
+	require.True(t, ok, "%s %s produced an unexpected %T", r.method, path, result)
+	body, err := responseBytes(response)
+	require.NoError(t, err, "response body")
+	resp := NewTestResponse(t, response.StatusCode, response.Headers, body, nil)
+
+	if r.status != 0 {
+		require.Equal(t, r.status, resp.StatusCode, "%s %s status\nBody: %s", r.method, path, resp.Body)
+	}
+	for _, fragment := range r.contains {
+		expanded, err := state.Expand(fragment)
+		require.NoError(t, err)
+		assert.Contains(t, resp.Body, expanded)
+	}
+	for _, expectation := range r.expected {
+		expected, err := state.expandJSON(expectation.value)
+		require.NoError(t, err, "expectation for %s", expectation.path)
+		assert.Equal(t, expected, resp.GetJSONPath(expectation.path), "%s %s: %s", r.method, path, expectation.path)
+	}
+	for _, capture := range r.captures {
+		state.Set(capture.name, resp.GetJSONPath(capture.path))
+	}
+	for _, fn := range r.then {
+		fn(t, resp, state)
+	}
+	return resp
+}
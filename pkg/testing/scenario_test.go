@@ -0,0 +1,151 @@
+package testing
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pay-theory/lift/pkg/lift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderService is a small app with an asynchronous webhook and an audit log
+type orderService struct {
+	mu       sync.Mutex
+	orders   map[string]map[string]any
+	webhooks []string
+	audit    []string
+}
+
+func newOrderService() (*orderService, *lift.App) {
+	svc := &orderService{orders: make(map[string]map[string]any)}
+	app := lift.New()
+
+	app.POST("/tenants", func(ctx *lift.Context) error {
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := ctx.ParseRequest(&req); err != nil {
+			return err
+		}
+		return ctx.Created(map[string]any{"id": "ten_1", "name": req.Name})
+	})
+	app.POST("/sessions", func(ctx *lift.Context) error {
+		var req struct {
+			TenantID string `json:"tenant_id"`
+		}
+		if err := ctx.ParseRequest(&req); err != nil {
+			return err
+		}
+		return ctx.OK(map[string]any{"token": "tok-" + req.TenantID})
+	})
+	app.POST("/orders", func(ctx *lift.Context) error {
+		if ctx.Request.GetHeader("Authorization") != "Bearer tok-ten_1" {
+			return lift.Unauthorized("invalid token")
+		}
+		var req struct {
+			Amount int `json:"amount"`
+		}
+		if err := ctx.ParseRequest(&req); err != nil {
+			return err
+		}
+
+		svc.mu.Lock()
+		id := fmt.Sprintf("ord_%d", len(svc.orders)+1)
+		order := map[string]any{"id": id, "tenant_id": "ten_1", "amount": req.Amount}
+		svc.orders[id] = order
+		svc.audit = append(svc.audit, "order.created "+id)
+		svc.mu.Unlock()
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			svc.mu.Lock()
+			defer svc.mu.Unlock()
+			svc.webhooks = append(svc.webhooks, "order.created "+id)
+		}()
+		return ctx.Created(order)
+	})
+	app.GET("/orders/:id", func(ctx *lift.Context) error {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		order, ok := svc.orders[ctx.Param("id")]
+		if !ok {
+			return lift.NotFound("order not found")
+		}
+		return ctx.OK(order)
+	})
+	return svc, app
+}
+
+func (s *orderService) delivered(event string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, webhook := range s.webhooks {
+		if webhook == event {
+			return nil
+		}
+	}
+	return errors.New("no webhook " + event)
+}
+
+func TestScenario(t *testing.T) {
+	svc, app := newOrderService()
+
+	state := NewScenario(app).
+		Header("Authorization", "Bearer {{token}}").
+		Set("amount", 1200).
+		Step("create tenant", Request("POST", "/tenants").
+			JSON(map[string]any{"name": "Acme"}).
+			ExpectStatus(201).
+			ExpectJSON("$.name", "Acme").
+			Capture("tenant_id", "$.id")).
+		Step("sign in", Request("POST", "/sessions").
+			JSON(map[string]any{"tenant_id": "{{tenant_id}}"}).
+			ExpectStatus(200).
+			Capture("token", "$.token")).
+		Step("create order", Request("POST", "/orders").
+			JSON(map[string]any{"amount": "{{amount}}"}).
+			ExpectStatus(201).
+			ExpectJSON("$.tenant_id", "{{tenant_id}}").
+			ExpectJSON("$.amount", 1200).
+			Capture("order_id", "$.id")).
+		Eventually("webhook delivered", time.Second, func(state *ScenarioState) error {
+			return svc.delivered("order.created " + state.String("order_id"))
+		}).
+		Check("audit entry", func(t *testing.T, state *ScenarioState) {
+			svc.mu.Lock()
+			defer svc.mu.Unlock()
+			assert.Equal(t, []string{"order.created " + state.String("order_id")}, svc.audit)
+		}).
+		Step("fetch order", Request("GET", "/orders/{{order_id}}").
+			ExpectStatus(200).
+			ExpectBodyContains(`"id":"{{order_id}}"`).
+			Then(func(t *testing.T, resp *TestResponse, state *ScenarioState) {
+				resp.AssertJSONPath("$.amount", float64(1200))
+			})).
+		Run(t)
+
+	assert.Equal(t, "ord_1", state.String("order_id"))
+	assert.Equal(t, "tok-ten_1", state.Get("token"))
+	require.NotNil(t, state.Response("create order"))
+	assert.Equal(t, 201, state.Response("create order").StatusCode)
+	assert.Equal(t, 200, state.Last.StatusCode)
+}
+
+func TestScenarioStateExpand(t *testing.T) {
+	state := &ScenarioState{vars: map[string]any{"id": "ord_1", "amount": float64(25)}}
+
+	expanded, err := state.Expand("/orders/{{ id }}?min={{amount}}")
+	require.NoError(t, err)
+	assert.Equal(t, "/orders/ord_1?min=25", expanded)
+
+	_, err = state.Expand("/tenants/{{tenant_id}}/users/{{user_id}}")
+	assert.EqualError(t, err, "unknown scenario variables: tenant_id, user_id")
+
+	body, err := state.expandJSON(map[string]any{"amount": "{{amount}}", "note": "order {{id}}", "ids": []string{"{{id}}"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"amount": float64(25), "note": "order ord_1", "ids": []any{"ord_1"}}, body)
+}
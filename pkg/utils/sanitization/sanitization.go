@@ -4,6 +4,7 @@ package sanitization
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/pay-theory/lift/pkg/security"
@@ -198,6 +199,28 @@ func (s *Sanitizer) SanitizeQueryParams(params map[string][]string) map[string]s
 	return result
 }
 
+// sensitiveMessagePatterns match credentials and personal data that free-form
+// messages such as error strings and panic values pick up from the values
+// being processed
+var sensitiveMessagePatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`), "$1 [REDACTED]"},
+	{regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key|authorization|ssn)["']?\s*[:=]\s*["']?)[^\s"',}&]+`), "${1}[REDACTED]"},
+	{regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), "[REDACTED_NUMBER]"},
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+}
+
+// SanitizeMessage masks credentials, card-like numbers and email addresses
+// in a free-form message
+func SanitizeMessage(message string) string {
+	for _, p := range sensitiveMessagePatterns {
+		message = p.pattern.ReplaceAllString(message, p.replacement)
+	}
+	return message
+}
+
 // SanitizeMap applies sanitization to all values in a map
 func (s *Sanitizer) SanitizeMap(data map[string]any) map[string]any {
 	result := make(map[string]any)
@@ -10,12 +10,27 @@ import (
 
 // ValidationError represents a validation error
 type ValidationError struct {
-	Field   string      `json:"field"`
-	Message string      `json:"message"`
-	Tag     string      `json:"tag"`
-	Value   any `json:"value"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Tag     string `json:"tag"`
+	// Code is a machine-readable reason, e.g. CodeTooShort
+	Code string `json:"code,omitempty"`
+	// Param is the rule's parameter, e.g. "3" for min=3
+	Param string `json:"param,omitempty"`
+	Value any    `json:"value"`
 }
 
+// Validation error codes
+const (
+	CodeRequired     = "required"
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeTooSmall     = "too_small"
+	CodeTooLarge     = "too_large"
+	CodeInvalidEmail = "invalid_email"
+	CodeNotAllowed   = "not_allowed"
+)
+
 func (e ValidationError) Error() string {
 	return fmt.Sprintf("validation failed for field '%s': %s", e.Field, e.Message)
 }
@@ -146,6 +161,7 @@ func validateRequired(field reflect.Value, fieldName string) error {
 			Field:   fieldName,
 			Message: "field is required",
 			Tag:     "required",
+			Code:    CodeRequired,
 			Value:   field.Interface(),
 		}
 	}
@@ -165,6 +181,8 @@ func validateMin(field reflect.Value, fieldName, ruleValue string) error {
 				Field:   fieldName,
 				Message: fmt.Sprintf("field must be at least %d characters", minVal),
 				Tag:     "min",
+				Code:    CodeTooShort,
+				Param:   ruleValue,
 				Value:   field.Interface(),
 			}
 		}
@@ -174,6 +192,8 @@ func validateMin(field reflect.Value, fieldName, ruleValue string) error {
 				Field:   fieldName,
 				Message: fmt.Sprintf("field must be at least %d", minVal),
 				Tag:     "min",
+				Code:    CodeTooSmall,
+				Param:   ruleValue,
 				Value:   field.Interface(),
 			}
 		}
@@ -183,6 +203,8 @@ func validateMin(field reflect.Value, fieldName, ruleValue string) error {
 				Field:   fieldName,
 				Message: fmt.Sprintf("field must be at least %d", minVal),
 				Tag:     "min",
+				Code:    CodeTooSmall,
+				Param:   ruleValue,
 				Value:   field.Interface(),
 			}
 		}
@@ -204,6 +226,8 @@ func validateMax(field reflect.Value, fieldName, ruleValue string) error {
 				Field:   fieldName,
 				Message: fmt.Sprintf("field must be at most %d characters", maxVal),
 				Tag:     "max",
+				Code:    CodeTooLong,
+				Param:   ruleValue,
 				Value:   field.Interface(),
 			}
 		}
@@ -213,6 +237,8 @@ func validateMax(field reflect.Value, fieldName, ruleValue string) error {
 				Field:   fieldName,
 				Message: fmt.Sprintf("field must be at most %d", maxVal),
 				Tag:     "max",
+				Code:    CodeTooLarge,
+				Param:   ruleValue,
 				Value:   field.Interface(),
 			}
 		}
@@ -222,6 +248,8 @@ func validateMax(field reflect.Value, fieldName, ruleValue string) error {
 				Field:   fieldName,
 				Message: fmt.Sprintf("field must be at most %d", maxVal),
 				Tag:     "max",
+				Code:    CodeTooLarge,
+				Param:   ruleValue,
 				Value:   field.Interface(),
 			}
 		}
@@ -247,6 +275,7 @@ func validateEmail(field reflect.Value, fieldName string) error {
 			Field:   fieldName,
 			Message: "field must be a valid email address",
 			Tag:     "email",
+			Code:    CodeInvalidEmail,
 			Value:   field.Interface(),
 		}
 	}
@@ -275,6 +304,8 @@ func validateOneOf(field reflect.Value, fieldName, ruleValue string) error {
 		Field:   fieldName,
 		Message: fmt.Sprintf("field must be one of: %s", strings.Join(validValues, ", ")),
 		Tag:     "oneof",
+		Code:    CodeNotAllowed,
+		Param:   ruleValue,
 		Value:   field.Interface(),
 	}
 }
@@ -0,0 +1,218 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// RuleFunc reports whether a field passes a custom rule. param is the
+// rule's parameter, e.g. "3" for `validate:"multiple=3"`.
+type RuleFunc func(field reflect.Value, param string) bool
+
+type customRule struct {
+	code    string
+	message string
+	check   RuleFunc
+}
+
+// Validator validates structs by their `validate` tags like Validate, and
+// adds custom rules, nested structs and slices, field names from another
+// tag, and translated messages:
+//
+//	v := validation.New().FieldNames("json")
+//	v.RegisterRule("currency", "invalid_currency", "{field} must be an ISO 4217 code", isCurrency)
+//	v.RegisterMessages("fr", map[string]string{
+//		validation.CodeRequired: "{field} est obligatoire",
+//	})
+//	err := v.ValidateLocale(req, "fr-CA")
+//
+// Messages are templates in which {field} and {param} are replaced by the
+// field's name and the rule's parameter. A Validator can be used as a
+// lift.Validator.
+type Validator struct {
+	mu           sync.RWMutex
+	rules        map[string]customRule
+	messages     map[string]map[string]string
+	fieldNameTag string
+}
+
+// New creates a Validator with the built-in rules
+func New() *Validator {
+	return &Validator{
+		rules:    make(map[string]customRule),
+		messages: make(map[string]map[string]string),
+	}
+}
+
+// FieldNames names fields in errors by a struct tag, e.g. "json", instead
+// of their Go names
+func (v *Validator) FieldNames(tag string) *Validator {
+	v.fieldNameTag = tag
+	return v
+}
+
+// RegisterRule adds a rule for a `validate` tag, replacing any built-in rule
+// of the same name. Fields failing it are reported with code and message.
+func (v *Validator) RegisterRule(tag, code, message string, check RuleFunc) *Validator {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[tag] = customRule{code: code, message: message, check: check}
+	return v
+}
+
+// RegisterMessages adds message templates by code for a locale, e.g. "fr"
+// or "pt-BR"
+func (v *Validator) RegisterMessages(locale string, messages map[string]string) *Validator {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	locale = strings.ToLower(locale)
+	if v.messages[locale] == nil {
+		v.messages[locale] = make(map[string]string)
+	}
+	for code, message := range messages {
+		v.messages[locale][code] = message
+	}
+	return v
+}
+
+// Validate validates a struct with untranslated messages
+func (v *Validator) Validate(value any) error {
+	return v.ValidateLocale(value, "")
+}
+
+// ValidateLocale validates a struct, translating messages into locale. A
+// regional locale such as "fr-CA" falls back to "fr", then to the default
+// messages.
+func (v *Validator) ValidateLocale(value any, locale string) error {
+	var errs ValidationErrors
+	v.validateValue(reflect.ValueOf(value), "", strings.ToLower(locale), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *Validator) validateValue(val reflect.Value, path, locale string, errs *ValidationErrors) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		typ := val.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			fieldType := typ.Field(i)
+			if !fieldType.IsExported() {
+				continue
+			}
+			field := val.Field(i)
+
+			// Fields of embedded structs are reported as the outer struct's
+			name := path
+			if !fieldType.Anonymous || v.fieldName(fieldType) != fieldType.Name {
+				name = joinPath(path, v.fieldName(fieldType))
+			}
+
+			if tag := fieldType.Tag.Get("validate"); tag != "" && tag != "-" {
+				v.validateRules(field, name, tag, locale, errs)
+			}
+			v.validateValue(field, name, locale, errs)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			v.validateValue(val.Index(i), fmt.Sprintf("%s[%d]", path, i), locale, errs)
+		}
+	}
+}
+
+func (v *Validator) validateRules(field reflect.Value, name, tag, locale string, errs *ValidationErrors) {
+	rules := strings.Split(tag, ",")
+	for _, rule := range rules {
+		if strings.TrimSpace(rule) == "omitempty" && isEmpty(field) {
+			return
+		}
+	}
+
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" || rule == "omitempty" {
+			continue
+		}
+		ruleName, param, _ := strings.Cut(rule, "=")
+
+		v.mu.RLock()
+		custom, ok := v.rules[ruleName]
+		v.mu.RUnlock()
+
+		var failure ValidationError
+		template := ""
+		if ok {
+			if custom.check(field, param) {
+				continue
+			}
+			failure = ValidationError{Field: name, Tag: ruleName, Code: custom.code, Param: param, Value: field.Interface()}
+			template = custom.message
+		} else {
+			err := validateField(field, name, rule)
+			if err == nil {
+				continue
+			}
+			if !errors.As(err, &failure) {
+				failure = ValidationError{Field: name, Message: err.Error(), Tag: ruleName, Value: field.Interface()}
+			}
+		}
+
+		if translated := v.message(locale, failure.Code); translated != "" {
+			template = translated
+		}
+		if template != "" {
+			failure.Message = strings.NewReplacer("{field}", name, "{param}", param).Replace(template)
+		}
+		*errs = append(*errs, failure)
+	}
+}
+
+// message finds the template for a code in locale, then in its language
+func (v *Validator) message(locale, code string) string {
+	if code == "" {
+		return ""
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for locale != "" {
+		if message, ok := v.messages[locale][code]; ok {
+			return message
+		}
+		cut := strings.LastIndexAny(locale, "-_")
+		if cut < 0 {
+			break
+		}
+		locale = locale[:cut]
+	}
+	return v.messages[""][code]
+}
+
+// fieldName names a field by the validator's field name tag
+func (v *Validator) fieldName(field reflect.StructField) string {
+	if v.fieldNameTag != "" {
+		name, _, _ := strings.Cut(field.Tag.Get(v.fieldNameTag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}